@@ -16,10 +16,16 @@ package bufapp
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
 	"github.com/bufbuild/buf/private/pkg/app/appname"
 	"github.com/bufbuild/buf/private/pkg/cert/certclient"
+	"github.com/bufbuild/buf/private/pkg/credentialstore"
 )
 
 const currentVersion = "v1"
@@ -30,16 +36,69 @@ type ExternalConfig struct {
 
 	Version string                             `json:"version,omitempty" yaml:"version,omitempty"`
 	TLS     certclient.ExternalClientTLSConfig `json:"tls,omitempty" yaml:"tls,omitempty"`
+	// Mirrors maps remotes, such as buf.build, to the mirrors that requests to
+	// the remote are sent to instead, in the order they are tried.
+	Mirrors map[string][]string `json:"mirrors,omitempty" yaml:"mirrors,omitempty"`
+	// CredentialStore is the type of the store that registry credentials are
+	// saved to, such as keychain. The default is netrc.
+	CredentialStore string `json:"credential_store,omitempty" yaml:"credential_store,omitempty"`
+	// SSO maps remotes to the identity providers that are used to log in to
+	// the remotes with single sign-on.
+	SSO map[string]ExternalSSOConfig `json:"sso,omitempty" yaml:"sso,omitempty"`
+	// RemoteTLS maps remotes, including mirrors, to the TLS configs that are
+	// used to connect to them instead of the TLS config, such as for
+	// self-hosted instances with a private certificate authority.
+	RemoteTLS map[string]certclient.ExternalClientTLSConfig `json:"remote_tls,omitempty" yaml:"remote_tls,omitempty"`
+	// TrustedKeys maps module names, of the form remote/owner or
+	// remote/owner/repository, to the paths of the PEM-encoded public keys
+	// that the modules must be signed with when they are downloaded.
+	TrustedKeys map[string][]string `json:"trusted_keys,omitempty" yaml:"trusted_keys,omitempty"`
+}
+
+// ExternalSSOConfig is an external single sign-on config for a remote.
+type ExternalSSOConfig struct {
+	// Issuer is the OpenID Connect issuer URL of the identity provider.
+	Issuer string `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+	// ClientID is the client ID of buf at the identity provider.
+	ClientID string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	// TokenExchangeURL is the URL of the endpoint of the remote that exchanges
+	// ID tokens from the identity provider for registry tokens, with the token
+	// exchange of RFC 8693.
+	TokenExchangeURL string `json:"token_exchange_url,omitempty" yaml:"token_exchange_url,omitempty"`
+	// Scopes are the scopes to request, if not the default scopes.
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
 }
 
 // IsEmpty returns true if the externalConfig is empty.
 func (e ExternalConfig) IsEmpty() bool {
-	return e.Version == "" && e.TLS.IsEmpty()
+	return e.Version == "" && e.TLS.IsEmpty() && len(e.Mirrors) == 0 && e.CredentialStore == "" && len(e.SSO) == 0 && len(e.RemoteTLS) == 0 && len(e.TrustedKeys) == 0
 }
 
 // Config is a config.
 type Config struct {
 	TLS *tls.Config
+	// RemoteToMirrors maps remotes to their mirrors, in the order they are tried.
+	RemoteToMirrors map[string][]string
+	// CredentialStoreType is the type of the credential store, which is
+	// empty for the default.
+	CredentialStoreType string
+	// RemoteToSSOConfig maps remotes to their single sign-on configs.
+	RemoteToSSOConfig map[string]*SSOConfig
+	// RemoteToTLS maps remotes to the TLS configs that are used for them
+	// instead of the TLS config. A nil TLS config means that TLS is not used.
+	RemoteToTLS map[string]*tls.Config
+	// TrustRoots are the keys that downloaded modules must be signed with,
+	// or nil if no module must be signed.
+	TrustRoots bufmodulesign.TrustRoots
+}
+
+// SSOConfig is a single sign-on config for a remote.
+type SSOConfig struct {
+	Issuer           string
+	ClientID         string
+	TokenExchangeURL string
+	// Scopes is empty for the default scopes.
+	Scopes []string
 }
 
 // NewConfig returns a new Config for the ExternalConfig.
@@ -54,7 +113,121 @@ func NewConfig(
 	if err != nil {
 		return nil, err
 	}
+	for remote, mirrors := range externalConfig.Mirrors {
+		if err := validateMirrors(remote, mirrors); err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: %w", container.ConfigDirPath(), err)
+		}
+	}
+	if err := validateCredentialStore(externalConfig.CredentialStore); err != nil {
+		return nil, fmt.Errorf("buf configuration at %q: %w", container.ConfigDirPath(), err)
+	}
+	remoteToSSOConfig := make(map[string]*SSOConfig, len(externalConfig.SSO))
+	for remote, externalSSOConfig := range externalConfig.SSO {
+		if err := validateSSO(remote, externalSSOConfig); err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: %w", container.ConfigDirPath(), err)
+		}
+		remoteToSSOConfig[remote] = &SSOConfig{
+			Issuer:           externalSSOConfig.Issuer,
+			ClientID:         externalSSOConfig.ClientID,
+			TokenExchangeURL: externalSSOConfig.TokenExchangeURL,
+			Scopes:           externalSSOConfig.Scopes,
+		}
+	}
+	remoteToTLS := make(map[string]*tls.Config, len(externalConfig.RemoteTLS))
+	for remote, externalClientTLSConfig := range externalConfig.RemoteTLS {
+		if err := validateHost(remote); err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: invalid remote %q in remote_tls: %w", container.ConfigDirPath(), remote, err)
+		}
+		remoteTLSConfig, err := certclient.NewClientTLSConfig(container, externalClientTLSConfig)
+		if err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: remote_tls for remote %q: %w", container.ConfigDirPath(), remote, err)
+		}
+		remoteToTLS[remote] = remoteTLSConfig
+	}
+	var trustRoots bufmodulesign.TrustRoots
+	if len(externalConfig.TrustedKeys) > 0 {
+		moduleNameToPublicKeyPEMs := make(map[string][][]byte, len(externalConfig.TrustedKeys))
+		for moduleName, publicKeyFilePaths := range externalConfig.TrustedKeys {
+			for _, publicKeyFilePath := range publicKeyFilePaths {
+				publicKeyPEM, err := os.ReadFile(publicKeyFilePath)
+				if err != nil {
+					return nil, fmt.Errorf("buf configuration at %q: trusted_keys for module %q: %w", container.ConfigDirPath(), moduleName, err)
+				}
+				moduleNameToPublicKeyPEMs[moduleName] = append(moduleNameToPublicKeyPEMs[moduleName], publicKeyPEM)
+			}
+		}
+		trustRoots, err = bufmodulesign.NewTrustRoots(moduleNameToPublicKeyPEMs)
+		if err != nil {
+			return nil, fmt.Errorf("buf configuration at %q: trusted_keys: %w", container.ConfigDirPath(), err)
+		}
+	}
 	return &Config{
-		TLS: tlsConfig,
+		TLS:                 tlsConfig,
+		RemoteToTLS:         remoteToTLS,
+		RemoteToMirrors:     externalConfig.Mirrors,
+		CredentialStoreType: externalConfig.CredentialStore,
+		RemoteToSSOConfig:   remoteToSSOConfig,
+		TrustRoots:          trustRoots,
 	}, nil
 }
+
+func validateMirrors(remote string, mirrors []string) error {
+	if err := validateHost(remote); err != nil {
+		return fmt.Errorf("invalid remote %q in mirrors: %w", remote, err)
+	}
+	if len(mirrors) == 0 {
+		return fmt.Errorf("no mirrors for remote %q", remote)
+	}
+	for _, mirror := range mirrors {
+		if err := validateHost(mirror); err != nil {
+			return fmt.Errorf("invalid mirror %q for remote %q: %w", mirror, remote, err)
+		}
+	}
+	return nil
+}
+
+func validateCredentialStore(credentialStore string) error {
+	if credentialStore == "" {
+		return nil
+	}
+	for _, credentialStoreType := range credentialstore.AllTypes {
+		if credentialStore == credentialStoreType {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown credential_store %q, must be one of %s", credentialStore, credentialstore.AllTypesString)
+}
+
+func validateSSO(remote string, externalSSOConfig ExternalSSOConfig) error {
+	if err := validateHost(remote); err != nil {
+		return fmt.Errorf("invalid remote %q in sso: %w", remote, err)
+	}
+	if externalSSOConfig.Issuer == "" {
+		return fmt.Errorf("no issuer in sso for remote %q", remote)
+	}
+	issuerURL, err := url.Parse(externalSSOConfig.Issuer)
+	if err != nil || (issuerURL.Scheme != "https" && issuerURL.Scheme != "http") || issuerURL.Host == "" {
+		return fmt.Errorf("invalid issuer %q in sso for remote %q: must be an http or https URL", externalSSOConfig.Issuer, remote)
+	}
+	if externalSSOConfig.ClientID == "" {
+		return fmt.Errorf("no client_id in sso for remote %q", remote)
+	}
+	if externalSSOConfig.TokenExchangeURL == "" {
+		return fmt.Errorf("no token_exchange_url in sso for remote %q", remote)
+	}
+	tokenExchangeURL, err := url.Parse(externalSSOConfig.TokenExchangeURL)
+	if err != nil || (tokenExchangeURL.Scheme != "https" && tokenExchangeURL.Scheme != "http") || tokenExchangeURL.Host == "" {
+		return fmt.Errorf("invalid token_exchange_url %q in sso for remote %q: must be an http or https URL", externalSSOConfig.TokenExchangeURL, remote)
+	}
+	return nil
+}
+
+func validateHost(host string) error {
+	if host == "" {
+		return errors.New("must not be empty")
+	}
+	if strings.Contains(host, "/") {
+		return errors.New("must be of the form host[:port], without a scheme or path")
+	}
+	return nil
+}
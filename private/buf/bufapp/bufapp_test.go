@@ -15,12 +15,302 @@
 package bufapp
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appname"
+	"github.com/bufbuild/buf/private/pkg/cert/certclient"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExternalConfigIsEmpty(t *testing.T) {
 	t.Parallel()
 	assert.True(t, ExternalConfig{}.IsEmpty())
 }
+
+func TestNewConfigMirrors(t *testing.T) {
+	t.Parallel()
+	container, err := appname.NewContainer(app.NewEnvContainer(map[string]string{"BUF_CONFIG_DIR": t.TempDir()}), "buf")
+	require.NoError(t, err)
+	assert.False(t, ExternalConfig{Mirrors: map[string][]string{"buf.build": {"mirror.example.com"}}}.IsEmpty())
+	config, err := NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			Mirrors: map[string][]string{
+				"buf.build": {"mirror1.example.com", "mirror2.example.com:8443"},
+			},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mirror1.example.com", "mirror2.example.com:8443"}, config.RemoteToMirrors["buf.build"])
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			Mirrors: map[string][]string{
+				"buf.build": nil,
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `no mirrors for remote "buf.build"`)
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			Mirrors: map[string][]string{
+				"buf.build": {"https://mirror.example.com"},
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `invalid mirror "https://mirror.example.com" for remote "buf.build"`)
+}
+
+func TestNewConfigCredentialStore(t *testing.T) {
+	t.Parallel()
+	container, err := appname.NewContainer(app.NewEnvContainer(map[string]string{"BUF_CONFIG_DIR": t.TempDir()}), "buf")
+	require.NoError(t, err)
+	assert.False(t, ExternalConfig{CredentialStore: "keychain"}.IsEmpty())
+	config, err := NewConfig(
+		container,
+		ExternalConfig{
+			Version:         "v1",
+			CredentialStore: "keychain",
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "keychain", config.CredentialStoreType)
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version:         "v1",
+			CredentialStore: "plaintext",
+		},
+	)
+	assert.ErrorContains(t, err, `unknown credential_store "plaintext"`)
+}
+
+func TestNewConfigSSO(t *testing.T) {
+	t.Parallel()
+	container, err := appname.NewContainer(app.NewEnvContainer(map[string]string{"BUF_CONFIG_DIR": t.TempDir()}), "buf")
+	require.NoError(t, err)
+	assert.False(t, ExternalConfig{SSO: map[string]ExternalSSOConfig{"buf.example.com": {}}}.IsEmpty())
+	config, err := NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			SSO: map[string]ExternalSSOConfig{
+				"buf.example.com": {
+					Issuer:           "https://idp.example.com",
+					ClientID:         "buf",
+					TokenExchangeURL: "https://buf.example.com/token",
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&SSOConfig{
+			Issuer:           "https://idp.example.com",
+			ClientID:         "buf",
+			TokenExchangeURL: "https://buf.example.com/token",
+		},
+		config.RemoteToSSOConfig["buf.example.com"],
+	)
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			SSO: map[string]ExternalSSOConfig{
+				"buf.example.com": {
+					Issuer:           "idp.example.com",
+					ClientID:         "buf",
+					TokenExchangeURL: "https://buf.example.com/token",
+				},
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `invalid issuer "idp.example.com" in sso for remote "buf.example.com"`)
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			SSO: map[string]ExternalSSOConfig{
+				"buf.example.com": {
+					Issuer: "https://idp.example.com",
+				},
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `no client_id in sso for remote "buf.example.com"`)
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			SSO: map[string]ExternalSSOConfig{
+				"buf.example.com": {
+					Issuer:   "https://idp.example.com",
+					ClientID: "buf",
+				},
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `no token_exchange_url in sso for remote "buf.example.com"`)
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			SSO: map[string]ExternalSSOConfig{
+				"buf.example.com": {
+					Issuer:           "https://idp.example.com",
+					ClientID:         "buf",
+					TokenExchangeURL: "/token",
+				},
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `invalid token_exchange_url "/token" in sso for remote "buf.example.com"`)
+}
+
+func TestNewConfigRemoteTLS(t *testing.T) {
+	t.Parallel()
+	configDirPath := t.TempDir()
+	container, err := appname.NewContainer(app.NewEnvContainer(map[string]string{"BUF_CONFIG_DIR": configDirPath}), "buf")
+	require.NoError(t, err)
+	certFilePath, keyFilePath := writeTestCertAndKey(t, configDirPath)
+	assert.False(t, ExternalConfig{RemoteTLS: map[string]certclient.ExternalClientTLSConfig{"buf.example.com": {}}}.IsEmpty())
+	config, err := NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			RemoteTLS: map[string]certclient.ExternalClientTLSConfig{
+				"buf.example.com": {
+					Use:                "local",
+					RootCertFilePaths:  []string{certFilePath},
+					ClientCertFilePath: certFilePath,
+					ClientKeyFilePath:  keyFilePath,
+				},
+				"localhost:8080": {
+					Use: "false",
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.NotNil(t, config.TLS)
+	remoteTLSConfig := config.RemoteToTLS["buf.example.com"]
+	require.NotNil(t, remoteTLSConfig)
+	assert.NotNil(t, remoteTLSConfig.RootCAs)
+	assert.Len(t, remoteTLSConfig.Certificates, 1)
+	remoteTLSConfig, ok := config.RemoteToTLS["localhost:8080"]
+	assert.True(t, ok)
+	assert.Nil(t, remoteTLSConfig)
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			RemoteTLS: map[string]certclient.ExternalClientTLSConfig{
+				"buf.example.com": {
+					ClientCertFilePath: certFilePath,
+				},
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `remote_tls for remote "buf.example.com": tls.client_cert_file_path and tls.client_key_file_path must be set together`)
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			RemoteTLS: map[string]certclient.ExternalClientTLSConfig{
+				"https://buf.example.com": {},
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `invalid remote "https://buf.example.com" in remote_tls`)
+}
+
+func TestNewConfigTrustedKeys(t *testing.T) {
+	t.Parallel()
+	configDirPath := t.TempDir()
+	container, err := appname.NewContainer(app.NewEnvContainer(map[string]string{"BUF_CONFIG_DIR": configDirPath}), "buf")
+	require.NoError(t, err)
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKeyData, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+	publicKeyFilePath := filepath.Join(configDirPath, "public.pem")
+	require.NoError(t, os.WriteFile(publicKeyFilePath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyData}), 0600))
+	assert.False(t, ExternalConfig{TrustedKeys: map[string][]string{"buf.build/acme": nil}}.IsEmpty())
+	config, err := NewConfig(container, ExternalConfig{Version: "v1"})
+	require.NoError(t, err)
+	assert.Nil(t, config.TrustRoots)
+	config, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			TrustedKeys: map[string][]string{
+				"buf.build/acme": {publicKeyFilePath},
+			},
+		},
+	)
+	require.NoError(t, err)
+	moduleIdentity, err := bufmoduleref.NewModuleIdentity("buf.build", "acme", "weather")
+	require.NoError(t, err)
+	assert.NotNil(t, config.TrustRoots.VerifierForModuleIdentity(moduleIdentity))
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			TrustedKeys: map[string][]string{
+				"buf.build/acme": {filepath.Join(configDirPath, "missing.pem")},
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `trusted_keys for module "buf.build/acme"`)
+	_, err = NewConfig(
+		container,
+		ExternalConfig{
+			Version: "v1",
+			TrustedKeys: map[string][]string{
+				"acme": {publicKeyFilePath},
+			},
+		},
+	)
+	assert.ErrorContains(t, err, `invalid module name "acme"`)
+}
+
+// writeTestCertAndKey writes a self-signed certificate and its key to the
+// directory, and returns their paths.
+func writeTestCertAndKey(t *testing.T, dirPath string) (string, string) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "buf.example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	certData, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	require.NoError(t, err)
+	keyData, err := x509.MarshalECPrivateKey(privateKey)
+	require.NoError(t, err)
+	certFilePath := filepath.Join(dirPath, "cert.pem")
+	keyFilePath := filepath.Join(dirPath, "key.pem")
+	require.NoError(t, os.WriteFile(certFilePath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certData}), 0600))
+	require.NoError(t, os.WriteFile(keyFilePath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyData}), 0600))
+	return certFilePath, keyFilePath
+}
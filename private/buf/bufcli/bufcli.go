@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 
 	"connectrpc.com/connect"
 	otelconnect "connectrpc.com/otelconnect"
@@ -43,6 +44,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	"github.com/bufbuild/buf/private/bufpkg/buftransport"
 	"github.com/bufbuild/buf/private/gen/data/datawkt"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
@@ -50,6 +52,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app/appname"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/credentialstore"
 	"github.com/bufbuild/buf/private/pkg/git"
 	"github.com/bufbuild/buf/private/pkg/httpauth"
 	"github.com/bufbuild/buf/private/pkg/netrc"
@@ -59,6 +62,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/bufbuild/buf/private/pkg/transport/http/httpclient"
 	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"golang.org/x/term"
 )
@@ -78,6 +82,32 @@ const (
 	// AlphaEnableWASMEnvKey is an env var to enable WASM local plugin execution
 	AlphaEnableWASMEnvKey = "BUF_ALPHA_ENABLE_WASM"
 
+	// OfflineEnvKey is an env var to disable network access to the Buf Schema Registry.
+	OfflineEnvKey = "BUF_OFFLINE"
+	// OfflineFlagName is the name of the root flag that sets OfflineEnvKey.
+	OfflineFlagName = "offline"
+
+	// ProfileEnvKey is an env var to select the profile of the configuration files.
+	ProfileEnvKey = "BUF_PROFILE"
+	// ProfileFlagName is the name of the root flag that sets ProfileEnvKey.
+	ProfileFlagName = "profile"
+
+	// JobsEnvKey is an env var to set the maximum number of concurrent jobs,
+	// such as files to compile, modules to fetch, and plugins to run.
+	JobsEnvKey = "BUF_JOBS"
+	// JobsFlagName is the name of the root flag that overrides JobsEnvKey.
+	JobsFlagName = "jobs"
+
+	// OTLPEndpointFlagName is the name of the root flag that sets the OTLP endpoint
+	// that traces are exported to.
+	OTLPEndpointFlagName = "otlp-endpoint"
+	// OTLPHeadersFlagName is the name of the root flag that sets the headers sent
+	// to the OTLP endpoint.
+	OTLPHeadersFlagName = "otlp-headers"
+	// TraceSampleRatioFlagName is the name of the root flag that sets the ratio
+	// of traces that are sampled.
+	TraceSampleRatioFlagName = "trace-sample-ratio"
+
 	inputHashtagFlagName      = "__hashtag__"
 	inputHashtagFlagShortName = "#"
 
@@ -86,8 +116,28 @@ const (
 	publicVisibility  = "public"
 	privateVisibility = "private"
 
+	ownerRole        = "owner"
+	adminRole        = "admin"
+	memberRole       = "member"
+	machineRole      = "machine"
+	writeRole        = "write"
+	readRole         = "read"
+	limitedWriteRole = "limited-write"
+
+	tagLabelNamespace       = "tag"
+	branchLabelNamespace    = "branch"
+	gitCommitLabelNamespace = "git-commit"
+	reviewLabelNamespace    = "review"
+	bsrHeadLabelNamespace   = "bsr-head"
+
 	// WASMCompilationCacheDir compiled WASM plugin cache directory
 	WASMCompilationCacheDir = "wasmplugin-bin"
+	// LSPCacheDir is the cache directory that the language server writes the files of
+	// dependency modules to, so that editors can open them.
+	LSPCacheDir = "lsp"
+	// CurlReflectionCacheDir is the cache directory where schemas downloaded using
+	// server reflection are cached.
+	CurlReflectionCacheDir = "curl-reflection"
 )
 
 var (
@@ -125,6 +175,7 @@ var (
 		v1CacheModuleLockRelDirPath,
 		v1CacheModuleSumRelDirPath,
 		v2CacheModuleRelDirPath,
+		LSPCacheDir,
 	}
 
 	// ErrNotATTY is returned when an input io.Reader is not a TTY where it is expected.
@@ -170,6 +221,32 @@ var (
 		publicVisibility,
 		privateVisibility,
 	}
+	// allOrganizationRoleStrings are the possible options that a user can set
+	// an organization role flag with.
+	allOrganizationRoleStrings = []string{
+		ownerRole,
+		adminRole,
+		memberRole,
+		machineRole,
+	}
+	// allRepositoryRoleStrings are the possible options that a user can set
+	// a repository role flag with.
+	allRepositoryRoleStrings = []string{
+		ownerRole,
+		adminRole,
+		writeRole,
+		readRole,
+		limitedWriteRole,
+	}
+	// allLabelNamespaceStrings are the possible options that a user can set
+	// a label namespace flag with.
+	allLabelNamespaceStrings = []string{
+		tagLabelNamespace,
+		branchLabelNamespace,
+		gitCommitLabelNamespace,
+		reviewLabelNamespace,
+		bsrHeadLabelNamespace,
+	}
 )
 
 // BindAsFileDescriptorSet binds the exclude-imports flag.
@@ -282,6 +359,47 @@ func BindCreateVisibility(flagSet *pflag.FlagSet, addr *string, flagName string,
 	)
 }
 
+// BindOrganizationRole binds an organization role flag.
+func BindOrganizationRole(flagSet *pflag.FlagSet, addr *string, flagName string) {
+	flagSet.StringVar(
+		addr,
+		flagName,
+		"",
+		fmt.Sprintf(`The role of the member in the organization. Must be one of %s`, stringutil.SliceToString(allOrganizationRoleStrings)),
+	)
+}
+
+// BindRepositoryRole binds a repository role flag.
+func BindRepositoryRole(flagSet *pflag.FlagSet, addr *string, flagName string) {
+	flagSet.StringVar(
+		addr,
+		flagName,
+		"",
+		fmt.Sprintf(`The role of the contributor in the repository. Must be one of %s`, stringutil.SliceToString(allRepositoryRoleStrings)),
+	)
+}
+
+// BindLabelNamespace binds a label namespace flag.
+func BindLabelNamespace(flagSet *pflag.FlagSet, addr *string, flagName string) {
+	flagSet.StringVar(
+		addr,
+		flagName,
+		"",
+		fmt.Sprintf(`The namespace of the labels. Must be one of %s. If not set, labels in all namespaces are used`, stringutil.SliceToString(allLabelNamespaceStrings)),
+	)
+}
+
+// BindLabelNamespaceWithDefault binds a label namespace flag for a single
+// label, which defaults to the tag namespace.
+func BindLabelNamespaceWithDefault(flagSet *pflag.FlagSet, addr *string, flagName string) {
+	flagSet.StringVar(
+		addr,
+		flagName,
+		tagLabelNamespace,
+		fmt.Sprintf(`The namespace of the label. Must be one of %s`, stringutil.SliceToString(allLabelNamespaceStrings)),
+	)
+}
+
 // GetInputLong gets the long command description for an input-based command.
 func GetInputLong(inputArgDescription string) string {
 	return fmt.Sprintf(
@@ -384,11 +502,14 @@ func NewStorageosProvider(disableSymlinks bool) storageos.Provider {
 }
 
 // NewWireImageConfigReader returns a new ImageConfigReader.
+//
+// The options are applied after the profile and extends resolver of the container.
 func NewWireImageConfigReader(
 	container appflag.Container,
 	storageosProvider storageos.Provider,
 	runner command.Runner,
 	clientConfig *connectclient.Config,
+	options ...bufwire.ImageConfigReaderOption,
 ) (bufwire.ImageConfigReader, error) {
 	logger := container.Logger()
 	moduleResolver := bufapimodule.NewModuleResolver(
@@ -402,9 +523,16 @@ func NewWireImageConfigReader(
 	return bufwire.NewImageConfigReader(
 		logger,
 		storageosProvider,
-		NewFetchReader(logger, storageosProvider, runner, moduleResolver, moduleReader),
+		NewFetchReader(container, logger, storageosProvider, runner, moduleResolver, moduleReader),
 		bufmodulebuild.NewModuleBucketBuilder(),
 		bufimagebuild.NewBuilder(logger, moduleReader),
+		append(
+			[]bufwire.ImageConfigReaderOption{
+				bufwire.ImageConfigReaderWithProfile(GetProfile(container)),
+				bufwire.ImageConfigReaderWithExtendsResolver(bufmodule.NewExtendsResolver(moduleReader)),
+			},
+			options...,
+		)...,
 	), nil
 }
 
@@ -427,8 +555,10 @@ func NewWireModuleConfigReader(
 	return bufwire.NewModuleConfigReader(
 		logger,
 		storageosProvider,
-		NewFetchReader(logger, storageosProvider, runner, moduleResolver, moduleReader),
+		NewFetchReader(container, logger, storageosProvider, runner, moduleResolver, moduleReader),
 		bufmodulebuild.NewModuleBucketBuilder(),
+		bufwire.ModuleConfigReaderWithProfile(GetProfile(container)),
+		bufwire.ModuleConfigReaderWithExtendsResolver(bufmodule.NewExtendsResolver(moduleReader)),
 	), nil
 }
 
@@ -449,8 +579,10 @@ func NewWireModuleConfigReaderForModuleReader(
 	return bufwire.NewModuleConfigReader(
 		logger,
 		storageosProvider,
-		NewFetchReader(logger, storageosProvider, runner, moduleResolver, moduleReader),
+		NewFetchReader(container, logger, storageosProvider, runner, moduleResolver, moduleReader),
 		bufmodulebuild.NewModuleBucketBuilder(),
+		bufwire.ModuleConfigReaderWithProfile(GetProfile(container)),
+		bufwire.ModuleConfigReaderWithExtendsResolver(bufmodule.NewExtendsResolver(moduleReader)),
 	), nil
 }
 
@@ -473,21 +605,26 @@ func NewWireFileLister(
 	return bufwire.NewFileLister(
 		logger,
 		storageosProvider,
-		NewFetchReader(logger, storageosProvider, runner, moduleResolver, moduleReader),
+		NewFetchReader(container, logger, storageosProvider, runner, moduleResolver, moduleReader),
 		bufmodulebuild.NewModuleBucketBuilder(),
 		bufimagebuild.NewBuilder(logger, moduleReader),
+		bufwire.FileListerWithProfile(GetProfile(container)),
+		bufwire.FileListerWithExtendsResolver(bufmodule.NewExtendsResolver(moduleReader)),
 	), nil
 }
 
 // NewWireImageReader returns a new ImageReader.
 func NewWireImageReader(
+	container app.EnvContainer,
 	logger *zap.Logger,
 	storageosProvider storageos.Provider,
 	runner command.Runner,
+	options ...bufwire.ImageReaderOption,
 ) bufwire.ImageReader {
 	return bufwire.NewImageReader(
 		logger,
-		newFetchMessageReader(logger, storageosProvider, runner),
+		newFetchMessageReader(container, logger, storageosProvider, runner),
+		options...,
 	)
 }
 
@@ -505,13 +642,14 @@ func NewWireImageWriter(
 
 // NewWireProtoEncodingReader returns a new ProtoEncodingReader.
 func NewWireProtoEncodingReader(
+	container app.EnvContainer,
 	logger *zap.Logger,
 	storageosProvider storageos.Provider,
 	runner command.Runner,
 ) bufwire.ProtoEncodingReader {
 	return bufwire.NewProtoEncodingReader(
 		logger,
-		newFetchMessageReader(logger, storageosProvider, runner),
+		newFetchMessageReader(container, logger, storageosProvider, runner),
 	)
 }
 
@@ -527,6 +665,22 @@ func NewWireProtoEncodingWriter(
 	)
 }
 
+// NewWireProtoEncodingStreamConverter returns a new ProtoEncodingStreamConverter.
+func NewWireProtoEncodingStreamConverter(
+	container app.EnvContainer,
+	logger *zap.Logger,
+	storageosProvider storageos.Provider,
+	runner command.Runner,
+) bufwire.ProtoEncodingStreamConverter {
+	return bufwire.NewProtoEncodingStreamConverter(
+		logger,
+		newFetchMessageReader(container, logger, storageosProvider, runner),
+		buffetch.NewWriter(
+			logger,
+		),
+	)
+}
+
 // NewModuleReaderAndCreateCacheDirs returns a new ModuleReader while creating the
 // required cache directories.
 func NewModuleReaderAndCreateCacheDirs(
@@ -547,12 +701,31 @@ func newModuleReaderAndCreateCacheDirs(
 	if err := createCacheDirs(cacheModuleDirPathV2); err != nil {
 		return nil, err
 	}
-	delegateReader := bufapimodule.NewModuleReader(
-		container.Logger(),
-		bufapimodule.NewDownloadServiceClientFactory(clientConfig),
+	pruneCacheIfConfigured(container)
+	config, err := NewConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	moduleReaderOptions := []bufapimodule.ModuleReaderOption{
 		bufapimodule.ModuleReaderWithDeprecationWarning(
 			bufapimodule.NewRepositoryServiceClientFactory(clientConfig),
 		),
+	}
+	if config.TrustRoots != nil {
+		// Modules are verified when they are downloaded, and trusted once they
+		// are in the cache.
+		moduleReaderOptions = append(
+			moduleReaderOptions,
+			bufapimodule.ModuleReaderWithSignatureVerification(
+				NewSignatureReader(clientConfig),
+				config.TrustRoots,
+			),
+		)
+	}
+	delegateReader := bufapimodule.NewModuleReader(
+		container.Logger(),
+		bufapimodule.NewDownloadServiceClientFactory(clientConfig),
+		moduleReaderOptions...,
 	)
 	storageosProvider := storageos.NewProvider(storageos.ProviderWithSymlinks())
 	var moduleReader bufmodule.ModuleReader
@@ -569,6 +742,15 @@ func newModuleReaderAndCreateCacheDirs(
 	return moduleReader, nil
 }
 
+// NewSignatureReader returns a new SignatureReader that reads the signatures
+// of commits from the registry.
+func NewSignatureReader(clientConfig *connectclient.Config) bufapimodule.SignatureReader {
+	return bufapimodule.NewSignatureReader(
+		bufapimodule.NewRepositoryServiceClientFactory(clientConfig),
+		bufapimodule.NewRepositoryTagServiceClientFactory(clientConfig),
+	)
+}
+
 // NewConfig creates a new Config.
 func NewConfig(container appflag.Container) (*bufapp.Config, error) {
 	externalConfig := bufapp.ExternalConfig{}
@@ -578,25 +760,91 @@ func NewConfig(container appflag.Container) (*bufapp.Config, error) {
 	return bufapp.NewConfig(container, externalConfig)
 }
 
+// NewCredentialStore returns the credential store that registry credentials
+// are saved to, as configured by the credential_store key of the buf
+// configuration.
+func NewCredentialStore(container appflag.Container) (credentialstore.Store, error) {
+	config, err := NewConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	return credentialstore.NewStore(container, command.NewRunner(), config.CredentialStoreType)
+}
+
+// GetMachineForName gets the registry credentials for the given name from the
+// credential store.
+//
+// If the credentials are from a single sign-on login and expire soon, they are
+// refreshed first. If the credential store is not the .netrc file and has no
+// credentials for the name, the .netrc file is used, so that credentials saved before the
+// credential store was configured can still be used.
+//
+// Returns nil if no such credentials.
+func GetMachineForName(
+	ctx context.Context,
+	container appflag.Container,
+	credentialStore credentialstore.Store,
+	name string,
+) (netrc.Machine, error) {
+	machine, err := credentialStore.GetMachine(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if machine != nil {
+		return refreshSSOMachineIfNeeded(ctx, container, credentialStore, machine)
+	}
+	if credentialStore.Type() == credentialstore.TypeNetrc {
+		return nil, nil
+	}
+	return netrc.GetMachineForName(container, name)
+}
+
+// RegistryAddress returns the address of the remote, with the scheme that is
+// used to connect to it based on the config.
+func RegistryAddress(config *bufapp.Config, remote string) string {
+	tlsConfig, ok := config.RemoteToTLS[remote]
+	if !ok {
+		tlsConfig = config.TLS
+	}
+	if tlsConfig == nil {
+		return buftransport.PrependHTTP(remote)
+	}
+	return buftransport.PrependHTTPS(remote)
+}
+
 // Returns a registry provider with the given options applied in addition to default ones for all providers
 func newConnectClientConfigWithOptions(container appflag.Container, opts ...connectclient.ConfigOption) (*connectclient.Config, error) {
 	config, err := NewConfig(container)
 	if err != nil {
 		return nil, err
 	}
-	client := httpclient.NewClient(config.TLS)
+	offline, err := IsOffline(container)
+	if err != nil {
+		return nil, err
+	}
+	var offlineErr error
+	if offline {
+		offlineErr = NewOfflineError()
+	}
+	interceptors := []connect.Interceptor{
+		bufconnect.NewSetCLIVersionInterceptor(Version),
+		bufconnect.NewCLIWarningInterceptor(container),
+		// Retries are within the warning interceptor, so that warnings are
+		// only logged for the last attempt.
+		bufconnect.NewRetryInterceptor(container.VerbosePrinter()),
+		otelconnect.NewInterceptor(),
+	}
+	client := httpclient.NewClient(
+		config.TLS,
+		httpclient.ClientWithHostTLSConfigs(config.RemoteToTLS),
+		httpclient.ClientWithHostMirrors(config.RemoteToMirrors),
+		httpclient.ClientWithOffline(offlineErr),
+	)
 	options := []connectclient.ConfigOption{
 		connectclient.WithAddressMapper(func(address string) string {
-			if config.TLS == nil {
-				return buftransport.PrependHTTP(address)
-			}
-			return buftransport.PrependHTTPS(address)
-		}),
-		connectclient.WithInterceptors([]connect.Interceptor{
-			bufconnect.NewSetCLIVersionInterceptor(Version),
-			bufconnect.NewCLIWarningInterceptor(container),
-			otelconnect.NewInterceptor(),
+			return RegistryAddress(config, address)
 		}),
+		connectclient.WithInterceptors(interceptors),
 	}
 	options = append(options, opts...)
 
@@ -604,14 +852,39 @@ func newConnectClientConfigWithOptions(container appflag.Container, opts ...conn
 }
 
 // NewConnectClientConfig creates a new connect.ClientConfig which uses a token reader to look
-// up the token in the container or in netrc based on the address of each individual client.
+// up the token in the container or in the credential store based on the address of each individual client.
 // It is then set in the header of all outgoing requests from clients created using this config.
 func NewConnectClientConfig(container appflag.Container) (*connectclient.Config, error) {
 	envTokenProvider, err := bufconnect.NewTokenProviderFromContainer(container)
 	if err != nil {
 		return nil, err
 	}
-	netrcTokenProvider := bufconnect.NewNetrcTokenProvider(container, netrc.GetMachineForName)
+	// The credential store is only created when a token is needed, as the
+	// .netrc file path cannot be determined in all environments.
+	var (
+		credentialStore     credentialstore.Store
+		credentialStoreErr  error
+		credentialStoreOnce sync.Once
+	)
+	netrcTokenProvider := bufconnect.NewNetrcTokenProvider(
+		container,
+		func(_ app.EnvContainer, name string) (netrc.Machine, error) {
+			credentialStoreOnce.Do(func() {
+				credentialStore, credentialStoreErr = NewCredentialStore(container)
+			})
+			if credentialStoreErr != nil {
+				return nil, credentialStoreErr
+			}
+			// The token provider does not have a context.
+			machine, err := GetMachineForName(context.Background(), container, credentialStore, name)
+			if err != nil {
+				// The token provider ignores errors, and sends the request
+				// without a token.
+				container.Logger().Warn(fmt.Sprintf("could not get credentials for %s: %v", name, err))
+			}
+			return machine, err
+		},
+	)
 	return newConnectClientConfigWithOptions(
 		container,
 		connectclient.WithAuthInterceptorProvider(
@@ -636,8 +909,9 @@ func NewConnectClientConfigWithToken(container appflag.Container, token string)
 }
 
 // NewFetchReader creates a new buffetch.Reader with the default HTTP client
-// and git cloner.
+// and git cloner, which fail if BUF_OFFLINE is set.
 func NewFetchReader(
+	container app.EnvContainer,
 	logger *zap.Logger,
 	storageosProvider storageos.Provider,
 	runner command.Runner,
@@ -647,9 +921,9 @@ func NewFetchReader(
 	return buffetch.NewReader(
 		logger,
 		storageosProvider,
-		defaultHTTPClient,
+		newFetchHTTPClient(container),
 		defaultHTTPAuthenticator,
-		git.NewCloner(logger, storageosProvider, runner, defaultGitClonerOptions),
+		newGitCloner(container, logger, storageosProvider, runner),
 		moduleResolver,
 		moduleReader,
 	)
@@ -704,6 +978,8 @@ func PromptUserForPassword(container app.Container, prompt string) (string, erro
 // exists and defines a module identity, returning ErrNoConfigFile and
 // ErrNoModuleName respectfully.
 //
+// The bucket must be closed by the caller.
+//
 // Workspaces are disabled when fetching the source.
 func BucketAndConfigForSource(
 	ctx context.Context,
@@ -712,7 +988,7 @@ func BucketAndConfigForSource(
 	storageosProvider storageos.Provider,
 	runner command.Runner,
 	source string,
-) (storage.ReadBucketCloser, *bufconfig.Config, error) {
+) (_ storage.ReadBucketCloser, _ *bufconfig.Config, retErr error) {
 	sourceRef, err := buffetch.NewSourceRefParser(
 		logger,
 	).GetSourceRef(
@@ -723,6 +999,7 @@ func BucketAndConfigForSource(
 		return nil, nil, err
 	}
 	sourceBucket, err := newFetchSourceReader(
+		container,
 		logger,
 		storageosProvider,
 		runner,
@@ -735,6 +1012,11 @@ func BucketAndConfigForSource(
 	if err != nil {
 		return nil, nil, err
 	}
+	defer func() {
+		if retErr != nil {
+			retErr = multierr.Append(retErr, sourceBucket.Close())
+		}
+	}()
 	existingConfigFilePath, err := bufconfig.ExistingConfigFilePath(ctx, sourceBucket)
 	if err != nil {
 		return nil, nil, NewInternalError(err)
@@ -746,6 +1028,7 @@ func BucketAndConfigForSource(
 	sourceConfig, err := bufconfig.GetConfigForBucket(
 		ctx,
 		sourceBucket,
+		bufconfig.GetConfigWithEnvContainer(container),
 	)
 	if err != nil {
 		return nil, nil, err
@@ -758,6 +1041,8 @@ func BucketAndConfigForSource(
 }
 
 // NewImageForSource resolves a single bufimage.Image from the user-provided source with the build options.
+//
+// The options are passed to NewWireImageConfigReader.
 func NewImageForSource(
 	ctx context.Context,
 	container appflag.Container,
@@ -769,6 +1054,7 @@ func NewImageForSource(
 	externalExcludeDirOrFilePaths []string,
 	externalDirOrFilePathsAllowNotExist bool,
 	excludeSourceCodeInfo bool,
+	options ...bufwire.ImageConfigReaderOption,
 ) (bufimage.Image, error) {
 	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, source)
 	if err != nil {
@@ -785,6 +1071,7 @@ func NewImageForSource(
 		storageosProvider,
 		runner,
 		clientConfig,
+		options...,
 	)
 	if err != nil {
 		return nil, err
@@ -872,11 +1159,183 @@ func VisibilityFlagToVisibilityAllowUnspecified(visibility string) (registryv1al
 	}
 }
 
+// OrganizationRoleFlagToOrganizationRole parses the given string as a registryv1alpha1.OrganizationRole.
+func OrganizationRoleFlagToOrganizationRole(role string) (registryv1alpha1.OrganizationRole, error) {
+	switch role {
+	case ownerRole:
+		return registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_OWNER, nil
+	case adminRole:
+		return registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_ADMIN, nil
+	case memberRole:
+		return registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_MEMBER, nil
+	case machineRole:
+		return registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_MACHINE, nil
+	default:
+		return 0, fmt.Errorf("invalid role: %s, expected one of %s", role, stringutil.SliceToString(allOrganizationRoleStrings))
+	}
+}
+
+// RepositoryRoleFlagToRepositoryRole parses the given string as a registryv1alpha1.RepositoryRole.
+func RepositoryRoleFlagToRepositoryRole(role string) (registryv1alpha1.RepositoryRole, error) {
+	switch role {
+	case ownerRole:
+		return registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_OWNER, nil
+	case adminRole:
+		return registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_ADMIN, nil
+	case writeRole:
+		return registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_WRITE, nil
+	case readRole:
+		return registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_READ, nil
+	case limitedWriteRole:
+		return registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_LIMITED_WRITE, nil
+	default:
+		return 0, fmt.Errorf("invalid role: %s, expected one of %s", role, stringutil.SliceToString(allRepositoryRoleStrings))
+	}
+}
+
+// LabelNamespaceFlagToLabelNamespace parses the given string as a registryv1alpha1.LabelNamespace.
+func LabelNamespaceFlagToLabelNamespace(namespace string) (registryv1alpha1.LabelNamespace, error) {
+	switch namespace {
+	case tagLabelNamespace:
+		return registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_TAG, nil
+	case branchLabelNamespace:
+		return registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_BRANCH, nil
+	case gitCommitLabelNamespace:
+		return registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_GIT_COMMIT, nil
+	case reviewLabelNamespace:
+		return registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_REVIEW, nil
+	case bsrHeadLabelNamespace:
+		return registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_BSR_HEAD, nil
+	default:
+		return 0, fmt.Errorf("invalid label namespace: %s, expected one of %s", namespace, stringutil.SliceToString(allLabelNamespaceStrings))
+	}
+}
+
+// GetOrganizationIDForName gets the ID of the organization with the given
+// name on the remote.
+func GetOrganizationIDForName(
+	ctx context.Context,
+	clientConfig *connectclient.Config,
+	remote string,
+	name string,
+) (string, error) {
+	service := connectclient.Make(clientConfig, remote, registryv1alpha1connect.NewOrganizationServiceClient)
+	resp, err := service.GetOrganizationByName(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetOrganizationByNameRequest{
+			Name: name,
+		}),
+	)
+	if err != nil {
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			return "", NewOrganizationNotFoundError(remote + "/" + name)
+		}
+		return "", err
+	}
+	return resp.Msg.Organization.Id, nil
+}
+
+// GetUserIDForUsername gets the ID of the user with the given username on
+// the remote.
+func GetUserIDForUsername(
+	ctx context.Context,
+	clientConfig *connectclient.Config,
+	remote string,
+	username string,
+) (string, error) {
+	service := connectclient.Make(clientConfig, remote, registryv1alpha1connect.NewUserServiceClient)
+	resp, err := service.GetUserByUsername(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetUserByUsernameRequest{
+			Username: username,
+		}),
+	)
+	if err != nil {
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			return "", NewUserNotFoundError(username)
+		}
+		return "", err
+	}
+	return resp.Msg.User.Id, nil
+}
+
+// GetRepositoryIDForModuleIdentity gets the ID of the repository of the
+// module identity.
+func GetRepositoryIDForModuleIdentity(
+	ctx context.Context,
+	clientConfig *connectclient.Config,
+	moduleIdentity bufmoduleref.ModuleIdentity,
+) (string, error) {
+	service := connectclient.Make(clientConfig, moduleIdentity.Remote(), registryv1alpha1connect.NewRepositoryServiceClient)
+	resp, err := service.GetRepositoryByFullName(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetRepositoryByFullNameRequest{
+			FullName: moduleIdentity.Owner() + "/" + moduleIdentity.Repository(),
+		}),
+	)
+	if err != nil {
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			return "", NewRepositoryNotFoundError(moduleIdentity.IdentityString())
+		}
+		return "", err
+	}
+	return resp.Msg.Repository.Id, nil
+}
+
+// GetCommitIDForModuleReference gets the ID of the commit that the module
+// reference resolves to on the remote.
+func GetCommitIDForModuleReference(
+	ctx context.Context,
+	clientConfig *connectclient.Config,
+	moduleReference bufmoduleref.ModuleReference,
+) (string, error) {
+	service := connectclient.Make(clientConfig, moduleReference.Remote(), registryv1alpha1connect.NewRepositoryCommitServiceClient)
+	resp, err := service.GetRepositoryCommitByReference(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetRepositoryCommitByReferenceRequest{
+			RepositoryOwner: moduleReference.Owner(),
+			RepositoryName:  moduleReference.Repository(),
+			Reference:       moduleReference.Reference(),
+		}),
+	)
+	if err != nil {
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			return "", NewModuleReferenceNotFoundError(moduleReference)
+		}
+		return "", err
+	}
+	return resp.Msg.RepositoryCommit.Id, nil
+}
+
 // IsAlphaWASMEnabled returns an BUF_ALPHA_ENABLE_WASM is set to true.
 func IsAlphaWASMEnabled(container app.EnvContainer) (bool, error) {
 	return app.EnvBool(container, AlphaEnableWASMEnvKey, false)
 }
 
+// NewOfflineError returns the error that network requests fail with if
+// BUF_OFFLINE is set, either directly or with the --offline flag.
+func NewOfflineError() error {
+	return fmt.Errorf(
+		"%w with --%s or %s=1. Only the local module and plugin caches can be used, so run the command without it to populate the caches",
+		bufconnect.ErrOffline,
+		OfflineFlagName,
+		OfflineEnvKey,
+	)
+}
+
+// IsOffline returns true if BUF_OFFLINE is set to true, either directly or with the --offline flag.
+func IsOffline(container app.EnvContainer) (bool, error) {
+	return app.EnvBool(container, OfflineEnvKey, false)
+}
+
+// GetProfile returns the profile of the configuration files to use, which is set with
+// BUF_PROFILE, either directly or with the --profile flag.
+//
+// Returns empty if no profile is selected.
+func GetProfile(container app.EnvContainer) string {
+	return strings.TrimSpace(container.Env(ProfileEnvKey))
+}
+
 // ValidateErrorFormatFlag validates the error format flag for all commands but lint.
 func ValidateErrorFormatFlag(errorFormatString string, errorFormatFlagName string) error {
 	return validateErrorFormatFlag(bufanalysis.AllFormatStrings, errorFormatString, errorFormatFlagName)
@@ -1001,8 +1460,9 @@ func promptUser(container app.Container, prompt string, isPassword bool) (string
 }
 
 // newFetchSourceReader creates a new buffetch.SourceReader with the default HTTP client
-// and git cloner.
+// and git cloner, which fail if BUF_OFFLINE is set.
 func newFetchSourceReader(
+	container app.EnvContainer,
 	logger *zap.Logger,
 	storageosProvider storageos.Provider,
 	runner command.Runner,
@@ -1010,15 +1470,16 @@ func newFetchSourceReader(
 	return buffetch.NewSourceReader(
 		logger,
 		storageosProvider,
-		defaultHTTPClient,
+		newFetchHTTPClient(container),
 		defaultHTTPAuthenticator,
-		git.NewCloner(logger, storageosProvider, runner, defaultGitClonerOptions),
+		newGitCloner(container, logger, storageosProvider, runner),
 	)
 }
 
 // newFetchMessageReader creates a new buffetch.MessageReader with the default HTTP client
-// and git cloner.
+// and git cloner, which fail if BUF_OFFLINE is set.
 func newFetchMessageReader(
+	container app.EnvContainer,
 	logger *zap.Logger,
 	storageosProvider storageos.Provider,
 	runner command.Runner,
@@ -1026,12 +1487,51 @@ func newFetchMessageReader(
 	return buffetch.NewMessageReader(
 		logger,
 		storageosProvider,
-		defaultHTTPClient,
+		newFetchHTTPClient(container),
 		defaultHTTPAuthenticator,
-		git.NewCloner(logger, storageosProvider, runner, defaultGitClonerOptions),
+		newGitCloner(container, logger, storageosProvider, runner),
 	)
 }
 
+// newFetchHTTPClient returns the HTTP client for inputs, which fails all
+// requests if BUF_OFFLINE is set.
+func newFetchHTTPClient(container app.EnvContainer) *http.Client {
+	offlineErr := getOfflineError(container)
+	if offlineErr == nil {
+		return defaultHTTPClient
+	}
+	return httpclient.NewClient(nil, httpclient.ClientWithOffline(offlineErr))
+}
+
+// newGitCloner returns the git cloner for inputs, which fails to clone
+// remote repositories if BUF_OFFLINE is set.
+func newGitCloner(
+	container app.EnvContainer,
+	logger *zap.Logger,
+	storageosProvider storageos.Provider,
+	runner command.Runner,
+) git.Cloner {
+	options := defaultGitClonerOptions
+	options.OfflineErr = getOfflineError(container)
+	return git.NewCloner(logger, storageosProvider, runner, options)
+}
+
+// getOfflineError returns the error from NewOfflineError if BUF_OFFLINE is
+// set, and nil otherwise.
+//
+// If BUF_OFFLINE is not a valid bool, the error for it is returned instead,
+// so that no network requests are made.
+func getOfflineError(container app.EnvContainer) error {
+	offline, err := IsOffline(container)
+	if err != nil {
+		return err
+	}
+	if offline {
+		return NewOfflineError()
+	}
+	return nil
+}
+
 func checkExistingCacheDirs(baseCacheDirPath string, dirPaths ...string) error {
 	dirPathsToCheck := make([]string, 0, len(dirPaths)+1)
 	// Check base cache directory in addition to subdirectories
@@ -22,12 +22,14 @@ import (
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -177,6 +179,112 @@ func TestBucketAndConfigForSource(t *testing.T) {
 	)
 }
 
+func TestOrganizationRoleFlagToOrganizationRole(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		role        string
+		expected    registryv1alpha1.OrganizationRole
+		expectedErr string
+	}{
+		{
+			role:     "owner",
+			expected: registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_OWNER,
+		},
+		{
+			role:     "admin",
+			expected: registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_ADMIN,
+		},
+		{
+			role:     "member",
+			expected: registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_MEMBER,
+		},
+		{
+			role:     "machine",
+			expected: registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_MACHINE,
+		},
+		{
+			role:        "",
+			expectedErr: "invalid role: , expected one of [owner,admin,member,machine]",
+		},
+		{
+			role:        "write",
+			expectedErr: "invalid role: write, expected one of [owner,admin,member,machine]",
+		},
+		{
+			role:        "Owner",
+			expectedErr: "invalid role: Owner, expected one of [owner,admin,member,machine]",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.role, func(t *testing.T) {
+			t.Parallel()
+			role, err := bufcli.OrganizationRoleFlagToOrganizationRole(testCase.role)
+			if testCase.expectedErr != "" {
+				assert.EqualError(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, role)
+		})
+	}
+}
+
+func TestRepositoryRoleFlagToRepositoryRole(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		role        string
+		expected    registryv1alpha1.RepositoryRole
+		expectedErr string
+	}{
+		{
+			role:     "owner",
+			expected: registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_OWNER,
+		},
+		{
+			role:     "admin",
+			expected: registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_ADMIN,
+		},
+		{
+			role:     "write",
+			expected: registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_WRITE,
+		},
+		{
+			role:     "read",
+			expected: registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_READ,
+		},
+		{
+			role:     "limited-write",
+			expected: registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_LIMITED_WRITE,
+		},
+		{
+			role:        "",
+			expectedErr: "invalid role: , expected one of [owner,admin,write,read,limited-write]",
+		},
+		{
+			role:        "member",
+			expectedErr: "invalid role: member, expected one of [owner,admin,write,read,limited-write]",
+		},
+		{
+			role:        "limited_write",
+			expectedErr: "invalid role: limited_write, expected one of [owner,admin,write,read,limited-write]",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.role, func(t *testing.T) {
+			t.Parallel()
+			role, err := bufcli.RepositoryRoleFlagToRepositoryRole(testCase.role)
+			if testCase.expectedErr != "" {
+				assert.EqualError(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, role)
+		})
+	}
+}
+
 func moduleFiles(name string) map[string][]byte {
 	bufConfig := "version: v1\n"
 	if name != "" {
@@ -0,0 +1,160 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/diskcache"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"go.uber.org/zap"
+)
+
+const (
+	// CacheMaxSizeEnvKey is an env var to set the maximum size of the cache, such as
+	// "5GB". If set, the least recently used entries of the cache are removed when
+	// the cache is larger.
+	CacheMaxSizeEnvKey = "BUF_CACHE_MAX_SIZE"
+	// CacheMaxAgeEnvKey is an env var to set the maximum age of the entries of the
+	// cache, such as "30d". If set, entries that were last used longer ago are removed.
+	//
+	// With either env var, entries that were used within the last hour are not removed.
+	CacheMaxAgeEnvKey = "BUF_CACHE_MAX_AGE"
+
+	// cachePruneInterval is the minimum interval between automatic prunes of the
+	// cache, as listing the cache reads the metadata of all of its files.
+	cachePruneInterval = time.Hour
+	// cachePruneFileName is the file in the cache directory whose modification
+	// time is the time of the last automatic prune.
+	cachePruneFileName = ".last-prune"
+	// cachePruneMinAge is the minimum time since the last use of an entry before
+	// it is automatically pruned. Other buf processes may be reading or writing
+	// the entries that were used recently, and the automatic prune does not take
+	// the locks of the caches.
+	cachePruneMinAge = time.Hour
+)
+
+// NewCaches returns the caches in the cache directory.
+//
+// Each entry of a cache can be removed on its own, and is fetched or written
+// again when it is next needed.
+func NewCaches(cacheDirPath string) []diskcache.Cache {
+	newCache := func(name string, relDirPath string, entryDepth int) diskcache.Cache {
+		return diskcache.Cache{
+			Name:       name,
+			DirPath:    filepath.Join(cacheDirPath, normalpath.Unnormalize(relDirPath)),
+			EntryDepth: entryDepth,
+		}
+	}
+	return []diskcache.Cache{
+		// {remote}/{owner}/{repository}
+		newCache("module", v2CacheModuleRelDirPath, 3),
+		// Caches of older versions of buf, which are no longer read.
+		newCache("module-legacy", v1beta1CacheModuleDataRelDirPath, 0),
+		newCache("module-legacy", v1beta1CacheModuleLockRelDirPath, 0),
+		newCache("module-legacy", normalpath.Dir(v1CacheModuleDataRelDirPath), 0),
+		// {remote}/{owner}/{repository}/{commit}, and the Well-Known Types.
+		newCache("lsp", LSPCacheDir, 4),
+		// The compiled plugins of each version of the WASM runtime.
+		newCache("wasm-plugin", WASMCompilationCacheDir, 2),
+		// A schema for each server and service.
+		newCache("curl-reflection", CurlReflectionCacheDir, 1),
+	}
+}
+
+// GetCachePruneOptions returns the options to prune the cache with as set by
+// CacheMaxSizeEnvKey and CacheMaxAgeEnvKey.
+//
+// Returns nil if neither is set.
+func GetCachePruneOptions(envContainer app.EnvContainer) ([]diskcache.PruneOption, error) {
+	var pruneOptions []diskcache.PruneOption
+	if maxSizeString := envContainer.Env(CacheMaxSizeEnvKey); maxSizeString != "" {
+		maxSize, err := diskcache.ParseSize(maxSizeString)
+		if err != nil {
+			return nil, fmt.Errorf("$%s: %w", CacheMaxSizeEnvKey, err)
+		}
+		pruneOptions = append(pruneOptions, diskcache.PruneWithMaxSize(maxSize))
+	}
+	if maxAgeString := envContainer.Env(CacheMaxAgeEnvKey); maxAgeString != "" {
+		maxAge, err := diskcache.ParseAge(maxAgeString)
+		if err != nil {
+			return nil, fmt.Errorf("$%s: %w", CacheMaxAgeEnvKey, err)
+		}
+		pruneOptions = append(pruneOptions, diskcache.PruneWithMaxAge(maxAge))
+	}
+	return pruneOptions, nil
+}
+
+// pruneCacheIfConfigured prunes the cache if CacheMaxSizeEnvKey or CacheMaxAgeEnvKey
+// is set and the cache was not pruned within the cachePruneInterval.
+//
+// The cache is only an optimization, so errors are logged instead of returned.
+func pruneCacheIfConfigured(container appflag.Container) {
+	logger := container.Logger()
+	pruneOptions, err := GetCachePruneOptions(container)
+	if err != nil {
+		logger.Warn("not pruning cache", zap.Error(err))
+		return
+	}
+	if len(pruneOptions) == 0 {
+		return
+	}
+	pruneFilePath := filepath.Join(container.CacheDirPath(), cachePruneFileName)
+	fileInfo, err := os.Stat(pruneFilePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Warn("not pruning cache", zap.Error(err))
+		return
+	}
+	now := time.Now()
+	if err == nil && now.Sub(fileInfo.ModTime()) < cachePruneInterval {
+		return
+	}
+	// Written before pruning so that concurrent invocations do not also prune.
+	if err := os.WriteFile(pruneFilePath, nil, 0600); err != nil {
+		logger.Warn("not pruning cache", zap.Error(err))
+		return
+	}
+	if err := os.Chtimes(pruneFilePath, now, now); err != nil {
+		logger.Warn("not pruning cache", zap.Error(err))
+		return
+	}
+	entries, err := diskcache.ListEntries(NewCaches(container.CacheDirPath())...)
+	if err != nil {
+		logger.Warn("could not list cache", zap.Error(err))
+		return
+	}
+	removedEntries, err := diskcache.Prune(
+		entries,
+		append(pruneOptions, diskcache.PruneWithMinAge(cachePruneMinAge))...,
+	)
+	for _, removedEntry := range removedEntries {
+		logger.Debug(
+			"removed cache entry",
+			zap.String("cache", removedEntry.CacheName),
+			zap.String("path", removedEntry.Path),
+			zap.Int64("size", removedEntry.Size),
+		)
+	}
+	if err != nil {
+		logger.Warn("could not prune cache", zap.Error(err))
+	}
+}
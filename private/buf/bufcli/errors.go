@@ -129,6 +129,12 @@ func NewRepositoryNotFoundError(name string) error {
 	return fmt.Errorf(`a repository named %q does not exist, use "buf beta registry repository create" to create one`, name)
 }
 
+// NewUserNotFoundError informs the user that a user with that username does
+// not exist.
+func NewUserNotFoundError(username string) error {
+	return fmt.Errorf("a user named %q does not exist", username)
+}
+
 // NewModuleReferenceNotFoundError informs the user that a module
 // reference does not exist.
 func NewModuleReferenceNotFoundError(reference bufmoduleref.ModuleReference) error {
@@ -163,6 +169,11 @@ func wrapError(err error) error {
 	}
 	// If the error is a Connect error, then interpret it and return an intuitive message
 	if ok {
+		// Requests fail in the transport if network access is disabled,
+		// which is not because the server is unavailable.
+		if errors.Is(err, bufconnect.ErrOffline) {
+			return fmt.Errorf("Failure: %w", connectErr.Unwrap())
+		}
 		connectCode := connectErr.Code()
 		switch {
 		case connectCode == connect.CodeUnauthenticated, isEmptyUnknownError(err):
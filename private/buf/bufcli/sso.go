@@ -0,0 +1,141 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufsso"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/credentialstore"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/netrc"
+	"github.com/bufbuild/buf/private/pkg/transport/http/httpclient"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	// ssoRefreshLockFileName is the name of the file that is locked while
+	// single sign-on credentials are refreshed, in the directory of the
+	// .netrc file, or the config directory for other credential stores.
+	ssoRefreshLockFileName = ".buf-sso.lock"
+	// ssoRefreshLockTimeout is how long to wait for another process to
+	// refresh the credentials, which takes requests to the identity provider
+	// and the registry.
+	ssoRefreshLockTimeout = 30 * time.Second
+)
+
+// refreshSSOMachineIfNeeded returns the machine, or the refreshed machine if
+// it is from a single sign-on login and its registry token expires soon.
+func refreshSSOMachineIfNeeded(
+	ctx context.Context,
+	container appflag.Container,
+	credentialStore credentialstore.Store,
+	machine netrc.Machine,
+) (_ netrc.Machine, retErr error) {
+	remote := machine.Name()
+	session, err := getSSOSession(ctx, credentialStore, remote)
+	if err != nil || session == nil || !session.NeedsRefresh(time.Now()) {
+		return machine, err
+	}
+	// Refreshes are serialized across processes, as identity providers may
+	// rotate refresh tokens, which can only be used once.
+	lockFilePath, err := getSSORefreshLockFilePath(container, credentialStore)
+	if err != nil {
+		return nil, err
+	}
+	unlocker, err := filelock.Lock(ctx, lockFilePath, filelock.LockWithTimeout(ssoRefreshLockTimeout))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, unlocker.Unlock())
+	}()
+	// The session may have been refreshed while waiting for the lock.
+	session, err = getSSOSession(ctx, credentialStore, remote)
+	if err != nil || session == nil {
+		return machine, err
+	}
+	if !session.NeedsRefresh(time.Now()) {
+		return credentialStore.GetMachine(ctx, remote)
+	}
+	config, err := NewConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := bufsso.Refresh(
+		ctx,
+		httpclient.NewClient(config.TLS, httpclient.ClientWithHostTLSConfigs(config.RemoteToTLS)),
+		session,
+		time.Now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	refreshedMachine := netrc.NewMachine(remote, machine.Login(), credentials.RegistryToken)
+	if err := PutSSOCredentials(ctx, credentialStore, refreshedMachine, credentials.Session); err != nil {
+		return nil, err
+	}
+	container.Logger().Debug("refreshed single sign-on credentials", zap.String("remote", remote))
+	return refreshedMachine, nil
+}
+
+// getSSORefreshLockFilePath returns the path of the file that is locked while
+// single sign-on credentials in the credential store are refreshed.
+func getSSORefreshLockFilePath(container appflag.Container, credentialStore credentialstore.Store) (string, error) {
+	if credentialStore.Type() != credentialstore.TypeNetrc {
+		return filepath.Join(container.ConfigDirPath(), ssoRefreshLockFileName), nil
+	}
+	netrcFilePath, err := netrc.GetFilePath(container)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(netrcFilePath), ssoRefreshLockFileName), nil
+}
+
+// PutSSOCredentials saves the machine with the registry token from a single
+// sign-on login, and the session that is used to refresh it.
+func PutSSOCredentials(
+	ctx context.Context,
+	credentialStore credentialstore.Store,
+	machine netrc.Machine,
+	session *bufsso.Session,
+) error {
+	sessionMachine, err := bufsso.NewSessionMachine(machine.Name(), session)
+	if err != nil {
+		return err
+	}
+	if err := credentialStore.PutMachine(ctx, sessionMachine); err != nil {
+		return err
+	}
+	return credentialStore.PutMachine(ctx, machine)
+}
+
+// getSSOSession gets the single sign-on session for the remote, or nil if
+// there is none.
+func getSSOSession(
+	ctx context.Context,
+	credentialStore credentialstore.Store,
+	remote string,
+) (*bufsso.Session, error) {
+	sessionMachine, err := credentialStore.GetMachine(ctx, bufsso.SessionMachineName(remote))
+	if err != nil || sessionMachine == nil {
+		return nil, err
+	}
+	return bufsso.SessionForMachine(sessionMachine)
+}
@@ -0,0 +1,217 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+)
+
+// authHelperRequest is written to the stdin of an auth helper.
+type authHelperRequest struct {
+	// The base URL of the server.
+	URL string `json:"url"`
+	// If true, the credentials from the previous invocation of the helper
+	// were rejected by the server, so the helper should not return them
+	// again, such as by refreshing an OAuth2 access token.
+	Refresh bool `json:"refresh,omitempty"`
+}
+
+// authHelperResponse is read from the stdout of an auth helper.
+type authHelperResponse struct {
+	// Headers to add to requests.
+	Headers map[string]string `json:"headers,omitempty"`
+	// A bearer token, which is added to requests as an "Authorization" header.
+	Token string `json:"token,omitempty"`
+}
+
+// NewAuthHelperInterceptor returns an interceptor that adds headers obtained
+// from the given auth helper command to requests. The helper command is split
+// on whitespace into the name of the program and its arguments. The helper is
+// run once, when this function is called, and the headers are used for all
+// requests, except for headers that are already present in a request.
+//
+// The helper reads a JSON object from stdin with the base URL of the server in
+// a "url" field, and writes a JSON object to stdout with a "headers" field
+// that maps header names to values, or a "token" field with a bearer token for
+// the "Authorization" header. If a unary RPC fails with the "unauthenticated"
+// code, the helper is run again with a "refresh" field set to true in its
+// input, and the RPC is retried once with the new headers.
+func NewAuthHelperInterceptor(
+	ctx context.Context,
+	container app.EnvStderrContainer,
+	runner command.Runner,
+	helper string,
+	baseURL string,
+	printer verbose.Printer,
+) (connect.Interceptor, error) {
+	helperFields := strings.Fields(helper)
+	if len(helperFields) == 0 {
+		return nil, errors.New("auth helper command is empty")
+	}
+	interceptor := &authHelperInterceptor{
+		container: container,
+		runner:    runner,
+		name:      helperFields[0],
+		args:      helperFields[1:],
+		baseURL:   baseURL,
+		printer:   printer,
+	}
+	headers, err := interceptor.run(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	interceptor.headers = headers
+	return interceptor, nil
+}
+
+type authHelperInterceptor struct {
+	container app.EnvStderrContainer
+	runner    command.Runner
+	name      string
+	args      []string
+	baseURL   string
+	printer   verbose.Printer
+
+	lock sync.Mutex
+	// The headers from the last invocation of the helper, and the number of
+	// times the helper has been invoked.
+	headers    http.Header
+	generation int
+}
+
+func (a *authHelperInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		requestHeaders := req.Header().Clone()
+		helperHeaders, generation := a.currentHeaders()
+		setHelperHeaders(req.Header(), requestHeaders, helperHeaders)
+		resp, err := next(ctx, req)
+		if connect.CodeOf(err) != connect.CodeUnauthenticated {
+			return resp, err
+		}
+		refreshedHeaders, refreshErr := a.refreshHeaders(ctx, generation)
+		if refreshErr != nil {
+			return nil, refreshErr
+		}
+		for name := range helperHeaders {
+			if _, ok := requestHeaders[name]; !ok {
+				req.Header().Del(name)
+			}
+		}
+		setHelperHeaders(req.Header(), requestHeaders, refreshedHeaders)
+		a.printer.Printf("* Retrying RPC with refreshed credentials from auth helper")
+		return next(ctx, req)
+	}
+}
+
+func (a *authHelperInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		helperHeaders, _ := a.currentHeaders()
+		setHelperHeaders(conn.RequestHeader(), conn.RequestHeader().Clone(), helperHeaders)
+		return conn
+	}
+}
+
+func (a *authHelperInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+func (a *authHelperInterceptor) currentHeaders() (http.Header, int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.headers, a.generation
+}
+
+// refreshHeaders runs the helper to refresh the headers, unless they have
+// already been refreshed since the given generation, such as by a concurrent
+// RPC that also failed.
+func (a *authHelperInterceptor) refreshHeaders(ctx context.Context, generation int) (http.Header, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.generation != generation {
+		return a.headers, nil
+	}
+	headers, err := a.run(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	a.headers = headers
+	a.generation++
+	return headers, nil
+}
+
+func (a *authHelperInterceptor) run(ctx context.Context, refresh bool) (http.Header, error) {
+	request, err := json.Marshal(&authHelperRequest{
+		URL:     a.baseURL,
+		Refresh: refresh,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if refresh {
+		a.printer.Printf("* Refreshing credentials with auth helper %s", a.name)
+	} else {
+		a.printer.Printf("* Obtaining credentials with auth helper %s", a.name)
+	}
+	stdout := bytes.NewBuffer(nil)
+	if err := a.runner.Run(
+		ctx,
+		a.name,
+		command.RunWithArgs(a.args...),
+		command.RunWithEnv(app.EnvironMap(a.container)),
+		command.RunWithStdin(bytes.NewReader(request)),
+		command.RunWithStdout(stdout),
+		command.RunWithStderr(a.container.Stderr()),
+	); err != nil {
+		return nil, fmt.Errorf("auth helper %s failed: %w", a.name, err)
+	}
+	var response authHelperResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("auth helper %s returned invalid output: %w", a.name, err)
+	}
+	headers := http.Header{}
+	for name, value := range response.Headers {
+		headers.Set(name, value)
+	}
+	if response.Token != "" {
+		headers.Set("Authorization", "Bearer "+response.Token)
+	}
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("auth helper %s returned neither headers nor a token", a.name)
+	}
+	return headers, nil
+}
+
+// setHelperHeaders sets the helper headers in the given headers, except for
+// those that are present in the original request headers.
+func setHelperHeaders(headers http.Header, requestHeaders http.Header, helperHeaders http.Header) {
+	for name, values := range helperHeaders {
+		if _, ok := requestHeaders[name]; !ok {
+			headers[name] = values
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// batchPendingResultsPerWorker bounds the number of results, per worker, that
+// are kept in memory while they wait for the result of an earlier request,
+// since results are written in the order of the requests.
+const batchPendingResultsPerWorker = 16
+
+// batchResult is written to the output for every request of a batch.
+type batchResult struct {
+	// The line of the input that contains the request.
+	Line int `json:"line"`
+	// The response message, if the RPC succeeded.
+	Response json.RawMessage `json:"response,omitempty"`
+	// The error, in the same format as errors are printed by other RPCs, if
+	// the RPC failed, or if the request is invalid.
+	Error json.RawMessage `json:"error,omitempty"`
+
+	code connect.Code
+	// If true, the request is invalid, and the RPC was not invoked.
+	invalid bool
+}
+
+type batchRequest struct {
+	line int
+	data []byte
+	// The result is sent to done when the request is complete.
+	done chan *batchResult
+}
+
+func (inv *invoker) InvokeBatch(
+	ctx context.Context,
+	dataSource string,
+	data io.Reader,
+	headers http.Header,
+	concurrency int,
+) (*BatchSummary, error) {
+	if inv.md.IsStreamingClient() || inv.md.IsStreamingServer() {
+		return nil, fmt.Errorf("method %s is a streaming RPC, but only unary RPCs can be invoked in a batch", inv.md.Name())
+	}
+	if data == nil {
+		data = bytes.NewReader(nil)
+	}
+	inv.printer.Printf("* Invoking RPC %s for every request in %s with %d concurrent workers", inv.md.FullName(), dataSource, concurrency)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var fatalErr error
+	var fatalErrOnce sync.Once
+	fail := func(err error) {
+		fatalErrOnce.Do(func() {
+			fatalErr = err
+			cancel()
+		})
+	}
+
+	requests := make(chan *batchRequest)
+	// Requests are added to pending in order, so that their results can be
+	// written in order.
+	pending := make(chan *batchRequest, concurrency*batchPendingResultsPerWorker)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for request := range requests {
+				result, err := inv.invokeBatchRequest(ctx, dataSource, request, headers)
+				if err != nil {
+					fail(err)
+				}
+				// The result is nil if there was an error, but it is sent
+				// regardless so that the writer does not wait for it.
+				request.done <- result
+			}
+		}()
+	}
+	go func() {
+		defer close(pending)
+		defer close(requests)
+		reader := bufio.NewReader(data)
+		for line := 1; ; line++ {
+			lineData, err := reader.ReadBytes('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				fail(ErrorHasFilename(err, dataSource))
+				return
+			}
+			if trimmed := bytes.TrimSpace(lineData); len(trimmed) > 0 {
+				request := &batchRequest{
+					line: line,
+					data: trimmed,
+					done: make(chan *batchResult, 1),
+				}
+				select {
+				case pending <- request:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case requests <- request:
+				case <-ctx.Done():
+					// The request was added to pending, so it must complete.
+					request.done <- nil
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	summary := &BatchSummary{
+		Errors: make(map[connect.Code]int),
+	}
+	for request := range pending {
+		result := <-request.done
+		// After a fatal error, the remaining results are discarded.
+		if result == nil || ctx.Err() != nil {
+			continue
+		}
+		if result.invalid {
+			summary.InvalidRequests++
+		} else {
+			summary.Requests++
+			if result.Error != nil {
+				summary.Errors[result.code]++
+			}
+		}
+		output, err := json.Marshal(result)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		if _, err := inv.output.Write(append(output, '\n')); err != nil {
+			fail(err)
+		}
+	}
+	wg.Wait()
+	if fatalErr != nil {
+		return nil, fatalErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	inv.printer.Printf(
+		"* Batch complete: %d RPCs, %d failed, %d invalid requests",
+		summary.Requests,
+		summary.ErrorCount(),
+		summary.InvalidRequests,
+	)
+	return summary, nil
+}
+
+// invokeBatchRequest invokes the RPC for a request of a batch. RPC errors and
+// invalid request data are returned in the result, and other errors are
+// returned.
+func (inv *invoker) invokeBatchRequest(
+	ctx context.Context,
+	dataSource string,
+	request *batchRequest,
+	headers http.Header,
+) (*batchResult, error) {
+	source := fmt.Sprintf("%s:%d", dataSource, request.line)
+	// An invalid request is reported in the result for its line, so that the
+	// rest of the batch is still invoked.
+	if err := inv.validateBatchRequest(source, request.data); err != nil {
+		errorData, err := marshalError(connect.NewError(connect.CodeInvalidArgument, err), inv.res)
+		if err != nil {
+			return nil, err
+		}
+		return &batchResult{
+			Line:    request.line,
+			Error:   errorData,
+			code:    connect.CodeInvalidArgument,
+			invalid: true,
+		}, nil
+	}
+	// Each request uses a copy of the invoker that captures the response and
+	// returns RPC errors.
+	var response bytes.Buffer
+	requestInvoker := *inv
+	requestInvoker.formatter = jsonResponseFormatter{}
+	requestInvoker.output = &response
+	requestInvoker.errOutput = io.Discard
+	requestInvoker.rawErrors = true
+	err := requestInvoker.Invoke(ctx, source, bytes.NewReader(request.data), headers.Clone())
+	result := &batchResult{
+		Line: request.line,
+	}
+	if err != nil {
+		var connectErr *connect.Error
+		if !errors.As(err, &connectErr) {
+			return nil, err
+		}
+		result.code = connectErr.Code()
+		result.Error, err = marshalError(connectErr, inv.res)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	result.Response = response.Bytes()
+	return result, nil
+}
+
+// validateBatchRequest returns an error if the data of a request of a batch is
+// not a single request message.
+func (inv *invoker) validateBatchRequest(source string, data []byte) error {
+	provider := newStreamMessageProvider(source, bytes.NewReader(data), inv.res)
+	if err := provider.next(dynamicpb.NewMessage(inv.md.Input())); err != nil {
+		return err
+	}
+	if err := provider.next(dynamicpb.NewMessage(inv.md.Input())); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%s contains more than one request message", source)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	healthv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/health/v1"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestInvokeBatch(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.Handle(
+		"/grpc.health.v1.Health/Check",
+		connect.NewUnaryHandler(
+			"/grpc.health.v1.Health/Check",
+			func(_ context.Context, request *connect.Request[healthv1.HealthCheckRequest]) (*connect.Response[healthv1.HealthCheckResponse], error) {
+				if request.Msg.GetService() == "unknown" {
+					return nil, connect.NewError(connect.CodeNotFound, errors.New("unknown service"))
+				}
+				return connect.NewResponse(
+					&healthv1.HealthCheckResponse{
+						Status: healthv1.HealthCheckResponse_SERVING,
+					},
+				), nil
+			},
+		),
+	)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	res, err := NewHealthCheckResolver()
+	require.NoError(t, err)
+	descriptor, err := res.FindDescriptorByName("grpc.health.v1.Health.Check")
+	require.NoError(t, err)
+	var output bytes.Buffer
+	inv := &invoker{
+		md:        descriptor.(protoreflect.MethodDescriptor),
+		res:       res,
+		formatter: jsonResponseFormatter{},
+		output:    &output,
+		errOutput: &output,
+		printer:   verbose.NopPrinter,
+		client: connect.NewClient[dynamicpb.Message, deferredMessage](
+			server.Client(),
+			server.URL+"/grpc.health.v1.Health/Check",
+			connect.WithCodec(protoCodec{}),
+		),
+	}
+	summary, err := inv.InvokeBatch(
+		context.Background(),
+		"requests.jsonl",
+		strings.NewReader(`{"service": "acme.v1.FooService"}
+{"service":
+{"service": "unknown"}
+
+{"unknown": true}
+{} {}
+{"service": "acme.v1.BarService"}
+`),
+		http.Header{},
+		2,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Requests)
+	assert.Equal(t, map[connect.Code]int{connect.CodeNotFound: 1}, summary.Errors)
+	assert.Equal(t, 3, summary.InvalidRequests)
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, `{"line":1,"response":{"status":"SERVING"}}`, lines[0])
+	assert.Contains(t, lines[1], `{"line":2,"error":{"code":"invalid_argument","message":"requests.jsonl:2 at offset`)
+	assert.Equal(t, `{"line":3,"error":{"code":"not_found","message":"unknown service"}}`, lines[2])
+	assert.Contains(t, lines[3], `{"line":5,"error":{"code":"invalid_argument","message":`)
+	assert.Equal(t, `{"line":6,"error":{"code":"invalid_argument","message":"requests.jsonl:6 contains more than one request message"}}`, lines[4])
+	assert.Equal(t, `{"line":7,"response":{"status":"SERVING"}}`, lines[5])
+}
+
+func TestBatchSummaryErr(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name        string
+		summary     *BatchSummary
+		expectedErr string
+	}{
+		{
+			name: "success",
+			summary: &BatchSummary{
+				Requests: 2,
+			},
+		},
+		{
+			name: "failed",
+			summary: &BatchSummary{
+				Requests: 3,
+				Errors:   map[connect.Code]int{connect.CodeNotFound: 1, connect.CodeInternal: 1},
+			},
+			expectedErr: "2 of 3 RPCs failed",
+		},
+		{
+			name: "single failed",
+			summary: &BatchSummary{
+				Requests: 1,
+				Errors:   map[connect.Code]int{connect.CodeNotFound: 1},
+			},
+			expectedErr: "1 of 1 RPC failed",
+		},
+		{
+			name: "single invalid",
+			summary: &BatchSummary{
+				Requests:        2,
+				InvalidRequests: 1,
+			},
+			expectedErr: "1 request was invalid",
+		},
+		{
+			name: "invalid",
+			summary: &BatchSummary{
+				InvalidRequests: 2,
+			},
+			expectedErr: "2 requests were invalid",
+		},
+		{
+			name: "mixed",
+			summary: &BatchSummary{
+				Requests:        3,
+				Errors:          map[connect.Code]int{connect.CodeNotFound: 1},
+				InvalidRequests: 3,
+			},
+			expectedErr: "1 of 3 RPCs failed and 3 requests were invalid",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			err := testCase.summary.Err()
+			if testCase.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, testCase.expectedErr)
+		})
+	}
+}
@@ -16,21 +16,234 @@ package bufcurl
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
+	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/pkg/protoencoding"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
+// MaxRetryBackoff is the maximum delay between retries of a failed RPC.
+const MaxRetryBackoff = 30 * time.Second
+
 // Invoker provides the ability to invoke RPCs dynamically.
 type Invoker interface {
 	// Invoke invokes an RPC method using the given input data and request headers.
 	// The dataSource is a string that describes the input data (e.g. a filename).
 	// The actual contents of the request data is read from the given reader.
 	Invoke(ctx context.Context, dataSource string, data io.Reader, headers http.Header) error
+	// InvokeInteractive invokes a client or bidi streaming RPC method using the
+	// given request headers, reading request messages from the given input as
+	// they are entered, one JSON message per line. Each message is sent as soon
+	// as it is read, and responses are written as they arrive.
+	//
+	// The input can also contain commands on their own line: "/close" closes the
+	// request stream, as does the end of the input, and "/cancel" cancels the RPC.
+	InvokeInteractive(ctx context.Context, input io.Reader, headers http.Header) error
+	// LoadTest invokes an RPC method repeatedly using the given input data and
+	// request headers, as configured by the given LoadTestConfig. The data is read
+	// once, and the same request data is used for every invocation. Responses are
+	// discarded.
+	//
+	// RPC errors are counted in the returned summary. Other errors, such as
+	// invalid request data, stop the load test and are returned.
+	LoadTest(ctx context.Context, dataSource string, data io.Reader, headers http.Header, config *LoadTestConfig) (*LoadTestSummary, error)
+	// Ping invokes an RPC method the given number of times, one after another,
+	// using the given input data and request headers, and records the time taken
+	// by each phase of every invocation. The data is read once, and the same
+	// request data is used for every invocation. Responses are discarded.
+	//
+	// RPC errors are recorded in the returned summary. Other errors, such as
+	// invalid request data, stop the invocations and are returned.
+	Ping(ctx context.Context, dataSource string, data io.Reader, headers http.Header, count int) (*PingSummary, error)
+	// InvokeBatch invokes a unary RPC method once for every request message in
+	// the given input data, which contains one request message in JSON format on
+	// each line, using the given number of concurrent workers. For every request,
+	// a JSON object with the line number of the request, and either the response
+	// message in a "response" field or the error in an "error" field, is written
+	// to the output on its own line. Results are written in the order of the
+	// requests. Blank lines in the input are skipped.
+	//
+	// RPC errors are counted in the returned summary. Other errors, such as
+	// invalid request data, stop the batch and are returned.
+	InvokeBatch(ctx context.Context, dataSource string, data io.Reader, headers http.Header, concurrency int) (*BatchSummary, error)
+}
+
+// RetryPolicy configures how failed unary RPCs are retried.
+type RetryPolicy struct {
+	// The maximum number of times to retry a failed RPC.
+	MaxRetries int
+	// The delay before the first retry. The delay doubles for every
+	// subsequent retry, up to a maximum of MaxRetryBackoff.
+	Backoff time.Duration
+	// The codes of the errors for which a failed RPC is retried.
+	Codes []connect.Code
+}
+
+// Timeouts configures the time limits of RPCs. The time limit of an attempt
+// is sent to the server as the timeout of the RPC, such as in the
+// "Connect-Timeout-Ms" or "Grpc-Timeout" header.
+type Timeouts struct {
+	// The time limit for each attempt of an RPC, including each retry of a
+	// unary RPC. If zero, there is no limit.
+	Attempt time.Duration
+	// The time limit for an RPC, including all of its attempts and the delays
+	// between them. If zero, there is no limit.
+	Deadline time.Duration
+}
+
+// LoadTestConfig configures a load test.
+type LoadTestConfig struct {
+	// The number of concurrent workers invoking the RPC.
+	Concurrency int
+	// The maximum number of RPCs to invoke per second across all workers.
+	// If zero, RPCs are invoked as fast as possible.
+	Rate float64
+	// How long to invoke RPCs for. RPCs that are in progress when the
+	// duration elapses are allowed to complete.
+	Duration time.Duration
+}
+
+// LoadTestSummary summarizes the results of a load test.
+type LoadTestSummary struct {
+	// The total number of RPCs invoked.
+	Requests int
+	// The number of failed RPCs by error code.
+	Errors map[connect.Code]int
+	// The time taken by the load test.
+	Elapsed time.Duration
+	// The latencies of all RPCs, sorted in ascending order.
+	Latencies []time.Duration
+}
+
+// ErrorCount returns the total number of failed RPCs.
+func (s *LoadTestSummary) ErrorCount() int {
+	var errorCount int
+	for _, count := range s.Errors {
+		errorCount += count
+	}
+	return errorCount
+}
+
+// Percentile returns the latency at the given percentile, between 0 and 100.
+//
+// This returns 0 if no RPCs were invoked.
+func (s *LoadTestSummary) Percentile(percentile float64) time.Duration {
+	return latencyPercentile(s.Latencies, percentile)
+}
+
+// WriteLoadTestSummary writes a human-readable version of the summary to the writer.
+func WriteLoadTestSummary(writer io.Writer, summary *LoadTestSummary) error {
+	return writeLoadTestSummary(writer, summary)
+}
+
+// PingResult is the time taken by each phase of an RPC invoked by a ping.
+// Phases that did not occur, such as connecting when an existing connection
+// was reused, take zero time.
+type PingResult struct {
+	// The time taken to resolve the address of the server.
+	DNS time.Duration
+	// The time taken to establish a connection to the server.
+	Connect time.Duration
+	// The time taken by the TLS handshake.
+	TLSHandshake time.Duration
+	// The time from the start of the RPC until the first byte of the response
+	// was received.
+	TimeToFirstByte time.Duration
+	// The time taken by the entire RPC.
+	Total time.Duration
+	// True if the RPC used an existing connection.
+	ReusedConnection bool
+	// The error of the RPC, or nil if it succeeded.
+	Error *connect.Error
+}
+
+// PingSummary summarizes the results of a ping.
+type PingSummary struct {
+	// The results of all RPCs, in the order they were invoked.
+	Results []PingResult
+}
+
+// WritePingSummary writes a human-readable version of the summary to the writer.
+func WritePingSummary(writer io.Writer, summary *PingSummary) error {
+	return writePingSummary(writer, summary)
+}
+
+// BatchSummary summarizes the results of a batch.
+type BatchSummary struct {
+	// The total number of RPCs invoked.
+	Requests int
+	// The number of failed RPCs by error code.
+	Errors map[connect.Code]int
+	// The number of requests that are invalid, such as malformed JSON, for
+	// which the RPC was not invoked.
+	InvalidRequests int
+}
+
+// ErrorCount returns the total number of failed RPCs.
+func (s *BatchSummary) ErrorCount() int {
+	var errorCount int
+	for _, count := range s.Errors {
+		errorCount += count
+	}
+	return errorCount
+}
+
+// Err returns an error that describes the failed RPCs and the invalid requests
+// of the batch, or nil if there are none.
+func (s *BatchSummary) Err() error {
+	var problems []string
+	if errorCount := s.ErrorCount(); errorCount > 0 {
+		rpcs := "RPCs"
+		if s.Requests == 1 {
+			rpcs = "RPC"
+		}
+		problems = append(problems, fmt.Sprintf("%d of %d %s failed", errorCount, s.Requests, rpcs))
+	}
+	switch s.InvalidRequests {
+	case 0:
+	case 1:
+		problems = append(problems, "1 request was invalid")
+	default:
+		problems = append(problems, fmt.Sprintf("%d requests were invalid", s.InvalidRequests))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, " and "))
+}
+
+// ResponseFormatter writes response messages to an output.
+type ResponseFormatter interface {
+	// Format writes the response message, given in its JSON format, to the writer.
+	Format(writer io.Writer, data []byte) error
+}
+
+// NewTemplateResponseFormatter returns a new ResponseFormatter that executes the
+// given Go template for every response message.
+//
+// The template is executed over the response message decoded from its JSON
+// format, so fields are referred to by their JSON names, such as {{.userId}}.
+// A newline is written after the output of every execution.
+func NewTemplateResponseFormatter(text string) (ResponseFormatter, error) {
+	return newTemplateResponseFormatter(text)
+}
+
+// NewPathResponseFormatter returns a new ResponseFormatter that writes only the
+// value at the given path of every response message.
+//
+// The path is a jq-style expression of JSON field names and array indexes, such
+// as ".user.addresses[0].city", where "." refers to the whole message. Strings
+// are written without quotes, and other values are written in JSON format.
+// A path that refers to an absent field results in null.
+func NewPathResponseFormatter(path string) (ResponseFormatter, error) {
+	return newPathResponseFormatter(path)
 }
 
 // ResolveMethodDescriptor uses the given resolver to find a descriptor for
@@ -0,0 +1,88 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	// Register the standard error detail types, such as google.rpc.ErrorInfo,
+	// so that they can be decoded even if they are not in the schema.
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// errorJSON is the JSON format of an RPC error. This is the same as the format
+// of errors in the Connect protocol, except that details are decoded when their
+// types can be resolved.
+type errorJSON struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message,omitempty"`
+	Details []errorDetailJSON `json:"details,omitempty"`
+}
+
+type errorDetailJSON struct {
+	Type string `json:"type"`
+	// The base64-encoded detail message, if its type could not be resolved.
+	Value string `json:"value,omitempty"`
+	// The JSON format of the detail message, if its type could be resolved.
+	Debug json.RawMessage `json:"debug,omitempty"`
+}
+
+// marshalError returns the JSON format of the error, using the resolver to
+// decode its details.
+func marshalError(connErr *connect.Error, res protoencoding.Resolver) ([]byte, error) {
+	errJSON := errorJSON{
+		Code:    connErr.Code().String(),
+		Message: connErr.Message(),
+	}
+	for _, detail := range connErr.Details() {
+		detailJSON := errorDetailJSON{
+			Type: detail.Type(),
+		}
+		if debug, ok := decodeErrorDetail(detail, res); ok {
+			detailJSON.Debug = debug
+		} else {
+			detailJSON.Value = base64.RawStdEncoding.EncodeToString(detail.Bytes())
+		}
+		errJSON.Details = append(errJSON.Details, detailJSON)
+	}
+	return json.Marshal(errJSON)
+}
+
+// decodeErrorDetail returns the JSON format of the error detail, or false if
+// its type cannot be resolved or it cannot be decoded.
+func decodeErrorDetail(detail *connect.ErrorDetail, res protoencoding.Resolver) (json.RawMessage, bool) {
+	messageType, err := res.FindMessageByName(protoreflect.FullName(detail.Type()))
+	if err != nil {
+		messageType, err = protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(detail.Type()))
+		if err != nil {
+			return nil, false
+		}
+	}
+	msg := dynamicpb.NewMessage(messageType.Descriptor())
+	if err := protoencoding.NewWireUnmarshaler(res).Unmarshal(detail.Bytes(), msg); err != nil {
+		return nil, false
+	}
+	data, err := protoencoding.NewJSONMarshaler(res).Marshal(msg)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,205 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"connectrpc.com/connect"
+)
+
+// ExpectedCodeOK is the expected code of an RPC that succeeds.
+const ExpectedCodeOK = "ok"
+
+// ResponseExpectations are assertions about the outcome of an RPC, which are
+// checked against a Session that records the RPC.
+type ResponseExpectations struct {
+	code         string
+	headers      []expectedHeader
+	bodyContains []string
+	paths        []expectedPath
+}
+
+type expectedHeader struct {
+	name string
+	// The value of the header, if hasValue is true. Otherwise, the header
+	// must only be present.
+	value    string
+	hasValue bool
+}
+
+type expectedPath struct {
+	path     string
+	elements []pathElement
+	// The value at the path, if hasValue is true. Otherwise, the value must
+	// only be present.
+	value    any
+	hasValue bool
+}
+
+// NewResponseExpectations returns new ResponseExpectations.
+//
+// The code is the expected code of the RPC, such as "not_found", or "ok" if
+// the RPC is expected to succeed. If empty, the code is not checked.
+//
+// Headers are in the form "Name: value", for a response header or trailer that
+// must have the given value, or "Name", for one that must be present.
+//
+// The body must contain each of bodyContains.
+//
+// Paths are in the form "path=value" or "path", where the path is in the same
+// format as for NewPathResponseFormatter. The value at the path of at least one
+// response message must equal the given value, which is in JSON format, or is
+// a string if it is not valid JSON. Without a value, the value at the path must
+// not be null.
+func NewResponseExpectations(code string, headers []string, bodyContains []string, paths []string) (*ResponseExpectations, error) {
+	expectations := &ResponseExpectations{
+		code:         strings.ToLower(strings.TrimSpace(code)),
+		bodyContains: bodyContains,
+	}
+	if expectations.code != "" && expectations.code != ExpectedCodeOK {
+		var connectCode connect.Code
+		if err := connectCode.UnmarshalText([]byte(expectations.code)); err != nil {
+			return nil, fmt.Errorf("%q is not a valid code", code)
+		}
+	}
+	for _, header := range headers {
+		name, value, hasValue := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("header %q should be in the form name: value or name", header)
+		}
+		expectations.headers = append(expectations.headers, expectedHeader{
+			name:     name,
+			value:    strings.TrimSpace(value),
+			hasValue: hasValue,
+		})
+	}
+	for _, path := range paths {
+		pathString, valueString, hasValue := strings.Cut(path, "=")
+		elements, err := parsePath(pathString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q: %w", pathString, err)
+		}
+		expected := expectedPath{
+			path:     pathString,
+			elements: elements,
+			hasValue: hasValue,
+		}
+		if hasValue {
+			if err := json.Unmarshal([]byte(valueString), &expected.value); err != nil {
+				expected.value = valueString
+			}
+		}
+		expectations.paths = append(expectations.paths, expected)
+	}
+	return expectations, nil
+}
+
+// Check checks the expectations against the RPC recorded in the given Session,
+// whose response messages were written as the given body. It returns a
+// description of every expectation that is not met.
+func (e *ResponseExpectations) Check(session *Session, body []byte) ([]string, error) {
+	var failures []string
+	if e.code != "" {
+		code := ExpectedCodeOK
+		if responseError, ok := session.Response.Error.(map[string]any); ok {
+			code, _ = responseError["code"].(string)
+		}
+		if code != e.code {
+			failures = append(failures, fmt.Sprintf("expected code %s, but got %s", e.code, code))
+		}
+	}
+	for _, header := range e.headers {
+		lowerName := strings.ToLower(header.name)
+		var values []string
+		values = append(values, session.Response.Headers[lowerName]...)
+		values = append(values, session.Response.Trailers[lowerName]...)
+		switch {
+		case len(values) == 0:
+			failures = append(failures, fmt.Sprintf("expected header %s, but it is absent", header.name))
+		case header.hasValue && !containsString(values, header.value):
+			failures = append(failures, fmt.Sprintf("expected header %s to be %q, but got %q", header.name, header.value, strings.Join(values, ", ")))
+		}
+	}
+	for _, substring := range e.bodyContains {
+		if !bytes.Contains(body, []byte(substring)) {
+			failures = append(failures, fmt.Sprintf("expected response body to contain %q", substring))
+		}
+	}
+	for _, path := range e.paths {
+		matched, err := path.matchesAny(session.Response.Messages)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			if path.hasValue {
+				value, err := json.Marshal(path.value)
+				if err != nil {
+					return nil, err
+				}
+				failures = append(failures, fmt.Sprintf("expected value at path %s to be %s in a response message", path.path, value))
+			} else {
+				failures = append(failures, fmt.Sprintf("expected a value at path %s in a response message", path.path))
+			}
+		}
+	}
+	return failures, nil
+}
+
+// HasCode returns true if the code of the RPC is checked.
+func (e *ResponseExpectations) HasCode() bool {
+	return e.code != ""
+}
+
+func (p expectedPath) matchesAny(messages []SessionMessage) (bool, error) {
+	for _, message := range messages {
+		value := message.Message
+		for _, element := range p.elements {
+			var err error
+			if value, err = element.apply(value); err != nil {
+				// The message does not have the structure of the path.
+				value = nil
+				break
+			}
+		}
+		if !p.hasValue {
+			if value != nil {
+				return true, nil
+			}
+			continue
+		}
+		equal, err := jsonEqual(value, p.value)
+		if err != nil {
+			return false, err
+		}
+		if equal {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
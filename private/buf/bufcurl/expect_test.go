@@ -0,0 +1,261 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResponseExpectations(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name         string
+		code         string
+		headers      []string
+		paths        []string
+		expected     *ResponseExpectations
+		expectedErr  string
+		expectedCode bool
+	}{
+		{
+			name:     "empty",
+			expected: &ResponseExpectations{},
+		},
+		{
+			name:         "ok code",
+			code:         " OK ",
+			expected:     &ResponseExpectations{code: "ok"},
+			expectedCode: true,
+		},
+		{
+			name:         "error code",
+			code:         "Not_Found",
+			expected:     &ResponseExpectations{code: "not_found"},
+			expectedCode: true,
+		},
+		{
+			name:        "invalid code",
+			code:        "missing",
+			expectedErr: `"missing" is not a valid code`,
+		},
+		{
+			name:    "headers",
+			headers: []string{"X-Request-Id: 123", "Grpc-Status-Details-Bin", "X-Empty:"},
+			expected: &ResponseExpectations{
+				headers: []expectedHeader{
+					{name: "X-Request-Id", value: "123", hasValue: true},
+					{name: "Grpc-Status-Details-Bin"},
+					{name: "X-Empty", hasValue: true},
+				},
+			},
+		},
+		{
+			name:        "header without name",
+			headers:     []string{": 123"},
+			expectedErr: `header ": 123" should be in the form name: value or name`,
+		},
+		{
+			name:  "paths",
+			paths: []string{".status=SERVING", ".count=2", `.items[0]={"a":true}`, ".name"},
+			expected: &ResponseExpectations{
+				paths: []expectedPath{
+					{path: ".status", elements: []pathElement{{name: "status"}}, value: "SERVING", hasValue: true},
+					{path: ".count", elements: []pathElement{{name: "count"}}, value: 2.0, hasValue: true},
+					{path: ".items[0]", elements: []pathElement{{name: "items"}, {index: 0, isIndex: true}}, value: map[string]any{"a": true}, hasValue: true},
+					{path: ".name", elements: []pathElement{{name: "name"}}},
+				},
+			},
+		},
+		{
+			name:        "invalid path",
+			paths:       []string{"status=SERVING"},
+			expectedErr: `invalid path "status"`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			expectations, err := NewResponseExpectations(testCase.code, testCase.headers, nil, testCase.paths)
+			if testCase.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, expectations)
+			assert.Equal(t, testCase.expectedCode, expectations.HasCode())
+		})
+	}
+}
+
+func TestResponseExpectationsCheck(t *testing.T) {
+	t.Parallel()
+	okSession := &Session{
+		Response: SessionResponse{
+			Headers: map[string][]string{
+				"x-request-id": {"123", "456"},
+			},
+			Messages: []SessionMessage{
+				{Message: map[string]any{"status": "NOT_SERVING"}},
+				{Message: map[string]any{"status": "SERVING", "count": 2.0, "items": []any{map[string]any{"a": true}}}},
+			},
+			Trailers: map[string][]string{
+				"x-trailer": {"done"},
+			},
+		},
+	}
+	errorSession := &Session{
+		Response: SessionResponse{
+			Error: map[string]any{"code": "not_found", "message": "unknown service"},
+		},
+	}
+	body := []byte(`{"status": "NOT_SERVING"}
+{"status": "SERVING"}`)
+	testCases := []struct {
+		name             string
+		code             string
+		headers          []string
+		bodyContains     []string
+		paths            []string
+		session          *Session
+		expectedFailures []string
+	}{
+		{
+			name:    "all met",
+			code:    "ok",
+			headers: []string{"X-Request-Id: 456", "x-trailer: done", "X-Request-Id"},
+			bodyContains: []string{
+				`"SERVING"`,
+			},
+			paths:   []string{".status=SERVING", ".count=2", `.items[0]={"a": true}`, ".items[-1].a=true", ".count"},
+			session: okSession,
+		},
+		{
+			name:    "error code met",
+			code:    "not_found",
+			session: errorSession,
+		},
+		{
+			name:             "error code not met",
+			code:             "ok",
+			session:          errorSession,
+			expectedFailures: []string{"expected code ok, but got not_found"},
+		},
+		{
+			name:             "ok code not met",
+			code:             "internal",
+			session:          okSession,
+			expectedFailures: []string{"expected code internal, but got ok"},
+		},
+		{
+			name:    "headers not met",
+			headers: []string{"X-Request-Id: 789", "X-Missing"},
+			session: okSession,
+			expectedFailures: []string{
+				`expected header X-Request-Id to be "789", but got "123, 456"`,
+				"expected header X-Missing, but it is absent",
+			},
+		},
+		{
+			name:             "body not met",
+			bodyContains:     []string{"UNKNOWN"},
+			session:          okSession,
+			expectedFailures: []string{`expected response body to contain "UNKNOWN"`},
+		},
+		{
+			name:    "paths not met",
+			paths:   []string{".status=UNKNOWN", `.count="2"`, ".missing", ".status.name", ".items[1]"},
+			session: okSession,
+			expectedFailures: []string{
+				`expected value at path .status to be "UNKNOWN" in a response message`,
+				`expected value at path .count to be "2" in a response message`,
+				"expected a value at path .missing in a response message",
+				"expected a value at path .status.name in a response message",
+				"expected a value at path .items[1] in a response message",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			expectations, err := NewResponseExpectations(testCase.code, testCase.headers, testCase.bodyContains, testCase.paths)
+			require.NoError(t, err)
+			failures, err := expectations.Check(testCase.session, body)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedFailures, failures)
+		})
+	}
+}
+
+func TestJSONEqual(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		value    any
+		other    any
+		expected bool
+	}{
+		{
+			name:     "nil",
+			expected: true,
+		},
+		{
+			name:  "nil and null string",
+			value: nil,
+			other: "null",
+		},
+		{
+			name:     "numbers",
+			value:    2.0,
+			other:    2,
+			expected: true,
+		},
+		{
+			name:  "number and string",
+			value: 2.0,
+			other: "2",
+		},
+		{
+			name:     "objects with different key order",
+			value:    map[string]any{"a": 1.0, "b": []any{"x", true}},
+			other:    map[string]any{"b": []any{"x", true}, "a": 1.0},
+			expected: true,
+		},
+		{
+			name:  "arrays with different order",
+			value: []any{1.0, 2.0},
+			other: []any{2.0, 1.0},
+		},
+		{
+			name:  "empty object and empty array",
+			value: map[string]any{},
+			other: []any{},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			equal, err := jsonEqual(testCase.value, testCase.other)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, equal)
+		})
+	}
+}
@@ -104,13 +104,20 @@ func LoadHeaders(headerFlags []string, dataFile string, others http.Header) (htt
 	// make sure there are no disallowed headers used
 	for key := range headers {
 		lowerKey := strings.ToLower(key)
-		if _, ok := headerBlockList[lowerKey]; ok || strings.HasPrefix(lowerKey, "grpc-") || strings.HasPrefix(lowerKey, "connect-") {
+		if isReservedHeader(lowerKey) {
 			return nil, nil, fmt.Errorf("invalid header: %q is reserved and may not be used", key)
 		}
 	}
 	return headers, dataReader, nil
 }
 
+// isReservedHeader returns true if the header with the given lower-case name
+// is set by the protocol and may not be provided by users.
+func isReservedHeader(lowerKey string) bool {
+	_, ok := headerBlockList[lowerKey]
+	return ok || strings.HasPrefix(lowerKey, "grpc-") || strings.HasPrefix(lowerKey, "connect-")
+}
+
 func readHeadersFile(headerFile string, stopAtBlankLine bool, headers http.Header) (reader io.ReadCloser, err error) {
 	var f *os.File
 	if headerFile == "-" {
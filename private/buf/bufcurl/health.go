@@ -0,0 +1,96 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	healthv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/health/v1"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/reflect/protodesc"
+)
+
+const (
+	// HealthServingStatusServing is the serving status of a healthy service.
+	HealthServingStatusServing = "SERVING"
+
+	healthService     = "grpc.health.v1.Health"
+	healthCheckMethod = "Check"
+	healthWatchMethod = "Watch"
+)
+
+// HealthCheckResponseFormatter is a ResponseFormatter for the responses of the
+// gRPC health checking service, that also records the serving status of the
+// last response.
+type HealthCheckResponseFormatter interface {
+	ResponseFormatter
+	// ServingStatus returns the serving status of the last response, such as
+	// "SERVING", or the empty string if there were no responses.
+	ServingStatus() string
+}
+
+// NewHealthCheckResponseFormatter returns a new HealthCheckResponseFormatter
+// that writes responses using the given formatter. If the formatter is nil,
+// responses are written in JSON format.
+//
+// The responses must be written with default values, so that the UNKNOWN
+// serving status is recorded.
+func NewHealthCheckResponseFormatter(formatter ResponseFormatter) HealthCheckResponseFormatter {
+	if formatter == nil {
+		formatter = jsonResponseFormatter{}
+	}
+	return &healthCheckResponseFormatter{
+		formatter: formatter,
+	}
+}
+
+// HealthCheckURL returns the URL of the method of the gRPC health checking
+// service on the server with the given base URL. This is the Watch method if
+// watch is true, and the Check method otherwise.
+func HealthCheckURL(baseURL string, watch bool) string {
+	method := healthCheckMethod
+	if watch {
+		method = healthWatchMethod
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + healthService + "/" + method
+}
+
+// NewHealthCheckResolver returns a new resolver for the schema of the gRPC
+// health checking service.
+func NewHealthCheckResolver() (protoencoding.Resolver, error) {
+	return protoencoding.NewResolver(protodesc.ToFileDescriptorProto(healthv1.File_grpc_health_v1_health_proto))
+}
+
+type healthCheckResponseFormatter struct {
+	formatter     ResponseFormatter
+	servingStatus string
+}
+
+func (h *healthCheckResponseFormatter) Format(writer io.Writer, data []byte) error {
+	var response struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return err
+	}
+	h.servingStatus = response.Status
+	return h.formatter.Format(writer, data)
+}
+
+func (h *healthCheckResponseFormatter) ServingStatus() string {
+	return h.servingStatus
+}
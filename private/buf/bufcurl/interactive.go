@@ -0,0 +1,77 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	interactiveCloseCommand  = "/close"
+	interactiveCancelCommand = "/cancel"
+)
+
+// errInteractiveCancel is returned by the interactive message provider when
+// the RPC is cancelled.
+var errInteractiveCancel = errors.New("RPC cancelled")
+
+type interactiveMessageProvider struct {
+	reader    *lineReader
+	res       protoencoding.Resolver
+	errOutput io.Writer
+	line      int
+}
+
+func newInteractiveMessageProvider(input io.Reader, res protoencoding.Resolver, errOutput io.Writer) *interactiveMessageProvider {
+	return &interactiveMessageProvider{
+		reader:    &lineReader{r: bufio.NewReader(input)},
+		res:       res,
+		errOutput: errOutput,
+	}
+}
+
+func (p *interactiveMessageProvider) next(msg proto.Message) error {
+	for {
+		line, err := p.reader.ReadLine()
+		if err != nil {
+			return err
+		}
+		p.line++
+		line = strings.TrimSpace(line)
+		switch line {
+		case "":
+			continue
+		case interactiveCloseCommand:
+			return io.EOF
+		case interactiveCancelCommand:
+			return errInteractiveCancel
+		}
+		if err := protoencoding.NewJSONUnmarshaler(
+			p.res, protoencoding.JSONUnmarshalerWithDisallowUnknown(),
+		).Unmarshal([]byte(line), msg); err != nil {
+			// A typo should not end the RPC, so the line is skipped.
+			_, _ = fmt.Fprintf(p.errOutput, "line %d: invalid request message, not sent: %v\n", p.line, err)
+			continue
+		}
+		return nil
+	}
+}
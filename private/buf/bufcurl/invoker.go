@@ -22,8 +22,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/http/httptest"
 	"sync"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/pkg/app"
@@ -53,6 +53,16 @@ func (p protoCodec) Marshal(a any) ([]byte, error) {
 	return protoencoding.NewWireMarshaler().Marshal(protoMessage)
 }
 
+// MarshalStable is used to encode the request message in the query string of
+// Connect GET requests. The wire marshaler is already deterministic.
+func (p protoCodec) MarshalStable(a any) ([]byte, error) {
+	return p.Marshal(a)
+}
+
+func (p protoCodec) IsBinary() bool {
+	return true
+}
+
 func (p protoCodec) Unmarshal(bytes []byte, a any) error {
 	if deferred, ok := a.(*deferredMessage); ok {
 		// must make a copy since Connect framework will re-use the byte slice
@@ -74,18 +84,30 @@ type invoker struct {
 	res          protoencoding.Resolver
 	emitDefaults bool
 	client       *invokeClient
+	retryPolicy  *RetryPolicy
+	timeouts     *Timeouts
+	formatter    ResponseFormatter
 	output       io.Writer
 	errOutput    io.Writer
 	printer      verbose.Printer
+	// If true, RPC errors are returned as is instead of being
+	// written to errOutput.
+	rawErrors bool
 }
 
 // NewInvoker creates a new invoker for invoking the method described by the
 // given descriptor. The given writer is used to write the output response(s)
 // in JSON format. The given resolver is used to resolve Any messages and
 // extensions that appear in the input or output. Other parameters are used
-// to create a Connect client, for issuing the RPC.
-func NewInvoker(container appflag.Container, md protoreflect.MethodDescriptor, res protoencoding.Resolver, emitDefaults bool, httpClient connect.HTTPClient, opts []connect.ClientOption, url string, out io.Writer) Invoker {
+// to create a Connect client, for issuing the RPC. If the retry policy is not
+// nil, failed unary RPCs are retried according to the policy. If the timeouts
+// are not nil, RPCs are limited by them. If the formatter is not nil, it is
+// used to write the response(s) instead.
+func NewInvoker(container appflag.Container, md protoreflect.MethodDescriptor, res protoencoding.Resolver, emitDefaults bool, httpClient connect.HTTPClient, opts []connect.ClientOption, retryPolicy *RetryPolicy, timeouts *Timeouts, formatter ResponseFormatter, url string, out io.Writer) Invoker {
 	opts = append(opts, connect.WithCodec(protoCodec{}))
+	if formatter == nil {
+		formatter = jsonResponseFormatter{}
+	}
 	// TODO: could also provide custom compressor implementations that could give us
 	//  optics into when request and response messages are compressed (which could be
 	//  useful to include in verbose output).
@@ -93,6 +115,9 @@ func NewInvoker(container appflag.Container, md protoreflect.MethodDescriptor, r
 		md:           md,
 		res:          res,
 		emitDefaults: emitDefaults,
+		retryPolicy:  retryPolicy,
+		timeouts:     timeouts,
+		formatter:    formatter,
 		output:       out,
 		printer:      container.VerbosePrinter(),
 		errOutput:    container.Stderr(),
@@ -105,18 +130,58 @@ func (inv *invoker) Invoke(ctx context.Context, dataSource string, data io.Reade
 	// request's user-agent header(s) get overwritten by protocol, so we stash them in the
 	// context so that underlying transport can restore them
 	ctx = withUserAgent(ctx, headers)
+	if inv.retryPolicy != nil && (inv.md.IsStreamingClient() || inv.md.IsStreamingServer()) {
+		return fmt.Errorf("method %s is a streaming RPC, but only unary RPCs can be retried", inv.md.Name())
+	}
+	ctx, cancel := inv.withDeadline(ctx)
+	defer cancel()
+	if inv.md.IsStreamingClient() || inv.md.IsStreamingServer() {
+		// Streaming RPCs are never retried, so they only have one attempt.
+		var cancelAttempt context.CancelFunc
+		ctx, cancelAttempt = inv.withAttemptTimeout(ctx)
+		defer cancelAttempt()
+	}
 	switch {
 	case inv.md.IsStreamingServer() && inv.md.IsStreamingClient():
-		return inv.handleBidiStream(ctx, dataSource, data, headers)
+		return inv.handleBidiStream(ctx, newStreamMessageProvider(dataSource, data, inv.res), headers)
 	case inv.md.IsStreamingServer():
 		return inv.handleServerStream(ctx, dataSource, data, headers)
 	case inv.md.IsStreamingClient():
-		return inv.handleClientStream(ctx, dataSource, data, headers)
+		return inv.handleClientStream(ctx, newStreamMessageProvider(dataSource, data, inv.res), headers)
 	default:
 		return inv.handleUnary(ctx, dataSource, data, headers)
 	}
 }
 
+func (inv *invoker) InvokeInteractive(ctx context.Context, input io.Reader, headers http.Header) error {
+	if !inv.md.IsStreamingClient() {
+		return fmt.Errorf("method %s is not a client or bidi streaming RPC, so it cannot be invoked interactively", inv.md.Name())
+	}
+	inv.printer.Printf("* Invoking RPC %s interactively\n", inv.md.FullName())
+	ctx = withUserAgent(ctx, headers)
+	_, _ = fmt.Fprintf(
+		inv.errOutput,
+		"Enter one JSON request message per line. Enter %s or end the input to close the request stream, or %s to cancel the RPC.\n",
+		interactiveCloseCommand,
+		interactiveCancelCommand,
+	)
+	provider := newInteractiveMessageProvider(input, inv.res, inv.errOutput)
+	ctx, cancel := inv.withDeadline(ctx)
+	defer cancel()
+	ctx, cancelAttempt := inv.withAttemptTimeout(ctx)
+	defer cancelAttempt()
+	var err error
+	if inv.md.IsStreamingServer() {
+		err = inv.handleBidiStream(ctx, provider, headers)
+	} else {
+		err = inv.handleClientStream(ctx, provider, headers)
+	}
+	if errors.Is(err, errInteractiveCancel) {
+		return inv.handleErrorResponse(connect.NewError(connect.CodeCanceled, err))
+	}
+	return err
+}
+
 func (inv *invoker) handleUnary(ctx context.Context, dataSource string, data io.Reader, headers http.Header) error {
 	provider := newMessageProvider(dataSource, data, inv.res)
 	msg := dynamicpb.NewMessage(inv.md.Input())
@@ -133,7 +198,7 @@ func (inv *invoker) handleUnary(ctx context.Context, dataSource string, data io.
 	for k, v := range headers {
 		req.Header()[k] = v
 	}
-	resp, err := inv.client.CallUnary(ctx, req)
+	resp, err := inv.callUnary(ctx, req)
 	if err != nil {
 		var connErr *connect.Error
 		if !errors.As(err, &connErr) {
@@ -145,8 +210,82 @@ func (inv *invoker) handleUnary(ctx context.Context, dataSource string, data io.
 	return inv.handleResponse(resp.Msg.data, nil)
 }
 
-func (inv *invoker) handleClientStream(ctx context.Context, dataSource string, data io.Reader, headers http.Header) (retErr error) {
-	provider := newStreamMessageProvider(dataSource, data, inv.res)
+// callUnary invokes the unary RPC, retrying it according to the retry policy.
+func (inv *invoker) callUnary(ctx context.Context, req *connect.Request[dynamicpb.Message]) (*connect.Response[deferredMessage], error) {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancelAttempt := inv.withAttemptTimeout(ctx)
+		resp, err := inv.client.CallUnary(attemptCtx, req)
+		cancelAttempt()
+		// If the deadline of the RPC is exceeded, there is no time left for
+		// another attempt.
+		if err == nil || ctx.Err() != nil || !inv.shouldRetry(err, attempt) {
+			return resp, err
+		}
+		backoff := retryBackoff(inv.retryPolicy.Backoff, attempt)
+		inv.printer.Printf(
+			"* Attempt %d of %d failed with code %s; retrying in %v",
+			attempt,
+			inv.retryPolicy.MaxRetries+1,
+			connect.CodeOf(err),
+			backoff,
+		)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// withDeadline returns a context that is done when the deadline of the RPC,
+// if any, is exceeded.
+func (inv *invoker) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if inv.timeouts == nil || inv.timeouts.Deadline == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, inv.timeouts.Deadline)
+}
+
+// withAttemptTimeout returns a context that is done when the time limit of an
+// attempt of the RPC, if any, is exceeded.
+func (inv *invoker) withAttemptTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if inv.timeouts == nil || inv.timeouts.Attempt == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, inv.timeouts.Attempt)
+}
+
+func (inv *invoker) shouldRetry(err error, attempt int) bool {
+	if inv.retryPolicy == nil || attempt > inv.retryPolicy.MaxRetries {
+		return false
+	}
+	var connErr *connect.Error
+	if !errors.As(err, &connErr) {
+		return false
+	}
+	for _, code := range inv.retryPolicy.Codes {
+		if connErr.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryBackoff returns the delay before the retry following the given
+// attempt, which starts at 1.
+func retryBackoff(backoff time.Duration, attempt int) time.Duration {
+	for i := 1; i < attempt && backoff < MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxRetryBackoff {
+		return MaxRetryBackoff
+	}
+	return backoff
+}
+
+func (inv *invoker) handleClientStream(ctx context.Context, provider messageProvider, headers http.Header) (retErr error) {
 	msg := dynamicpb.NewMessage(inv.md.Input())
 	stream := inv.client.CallClientStream(ctx)
 	for k, v := range headers {
@@ -210,9 +349,8 @@ func (inv *invoker) handleServerStream(ctx context.Context, dataSource string, d
 	return inv.handleStreamResponse(&serverStreamAdapter{stream: stream})
 }
 
-func (inv *invoker) handleBidiStream(ctx context.Context, dataSource string, data io.Reader, headers http.Header) (retErr error) {
+func (inv *invoker) handleBidiStream(ctx context.Context, provider messageProvider, headers http.Header) (retErr error) {
 	ctx, cancel := context.WithCancel(ctx)
-	provider := newStreamMessageProvider(dataSource, data, inv.res)
 	msg := dynamicpb.NewMessage(inv.md.Input())
 	stream := inv.client.CallBidiStream(ctx)
 	for k, v := range headers {
@@ -252,6 +390,10 @@ func (inv *invoker) handleBidiStream(ctx context.Context, dataSource string, dat
 	defer func() {
 		if shouldCancel {
 			cancel()
+			// The transport may be blocked reading the request body, in
+			// which case it does not observe the cancellation until the
+			// request stream is closed.
+			_ = stream.CloseRequest()
 		}
 	}()
 
@@ -299,8 +441,7 @@ func (inv *invoker) handleResponse(data []byte, msg *dynamicpb.Message) error {
 	if err != nil {
 		return err
 	}
-	_, err = fmt.Fprintf(inv.output, "%s\n", outputBytes)
-	return err
+	return inv.formatter.Format(inv.output, outputBytes)
 }
 
 type clientStream interface {
@@ -367,27 +508,15 @@ func (inv *invoker) handleStreamResponse(stream serverStream) (retError error) {
 }
 
 func (inv *invoker) handleErrorResponse(connErr *connect.Error) error {
-	// NB: This is a nasty hack: we create a fake request that looks
-	//     like a unary Connect request, so that the ErrorWriter will
-	//     print the error in the format we want, which is just the
-	//     JSON representation of the Connect error. (We don't need
-	//     an end-stream message representation or for the content
-	//     to be put into response headers, which is what it may
-	//     choose to do if it detects a different protocol in the
-	//     request).
-	req := &http.Request{
-		Header: http.Header{},
-	}
-	req.Header.Add("content-type", "application/json")
-
-	w := connect.NewErrorWriter()
-	responseWriter := httptest.NewRecorder()
-	err := w.Write(responseWriter, req, connErr)
+	if inv.rawErrors {
+		return connErr
+	}
+	data, err := marshalError(connErr, inv.res)
 	if err != nil {
 		return err
 	}
 	var prettyPrinted bytes.Buffer
-	if err := json.Indent(&prettyPrinted, responseWriter.Body.Bytes(), "", "   "); err != nil {
+	if err := json.Indent(&prettyPrinted, data, "", "   "); err != nil {
 		return err
 	}
 	_, _ = inv.errOutput.Write(prettyPrinted.Bytes())
@@ -0,0 +1,133 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ReadPassword returns the password if it is not empty, or else the contents of
+// the password file without a trailing newline if its name is not empty, or
+// else the value of the environment variable.
+func ReadPassword(container app.EnvContainer, password string, passwordFile string, envKey string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	if passwordFile != "" {
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return "", ErrorHasFilename(err, passwordFile)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return container.Env(envKey), nil
+}
+
+// loadKeystore loads the client certificate, its chain, and its private key
+// from the PKCS#12 file with the given name.
+func loadKeystore(keystoreFile string, password string) (*tls.Certificate, error) {
+	data, err := os.ReadFile(keystoreFile)
+	if err != nil {
+		return nil, ErrorHasFilename(err, keystoreFile)
+	}
+	privateKey, cert, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, ErrorHasFilename(err, keystoreFile)
+	}
+	certPair := &tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  privateKey,
+		Leaf:        cert,
+	}
+	for _, caCert := range caCerts {
+		certPair.Certificate = append(certPair.Certificate, caCert.Raw)
+	}
+	return certPair, nil
+}
+
+// loadKeyPair loads the client certificate and its private key from the PEM
+// files with the given names. If the private key is encrypted, it is decrypted
+// with the given password.
+func loadKeyPair(certFile string, keyFile string, password string) (*tls.Certificate, error) {
+	cert, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, ErrorHasFilename(err, certFile)
+	}
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, ErrorHasFilename(err, keyFile)
+	}
+	key, err = decryptPEMPrivateKey(key, password)
+	if err != nil {
+		return nil, ErrorHasFilename(err, keyFile)
+	}
+	certPair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, err
+	}
+	certPair.Leaf, err = x509.ParseCertificate(certPair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	return &certPair, nil
+}
+
+// decryptPEMPrivateKey returns the given PEM data with its private key
+// decrypted, if it is an encrypted PKCS#8 key, with the "ENCRYPTED PRIVATE KEY"
+// type. Legacy keys encrypted as described in RFC 1423, with a
+// "Proc-Type: 4,ENCRYPTED" header, are insecure and not supported. If the
+// private key is not encrypted, the data is returned unchanged.
+func decryptPEMPrivateKey(data []byte, password string) ([]byte, error) {
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			// no private key, which tls.X509KeyPair will report
+			return data, nil
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+		if _, ok := block.Headers["DEK-Info"]; ok {
+			return nil, errors.New(`legacy encrypted private keys are not supported, convert the key to an encrypted PKCS#8 key with "openssl pkcs8 -topk8"`)
+		}
+		if block.Type != "ENCRYPTED PRIVATE KEY" {
+			return data, nil
+		}
+		if password == "" {
+			return nil, errors.New("private key is encrypted, but no password was provided")
+		}
+		privateKey, err := pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt private key: %w", err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+}
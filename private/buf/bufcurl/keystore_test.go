@@ -0,0 +1,125 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKeyPair(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name          string
+		keyFile       string
+		password      string
+		expectedError string
+	}{
+		{
+			name:    "unencrypted",
+			keyFile: "key.pem",
+		},
+		{
+			name:     "unencrypted_with_password",
+			keyFile:  "key.pem",
+			password: "password",
+		},
+		{
+			name:     "encrypted_aes256_sha256",
+			keyFile:  "key.encrypted.pem",
+			password: "password",
+		},
+		{
+			name:     "encrypted_aes128_sha1",
+			keyFile:  "key.encrypted.sha1.pem",
+			password: "password",
+		},
+		{
+			name:          "encrypted_incorrect_password",
+			keyFile:       "key.encrypted.pem",
+			password:      "incorrect",
+			expectedError: "could not decrypt private key",
+		},
+		{
+			name:          "encrypted_no_password",
+			keyFile:       "key.encrypted.pem",
+			expectedError: "private key is encrypted, but no password was provided",
+		},
+		{
+			name:          "legacy",
+			keyFile:       "key.legacy.pem",
+			password:      "password",
+			expectedError: "legacy encrypted private keys are not supported",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			certPair, err := loadKeyPair(
+				filepath.Join("testdata", "keystore", "cert.pem"),
+				filepath.Join("testdata", "keystore", testCase.keyFile),
+				testCase.password,
+			)
+			if testCase.expectedError != "" {
+				assert.ErrorContains(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "client.example.com", certPair.Leaf.Subject.CommonName)
+			assert.NotNil(t, certPair.PrivateKey)
+		})
+	}
+}
+
+func TestLoadKeystore(t *testing.T) {
+	t.Parallel()
+	keystoreFile := filepath.Join("testdata", "keystore", "keystore.p12")
+	certPair, err := loadKeystore(keystoreFile, "password")
+	require.NoError(t, err)
+	assert.Equal(t, "client.example.com", certPair.Leaf.Subject.CommonName)
+	assert.Len(t, certPair.Certificate, 1)
+	assert.NotNil(t, certPair.PrivateKey)
+	_, err = loadKeystore(keystoreFile, "incorrect")
+	assert.ErrorContains(t, err, keystoreFile)
+	_, err = loadKeystore(filepath.Join("testdata", "keystore", "missing.p12"), "password")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadPassword(t *testing.T) {
+	t.Parallel()
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("from-file\n"), 0600))
+	container := app.NewEnvContainer(map[string]string{"PASSWORD": "from-env"})
+	password, err := ReadPassword(container, "from-flag", "", "PASSWORD")
+	require.NoError(t, err)
+	assert.Equal(t, "from-flag", password)
+	password, err = ReadPassword(container, "", passwordFile, "PASSWORD")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", password)
+	password, err = ReadPassword(container, "", "", "PASSWORD")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", password)
+	password, err = ReadPassword(container, "", "", "OTHER_PASSWORD")
+	require.NoError(t, err)
+	assert.Equal(t, "", password)
+	_, err = ReadPassword(container, "", filepath.Join(t.TempDir(), "missing"), "PASSWORD")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
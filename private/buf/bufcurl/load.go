@@ -0,0 +1,213 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+)
+
+type loadTestWorkerResult struct {
+	latencies []time.Duration
+	errors    map[connect.Code]int
+}
+
+func (inv *invoker) LoadTest(
+	ctx context.Context,
+	dataSource string,
+	data io.Reader,
+	headers http.Header,
+	config *LoadTestConfig,
+) (*LoadTestSummary, error) {
+	var requestData []byte
+	if data != nil {
+		var err error
+		requestData, err = io.ReadAll(data)
+		if err != nil {
+			return nil, ErrorHasFilename(err, dataSource)
+		}
+	}
+	inv.printer.Printf(
+		"* Load testing RPC %s for %v with %d concurrent workers",
+		inv.md.FullName(),
+		config.Duration,
+		config.Concurrency,
+	)
+	// Each invocation uses a copy of the invoker that discards responses and
+	// returns RPC errors so that they can be counted.
+	loadInvoker := *inv
+	loadInvoker.formatter = jsonResponseFormatter{}
+	loadInvoker.output = io.Discard
+	loadInvoker.errOutput = io.Discard
+	loadInvoker.printer = verbose.NopPrinter
+	loadInvoker.rawErrors = true
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// RPCs that are in progress when stop is closed are allowed to complete,
+	// so ctx is not cancelled when the duration elapses.
+	stop := make(chan struct{})
+	stopTimer := time.AfterFunc(config.Duration, func() { close(stop) })
+	defer stopTimer.Stop()
+	var tokens <-chan time.Time
+	if config.Rate > 0 {
+		interval := time.Duration(float64(time.Second) / config.Rate)
+		if interval <= 0 {
+			interval = 1
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+
+	results := make([]loadTestWorkerResult, config.Concurrency)
+	var fatalErr error
+	var fatalErrOnce sync.Once
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range results {
+		wg.Add(1)
+		go func(result *loadTestWorkerResult) {
+			defer wg.Done()
+			result.errors = make(map[connect.Code]int)
+			for {
+				if tokens != nil {
+					select {
+					case <-tokens:
+					case <-stop:
+						return
+					case <-ctx.Done():
+						return
+					}
+				}
+				select {
+				case <-stop:
+					return
+				case <-ctx.Done():
+					return
+				default:
+				}
+				var requestReader io.Reader
+				if requestData != nil {
+					requestReader = bytes.NewReader(requestData)
+				}
+				callStart := time.Now()
+				err := loadInvoker.Invoke(ctx, dataSource, requestReader, headers.Clone())
+				result.latencies = append(result.latencies, time.Since(callStart))
+				if err != nil {
+					var connectErr *connect.Error
+					if !errors.As(err, &connectErr) {
+						fatalErrOnce.Do(func() {
+							fatalErr = err
+							cancel()
+						})
+						return
+					}
+					result.errors[connectErr.Code()]++
+				}
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+	if fatalErr != nil {
+		return nil, fatalErr
+	}
+	summary := &LoadTestSummary{
+		Errors:  make(map[connect.Code]int),
+		Elapsed: time.Since(start),
+	}
+	for _, result := range results {
+		summary.Latencies = append(summary.Latencies, result.latencies...)
+		for code, count := range result.errors {
+			summary.Errors[code] += count
+		}
+	}
+	summary.Requests = len(summary.Latencies)
+	sort.Slice(
+		summary.Latencies,
+		func(i int, j int) bool {
+			return summary.Latencies[i] < summary.Latencies[j]
+		},
+	)
+	inv.printer.Printf("* Load test complete: %d RPCs in %v", summary.Requests, summary.Elapsed)
+	return summary, nil
+}
+
+func writeLoadTestSummary(writer io.Writer, summary *LoadTestSummary) error {
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	var requestsPerSecond float64
+	if seconds := summary.Elapsed.Seconds(); seconds > 0 {
+		requestsPerSecond = float64(summary.Requests) / seconds
+	}
+	var errorRate float64
+	errorCount := summary.ErrorCount()
+	if summary.Requests > 0 {
+		errorRate = float64(errorCount) / float64(summary.Requests) * 100
+	}
+	fmt.Fprintf(tabWriter, "Requests:\t%d\n", summary.Requests)
+	fmt.Fprintf(tabWriter, "Duration:\t%v\n", summary.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(tabWriter, "Requests/sec:\t%.2f\n", requestsPerSecond)
+	fmt.Fprintf(tabWriter, "Errors:\t%d (%.2f%%)\n", errorCount, errorRate)
+	codes := make([]connect.Code, 0, len(summary.Errors))
+	for code := range summary.Errors {
+		codes = append(codes, code)
+	}
+	sort.Slice(
+		codes,
+		func(i int, j int) bool {
+			return codes[i] < codes[j]
+		},
+	)
+	for _, code := range codes {
+		fmt.Fprintf(tabWriter, "  %s:\t%d\n", code.String(), summary.Errors[code])
+	}
+	fmt.Fprintf(tabWriter, "Latency:\n")
+	if len(summary.Latencies) > 0 {
+		fmt.Fprintf(tabWriter, "  min:\t%v\n", summary.Latencies[0])
+		fmt.Fprintf(tabWriter, "  p50:\t%v\n", summary.Percentile(50))
+		fmt.Fprintf(tabWriter, "  p95:\t%v\n", summary.Percentile(95))
+		fmt.Fprintf(tabWriter, "  p99:\t%v\n", summary.Percentile(99))
+		fmt.Fprintf(tabWriter, "  max:\t%v\n", summary.Latencies[len(summary.Latencies)-1])
+	}
+	return tabWriter.Flush()
+}
+
+// latencyPercentile returns the latency at the percentile using the
+// nearest-rank method. The latencies must be sorted.
+func latencyPercentile(latencies []time.Duration, percentile float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(len(latencies))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(latencies) {
+		rank = len(latencies)
+	}
+	return latencies[rank-1]
+}
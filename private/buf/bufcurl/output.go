@@ -0,0 +1,213 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+type jsonResponseFormatter struct{}
+
+func (jsonResponseFormatter) Format(writer io.Writer, data []byte) error {
+	_, err := fmt.Fprintf(writer, "%s\n", data)
+	return err
+}
+
+type templateResponseFormatter struct {
+	template *template.Template
+}
+
+func newTemplateResponseFormatter(text string) (*templateResponseFormatter, error) {
+	tmpl, err := template.New("output").Funcs(
+		template.FuncMap{
+			"json": func(value any) (string, error) {
+				data, err := json.Marshal(value)
+				return string(data), err
+			},
+		},
+	).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+	return &templateResponseFormatter{template: tmpl}, nil
+}
+
+func (t *templateResponseFormatter) Format(writer io.Writer, data []byte) error {
+	value, err := decodeJSONValue(data)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	if err := t.template.Execute(&buffer, value); err != nil {
+		return err
+	}
+	buffer.WriteByte('\n')
+	_, err = writer.Write(buffer.Bytes())
+	return err
+}
+
+type pathElement struct {
+	// The name of the field, if this is not an index.
+	name    string
+	index   int
+	isIndex bool
+}
+
+type pathResponseFormatter struct {
+	path     string
+	elements []pathElement
+}
+
+func newPathResponseFormatter(path string) (*pathResponseFormatter, error) {
+	elements, err := parsePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	return &pathResponseFormatter{
+		path:     path,
+		elements: elements,
+	}, nil
+}
+
+func (p *pathResponseFormatter) Format(writer io.Writer, data []byte) error {
+	if len(p.elements) == 0 {
+		// Keep the field order of the JSON format of the message.
+		return jsonResponseFormatter{}.Format(writer, data)
+	}
+	value, err := decodeJSONValue(data)
+	if err != nil {
+		return err
+	}
+	for _, element := range p.elements {
+		value, err = element.apply(value)
+		if err != nil {
+			return fmt.Errorf("path %q: %w", p.path, err)
+		}
+	}
+	if s, ok := value.(string); ok {
+		_, err := fmt.Fprintln(writer, s)
+		return err
+	}
+	output, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "%s\n", output)
+	return err
+}
+
+func (p pathElement) apply(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if p.isIndex {
+		array, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s with %d", jsonTypeName(value), p.index)
+		}
+		index := p.index
+		if index < 0 {
+			index += len(array)
+		}
+		if index < 0 || index >= len(array) {
+			return nil, nil
+		}
+		return array[index], nil
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot get field %q of %s", p.name, jsonTypeName(value))
+	}
+	return object[p.name], nil
+}
+
+// parsePath parses a path of the form ".a.b[0].c".
+func parsePath(path string) ([]pathElement, error) {
+	if !strings.HasPrefix(path, ".") {
+		return nil, fmt.Errorf("must start with %q", ".")
+	}
+	if path == "." {
+		return nil, nil
+	}
+	var elements []pathElement
+	for rest := path; rest != ""; {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				if rest != "" && rest[0] == '[' && len(elements) == 0 {
+					// ".[0]" indexes the top-level value.
+					continue
+				}
+				return nil, fmt.Errorf("expected field name after %q", ".")
+			}
+			elements = append(elements, pathElement{name: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing %q", "]")
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid array index %q", rest[1:end])
+			}
+			elements = append(elements, pathElement{index: index, isIndex: true})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest[0])
+		}
+	}
+	return elements, nil
+}
+
+func decodeJSONValue(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep the exact representation of numbers, instead of converting them
+	// to float64.
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
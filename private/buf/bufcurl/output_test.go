@@ -0,0 +1,200 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name             string
+		path             string
+		expectedElements []pathElement
+		expectedErr      string
+	}{
+		{
+			name: "root",
+			path: ".",
+		},
+		{
+			name: "fields",
+			path: ".a.b",
+			expectedElements: []pathElement{
+				{name: "a"},
+				{name: "b"},
+			},
+		},
+		{
+			name: "indexes",
+			path: ".a[0][-1].b",
+			expectedElements: []pathElement{
+				{name: "a"},
+				{index: 0, isIndex: true},
+				{index: -1, isIndex: true},
+				{name: "b"},
+			},
+		},
+		{
+			name: "top_level_index",
+			path: ".[2]",
+			expectedElements: []pathElement{
+				{index: 2, isIndex: true},
+			},
+		},
+		{
+			name:        "empty",
+			path:        "",
+			expectedErr: `must start with "."`,
+		},
+		{
+			name:        "no_leading_dot",
+			path:        "a.b",
+			expectedErr: `must start with "."`,
+		},
+		{
+			name:        "trailing_dot",
+			path:        ".a.",
+			expectedErr: `expected field name after "."`,
+		},
+		{
+			name:        "double_dot",
+			path:        ".a..b",
+			expectedErr: `expected field name after "."`,
+		},
+		{
+			name:        "index_after_dot",
+			path:        ".a.[0]",
+			expectedErr: `expected field name after "."`,
+		},
+		{
+			name:        "missing_close_bracket",
+			path:        ".a[0",
+			expectedErr: `missing "]"`,
+		},
+		{
+			name:        "invalid_index",
+			path:        ".a[b]",
+			expectedErr: `invalid array index "b"`,
+		},
+		{
+			name:        "field_after_index",
+			path:        ".a[0]b",
+			expectedErr: `unexpected 'b'`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			elements, err := parsePath(testCase.path)
+			if testCase.expectedErr != "" {
+				assert.EqualError(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedElements, elements)
+		})
+	}
+}
+
+func TestPathResponseFormatter(t *testing.T) {
+	t.Parallel()
+	const data = `{"b":{"items":[1,"two",{"three":true}]},"a":12345678901234567890}`
+	testCases := []struct {
+		name           string
+		path           string
+		expectedOutput string
+		expectedErr    string
+	}{
+		{
+			name:           "root_keeps_field_order",
+			path:           ".",
+			expectedOutput: data + "\n",
+		},
+		{
+			name:           "string_is_not_quoted",
+			path:           ".b.items[1]",
+			expectedOutput: "two\n",
+		},
+		{
+			name:           "number_is_exact",
+			path:           ".a",
+			expectedOutput: "12345678901234567890\n",
+		},
+		{
+			name:           "negative_index",
+			path:           ".b.items[-1].three",
+			expectedOutput: "true\n",
+		},
+		{
+			name:           "object",
+			path:           ".b.items[2]",
+			expectedOutput: "{\n  \"three\": true\n}\n",
+		},
+		{
+			name:           "index_out_of_range",
+			path:           ".b.items[3]",
+			expectedOutput: "null\n",
+		},
+		{
+			name:           "missing_field",
+			path:           ".c.d[0]",
+			expectedOutput: "null\n",
+		},
+		{
+			name:        "field_of_array",
+			path:        ".b.items.three",
+			expectedErr: `path ".b.items.three": cannot get field "three" of array`,
+		},
+		{
+			name:        "index_of_object",
+			path:        ".b[0]",
+			expectedErr: `path ".b[0]": cannot index object with 0`,
+		},
+		{
+			name:        "field_of_number",
+			path:        ".a.b",
+			expectedErr: `path ".a.b": cannot get field "b" of number`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			formatter, err := newPathResponseFormatter(testCase.path)
+			require.NoError(t, err)
+			var buffer bytes.Buffer
+			err = formatter.Format(&buffer, []byte(data))
+			if testCase.expectedErr != "" {
+				assert.EqualError(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedOutput, buffer.String())
+		})
+	}
+}
+
+func TestNewPathResponseFormatterInvalid(t *testing.T) {
+	t.Parallel()
+	_, err := newPathResponseFormatter("a")
+	assert.EqualError(t, err, `invalid path "a": must start with "."`)
+}
@@ -0,0 +1,245 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/verbose"
+)
+
+func (inv *invoker) Ping(
+	ctx context.Context,
+	dataSource string,
+	data io.Reader,
+	headers http.Header,
+	count int,
+) (*PingSummary, error) {
+	var requestData []byte
+	if data != nil {
+		var err error
+		requestData, err = io.ReadAll(data)
+		if err != nil {
+			return nil, ErrorHasFilename(err, dataSource)
+		}
+	}
+	inv.printer.Printf("* Pinging RPC %s %d times", inv.md.FullName(), count)
+	// Each invocation uses a copy of the invoker that discards responses and
+	// returns RPC errors so that they can be reported.
+	pingInvoker := *inv
+	pingInvoker.formatter = jsonResponseFormatter{}
+	pingInvoker.output = io.Discard
+	pingInvoker.errOutput = io.Discard
+	pingInvoker.printer = verbose.NopPrinter
+	pingInvoker.rawErrors = true
+
+	summary := &PingSummary{
+		Results: make([]PingResult, 0, count),
+	}
+	for i := 0; i < count; i++ {
+		var requestReader io.Reader
+		if requestData != nil {
+			requestReader = bytes.NewReader(requestData)
+		}
+		timing := &pingTiming{}
+		callStart := time.Now()
+		err := pingInvoker.Invoke(httptrace.WithClientTrace(ctx, timing.clientTrace()), dataSource, requestReader, headers.Clone())
+		result := timing.result(callStart, time.Since(callStart))
+		if err != nil {
+			if !errors.As(err, &result.Error) {
+				return nil, err
+			}
+		}
+		inv.printer.Printf("* Ping %d complete in %v", i+1, result.Total)
+		summary.Results = append(summary.Results, result)
+	}
+	return summary, nil
+}
+
+// pingTiming records the times of the events of an RPC that are reported by
+// an httptrace.ClientTrace. The events may be reported from other goroutines,
+// such as those of an HTTP/2 connection.
+type pingTiming struct {
+	lock                 sync.Mutex
+	dnsStart             time.Time
+	dnsDone              time.Time
+	connectStart         time.Time
+	connectDone          time.Time
+	tlsHandshakeStart    time.Time
+	tlsHandshakeDone     time.Time
+	gotFirstResponseByte time.Time
+	reusedConnection     bool
+}
+
+func (p *pingTiming) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.lock.Lock()
+			defer p.lock.Unlock()
+			p.reusedConnection = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			p.record(&p.dnsStart, false)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.record(&p.dnsDone, true)
+		},
+		// When more than one address is tried, the time from the first attempt
+		// to the last is recorded.
+		ConnectStart: func(string, string) {
+			p.record(&p.connectStart, false)
+		},
+		ConnectDone: func(string, string, error) {
+			p.record(&p.connectDone, true)
+		},
+		TLSHandshakeStart: func() {
+			p.record(&p.tlsHandshakeStart, false)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.record(&p.tlsHandshakeDone, true)
+		},
+		GotFirstResponseByte: func() {
+			p.record(&p.gotFirstResponseByte, false)
+		},
+	}
+}
+
+// record sets the given time to now. Unless replace is true, a time that is
+// already set is not changed.
+func (p *pingTiming) record(t *time.Time, replace bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if t.IsZero() || replace {
+		*t = time.Now()
+	}
+}
+
+func (p *pingTiming) result(start time.Time, total time.Duration) PingResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return PingResult{
+		DNS:              elapsedBetween(p.dnsStart, p.dnsDone),
+		Connect:          elapsedBetween(p.connectStart, p.connectDone),
+		TLSHandshake:     elapsedBetween(p.tlsHandshakeStart, p.tlsHandshakeDone),
+		TimeToFirstByte:  elapsedBetween(start, p.gotFirstResponseByte),
+		Total:            total,
+		ReusedConnection: p.reusedConnection,
+	}
+}
+
+// elapsedBetween returns the time between start and end, or zero if either
+// is not set.
+func elapsedBetween(start time.Time, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+func writePingSummary(writer io.Writer, summary *PingSummary) error {
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tabWriter, "#\tDNS\tConnect\tTLS\tTTFB\tTotal\tResult\n")
+	for i, result := range summary.Results {
+		status := "ok"
+		if result.Error != nil {
+			status = result.Error.Code().String()
+		}
+		if result.ReusedConnection {
+			status += " (reused connection)"
+		}
+		fmt.Fprintf(
+			tabWriter,
+			"%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			i+1,
+			formatPingDuration(result.DNS),
+			formatPingDuration(result.Connect),
+			formatPingDuration(result.TLSHandshake),
+			formatPingDuration(result.TimeToFirstByte),
+			formatPingDuration(result.Total),
+			status,
+		)
+	}
+	phases := []func(PingResult) time.Duration{
+		func(result PingResult) time.Duration { return result.DNS },
+		func(result PingResult) time.Duration { return result.Connect },
+		func(result PingResult) time.Duration { return result.TLSHandshake },
+		func(result PingResult) time.Duration { return result.TimeToFirstByte },
+		func(result PingResult) time.Duration { return result.Total },
+	}
+	stats := []struct {
+		name  string
+		value func([]time.Duration) time.Duration
+	}{
+		{name: "min", value: func(durations []time.Duration) time.Duration { return durations[0] }},
+		{name: "avg", value: averageDuration},
+		{name: "max", value: func(durations []time.Duration) time.Duration { return durations[len(durations)-1] }},
+	}
+	for _, stat := range stats {
+		fmt.Fprintf(tabWriter, "%s", stat.name)
+		for _, phase := range phases {
+			var durations []time.Duration
+			for _, result := range summary.Results {
+				// Phases that did not occur, such as the DNS lookup or TLS
+				// handshake of a reused connection, are not included.
+				if duration := phase(result); duration != 0 {
+					durations = append(durations, duration)
+				}
+			}
+			if len(durations) == 0 {
+				fmt.Fprintf(tabWriter, "\t%s", formatPingDuration(0))
+				continue
+			}
+			sort.Slice(
+				durations,
+				func(i int, j int) bool {
+					return durations[i] < durations[j]
+				},
+			)
+			fmt.Fprintf(tabWriter, "\t%s", formatPingDuration(stat.value(durations)))
+		}
+		fmt.Fprintf(tabWriter, "\n")
+	}
+	return tabWriter.Flush()
+}
+
+// formatPingDuration formats the duration of a phase of an RPC, which is "-"
+// if the phase did not occur.
+func formatPingDuration(duration time.Duration) string {
+	if duration == 0 {
+		return "-"
+	}
+	// Milliseconds with a fixed precision are easier to compare in a table.
+	return strconv.FormatFloat(float64(duration)/float64(time.Millisecond), 'f', 3, 64) + "ms"
+}
+
+func averageDuration(durations []time.Duration) time.Duration {
+	var total time.Duration
+	for _, duration := range durations {
+		total += duration
+	}
+	return total / time.Duration(len(durations))
+}
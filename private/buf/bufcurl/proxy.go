@@ -0,0 +1,224 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"golang.org/x/net/proxy"
+)
+
+// DialFunc dials a connection to the given address, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Proxy determines how to connect to addresses through the proxies returned
+// by a proxy function for the addresses.
+//
+// Proxies with the "http" and "https" schemes are used by an http.Transport
+// via its Proxy field, and proxies with the "socks5" and "socks5h" schemes are
+// used by dialing through them. In both cases, the host name of the address
+// is resolved by the proxy. Credentials in the proxy URL are used to
+// authenticate with the proxy.
+type Proxy interface {
+	// HTTPProxyFunc returns the function to use as the Proxy of an http.Transport.
+	//
+	// The function only returns proxies with the "http" and "https" schemes.
+	HTTPProxyFunc() func(*http.Request) (*url.URL, error)
+	// SOCKSDialFunc returns a DialFunc that connects to addresses through
+	// their SOCKS5 proxies, using dial to connect to the proxies. Addresses
+	// without a SOCKS5 proxy are connected to directly with dial.
+	SOCKSDialFunc(dial DialFunc) DialFunc
+	// TLSDialFunc returns a DialFunc to use as the DialTLSContext of an
+	// http.Transport that also has HTTPProxyFunc as its Proxy.
+	//
+	// The http.Transport uses its DialTLSContext to connect to proxies with
+	// the "https" scheme, so the returned DialFunc does a TLS handshake with
+	// the proxy for their addresses, using dial to connect to them. Other
+	// addresses are connected to with dialTLS.
+	TLSDialFunc(dial DialFunc, dialTLS DialFunc) DialFunc
+}
+
+// NewProxy returns a new Proxy for the proxy function, which returns the
+// proxy to use for an address, or nil if the address is connected to directly.
+func NewProxy(proxyFunc func(address string) (*url.URL, error), printer verbose.Printer) Proxy {
+	return newProxy(proxyFunc, printer)
+}
+
+type proxyProvider struct {
+	proxyFunc func(address string) (*url.URL, error)
+	printer   verbose.Printer
+	// The addresses of the proxies with the "https" scheme returned by
+	// HTTPProxyFunc. The http.Transport calls its Proxy before dialing, so
+	// TLSDialFunc knows which of the addresses it dials are these proxies.
+	httpsProxyAddresses map[string]struct{}
+	lock                sync.RWMutex
+}
+
+func newProxy(proxyFunc func(address string) (*url.URL, error), printer verbose.Printer) *proxyProvider {
+	return &proxyProvider{
+		proxyFunc:           proxyFunc,
+		printer:             printer,
+		httpsProxyAddresses: make(map[string]struct{}),
+	}
+}
+
+func (p *proxyProvider) HTTPProxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(request *http.Request) (*url.URL, error) {
+		address := requestAddress(request.URL)
+		proxyURL, err := p.proxyFunc(address)
+		if err != nil || proxyURL == nil {
+			return nil, err
+		}
+		switch proxyURL.Scheme {
+		case "http":
+		case "https":
+			p.lock.Lock()
+			p.httpsProxyAddresses[proxyAddress(proxyURL)] = struct{}{}
+			p.lock.Unlock()
+		default:
+			// SOCKS5 proxies are used by SOCKSDialFunc.
+			return nil, nil
+		}
+		p.printer.Printf("* Using proxy %s for %s", proxyURL.Redacted(), address)
+		return proxyURL, nil
+	}
+}
+
+func (p *proxyProvider) SOCKSDialFunc(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		proxyURL, err := p.proxyFunc(address)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL == nil {
+			return dial(ctx, network, address)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https":
+			// HTTP proxies are used by the transport via HTTPProxyFunc.
+			return dial(ctx, network, address)
+		case "socks5", "socks5h":
+			p.printer.Printf("* Using proxy %s for %s", proxyURL.Redacted(), address)
+			return dialSOCKS5Proxy(ctx, dial, proxyURL, network, address, p.printer)
+		default:
+			return nil, fmt.Errorf("proxy %s has unsupported scheme %q", proxyURL.Redacted(), proxyURL.Scheme)
+		}
+	}
+}
+
+func (p *proxyProvider) TLSDialFunc(dial DialFunc, dialTLS DialFunc) DialFunc {
+	return func(ctx context.Context, network, address string) (_ net.Conn, retErr error) {
+		p.lock.RLock()
+		_, isHTTPSProxy := p.httpsProxyAddresses[address]
+		p.lock.RUnlock()
+		if !isHTTPSProxy {
+			return dialTLS(ctx, network, address)
+		}
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName: host,
+			MinVersion: tls.VersionTLS12,
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("TLS handshake with proxy %s failed: %w", address, err)
+		}
+		return tlsConn, nil
+	}
+}
+
+func dialSOCKS5Proxy(
+	ctx context.Context,
+	dial DialFunc,
+	proxyURL *url.URL,
+	network string,
+	address string,
+	printer verbose.Printer,
+) (net.Conn, error) {
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{
+			User:     proxyURL.User.Username(),
+			Password: password,
+		}
+	}
+	dialer, err := proxy.SOCKS5("tcp", proxyAddress(proxyURL), auth, dial)
+	if err != nil {
+		return nil, err
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 dialer %T does not support contexts", dialer)
+	}
+	conn, err := contextDialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s failed to connect to %s: %w", proxyURL.Redacted(), address, err)
+	}
+	printer.Printf("* Proxy tunnel to %s established", address)
+	return conn, nil
+}
+
+// Dial implements proxy.Dialer.
+func (d DialFunc) Dial(network, address string) (net.Conn, error) {
+	return d(context.Background(), network, address)
+}
+
+// DialContext implements proxy.ContextDialer.
+func (d DialFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d(ctx, network, address)
+}
+
+// requestAddress returns the address of the host of the request URL, using
+// the default port for its scheme if the URL does not have one.
+func requestAddress(requestURL *url.URL) string {
+	if requestURL.Port() != "" {
+		return requestURL.Host
+	}
+	if requestURL.Scheme == "https" {
+		return net.JoinHostPort(requestURL.Hostname(), "443")
+	}
+	return net.JoinHostPort(requestURL.Hostname(), "80")
+}
+
+// proxyAddress returns the address of the proxy, using the default port for
+// its scheme if the URL does not have one.
+func proxyAddress(proxyURL *url.URL) string {
+	if proxyURL.Port() != "" {
+		return proxyURL.Host
+	}
+	switch proxyURL.Scheme {
+	case "https":
+		return net.JoinHostPort(proxyURL.Hostname(), "443")
+	case "socks5", "socks5h":
+		return net.JoinHostPort(proxyURL.Hostname(), "1080")
+	default:
+		return net.JoinHostPort(proxyURL.Hostname(), "80")
+	}
+}
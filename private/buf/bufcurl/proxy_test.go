@@ -0,0 +1,208 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHTTP(t *testing.T) {
+	t.Parallel()
+	var proxiedURL string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		proxiedURL = request.URL.String()
+		_, _ = responseWriter.Write([]byte("proxied"))
+	}))
+	defer proxyServer.Close()
+	proxyURL, err := url.Parse(proxyServer.URL)
+	require.NoError(t, err)
+	proxy := NewProxy(
+		func(string) (*url.URL, error) {
+			return proxyURL, nil
+		},
+		verbose.NopPrinter,
+	)
+	var dialed bool
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy: proxy.HTTPProxyFunc(),
+			DialContext: proxy.SOCKSDialFunc(
+				func(ctx context.Context, network, address string) (net.Conn, error) {
+					dialed = true
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, network, address)
+				},
+			),
+		},
+	}
+	response, err := client.Get("http://example.invalid/foo")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "proxied", string(body))
+	// The request is sent to the proxy with the absolute URL, not through a tunnel.
+	assert.Equal(t, "http://example.invalid/foo", proxiedURL)
+	// The proxy itself is dialed directly.
+	assert.True(t, dialed)
+}
+
+func TestProxySOCKS5(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		_, _ = responseWriter.Write([]byte("direct"))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	socksAddress, socksTargets := startTestSOCKS5Server(t)
+	proxyURL := &url.URL{Scheme: "socks5", Host: socksAddress}
+	proxy := NewProxy(
+		func(address string) (*url.URL, error) {
+			if address == socksAddress {
+				return nil, nil
+			}
+			return proxyURL, nil
+		},
+		verbose.NopPrinter,
+	)
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	httpProxyURL, err := proxy.HTTPProxyFunc()(request)
+	require.NoError(t, err)
+	// SOCKS5 proxies are not used by the transport.
+	assert.Nil(t, httpProxyURL)
+	var dialer net.Dialer
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:       proxy.HTTPProxyFunc(),
+			DialContext: proxy.SOCKSDialFunc(dialer.DialContext),
+		},
+	}
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "direct", string(body))
+	assert.Equal(t, serverURL.Host, <-socksTargets)
+}
+
+func TestProxySOCKSDialFuncHTTPProxy(t *testing.T) {
+	t.Parallel()
+	proxy := NewProxy(
+		func(string) (*url.URL, error) {
+			return &url.URL{Scheme: "http", Host: "proxy.invalid:3128"}, nil
+		},
+		verbose.NopPrinter,
+	)
+	var dialedAddress string
+	_, err := proxy.SOCKSDialFunc(
+		func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialedAddress = address
+			return nil, io.EOF
+		},
+	)(context.Background(), "tcp", "example.invalid:80")
+	assert.Equal(t, io.EOF, err)
+	// HTTP proxies are left to the transport, so the address is dialed directly.
+	assert.Equal(t, "example.invalid:80", dialedAddress)
+}
+
+// startTestSOCKS5Server starts a SOCKS5 server without authentication that
+// accepts a single connection, and returns its address and a channel that
+// receives the address that the connection was made to.
+func startTestSOCKS5Server(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	targets := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// The greeting is the version, the number of methods, and the methods.
+		greeting := make([]byte, 2)
+		if _, err := io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, make([]byte, greeting[1])); err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte{5, 0}); err != nil {
+			return
+		}
+		// The request is the version, the command, a reserved byte, and the address.
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		var host string
+		switch header[3] {
+		case 1:
+			ip := make([]byte, 4)
+			if _, err := io.ReadFull(conn, ip); err != nil {
+				return
+			}
+			host = net.IP(ip).String()
+		case 3:
+			length := make([]byte, 1)
+			if _, err := io.ReadFull(conn, length); err != nil {
+				return
+			}
+			name := make([]byte, length[0])
+			if _, err := io.ReadFull(conn, name); err != nil {
+				return
+			}
+			host = string(name)
+		default:
+			return
+		}
+		port := make([]byte, 2)
+		if _, err := io.ReadFull(conn, port); err != nil {
+			return
+		}
+		target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+		targets <- target
+		targetConn, err := net.Dial("tcp", target)
+		if err != nil {
+			return
+		}
+		defer targetConn.Close()
+		if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+			return
+		}
+		go func() {
+			_, _ = io.Copy(targetConn, conn)
+		}()
+		_, _ = io.Copy(conn, targetConn)
+	}()
+	return listener.Addr().String(), targets
+}
@@ -0,0 +1,209 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ReflectionCacheConfig configures the cache of a caching server reflection resolver.
+type ReflectionCacheConfig struct {
+	// The directory in which the cache files are stored.
+	DirPath string
+	// The identity of the server, such as its base URL. Servers that can be
+	// reached at the same URL, such as via different unix sockets, must have
+	// different identities.
+	Server string
+	// The fully-qualified name of the service.
+	Service string
+	// How long the cached descriptors are used for. The cached descriptors are
+	// not used if they were written longer ago than this, and they are replaced
+	// by the descriptors that are downloaded.
+	TTL time.Duration
+}
+
+// NewCachingServerReflectionResolver creates a new resolver, like
+// NewServerReflectionResolver, that also caches the downloaded descriptors on
+// disk, as configured by the given ReflectionCacheConfig.
+//
+// The cache is keyed by the server and the service, so that later invocations
+// of methods of the same service on the same server can use the cached
+// descriptors, and only use server reflection for descriptors that are not in
+// the cache. The cache is updated when the returned function is called.
+//
+// The cached descriptors expire after the TTL of the ReflectionCacheConfig.
+// Descriptors that are added to a cache that has not expired do not extend
+// when it expires, so that the schema is downloaded again at least once per TTL.
+func NewCachingServerReflectionResolver(
+	ctx context.Context,
+	httpClient connect.HTTPClient,
+	opts []connect.ClientOption,
+	baseURL string,
+	reflectProtocol ReflectProtocol,
+	headers http.Header,
+	printer verbose.Printer,
+	cacheConfig *ReflectionCacheConfig,
+) (r protoencoding.Resolver, closeResolver func()) {
+	reflectionResolver := newServerReflectionResolver(ctx, httpClient, opts, baseURL, reflectProtocol, headers, printer)
+	service := cacheConfig.Service
+	cache := &reflectionCache{
+		filePath: filepath.Join(cacheConfig.DirPath, reflectionCacheKey(cacheConfig.Server, service)),
+		service:  service,
+		printer:  printer,
+	}
+	cachedFiles, cacheModTime, err := cache.read(time.Now(), cacheConfig.TTL)
+	if err != nil {
+		// The cache is only an optimization, so we fall back to server reflection.
+		printer.Printf("* Ignoring server reflection cache for service %s: %v\n", service, err)
+		cachedFiles = nil
+	}
+	res := protoencoding.Resolver(reflectionResolver)
+	if len(cachedFiles) > 0 {
+		cachedResolver, err := protoencoding.NewResolver(cachedFiles...)
+		if err != nil {
+			printer.Printf("* Ignoring server reflection cache for service %s: %v\n", service, err)
+			cachedFiles = nil
+		} else {
+			printer.Printf("* Using server reflection cache for service %s from %q\n", service, cache.filePath)
+			res = protoencoding.CombineResolvers(cachedResolver, reflectionResolver)
+		}
+	}
+	return res, func() {
+		reflectionResolver.Reset()
+		downloadedFiles := reflectionResolver.downloadedFiles()
+		if len(downloadedFiles) == 0 {
+			return
+		}
+		if err := cache.write(cachedFiles, cacheModTime, downloadedFiles); err != nil {
+			printer.Printf("* Failed to update server reflection cache for service %s: %v\n", service, err)
+		}
+	}
+}
+
+type reflectionCache struct {
+	filePath string
+	service  string
+	printer  verbose.Printer
+}
+
+// read returns the cached files and the time that they were written, or nil
+// if there are none or they were written longer ago than the ttl.
+func (c *reflectionCache) read(now time.Time, ttl time.Duration) ([]*descriptorpb.FileDescriptorProto, time.Time, error) {
+	fileInfo, err := os.Stat(c.filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, err
+	}
+	if now.Sub(fileInfo.ModTime()) >= ttl {
+		c.printer.Printf("* Server reflection cache for service %s in %q has expired\n", c.service, c.filePath)
+		return nil, time.Time{}, nil
+	}
+	data, err := os.ReadFile(c.filePath)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := protoencoding.NewWireUnmarshaler(nil).Unmarshal(data, fileDescriptorSet); err != nil {
+		return nil, time.Time{}, err
+	}
+	return fileDescriptorSet.GetFile(), fileInfo.ModTime(), nil
+}
+
+// write writes the cached files, replacing those that have been downloaded again.
+//
+// If there are cached files, the cache keeps the time that they were written,
+// so that the cache expires when the cached files expire.
+func (c *reflectionCache) write(
+	cachedFiles []*descriptorpb.FileDescriptorProto,
+	cacheModTime time.Time,
+	downloadedFiles []*descriptorpb.FileDescriptorProto,
+) (retErr error) {
+	nameToFile := make(map[string]*descriptorpb.FileDescriptorProto, len(cachedFiles)+len(downloadedFiles))
+	for _, file := range cachedFiles {
+		nameToFile[file.GetName()] = file
+	}
+	for _, file := range downloadedFiles {
+		nameToFile[file.GetName()] = file
+	}
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{
+		File: make([]*descriptorpb.FileDescriptorProto, 0, len(nameToFile)),
+	}
+	for _, file := range nameToFile {
+		fileDescriptorSet.File = append(fileDescriptorSet.File, file)
+	}
+	sort.Slice(
+		fileDescriptorSet.File,
+		func(i int, j int) bool {
+			return fileDescriptorSet.File[i].GetName() < fileDescriptorSet.File[j].GetName()
+		},
+	)
+	data, err := protoencoding.NewWireMarshaler().Marshal(fileDescriptorSet)
+	if err != nil {
+		return err
+	}
+	dirPath := filepath.Dir(c.filePath)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it, so that concurrent invocations
+	// never read a partially written cache file.
+	file, err := os.CreateTemp(dirPath, filepath.Base(c.filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			retErr = multierr.Append(retErr, os.Remove(file.Name()))
+		}
+	}()
+	if _, err := file.Write(data); err != nil {
+		return multierr.Append(err, file.Close())
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if len(cachedFiles) > 0 {
+		if err := os.Chtimes(file.Name(), cacheModTime, cacheModTime); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(file.Name(), c.filePath); err != nil {
+		return err
+	}
+	c.printer.Printf("* Updated server reflection cache for service %s in %q\n", c.service, c.filePath)
+	return nil
+}
+
+// reflectionCacheKey returns the name of the cache file for the service on the server.
+func reflectionCacheKey(server string, service string) string {
+	digest := sha256.Sum256([]byte(server + "\x00" + service))
+	return hex.EncodeToString(digest[:]) + ".binpb"
+}
@@ -0,0 +1,68 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestReflectionCache(t *testing.T) {
+	t.Parallel()
+	cache := &reflectionCache{
+		filePath: filepath.Join(t.TempDir(), reflectionCacheKey("https://localhost", "acme.v1.FooService")),
+		service:  "acme.v1.FooService",
+		printer:  verbose.NopPrinter,
+	}
+	now := time.Now()
+	cachedFiles, _, err := cache.read(now, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, cachedFiles)
+
+	aFile := &descriptorpb.FileDescriptorProto{Name: proto.String("a.proto")}
+	require.NoError(t, cache.write(nil, time.Time{}, []*descriptorpb.FileDescriptorProto{aFile}))
+	cachedFiles, cacheModTime, err := cache.read(time.Now(), time.Hour)
+	require.NoError(t, err)
+	require.Len(t, cachedFiles, 1)
+	assert.Equal(t, "a.proto", cachedFiles[0].GetName())
+
+	// Adding files to the cache does not change when it expires.
+	writeTime := now.Add(-30 * time.Minute)
+	require.NoError(t, os.Chtimes(cache.filePath, writeTime, writeTime))
+	cachedFiles, cacheModTime, err = cache.read(now, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, cachedFiles, 1)
+	bFile := &descriptorpb.FileDescriptorProto{Name: proto.String("b.proto")}
+	require.NoError(t, cache.write(cachedFiles, cacheModTime, []*descriptorpb.FileDescriptorProto{bFile}))
+	cachedFiles, cacheModTime, err = cache.read(now, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, cachedFiles, 2)
+	assert.Equal(t, "a.proto", cachedFiles[0].GetName())
+	assert.Equal(t, "b.proto", cachedFiles[1].GetName())
+	assert.True(t, writeTime.Equal(cacheModTime))
+
+	// The cache expires after the TTL.
+	cachedFiles, _, err = cache.read(now.Add(30*time.Minute), time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, cachedFiles)
+}
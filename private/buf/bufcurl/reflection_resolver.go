@@ -102,6 +102,19 @@ func NewServerReflectionResolver(
 	headers http.Header,
 	printer verbose.Printer,
 ) (r protoencoding.Resolver, closeResolver func()) {
+	res := newServerReflectionResolver(ctx, httpClient, opts, baseURL, reflectProtocol, headers, printer)
+	return res, res.Reset
+}
+
+func newServerReflectionResolver(
+	ctx context.Context,
+	httpClient connect.HTTPClient,
+	opts []connect.ClientOption,
+	baseURL string,
+	reflectProtocol ReflectProtocol,
+	headers http.Header,
+	printer verbose.Printer,
+) *reflectionResolver {
 	baseURL = strings.TrimSuffix(baseURL, "/")
 	var v1Client, v1alphaClient *reflectClient
 	if reflectProtocol != ReflectProtocolGRPCV1 {
@@ -120,7 +133,7 @@ func NewServerReflectionResolver(
 	// context so that underlying transport can restore them
 	ctx = withUserAgent(ctx, headers)
 
-	res := &reflectionResolver{
+	return &reflectionResolver{
 		ctx:              ctx,
 		v1Client:         v1Client,
 		v1alphaClient:    v1alphaClient,
@@ -129,7 +142,6 @@ func NewServerReflectionResolver(
 		printer:          printer,
 		downloadedProtos: map[string]*descriptorpb.FileDescriptorProto{},
 	}
-	return res, res.Reset
 }
 
 type reflectClient = connect.Client[reflectionv1.ServerReflectionRequest, reflectionv1.ServerReflectionResponse]
@@ -393,6 +405,18 @@ func (r *reflectionResolver) cacheFileLocked(name string, seen []string) error {
 	return nil
 }
 
+// downloadedFiles returns the files that have been downloaded from the server.
+func (r *reflectionResolver) downloadedFiles() []*descriptorpb.FileDescriptorProto {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	files := make([]*descriptorpb.FileDescriptorProto, 0, len(r.downloadedProtos))
+	for _, file := range r.downloadedProtos {
+		files = append(files, file)
+	}
+	return files
+}
+
 func (r *reflectionResolver) sendLocked(req *reflectionv1.ServerReflectionRequest) (*reflectionv1.ServerReflectionResponse, error) {
 	stream, isNew := r.getStreamLocked()
 	resp, err := send(stream, req)
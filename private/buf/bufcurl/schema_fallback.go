@@ -0,0 +1,77 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadSchemaMap reads the YAML or JSON file with the given name, which maps
+// fully-qualified service names, or the names of packages that contain
+// services, to the schemas that define them, such as modules in the Buf Schema
+// Registry.
+//
+// For example:
+//
+//	acme.foo.v1.FooService: buf.build/acme/foo
+//	acme.bar.v1: buf.build/acme/bar
+func ReadSchemaMap(schemaMapFile string) (map[string]string, error) {
+	data, err := os.ReadFile(schemaMapFile)
+	if err != nil {
+		return nil, ErrorHasFilename(err, schemaMapFile)
+	}
+	var schemaMap map[string]string
+	if err := yaml.Unmarshal(data, &schemaMap); err != nil {
+		return nil, ErrorHasFilename(err, schemaMapFile)
+	}
+	for name, schema := range schemaMap {
+		if name == "" || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+			return nil, ErrorHasFilename(fmt.Errorf("invalid service or package name %q", name), schemaMapFile)
+		}
+		if schema == "" {
+			return nil, ErrorHasFilename(fmt.Errorf("no schema for %q", name), schemaMapFile)
+		}
+	}
+	return schemaMap, nil
+}
+
+// FallbackSchemas returns the schemas to consult, in order, for the service
+// with the given fully-qualified name when it cannot be resolved using server
+// reflection. The schema in schemaMap for the longest matching service or
+// package name is first, followed by the given schemas.
+func FallbackSchemas(service string, schemaMap map[string]string, schemas []string) []string {
+	var fallbackSchemas []string
+	for name := service; name != ""; {
+		if schema, ok := schemaMap[name]; ok {
+			fallbackSchemas = append(fallbackSchemas, schema)
+			break
+		}
+		lastDot := strings.LastIndexByte(name, '.')
+		if lastDot < 0 {
+			break
+		}
+		name = name[:lastDot]
+	}
+	for _, schema := range schemas {
+		if len(fallbackSchemas) == 0 || fallbackSchemas[0] != schema {
+			fallbackSchemas = append(fallbackSchemas, schema)
+		}
+	}
+	return fallbackSchemas
+}
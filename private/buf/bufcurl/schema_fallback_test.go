@@ -0,0 +1,161 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSchemaMap(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name              string
+		content           string
+		expectedSchemaMap map[string]string
+		expectedErr       string
+	}{
+		{
+			name: "yaml",
+			content: `acme.foo.v1.FooService: buf.build/acme/foo
+acme.bar.v1: buf.build/acme/bar
+`,
+			expectedSchemaMap: map[string]string{
+				"acme.foo.v1.FooService": "buf.build/acme/foo",
+				"acme.bar.v1":            "buf.build/acme/bar",
+			},
+		},
+		{
+			name:    "json",
+			content: `{"acme.foo.v1": "./proto"}`,
+			expectedSchemaMap: map[string]string{
+				"acme.foo.v1": "./proto",
+			},
+		},
+		{
+			name:        "leading dot",
+			content:     `.acme.foo.v1: buf.build/acme/foo`,
+			expectedErr: `invalid service or package name ".acme.foo.v1"`,
+		},
+		{
+			name:        "trailing dot",
+			content:     `acme.foo.v1.: buf.build/acme/foo`,
+			expectedErr: `invalid service or package name "acme.foo.v1."`,
+		},
+		{
+			name:        "empty name",
+			content:     `"": buf.build/acme/foo`,
+			expectedErr: `invalid service or package name ""`,
+		},
+		{
+			name:        "empty schema",
+			content:     `acme.foo.v1: ""`,
+			expectedErr: `no schema for "acme.foo.v1"`,
+		},
+		{
+			name:        "not a map",
+			content:     `- buf.build/acme/foo`,
+			expectedErr: `cannot unmarshal`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			schemaMapFile := filepath.Join(t.TempDir(), "schemas.yaml")
+			require.NoError(t, os.WriteFile(schemaMapFile, []byte(testCase.content), 0600))
+			schemaMap, err := ReadSchemaMap(schemaMapFile)
+			if testCase.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), schemaMapFile)
+				assert.Contains(t, err.Error(), testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedSchemaMap, schemaMap)
+		})
+	}
+}
+
+func TestReadSchemaMapNotExist(t *testing.T) {
+	t.Parallel()
+	_, err := ReadSchemaMap(filepath.Join(t.TempDir(), "schemas.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFallbackSchemas(t *testing.T) {
+	t.Parallel()
+	schemaMap := map[string]string{
+		"acme.foo.v1.FooService": "buf.build/acme/foo-service",
+		"acme.foo.v1":            "buf.build/acme/foo",
+		"acme":                   "buf.build/acme/all",
+	}
+	testCases := []struct {
+		name     string
+		service  string
+		schemas  []string
+		expected []string
+	}{
+		{
+			name:     "service",
+			service:  "acme.foo.v1.FooService",
+			expected: []string{"buf.build/acme/foo-service"},
+		},
+		{
+			name:     "package",
+			service:  "acme.foo.v1.BarService",
+			expected: []string{"buf.build/acme/foo"},
+		},
+		{
+			name:     "parent package",
+			service:  "acme.bar.v1.BarService",
+			expected: []string{"buf.build/acme/all"},
+		},
+		{
+			name:     "name prefix is not a package",
+			service:  "acmecorp.v1.FooService",
+			schemas:  []string{"./proto"},
+			expected: []string{"./proto"},
+		},
+		{
+			name:     "no match",
+			service:  "other.v1.FooService",
+			expected: nil,
+		},
+		{
+			name:     "mapped schema first",
+			service:  "acme.foo.v1.BarService",
+			schemas:  []string{"./proto", "buf.build/acme/other"},
+			expected: []string{"buf.build/acme/foo", "./proto", "buf.build/acme/other"},
+		},
+		{
+			name:     "mapped schema not repeated",
+			service:  "acme.foo.v1.BarService",
+			schemas:  []string{"buf.build/acme/foo", "./proto"},
+			expected: []string{"buf.build/acme/foo", "./proto"},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expected, FallbackSchemas(testCase.service, schemaMap, testCase.schemas))
+		})
+	}
+}
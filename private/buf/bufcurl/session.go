@@ -0,0 +1,461 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedHeaderValue is the recorded value of headers that contain
+// credentials, such as "Authorization".
+const RedactedHeaderValue = "REDACTED"
+
+// redactedHeaders are the headers whose values are not recorded.
+var redactedHeaders = map[string]struct{}{
+	"Authorization":       {},
+	"Cookie":              {},
+	"Proxy-Authorization": {},
+}
+
+// Session is a recording of an RPC.
+type Session struct {
+	// The URL of the method that was invoked.
+	URL string `yaml:"url"`
+	// The protocol that was used, such as "connect".
+	Protocol string          `yaml:"protocol,omitempty"`
+	Request  SessionRequest  `yaml:"request"`
+	Response SessionResponse `yaml:"response"`
+}
+
+// SessionRequest is a recording of the request of an RPC.
+type SessionRequest struct {
+	// The request headers, except for those that are set by the protocol.
+	// The values of headers with credentials are RedactedHeaderValue.
+	Headers map[string][]string `yaml:"headers,omitempty"`
+	// The request messages, in the structure of their JSON format.
+	Messages []any `yaml:"messages,omitempty"`
+}
+
+// SessionResponse is a recording of the response of an RPC.
+type SessionResponse struct {
+	Headers map[string][]string `yaml:"headers,omitempty"`
+	// The response messages.
+	Messages []SessionMessage    `yaml:"messages,omitempty"`
+	Trailers map[string][]string `yaml:"trailers,omitempty"`
+	// The error of the RPC, in the structure of its JSON format, or nil if
+	// the RPC succeeded.
+	Error any `yaml:"error,omitempty"`
+	// The duration of the RPC, such as "12.5ms".
+	Duration string `yaml:"duration"`
+}
+
+// SessionMessage is a recording of a response message.
+type SessionMessage struct {
+	// The time since the start of the RPC at which the message was received,
+	// such as "10.2ms".
+	Elapsed string `yaml:"elapsed"`
+	// The message, in the structure of its JSON format.
+	Message any `yaml:"message"`
+}
+
+// SessionRecorder is an interceptor that records an RPC in a Session. If the
+// RPC is retried, only the last attempt is recorded.
+type SessionRecorder interface {
+	connect.Interceptor
+	// Session returns the recording of the RPC, or an error if a message or
+	// the error of the RPC could not be recorded.
+	Session() (*Session, error)
+}
+
+// NewSessionRecorder returns a new SessionRecorder for RPCs of the given
+// method. The given resolver is used to format messages in JSON.
+func NewSessionRecorder(md protoreflect.MethodDescriptor, res protoencoding.Resolver) SessionRecorder {
+	return &sessionRecorder{
+		md:      md,
+		res:     res,
+		session: &Session{},
+	}
+}
+
+// ReadSession reads a Session from the YAML file with the given name.
+func ReadSession(sessionFile string) (*Session, error) {
+	data, err := os.ReadFile(sessionFile)
+	if err != nil {
+		return nil, ErrorHasFilename(err, sessionFile)
+	}
+	session := &Session{}
+	if err := yaml.Unmarshal(data, session); err != nil {
+		return nil, ErrorHasFilename(err, sessionFile)
+	}
+	return session, nil
+}
+
+// WriteSession writes the Session to the YAML file with the given name.
+func WriteSession(sessionFile string, session *Session) error {
+	data, err := yaml.Marshal(session)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(sessionFile, data, 0644); err != nil {
+		return ErrorHasFilename(err, sessionFile)
+	}
+	return nil
+}
+
+// RequestData returns the request messages of the Session as request data,
+// which is a sequence of JSON documents.
+func (s *Session) RequestData() ([]byte, error) {
+	var data bytes.Buffer
+	for _, message := range s.Request.Messages {
+		messageData, err := json.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		data.Write(messageData)
+		data.WriteByte('\n')
+	}
+	return data.Bytes(), nil
+}
+
+// RequestHeaders returns the request headers of the Session, except for
+// those with redacted values.
+func (s *Session) RequestHeaders() http.Header {
+	headers := http.Header{}
+	for name, values := range s.Request.Headers {
+		for _, value := range values {
+			if value != RedactedHeaderValue {
+				headers.Add(name, value)
+			}
+		}
+	}
+	return headers
+}
+
+// CompareResponse compares the response messages and error of the Session
+// with those of the given Session, returning a description of the first
+// difference, or the empty string if there are none. Headers, trailers, and
+// timings are not compared, since they are expected to vary.
+func (s *Session) CompareResponse(other *Session) (string, error) {
+	if len(s.Response.Messages) != len(other.Response.Messages) {
+		return fmt.Sprintf("expected %d response messages, but got %d", len(s.Response.Messages), len(other.Response.Messages)), nil
+	}
+	for i := range s.Response.Messages {
+		equal, err := jsonEqual(s.Response.Messages[i].Message, other.Response.Messages[i].Message)
+		if err != nil {
+			return "", err
+		}
+		if !equal {
+			return fmt.Sprintf("response message %d differs", i+1), nil
+		}
+	}
+	equal, err := jsonEqual(s.Response.Error, other.Response.Error)
+	if err != nil {
+		return "", err
+	}
+	if !equal {
+		return "response error differs", nil
+	}
+	return "", nil
+}
+
+type sessionRecorder struct {
+	md  protoreflect.MethodDescriptor
+	res protoencoding.Resolver
+
+	lock    sync.Mutex
+	session *Session
+	start   time.Time
+	// err is the first error that occurred while recording.
+	err error
+}
+
+func (r *sessionRecorder) Session() (*Session, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.session, nil
+}
+
+func (r *sessionRecorder) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		r.startRPC(req.Header())
+		r.recordRequestMessage(req.Any())
+		resp, err := next(ctx, req)
+		if err != nil {
+			if connErr := new(connect.Error); errors.As(err, &connErr) {
+				// The headers and trailers of a failed unary RPC are both
+				// in the metadata of the error.
+				r.recordResponseHeaders(connErr.Meta())
+			}
+			r.recordError(err)
+		} else {
+			r.recordResponseHeaders(resp.Header())
+			r.recordResponseMessage(resp.Any())
+			r.recordResponseTrailers(resp.Trailer())
+		}
+		r.finishRPC()
+		return resp, err
+	}
+}
+
+func (r *sessionRecorder) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return &recordingStreamingClientConn{
+			StreamingClientConn: next(ctx, spec),
+			recorder:            r,
+		}
+	}
+}
+
+func (r *sessionRecorder) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+func (r *sessionRecorder) startRPC(headers http.Header) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.session.Request = SessionRequest{
+		Headers: recordHeaders(headers, true),
+	}
+	r.session.Response = SessionResponse{}
+	r.start = time.Now()
+	r.err = nil
+}
+
+func (r *sessionRecorder) finishRPC() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.session.Response.Duration = time.Since(r.start).String()
+}
+
+func (r *sessionRecorder) recordRequestMessage(msg any) {
+	protoMessage, ok := msg.(proto.Message)
+	if !ok {
+		return
+	}
+	data, err := protoencoding.NewJSONMarshaler(r.res).Marshal(protoMessage)
+	if err != nil {
+		r.recordFailure(fmt.Errorf("could not record request message: %w", err))
+		return
+	}
+	message, err := decodeJSON(data)
+	if err != nil {
+		r.recordFailure(fmt.Errorf("could not record request message: %w", err))
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.session.Request.Messages = append(r.session.Request.Messages, message)
+}
+
+func (r *sessionRecorder) recordResponseHeaders(headers http.Header) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.session.Response.Headers = recordHeaders(headers, false)
+}
+
+func (r *sessionRecorder) recordResponseMessage(msg any) {
+	deferred, ok := msg.(*deferredMessage)
+	if !ok {
+		return
+	}
+	protoMessage := dynamicpb.NewMessage(r.md.Output())
+	if err := protoencoding.NewWireUnmarshaler(r.res).Unmarshal(deferred.data, protoMessage); err != nil {
+		r.recordFailure(fmt.Errorf("could not record response message: %w", err))
+		return
+	}
+	data, err := protoencoding.NewJSONMarshaler(r.res).Marshal(protoMessage)
+	if err != nil {
+		r.recordFailure(fmt.Errorf("could not record response message: %w", err))
+		return
+	}
+	message, err := decodeJSON(data)
+	if err != nil {
+		r.recordFailure(fmt.Errorf("could not record response message: %w", err))
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.session.Response.Messages = append(r.session.Response.Messages, SessionMessage{
+		Elapsed: time.Since(r.start).String(),
+		Message: message,
+	})
+}
+
+func (r *sessionRecorder) recordResponseTrailers(trailers http.Header) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.session.Response.Trailers = recordHeaders(trailers, false)
+}
+
+func (r *sessionRecorder) recordError(err error) {
+	connErr := new(connect.Error)
+	if !errors.As(err, &connErr) {
+		connErr = connect.NewError(connect.CodeOf(err), err)
+	}
+	data, err := marshalError(connErr, r.res)
+	if err != nil {
+		r.recordFailure(fmt.Errorf("could not record response error: %w", err))
+		return
+	}
+	recordedError, err := decodeJSON(data)
+	if err != nil {
+		r.recordFailure(fmt.Errorf("could not record response error: %w", err))
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.session.Response.Error = recordedError
+	if len(r.session.Response.Headers) == 0 {
+		r.session.Response.Headers = recordHeaders(connErr.Meta(), false)
+	}
+}
+
+// recordFailure records that the RPC could not be recorded. Only the first
+// error is kept.
+func (r *sessionRecorder) recordFailure(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+type recordingStreamingClientConn struct {
+	connect.StreamingClientConn
+	recorder *sessionRecorder
+
+	started  bool
+	received bool
+	finished bool
+}
+
+func (c *recordingStreamingClientConn) Send(msg any) error {
+	c.start()
+	c.recorder.recordRequestMessage(msg)
+	return c.StreamingClientConn.Send(msg)
+}
+
+func (c *recordingStreamingClientConn) CloseRequest() error {
+	c.start()
+	return c.StreamingClientConn.CloseRequest()
+}
+
+func (c *recordingStreamingClientConn) Receive(msg any) error {
+	c.start()
+	err := c.StreamingClientConn.Receive(msg)
+	if !c.received {
+		c.received = true
+		c.recorder.recordResponseHeaders(c.StreamingClientConn.ResponseHeader())
+	}
+	switch {
+	case err == nil:
+		c.recorder.recordResponseMessage(msg)
+	case errors.Is(err, io.EOF):
+		c.finish()
+	default:
+		c.recorder.recordError(err)
+		c.finish()
+	}
+	return err
+}
+
+func (c *recordingStreamingClientConn) CloseResponse() error {
+	c.start()
+	c.finish()
+	return c.StreamingClientConn.CloseResponse()
+}
+
+// start records the start of the RPC, when the first message is sent, since
+// the request headers are set after the stream is created.
+func (c *recordingStreamingClientConn) start() {
+	if !c.started {
+		c.started = true
+		c.recorder.startRPC(c.StreamingClientConn.RequestHeader())
+	}
+}
+
+func (c *recordingStreamingClientConn) finish() {
+	if !c.finished {
+		c.finished = true
+		c.recorder.recordResponseTrailers(c.StreamingClientConn.ResponseTrailer())
+		c.recorder.finishRPC()
+	}
+}
+
+// recordHeaders returns the headers to record. If isRequest is true, headers
+// that are set by the protocol or the user agent are omitted, since they could
+// not be provided again when the session is replayed, and the values of
+// headers with credentials are redacted.
+func recordHeaders(headers http.Header, isRequest bool) map[string][]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	recorded := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		lowerName := strings.ToLower(name)
+		if isRequest && (isReservedHeader(lowerName) || lowerName == "user-agent") {
+			continue
+		}
+		if _, ok := redactedHeaders[http.CanonicalHeaderKey(name)]; ok && isRequest {
+			values = []string{RedactedHeaderValue}
+		}
+		recorded[lowerName] = values
+	}
+	if len(recorded) == 0 {
+		return nil
+	}
+	return recorded
+}
+
+func decodeJSON(data []byte) (any, error) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// jsonEqual returns true if the given values have the same JSON format.
+func jsonEqual(value any, other any) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	otherData, err := json.Marshal(other)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(data, otherData), nil
+}
@@ -0,0 +1,225 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestRecordHeaders(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name      string
+		headers   http.Header
+		isRequest bool
+		expected  map[string][]string
+	}{
+		{
+			name:      "empty",
+			headers:   http.Header{},
+			isRequest: true,
+		},
+		{
+			name: "request",
+			headers: http.Header{
+				"Authorization":       []string{"Bearer secret"},
+				"Cookie":              []string{"a=1", "b=2"},
+				"Proxy-Authorization": []string{"Basic secret"},
+				"Content-Type":        []string{"application/proto"},
+				"Connect-Timeout-Ms":  []string{"1000"},
+				"Grpc-Timeout":        []string{"1S"},
+				"User-Agent":          []string{"buf"},
+				"X-Request-Id":        []string{"123"},
+			},
+			isRequest: true,
+			expected: map[string][]string{
+				"authorization":       {RedactedHeaderValue},
+				"cookie":              {RedactedHeaderValue},
+				"proxy-authorization": {RedactedHeaderValue},
+				"x-request-id":        {"123"},
+			},
+		},
+		{
+			name: "request with only reserved headers",
+			headers: http.Header{
+				"Content-Type": []string{"application/proto"},
+				"User-Agent":   []string{"buf"},
+			},
+			isRequest: true,
+		},
+		{
+			name: "response",
+			headers: http.Header{
+				"Authorization": []string{"Bearer token"},
+				"Content-Type":  []string{"application/proto"},
+				"Grpc-Status":   []string{"0"},
+			},
+			expected: map[string][]string{
+				"authorization": {"Bearer token"},
+				"content-type":  {"application/proto"},
+				"grpc-status":   {"0"},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expected, recordHeaders(testCase.headers, testCase.isRequest))
+		})
+	}
+}
+
+func TestSessionRequestHeaders(t *testing.T) {
+	t.Parallel()
+	session := &Session{
+		Request: SessionRequest{
+			Headers: map[string][]string{
+				"authorization": {RedactedHeaderValue},
+				"x-request-id":  {"123", "456"},
+			},
+		},
+	}
+	assert.Equal(
+		t,
+		http.Header{
+			"X-Request-Id": []string{"123", "456"},
+		},
+		session.RequestHeaders(),
+	)
+}
+
+func TestSessionRequestData(t *testing.T) {
+	t.Parallel()
+	session := &Session{
+		Request: SessionRequest{
+			Messages: []any{
+				map[string]any{"service": "foo"},
+				map[string]any{},
+			},
+		},
+	}
+	data, err := session.RequestData()
+	require.NoError(t, err)
+	assert.Equal(t, "{\"service\":\"foo\"}\n{}\n", string(data))
+}
+
+func TestSessionCompareResponse(t *testing.T) {
+	t.Parallel()
+	newSession := func(recordedError any, messages ...any) *Session {
+		session := &Session{
+			Response: SessionResponse{
+				Headers:  map[string][]string{"x-request-id": {"123"}},
+				Error:    recordedError,
+				Duration: "1ms",
+			},
+		}
+		for _, message := range messages {
+			session.Response.Messages = append(session.Response.Messages, SessionMessage{
+				Elapsed: "1ms",
+				Message: message,
+			})
+		}
+		return session
+	}
+	testCases := []struct {
+		name               string
+		session            *Session
+		other              *Session
+		expectedDifference string
+	}{
+		{
+			name:    "equal",
+			session: newSession(nil, map[string]any{"status": "SERVING", "count": 1.0}),
+			other: &Session{
+				Response: SessionResponse{
+					Messages: []SessionMessage{
+						{
+							Elapsed: "2ms",
+							Message: map[string]any{"count": 1.0, "status": "SERVING"},
+						},
+					},
+					Duration: "2ms",
+				},
+			},
+		},
+		{
+			name:               "different number of messages",
+			session:            newSession(nil, map[string]any{}),
+			other:              newSession(nil, map[string]any{}, map[string]any{}),
+			expectedDifference: "expected 1 response messages, but got 2",
+		},
+		{
+			name:               "different message",
+			session:            newSession(nil, map[string]any{"a": 1.0}, map[string]any{"a": 2.0}),
+			other:              newSession(nil, map[string]any{"a": 1.0}, map[string]any{"a": 3.0}),
+			expectedDifference: "response message 2 differs",
+		},
+		{
+			name:    "equal error",
+			session: newSession(map[string]any{"code": "not_found"}),
+			other:   newSession(map[string]any{"code": "not_found"}),
+		},
+		{
+			name:               "different error",
+			session:            newSession(map[string]any{"code": "not_found"}),
+			other:              newSession(map[string]any{"code": "internal"}),
+			expectedDifference: "response error differs",
+		},
+		{
+			name:               "missing error",
+			session:            newSession(map[string]any{"code": "not_found"}),
+			other:              newSession(nil),
+			expectedDifference: "response error differs",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			difference, err := testCase.session.CompareResponse(testCase.other)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedDifference, difference)
+		})
+	}
+}
+
+func TestSessionRecorderFailure(t *testing.T) {
+	t.Parallel()
+	res, err := NewHealthCheckResolver()
+	require.NoError(t, err)
+	descriptor, err := res.FindDescriptorByName("grpc.health.v1.Health.Check")
+	require.NoError(t, err)
+	recorder := NewSessionRecorder(descriptor.(protoreflect.MethodDescriptor), res).(*sessionRecorder)
+	recorder.startRPC(http.Header{})
+	// Invalid wire data, as the field number 0 is reserved.
+	recorder.recordResponseMessage(&deferredMessage{data: []byte{0x00}})
+	recorder.finishRPC()
+	_, err = recorder.Session()
+	assert.ErrorContains(t, err, "could not record response message")
+	// A retry records the RPC again.
+	recorder.startRPC(http.Header{})
+	recorder.recordResponseMessage(&deferredMessage{data: []byte{0x08, 0x01}})
+	recorder.finishRPC()
+	session, err := recorder.Session()
+	require.NoError(t, err)
+	require.Len(t, session.Response.Messages, 1)
+	assert.Equal(t, map[string]any{"status": "SERVING"}, session.Response.Messages[0].Message)
+}
@@ -0,0 +1,116 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+)
+
+// ParseTemplateVars parses the given flag values, each in the form "name=value",
+// into a map of template variables. If a name appears more than once, the last
+// value is used.
+func ParseTemplateVars(varFlags []string) (map[string]string, error) {
+	vars := make(map[string]string, len(varFlags))
+	for _, varFlag := range varFlags {
+		name, value, ok := strings.Cut(varFlag, "=")
+		if !ok {
+			return nil, fmt.Errorf("variable %q should be in the form name=value", varFlag)
+		}
+		if !isTemplateVarName(name) {
+			return nil, fmt.Errorf("variable name %q should consist of only letters, digits, and underscores", name)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// ExpandTemplate replaces references to variables in the given request data
+// template, in the form "$name" or "${name}", with their values. Values are
+// taken from vars, or else from lookupEnv. It is an error to reference a
+// variable that is not defined in either, but a variable may be defined with
+// an empty value. A literal dollar sign is written as "$$".
+//
+// A name in the form "$name" must start with a letter or an underscore, so a
+// dollar sign that is followed by anything else, such as in "$1" or "$ ", is
+// kept as is. It is an error for "${" to not be followed by a name and "}".
+func ExpandTemplate(
+	template []byte,
+	vars map[string]string,
+	lookupEnv func(string) (string, bool),
+) ([]byte, error) {
+	expanded := make([]byte, 0, len(template))
+	var undefinedNames []string
+	for i := 0; i < len(template); i++ {
+		if template[i] != '$' || i+1 == len(template) {
+			expanded = append(expanded, template[i])
+			continue
+		}
+		var name string
+		switch next := template[i+1]; {
+		case next == '$':
+			expanded = append(expanded, '$')
+			i++
+			continue
+		case next == '{':
+			length := bytes.IndexByte(template[i+2:], '}')
+			if length < 0 || !isTemplateVarName(string(template[i+2:i+2+length])) {
+				return nil, fmt.Errorf("request data has invalid variable reference at offset %d: references should be in the form \"${name}\"", i)
+			}
+			name = string(template[i+2 : i+2+length])
+			i += length + 2
+		case next == '_' || ('a' <= next && next <= 'z') || ('A' <= next && next <= 'Z'):
+			length := 1
+			for i+1+length < len(template) && isTemplateVarName(string(template[i+1+length])) {
+				length++
+			}
+			name = string(template[i+1 : i+1+length])
+			i += length
+		default:
+			expanded = append(expanded, '$')
+			continue
+		}
+		value, ok := vars[name]
+		if !ok {
+			value, ok = lookupEnv(name)
+		}
+		if !ok {
+			undefinedNames = append(undefinedNames, name)
+			continue
+		}
+		expanded = append(expanded, value...)
+	}
+	if len(undefinedNames) > 0 {
+		undefinedNames = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(undefinedNames)
+		return nil, errors.New("request data references undefined variables: " + strings.Join(undefinedNames, ", "))
+	}
+	return expanded, nil
+}
+
+func isTemplateVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, char := range name {
+		if char != '_' && !('a' <= char && char <= 'z') && !('A' <= char && char <= 'Z') && !('0' <= char && char <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTemplateVars(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name         string
+		varFlags     []string
+		expectedVars map[string]string
+		expectedErr  string
+	}{
+		{
+			name:         "none",
+			expectedVars: map[string]string{},
+		},
+		{
+			name:     "multiple",
+			varFlags: []string{"a=1", "B_2=x=y", "empty="},
+			expectedVars: map[string]string{
+				"a":     "1",
+				"B_2":   "x=y",
+				"empty": "",
+			},
+		},
+		{
+			name:         "last value wins",
+			varFlags:     []string{"a=1", "a=2"},
+			expectedVars: map[string]string{"a": "2"},
+		},
+		{
+			name:        "missing equals",
+			varFlags:    []string{"a"},
+			expectedErr: `variable "a" should be in the form name=value`,
+		},
+		{
+			name:        "empty name",
+			varFlags:    []string{"=1"},
+			expectedErr: `variable name "" should consist of only letters, digits, and underscores`,
+		},
+		{
+			name:        "invalid name",
+			varFlags:    []string{"a-b=1"},
+			expectedErr: `variable name "a-b" should consist of only letters, digits, and underscores`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			vars, err := ParseTemplateVars(testCase.varFlags)
+			if testCase.expectedErr != "" {
+				assert.EqualError(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedVars, vars)
+		})
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	t.Parallel()
+	vars := map[string]string{
+		"name":  "world",
+		"empty": "",
+		"1":     "one",
+		"both":  "from vars",
+	}
+	env := map[string]string{
+		"GREETING": "hello",
+		"EMPTY":    "",
+		"both":     "from env",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	testCases := []struct {
+		name        string
+		template    string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "no references",
+			template: `{"a": 1}`,
+			expected: `{"a": 1}`,
+		},
+		{
+			name:     "references",
+			template: `{"sentence": "$GREETING, ${name}!"}`,
+			expected: `{"sentence": "hello, world!"}`,
+		},
+		{
+			name:     "name ends at non-name character",
+			template: `$name-$name.$name`,
+			expected: `world-world.world`,
+		},
+		{
+			name:     "vars before env",
+			template: `$both`,
+			expected: `from vars`,
+		},
+		{
+			name:     "empty values are defined",
+			template: `[$empty${EMPTY}]`,
+			expected: `[]`,
+		},
+		{
+			name:     "escaped dollar sign",
+			template: `$$name $$$$ $$${name}`,
+			expected: `$name $$ $world`,
+		},
+		{
+			name:     "dollar sign not followed by a name",
+			template: `{"price": "$100", "all": "$*", "space": "$ ", "end": "$"}`,
+			expected: `{"price": "$100", "all": "$*", "space": "$ ", "end": "$"}`,
+		},
+		{
+			name:     "braces allow names starting with a digit",
+			template: `$1 ${1}`,
+			expected: `$1 one`,
+		},
+		{
+			name:        "empty braces",
+			template:    `{"a": "${}"}`,
+			expectedErr: `request data has invalid variable reference at offset 7: references should be in the form "${name}"`,
+		},
+		{
+			name:        "unterminated braces",
+			template:    `${name`,
+			expectedErr: `request data has invalid variable reference at offset 0: references should be in the form "${name}"`,
+		},
+		{
+			name:        "invalid name in braces",
+			template:    `${a-b}`,
+			expectedErr: `request data has invalid variable reference at offset 0: references should be in the form "${name}"`,
+		},
+		{
+			name:        "undefined",
+			template:    `$b ${a} $b $name`,
+			expectedErr: `request data references undefined variables: a, b`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			expanded, err := ExpandTemplate([]byte(testCase.template), vars, lookupEnv)
+			if testCase.expectedErr != "" {
+				assert.EqualError(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, string(expanded))
+		})
+	}
+}
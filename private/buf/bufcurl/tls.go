@@ -29,6 +29,13 @@ import (
 type TLSSettings struct {
 	// Filenames for a private key, certificate, and CA certificate pool.
 	KeyFile, CertFile, CACertFile string
+	// Password for the private key, if it is encrypted.
+	KeyPassword string
+	// Filename for a PKCS#12 keystore that contains a private key and
+	// certificate, as an alternative to KeyFile and CertFile.
+	KeystoreFile string
+	// Password for the keystore.
+	KeystorePassword string
 	// Override server name, for SNI.
 	ServerName string
 	// If true, the server's certificate is not verified.
@@ -38,15 +45,22 @@ type TLSSettings struct {
 	// "h2", and exclude "http/1.1". If the server does not pick a
 	// protocol, "h2" is assumed as the default.
 	HTTP2PriorKnowledge bool
+	// If true, the connection is made over QUIC and HTTP/3 is used.
+	// When set, the ALPN protocols sent during the handshake will include
+	// only "h3".
+	HTTP3 bool
 }
 
 // MakeVerboseTLSConfig constructs a *tls.Config that logs information to the
 // given printer as a TLS connection is negotiated.
 func MakeVerboseTLSConfig(settings *TLSSettings, authority string, printer verbose.Printer) (*tls.Config, error) {
 	var conf tls.Config
-	if settings.HTTP2PriorKnowledge {
+	switch {
+	case settings.HTTP3:
+		conf.NextProtos = []string{"h3"}
+	case settings.HTTP2PriorKnowledge:
 		conf.NextProtos = []string{"h2"}
-	} else {
+	default:
 		conf.NextProtos = []string{"h2", "http/1.1"}
 	}
 	// we verify manually so that we can emit verbose output while doing so
@@ -57,9 +71,12 @@ func MakeVerboseTLSConfig(settings *TLSSettings, authority string, printer verbo
 			printer.Printf("* (TLS session resumed)")
 		}
 		if state.NegotiatedProtocol == "" {
-			if settings.HTTP2PriorKnowledge {
+			switch {
+			case settings.HTTP3:
+				printer.Printf("* ALPN: server did not agree on a protocol. Using default h3")
+			case settings.HTTP2PriorKnowledge:
 				printer.Printf("* ALPN: server did not agree on a protocol. Using default h2")
-			} else {
+			default:
 				printer.Printf("* ALPN: server did not agree on a protocol. Using default http/1.1.")
 			}
 		} else {
@@ -124,30 +141,29 @@ func MakeVerboseTLSConfig(settings *TLSSettings, authority string, printer verbo
 		conf.RootCAs.AppendCertsFromPEM(caCert)
 	}
 
-	if settings.KeyFile != "" && settings.CertFile != "" {
-		cert, err := os.ReadFile(settings.CertFile)
-		if err != nil {
-			return nil, ErrorHasFilename(err, settings.CertFile)
-		}
-		key, err := os.ReadFile(settings.KeyFile)
-		if err != nil {
-			return nil, ErrorHasFilename(err, settings.KeyFile)
-		}
-		certPair, err := tls.X509KeyPair(cert, key)
+	var certPair *tls.Certificate
+	switch {
+	case settings.KeystoreFile != "":
+		var err error
+		certPair, err = loadKeystore(settings.KeystoreFile, settings.KeystorePassword)
 		if err != nil {
 			return nil, err
 		}
-		certPair.Leaf, err = x509.ParseCertificate(certPair.Certificate[0])
+	case settings.KeyFile != "" && settings.CertFile != "":
+		var err error
+		certPair, err = loadKeyPair(settings.CertFile, settings.KeyFile, settings.KeyPassword)
 		if err != nil {
 			return nil, err
 		}
+	}
+	if certPair != nil {
 		conf.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
 			printer.Printf("* Offering client cert:")
 			printer.Printf("*   subject: %s", certPair.Leaf.Subject.String())
 			printer.Printf("*   start date: %s", certPair.Leaf.NotBefore)
 			printer.Printf("*   end date: %s", certPair.Leaf.NotAfter)
 			printer.Printf("*   issuer: %s", certPair.Leaf.Issuer.String())
-			return &certPair, nil
+			return certPair, nil
 		}
 	}
 
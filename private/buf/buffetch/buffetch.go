@@ -24,6 +24,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/git"
 	"github.com/bufbuild/buf/private/pkg/httpauth"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"go.uber.org/zap"
@@ -382,6 +383,41 @@ func NewWriter(
 	)
 }
 
+// IsLocalSourceRef returns true if the reference is to a local directory or
+// file, rather than to an archive, git repository, or module, and so its files
+// can be rewritten in place.
+func IsLocalSourceRef(sourceOrModuleRef SourceOrModuleRef) bool {
+	switch ref := sourceOrModuleRef.(type) {
+	case ProtoFileRef:
+		return true
+	case SourceRef:
+		_, ok := ref.internalBucketRef().(internal.DirRef)
+		return ok
+	default:
+		return false
+	}
+}
+
+// GetLocalSourceRefDirPath returns the path of the directory of the local
+// source ref, which is the directory itself for directories, and the directory
+// that contains the file for proto files.
+//
+// Returns false if the ref is not a local source ref.
+func GetLocalSourceRefDirPath(sourceOrModuleRef SourceOrModuleRef) (string, bool) {
+	switch ref := sourceOrModuleRef.(type) {
+	case ProtoFileRef:
+		return normalpath.Unnormalize(normalpath.Dir(ref.internalProtoFileRef().Path())), true
+	case SourceRef:
+		dirRef, ok := ref.internalBucketRef().(internal.DirRef)
+		if !ok {
+			return "", false
+		}
+		return normalpath.Unnormalize(dirRef.Path()), true
+	default:
+		return "", false
+	}
+}
+
 type getSourceBucketOptions struct {
 	workspacesDisabled bool
 }
@@ -93,6 +93,28 @@ func TestRoundTripBinpbZst(t *testing.T) {
 	)
 }
 
+func TestRoundTripBinpbGzCompressionLevel(t *testing.T) {
+	t.Parallel()
+	testRoundTripLocalFile(
+		t,
+		"file.binpb.gz#compression_level=9",
+		[]byte("one"),
+		formatBinpb,
+		internal.CompressionTypeGzip,
+	)
+}
+
+func TestRoundTripBinpbZstCompressionLevel(t *testing.T) {
+	t.Parallel()
+	testRoundTripLocalFile(
+		t,
+		"file.binpb.zst#compression_level=19,zstd_long=true",
+		[]byte("one"),
+		formatBinpb,
+		internal.CompressionTypeZstd,
+	)
+}
+
 func testRoundTripLocalFile(
 	t *testing.T,
 	filename string,
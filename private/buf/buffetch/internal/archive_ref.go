@@ -26,8 +26,11 @@ type archiveRef struct {
 	fileScheme      FileScheme
 	archiveType     ArchiveType
 	compressionType CompressionType
-	stripComponents uint32
-	subDirPath      string
+	// compressionLevel and zstdWindowLog are only set by the ref parser.
+	compressionLevel int
+	zstdWindowLog    int
+	stripComponents  uint32
+	subDirPath       string
 }
 
 func newArchiveRef(
@@ -108,6 +111,14 @@ func (r *archiveRef) CompressionType() CompressionType {
 	return r.compressionType
 }
 
+func (r *archiveRef) CompressionLevel() int {
+	return r.compressionLevel
+}
+
+func (r *archiveRef) ZstdWindowLog() int {
+	return r.zstdWindowLog
+}
+
 func (r *archiveRef) StripComponents() uint32 {
 	return r.stripComponents
 }
@@ -73,6 +73,21 @@ func NewCannotSpecifyCompressionForZipError() error {
 	return errors.New("cannot specify compression type for zip files")
 }
 
+// NewCannotSpecifyCompressionLevelWithoutCompressionError is a fetch error.
+func NewCannotSpecifyCompressionLevelWithoutCompressionError() error {
+	return errors.New("cannot specify compression_level without compression")
+}
+
+// NewCompressionLevelOutOfRangeError is a fetch error.
+func NewCompressionLevelOutOfRangeError(compression string, compressionLevel int, minCompressionLevel int, maxCompressionLevel int) error {
+	return fmt.Errorf("compression_level %d is out of range for %s compression (valid values are %d to %d)", compressionLevel, compression, minCompressionLevel, maxCompressionLevel)
+}
+
+// NewCannotSpecifyZstdLongWithoutZstdError is a fetch error.
+func NewCannotSpecifyZstdLongWithoutZstdError() error {
+	return errors.New("cannot specify zstd_long without zstd compression")
+}
+
 // NewNoPathError is a fetch error.
 func NewNoPathError() error {
 	return errors.New("value has no path once processed")
@@ -106,6 +121,16 @@ func NewOptionsCouldNotParseRecurseSubmodulesError(s string) error {
 	return fmt.Errorf("could not parse recurse_submodules value %q", s)
 }
 
+// NewOptionsCouldNotParseCompressionLevelError is a fetch error.
+func NewOptionsCouldNotParseCompressionLevelError(s string) error {
+	return fmt.Errorf("could not parse compression_level value %q", s)
+}
+
+// NewOptionsCouldNotParseZstdLongError is a fetch error.
+func NewOptionsCouldNotParseZstdLongError(s string) error {
+	return fmt.Errorf("could not parse zstd_long value %q (valid values are true, false, or a window log from %d to %d)", s, minZstdWindowLog, maxZstdWindowLog)
+}
+
 // NewFormatOverrideNotAllowedForDevNullError is a fetch error.
 func NewFormatOverrideNotAllowedForDevNullError(devNull string) error {
 	return fmt.Errorf("not allowed if path is %s", devNull)
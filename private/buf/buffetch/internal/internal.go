@@ -100,6 +100,15 @@ type FileRef interface {
 	Path() string
 	FileScheme() FileScheme
 	CompressionType() CompressionType
+	// CompressionLevel is the level to compress with when writing.
+	//
+	// This will be zero for the default level of the CompressionType.
+	CompressionLevel() int
+	// ZstdWindowLog is the base 2 logarithm of the window size to compress with
+	// when writing with CompressionTypeZstd.
+	//
+	// This will be zero for the default window size of the CompressionLevel.
+	ZstdWindowLog() int
 	fileRef()
 }
 
@@ -508,6 +517,14 @@ type RawRef struct {
 	// Only set for single, archive formats
 	// Cannot be set for zip archives
 	CompressionType CompressionType
+	// Only set for single, archive formats
+	// Cannot be set for zip archives
+	// Zero means the default level for the compression type
+	CompressionLevel int
+	// Only set for single, archive formats
+	// Only valid with zstd compression
+	// Zero means the default window size for the compression level
+	ZstdWindowLog int
 	// Only set for archive, git formats
 	SubDirPath string
 	// Only set for git formats
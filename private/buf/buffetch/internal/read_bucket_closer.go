@@ -15,6 +15,8 @@
 package internal
 
 import (
+	"io"
+
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
 )
@@ -55,3 +57,22 @@ func (r *readBucketCloser) SubDirPath() string {
 func (r *readBucketCloser) SetSubDirPath(subDirPath string) {
 	r.subDirPath = subDirPath
 }
+
+// closerReadBucketCloser is a storage.ReadBucketCloser that closes the closer,
+// such as the bucket that the ReadBucket is mapped from, when it is closed.
+type closerReadBucketCloser struct {
+	storage.ReadBucket
+
+	closer io.Closer
+}
+
+func newCloserReadBucketCloser(readBucket storage.ReadBucket, closer io.Closer) *closerReadBucketCloser {
+	return &closerReadBucketCloser{
+		ReadBucket: readBucket,
+		closer:     closer,
+	}
+}
+
+func (c *closerReadBucketCloser) Close() error {
+	return c.closer.Close()
+}
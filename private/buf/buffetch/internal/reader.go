@@ -36,6 +36,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/storage/storagespill"
 	"github.com/klauspost/compress/zstd"
 	"github.com/klauspost/pgzip"
 	"go.opentelemetry.io/otel"
@@ -45,6 +46,15 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// maxArchiveMemorySize is the maximum size of the files of an archive, and of
+	// a zip archive itself, that are kept in memory. The rest are written to
+	// temporary files.
+	maxArchiveMemorySize = 256 << 20
+
+	tempZipFilePattern = "buf-zip-*"
+)
+
 type reader struct {
 	logger            *zap.Logger
 	storageosProvider storageos.Provider
@@ -207,12 +217,19 @@ func (r *reader) getArchiveBucket(
 	if err != nil {
 		return nil, err
 	}
-	readWriteBucket := storagemem.NewReadWriteBucket()
+	// The files of the archive are kept in memory up to maxArchiveMemorySize,
+	// and the rest are written to a temporary file, so that large archives do
+	// not need to fit in memory.
+	//
+	// The bucket is closed when the returned ReadBucketCloser is closed, which
+	// removes the temporary file.
+	readWriteBucket := storagespill.NewReadWriteBucketCloser(maxArchiveMemorySize)
 	ctx, span := r.tracer.Start(ctx, "unarchive")
 	defer span.End()
 	defer func() {
 		retErr = multierr.Append(retErr, readCloser.Close())
 		if retErr != nil {
+			retErr = multierr.Append(retErr, readWriteBucket.Close())
 			span.RecordError(retErr)
 			span.SetStatus(codes.Error, retErr.Error())
 		}
@@ -229,20 +246,13 @@ func (r *reader) getArchiveBucket(
 			return nil, err
 		}
 	case ArchiveTypeZip:
-		var readerAt io.ReaderAt
-		if size < 0 {
-			data, err := io.ReadAll(readCloser)
-			if err != nil {
-				return nil, err
-			}
-			readerAt = bytes.NewReader(data)
-			size = int64(len(data))
-		} else {
-			readerAt, err = ioext.ReaderAtForReader(readCloser)
-			if err != nil {
-				return nil, err
-			}
+		readerAt, size, closer, err := getZipReaderAtAndSize(readCloser, size, maxArchiveMemorySize)
+		if err != nil {
+			return nil, err
 		}
+		defer func() {
+			retErr = multierr.Append(retErr, closer.Close())
+		}()
 		if err := storagearchive.Unzip(
 			ctx,
 			readerAt,
@@ -272,7 +282,7 @@ func (r *reader) getArchiveBucket(
 			return nil, err
 		}
 		readBucketCloser, err := newReadBucketCloser(
-			storage.NopReadBucketCloser(storage.MapReadBucket(readWriteBucket, storage.MapOnPrefix(terminateFileDirectoryPath))),
+			newCloserReadBucketCloser(storage.MapReadBucket(readWriteBucket, storage.MapOnPrefix(terminateFileDirectoryPath)), readWriteBucket),
 			terminateFileDirectoryPath,
 			relativeSubDirPath,
 		)
@@ -289,7 +299,7 @@ func (r *reader) getArchiveBucket(
 		readBucket = storage.MapReadBucket(readWriteBucket, storage.MapOnPrefix(subDirPath))
 	}
 	readBucketCloser, err := newReadBucketCloser(
-		storage.NopReadBucketCloser(readBucket),
+		newCloserReadBucketCloser(readBucket, readWriteBucket),
 		"",
 		"",
 	)
@@ -682,6 +692,55 @@ func (r *reader) getFileReadCloserAndSizePotentiallyCompressedHTTP(
 	return response.Body, response.ContentLength, nil
 }
 
+// getZipReaderAtAndSize returns a ReaderAt for the zip archive of the reader,
+// which is required to read the central directory at the end of the archive,
+// and the size of the archive.
+//
+// If the reader is a ReaderAt of a known size, such as a local file, it is
+// returned. Otherwise, the archive is read into memory if it is at most
+// maxMemorySize, and is written to a temporary file if it is larger. The
+// returned Closer removes the temporary file.
+func getZipReaderAtAndSize(
+	reader io.Reader,
+	size int64,
+	maxMemorySize int64,
+) (_ io.ReaderAt, _ int64, _ io.Closer, retErr error) {
+	if readerAt, ok := reader.(io.ReaderAt); ok && size >= 0 {
+		return readerAt, size, ioext.NopCloser, nil
+	}
+	buffer := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buffer, io.LimitReader(reader, maxMemorySize+1)); err != nil {
+		return nil, -1, nil, err
+	}
+	if int64(buffer.Len()) <= maxMemorySize {
+		return bytes.NewReader(buffer.Bytes()), int64(buffer.Len()), ioext.NopCloser, nil
+	}
+	file, err := os.CreateTemp("", tempZipFilePattern)
+	if err != nil {
+		return nil, -1, nil, err
+	}
+	closer := tempFileCloser{file: file}
+	defer func() {
+		if retErr != nil {
+			retErr = multierr.Append(retErr, closer.Close())
+		}
+	}()
+	size, err = io.Copy(file, io.MultiReader(buffer, reader))
+	if err != nil {
+		return nil, -1, nil, err
+	}
+	return file, size, closer, nil
+}
+
+// tempFileCloser closes and removes a temporary file.
+type tempFileCloser struct {
+	file *os.File
+}
+
+func (t tempFileCloser) Close() error {
+	return multierr.Append(t.file.Close(), os.Remove(t.file.Name()))
+}
+
 func getGitURL(gitRef GitRef) (string, error) {
 	switch gitScheme := gitRef.GitScheme(); gitScheme {
 	case GitSchemeHTTP:
@@ -0,0 +1,67 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetZipReaderAtAndSize(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	pathToContent := map[string]string{
+		"a.proto": "syntax = \"proto3\";\n",
+		"b.proto": "syntax = \"proto3\";\n",
+	}
+	pathToData := make(map[string][]byte, len(pathToContent))
+	for path, content := range pathToContent {
+		pathToData[path] = []byte(content)
+	}
+	readBucket, err := storagemem.NewReadBucket(pathToData)
+	require.NoError(t, err)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, storagearchive.Zip(ctx, readBucket, buffer, true))
+	zipData := buffer.Bytes()
+	for _, maxMemorySize := range []int64{0, int64(len(zipData)) - 1, int64(len(zipData))} {
+		// The reader is not a ReaderAt, and the size is unknown.
+		readerAt, size, closer, err := getZipReaderAtAndSize(io.MultiReader(bytes.NewReader(zipData)), -1, maxMemorySize)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(zipData)), size)
+		file, isFile := readerAt.(*os.File)
+		assert.Equal(t, maxMemorySize < int64(len(zipData)), isFile)
+		readWriteBucket := storagemem.NewReadWriteBucket()
+		require.NoError(t, storagearchive.Unzip(ctx, readerAt, size, readWriteBucket, nil, 0))
+		for path, content := range pathToContent {
+			data, err := storage.ReadPath(ctx, readWriteBucket, path)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(data))
+		}
+		require.NoError(t, closer.Close())
+		if isFile {
+			_, err := os.Stat(file.Name())
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		}
+	}
+}
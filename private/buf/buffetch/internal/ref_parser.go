@@ -26,6 +26,21 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// These match the levels accepted by the gzip and zstd command line tools.
+	minGzipCompressionLevel = 1
+	maxGzipCompressionLevel = 9
+	minZstdCompressionLevel = 1
+	maxZstdCompressionLevel = 22
+
+	// defaultZstdLongWindowLog is the window log used for zstd_long=true, which
+	// matches the default of "zstd --long".
+	defaultZstdLongWindowLog = 27
+	// These are the window logs supported by both the encoder and the decoder.
+	minZstdWindowLog = 10
+	maxZstdWindowLog = 29
+)
+
 type refParser struct {
 	logger                *zap.Logger
 	rawRefProcessor       func(*RawRef) error
@@ -154,6 +169,24 @@ func (a *refParser) getRawRef(value string) (*RawRef, error) {
 			default:
 				return nil, NewCompressionUnknownError(value)
 			}
+		case "compression_level":
+			compressionLevel, err := strconv.ParseUint(value, 10, 8)
+			if err != nil || compressionLevel == 0 {
+				return nil, NewOptionsCouldNotParseCompressionLevelError(value)
+			}
+			rawRef.CompressionLevel = int(compressionLevel)
+		case "zstd_long":
+			switch value {
+			case "true":
+				rawRef.ZstdWindowLog = defaultZstdLongWindowLog
+			case "false":
+			default:
+				zstdWindowLog, err := strconv.ParseUint(value, 10, 8)
+				if err != nil || zstdWindowLog < minZstdWindowLog || zstdWindowLog > maxZstdWindowLog {
+					return nil, NewOptionsCouldNotParseZstdLongError(value)
+				}
+				rawRef.ZstdWindowLog = int(zstdWindowLog)
+			}
 		case "branch":
 			if rawRef.GitBranch != "" || rawRef.GitTag != "" {
 				return nil, NewCannotSpecifyGitBranchAndTagError()
@@ -245,12 +278,12 @@ func (a *refParser) getRawRef(value string) (*RawRef, error) {
 			return nil, NewOptionsInvalidForFormatError(rawRef.Format, value)
 		}
 	} else {
-		if archiveFormatInfo.archiveType == ArchiveTypeZip && rawRef.CompressionType != 0 {
+		if archiveFormatInfo.archiveType == ArchiveTypeZip && (rawRef.CompressionType != 0 || rawRef.CompressionLevel != 0 || rawRef.ZstdWindowLog != 0) {
 			return nil, NewCannotSpecifyCompressionForZipError()
 		}
 	}
 	if !singleOK && !archiveOK {
-		if rawRef.CompressionType != 0 {
+		if rawRef.CompressionType != 0 || rawRef.CompressionLevel != 0 || rawRef.ZstdWindowLog != 0 {
 			return nil, NewOptionsInvalidForFormatError(rawRef.Format, value)
 		}
 	}
@@ -322,12 +355,21 @@ func getSingleRef(
 	if len(invalidKeys) > 0 {
 		return nil, NewOptionsInvalidKeysError(invalidKeys...)
 	}
-	return newSingleRef(
+	if err := validateCompressionOptions(compressionType, rawRef.CompressionLevel, rawRef.ZstdWindowLog); err != nil {
+		return nil, err
+	}
+	singleRef, err := newSingleRef(
 		rawRef.Format,
 		rawRef.Path,
 		compressionType,
 		rawRef.UnrecognizedOptions,
 	)
+	if err != nil {
+		return nil, err
+	}
+	singleRef.compressionLevel = rawRef.CompressionLevel
+	singleRef.zstdWindowLog = rawRef.ZstdWindowLog
+	return singleRef, nil
 }
 
 func getArchiveRef(
@@ -339,7 +381,10 @@ func getArchiveRef(
 	if compressionType == 0 {
 		compressionType = defaultCompressionType
 	}
-	return newArchiveRef(
+	if err := validateCompressionOptions(compressionType, rawRef.CompressionLevel, rawRef.ZstdWindowLog); err != nil {
+		return nil, err
+	}
+	archiveRef, err := newArchiveRef(
 		rawRef.Format,
 		rawRef.Path,
 		archiveType,
@@ -347,6 +392,40 @@ func getArchiveRef(
 		rawRef.ArchiveStripComponents,
 		rawRef.SubDirPath,
 	)
+	if err != nil {
+		return nil, err
+	}
+	archiveRef.compressionLevel = rawRef.CompressionLevel
+	archiveRef.zstdWindowLog = rawRef.ZstdWindowLog
+	return archiveRef, nil
+}
+
+// validateCompressionOptions validates the compression_level and zstd_long
+// options against the compression type once the default has been applied.
+func validateCompressionOptions(
+	compressionType CompressionType,
+	compressionLevel int,
+	zstdWindowLog int,
+) error {
+	if zstdWindowLog != 0 && compressionType != CompressionTypeZstd {
+		return NewCannotSpecifyZstdLongWithoutZstdError()
+	}
+	if compressionLevel == 0 {
+		return nil
+	}
+	switch compressionType {
+	case CompressionTypeGzip:
+		if compressionLevel > maxGzipCompressionLevel {
+			return NewCompressionLevelOutOfRangeError("gzip", compressionLevel, minGzipCompressionLevel, maxGzipCompressionLevel)
+		}
+	case CompressionTypeZstd:
+		if compressionLevel > maxZstdCompressionLevel {
+			return NewCompressionLevelOutOfRangeError("zstd", compressionLevel, minZstdCompressionLevel, maxZstdCompressionLevel)
+		}
+	default:
+		return NewCannotSpecifyCompressionLevelWithoutCompressionError()
+	}
+	return nil
 }
 
 func getDirRef(
@@ -36,7 +36,10 @@ type singleRef struct {
 	path            string
 	fileScheme      FileScheme
 	compressionType CompressionType
-	customOptions   map[string]string
+	// compressionLevel and zstdWindowLog are only set by the ref parser.
+	compressionLevel int
+	zstdWindowLog    int
+	customOptions    map[string]string
 }
 
 func newSingleRef(
@@ -152,6 +155,14 @@ func (r *singleRef) CompressionType() CompressionType {
 	return r.compressionType
 }
 
+func (r *singleRef) CompressionLevel() int {
+	return r.compressionLevel
+}
+
+func (r *singleRef) ZstdWindowLog() int {
+	return r.zstdWindowLog
+}
+
 func (r *singleRef) CustomOptionValue(key string) (string, bool) {
 	value, ok := r.customOptions[key]
 	return value, ok
@@ -121,7 +121,14 @@ func (w *writer) putFileWriteCloser(
 	case CompressionTypeNone:
 		return writeCloser, nil
 	case CompressionTypeGzip:
-		gzipWriteCloser := gzip.NewWriter(writeCloser)
+		compressionLevel := gzip.DefaultCompression
+		if fileRef.CompressionLevel() != 0 {
+			compressionLevel = fileRef.CompressionLevel()
+		}
+		gzipWriteCloser, err := gzip.NewWriterLevel(writeCloser, compressionLevel)
+		if err != nil {
+			return nil, err
+		}
 		return ioext.CompositeWriteCloser(
 			gzipWriteCloser,
 			ioext.ChainCloser(
@@ -130,7 +137,20 @@ func (w *writer) putFileWriteCloser(
 			),
 		), nil
 	case CompressionTypeZstd:
-		zstdWriteCloser, err := zstd.NewWriter(writeCloser)
+		var zstdOptions []zstd.EOption
+		if fileRef.CompressionLevel() != 0 {
+			zstdOptions = append(
+				zstdOptions,
+				zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(fileRef.CompressionLevel())),
+			)
+		}
+		if fileRef.ZstdWindowLog() != 0 {
+			zstdOptions = append(
+				zstdOptions,
+				zstd.WithWindowSize(1<<fileRef.ZstdWindowLog()),
+			)
+		}
+		zstdWriteCloser, err := zstd.NewWriter(writeCloser, zstdOptions...)
 		if err != nil {
 			return nil, err
 		}
@@ -1287,6 +1287,51 @@ func TestGetParsedRefError(t *testing.T) {
 		internal.NewCannotSpecifyCompressionForZipError(),
 		"path/to/foo#format=zip,compression=gzip",
 	)
+	testGetParsedRefError(
+		t,
+		internal.NewCannotSpecifyCompressionForZipError(),
+		"path/to/foo.zip#compression_level=9",
+	)
+	testGetParsedRefError(
+		t,
+		internal.NewOptionsInvalidForFormatError(formatDir, "path/to/some/foo#compression_level=9"),
+		"path/to/some/foo#compression_level=9",
+	)
+	testGetParsedRefError(
+		t,
+		internal.NewOptionsCouldNotParseCompressionLevelError("0"),
+		"path/to/foo.binpb.gz#compression_level=0",
+	)
+	testGetParsedRefError(
+		t,
+		internal.NewOptionsCouldNotParseCompressionLevelError("foo"),
+		"path/to/foo.binpb.gz#compression_level=foo",
+	)
+	testGetParsedRefError(
+		t,
+		internal.NewCompressionLevelOutOfRangeError("gzip", 10, 1, 9),
+		"path/to/foo.binpb.gz#compression_level=10",
+	)
+	testGetParsedRefError(
+		t,
+		internal.NewCompressionLevelOutOfRangeError("zstd", 23, 1, 22),
+		"path/to/foo.tar.zst#compression_level=23",
+	)
+	testGetParsedRefError(
+		t,
+		internal.NewCannotSpecifyCompressionLevelWithoutCompressionError(),
+		"path/to/foo.binpb#compression_level=9",
+	)
+	testGetParsedRefError(
+		t,
+		internal.NewOptionsCouldNotParseZstdLongError("31"),
+		"path/to/foo.binpb.zst#zstd_long=31",
+	)
+	testGetParsedRefError(
+		t,
+		internal.NewCannotSpecifyZstdLongWithoutZstdError(),
+		"path/to/foo.tar.gz#zstd_long=true",
+	)
 }
 
 func testGetParsedRefSuccess(
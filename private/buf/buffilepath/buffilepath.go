@@ -0,0 +1,43 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buffilepath resolves the files of images to paths on disk, so that
+// the files can be opened by editors and other tools.
+package buffilepath
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+)
+
+// Resolver resolves the files of images to paths on disk.
+type Resolver interface {
+	// GetFilePath returns the path on disk of the file.
+	//
+	// Files of dependency modules that are read from the module cache and the
+	// Well-Known Types are not on disk, so they are written to the dependency
+	// directory of the Resolver, in a directory for each module commit. Other
+	// files are on disk at their external path.
+	GetFilePath(ctx context.Context, imageFile bufimage.ImageFile) (string, error)
+}
+
+// NewResolver returns a new Resolver.
+//
+// The moduleReader reads the files of dependency modules, which are written to
+// the directory at dependencyDirPath.
+func NewResolver(moduleReader bufmodule.ModuleReader, dependencyDirPath string) Resolver {
+	return newResolver(moduleReader, dependencyDirPath)
+}
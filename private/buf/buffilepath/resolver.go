@@ -0,0 +1,134 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffilepath
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/data/datawkt"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/multierr"
+)
+
+// wktDirName is the directory within the dependency directory that the
+// Well-Known Types are written to.
+const wktDirName = "wkt"
+
+type resolver struct {
+	moduleReader      bufmodule.ModuleReader
+	dependencyDirPath string
+	// lock guards the writes of dependency files.
+	lock sync.Mutex
+}
+
+func newResolver(moduleReader bufmodule.ModuleReader, dependencyDirPath string) *resolver {
+	return &resolver{
+		moduleReader:      moduleReader,
+		dependencyDirPath: dependencyDirPath,
+	}
+}
+
+func (r *resolver) GetFilePath(ctx context.Context, imageFile bufimage.ImageFile) (string, error) {
+	if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil && imageFile.Commit() != "" {
+		filePath := filepath.Join(
+			r.dependencyDirPath,
+			moduleIdentity.Remote(),
+			moduleIdentity.Owner(),
+			moduleIdentity.Repository(),
+			imageFile.Commit(),
+			filepath.FromSlash(imageFile.Path()),
+		)
+		return filePath, r.writeDependencyFile(filePath, func() ([]byte, error) {
+			return r.readModuleFile(ctx, moduleIdentity, imageFile.Commit(), imageFile.Path())
+		})
+	}
+	// The Well-Known Types are provided by the compiler if no module provides
+	// them, in which case they have no module, and their external path is their path.
+	if imageFile.IsImport() &&
+		imageFile.ModuleIdentity() == nil &&
+		imageFile.ExternalPath() == imageFile.Path() &&
+		datawkt.Exists(imageFile.Path()) {
+		filePath := filepath.Join(
+			r.dependencyDirPath,
+			wktDirName,
+			filepath.FromSlash(imageFile.Path()),
+		)
+		return filePath, r.writeDependencyFile(filePath, func() ([]byte, error) {
+			return storage.ReadPath(ctx, datawkt.ReadBucket, imageFile.Path())
+		})
+	}
+	return imageFile.ExternalPath(), nil
+}
+
+func (r *resolver) readModuleFile(
+	ctx context.Context,
+	moduleIdentity bufmoduleref.ModuleIdentity,
+	commit string,
+	path string,
+) (_ []byte, retErr error) {
+	modulePin, err := bufmoduleref.NewModulePin(
+		moduleIdentity.Remote(),
+		moduleIdentity.Owner(),
+		moduleIdentity.Repository(),
+		commit,
+		"", // the digest is not needed to read the module
+	)
+	if err != nil {
+		return nil, err
+	}
+	module, err := r.moduleReader.GetModule(ctx, modulePin)
+	if err != nil {
+		return nil, err
+	}
+	moduleFile, err := module.GetModuleFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, moduleFile.Close())
+	}()
+	return io.ReadAll(moduleFile)
+}
+
+// writeDependencyFile writes the file at the path with the data from getData, if
+// the file does not already exist.
+//
+// Dependency files are read-only, as editing them has no effect.
+func (r *resolver) writeDependencyFile(filePath string, getData func() ([]byte, error)) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, err := os.Stat(filePath); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	data, err := getData()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0444)
+}
@@ -0,0 +1,194 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufformat
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bufbuild/protocompile/ast"
+)
+
+// fieldAlignment is the padding written within a field or enum value so that
+// it is aligned with the other fields or enum values in its run.
+type fieldAlignment struct {
+	// The padding written before the '='.
+	namePadding int
+	// The padding written before the '[' of the compact options, if any.
+	numberPadding int
+	// The width of the widest name and number of the run once aligned, e.g.
+	// 'repeated string labels = 10'. See fitAlignment.
+	width int
+}
+
+// alignedElement is a field or enum value that can be aligned.
+type alignedElement struct {
+	node ast.Node
+	// The width of everything before the '=', e.g. 'repeated string name'.
+	nameWidth int
+	// The width of the field number, e.g. '10'.
+	numberWidth int
+	hasOptions  bool
+}
+
+// computeAlignments computes the alignments of all of the fields and enum
+// values in the file.
+//
+// Consecutive fields and enum values in the same block form a run, which is
+// aligned to its widest name and number. A run is ended by a blank line, by
+// any other element, and by an element with comments between its tokens,
+// which is not aligned.
+func (f *formatter) computeAlignments() map[ast.Node]fieldAlignment {
+	alignments := make(map[ast.Node]fieldAlignment)
+	_ = ast.Walk(
+		f.fileNode,
+		ast.NoOpVisitor{},
+		ast.WithBefore(func(node ast.Node) error {
+			compositeNode, ok := node.(ast.CompositeNode)
+			if !ok {
+				return nil
+			}
+			var run []alignedElement
+			for _, child := range compositeNode.Children() {
+				element, ok := f.alignedElement(child)
+				if !ok || (len(run) > 0 && f.leadingCommentsContainBlankLine(child)) {
+					alignRun(run, alignments)
+					run = nil
+				}
+				if ok {
+					run = append(run, element)
+				}
+			}
+			alignRun(run, alignments)
+			return nil
+		}),
+	)
+	return alignments
+}
+
+// alignedElement returns the alignedElement for the given node, and false if
+// the node cannot be aligned.
+func (f *formatter) alignedElement(node ast.Node) (alignedElement, bool) {
+	var (
+		tokens      []ast.Node
+		name        string
+		number      ast.Node
+		optionsNode *ast.CompactOptionsNode
+		endNode     ast.Node
+	)
+	switch node := node.(type) {
+	case *ast.FieldNode:
+		if node.Label.KeywordNode != nil {
+			tokens = append(tokens, node.Label.KeywordNode)
+			name = node.Label.KeywordNode.Val + " "
+		}
+		tokens = append(tokens, node.FldType, node.Name, node.Equals, node.Tag)
+		name += string(node.FldType.AsIdentifier()) + " " + node.Name.Val
+		number, optionsNode, endNode = node.Tag, node.Options, node.Semicolon
+	case *ast.MapFieldNode:
+		mapType := node.MapType
+		tokens = append(tokens, mapType.Keyword, mapType.OpenAngle, mapType.KeyType, mapType.Comma, mapType.ValueType, mapType.CloseAngle, node.Name, node.Equals, node.Tag)
+		name = mapType.Keyword.Val + "<" + mapType.KeyType.Val + ", " + string(mapType.ValueType.AsIdentifier()) + "> " + node.Name.Val
+		number, optionsNode, endNode = node.Tag, node.Options, node.Semicolon
+	case *ast.EnumValueNode:
+		tokens = append(tokens, node.Name, node.Equals, node.Number)
+		name = node.Name.Val
+		number, optionsNode, endNode = node.Number, node.Options, node.Semicolon
+	default:
+		return alignedElement{}, false
+	}
+	if optionsNode != nil {
+		endNode = optionsNode.OpenBracket
+	}
+	if f.hasInteriorComments(append(tokens, endNode)...) {
+		return alignedElement{}, false
+	}
+	return alignedElement{
+		node:        node,
+		nameWidth:   utf8.RuneCountInString(name),
+		numberWidth: utf8.RuneCountInString(f.rawText(number)),
+		hasOptions:  optionsNode != nil,
+	}, true
+}
+
+// rawText returns the text of the given number, as it is written.
+func (f *formatter) rawText(number ast.Node) string {
+	if negativeIntLiteralNode, ok := number.(*ast.NegativeIntLiteralNode); ok {
+		return "-" + f.fileNode.NodeInfo(negativeIntLiteralNode.Uint).RawText()
+	}
+	return f.fileNode.NodeInfo(number).RawText()
+}
+
+// writePadding writes the given number of spaces.
+func (f *formatter) writePadding(padding int) {
+	if padding > 0 {
+		f.WriteString(strings.Repeat(" ", padding))
+	}
+}
+
+// alignRun adds the alignments of the given run of elements to alignments.
+func alignRun(run []alignedElement, alignments map[ast.Node]fieldAlignment) {
+	var maxNameWidth, maxNumberWidth, maxOptionsNumberWidth int
+	for _, element := range run {
+		if element.nameWidth > maxNameWidth {
+			maxNameWidth = element.nameWidth
+		}
+		if element.numberWidth > maxNumberWidth {
+			maxNumberWidth = element.numberWidth
+		}
+		// Only the numbers that are followed by compact options affect
+		// the alignment of the '['.
+		if element.hasOptions && element.numberWidth > maxOptionsNumberWidth {
+			maxOptionsNumberWidth = element.numberWidth
+		}
+	}
+	for _, element := range run {
+		alignment := fieldAlignment{
+			namePadding: maxNameWidth - element.nameWidth,
+			width:       maxNameWidth + len(" = ") + maxNumberWidth,
+		}
+		if element.hasOptions {
+			alignment.numberPadding = maxOptionsNumberWidth - element.numberWidth
+		}
+		alignments[element.node] = alignment
+	}
+}
+
+// fitAlignment returns the given alignment of a field or enum value, or no
+// alignment if the aligned names and numbers of its run would not fit within
+// the maximum line width.
+//
+// Every element of a run is in the same block, and so has the same
+// indentation, so either all or none of the elements of a run are aligned.
+// Otherwise the padding could make a short field, such as 'string name = 1',
+// wrap onto two lines.
+func (f *formatter) fitAlignment(alignment fieldAlignment) fieldAlignment {
+	if f.maxLineWidth <= 0 {
+		return alignment
+	}
+	var indentWidth int
+	for _, r := range f.indentString {
+		if r == '\t' {
+			indentWidth += f.tabWidth
+			continue
+		}
+		indentWidth++
+	}
+	// The '+1' leaves room for the ';' or the '[' of compact options.
+	if f.indent*indentWidth+alignment.width+1 > f.maxLineWidth {
+		return fieldAlignment{}
+	}
+	return alignment
+}
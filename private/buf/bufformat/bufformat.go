@@ -15,6 +15,7 @@
 package bufformat
 
 import (
+	"bytes"
 	"context"
 	"io"
 
@@ -29,11 +30,24 @@ import (
 )
 
 // FormatModule formats and writes the target module files into a read bucket.
-func FormatModule(ctx context.Context, module bufmodule.Module) (_ storage.ReadBucket, retErr error) {
+func FormatModule(ctx context.Context, module bufmodule.Module, options ...FormatOption) (_ storage.ReadBucket, retErr error) {
 	fileInfos, err := module.TargetFileInfos(ctx)
 	if err != nil {
 		return nil, err
 	}
+	sourceFileInfos, err := module.SourceFileInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modulePaths := make(map[string]struct{}, len(sourceFileInfos))
+	for _, sourceFileInfo := range sourceFileInfos {
+		modulePaths[sourceFileInfo.Path()] = struct{}{}
+	}
+	formatOptions := newFormatOptions()
+	for _, option := range options {
+		option(formatOptions)
+	}
+	options = append(options, formatWithModulePaths(modulePaths))
 	readWriteBucket := storagemem.NewReadWriteBucket()
 	jobs := make([]func(context.Context) error, len(fileInfos))
 	for i, fileInfo := range fileInfos {
@@ -50,6 +64,15 @@ func FormatModule(ctx context.Context, module bufmodule.Module) (_ storage.ReadB
 			if err != nil {
 				return err
 			}
+			fileOptions := options
+			if formatOptions.fileOptions != nil {
+				externalPathOptions, err := formatOptions.fileOptions(moduleFile.ExternalPath())
+				if err != nil {
+					return err
+				}
+				// The options given to FormatModule take precedence.
+				fileOptions = append(externalPathOptions, options...)
+			}
 			writeObjectCloser, err := readWriteBucket.Put(ctx, moduleFile.Path())
 			if err != nil {
 				return err
@@ -57,7 +80,7 @@ func FormatModule(ctx context.Context, module bufmodule.Module) (_ storage.ReadB
 			defer func() {
 				retErr = multierr.Append(retErr, writeObjectCloser.Close())
 			}()
-			if err := FormatFileNode(writeObjectCloser, fileNode); err != nil {
+			if err := FormatFileNode(writeObjectCloser, fileNode, fileOptions...); err != nil {
 				return err
 			}
 			return writeObjectCloser.SetExternalPath(moduleFile.ExternalPath())
@@ -70,7 +93,266 @@ func FormatModule(ctx context.Context, module bufmodule.Module) (_ storage.ReadB
 }
 
 // FormatFileNode formats the given file node and writ the result to dest.
-func FormatFileNode(dest io.Writer, fileNode *ast.FileNode) error {
-	formatter := newFormatter(dest, fileNode)
-	return formatter.Run()
+func FormatFileNode(dest io.Writer, fileNode *ast.FileNode, options ...FormatOption) error {
+	formatOptions := newFormatOptions()
+	for _, option := range options {
+		option(formatOptions)
+	}
+	if formatOptions.lineEnding == "\n" && formatOptions.finalNewline {
+		return newFormatter(dest, fileNode, formatOptions).Run()
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := newFormatter(buffer, fileNode, formatOptions).Run(); err != nil {
+		return err
+	}
+	data := buffer.Bytes()
+	if !formatOptions.finalNewline {
+		data = bytes.TrimRight(data, "\n")
+	}
+	if formatOptions.lineEnding != "\n" {
+		// Comments are written as they are in the original file, so they
+		// may already have "\r\n" line endings.
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		data = bytes.ReplaceAll(data, []byte("\n"), []byte(formatOptions.lineEnding))
+	}
+	_, err := dest.Write(data)
+	return err
+}
+
+// FormatOption is an option for formatting.
+type FormatOption func(*formatOptions)
+
+// FormatWithIndent returns a new FormatOption that sets the number of spaces
+// in each level of indentation.
+//
+// The default is 2. If tabs are used, this is the width of a tab when lines
+// are measured against the maximum line width.
+func FormatWithIndent(indent int) FormatOption {
+	return func(formatOptions *formatOptions) {
+		if indent > 0 {
+			formatOptions.indent = indent
+		}
+	}
+}
+
+// FormatWithTabs returns a new FormatOption that indents with a tab for each
+// level of indentation instead of spaces.
+func FormatWithTabs() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.useTabs = true
+	}
+}
+
+// FormatWithSpaces returns a new FormatOption that indents with spaces
+// instead of tabs.
+//
+// This is the default, and can be used to override a previous FormatWithTabs,
+// such as one from the options of a file given by FormatWithFileOptions.
+func FormatWithSpaces() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.useTabs = false
+	}
+}
+
+// FormatWithMaxLineWidth returns a new FormatOption that sets the maximum
+// width of a line.
+//
+// Field definitions, compact options and option values that would otherwise
+// be written on a single line are written across multiple lines if they would
+// exceed the maximum width. A field definition is wrapped by writing its name
+// and number on the line after its type. Lines that cannot be wrapped, such as a long
+// string literal, may still exceed it.
+//
+// The default is to not have a maximum width.
+func FormatWithMaxLineWidth(maxLineWidth int) FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.maxLineWidth = maxLineWidth
+	}
+}
+
+// FormatWithImportGroups returns a new FormatOption that groups the imports
+// of each file, with a blank line between each group. The groups are, in order:
+//
+//   - Imports from google/, such as the well-known types.
+//   - Imports from other modules, such as dependencies.
+//   - Imports from the same module.
+//
+// When FormatFileNode is used, the module is not known, so there are only two
+// groups. Imports are always sorted and deduplicated within each group.
+//
+// The default is to write all imports as a single group.
+func FormatWithImportGroups() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.importGroups = true
+	}
+}
+
+// FormatWithAlignedFields returns a new FormatOption that vertically aligns
+// consecutive fields and enum values within a block. The '=' signs, and thus
+// the field numbers, are aligned, as are the '[' of compact options that follow
+// the field numbers.
+//
+// For example,
+//
+//	string name                  = 1;
+//	repeated string labels       = 2;
+//	map<string, string> metadata = 10 [deprecated = true];
+//	int64 size                   = 11 [json_name = "bytes"];
+//
+// The alignment is reset by a blank line, and by any other element, such as an
+// option or a nested message. With FormatWithMaxLineWidth, consecutive fields
+// and enum values whose aligned names and numbers would not fit on a line are
+// not aligned.
+//
+// The default is to not align fields.
+func FormatWithAlignedFields() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.alignFields = true
+	}
+}
+
+// FormatWithCanonicalOrder returns a new FormatOption that writes the types of
+// each file in a canonical order, which is the order of the fields of a
+// FileDescriptorProto: messages, then enums, then services, then extensions.
+// Types of the same kind are written in their original order.
+//
+// The syntax, package, imports, and options of a file are always written
+// first, in that order.
+//
+// The default is to write types in their original order.
+func FormatWithCanonicalOrder() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.canonicalOrder = true
+	}
+}
+
+// FormatWithSortedEnumValues returns a new FormatOption that sorts the values
+// of each enum by number. The first value is always kept first, as it is the
+// default value of the enum, and values with the same number, such as aliases,
+// are written in their original order. The other elements of the enum, such as
+// options and reserved ranges, are written before the values, in their
+// original order.
+//
+// The default is to write enum values in their original order.
+func FormatWithSortedEnumValues() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.sortEnumValues = true
+	}
+}
+
+// FormatWithLineDocComments returns a new FormatOption that writes '/* */'
+// doc comments, which are the comments on their own lines before an element,
+// as '//' comments. For example,
+//
+//	/*
+//	 * Foo is a message.
+//	 */
+//
+// is written as
+//
+//	// Foo is a message.
+//
+// Comments that contain a directive, such as 'buf:lint:ignore', are not
+// converted.
+//
+// The default is to write comments in their original style.
+func FormatWithLineDocComments() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.lineDocComments = true
+	}
+}
+
+// FormatWithCommentSpace returns a new FormatOption that writes a space after
+// the '//' of comments, so that '//Comment.' is written as '// Comment.'.
+//
+// Directives, such as '//buf:lint:ignore', and comments that start with more
+// than two '/' are not changed.
+func FormatWithCommentSpace() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.commentSpace = true
+	}
+}
+
+// FormatWithReflowedComments returns a new FormatOption that reflows the
+// paragraphs of '//' doc comments that have a line that is longer than the
+// maximum line width, so that they fit within it.
+//
+// List items, indented lines such as code examples, and directives such as
+// 'buf:lint:ignore' are not reflowed. This has no effect without
+// FormatWithMaxLineWidth.
+func FormatWithReflowedComments() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.reflowComments = true
+	}
+}
+
+// FormatWithLineEnding returns a new FormatOption that sets the line ending,
+// which must be one of "\n", "\r\n", or "\r". Others are ignored.
+//
+// The default is "\n".
+func FormatWithLineEnding(lineEnding string) FormatOption {
+	return func(formatOptions *formatOptions) {
+		switch lineEnding {
+		case "\n", "\r\n", "\r":
+			formatOptions.lineEnding = lineEnding
+		}
+	}
+}
+
+// FormatWithoutFinalNewline returns a new FormatOption that does not end
+// files with a newline.
+//
+// The default is to end files with a newline.
+func FormatWithoutFinalNewline() FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.finalNewline = false
+	}
+}
+
+// FormatWithFileOptions returns a new FormatOption that sets a function to get
+// additional options for each file that FormatModule formats, given the
+// external path of the file. For example, this can be used to apply the
+// settings of the editor configuration of each file.
+//
+// The other options given to FormatModule take precedence over the
+// additional options.
+//
+// This option is ignored by FormatFileNode.
+func FormatWithFileOptions(fileOptions func(externalPath string) ([]FormatOption, error)) FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.fileOptions = fileOptions
+	}
+}
+
+// formatWithModulePaths returns a new FormatOption that sets the paths of the
+// files in the module that contains the formatted files.
+func formatWithModulePaths(modulePaths map[string]struct{}) FormatOption {
+	return func(formatOptions *formatOptions) {
+		formatOptions.modulePaths = modulePaths
+	}
+}
+
+type formatOptions struct {
+	indent          int
+	useTabs         bool
+	maxLineWidth    int
+	importGroups    bool
+	modulePaths     map[string]struct{}
+	alignFields     bool
+	canonicalOrder  bool
+	sortEnumValues  bool
+	lineDocComments bool
+	commentSpace    bool
+	reflowComments  bool
+	lineEnding      string
+	finalNewline    bool
+	fileOptions     func(string) ([]FormatOption, error)
+}
+
+func newFormatOptions() *formatOptions {
+	return &formatOptions{
+		indent:       2,
+		lineEnding:   "\n",
+		finalNewline: true,
+	}
 }
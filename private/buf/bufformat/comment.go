@@ -0,0 +1,226 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufformat
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// directiveCommentRegexp matches the text of comments that are directives
+	// to tools, such as 'buf:lint:ignore FIELD_LOWER_SNAKE_CASE', which are
+	// never normalized.
+	directiveCommentRegexp = regexp.MustCompile(`^\s*[A-Za-z][A-Za-z0-9_.-]*:\S`)
+	// listItemCommentRegexp matches the text of comments that start list
+	// items, such as '- item' or '1. item', which are not reflowed.
+	listItemCommentRegexp = regexp.MustCompile(`^([-*+]|[0-9]+[.)])\s`)
+)
+
+// writeLineComments writes the given '//' comments, each on its own line.
+//
+// If reflowComments is true, the paragraphs of the comments that have a line
+// that is longer than the maximum line width are reflowed to fit within it.
+func (f *formatter) writeLineComments(comments []string) {
+	if len(comments) == 0 {
+		return
+	}
+	f.Indent(nil)
+	if f.reflowComments && f.maxLineWidth > 0 {
+		comments = reflowLineComments(comments, f.maxLineWidth-f.column)
+	}
+	for i, comment := range comments {
+		if i > 0 {
+			f.Indent(nil)
+		}
+		f.WriteString(f.normalizeLineComment(comment))
+		f.WriteString("\n")
+	}
+}
+
+// normalizeLineComment returns the given comment with a space after the '//',
+// if commentSpace is true and it is a '//' comment that is not a directive.
+func (f *formatter) normalizeLineComment(comment string) string {
+	comment = strings.TrimSpace(comment)
+	if !f.commentSpace || !strings.HasPrefix(comment, "//") {
+		return comment
+	}
+	text := strings.TrimPrefix(comment, "//")
+	if text == "" || strings.HasPrefix(text, "/") || isDirectiveComment(text) {
+		// Comments such as '////////' are left as they are.
+		return comment
+	}
+	if r, _ := utf8.DecodeRuneInString(text); r == ' ' || r == '\t' {
+		return comment
+	}
+	return "// " + text
+}
+
+// blockCommentToLineComments converts the given '/* */' comment to '//'
+// comments, one for each line of its text. It returns false if the comment
+// cannot be converted, such as if it is a '//' comment, or if it contains a
+// directive.
+func blockCommentToLineComments(comment string) ([]string, bool) {
+	if !strings.HasPrefix(comment, "/*") || !strings.HasSuffix(comment, "*/") || len(comment) < 4 {
+		return nil, false
+	}
+	text := comment[2 : len(comment)-2]
+	if strings.HasPrefix(text, "*") {
+		// Javadoc-style comment, e.g. '/** Comment. */'.
+		text = text[1:]
+	}
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " \t\r")
+	}
+	// If every line after the first starts with '*', it is a prefix that is
+	// removed, as are the spaces before it.
+	hasPrefix := len(lines) > 1
+	for _, line := range lines[1:] {
+		if trimmedLine := strings.TrimSpace(line); trimmedLine != "" && !strings.HasPrefix(trimmedLine, "*") {
+			hasPrefix = false
+			break
+		}
+	}
+	lines[0] = strings.TrimSpace(lines[0])
+	minIndent := -1
+	for i := 1; i < len(lines); i++ {
+		if hasPrefix {
+			lines[i] = strings.TrimPrefix(strings.TrimSpace(lines[i]), "*")
+			lines[i] = strings.TrimPrefix(lines[i], " ")
+			continue
+		}
+		if indent, ok := computeIndent(lines[i]); ok && (minIndent < 0 || indent < minIndent) {
+			minIndent = indent
+		}
+	}
+	if minIndent > 0 {
+		for i := 1; i < len(lines); i++ {
+			lines[i] = unindent(lines[i], minIndent)
+		}
+	}
+	// Blank lines at the start and end, such as the lines with only '/*' and
+	// '*/', are not written.
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil, false
+	}
+	comments := make([]string, len(lines))
+	for i, line := range lines {
+		if isDirectiveComment(line) {
+			return nil, false
+		}
+		if strings.TrimSpace(line) == "" {
+			comments[i] = "//"
+			continue
+		}
+		comments[i] = "// " + line
+	}
+	return comments, true
+}
+
+// reflowLineComments reflows the paragraphs of the given '//' comments that
+// have a line longer than the given width, so that the lines are as long as
+// possible without exceeding it.
+//
+// A paragraph is a run of comments with text that starts with a single space
+// and is not a list item or directive. Other comments, such as blank comments
+// and indented code, are not reflowed. Words that are longer than the width
+// are written on their own line.
+func reflowLineComments(comments []string, width int) []string {
+	var reflowed []string
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		reflowed = append(reflowed, reflowParagraph(paragraph, width)...)
+		paragraph = nil
+	}
+	for _, comment := range comments {
+		comment = strings.TrimSpace(comment)
+		text, ok := reflowableCommentText(comment)
+		if !ok {
+			flush()
+			reflowed = append(reflowed, comment)
+			continue
+		}
+		paragraph = append(paragraph, text)
+	}
+	flush()
+	return reflowed
+}
+
+// reflowParagraph reflows the text of the comments of a paragraph, if any of
+// the comments are longer than width.
+func reflowParagraph(texts []string, width int) []string {
+	tooLong := false
+	for _, text := range texts {
+		if utf8.RuneCountInString("// "+text) > width {
+			tooLong = true
+			break
+		}
+	}
+	if !tooLong {
+		comments := make([]string, len(texts))
+		for i, text := range texts {
+			comments[i] = "// " + text
+		}
+		return comments
+	}
+	var comments []string
+	var line strings.Builder
+	for _, word := range strings.Fields(strings.Join(texts, " ")) {
+		if line.Len() > 0 && utf8.RuneCountInString(line.String())+1+utf8.RuneCountInString(word) > width {
+			comments = append(comments, line.String())
+			line.Reset()
+		}
+		if line.Len() == 0 {
+			line.WriteString("//")
+		}
+		line.WriteString(" ")
+		line.WriteString(word)
+	}
+	return append(comments, line.String())
+}
+
+// reflowableCommentText returns the text of the given '//' comment without
+// the '// ' prefix, and false if it cannot be reflowed.
+func reflowableCommentText(comment string) (string, bool) {
+	if !strings.HasPrefix(comment, "// ") {
+		return "", false
+	}
+	text := strings.TrimPrefix(comment, "// ")
+	if text == "" ||
+		strings.HasPrefix(text, " ") ||
+		strings.HasPrefix(text, "\t") ||
+		listItemCommentRegexp.MatchString(text) ||
+		isDirectiveComment(text) {
+		return "", false
+	}
+	return text, true
+}
+
+// isDirectiveComment returns true if the given comment text, without the
+// comment markers, is a directive, such as 'buf:lint:ignore'.
+func isDirectiveComment(text string) bool {
+	return directiveCommentRegexp.MatchString(text)
+}
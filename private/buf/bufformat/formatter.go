@@ -15,6 +15,7 @@
 package bufformat
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -33,10 +34,39 @@ type formatter struct {
 	writer   io.Writer
 	fileNode *ast.FileNode
 
+	// The string written for each level of indentation.
+	indentString string
+	// The width of a tab, which is only used to measure lines.
+	tabWidth int
+	// The maximum width of a line, or zero if there is no maximum.
+	maxLineWidth int
+	// If true, imports are written in groups. See importGroup.
+	importGroups bool
+	// The paths of the files in the module. Imports of these paths are
+	// in their own group.
+	modulePaths map[string]struct{}
+	// If true, consecutive fields and enum values are aligned. See alignFields.
+	alignFields bool
+	// The alignments of the fields and enum values, if alignFields is true.
+	alignments map[ast.Node]fieldAlignment
+	// If true, the types of the file are written in a canonical order. See
+	// canonicalFileElementOrder.
+	canonicalOrder bool
+	// If true, enum values are sorted by number.
+	sortEnumValues bool
+	// If true, '/* */' doc comments are written as '//' comments.
+	lineDocComments bool
+	// If true, a space is written after the '//' of comments.
+	commentSpace bool
+	// If true, doc comments are reflowed to fit within maxLineWidth.
+	reflowComments bool
+
 	// Current level of indentation.
 	indent int
 	// The last character written to writer.
 	lastWritten rune
+	// The width of the current line written so far.
+	column int
 
 	// The last node written. This must be updated from all functions
 	// that write comments with a node. This flag informs how the next
@@ -71,15 +101,34 @@ type formatter struct {
 func newFormatter(
 	writer io.Writer,
 	fileNode *ast.FileNode,
+	formatOptions *formatOptions,
 ) *formatter {
+	indentString := strings.Repeat(" ", formatOptions.indent)
+	if formatOptions.useTabs {
+		indentString = "\t"
+	}
 	return &formatter{
-		writer:   writer,
-		fileNode: fileNode,
+		writer:          writer,
+		fileNode:        fileNode,
+		indentString:    indentString,
+		tabWidth:        formatOptions.indent,
+		maxLineWidth:    formatOptions.maxLineWidth,
+		importGroups:    formatOptions.importGroups,
+		modulePaths:     formatOptions.modulePaths,
+		alignFields:     formatOptions.alignFields,
+		canonicalOrder:  formatOptions.canonicalOrder,
+		sortEnumValues:  formatOptions.sortEnumValues,
+		lineDocComments: formatOptions.lineDocComments,
+		commentSpace:    formatOptions.commentSpace,
+		reflowComments:  formatOptions.reflowComments,
 	}
 }
 
 // Run runs the formatter and writes the file's content to the formatter's writer.
 func (f *formatter) Run() error {
+	if f.alignFields {
+		f.alignments = f.computeAlignments()
+	}
 	f.writeFile()
 	return f.err
 }
@@ -144,7 +193,7 @@ func (f *formatter) Indent(nextNode ast.Node) {
 			indent--
 		}
 	}
-	f.WriteString(strings.Repeat("  ", indent))
+	f.WriteString(strings.Repeat(f.indentString, indent))
 }
 
 // WriteString writes the given element to the generated output.
@@ -178,6 +227,7 @@ func (f *formatter) WriteString(elem string) {
 				f.err = multierr.Append(f.err, err)
 				return
 			}
+			f.column++
 		}
 	}
 	if len(elem) == 0 {
@@ -187,6 +237,38 @@ func (f *formatter) WriteString(elem string) {
 	if _, err := f.writer.Write([]byte(elem)); err != nil {
 		f.err = multierr.Append(f.err, err)
 	}
+	if lastNewline := strings.LastIndexByte(elem, '\n'); lastNewline >= 0 {
+		f.column = 0
+		elem = elem[lastNewline+1:]
+	}
+	for _, r := range elem {
+		if r == '\t' {
+			f.column += f.tabWidth
+			continue
+		}
+		f.column++
+	}
+}
+
+// fitsOnLine returns true if the output of the given write function fits on
+// the current line without exceeding the maximum line width, with room for a
+// trailing character such as ';' or ','.
+//
+// The write function is called with a copy of the formatter that discards its
+// output, so the node can then be written in whichever way fits.
+func (f *formatter) fitsOnLine(write func(*formatter)) bool {
+	if f.maxLineWidth <= 0 {
+		return true
+	}
+	var buffer bytes.Buffer
+	probe := *f
+	probe.writer = &buffer
+	probe.err = nil
+	write(&probe)
+	if probe.err != nil || bytes.ContainsRune(buffer.Bytes(), '\n') {
+		return false
+	}
+	return probe.column+1 <= f.maxLineWidth
 }
 
 // SetPreviousNode sets the previously written node. This should
@@ -260,6 +342,9 @@ func (f *formatter) writeFileHeader() {
 		iOrder := importSortOrder(importNodes[i])
 		jOrder := importSortOrder(importNodes[j])
 
+		if iGroup, jGroup := f.importGroup(iName), f.importGroup(jName); iGroup != jGroup {
+			return iGroup < jGroup
+		}
 		if iName < jName {
 			return true
 		}
@@ -288,6 +373,10 @@ func (f *formatter) writeFileHeader() {
 			continue
 		}
 
+		if i > 0 && f.importGroup(importNode.Name.AsString()) != f.importGroup(importNodes[i-1].Name.AsString()) {
+			// Each group is separated by a blank line.
+			f.P("")
+		}
 		f.writeImport(importNode, i > 0)
 	}
 	sort.Slice(optionNodes, func(i, j int) bool {
@@ -318,7 +407,11 @@ func (f *formatter) writeFileHeader() {
 // writeFileTypes writes the types defined in a .proto file. This includes the messages, enums,
 // services, etc. All other elements are ignored since they are handled by f.writeFileHeader.
 func (f *formatter) writeFileTypes() {
-	for i, fileElement := range f.fileNode.Decls {
+	decls := f.fileNode.Decls
+	if f.canonicalOrder {
+		decls = canonicalFileElementOrder(decls)
+	}
+	for i, fileElement := range decls {
 		switch node := fileElement.(type) {
 		case *ast.PackageNode, *ast.OptionNode, *ast.ImportNode, *ast.EmptyDeclNode:
 			// These elements have already been written by f.writeFileHeader.
@@ -616,7 +709,24 @@ func (f *formatter) maybeWriteCompactMessageLiteral(
 		return false
 	}
 	// messages with a single scalar field and no comments can be
-	// printed all on one line
+	// printed all on one line, unless the line would be too long
+	if !f.fitsOnLine(func(probe *formatter) { probe.writeCompactMessageLiteral(messageLiteralNode, inArrayLiteral) }) {
+		return false
+	}
+	f.writeCompactMessageLiteral(messageLiteralNode, inArrayLiteral)
+	return true
+}
+
+// writeCompactMessageLiteral writes a message literal with a single scalar
+// field on one line.
+//
+// For example,
+//
+//	{foo: 1}
+func (f *formatter) writeCompactMessageLiteral(
+	messageLiteralNode *ast.MessageLiteralNode,
+	inArrayLiteral bool,
+) {
 	if inArrayLiteral {
 		f.Indent(messageLiteralNode.Open)
 	}
@@ -629,7 +739,6 @@ func (f *formatter) maybeWriteCompactMessageLiteral(
 	f.Space()
 	f.writeInline(fieldNode.Val)
 	f.writeInline(messageLiteralNode.Close)
-	return true
 }
 
 func messageLiteralHasNestedMessageOrArray(messageLiteralNode *ast.MessageLiteralNode) bool {
@@ -733,8 +842,12 @@ func (f *formatter) writeMessageFieldPrefix(messageFieldNode *ast.MessageFieldNo
 func (f *formatter) writeEnum(enumNode *ast.EnumNode) {
 	var elementWriterFunc func()
 	if len(enumNode.Decls) > 0 {
+		decls := enumNode.Decls
+		if f.sortEnumValues {
+			decls = sortedEnumValues(decls)
+		}
 		elementWriterFunc = func() {
-			for _, decl := range enumNode.Decls {
+			for _, decl := range decls {
 				f.writeNode(decl)
 			}
 		}
@@ -759,13 +872,16 @@ func (f *formatter) writeEnum(enumNode *ast.EnumNode) {
 //	  deprecated = true
 //	];
 func (f *formatter) writeEnumValue(enumValueNode *ast.EnumValueNode) {
+	alignment := f.fitAlignment(f.alignments[enumValueNode])
 	f.writeStart(enumValueNode.Name)
 	f.Space()
+	f.writePadding(alignment.namePadding)
 	f.writeInline(enumValueNode.Equals)
 	f.Space()
 	f.writeInline(enumValueNode.Number)
 	if enumValueNode.Options != nil {
 		f.Space()
+		f.writePadding(alignment.numberPadding)
 		f.writeNode(enumValueNode.Options)
 	}
 	f.writeLineEnd(enumValueNode.Semicolon)
@@ -797,14 +913,14 @@ func (f *formatter) writeField(fieldNode *ast.FieldNode) {
 			f.writeStart(fieldNode.FldType)
 		}
 	}
-	f.Space()
-	f.writeInline(fieldNode.Name)
-	f.Space()
-	f.writeInline(fieldNode.Equals)
-	f.Space()
-	f.writeInline(fieldNode.Tag)
+	alignment := f.fitAlignment(f.alignments[fieldNode])
+	if f.writeFieldNameAndTag(fieldNode.Name, fieldNode.Equals, fieldNode.Tag, alignment) {
+		defer f.Out()
+		alignment = fieldAlignment{}
+	}
 	if fieldNode.Options != nil {
 		f.Space()
+		f.writePadding(alignment.numberPadding)
 		f.writeNode(fieldNode.Options)
 	}
 	f.writeLineEnd(fieldNode.Semicolon)
@@ -812,20 +928,53 @@ func (f *formatter) writeField(fieldNode *ast.FieldNode) {
 
 // writeMapField writes a map field (e.g. 'map<string, string> pairs = 1;').
 func (f *formatter) writeMapField(mapFieldNode *ast.MapFieldNode) {
+	alignment := f.fitAlignment(f.alignments[mapFieldNode])
 	f.writeNode(mapFieldNode.MapType)
-	f.Space()
-	f.writeInline(mapFieldNode.Name)
-	f.Space()
-	f.writeInline(mapFieldNode.Equals)
-	f.Space()
-	f.writeInline(mapFieldNode.Tag)
+	if f.writeFieldNameAndTag(mapFieldNode.Name, mapFieldNode.Equals, mapFieldNode.Tag, alignment) {
+		defer f.Out()
+		alignment = fieldAlignment{}
+	}
 	if mapFieldNode.Options != nil {
 		f.Space()
+		f.writePadding(alignment.numberPadding)
 		f.writeNode(mapFieldNode.Options)
 	}
 	f.writeLineEnd(mapFieldNode.Semicolon)
 }
 
+// writeFieldNameAndTag writes the name and tag of a field after its type
+// (e.g. 'name = 1').
+//
+// If they do not fit within the maximum line width, they are written on the
+// next line with an extra level of indentation and without alignment padding.
+// For example,
+//
+//	repeated acme.weather.v1.TemperatureMeasurementWithUnits
+//	  temperature_measurements = 1;
+//
+// This returns true if the field was wrapped, in which case the caller must
+// call Out after writing the rest of the field.
+func (f *formatter) writeFieldNameAndTag(name ast.Node, equals ast.Node, tag ast.Node, alignment fieldAlignment) bool {
+	write := func(f *formatter, namePadding int) {
+		f.Space()
+		f.writeInline(name)
+		f.Space()
+		f.writePadding(namePadding)
+		f.writeInline(equals)
+		f.Space()
+		f.writeInline(tag)
+	}
+	if f.fitsOnLine(func(probe *formatter) { write(probe, alignment.namePadding) }) {
+		write(f, alignment.namePadding)
+		return false
+	}
+	f.P("")
+	f.In()
+	f.Indent(nil)
+	write(f, 0)
+	return true
+}
+
 // writeMapType writes a map type (e.g. 'map<string, string>').
 func (f *formatter) writeMapType(mapTypeNode *ast.MapTypeNode) {
 	f.writeStart(mapTypeNode.Keyword)
@@ -1124,7 +1273,8 @@ func (f *formatter) writeCompactOptions(compactOptionsNode *ast.CompactOptionsNo
 		f.inCompactOptions = false
 	}()
 	if len(compactOptionsNode.Options) == 1 &&
-		!f.hasInteriorComments(compactOptionsNode.OpenBracket, compactOptionsNode.Options[0].Name) {
+		!f.hasInteriorComments(compactOptionsNode.OpenBracket, compactOptionsNode.Options[0].Name) &&
+		f.fitsOnLine(func(probe *formatter) { probe.writeSingleCompactOption(compactOptionsNode) }) {
 		// If there's only a single compact scalar option without comments, we can write it
 		// in-line. For example:
 		//
@@ -1139,21 +1289,8 @@ func (f *formatter) writeCompactOptions(compactOptionsNode *ast.CompactOptionsNo
 		//    deprecated = true
 		//  ]
 		//
-		optionNode := compactOptionsNode.Options[0]
-		f.writeInline(compactOptionsNode.OpenBracket)
-		f.writeInline(optionNode.Name)
-		f.Space()
-		f.writeInline(optionNode.Equals)
-		if node, ok := optionNode.Val.(*ast.CompoundStringLiteralNode); ok {
-			// If there's only a single compact option, the value needs to
-			// write its comments (if any) in a way that preserves the closing ']'.
-			f.writeCompoundStringLiteralNoIndentEndInline(node)
-			f.writeInline(compactOptionsNode.CloseBracket)
-			return
-		}
-		f.Space()
-		f.writeInline(optionNode.Val)
-		f.writeInline(compactOptionsNode.CloseBracket)
+		// The same is true if the option would exceed the maximum line width.
+		f.writeSingleCompactOption(compactOptionsNode)
 		return
 	}
 	var elementWriterFunc func()
@@ -1177,6 +1314,30 @@ func (f *formatter) writeCompactOptions(compactOptionsNode *ast.CompactOptionsNo
 	)
 }
 
+// writeSingleCompactOption writes a compact options node with a single
+// option in-line.
+//
+// For example,
+//
+//	[deprecated = true]
+func (f *formatter) writeSingleCompactOption(compactOptionsNode *ast.CompactOptionsNode) {
+	optionNode := compactOptionsNode.Options[0]
+	f.writeInline(compactOptionsNode.OpenBracket)
+	f.writeInline(optionNode.Name)
+	f.Space()
+	f.writeInline(optionNode.Equals)
+	if node, ok := optionNode.Val.(*ast.CompoundStringLiteralNode); ok {
+		// If there's only a single compact option, the value needs to
+		// write its comments (if any) in a way that preserves the closing ']'.
+		f.writeCompoundStringLiteralNoIndentEndInline(node)
+		f.writeInline(compactOptionsNode.CloseBracket)
+		return
+	}
+	f.Space()
+	f.writeInline(optionNode.Val)
+	f.writeInline(compactOptionsNode.CloseBracket)
+}
+
 func (f *formatter) hasInteriorComments(nodes ...ast.Node) bool {
 	for i, n := range nodes {
 		// interior comments mean we ignore leading comments on first
@@ -1203,13 +1364,11 @@ func (f *formatter) hasInteriorComments(nodes ...ast.Node) bool {
 func (f *formatter) writeArrayLiteral(arrayLiteralNode *ast.ArrayLiteralNode) {
 	if len(arrayLiteralNode.Elements) == 1 &&
 		!f.hasInteriorComments(arrayLiteralNode.Children()...) &&
-		!arrayLiteralHasNestedMessageOrArray(arrayLiteralNode) {
+		!arrayLiteralHasNestedMessageOrArray(arrayLiteralNode) &&
+		f.fitsOnLine(func(probe *formatter) { probe.writeSingleElementArrayLiteral(arrayLiteralNode) }) {
 		// arrays with a single scalar value and no comments can be
-		// printed all on one line
-		valueNode := arrayLiteralNode.Elements[0]
-		f.writeInline(arrayLiteralNode.OpenBracket)
-		f.writeInline(valueNode)
-		f.writeInline(arrayLiteralNode.CloseBracket)
+		// printed all on one line, unless the line would be too long
+		f.writeSingleElementArrayLiteral(arrayLiteralNode)
 		return
 	}
 
@@ -1242,6 +1401,18 @@ func (f *formatter) writeArrayLiteral(arrayLiteralNode *ast.ArrayLiteralNode) {
 	)
 }
 
+// writeSingleElementArrayLiteral writes an array literal with a single scalar
+// value on one line.
+//
+// For example,
+//
+//	["foo"]
+func (f *formatter) writeSingleElementArrayLiteral(arrayLiteralNode *ast.ArrayLiteralNode) {
+	f.writeInline(arrayLiteralNode.OpenBracket)
+	f.writeInline(arrayLiteralNode.Elements[0])
+	f.writeInline(arrayLiteralNode.CloseBracket)
+}
+
 // writeCompositeForArrayLiteral writes the composite node in a way that's suitable
 // for array literals. In general, signed integers and compound strings should have their
 // comments written in-line because they are one of many components in a single line.
@@ -1970,9 +2141,14 @@ func (f *formatter) writeMultilineComments(comments ast.Comments) {
 
 func (f *formatter) writeMultilineCommentsMaybeCompact(comments ast.Comments, forceCompact bool) {
 	compact := forceCompact || isOpenBrace(f.previousNode)
+	// Consecutive '//' comments are written together so that they can be
+	// reflowed.
+	var lineComments []string
 	for i := 0; i < comments.Len(); i++ {
 		comment := comments.Index(i)
 		if !compact && newlineCount(comment.LeadingWhitespace()) > 1 {
+			f.writeLineComments(lineComments)
+			lineComments = nil
 			// Newlines between blocks of comments should be preserved.
 			//
 			// For example,
@@ -1986,9 +2162,23 @@ func (f *formatter) writeMultilineCommentsMaybeCompact(comments ast.Comments, fo
 			f.P("")
 		}
 		compact = false
-		f.writeComment(comment.RawText())
+		text := comment.RawText()
+		if f.lineDocComments {
+			if convertedComments, ok := blockCommentToLineComments(text); ok {
+				lineComments = append(lineComments, convertedComments...)
+				continue
+			}
+		}
+		if strings.HasPrefix(text, "//") {
+			lineComments = append(lineComments, text)
+			continue
+		}
+		f.writeLineComments(lineComments)
+		lineComments = nil
+		f.writeComment(text)
 		f.WriteString("\n")
 	}
+	f.writeLineComments(lineComments)
 }
 
 // writeInlineComments writes the given comments in-line. Standard comments are
@@ -2153,7 +2343,7 @@ func (f *formatter) writeComment(comment string) {
 		}
 	} else {
 		f.Indent(nil)
-		f.WriteString(strings.TrimSpace(comment))
+		f.WriteString(f.normalizeLineComment(comment))
 	}
 }
 
@@ -2265,6 +2455,22 @@ func importSortOrder(node *ast.ImportNode) int {
 	}
 }
 
+// importGroup returns the group of the import with the given path, where
+// lower groups are written first. All imports are in the same group unless
+// import groups are enabled.
+func (f *formatter) importGroup(importPath string) int {
+	if !f.importGroups {
+		return 0
+	}
+	if strings.HasPrefix(importPath, "google/") {
+		return 0
+	}
+	if _, ok := f.modulePaths[importPath]; ok {
+		return 2
+	}
+	return 1
+}
+
 // stringForOptionName returns the string representation of the given option name node.
 // This is used for sorting file-level options.
 func stringForOptionName(optionNameNode *ast.OptionNameNode) string {
@@ -25,6 +25,8 @@ import (
 	"github.com/bufbuild/buf/private/pkg/diff"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
 	"github.com/stretchr/testify/require"
 )
 
@@ -33,6 +35,7 @@ func TestFormatter(t *testing.T) {
 	testFormatCustomOptions(t)
 	testFormatProto2(t)
 	testFormatProto3(t)
+	testFormatOptions(t)
 }
 
 func testFormatCustomOptions(t *testing.T) {
@@ -63,7 +66,25 @@ func testFormatProto3(t *testing.T) {
 	testFormatNoDiff(t, "testdata/proto3/block/v1")
 }
 
-func testFormatNoDiff(t *testing.T, path string) {
+func testFormatOptions(t *testing.T) {
+	testFormatNoDiff(t, "testdata/options/indent/v1", FormatWithIndent(4))
+	testFormatNoDiff(t, "testdata/options/tabs/v1", FormatWithTabs())
+	testFormatNoDiff(t, "testdata/options/maxlinewidth/v1", FormatWithMaxLineWidth(80))
+	testFormatNoDiff(t, "testdata/options/importgroups/v1", FormatWithImportGroups())
+	testFormatNoDiff(t, "testdata/options/alignfields/v1", FormatWithAlignedFields())
+	testFormatNoDiff(t, "testdata/options/alignfieldsmaxlinewidth/v1", FormatWithAlignedFields(), FormatWithMaxLineWidth(60))
+	testFormatNoDiff(t, "testdata/options/order/v1", FormatWithCanonicalOrder(), FormatWithSortedEnumValues())
+	testFormatNoDiff(
+		t,
+		"testdata/options/comments/v1",
+		FormatWithLineDocComments(),
+		FormatWithCommentSpace(),
+		FormatWithReflowedComments(),
+		FormatWithMaxLineWidth(60),
+	)
+}
+
+func testFormatNoDiff(t *testing.T, path string, options ...FormatOption) {
 	t.Run(path, func(t *testing.T) {
 		ctx := context.Background()
 		runner := command.NewRunner()
@@ -71,7 +92,7 @@ func testFormatNoDiff(t *testing.T, path string) {
 		require.NoError(t, err)
 		module, err := bufmodule.NewModuleForBucket(ctx, moduleBucket)
 		require.NoError(t, err)
-		readBucket, err := FormatModule(ctx, module)
+		readBucket, err := FormatModule(ctx, module, options...)
 		require.NoError(t, err)
 		require.NoError(
 			t,
@@ -104,3 +125,153 @@ func testFormatNoDiff(t *testing.T, path string) {
 		)
 	})
 }
+
+func TestFormatRange(t *testing.T) {
+	t.Parallel()
+	const input = `syntax = "proto3";
+
+package a;
+
+message Foo {
+  // The name.
+  string   name=1;
+  int32 id   = 2; // The ID.
+  oneof value {
+      string text =3;
+  }
+  message   Bar {  }
+}
+
+enum   Baz {
+BAZ_UNSPECIFIED=0;
+}
+`
+	testCases := []struct {
+		name              string
+		startLine         int
+		endLine           int
+		expectedStartLine int
+		expectedEndLine   int
+		expected          string
+	}{
+		{
+			name:              "field_with_comments",
+			startLine:         7,
+			endLine:           8,
+			expectedStartLine: 6,
+			expectedEndLine:   8,
+			expected: `  // The name.
+  string name = 1;
+  int32 id = 2; // The ID.
+`,
+		},
+		{
+			name:              "oneof_field",
+			startLine:         10,
+			endLine:           10,
+			expectedStartLine: 10,
+			expectedEndLine:   10,
+			expected: `    string text = 3;
+`,
+		},
+		{
+			name:              "nested_message",
+			startLine:         12,
+			endLine:           12,
+			expectedStartLine: 12,
+			expectedEndLine:   12,
+			expected: `  message Bar {}
+`,
+		},
+		{
+			name:              "across_blocks",
+			startLine:         13,
+			endLine:           15,
+			expectedStartLine: 5,
+			expectedEndLine:   17,
+			expected: `message Foo {
+  // The name.
+  string name = 1;
+  int32 id = 2; // The ID.
+  oneof value {
+    string text = 3;
+  }
+  message Bar {}
+}
+
+enum Baz {
+  BAZ_UNSPECIFIED = 0;
+}
+`,
+		},
+		{
+			name:              "whitespace",
+			startLine:         2,
+			endLine:           2,
+			expectedStartLine: 2,
+			expectedEndLine:   2,
+			expected:          "\n",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			var formatted strings.Builder
+			startLine, endLine, err := FormatRange(&formatted, "a.proto", []byte(input), testCase.startLine, testCase.endLine)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectedStartLine, startLine)
+			require.Equal(t, testCase.expectedEndLine, endLine)
+			require.Equal(t, testCase.expected, formatted.String())
+		})
+	}
+}
+
+func TestFormatLineEnding(t *testing.T) {
+	t.Parallel()
+	const input = "syntax = \"proto3\";\r\n/* A\r\n   message. */\r\nmessage Foo {}\r\n"
+	testFormatFileNode(
+		t,
+		input,
+		"syntax = \"proto3\";\n\n/* A\n   message. */\nmessage Foo {}\n",
+	)
+	testFormatFileNode(
+		t,
+		input,
+		"syntax = \"proto3\";\r\n\r\n/* A\r\n   message. */\r\nmessage Foo {}\r\n",
+		FormatWithLineEnding("\r\n"),
+	)
+	testFormatFileNode(
+		t,
+		input,
+		"syntax = \"proto3\";\r\n\r\n/* A\r\n   message. */\r\nmessage Foo {}",
+		FormatWithLineEnding("\r\n"),
+		FormatWithoutFinalNewline(),
+	)
+}
+
+func TestFormatSpaces(t *testing.T) {
+	t.Parallel()
+	const input = "syntax = \"proto3\";\nmessage Foo {\n  string bar = 1;\n}\n"
+	testFormatFileNode(
+		t,
+		input,
+		"syntax = \"proto3\";\n\nmessage Foo {\n\tstring bar = 1;\n}\n",
+		FormatWithTabs(),
+	)
+	testFormatFileNode(
+		t,
+		input,
+		"syntax = \"proto3\";\n\nmessage Foo {\n  string bar = 1;\n}\n",
+		FormatWithTabs(),
+		FormatWithSpaces(),
+	)
+}
+
+func testFormatFileNode(t *testing.T, input string, expected string, options ...FormatOption) {
+	fileNode, err := parser.Parse("a.proto", strings.NewReader(input), reporter.NewHandler(nil))
+	require.NoError(t, err)
+	var formatted strings.Builder
+	require.NoError(t, FormatFileNode(&formatted, fileNode, options...))
+	require.Equal(t, expected, formatted.String())
+}
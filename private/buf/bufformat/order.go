@@ -0,0 +1,83 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufformat
+
+import (
+	"sort"
+
+	"github.com/bufbuild/protocompile/ast"
+)
+
+// canonicalFileElementOrder returns a copy of the given file elements in their
+// canonical order, which is the order of the fields of a FileDescriptorProto:
+// messages, then enums, then services, then extensions.
+//
+// The other elements, such as imports and options, are first, though they are
+// written separately by writeFileHeader.
+func canonicalFileElementOrder(fileElements []ast.FileElement) []ast.FileElement {
+	ordered := make([]ast.FileElement, len(fileElements))
+	copy(ordered, fileElements)
+	sort.SliceStable(
+		ordered,
+		func(i int, j int) bool {
+			return fileElementRank(ordered[i]) < fileElementRank(ordered[j])
+		},
+	)
+	return ordered
+}
+
+func fileElementRank(fileElement ast.FileElement) int {
+	switch fileElement.(type) {
+	case *ast.MessageNode:
+		return 1
+	case *ast.EnumNode:
+		return 2
+	case *ast.ServiceNode:
+		return 3
+	case *ast.ExtendNode:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// sortedEnumValues returns a copy of the given enum elements with the values
+// sorted by number, except for the first value, which is kept first because it
+// is the default value of the enum. The other elements, such as options and
+// reserved ranges, are written before the values, in their original order.
+func sortedEnumValues(enumElements []ast.EnumElement) []ast.EnumElement {
+	sorted := make([]ast.EnumElement, 0, len(enumElements))
+	var values []ast.EnumElement
+	for _, enumElement := range enumElements {
+		if _, ok := enumElement.(*ast.EnumValueNode); ok {
+			values = append(values, enumElement)
+			continue
+		}
+		sorted = append(sorted, enumElement)
+	}
+	if len(values) == 0 {
+		return sorted
+	}
+	rest := values[1:]
+	sort.SliceStable(
+		rest,
+		func(i int, j int) bool {
+			iNumber, _ := rest[i].(*ast.EnumValueNode).Number.AsInt64()
+			jNumber, _ := rest[j].(*ast.EnumValueNode).Number.AsInt64()
+			return iNumber < jNumber
+		},
+	)
+	return append(sorted, values...)
+}
@@ -0,0 +1,263 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufformat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bufbuild/protocompile/ast"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+)
+
+// FormatRange formats the lines from startLine to endLine, inclusive, of the
+// given .proto file content, and writes the formatted replacement for those
+// lines to dest. Lines are numbered from 1.
+//
+// The range is expanded to include whole declarations, such as fields or
+// messages, so the first and last lines of the range that the replacement
+// replaces are returned. If the range does not overlap any declarations, the
+// lines are written unchanged. The declarations are formatted as they would be
+// within the whole file, except that file options and imports are only sorted
+// within the range.
+func FormatRange(
+	dest io.Writer,
+	filename string,
+	data []byte,
+	startLine int,
+	endLine int,
+	options ...FormatOption,
+) (int, int, error) {
+	if startLine < 1 || endLine < startLine {
+		return 0, 0, fmt.Errorf("invalid range %d:%d", startLine, endLine)
+	}
+	fileNode, err := parser.Parse(filename, bytes.NewReader(data), reporter.NewHandler(nil))
+	if err != nil {
+		return 0, 0, err
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if endLine > len(lines) {
+		endLine = len(lines)
+	}
+	if startLine > endLine {
+		return 0, 0, fmt.Errorf("invalid range %d:%d for %d lines", startLine, endLine, len(lines))
+	}
+	blocks, declsStartLine, declsEndLine := expandRange(fileNode, fileNode.Decls, nil, startLine, endLine)
+	if declsStartLine == 0 {
+		// The range does not overlap any declarations, so only whitespace
+		// or comments outside of declarations are in the range, which are
+		// left as they are.
+		for _, line := range lines[startLine-1 : endLine] {
+			if _, err := io.WriteString(dest, line); err != nil {
+				return 0, 0, err
+			}
+		}
+		return startLine, endLine, nil
+	}
+	startLine, endLine = declsStartLine, declsEndLine
+	// The declarations are formatted on their own, within copies of the
+	// blocks that contain them so that they are indented the same.
+	var snippet strings.Builder
+	for _, block := range blocks {
+		snippet.WriteString(block.open)
+		snippet.WriteString("\n")
+	}
+	for _, line := range lines[startLine-1 : endLine] {
+		snippet.WriteString(line)
+	}
+	if !strings.HasSuffix(snippet.String(), "\n") {
+		snippet.WriteString("\n")
+	}
+	for range blocks {
+		snippet.WriteString("}\n")
+	}
+	snippetFileNode, err := parser.Parse(filename, strings.NewReader(snippet.String()), reporter.NewHandler(nil))
+	if err != nil {
+		return 0, 0, err
+	}
+	var formatted bytes.Buffer
+	if err := FormatFileNode(&formatted, snippetFileNode, options...); err != nil {
+		return 0, 0, err
+	}
+	formattedLines := strings.SplitAfter(formatted.String(), "\n")
+	if formattedLines[len(formattedLines)-1] == "" {
+		formattedLines = formattedLines[:len(formattedLines)-1]
+	}
+	if len(formattedLines) < 2*len(blocks) {
+		// Unreachable.
+		return 0, 0, fmt.Errorf("internal error: could not format range %d:%d", startLine, endLine)
+	}
+	for _, line := range formattedLines[len(blocks) : len(formattedLines)-len(blocks)] {
+		if _, err := io.WriteString(dest, line); err != nil {
+			return 0, 0, err
+		}
+	}
+	return startLine, endLine, nil
+}
+
+// rangeBlock is a block, such as a message, that contains a range.
+type rangeBlock struct {
+	// The opening line of a copy of the block, e.g. 'message Foo {'.
+	open string
+}
+
+// expandRange expands the range from startLine to endLine to the whole
+// declarations that it overlaps within the given declarations of the parent
+// blocks.
+//
+// If the range is within the body of a single declaration that is a block, the
+// range is expanded within the declarations of that block instead, and the
+// block is added to the returned blocks. If the range does not overlap any
+// declarations, the returned lines are zero.
+func expandRange[T ast.Node](
+	fileNode *ast.FileNode,
+	decls []T,
+	blocks []rangeBlock,
+	startLine int,
+	endLine int,
+) ([]rangeBlock, int, int) {
+	first, last := -1, -1
+	for i, decl := range decls {
+		declStartLine, declEndLine := declLines(fileNode, decl)
+		if declStartLine <= endLine && declEndLine >= startLine {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return blocks, 0, 0
+	}
+	if first == last {
+		if innerBlocks, innerStartLine, innerEndLine, ok := expandRangeInBlock(fileNode, decls[first], blocks, startLine, endLine); ok {
+			return innerBlocks, innerStartLine, innerEndLine
+		}
+	}
+	startLine, _ = declLines(fileNode, decls[first])
+	_, endLine = declLines(fileNode, decls[last])
+	// Declarations that share a line with the range are also included,
+	// since only whole lines can be replaced.
+	for first > 0 {
+		if _, previousEndLine := declLines(fileNode, decls[first-1]); previousEndLine < startLine {
+			break
+		}
+		first--
+		startLine, _ = declLines(fileNode, decls[first])
+	}
+	for last < len(decls)-1 {
+		if nextStartLine, _ := declLines(fileNode, decls[last+1]); nextStartLine > endLine {
+			break
+		}
+		last++
+		_, endLine = declLines(fileNode, decls[last])
+	}
+	return blocks, startLine, endLine
+}
+
+// expandRangeInBlock expands the range within the body of the given
+// declaration, if it is a block and the range is within its body. Otherwise,
+// it returns false.
+func expandRangeInBlock(
+	fileNode *ast.FileNode,
+	decl ast.Node,
+	blocks []rangeBlock,
+	startLine int,
+	endLine int,
+) ([]rangeBlock, int, int, bool) {
+	var (
+		open       string
+		openBrace  *ast.RuneNode
+		closeBrace *ast.RuneNode
+		expand     func([]rangeBlock) ([]rangeBlock, int, int)
+	)
+	switch node := decl.(type) {
+	case *ast.MessageNode:
+		open = "message " + node.Name.Val + " {"
+		openBrace, closeBrace = node.OpenBrace, node.CloseBrace
+		expand = func(blocks []rangeBlock) ([]rangeBlock, int, int) {
+			return expandRange(fileNode, node.Decls, blocks, startLine, endLine)
+		}
+	case *ast.EnumNode:
+		open = "enum " + node.Name.Val + " {"
+		openBrace, closeBrace = node.OpenBrace, node.CloseBrace
+		expand = func(blocks []rangeBlock) ([]rangeBlock, int, int) {
+			return expandRange(fileNode, node.Decls, blocks, startLine, endLine)
+		}
+	case *ast.ServiceNode:
+		open = "service " + node.Name.Val + " {"
+		openBrace, closeBrace = node.OpenBrace, node.CloseBrace
+		expand = func(blocks []rangeBlock) ([]rangeBlock, int, int) {
+			return expandRange(fileNode, node.Decls, blocks, startLine, endLine)
+		}
+	case *ast.OneofNode:
+		open = "oneof " + node.Name.Val + " {"
+		openBrace, closeBrace = node.OpenBrace, node.CloseBrace
+		expand = func(blocks []rangeBlock) ([]rangeBlock, int, int) {
+			return expandRange(fileNode, node.Decls, blocks, startLine, endLine)
+		}
+	case *ast.ExtendNode:
+		open = "extend " + string(node.Extendee.AsIdentifier()) + " {"
+		openBrace, closeBrace = node.OpenBrace, node.CloseBrace
+		expand = func(blocks []rangeBlock) ([]rangeBlock, int, int) {
+			return expandRange(fileNode, node.Decls, blocks, startLine, endLine)
+		}
+	case *ast.RPCNode:
+		if node.OpenBrace == nil {
+			return nil, 0, 0, false
+		}
+		open = "rpc " + node.Name.Val + "(M) returns (M) {"
+		openBrace, closeBrace = node.OpenBrace, node.CloseBrace
+		expand = func(blocks []rangeBlock) ([]rangeBlock, int, int) {
+			return expandRange(fileNode, node.Decls, blocks, startLine, endLine)
+		}
+	default:
+		return nil, 0, 0, false
+	}
+	openLine := fileNode.NodeInfo(openBrace).Start().Line
+	closeLine := fileNode.NodeInfo(closeBrace).Start().Line
+	if startLine <= openLine || endLine >= closeLine {
+		return nil, 0, 0, false
+	}
+	innerBlocks := append(append([]rangeBlock{}, blocks...), rangeBlock{open: open})
+	innerBlocks, innerStartLine, innerEndLine := expand(innerBlocks)
+	if innerStartLine == 0 || innerStartLine <= openLine || innerEndLine >= closeLine {
+		// The range only overlaps whitespace or comments in the body, or the
+		// declarations in the body share a line with the braces.
+		return nil, 0, 0, false
+	}
+	return innerBlocks, innerStartLine, innerEndLine, true
+}
+
+// declLines returns the first and last lines of the given declaration,
+// including its comments.
+func declLines(fileNode *ast.FileNode, decl ast.Node) (int, int) {
+	info := fileNode.NodeInfo(decl)
+	startLine := info.Start().Line
+	if leadingComments := info.LeadingComments(); leadingComments.Len() > 0 {
+		startLine = leadingComments.Index(0).Start().Line
+	}
+	endLine := info.End().Line
+	if trailingComments := info.TrailingComments(); trailingComments.Len() > 0 {
+		endLine = trailingComments.Index(trailingComments.Len() - 1).End().Line
+	}
+	return startLine, endLine
+}
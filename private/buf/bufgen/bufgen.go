@@ -24,6 +24,7 @@ import (
 	"strconv"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagemodify"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	"github.com/bufbuild/buf/private/bufpkg/bufplugin/bufpluginref"
 	"github.com/bufbuild/buf/private/bufpkg/bufremoteplugin"
@@ -40,6 +41,10 @@ import (
 const (
 	// ExternalConfigFilePath is the default external configuration file path.
 	ExternalConfigFilePath = "buf.gen.yaml"
+	// ExternalLockFilePath is the default external lock file path.
+	ExternalLockFilePath = "buf.gen.lock"
+	// ExternalManifestFilePath is the default external provenance manifest file path.
+	ExternalManifestFilePath = "buf.gen.manifest.json"
 	// V1Version is the string used to identify the v1 version of the generate template.
 	V1Version = "v1"
 	// V1Beta1Version is the string used to identify the v1beta1 version of the generate template.
@@ -53,6 +58,8 @@ const (
 	StrategyDirectory Strategy = 1
 	// StrategyAll is the strategy that says to generate with all files at once.
 	StrategyAll Strategy = 2
+	// StrategyPackage is the strategy that says to generate per package.
+	StrategyPackage Strategy = 3
 )
 
 // Strategy is a generation stategy.
@@ -67,6 +74,8 @@ func ParseStrategy(s string) (Strategy, error) {
 		return StrategyDirectory, nil
 	case "all":
 		return StrategyAll, nil
+	case "package":
+		return StrategyPackage, nil
 	default:
 		return 0, fmt.Errorf("unknown strategy: %s", s)
 	}
@@ -79,6 +88,8 @@ func (s Strategy) String() string {
 		return "directory"
 	case StrategyAll:
 		return "all"
+	case StrategyPackage:
+		return "package"
 	default:
 		return strconv.Itoa(int(s))
 	}
@@ -89,7 +100,29 @@ type Provider interface {
 	// GetConfig gets the Config for the YAML data at ExternalConfigFilePath.
 	//
 	// If the data is of length 0, returns the default config.
-	GetConfig(ctx context.Context, readBucket storage.ReadBucket) (*Config, error)
+	GetConfig(ctx context.Context, readBucket storage.ReadBucket, options ...GetConfigOption) (*Config, error)
+}
+
+// GetConfigOption is an option for GetConfig.
+type GetConfigOption func(*getConfigOptions)
+
+// GetConfigWithProfile sets the profile to apply to v1 configurations.
+//
+// See ReadConfigWithProfile for how profiles are applied.
+func GetConfigWithProfile(profile string) GetConfigOption {
+	return func(getConfigOptions *getConfigOptions) {
+		getConfigOptions.profile = profile
+	}
+}
+
+// GetConfigWithEnvContainer sets the environment variables that are expanded in
+// v1 configurations.
+//
+// See ReadConfigWithEnvContainer for how environment variables are expanded.
+func GetConfigWithEnvContainer(envContainer app.EnvContainer) GetConfigOption {
+	return func(getConfigOptions *getConfigOptions) {
+		getConfigOptions.envContainer = envContainer
+	}
 }
 
 // NewProvider returns a new Provider.
@@ -161,6 +194,17 @@ func GenerateWithIncludeWellKnownTypes() GenerateOption {
 	}
 }
 
+// GenerateWithManifest returns a new GenerateOption that records the provenance
+// of the generation of the input in the Manifest.
+//
+// The same Manifest can be used for multiple generations, for example one per input.
+func GenerateWithManifest(manifest *Manifest, input string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.manifest = manifest
+		generateOptions.manifestInput = input
+	}
+}
+
 // GenerateWithWASMEnabled says to enable WASM support.
 func GenerateWithWASMEnabled() GenerateOption {
 	return func(generateOptions *generateOptions) {
@@ -176,6 +220,8 @@ type Config struct {
 	ManagedConfig *ManagedConfig
 	// Optional
 	TypesConfig *TypesConfig
+	// Optional
+	InputConfigs []*InputConfig
 }
 
 // PluginConfig is a plugin configuration.
@@ -192,6 +238,9 @@ type PluginConfig struct {
 	Opt string
 	// Optional, exclusive with Remote
 	Path []string
+	// Optional, the expected hex-encoded SHA-256 digest of the binary at
+	// Path, verified before the plugin is executed. Requires Path.
+	SHA256 string
 	// Required
 	Strategy Strategy
 	// Optional
@@ -232,6 +281,9 @@ func (p *PluginConfig) GetRemoteHostname() string {
 	if reference, err := bufpluginref.PluginReferenceForString(p.Plugin, 0); err == nil {
 		return reference.Remote()
 	}
+	if identity, _, err := bufpluginref.ParsePluginIdentityVersionConstraint(p.Plugin); err == nil {
+		return identity.Remote()
+	}
 	if p.Remote == "" {
 		return ""
 	}
@@ -253,6 +305,7 @@ type ManagedConfig struct {
 	ObjcClassPrefixConfig   *ObjcClassPrefixConfig
 	RubyPackageConfig       *RubyPackageConfig
 	Override                map[string]map[string]string
+	OptionInjections        []*bufimagemodify.OptionInjection
 }
 
 // JavaPackagePrefixConfig is the java_package prefix configuration.
@@ -305,6 +358,25 @@ type TypesConfig struct {
 	Include []string
 }
 
+// InputConfig is an input configuration.
+//
+// Each InputConfig results in a separate image being built and the
+// plugins being invoked once for that image.
+type InputConfig struct {
+	// Required
+	Input string
+	// Optional
+	Types []string
+	// Optional
+	Paths []string
+	// Optional
+	ExcludePaths []string
+	// Optional
+	IncludeImports bool
+	// Optional, requires IncludeImports
+	IncludeWKT bool
+}
+
 // ReadConfig reads the configuration from the OS or an override, if any.
 //
 // Only use in CLI tools.
@@ -340,17 +412,224 @@ func ReadConfigWithOverride(override string) ReadConfigOption {
 	}
 }
 
+// ReadConfigWithProfile sets the profile to apply to v1 configurations.
+//
+// The plugins, managed, types, and inputs keys that the profile sets replace the keys
+// of the configuration. It is an error for a configuration to define profiles but not
+// the given profile. Configurations that do not define any profiles are unaffected.
+func ReadConfigWithProfile(profile string) ReadConfigOption {
+	return func(readConfigOptions *readConfigOptions) {
+		readConfigOptions.profile = profile
+	}
+}
+
+// ReadConfigWithEnvContainer sets the environment variables that are expanded in
+// v1 configurations.
+//
+// References to environment variables of the form ${VAR} or ${VAR:-default} are
+// expanded in the plugin, remote, and revision keys of the plugins. It is an error
+// to reference a variable that is not set without a default.
+func ReadConfigWithEnvContainer(envContainer app.EnvContainer) ReadConfigOption {
+	return func(readConfigOptions *readConfigOptions) {
+		readConfigOptions.envContainer = envContainer
+	}
+}
+
 // ConfigExists checks if a generation configuration file exists.
 func ConfigExists(ctx context.Context, readBucket storage.ReadBucket) (bool, error) {
 	return storage.Exists(ctx, readBucket, ExternalConfigFilePath)
 }
 
+// Lock pins the remote plugins of a Config that are specified with version
+// constraints to exact versions and revisions.
+type Lock struct {
+	Plugins []*LockedPlugin
+}
+
+// LockedPlugin is a remote plugin pinned to an exact version and revision.
+type LockedPlugin struct {
+	// The plugin identity, i.e. remote/owner/plugin.
+	Plugin string
+	// The version constraint the version was resolved from.
+	Constraint string
+	Version    string
+	Revision   int
+}
+
+// ReadLock reads the lock file at ExternalLockFilePath in the bucket.
+//
+// If the lock file does not exist, an empty Lock is returned.
+func ReadLock(ctx context.Context, readBucket storage.ReadBucket) (*Lock, error) {
+	return readLock(ctx, readBucket)
+}
+
+// WriteLock writes the lock file to ExternalLockFilePath in the bucket.
+func WriteLock(ctx context.Context, writeBucket storage.WriteBucket, lock *Lock) error {
+	return writeLock(ctx, writeBucket, lock)
+}
+
+// ResolvePluginVersions resolves the remote plugins of the Config that are specified
+// with version constraints, such as buf.build/protocolbuffers/go:^1.31, to exact
+// versions and revisions, and updates the PluginConfigs accordingly.
+//
+// Versions already recorded in the lock file at ExternalLockFilePath for the same
+// constraint are reused, otherwise the latest matching version is resolved from the
+// remote and the lock file is updated.
+func ResolvePluginVersions(
+	ctx context.Context,
+	logger *zap.Logger,
+	clientConfig *connectclient.Config,
+	readWriteBucket storage.ReadWriteBucket,
+	config *Config,
+	options ...ResolvePluginVersionsOption,
+) error {
+	return resolvePluginVersions(ctx, logger, clientConfig, readWriteBucket, config, options...)
+}
+
+// ResolvePluginVersionsOption is an option for ResolvePluginVersions.
+type ResolvePluginVersionsOption func(*resolvePluginVersionsOptions)
+
+// ResolvePluginVersionsWithLocked says to fail instead of resolving or updating
+// versions if the lock file does not exactly match the constraints in the Config.
+func ResolvePluginVersionsWithLocked() ResolvePluginVersionsOption {
+	return func(resolvePluginVersionsOptions *resolvePluginVersionsOptions) {
+		resolvePluginVersionsOptions.locked = true
+	}
+}
+
+// Manifest records the provenance of generated code: the inputs it was generated
+// from, the plugins that generated it, and the digests of the generated files.
+//
+// All digests are of the form sha256:<hex>.
+type Manifest struct {
+	Inputs  []*ManifestInput
+	Plugins []*ManifestPlugin
+	Files   []*ManifestFile
+}
+
+// NewManifest returns a new, empty Manifest.
+func NewManifest() *Manifest {
+	return &Manifest{}
+}
+
+// ManifestInput is an input recorded in a Manifest.
+type ManifestInput struct {
+	Input string
+	// The digest of the image built from the input, before managed mode is applied.
+	Digest string
+	// The remote modules the image contains files from, sorted by module.
+	Modules []*ManifestModule
+}
+
+// ManifestModule is a remote module recorded in a Manifest.
+type ManifestModule struct {
+	// The module identity, i.e. remote/owner/repository.
+	Module string
+	Commit string
+}
+
+// ManifestPlugin is a plugin recorded in a Manifest.
+type ManifestPlugin struct {
+	Name string
+	// The version and revision are only set for remote plugins with a version.
+	Version  string
+	Revision int
+	// The digest of the plugin binary, only set for local plugins that are binaries.
+	Digest string
+}
+
+// ManifestFile is a generated file recorded in a Manifest.
+type ManifestFile struct {
+	// The path of the file, or of the archive for outputs to .jar or .zip files.
+	Path string
+	// The name of the plugin that generated the file.
+	Plugin string
+	Digest string
+}
+
+// WriteManifest writes the Manifest to ExternalManifestFilePath in the bucket.
+func WriteManifest(ctx context.Context, writeBucket storage.WriteBucket, manifest *Manifest) error {
+	return writeManifest(ctx, writeBucket, manifest)
+}
+
+// ExternalLockV1 is an external lock file.
+type ExternalLockV1 struct {
+	Version string                 `json:"version,omitempty" yaml:"version,omitempty"`
+	Plugins []ExternalLockPluginV1 `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+}
+
+// ExternalLockPluginV1 is an external locked plugin.
+type ExternalLockPluginV1 struct {
+	Plugin     string `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Constraint string `json:"constraint,omitempty" yaml:"constraint,omitempty"`
+	Version    string `json:"version,omitempty" yaml:"version,omitempty"`
+	Revision   int    `json:"revision,omitempty" yaml:"revision,omitempty"`
+}
+
+// ExternalManifestV1 is an external provenance manifest.
+type ExternalManifestV1 struct {
+	Version string                     `json:"version,omitempty" yaml:"version,omitempty"`
+	Inputs  []ExternalManifestInputV1  `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	Plugins []ExternalManifestPluginV1 `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	Files   []ExternalManifestFileV1   `json:"files,omitempty" yaml:"files,omitempty"`
+}
+
+// ExternalManifestInputV1 is an external manifest input.
+type ExternalManifestInputV1 struct {
+	Input   string                     `json:"input,omitempty" yaml:"input,omitempty"`
+	Digest  string                     `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Modules []ExternalManifestModuleV1 `json:"modules,omitempty" yaml:"modules,omitempty"`
+}
+
+// ExternalManifestModuleV1 is an external manifest module.
+type ExternalManifestModuleV1 struct {
+	Module string `json:"module,omitempty" yaml:"module,omitempty"`
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+}
+
+// ExternalManifestPluginV1 is an external manifest plugin.
+type ExternalManifestPluginV1 struct {
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	Version  string `json:"version,omitempty" yaml:"version,omitempty"`
+	Revision int    `json:"revision,omitempty" yaml:"revision,omitempty"`
+	Digest   string `json:"digest,omitempty" yaml:"digest,omitempty"`
+}
+
+// ExternalManifestFileV1 is an external manifest file.
+type ExternalManifestFileV1 struct {
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	Plugin string `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+}
+
 // ExternalConfigV1 is an external configuration.
 type ExternalConfigV1 struct {
 	Version string                   `json:"version,omitempty" yaml:"version,omitempty"`
 	Plugins []ExternalPluginConfigV1 `json:"plugins,omitempty" yaml:"plugins,omitempty"`
 	Managed ExternalManagedConfigV1  `json:"managed,omitempty" yaml:"managed,omitempty"`
 	Types   ExternalTypesConfigV1    `json:"types,omitempty" yaml:"types,omitempty"`
+	Inputs  []ExternalInputConfigV1  `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	// Profiles are keyed by the name of the profile, such as ci.
+	Profiles map[string]ExternalProfileConfigV1 `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// ExternalProfileConfigV1 is an external profile configuration, which replaces
+// the keys of the configuration that it sets when it is selected.
+type ExternalProfileConfigV1 struct {
+	Plugins []ExternalPluginConfigV1 `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	Managed ExternalManagedConfigV1  `json:"managed,omitempty" yaml:"managed,omitempty"`
+	Types   ExternalTypesConfigV1    `json:"types,omitempty" yaml:"types,omitempty"`
+	Inputs  []ExternalInputConfigV1  `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+}
+
+// ExternalInputConfigV1 is an external input configuration.
+type ExternalInputConfigV1 struct {
+	Input          string   `json:"input,omitempty" yaml:"input,omitempty"`
+	Types          []string `json:"types,omitempty" yaml:"types,omitempty"`
+	Paths          []string `json:"paths,omitempty" yaml:"paths,omitempty"`
+	ExcludePaths   []string `json:"exclude_paths,omitempty" yaml:"exclude_paths,omitempty"`
+	IncludeImports bool     `json:"include_imports,omitempty" yaml:"include_imports,omitempty"`
+	IncludeWKT     bool     `json:"include_wkt,omitempty" yaml:"include_wkt,omitempty"`
 }
 
 // ExternalPluginConfigV1 is an external plugin configuration.
@@ -362,6 +641,7 @@ type ExternalPluginConfigV1 struct {
 	Out        string      `json:"out,omitempty" yaml:"out,omitempty"`
 	Opt        interface{} `json:"opt,omitempty" yaml:"opt,omitempty"`
 	Path       interface{} `json:"path,omitempty" yaml:"path,omitempty"`
+	SHA256     string      `json:"sha256,omitempty" yaml:"sha256,omitempty"`
 	ProtocPath string      `json:"protoc_path,omitempty" yaml:"protoc_path,omitempty"`
 	Strategy   string      `json:"strategy,omitempty" yaml:"strategy,omitempty"`
 }
@@ -381,6 +661,7 @@ type ExternalManagedConfigV1 struct {
 	ObjcClassPrefix     ExternalObjcClassPrefixConfigV1   `json:"objc_class_prefix,omitempty" yaml:"objc_class_prefix,omitempty"`
 	RubyPackage         ExternalRubyPackageConfigV1       `json:"ruby_package,omitempty" yaml:"ruby_package,omitempty"`
 	Override            map[string]map[string]string      `json:"override,omitempty" yaml:"override,omitempty"`
+	Options             []ExternalOptionInjectionConfigV1 `json:"options,omitempty" yaml:"options,omitempty"`
 }
 
 // IsEmpty returns true if the config is empty, excluding the 'Enabled' setting.
@@ -395,7 +676,17 @@ func (e ExternalManagedConfigV1) IsEmpty() bool {
 		e.GoPackagePrefix.IsEmpty() &&
 		e.ObjcClassPrefix.IsEmpty() &&
 		e.RubyPackage.IsEmpty() &&
-		len(e.Override) == 0
+		len(e.Override) == 0 &&
+		len(e.Options) == 0
+}
+
+// ExternalOptionInjectionConfigV1 is the external configuration of a custom option
+// to set in managed mode.
+type ExternalOptionInjectionConfigV1 struct {
+	Option string   `json:"option,omitempty" yaml:"option,omitempty"`
+	Value  string   `json:"value,omitempty" yaml:"value,omitempty"`
+	Target string   `json:"target,omitempty" yaml:"target,omitempty"`
+	Match  []string `json:"match,omitempty" yaml:"match,omitempty"`
 }
 
 // ExternalJavaPackagePrefixConfigV1 is the external java_package prefix configuration.
@@ -16,21 +16,36 @@ package bufgen
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagemodify"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	"github.com/bufbuild/buf/private/bufpkg/bufplugin/bufpluginref"
 	"github.com/bufbuild/buf/private/bufpkg/bufremoteplugin"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/envexpand"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
+// expandedExternalConfigPaths are the paths of the values of configurations that
+// environment variables are expanded in.
+var expandedExternalConfigPaths = [][]string{
+	{"plugins", "*", "plugin"},
+	{"plugins", "*", "remote"},
+	{"plugins", "*", "revision"},
+}
+
 func readConfig(
 	ctx context.Context,
 	logger *zap.Logger,
@@ -45,17 +60,22 @@ func readConfig(
 	if override := readConfigOptions.override; override != "" {
 		switch filepath.Ext(override) {
 		case ".json":
-			return getConfigJSONFile(logger, override)
+			return getConfigJSONFile(logger, override, readConfigOptions.profile, readConfigOptions.envContainer)
 		case ".yaml", ".yml":
-			return getConfigYAMLFile(logger, override)
+			return getConfigYAMLFile(logger, override, readConfigOptions.profile, readConfigOptions.envContainer)
 		default:
-			return getConfigJSONOrYAMLData(logger, override)
+			return getConfigJSONOrYAMLData(logger, override, readConfigOptions.profile, readConfigOptions.envContainer)
 		}
 	}
-	return provider.GetConfig(ctx, readBucket)
+	return provider.GetConfig(
+		ctx,
+		readBucket,
+		GetConfigWithProfile(readConfigOptions.profile),
+		GetConfigWithEnvContainer(readConfigOptions.envContainer),
+	)
 }
 
-func getConfigJSONFile(logger *zap.Logger, file string) (*Config, error) {
+func getConfigJSONFile(logger *zap.Logger, file string, profile string, envContainer app.EnvContainer) (*Config, error) {
 	data, err := os.ReadFile(file)
 	if err != nil {
 		return nil, fmt.Errorf("could not read file %s: %v", file, err)
@@ -66,10 +86,12 @@ func getConfigJSONFile(logger *zap.Logger, file string) (*Config, error) {
 		encoding.UnmarshalJSONStrict,
 		data,
 		file,
+		profile,
+		envContainer,
 	)
 }
 
-func getConfigYAMLFile(logger *zap.Logger, file string) (*Config, error) {
+func getConfigYAMLFile(logger *zap.Logger, file string, profile string, envContainer app.EnvContainer) (*Config, error) {
 	data, err := os.ReadFile(file)
 	if err != nil {
 		return nil, fmt.Errorf("could not read file %s: %v", file, err)
@@ -80,16 +102,20 @@ func getConfigYAMLFile(logger *zap.Logger, file string) (*Config, error) {
 		encoding.UnmarshalYAMLStrict,
 		data,
 		file,
+		profile,
+		envContainer,
 	)
 }
 
-func getConfigJSONOrYAMLData(logger *zap.Logger, data string) (*Config, error) {
+func getConfigJSONOrYAMLData(logger *zap.Logger, data string, profile string, envContainer app.EnvContainer) (*Config, error) {
 	return getConfig(
 		logger,
 		encoding.UnmarshalJSONOrYAMLNonStrict,
 		encoding.UnmarshalJSONOrYAMLStrict,
 		[]byte(data),
 		"Generate configuration data",
+		profile,
+		envContainer,
 	)
 }
 
@@ -99,6 +125,8 @@ func getConfig(
 	unmarshalStrict func([]byte, interface{}) error,
 	data []byte,
 	id string,
+	profile string,
+	envContainer app.EnvContainer,
 ) (*Config, error) {
 	var externalConfigVersion ExternalConfigVersion
 	if err := unmarshalNonStrict(data, &externalConfigVersion); err != nil {
@@ -115,6 +143,20 @@ func getConfig(
 		}
 		return newConfigV1Beta1(externalConfigV1Beta1, id)
 	case V1Version:
+		if profile != "" {
+			var err error
+			data, err = applyConfigProfile(data, unmarshalNonStrict, id, profile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if envContainer != nil {
+			var err error
+			data, err = envexpand.ExpandYAML(data, envContainer, expandedExternalConfigPaths...)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", id, err)
+			}
+		}
 		var externalConfigV1 ExternalConfigV1
 		if err := unmarshalStrict(data, &externalConfigV1); err != nil {
 			return nil, err
@@ -155,6 +197,7 @@ func newConfigV1(logger *zap.Logger, externalConfig ExternalConfigV1, id string)
 			Out:        plugin.Out,
 			Opt:        opt,
 			Path:       path,
+			SHA256:     plugin.SHA256,
 			ProtocPath: plugin.ProtocPath,
 			Strategy:   strategy,
 		}
@@ -165,10 +208,12 @@ func newConfigV1(logger *zap.Logger, externalConfig ExternalConfigV1, id string)
 		pluginConfigs = append(pluginConfigs, pluginConfig)
 	}
 	typesConfig := newTypesConfigV1(externalConfig.Types)
+	inputConfigs := newInputConfigsV1(externalConfig.Inputs)
 	return &Config{
 		PluginConfigs: pluginConfigs,
 		ManagedConfig: managedConfig,
 		TypesConfig:   typesConfig,
+		InputConfigs:  inputConfigs,
 	}, nil
 }
 
@@ -195,6 +240,14 @@ func validateExternalConfigV1(externalConfig ExternalConfigV1, id string) error
 		if plugin.Out == "" {
 			return fmt.Errorf("%s: plugin %s out is required", id, pluginIdentifier)
 		}
+		if plugin.SHA256 != "" {
+			if plugin.Path == nil {
+				return fmt.Errorf("%s: plugin %s cannot specify a sha256 without a path", id, pluginIdentifier)
+			}
+			if !isValidSHA256(plugin.SHA256) {
+				return fmt.Errorf("%s: plugin %s sha256 %q must be a hex-encoded SHA-256 digest", id, pluginIdentifier, plugin.SHA256)
+			}
+		}
 		switch {
 		case plugin.Plugin != "":
 			if bufpluginref.IsPluginReferenceOrIdentity(pluginIdentifier) {
@@ -202,6 +255,9 @@ func validateExternalConfigV1(externalConfig ExternalConfigV1, id string) error
 				if err := checkPathAndStrategyUnset(id, plugin, pluginIdentifier); err != nil {
 					return err
 				}
+				if _, _, err := bufpluginref.ParsePluginIdentityVersionConstraint(pluginIdentifier); err == nil && plugin.Revision != 0 {
+					return fmt.Errorf("%s: remote plugin %s cannot specify a revision with a version constraint", id, pluginIdentifier)
+				}
 			} else {
 				// plugin.Plugin is a local plugin - verify it isn't using an alpha remote plugin path
 				if _, _, _, _, err := bufremoteplugin.ParsePluginVersionPath(pluginIdentifier); err == nil {
@@ -229,6 +285,14 @@ func validateExternalConfigV1(externalConfig ExternalConfigV1, id string) error
 			return errors.New("one of plugin, name, or remote is required")
 		}
 	}
+	for i, input := range externalConfig.Inputs {
+		if input.Input == "" {
+			return fmt.Errorf("%s: inputs[%d]: input is required", id, i)
+		}
+		if input.IncludeWKT && !input.IncludeImports {
+			return fmt.Errorf("%s: input %s: include_wkt cannot be set without include_imports", id, input.Input)
+		}
+	}
 	return nil
 }
 
@@ -245,6 +309,11 @@ func checkPathAndStrategyUnset(id string, plugin ExternalPluginConfigV1, pluginI
 	return nil
 }
 
+func isValidSHA256(value string) bool {
+	decoded, err := hex.DecodeString(value)
+	return err == nil && len(decoded) == sha256.Size
+}
+
 func newManagedConfigV1(logger *zap.Logger, externalManagedConfig ExternalManagedConfigV1) (*ManagedConfig, error) {
 	if !externalManagedConfig.Enabled {
 		if !externalManagedConfig.IsEmpty() && logger != nil {
@@ -279,6 +348,16 @@ func newManagedConfigV1(logger *zap.Logger, externalManagedConfig ExternalManage
 	override := externalManagedConfig.Override
 	for overrideID, overrideValue := range override {
 		for importPath := range overrideValue {
+			if strings.HasPrefix(importPath, bufimagemodify.OverridePackageKeyPrefix) {
+				if strings.TrimPrefix(importPath, bufimagemodify.OverridePackageKeyPrefix) == "" {
+					return nil, fmt.Errorf(
+						"override package key %s must specify a package for override: %s",
+						importPath,
+						overrideID,
+					)
+				}
+				continue
+			}
 			normalizedImportPath, err := normalpath.NormalizeAndValidate(importPath)
 			if err != nil {
 				return nil, fmt.Errorf(
@@ -296,6 +375,10 @@ func newManagedConfigV1(logger *zap.Logger, externalManagedConfig ExternalManage
 			}
 		}
 	}
+	optionInjections, err := newOptionInjectionsV1(externalManagedConfig.Options)
+	if err != nil {
+		return nil, err
+	}
 	return &ManagedConfig{
 		CcEnableArenas:          externalManagedConfig.CcEnableArenas,
 		JavaMultipleFiles:       externalManagedConfig.JavaMultipleFiles,
@@ -307,9 +390,59 @@ func newManagedConfigV1(logger *zap.Logger, externalManagedConfig ExternalManage
 		ObjcClassPrefixConfig:   objcClassPrefixConfig,
 		RubyPackageConfig:       rubyPackageConfig,
 		Override:                override,
+		OptionInjections:        optionInjections,
 	}, nil
 }
 
+func newOptionInjectionsV1(externalOptionInjectionConfigs []ExternalOptionInjectionConfigV1) ([]*bufimagemodify.OptionInjection, error) {
+	if len(externalOptionInjectionConfigs) == 0 {
+		return nil, nil
+	}
+	optionInjections := make([]*bufimagemodify.OptionInjection, 0, len(externalOptionInjectionConfigs))
+	for _, externalOptionInjectionConfig := range externalOptionInjectionConfigs {
+		if externalOptionInjectionConfig.Option == "" {
+			return nil, errors.New("managed mode options require an option name")
+		}
+		if externalOptionInjectionConfig.Value == "" {
+			return nil, fmt.Errorf("managed mode option %s requires a value", externalOptionInjectionConfig.Option)
+		}
+		target, err := bufimagemodify.ParseOptionTarget(externalOptionInjectionConfig.Target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target for managed mode option %s: %w", externalOptionInjectionConfig.Option, err)
+		}
+		for _, key := range externalOptionInjectionConfig.Match {
+			if strings.HasPrefix(key, bufimagemodify.OverridePackageKeyPrefix) {
+				if strings.TrimPrefix(key, bufimagemodify.OverridePackageKeyPrefix) == "" {
+					return nil, fmt.Errorf(
+						"match package key %s must specify a package for managed mode option: %s",
+						key,
+						externalOptionInjectionConfig.Option,
+					)
+				}
+				continue
+			}
+			normalizedKey, err := normalpath.NormalizeAndValidate(key)
+			if err != nil || key != normalizedKey {
+				return nil, fmt.Errorf(
+					"match can only take normalized import paths, invalid import path: %s provided for managed mode option: %s",
+					key,
+					externalOptionInjectionConfig.Option,
+				)
+			}
+		}
+		optionInjections = append(
+			optionInjections,
+			&bufimagemodify.OptionInjection{
+				Option: externalOptionInjectionConfig.Option,
+				Value:  externalOptionInjectionConfig.Value,
+				Target: target,
+				Match:  externalOptionInjectionConfig.Match,
+			},
+		)
+	}
+	return optionInjections, nil
+}
+
 func newJavaPackagePrefixConfigV1(externalJavaPackagePrefixConfig ExternalJavaPackagePrefixConfigV1) (*JavaPackagePrefixConfig, error) {
 	if externalJavaPackagePrefixConfig.IsEmpty() {
 		return nil, nil
@@ -648,14 +781,71 @@ func enumMapToStringSlice(enums map[string]int32) []string {
 	return slice
 }
 
+// applyConfigProfile returns the given v1 configuration data with the plugins,
+// managed, types, and inputs keys replaced by the keys that the given profile sets.
+//
+// The data is returned as is if it does not define any profiles.
+func applyConfigProfile(
+	data []byte,
+	unmarshalNonStrict func([]byte, interface{}) error,
+	id string,
+	profile string,
+) ([]byte, error) {
+	externalConfig := make(map[string]interface{})
+	if err := unmarshalNonStrict(data, &externalConfig); err != nil {
+		return nil, err
+	}
+	value, ok := externalConfig["profiles"]
+	if !ok || value == nil {
+		return data, nil
+	}
+	externalProfiles, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: profiles must be a map, but was %T", id, value)
+	}
+	value, ok = externalProfiles[profile]
+	if !ok {
+		profiles := make([]string, 0, len(externalProfiles))
+		for profile := range externalProfiles {
+			profiles = append(profiles, profile)
+		}
+		sort.Strings(profiles)
+		return nil, fmt.Errorf("%s: profile %q is not defined, the defined profiles are: %s", id, profile, strings.Join(profiles, ", "))
+	}
+	if value == nil {
+		return data, nil
+	}
+	externalProfile, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: profile %q must be a map, but was %T", id, profile, value)
+	}
+	for _, key := range []string{"plugins", "managed", "types", "inputs"} {
+		if value, ok := externalProfile[key]; ok {
+			externalConfig[key] = value
+		}
+	}
+	return encoding.MarshalYAML(externalConfig)
+}
+
 type readConfigOptions struct {
-	override string
+	override     string
+	profile      string
+	envContainer app.EnvContainer
 }
 
 func newReadConfigOptions() *readConfigOptions {
 	return &readConfigOptions{}
 }
 
+type getConfigOptions struct {
+	profile      string
+	envContainer app.EnvContainer
+}
+
+func newGetConfigOptions() *getConfigOptions {
+	return &getConfigOptions{}
+}
+
 func newTypesConfigV1(externalConfig ExternalTypesConfigV1) *TypesConfig {
 	if externalConfig.IsEmpty() {
 		return nil
@@ -664,3 +854,24 @@ func newTypesConfigV1(externalConfig ExternalTypesConfigV1) *TypesConfig {
 		Include: externalConfig.Include,
 	}
 }
+
+func newInputConfigsV1(externalInputConfigs []ExternalInputConfigV1) []*InputConfig {
+	if len(externalInputConfigs) == 0 {
+		return nil
+	}
+	inputConfigs := make([]*InputConfig, 0, len(externalInputConfigs))
+	for _, externalInputConfig := range externalInputConfigs {
+		inputConfigs = append(
+			inputConfigs,
+			&InputConfig{
+				Input:          externalInputConfig.Input,
+				Types:          externalInputConfig.Types,
+				Paths:          externalInputConfig.Paths,
+				ExcludePaths:   externalInputConfig.ExcludePaths,
+				IncludeImports: externalInputConfig.IncludeImports,
+				IncludeWKT:     externalInputConfig.IncludeWKT,
+			},
+		)
+	}
+	return inputConfigs
+}
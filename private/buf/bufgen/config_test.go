@@ -22,6 +22,7 @@ import (
 
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagemodify"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/stretchr/testify/assert"
@@ -588,6 +589,112 @@ func TestReadConfigV1(t *testing.T) {
 	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(string(data)))
 	require.NoError(t, err)
 	assertConfigsWithEqualOptimizeFor(t, successConfig9, config)
+	successConfig10 := &Config{
+		PluginConfigs: []*PluginConfig{
+			{
+				Name:     "go",
+				Out:      "gen/go",
+				Strategy: StrategyDirectory,
+			},
+		},
+		InputConfigs: []*InputConfig{
+			{
+				Input:          "proto",
+				Types:          []string{"a.v1.Foo"},
+				Paths:          []string{"proto/a"},
+				ExcludePaths:   []string{"proto/a/internal"},
+				IncludeImports: true,
+				IncludeWKT:     true,
+			},
+			{
+				Input: "buf.build/acme/petapis",
+			},
+		},
+	}
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success10.yaml")))
+	require.NoError(t, err)
+	require.Equal(t, successConfig10, config)
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success10.json")))
+	require.NoError(t, err)
+	require.Equal(t, successConfig10, config)
+	successConfig11 := &Config{
+		PluginConfigs: []*PluginConfig{
+			{
+				Name:     "foo",
+				Out:      "gen/foo",
+				Path:     []string{"./bin/protoc-gen-foo"},
+				SHA256:   "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+				Strategy: StrategyDirectory,
+			},
+		},
+	}
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success11.yaml")))
+	require.NoError(t, err)
+	require.Equal(t, successConfig11, config)
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success11.json")))
+	require.NoError(t, err)
+	require.Equal(t, successConfig11, config)
+
+	successConfig12 := &Config{
+		PluginConfigs: []*PluginConfig{
+			{
+				Name:     "java",
+				Out:      "gen/java",
+				Strategy: StrategyDirectory,
+			},
+		},
+		ManagedConfig: &ManagedConfig{
+			Override: map[string]map[string]string{
+				bufimagemodify.JavaPackageID: {
+					"acme/**/*.proto":         "com.acme",
+					"package:acme.weather.v1": "com.acme.weather",
+				},
+			},
+		},
+	}
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success12.yaml")))
+	require.NoError(t, err)
+	require.Equal(t, successConfig12, config)
+
+	successConfig13 := &Config{
+		PluginConfigs: []*PluginConfig{
+			{
+				Name:     "java",
+				Out:      "gen/java",
+				Strategy: StrategyDirectory,
+			},
+		},
+		ManagedConfig: &ManagedConfig{
+			OptionInjections: []*bufimagemodify.OptionInjection{
+				{
+					Option: "acme.api.version",
+					Value:  "v1",
+					Target: bufimagemodify.OptionTargetFile,
+				},
+				{
+					Option: "acme.api.audited",
+					Value:  "true",
+					Target: bufimagemodify.OptionTargetMessage,
+					Match:  []string{"acme/orders/**/*.proto", "package:acme.billing.*"},
+				},
+			},
+		},
+	}
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success13.yaml")))
+	require.NoError(t, err)
+	require.Equal(t, successConfig13, config)
+	successConfig14 := &Config{
+		PluginConfigs: []*PluginConfig{
+			{
+				Name:     "doc",
+				Out:      "gen/doc",
+				Strategy: StrategyPackage,
+			},
+		},
+	}
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success14.yaml")))
+	require.NoError(t, err)
+	require.Equal(t, successConfig14, config)
 
 	testReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error1.yaml"))
 	testReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error2.yaml"))
@@ -605,6 +712,13 @@ func TestReadConfigV1(t *testing.T) {
 	testReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error14.yaml"))
 	testReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error15.yaml"))
 	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error15.yaml"), "the remote field no longer works")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error16.yaml"), "input is required")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error17.yaml"), "include_wkt cannot be set without include_imports")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error18.yaml"), "cannot specify a revision with a version constraint")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error19.yaml"), "cannot specify a sha256 without a path")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error20.yaml"), "must be a hex-encoded SHA-256 digest")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error21.yaml"), "must specify a package")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error22.yaml"), "unknown option target")
 
 	successConfig = &Config{
 		PluginConfigs: []*PluginConfig{
@@ -650,6 +764,83 @@ func TestReadConfigV1(t *testing.T) {
 	testReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "go_gen_error6.yaml"))
 }
 
+func TestReadConfigV1WithProfile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	nopLogger := zap.NewNop()
+	provider := NewProvider(zap.NewNop())
+	testFilePath := filepath.Join("testdata", "v1", "gen_profile1.yaml")
+	data, err := os.ReadFile(testFilePath)
+	require.NoError(t, err)
+	readBucket, err := storagemem.NewReadBucket(map[string][]byte{ExternalConfigFilePath: data})
+	require.NoError(t, err)
+	config, err := ReadConfig(ctx, nopLogger, provider, readBucket)
+	require.NoError(t, err)
+	require.Len(t, config.PluginConfigs, 1)
+	assert.NotNil(t, config.ManagedConfig)
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithProfile("ci"))
+	require.NoError(t, err)
+	require.Len(t, config.PluginConfigs, 2)
+	assert.Equal(t, "gen/doc", config.PluginConfigs[1].Out)
+	assert.Nil(t, config.ManagedConfig)
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(testFilePath), ReadConfigWithProfile("ci"))
+	require.NoError(t, err)
+	require.Len(t, config.PluginConfigs, 2)
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithProfile("local"))
+	require.NoError(t, err)
+	require.Len(t, config.PluginConfigs, 1)
+	assert.NotNil(t, config.ManagedConfig)
+	_, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithProfile("strict"))
+	assert.ErrorContains(t, err, `profile "strict" is not defined, the defined profiles are: ci, local`)
+	// Configurations that do not define any profiles are unaffected.
+	config, err = ReadConfig(
+		ctx,
+		nopLogger,
+		provider,
+		readBucket,
+		ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success14.yaml")),
+		ReadConfigWithProfile("strict"),
+	)
+	require.NoError(t, err)
+	require.Len(t, config.PluginConfigs, 1)
+}
+
+func TestReadConfigV1WithEnvContainer(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	nopLogger := zap.NewNop()
+	provider := NewProvider(zap.NewNop())
+	data := `version: v1
+plugins:
+  - plugin: ${BUF_REMOTE:-buf.build}/protocolbuffers/go:v1.28.1
+    revision: ${GO_REVISION}
+    out: gen/${GO_REVISION}
+`
+	config, err := ReadConfig(
+		ctx,
+		nopLogger,
+		provider,
+		nil,
+		ReadConfigWithOverride(data),
+		ReadConfigWithEnvContainer(app.NewEnvContainer(map[string]string{"GO_REVISION": "2"})),
+	)
+	require.NoError(t, err)
+	require.Len(t, config.PluginConfigs, 1)
+	assert.Equal(t, "buf.build/protocolbuffers/go:v1.28.1", config.PluginConfigs[0].Plugin)
+	assert.Equal(t, 2, config.PluginConfigs[0].Revision)
+	// Environment variables are only expanded in the plugin, remote, and revision keys.
+	assert.Equal(t, "gen/${GO_REVISION}", config.PluginConfigs[0].Out)
+	_, err = ReadConfig(
+		ctx,
+		nopLogger,
+		provider,
+		nil,
+		ReadConfigWithOverride(data),
+		ReadConfigWithEnvContainer(app.NewEnvContainer(nil)),
+	)
+	assert.ErrorContains(t, err, "environment variable GO_REVISION is not set")
+}
+
 func testReadConfigError(t *testing.T, logger *zap.Logger, provider Provider, readBucket storage.ReadBucket, testFilePath string) {
 	ctx := context.Background()
 	_, err := ReadConfig(ctx, logger, provider, readBucket, ReadConfigWithOverride(testFilePath))
@@ -101,6 +101,8 @@ func (g *generator) Generate(
 		generateOptions.includeImports,
 		generateOptions.includeWellKnownTypes,
 		generateOptions.wasmEnabled,
+		generateOptions.manifest,
+		generateOptions.manifestInput,
 	)
 }
 
@@ -113,7 +115,17 @@ func (g *generator) generate(
 	includeImports bool,
 	includeWellKnownTypes bool,
 	wasmEnabled bool,
+	manifest *Manifest,
+	manifestInput string,
 ) error {
+	if manifest != nil {
+		// The input is recorded as it was built, before managed mode modifies it.
+		input, err := newManifestInput(manifestInput, image)
+		if err != nil {
+			return err
+		}
+		manifest.Inputs = append(manifest.Inputs, input)
+	}
 	if err := modifyImage(ctx, g.logger, config, image); err != nil {
 		return err
 	}
@@ -135,11 +147,13 @@ func (g *generator) generate(
 		g.storageosProvider,
 		appprotoos.ResponseWriterWithCreateOutDirIfNotExists(),
 	)
+	outs := make([]string, len(config.PluginConfigs))
 	for i, pluginConfig := range config.PluginConfigs {
 		out := pluginConfig.Out
 		if baseOutDirPath != "" && baseOutDirPath != "." {
 			out = filepath.Join(baseOutDirPath, out)
 		}
+		outs[i] = out
 		response := responses[i]
 		if response == nil {
 			return fmt.Errorf("failed to get plugin response for %s", pluginConfig.PluginName())
@@ -155,6 +169,16 @@ func (g *generator) generate(
 	if err := responseWriter.Close(); err != nil {
 		return err
 	}
+	if manifest != nil {
+		if err := addManifestPlugins(manifest, config.PluginConfigs); err != nil {
+			return err
+		}
+		for i, pluginConfig := range config.PluginConfigs {
+			if err := addManifestFiles(manifest, pluginConfig, outs[i], responses[i]); err != nil {
+				return fmt.Errorf("plugin %s: %v", pluginConfig.PluginName(), err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -284,6 +308,12 @@ func (g *generator) execLocalPlugin(
 		bufpluginexec.GenerateWithPluginPath(pluginConfig.Path...),
 		bufpluginexec.GenerateWithProtocPath(pluginConfig.ProtocPath),
 	}
+	if pluginConfig.SHA256 != "" {
+		generateOptions = append(
+			generateOptions,
+			bufpluginexec.GenerateWithPluginSHA256(pluginConfig.SHA256),
+		)
+	}
 	if wasmEnabled {
 		generateOptions = append(
 			generateOptions,
@@ -579,6 +609,16 @@ func newModifier(
 		modifier,
 		rubyPackageModifier,
 	)
+	if len(managedConfig.OptionInjections) > 0 {
+		modifier = bufimagemodify.Merge(
+			modifier,
+			bufimagemodify.InjectOptions(
+				logger,
+				sweeper,
+				managedConfig.OptionInjections,
+			),
+		)
+	}
 	return modifier, nil
 }
 
@@ -618,6 +658,8 @@ type generateOptions struct {
 	includeImports        bool
 	includeWellKnownTypes bool
 	wasmEnabled           bool
+	manifest              *Manifest
+	manifestInput         string
 }
 
 func newGenerateOptions() *generateOptions {
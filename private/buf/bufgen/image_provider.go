@@ -24,13 +24,14 @@ import (
 // imageProvider is used to provide the images used
 // when generating with a local plugin. Each plugin is
 // in control of its own Strategy - we cache the
-// imagesByDir so that we only have to build it once for
-// all of the plugins that configure the the Directory
-// strategy.
+// imagesByDir and imagesByPackage so that we only have to
+// build them once for all of the plugins that configure the
+// Directory and Package strategies.
 type imageProvider struct {
-	image       bufimage.Image
-	imagesByDir []bufimage.Image
-	lock        sync.Mutex
+	image           bufimage.Image
+	imagesByDir     []bufimage.Image
+	imagesByPackage []bufimage.Image
+	lock            sync.Mutex
 }
 
 func newImageProvider(image bufimage.Image) *imageProvider {
@@ -54,6 +55,17 @@ func (p *imageProvider) GetImages(strategy Strategy) ([]bufimage.Image, error) {
 			}
 		}
 		return p.imagesByDir, nil
+	case StrategyPackage:
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if p.imagesByPackage == nil {
+			var err error
+			p.imagesByPackage, err = bufimage.ImageByPackage(p.image)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return p.imagesByPackage, nil
 	default:
 		return nil, fmt.Errorf("unknown strategy: %v", strategy)
 	}
@@ -0,0 +1,279 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	connect "connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufplugin/bufpluginref"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// lockHeader is the header prepended to any lock files.
+const lockHeader = "# Generated by buf. DO NOT EDIT.\n"
+
+// getPluginVersionsFunc returns the versions of the plugin, semver-sorted
+// in descending order, with the revisions of each version also sorted in
+// descending order.
+type getPluginVersionsFunc func(
+	ctx context.Context,
+	identity bufpluginref.PluginIdentity,
+) ([]*registryv1alpha1.CuratedPluginVersionRevisions, error)
+
+func readLock(ctx context.Context, readBucket storage.ReadBucket) (*Lock, error) {
+	data, err := storage.ReadPath(ctx, readBucket, ExternalLockFilePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &Lock{}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", ExternalLockFilePath, err)
+	}
+	var externalConfigVersion ExternalConfigVersion
+	if err := encoding.UnmarshalYAMLNonStrict(data, &externalConfigVersion); err != nil {
+		return nil, fmt.Errorf("failed to decode %s as YAML: %w", ExternalLockFilePath, err)
+	}
+	if externalConfigVersion.Version != V1Version {
+		return nil, fmt.Errorf("unknown %s version %q", ExternalLockFilePath, externalConfigVersion.Version)
+	}
+	var externalLock ExternalLockV1
+	if err := encoding.UnmarshalYAMLStrict(data, &externalLock); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", ExternalLockFilePath, err)
+	}
+	lock := &Lock{
+		Plugins: make([]*LockedPlugin, 0, len(externalLock.Plugins)),
+	}
+	for _, externalLockPlugin := range externalLock.Plugins {
+		if err := bufpluginref.ValidatePluginVersion(externalLockPlugin.Version); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ExternalLockFilePath, err)
+		}
+		lock.Plugins = append(
+			lock.Plugins,
+			&LockedPlugin{
+				Plugin:     externalLockPlugin.Plugin,
+				Constraint: externalLockPlugin.Constraint,
+				Version:    externalLockPlugin.Version,
+				Revision:   externalLockPlugin.Revision,
+			},
+		)
+	}
+	return lock, nil
+}
+
+func writeLock(ctx context.Context, writeBucket storage.WriteBucket, lock *Lock) error {
+	externalLock := ExternalLockV1{
+		Version: V1Version,
+		Plugins: make([]ExternalLockPluginV1, 0, len(lock.Plugins)),
+	}
+	for _, lockedPlugin := range lock.Plugins {
+		externalLock.Plugins = append(
+			externalLock.Plugins,
+			ExternalLockPluginV1{
+				Plugin:     lockedPlugin.Plugin,
+				Constraint: lockedPlugin.Constraint,
+				Version:    lockedPlugin.Version,
+				Revision:   lockedPlugin.Revision,
+			},
+		)
+	}
+	data, err := encoding.MarshalYAML(&externalLock)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", ExternalLockFilePath, err)
+	}
+	if err := storage.PutPath(ctx, writeBucket, ExternalLockFilePath, append([]byte(lockHeader), data...)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ExternalLockFilePath, err)
+	}
+	return nil
+}
+
+func resolvePluginVersions(
+	ctx context.Context,
+	logger *zap.Logger,
+	clientConfig *connectclient.Config,
+	readWriteBucket storage.ReadWriteBucket,
+	config *Config,
+	options ...ResolvePluginVersionsOption,
+) error {
+	resolvePluginVersionsOptions := newResolvePluginVersionsOptions()
+	for _, option := range options {
+		option(resolvePluginVersionsOptions)
+	}
+	getPluginVersions := func(
+		ctx context.Context,
+		identity bufpluginref.PluginIdentity,
+	) ([]*registryv1alpha1.CuratedPluginVersionRevisions, error) {
+		pluginCurationService := connectclient.Make(
+			clientConfig,
+			identity.Remote(),
+			registryv1alpha1connect.NewPluginCurationServiceClient,
+		)
+		response, err := pluginCurationService.GetLatestCuratedPlugin(
+			ctx,
+			connect.NewRequest(
+				&registryv1alpha1.GetLatestCuratedPluginRequest{
+					Owner: identity.Owner(),
+					Name:  identity.Plugin(),
+				},
+			),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return response.Msg.Versions, nil
+	}
+	return resolvePluginVersionsForGetter(
+		ctx,
+		logger,
+		readWriteBucket,
+		config,
+		getPluginVersions,
+		resolvePluginVersionsOptions.locked,
+	)
+}
+
+func resolvePluginVersionsForGetter(
+	ctx context.Context,
+	logger *zap.Logger,
+	readWriteBucket storage.ReadWriteBucket,
+	config *Config,
+	getPluginVersions getPluginVersionsFunc,
+	locked bool,
+) error {
+	lock, err := readLock(ctx, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	keyToLockedPlugin := make(map[string]*LockedPlugin, len(lock.Plugins))
+	for _, lockedPlugin := range lock.Plugins {
+		keyToLockedPlugin[lockedPluginKey(lockedPlugin.Plugin, lockedPlugin.Constraint)] = lockedPlugin
+	}
+	keyToResolvedPlugin := make(map[string]*LockedPlugin)
+	var resolvedPlugins []*LockedPlugin
+	for _, pluginConfig := range config.PluginConfigs {
+		identity, constraint, err := bufpluginref.ParsePluginIdentityVersionConstraint(pluginConfig.Plugin)
+		if err != nil {
+			// Not a version constraint, nothing to resolve.
+			continue
+		}
+		key := lockedPluginKey(identity.IdentityString(), constraint.String())
+		resolvedPlugin, ok := keyToResolvedPlugin[key]
+		if !ok {
+			resolvedPlugin, ok = keyToLockedPlugin[key]
+			if ok && !constraint.Matches(resolvedPlugin.Version) {
+				if locked {
+					return fmt.Errorf(
+						"%s: locked version %s of plugin %s does not match constraint %q",
+						ExternalLockFilePath,
+						resolvedPlugin.Version,
+						identity.IdentityString(),
+						constraint.String(),
+					)
+				}
+				ok = false
+			}
+			if !ok {
+				if locked {
+					return fmt.Errorf(
+						"%s: plugin %s with constraint %q is not locked, run buf generate without --locked to update the lock file",
+						ExternalLockFilePath,
+						identity.IdentityString(),
+						constraint.String(),
+					)
+				}
+				resolvedPlugin, err = resolvePluginVersion(ctx, identity, constraint, getPluginVersions)
+				if err != nil {
+					return err
+				}
+				logger.Debug(
+					"resolved_plugin_version",
+					zap.String("plugin", resolvedPlugin.Plugin),
+					zap.String("constraint", resolvedPlugin.Constraint),
+					zap.String("version", resolvedPlugin.Version),
+					zap.Int("revision", resolvedPlugin.Revision),
+				)
+			}
+			keyToResolvedPlugin[key] = resolvedPlugin
+			resolvedPlugins = append(resolvedPlugins, resolvedPlugin)
+		}
+		pluginConfig.Plugin = resolvedPlugin.Plugin + ":" + resolvedPlugin.Version
+		pluginConfig.Revision = resolvedPlugin.Revision
+	}
+	if lockedPluginsEqual(lock.Plugins, resolvedPlugins) {
+		return nil
+	}
+	if locked {
+		return fmt.Errorf(
+			"%s is out of date with the plugins in the generation template, run buf generate without --locked to update it",
+			ExternalLockFilePath,
+		)
+	}
+	return writeLock(ctx, readWriteBucket, &Lock{Plugins: resolvedPlugins})
+}
+
+func resolvePluginVersion(
+	ctx context.Context,
+	identity bufpluginref.PluginIdentity,
+	constraint bufpluginref.PluginVersionConstraint,
+	getPluginVersions getPluginVersionsFunc,
+) (*LockedPlugin, error) {
+	versionRevisions, err := getPluginVersions(ctx, identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get versions of plugin %s: %w", identity.IdentityString(), err)
+	}
+	for _, versionRevision := range versionRevisions {
+		if !constraint.Matches(versionRevision.Version) || len(versionRevision.Revisions) == 0 {
+			continue
+		}
+		return &LockedPlugin{
+			Plugin:     identity.IdentityString(),
+			Constraint: constraint.String(),
+			Version:    versionRevision.Version,
+			Revision:   int(versionRevision.Revisions[0]),
+		}, nil
+	}
+	return nil, fmt.Errorf("no version of plugin %s matches constraint %q", identity.IdentityString(), constraint.String())
+}
+
+func lockedPluginsEqual(one []*LockedPlugin, two []*LockedPlugin) bool {
+	if len(one) != len(two) {
+		return false
+	}
+	for i := range one {
+		if *one[i] != *two[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func lockedPluginKey(plugin string, constraint string) string {
+	return plugin + ":" + constraint
+}
+
+type resolvePluginVersionsOptions struct {
+	locked bool
+}
+
+func newResolvePluginVersionsOptions() *resolvePluginVersionsOptions {
+	return &resolvePluginVersionsOptions{}
+}
@@ -0,0 +1,110 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufplugin/bufpluginref"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestResolvePluginVersions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	versions := []*registryv1alpha1.CuratedPluginVersionRevisions{
+		{Version: "v2.0.0", Revisions: []uint32{1}},
+		{Version: "v1.32.0", Revisions: []uint32{3, 2, 1}},
+		{Version: "v1.31.0", Revisions: []uint32{1}},
+	}
+	var numCalls int
+	getPluginVersions := func(
+		context.Context,
+		bufpluginref.PluginIdentity,
+	) ([]*registryv1alpha1.CuratedPluginVersionRevisions, error) {
+		numCalls++
+		return versions, nil
+	}
+	newTestConfig := func(plugin string) *Config {
+		return &Config{
+			PluginConfigs: []*PluginConfig{
+				{Plugin: plugin, Out: "gen/go", Strategy: StrategyAll},
+				{Plugin: "buf.build/protocolbuffers/java:v21.9", Out: "gen/java", Strategy: StrategyAll},
+			},
+		}
+	}
+
+	// Locked with no lock file fails.
+	config := newTestConfig("buf.build/protocolbuffers/go:^1.31")
+	err := resolvePluginVersionsForGetter(ctx, zap.NewNop(), readWriteBucket, config, getPluginVersions, true)
+	require.ErrorContains(t, err, "is not locked")
+
+	// Resolves to the latest matching version and revision, and writes the lock file.
+	err = resolvePluginVersionsForGetter(ctx, zap.NewNop(), readWriteBucket, config, getPluginVersions, false)
+	require.NoError(t, err)
+	assert.Equal(t, "buf.build/protocolbuffers/go:v1.32.0", config.PluginConfigs[0].Plugin)
+	assert.Equal(t, 3, config.PluginConfigs[0].Revision)
+	assert.Equal(t, "buf.build/protocolbuffers/java:v21.9", config.PluginConfigs[1].Plugin)
+	lock, err := ReadLock(ctx, readWriteBucket)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&Lock{
+			Plugins: []*LockedPlugin{
+				{
+					Plugin:     "buf.build/protocolbuffers/go",
+					Constraint: "^1.31",
+					Version:    "v1.32.0",
+					Revision:   3,
+				},
+			},
+		},
+		lock,
+	)
+	assert.Equal(t, 1, numCalls)
+
+	// Newer versions are not picked up while the constraint is unchanged.
+	versions = append([]*registryv1alpha1.CuratedPluginVersionRevisions{{Version: "v1.33.0", Revisions: []uint32{1}}}, versions...)
+	config = newTestConfig("buf.build/protocolbuffers/go:^1.31")
+	err = resolvePluginVersionsForGetter(ctx, zap.NewNop(), readWriteBucket, config, getPluginVersions, true)
+	require.NoError(t, err)
+	assert.Equal(t, "buf.build/protocolbuffers/go:v1.32.0", config.PluginConfigs[0].Plugin)
+	assert.Equal(t, 1, numCalls)
+
+	// Changing the constraint is drift.
+	config = newTestConfig("buf.build/protocolbuffers/go:~1.31")
+	err = resolvePluginVersionsForGetter(ctx, zap.NewNop(), readWriteBucket, config, getPluginVersions, true)
+	require.Error(t, err)
+	err = resolvePluginVersionsForGetter(ctx, zap.NewNop(), readWriteBucket, config, getPluginVersions, false)
+	require.NoError(t, err)
+	assert.Equal(t, "buf.build/protocolbuffers/go:v1.31.0", config.PluginConfigs[0].Plugin)
+	assert.Equal(t, 1, config.PluginConfigs[0].Revision)
+
+	// Removing the constraint leaves a stale entry, which is drift.
+	config = newTestConfig("buf.build/protocolbuffers/go:v1.31.0")
+	err = resolvePluginVersionsForGetter(ctx, zap.NewNop(), readWriteBucket, config, getPluginVersions, true)
+	require.ErrorContains(t, err, "is out of date")
+
+	// No matching version.
+	config = newTestConfig("buf.build/protocolbuffers/go:^3")
+	err = resolvePluginVersionsForGetter(ctx, zap.NewNop(), readWriteBucket, config, getPluginVersions, false)
+	require.ErrorContains(t, err, "no version of plugin")
+}
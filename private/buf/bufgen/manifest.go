@@ -0,0 +1,239 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufplugin/bufpluginref"
+	"github.com/bufbuild/buf/private/bufpkg/bufpluginexec"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const manifestDigestPrefix = "sha256:"
+
+func writeManifest(ctx context.Context, writeBucket storage.WriteBucket, manifest *Manifest) error {
+	externalManifest := ExternalManifestV1{
+		Version: V1Version,
+		Inputs:  make([]ExternalManifestInputV1, 0, len(manifest.Inputs)),
+		Plugins: make([]ExternalManifestPluginV1, 0, len(manifest.Plugins)),
+		Files:   make([]ExternalManifestFileV1, 0, len(manifest.Files)),
+	}
+	for _, manifestInput := range manifest.Inputs {
+		externalManifestInput := ExternalManifestInputV1{
+			Input:   manifestInput.Input,
+			Digest:  manifestInput.Digest,
+			Modules: make([]ExternalManifestModuleV1, 0, len(manifestInput.Modules)),
+		}
+		for _, manifestModule := range manifestInput.Modules {
+			externalManifestInput.Modules = append(
+				externalManifestInput.Modules,
+				ExternalManifestModuleV1{
+					Module: manifestModule.Module,
+					Commit: manifestModule.Commit,
+				},
+			)
+		}
+		externalManifest.Inputs = append(externalManifest.Inputs, externalManifestInput)
+	}
+	for _, manifestPlugin := range manifest.Plugins {
+		externalManifest.Plugins = append(
+			externalManifest.Plugins,
+			ExternalManifestPluginV1{
+				Name:     manifestPlugin.Name,
+				Version:  manifestPlugin.Version,
+				Revision: manifestPlugin.Revision,
+				Digest:   manifestPlugin.Digest,
+			},
+		)
+	}
+	for _, manifestFile := range manifest.Files {
+		externalManifest.Files = append(
+			externalManifest.Files,
+			ExternalManifestFileV1{
+				Path:   manifestFile.Path,
+				Plugin: manifestFile.Plugin,
+				Digest: manifestFile.Digest,
+			},
+		)
+	}
+	data, err := json.MarshalIndent(externalManifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", ExternalManifestFilePath, err)
+	}
+	if err := storage.PutPath(ctx, writeBucket, ExternalManifestFilePath, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ExternalManifestFilePath, err)
+	}
+	return nil
+}
+
+// newManifestInput returns the ManifestInput for the image built from the input.
+//
+// This must be called before the image is modified.
+func newManifestInput(input string, image bufimage.Image) (*ManifestInput, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(bufimage.ImageToProtoImage(image))
+	if err != nil {
+		return nil, err
+	}
+	moduleToCommit := make(map[string]string)
+	for _, imageFile := range image.Files() {
+		moduleIdentity := imageFile.ModuleIdentity()
+		if moduleIdentity == nil {
+			continue
+		}
+		moduleToCommit[moduleIdentity.IdentityString()] = imageFile.Commit()
+	}
+	manifestModules := make([]*ManifestModule, 0, len(moduleToCommit))
+	for module, commit := range moduleToCommit {
+		manifestModules = append(
+			manifestModules,
+			&ManifestModule{
+				Module: module,
+				Commit: commit,
+			},
+		)
+	}
+	sort.Slice(
+		manifestModules,
+		func(i int, j int) bool {
+			return manifestModules[i].Module < manifestModules[j].Module
+		},
+	)
+	return &ManifestInput{
+		Input:   input,
+		Digest:  getManifestDigest(data),
+		Modules: manifestModules,
+	}, nil
+}
+
+// addManifestPlugins adds the plugins to the manifest that are not already in it.
+func addManifestPlugins(manifest *Manifest, pluginConfigs []*PluginConfig) error {
+	seenPluginNames := make(map[string]struct{}, len(manifest.Plugins))
+	for _, manifestPlugin := range manifest.Plugins {
+		seenPluginNames[manifestPlugin.Name] = struct{}{}
+	}
+	for _, pluginConfig := range pluginConfigs {
+		pluginName := pluginConfig.PluginName()
+		if _, ok := seenPluginNames[pluginName]; ok {
+			continue
+		}
+		seenPluginNames[pluginName] = struct{}{}
+		manifestPlugin := &ManifestPlugin{
+			Name: pluginName,
+		}
+		if pluginConfig.IsRemote() {
+			if reference, err := bufpluginref.PluginReferenceForString(pluginConfig.Plugin, pluginConfig.Revision); err == nil {
+				manifestPlugin.Version = reference.Version()
+				manifestPlugin.Revision = reference.Revision()
+			}
+		} else if pluginPath, ok := bufpluginexec.LookPluginBinaryPath(pluginConfig.PluginName(), pluginConfig.Path...); ok {
+			digest, err := getManifestFileDigest(pluginPath)
+			if err != nil {
+				return fmt.Errorf("plugin %s: %w", pluginName, err)
+			}
+			manifestPlugin.Digest = digest
+		}
+		manifest.Plugins = append(manifest.Plugins, manifestPlugin)
+	}
+	return nil
+}
+
+// addManifestFiles adds the files written for the response of the plugin to the manifest.
+//
+// This must be called after the files are written, so that the digests include
+// the content added by insertion points.
+func addManifestFiles(manifest *Manifest, pluginConfig *PluginConfig, out string, response *pluginpb.CodeGeneratorResponse) error {
+	switch filepath.Ext(out) {
+	case ".jar", ".zip":
+		digest, err := getManifestFileDigest(out)
+		if err != nil {
+			return err
+		}
+		setManifestFile(
+			manifest,
+			&ManifestFile{
+				Path:   normalpath.Normalize(out),
+				Plugin: pluginConfig.PluginName(),
+				Digest: digest,
+			},
+		)
+		return nil
+	}
+	for _, file := range response.GetFile() {
+		if file.GetInsertionPoint() != "" {
+			// The file is recorded for the plugin that created it.
+			continue
+		}
+		path := normalpath.Join(normalpath.Normalize(out), file.GetName())
+		digest, err := getManifestFileDigest(normalpath.Unnormalize(path))
+		if err != nil {
+			return err
+		}
+		setManifestFile(
+			manifest,
+			&ManifestFile{
+				Path:   path,
+				Plugin: pluginConfig.PluginName(),
+				Digest: digest,
+			},
+		)
+	}
+	return nil
+}
+
+// setManifestFile adds the file to the manifest, replacing the file with the same
+// path if it was already generated, for example for a previous input.
+func setManifestFile(manifest *Manifest, manifestFile *ManifestFile) {
+	for i, existingManifestFile := range manifest.Files {
+		if existingManifestFile.Path == manifestFile.Path {
+			manifest.Files[i] = manifestFile
+			return
+		}
+	}
+	manifest.Files = append(manifest.Files, manifestFile)
+}
+
+func getManifestFileDigest(path string) (_ string, retErr error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, file.Close())
+	}()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return manifestDigestPrefix + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func getManifestDigest(data []byte) string {
+	digest := sha256.Sum256(data)
+	return manifestDigestPrefix + hex.EncodeToString(digest[:])
+}
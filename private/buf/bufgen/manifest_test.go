@@ -0,0 +1,164 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestAddManifestFiles(t *testing.T) {
+	t.Parallel()
+	out := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(out, "a"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(out, "a", "b.txt"), []byte("hello"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(out, "c.zip"), []byte("world"), 0600))
+	pluginConfig := &PluginConfig{Name: "test"}
+	manifest := NewManifest()
+	response := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:    proto.String("a/b.txt"),
+				Content: proto.String("hello"),
+			},
+			{
+				Name:           proto.String("a/b.txt"),
+				InsertionPoint: proto.String("point"),
+				Content:        proto.String("ignored"),
+			},
+		},
+	}
+	require.NoError(t, addManifestFiles(manifest, pluginConfig, out, response))
+	require.NoError(t, addManifestFiles(manifest, pluginConfig, filepath.Join(out, "c.zip"), response))
+	// Adding the same file again replaces it.
+	require.NoError(t, addManifestFiles(manifest, pluginConfig, out, response))
+	require.Len(t, manifest.Files, 2)
+	assert.Equal(t, normalpath.Join(normalpath.Normalize(out), "a/b.txt"), manifest.Files[0].Path)
+	assert.Equal(t, "test", manifest.Files[0].Plugin)
+	// echo -n hello | sha256sum
+	assert.Equal(t, "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", manifest.Files[0].Digest)
+	assert.Equal(t, normalpath.Join(normalpath.Normalize(out), "c.zip"), manifest.Files[1].Path)
+	// echo -n world | sha256sum
+	assert.Equal(t, "sha256:486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7", manifest.Files[1].Digest)
+}
+
+func TestAddManifestPlugins(t *testing.T) {
+	t.Parallel()
+	manifest := NewManifest()
+	require.NoError(
+		t,
+		addManifestPlugins(
+			manifest,
+			[]*PluginConfig{
+				{Plugin: "buf.build/protocolbuffers/go:v1.31.0", Revision: 2},
+				{Plugin: "buf.build/protocolbuffers/go:v1.31.0", Revision: 2},
+				{Name: "java"},
+			},
+		),
+	)
+	require.Len(t, manifest.Plugins, 2)
+	assert.Equal(
+		t,
+		&ManifestPlugin{
+			Name:     "buf.build/protocolbuffers/go:v1.31.0",
+			Version:  "v1.31.0",
+			Revision: 2,
+		},
+		manifest.Plugins[0],
+	)
+	assert.Equal(t, "java", manifest.Plugins[1].Name)
+	assert.Empty(t, manifest.Plugins[1].Version)
+}
+
+func TestWriteManifest(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	manifest := &Manifest{
+		Inputs: []*ManifestInput{
+			{
+				Input:  ".",
+				Digest: "sha256:abc",
+				Modules: []*ManifestModule{
+					{
+						Module: "buf.build/acme/weather",
+						Commit: "1234",
+					},
+				},
+			},
+		},
+		Plugins: []*ManifestPlugin{
+			{
+				Name:   "go",
+				Digest: "sha256:def",
+			},
+		},
+		Files: []*ManifestFile{
+			{
+				Path:   "gen/go/a.pb.go",
+				Plugin: "go",
+				Digest: "sha256:ghi",
+			},
+		},
+	}
+	require.NoError(t, WriteManifest(ctx, readWriteBucket, manifest))
+	data, err := storage.ReadPath(ctx, readWriteBucket, ExternalManifestFilePath)
+	require.NoError(t, err)
+	var externalManifest ExternalManifestV1
+	require.NoError(t, json.Unmarshal(data, &externalManifest))
+	assert.Equal(
+		t,
+		ExternalManifestV1{
+			Version: V1Version,
+			Inputs: []ExternalManifestInputV1{
+				{
+					Input:  ".",
+					Digest: "sha256:abc",
+					Modules: []ExternalManifestModuleV1{
+						{
+							Module: "buf.build/acme/weather",
+							Commit: "1234",
+						},
+					},
+				},
+			},
+			Plugins: []ExternalManifestPluginV1{
+				{
+					Name:   "go",
+					Digest: "sha256:def",
+				},
+			},
+			Files: []ExternalManifestFileV1{
+				{
+					Path:   "gen/go/a.pb.go",
+					Plugin: "go",
+					Digest: "sha256:ghi",
+				},
+			},
+		},
+		externalManifest,
+	)
+}
@@ -39,7 +39,15 @@ func newProvider(logger *zap.Logger) *provider {
 	}
 }
 
-func (p *provider) GetConfig(ctx context.Context, readBucket storage.ReadBucket) (_ *Config, retErr error) {
+func (p *provider) GetConfig(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	options ...GetConfigOption,
+) (_ *Config, retErr error) {
+	getConfigOptions := newGetConfigOptions()
+	for _, option := range options {
+		option(getConfigOptions)
+	}
 	ctx, span := p.tracer.Start(ctx, "get_config")
 	defer span.End()
 	defer func() {
@@ -68,5 +76,7 @@ func (p *provider) GetConfig(ctx context.Context, readBucket storage.ReadBucket)
 		encoding.UnmarshalYAMLStrict,
 		data,
 		`File "`+readObjectCloser.ExternalPath()+`"`,
+		getConfigOptions.profile,
+		getConfigOptions.envContainer,
 	)
 }
@@ -0,0 +1,92 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buflsp implements a language server for Protobuf files.
+//
+// The server speaks the Language Server Protocol over a reader and writer, and
+// provides diagnostics, go-to-definition, hover, and references. The workspace
+// or module of each file is built in the same way as buf build and buf lint
+// build it, so that the server reports the same errors and lint and breaking
+// change violations as the CLI.
+package buflsp
+
+import (
+	"context"
+	"io"
+
+	"github.com/bufbuild/buf/private/buf/bufwire"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"go.uber.org/zap"
+)
+
+// Server is a language server.
+type Server interface {
+	// Serve serves the Language Server Protocol on the reader and writer until
+	// the client sends the exit notification, or the reader is closed.
+	//
+	// Files are built when they are opened and saved. The input that a file is
+	// built as is the closest directory that contains the file and a buf.yaml
+	// or buf.work.yaml, or the directory of the file if there is no such directory.
+	Serve(
+		ctx context.Context,
+		container app.EnvStdinContainer,
+		reader io.Reader,
+		writer io.Writer,
+	) error
+}
+
+// NewServer returns a new Server.
+//
+// The imageConfigReader builds the inputs of files. The moduleReader reads the
+// dependency modules of the inputs, whose files are written to the directory at
+// dependencyDirPath when they are the target of a definition or reference, so
+// that clients can open them.
+func NewServer(
+	logger *zap.Logger,
+	imageConfigReader bufwire.ImageConfigReader,
+	moduleReader bufmodule.ModuleReader,
+	dependencyDirPath string,
+	options ...ServerOption,
+) Server {
+	return newServer(
+		logger,
+		imageConfigReader,
+		moduleReader,
+		dependencyDirPath,
+		options...,
+	)
+}
+
+// ServerOption is an option for a new Server.
+type ServerOption func(*server)
+
+// ServerWithAgainst returns a new ServerOption that checks the inputs for breaking
+// changes against the given input, in the same way as buf breaking --against.
+//
+// Relative paths in the input are relative to the working directory of the server.
+// By default, inputs are not checked for breaking changes.
+func ServerWithAgainst(against string) ServerOption {
+	return func(server *server) {
+		server.against = against
+	}
+}
+
+// ServerWithVersion returns a new ServerOption that sets the version that the
+// server reports to clients.
+func ServerWithVersion(version string) ServerOption {
+	return func(server *server) {
+		server.version = version
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufwire"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/git"
+	"github.com/bufbuild/buf/private/pkg/httpauth"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+// Order is an order.
+message Order {
+  string orderId = 1;
+  Customer customer = 2;
+}
+
+message Customer {}
+`
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	writeTestFile(t, dirPath, "buf.yaml", "version: v1\nlint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n")
+	orderFilePath := writeTestFile(t, dirPath, "acme/v1/order.proto", testOrderProto)
+	orderURI, err := pathToURI(orderFilePath)
+	require.NoError(t, err)
+
+	messages := runTestServer(
+		t,
+		newTestRequest(1, "initialize", map[string]interface{}{}),
+		newTestNotification("initialized", map[string]interface{}{}),
+		newTestNotification("textDocument/didOpen", didOpenTextDocumentParams{
+			TextDocument: textDocumentItem{URI: orderURI, LanguageID: "protobuf", Text: testOrderProto},
+		}),
+		newTestRequest(2, "textDocument/definition", newTestPositionParams(orderURI, 7, 3)),
+		newTestRequest(3, "textDocument/hover", newTestPositionParams(orderURI, 5, 9)),
+		newTestRequest(4, "textDocument/references", referenceParams{
+			textDocumentPositionParams: newTestPositionParams(orderURI, 10, 9),
+			Context:                    referenceContext{IncludeDeclaration: true},
+		}),
+		newTestRequest(5, "textDocument/codeAction", codeActionParams{
+			TextDocument: textDocumentIdentifier{URI: orderURI},
+			Range:        newTestLocation(orderURI, 6, 9, 16).Range,
+			Context: codeActionContext{
+				Diagnostics: []diagnostic{
+					{
+						Range:   newTestLocation(orderURI, 6, 9, 16).Range,
+						Code:    "FIELD_LOWER_SNAKE_CASE",
+						Source:  diagnosticSource,
+						Message: "Field name \"orderId\" should be lower_snake_case, such as \"order_id\".",
+						Data: &diagnosticData{
+							Edits: []textEdit{
+								{Range: newTestLocation(orderURI, 6, 9, 16).Range, NewText: "order_id"},
+							},
+						},
+					},
+					{
+						Range:  newTestLocation(orderURI, 0, 0, 1).Range,
+						Source: "other",
+					},
+				},
+			},
+		}),
+		newTestRequest(6, "textDocument/rename", newTestPositionParams(orderURI, 10, 9)),
+		newTestRequest(7, "shutdown", nil),
+		newTestNotification("exit", nil),
+	)
+	require.Len(t, messages, 8)
+
+	var initializeResult initializeResult
+	unmarshalTestResult(t, messages[0], &initializeResult)
+	assert.Equal(t, "buf", initializeResult.ServerInfo.Name)
+	assert.True(t, initializeResult.Capabilities.DefinitionProvider)
+
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[1].Method)
+	var publishDiagnosticsParams publishDiagnosticsParams
+	require.NoError(t, json.Unmarshal(messages[1].Params, &publishDiagnosticsParams))
+	assert.Equal(t, orderURI, publishDiagnosticsParams.URI)
+	require.Len(t, publishDiagnosticsParams.Diagnostics, 1)
+	assert.Equal(t, "FIELD_LOWER_SNAKE_CASE", publishDiagnosticsParams.Diagnostics[0].Code)
+	assert.Equal(t, diagnosticSeverityWarning, publishDiagnosticsParams.Diagnostics[0].Severity)
+	assert.Equal(t, 6, publishDiagnosticsParams.Diagnostics[0].Range.Start.Line)
+	require.NotNil(t, publishDiagnosticsParams.Diagnostics[0].Data)
+	assert.Equal(
+		t,
+		[]textEdit{{Range: newTestLocation(orderURI, 6, 9, 16).Range, NewText: "order_id"}},
+		publishDiagnosticsParams.Diagnostics[0].Data.Edits,
+	)
+
+	var definitionLocations []location
+	unmarshalTestResult(t, messages[2], &definitionLocations)
+	assert.Equal(
+		t,
+		[]location{newTestLocation(orderURI, 10, 8, 16)},
+		definitionLocations,
+	)
+
+	var hover hover
+	unmarshalTestResult(t, messages[3], &hover)
+	assert.Equal(t, markupKindMarkdown, hover.Contents.Kind)
+	assert.Equal(t, "```proto\nmessage acme.v1.Order\n```\n\nOrder is an order.", hover.Contents.Value)
+
+	var referenceLocations []location
+	unmarshalTestResult(t, messages[4], &referenceLocations)
+	assert.Equal(
+		t,
+		[]location{
+			newTestLocation(orderURI, 10, 8, 16),
+			newTestLocation(orderURI, 7, 2, 10),
+		},
+		referenceLocations,
+	)
+
+	var codeActions []codeAction
+	unmarshalTestResult(t, messages[5], &codeActions)
+	require.Len(t, codeActions, 1)
+	assert.Equal(t, codeActionKindQuickFix, codeActions[0].Kind)
+	assert.Equal(t, `Fix FIELD_LOWER_SNAKE_CASE: change to "order_id"`, codeActions[0].Title)
+	require.NotNil(t, codeActions[0].Edit)
+	assert.Equal(
+		t,
+		map[string][]textEdit{
+			orderURI: {{Range: newTestLocation(orderURI, 6, 9, 16).Range, NewText: "order_id"}},
+		},
+		codeActions[0].Edit.Changes,
+	)
+
+	require.NotNil(t, messages[6].Error)
+	assert.Equal(t, errorCodeMethodNotFound, messages[6].Error.Code)
+	assert.Nil(t, messages[7].Error)
+}
+
+func TestServerCompileError(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	writeTestFile(t, dirPath, "buf.yaml", "version: v1\n")
+	orderFilePath := writeTestFile(t, dirPath, "acme/v1/order.proto", testOrderProto)
+	brokenFilePath := writeTestFile(t, dirPath, "acme/v1/broken.proto", "syntax = \"proto3\";\n\npackage acme.v1;\n\nmessage Broken {\n  Unknown unknown = 1;\n}\n")
+	orderURI, err := pathToURI(orderFilePath)
+	require.NoError(t, err)
+	brokenURI, err := pathToURI(brokenFilePath)
+	require.NoError(t, err)
+
+	messages := runTestServer(
+		t,
+		newTestNotification("textDocument/didOpen", didOpenTextDocumentParams{
+			TextDocument: textDocumentItem{URI: brokenURI, LanguageID: "protobuf"},
+		}),
+		newTestRequest(1, "textDocument/definition", newTestPositionParams(orderURI, 7, 3)),
+	)
+	require.Len(t, messages, 2)
+
+	var publishDiagnosticsParams publishDiagnosticsParams
+	require.NoError(t, json.Unmarshal(messages[0].Params, &publishDiagnosticsParams))
+	assert.Equal(t, brokenURI, publishDiagnosticsParams.URI)
+	require.Len(t, publishDiagnosticsParams.Diagnostics, 1)
+	assert.Equal(t, diagnosticSeverityError, publishDiagnosticsParams.Diagnostics[0].Severity)
+	assert.Equal(t, 5, publishDiagnosticsParams.Diagnostics[0].Range.Start.Line)
+
+	// The input has never compiled, so there is nothing to go to.
+	var definitionLocations []location
+	unmarshalTestResult(t, messages[1], &definitionLocations)
+	assert.Empty(t, definitionLocations)
+}
+
+func TestURI(t *testing.T) {
+	t.Parallel()
+	filePath, err := filepath.Abs(filepath.Join("foo bar", "a.proto"))
+	require.NoError(t, err)
+	uri, err := pathToURI(filePath)
+	require.NoError(t, err)
+	assert.Contains(t, uri, "file:///")
+	assert.Contains(t, uri, "foo%20bar/a.proto")
+	roundTripFilePath, err := uriToPath(uri)
+	require.NoError(t, err)
+	assert.Equal(t, filePath, roundTripFilePath)
+	_, err = uriToPath("https://example.com/a.proto")
+	assert.Error(t, err)
+}
+
+// runTestServer serves the messages, and returns the messages that the server
+// writes in response.
+func runTestServer(t *testing.T, messages ...*message) []*message {
+	logger := zap.NewNop()
+	storageosProvider := storageos.NewProvider()
+	moduleReader := bufmodule.NewNopModuleReader()
+	imageConfigReader := bufwire.NewImageConfigReader(
+		logger,
+		storageosProvider,
+		buffetch.NewReader(
+			logger,
+			storageosProvider,
+			nil,
+			httpauth.NewNopAuthenticator(),
+			git.NewCloner(logger, storageosProvider, command.NewRunner(), git.ClonerOptions{}),
+			bufmodule.NewNopModuleResolver(),
+			moduleReader,
+		),
+		bufmodulebuild.NewModuleBucketBuilder(),
+		bufimagebuild.NewBuilder(logger, moduleReader),
+	)
+	input := bytes.NewBuffer(nil)
+	inputConn := newConn(nil, input)
+	for _, message := range messages {
+		require.NoError(t, inputConn.write(message))
+	}
+	output := bytes.NewBuffer(nil)
+	server := NewServer(logger, imageConfigReader, moduleReader, t.TempDir())
+	require.NoError(
+		t,
+		server.Serve(
+			context.Background(),
+			app.NewContainer(nil, nil, nil, nil),
+			input,
+			output,
+		),
+	)
+	outputConn := newConn(output, nil)
+	var outputMessages []*message
+	for {
+		message, err := outputConn.read()
+		if errors.Is(err, io.EOF) {
+			return outputMessages
+		}
+		require.NoError(t, err)
+		outputMessages = append(outputMessages, message)
+	}
+}
+
+func writeTestFile(t *testing.T, dirPath string, path string, content string) string {
+	filePath := filepath.Join(dirPath, filepath.FromSlash(path))
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0600))
+	return filePath
+}
+
+func newTestRequest(id int, method string, params interface{}) *message {
+	message := newTestNotification(method, params)
+	rawID := json.RawMessage(fmt.Sprint(id))
+	message.ID = &rawID
+	return message
+}
+
+func newTestNotification(method string, params interface{}) *message {
+	message := &message{
+		JSONRPC: jsonRPCVersion,
+		Method:  method,
+	}
+	if params != nil {
+		data, _ := json.Marshal(params)
+		message.Params = data
+	}
+	return message
+}
+
+func newTestPositionParams(uri string, line int, character int) textDocumentPositionParams {
+	return textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     position{Line: line, Character: character},
+	}
+}
+
+func newTestLocation(uri string, line int, startCharacter int, endCharacter int) location {
+	return location{
+		URI: uri,
+		Range: lspRange{
+			Start: position{Line: line, Character: startCharacter},
+			End:   position{Line: line, Character: endCharacter},
+		},
+	}
+}
+
+func unmarshalTestResult(t *testing.T, message *message, result interface{}) {
+	require.Nil(t, message.Error)
+	if message.Result == nil {
+		// A null result.
+		return
+	}
+	require.NoError(t, json.Unmarshal(*message.Result, result))
+}
@@ -0,0 +1,55 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp
+
+import "fmt"
+
+// codeActions returns a quick fix for each diagnostic of the request that has
+// edits that fix it.
+//
+// The edits are in the data of the diagnostics, which the client passes back
+// from the published diagnostics, so the input does not need to be rebuilt.
+func (s *session) codeActions(params codeActionParams) []codeAction {
+	// The result must be an array, not null, if there are no code actions.
+	codeActions := make([]codeAction, 0)
+	for _, contextDiagnostic := range params.Context.Diagnostics {
+		if contextDiagnostic.Source != diagnosticSource || contextDiagnostic.Data == nil || len(contextDiagnostic.Data.Edits) == 0 {
+			continue
+		}
+		codeActions = append(
+			codeActions,
+			codeAction{
+				Title:       getCodeActionTitle(contextDiagnostic),
+				Kind:        codeActionKindQuickFix,
+				Diagnostics: []diagnostic{contextDiagnostic},
+				IsPreferred: true,
+				Edit: &workspaceEdit{
+					Changes: map[string][]textEdit{
+						params.TextDocument.URI: contextDiagnostic.Data.Edits,
+					},
+				},
+			},
+		)
+	}
+	return codeActions
+}
+
+// getCodeActionTitle returns the title of the quick fix for the diagnostic.
+func getCodeActionTitle(contextDiagnostic diagnostic) string {
+	if len(contextDiagnostic.Data.Edits) == 1 {
+		return fmt.Sprintf("Fix %s: change to %q", contextDiagnostic.Code, contextDiagnostic.Data.Edits[0].NewText)
+	}
+	return fmt.Sprintf("Fix %s", contextDiagnostic.Code)
+}
@@ -0,0 +1,161 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	jsonRPCVersion = "2.0"
+
+	errorCodeParseError     = -32700
+	errorCodeInvalidParams  = -32602
+	errorCodeMethodNotFound = -32601
+	errorCodeInternalError  = -32603
+	// errorCodeInvalidRequest is the LSP code for a request received after shutdown.
+	errorCodeInvalidRequest = -32600
+)
+
+// message is a JSON-RPC 2.0 request, notification, or response.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+// responseError is the error of a JSON-RPC 2.0 response.
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func newResponseError(code int, format string, args ...interface{}) *responseError {
+	return &responseError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+// conn reads and writes JSON-RPC 2.0 messages with the base protocol of LSP,
+// that is each message is preceded by a Content-Length header.
+type conn struct {
+	reader *textproto.Reader
+	writer io.Writer
+	lock   sync.Mutex
+}
+
+func newConn(reader io.Reader, writer io.Writer) *conn {
+	return &conn{
+		reader: textproto.NewReader(bufio.NewReader(reader)),
+		writer: writer,
+	}
+}
+
+// read reads the next message.
+//
+// Returns io.EOF if the reader is closed between messages.
+func (c *conn) read() (*message, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+	contentLength, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || contentLength < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header: %q", header.Get("Content-Length"))
+	}
+	data := make([]byte, contentLength)
+	if _, err := io.ReadFull(c.reader.R, data); err != nil {
+		return nil, fmt.Errorf("could not read content: %w", err)
+	}
+	message := &message{}
+	if err := json.Unmarshal(data, message); err != nil {
+		return nil, newResponseError(errorCodeParseError, "could not parse message: %v", err)
+	}
+	return message, nil
+}
+
+// notify writes a notification.
+func (c *conn) notify(method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(
+		&message{
+			JSONRPC: jsonRPCVersion,
+			Method:  method,
+			Params:  data,
+		},
+	)
+}
+
+// reply writes the response to the request with the given ID.
+//
+// If err is not nil, the response is an error. Errors that are not a
+// *responseError are reported as internal errors.
+func (c *conn) reply(id *json.RawMessage, result interface{}, err error) error {
+	response := &message{
+		JSONRPC: jsonRPCVersion,
+		ID:      id,
+	}
+	if err != nil {
+		var responseErr *responseError
+		if !errors.As(err, &responseErr) {
+			responseErr = newResponseError(errorCodeInternalError, "%v", err)
+		}
+		response.Error = responseErr
+		return c.write(response)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	rawResult := json.RawMessage(data)
+	response.Result = &rawResult
+	return c.write(response)
+}
+
+func (c *conn) write(message *message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.writer.Write(data)
+	return err
+}
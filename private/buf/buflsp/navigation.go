@@ -0,0 +1,193 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufsymbol"
+)
+
+const markupKindMarkdown = "markdown"
+
+func (s *session) definition(ctx context.Context, params textDocumentPositionParams) ([]location, error) {
+	symbol, _, err := s.getSymbolAtPosition(params)
+	if err != nil || symbol == nil {
+		return nil, err
+	}
+	span := symbol.NameSpan
+	if span.IsZero() {
+		span = symbol.Span
+	}
+	symbolLocation, err := s.newLocation(ctx, symbol.ImageFile, span)
+	if err != nil {
+		return nil, err
+	}
+	return []location{symbolLocation}, nil
+}
+
+func (s *session) hover(params textDocumentPositionParams) (*hover, error) {
+	symbol, _, err := s.getSymbolAtPosition(params)
+	if err != nil || symbol == nil {
+		return nil, err
+	}
+	var builder strings.Builder
+	builder.WriteString("```proto\n")
+	builder.WriteString(symbol.Declaration())
+	builder.WriteString("\n```")
+	if location := symbol.Descriptor.Location(); location != nil {
+		for _, comments := range []string{location.LeadingComments(), location.TrailingComments()} {
+			if comments = strings.TrimSpace(comments); comments != "" {
+				builder.WriteString("\n\n")
+				builder.WriteString(comments)
+			}
+		}
+	}
+	return &hover{
+		Contents: markupContent{
+			Kind:  markupKindMarkdown,
+			Value: builder.String(),
+		},
+	}, nil
+}
+
+func (s *session) references(ctx context.Context, params referenceParams) ([]location, error) {
+	symbol, input, err := s.getSymbolAtPosition(params.textDocumentPositionParams)
+	if err != nil || symbol == nil {
+		return nil, err
+	}
+	var locations []location
+	seen := make(map[location]struct{})
+	addLocation := func(imageFile bufimage.ImageFile, span bufsymbol.Span) error {
+		if span.IsZero() {
+			return nil
+		}
+		referenceLocation, err := s.newLocation(ctx, imageFile, span)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[referenceLocation]; ok {
+			return nil
+		}
+		seen[referenceLocation] = struct{}{}
+		locations = append(locations, referenceLocation)
+		return nil
+	}
+	if params.Context.IncludeDeclaration {
+		if err := addLocation(symbol.ImageFile, symbol.NameSpan); err != nil {
+			return nil, err
+		}
+	}
+	// The same symbol can be referenced from each module of a workspace.
+	for _, index := range input.indexes {
+		indexSymbol := index.SymbolForFullName(symbol.FullName)
+		if indexSymbol == nil {
+			continue
+		}
+		for _, reference := range index.References(indexSymbol) {
+			if err := addLocation(reference.ImageFile, reference.Span); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return locations, nil
+}
+
+// getSymbolAtPosition returns the Symbol at the position of the document, and the
+// input that the document was last built as.
+//
+// Returns a nil Symbol if the document has not been built, or there is no Symbol
+// at the position.
+func (s *session) getSymbolAtPosition(params textDocumentPositionParams) (*bufsymbol.Symbol, *input, error) {
+	filePath, err := uriToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil, newResponseError(errorCodeInvalidParams, "%v", err)
+	}
+	input, ok := s.inputDirPathToInput[getInputDirPath(filePath)]
+	if !ok {
+		return nil, nil, nil
+	}
+	for _, index := range input.indexes {
+		imageFile := index.ImageFileForExternalPath(filePath)
+		if imageFile == nil || imageFile.IsImport() {
+			// The document is a target of the image of another module.
+			continue
+		}
+		symbol := index.SymbolAtPosition(
+			imageFile.Path(),
+			params.Position.Line+1,
+			params.Position.Character+1,
+		)
+		return symbol, input, nil
+	}
+	return nil, nil, nil
+}
+
+// newLocation returns the location of the span of the file.
+func (s *session) newLocation(ctx context.Context, imageFile bufimage.ImageFile, span bufsymbol.Span) (location, error) {
+	filePath, err := s.server.filePathResolver.GetFilePath(ctx, imageFile)
+	if err != nil {
+		return location{}, err
+	}
+	uri, err := pathToURI(filePath)
+	if err != nil {
+		return location{}, err
+	}
+	return location{
+		URI: uri,
+		Range: lspRange{
+			Start: newPosition(span.StartLine, span.StartColumn),
+			End:   newPosition(span.EndLine, span.EndColumn),
+		},
+	}, nil
+}
+
+// uriToPath returns the absolute path of the file URI.
+func uriToPath(uri string) (string, error) {
+	parsedURI, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid URI %q: %w", uri, err)
+	}
+	if parsedURI.Scheme != "file" {
+		return "", fmt.Errorf("URI %q does not have the file scheme", uri)
+	}
+	filePath := filepath.FromSlash(parsedURI.Path)
+	// On Windows, the path of file:///C:/foo is /C:/foo.
+	if volumePath := strings.TrimPrefix(filePath, string(filepath.Separator)); filepath.VolumeName(volumePath) != "" {
+		filePath = volumePath
+	}
+	if !filepath.IsAbs(filePath) {
+		return "", fmt.Errorf("URI %q does not have an absolute path", uri)
+	}
+	return filepath.Clean(filePath), nil
+}
+
+// pathToURI returns the file URI of the path.
+func pathToURI(filePath string) (string, error) {
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+	uriPath := filepath.ToSlash(absFilePath)
+	if !strings.HasPrefix(uriPath, "/") {
+		uriPath = "/" + uriPath
+	}
+	return (&url.URL{Scheme: "file", Path: uriPath}).String(), nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp
+
+// The subset of the types of the Language Server Protocol that the server uses.
+//
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification.
+
+const (
+	diagnosticSeverityError   = 1
+	diagnosticSeverityWarning = 2
+
+	messageTypeError = 1
+
+	textDocumentSyncKindFull = 1
+
+	codeActionKindQuickFix = "quickfix"
+)
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   *serverInfo        `json:"serverInfo,omitempty"`
+}
+
+type serverCapabilities struct {
+	TextDocumentSync   *textDocumentSyncOptions `json:"textDocumentSync,omitempty"`
+	DefinitionProvider bool                     `json:"definitionProvider,omitempty"`
+	HoverProvider      bool                     `json:"hoverProvider,omitempty"`
+	ReferencesProvider bool                     `json:"referencesProvider,omitempty"`
+	CodeActionProvider bool                     `json:"codeActionProvider,omitempty"`
+}
+
+type textDocumentSyncOptions struct {
+	OpenClose bool         `json:"openClose,omitempty"`
+	Change    int          `json:"change"`
+	Save      *saveOptions `json:"save,omitempty"`
+}
+
+type saveOptions struct {
+	IncludeText bool `json:"includeText,omitempty"`
+}
+
+type serverInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type didOpenTextDocumentParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didSaveTextDocumentParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+type referenceParams struct {
+	textDocumentPositionParams
+	Context referenceContext `json:"context"`
+}
+
+type referenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+// position is a zero-indexed position in a document.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type hover struct {
+	Contents markupContent `json:"contents"`
+	Range    *lspRange     `json:"range,omitempty"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type diagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity,omitempty"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	Message  string   `json:"message"`
+	// Data is passed back to the server by the client in the diagnostics of
+	// textDocument/codeAction requests.
+	Data *diagnosticData `json:"data,omitempty"`
+}
+
+// diagnosticData contains the edits that fix a diagnostic, so that code actions
+// can be created from the diagnostics of a textDocument/codeAction request
+// without rebuilding the input.
+type diagnosticData struct {
+	Edits []textEdit `json:"edits,omitempty"`
+}
+
+type textEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type codeActionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Range        lspRange               `json:"range"`
+	Context      codeActionContext      `json:"context"`
+}
+
+type codeActionContext struct {
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type codeAction struct {
+	Title       string         `json:"title"`
+	Kind        string         `json:"kind,omitempty"`
+	Diagnostics []diagnostic   `json:"diagnostics,omitempty"`
+	IsPreferred bool           `json:"isPreferred,omitempty"`
+	Edit        *workspaceEdit `json:"edit,omitempty"`
+}
+
+type workspaceEdit struct {
+	Changes map[string][]textEdit `json:"changes"`
+}
+
+type showMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
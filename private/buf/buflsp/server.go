@@ -0,0 +1,491 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/buffilepath"
+	"github.com/bufbuild/buf/private/buf/bufwire"
+	"github.com/bufbuild/buf/private/buf/bufwork"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufsymbol"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"go.uber.org/zap"
+)
+
+const (
+	serverName       = "buf"
+	diagnosticSource = "buf"
+)
+
+type server struct {
+	logger            *zap.Logger
+	imageConfigReader bufwire.ImageConfigReader
+	filePathResolver  buffilepath.Resolver
+	refParser         buffetch.RefParser
+	against           string
+	version           string
+}
+
+func newServer(
+	logger *zap.Logger,
+	imageConfigReader bufwire.ImageConfigReader,
+	moduleReader bufmodule.ModuleReader,
+	dependencyDirPath string,
+	options ...ServerOption,
+) *server {
+	server := &server{
+		logger:            logger.Named("buflsp"),
+		imageConfigReader: imageConfigReader,
+		filePathResolver:  buffilepath.NewResolver(moduleReader, dependencyDirPath),
+		refParser:         buffetch.NewRefParser(logger),
+	}
+	for _, option := range options {
+		option(server)
+	}
+	return server
+}
+
+func (s *server) Serve(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	reader io.Reader,
+	writer io.Writer,
+) error {
+	return newSession(s, container, newConn(reader, writer)).run(ctx)
+}
+
+// session is the state of the server for a single client.
+type session struct {
+	server    *server
+	container app.EnvStdinContainer
+	conn      *conn
+	// inputDirPathToInput contains the inputs that have been built.
+	inputDirPathToInput map[string]*input
+	shutdown            bool
+}
+
+// input is the result of the last build of an input.
+type input struct {
+	// indexes are the indexes of the images of the last successful build,
+	// one for each module of the input.
+	indexes []*bufsymbol.Index
+	// publishedURIs are the URIs of the documents that diagnostics were last
+	// published for, so that they are cleared once they are fixed.
+	publishedURIs map[string]struct{}
+}
+
+func newSession(server *server, container app.EnvStdinContainer, conn *conn) *session {
+	return &session{
+		server:              server,
+		container:           container,
+		conn:                conn,
+		inputDirPathToInput: make(map[string]*input),
+	}
+}
+
+func (s *session) run(ctx context.Context) error {
+	for {
+		message, err := s.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var responseErr *responseError
+			if errors.As(err, &responseErr) {
+				if err := s.conn.reply(nil, nil, responseErr); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		if message.Method == "" {
+			// Responses to requests, which the server does not send.
+			continue
+		}
+		if message.Method == "exit" {
+			return nil
+		}
+		if message.ID == nil {
+			if err := s.handleNotification(ctx, message); err != nil {
+				return err
+			}
+			continue
+		}
+		result, err := s.handleRequest(ctx, message)
+		if err := s.conn.reply(message.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *session) handleRequest(ctx context.Context, message *message) (interface{}, error) {
+	if s.shutdown {
+		return nil, newResponseError(errorCodeInvalidRequest, "server is shut down")
+	}
+	switch message.Method {
+	case "initialize":
+		return &initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync: &textDocumentSyncOptions{
+					OpenClose: true,
+					// The server builds files from disk, so changes are only
+					// used once they are saved.
+					Change: textDocumentSyncKindFull,
+					Save:   &saveOptions{},
+				},
+				DefinitionProvider: true,
+				HoverProvider:      true,
+				ReferencesProvider: true,
+				CodeActionProvider: true,
+			},
+			ServerInfo: &serverInfo{
+				Name:    serverName,
+				Version: s.server.version,
+			},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(message, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(ctx, params)
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(message, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params)
+	case "textDocument/references":
+		var params referenceParams
+		if err := unmarshalParams(message, &params); err != nil {
+			return nil, err
+		}
+		return s.references(ctx, params)
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := unmarshalParams(message, &params); err != nil {
+			return nil, err
+		}
+		return s.codeActions(params), nil
+	default:
+		return nil, newResponseError(errorCodeMethodNotFound, "method not found: %s", message.Method)
+	}
+}
+
+func (s *session) handleNotification(ctx context.Context, message *message) error {
+	switch message.Method {
+	case "textDocument/didOpen":
+		var params didOpenTextDocumentParams
+		if err := unmarshalParams(message, &params); err != nil {
+			s.server.logger.Debug("invalid_notification", zap.String("method", message.Method), zap.Error(err))
+			return nil
+		}
+		return s.check(ctx, params.TextDocument.URI)
+	case "textDocument/didSave":
+		var params didSaveTextDocumentParams
+		if err := unmarshalParams(message, &params); err != nil {
+			s.server.logger.Debug("invalid_notification", zap.String("method", message.Method), zap.Error(err))
+			return nil
+		}
+		return s.check(ctx, params.TextDocument.URI)
+	default:
+		// Includes initialized, textDocument/didChange, and textDocument/didClose,
+		// which the server has nothing to do for.
+		return nil
+	}
+}
+
+// check builds the input of the document, and publishes its diagnostics.
+//
+// Errors that are not from writing to the client are shown to the client,
+// rather than returned, so that the server keeps running.
+func (s *session) check(ctx context.Context, uri string) error {
+	filePath, err := uriToPath(uri)
+	if err != nil {
+		s.server.logger.Debug("ignoring_document", zap.String("uri", uri), zap.Error(err))
+		return nil
+	}
+	inputDirPath := getInputDirPath(filePath)
+	fileAnnotations, err := s.build(ctx, inputDirPath)
+	if err != nil {
+		return s.conn.notify(
+			"window/showMessage",
+			&showMessageParams{
+				Type:    messageTypeError,
+				Message: fmt.Sprintf("buf: could not build %s: %v", inputDirPath, err),
+			},
+		)
+	}
+	return s.publishDiagnostics(inputDirPath, fileAnnotations)
+}
+
+// build builds the input at the directory, and returns the annotations of the
+// compilation if it failed, or the lint and breaking change annotations otherwise.
+//
+// The indexes of the input are only updated if the compilation succeeds, so that
+// definitions can still be found while a file does not compile.
+func (s *session) build(ctx context.Context, inputDirPath string) ([]bufanalysis.FileAnnotation, error) {
+	ref, err := s.server.refParser.GetRef(ctx, inputDirPath)
+	if err != nil {
+		return nil, err
+	}
+	imageConfigs, fileAnnotations, err := s.server.imageConfigReader.GetImageConfigs(
+		ctx,
+		s.container,
+		ref,
+		"",    // use the buf.yaml of the input
+		nil,   // build all files
+		nil,   // exclude no files
+		false, // no paths are given
+		false, // source code info is required for locations
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileAnnotations) > 0 {
+		return fileAnnotations, nil
+	}
+	indexes := make([]*bufsymbol.Index, 0, len(imageConfigs))
+	for _, imageConfig := range imageConfigs {
+		index, err := bufsymbol.NewIndex(ctx, imageConfig.Image())
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+	s.getInput(inputDirPath).indexes = indexes
+	for _, imageConfig := range imageConfigs {
+		lintFileAnnotations, err := buflint.NewHandler(s.server.logger).Check(
+			ctx,
+			imageConfig.Config().Lint,
+			imageConfig.Image(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		fileAnnotations = append(fileAnnotations, lintFileAnnotations...)
+	}
+	if s.server.against != "" {
+		breakingFileAnnotations, err := s.checkBreaking(ctx, imageConfigs)
+		if err != nil {
+			return nil, err
+		}
+		fileAnnotations = append(fileAnnotations, breakingFileAnnotations...)
+	}
+	return bufanalysis.DeduplicateAndSortFileAnnotations(fileAnnotations), nil
+}
+
+func (s *session) checkBreaking(ctx context.Context, imageConfigs []bufwire.ImageConfig) ([]bufanalysis.FileAnnotation, error) {
+	againstRef, err := s.server.refParser.GetRef(ctx, s.server.against)
+	if err != nil {
+		return nil, err
+	}
+	againstImageConfigs, fileAnnotations, err := s.server.imageConfigReader.GetImageConfigs(
+		ctx,
+		s.container,
+		againstRef,
+		"",    // use the buf.yaml of the against input
+		nil,   // build all files
+		nil,   // exclude no files
+		false, // no paths are given
+		true,  // no need to include source info for against
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileAnnotations) > 0 {
+		return nil, fmt.Errorf("could not build %s: %s", s.server.against, fileAnnotations[0].String())
+	}
+	if len(imageConfigs) != len(againstImageConfigs) {
+		return nil, fmt.Errorf("input contained %d images, whereas against contained %d images", len(imageConfigs), len(againstImageConfigs))
+	}
+	var allFileAnnotations []bufanalysis.FileAnnotation
+	for i, imageConfig := range imageConfigs {
+		fileAnnotations, err := bufbreaking.NewHandler(s.server.logger).Check(
+			ctx,
+			imageConfig.Config().Breaking,
+			bufimage.ImageWithoutImports(againstImageConfigs[i].Image()),
+			bufimage.ImageWithoutImports(imageConfig.Image()),
+		)
+		if err != nil {
+			return nil, err
+		}
+		allFileAnnotations = append(allFileAnnotations, fileAnnotations...)
+	}
+	return allFileAnnotations, nil
+}
+
+// publishDiagnostics publishes the diagnostics for the annotations of the input,
+// and clears the diagnostics of the documents of the input that no longer have any.
+func (s *session) publishDiagnostics(inputDirPath string, fileAnnotations []bufanalysis.FileAnnotation) error {
+	uriToDiagnostics := make(map[string][]diagnostic)
+	for _, fileAnnotation := range fileAnnotations {
+		fileInfo := fileAnnotation.FileInfo()
+		if fileInfo == nil {
+			// Annotations without a file are reported against the input itself.
+			if err := s.conn.notify(
+				"window/showMessage",
+				&showMessageParams{
+					Type:    messageTypeError,
+					Message: fmt.Sprintf("buf: %s", fileAnnotation.Message()),
+				},
+			); err != nil {
+				return err
+			}
+			continue
+		}
+		uri, err := pathToURI(fileInfo.ExternalPath())
+		if err != nil {
+			return err
+		}
+		uriToDiagnostics[uri] = append(uriToDiagnostics[uri], newDiagnostic(fileAnnotation))
+	}
+	input := s.getInput(inputDirPath)
+	for uri := range input.publishedURIs {
+		if _, ok := uriToDiagnostics[uri]; !ok {
+			uriToDiagnostics[uri] = []diagnostic{}
+		}
+	}
+	uris := make([]string, 0, len(uriToDiagnostics))
+	for uri := range uriToDiagnostics {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	input.publishedURIs = make(map[string]struct{})
+	for _, uri := range uris {
+		diagnostics := uriToDiagnostics[uri]
+		if len(diagnostics) > 0 {
+			input.publishedURIs[uri] = struct{}{}
+		}
+		if err := s.conn.notify(
+			"textDocument/publishDiagnostics",
+			&publishDiagnosticsParams{
+				URI:         uri,
+				Diagnostics: diagnostics,
+			},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *session) getInput(inputDirPath string) *input {
+	i, ok := s.inputDirPathToInput[inputDirPath]
+	if !ok {
+		i = &input{}
+		s.inputDirPathToInput[inputDirPath] = i
+	}
+	return i
+}
+
+// getInputDirPath returns the directory to build the file as. This is the closest
+// directory that contains the file and a buf.yaml or buf.work.yaml, or the directory
+// of the file if there is no such directory.
+func getInputDirPath(filePath string) string {
+	fileDirPath := filepath.Dir(filePath)
+	for dirPath := fileDirPath; ; dirPath = filepath.Dir(dirPath) {
+		for _, configFilePath := range append(bufconfig.AllConfigFilePaths, bufwork.AllConfigFilePaths...) {
+			if fileInfo, err := os.Stat(filepath.Join(dirPath, configFilePath)); err == nil && fileInfo.Mode().IsRegular() {
+				return dirPath
+			}
+		}
+		if parentDirPath := filepath.Dir(dirPath); parentDirPath == dirPath {
+			return fileDirPath
+		}
+	}
+}
+
+func newDiagnostic(fileAnnotation bufanalysis.FileAnnotation) diagnostic {
+	severity := diagnosticSeverityWarning
+	if fileAnnotation.Type() == "COMPILE" {
+		severity = diagnosticSeverityError
+	}
+	startLine := fileAnnotation.StartLine()
+	startColumn := fileAnnotation.StartColumn()
+	endLine := fileAnnotation.EndLine()
+	endColumn := fileAnnotation.EndColumn()
+	if endLine == 0 {
+		endLine = startLine
+		endColumn = startColumn
+	}
+	diagnostic := diagnostic{
+		Range: lspRange{
+			Start: newPosition(startLine, startColumn),
+			End:   newPosition(endLine, endColumn),
+		},
+		Severity: severity,
+		Code:     fileAnnotation.Type(),
+		Source:   diagnosticSource,
+		Message:  fileAnnotation.Message(),
+	}
+	if edits := fileAnnotation.Edits(); len(edits) > 0 {
+		diagnostic.Data = &diagnosticData{}
+		for _, edit := range edits {
+			diagnostic.Data.Edits = append(
+				diagnostic.Data.Edits,
+				textEdit{
+					Range: lspRange{
+						Start: newPosition(edit.StartLine(), edit.StartColumn()),
+						End:   newPosition(edit.EndLine(), edit.EndColumn()),
+					},
+					NewText: edit.NewText(),
+				},
+			)
+		}
+	}
+	return diagnostic
+}
+
+// newPosition returns the position for the 1-indexed line and column, where
+// 0 means that the line or column is not known.
+func newPosition(line int, column int) position {
+	if line > 0 {
+		line--
+	}
+	if column > 0 {
+		column--
+	}
+	return position{
+		Line:      line,
+		Character: column,
+	}
+}
+
+func unmarshalParams(message *message, params interface{}) error {
+	if err := json.Unmarshal(message.Params, params); err != nil {
+		return newResponseError(errorCodeInvalidParams, "invalid params for %s: %v", message.Method, err)
+	}
+	return nil
+}
@@ -40,3 +40,26 @@ func V1Beta1MigratorWithNotifier(notifier func(message string) error) V1Beta1Mig
 		migrateOptions.notifier = notifier
 	}
 }
+
+// V2MigrateOption defines the type used
+// to configure the v2 migrator.
+type V2MigrateOption func(*v2Migrator)
+
+// NewV2Migrator creates a new migrator that migrates a workspace defined by
+// a buf.work.yaml, and the v1 configuration files of its modules, to a v2 buf.yaml.
+//
+// The deps, format, and dependency_constraints of the modules are shared in a
+// v2 buf.yaml, so the migration fails if they conflict. The buf.lock files of the
+// modules are merged into a buf.lock next to the buf.yaml.
+func NewV2Migrator(commandName string, options ...V2MigrateOption) Migrator {
+	return newV2Migrator(commandName, options...)
+}
+
+// V2MigratorWithNotifier instruments the migrator with
+// a callback to call whenever an event that should notify the
+// user occurs during the migration.
+func V2MigratorWithNotifier(notifier func(message string) error) V2MigrateOption {
+	return func(migrateOptions *v2Migrator) {
+		migrateOptions.notifier = notifier
+	}
+}
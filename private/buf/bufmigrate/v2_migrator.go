@@ -0,0 +1,372 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+
+	"github.com/bufbuild/buf/private/buf/bufwork"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/buflock"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+)
+
+const bufYAMLV2Header = `# Generated by %q. Edit as necessary, and
+# remove this comment when you're finished.
+#
+# This file defines the modules that were listed in your
+# previous %q configuration.
+`
+
+type v2Migrator struct {
+	notifier    func(string) error
+	commandName string
+}
+
+func newV2Migrator(commandName string, options ...V2MigrateOption) *v2Migrator {
+	migrator := v2Migrator{
+		commandName: commandName,
+		notifier:    func(string) error { return nil },
+	}
+	for _, option := range options {
+		option(&migrator)
+	}
+	return &migrator
+}
+
+// externalConfigV2 is the ExternalConfigV2 that is written, which keeps only the
+// keys of the sections that are set in the previous configuration files.
+type externalConfigV2 struct {
+	Version               string                   `yaml:"version"`
+	Modules               []externalModuleConfigV2 `yaml:"modules"`
+	Deps                  []string                 `yaml:"deps,omitempty"`
+	Extends               string                   `yaml:"extends,omitempty"`
+	Breaking              map[string]interface{}   `yaml:"breaking,omitempty"`
+	Lint                  map[string]interface{}   `yaml:"lint,omitempty"`
+	Format                interface{}              `yaml:"format,omitempty"`
+	DependencyConstraints map[string]interface{}   `yaml:"dependency_constraints,omitempty"`
+	Profiles              interface{}              `yaml:"profiles,omitempty"`
+}
+
+type externalModuleConfigV2 struct {
+	Path     string                 `yaml:"path"`
+	Name     string                 `yaml:"name,omitempty"`
+	Excludes []string               `yaml:"excludes,omitempty"`
+	Includes []string               `yaml:"includes,omitempty"`
+	Breaking map[string]interface{} `yaml:"breaking,omitempty"`
+	Lint     map[string]interface{} `yaml:"lint,omitempty"`
+}
+
+func (m *v2Migrator) Migrate(dirPath string) error {
+	// The migrator only reads and writes local files.
+	ctx := context.Background()
+	readWriteBucket, err := storageos.NewProvider().NewReadWriteBucket(dirPath)
+	if err != nil {
+		return err
+	}
+	workspaceConfigFilePath, err := bufwork.ExistingConfigFilePath(ctx, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	switch workspaceConfigFilePath {
+	case "":
+		return fmt.Errorf("no %s found in %s, only workspaces need to be migrated to a %s %s", bufwork.ExternalConfigV1FilePath, dirPath, bufconfig.V2Version, bufconfig.ExternalConfigV2FilePath)
+	case bufconfig.ExternalConfigV2FilePath:
+		// OK, the workspace is already defined by a v2 buf.yaml.
+		return nil
+	}
+	for _, path := range append([]string{buflock.ExternalConfigFilePath}, bufconfig.AllConfigFilePaths...) {
+		exists, err := storage.Exists(ctx, readWriteBucket, path)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%s already exists in %s, next to %s", path, dirPath, workspaceConfigFilePath)
+		}
+	}
+	workspaceConfig, err := bufwork.GetConfigForBucket(ctx, readWriteBucket, ".")
+	if err != nil {
+		return err
+	}
+	config := externalConfigV2{
+		Version: bufconfig.V2Version,
+		Modules: make([]externalModuleConfigV2, 0, len(workspaceConfig.Directories)),
+	}
+	if moduleConfigDefaults := workspaceConfig.ModuleConfigDefaults; moduleConfigDefaults != nil {
+		if len(moduleConfigDefaults.Build) > 0 {
+			return fmt.Errorf("the build section of %s cannot be migrated, move its excludes to the modules", workspaceConfigFilePath)
+		}
+		config.Breaking = moduleConfigDefaults.Breaking
+		config.Lint = moduleConfigDefaults.Lint
+	}
+	identityToDep := make(map[string]string)
+	identityToDependency := make(map[string]buflock.Dependency)
+	// The files that are deleted once the buf.yaml and buf.lock are written.
+	oldFilePaths := []string{workspaceConfigFilePath}
+	var formatDirectory string
+	var profilesDirectory string
+	var extendsDirectory string
+	var extendsDependency *buflock.Dependency
+	for _, directory := range workspaceConfig.Directories {
+		moduleConfig, moduleConfigFilePath, err := readModuleConfigV1ForV2(ctx, readWriteBucket, directory)
+		if err != nil {
+			return err
+		}
+		if moduleConfigFilePath != "" {
+			oldFilePaths = append(oldFilePaths, normalpath.Join(directory, moduleConfigFilePath))
+		}
+		config.Modules = append(
+			config.Modules,
+			externalModuleConfigV2{
+				Path:     directory,
+				Name:     moduleConfig.name,
+				Excludes: moduleConfig.excludes,
+				Includes: moduleConfig.includes,
+				Breaking: moduleConfig.breaking,
+				Lint:     moduleConfig.lint,
+			},
+		)
+		for _, dep := range moduleConfig.deps {
+			moduleReference, err := bufmoduleref.ModuleReferenceForString(dep)
+			if err != nil {
+				return err
+			}
+			identity := moduleReference.IdentityString()
+			if existingDep, ok := identityToDep[identity]; ok {
+				if existingDep != dep {
+					return fmt.Errorf("modules depend on both %q and %q, but the deps of a %s %s are shared by all modules", existingDep, dep, bufconfig.V2Version, bufconfig.ExternalConfigV2FilePath)
+				}
+				continue
+			}
+			identityToDep[identity] = dep
+			config.Deps = append(config.Deps, dep)
+		}
+		if moduleConfig.format != nil {
+			if config.Format != nil && !reflect.DeepEqual(config.Format, moduleConfig.format) {
+				return fmt.Errorf(
+					`directories "%s" and "%s" have different format sections, but the format section of a %s %s is shared by all modules`,
+					normalpath.Unnormalize(formatDirectory),
+					normalpath.Unnormalize(directory),
+					bufconfig.V2Version,
+					bufconfig.ExternalConfigV2FilePath,
+				)
+			}
+			config.Format = moduleConfig.format
+			formatDirectory = directory
+		}
+		if moduleConfig.extends != "" {
+			if config.Extends != "" && config.Extends != moduleConfig.extends {
+				return fmt.Errorf(
+					`directories "%s" and "%s" extend %q and %q, but the extends of a %s %s is shared by all modules`,
+					normalpath.Unnormalize(extendsDirectory),
+					normalpath.Unnormalize(directory),
+					config.Extends,
+					moduleConfig.extends,
+					bufconfig.V2Version,
+					bufconfig.ExternalConfigV2FilePath,
+				)
+			}
+			config.Extends = moduleConfig.extends
+			extendsDirectory = directory
+		}
+		if moduleConfig.profiles != nil {
+			if config.Profiles != nil && !reflect.DeepEqual(config.Profiles, moduleConfig.profiles) {
+				return fmt.Errorf(
+					`directories "%s" and "%s" have different profiles sections, but the profiles section of a %s %s is shared by all modules`,
+					normalpath.Unnormalize(profilesDirectory),
+					normalpath.Unnormalize(directory),
+					bufconfig.V2Version,
+					bufconfig.ExternalConfigV2FilePath,
+				)
+			}
+			config.Profiles = moduleConfig.profiles
+			profilesDirectory = directory
+		}
+		for identity, dependencyConstraint := range moduleConfig.dependencyConstraints {
+			if existingDependencyConstraint, ok := config.DependencyConstraints[identity]; ok {
+				if !reflect.DeepEqual(existingDependencyConstraint, dependencyConstraint) {
+					return fmt.Errorf("modules have different dependency_constraints for %q, but the dependency_constraints of a %s %s are shared by all modules", identity, bufconfig.V2Version, bufconfig.ExternalConfigV2FilePath)
+				}
+				continue
+			}
+			if config.DependencyConstraints == nil {
+				config.DependencyConstraints = make(map[string]interface{})
+			}
+			config.DependencyConstraints[identity] = dependencyConstraint
+		}
+		readBucketForDirectory := storage.MapReadBucket(readWriteBucket, storage.MapOnPrefix(directory))
+		exists, err := storage.Exists(ctx, readBucketForDirectory, buflock.ExternalConfigFilePath)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		oldFilePaths = append(oldFilePaths, normalpath.Join(directory, buflock.ExternalConfigFilePath))
+		lockFile, err := buflock.ReadConfig(ctx, readBucketForDirectory)
+		if err != nil {
+			return err
+		}
+		for _, dependency := range lockFile.Dependencies {
+			identity := dependency.Remote + "/" + dependency.Owner + "/" + dependency.Repository
+			if existingDependency, ok := identityToDependency[identity]; ok {
+				if existingDependency.Commit != dependency.Commit {
+					return fmt.Errorf("modules are pinned to both commits %q and %q of %q, run buf mod update so that they are pinned to the same commit", existingDependency.Commit, dependency.Commit, identity)
+				}
+				continue
+			}
+			identityToDependency[identity] = dependency
+		}
+		if lockFile.Extends != nil && moduleConfig.extends != "" {
+			if extendsDependency != nil && extendsDependency.Commit != lockFile.Extends.Commit {
+				return fmt.Errorf("modules extend both commits %q and %q of %q, run buf mod update so that they extend the same commit", extendsDependency.Commit, lockFile.Extends.Commit, moduleConfig.extends)
+			}
+			extendsDependency = lockFile.Extends
+		}
+	}
+	sort.Strings(config.Deps)
+	data, err := encoding.MarshalYAML(&config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal new config: %w", err)
+	}
+	// This validates the new configuration before anything is written.
+	if _, err := bufconfig.GetConfigForData(ctx, data); err != nil {
+		return fmt.Errorf("failed to migrate to a valid configuration: %w", err)
+	}
+	if err := storage.PutPath(
+		ctx,
+		readWriteBucket,
+		bufconfig.ExternalConfigV2FilePath,
+		append([]byte(fmt.Sprintf(bufYAMLV2Header, m.commandName, workspaceConfigFilePath)), data...),
+	); err != nil {
+		return fmt.Errorf("failed to write new config: %w", err)
+	}
+	if len(identityToDependency) > 0 || extendsDependency != nil {
+		lockFile := &buflock.Config{
+			Dependencies: make([]buflock.Dependency, 0, len(identityToDependency)),
+			Extends:      extendsDependency,
+		}
+		for _, dependency := range identityToDependency {
+			lockFile.Dependencies = append(lockFile.Dependencies, dependency)
+		}
+		sort.Slice(lockFile.Dependencies, func(i, j int) bool {
+			left, right := lockFile.Dependencies[i], lockFile.Dependencies[j]
+			return left.Remote+"/"+left.Owner+"/"+left.Repository < right.Remote+"/"+right.Owner+"/"+right.Repository
+		})
+		if err := buflock.WriteConfig(ctx, readWriteBucket, lockFile); err != nil {
+			return fmt.Errorf("failed to write new lock file: %w", err)
+		}
+	}
+	for _, oldFilePath := range oldFilePaths {
+		if err := readWriteBucket.Delete(ctx, oldFilePath); err != nil {
+			return fmt.Errorf("failed to clean up old file %s: %w", oldFilePath, err)
+		}
+	}
+	if err := m.notifier(
+		fmt.Sprintf(
+			"Successfully migrated your %s and the configuration of its %d modules to a %s %s.\n",
+			workspaceConfigFilePath,
+			len(config.Modules),
+			bufconfig.V2Version,
+			bufconfig.ExternalConfigV2FilePath,
+		),
+	); err != nil {
+		return fmt.Errorf("failed to write success message: %w", err)
+	}
+	return nil
+}
+
+// moduleConfigV1ForV2 is the configuration of a module that is migrated to
+// a v2 configuration, with the keys of the sections that are set.
+type moduleConfigV1ForV2 struct {
+	name                  string
+	deps                  []string
+	extends               string
+	excludes              []string
+	includes              []string
+	breaking              map[string]interface{}
+	lint                  map[string]interface{}
+	format                interface{}
+	dependencyConstraints map[string]interface{}
+	profiles              interface{}
+}
+
+// readModuleConfigV1ForV2 reads the v1 configuration of the module in the given
+// directory, and returns the path of the configuration file in the directory,
+// or empty if there is none.
+func readModuleConfigV1ForV2(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	directory string,
+) (*moduleConfigV1ForV2, string, error) {
+	readBucketForDirectory := storage.MapReadBucket(readBucket, storage.MapOnPrefix(directory))
+	moduleConfigFilePath, err := bufconfig.ExistingConfigFilePath(ctx, readBucketForDirectory)
+	if err != nil {
+		return nil, "", err
+	}
+	if moduleConfigFilePath == "" {
+		return &moduleConfigV1ForV2{}, "", nil
+	}
+	data, err := storage.ReadPath(ctx, readBucketForDirectory, moduleConfigFilePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &moduleConfigV1ForV2{}, "", nil
+		}
+		return nil, "", err
+	}
+	externalPath := normalpath.Unnormalize(normalpath.Join(directory, moduleConfigFilePath))
+	var externalConfigVersion bufconfig.ExternalConfigVersion
+	if err := encoding.UnmarshalYAMLNonStrict(data, &externalConfigVersion); err != nil {
+		return nil, "", fmt.Errorf("failed to read %s version: %w", externalPath, err)
+	}
+	if externalConfigVersion.Version != bufconfig.V1Version {
+		return nil, "", fmt.Errorf("%s has version %q, but only %s configurations can be migrated, see buf beta migrate-v1beta1", externalPath, externalConfigVersion.Version, bufconfig.V1Version)
+	}
+	var externalConfig bufconfig.ExternalConfigV1
+	if err := encoding.UnmarshalYAMLStrict(data, &externalConfig); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal %s: %w", externalPath, err)
+	}
+	var externalConfigMap struct {
+		Breaking              map[string]interface{} `yaml:"breaking"`
+		Lint                  map[string]interface{} `yaml:"lint"`
+		Format                interface{}            `yaml:"format"`
+		DependencyConstraints map[string]interface{} `yaml:"dependency_constraints"`
+		Profiles              interface{}            `yaml:"profiles"`
+	}
+	if err := encoding.UnmarshalYAMLNonStrict(data, &externalConfigMap); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal %s: %w", externalPath, err)
+	}
+	return &moduleConfigV1ForV2{
+		name:                  externalConfig.Name,
+		deps:                  externalConfig.Deps,
+		extends:               externalConfig.Extends,
+		excludes:              externalConfig.Build.Excludes,
+		includes:              externalConfig.Build.Includes,
+		breaking:              externalConfigMap.Breaking,
+		lint:                  externalConfigMap.Lint,
+		format:                externalConfigMap.Format,
+		dependencyConstraints: externalConfigMap.DependencyConstraints,
+		profiles:              externalConfigMap.Profiles,
+	}, moduleConfigFilePath, nil
+}
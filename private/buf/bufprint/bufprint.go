@@ -91,6 +91,16 @@ func NewOrganizationPrinter(address string, writer io.Writer) OrganizationPrinte
 	return newOrganizationPrinter(address, writer)
 }
 
+// OrganizationMemberPrinter is an organization member printer.
+type OrganizationMemberPrinter interface {
+	PrintOrganizationMembers(ctx context.Context, format Format, nextPageToken string, organizationUsers ...*registryv1alpha1.OrganizationUser) error
+}
+
+// NewOrganizationMemberPrinter returns a new OrganizationMemberPrinter.
+func NewOrganizationMemberPrinter(writer io.Writer) OrganizationMemberPrinter {
+	return newOrganizationMemberPrinter(writer)
+}
+
 // RepositoryPrinter is a repository printer.
 type RepositoryPrinter interface {
 	PrintRepository(ctx context.Context, format Format, repository *registryv1alpha1.Repository) error
@@ -106,6 +116,16 @@ func NewRepositoryPrinter(
 	return newRepositoryPrinter(clientConfig, address, writer)
 }
 
+// RepositoryContributorPrinter is a repository contributor printer.
+type RepositoryContributorPrinter interface {
+	PrintRepositoryContributors(ctx context.Context, format Format, nextPageToken string, repositoryContributors ...*registryv1alpha1.RepositoryContributor) error
+}
+
+// NewRepositoryContributorPrinter returns a new RepositoryContributorPrinter.
+func NewRepositoryContributorPrinter(writer io.Writer) RepositoryContributorPrinter {
+	return newRepositoryContributorPrinter(writer)
+}
+
 // RepositoryTagPrinter is a repository tag printer.
 type RepositoryTagPrinter interface {
 	PrintRepositoryTag(ctx context.Context, format Format, repositoryTag *registryv1alpha1.RepositoryTag) error
@@ -117,6 +137,16 @@ func NewRepositoryTagPrinter(writer io.Writer) RepositoryTagPrinter {
 	return newRepositoryTagPrinter(writer)
 }
 
+// LabelPrinter is a label printer.
+type LabelPrinter interface {
+	PrintLabels(ctx context.Context, format Format, labels ...*registryv1alpha1.Label) error
+}
+
+// NewLabelPrinter returns a new LabelPrinter.
+func NewLabelPrinter(writer io.Writer) LabelPrinter {
+	return newLabelPrinter(writer)
+}
+
 // RepositoryCommitPrinter is a repository commit printer.
 type RepositoryCommitPrinter interface {
 	PrintRepositoryCommit(ctx context.Context, format Format, repositoryCommit *registryv1alpha1.RepositoryCommit) error
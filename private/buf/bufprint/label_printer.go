@@ -0,0 +1,110 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+)
+
+type labelPrinter struct {
+	writer io.Writer
+}
+
+func newLabelPrinter(
+	writer io.Writer,
+) *labelPrinter {
+	return &labelPrinter{
+		writer: writer,
+	}
+}
+
+func (p *labelPrinter) PrintLabels(ctx context.Context, format Format, messages ...*registryv1alpha1.Label) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	outputLabels := make([]outputLabel, len(messages))
+	for i, message := range messages {
+		outputLabels[i] = registryLabelToOutputLabel(message)
+	}
+	switch format {
+	case FormatText:
+		return p.printLabelsText(outputLabels)
+	case FormatJSON:
+		return json.NewEncoder(p.writer).Encode(paginationWrapper{
+			Results: outputLabels,
+		})
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+func (p *labelPrinter) printLabelsText(outputLabels []outputLabel) error {
+	return WithTabWriter(
+		p.writer,
+		[]string{
+			"Namespace",
+			"Name",
+			"Commit",
+		},
+		func(tabWriter TabWriter) error {
+			for _, outputLabel := range outputLabels {
+				if err := tabWriter.Write(
+					outputLabel.Namespace,
+					outputLabel.Name,
+					outputLabel.Commit,
+				); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+type outputLabel struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+}
+
+func registryLabelToOutputLabel(label *registryv1alpha1.Label) outputLabel {
+	return outputLabel{
+		Namespace: labelNamespaceString(label.GetLabelName().GetNamespace()),
+		Name:      label.GetLabelName().GetName(),
+		Commit:    label.GetLabelValue().GetCommitId(),
+	}
+}
+
+func labelNamespaceString(labelNamespace registryv1alpha1.LabelNamespace) string {
+	switch labelNamespace {
+	case registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_TAG:
+		return "tag"
+	case registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_BRANCH:
+		return "branch"
+	case registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_GIT_COMMIT:
+		return "git-commit"
+	case registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_REVIEW:
+		return "review"
+	case registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_BSR_HEAD:
+		return "bsr-head"
+	default:
+		return ""
+	}
+}
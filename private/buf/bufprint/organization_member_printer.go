@@ -0,0 +1,124 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+)
+
+type organizationMemberPrinter struct {
+	writer io.Writer
+}
+
+func newOrganizationMemberPrinter(
+	writer io.Writer,
+) *organizationMemberPrinter {
+	return &organizationMemberPrinter{
+		writer: writer,
+	}
+}
+
+func (p *organizationMemberPrinter) PrintOrganizationMembers(ctx context.Context, format Format, nextPageToken string, messages ...*registryv1alpha1.OrganizationUser) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	outputOrganizationMembers := make([]outputOrganizationMember, len(messages))
+	for i, organizationUser := range messages {
+		outputOrganizationMembers[i] = registryOrganizationUserToOutputOrganizationMember(organizationUser)
+	}
+	switch format {
+	case FormatText:
+		return p.printOrganizationMembersText(outputOrganizationMembers)
+	case FormatJSON:
+		return json.NewEncoder(p.writer).Encode(paginationWrapper{
+			NextPage: nextPageToken,
+			Results:  outputOrganizationMembers,
+		})
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+func (p *organizationMemberPrinter) printOrganizationMembersText(outputOrganizationMembers []outputOrganizationMember) error {
+	return WithTabWriter(
+		p.writer,
+		[]string{
+			"Username",
+			"Role",
+			"Source",
+		},
+		func(tabWriter TabWriter) error {
+			for _, outputOrganizationMember := range outputOrganizationMembers {
+				if err := tabWriter.Write(
+					outputOrganizationMember.Username,
+					outputOrganizationMember.Role,
+					outputOrganizationMember.RoleSource,
+				); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+type outputOrganizationMember struct {
+	UserID     string `json:"user_id,omitempty"`
+	Username   string `json:"username,omitempty"`
+	Role       string `json:"role,omitempty"`
+	RoleSource string `json:"role_source,omitempty"`
+}
+
+func registryOrganizationUserToOutputOrganizationMember(organizationUser *registryv1alpha1.OrganizationUser) outputOrganizationMember {
+	return outputOrganizationMember{
+		UserID:     organizationUser.GetUser().GetId(),
+		Username:   organizationUser.GetUser().GetUsername(),
+		Role:       organizationRoleString(organizationUser.OrganizationRole),
+		RoleSource: organizationRoleSourceString(organizationUser.OrganizationRoleSource),
+	}
+}
+
+func organizationRoleString(organizationRole registryv1alpha1.OrganizationRole) string {
+	switch organizationRole {
+	case registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_OWNER:
+		return "owner"
+	case registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_ADMIN:
+		return "admin"
+	case registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_MEMBER:
+		return "member"
+	case registryv1alpha1.OrganizationRole_ORGANIZATION_ROLE_MACHINE:
+		return "machine"
+	default:
+		return ""
+	}
+}
+
+func organizationRoleSourceString(organizationRoleSource registryv1alpha1.OrganizationRoleSource) string {
+	switch organizationRoleSource {
+	case registryv1alpha1.OrganizationRoleSource_ORGANIZATION_ROLE_SOURCE_DIRECT:
+		return "direct"
+	case registryv1alpha1.OrganizationRoleSource_ORGANIZATION_ROLE_SOURCE_JIT:
+		return "jit"
+	case registryv1alpha1.OrganizationRoleSource_ORGANIZATION_ROLE_SOURCE_IDP_GROUP:
+		return "idp-group"
+	default:
+		return ""
+	}
+}
@@ -95,17 +95,21 @@ func (p *organizationPrinter) printOrganizationsText(outputOrganizations []outpu
 }
 
 type outputOrganization struct {
-	ID         string    `json:"id,omitempty"`
-	Remote     string    `json:"remote,omitempty"`
-	Name       string    `json:"name,omitempty"`
-	CreateTime time.Time `json:"create_time,omitempty"`
+	ID          string    `json:"id,omitempty"`
+	Remote      string    `json:"remote,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	CreateTime  time.Time `json:"create_time,omitempty"`
 }
 
 func registryOrganizationToOutputOrganization(address string, organization *registryv1alpha1.Organization) outputOrganization {
 	return outputOrganization{
-		ID:         organization.Id,
-		Remote:     address,
-		Name:       organization.Name,
-		CreateTime: organization.CreateTime.AsTime(),
+		ID:          organization.Id,
+		Remote:      address,
+		Name:        organization.Name,
+		Description: organization.Description,
+		URL:         organization.Url,
+		CreateTime:  organization.CreateTime.AsTime(),
 	}
 }
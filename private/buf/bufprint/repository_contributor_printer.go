@@ -0,0 +1,116 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+)
+
+type repositoryContributorPrinter struct {
+	writer io.Writer
+}
+
+func newRepositoryContributorPrinter(
+	writer io.Writer,
+) *repositoryContributorPrinter {
+	return &repositoryContributorPrinter{
+		writer: writer,
+	}
+}
+
+func (p *repositoryContributorPrinter) PrintRepositoryContributors(ctx context.Context, format Format, nextPageToken string, messages ...*registryv1alpha1.RepositoryContributor) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	outputRepositoryContributors := make([]outputRepositoryContributor, len(messages))
+	for i, repositoryContributor := range messages {
+		outputRepositoryContributors[i] = registryRepositoryContributorToOutputRepositoryContributor(repositoryContributor)
+	}
+	switch format {
+	case FormatText:
+		return p.printRepositoryContributorsText(outputRepositoryContributors)
+	case FormatJSON:
+		return json.NewEncoder(p.writer).Encode(paginationWrapper{
+			NextPage: nextPageToken,
+			Results:  outputRepositoryContributors,
+		})
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+func (p *repositoryContributorPrinter) printRepositoryContributorsText(outputRepositoryContributors []outputRepositoryContributor) error {
+	return WithTabWriter(
+		p.writer,
+		[]string{
+			"Username",
+			"Role",
+			"Implicit role",
+		},
+		func(tabWriter TabWriter) error {
+			for _, outputRepositoryContributor := range outputRepositoryContributors {
+				if err := tabWriter.Write(
+					outputRepositoryContributor.Username,
+					outputRepositoryContributor.Role,
+					outputRepositoryContributor.ImplicitRole,
+				); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+type outputRepositoryContributor struct {
+	UserID   string `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	// Role is the role that is explicitly set for the contributor.
+	Role string `json:"role,omitempty"`
+	// ImplicitRole is the role from the membership of the contributor in the
+	// organization that owns the repository.
+	ImplicitRole string `json:"implicit_role,omitempty"`
+}
+
+func registryRepositoryContributorToOutputRepositoryContributor(repositoryContributor *registryv1alpha1.RepositoryContributor) outputRepositoryContributor {
+	return outputRepositoryContributor{
+		UserID:       repositoryContributor.GetUser().GetId(),
+		Username:     repositoryContributor.GetUser().GetUsername(),
+		Role:         repositoryRoleString(repositoryContributor.ExplicitRole),
+		ImplicitRole: repositoryRoleString(repositoryContributor.ImplicitRole),
+	}
+}
+
+func repositoryRoleString(repositoryRole registryv1alpha1.RepositoryRole) string {
+	switch repositoryRole {
+	case registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_OWNER:
+		return "owner"
+	case registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_ADMIN:
+		return "admin"
+	case registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_WRITE:
+		return "write"
+	case registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_READ:
+		return "read"
+	case registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_LIMITED_WRITE:
+		return "limited-write"
+	default:
+		return ""
+	}
+}
@@ -19,6 +19,7 @@ package bufwire
 
 import (
 	"context"
+	"io"
 
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
@@ -29,6 +30,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/recordio"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
@@ -64,16 +66,57 @@ func NewImageConfigReader(
 	fetchReader buffetch.Reader,
 	moduleBucketBuilder bufmodulebuild.ModuleBucketBuilder,
 	imageBuilder bufimagebuild.Builder,
+	options ...ImageConfigReaderOption,
 ) ImageConfigReader {
+	imageConfigReaderOptions := &imageConfigReaderOptions{}
+	for _, option := range options {
+		option(imageConfigReaderOptions)
+	}
 	return newImageConfigReader(
 		logger,
 		storageosProvider,
 		fetchReader,
 		moduleBucketBuilder,
 		imageBuilder,
+		imageConfigReaderOptions.profile,
+		imageConfigReaderOptions.extendsResolver,
+		imageConfigReaderOptions.lazySourceCodeInfo,
 	)
 }
 
+// ImageConfigReaderOption is an option for a new ImageConfigReader.
+type ImageConfigReaderOption func(*imageConfigReaderOptions)
+
+// ImageConfigReaderWithProfile returns a new ImageConfigReaderOption that applies
+// the given profile to the configurations that are read.
+//
+// See bufconfig.ReadConfigOSWithProfile for how profiles are applied.
+func ImageConfigReaderWithProfile(profile string) ImageConfigReaderOption {
+	return func(imageConfigReaderOptions *imageConfigReaderOptions) {
+		imageConfigReaderOptions.profile = profile
+	}
+}
+
+// ImageConfigReaderWithExtendsResolver returns a new ImageConfigReaderOption that resolves the modules
+// that the configurations that are read extend with the given ExtendsResolver.
+//
+// See bufconfig.ReadConfigOSWithExtendsResolver for how extends are applied.
+func ImageConfigReaderWithExtendsResolver(extendsResolver bufconfig.ExtendsResolver) ImageConfigReaderOption {
+	return func(imageConfigReaderOptions *imageConfigReaderOptions) {
+		imageConfigReaderOptions.extendsResolver = extendsResolver
+	}
+}
+
+// ImageConfigReaderWithLazySourceCodeInfo returns a new ImageConfigReaderOption that
+// only decodes the source code info of binary images when it is requested.
+//
+// See ImageReaderWithLazySourceCodeInfo.
+func ImageConfigReaderWithLazySourceCodeInfo() ImageConfigReaderOption {
+	return func(imageConfigReaderOptions *imageConfigReaderOptions) {
+		imageConfigReaderOptions.lazySourceCodeInfo = true
+	}
+}
+
 // ModuleConfig is a Module and configuration.
 type ModuleConfig interface {
 	Module() bufmodule.Module
@@ -81,7 +124,12 @@ type ModuleConfig interface {
 }
 
 // ModuleConfigSet is a set of ModuleConfigs with a potentially associated Workspace.
+//
+// The ModuleConfigSet must be closed when its Modules are no longer read, which
+// closes the bucket of the source of the Modules, such as an archive.
 type ModuleConfigSet interface {
+	io.Closer
+
 	ModuleConfigs() []ModuleConfig
 	// Optional. May be nil.
 	Workspace() bufmodule.Workspace
@@ -112,15 +160,45 @@ func NewModuleConfigReader(
 	storageosProvider storageos.Provider,
 	fetchReader buffetch.Reader,
 	moduleBucketBuilder bufmodulebuild.ModuleBucketBuilder,
+	options ...ModuleConfigReaderOption,
 ) ModuleConfigReader {
+	moduleConfigReaderOptions := &moduleConfigReaderOptions{}
+	for _, option := range options {
+		option(moduleConfigReaderOptions)
+	}
 	return newModuleConfigReader(
 		logger,
 		storageosProvider,
 		fetchReader,
 		moduleBucketBuilder,
+		moduleConfigReaderOptions.profile,
+		moduleConfigReaderOptions.extendsResolver,
 	)
 }
 
+// ModuleConfigReaderOption is an option for a new ModuleConfigReader.
+type ModuleConfigReaderOption func(*moduleConfigReaderOptions)
+
+// ModuleConfigReaderWithProfile returns a new ModuleConfigReaderOption that applies
+// the given profile to the configurations that are read.
+//
+// See bufconfig.ReadConfigOSWithProfile for how profiles are applied.
+func ModuleConfigReaderWithProfile(profile string) ModuleConfigReaderOption {
+	return func(moduleConfigReaderOptions *moduleConfigReaderOptions) {
+		moduleConfigReaderOptions.profile = profile
+	}
+}
+
+// ModuleConfigReaderWithExtendsResolver returns a new ModuleConfigReaderOption that resolves the modules
+// that the configurations that are read extend with the given ExtendsResolver.
+//
+// See bufconfig.ReadConfigOSWithExtendsResolver for how extends are applied.
+func ModuleConfigReaderWithExtendsResolver(extendsResolver bufconfig.ExtendsResolver) ModuleConfigReaderOption {
+	return func(moduleConfigReaderOptions *moduleConfigReaderOptions) {
+		moduleConfigReaderOptions.extendsResolver = extendsResolver
+	}
+}
+
 // FileLister lists files.
 type FileLister interface {
 	// ListFiles lists the files.
@@ -144,16 +222,46 @@ func NewFileLister(
 	fetchReader buffetch.Reader,
 	moduleBucketBuilder bufmodulebuild.ModuleBucketBuilder,
 	imageBuilder bufimagebuild.Builder,
+	options ...FileListerOption,
 ) FileLister {
+	fileListerOptions := &fileListerOptions{}
+	for _, option := range options {
+		option(fileListerOptions)
+	}
 	return newFileLister(
 		logger,
 		storageosProvider,
 		fetchReader,
 		moduleBucketBuilder,
 		imageBuilder,
+		fileListerOptions.profile,
+		fileListerOptions.extendsResolver,
 	)
 }
 
+// FileListerOption is an option for a new FileLister.
+type FileListerOption func(*fileListerOptions)
+
+// FileListerWithProfile returns a new FileListerOption that applies the given
+// profile to the configurations that are read.
+//
+// See bufconfig.ReadConfigOSWithProfile for how profiles are applied.
+func FileListerWithProfile(profile string) FileListerOption {
+	return func(fileListerOptions *fileListerOptions) {
+		fileListerOptions.profile = profile
+	}
+}
+
+// FileListerWithExtendsResolver returns a new FileListerOption that resolves the modules
+// that the configurations that are read extend with the given ExtendsResolver.
+//
+// See bufconfig.ReadConfigOSWithExtendsResolver for how extends are applied.
+func FileListerWithExtendsResolver(extendsResolver bufconfig.ExtendsResolver) FileListerOption {
+	return func(fileListerOptions *fileListerOptions) {
+		fileListerOptions.extendsResolver = extendsResolver
+	}
+}
+
 // ImageReader is an image reader.
 type ImageReader interface {
 	// GetImage reads the image from the value.
@@ -172,13 +280,35 @@ type ImageReader interface {
 func NewImageReader(
 	logger *zap.Logger,
 	fetchReader buffetch.MessageReader,
+	options ...ImageReaderOption,
 ) ImageReader {
+	imageReaderOptions := &imageReaderOptions{}
+	for _, option := range options {
+		option(imageReaderOptions)
+	}
 	return newImageReader(
 		logger,
 		fetchReader,
+		imageReaderOptions.lazySourceCodeInfo,
 	)
 }
 
+// ImageReaderOption is an option for a new ImageReader.
+type ImageReaderOption func(*imageReaderOptions)
+
+// ImageReaderWithLazySourceCodeInfo returns a new ImageReaderOption that only
+// decodes the source code info of binary images when it is requested.
+//
+// This is for commands that do not need the source code info of most files, such
+// as commands that only print the locations of failures. The FileDescriptorProtos
+// of the files of the images will not contain the source code info, see
+// bufimage.WithLazySourceCodeInfo.
+func ImageReaderWithLazySourceCodeInfo() ImageReaderOption {
+	return func(imageReaderOptions *imageReaderOptions) {
+		imageReaderOptions.lazySourceCodeInfo = true
+	}
+}
+
 // ImageWriter is an image writer.
 type ImageWriter interface {
 	// PutImage writes the image to the value.
@@ -229,6 +359,41 @@ func NewProtoEncodingReader(
 	)
 }
 
+// ProtoEncodingStreamConverter converts a stream of framed protobuf messages
+// between encodings.
+type ProtoEncodingStreamConverter interface {
+	// ConvertStream reads the messages framed with fromFraming from fromMessageRef,
+	// and writes them framed with toFraming to toMessageRef.
+	//
+	// Messages are converted one at a time, so memory is bounded by the size of
+	// the largest message, and reading does not get ahead of writing.
+	//
+	// recordio.FramingNewline can only be used with JSON encoding.
+	ConvertStream(
+		ctx context.Context,
+		container app.EnvStdioContainer,
+		image bufimage.Image,
+		typeName string,
+		fromMessageRef buffetch.MessageRef,
+		fromFraming recordio.Framing,
+		toMessageRef buffetch.MessageRef,
+		toFraming recordio.Framing,
+	) error
+}
+
+// NewProtoEncodingStreamConverter returns a new ProtoEncodingStreamConverter.
+func NewProtoEncodingStreamConverter(
+	logger *zap.Logger,
+	fetchReader buffetch.MessageReader,
+	fetchWriter buffetch.Writer,
+) ProtoEncodingStreamConverter {
+	return newProtoEncodingStreamConverter(
+		logger,
+		fetchReader,
+		fetchWriter,
+	)
+}
+
 // ProtoEncodingWriter is a writer that writes a protobuf message in different encoding.
 type ProtoEncodingWriter interface {
 	// PutMessage writes the message to the path, which can be
@@ -254,3 +419,23 @@ func NewProtoEncodingWriter(
 		fetchWriter,
 	)
 }
+
+type imageConfigReaderOptions struct {
+	profile            string
+	extendsResolver    bufconfig.ExtendsResolver
+	lazySourceCodeInfo bool
+}
+
+type imageReaderOptions struct {
+	lazySourceCodeInfo bool
+}
+
+type moduleConfigReaderOptions struct {
+	profile         string
+	extendsResolver bufconfig.ExtendsResolver
+}
+
+type fileListerOptions struct {
+	profile         string
+	extendsResolver bufconfig.ExtendsResolver
+}
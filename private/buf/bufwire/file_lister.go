@@ -38,6 +38,8 @@ type fileLister struct {
 	fetchReader         buffetch.Reader
 	moduleBucketBuilder bufmodulebuild.ModuleBucketBuilder
 	imageBuilder        bufimagebuild.Builder
+	profile             string
+	extendsResolver     bufconfig.ExtendsResolver
 	// imageReaders require ImageRefs, we only use this in the withoutImports flow
 	// the imageConfigReader is used when we need to build an image
 	imageReader       *imageReader
@@ -50,15 +52,21 @@ func newFileLister(
 	fetchReader buffetch.Reader,
 	moduleBucketBuilder bufmodulebuild.ModuleBucketBuilder,
 	imageBuilder bufimagebuild.Builder,
+	profile string,
+	extendsResolver bufconfig.ExtendsResolver,
 ) *fileLister {
 	return &fileLister{
 		logger:              logger.Named("bufwire"),
 		fetchReader:         fetchReader,
 		moduleBucketBuilder: moduleBucketBuilder,
 		imageBuilder:        imageBuilder,
+		profile:             profile,
+		extendsResolver:     extendsResolver,
+		// Only the paths of the files are listed, the source code info is never needed.
 		imageReader: newImageReader(
 			logger,
 			fetchReader,
+			true,
 		),
 		imageConfigReader: newImageConfigReader(
 			logger,
@@ -66,6 +74,9 @@ func newFileLister(
 			fetchReader,
 			moduleBucketBuilder,
 			imageBuilder,
+			profile,
+			extendsResolver,
+			true,
 		),
 	}
 }
@@ -204,20 +215,60 @@ func (e *fileLister) listFilesWithoutImports(
 		if err != nil {
 			return nil, nil, err
 		}
-		if subDirPath := readBucketCloser.SubDirPath(); existingConfigFilePath == "" || subDirPath != "." {
-			fileInfos, err := e.sourceFileInfosForDirectory(ctx, readBucketCloser, subDirPath, configOverride)
+		subDirPath := readBucketCloser.SubDirPath()
+		if existingConfigFilePath == "" {
+			fileInfos, err := e.sourceFileInfosForDirectory(
+				ctx,
+				container,
+				storage.MapReadBucket(readBucketCloser, storage.MapOnPrefix(subDirPath)),
+				configOverride,
+			)
 			if err != nil {
 				return nil, nil, err
 			}
 			return fileInfos, nil, nil
 		}
-		workspaceConfig, err := bufwork.GetConfigForBucket(ctx, readBucketCloser, readBucketCloser.RelativeRootPath())
+		workspaceConfig, err := bufwork.GetConfigForBucket(
+			ctx,
+			readBucketCloser,
+			readBucketCloser.RelativeRootPath(),
+			bufwork.GetConfigWithEnvContainer(container),
+		)
 		if err != nil {
 			return nil, nil, err
 		}
+		if subDirPath != "." {
+			// The modules of a workspace defined by a v2 buf.yaml are configured
+			// in the buf.yaml, so the workspace is needed for the configuration.
+			mappedReadBucket, err := bufwork.ReadBucketForDirectory(
+				ctx,
+				workspaceConfig,
+				readBucketCloser,
+				readBucketCloser.RelativeRootPath(),
+				subDirPath,
+			)
+			if err != nil {
+				return nil, nil, err
+			}
+			fileInfos, err := e.sourceFileInfosForDirectory(ctx, container, mappedReadBucket, configOverride)
+			if err != nil {
+				return nil, nil, err
+			}
+			return fileInfos, nil, nil
+		}
 		var allSourceFileInfos []bufmoduleref.FileInfo
 		for _, directory := range workspaceConfig.Directories {
-			sourceFileInfos, err := e.sourceFileInfosForDirectory(ctx, readBucketCloser, directory, configOverride)
+			mappedReadBucket, err := bufwork.ReadBucketForDirectory(
+				ctx,
+				workspaceConfig,
+				readBucketCloser,
+				readBucketCloser.RelativeRootPath(),
+				directory,
+			)
+			if err != nil {
+				return nil, nil, err
+			}
+			sourceFileInfos, err := e.sourceFileInfosForDirectory(ctx, container, mappedReadBucket, configOverride)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -240,18 +291,21 @@ func (e *fileLister) listFilesWithoutImports(
 }
 
 // sourceFileInfosForDirectory returns the source file infos
-// for the module defined in the given diretory of the read bucket.
+// for the module defined in the given read bucket, which is
+// mapped to the directory of the module.
 func (e *fileLister) sourceFileInfosForDirectory(
 	ctx context.Context,
-	readBucket storage.ReadBucket,
-	directory string,
+	container app.EnvContainer,
+	mappedReadBucket storage.ReadBucket,
 	configOverride string,
 ) ([]bufmoduleref.FileInfo, error) {
-	mappedReadBucket := storage.MapReadBucket(readBucket, storage.MapOnPrefix(directory))
 	config, err := bufconfig.ReadConfigOS(
 		ctx,
 		mappedReadBucket,
 		bufconfig.ReadConfigOSWithOverride(configOverride),
+		bufconfig.ReadConfigOSWithProfile(e.profile),
+		bufconfig.ReadConfigOSWithExtendsResolver(e.extendsResolver),
+		bufconfig.ReadConfigOSWithEnvContainer(container),
 	)
 	if err != nil {
 		return nil, err
@@ -28,6 +28,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
@@ -37,6 +38,8 @@ type imageConfigReader struct {
 	fetchReader         buffetch.Reader
 	moduleBucketBuilder bufmodulebuild.ModuleBucketBuilder
 	imageBuilder        bufimagebuild.Builder
+	profile             string
+	extendsResolver     bufconfig.ExtendsResolver
 	moduleConfigReader  *moduleConfigReader
 	imageReader         *imageReader
 }
@@ -47,6 +50,9 @@ func newImageConfigReader(
 	fetchReader buffetch.Reader,
 	moduleBucketBuilder bufmodulebuild.ModuleBucketBuilder,
 	imageBuilder bufimagebuild.Builder,
+	profile string,
+	extendsResolver bufconfig.ExtendsResolver,
+	lazySourceCodeInfo bool,
 ) *imageConfigReader {
 	return &imageConfigReader{
 		logger:              logger.Named("bufwire"),
@@ -54,15 +60,20 @@ func newImageConfigReader(
 		fetchReader:         fetchReader,
 		moduleBucketBuilder: moduleBucketBuilder,
 		imageBuilder:        imageBuilder,
+		profile:             profile,
+		extendsResolver:     extendsResolver,
 		moduleConfigReader: newModuleConfigReader(
 			logger,
 			storageosProvider,
 			fetchReader,
 			moduleBucketBuilder,
+			profile,
+			extendsResolver,
 		),
 		imageReader: newImageReader(
 			logger,
 			fetchReader,
+			lazySourceCodeInfo,
 		),
 	}
 }
@@ -126,7 +137,7 @@ func (i *imageConfigReader) getSourceOrModuleImageConfigs(
 	externalExcludeDirOrFilePaths []string,
 	externalDirOrFilePathsAllowNotExist bool,
 	excludeSourceCodeInfo bool,
-) ([]ImageConfig, []bufanalysis.FileAnnotation, error) {
+) (_ []ImageConfig, _ []bufanalysis.FileAnnotation, retErr error) {
 	moduleConfigSet, err := i.moduleConfigReader.GetModuleConfigSet(
 		ctx,
 		container,
@@ -139,6 +150,9 @@ func (i *imageConfigReader) getSourceOrModuleImageConfigs(
 	if err != nil {
 		return nil, nil, err
 	}
+	defer func() {
+		retErr = multierr.Append(retErr, moduleConfigSet.Close())
+	}()
 	moduleConfigs := moduleConfigSet.ModuleConfigs()
 	imageConfigs := make([]ImageConfig, 0, len(moduleConfigs))
 	var allFileAnnotations []bufanalysis.FileAnnotation
@@ -223,6 +237,9 @@ func (i *imageConfigReader) getImageImageConfig(
 		ctx,
 		readWriteBucket,
 		bufconfig.ReadConfigOSWithOverride(configOverride),
+		bufconfig.ReadConfigOSWithProfile(i.profile),
+		bufconfig.ReadConfigOSWithExtendsResolver(i.extendsResolver),
+		bufconfig.ReadConfigOSWithEnvContainer(container),
 	)
 	if err != nil {
 		return nil, err
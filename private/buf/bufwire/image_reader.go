@@ -37,19 +37,22 @@ const (
 )
 
 type imageReader struct {
-	logger      *zap.Logger
-	fetchReader buffetch.MessageReader
-	tracer      trace.Tracer
+	logger             *zap.Logger
+	fetchReader        buffetch.MessageReader
+	lazySourceCodeInfo bool
+	tracer             trace.Tracer
 }
 
 func newImageReader(
 	logger *zap.Logger,
 	fetchReader buffetch.MessageReader,
+	lazySourceCodeInfo bool,
 ) *imageReader {
 	return &imageReader{
-		logger:      logger.Named(loggerName),
-		fetchReader: fetchReader,
-		tracer:      otel.GetTracerProvider().Tracer(tracerName),
+		logger:             logger.Named(loggerName),
+		fetchReader:        fetchReader,
+		lazySourceCodeInfo: lazySourceCodeInfo,
+		tracer:             otel.GetTracerProvider().Tracer(tracerName),
 	}
 }
 
@@ -81,21 +84,69 @@ func (i *imageReader) GetImage(
 	if err != nil {
 		return nil, err
 	}
-	protoImage := &imagev1.Image{}
-	var imageFromProtoOptions []bufimage.NewImageForProtoOption
-	switch messageEncoding := messageRef.MessageEncoding(); messageEncoding {
-	// we have to double parse due to custom options
-	// See https://github.com/golang/protobuf/issues/1123
-	// TODO: revisit
-	case buffetch.MessageEncodingBinpb:
+	var image bufimage.Image
+	if messageRef.MessageEncoding() == buffetch.MessageEncodingBinpb {
+		// Binary images are decoded lazily, as they may be very large.
+		var imageForBinaryOptions []bufimage.NewImageForBinaryOption
+		if excludeSourceCodeInfo {
+			imageForBinaryOptions = append(imageForBinaryOptions, bufimage.WithExcludeSourceCodeInfo())
+		} else if i.lazySourceCodeInfo {
+			imageForBinaryOptions = append(imageForBinaryOptions, bufimage.WithLazySourceCodeInfo())
+		}
 		_, span := i.tracer.Start(ctx, "wire_unmarshal")
-		if err := protoencoding.NewWireUnmarshaler(nil).Unmarshal(data, protoImage); err != nil {
+		image, err = bufimage.NewImageForBinary(data, imageForBinaryOptions...)
+		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
 			span.End()
-			return nil, fmt.Errorf("could not unmarshal image: %v", err)
+			return nil, err
 		}
 		span.End()
+	} else {
+		image, err = i.getImageForNonBinary(ctx, messageRef, data, excludeSourceCodeInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(externalDirOrFilePaths) == 0 && len(externalExcludeDirOrFilePaths) == 0 {
+		return image, nil
+	}
+	imagePaths := make([]string, len(externalDirOrFilePaths))
+	for i, externalDirOrFilePath := range externalDirOrFilePaths {
+		imagePath, err := messageRef.PathForExternalPath(externalDirOrFilePath)
+		if err != nil {
+			return nil, err
+		}
+		imagePaths[i] = imagePath
+	}
+	excludePaths := make([]string, len(externalExcludeDirOrFilePaths))
+	for i, excludeDirOrFilePath := range externalExcludeDirOrFilePaths {
+		excludePath, err := messageRef.PathForExternalPath(excludeDirOrFilePath)
+		if err != nil {
+			return nil, err
+		}
+		excludePaths[i] = excludePath
+	}
+	if externalDirOrFilePathsAllowNotExist {
+		// externalDirOrFilePaths have to be targetPaths
+		return bufimage.ImageWithOnlyPathsAllowNotExist(image, imagePaths, excludePaths)
+	}
+	return bufimage.ImageWithOnlyPaths(image, imagePaths, excludePaths)
+}
+
+// getImageForNonBinary returns the Image for the JSON, txtpb, or YAML encoding.
+func (i *imageReader) getImageForNonBinary(
+	ctx context.Context,
+	messageRef buffetch.MessageRef,
+	data []byte,
+	excludeSourceCodeInfo bool,
+) (bufimage.Image, error) {
+	protoImage := &imagev1.Image{}
+	var imageFromProtoOptions []bufimage.NewImageForProtoOption
+	switch messageEncoding := messageRef.MessageEncoding(); messageEncoding {
+	// we have to double parse due to custom options
+	// See https://github.com/golang/protobuf/issues/1123
+	// TODO: revisit
 	case buffetch.MessageEncodingJSON:
 		resolver, err := i.bootstrapResolver(ctx, protoencoding.NewJSONUnmarshaler(nil), data)
 		if err != nil {
@@ -149,34 +200,7 @@ func (i *imageReader) GetImage(
 			fileDescriptorProto.SourceCodeInfo = nil
 		}
 	}
-	image, err := bufimage.NewImageForProto(protoImage, imageFromProtoOptions...)
-	if err != nil {
-		return nil, err
-	}
-	if len(externalDirOrFilePaths) == 0 && len(externalExcludeDirOrFilePaths) == 0 {
-		return image, nil
-	}
-	imagePaths := make([]string, len(externalDirOrFilePaths))
-	for i, externalDirOrFilePath := range externalDirOrFilePaths {
-		imagePath, err := messageRef.PathForExternalPath(externalDirOrFilePath)
-		if err != nil {
-			return nil, err
-		}
-		imagePaths[i] = imagePath
-	}
-	excludePaths := make([]string, len(externalExcludeDirOrFilePaths))
-	for i, excludeDirOrFilePath := range externalExcludeDirOrFilePaths {
-		excludePath, err := messageRef.PathForExternalPath(excludeDirOrFilePath)
-		if err != nil {
-			return nil, err
-		}
-		excludePaths[i] = excludePath
-	}
-	if externalDirOrFilePathsAllowNotExist {
-		// externalDirOrFilePaths have to be targetPaths
-		return bufimage.ImageWithOnlyPathsAllowNotExist(image, imagePaths, excludePaths)
-	}
-	return bufimage.ImageWithOnlyPaths(image, imagePaths, excludePaths)
+	return bufimage.NewImageForProto(protoImage, imageFromProtoOptions...)
 }
 
 func (i *imageReader) bootstrapResolver(
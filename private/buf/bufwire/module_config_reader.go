@@ -44,6 +44,8 @@ type moduleConfigReader struct {
 	storageosProvider   storageos.Provider
 	fetchReader         buffetch.Reader
 	moduleBucketBuilder bufmodulebuild.ModuleBucketBuilder
+	profile             string
+	extendsResolver     bufconfig.ExtendsResolver
 	tracer              trace.Tracer
 }
 
@@ -52,12 +54,16 @@ func newModuleConfigReader(
 	storageosProvider storageos.Provider,
 	fetchReader buffetch.Reader,
 	moduleBucketBuilder bufmodulebuild.ModuleBucketBuilder,
+	profile string,
+	extendsResolver bufconfig.ExtendsResolver,
 ) *moduleConfigReader {
 	return &moduleConfigReader{
 		logger:              logger,
 		storageosProvider:   storageosProvider,
 		fetchReader:         fetchReader,
 		moduleBucketBuilder: moduleBucketBuilder,
+		profile:             profile,
+		extendsResolver:     extendsResolver,
 		tracer:              otel.GetTracerProvider().Tracer("bufbuild/buf"),
 	}
 }
@@ -80,7 +86,11 @@ func (m *moduleConfigReader) GetModuleConfigSet(
 		}
 	}()
 	// We construct a new WorkspaceBuilder here so that the cache is only used for a single call.
-	workspaceBuilder := bufwork.NewWorkspaceBuilder()
+	workspaceBuilder := bufwork.NewWorkspaceBuilder(
+		bufwork.WorkspaceBuilderWithProfile(m.profile),
+		bufwork.WorkspaceBuilderWithExtendsResolver(m.extendsResolver),
+		bufwork.WorkspaceBuilderWithEnvContainer(container),
+	)
 	switch t := sourceOrModuleRef.(type) {
 	case buffetch.ProtoFileRef:
 		return m.getProtoFileModuleSourceConfigSet(
@@ -137,13 +147,18 @@ func (m *moduleConfigReader) getSourceModuleConfigSet(
 	externalDirOrFilePaths []string,
 	externalExcludeDirOrFilePaths []string,
 	externalDirOrFilePathsAllowNotExist bool,
-) (_ ModuleConfigSet, retErr error) {
+) (retModuleConfigSet ModuleConfigSet, retErr error) {
 	readBucketCloser, err := m.fetchReader.GetSourceBucket(ctx, container, sourceRef)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
-		retErr = multierr.Append(retErr, readBucketCloser.Close())
+		if retErr != nil {
+			retErr = multierr.Append(retErr, readBucketCloser.Close())
+			return
+		}
+		// The Modules read from the bucket, so it is closed with the ModuleConfigSet.
+		retModuleConfigSet = newModuleConfigSetWithCloser(retModuleConfigSet, readBucketCloser)
 	}()
 	existingConfigFilePath, err := bufwork.ExistingConfigFilePath(ctx, readBucketCloser)
 	if err != nil {
@@ -152,6 +167,7 @@ func (m *moduleConfigReader) getSourceModuleConfigSet(
 	if existingConfigFilePath != "" {
 		return m.getWorkspaceModuleConfigSet(
 			ctx,
+			container,
 			sourceRef,
 			workspaceBuilder,
 			readBucketCloser,
@@ -165,6 +181,7 @@ func (m *moduleConfigReader) getSourceModuleConfigSet(
 	}
 	moduleConfig, err := m.getSourceModuleConfig(
 		ctx,
+		container,
 		sourceRef,
 		readBucketCloser,
 		readBucketCloser.RelativeRootPath(),
@@ -247,6 +264,9 @@ func (m *moduleConfigReader) getModuleModuleConfig(
 		ctx,
 		readWriteBucket,
 		bufconfig.ReadConfigOSWithOverride(configOverride),
+		bufconfig.ReadConfigOSWithProfile(m.profile),
+		bufconfig.ReadConfigOSWithExtendsResolver(m.extendsResolver),
+		bufconfig.ReadConfigOSWithEnvContainer(container),
 	)
 	if err != nil {
 		return nil, err
@@ -263,11 +283,19 @@ func (m *moduleConfigReader) getProtoFileModuleSourceConfigSet(
 	externalDirOrFilePaths []string,
 	externalExcludeDirOrFilePaths []string,
 	externalDirOrFilePathsAllowNotExist bool,
-) (_ ModuleConfigSet, retErr error) {
+) (retModuleConfigSet ModuleConfigSet, retErr error) {
 	readBucketCloser, err := m.fetchReader.GetSourceBucket(ctx, container, protoFileRef)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if retErr != nil {
+			retErr = multierr.Append(retErr, readBucketCloser.Close())
+			return
+		}
+		// The Modules read from the bucket, so it is closed with the ModuleConfigSet.
+		retModuleConfigSet = newModuleConfigSetWithCloser(retModuleConfigSet, readBucketCloser)
+	}()
 	workspaceConfigs := slicesext.ToStructMap(bufwork.AllConfigFilePaths)
 	moduleConfigs := slicesext.ToStructMap(bufconfig.AllConfigFilePaths)
 	terminateFileProvider := readBucketCloser.TerminateFileProvider()
@@ -282,6 +310,18 @@ func (m *moduleConfigReader) getProtoFileModuleSourceConfigSet(
 			moduleConfigDirectory = terminateFile.Path()
 		}
 	}
+	if workspaceConfigDirectory == "" && moduleConfigDirectory != "" {
+		// A v2 buf.yaml defines a workspace instead of a module. The bucket is
+		// for the directory of the buf.yaml, as it is the only terminate file.
+		existingConfigFilePath, err := bufwork.ExistingConfigFilePath(ctx, readBucketCloser)
+		if err != nil {
+			return nil, err
+		}
+		if existingConfigFilePath != "" {
+			workspaceConfigDirectory = moduleConfigDirectory
+			moduleConfigDirectory = ""
+		}
+	}
 	// If a workspace and module are both found, then we need to check of the module is within
 	// the workspace. If it is, we use the workspace. Otherwise, we use the module.
 	if workspaceConfigDirectory != "" {
@@ -296,7 +336,12 @@ func (m *moduleConfigReader) getProtoFileModuleSourceConfigSet(
 			// proto file ref is contained within one of the workspace directories.
 			// If yes, we can set the `SubDirPath` for the bucket to the directory, to ensure we build all the
 			// dependencies for the directory. If not, then we will keep the `SubDirPath` as the working directory.
-			workspaceConfig, err := bufwork.GetConfigForBucket(ctx, readBucketCloser, readBucketCloser.RelativeRootPath())
+			workspaceConfig, err := bufwork.GetConfigForBucket(
+				ctx,
+				readBucketCloser,
+				readBucketCloser.RelativeRootPath(),
+				bufwork.GetConfigWithEnvContainer(container),
+			)
 			if err != nil {
 				return nil, err
 			}
@@ -309,6 +354,7 @@ func (m *moduleConfigReader) getProtoFileModuleSourceConfigSet(
 		}
 		return m.getWorkspaceModuleConfigSet(
 			ctx,
+			container,
 			protoFileRef,
 			workspaceBuilder,
 			readBucketCloser,
@@ -322,6 +368,7 @@ func (m *moduleConfigReader) getProtoFileModuleSourceConfigSet(
 	}
 	moduleConfig, err := m.getSourceModuleConfig(
 		ctx,
+		container,
 		protoFileRef,
 		readBucketCloser,
 		readBucketCloser.RelativeRootPath(),
@@ -347,6 +394,7 @@ func (m *moduleConfigReader) getProtoFileModuleSourceConfigSet(
 
 func (m *moduleConfigReader) getWorkspaceModuleConfigSet(
 	ctx context.Context,
+	container app.EnvContainer,
 	sourceRef buffetch.SourceRef,
 	workspaceBuilder bufwork.WorkspaceBuilder,
 	readBucket storage.ReadBucket,
@@ -357,7 +405,7 @@ func (m *moduleConfigReader) getWorkspaceModuleConfigSet(
 	externalExcludeDirOrFilePaths []string,
 	externalDirOrFilePathsAllowNotExist bool,
 ) (ModuleConfigSet, error) {
-	workspaceConfig, err := bufwork.GetConfigForBucket(ctx, readBucket, relativeRootPath)
+	workspaceConfig, err := bufwork.GetConfigForBucket(ctx, readBucket, relativeRootPath, bufwork.GetConfigWithEnvContainer(container))
 	if err != nil {
 		return nil, err
 	}
@@ -378,6 +426,7 @@ func (m *moduleConfigReader) getWorkspaceModuleConfigSet(
 	if subDirPath != "." {
 		moduleConfig, err := m.getSourceModuleConfig(
 			ctx,
+			container,
 			sourceRef,
 			readBucket,
 			relativeRootPath,
@@ -444,6 +493,7 @@ func (m *moduleConfigReader) getWorkspaceModuleConfigSet(
 		}
 		moduleConfig, err := m.getSourceModuleConfig(
 			ctx,
+			container,
 			sourceRef,
 			readBucket,
 			relativeRootPath,
@@ -479,6 +529,7 @@ func (m *moduleConfigReader) getWorkspaceModuleConfigSet(
 
 func (m *moduleConfigReader) getSourceModuleConfig(
 	ctx context.Context,
+	container app.EnvContainer,
 	sourceRef buffetch.SourceRef,
 	readBucket storage.ReadBucket,
 	relativeRootPath string,
@@ -519,6 +570,9 @@ func (m *moduleConfigReader) getSourceModuleConfig(
 		ctx,
 		mappedReadBucket,
 		bufconfig.ReadConfigOSWithOverride(configOverride),
+		bufconfig.ReadConfigOSWithProfile(m.profile),
+		bufconfig.ReadConfigOSWithExtendsResolver(m.extendsResolver),
+		bufconfig.ReadConfigOSWithEnvContainer(container),
 	)
 	if err != nil {
 		return nil, err
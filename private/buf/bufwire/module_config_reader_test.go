@@ -161,6 +161,9 @@ func TestExcludePathsForModule(t *testing.T) {
 				true,
 			)
 			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, moduleConfigSet.Close())
+			}()
 			require.Len(t, moduleConfigSet.ModuleConfigs(), 1)
 			moduleConfig := moduleConfigSet.ModuleConfigs()[0]
 			require.NotNil(t, moduleConfig)
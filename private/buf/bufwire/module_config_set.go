@@ -15,12 +15,16 @@
 package bufwire
 
 import (
+	"io"
+
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 )
 
 type moduleConfigSet struct {
 	moduleConfigs []ModuleConfig
 	workspace     bufmodule.Workspace
+	// Optional. May be nil.
+	closer io.Closer
 }
 
 func newModuleConfigSet(moduleConfigs []ModuleConfig, workspace bufmodule.Workspace) *moduleConfigSet {
@@ -30,6 +34,16 @@ func newModuleConfigSet(moduleConfigs []ModuleConfig, workspace bufmodule.Worksp
 	}
 }
 
+// newModuleConfigSetWithCloser returns a copy of the ModuleConfigSet that closes
+// the closer when it is closed.
+func newModuleConfigSetWithCloser(set ModuleConfigSet, closer io.Closer) *moduleConfigSet {
+	return &moduleConfigSet{
+		moduleConfigs: set.ModuleConfigs(),
+		workspace:     set.Workspace(),
+		closer:        closer,
+	}
+}
+
 func (m *moduleConfigSet) ModuleConfigs() []ModuleConfig {
 	return m.moduleConfigs
 }
@@ -37,3 +51,10 @@ func (m *moduleConfigSet) ModuleConfigs() []ModuleConfig {
 func (m *moduleConfigSet) Workspace() bufmodule.Workspace {
 	return m.workspace
 }
+
+func (m *moduleConfigSet) Close() error {
+	if m.closer == nil {
+		return nil
+	}
+	return m.closer.Close()
+}
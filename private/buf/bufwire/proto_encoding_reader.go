@@ -71,21 +71,9 @@ func (p *protoEncodingReader) GetMessage(
 	if err != nil {
 		return nil, err
 	}
-	var unmarshaler protoencoding.Unmarshaler
-	switch messageRef.MessageEncoding() {
-	case buffetch.MessageEncodingBinpb:
-		unmarshaler = protoencoding.NewWireUnmarshaler(resolver)
-	case buffetch.MessageEncodingJSON:
-		unmarshaler = protoencoding.NewJSONUnmarshaler(resolver)
-	case buffetch.MessageEncodingTxtpb:
-		unmarshaler = protoencoding.NewTxtpbUnmarshaler(resolver)
-	case buffetch.MessageEncodingYAML:
-		unmarshaler = protoencoding.NewYAMLUnmarshaler(
-			resolver,
-			protoencoding.YAMLUnmarshalerWithPath(messageRef.Path()),
-		)
-	default:
-		return nil, errors.New("unknown message encoding type")
+	unmarshaler, err := newUnmarshaler(resolver, messageRef)
+	if err != nil {
+		return nil, err
 	}
 	readCloser, err := p.fetchReader.GetMessageFile(ctx, container, messageRef)
 	if err != nil {
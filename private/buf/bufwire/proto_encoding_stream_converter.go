@@ -0,0 +1,151 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufreflect"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/recordio"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+type protoEncodingStreamConverter struct {
+	logger      *zap.Logger
+	fetchReader buffetch.MessageReader
+	fetchWriter buffetch.Writer
+}
+
+var _ ProtoEncodingStreamConverter = &protoEncodingStreamConverter{}
+
+func newProtoEncodingStreamConverter(
+	logger *zap.Logger,
+	fetchReader buffetch.MessageReader,
+	fetchWriter buffetch.Writer,
+) *protoEncodingStreamConverter {
+	return &protoEncodingStreamConverter{
+		logger:      logger,
+		fetchReader: fetchReader,
+		fetchWriter: fetchWriter,
+	}
+}
+
+func (p *protoEncodingStreamConverter) ConvertStream(
+	ctx context.Context,
+	container app.EnvStdioContainer,
+	image bufimage.Image,
+	typeName string,
+	fromMessageRef buffetch.MessageRef,
+	fromFraming recordio.Framing,
+	toMessageRef buffetch.MessageRef,
+	toFraming recordio.Framing,
+) (retErr error) {
+	ctx, span := otel.GetTracerProvider().Tracer("bufbuild/buf").Start(ctx, "convert_stream")
+	defer span.End()
+	defer func() {
+		if retErr != nil {
+			span.RecordError(retErr)
+			span.SetStatus(codes.Error, retErr.Error())
+		}
+	}()
+	if err := validateFramingForMessageRef(fromFraming, fromMessageRef); err != nil {
+		return err
+	}
+	if err := validateFramingForMessageRef(toFraming, toMessageRef); err != nil {
+		return err
+	}
+	resolver, err := protoencoding.NewResolver(
+		bufimage.ImageToFileDescriptorProtos(image)...,
+	)
+	if err != nil {
+		return err
+	}
+	unmarshaler, err := newUnmarshaler(resolver, fromMessageRef)
+	if err != nil {
+		return err
+	}
+	marshaler, err := newStreamMarshaler(resolver, toMessageRef)
+	if err != nil {
+		return err
+	}
+	message, err := bufreflect.NewMessage(ctx, image, typeName)
+	if err != nil {
+		return err
+	}
+	readCloser, err := p.fetchReader.GetMessageFile(ctx, container, fromMessageRef)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readCloser.Close())
+	}()
+	writeCloser, err := p.fetchWriter.PutMessageFile(ctx, container, toMessageRef)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeCloser.Close())
+	}()
+	recordReader, err := recordio.NewReader(readCloser, fromFraming)
+	if err != nil {
+		return err
+	}
+	recordWriter, err := recordio.NewWriter(writeCloser, toFraming)
+	if err != nil {
+		return err
+	}
+	var numRecords int
+	for {
+		record, err := recordReader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("record %d: %w", numRecords+1, err)
+		}
+		proto.Reset(message)
+		if err := unmarshaler.Unmarshal(record, message); err != nil {
+			return fmt.Errorf("record %d: unable to unmarshal the message: %v", numRecords+1, err)
+		}
+		data, err := marshaler.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", numRecords+1, err)
+		}
+		if err := recordWriter.Write(data); err != nil {
+			return fmt.Errorf("record %d: %w", numRecords+1, err)
+		}
+		numRecords++
+	}
+	p.logger.Debug("converted_stream", zap.Int("records", numRecords))
+	return recordWriter.Flush()
+}
+
+func validateFramingForMessageRef(framing recordio.Framing, messageRef buffetch.MessageRef) error {
+	if framing == recordio.FramingNewline && messageRef.MessageEncoding() != buffetch.MessageEncodingJSON {
+		return fmt.Errorf("%s framing can only be used with JSON encoding", recordio.FramingNewline.String())
+	}
+	return nil
+}
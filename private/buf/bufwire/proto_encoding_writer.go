@@ -16,7 +16,6 @@ package bufwire
 
 import (
 	"context"
-	"errors"
 
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
@@ -58,18 +57,9 @@ func (p *protoEncodingWriter) PutMessage(
 	if err != nil {
 		return err
 	}
-	var marshaler protoencoding.Marshaler
-	switch messageRef.MessageEncoding() {
-	case buffetch.MessageEncodingBinpb:
-		marshaler = protoencoding.NewWireMarshaler()
-	case buffetch.MessageEncodingJSON:
-		marshaler = newJSONMarshaler(resolver, messageRef)
-	case buffetch.MessageEncodingTxtpb:
-		marshaler = protoencoding.NewTxtpbMarshaler(resolver)
-	case buffetch.MessageEncodingYAML:
-		marshaler = newYAMLMarshaler(resolver, messageRef)
-	default:
-		return errors.New("unknown message encoding type")
+	marshaler, err := newMarshaler(resolver, messageRef)
+	if err != nil {
+		return err
 	}
 	data, err := marshaler.Marshal(message)
 	if err != nil {
@@ -15,14 +15,73 @@
 package bufwire
 
 import (
+	"errors"
+
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/pkg/protoencoding"
 )
 
+func newUnmarshaler(
+	resolver protoencoding.Resolver,
+	messageRef buffetch.MessageRef,
+) (protoencoding.Unmarshaler, error) {
+	switch messageRef.MessageEncoding() {
+	case buffetch.MessageEncodingBinpb:
+		return protoencoding.NewWireUnmarshaler(resolver), nil
+	case buffetch.MessageEncodingJSON:
+		return protoencoding.NewJSONUnmarshaler(resolver), nil
+	case buffetch.MessageEncodingTxtpb:
+		return protoencoding.NewTxtpbUnmarshaler(resolver), nil
+	case buffetch.MessageEncodingYAML:
+		return protoencoding.NewYAMLUnmarshaler(
+			resolver,
+			protoencoding.YAMLUnmarshalerWithPath(messageRef.Path()),
+		), nil
+	default:
+		return nil, errors.New("unknown message encoding type")
+	}
+}
+
+func newMarshaler(
+	resolver protoencoding.Resolver,
+	messageRef buffetch.MessageRef,
+) (protoencoding.Marshaler, error) {
+	switch messageRef.MessageEncoding() {
+	case buffetch.MessageEncodingBinpb:
+		return protoencoding.NewWireMarshaler(), nil
+	case buffetch.MessageEncodingJSON:
+		return newJSONMarshaler(resolver, messageRef), nil
+	case buffetch.MessageEncodingTxtpb:
+		return protoencoding.NewTxtpbMarshaler(resolver), nil
+	case buffetch.MessageEncodingYAML:
+		return newYAMLMarshaler(resolver, messageRef), nil
+	default:
+		return nil, errors.New("unknown message encoding type")
+	}
+}
+
 func newJSONMarshaler(
 	resolver protoencoding.Resolver,
 	messageRef buffetch.MessageRef,
 ) protoencoding.Marshaler {
+	return protoencoding.NewJSONMarshaler(resolver, newJSONMarshalerOptions(messageRef)...)
+}
+
+// newStreamMarshaler returns a new Marshaler for the records of a stream.
+//
+// This is the same as newMarshaler, except that JSON is marshalled with a
+// Marshaler that is specialized for many messages of the same type.
+func newStreamMarshaler(
+	resolver protoencoding.Resolver,
+	messageRef buffetch.MessageRef,
+) (protoencoding.Marshaler, error) {
+	if messageRef.MessageEncoding() == buffetch.MessageEncodingJSON {
+		return protoencoding.NewJSONStreamMarshaler(resolver, newJSONMarshalerOptions(messageRef)...), nil
+	}
+	return newMarshaler(resolver, messageRef)
+}
+
+func newJSONMarshalerOptions(messageRef buffetch.MessageRef) []protoencoding.JSONMarshalerOption {
 	jsonMarshalerOptions := []protoencoding.JSONMarshalerOption{
 		//protoencoding.JSONMarshalerWithIndent(),
 	}
@@ -38,7 +97,7 @@ func newJSONMarshaler(
 			protoencoding.JSONMarshalerWithUseEnumNumbers(),
 		)
 	}
-	return protoencoding.NewJSONMarshaler(resolver, jsonMarshalerOptions...)
+	return jsonMarshalerOptions
 }
 
 func newYAMLMarshaler(
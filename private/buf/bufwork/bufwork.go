@@ -51,6 +51,65 @@
 //	    │           └── pet.proto
 //	    └── buf.yaml
 //
+// Directories can also be patterns, which are expanded to the directories in the
+// workspace that they match when the workspace is read. A "*" matches any part of a
+// single directory name, and a "**" matches any number of nested directories:
+//
+//	// buf.work.yaml
+//	version: v1
+//	directories:
+//	  - "services/*/proto"
+//	  - "libs/**/schema"
+//
+// Patterns that start with "*" must be quoted, as they are otherwise YAML aliases.
+// The directories that a pattern matches are sorted, and it is an error for a
+// pattern to not match any directories.
+//
+// The build, breaking, and lint sections of a buf.work.yaml set the defaults for
+// the modules in the workspace. Each module uses the defaults for the keys of these
+// sections that its buf.yaml does not set, so a module can override a specific
+// key, such as lint.except, and still inherit the rest:
+//
+//	// buf.work.yaml
+//	version: v1
+//	directories:
+//	  - paymentapis
+//	  - petapis
+//	lint:
+//	  use:
+//	    - DEFAULT
+//	  except:
+//	    - PACKAGE_VERSION_SUFFIX
+//	breaking:
+//	  use:
+//	    - FILE
+//
+// Paths in the defaults, such as lint.ignore, are relative to the root of each
+// module. The defaults only apply to modules with v1 configurations.
+//
+// A workspace can instead be defined by a v2 buf.yaml at its root, which configures
+// the modules of the workspace in a single file, so that the modules do not have
+// their own buf.yaml or buf.lock files:
+//
+//	// buf.yaml
+//	version: v2
+//	modules:
+//	  - path: paymentapis
+//	    name: buf.build/acme/paymentapis
+//	  - path: petapis
+//	    lint:
+//	      except:
+//	        - PACKAGE_VERSION_SUFFIX
+//	deps:
+//	  - buf.build/googleapis/googleapis
+//	lint:
+//	  use:
+//	    - DEFAULT
+//
+// The deps, and the buf.lock next to the buf.yaml, are shared by all of the modules,
+// and the lint and breaking sections set the defaults for the modules as above. A
+// buf.work.yaml can be migrated to a v2 buf.yaml with `buf beta migrate-v2`.
+//
 // Note that inputs MUST NOT overlap with any of the directories defined in the buf.work.yaml
 // file. For example, it's not possible to build input "paymentapis/acme" since the image
 // would otherwise include the content defined in paymentapis/acme/payment/v2/payment.proto as
@@ -64,9 +123,13 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/bufbreakingconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleconfig"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
 )
@@ -125,8 +188,42 @@ type WorkspaceBuilder interface {
 }
 
 // NewWorkspaceBuilder returns a new WorkspaceBuilder.
-func NewWorkspaceBuilder() WorkspaceBuilder {
-	return newWorkspaceBuilder()
+func NewWorkspaceBuilder(options ...WorkspaceBuilderOption) WorkspaceBuilder {
+	return newWorkspaceBuilder(options...)
+}
+
+// WorkspaceBuilderOption is an option for a new WorkspaceBuilder.
+type WorkspaceBuilderOption func(*workspaceBuilder)
+
+// WorkspaceBuilderWithProfile returns a new WorkspaceBuilderOption that applies the
+// given profile to the configurations of the modules of the workspace.
+//
+// See bufconfig.ReadConfigOSWithProfile for how profiles are applied.
+func WorkspaceBuilderWithProfile(profile string) WorkspaceBuilderOption {
+	return func(workspaceBuilder *workspaceBuilder) {
+		workspaceBuilder.profile = profile
+	}
+}
+
+// WorkspaceBuilderWithExtendsResolver returns a new WorkspaceBuilderOption that resolves
+// the modules that the configurations of the modules of the workspace extend with the
+// given ExtendsResolver.
+//
+// See bufconfig.ReadConfigOSWithExtendsResolver for how extends are applied.
+func WorkspaceBuilderWithExtendsResolver(extendsResolver bufconfig.ExtendsResolver) WorkspaceBuilderOption {
+	return func(workspaceBuilder *workspaceBuilder) {
+		workspaceBuilder.extendsResolver = extendsResolver
+	}
+}
+
+// WorkspaceBuilderWithEnvContainer returns a new WorkspaceBuilderOption that expands
+// the given environment variables in the configurations of the modules of the workspace.
+//
+// See bufconfig.ReadConfigOSWithEnvContainer for how environment variables are expanded.
+func WorkspaceBuilderWithEnvContainer(envContainer app.EnvContainer) WorkspaceBuilderOption {
+	return func(workspaceBuilder *workspaceBuilder) {
+		workspaceBuilder.envContainer = envContainer
+	}
 }
 
 // BuildOptionsForWorkspaceDirectory returns the bufmodulebuild.BuildOptions required for
@@ -324,29 +421,72 @@ func ExternalPathsToSubDirRelPaths(
 
 // Config is the workspace config.
 type Config struct {
-	// Directories are normalized and validated.
+	// Directories are normalized and validated, and any directory patterns
+	// are expanded.
 	//
 	// Must be non-empty to be a valid configuration.
 	Directories []string
+	// ModuleConfigDefaults are the defaults for the configurations of the
+	// modules in the workspace, if any.
+	//
+	// A module uses the default for each key of its build, breaking, and lint
+	// sections that its configuration does not set.
+	ModuleConfigDefaults *bufconfig.ConfigDefaults
+	// DirectoryToModuleConfigData are the generated v1 configurations of the
+	// modules of a workspace defined by a v2 buf.yaml, keyed by directory.
+	//
+	// Empty for a workspace defined by a buf.work.yaml.
+	DirectoryToModuleConfigData map[string][]byte
+	// LockFileData is the buf.lock of a workspace defined by a v2 buf.yaml,
+	// which is shared by all of its modules, if any.
+	LockFileData []byte
 }
 
 // GetConfigForBucket gets the Config for the YAML data at ConfigFilePath.
 //
 // This function expects that there is a valid non-empty configuration in the bucket. Otherwise, this errors.
-func GetConfigForBucket(ctx context.Context, readBucket storage.ReadBucket, relativeRootPath string) (*Config, error) {
-	return getConfigForBucket(ctx, readBucket, relativeRootPath)
+func GetConfigForBucket(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	relativeRootPath string,
+	options ...GetConfigOption,
+) (*Config, error) {
+	getConfigOptions := newGetConfigOptions()
+	for _, option := range options {
+		option(getConfigOptions)
+	}
+	return getConfigForBucket(ctx, readBucket, relativeRootPath, getConfigOptions.envContainer)
 }
 
 // GetConfigForData gets the Config for the given JSON or YAML data.
 //
 // This function expects that there is a valid non-empty configuration. Otherwise, this errors.
-func GetConfigForData(ctx context.Context, data []byte) (*Config, error) {
-	return getConfigForData(ctx, data)
+func GetConfigForData(ctx context.Context, data []byte, options ...GetConfigOption) (*Config, error) {
+	getConfigOptions := newGetConfigOptions()
+	for _, option := range options {
+		option(getConfigOptions)
+	}
+	return getConfigForData(ctx, data, getConfigOptions.envContainer)
+}
+
+// GetConfigOption is an option for GetConfigForBucket and GetConfigForData.
+type GetConfigOption func(*getConfigOptions)
+
+// GetConfigWithEnvContainer sets the environment variables that are expanded in
+// the directories of the configuration, and in the deps of a v2 configuration.
+//
+// See bufconfig.GetConfigWithEnvContainer for how environment variables are expanded.
+func GetConfigWithEnvContainer(envContainer app.EnvContainer) GetConfigOption {
+	return func(getConfigOptions *getConfigOptions) {
+		getConfigOptions.envContainer = envContainer
+	}
 }
 
 // ExistingConfigFilePath checks if a configuration file exists, and if so, returns the path
 // within the ReadBucket of this configuration file.
 //
+// A v2 buf.yaml is a workspace configuration file, as it defines a workspace.
+//
 // Returns empty string and no error if no configuration file exists.
 func ExistingConfigFilePath(ctx context.Context, readBucket storage.ReadBucket) (string, error) {
 	for _, configFilePath := range AllConfigFilePaths {
@@ -358,14 +498,54 @@ func ExistingConfigFilePath(ctx context.Context, readBucket storage.ReadBucket)
 			return configFilePath, nil
 		}
 	}
+	_, isConfigV2, err := readConfigV2Data(ctx, readBucket)
+	if err != nil {
+		return "", err
+	}
+	if isConfigV2 {
+		return bufconfig.ExternalConfigV2FilePath, nil
+	}
 	return "", nil
 }
 
+// ReadBucketForDirectory returns the ReadBucket for the module in the given
+// directory of the workspace.
+//
+// For a workspace defined by a v2 buf.yaml, this includes the generated
+// configuration of the module and the buf.lock of the workspace.
+func ReadBucketForDirectory(
+	ctx context.Context,
+	workspaceConfig *Config,
+	readBucket storage.ReadBucket,
+	relativeRootPath string,
+	directory string,
+) (storage.ReadBucket, error) {
+	return newReadBucketForDirectory(
+		ctx,
+		workspaceConfig,
+		readBucket,
+		directory,
+		filepath.Join(normalpath.Unnormalize(relativeRootPath), workspaceConfigFilePath(workspaceConfig)),
+	)
+}
+
 // ExternalConfigV1 represents the on-disk representation
 // of the workspace configuration at version v1.
 type ExternalConfigV1 struct {
 	Version     string   `json:"version,omitempty" yaml:"version,omitempty"`
 	Directories []string `json:"directories,omitempty" yaml:"directories,omitempty"`
+	// Build, Breaking, and Lint are the defaults for the modules in the workspace.
+	Build    bufmoduleconfig.ExternalConfigV1   `json:"build,omitempty" yaml:"build,omitempty"`
+	Breaking bufbreakingconfig.ExternalConfigV1 `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	Lint     buflintconfig.ExternalConfigV1     `json:"lint,omitempty" yaml:"lint,omitempty"`
+}
+
+// externalConfigDefaultsV1 is the representation of the defaults in the
+// ExternalConfigV1 that keeps which keys are set.
+type externalConfigDefaultsV1 struct {
+	Build    map[string]interface{} `json:"build,omitempty" yaml:"build,omitempty"`
+	Breaking map[string]interface{} `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	Lint     map[string]interface{} `json:"lint,omitempty" yaml:"lint,omitempty"`
 }
 
 type externalConfigVersion struct {
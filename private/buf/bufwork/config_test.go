@@ -15,8 +15,12 @@
 package bufwork
 
 import (
+	"context"
 	"testing"
 
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,3 +49,229 @@ func TestNewConfigV1RootDirectoryError(t *testing.T) {
 	)
 	require.Error(t, err)
 }
+
+func TestGetConfigForBucketDirectoryPatterns(t *testing.T) {
+	t.Parallel()
+	config, err := testGetConfigForBucketDirectories(
+		t,
+		[]string{"services/*/proto", "libs/**/schema", "services/b/proto", "other"},
+		"services/b/proto/b.proto",
+		"services/a/proto/a/v1/a.proto",
+		"services/c/api/c.proto",
+		"services/.hidden/proto/hidden.proto",
+		"libs/schema/schema.proto",
+		"libs/x/y/schema/schema.proto",
+		"libs/.hidden/schema/schema.proto",
+		"other/other.proto",
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"libs/schema",
+			"libs/x/y/schema",
+			"other",
+			"services/a/proto",
+			"services/b/proto",
+		},
+		config.Directories,
+	)
+}
+
+func TestGetConfigForBucketDirectoryPatternNoMatchError(t *testing.T) {
+	t.Parallel()
+	_, err := testGetConfigForBucketDirectories(
+		t,
+		[]string{"services/*/proto"},
+		"services/a/api/a.proto",
+	)
+	require.EqualError(t, err, `directory pattern "services/*/proto" listed in buf.work.yaml did not match any directories`)
+}
+
+func TestGetConfigForBucketDirectoryPatternOverlapError(t *testing.T) {
+	t.Parallel()
+	_, err := testGetConfigForBucketDirectories(
+		t,
+		[]string{"**/proto"},
+		"proto/a.proto",
+		"proto/b/proto/b.proto",
+	)
+	require.EqualError(t, err, `directory "proto" contains directory "proto/b/proto" in buf.work.yaml`)
+}
+
+func TestGetConfigForDataDirectoryPatternError(t *testing.T) {
+	t.Parallel()
+	_, err := GetConfigForData(
+		context.Background(),
+		[]byte(`{"version":"v1","directories":["services/*/proto"]}`),
+	)
+	require.Error(t, err)
+}
+
+func TestGetConfigForDataWithEnvContainer(t *testing.T) {
+	t.Parallel()
+	config, err := GetConfigForData(
+		context.Background(),
+		[]byte(`version: v1
+directories:
+  - ${PROTO_DIR}
+  - ${OTHER_DIR:-other}
+`),
+		GetConfigWithEnvContainer(app.NewEnvContainer(map[string]string{"PROTO_DIR": "proto"})),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"other", "proto"}, config.Directories)
+	_, err = GetConfigForData(
+		context.Background(),
+		[]byte(`version: v1
+directories:
+  - ${PROTO_DIR}
+`),
+		GetConfigWithEnvContainer(app.NewEnvContainer(nil)),
+	)
+	require.EqualError(t, err, "Configuration data: line 3: environment variable PROTO_DIR is not set")
+}
+
+func TestGetConfigForBucketV2(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	readBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"buf.yaml": []byte(`version: v2
+modules:
+  - path: proto/a
+    name: buf.build/acme/a
+    excludes:
+      - internal
+    includes:
+      - "**/v1/**"
+    lint:
+      except:
+        - PACKAGE_VERSION_SUFFIX
+  - path: proto/b
+deps:
+  - buf.build/acme/a
+  - buf.build/acme/c
+breaking:
+  use:
+    - WIRE
+`),
+			"buf.lock":          []byte("version: v1\n"),
+			"proto/a/a/a.proto": []byte(`syntax = "proto3";`),
+			"proto/b/b/b.proto": []byte(`syntax = "proto3";`),
+		},
+	)
+	require.NoError(t, err)
+	config, err := GetConfigForBucket(ctx, readBucket, ".")
+	require.NoError(t, err)
+	require.Equal(t, []string{"proto/a", "proto/b"}, config.Directories)
+	require.Equal(t, []byte("version: v1\n"), config.LockFileData)
+	require.NotNil(t, config.ModuleConfigDefaults)
+	require.Equal(t, map[string]interface{}{"use": []interface{}{"WIRE"}}, config.ModuleConfigDefaults.Breaking)
+	require.Equal(
+		t,
+		`build:
+  excludes:
+    - internal
+  includes:
+    - '**/v1/**'
+deps:
+  - buf.build/acme/c
+lint:
+  except:
+    - PACKAGE_VERSION_SUFFIX
+name: buf.build/acme/a
+version: v1
+`,
+		string(config.DirectoryToModuleConfigData["proto/a"]),
+	)
+	require.Equal(
+		t,
+		`deps:
+  - buf.build/acme/a
+  - buf.build/acme/c
+version: v1
+`,
+		string(config.DirectoryToModuleConfigData["proto/b"]),
+	)
+	existingConfigFilePath, err := ExistingConfigFilePath(ctx, readBucket)
+	require.NoError(t, err)
+	require.Equal(t, "buf.yaml", existingConfigFilePath)
+	readBucketForDirectory, err := ReadBucketForDirectory(ctx, config, readBucket, ".", "proto/b")
+	require.NoError(t, err)
+	for _, path := range []string{"buf.yaml", "buf.lock", "b/b.proto"} {
+		exists, err := storage.Exists(ctx, readBucketForDirectory, path)
+		require.NoError(t, err)
+		require.True(t, exists, path)
+	}
+}
+
+func TestGetConfigForBucketV2ModuleConfigError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	readBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"buf.yaml":          []byte("version: v2\nmodules:\n  - path: proto/a\n"),
+			"proto/a/buf.yaml":  []byte("version: v1\n"),
+			"proto/a/a/a.proto": []byte(`syntax = "proto3";`),
+		},
+	)
+	require.NoError(t, err)
+	config, err := GetConfigForBucket(ctx, readBucket, ".")
+	require.NoError(t, err)
+	_, err = ReadBucketForDirectory(ctx, config, readBucket, ".", "proto/a")
+	require.Error(t, err)
+	readBucket, err = storagemem.NewReadBucket(
+		map[string][]byte{
+			"buf.yaml":      []byte("version: v2\nmodules:\n  - path: proto/a\n"),
+			"buf.work.yaml": []byte("version: v1\ndirectories:\n  - proto/a\n"),
+		},
+	)
+	require.NoError(t, err)
+	_, err = GetConfigForBucket(ctx, readBucket, ".")
+	require.Error(t, err)
+}
+
+func testGetConfigForBucketDirectories(t *testing.T, directories []string, filePaths ...string) (*Config, error) {
+	configData := "version: v1\ndirectories:\n"
+	for _, directory := range directories {
+		configData += "  - \"" + directory + "\"\n"
+	}
+	pathToData := map[string][]byte{
+		ExternalConfigV1FilePath: []byte(configData),
+	}
+	for _, filePath := range filePaths {
+		pathToData[filePath] = []byte(`syntax = "proto3";`)
+	}
+	readBucket, err := storagemem.NewReadBucket(pathToData)
+	require.NoError(t, err)
+	return GetConfigForBucket(context.Background(), readBucket, ".")
+}
+
+func TestGetConfigForDataModuleConfigDefaults(t *testing.T) {
+	t.Parallel()
+	config, err := GetConfigForData(
+		context.Background(),
+		[]byte(`version: v1
+directories:
+  - foo
+lint:
+  except:
+    - PACKAGE_VERSION_SUFFIX
+`),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, config.ModuleConfigDefaults)
+	require.Equal(t, map[string]interface{}{"except": []interface{}{"PACKAGE_VERSION_SUFFIX"}}, config.ModuleConfigDefaults.Lint)
+	require.Empty(t, config.ModuleConfigDefaults.Breaking)
+	_, err = GetConfigForData(
+		context.Background(),
+		[]byte(`version: v1
+directories:
+  - foo
+lint:
+  unknown: true
+`),
+	)
+	require.Error(t, err)
+}
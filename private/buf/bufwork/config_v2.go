@@ -0,0 +1,263 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/buflock"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/envexpand"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+)
+
+// sharedExternalConfigV2Keys are the keys of a v2 configuration that are
+// shared by all of its modules.
+//
+// The deps are also shared, but not with the module that is a dependency.
+var sharedExternalConfigV2Keys = []string{
+	"extends",
+	"format",
+	"dependency_constraints",
+	"profiles",
+}
+
+// readConfigV2Data reads the v2 configuration at the root of the bucket, and
+// returns false if there is no v2 configuration.
+func readConfigV2Data(ctx context.Context, readBucket storage.ReadBucket) ([]byte, bool, error) {
+	data, err := storage.ReadPath(ctx, readBucket, bufconfig.ExternalConfigV2FilePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	var externalConfigVersion bufconfig.ExternalConfigVersion
+	if err := encoding.UnmarshalYAMLNonStrict(data, &externalConfigVersion); err != nil {
+		// The error is reported when the file is read as a module configuration.
+		return nil, false, nil
+	}
+	return data, externalConfigVersion.Version == bufconfig.V2Version, nil
+}
+
+// newConfigV2 returns the Config for the workspace that is defined by the given
+// v2 configuration data.
+//
+// The configuration of each module is generated as a v1 configuration with the
+// name and excludes of the module, its lint and breaking sections, and the
+// sections that are shared by all modules.
+func newConfigV2(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	data []byte,
+	workspaceID string,
+	envContainer app.EnvContainer,
+) (*Config, error) {
+	if envContainer != nil {
+		// The deps are expanded here, as they are copied to the configurations of
+		// the modules. The other values are expanded when the configurations of
+		// the modules are read.
+		var err error
+		data, err = envexpand.ExpandYAML(data, envContainer, []string{"deps", "*"})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", workspaceID, err)
+		}
+	}
+	// This validates the configuration, including the modules.
+	if _, err := bufconfig.GetConfigForData(ctx, data, bufconfig.GetConfigWithEnvContainer(envContainer)); err != nil {
+		return nil, fmt.Errorf("%s is invalid: %w", workspaceID, err)
+	}
+	var externalConfig bufconfig.ExternalConfigV2
+	if err := encoding.UnmarshalYAMLStrict(data, &externalConfig); err != nil {
+		return nil, err
+	}
+	if len(externalConfig.Modules) == 0 {
+		return nil, fmt.Errorf(`%s has no modules set. Please add "modules: [...]"`, workspaceID)
+	}
+	// The keys that are set are needed, so that a module only overrides the
+	// defaults for the keys that it sets.
+	externalConfigMap := make(map[string]interface{})
+	if err := encoding.UnmarshalYAMLNonStrict(data, &externalConfigMap); err != nil {
+		return nil, err
+	}
+	externalModuleConfigMaps, ok := externalConfigMap["modules"].([]interface{})
+	if !ok || len(externalModuleConfigMaps) != len(externalConfig.Modules) {
+		// Unreachable, as the configuration was unmarshalled strictly.
+		return nil, fmt.Errorf("%s has invalid modules", workspaceID)
+	}
+	directories := make([]string, len(externalConfig.Modules))
+	for i, externalModuleConfig := range externalConfig.Modules {
+		directories[i] = externalModuleConfig.Path
+	}
+	config, err := newConfigV1(
+		ExternalConfigV1{
+			Version:     V1Version,
+			Directories: directories,
+		},
+		workspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	config.DirectoryToModuleConfigData = make(map[string][]byte, len(externalConfig.Modules))
+	for i, externalModuleConfig := range externalConfig.Modules {
+		externalModuleConfigMap, ok := externalModuleConfigMaps[i].(map[string]interface{})
+		if !ok {
+			// Unreachable, as the configuration was unmarshalled strictly.
+			return nil, fmt.Errorf("%s has an invalid module %q", workspaceID, externalModuleConfig.Path)
+		}
+		moduleConfigData, err := newModuleConfigV1DataForV2(externalConfigMap, externalModuleConfigMap, externalConfig.Deps, externalModuleConfig)
+		if err != nil {
+			return nil, err
+		}
+		// The directories were validated by newConfigV1.
+		config.DirectoryToModuleConfigData[normalpath.Normalize(externalModuleConfig.Path)] = moduleConfigData
+	}
+	lintDefaults, _ := externalConfigMap["lint"].(map[string]interface{})
+	breakingDefaults, _ := externalConfigMap["breaking"].(map[string]interface{})
+	if len(lintDefaults) > 0 || len(breakingDefaults) > 0 {
+		config.ModuleConfigDefaults = &bufconfig.ConfigDefaults{
+			Breaking: breakingDefaults,
+			Lint:     lintDefaults,
+		}
+	}
+	lockFileData, err := storage.ReadPath(ctx, readBucket, buflock.ExternalConfigFilePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	config.LockFileData = lockFileData
+	return config, nil
+}
+
+// newModuleConfigV1DataForV2 returns the v1 configuration data for a module of
+// a v2 configuration.
+func newModuleConfigV1DataForV2(
+	externalConfigMap map[string]interface{},
+	externalModuleConfigMap map[string]interface{},
+	deps []string,
+	externalModuleConfig bufconfig.ExternalModuleConfigV2,
+) ([]byte, error) {
+	moduleConfigMap := map[string]interface{}{
+		"version": bufconfig.V1Version,
+	}
+	for _, key := range sharedExternalConfigV2Keys {
+		if value, ok := externalConfigMap[key]; ok {
+			moduleConfigMap[key] = value
+		}
+	}
+	moduleDeps := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		if externalModuleConfig.Name != "" {
+			// The deps were validated with the configuration.
+			moduleReference, err := bufmoduleref.ModuleReferenceForString(dep)
+			if err != nil {
+				return nil, err
+			}
+			if moduleReference.IdentityString() == externalModuleConfig.Name {
+				continue
+			}
+		}
+		moduleDeps = append(moduleDeps, dep)
+	}
+	if len(moduleDeps) > 0 {
+		moduleConfigMap["deps"] = moduleDeps
+	}
+	if externalModuleConfig.Name != "" {
+		moduleConfigMap["name"] = externalModuleConfig.Name
+	}
+	buildConfigMap := make(map[string]interface{})
+	if len(externalModuleConfig.Excludes) > 0 {
+		buildConfigMap["excludes"] = externalModuleConfig.Excludes
+	}
+	if len(externalModuleConfig.Includes) > 0 {
+		buildConfigMap["includes"] = externalModuleConfig.Includes
+	}
+	if len(buildConfigMap) > 0 {
+		moduleConfigMap["build"] = buildConfigMap
+	}
+	for _, key := range []string{"lint", "breaking"} {
+		if value, ok := externalModuleConfigMap[key]; ok {
+			moduleConfigMap[key] = value
+		}
+	}
+	return encoding.MarshalYAML(moduleConfigMap)
+}
+
+// newReadBucketForDirectory returns the ReadBucket for the module in the given
+// workspace directory.
+//
+// For a workspace that is defined by a v2 configuration, the generated configuration
+// of the module, and the buf.lock at the root of the workspace, are added to the
+// directory. It is an error for the directory to have its own configuration files.
+func newReadBucketForDirectory(
+	ctx context.Context,
+	workspaceConfig *Config,
+	readBucket storage.ReadBucket,
+	directory string,
+	workspaceID string,
+) (storage.ReadBucket, error) {
+	readBucketForDirectory := storage.MapReadBucket(readBucket, storage.MapOnPrefix(directory))
+	moduleConfigData, ok := workspaceConfig.DirectoryToModuleConfigData[directory]
+	if !ok {
+		return readBucketForDirectory, nil
+	}
+	for _, configFilePath := range append([]string{buflock.ExternalConfigFilePath}, bufconfig.AllConfigFilePaths...) {
+		exists, err := storage.Exists(ctx, readBucketForDirectory, configFilePath)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf(
+				`directory "%s" listed in %s has a %s, but the modules of a %s configuration are configured in its %s`,
+				normalpath.Unnormalize(directory),
+				workspaceID,
+				configFilePath,
+				bufconfig.V2Version,
+				bufconfig.ExternalConfigV2FilePath,
+			)
+		}
+	}
+	pathToData := map[string][]byte{
+		bufconfig.ExternalConfigV1FilePath: moduleConfigData,
+	}
+	if len(workspaceConfig.LockFileData) > 0 {
+		pathToData[buflock.ExternalConfigFilePath] = workspaceConfig.LockFileData
+	}
+	configReadBucket, err := storagemem.NewReadBucket(pathToData)
+	if err != nil {
+		return nil, err
+	}
+	return storage.MultiReadBucket(readBucketForDirectory, configReadBucket), nil
+}
+
+// workspaceConfigFilePath returns the path of the file that defines the workspace,
+// which is only used for error messages.
+func workspaceConfigFilePath(workspaceConfig *Config) string {
+	if workspaceConfig.DirectoryToModuleConfigData != nil {
+		return bufconfig.ExternalConfigV2FilePath
+	}
+	// We know that if the file is actually buf.work for legacy reasons, this will be wrong,
+	// but we accept that as this shouldn't happen often anymore and this is just
+	// used for error messages.
+	return ExternalConfigV1FilePath
+}
@@ -20,7 +20,10 @@ import (
 	"io"
 	"path/filepath"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/envexpand"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
@@ -29,7 +32,12 @@ import (
 	"go.uber.org/multierr"
 )
 
-func getConfigForBucket(ctx context.Context, readBucket storage.ReadBucket, relativeRootPath string) (_ *Config, retErr error) {
+func getConfigForBucket(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	relativeRootPath string,
+	envContainer app.EnvContainer,
+) (_ *Config, retErr error) {
 	ctx, span := otel.GetTracerProvider().Tracer("bufbuild/buf").Start(ctx, "get_workspace_config")
 	defer span.End()
 	defer func() {
@@ -53,6 +61,27 @@ func getConfigForBucket(ctx context.Context, readBucket storage.ReadBucket, rela
 			foundConfigFilePaths = append(foundConfigFilePaths, configFilePath)
 		}
 	}
+	configV2Data, isConfigV2, err := readConfigV2Data(ctx, readBucket)
+	if err != nil {
+		return nil, err
+	}
+	if isConfigV2 {
+		if len(foundConfigFilePaths) > 0 {
+			return nil, fmt.Errorf(
+				"a %s %s defines a workspace, so %s cannot also exist",
+				bufconfig.V2Version,
+				bufconfig.ExternalConfigV2FilePath,
+				stringutil.SliceToString(foundConfigFilePaths),
+			)
+		}
+		return newConfigV2(
+			ctx,
+			readBucket,
+			configV2Data,
+			filepath.Join(normalpath.Unnormalize(relativeRootPath), bufconfig.ExternalConfigV2FilePath),
+			envContainer,
+		)
+	}
 	switch len(foundConfigFilePaths) {
 	case 0:
 		// Did not find anything, return the default.
@@ -77,13 +106,15 @@ func getConfigForBucket(ctx context.Context, readBucket storage.ReadBucket, rela
 			workspaceID,
 			data,
 			readObjectCloser.ExternalPath(),
+			readBucket,
+			envContainer,
 		)
 	default:
 		return nil, fmt.Errorf("only one workspace file can exist but found multiple workspace files: %s", stringutil.SliceToString(foundConfigFilePaths))
 	}
 }
 
-func getConfigForData(ctx context.Context, data []byte) (*Config, error) {
+func getConfigForData(ctx context.Context, data []byte, envContainer app.EnvContainer) (*Config, error) {
 	_, span := otel.GetTracerProvider().Tracer("bufbuild/buf").Start(ctx, "get_workspace_config_for_data")
 	defer span.End()
 	config, err := getConfigForDataInternal(
@@ -93,6 +124,8 @@ func getConfigForData(ctx context.Context, data []byte) (*Config, error) {
 		"configuration data",
 		data,
 		"Configuration data",
+		nil,
+		envContainer,
 	)
 	if err != nil {
 		span.RecordError(err)
@@ -108,7 +141,16 @@ func getConfigForDataInternal(
 	workspaceID string,
 	data []byte,
 	id string,
+	readBucket storage.ReadBucket,
+	envContainer app.EnvContainer,
 ) (*Config, error) {
+	if envContainer != nil {
+		var err error
+		data, err = envexpand.ExpandYAML(data, envContainer, []string{"directories", "*"})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+	}
 	var externalConfigVersion externalConfigVersion
 	if err := unmarshalNonStrict(data, &externalConfigVersion); err != nil {
 		return nil, err
@@ -120,7 +162,27 @@ func getConfigForDataInternal(
 	if err := unmarshalStrict(data, &externalConfigV1); err != nil {
 		return nil, err
 	}
-	return newConfigV1(externalConfigV1, workspaceID)
+	directories, err := expandDirectoryPatterns(ctx, readBucket, externalConfigV1.Directories, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	externalConfigV1.Directories = directories
+	config, err := newConfigV1(externalConfigV1, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	var externalConfigDefaultsV1 externalConfigDefaultsV1
+	if err := unmarshalNonStrict(data, &externalConfigDefaultsV1); err != nil {
+		return nil, err
+	}
+	if len(externalConfigDefaultsV1.Build) > 0 || len(externalConfigDefaultsV1.Breaking) > 0 || len(externalConfigDefaultsV1.Lint) > 0 {
+		config.ModuleConfigDefaults = &bufconfig.ConfigDefaults{
+			Build:    externalConfigDefaultsV1.Build,
+			Breaking: externalConfigDefaultsV1.Breaking,
+			Lint:     externalConfigDefaultsV1.Lint,
+		}
+	}
+	return config, nil
 }
 
 func validateExternalConfigVersion(externalConfigVersion externalConfigVersion, id string) error {
@@ -142,3 +204,11 @@ func validateExternalConfigVersion(externalConfigVersion externalConfigVersion,
 		)
 	}
 }
+
+type getConfigOptions struct {
+	envContainer app.EnvContainer
+}
+
+func newGetConfigOptions() *getConfigOptions {
+	return &getConfigOptions{}
+}
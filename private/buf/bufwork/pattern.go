@@ -0,0 +1,185 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwork
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+)
+
+// expandDirectoryPatterns expands the directory patterns in the given directories
+// to the directories in the bucket that they match.
+//
+// Directories that are not patterns are returned as they are. The directories that
+// each pattern matches are sorted, and directories that are matched by more than one
+// pattern, or that are also listed explicitly, are only returned once. It is an error
+// for a pattern to not match any directories.
+//
+// If readBucket is nil, any pattern results in an error, as there is nothing to
+// expand the pattern against.
+func expandDirectoryPatterns(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	directories []string,
+	workspaceID string,
+) ([]string, error) {
+	if !containsDirectoryPattern(directories) {
+		return directories, nil
+	}
+	seen := make(map[string]struct{}, len(directories))
+	for _, directory := range directories {
+		if !isDirectoryPattern(directory) {
+			seen[normalpath.Normalize(directory)] = struct{}{}
+		}
+	}
+	expandedDirectories := make([]string, 0, len(directories))
+	for _, directory := range directories {
+		if !isDirectoryPattern(directory) {
+			expandedDirectories = append(expandedDirectories, directory)
+			continue
+		}
+		if readBucket == nil {
+			return nil, fmt.Errorf(`directory pattern "%s" listed in %s can only be used in a workspace file`, directory, workspaceID)
+		}
+		matches, err := matchDirectoryPattern(ctx, readBucket, directory)
+		if err != nil {
+			return nil, fmt.Errorf(`directory pattern "%s" listed in %s is invalid: %w`, directory, workspaceID, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf(`directory pattern "%s" listed in %s did not match any directories`, directory, workspaceID)
+		}
+		for _, match := range matches {
+			if _, ok := seen[match]; ok {
+				continue
+			}
+			seen[match] = struct{}{}
+			expandedDirectories = append(expandedDirectories, match)
+		}
+	}
+	return expandedDirectories, nil
+}
+
+// matchDirectoryPattern returns the sorted directories in the bucket that match
+// the given pattern.
+//
+// Each component of the pattern is matched against a component of the directory
+// with path.Match, except for "**", which matches zero or more components. Hidden
+// directories are only matched by components that start with ".".
+func matchDirectoryPattern(ctx context.Context, readBucket storage.ReadBucket, pattern string) ([]string, error) {
+	normalizedPattern, err := normalpath.NormalizeAndValidate(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternComponents := strings.Split(normalizedPattern, "/")
+	for _, patternComponent := range patternComponents {
+		// Validates the syntax of the component, which path.Match only
+		// reports if it gets far enough to see the error.
+		if _, err := path.Match(patternComponent, ""); err != nil {
+			return nil, err
+		}
+	}
+	// Only the directories under the components before the first pattern
+	// need to be walked.
+	var prefixComponents []string
+	for _, patternComponent := range patternComponents {
+		if isDirectoryPattern(patternComponent) {
+			break
+		}
+		prefixComponents = append(prefixComponents, patternComponent)
+	}
+	prefix := "."
+	if len(prefixComponents) > 0 {
+		prefix = normalpath.Join(prefixComponents...)
+	}
+	matchSet := make(map[string]struct{})
+	if err := readBucket.Walk(
+		ctx,
+		prefix,
+		func(objectInfo storage.ObjectInfo) error {
+			// Every parent directory of a file is a directory in the bucket.
+			for directory := normalpath.Dir(objectInfo.Path()); directory != "."; directory = normalpath.Dir(directory) {
+				if _, ok := matchSet[directory]; ok {
+					// The parents of this directory have already been matched.
+					break
+				}
+				if matchPatternComponents(patternComponents, strings.Split(directory, "/")) {
+					matchSet[directory] = struct{}{}
+				}
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	matches := make([]string, 0, len(matchSet))
+	for match := range matchSet {
+		matches = append(matches, match)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// matchPatternComponents returns true if the given directory components match
+// the given pattern components.
+func matchPatternComponents(patternComponents []string, components []string) bool {
+	if len(patternComponents) == 0 {
+		return len(components) == 0
+	}
+	if patternComponents[0] == "**" {
+		for i := 0; i <= len(components); i++ {
+			if i > 0 && strings.HasPrefix(components[i-1], ".") {
+				// "**" does not descend into hidden directories.
+				return false
+			}
+			if matchPatternComponents(patternComponents[1:], components[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(components) == 0 {
+		return false
+	}
+	if strings.HasPrefix(components[0], ".") && !strings.HasPrefix(patternComponents[0], ".") {
+		return false
+	}
+	// The syntax of the pattern components is validated beforehand.
+	matched, _ := path.Match(patternComponents[0], components[0])
+	if !matched {
+		return false
+	}
+	return matchPatternComponents(patternComponents[1:], components[1:])
+}
+
+func containsDirectoryPattern(directories []string) bool {
+	for _, directory := range directories {
+		if isDirectoryPattern(directory) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDirectoryPattern returns true if the given directory contains any of the
+// special characters of path.Match.
+func isDirectoryPattern(directory string) bool {
+	return strings.ContainsAny(directory, "*?[")
+}
@@ -23,18 +23,26 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
 )
 
 type workspaceBuilder struct {
-	moduleCache map[string]*cachedModule
+	profile         string
+	extendsResolver bufconfig.ExtendsResolver
+	envContainer    app.EnvContainer
+	moduleCache     map[string]*cachedModule
 }
 
-func newWorkspaceBuilder() *workspaceBuilder {
-	return &workspaceBuilder{
+func newWorkspaceBuilder(options ...WorkspaceBuilderOption) *workspaceBuilder {
+	workspaceBuilder := &workspaceBuilder{
 		moduleCache: make(map[string]*cachedModule),
 	}
+	for _, option := range options {
+		option(workspaceBuilder)
+	}
+	return workspaceBuilder
 }
 
 // BuildWorkspace builds a bufmodule.Workspace for the given targetSubDirPath.
@@ -52,10 +60,7 @@ func (w *workspaceBuilder) BuildWorkspace(
 	if workspaceConfig == nil {
 		return nil, errors.New("received a nil workspace config")
 	}
-	// We know that if the file is actually buf.work for legacy reasons, this will be wrong,
-	// but we accept that as this shouldn't happen often anymore and this is just
-	// used for error messages.
-	workspaceID := filepath.Join(normalpath.Unnormalize(relativeRootPath), ExternalConfigV1FilePath)
+	workspaceID := filepath.Join(normalpath.Unnormalize(relativeRootPath), workspaceConfigFilePath(workspaceConfig))
 	namedModules := make(map[string]bufmodule.Module, len(workspaceConfig.Directories))
 	allModules := make([]bufmodule.Module, 0, len(workspaceConfig.Directories))
 	for _, directory := range workspaceConfig.Directories {
@@ -74,7 +79,10 @@ func (w *workspaceBuilder) BuildWorkspace(
 			allModules = append(allModules, cachedModule.module)
 			continue
 		}
-		readBucketForDirectory := storage.MapReadBucket(readBucket, storage.MapOnPrefix(directory))
+		readBucketForDirectory, err := newReadBucketForDirectory(ctx, workspaceConfig, readBucket, directory, workspaceID)
+		if err != nil {
+			return nil, err
+		}
 		if err := validateWorkspaceDirectoryNonEmpty(ctx, readBucketForDirectory, directory, workspaceID); err != nil {
 			return nil, err
 		}
@@ -90,6 +98,10 @@ func (w *workspaceBuilder) BuildWorkspace(
 			ctx,
 			readBucketForDirectory,
 			bufconfig.ReadConfigOSWithOverride(localConfigOverride),
+			bufconfig.ReadConfigOSWithDefaults(workspaceConfig.ModuleConfigDefaults),
+			bufconfig.ReadConfigOSWithProfile(w.profile),
+			bufconfig.ReadConfigOSWithExtendsResolver(w.extendsResolver),
+			bufconfig.ReadConfigOSWithEnvContainer(w.envContainer),
 		)
 		if err != nil {
 			return nil, fmt.Errorf(
@@ -99,6 +111,14 @@ func (w *workspaceBuilder) BuildWorkspace(
 				err,
 			)
 		}
+		if moduleConfig.Version == bufconfig.V2Version {
+			return nil, fmt.Errorf(
+				`directory "%s" listed in %s has a %s configuration, which defines a workspace and cannot be in one`,
+				normalpath.Unnormalize(directory),
+				workspaceID,
+				bufconfig.V2Version,
+			)
+		}
 		externalToSubDirRelPaths, err := ExternalPathsToSubDirRelPaths(
 			relativeRootPath,
 			directory,
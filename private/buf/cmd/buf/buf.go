@@ -16,6 +16,7 @@ package buf
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
@@ -30,18 +31,49 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/registry/token/tokenlist"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/repo/reposync"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/workspace/workspacepush"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/convertschema"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/def"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/deprecations"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/docs/docsgenerate"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/docs/docsserve"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/examples/examplesextract"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/exportgraphql"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/exportjsonschema"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/exportopenapi"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/fuzzcorpus"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/generatedata"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/graph"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/image/imagediff"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/kafka/kafkaconsume"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/kafka/kafkadecode"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/lsp"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/migratev1beta1"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/migratev2"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/mock/mockserve"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/price"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/printconfig"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/reflect/reflectserve"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/commit/commitget"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/commit/commitlist"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/draft/draftdelete"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/draft/draftlist"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/label/labelcreate"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/label/labellist"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/label/labelmove"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/label/labelpoint"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/organization/organizationcreate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/organization/organizationdelete"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/organization/organizationget"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/organization/organizationmemberlist"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/organization/organizationmemberremove"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/organization/organizationmemberset"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/organization/organizationupdate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/plugin/plugindelete"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/plugin/pluginpush"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/registryserve"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/repository/repositorycontributorlist"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/repository/repositorycontributorremove"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/repository/repositorycontributorset"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/repository/repositorycreate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/repository/repositorydelete"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/repository/repositorydeprecate"
@@ -54,10 +86,20 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhookcreate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhookdelete"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhooklist"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/sanitize"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/sbom"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/stats"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/studioagent"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/symbols"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/synthesize"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/validate"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/why"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/breaking"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/build"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/cache/cachels"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/cache/cacheprune"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/config/configjsonschema"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/config/configvalidate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/convert"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/curl"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/export"
@@ -72,11 +114,13 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modopen"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modprune"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modupdate"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modverify"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/push"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/registrylogin"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/registrylogout"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/observabilityotlp"
 )
 
 // Main is the entrypoint to the buf CLI.
@@ -92,6 +136,56 @@ func NewRootCommand(name string) *appcmd.Command {
 		name,
 		appflag.BuilderWithTimeout(120*time.Second),
 		appflag.BuilderWithTracing(),
+		// The --profile flag selects the profile of the configuration files.
+		appflag.BuilderWithProfileFlagPrefix("pprof"),
+		appflag.BuilderWithEnvFlag(
+			bufcli.OfflineFlagName,
+			bufcli.OfflineEnvKey,
+			fmt.Sprintf(
+				"Fail instead of making network requests, such as to the Buf Schema Registry, for remote git and archive inputs, and by buf curl. Only the local module and plugin caches are used. Equivalent to setting %s=1",
+				bufcli.OfflineEnvKey,
+			),
+		),
+		appflag.BuilderWithEnvStringFlag(
+			bufcli.ProfileFlagName,
+			bufcli.ProfileEnvKey,
+			fmt.Sprintf(
+				"The profile of the configuration files to use, which overrides the lint and breaking configuration, and the generate configuration, with the profile of the same name. Equivalent to setting %s",
+				bufcli.ProfileEnvKey,
+			),
+		),
+		appflag.BuilderWithParallelismFlag(
+			bufcli.JobsFlagName,
+			bufcli.JobsEnvKey,
+			fmt.Sprintf(
+				"The maximum number of concurrent jobs, such as files to compile, modules to fetch, and plugins to run. Defaults to the number of CPUs. Overrides %s",
+				bufcli.JobsEnvKey,
+			),
+		),
+		appflag.BuilderWithEnvStringFlag(
+			bufcli.OTLPEndpointFlagName,
+			observabilityotlp.EndpointEnvKey,
+			fmt.Sprintf(
+				"The base URL of an OTLP/HTTP endpoint to export traces to, such as http://localhost:4318. Equivalent to setting %s",
+				observabilityotlp.EndpointEnvKey,
+			),
+		),
+		appflag.BuilderWithEnvStringFlag(
+			bufcli.OTLPHeadersFlagName,
+			observabilityotlp.HeadersEnvKey,
+			fmt.Sprintf(
+				"The headers to send to the OTLP endpoint, as comma-separated key=value pairs. Equivalent to setting %s",
+				observabilityotlp.HeadersEnvKey,
+			),
+		),
+		appflag.BuilderWithEnvStringFlag(
+			bufcli.TraceSampleRatioFlagName,
+			observabilityotlp.SamplerArgEnvKey,
+			fmt.Sprintf(
+				"The ratio of traces to sample, between 0 and 1. Defaults to sampling all traces. Equivalent to setting %s",
+				observabilityotlp.SamplerArgEnvKey,
+			),
+		),
 	)
 	return &appcmd.Command{
 		Use:                 name,
@@ -110,6 +204,22 @@ func NewRootCommand(name string) *appcmd.Command {
 			push.NewCommand("push", builder),
 			convert.NewCommand("convert", builder),
 			curl.NewCommand("curl", builder),
+			{
+				Use:   "config",
+				Short: "Work with configuration files",
+				SubCommands: []*appcmd.Command{
+					configvalidate.NewCommand("validate", builder),
+					configjsonschema.NewCommand("jsonschema", builder),
+				},
+			},
+			{
+				Use:   "cache",
+				Short: "Manage the Buf cache",
+				SubCommands: []*appcmd.Command{
+					cachels.NewCommand("ls", builder),
+					cacheprune.NewCommand("prune", builder),
+				},
+			},
 			{
 				Use:   "mod",
 				Short: "Manage Buf modules",
@@ -117,6 +227,7 @@ func NewRootCommand(name string) *appcmd.Command {
 					modinit.NewCommand("init", builder),
 					modprune.NewCommand("prune", builder),
 					modupdate.NewCommand("update", builder),
+					modverify.NewCommand("verify", builder),
 					modopen.NewCommand("open", builder),
 					modclearcache.NewCommand("clear-cache", builder, "cc"),
 					modlslintrules.NewCommand("ls-lint-rules", builder),
@@ -135,11 +246,72 @@ func NewRootCommand(name string) *appcmd.Command {
 				Use:   "beta",
 				Short: "Beta commands. Unstable and likely to change",
 				SubCommands: []*appcmd.Command{
+					convertschema.NewCommand("convert-schema", builder),
+					def.NewCommand("def", builder),
+					deprecations.NewCommand("deprecations", builder),
+					exportgraphql.NewCommand("export-graphql", builder),
+					exportjsonschema.NewCommand("export-jsonschema", builder),
+					exportopenapi.NewCommand("export-openapi", builder),
+					fuzzcorpus.NewCommand("fuzz-corpus", builder),
+					generatedata.NewCommand("generate-data", builder),
 					graph.NewCommand("graph", builder),
+					lsp.NewCommand("lsp", builder),
 					price.NewCommand("price", builder),
+					printconfig.NewCommand("print-config", builder),
+					sanitize.NewCommand("sanitize", builder),
 					stats.NewCommand("stats", builder),
+					sbom.NewCommand("sbom", builder),
 					migratev1beta1.NewCommand("migrate-v1beta1", builder),
+					migratev2.NewCommand("migrate-v2", builder),
 					studioagent.NewCommand("studio-agent", builder),
+					symbols.NewCommand("symbols", builder),
+					synthesize.NewCommand("synthesize", builder),
+					validate.NewCommand("validate", builder),
+					why.NewCommand("why", builder),
+					{
+						Use:   "docs",
+						Short: "Generate documentation from the comments of Protobuf files",
+						SubCommands: []*appcmd.Command{
+							docsgenerate.NewCommand("generate", builder),
+							docsserve.NewCommand("serve", builder),
+						},
+					},
+					{
+						Use:   "examples",
+						Short: "Work with examples declared in Protobuf files",
+						SubCommands: []*appcmd.Command{
+							examplesextract.NewCommand("extract", builder),
+						},
+					},
+					{
+						Use:   "image",
+						Short: "Work with images",
+						SubCommands: []*appcmd.Command{
+							imagediff.NewCommand("diff", builder),
+						},
+					},
+					{
+						Use:   "kafka",
+						Short: "Inspect the Protobuf records of Kafka topics",
+						SubCommands: []*appcmd.Command{
+							kafkaconsume.NewCommand("consume", builder),
+							kafkadecode.NewCommand("decode", builder),
+						},
+					},
+					{
+						Use:   "mock",
+						Short: "Mock the services of Protobuf files",
+						SubCommands: []*appcmd.Command{
+							mockserve.NewCommand("serve", builder),
+						},
+					},
+					{
+						Use:   "reflect",
+						Short: "Serve gRPC server reflection for Protobuf files",
+						SubCommands: []*appcmd.Command{
+							reflectserve.NewCommand("serve", builder),
+						},
+					},
 					{
 						Use:   "registry",
 						Short: "Manage assets on the Buf Schema Registry",
@@ -151,6 +323,16 @@ func NewRootCommand(name string) *appcmd.Command {
 									organizationcreate.NewCommand("create", builder),
 									organizationget.NewCommand("get", builder),
 									organizationdelete.NewCommand("delete", builder),
+									organizationupdate.NewCommand("update", builder),
+									{
+										Use:   "member",
+										Short: "Manage the members of an organization",
+										SubCommands: []*appcmd.Command{
+											organizationmemberlist.NewCommand("list", builder),
+											organizationmemberset.NewCommand("set", builder),
+											organizationmemberremove.NewCommand("remove", builder),
+										},
+									},
 								},
 							},
 							{
@@ -164,6 +346,15 @@ func NewRootCommand(name string) *appcmd.Command {
 									repositorydeprecate.NewCommand("deprecate", builder),
 									repositoryundeprecate.NewCommand("undeprecate", builder),
 									repositoryupdate.NewCommand("update", builder),
+									{
+										Use:   "contributor",
+										Short: "Manage the contributors of a repository",
+										SubCommands: []*appcmd.Command{
+											repositorycontributorlist.NewCommand("list", builder),
+											repositorycontributorset.NewCommand("set", builder),
+											repositorycontributorremove.NewCommand("remove", builder),
+										},
+									},
 								},
 							},
 							{
@@ -190,6 +381,16 @@ func NewRootCommand(name string) *appcmd.Command {
 									draftlist.NewCommand("list", builder),
 								},
 							},
+							{
+								Use:   "label",
+								Short: "Manage a repository's labels",
+								SubCommands: []*appcmd.Command{
+									labelcreate.NewCommand("create", builder),
+									labellist.NewCommand("list", builder),
+									labelmove.NewCommand("move", builder),
+									labelpoint.NewCommand("point", builder),
+								},
+							},
 							{
 								Use:   "webhook",
 								Short: "Manage webhooks for a repository on the Buf Schema Registry",
@@ -207,6 +408,7 @@ func NewRootCommand(name string) *appcmd.Command {
 									plugindelete.NewCommand("delete", builder),
 								},
 							},
+							registryserve.NewCommand("serve", builder),
 						},
 					},
 				},
@@ -17,12 +17,17 @@ package buf
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/internal/internaltesting"
@@ -30,6 +35,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
 	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/observabilityotlp"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/storage/storagetesting"
@@ -804,6 +810,51 @@ FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED          WIRE_JSON, WIRE
 	)
 }
 
+func TestBetaSBOM(t *testing.T) {
+	t.Parallel()
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"beta",
+		"sbom",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"cyclonedx",
+		"--template",
+		`{"version":"v1","plugins":[{"plugin":"buf.build/protocolbuffers/go:v1.30.0","out":"gen"},{"plugin":"go-grpc","out":"gen"}]}`,
+	)
+	var document struct {
+		BOMFormat  string `json:"bomFormat"`
+		Components []struct {
+			Type    string `json:"type"`
+			Group   string `json:"group"`
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &document))
+	assert.Equal(t, "CycloneDX", document.BOMFormat)
+	require.Len(t, document.Components, 1)
+	assert.Equal(t, "application", document.Components[0].Type)
+	assert.Equal(t, "buf.build/protocolbuffers", document.Components[0].Group)
+	assert.Equal(t, "go", document.Components[0].Name)
+	assert.Equal(t, "v1.30.0", document.Components[0].Version)
+	testRun(
+		t,
+		1,
+		nil,
+		nil,
+		"beta",
+		"sbom",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"json",
+	)
+}
+
 func TestLsFiles(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
@@ -881,6 +932,33 @@ func TestLsFilesIncludeImports(t *testing.T) {
 	)
 }
 
+func TestLsFilesJSON(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`{"path":"buf/buf.proto","external_path":"`+filepath.ToSlash(filepath.Join("testdata", "success", "buf", "buf.proto"))+`","package":"buf","imports":["google/protobuf/descriptor.proto"],"digest":"shake256:99e73c2fd61456dd5facc5f66c9e70c94c4ad6efd622aec6353b9d3b110103b87652673e8d3db2a8cc0b4352a6c748f20826a39573841ac3efe0150d13e20333"}`,
+		"ls-files",
+		"--format",
+		"json",
+		filepath.Join("testdata", "success"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`{"path":"buf/buf.proto","external_path":"`+filepath.ToSlash(filepath.Join("testdata", "success", "buf", "buf.proto"))+`","package":"buf","imports":["google/protobuf/descriptor.proto"],"digest":"shake256:99e73c2fd61456dd5facc5f66c9e70c94c4ad6efd622aec6353b9d3b110103b87652673e8d3db2a8cc0b4352a6c748f20826a39573841ac3efe0150d13e20333"}
+		{"path":"google/protobuf/descriptor.proto","external_path":"google/protobuf/descriptor.proto","package":"google.protobuf","import":true,"wkt":true,"digest":"shake256:9cbd6f13cffb878a52edd0c34b1869a54ee070c5605e608837bcb20f4ba239d986609a9d90b5fd1ee19fd085f61e18deb05c8dc25425c99978c14ae3b9daa19f"}`,
+		"ls-files",
+		"--format",
+		"json",
+		"--include-imports",
+		"--as-import-paths",
+		filepath.Join("testdata", "success"),
+	)
+}
+
 func TestLsFilesIncludeImportsAsImportPaths(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
@@ -1506,6 +1584,276 @@ testdata/paths/a/v3/foo/foo.proto:3:1:Package name "a.v3.foo" should be suffixed
 	)
 }
 
+func TestLintWithProfile(t *testing.T) {
+	t.Parallel()
+	testRunStdout(t, nil, 0, ``, "lint", filepath.Join("testdata", "profiles"))
+	testRunStdout(t, nil, 0, ``, "lint", filepath.Join("testdata", "profiles"), "--profile", "local")
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		bufcli.ExitCodeFileAnnotation,
+		filepath.FromSlash(`testdata/profiles/a/a.proto:3:1:Package name "a" should be suffixed with a correctly formed version, such as "a.v1".`),
+		"",
+		"lint",
+		filepath.Join("testdata", "profiles"),
+		"--profile",
+		"strict",
+	)
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		1,
+		"",
+		filepath.FromSlash(`Failure: testdata/profiles/buf.yaml: profile "ci" is not defined, the defined profiles are: local, strict`),
+		"lint",
+		filepath.Join("testdata", "profiles"),
+		"--profile",
+		"ci",
+	)
+}
+
+func TestLintWithExtendsNotPinned(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		1,
+		"",
+		`Failure: extends "buf.build/acme/style" is not pinned in buf.lock, run "buf mod update" to pin it`,
+		"lint",
+		filepath.Join("testdata", "extends"),
+	)
+}
+
+func TestLintWithEnvExpansion(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderrWithEnv(
+		t,
+		map[string]string{"IGNORE_DIR": "a"},
+		0,
+		"",
+		"",
+		"lint",
+		filepath.Join("testdata", "envexpand"),
+	)
+	testRunStdoutStderrWithEnv(
+		t,
+		map[string]string{"IGNORE_DIR": "b"},
+		bufcli.ExitCodeFileAnnotation,
+		filepath.FromSlash(`testdata/envexpand/a/a.proto:3:1:Package name "a" should be suffixed with a correctly formed version, such as "a.v1".`),
+		"",
+		"lint",
+		filepath.Join("testdata", "envexpand"),
+	)
+	testRunStdoutStderrWithEnv(
+		t,
+		nil,
+		1,
+		"",
+		filepath.FromSlash(`Failure: testdata/envexpand/buf.yaml: line 6: environment variable IGNORE_DIR is not set`),
+		"lint",
+		filepath.Join("testdata", "envexpand"),
+	)
+}
+
+func TestJobs(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderrWithEnv(
+		t,
+		nil,
+		bufcli.ExitCodeFileAnnotation,
+		filepath.FromSlash(`testdata/envexpand/a/a.proto:3:1:Package name "a" should be suffixed with a correctly formed version, such as "a.v1".`),
+		"",
+		"lint",
+		filepath.Join("testdata", "envexpand"),
+		"--config",
+		`{"version":"v1","lint":{"use":["PACKAGE_VERSION_SUFFIX"]}}`,
+		"--jobs",
+		"1",
+	)
+	testRunStdoutStderrWithEnv(
+		t,
+		map[string]string{bufcli.JobsEnvKey: "2"},
+		0,
+		"",
+		"",
+		"build",
+		filepath.Join("testdata", "success"),
+	)
+	testRunStdoutStderrWithEnv(
+		t,
+		map[string]string{bufcli.JobsEnvKey: "many"},
+		1,
+		"",
+		`BUF_JOBS must be a positive integer but was "many"`,
+		"build",
+		filepath.Join("testdata", "success"),
+	)
+	testRunStdoutStderrWithEnv(
+		t,
+		map[string]string{bufcli.JobsEnvKey: "many"},
+		0,
+		"",
+		"",
+		"build",
+		filepath.Join("testdata", "success"),
+		"--jobs",
+		"1",
+	)
+	testRunStdoutStderrWithEnv(
+		t,
+		nil,
+		1,
+		"",
+		`--jobs must be positive but was -1`,
+		"build",
+		filepath.Join("testdata", "success"),
+		"--jobs",
+		"-1",
+	)
+	testRunStdoutStderrWithEnv(
+		t,
+		nil,
+		1,
+		"",
+		`--jobs must be positive but was 0`,
+		"build",
+		filepath.Join("testdata", "success"),
+		"--jobs",
+		"0",
+	)
+}
+
+func TestCacheLsAndPrune(t *testing.T) {
+	t.Parallel()
+	cacheDirPath := t.TempDir()
+	now := time.Now()
+	writeCacheFile := func(relPath string, size int, lastUsed time.Time) {
+		path := filepath.Join(cacheDirPath, filepath.FromSlash(relPath))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0600))
+		require.NoError(t, os.Chtimes(path, lastUsed, lastUsed))
+	}
+	writeCacheFile("v2/module/buf.build/acme/old/blobs/abc", 100, now.Add(-60*24*time.Hour))
+	writeCacheFile("v2/module/buf.build/acme/new/blobs/def", 200, now.Add(-time.Hour))
+	writeCacheFile("curl-reflection/server", 300, now.Add(-2*time.Hour))
+	env := map[string]string{useEnvVar("test", "CACHE_DIR"): cacheDirPath}
+	stdout := bytes.NewBuffer(nil)
+	appcmdtesting.RunCommandExitCode(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		0,
+		func(use string) map[string]string { return env },
+		nil,
+		stdout,
+		io.Discard,
+		"cache",
+		"ls",
+		"--format",
+		"json",
+	)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"cache":"module"`)
+	assert.Contains(t, lines[0], `"size":200`)
+	assert.Contains(t, lines[2], `"cache":"curl-reflection"`)
+	stderr := bytes.NewBuffer(nil)
+	appcmdtesting.RunCommandExitCode(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		1,
+		func(use string) map[string]string { return env },
+		nil,
+		io.Discard,
+		stderr,
+		"cache",
+		"prune",
+	)
+	assert.Contains(t, stderr.String(), "at least one of --max-size and --max-age must be set")
+	testRunStdoutStderrWithEnv(
+		t,
+		env,
+		0,
+		"",
+		fmt.Sprintf(
+			"deleted %s\nremoved 1 entries, 100B",
+			filepath.Join(cacheDirPath, "v2", "module", "buf.build", "acme", "old"),
+		),
+		"cache",
+		"prune",
+		"--max-age",
+		"30d",
+	)
+	testRunStdoutStderrWithEnv(
+		t,
+		env,
+		0,
+		"",
+		fmt.Sprintf(
+			"deleted %s\nremoved 1 entries, 300B",
+			filepath.Join(cacheDirPath, "curl-reflection", "server"),
+		),
+		"cache",
+		"prune",
+		"--max-size",
+		"250B",
+	)
+	assert.DirExists(t, filepath.Join(cacheDirPath, "v2", "module", "buf.build", "acme", "new"))
+}
+
+func TestOTLPExporter(t *testing.T) {
+	t.Parallel()
+	var lock sync.Mutex
+	var bodies []string
+	var authorizations []string
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				data, err := io.ReadAll(request.Body)
+				assert.NoError(t, err)
+				lock.Lock()
+				defer lock.Unlock()
+				assert.Equal(t, "/v1/traces", request.URL.Path)
+				bodies = append(bodies, string(data))
+				authorizations = append(authorizations, request.Header.Get("Authorization"))
+			},
+		),
+	)
+	t.Cleanup(server.Close)
+	testRunStdoutStderrWithEnv(
+		t,
+		map[string]string{observabilityotlp.HeadersEnvKey: "Authorization=Bearer%20token"},
+		0,
+		"",
+		"",
+		"build",
+		filepath.Join("testdata", "success"),
+		"--otlp-endpoint",
+		server.URL,
+	)
+	lock.Lock()
+	defer lock.Unlock()
+	require.NotEmpty(t, bodies)
+	assert.Contains(t, strings.Join(bodies, "\n"), `"name":"command"`)
+	assert.Contains(t, strings.Join(bodies, "\n"), `{"key":"service.name","value":{"stringValue":"test"}}`)
+	for _, authorization := range authorizations {
+		assert.Equal(t, "Bearer token", authorization)
+	}
+	testRunStdoutStderrWithEnv(
+		t,
+		nil,
+		1,
+		"",
+		`OTEL_TRACES_SAMPLER_ARG must be a number between 0 and 1 but was "2"`,
+		"build",
+		filepath.Join("testdata", "success"),
+		"--otlp-endpoint",
+		server.URL,
+		"--trace-sample-ratio",
+		"2",
+	)
+}
+
 func TestBreakingWithPaths(t *testing.T) {
 	t.Parallel()
 	tempDir := t.TempDir()
@@ -1743,82 +2091,664 @@ Successfully migrated your buf.yaml and buf.gen.yaml to v1.`,
 	})
 }
 
-func TestConvertWithImage(t *testing.T) {
+func TestMigrateV2(t *testing.T) {
 	t.Parallel()
-	tempDir := t.TempDir()
-	testRunStdout(
-		t,
-		nil,
-		0,
-		``,
-		"build",
-		filepath.Join("testdata", "success"),
-		"-o",
-		filepath.Join(tempDir, "image.binpb"),
-	)
-
-	t.Run("stdin input", func(t *testing.T) {
+	storageosProvider := storageos.NewProvider()
+	runner := command.NewRunner()
+	t.Run("workspace", func(t *testing.T) {
 		t.Parallel()
-		stdin, err := os.Open(filepath.Join(convertTestDataDir, "descriptor.plain.binpb"))
-		require.NoError(t, err)
-		defer stdin.Close()
-		stdout := bytes.NewBuffer(nil)
-		testRun(
+		testMigrateV2Diff(
 			t,
-			0,
-			stdin,
-			stdout,
-			"convert",
-			filepath.Join(tempDir, "image.binpb"),
-			"--type",
-			"buf.Foo",
+			storageosProvider,
+			runner,
+			"workspace",
+			"Successfully migrated your buf.work.yaml and the configuration of its 2 modules to a v2 buf.yaml.",
 		)
-		assert.JSONEq(t, `{"one":"55"}`, stdout.String())
 	})
-
-	t.Run("no stdin input", func(t *testing.T) {
+	t.Run("fails-on-conflicting-deps", func(t *testing.T) {
 		t.Parallel()
-		testRunStdoutStderrNoWarn(
+		testMigrateV2Failure(
 			t,
-			nil,
-			1,
-			"",
-			"Failure: size of input message must not be zero",
-			"convert",
-			filepath.Join(tempDir, "image.binpb"),
-			"--type",
-			"buf.Foo",
+			storageosProvider,
+			"conflicting-deps",
+			`modules depend on both "buf.build/acme/c:v1" and "buf.build/acme/c:v2", but the deps of a v2 buf.yaml are shared by all modules`,
 		)
 	})
 }
 
-func TestConvertOutput(t *testing.T) {
+func TestConfigValidate(t *testing.T) {
 	t.Parallel()
-	tempDir := t.TempDir()
 	testRunStdout(
 		t,
 		nil,
 		0,
-		``,
-		"build",
+		"",
+		"config",
+		"validate",
+		filepath.Join("testdata", "config-validate", "success"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		bufcli.ExitCodeFileAnnotation,
+		filepath.FromSlash(`testdata/config-validate/failure/buf.yaml: is not used, as the directory is a workspace defined by buf.work.yaml that does not list it
+		testdata/config-validate/failure/a/buf.yaml: has deps, but there is no a/buf.lock, run buf mod update to create it
+		testdata/config-validate/failure/b/buf.yaml: could not unmarshal as YAML: yaml: unmarshal errors:
+		  line 4: field usee not found in type buflintconfig.ExternalConfigV1
+		testdata/config-validate/failure/buf.work.yaml: directories "a" and "c" are both modules named buf.build/acme/a
+		testdata/config-validate/failure/buf.gen.yaml: could not unmarshal as YAML: yaml: unmarshal errors:
+		  line 4: field outt not found in type bufgen.ExternalPluginConfigV1`),
+		"config",
+		"validate",
+		filepath.Join("testdata", "config-validate", "failure"),
+	)
+}
+
+func TestConfigJSONSchema(t *testing.T) {
+	t.Parallel()
+	for _, fileName := range []string{"buf.yaml", "buf.work.yaml", "buf.gen.yaml"} {
+		stdout := bytes.NewBuffer(nil)
+		testRun(t, 0, nil, stdout, "config", "jsonschema", fileName)
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &schema))
+		assert.Equal(t, fileName, schema["title"])
+	}
+	testRun(t, 1, nil, bytes.NewBuffer(nil), "config", "jsonschema", "buf.lock")
+}
+
+func TestDocsGenerate(t *testing.T) {
+	t.Parallel()
+	outputDirPath := t.TempDir()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		"",
+		"beta",
+		"docs",
+		"generate",
 		filepath.Join("testdata", "success"),
-		"-o",
-		filepath.Join(tempDir, "image.binpb"),
+		"--output",
+		outputDirPath,
 	)
-	t.Run("json file output", func(t *testing.T) {
-		t.Parallel()
-		stdin, err := os.Open(filepath.Join(convertTestDataDir, "descriptor.plain.binpb"))
-		require.NoError(t, err)
-		defer stdin.Close()
-		outputTempDir := t.TempDir()
-		testRunStdout(
-			t,
-			stdin,
-			0,
-			``,
-			"convert",
-			filepath.Join(tempDir, "image.binpb"),
-			"--type",
+	index, err := os.ReadFile(filepath.Join(outputDirPath, "index.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "| [buf](buf.md) |")
+	pkg, err := os.ReadFile(filepath.Join(outputDirPath, "buf.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(pkg), "| `two` | `google.protobuf.DescriptorProto` | 2 |  |")
+}
+
+func TestSymbols(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		filepath.FromSlash(`testdata/success/buf/buf.proto:7:9 message buf.Foo
+		testdata/success/buf/buf.proto:8:9 field buf.Foo.one
+		testdata/success/buf/buf.proto:9:35 field buf.Foo.two`),
+		"beta",
+		"symbols",
+		"buf.**",
+		filepath.Join("testdata", "success"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`google/protobuf/descriptor.proto:125:19 field google.protobuf.DescriptorProto.name`,
+		"beta",
+		"symbols",
+		"--regex",
+		`^google\.protobuf\.DescriptorProto\.n.me$`,
+		filepath.Join("testdata", "success"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`{"full_name":"buf.Foo","kind":"message","path":"buf/buf.proto","external_path":"`+filepath.ToSlash(filepath.Join("testdata", "success", "buf", "buf.proto"))+`","line":7,"column":9}`,
+		"beta",
+		"symbols",
+		"*.Foo",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"json",
+	)
+}
+
+func TestDef(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		filepath.FromSlash(`testdata/success/buf/buf.proto:8:9 field buf.Foo.one
+		  int64 one = 1;
+		type: scalar int64`),
+		"beta",
+		"def",
+		"buf.Foo.one",
+		filepath.Join("testdata", "success"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`{"full_name":"buf.Foo","kind":"message","path":"`+filepath.ToSlash(filepath.Join("testdata", "success", "buf", "buf.proto"))+`","line":7,"column":9,"declaration":"message buf.Foo"}`,
+		"beta",
+		"def",
+		".buf.Foo",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"json",
+	)
+	testRunStdout(
+		t,
+		nil,
+		1,
+		``,
+		"beta",
+		"def",
+		"buf.Bar",
+		filepath.Join("testdata", "success"),
+	)
+}
+
+func TestExportOpenAPI(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	require.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(tempDir, "order.proto"),
+			[]byte(`syntax = "proto3";
+
+package acme.v1;
+
+// Manages orders.
+service OrderService {
+  rpc GetOrder(GetOrderRequest) returns (Order);
+}
+
+message GetOrderRequest {
+  string name = 1;
+}
+
+// An order.
+message Order {
+  string name = 1;
+}
+`),
+			0600,
+		),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`openapi: 3.0.3
+info:
+  title: acme.v1.OrderService
+  description: Manages orders.
+  version: 0.0.1
+tags:
+  - name: acme.v1.OrderService
+    description: Manages orders.
+paths:
+  /acme.v1.OrderService/GetOrder:
+    post:
+      tags:
+        - acme.v1.OrderService
+      operationId: acme.v1.OrderService.GetOrder
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/acme.v1.GetOrderRequest'
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/acme.v1.Order'
+components:
+  schemas:
+    acme.v1.GetOrderRequest:
+      type: object
+      properties:
+        name:
+          type: string
+    acme.v1.Order:
+      type: object
+      description: An order.
+      properties:
+        name:
+          type: string`,
+		"beta",
+		"export-openapi",
+		tempDir,
+	)
+	outputDirPath := filepath.Join(tempDir, "openapi")
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"beta",
+		"export-openapi",
+		tempDir,
+		"--per-service",
+		"--format",
+		"json",
+		"--output",
+		outputDirPath,
+	)
+	assert.FileExists(t, filepath.Join(outputDirPath, "acme.v1.OrderService.openapi.json"))
+	testRunStdout(
+		t,
+		nil,
+		1,
+		``,
+		"beta",
+		"export-openapi",
+		tempDir,
+		"--service",
+		"acme.v1.Unknown",
+	)
+}
+
+func TestConvertSchemaAvro(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	require.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(tempDir, "order.proto"),
+			[]byte(`syntax = "proto3";
+
+package acme.v1;
+
+message Order {
+  string name = 1;
+  optional int64 quantity = 2;
+}
+`),
+			0600,
+		),
+	)
+	avscPath := filepath.Join(tempDir, "order.avsc")
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"beta",
+		"convert-schema",
+		tempDir,
+		"--to",
+		"avro",
+		"--type",
+		"acme.v1.Order",
+		"--output",
+		avscPath,
+	)
+	data, err := os.ReadFile(avscPath)
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{
+  "type": "record",
+  "name": "Order",
+  "namespace": "acme.v1",
+  "fields": [
+    {"name": "name", "type": "string", "default": ""},
+    {"name": "quantity", "type": ["null", "long"], "default": null}
+  ]
+}`,
+		string(data),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`syntax = "proto3";
+
+package acme.v2;
+
+message Order {
+  string name = 1;
+  optional int64 quantity = 2;
+}`,
+		"beta",
+		"convert-schema",
+		avscPath,
+		"--to",
+		"proto",
+		"--package",
+		"acme.v2",
+	)
+	stderr := bytes.NewBuffer(nil)
+	appcmdtesting.RunCommandExitCode(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		1,
+		nil,
+		nil,
+		nil,
+		stderr,
+		"beta",
+		"convert-schema",
+		tempDir,
+		"--to",
+		"avro",
+	)
+	assert.Contains(t, stderr.String(), "--type is required if --to is avro")
+}
+
+func TestKafkaDecode(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	require.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(tempDir, "order.proto"),
+			[]byte(`syntax = "proto3";
+
+package acme.v1;
+
+message Order {
+  string name = 1;
+}
+`),
+			0600,
+		),
+	)
+	// The schema registry header with schema ID 7, followed by name "abc".
+	value := []byte{0, 0, 0, 0, 7, 0, 0x0a, 0x03, 'a', 'b', 'c'}
+	testRunStdout(
+		t,
+		bytes.NewReader(value),
+		0,
+		`{"name":"abc"}`,
+		"beta",
+		"kafka",
+		"decode",
+		tempDir,
+		"--type",
+		"acme.v1.Order",
+		"--schema-registry-framing",
+	)
+	testRunStdout(
+		t,
+		bytes.NewReader(append([]byte{byte(len(value) - 6)}, value[6:]...)),
+		0,
+		`{"name":"abc"}`,
+		"beta",
+		"kafka",
+		"decode",
+		tempDir,
+		"--type",
+		"acme.v1.Order",
+		"--framing",
+		"varint",
+	)
+	stderr := bytes.NewBuffer(nil)
+	appcmdtesting.RunCommandExitCode(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		1,
+		nil,
+		bytes.NewReader(value[6:]),
+		nil,
+		stderr,
+		"beta",
+		"kafka",
+		"decode",
+		tempDir,
+		"--type",
+		"acme.v1.Order",
+		"--schema-registry-framing",
+	)
+	assert.Contains(t, stderr.String(), "the value does not start with the magic byte of a schema registry header, found 0xa")
+}
+
+func TestExportGraphQL(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	require.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(tempDir, "order.proto"),
+			[]byte(`syntax = "proto3";
+
+package acme.v1;
+
+service OrderService {
+  rpc GetOrder(GetOrderRequest) returns (Order);
+}
+
+message GetOrderRequest {
+  string name = 1;
+}
+
+message Order {
+  string name = 1;
+  int64 quantity = 2;
+}
+`),
+			0600,
+		),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`scalar Long
+
+type Query {
+  getOrder(input: GetOrderRequestInput!): Order
+}
+
+type Order {
+  name: String!
+  quantity: Long!
+}
+
+input GetOrderRequestInput {
+  name: String
+}`,
+		"beta",
+		"export-graphql",
+		tempDir,
+		"--scalar",
+		"int64=Long",
+	)
+}
+
+func TestExportJSONSchema(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	require.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(tempDir, "events.proto"),
+			[]byte(`syntax = "proto3";
+
+package acme.events.v1;
+
+message OrderCreated {
+  string id = 1;
+}
+
+message OrderCanceled {
+  string id = 1;
+}
+`),
+			0600,
+		),
+	)
+	outputDirPath := filepath.Join(tempDir, "schemas")
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"beta",
+		"export-jsonschema",
+		tempDir,
+		"--type",
+		"acme.events.v1.OrderCreated",
+		"--require-implicit-presence",
+		"-o",
+		outputDirPath,
+	)
+	data, err := os.ReadFile(filepath.Join(outputDirPath, "acme.events.v1.OrderCreated.schema.json"))
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "acme.events.v1.OrderCreated.schema.json",
+  "title": "acme.events.v1.OrderCreated",
+  "type": "object",
+  "properties": {
+    "id": {
+      "type": "string"
+    }
+  },
+  "additionalProperties": false,
+  "required": ["id"]
+}`,
+		string(data),
+	)
+	assert.NoFileExists(t, filepath.Join(outputDirPath, "acme.events.v1.OrderCanceled.schema.json"))
+	testRunStdout(
+		t,
+		nil,
+		1,
+		``,
+		"beta",
+		"export-jsonschema",
+		tempDir,
+		"--type",
+		"acme.events.v1.Unknown",
+		"-o",
+		outputDirPath,
+	)
+}
+
+func TestDeprecations(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		filepath.FromSlash(`testdata/deprecations/acme/v1/order.proto:22:9 message acme.v1.LegacyOrder
+		  testdata/deprecations/acme/v1/order.proto:7:48 method acme.v1.OrderService.GetLegacyOrder
+		  testdata/deprecations/acme/v1/order.proto:19:3 field acme.v1.Order.legacy
+		testdata/deprecations/acme/v1/order.proto:18:10 field acme.v1.Order.notes
+		testdata/deprecations/acme/v1/order.proto:7:7 method acme.v1.OrderService.GetLegacyOrder`),
+		"beta",
+		"deprecations",
+		filepath.Join("testdata", "deprecations"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		filepath.FromSlash(`kind,full_name,path,line,column,module,referrer_kind,referrer_full_name,referrer_path,referrer_line,referrer_column
+		message,acme.v1.LegacyOrder,testdata/deprecations/acme/v1/order.proto,22,9,,method,acme.v1.OrderService.GetLegacyOrder,testdata/deprecations/acme/v1/order.proto,7,48
+		message,acme.v1.LegacyOrder,testdata/deprecations/acme/v1/order.proto,22,9,,field,acme.v1.Order.legacy,testdata/deprecations/acme/v1/order.proto,19,3
+		field,acme.v1.Order.notes,testdata/deprecations/acme/v1/order.proto,18,10,,,,,,
+		method,acme.v1.OrderService.GetLegacyOrder,testdata/deprecations/acme/v1/order.proto,7,7,,,,,,`),
+		"beta",
+		"deprecations",
+		filepath.Join("testdata", "deprecations"),
+		"--format",
+		"csv",
+	)
+}
+
+func TestConvertWithImage(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"build",
+		filepath.Join("testdata", "success"),
+		"-o",
+		filepath.Join(tempDir, "image.binpb"),
+	)
+
+	t.Run("stdin input", func(t *testing.T) {
+		t.Parallel()
+		stdin, err := os.Open(filepath.Join(convertTestDataDir, "descriptor.plain.binpb"))
+		require.NoError(t, err)
+		defer stdin.Close()
+		stdout := bytes.NewBuffer(nil)
+		testRun(
+			t,
+			0,
+			stdin,
+			stdout,
+			"convert",
+			filepath.Join(tempDir, "image.binpb"),
+			"--type",
+			"buf.Foo",
+		)
+		assert.JSONEq(t, `{"one":"55"}`, stdout.String())
+	})
+
+	t.Run("no stdin input", func(t *testing.T) {
+		t.Parallel()
+		testRunStdoutStderrNoWarn(
+			t,
+			nil,
+			1,
+			"",
+			"Failure: size of input message must not be zero",
+			"convert",
+			filepath.Join(tempDir, "image.binpb"),
+			"--type",
+			"buf.Foo",
+		)
+	})
+}
+
+func TestConvertOutput(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"build",
+		filepath.Join("testdata", "success"),
+		"-o",
+		filepath.Join(tempDir, "image.binpb"),
+	)
+	t.Run("json file output", func(t *testing.T) {
+		t.Parallel()
+		stdin, err := os.Open(filepath.Join(convertTestDataDir, "descriptor.plain.binpb"))
+		require.NoError(t, err)
+		defer stdin.Close()
+		outputTempDir := t.TempDir()
+		testRunStdout(
+			t,
+			stdin,
+			0,
+			``,
+			"convert",
+			filepath.Join(tempDir, "image.binpb"),
+			"--type",
 			"buf.Foo",
 			"--to",
 			filepath.Join(outputTempDir, "result.json"),
@@ -2127,7 +3057,299 @@ func TestConvert(t *testing.T) {
 	})
 }
 
-func TestFormat(t *testing.T) {
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+syntax = "proto3";
+
+package simple;
+
+message Object {
+  string key = 1;
+  bytes value = 2;
+}
+		`,
+		"format",
+		filepath.Join("testdata", "format", "simple"),
+	)
+}
+
+func TestFormatWithConfig(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+syntax = "proto3";
+
+package options;
+
+message Object {
+    string key = 1 [
+        json_name = "objectKey"
+    ];
+    bytes value = 2;
+}
+		`,
+		"format",
+		filepath.Join("testdata", "format", "options"),
+	)
+}
+
+func TestFormatWithFlagsOverridingConfig(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+syntax = "proto3";
+
+package options;
+
+message Object {
+	string key = 1 [json_name = "objectKey"];
+	bytes value = 2;
+}
+		`,
+		"format",
+		filepath.Join("testdata", "format", "options"),
+		"--use-tabs",
+		"--max-line-width",
+		"0",
+	)
+}
+
+func TestFormatGroupImports(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+syntax = "proto3";
+
+package groupimports;
+
+import "google/protobuf/timestamp.proto";
+
+import "acme/weather/v1/weather.proto";
+
+import "b.proto";
+syntax = "proto3";
+
+package groupimports;
+		`,
+		"format",
+		filepath.Join("testdata", "format", "groupimports"),
+		"--group-imports",
+	)
+}
+
+func TestFormatAlignFields(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+syntax = "proto3";
+
+package simple;
+
+message Object {
+  string key  = 1;
+  bytes value = 2;
+}
+		`,
+		"format",
+		filepath.Join("testdata", "format", "simple"),
+		"--align-fields",
+	)
+}
+
+func TestFormatRange(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderrNoWarn(
+		t,
+		strings.NewReader(`syntax = "proto3";
+
+package simple;
+
+message Object {
+    string   key=1;
+  bytes value = 2;
+}
+`),
+		0,
+		`  string key = 1;`,
+		`6:6`,
+		"format",
+		"-",
+		"--range",
+		"6:6",
+	)
+}
+
+func TestFormatInvalidRange(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		1,
+		"",
+		`Failure: --range can only be used with the source -`,
+		"format",
+		filepath.Join("testdata", "format", "simple"),
+		"--range",
+		"1:2",
+	)
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		1,
+		"",
+		`Failure: --range must be in the form start:end, but was "1"`,
+		"format",
+		"-",
+		"--range",
+		"1",
+	)
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		1,
+		"",
+		`Failure: --write cannot be used with --range`,
+		"format",
+		"-",
+		"--range",
+		"1:2",
+		"-w",
+	)
+}
+
+func TestBuildOffline(t *testing.T) {
+	t.Parallel()
+	const offlineMessage = "network access is disabled with --offline or BUF_OFFLINE=1. Only the local module and plugin caches can be used, so run the command without it to populate the caches"
+	testRunStdoutStderrWithEnv(
+		t,
+		map[string]string{"HOME": t.TempDir()},
+		1,
+		"",
+		"Failure: could not clone https://github.com/bufbuild/buf.git: "+offlineMessage,
+		"build",
+		"https://github.com/bufbuild/buf.git",
+		"--offline",
+	)
+	testRunStdoutStderrWithEnv(
+		t,
+		map[string]string{"HOME": t.TempDir()},
+		1,
+		"",
+		`Failure: Get "https://example.com/foo.tar.gz": `+offlineMessage,
+		"build",
+		"https://example.com/foo.tar.gz",
+		"--offline",
+	)
+}
+
+func TestFormatEditorConfig(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		"syntax = \"proto3\";\r\n\r\npackage simple;\r\n\r\nmessage Object {\r\n    string key = 1;\r\n    bytes value = 2;\r\n}",
+		"format",
+		filepath.Join("testdata", "format", "editorconfig"),
+		"--editorconfig",
+	)
+	// The flags take precedence over the .editorconfig file.
+	testRunStdout(
+		t,
+		nil,
+		0,
+		"syntax = \"proto3\";\r\n\r\npackage simple;\r\n\r\nmessage Object {\r\n  string key = 1;\r\n  bytes value = 2;\r\n}",
+		"format",
+		filepath.Join("testdata", "format", "editorconfig"),
+		"--editorconfig",
+		"--indent",
+		"2",
+	)
+	// --use-tabs=false takes precedence over indent_style = tab.
+	testRunStdout(
+		t,
+		nil,
+		0,
+		"syntax = \"proto3\";\n\npackage simple;\n\nmessage Object {\n\tstring key = 1;\n\tbytes value = 2;\n}",
+		"format",
+		filepath.Join("testdata", "format", "editorconfigtabs"),
+		"--editorconfig",
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		"syntax = \"proto3\";\n\npackage simple;\n\nmessage Object {\n  string key = 1;\n  bytes value = 2;\n}",
+		"format",
+		filepath.Join("testdata", "format", "editorconfigtabs"),
+		"--editorconfig",
+		"--use-tabs=false",
+	)
+	// The .editorconfig file is only applied with --editorconfig.
+	testRunStdout(
+		t,
+		nil,
+		0,
+		"syntax = \"proto3\";\n\npackage simple;\n\nmessage Object {\n  string key = 1;\n  bytes value = 2;\n}",
+		"format",
+		filepath.Join("testdata", "format", "editorconfig"),
+	)
+}
+
+func TestFormatDiffJSON(t *testing.T) {
+	t.Parallel()
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"format",
+		filepath.Join("testdata", "format", "diff"),
+		"-d",
+		"--error-format",
+		"json",
+	)
+	var jsonDiff struct {
+		Path    string `json:"path"`
+		Differs bool   `json:"differs"`
+		Diff    string `json:"diff"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &jsonDiff))
+	assert.Equal(t, filepath.Join("testdata", "format", "diff", "diff.proto"), jsonDiff.Path)
+	assert.True(t, jsonDiff.Differs)
+	assert.Contains(t, jsonDiff.Diff, "@@ -1,13 +1,7 @@")
+	testRunStdout(
+		t,
+		nil,
+		0,
+		fmt.Sprintf(`{"path":%q,"differs":false}`, filepath.Join("testdata", "format", "simple", "simple.proto")),
+		"format",
+		filepath.Join("testdata", "format", "simple"),
+		"-d",
+		"--error-format",
+		"json",
+	)
+}
+
+func TestFormatCommentRules(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
@@ -2136,15 +3358,61 @@ func TestFormat(t *testing.T) {
 		`
 syntax = "proto3";
 
-package simple;
+package comments;
 
+// Object is an object.
 message Object {
-  string key = 1;
-  bytes value = 2;
+  //buf:lint:ignore FIELD_LOWER_SNAKE_CASE
+  string Key = 1; // The key.
+}
+		`,
+		"format",
+		filepath.Join("testdata", "format", "comments"),
+		"--line-doc-comments",
+		"--comment-space",
+	)
+}
+
+func TestFormatCanonicalOrder(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+syntax = "proto3";
+
+package order;
+
+message Object {
+  Status status = 1;
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OK = 1;
+  STATUS_FAILED = 2;
 }
 		`,
 		"format",
+		filepath.Join("testdata", "format", "order"),
+		"--canonical-order",
+		"--sort-enum-values",
+	)
+}
+
+func TestFormatInvalidIndent(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		1,
+		"",
+		`Failure: --indent must be positive`,
+		"format",
 		filepath.Join("testdata", "format", "simple"),
+		"--indent",
+		"0",
 	)
 }
 
@@ -2172,6 +3440,58 @@ func TestFormatSingleFile(t *testing.T) {
 	)
 }
 
+func TestFormatArchiveToOutputDir(t *testing.T) {
+	t.Parallel()
+	zipDir := createZipFromDir(
+		t,
+		filepath.Join("testdata", "format"),
+		"archive.zip",
+	)
+	tempDir := t.TempDir()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"format",
+		filepath.Join(zipDir, "archive.zip#subdir=diff"),
+		"-o",
+		filepath.Join(tempDir, "formatted"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"format",
+		filepath.Join(tempDir, "formatted"),
+		"--exit-code",
+		"-d",
+	)
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		1,
+		"",
+		`Failure: --write cannot be used with archive or git inputs`,
+		"format",
+		filepath.Join(zipDir, "archive.zip#subdir=diff"),
+		"-w",
+	)
+	// Only the value of the flags is checked, not whether they are set.
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"format",
+		filepath.Join(zipDir, "archive.zip#subdir=diff"),
+		"-w=false",
+		"-o",
+		filepath.Join(tempDir, "formatted-again"),
+	)
+}
+
 func TestFormatDiff(t *testing.T) {
 	t.Parallel()
 	tempDir := t.TempDir()
@@ -2297,6 +3617,21 @@ func TestFormatInvalidFlagCombination(t *testing.T) {
 	)
 }
 
+func TestFormatInvalidAgainstGitRefWithoutOnlyChanged(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		1,
+		"",
+		`Failure: --against-git-ref can only be used with --only-changed`,
+		"format",
+		filepath.Join("testdata", "format", "simple"),
+		"--against-git-ref",
+		"main",
+	)
+}
+
 func TestFormatInvalidWriteWithModuleReference(t *testing.T) {
 	t.Parallel()
 	testRunStdoutStderrNoWarn(
@@ -2515,6 +3850,55 @@ func testMigrateV1Beta1Failure(t *testing.T, storageosProvider storageos.Provide
 	)
 }
 
+func testMigrateV2Diff(
+	t *testing.T,
+	storageosProvider storageos.Provider,
+	runner command.Runner,
+	scenario string,
+	expectedStderr string,
+) {
+	// Copy test setup to temporary directory to avoid writing to filesystem
+	inputBucket, err := storageosProvider.NewReadWriteBucket(filepath.Join("testdata", "migrate-v2", "success", scenario, "input"))
+	require.NoError(t, err)
+	tempDir, readWriteBucket := internaltesting.CopyReadBucketToTempDir(context.Background(), t, storageosProvider, inputBucket)
+
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		0,
+		"",
+		expectedStderr,
+		"beta",
+		"migrate-v2",
+		tempDir,
+	)
+
+	expectedOutputBucket, err := storageosProvider.NewReadWriteBucket(filepath.Join("testdata", "migrate-v2", "success", scenario, "output"))
+	require.NoError(t, err)
+
+	diff, err := storage.DiffBytes(context.Background(), runner, expectedOutputBucket, readWriteBucket)
+	require.NoError(t, err)
+	require.Empty(t, string(diff))
+}
+
+func testMigrateV2Failure(t *testing.T, storageosProvider storageos.Provider, scenario string, expectedStderr string) {
+	// Copy test setup to temporary directory to avoid writing to filesystem
+	inputBucket, err := storageosProvider.NewReadWriteBucket(filepath.Join("testdata", "migrate-v2", "failure", scenario))
+	require.NoError(t, err)
+	tempDir, _ := internaltesting.CopyReadBucketToTempDir(context.Background(), t, storageosProvider, inputBucket)
+
+	testRunStdoutStderrNoWarn(
+		t,
+		nil,
+		1,
+		"",
+		expectedStderr,
+		"beta",
+		"migrate-v2",
+		tempDir,
+	)
+}
+
 func testModInit(t *testing.T, expectedData string, document bool, name string, deps ...string) {
 	tempDir := t.TempDir()
 	baseArgs := []string{"mod", "init"}
@@ -2572,6 +3956,36 @@ func testRunStdoutStderrNoWarn(t *testing.T, stdin io.Reader, expectedExitCode i
 	)
 }
 
+func testRunStdoutStderrWithEnv(
+	t *testing.T,
+	env map[string]string,
+	expectedExitCode int,
+	expectedStdout string,
+	expectedStderr string,
+	args ...string,
+) {
+	newEnv := internaltesting.NewEnvFunc(t)
+	appcmdtesting.RunCommandExitCodeStdoutStderr(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		expectedExitCode,
+		expectedStdout,
+		expectedStderr,
+		func(use string) map[string]string {
+			useEnv := newEnv(use)
+			for key, value := range env {
+				useEnv[key] = value
+			}
+			return useEnv
+		},
+		nil,
+		append(
+			args,
+			"--no-warn",
+		)...,
+	)
+}
+
 func testRunStdoutProfile(t *testing.T, stdin io.Reader, expectedExitCode int, expectedStdout string, args ...string) {
 	tempDirPath := t.TempDir()
 	testRunStdout(
@@ -2581,10 +3995,10 @@ func testRunStdoutProfile(t *testing.T, stdin io.Reader, expectedExitCode int, e
 		``,
 		append(
 			args,
-			"--profile",
-			fmt.Sprintf("--profile-path=%s", tempDirPath),
-			"--profile-loops=1",
-			"--profile-type=cpu",
+			"--pprof",
+			fmt.Sprintf("--pprof-path=%s", tempDirPath),
+			"--pprof-loops=1",
+			"--pprof-type=cpu",
 		)...,
 	)
 }
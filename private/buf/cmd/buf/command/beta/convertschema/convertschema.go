@@ -0,0 +1,254 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convertschema
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufavro"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	outputFlagName          = "output"
+	outputFlagShortName     = "o"
+	toFlagName              = "to"
+	typeFlagName            = "type"
+	packageFlagName         = "package"
+
+	toAvro  = "avro"
+	toProto = "proto"
+)
+
+var allTosString = stringutil.SliceToString([]string{toAvro, toProto})
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Convert between Protobuf messages and Avro schemas",
+		Long: `With --to avro, the message given by --type is converted to an Avro schema, and the
+input is a source, module, or image. With --to proto, the input is the path to an Avro
+schema of a record, or - for stdin, and it is converted to a Protobuf file.
+
+Messages are records, enums are enums, repeated fields are arrays, and map fields are
+maps. Fields with explicit presence, such as message fields, optional fields, and the
+fields of oneofs, are unions of null and their type. google.protobuf.Timestamp is a
+long with the logical type timestamp-micros, and wrappers are unions of null and their
+wrapped type.
+
+In the other direction, unions of null and one other type are optional fields, and
+unions of more than one other type are oneofs. Fields are numbered in the order that
+they are declared. Arrays and maps of arrays, maps, and unions cannot be converted.
+The logical types timestamp-millis and timestamp-micros are google.protobuf.Timestamp,
+and other logical types are their underlying types.
+
+The schema is written to stdout, or to the file given by --output.
+
+` + bufcli.GetInputLong(`the source, module, or image to convert a message of, if --to is avro`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Output          string
+	To              string
+	Type            string
+	Package         string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"-",
+		`The file to write the schema to`,
+	)
+	flagSet.StringVar(
+		&f.To,
+		toFlagName,
+		"",
+		fmt.Sprintf(
+			`Required. The kind of schema to convert to. Must be one of %s`,
+			allTosString,
+		),
+	)
+	flagSet.StringVar(
+		&f.Type,
+		typeFlagName,
+		"",
+		fmt.Sprintf(
+			`The fully-qualified name of the message to convert, such as acme.v1.Order. Required if --%s is %s`,
+			toFlagName,
+			toAvro,
+		),
+	)
+	flagSet.StringVar(
+		&f.Package,
+		packageFlagName,
+		"",
+		fmt.Sprintf(
+			`The package of the Protobuf file if --%s is %s. Defaults to the namespace of the record`,
+			toFlagName,
+			toProto,
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	var data []byte
+	var err error
+	switch flags.To {
+	case toAvro:
+		if flags.Type == "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s is required if --%s is %s", typeFlagName, toFlagName, toAvro)
+		}
+		if flags.Package != "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s can only be set if --%s is %s", packageFlagName, toFlagName, toProto)
+		}
+		data, err = convertToAvro(ctx, container, flags)
+	case toProto:
+		if flags.Type != "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s can only be set if --%s is %s", typeFlagName, toFlagName, toAvro)
+		}
+		data, err = convertToProto(container, flags)
+	case "":
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", toFlagName)
+	default:
+		return appcmd.NewInvalidArgumentErrorf("--%s must be one of %s", toFlagName, allTosString)
+	}
+	if err != nil {
+		return err
+	}
+	if flags.Output == "" || flags.Output == "-" {
+		_, err := container.Stdout().Write(data)
+		return err
+	}
+	return os.WriteFile(flags.Output, data, 0644)
+}
+
+func convertToAvro(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) ([]byte, error) {
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return nil, err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false, // docs are read from the source code info
+	)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := bufavro.FromMessage(image, flags.Type)
+	if err != nil {
+		return nil, err
+	}
+	return bufavro.Marshal(schema)
+}
+
+func convertToProto(
+	container appflag.Container,
+	flags *flags,
+) ([]byte, error) {
+	if container.NumArgs() == 0 {
+		return nil, appcmd.NewInvalidArgumentError("the path to an Avro schema is required")
+	}
+	path := container.Arg(0)
+	var data []byte
+	var err error
+	switch path {
+	case "-":
+		data, err = io.ReadAll(container.Stdin())
+	default:
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	schema, err := bufavro.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the Avro schema %s: %w", path, err)
+	}
+	return bufavro.ToProto(schema, bufavro.ToProtoWithPackage(flags.Package))
+}
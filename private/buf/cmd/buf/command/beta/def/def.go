@@ -0,0 +1,366 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package def
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/buffilepath"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufsymbol"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/protosource"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	formatFlagName          = "format"
+
+	formatText = "text"
+	formatJSON = "json"
+
+	roleType     = "type"
+	roleExtendee = "extendee"
+	roleInput    = "input"
+	roleOutput   = "output"
+)
+
+var allFormats = []string{formatText, formatJSON}
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <name> <input>",
+		Short: "Print the definition of a message, enum, field, or service",
+		Long: `The first argument is the fully-qualified name of the symbol to print the definition
+of, such as "acme.orders.v1.Order.customer_id".
+
+The file, line, and column of the declaration of the symbol are printed, followed by the
+declaration itself and the types that it refers to: the type of a field, the message that
+an extension extends, and the request and response of a method. Each type is printed with
+the location of its own declaration.
+
+Files of dependencies that are not on disk, such as the files of modules in the module cache
+and the Well-Known Types, are written to the cache so that the printed paths can be opened.
+
+The second argument is the source, module, or image to read the symbol from.
+The second argument must be one of format ` + buffetch.AllFormatsString + `.
+Defaults to "." if no second argument is specified.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Format          string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		formatText,
+		fmt.Sprintf(
+			"The format to print the definition with. Must be one of %s",
+			stringutil.SliceToString(allFormats),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Format != formatText && flags.Format != formatJSON {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s must be one of %s",
+			formatFlagName,
+			stringutil.SliceToString(allFormats),
+		)
+	}
+	fullName := strings.TrimPrefix(container.Arg(0), ".")
+	input := "."
+	if container.NumArgs() > 1 {
+		input = container.Arg(1)
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false, // the locations of symbols are read from the source code info
+	)
+	if err != nil {
+		return err
+	}
+	index, err := bufsymbol.NewIndex(ctx, image)
+	if err != nil {
+		return err
+	}
+	symbol := index.SymbolForFullName(fullName)
+	if symbol == nil {
+		return fmt.Errorf("%q is not declared in %s or its dependencies", fullName, input)
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	moduleReader, err := bufcli.NewModuleReaderAndCreateCacheDirs(container, clientConfig)
+	if err != nil {
+		return err
+	}
+	filePathResolver := buffilepath.NewResolver(
+		moduleReader,
+		filepath.Join(container.CacheDirPath(), bufcli.LSPCacheDir),
+	)
+	definition, err := newExternalDefinition(ctx, filePathResolver, index, symbol)
+	if err != nil {
+		return err
+	}
+	if flags.Format == formatJSON {
+		return json.NewEncoder(container.Stdout()).Encode(definition)
+	}
+	return printDefinitionText(container.Stdout(), definition)
+}
+
+// externalDefinition is the JSON representation of the definition of a symbol.
+type externalDefinition struct {
+	externalLocation
+	Declaration string `json:"declaration"`
+	// Types are the types that the declaration refers to.
+	Types []*externalType `json:"types,omitempty"`
+}
+
+// externalType is a type that a declaration refers to.
+type externalType struct {
+	// Role is how the declaration refers to the type, one of type, extendee,
+	// input, or output.
+	Role string `json:"role"`
+	externalLocation
+}
+
+// externalLocation is a symbol and the location of its declaration.
+//
+// Scalar types do not have a location, and only have a FullName and Kind.
+type externalLocation struct {
+	FullName string `json:"full_name"`
+	Kind     string `json:"kind"`
+	// Path is the path on disk of the file that declares the symbol.
+	Path   string `json:"path,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+	// Module is the module that the file is from, if any.
+	Module string `json:"module,omitempty"`
+}
+
+func newExternalDefinition(
+	ctx context.Context,
+	filePathResolver buffilepath.Resolver,
+	index *bufsymbol.Index,
+	symbol *bufsymbol.Symbol,
+) (*externalDefinition, error) {
+	location, err := newExternalLocation(ctx, filePathResolver, symbol)
+	if err != nil {
+		return nil, err
+	}
+	definition := &externalDefinition{
+		externalLocation: *location,
+		Declaration:      symbol.Declaration(),
+	}
+	addType := func(role string, typeName string) error {
+		typeName = strings.TrimPrefix(typeName, ".")
+		typeSymbol := index.SymbolForFullName(typeName)
+		if typeSymbol == nil {
+			// This should not happen for a valid image, but the type is
+			// still printed without a location.
+			definition.Types = append(
+				definition.Types,
+				&externalType{
+					Role:             role,
+					externalLocation: externalLocation{FullName: typeName},
+				},
+			)
+			return nil
+		}
+		typeLocation, err := newExternalLocation(ctx, filePathResolver, typeSymbol)
+		if err != nil {
+			return err
+		}
+		definition.Types = append(
+			definition.Types,
+			&externalType{
+				Role:             role,
+				externalLocation: *typeLocation,
+			},
+		)
+		return nil
+	}
+	switch descriptor := symbol.Descriptor.(type) {
+	case protosource.Field:
+		if symbol.Kind == bufsymbol.KindExtension {
+			if err := addType(roleExtendee, descriptor.Extendee()); err != nil {
+				return nil, err
+			}
+		}
+		switch descriptor.Type() {
+		case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+			descriptorpb.FieldDescriptorProto_TYPE_ENUM,
+			descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+			if err := addType(roleType, descriptor.TypeName()); err != nil {
+				return nil, err
+			}
+		default:
+			definition.Types = append(
+				definition.Types,
+				&externalType{
+					Role: roleType,
+					externalLocation: externalLocation{
+						FullName: strings.ToLower(strings.TrimPrefix(descriptor.Type().String(), "TYPE_")),
+						Kind:     "scalar",
+					},
+				},
+			)
+		}
+	case protosource.Method:
+		if err := addType(roleInput, descriptor.InputTypeName()); err != nil {
+			return nil, err
+		}
+		if err := addType(roleOutput, descriptor.OutputTypeName()); err != nil {
+			return nil, err
+		}
+	}
+	return definition, nil
+}
+
+func newExternalLocation(
+	ctx context.Context,
+	filePathResolver buffilepath.Resolver,
+	symbol *bufsymbol.Symbol,
+) (*externalLocation, error) {
+	path, err := filePathResolver.GetFilePath(ctx, symbol.ImageFile)
+	if err != nil {
+		return nil, err
+	}
+	span := symbol.NameSpan
+	if span.IsZero() {
+		span = symbol.Span
+	}
+	location := &externalLocation{
+		FullName: symbol.FullName,
+		Kind:     strings.ReplaceAll(symbol.Kind.String(), " ", "_"),
+		Path:     path,
+		Line:     span.StartLine,
+		Column:   span.StartColumn,
+	}
+	if moduleIdentity := symbol.ImageFile.ModuleIdentity(); moduleIdentity != nil {
+		location.Module = moduleIdentity.IdentityString()
+	}
+	return location, nil
+}
+
+// printDefinitionText prints the location of the symbol in the same form as
+// compiler errors so that editors can link it, followed by the declaration
+// and the types that it refers to.
+func printDefinitionText(writer io.Writer, definition *externalDefinition) error {
+	var builder strings.Builder
+	writeLocation(&builder, &definition.externalLocation)
+	builder.WriteString("\n  ")
+	builder.WriteString(definition.Declaration)
+	builder.WriteString("\n")
+	for _, externalType := range definition.Types {
+		builder.WriteString(externalType.Role)
+		builder.WriteString(": ")
+		writeLocation(&builder, &externalType.externalLocation)
+		builder.WriteString("\n")
+	}
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+func writeLocation(builder *strings.Builder, location *externalLocation) {
+	if location.Path != "" {
+		builder.WriteString(location.Path)
+		if location.Line > 0 {
+			builder.WriteString(fmt.Sprintf(":%d:%d", location.Line, location.Column))
+		}
+		builder.WriteString(" ")
+	}
+	if location.Kind != "" {
+		builder.WriteString(location.Kind)
+		builder.WriteString(" ")
+	}
+	builder.WriteString(location.FullName)
+	if location.Module != "" {
+		builder.WriteString(" (")
+		builder.WriteString(location.Module)
+		builder.WriteString(")")
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deprecations
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufsymbol"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	excludeImportsFlagName  = "exclude-imports"
+	formatFlagName          = "format"
+
+	formatText = "text"
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+var (
+	allFormats = []string{formatText, formatJSON, formatCSV}
+	csvHeader  = []string{
+		"kind",
+		"full_name",
+		"path",
+		"line",
+		"column",
+		"module",
+		"referrer_kind",
+		"referrer_full_name",
+		"referrer_path",
+		"referrer_line",
+		"referrer_column",
+	}
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "List the deprecated declarations and the declarations that still refer to them",
+		Long: `List the messages, enums, enum values, fields, extensions, services, and methods of the
+input and its dependencies that are marked with deprecated = true, sorted by name.
+
+Each deprecated declaration is followed by the declarations that still refer to it: the
+fields and extensions with it as their type or extendee, and the methods with it as their
+input or output type. These are what needs to be changed before the deprecated declaration
+can be removed:
+
+    $ buf beta deprecations
+    acme/v1/order.proto:12:9 message acme.v1.LegacyAddress
+      acme/v1/order.proto:20:3 field acme.v1.Order.shipping_address
+    acme/v1/order.proto:24:3 field acme.v1.Order.notes
+
+With --format csv, there is one row for each reference, or a single row without a
+referrer for a deprecated declaration that is no longer referred to, so that the
+cleanup can be tracked in a spreadsheet. With --format json, each deprecated
+declaration is printed as a JSON object on its own line, with its references.
+
+` + bufcli.GetInputLong(`the source, module, or image to list the deprecations of`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	ExcludeImports  bool
+	Format          string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.BoolVar(
+		&f.ExcludeImports,
+		excludeImportsFlagName,
+		false,
+		"Exclude the deprecated declarations of dependencies. References from dependencies are still listed",
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		formatText,
+		fmt.Sprintf(
+			"The format to print the deprecations with. Must be one of %s",
+			stringutil.SliceToString(allFormats),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Format != formatText && flags.Format != formatJSON && flags.Format != formatCSV {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s must be one of %s",
+			formatFlagName,
+			stringutil.SliceToString(allFormats),
+		)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false, // the locations of declarations and references are read from the source code info
+	)
+	if err != nil {
+		return err
+	}
+	index, err := bufsymbol.NewIndex(ctx, image)
+	if err != nil {
+		return err
+	}
+	var deprecations []*externalDeprecation
+	for _, symbol := range index.Symbols() {
+		if !isDeprecated(symbol) || (flags.ExcludeImports && symbol.ImageFile.IsImport()) {
+			continue
+		}
+		deprecations = append(deprecations, newExternalDeprecation(symbol, index.References(symbol)))
+	}
+	switch flags.Format {
+	case formatJSON:
+		return printDeprecationsJSON(container.Stdout(), deprecations)
+	case formatCSV:
+		return printDeprecationsCSV(container.Stdout(), deprecations)
+	default:
+		return printDeprecationsText(container.Stdout(), deprecations)
+	}
+}
+
+// isDeprecated returns true if the symbol is marked with deprecated = true.
+//
+// Oneofs can not be deprecated.
+func isDeprecated(symbol *bufsymbol.Symbol) bool {
+	deprecatable, ok := symbol.Descriptor.(interface{ Deprecated() bool })
+	return ok && deprecatable.Deprecated()
+}
+
+// externalDeprecation is the JSON representation of a deprecated symbol.
+type externalDeprecation struct {
+	FullName string `json:"full_name"`
+	Kind     string `json:"kind"`
+	// Path is the path of the file within its module.
+	Path string `json:"path"`
+	// ExternalPath is the path of the file as it is referred to by the input,
+	// which is the same as Path for files of dependencies.
+	ExternalPath string `json:"external_path"`
+	Line         int    `json:"line,omitempty"`
+	Column       int    `json:"column,omitempty"`
+	// Import is true if the symbol is declared in a dependency.
+	Import bool `json:"import,omitempty"`
+	// Module is the module that the file is from, if any.
+	Module     string               `json:"module,omitempty"`
+	References []*externalReference `json:"references,omitempty"`
+}
+
+// externalReference is the JSON representation of a reference to a deprecated
+// symbol, by the symbol that refers to it.
+type externalReference struct {
+	FullName     string `json:"full_name"`
+	Kind         string `json:"kind"`
+	Path         string `json:"path"`
+	ExternalPath string `json:"external_path"`
+	Line         int    `json:"line,omitempty"`
+	Column       int    `json:"column,omitempty"`
+	Import       bool   `json:"import,omitempty"`
+}
+
+func newExternalDeprecation(symbol *bufsymbol.Symbol, references []*bufsymbol.Reference) *externalDeprecation {
+	span := symbol.NameSpan
+	if span.IsZero() {
+		span = symbol.Span
+	}
+	externalDeprecation := &externalDeprecation{
+		FullName:     symbol.FullName,
+		Kind:         newExternalKind(symbol.Kind),
+		Path:         symbol.ImageFile.Path(),
+		ExternalPath: symbol.ImageFile.ExternalPath(),
+		Line:         span.StartLine,
+		Column:       span.StartColumn,
+		Import:       symbol.ImageFile.IsImport(),
+	}
+	if moduleIdentity := symbol.ImageFile.ModuleIdentity(); moduleIdentity != nil {
+		externalDeprecation.Module = moduleIdentity.IdentityString()
+	}
+	for _, reference := range references {
+		externalDeprecation.References = append(
+			externalDeprecation.References,
+			&externalReference{
+				FullName:     reference.Referrer.FullName,
+				Kind:         newExternalKind(reference.Referrer.Kind),
+				Path:         reference.ImageFile.Path(),
+				ExternalPath: reference.ImageFile.ExternalPath(),
+				Line:         reference.Span.StartLine,
+				Column:       reference.Span.StartColumn,
+				Import:       reference.ImageFile.IsImport(),
+			},
+		)
+	}
+	return externalDeprecation
+}
+
+func newExternalKind(kind bufsymbol.Kind) string {
+	return strings.ReplaceAll(kind.String(), " ", "_")
+}
+
+func printDeprecationsJSON(writer io.Writer, deprecations []*externalDeprecation) error {
+	encoder := json.NewEncoder(writer)
+	for _, deprecation := range deprecations {
+		if err := encoder.Encode(deprecation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printDeprecationsCSV(writer io.Writer, deprecations []*externalDeprecation) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, deprecation := range deprecations {
+		record := []string{
+			deprecation.Kind,
+			deprecation.FullName,
+			deprecation.ExternalPath,
+			formatPosition(deprecation.Line),
+			formatPosition(deprecation.Column),
+			deprecation.Module,
+		}
+		if len(deprecation.References) == 0 {
+			if err := csvWriter.Write(append(record, "", "", "", "", "")); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, reference := range deprecation.References {
+			if err := csvWriter.Write(
+				append(
+					record[:len(record):len(record)],
+					reference.Kind,
+					reference.FullName,
+					reference.ExternalPath,
+					formatPosition(reference.Line),
+					formatPosition(reference.Column),
+				),
+			); err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// printDeprecationsText prints each deprecation as path:line:column, followed by
+// its kind and name, and each of its references indented below it, in the same
+// form as compiler errors so that editors can link them.
+func printDeprecationsText(writer io.Writer, deprecations []*externalDeprecation) error {
+	var builder strings.Builder
+	for _, deprecation := range deprecations {
+		writeTextLine(&builder, deprecation.ExternalPath, deprecation.Line, deprecation.Column, deprecation.Kind, deprecation.FullName)
+		if deprecation.Import && deprecation.Module != "" {
+			builder.WriteString(" (")
+			builder.WriteString(deprecation.Module)
+			builder.WriteString(")")
+		}
+		builder.WriteString("\n")
+		for _, reference := range deprecation.References {
+			builder.WriteString("  ")
+			writeTextLine(&builder, reference.ExternalPath, reference.Line, reference.Column, reference.Kind, reference.FullName)
+			builder.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+func writeTextLine(builder *strings.Builder, path string, line int, column int, kind string, fullName string) {
+	builder.WriteString(path)
+	if line > 0 {
+		builder.WriteString(fmt.Sprintf(":%d:%d", line, column))
+	}
+	builder.WriteString(" ")
+	builder.WriteString(kind)
+	builder.WriteString(" ")
+	builder.WriteString(fullName)
+}
+
+// formatPosition formats the line or column, which is empty if unknown.
+func formatPosition(position int) string {
+	if position == 0 {
+		return ""
+	}
+	return strconv.Itoa(position)
+}
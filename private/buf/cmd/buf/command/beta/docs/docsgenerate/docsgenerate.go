@@ -0,0 +1,200 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docsgenerate
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufdoc"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	outputFlagName          = "output"
+	outputFlagShortName     = "o"
+	formatFlagName          = "format"
+	templateFlagName        = "template"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Generate documentation from the comments of Protobuf files",
+		Long: `The documentation lists the messages, enums, services, and extensions of each
+package of the input, with their fields, values, methods, options, and comments.
+
+An index file and a file for each package are written to the output directory, for
+example index.md and acme.v1.md. Types that are declared in the input are linked.
+
+The layout of the documentation can be changed with --template, which is a directory
+of Go templates in files with the .tmpl extension. The templates are parsed after the
+built-in templates of the format, and a template that is defined with the name of a
+built-in template replaces it, for example:
+
+    {{define "message"}}<section id="{{.FullName}}">...</section>{{end}}
+
+The index file is rendered by the "index" template with the documentation of all of the
+packages, and the file of each package by the "package" template with the documentation
+of the package. The other built-in templates are "head" (html only), "service",
+"message", "enum", "comments", "options", "type", "fieldType", and "fieldDescription".
+Templates of the html format are parsed with html/template, and templates of the
+markdown format with text/template.
+
+The json format writes the documentation of all of the packages to a single file,
+document.json, for tools that render documentation themselves. The names of its
+fields are only changed in backwards-compatible ways. Templates cannot be used with
+the json format.
+
+` + bufcli.GetInputLong(`the source, module, or image to generate documentation for`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Output          string
+	Format          string
+	Template        string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"",
+		`Required. The output directory for the documentation`,
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufdoc.FormatMarkdown.String(),
+		fmt.Sprintf(
+			"The format of the documentation. Must be one of %s",
+			bufdoc.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&f.Template,
+		templateFlagName,
+		"",
+		`The directory of Go templates in files with the .tmpl extension that replace the built-in templates with the same name`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Output == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", outputFlagName)
+	}
+	format, err := bufdoc.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false, // comments are read from the source code info
+	)
+	if err != nil {
+		return err
+	}
+	document, err := bufdoc.NewDocument(ctx, image)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(flags.Output, 0755); err != nil {
+		return err
+	}
+	readWriteBucket, err := storageos.NewProvider().NewReadWriteBucket(flags.Output)
+	if err != nil {
+		return err
+	}
+	var generateOptions []bufdoc.GenerateOption
+	if flags.Template != "" {
+		templateReadBucket, err := storageos.NewProvider().NewReadWriteBucket(flags.Template)
+		if err != nil {
+			return err
+		}
+		generateOptions = append(generateOptions, bufdoc.GenerateWithTemplates(templateReadBucket))
+	}
+	return bufdoc.Generate(ctx, readWriteBucket, format, document, generateOptions...)
+}
@@ -0,0 +1,267 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docsserve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufwire"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufdoc"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/transport/http/httpserver"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	bindFlagName            = "bind"
+	portFlagName            = "port"
+
+	// pollInterval is the interval at which the input directory is checked for changes.
+	pollInterval = time.Second
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Serve the documentation of Protobuf files, and rebuild it when they change",
+		Long: `The HTML documentation of the input, as generated by "buf beta docs generate --format html",
+is served over HTTP.
+
+If the input is a directory, the files in the directory are checked for changes every second,
+and the documentation is rebuilt when they change. Open pages reload when the documentation is
+rebuilt. If the build fails, the errors are shown instead until it succeeds.
+
+The --timeout flag does not apply to this command.
+
+` + bufcli.GetInputLong(`the source, module, or image to serve documentation for`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFuncWithoutTimeout(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Config          string
+	DisableSymlinks bool
+	BindAddress     string
+	Port            string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.BindAddress,
+		bindFlagName,
+		"127.0.0.1",
+		"The address to be exposed to accept HTTP requests",
+	)
+	flagSet.StringVar(
+		&f.Port,
+		portFlagName,
+		"8080",
+		"The port to be exposed to accept HTTP requests",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, input)
+	if err != nil {
+		return err
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	imageConfigReader, err := bufcli.NewWireImageConfigReader(
+		container,
+		bufcli.NewStorageosProvider(flags.DisableSymlinks),
+		command.NewRunner(),
+		clientConfig,
+	)
+	if err != nil {
+		return err
+	}
+	builder := &documentBuilder{
+		container:         container,
+		imageConfigReader: imageConfigReader,
+		ref:               ref,
+		configOverride:    flags.Config,
+	}
+	site := newSite()
+	document, err := builder.build(ctx)
+	if err := site.update(ctx, document, err); err != nil {
+		return err
+	}
+	var httpListenConfig net.ListenConfig
+	httpListener, err := httpListenConfig.Listen(ctx, "tcp", net.JoinHostPort(flags.BindAddress, flags.Port))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(
+		container.Stderr(),
+		"Serving the documentation of %s on http://%s\n",
+		input,
+		httpListener.Addr().String(),
+	); err != nil {
+		return bufcli.NewInternalError(err)
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	if fileInfo, err := os.Stat(input); err == nil && fileInfo.IsDir() {
+		eg.Go(func() error {
+			return watch(ctx, container.Logger(), input, builder, site)
+		})
+	}
+	eg.Go(func() error {
+		return httpserver.Run(
+			ctx,
+			container.Logger(),
+			httpListener,
+			site,
+		)
+	})
+	return eg.Wait()
+}
+
+// documentBuilder builds the Document of the input.
+type documentBuilder struct {
+	container         appflag.Container
+	imageConfigReader bufwire.ImageConfigReader
+	ref               buffetch.Ref
+	configOverride    string
+}
+
+// build builds the Document of the input.
+//
+// If the input does not compile, the returned error contains the file annotations.
+func (b *documentBuilder) build(ctx context.Context) (*bufdoc.Document, error) {
+	imageConfigs, fileAnnotations, err := b.imageConfigReader.GetImageConfigs(
+		ctx,
+		b.container,
+		b.ref,
+		b.configOverride,
+		nil,
+		nil,
+		false,
+		false, // comments are read from the source code info
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileAnnotations) > 0 {
+		buffer := bytes.NewBuffer(nil)
+		if err := bufanalysis.PrintFileAnnotations(buffer, fileAnnotations, bufanalysis.FormatText.String()); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(buffer.String())
+	}
+	images := make([]bufimage.Image, 0, len(imageConfigs))
+	for _, imageConfig := range imageConfigs {
+		images = append(images, imageConfig.Image())
+	}
+	image, err := bufimage.MergeImages(images...)
+	if err != nil {
+		return nil, err
+	}
+	return bufdoc.NewDocument(ctx, image)
+}
+
+// watch rebuilds the documentation whenever the files in the directory change,
+// until the context is done.
+func watch(
+	ctx context.Context,
+	logger *zap.Logger,
+	dirPath string,
+	builder *documentBuilder,
+	site *site,
+) error {
+	fingerprint, err := getFingerprint(dirPath)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		newFingerprint, err := getFingerprint(dirPath)
+		if err != nil {
+			return err
+		}
+		if newFingerprint == fingerprint {
+			continue
+		}
+		fingerprint = newFingerprint
+		document, err := builder.build(ctx)
+		if err != nil {
+			logger.Warn("build_failed", zap.Error(err))
+		} else {
+			logger.Info("rebuilt")
+		}
+		if err := site.update(ctx, document, err); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docsserve
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufdoc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSite(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	site := newSite()
+	require.NoError(
+		t,
+		site.update(
+			ctx,
+			&bufdoc.Document{
+				Packages: []*bufdoc.Package{
+					{
+						Name:  "acme.v1",
+						Files: []*bufdoc.File{{Path: "acme/v1/order.proto"}},
+					},
+				},
+			},
+			nil,
+		),
+	)
+	server := httptest.NewServer(site)
+	defer server.Close()
+
+	statusCode, body := testGet(t, server.URL+"/")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Contains(t, body, `<a href="acme.v1.html">acme.v1</a>`)
+	assert.Contains(t, body, `fetch("/_buf/version")`)
+	assert.Contains(t, body, `if (version !== "1")`)
+	statusCode, body = testGet(t, server.URL+"/acme.v1.html")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Contains(t, body, "<code>acme/v1/order.proto</code>")
+	statusCode, _ = testGet(t, server.URL+"/missing.html")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	statusCode, body = testGet(t, server.URL+versionPath)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "1", body)
+
+	// A failed build shows the error until the next successful build.
+	require.NoError(t, site.update(ctx, nil, errors.New("a.proto:1:1:<syntax error>")))
+	statusCode, body = testGet(t, server.URL+"/acme.v1.html")
+	assert.Equal(t, http.StatusInternalServerError, statusCode)
+	assert.Contains(t, body, "<pre>a.proto:1:1:&lt;syntax error&gt;</pre>")
+	assert.Contains(t, body, `if (version !== "2")`)
+	require.NoError(t, site.update(ctx, &bufdoc.Document{}, nil))
+	statusCode, _ = testGet(t, server.URL+"/acme.v1.html")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	statusCode, body = testGet(t, server.URL+versionPath)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "3", body)
+}
+
+func TestGetFingerprint(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dirPath, "a.proto"), []byte("syntax = \"proto3\";"), 0600))
+	fingerprint, err := getFingerprint(dirPath)
+	require.NoError(t, err)
+	// Hidden directories are not checked.
+	require.NoError(t, os.Mkdir(filepath.Join(dirPath, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dirPath, ".git", "HEAD"), []byte("ref"), 0600))
+	newFingerprint, err := getFingerprint(dirPath)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, newFingerprint)
+	require.NoError(t, os.WriteFile(filepath.Join(dirPath, "b.proto"), []byte("syntax = \"proto3\";"), 0600))
+	newFingerprint, err = getFingerprint(dirPath)
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, newFingerprint)
+}
+
+func testGet(t *testing.T, url string) (int, string) {
+	response, err := http.Get(url)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response.StatusCode, string(body)
+}
@@ -0,0 +1,186 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docsserve
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufdoc"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+)
+
+const (
+	// versionPath is the path that returns the version of the site, which pages
+	// poll to reload when the documentation is rebuilt.
+	versionPath = "/_buf/version"
+	indexPath   = "index.html"
+)
+
+// site is the documentation that is served, which is replaced when the input
+// is rebuilt.
+type site struct {
+	lock       sync.RWMutex
+	pathToData map[string][]byte
+	// err is the error of the last build, if it failed.
+	err error
+	// version is incremented on every build, whether it succeeds or not.
+	version int
+}
+
+func newSite() *site {
+	return &site{
+		pathToData: make(map[string][]byte),
+	}
+}
+
+// update replaces the documentation with the HTML documentation of the Document.
+//
+// If err is not nil, the documentation is kept, and err is shown instead until
+// the next successful update.
+func (s *site) update(ctx context.Context, document *bufdoc.Document, err error) error {
+	var pathToData map[string][]byte
+	if err == nil {
+		readWriteBucket := storagemem.NewReadWriteBucket()
+		if err := bufdoc.Generate(ctx, readWriteBucket, bufdoc.FormatHTML, document); err != nil {
+			return err
+		}
+		pathToData = make(map[string][]byte)
+		if err := storage.WalkReadObjects(
+			ctx,
+			readWriteBucket,
+			"",
+			func(readObject storage.ReadObject) error {
+				data, err := io.ReadAll(readObject)
+				if err != nil {
+					return err
+				}
+				pathToData[readObject.Path()] = data
+				return nil
+			},
+		); err != nil {
+			return err
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if pathToData != nil {
+		s.pathToData = pathToData
+	}
+	s.err = err
+	s.version++
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *site) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	responseWriter.Header().Set("Cache-Control", "no-store")
+	if request.URL.Path == versionPath {
+		responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = responseWriter.Write([]byte(strconv.Itoa(s.version)))
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if s.err != nil {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(
+			responseWriter,
+			"<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>Build failed</title></head>\n<body>\n<h1>Build failed</h1>\n<pre>%s</pre>\n%s</body>\n</html>\n",
+			html.EscapeString(s.err.Error()),
+			s.reloadScript(),
+		)
+		return
+	}
+	filePath := strings.TrimPrefix(path.Clean(request.URL.Path), "/")
+	if filePath == "" {
+		filePath = indexPath
+	}
+	data, ok := s.pathToData[filePath]
+	if !ok {
+		http.NotFound(responseWriter, request)
+		return
+	}
+	// The script is added to the end of the body, so that the page reloads when
+	// the documentation is rebuilt.
+	if index := bytes.LastIndex(data, []byte("</body>")); index >= 0 {
+		data = append(append(append([]byte{}, data[:index]...), s.reloadScript()...), data[index:]...)
+	}
+	_, _ = responseWriter.Write(data)
+}
+
+// reloadScript returns the script that reloads the page when the version of the
+// site changes.
+//
+// Must be called with the lock held.
+func (s *site) reloadScript() string {
+	return fmt.Sprintf(
+		`<script>
+setInterval(function () {
+  fetch(%q).then(function (response) { return response.text(); }).then(function (version) {
+    if (version !== %q) { location.reload(); }
+  }).catch(function () {});
+}, 1000);
+</script>
+`,
+		versionPath,
+		strconv.Itoa(s.version),
+	)
+}
+
+// getFingerprint returns a fingerprint of the paths, sizes, and modification
+// times of the files in the directory, which changes when a file is added,
+// removed, or written.
+//
+// Hidden directories, such as .git, are skipped.
+func getFingerprint(dirPath string) (uint64, error) {
+	hash := fnv.New64a()
+	if err := filepath.WalkDir(
+		dirPath,
+		func(filePath string, dirEntry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if dirEntry.IsDir() {
+				if filePath != dirPath && strings.HasPrefix(dirEntry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			fileInfo, err := dirEntry.Info()
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(hash, "%s\x00%d\x00%d\x00", filePath, fileInfo.Size(), fileInfo.ModTime().UnixNano())
+			return nil
+		},
+	); err != nil {
+		return 0, err
+	}
+	return hash.Sum64(), nil
+}
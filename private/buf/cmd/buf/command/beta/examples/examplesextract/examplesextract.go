@@ -0,0 +1,244 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package examplesextract
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufexample"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	outputFlagName          = "output"
+	outputFlagShortName     = "o"
+	optionFlagName          = "option"
+	excludeCommentsFlagName = "exclude-comments"
+	formatFlagName          = "format"
+
+	formatJSON  = "json"
+	formatBinpb = "binpb"
+)
+
+var allFormats = []string{formatJSON, formatBinpb}
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Validate the examples declared in Protobuf files and write them as fixture files",
+		Long: `Examples are declared in the leading comments of a message with a line
+starting with "@example", optionally followed by the name of the example, and
+continue with the JSON of the example up to the next blank line:
+
+    // @example single
+    // {
+    //   "id": "1234",
+    //   "quantity": 1
+    // }
+    message Order { ... }
+
+Examples can also be declared with a custom message option of type string or
+repeated string that contains the JSON of the example, selected with --option:
+
+    message Order {
+      option (corp.example) = "{\"id\": \"1234\"}";
+    }
+
+Every example is validated against the message it is declared on. If any
+example is invalid, nothing is written and all invalid examples are reported.
+
+Valid examples are written to <output>/<message>/<name>.<format>. Examples
+without a name are named after their 1-indexed position within the message.
+
+` + bufcli.GetSourceOrModuleLong(`the source or module to extract examples from`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Output          string
+	Option          string
+	ExcludeComments bool
+	Format          string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"",
+		`The output directory for the fixture files. If not set, the examples are only validated`,
+	)
+	flagSet.StringVar(
+		&f.Option,
+		optionFlagName,
+		"",
+		`The fully-qualified name of a custom message option to also read examples from, for example "corp.example"`,
+	)
+	flagSet.BoolVar(
+		&f.ExcludeComments,
+		excludeCommentsFlagName,
+		false,
+		`Do not read examples from comments`,
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		formatJSON,
+		fmt.Sprintf(
+			"The format of the fixture files. Must be one of %s",
+			stringutil.SliceToString(allFormats),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Format != formatJSON && flags.Format != formatBinpb {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s must be one of %s",
+			formatFlagName,
+			stringutil.SliceToString(allFormats),
+		)
+	}
+	if flags.ExcludeComments && flags.Option == "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s requires --%s to be set", excludeCommentsFlagName, optionFlagName)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	var extractOptions []bufexample.ExtractOption
+	if flags.Option != "" {
+		extractOptions = append(extractOptions, bufexample.ExtractWithOptionName(flags.Option))
+	}
+	if flags.ExcludeComments {
+		extractOptions = append(extractOptions, bufexample.ExtractWithoutComments())
+	}
+	examples, err := bufexample.Extract(image, extractOptions...)
+	if err != nil {
+		return err
+	}
+	if flags.Output == "" {
+		return nil
+	}
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptorProtos(image)...)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(flags.Output, 0755); err != nil {
+		return err
+	}
+	readWriteBucket, err := storageos.NewProvider().NewReadWriteBucket(flags.Output)
+	if err != nil {
+		return err
+	}
+	marshaler := protoencoding.NewWireMarshaler()
+	if flags.Format == formatJSON {
+		marshaler = protoencoding.NewJSONMarshaler(resolver, protoencoding.JSONMarshalerWithIndent())
+	}
+	for _, example := range examples {
+		data, err := marshaler.Marshal(example.Message)
+		if err != nil {
+			return fmt.Errorf("failed to marshal example %q of %s: %w", example.Name, example.TypeName, err)
+		}
+		path, err := normalpath.NormalizeAndValidate(
+			normalpath.Join(example.TypeName, example.Name+"."+flags.Format),
+		)
+		if err != nil {
+			return fmt.Errorf("invalid name for example %q of %s: %w", example.Name, example.TypeName, err)
+		}
+		if err := storage.PutPath(ctx, readWriteBucket, path, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportgraphql
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufgraphql"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	outputFlagName          = "output"
+	outputFlagShortName     = "o"
+	serviceFlagName         = "service"
+	typeNameStyleFlagName   = "type-name-style"
+	fieldNameStyleFlagName  = "field-name-style"
+	scalarFlagName          = "scalar"
+	queryPrefixFlagName     = "query-prefix"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Export the messages and services of Protobuf files as a GraphQL schema",
+		Long: `The schema is printed in the GraphQL schema definition language, and is a starting
+point for a GraphQL gateway in front of the services.
+
+Each unary method is a field of the Query type if its name starts with one of the
+prefixes given by --query-prefix, or if it has the idempotency level NO_SIDE_EFFECTS,
+and a field of the Mutation type otherwise. The field takes the request as the input
+argument, and returns the response. Streaming methods are not exported.
+
+Messages that are used in requests are input types with the suffix "Input", and
+messages that are used in responses are object types. Map fields are lists of the
+entries of the map.
+
+The GraphQL type of scalar types and of well-known types can be changed with --scalar,
+for example --scalar int64=Long --scalar google.protobuf.Timestamp=DateTime. Types that
+are not built-in scalars of GraphQL are declared as custom scalars.
+
+` + bufcli.GetInputLong(`the source, module, or image to export the schema of`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Output          string
+	Services        []string
+	TypeNameStyle   string
+	FieldNameStyle  string
+	Scalars         map[string]string
+	QueryPrefixes   []string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"-",
+		`The file to write the schema to`,
+	)
+	flagSet.StringSliceVar(
+		&f.Services,
+		serviceFlagName,
+		nil,
+		"The fully-qualified name of a service to export, such as acme.v1.OrderService. May be provided multiple times. Defaults to all of the services of the input",
+	)
+	flagSet.StringVar(
+		&f.TypeNameStyle,
+		typeNameStyleFlagName,
+		bufgraphql.TypeNameStyleShort.String(),
+		fmt.Sprintf(
+			`How messages and enums are named. Must be one of %s. With short, types are named by their names within their package, such as Order_Item, and with full, by their fully-qualified names, such as acme_v1_Order_Item`,
+			bufgraphql.AllTypeNameStylesString,
+		),
+	)
+	flagSet.StringVar(
+		&f.FieldNameStyle,
+		fieldNameStyleFlagName,
+		bufgraphql.FieldNameStyleJSON.String(),
+		fmt.Sprintf(
+			`How fields are named. Must be one of %s`,
+			bufgraphql.AllFieldNameStylesString,
+		),
+	)
+	flagSet.StringToStringVar(
+		&f.Scalars,
+		scalarFlagName,
+		nil,
+		"The GraphQL type of a scalar type or well-known type, such as int64=Long. May be provided multiple times",
+	)
+	flagSet.StringSliceVar(
+		&f.QueryPrefixes,
+		queryPrefixFlagName,
+		bufgraphql.DefaultQueryMethodPrefixes,
+		"The prefix of the names of methods that are queries. May be provided multiple times",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	typeNameStyle, err := bufgraphql.ParseTypeNameStyle(flags.TypeNameStyle)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	fieldNameStyle, err := bufgraphql.ParseFieldNameStyle(flags.FieldNameStyle)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false, // descriptions are read from the source code info
+	)
+	if err != nil {
+		return err
+	}
+	generateOptions := []bufgraphql.GenerateOption{
+		bufgraphql.GenerateWithServiceNames(flags.Services...),
+		bufgraphql.GenerateWithTypeNameStyle(typeNameStyle),
+		bufgraphql.GenerateWithFieldNameStyle(fieldNameStyle),
+		bufgraphql.GenerateWithQueryMethodPrefixes(flags.QueryPrefixes...),
+	}
+	for protobufType, graphQLType := range flags.Scalars {
+		generateOptions = append(generateOptions, bufgraphql.GenerateWithScalarMapping(protobufType, graphQLType))
+	}
+	data, err := bufgraphql.Generate(image, generateOptions...)
+	if err != nil {
+		return err
+	}
+	if flags.Output == "" || flags.Output == "-" {
+		_, err := container.Stdout().Write(data)
+		return err
+	}
+	return os.WriteFile(flags.Output, data, 0644)
+}
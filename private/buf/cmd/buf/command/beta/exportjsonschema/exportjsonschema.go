@@ -0,0 +1,201 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportjsonschema
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufjsonschema"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName             = "error-format"
+	configFlagName                  = "config"
+	disableSymlinksFlagName         = "disable-symlinks"
+	outputFlagName                  = "output"
+	outputFlagShortName             = "o"
+	typeFlagName                    = "type"
+	requireImplicitPresenceFlagName = "require-implicit-presence"
+	exclusiveOneofsFlagName         = "exclusive-oneofs"
+
+	schemaFileExt = ".schema.json"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Export JSON Schemas for the JSON representation of Protobuf messages",
+		Long: `The schemas conform to JSON Schema draft 2020-12, and describe the Protobuf JSON
+representation of the messages, so that JSON payloads can be validated by consumers
+that do not use Protobuf.
+
+A schema is written to the output directory for each message given by --type, or for
+each message of the input if --type is not set, for example
+acme.events.v1.OrderCreated.schema.json. The messages and enums that a message refers
+to are included in the $defs of its schema.
+
+By default, no field is required, as Protobuf JSON marshalers omit the fields that are
+not set. With --require-implicit-presence, the fields that do not have explicit
+presence are required, which matches the JSON of marshalers that emit unpopulated
+fields. With --exclusive-oneofs, at most one field of each oneof may be set.
+
+` + bufcli.GetInputLong(`the source, module, or image to export the schemas of messages of`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat             string
+	Config                  string
+	DisableSymlinks         bool
+	Output                  string
+	Types                   []string
+	RequireImplicitPresence bool
+	ExclusiveOneofs         bool
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"",
+		`Required. The output directory for the schemas`,
+	)
+	flagSet.StringSliceVar(
+		&f.Types,
+		typeFlagName,
+		nil,
+		"The fully-qualified name of a message to export the schema of, such as acme.events.v1.OrderCreated. May be provided multiple times. Defaults to all of the messages of the input",
+	)
+	flagSet.BoolVar(
+		&f.RequireImplicitPresence,
+		requireImplicitPresenceFlagName,
+		false,
+		"Require the fields that do not have explicit presence, such as proto3 fields that are not optional",
+	)
+	flagSet.BoolVar(
+		&f.ExclusiveOneofs,
+		exclusiveOneofsFlagName,
+		false,
+		"Allow at most one field of each oneof to be set",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Output == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", outputFlagName)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false, // descriptions are read from the source code info
+	)
+	if err != nil {
+		return err
+	}
+	generateOptions := []bufjsonschema.GenerateOption{
+		bufjsonschema.GenerateWithTypeNames(flags.Types...),
+	}
+	if flags.RequireImplicitPresence {
+		generateOptions = append(generateOptions, bufjsonschema.GenerateWithImplicitPresenceRequired())
+	}
+	if flags.ExclusiveOneofs {
+		generateOptions = append(generateOptions, bufjsonschema.GenerateWithExclusiveOneofs())
+	}
+	typeNameToSchema, err := bufjsonschema.Generate(image, generateOptions...)
+	if err != nil {
+		return err
+	}
+	if len(typeNameToSchema) == 0 {
+		return fmt.Errorf("no messages are declared in %s", input)
+	}
+	if err := os.MkdirAll(flags.Output, 0755); err != nil {
+		return err
+	}
+	for typeName, schema := range typeNameToSchema {
+		data, err := bufjsonschema.Marshal(schema)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(flags.Output, typeName+schemaFileExt), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exportopenapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufopenapi"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	outputFlagName          = "output"
+	outputFlagShortName     = "o"
+	formatFlagName          = "format"
+	perServiceFlagName      = "per-service"
+	serviceFlagName         = "service"
+	titleFlagName           = "title"
+	versionFlagName         = "version"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Export the services of Protobuf files as an OpenAPI v3 document",
+		Long: `A method with a google.api.http annotation is exported as the operations of its
+HTTP rule and additional bindings. The variables of the path template are path
+parameters, and the fields of the request that are not bound to the path or the body
+are query parameters.
+
+A method without a google.api.http annotation is exported as a POST operation on
+/<package>.<Service>/<Method> with the JSON of the request as body, which is how the
+method is called with the Connect protocol. Streaming methods are not exported.
+
+By default, the services are merged into a single document that is written to stdout,
+or to the file given by --output. With --per-service, a document is written for each
+service to the directory given by --output, for example acme.v1.OrderService.openapi.yaml.
+
+` + bufcli.GetInputLong(`the source, module, or image to export the services of`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Output          string
+	Format          string
+	PerService      bool
+	Services        []string
+	Title           string
+	Version         string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"-",
+		fmt.Sprintf(
+			`The file to write the document to, or the directory to write the documents to if --%s is set`,
+			perServiceFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufopenapi.FormatYAML.String(),
+		fmt.Sprintf(
+			"The format of the documents. Must be one of %s",
+			bufopenapi.AllFormatsString,
+		),
+	)
+	flagSet.BoolVar(
+		&f.PerService,
+		perServiceFlagName,
+		false,
+		"Write a document for each service instead of a single document for all of the services",
+	)
+	flagSet.StringSliceVar(
+		&f.Services,
+		serviceFlagName,
+		nil,
+		"The fully-qualified name of a service to export, such as acme.v1.OrderService. May be provided multiple times. Defaults to all of the services of the input",
+	)
+	flagSet.StringVar(
+		&f.Title,
+		titleFlagName,
+		"",
+		"The title of the merged document. Defaults to the names of the services",
+	)
+	flagSet.StringVar(
+		&f.Version,
+		versionFlagName,
+		bufopenapi.DefaultInfoVersion,
+		"The version of the API in the documents",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	format, err := bufopenapi.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	if flags.PerService && (flags.Output == "" || flags.Output == "-") {
+		return appcmd.NewInvalidArgumentErrorf("--%s must be a directory if --%s is set", outputFlagName, perServiceFlagName)
+	}
+	if flags.PerService && flags.Title != "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s cannot be set if --%s is set", titleFlagName, perServiceFlagName)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false, // descriptions are read from the source code info
+	)
+	if err != nil {
+		return err
+	}
+	serviceNameToDocument, err := bufopenapi.Generate(
+		image,
+		bufopenapi.GenerateWithServiceNames(flags.Services...),
+		bufopenapi.GenerateWithInfoVersion(flags.Version),
+	)
+	if err != nil {
+		return err
+	}
+	if len(serviceNameToDocument) == 0 {
+		return fmt.Errorf("no services are declared in %s", input)
+	}
+	serviceNames := make([]string, 0, len(serviceNameToDocument))
+	for serviceName := range serviceNameToDocument {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+	if flags.PerService {
+		if err := os.MkdirAll(flags.Output, 0755); err != nil {
+			return err
+		}
+		for _, serviceName := range serviceNames {
+			data, err := bufopenapi.Marshal(serviceNameToDocument[serviceName], format)
+			if err != nil {
+				return err
+			}
+			filePath := filepath.Join(flags.Output, serviceName+".openapi."+format.String())
+			if err := os.WriteFile(filePath, data, 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	documents := make([]*bufopenapi.Document, 0, len(serviceNames))
+	for _, serviceName := range serviceNames {
+		documents = append(documents, serviceNameToDocument[serviceName])
+	}
+	title := flags.Title
+	if title == "" {
+		title = strings.Join(serviceNames, ", ")
+	}
+	info := &bufopenapi.Info{
+		Title:   title,
+		Version: flags.Version,
+	}
+	if len(documents) == 1 {
+		info.Description = documents[0].Info.Description
+	}
+	document, err := bufopenapi.Merge(info, documents...)
+	if err != nil {
+		return err
+	}
+	data, err := bufopenapi.Marshal(document, format)
+	if err != nil {
+		return err
+	}
+	if flags.Output == "" || flags.Output == "-" {
+		_, err := container.Stdout().Write(data)
+		return err
+	}
+	return os.WriteFile(flags.Output, data, 0644)
+}
@@ -0,0 +1,283 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzzcorpus
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufdatagen"
+	"github.com/bufbuild/buf/private/bufpkg/bufreflect"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	typeFlagName            = "type"
+	outputFlagName          = "output"
+	outputFlagShortName     = "o"
+	countFlagName           = "count"
+	mutatedCountFlagName    = "mutated-count"
+	seedFlagName            = "seed"
+	formatFlagName          = "format"
+
+	formatRaw = "raw"
+	formatGo  = "go"
+)
+
+var allFormats = []string{formatRaw, formatGo}
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input> --type <type> --output <dir>",
+		Short: "Generate a fuzzing corpus of serialized messages of a type",
+		Long: `Generate a corpus of serialized messages of a type to seed fuzzers of code that
+parses those messages:
+
+    $ buf beta fuzz-corpus --type acme.v1.Order --count 100 --mutated-count 100 --output corpus
+
+The corpus contains --count random messages that are valid according to the schema and
+the protovalidate constraints of the type, as generated by "buf beta generate-data".
+
+With --mutated-count, the corpus also contains that many messages with a single
+mutation each, such as a changed wire type, a length that overflows, an overlong varint,
+invalid UTF-8, an undefined enum value, a removed, duplicated, or unknown field, a
+truncation, or a flipped bit. Most mutated messages are invalid.
+
+Each message is written to its own file in the --output directory, which is created if
+it does not exist. With --format raw, the files contain the binary messages, and are
+named by the SHA-1 of their contents, as used by libFuzzer, go-fuzz, and AFL. With
+--format go, the files are in the format of the seed corpus of native Go fuzz tests,
+and the directory is typically testdata/fuzz/<FuzzTestName>, where the fuzz test takes
+a single []byte argument.
+
+The same --seed always results in the same corpus, unless the type has timestamp
+constraints relative to the current time.
+
+` + bufcli.GetSourceOrModuleLong(`the source or module that defines --type`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Type            string
+	Output          string
+	Count           int
+	MutatedCount    int
+	Seed            int64
+	Format          string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Type,
+		typeFlagName,
+		"",
+		`The full type name of the messages of the corpus, for example "acme.v1.Order"`,
+	)
+	_ = cobra.MarkFlagRequired(flagSet, typeFlagName)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"",
+		`Required. The directory to write the corpus to`,
+	)
+	flagSet.IntVar(
+		&f.Count,
+		countFlagName,
+		100,
+		`The number of valid messages to generate`,
+	)
+	flagSet.IntVar(
+		&f.MutatedCount,
+		mutatedCountFlagName,
+		0,
+		`The number of mutated messages to generate, which are likely invalid`,
+	)
+	flagSet.Int64Var(
+		&f.Seed,
+		seedFlagName,
+		0,
+		`The seed for the random number generator. If not set, a time-based seed is used`,
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		formatRaw,
+		fmt.Sprintf(
+			"The format of the corpus files. Must be one of %s",
+			stringutil.SliceToString(allFormats),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if flags.Output == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", outputFlagName)
+	}
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Count < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must be non-negative", countFlagName)
+	}
+	if flags.MutatedCount < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must be non-negative", mutatedCountFlagName)
+	}
+	if flags.Format != formatRaw && flags.Format != formatGo {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s must be one of %s",
+			formatFlagName,
+			stringutil.SliceToString(allFormats),
+		)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		true,
+	)
+	if err != nil {
+		return err
+	}
+	message, err := bufreflect.NewMessage(ctx, image, flags.Type)
+	if err != nil {
+		return err
+	}
+	messageDescriptor := message.ProtoReflect().Descriptor()
+	generator, err := bufdatagen.NewGenerator(messageDescriptor)
+	if err != nil {
+		return err
+	}
+	mutator := bufdatagen.NewMutator(messageDescriptor)
+	seed := flags.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	random := rand.New(rand.NewSource(seed))
+	if err := os.MkdirAll(flags.Output, 0755); err != nil {
+		return err
+	}
+	readWriteBucket, err := bufcli.NewStorageosProvider(flags.DisableSymlinks).NewReadWriteBucket(flags.Output)
+	if err != nil {
+		return err
+	}
+	// Messages are marshaled deterministically, so that the same seed results
+	// in the same files.
+	marshalOptions := proto.MarshalOptions{Deterministic: true}
+	for i := 0; i < flags.Count+flags.MutatedCount; i++ {
+		generated, err := generator.Generate(random)
+		if err != nil {
+			return err
+		}
+		data, err := marshalOptions.Marshal(generated)
+		if err != nil {
+			return err
+		}
+		if i >= flags.Count {
+			data = mutator.Mutate(random, data)
+		}
+		path, fileData := newCorpusFile(data, flags.Format)
+		if err := storage.PutPath(ctx, readWriteBucket, path, fileData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newCorpusFile returns the path and the contents of the corpus file for the
+// serialized message.
+//
+// Files are named by the hash of their contents as the fuzzers do, so that
+// duplicate messages result in a single file.
+func newCorpusFile(data []byte, format string) (string, []byte) {
+	if format == formatGo {
+		fileData := []byte("go test fuzz v1\n[]byte(" + strconv.Quote(string(data)) + ")\n")
+		sum := sha256.Sum256(fileData)
+		return hex.EncodeToString(sum[:])[:16], fileData
+	}
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:]), data
+}
@@ -0,0 +1,276 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generatedata
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufdatagen"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufreflect"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/recordio"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	typeFlagName            = "type"
+	toFlagName              = "to"
+	toFramingFlagName       = "to-framing"
+	countFlagName           = "count"
+	seedFlagName            = "seed"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Generate random messages from the schema of their type",
+		Long: `Generate random messages of a type for load tests and fixtures:
+
+    $ buf beta generate-data --type acme.v1.Order --count 100 --seed 42 --to orders.jsonl
+
+Values resemble real data where the schema allows it: strings are words, or email
+addresses, UUIDs, URLs, and the like for fields whose names suggest them, numbers are
+small, and timestamps are recent.
+
+The protovalidate constraints of the type are respected: values are drawn from the
+ranges, lengths, formats, patterns, and sets of values that the constraints allow, and
+required fields are always set. Constraints that values cannot be drawn from directly,
+such as CEL expressions, are respected by generating messages until one is valid. The
+command fails if no valid message is generated within a limited number of attempts.
+
+The messages are written as a stream to --to, delimited according to --to-framing.
+The same --seed always results in the same messages, unless the type has timestamp
+constraints relative to the current time.
+
+` + bufcli.GetSourceOrModuleLong(`the source or module that defines --type`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Type            string
+	To              string
+	ToFraming       string
+	Count           int
+	Seed            int64
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Type,
+		typeFlagName,
+		"",
+		`The full type name of the messages to generate, for example "acme.v1.Order"`,
+	)
+	_ = cobra.MarkFlagRequired(flagSet, typeFlagName)
+	flagSet.StringVar(
+		&f.To,
+		toFlagName,
+		"-",
+		fmt.Sprintf(
+			`The location to write the generated messages to. Must be one of format %s`,
+			buffetch.MessageFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&f.ToFraming,
+		toFramingFlagName,
+		"",
+		fmt.Sprintf(
+			`The framing of the generated messages. Must be one of %s. Defaults to %q for JSON and %q otherwise`,
+			stringutil.SliceToString(recordio.AllFramingStrings),
+			recordio.FramingNewline.String(),
+			recordio.FramingVarint.String(),
+		),
+	)
+	flagSet.IntVar(
+		&f.Count,
+		countFlagName,
+		1,
+		`The number of messages to generate`,
+	)
+	flagSet.Int64Var(
+		&f.Seed,
+		seedFlagName,
+		0,
+		`The seed for the random number generator. If not set, a time-based seed is used`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) (retErr error) {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Count < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must be non-negative", countFlagName)
+	}
+	toMessageRef, err := buffetch.NewMessageRefParser(
+		container.Logger(),
+		buffetch.MessageRefParserWithDefaultMessageEncoding(
+			buffetch.MessageEncodingJSON,
+		),
+	).GetMessageRef(ctx, flags.To)
+	if err != nil {
+		return fmt.Errorf("--%s: %v", toFlagName, err)
+	}
+	toFraming := recordio.FramingVarint
+	if toMessageRef.MessageEncoding() == buffetch.MessageEncodingJSON {
+		toFraming = recordio.FramingNewline
+	}
+	if flags.ToFraming != "" {
+		toFraming, err = recordio.ParseFraming(flags.ToFraming)
+		if err != nil {
+			return appcmd.NewInvalidArgumentErrorf("--%s: %v", toFramingFlagName, err)
+		}
+	}
+	if toFraming == recordio.FramingNewline && toMessageRef.MessageEncoding() != buffetch.MessageEncodingJSON {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s %s can only be used with JSON encoding",
+			toFramingFlagName,
+			recordio.FramingNewline.String(),
+		)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		true,
+	)
+	if err != nil {
+		return err
+	}
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptorProtos(image)...)
+	if err != nil {
+		return err
+	}
+	message, err := bufreflect.NewMessage(ctx, image, flags.Type)
+	if err != nil {
+		return err
+	}
+	generator, err := bufdatagen.NewGenerator(message.ProtoReflect().Descriptor())
+	if err != nil {
+		return err
+	}
+	var marshaler protoencoding.Marshaler
+	switch toMessageRef.MessageEncoding() {
+	case buffetch.MessageEncodingBinpb:
+		marshaler = protoencoding.NewWireMarshaler()
+	case buffetch.MessageEncodingJSON:
+		marshaler = protoencoding.NewJSONMarshaler(resolver)
+	case buffetch.MessageEncodingTxtpb:
+		marshaler = protoencoding.NewTxtpbMarshaler(resolver)
+	case buffetch.MessageEncodingYAML:
+		marshaler = protoencoding.NewYAMLMarshaler(resolver)
+	default:
+		return fmt.Errorf("unknown message encoding type")
+	}
+	seed := flags.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	random := rand.New(rand.NewSource(seed))
+	writeCloser, err := buffetch.NewWriter(container.Logger()).PutMessageFile(ctx, container, toMessageRef)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeCloser.Close())
+	}()
+	recordWriter, err := recordio.NewWriter(writeCloser, toFraming)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < flags.Count; i++ {
+		generated, err := generator.Generate(random)
+		if err != nil {
+			return err
+		}
+		data, err := marshaler.Marshal(generated)
+		if err != nil {
+			return err
+		}
+		if err := recordWriter.Write(data); err != nil {
+			return err
+		}
+	}
+	return recordWriter.Flush()
+}
@@ -16,7 +16,9 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/buffetch"
@@ -29,15 +31,31 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/dag"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
 )
 
 const (
 	errorFormatFlagName     = "error-format"
 	configFlagName          = "config"
 	disableSymlinksFlagName = "disable-symlinks"
+	formatFlagName          = "format"
+	levelFlagName           = "level"
+
+	formatDOT     = "dot"
+	formatMermaid = "mermaid"
+	formatJSON    = "json"
+
+	levelModule = "module"
+	levelFile   = "file"
+)
+
+var (
+	allFormats = []string{formatDOT, formatMermaid, formatJSON}
+	allLevels  = []string{levelModule, levelFile}
 )
 
 // NewCommand returns a new Command.
@@ -48,9 +66,17 @@ func NewCommand(
 	flags := newFlags()
 	return &appcmd.Command{
 		Use:   name + " <input>",
-		Short: "Print the dependency graph in DOT format",
-		Long:  bufcli.GetSourceOrModuleLong(`the source or module to print for`),
-		Args:  cobra.MaximumNArgs(1),
+		Short: "Print the dependency graph in DOT, Mermaid, or JSON format",
+		Long: `The module-level graph has a node for each module, and an edge from each module to each of its
+direct dependencies. The file-level graph has a node for each file, including imports, and an edge from
+each file to each file that it imports.
+
+The JSON format is an object with "nodes" and "edges" fields. Each node has an "id", which is
+remote/owner/repository[:commit] for modules and the path for files, and each edge has "from" and "to"
+fields with the ids of the nodes.
+
+` + bufcli.GetSourceOrModuleLong(`the source or module to print for`),
+		Args: cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
 				return run(ctx, container, flags)
@@ -63,6 +89,8 @@ func NewCommand(
 
 type flags struct {
 	ErrorFormat     string
+	Format          string
+	Level           string
 	Config          string
 	DisableSymlinks bool
 	// special
@@ -91,16 +119,46 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		"",
 		`The file or data to use to use for configuration`,
 	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		formatDOT,
+		fmt.Sprintf(
+			"The format to print the graph in. Must be one of %s",
+			stringutil.SliceToString(allFormats),
+		),
+	)
+	flagSet.StringVar(
+		&f.Level,
+		levelFlagName,
+		levelModule,
+		fmt.Sprintf(
+			"The level of the graph, either the dependencies between modules or the imports between files. Must be one of %s",
+			stringutil.SliceToString(allLevels),
+		),
+	)
 }
 
 func run(
 	ctx context.Context,
 	container appflag.Container,
 	flags *flags,
-) error {
+) (retErr error) {
 	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
 		return err
 	}
+	switch flags.Format {
+	case formatDOT, formatMermaid, formatJSON:
+	default:
+		return appcmd.NewInvalidArgumentErrorf("--%s must be one of %s", formatFlagName, stringutil.SliceToString(allFormats))
+	}
+	switch flags.Level {
+	case levelModule:
+	case levelFile:
+		return runFile(ctx, container, flags)
+	default:
+		return appcmd.NewInvalidArgumentErrorf("--%s must be one of %s", levelFlagName, stringutil.SliceToString(allLevels))
+	}
 	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
 	if err != nil {
 		return err
@@ -126,8 +184,10 @@ func run(
 	moduleConfigReader := bufwire.NewModuleConfigReader(
 		container.Logger(),
 		storageosProvider,
-		bufcli.NewFetchReader(container.Logger(), storageosProvider, runner, moduleResolver, moduleReader),
+		bufcli.NewFetchReader(container, container.Logger(), storageosProvider, runner, moduleResolver, moduleReader),
 		bufmodulebuild.NewModuleBucketBuilder(),
+		bufwire.ModuleConfigReaderWithProfile(bufcli.GetProfile(container)),
+		bufwire.ModuleConfigReaderWithExtendsResolver(bufmodule.NewExtendsResolver(moduleReader)),
 	)
 	if err != nil {
 		return err
@@ -149,6 +209,9 @@ func run(
 	if err != nil {
 		return err
 	}
+	defer func() {
+		retErr = multierr.Append(retErr, moduleConfigSet.Close())
+	}()
 	moduleConfigs := moduleConfigSet.ModuleConfigs()
 	modules := make([]bufmodule.Module, len(moduleConfigs))
 	for i, moduleConfig := range moduleConfigs {
@@ -173,14 +236,168 @@ func run(
 		}
 		return bufcli.ErrFileAnnotation
 	}
-	dotString, err := graph.DOTString(
+	return printGraph(
+		container.Stdout(),
+		flags.Format,
+		graph,
 		func(node bufgraph.Node) string {
 			return node.String()
 		},
+		func(node bufgraph.Node) interface{} {
+			return &externalModuleNode{
+				ID:         node.String(),
+				Remote:     node.Remote,
+				Owner:      node.Owner,
+				Repository: node.Repository,
+				Commit:     node.Commit,
+			}
+		},
+	)
+}
+
+func runFile(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		true,
 	)
 	if err != nil {
 		return err
 	}
-	_, err = fmt.Fprintln(container.Stdout(), dotString)
+	graph := dag.NewGraph[string]()
+	pathToExternalNode := make(map[string]*externalFileNode)
+	for _, imageFile := range image.Files() {
+		graph.AddNode(imageFile.Path())
+		externalNode := &externalFileNode{
+			ID:       imageFile.Path(),
+			IsImport: imageFile.IsImport(),
+		}
+		if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil {
+			externalNode.Module = moduleIdentity.IdentityString()
+		}
+		pathToExternalNode[imageFile.Path()] = externalNode
+	}
+	for _, imageFile := range image.Files() {
+		for _, dependency := range imageFile.FileDescriptorProto().GetDependency() {
+			graph.AddEdge(imageFile.Path(), dependency)
+		}
+	}
+	return printGraph(
+		container.Stdout(),
+		flags.Format,
+		graph,
+		func(path string) string {
+			return path
+		},
+		func(path string) interface{} {
+			if externalNode, ok := pathToExternalNode[path]; ok {
+				return externalNode
+			}
+			// Unreachable, as all dependencies are in the image.
+			return &externalFileNode{ID: path}
+		},
+	)
+}
+
+type externalModuleNode struct {
+	ID         string `json:"id"`
+	Remote     string `json:"remote"`
+	Owner      string `json:"owner"`
+	Repository string `json:"repository"`
+	Commit     string `json:"commit,omitempty"`
+}
+
+type externalFileNode struct {
+	ID       string `json:"id"`
+	Module   string `json:"module,omitempty"`
+	IsImport bool   `json:"is_import"`
+}
+
+type externalGraph struct {
+	Nodes []interface{}   `json:"nodes"`
+	Edges []*externalEdge `json:"edges"`
+}
+
+type externalEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// printGraph prints the graph in the given format.
+//
+// keyToString returns the label of a node for the DOT and Mermaid formats, and
+// the id of a node for the JSON format, which keyToExternalNode returns.
+func printGraph[Key comparable](
+	writer io.Writer,
+	format string,
+	graph *dag.Graph[Key],
+	keyToString func(Key) string,
+	keyToExternalNode func(Key) interface{},
+) error {
+	var graphString string
+	switch format {
+	case formatDOT:
+		dotString, err := graph.DOTString(keyToString)
+		if err != nil {
+			return err
+		}
+		graphString = dotString
+	case formatMermaid:
+		mermaidString, err := graph.MermaidString(keyToString)
+		if err != nil {
+			return err
+		}
+		graphString = mermaidString
+	case formatJSON:
+		externalGraph := &externalGraph{
+			Nodes: make([]interface{}, 0, graph.NumNodes()),
+			Edges: make([]*externalEdge, 0, graph.NumEdges()),
+		}
+		if err := graph.WalkNodes(
+			func(key Key, _ []Key, _ []Key) error {
+				externalGraph.Nodes = append(externalGraph.Nodes, keyToExternalNode(key))
+				return nil
+			},
+		); err != nil {
+			return err
+		}
+		if err := graph.WalkEdges(
+			func(from Key, to Key) error {
+				externalGraph.Edges = append(
+					externalGraph.Edges,
+					&externalEdge{
+						From: keyToString(from),
+						To:   keyToString(to),
+					},
+				)
+				return nil
+			},
+		); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(externalGraph, "", "  ")
+		if err != nil {
+			return err
+		}
+		graphString = string(data)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+	_, err := fmt.Fprintln(writer, graphString)
 	return err
 }
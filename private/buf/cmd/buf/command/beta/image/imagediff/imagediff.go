@@ -0,0 +1,206 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagediff
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagediff"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	excludeImportsFlagName  = "exclude-imports"
+	configFlagName          = "config"
+	againstFlagName         = "against"
+	againstConfigFlagName   = "against-config"
+	disableSymlinksFlagName = "disable-symlinks"
+	exitCodeFlagName        = "exit-code"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input> --against <against-input>",
+		Short: "Print the semantic differences between two images",
+		Long: `Compare the descriptors of the <input> location to the descriptors of the
+<against-input> location declaration by declaration, and print one line per difference:
+
+    $ buf beta image diff image.binpb --against previous.binpb
+    acme/v1/order.proto: field acme.v1.Order.note added
+    acme/v1/order.proto: field acme.v1.Order.quantity: type changed from TYPE_INT32 to TYPE_INT64
+
+The order of files and declarations, and source code info such as comments, are
+ignored, so an identical schema that was rebuilt has no differences. Declarations are
+matched by name, and custom options are compared by value.
+
+By default, the command exits with code 0 whether or not there are differences. With
+--exit-code, it exits with code 100 if there are differences, so that pipelines can
+tell a schema that actually changed from a rebuilt one:
+
+    $ buf beta image diff image.binpb --against previous.binpb --exit-code > /dev/null || echo "schema changed"
+
+` + bufcli.GetInputLong(`the source, module, or image to compare`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	ExcludeImports  bool
+	Config          string
+	Against         string
+	AgainstConfig   string
+	DisableSymlinks bool
+	ExitCode        bool
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.BoolVar(
+		&f.ExcludeImports,
+		excludeImportsFlagName,
+		false,
+		"Exclude imports from the comparison",
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The buf.yaml file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Against,
+		againstFlagName,
+		"",
+		fmt.Sprintf(
+			`Required. The source, module, or image to compare against. Must be one of format %s`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&f.AgainstConfig,
+		againstConfigFlagName,
+		"",
+		`The buf.yaml file or data to use to configure the against source, module, or image`,
+	)
+	flagSet.BoolVar(
+		&f.ExitCode,
+		exitCodeFlagName,
+		false,
+		"Exit with code 100 if there are differences",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if flags.Against == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", againstFlagName)
+	}
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		true, // source code info is not compared
+	)
+	if err != nil {
+		return err
+	}
+	againstImage, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		flags.Against,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.AgainstConfig,
+		nil,
+		nil,
+		false,
+		true, // source code info is not compared
+	)
+	if err != nil {
+		return err
+	}
+	if flags.ExcludeImports {
+		image = bufimage.ImageWithoutImports(image)
+		againstImage = bufimage.ImageWithoutImports(againstImage)
+	}
+	differences, err := bufimagediff.Diff(againstImage, image)
+	if err != nil {
+		return err
+	}
+	for _, difference := range differences {
+		if _, err := fmt.Fprintln(container.Stdout(), difference.String()); err != nil {
+			return err
+		}
+	}
+	if flags.ExitCode && len(differences) > 0 {
+		return bufcli.ErrFileAnnotation
+	}
+	return nil
+}
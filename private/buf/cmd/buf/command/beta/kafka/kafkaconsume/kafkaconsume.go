@@ -0,0 +1,301 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkaconsume
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufkafka"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/cert/certclient"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName           = "error-format"
+	configFlagName                = "config"
+	disableSymlinksFlagName       = "disable-symlinks"
+	typeFlagName                  = "type"
+	topicFlagName                 = "topic"
+	bootstrapServerFlagName       = "bootstrap-server"
+	schemaRegistryFramingFlagName = "schema-registry-framing"
+	fromBeginningFlagName         = "from-beginning"
+	maxMessagesFlagName           = "max-messages"
+	tlsFlagName                   = "tls"
+	cacertFlagName                = "cacert"
+	saslMechanismFlagName         = "sasl-mechanism"
+	saslUsernameFlagName          = "sasl-username"
+
+	saslPasswordEnvKey = "BUF_KAFKA_SASL_PASSWORD"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Consume the records of a Kafka topic and print their values as JSON",
+		Long: `The values of the records of the topic are decoded as the message given by --type,
+and each record is printed as a line of JSON with its topic, partition, offset,
+timestamp, key, and decoded value. Records that cannot be decoded are printed with
+the error instead of the value, and consuming continues.
+
+Records are consumed from all of the partitions of the topic without joining a consumer
+group, so no offsets are committed. By default, only the records that are produced after
+the command starts are printed, until the command is interrupted.
+
+    $ buf beta kafka consume --bootstrap-server localhost:9092 --topic orders --type acme.v1.Order --from-beginning
+
+If the values are framed by a schema registry serializer, set --schema-registry-framing,
+and the ID of the schema is printed with each record.
+
+To authenticate with SASL, set --sasl-mechanism and --sasl-username, and set the password
+with the ` + saslPasswordEnvKey + ` environment variable.
+
+` + bufcli.GetInputLong(`the source, module, or image to read the message type from`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFuncWithoutTimeout(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat           string
+	Config                string
+	DisableSymlinks       bool
+	Type                  string
+	Topic                 string
+	BootstrapServers      []string
+	SchemaRegistryFraming bool
+	FromBeginning         bool
+	MaxMessages           int
+	TLS                   bool
+	CACert                string
+	SASLMechanism         string
+	SASLUsername          string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Type,
+		typeFlagName,
+		"",
+		`Required. The fully-qualified name of the message of the values, such as acme.v1.Order`,
+	)
+	flagSet.StringVar(
+		&f.Topic,
+		topicFlagName,
+		"",
+		`Required. The topic to consume`,
+	)
+	flagSet.StringSliceVar(
+		&f.BootstrapServers,
+		bootstrapServerFlagName,
+		nil,
+		`Required. The host:port of a broker to connect to. May be provided multiple times`,
+	)
+	flagSet.BoolVar(
+		&f.SchemaRegistryFraming,
+		schemaRegistryFramingFlagName,
+		false,
+		`The values are framed with the header of a schema registry serializer`,
+	)
+	flagSet.BoolVar(
+		&f.FromBeginning,
+		fromBeginningFlagName,
+		false,
+		`Consume the records from the beginning of each partition, instead of only new records`,
+	)
+	flagSet.IntVar(
+		&f.MaxMessages,
+		maxMessagesFlagName,
+		0,
+		`The number of records to consume before exiting. If zero, records are consumed until the command is interrupted`,
+	)
+	flagSet.BoolVar(
+		&f.TLS,
+		tlsFlagName,
+		false,
+		`Connect to the brokers with TLS`,
+	)
+	flagSet.StringVar(
+		&f.CACert,
+		cacertFlagName,
+		"",
+		fmt.Sprintf(
+			`The path to a PEM file with the root certificates to verify the brokers with, instead of the system root certificates. Implies --%s`,
+			tlsFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.SASLMechanism,
+		saslMechanismFlagName,
+		"",
+		fmt.Sprintf(
+			`The SASL mechanism to authenticate with. Must be one of %s`,
+			stringutil.SliceToString(bufkafka.AllSASLMechanismStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.SASLUsername,
+		saslUsernameFlagName,
+		"",
+		fmt.Sprintf(
+			`The username to authenticate with. Requires --%s`,
+			saslMechanismFlagName,
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Type == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", typeFlagName)
+	}
+	if flags.Topic == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", topicFlagName)
+	}
+	if len(flags.BootstrapServers) == 0 {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", bootstrapServerFlagName)
+	}
+	if flags.MaxMessages < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must not be negative", maxMessagesFlagName)
+	}
+	consumeOptions, err := getConsumeOptions(container, flags)
+	if err != nil {
+		return err
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		true, // SourceCodeInfo is not needed to decode values
+	)
+	if err != nil {
+		return err
+	}
+	var decoderOptions []bufkafka.DecoderOption
+	if flags.SchemaRegistryFraming {
+		decoderOptions = append(decoderOptions, bufkafka.DecoderWithSchemaRegistryFraming())
+	}
+	decoder, err := bufkafka.NewDecoder(ctx, image, flags.Type, decoderOptions...)
+	if err != nil {
+		return err
+	}
+	return bufkafka.Consume(
+		ctx,
+		flags.BootstrapServers,
+		flags.Topic,
+		func(record *bufkafka.Record) error {
+			data, err := decoder.DecodeRecord(record)
+			if err != nil {
+				return err
+			}
+			_, err = container.Stdout().Write(append(data, '\n'))
+			return err
+		},
+		consumeOptions...,
+	)
+}
+
+func getConsumeOptions(container appflag.Container, flags *flags) ([]bufkafka.ConsumeOption, error) {
+	var consumeOptions []bufkafka.ConsumeOption
+	if flags.FromBeginning {
+		consumeOptions = append(consumeOptions, bufkafka.ConsumeWithFromBeginning())
+	}
+	if flags.MaxMessages > 0 {
+		consumeOptions = append(consumeOptions, bufkafka.ConsumeWithMaxRecords(flags.MaxMessages))
+	}
+	if flags.TLS || flags.CACert != "" {
+		var tlsConfig *tls.Config
+		var err error
+		if flags.CACert != "" {
+			tlsConfig, err = certclient.NewClientTLS(certclient.WithRootCertFilePaths(flags.CACert))
+		} else {
+			tlsConfig, err = certclient.NewClientTLS(certclient.WithSystemCertPool())
+		}
+		if err != nil {
+			return nil, err
+		}
+		consumeOptions = append(consumeOptions, bufkafka.ConsumeWithTLSConfig(tlsConfig))
+	}
+	if flags.SASLMechanism != "" {
+		saslMechanism, err := bufkafka.ParseSASLMechanism(flags.SASLMechanism)
+		if err != nil {
+			return nil, appcmd.NewInvalidArgumentError(err.Error())
+		}
+		consumeOptions = append(
+			consumeOptions,
+			bufkafka.ConsumeWithSASL(saslMechanism, flags.SASLUsername, container.Env(saslPasswordEnvKey)),
+		)
+	} else if flags.SASLUsername != "" {
+		return nil, appcmd.NewInvalidArgumentErrorf("--%s requires --%s to be set", saslUsernameFlagName, saslMechanismFlagName)
+	}
+	return consumeOptions, nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkadecode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufkafka"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/recordio"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName           = "error-format"
+	configFlagName                = "config"
+	disableSymlinksFlagName       = "disable-symlinks"
+	typeFlagName                  = "type"
+	schemaRegistryFramingFlagName = "schema-registry-framing"
+	framingFlagName               = "framing"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Decode the values of Kafka records from stdin and print them as JSON",
+		Long: `The values are read from stdin in the binary format, decoded as the message given by
+--type, and printed as lines of JSON. This decodes values that were saved from a topic
+by another tool, for example:
+
+    $ kcat -C -b localhost:9092 -t orders -o -1 -e -f '%s' | buf beta kafka decode --type acme.v1.Order --schema-registry-framing
+
+By default, stdin is a single value. Set --framing to read a stream of values, with the
+framing given by --framing around each value.
+
+If the values are framed by a schema registry serializer, set --schema-registry-framing
+to remove the header of the serializer before decoding.
+
+` + bufcli.GetInputLong(`the source, module, or image to read the message type from`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat           string
+	Config                string
+	DisableSymlinks       bool
+	Type                  string
+	SchemaRegistryFraming bool
+	Framing               string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Type,
+		typeFlagName,
+		"",
+		`Required. The fully-qualified name of the message of the values, such as acme.v1.Order`,
+	)
+	flagSet.BoolVar(
+		&f.SchemaRegistryFraming,
+		schemaRegistryFramingFlagName,
+		false,
+		`The values are framed with the header of a schema registry serializer`,
+	)
+	flagSet.StringVar(
+		&f.Framing,
+		framingFlagName,
+		"",
+		fmt.Sprintf(
+			`The framing of the values in a stream of values. If not set, stdin is a single value. Must be one of %s`,
+			stringutil.SliceToString([]string{recordio.FramingVarint.String(), recordio.FramingFixed32.String()}),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Type == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", typeFlagName)
+	}
+	var framing recordio.Framing
+	if flags.Framing != "" {
+		var err error
+		framing, err = recordio.ParseFraming(flags.Framing)
+		if err != nil {
+			return appcmd.NewInvalidArgumentErrorf("--%s: %v", framingFlagName, err)
+		}
+		if framing == recordio.FramingNewline {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be %s, as values are binary", framingFlagName, recordio.FramingNewline.String())
+		}
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		true, // SourceCodeInfo is not needed to decode values
+	)
+	if err != nil {
+		return err
+	}
+	var decoderOptions []bufkafka.DecoderOption
+	if flags.SchemaRegistryFraming {
+		decoderOptions = append(decoderOptions, bufkafka.DecoderWithSchemaRegistryFraming())
+	}
+	decoder, err := bufkafka.NewDecoder(ctx, image, flags.Type, decoderOptions...)
+	if err != nil {
+		return err
+	}
+	if framing == 0 {
+		value, err := io.ReadAll(container.Stdin())
+		if err != nil {
+			return err
+		}
+		return decodeValue(container, decoder, value)
+	}
+	recordReader, err := recordio.NewReader(container.Stdin(), framing)
+	if err != nil {
+		return err
+	}
+	for numRecords := 1; ; numRecords++ {
+		value, err := recordReader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("value %d: %w", numRecords, err)
+		}
+		if err := decodeValue(container, decoder, value); err != nil {
+			return fmt.Errorf("value %d: %w", numRecords, err)
+		}
+	}
+}
+
+func decodeValue(container appflag.Container, decoder bufkafka.Decoder, value []byte) error {
+	data, err := decoder.DecodeValue(value)
+	if err != nil {
+		return err
+	}
+	_, err = container.Stdout().Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,135 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/buflsp"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	againstFlagName         = "against"
+	disableSymlinksFlagName = "disable-symlinks"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name,
+		Short: "Run a language server for Protobuf files",
+		Long: `The language server speaks the Language Server Protocol over stdin and stdout, and provides
+diagnostics, go-to-definition, hover, and references for Protobuf files.
+
+Files are built when they are opened and saved. Each file is built as part of the closest
+directory that contains the file and a buf.yaml or buf.work.yaml, in the same way that buf build
+and buf lint build a directory, so that the errors and lint violations that the editor shows are
+the same as those of the CLI. If --against is set, breaking changes against the given input are
+also reported.
+
+Definitions and references in dependency modules are written to the cache directory, so that the
+editor can open them.
+
+The --timeout flag does not apply to this command.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFuncWithoutTimeout(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Against         string
+	DisableSymlinks bool
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.Against,
+		againstFlagName,
+		"",
+		fmt.Sprintf(
+			`The source, module, or image to check for breaking changes against. Must be one of format %s.
+Relative paths are relative to the directory that the server is started in.
+By default, breaking changes are not checked`,
+			buffetch.AllFormatsString,
+		),
+	)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	storageosProvider := bufcli.NewStorageosProvider(flags.DisableSymlinks)
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	imageConfigReader, err := bufcli.NewWireImageConfigReader(
+		container,
+		storageosProvider,
+		command.NewRunner(),
+		clientConfig,
+	)
+	if err != nil {
+		return err
+	}
+	moduleReader, err := bufcli.NewModuleReaderAndCreateCacheDirs(container, clientConfig)
+	if err != nil {
+		return err
+	}
+	options := []buflsp.ServerOption{
+		buflsp.ServerWithVersion(bufcli.Version),
+	}
+	if flags.Against != "" {
+		options = append(options, buflsp.ServerWithAgainst(flags.Against))
+	}
+	return buflsp.NewServer(
+		container.Logger(),
+		imageConfigReader,
+		moduleReader,
+		filepath.Join(container.CacheDirPath(), bufcli.LSPCacheDir),
+		options...,
+	).Serve(
+		ctx,
+		container,
+		container.Stdin(),
+		container.Stdout(),
+	)
+}
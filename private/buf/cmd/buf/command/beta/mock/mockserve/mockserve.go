@@ -0,0 +1,252 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mockserve
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufmock"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/transport/http/httpserver"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	overridesFlagName       = "overrides"
+	bindFlagName            = "bind"
+	portFlagName            = "port"
+	serverCertFlagName      = "server-cert"
+	serverKeyFlagName       = "server-key"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Serve mock implementations of the services of Protobuf files",
+		Long: `Every method of the services of the input is served with the Connect, gRPC, and
+gRPC-Web protocols, and responds with an example of its response message, regardless of
+the request. This gives clients working endpoints before the services are implemented.
+
+    $ buf beta mock serve proto --port 8080
+    $ buf curl --schema proto --http2-prior-knowledge http://localhost:8080/acme.v1.OrderService/GetOrder
+
+The example of a message is the first example that is declared on the message, as extracted
+by "buf beta examples extract". Otherwise, it is derived from the schema: every scalar field
+is set to a placeholder value, every repeated and map field has a single element, the first
+field of every oneof is set, and every message field is set to the example of its message.
+
+Responses can be overridden per method with --overrides, which is a YAML or JSON file of
+the form:
+
+    methods:
+      acme.v1.OrderService/GetOrder:
+        response:
+          id: "1234"
+          quantity: 3
+      acme.v1.OrderService/DeleteOrder:
+        error:
+          code: permission_denied
+          message: orders cannot be deleted
+
+Server-streaming methods send a single response, and bidirectional-streaming methods send a
+response for every request.
+
+The --timeout flag does not apply to this command.
+
+` + bufcli.GetInputLong(`the source, module, or image to serve the services of`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFuncWithoutTimeout(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Overrides       string
+	BindAddress     string
+	Port            string
+	ServerCert      string
+	ServerKey       string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Overrides,
+		overridesFlagName,
+		"",
+		`The path to a YAML or JSON file with overrides of the responses of methods`,
+	)
+	flagSet.StringVar(
+		&f.BindAddress,
+		bindFlagName,
+		"127.0.0.1",
+		"The address to be exposed to accept HTTP requests",
+	)
+	flagSet.StringVar(
+		&f.Port,
+		portFlagName,
+		"8080",
+		"The port to be exposed to accept HTTP requests",
+	)
+	flagSet.StringVar(
+		&f.ServerCert,
+		serverCertFlagName,
+		"",
+		"The cert to be used in the server TLS configuration. If not set, the server does not use TLS",
+	)
+	flagSet.StringVar(
+		&f.ServerKey,
+		serverKeyFlagName,
+		"",
+		"The key to be used in the server TLS configuration",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if (flags.ServerCert == "") != (flags.ServerKey == "") {
+		return appcmd.NewInvalidArgumentErrorf("--%s and --%s must be set together", serverCertFlagName, serverKeyFlagName)
+	}
+	var serverTLSConfig *tls.Config
+	if flags.ServerCert != "" {
+		cert, err := tls.LoadX509KeyPair(flags.ServerCert, flags.ServerKey)
+		if err != nil {
+			return fmt.Errorf("error creating x509 keypair from cert file %s and key file %s: %w", flags.ServerCert, flags.ServerKey, err)
+		}
+		serverTLSConfig = &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+	var handlerOptions []bufmock.HandlerOption
+	if flags.Overrides != "" {
+		data, err := os.ReadFile(flags.Overrides)
+		if err != nil {
+			return err
+		}
+		overrides, err := bufmock.ParseOverrides(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", flags.Overrides, err)
+		}
+		handlerOptions = append(handlerOptions, bufmock.HandlerWithOverrides(overrides))
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false, // examples are read from the source code info
+	)
+	if err != nil {
+		return err
+	}
+	handler, err := bufmock.NewHandler(image, handlerOptions...)
+	if err != nil {
+		return err
+	}
+	if len(handler.Procedures()) == 0 {
+		return fmt.Errorf("%s has no services to serve", input)
+	}
+	var httpListenConfig net.ListenConfig
+	httpListener, err := httpListenConfig.Listen(ctx, "tcp", net.JoinHostPort(flags.BindAddress, flags.Port))
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if serverTLSConfig != nil {
+		scheme = "https"
+	}
+	if _, err := fmt.Fprintf(
+		container.Stderr(),
+		"Serving %d methods of %s on %s://%s\n",
+		len(handler.Procedures()),
+		input,
+		scheme,
+		httpListener.Addr().String(),
+	); err != nil {
+		return bufcli.NewInternalError(err)
+	}
+	return httpserver.Run(
+		ctx,
+		container.Logger(),
+		httpListener,
+		handler,
+		httpserver.RunWithTLSConfig(
+			serverTLSConfig,
+		),
+	)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/protostat"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
 )
 
 const (
@@ -106,7 +107,7 @@ func run(
 	ctx context.Context,
 	container appflag.Container,
 	flags *flags,
-) error {
+) (retErr error) {
 	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
 	if err != nil {
 		return err
@@ -147,6 +148,9 @@ func run(
 	if err != nil {
 		return err
 	}
+	defer func() {
+		retErr = multierr.Append(retErr, moduleConfigSet.Close())
+	}()
 	moduleConfigs := moduleConfigSet.ModuleConfigs()
 	statsSlice := make([]*protostat.Stats, len(moduleConfigs))
 	for i, moduleConfig := range moduleConfigs {
@@ -0,0 +1,167 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printconfig
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+const (
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <source>",
+		Short: "Print the effective configuration of each module in a source or module",
+		Long: `The effective configuration of a module in a workspace is its buf.yaml,
+with the defaults from the build, breaking, and lint sections of the buf.work.yaml
+for the keys that its buf.yaml does not set.
+
+The configuration of each module is printed as a YAML document, preceded by a
+comment with the workspace directory of the module, if any.
+
+` + bufcli.GetSourceOrModuleLong(`the source or module to print the configuration for`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Config          string
+	DisableSymlinks bool
+
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The buf.yaml file or data to use for configuration`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) (retErr error) {
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	sourceOrModuleRef, err := buffetch.NewRefParser(container.Logger()).GetSourceOrModuleRef(ctx, input)
+	if err != nil {
+		return err
+	}
+	storageosProvider := bufcli.NewStorageosProvider(flags.DisableSymlinks)
+	runner := command.NewRunner()
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	moduleConfigReader, err := bufcli.NewWireModuleConfigReader(
+		container,
+		storageosProvider,
+		runner,
+		clientConfig,
+	)
+	if err != nil {
+		return err
+	}
+	moduleConfigSet, err := moduleConfigReader.GetModuleConfigSet(
+		ctx,
+		container,
+		sourceOrModuleRef,
+		flags.Config,
+		nil,
+		nil,
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, moduleConfigSet.Close())
+	}()
+	for i, moduleConfig := range moduleConfigSet.ModuleConfigs() {
+		if i > 0 {
+			if _, err := io.WriteString(container.Stdout(), "---\n"); err != nil {
+				return err
+			}
+		}
+		if err := printConfig(
+			container.Stdout(),
+			moduleConfig.Module().WorkspaceDirectory(),
+			moduleConfig.Config(),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printConfig(writer io.Writer, workspaceDirectory string, config *bufconfig.Config) error {
+	if workspaceDirectory != "" {
+		if _, err := fmt.Fprintf(writer, "# %s\n", normalpath.Unnormalize(workspaceDirectory)); err != nil {
+			return err
+		}
+	}
+	externalConfig, err := bufconfig.ExternalConfigV1ForConfig(config)
+	if err != nil {
+		return err
+	}
+	data, err := encoding.MarshalYAML(externalConfig)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
@@ -0,0 +1,213 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectserve
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufgrpcreflect"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/transport/http/httpserver"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	bindFlagName            = "bind"
+	portFlagName            = "port"
+	serverCertFlagName      = "server-cert"
+	serverKeyFlagName       = "server-key"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Serve gRPC server reflection for Protobuf files",
+		Long: `The grpc.reflection.v1.ServerReflection and grpc.reflection.v1alpha.ServerReflection
+services are served for the input, with the Connect, gRPC, and gRPC-Web protocols. This gives
+tools that need server reflection, such as grpcurl and load balancers, a server to be tested
+against without a real implementation of the services.
+
+    $ buf build -o image.binpb
+    $ buf beta reflect serve image.binpb --port 10000
+    $ grpcurl -plaintext localhost:10000 list
+
+The services of the input are listed, and every file of the input, including its imports,
+can be looked up by path, by the symbols it declares, and by the extensions it declares.
+The services themselves are not served, so use "buf beta mock serve" to call them.
+
+The --timeout flag does not apply to this command.
+
+` + bufcli.GetInputLong(`the source, module, or image to serve reflection for`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFuncWithoutTimeout(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	BindAddress     string
+	Port            string
+	ServerCert      string
+	ServerKey       string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.BindAddress,
+		bindFlagName,
+		"127.0.0.1",
+		"The address to be exposed to accept HTTP requests",
+	)
+	flagSet.StringVar(
+		&f.Port,
+		portFlagName,
+		"8080",
+		"The port to be exposed to accept HTTP requests",
+	)
+	flagSet.StringVar(
+		&f.ServerCert,
+		serverCertFlagName,
+		"",
+		"The cert to be used in the server TLS configuration. If not set, the server does not use TLS",
+	)
+	flagSet.StringVar(
+		&f.ServerKey,
+		serverKeyFlagName,
+		"",
+		"The key to be used in the server TLS configuration",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if (flags.ServerCert == "") != (flags.ServerKey == "") {
+		return appcmd.NewInvalidArgumentErrorf("--%s and --%s must be set together", serverCertFlagName, serverKeyFlagName)
+	}
+	var serverTLSConfig *tls.Config
+	if flags.ServerCert != "" {
+		cert, err := tls.LoadX509KeyPair(flags.ServerCert, flags.ServerKey)
+		if err != nil {
+			return fmt.Errorf("error creating x509 keypair from cert file %s and key file %s: %w", flags.ServerCert, flags.ServerKey, err)
+		}
+		serverTLSConfig = &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		true,
+	)
+	if err != nil {
+		return err
+	}
+	handler, err := bufgrpcreflect.NewHandler(image)
+	if err != nil {
+		return err
+	}
+	var httpListenConfig net.ListenConfig
+	httpListener, err := httpListenConfig.Listen(ctx, "tcp", net.JoinHostPort(flags.BindAddress, flags.Port))
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if serverTLSConfig != nil {
+		scheme = "https"
+	}
+	if _, err := fmt.Fprintf(
+		container.Stderr(),
+		"Serving reflection for %d services of %s on %s://%s\n",
+		len(handler.Services()),
+		input,
+		scheme,
+		httpListener.Addr().String(),
+	); err != nil {
+		return bufcli.NewInternalError(err)
+	}
+	return httpserver.Run(
+		ctx,
+		container.Logger(),
+		httpListener,
+		handler,
+		httpserver.RunWithTLSConfig(
+			serverTLSConfig,
+		),
+	)
+}
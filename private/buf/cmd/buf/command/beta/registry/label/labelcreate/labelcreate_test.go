@@ -0,0 +1,98 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labelcreate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateLabel(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	service := &mockLabelService{labels: map[string]string{}}
+	labelName := &registryv1alpha1.LabelName{
+		Namespace: registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_TAG,
+		Name:      "v1.0.0",
+	}
+	label, err := createLabel(ctx, service, labelName, "commit1")
+	require.NoError(t, err)
+	assert.Equal(t, labelName, label.LabelName)
+	assert.Equal(t, "commit1", label.LabelValue.CommitId)
+	assert.Equal(t, map[string]string{"v1.0.0": "commit1"}, service.labels)
+	_, err = createLabel(ctx, service, labelName, "commit2")
+	assert.ErrorContains(t, err, `label "v1.0.0" already exists`)
+	assert.Equal(t, map[string]string{"v1.0.0": "commit1"}, service.labels)
+	assert.Equal(t, 0, service.moveLabelCalls)
+}
+
+type mockLabelService struct {
+	labels           map[string]string
+	createLabelCalls int
+	moveLabelCalls   int
+}
+
+var _ registryv1alpha1connect.LabelServiceClient = (*mockLabelService)(nil)
+
+func (m *mockLabelService) CreateLabel(
+	_ context.Context,
+	req *connect.Request[registryv1alpha1.CreateLabelRequest],
+) (*connect.Response[registryv1alpha1.CreateLabelResponse], error) {
+	m.createLabelCalls++
+	name := req.Msg.LabelName.Name
+	if _, ok := m.labels[name]; ok {
+		return nil, connect.NewError(connect.CodeAlreadyExists, errors.New("label already exists"))
+	}
+	m.labels[name] = req.Msg.LabelValue.CommitId
+	return connect.NewResponse(&registryv1alpha1.CreateLabelResponse{CommitId: req.Msg.LabelValue}), nil
+}
+
+func (m *mockLabelService) MoveLabel(
+	_ context.Context,
+	req *connect.Request[registryv1alpha1.MoveLabelRequest],
+) (*connect.Response[registryv1alpha1.MoveLabelResponse], error) {
+	m.moveLabelCalls++
+	name := req.Msg.LabelName.Name
+	commitID, ok := m.labels[name]
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("label not found"))
+	}
+	if req.Msg.From != nil && req.Msg.From.CommitId != commitID {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("label moved"))
+	}
+	m.labels[name] = req.Msg.To.CommitId
+	return connect.NewResponse(&registryv1alpha1.MoveLabelResponse{}), nil
+}
+
+func (m *mockLabelService) GetLabels(
+	context.Context,
+	*connect.Request[registryv1alpha1.GetLabelsRequest],
+) (*connect.Response[registryv1alpha1.GetLabelsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, nil)
+}
+
+func (m *mockLabelService) GetLabelsInNamespace(
+	context.Context,
+	*connect.Request[registryv1alpha1.GetLabelsInNamespaceRequest],
+) (*connect.Response[registryv1alpha1.GetLabelsInNamespaceResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, nil)
+}
@@ -0,0 +1,152 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labellist
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	namespaceFlagName = "namespace"
+	nameFlagName      = "name"
+	formatFlagName    = "format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <buf.build/owner/repository>",
+		Short: "List the labels of a BSR repository",
+		Long:  "Labels are the tags, branches, Git commits, and other names that point to commits of a repository.",
+		Args:  cobra.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Namespace string
+	Names     []string
+	Format    string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindLabelNamespace(flagSet, &f.Namespace, namespaceFlagName)
+	flagSet.StringSliceVar(
+		&f.Names,
+		nameFlagName,
+		nil,
+		fmt.Sprintf(`Only list the labels with these names. Requires --%s`, namespaceFlagName),
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	bufcli.WarnBetaCommand(ctx, container)
+	moduleIdentity, err := bufmoduleref.ModuleIdentityForString(container.Arg(0))
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	if len(flags.Names) > 0 && flags.Namespace == "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s requires --%s", nameFlagName, namespaceFlagName)
+	}
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	service := connectclient.Make(
+		clientConfig,
+		moduleIdentity.Remote(),
+		registryv1alpha1connect.NewLabelServiceClient,
+	)
+	var labels []*registryv1alpha1.Label
+	if flags.Namespace == "" {
+		resp, err := service.GetLabels(
+			ctx,
+			connect.NewRequest(&registryv1alpha1.GetLabelsRequest{
+				RepositoryOwner: moduleIdentity.Owner(),
+				RepositoryName:  moduleIdentity.Repository(),
+			}),
+		)
+		if err != nil {
+			if connect.CodeOf(err) == connect.CodeNotFound {
+				return bufcli.NewRepositoryNotFoundError(moduleIdentity.IdentityString())
+			}
+			return err
+		}
+		labels = resp.Msg.Labels
+	} else {
+		namespace, err := bufcli.LabelNamespaceFlagToLabelNamespace(flags.Namespace)
+		if err != nil {
+			return appcmd.NewInvalidArgumentError(err.Error())
+		}
+		resp, err := service.GetLabelsInNamespace(
+			ctx,
+			connect.NewRequest(&registryv1alpha1.GetLabelsInNamespaceRequest{
+				RepositoryOwner: moduleIdentity.Owner(),
+				RepositoryName:  moduleIdentity.Repository(),
+				LabelNamespace:  namespace,
+				LabelNames:      flags.Names,
+			}),
+		)
+		if err != nil {
+			if connect.CodeOf(err) == connect.CodeNotFound {
+				return bufcli.NewRepositoryNotFoundError(moduleIdentity.IdentityString())
+			}
+			return err
+		}
+		labels = resp.Msg.Labels
+	}
+	return bufprint.NewLabelPrinter(container.Stdout()).PrintLabels(ctx, format, labels...)
+}
@@ -0,0 +1,186 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labelmove
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	namespaceFlagName = "namespace"
+	fromFlagName      = "from"
+	formatFlagName    = "format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <buf.build/owner/repository:reference> <label>",
+		Short: "Move a label to the commit of a reference",
+		Long: `The reference can be a commit, tag, branch, or draft. The label must already exist, use "buf beta registry label create" to create a label.
+
+If --from is set, the label is only moved if it still points at the commit of that reference, which guards against concurrent moves.`,
+		Args: cobra.ExactArgs(2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Namespace string
+	From      string
+	Format    string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindLabelNamespaceWithDefault(flagSet, &f.Namespace, namespaceFlagName)
+	flagSet.StringVar(
+		&f.From,
+		fromFlagName,
+		"",
+		"The commit, tag, branch, or draft of the repository that the label must point at to be moved",
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	bufcli.WarnBetaCommand(ctx, container)
+	moduleReference, err := bufmoduleref.ModuleReferenceForString(container.Arg(0))
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	var fromModuleReference bufmoduleref.ModuleReference
+	if flags.From != "" {
+		fromModuleReference, err = bufmoduleref.NewModuleReference(
+			moduleReference.Remote(),
+			moduleReference.Owner(),
+			moduleReference.Repository(),
+			flags.From,
+		)
+		if err != nil {
+			return appcmd.NewInvalidArgumentErrorf("--%s: %v", fromFlagName, err)
+		}
+	}
+	namespace, err := bufcli.LabelNamespaceFlagToLabelNamespace(flags.Namespace)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	commitID, err := bufcli.GetCommitIDForModuleReference(ctx, clientConfig, moduleReference)
+	if err != nil {
+		return err
+	}
+	var fromCommitID string
+	if fromModuleReference != nil {
+		fromCommitID, err = bufcli.GetCommitIDForModuleReference(ctx, clientConfig, fromModuleReference)
+		if err != nil {
+			return err
+		}
+	}
+	service := connectclient.Make(
+		clientConfig,
+		moduleReference.Remote(),
+		registryv1alpha1connect.NewLabelServiceClient,
+	)
+	label, err := moveLabel(
+		ctx,
+		service,
+		&registryv1alpha1.LabelName{
+			Namespace: namespace,
+			Name:      container.Arg(1),
+		},
+		fromCommitID,
+		commitID,
+	)
+	if err != nil {
+		return err
+	}
+	return bufprint.NewLabelPrinter(container.Stdout()).PrintLabels(ctx, format, label)
+}
+
+// moveLabel moves the label to the commit. If fromCommitID is not empty, the
+// label is only moved if it points at that commit.
+func moveLabel(
+	ctx context.Context,
+	service registryv1alpha1connect.LabelServiceClient,
+	labelName *registryv1alpha1.LabelName,
+	fromCommitID string,
+	commitID string,
+) (*registryv1alpha1.Label, error) {
+	request := &registryv1alpha1.MoveLabelRequest{
+		LabelName: labelName,
+		To:        &registryv1alpha1.LabelValue{CommitId: commitID},
+	}
+	if fromCommitID != "" {
+		request.From = &registryv1alpha1.LabelValue{CommitId: fromCommitID}
+	}
+	if _, err := service.MoveLabel(ctx, connect.NewRequest(request)); err != nil {
+		switch connect.CodeOf(err) {
+		case connect.CodeNotFound:
+			return nil, fmt.Errorf(`label %q does not exist, use "buf beta registry label create" to create it`, labelName.Name)
+		case connect.CodeFailedPrecondition, connect.CodeAborted:
+			if fromCommitID != "" {
+				return nil, fmt.Errorf("label %q does not point at commit %s: %w", labelName.Name, fromCommitID, err)
+			}
+		}
+		return nil, err
+	}
+	return &registryv1alpha1.Label{
+		LabelName:  labelName,
+		LabelValue: request.To,
+	}, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labelpoint
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	namespaceFlagName = "namespace"
+	formatFlagName    = "format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <buf.build/owner/repository:reference> <label>",
+		Short: "Point a label at the commit of a reference",
+		Long: `The reference can be a commit, tag, branch, or draft. The label is created if it does not exist, and moved to the commit otherwise.
+
+Pointing a label at the commit of a draft promotes the commit.`,
+		Args: cobra.ExactArgs(2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Namespace string
+	Format    string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindLabelNamespaceWithDefault(flagSet, &f.Namespace, namespaceFlagName)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	bufcli.WarnBetaCommand(ctx, container)
+	moduleReference, err := bufmoduleref.ModuleReferenceForString(container.Arg(0))
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	namespace, err := bufcli.LabelNamespaceFlagToLabelNamespace(flags.Namespace)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	commitID, err := bufcli.GetCommitIDForModuleReference(ctx, clientConfig, moduleReference)
+	if err != nil {
+		return err
+	}
+	service := connectclient.Make(
+		clientConfig,
+		moduleReference.Remote(),
+		registryv1alpha1connect.NewLabelServiceClient,
+	)
+	label, err := pointLabel(
+		ctx,
+		service,
+		&registryv1alpha1.LabelName{
+			Namespace: namespace,
+			Name:      container.Arg(1),
+		},
+		commitID,
+	)
+	if err != nil {
+		return err
+	}
+	return bufprint.NewLabelPrinter(container.Stdout()).PrintLabels(ctx, format, label)
+}
+
+// pointLabel creates the label for the commit, or moves the label to the
+// commit if it already exists.
+func pointLabel(
+	ctx context.Context,
+	service registryv1alpha1connect.LabelServiceClient,
+	labelName *registryv1alpha1.LabelName,
+	commitID string,
+) (*registryv1alpha1.Label, error) {
+	labelValue := &registryv1alpha1.LabelValue{CommitId: commitID}
+	if _, err := service.CreateLabel(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.CreateLabelRequest{
+			LabelName:  labelName,
+			LabelValue: labelValue,
+		}),
+	); err != nil {
+		if connect.CodeOf(err) != connect.CodeAlreadyExists {
+			return nil, err
+		}
+		if _, err := service.MoveLabel(
+			ctx,
+			connect.NewRequest(&registryv1alpha1.MoveLabelRequest{
+				LabelName: labelName,
+				To:        labelValue,
+			}),
+		); err != nil {
+			return nil, err
+		}
+	}
+	return &registryv1alpha1.Label{
+		LabelName:  labelName,
+		LabelValue: labelValue,
+	}, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package organizationmemberset
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	roleFlagName = "role"
+)
+
+// NewCommand returns a new Command
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <buf.build/organization> <username>",
+		Short: "Set the role of a member of a BSR organization",
+		Long:  "The user is added to the organization if they are not already a member.",
+		Args:  cobra.ExactArgs(2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Role string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindOrganizationRole(flagSet, &f.Role, roleFlagName)
+	_ = cobra.MarkFlagRequired(flagSet, roleFlagName)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	bufcli.WarnBetaCommand(ctx, container)
+	moduleOwner, err := bufmoduleref.ModuleOwnerForString(container.Arg(0))
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	username := container.Arg(1)
+	role, err := bufcli.OrganizationRoleFlagToOrganizationRole(flags.Role)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	organizationID, err := bufcli.GetOrganizationIDForName(ctx, clientConfig, moduleOwner.Remote(), moduleOwner.Owner())
+	if err != nil {
+		return err
+	}
+	userID, err := bufcli.GetUserIDForUsername(ctx, clientConfig, moduleOwner.Remote(), username)
+	if err != nil {
+		return err
+	}
+	service := connectclient.Make(
+		clientConfig,
+		moduleOwner.Remote(),
+		registryv1alpha1connect.NewOrganizationServiceClient,
+	)
+	if _, err := service.SetOrganizationMember(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.SetOrganizationMemberRequest{
+			OrganizationId:   organizationID,
+			UserId:           userID,
+			OrganizationRole: role,
+		}),
+	); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(container.Stdout(), "Member role set."); err != nil {
+		return bufcli.NewInternalError(err)
+	}
+	return nil
+}
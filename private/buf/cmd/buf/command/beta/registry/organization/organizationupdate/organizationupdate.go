@@ -0,0 +1,150 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package organizationupdate
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	descriptionFlagName = "description"
+	urlFlagName         = "url"
+	formatFlagName      = "format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <buf.build/organization>",
+		Short: "Update BSR organization settings",
+		Long:  "Only the settings for the flags that are set are updated.",
+		Args:  cobra.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Description string
+	URL         string
+	Format      string
+
+	// special
+	flagSet *pflag.FlagSet
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	f.flagSet = flagSet
+	flagSet.StringVar(
+		&f.Description,
+		descriptionFlagName,
+		"",
+		"The description of the organization",
+	)
+	flagSet.StringVar(
+		&f.URL,
+		urlFlagName,
+		"",
+		"The URL in the description of the organization",
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	bufcli.WarnBetaCommand(ctx, container)
+	moduleOwner, err := bufmoduleref.ModuleOwnerForString(container.Arg(0))
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	request := &registryv1alpha1.UpdateOrganizationSettingsRequest{}
+	if flags.flagSet.Changed(descriptionFlagName) {
+		request.Description = &flags.Description
+	}
+	if flags.flagSet.Changed(urlFlagName) {
+		request.Url = &flags.URL
+	}
+	if request.Description == nil && request.Url == nil {
+		return appcmd.NewInvalidArgumentErrorf("at least one of --%s or --%s must be set", descriptionFlagName, urlFlagName)
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	organizationID, err := bufcli.GetOrganizationIDForName(ctx, clientConfig, moduleOwner.Remote(), moduleOwner.Owner())
+	if err != nil {
+		return err
+	}
+	request.OrganizationId = organizationID
+	service := connectclient.Make(
+		clientConfig,
+		moduleOwner.Remote(),
+		registryv1alpha1connect.NewOrganizationServiceClient,
+	)
+	if _, err := service.UpdateOrganizationSettings(ctx, connect.NewRequest(request)); err != nil {
+		return err
+	}
+	resp, err := service.GetOrganization(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetOrganizationRequest{
+			Id: organizationID,
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	return bufprint.NewOrganizationPrinter(
+		moduleOwner.Remote(),
+		container.Stdout(),
+	).PrintOrganization(ctx, format, resp.Msg.Organization)
+}
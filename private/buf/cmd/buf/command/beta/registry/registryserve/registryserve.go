@@ -0,0 +1,171 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registryserve
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/buflocalregistry"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/transport/http/httpserver"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	dirFlagName        = "dir"
+	bindFlagName       = "bind"
+	portFlagName       = "port"
+	serverCertFlagName = "server-cert"
+	serverKeyFlagName  = "server-key"
+
+	disableSymlinksFlagName = "disable-symlinks"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name,
+		Short: "Serve modules from a local directory with the BSR module APIs",
+		Long: `The modules are in the owner/repository subdirectories of the directory, such as acme/weather for the buf.build/acme/weather module, and each has a buf.yaml file.
+
+The APIs that are used to resolve and download modules, such as by "buf mod update" and "buf build", are served, so dependencies can be resolved without the BSR, such as in integration tests and air-gapped environments. Each module has a single commit, and all labels resolve to it. Modules are matched by their owner and repository, so the same modules are served for any remote.
+
+To use the server for a remote, add its address to the mirrors of the remote in the buf configuration. If the server does not use TLS, also set "use: false" in the remote_tls of the remote. The modules are read when the server starts.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFuncWithoutTimeout(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Dir         string
+	BindAddress string
+	Port        string
+	ServerCert  string
+	ServerKey   string
+
+	DisableSymlinks bool
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.Dir,
+		dirFlagName,
+		".",
+		"The directory that contains the modules",
+	)
+	flagSet.StringVar(
+		&f.BindAddress,
+		bindFlagName,
+		"127.0.0.1",
+		"The address to be exposed to accept HTTP requests",
+	)
+	flagSet.StringVar(
+		&f.Port,
+		portFlagName,
+		"8080",
+		"The port to be exposed to accept HTTP requests",
+	)
+	flagSet.StringVar(
+		&f.ServerCert,
+		serverCertFlagName,
+		"",
+		"The cert to be used in the server TLS configuration. If not set, the server does not use TLS",
+	)
+	flagSet.StringVar(
+		&f.ServerKey,
+		serverKeyFlagName,
+		"",
+		"The key to be used in the server TLS configuration",
+	)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	bufcli.WarnBetaCommand(ctx, container)
+	if (flags.ServerCert == "") != (flags.ServerKey == "") {
+		return appcmd.NewInvalidArgumentErrorf("--%s and --%s must be set together", serverCertFlagName, serverKeyFlagName)
+	}
+	var serverTLSConfig *tls.Config
+	if flags.ServerCert != "" {
+		cert, err := tls.LoadX509KeyPair(flags.ServerCert, flags.ServerKey)
+		if err != nil {
+			return fmt.Errorf("error creating x509 keypair from cert file %s and key file %s: %w", flags.ServerCert, flags.ServerKey, err)
+		}
+		serverTLSConfig = &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+	readWriteBucket, err := bufcli.NewStorageosProvider(flags.DisableSymlinks).NewReadWriteBucket(flags.Dir)
+	if err != nil {
+		return err
+	}
+	registry, err := buflocalregistry.NewRegistry(ctx, container.Logger(), readWriteBucket)
+	if err != nil {
+		return err
+	}
+	var httpListenConfig net.ListenConfig
+	httpListener, err := httpListenConfig.Listen(ctx, "tcp", net.JoinHostPort(flags.BindAddress, flags.Port))
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if serverTLSConfig != nil {
+		scheme = "https"
+	}
+	if _, err := fmt.Fprintf(
+		container.Stderr(),
+		"Serving %d modules from %s on %s://%s\n",
+		len(registry.ModuleFullNames()),
+		flags.Dir,
+		scheme,
+		httpListener.Addr().String(),
+	); err != nil {
+		return bufcli.NewInternalError(err)
+	}
+	return httpserver.Run(
+		ctx,
+		container.Logger(),
+		httpListener,
+		buflocalregistry.NewHandler(registry),
+		httpserver.RunWithTLSConfig(
+			serverTLSConfig,
+		),
+	)
+}
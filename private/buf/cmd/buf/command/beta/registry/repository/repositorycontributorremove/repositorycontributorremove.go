@@ -0,0 +1,93 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repositorycontributorremove
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/spf13/cobra"
+)
+
+// NewCommand returns a new Command
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   name + " <buf.build/owner/repository> <username>",
+		Short: "Remove the role of a contributor to a BSR repository",
+		Long:  "The contributor keeps any implicit role from their membership in the organization that owns the repository.",
+		Args:  cobra.ExactArgs(2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+	}
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+) error {
+	bufcli.WarnBetaCommand(ctx, container)
+	moduleIdentity, err := bufmoduleref.ModuleIdentityForString(container.Arg(0))
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	username := container.Arg(1)
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	repositoryID, err := bufcli.GetRepositoryIDForModuleIdentity(ctx, clientConfig, moduleIdentity)
+	if err != nil {
+		return err
+	}
+	userID, err := bufcli.GetUserIDForUsername(ctx, clientConfig, moduleIdentity.Remote(), username)
+	if err != nil {
+		return err
+	}
+	service := connectclient.Make(
+		clientConfig,
+		moduleIdentity.Remote(),
+		registryv1alpha1connect.NewRepositoryServiceClient,
+	)
+	if _, err := service.SetRepositoryContributor(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.SetRepositoryContributorRequest{
+			RepositoryId: repositoryID,
+			UserId:       userID,
+			// Setting the unspecified role removes the role of the contributor.
+			RepositoryRole: registryv1alpha1.RepositoryRole_REPOSITORY_ROLE_UNSPECIFIED,
+		}),
+	); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(container.Stdout(), "Contributor removed."); err != nil {
+		return bufcli.NewInternalError(err)
+	}
+	return nil
+}
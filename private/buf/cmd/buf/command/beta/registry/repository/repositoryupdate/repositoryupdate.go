@@ -31,7 +31,10 @@ import (
 )
 
 const (
-	visibilityFlagName = "visibility"
+	visibilityFlagName    = "visibility"
+	descriptionFlagName   = "description"
+	urlFlagName           = "url"
+	defaultBranchFlagName = "default-branch"
 )
 
 // NewCommand returns a new Command
@@ -40,6 +43,7 @@ func NewCommand(name string, builder appflag.Builder) *appcmd.Command {
 	return &appcmd.Command{
 		Use:   name + " <buf.build/owner/repository>",
 		Short: "Update BSR repository settings",
+		Long:  "Only the settings for the flags that are set are updated.",
 		Args:  cobra.ExactArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
@@ -51,9 +55,14 @@ func NewCommand(name string, builder appflag.Builder) *appcmd.Command {
 	}
 }
 
-// TODO: add Description and Url field if it's desired to udpate them from the CLI
 type flags struct {
-	Visibility string
+	Visibility    string
+	Description   string
+	URL           string
+	DefaultBranch string
+
+	// special
+	flagSet *pflag.FlagSet
 }
 
 func newFlags() *flags {
@@ -61,7 +70,26 @@ func newFlags() *flags {
 }
 
 func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	f.flagSet = flagSet
 	bufcli.BindVisibility(flagSet, &f.Visibility, visibilityFlagName)
+	flagSet.StringVar(
+		&f.Description,
+		descriptionFlagName,
+		"",
+		"The description of the repository",
+	)
+	flagSet.StringVar(
+		&f.URL,
+		urlFlagName,
+		"",
+		"The URL in the description of the repository",
+	)
+	flagSet.StringVar(
+		&f.DefaultBranch,
+		defaultBranchFlagName,
+		"",
+		"The default branch of the repository",
+	)
 }
 
 func run(
@@ -78,6 +106,20 @@ func run(
 	if err != nil {
 		return appcmd.NewInvalidArgumentError(err.Error())
 	}
+	request := &registryv1alpha1.UpdateRepositorySettingsByNameRequest{
+		OwnerName:      moduleIdentity.Owner(),
+		RepositoryName: moduleIdentity.Repository(),
+		Visibility:     visibility,
+	}
+	if flags.flagSet.Changed(descriptionFlagName) {
+		request.Description = &flags.Description
+	}
+	if flags.flagSet.Changed(urlFlagName) {
+		request.Url = &flags.URL
+	}
+	if flags.flagSet.Changed(defaultBranchFlagName) {
+		request.DefaultBranch = &flags.DefaultBranch
+	}
 	clientConfig, err := bufcli.NewConnectClientConfig(container)
 	if err != nil {
 		return err
@@ -87,15 +129,7 @@ func run(
 		moduleIdentity.Remote(),
 		registryv1alpha1connect.NewRepositoryServiceClient,
 	)
-	if _, err := service.UpdateRepositorySettingsByName(
-		ctx,
-		connect.NewRequest(&registryv1alpha1.UpdateRepositorySettingsByNameRequest{
-			OwnerName:      moduleIdentity.Owner(),
-			RepositoryName: moduleIdentity.Repository(),
-			Visibility:     visibility,
-			// TODO: pass description and url
-		}),
-	); err != nil {
+	if _, err := service.UpdateRepositorySettingsByName(ctx, connect.NewRequest(request)); err != nil {
 		if connect.CodeOf(err) == connect.CodeNotFound {
 			return bufcli.NewRepositoryNotFoundError(container.Arg(0))
 		}
@@ -0,0 +1,222 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagesanitize"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	asFileDescriptorSetFlagName = "as-file-descriptor-set"
+	errorFormatFlagName         = "error-format"
+	excludeImportsFlagName      = "exclude-imports"
+	outputFlagName              = "output"
+	outputFlagShortName         = "o"
+	configFlagName              = "config"
+	disableSymlinksFlagName     = "disable-symlinks"
+	stripOptionFlagName         = "strip-option"
+	removeAnnotatedFlagName     = "remove-annotated"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input> --output <output>",
+		Short: "Build an image with internal details removed for external publication",
+		Long: `Build an image of the <input> location with its internal details removed, so that
+the schema can be shared outside of an organization, for example with partners:
+
+    $ buf beta sanitize \
+        --strip-option acme.owner \
+        --remove-annotated acme.internal \
+        --remove-annotated acme.visibility=VISIBILITY_INTERNAL \
+        --output public.binpb
+
+The image does not contain any source code info, so all comments are removed.
+
+Custom options provided with --strip-option are stripped from all declarations.
+
+Declarations annotated with a custom option provided with --remove-annotated are
+removed, along with the declarations nested in them. A custom option provided
+without a value removes the declarations with the option set to true, such as
+(acme.internal) = true. Files are removed if their file options are annotated.
+
+Imports that are no longer used are removed. The custom options must be declared
+in the image, so that a typo does not result in internal details being published.
+
+If a declaration that is kept refers to a declaration that is removed, such as a
+field with a removed message type, the references are printed to stderr, no image
+is written, and the command exits with code 100.
+
+` + bufcli.GetInputLong(`the source, module, or image to sanitize`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	AsFileDescriptorSet bool
+	ErrorFormat         string
+	ExcludeImports      bool
+	Output              string
+	Config              string
+	DisableSymlinks     bool
+	StripOptions        []string
+	RemoveAnnotated     []string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindAsFileDescriptorSet(flagSet, &f.AsFileDescriptorSet, asFileDescriptorSetFlagName)
+	bufcli.BindExcludeImports(flagSet, &f.ExcludeImports, excludeImportsFlagName)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"",
+		fmt.Sprintf(
+			`Required. The output location for the sanitized image. Must be one of format %s`,
+			buffetch.MessageFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The buf.yaml file or data to use for configuration`,
+	)
+	flagSet.StringSliceVar(
+		&f.StripOptions,
+		stripOptionFlagName,
+		nil,
+		`The full name of a custom option to strip from all declarations, such as "acme.owner". May be provided multiple times`,
+	)
+	flagSet.StringSliceVar(
+		&f.RemoveAnnotated,
+		removeAnnotatedFlagName,
+		nil,
+		`The full name of a custom option that removes the declarations it is set to true on, such as "acme.internal", optionally followed by "=" and another value to match instead, such as "acme.visibility=VISIBILITY_INTERNAL". May be provided multiple times`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if flags.Output == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", outputFlagName)
+	}
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	var sanitizeOptions []bufimagesanitize.SanitizeOption
+	for _, stripOption := range flags.StripOptions {
+		sanitizeOptions = append(sanitizeOptions, bufimagesanitize.SanitizeWithStrippedOption(stripOption))
+	}
+	for _, removeAnnotated := range flags.RemoveAnnotated {
+		optionName, value, ok := strings.Cut(removeAnnotated, "=")
+		if !ok {
+			value = "true"
+		}
+		if optionName == "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s: option name is empty in %q", removeAnnotatedFlagName, removeAnnotated)
+		}
+		sanitizeOptions = append(sanitizeOptions, bufimagesanitize.SanitizeWithRemovedDeclarations(optionName, value))
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		true, // source code info is removed
+	)
+	if err != nil {
+		return err
+	}
+	messageRef, err := buffetch.NewMessageRefParser(container.Logger()).GetMessageRef(ctx, flags.Output)
+	if err != nil {
+		return fmt.Errorf("--%s: %v", outputFlagName, err)
+	}
+	sanitizedImage, danglingReferences, err := bufimagesanitize.Sanitize(image, sanitizeOptions...)
+	if err != nil {
+		return err
+	}
+	if len(danglingReferences) > 0 {
+		for _, danglingReference := range danglingReferences {
+			if _, err := fmt.Fprintln(container.Stderr(), danglingReference.String()); err != nil {
+				return err
+			}
+		}
+		return bufcli.ErrFileAnnotation
+	}
+	return bufcli.NewWireImageWriter(
+		container.Logger(),
+	).PutImage(
+		ctx,
+		container,
+		messageRef,
+		sanitizedImage,
+		flags.AsFileDescriptorSet,
+		flags.ExcludeImports,
+	)
+}
@@ -0,0 +1,340 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufgen"
+	"github.com/bufbuild/buf/private/buf/bufwire"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufplugin/bufpluginref"
+	"github.com/bufbuild/buf/private/bufpkg/bufremoteplugin"
+	"github.com/bufbuild/buf/private/bufpkg/bufsbom"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+const (
+	formatFlagName          = "format"
+	templateFlagName        = "template"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Print a software bill of materials (SBOM) of the dependencies of a source or module",
+		Long: `The SBOM lists the module dependencies of the input, with their commits, digests, and licenses
+if the modules have a LICENSE file, and the remote plugins of the generation template, with their versions.
+
+The module dependencies are read from the buf.lock files of the input, so run "buf mod update" first if
+they are out of date. Modules that are provided by the workspace are not listed.
+
+The generation template is read from --template, or from buf.gen.yaml in the current directory if it exists.
+
+` + bufcli.GetSourceOrModuleLong(`the source or module to print the SBOM for`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Format          string
+	Template        string
+	Config          string
+	DisableSymlinks bool
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufsbom.FormatSPDX.String(),
+		fmt.Sprintf(`The SBOM format to use. Must be one of %s`, bufsbom.AllFormatsString),
+	)
+	flagSet.StringVar(
+		&f.Template,
+		templateFlagName,
+		"",
+		`The generation template file or data to read remote plugins from`,
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use to use for configuration`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) (retErr error) {
+	format, err := bufsbom.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	sourceOrModuleRef, err := buffetch.NewRefParser(container.Logger()).GetSourceOrModuleRef(ctx, input)
+	if err != nil {
+		return err
+	}
+	storageosProvider := bufcli.NewStorageosProvider(flags.DisableSymlinks)
+	runner := command.NewRunner()
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	moduleReader, err := bufcli.NewModuleReaderAndCreateCacheDirs(container, clientConfig)
+	if err != nil {
+		return err
+	}
+	moduleConfigReader, err := bufcli.NewWireModuleConfigReaderForModuleReader(
+		container,
+		storageosProvider,
+		runner,
+		clientConfig,
+		moduleReader,
+	)
+	if err != nil {
+		return err
+	}
+	moduleConfigSet, err := moduleConfigReader.GetModuleConfigSet(
+		ctx,
+		container,
+		sourceOrModuleRef,
+		flags.Config,
+		nil,
+		nil,
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, moduleConfigSet.Close())
+	}()
+	moduleComponents, err := getModuleComponents(ctx, moduleReader, moduleConfigSet.ModuleConfigs(), moduleConfigSet.Workspace())
+	if err != nil {
+		return err
+	}
+	pluginComponents, err := getPluginComponents(ctx, container, storageosProvider, flags.Template)
+	if err != nil {
+		return err
+	}
+	return bufsbom.Write(
+		container.Stdout(),
+		format,
+		&bufsbom.Document{
+			Name:       input,
+			Created:    time.Now(),
+			Components: append(moduleComponents, pluginComponents...),
+		},
+	)
+}
+
+// getModuleComponents returns the Components for the dependencies of the
+// modules that are not provided by the workspace, sorted by name.
+func getModuleComponents(
+	ctx context.Context,
+	moduleReader bufmodule.ModuleReader,
+	moduleConfigs []bufwire.ModuleConfig,
+	workspace bufmodule.Workspace,
+) ([]*bufsbom.Component, error) {
+	identityToModulePin := make(map[string]bufmoduleref.ModulePin)
+	for _, moduleConfig := range moduleConfigs {
+		for _, modulePin := range moduleConfig.Module().DependencyModulePins() {
+			if workspace != nil {
+				if _, ok := workspace.GetModule(modulePin); ok {
+					continue
+				}
+			}
+			identityString := modulePin.IdentityString()
+			if existingModulePin, ok := identityToModulePin[identityString]; ok && existingModulePin.Commit() != modulePin.Commit() {
+				return nil, fmt.Errorf(
+					"modules in the workspace depend on different commits of %s: %s and %s",
+					identityString,
+					existingModulePin.Commit(),
+					modulePin.Commit(),
+				)
+			}
+			identityToModulePin[identityString] = modulePin
+		}
+	}
+	modulePins := make([]bufmoduleref.ModulePin, 0, len(identityToModulePin))
+	for _, modulePin := range identityToModulePin {
+		modulePins = append(modulePins, modulePin)
+	}
+	sort.Slice(
+		modulePins,
+		func(i int, j int) bool {
+			return modulePins[i].IdentityString() < modulePins[j].IdentityString()
+		},
+	)
+	components := make([]*bufsbom.Component, len(modulePins))
+	jobs := make([]func(context.Context) error, len(modulePins))
+	for i, modulePin := range modulePins {
+		i := i
+		modulePin := modulePin
+		jobs[i] = func(ctx context.Context) error {
+			// The module is read for its license.
+			module, err := moduleReader.GetModule(ctx, modulePin)
+			if err != nil {
+				return err
+			}
+			components[i] = &bufsbom.Component{
+				Type:    bufsbom.ComponentTypeModule,
+				Name:    modulePin.IdentityString(),
+				Version: modulePin.Commit(),
+				Digest:  modulePin.Digest(),
+				License: module.License(),
+			}
+			return nil
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := thread.Parallelize(ctx, jobs, thread.ParallelizeWithCancel(cancel)); err != nil {
+		return nil, err
+	}
+	return components, nil
+}
+
+// getPluginComponents returns the Components for the remote plugins of the
+// generation template, sorted by name.
+//
+// If template is empty, buf.gen.yaml in the current directory is read, if it
+// exists.
+func getPluginComponents(
+	ctx context.Context,
+	container appflag.Container,
+	storageosProvider storageos.Provider,
+	template string,
+) ([]*bufsbom.Component, error) {
+	readWriteBucket, err := storageosProvider.NewReadWriteBucket(
+		".",
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if template == "" {
+		exists, err := bufgen.ConfigExists(ctx, readWriteBucket)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, nil
+		}
+	}
+	genConfig, err := bufgen.ReadConfig(
+		ctx,
+		container.Logger(),
+		bufgen.NewProvider(container.Logger()),
+		readWriteBucket,
+		bufgen.ReadConfigWithOverride(template),
+		bufgen.ReadConfigWithProfile(bufcli.GetProfile(container)),
+		bufgen.ReadConfigWithEnvContainer(container),
+	)
+	if err != nil {
+		return nil, err
+	}
+	nameToComponent := make(map[string]*bufsbom.Component)
+	for _, pluginConfig := range genConfig.PluginConfigs {
+		if !pluginConfig.IsRemote() {
+			continue
+		}
+		name, version, err := getRemotePluginNameAndVersion(pluginConfig)
+		if err != nil {
+			return nil, err
+		}
+		nameToComponent[name+":"+version] = &bufsbom.Component{
+			Type:    bufsbom.ComponentTypePlugin,
+			Name:    name,
+			Version: version,
+		}
+	}
+	components := make([]*bufsbom.Component, 0, len(nameToComponent))
+	for _, component := range nameToComponent {
+		components = append(components, component)
+	}
+	sort.Slice(
+		components,
+		func(i int, j int) bool {
+			if components[i].Name != components[j].Name {
+				return components[i].Name < components[j].Name
+			}
+			return components[i].Version < components[j].Version
+		},
+	)
+	return components, nil
+}
+
+// getRemotePluginNameAndVersion returns the name and version of a remote
+// plugin, where the version is empty if the latest version is used.
+func getRemotePluginNameAndVersion(pluginConfig *bufgen.PluginConfig) (string, string, error) {
+	if pluginConfig.Plugin != "" {
+		identity, version, err := bufpluginref.ParsePluginIdentityOptionalVersion(pluginConfig.Plugin)
+		if err != nil {
+			return "", "", err
+		}
+		if version != "" && pluginConfig.Revision > 0 {
+			version = fmt.Sprintf("%s-%d", version, pluginConfig.Revision)
+		}
+		return identity.IdentityString(), version, nil
+	}
+	remote, owner, name, version, err := bufremoteplugin.ParsePluginVersionPath(pluginConfig.Remote)
+	if err != nil {
+		return "", "", err
+	}
+	return remote + "/" + owner + "/" + name, version, nil
+}
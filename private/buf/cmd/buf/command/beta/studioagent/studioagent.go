@@ -17,14 +17,22 @@ package studioagent
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/bufpkg/bufstudioagent"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/cert/certclient"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/envexpand"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/transport/http/httpserver"
 	"github.com/spf13/cobra"
@@ -43,6 +51,10 @@ const (
 	serverCertFlagName        = "server-cert"
 	serverKeyFlagName         = "server-key"
 	privateNetworkFlagName    = "private-network"
+	upstreamConfigFlagName    = "upstream-config"
+	redactedHeadersFlagName   = "redacted-header"
+
+	defaultTokenPrefix = "Bearer "
 )
 
 // NewCommand returns a new Command.
@@ -54,7 +66,37 @@ func NewCommand(
 	return &appcmd.Command{
 		Use:   name,
 		Short: "Run an HTTP(S) server as the Studio agent",
-		Args:  cobra.ExactArgs(0),
+		Long: `Run an HTTP(S) server that forwards the requests of Studio to their targets.
+
+To front internal services that need real credentials, use --upstream-config with a
+YAML file that configures the upstreams that requests are forwarded to:
+
+    upstreams:
+      - origin: https://orders.internal.example.com
+        # Set on every request to the origin. Environment variables are expanded,
+        # and it is an error to reference a variable that is not set, unless a
+        # default is given as ${VAR:-default}. Write "$${" for a literal "${".
+        headers:
+          X-Api-Key: ${ORDERS_API_KEY}
+      - origin: https://billing.internal.example.com:8443
+        # The output of the command is set as a token on every request to the origin.
+        token_command: [gcloud, auth, print-access-token]
+        # Defaults to Authorization.
+        token_header: Authorization
+        # Defaults to "Bearer ".
+        token_prefix: "Bearer "
+        # How long the output of the command is reused. Defaults to running the
+        # command for every request.
+        token_ttl: 5m
+
+Requests are then only forwarded to the origins of the upstreams, and the headers and
+tokens of an upstream are only set on the requests to its origin, replacing the headers
+with the same names that Studio sent. Redirects from the upstreams are not followed,
+so that the credentials of an upstream are never sent to another origin.
+
+Use --redacted-header to hide the values of response headers and trailers from Studio,
+such as session cookies that the upstreams set.`,
+		Args: cobra.ExactArgs(0),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
 				return run(ctx, container, flags)
@@ -77,6 +119,8 @@ type flags struct {
 	ServerCert        string
 	ServerKey         string
 	PrivateNetwork    bool
+	UpstreamConfig    string
+	RedactedHeaders   []string
 }
 
 func newFlags() *flags {
@@ -150,6 +194,18 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		false,
 		`Use the agent with private network CORS`,
 	)
+	flagSet.StringVar(
+		&f.UpstreamConfig,
+		upstreamConfigFlagName,
+		"",
+		`The YAML file that configures the upstreams to forward requests to. If set, requests are only forwarded to the origins of the upstreams`,
+	)
+	flagSet.StringSliceVar(
+		&f.RedactedHeaders,
+		redactedHeadersFlagName,
+		nil,
+		`The header names whose values are redacted in the responses to the browser. Multiple headers are appended if specified multiple times`,
+	)
 }
 
 func run(
@@ -182,6 +238,17 @@ func run(
 			return fmt.Errorf("cannot create new server TLS config: %w", err)
 		}
 	}
+	var handlerOptions []bufstudioagent.HandlerOption
+	if flags.UpstreamConfig != "" {
+		upstreams, err := readUpstreams(container, flags.UpstreamConfig)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", upstreamConfigFlagName, err)
+		}
+		handlerOptions = append(handlerOptions, bufstudioagent.HandlerWithUpstreams(upstreams...))
+	}
+	if len(flags.RedactedHeaders) > 0 {
+		handlerOptions = append(handlerOptions, bufstudioagent.HandlerWithRedactedHeaders(flags.RedactedHeaders...))
+	}
 	mux := bufstudioagent.NewHandler(
 		container.Logger(),
 		flags.Origin,
@@ -189,6 +256,7 @@ func run(
 		slicesext.ToStructMap(flags.DisallowedHeaders),
 		flags.ForwardHeaders,
 		flags.PrivateNetwork,
+		handlerOptions...,
 	)
 	var httpListenConfig net.ListenConfig
 	httpListener, err := httpListenConfig.Listen(ctx, "tcp", fmt.Sprintf("%s:%s", flags.BindAddress, flags.Port))
@@ -221,3 +289,82 @@ func newTLSConfig(baseConfig *tls.Config, certFile, keyFile string) (*tls.Config
 	config.Certificates = []tls.Certificate{cert}
 	return config, nil
 }
+
+// externalUpstreamConfig is the YAML representation of the upstream config.
+type externalUpstreamConfig struct {
+	Upstreams []*externalUpstream `yaml:"upstreams,omitempty"`
+}
+
+type externalUpstream struct {
+	Origin       string            `yaml:"origin,omitempty"`
+	Headers      map[string]string `yaml:"headers,omitempty"`
+	TokenCommand []string          `yaml:"token_command,omitempty"`
+	TokenHeader  string            `yaml:"token_header,omitempty"`
+	TokenPrefix  *string           `yaml:"token_prefix,omitempty"`
+	TokenTTL     string            `yaml:"token_ttl,omitempty"`
+}
+
+func readUpstreams(container appflag.Container, path string) ([]*bufstudioagent.Upstream, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var externalConfig externalUpstreamConfig
+	if err := encoding.UnmarshalYAMLStrict(data, &externalConfig); err != nil {
+		return nil, err
+	}
+	if len(externalConfig.Upstreams) == 0 {
+		return nil, errors.New("no upstreams configured")
+	}
+	runner := command.NewRunner()
+	origins := make(map[string]struct{}, len(externalConfig.Upstreams))
+	upstreams := make([]*bufstudioagent.Upstream, len(externalConfig.Upstreams))
+	for i, externalUpstream := range externalConfig.Upstreams {
+		origin, err := bufstudioagent.NormalizeOrigin(externalUpstream.Origin)
+		if err != nil {
+			return nil, fmt.Errorf("upstream origin %q: %w", externalUpstream.Origin, err)
+		}
+		if _, ok := origins[origin]; ok {
+			return nil, fmt.Errorf("duplicate upstream origin %q", externalUpstream.Origin)
+		}
+		origins[origin] = struct{}{}
+		upstream := &bufstudioagent.Upstream{
+			Origin:      externalUpstream.Origin,
+			Headers:     make(http.Header, len(externalUpstream.Headers)),
+			TokenHeader: externalUpstream.TokenHeader,
+			TokenPrefix: defaultTokenPrefix,
+		}
+		for key, value := range externalUpstream.Headers {
+			expandedValue, err := envexpand.Expand(value, container)
+			if err != nil {
+				return nil, fmt.Errorf("upstream origin %q: header %q: %w", externalUpstream.Origin, key, err)
+			}
+			upstream.Headers.Set(key, expandedValue)
+		}
+		if externalUpstream.TokenPrefix != nil {
+			upstream.TokenPrefix = *externalUpstream.TokenPrefix
+		}
+		if len(externalUpstream.TokenCommand) > 0 {
+			tokenSourceOptions := []bufstudioagent.CommandTokenSourceOption{
+				bufstudioagent.CommandTokenSourceWithEnv(app.EnvironMap(container)),
+			}
+			if externalUpstream.TokenTTL != "" {
+				ttl, err := time.ParseDuration(externalUpstream.TokenTTL)
+				if err != nil {
+					return nil, fmt.Errorf("upstream origin %q: token_ttl: %w", externalUpstream.Origin, err)
+				}
+				tokenSourceOptions = append(tokenSourceOptions, bufstudioagent.CommandTokenSourceWithTTL(ttl))
+			}
+			upstream.TokenSource = bufstudioagent.NewCommandTokenSource(
+				runner,
+				externalUpstream.TokenCommand[0],
+				externalUpstream.TokenCommand[1:],
+				tokenSourceOptions...,
+			)
+		} else if externalUpstream.TokenHeader != "" || externalUpstream.TokenPrefix != nil || externalUpstream.TokenTTL != "" {
+			return nil, fmt.Errorf("upstream origin %q: token_header, token_prefix, and token_ttl require token_command", externalUpstream.Origin)
+		}
+		upstreams[i] = upstream
+	}
+	return upstreams, nil
+}
@@ -0,0 +1,279 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symbols
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufsymbol"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	formatFlagName          = "format"
+	regexFlagName           = "regex"
+
+	formatText = "text"
+	formatJSON = "json"
+)
+
+var allFormats = []string{formatText, formatJSON}
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <pattern> <input>",
+		Short: "Search for the messages, enums, fields, and services whose names match a pattern",
+		Long: `The first argument is the pattern to match the fully-qualified names of the symbols
+of the input and its dependencies against, such as "acme.v1.*" or "**.Order".
+
+The pattern is a glob that must match the whole name, where "*" matches any characters
+except ".", "**" matches any characters, and "?" matches any character except ".". If --regex
+is set, the pattern is instead a regular expression that matches any part of the name.
+
+The symbols are messages, enums, enum values, fields, extensions, oneofs, services, and
+methods. Each matching symbol is printed with the location of its declaration, sorted by name.
+Symbols that are declared in dependencies are followed by their module, if any.
+
+The second argument is the source, module, or image to search.
+The second argument must be one of format ` + buffetch.AllFormatsString + `.
+Defaults to "." if no second argument is specified.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Format          string
+	Regex           bool
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		formatText,
+		fmt.Sprintf(
+			"The format to print the symbols with. Must be one of %s",
+			stringutil.SliceToString(allFormats),
+		),
+	)
+	flagSet.BoolVar(
+		&f.Regex,
+		regexFlagName,
+		false,
+		`Interpret the pattern as a regular expression instead of a glob`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Format != formatText && flags.Format != formatJSON {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s must be one of %s",
+			formatFlagName,
+			stringutil.SliceToString(allFormats),
+		)
+	}
+	pattern, err := compilePattern(container.Arg(0), flags.Regex)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	input := "."
+	if container.NumArgs() > 1 {
+		input = container.Arg(1)
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false, // the locations of symbols are read from the source code info
+	)
+	if err != nil {
+		return err
+	}
+	index, err := bufsymbol.NewIndex(ctx, image)
+	if err != nil {
+		return err
+	}
+	var symbols []*bufsymbol.Symbol
+	for _, symbol := range index.Symbols() {
+		if pattern.MatchString(symbol.FullName) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if flags.Format == formatJSON {
+		return printSymbolsJSON(container.Stdout(), symbols)
+	}
+	return printSymbolsText(container.Stdout(), symbols)
+}
+
+// compilePattern compiles the glob or regular expression.
+func compilePattern(pattern string, isRegex bool) (*regexp.Regexp, error) {
+	if isRegex {
+		return regexp.Compile(pattern)
+	}
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				builder.WriteString(".*")
+				i++
+			} else {
+				builder.WriteString(`[^.]*`)
+			}
+		case '?':
+			builder.WriteString(`[^.]`)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.Compile(builder.String())
+}
+
+// externalSymbol is the JSON representation of a symbol.
+type externalSymbol struct {
+	FullName string `json:"full_name"`
+	Kind     string `json:"kind"`
+	// Path is the path of the file within its module.
+	Path string `json:"path"`
+	// ExternalPath is the path of the file as it is referred to by the input,
+	// which is the same as Path for files of dependencies.
+	ExternalPath string `json:"external_path"`
+	Line         int    `json:"line,omitempty"`
+	Column       int    `json:"column,omitempty"`
+	// Import is true if the symbol is declared in a dependency.
+	Import bool `json:"import,omitempty"`
+	// Module is the module that the file is from, if any.
+	Module string `json:"module,omitempty"`
+}
+
+func newExternalSymbol(symbol *bufsymbol.Symbol) *externalSymbol {
+	span := symbol.NameSpan
+	if span.IsZero() {
+		span = symbol.Span
+	}
+	externalSymbol := &externalSymbol{
+		FullName:     symbol.FullName,
+		Kind:         strings.ReplaceAll(symbol.Kind.String(), " ", "_"),
+		Path:         symbol.ImageFile.Path(),
+		ExternalPath: symbol.ImageFile.ExternalPath(),
+		Line:         span.StartLine,
+		Column:       span.StartColumn,
+		Import:       symbol.ImageFile.IsImport(),
+	}
+	if moduleIdentity := symbol.ImageFile.ModuleIdentity(); moduleIdentity != nil {
+		externalSymbol.Module = moduleIdentity.IdentityString()
+	}
+	return externalSymbol
+}
+
+func printSymbolsJSON(writer io.Writer, symbols []*bufsymbol.Symbol) error {
+	encoder := json.NewEncoder(writer)
+	for _, symbol := range symbols {
+		if err := encoder.Encode(newExternalSymbol(symbol)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printSymbolsText prints each symbol as path:line:column, followed by its kind
+// and name, in the same form as compiler errors so that editors can link them.
+func printSymbolsText(writer io.Writer, symbols []*bufsymbol.Symbol) error {
+	var builder strings.Builder
+	for _, symbol := range symbols {
+		externalSymbol := newExternalSymbol(symbol)
+		builder.WriteString(externalSymbol.ExternalPath)
+		if externalSymbol.Line > 0 {
+			builder.WriteString(fmt.Sprintf(":%d:%d", externalSymbol.Line, externalSymbol.Column))
+		}
+		builder.WriteString(" ")
+		builder.WriteString(externalSymbol.Kind)
+		builder.WriteString(" ")
+		builder.WriteString(externalSymbol.FullName)
+		if externalSymbol.Import && externalSymbol.Module != "" {
+			builder.WriteString(" (")
+			builder.WriteString(externalSymbol.Module)
+			builder.WriteString(")")
+		}
+		builder.WriteString("\n")
+	}
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
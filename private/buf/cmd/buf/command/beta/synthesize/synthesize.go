@@ -0,0 +1,433 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package synthesize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufreflect"
+	"github.com/bufbuild/buf/private/bufpkg/bufsynth"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/recordio"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	errorFormatFlagName       = "error-format"
+	configFlagName            = "config"
+	disableSymlinksFlagName   = "disable-symlinks"
+	typeFlagName              = "type"
+	fromFlagName              = "from"
+	toFlagName                = "to"
+	toFramingFlagName         = "to-framing"
+	anonymizeByOptionFlagName = "anonymize-by-option"
+	countFlagName             = "count"
+	seedFlagName              = "seed"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Synthesize messages that resemble sample messages",
+		Long: `Learn how often each field is set and which values it takes from sample
+messages, and write synthetic messages drawn from what was learned:
+
+    $ buf beta synthesize --type foo.v1.Order --from samples/ --count 1000 --to orders.jsonl
+
+The --from flag is a sample file or a directory of sample files. The encoding of a
+sample file is determined by its extension: .binpb for a binary message, .json for a
+JSON message, .jsonl for newline-delimited JSON messages, and .txtpb for a text
+message. Files with other extensions are ignored.
+
+Fields selected by --anonymize-by-option are anonymized: their values are never
+written, and are replaced with random values of the same shape, that is strings and
+bytes of similar lengths, numbers within the range of the samples, and bools with
+the same ratio of true to false:
+
+    $ buf beta synthesize --type foo.v1.Order --from samples/ --anonymize-by-option "(corp.pii)=true"
+
+The messages are written as a stream to --to, delimited according to --to-framing.
+The same --seed and samples always result in the same messages.
+
+` + bufcli.GetSourceOrModuleLong(`the source or module that defines --type`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat       string
+	Config            string
+	DisableSymlinks   bool
+	Type              string
+	From              string
+	To                string
+	ToFraming         string
+	AnonymizeByOption []string
+	Count             int
+	Seed              int64
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Type,
+		typeFlagName,
+		"",
+		`The full type name of the messages to synthesize, for example "foo.v1.Order"`,
+	)
+	_ = cobra.MarkFlagRequired(flagSet, typeFlagName)
+	flagSet.StringVar(
+		&f.From,
+		fromFlagName,
+		"",
+		`The sample file or directory of sample files to learn from`,
+	)
+	_ = cobra.MarkFlagRequired(flagSet, fromFlagName)
+	flagSet.StringVar(
+		&f.To,
+		toFlagName,
+		"-",
+		fmt.Sprintf(
+			`The location to write the synthesized messages to. Must be one of format %s`,
+			buffetch.MessageFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&f.ToFraming,
+		toFramingFlagName,
+		"",
+		fmt.Sprintf(
+			`The framing of the synthesized messages. Must be one of %s. Defaults to %q for JSON and %q otherwise`,
+			stringutil.SliceToString(recordio.AllFramingStrings),
+			recordio.FramingNewline.String(),
+			recordio.FramingVarint.String(),
+		),
+	)
+	flagSet.StringSliceVar(
+		&f.AnonymizeByOption,
+		anonymizeByOptionFlagName,
+		nil,
+		`Anonymize the fields with the field option set to the value, for example "(corp.pii)=true". May be provided multiple times`,
+	)
+	flagSet.IntVar(
+		&f.Count,
+		countFlagName,
+		100,
+		`The number of messages to synthesize`,
+	)
+	flagSet.Int64Var(
+		&f.Seed,
+		seedFlagName,
+		0,
+		`The seed for the random number generator. If not set, a time-based seed is used`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) (retErr error) {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Count < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must be non-negative", countFlagName)
+	}
+	toMessageRef, err := buffetch.NewMessageRefParser(
+		container.Logger(),
+		buffetch.MessageRefParserWithDefaultMessageEncoding(
+			buffetch.MessageEncodingJSON,
+		),
+	).GetMessageRef(ctx, flags.To)
+	if err != nil {
+		return fmt.Errorf("--%s: %v", toFlagName, err)
+	}
+	toFraming := recordio.FramingVarint
+	if toMessageRef.MessageEncoding() == buffetch.MessageEncodingJSON {
+		toFraming = recordio.FramingNewline
+	}
+	if flags.ToFraming != "" {
+		toFraming, err = recordio.ParseFraming(flags.ToFraming)
+		if err != nil {
+			return appcmd.NewInvalidArgumentErrorf("--%s: %v", toFramingFlagName, err)
+		}
+	}
+	if toFraming == recordio.FramingNewline && toMessageRef.MessageEncoding() != buffetch.MessageEncodingJSON {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s %s can only be used with JSON encoding",
+			toFramingFlagName,
+			recordio.FramingNewline.String(),
+		)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptorProtos(image)...)
+	if err != nil {
+		return err
+	}
+	message, err := bufreflect.NewMessage(ctx, image, flags.Type)
+	if err != nil {
+		return err
+	}
+	messageDescriptor := message.ProtoReflect().Descriptor()
+	var learnOptions []bufsynth.LearnOption
+	for _, anonymizeByOption := range flags.AnonymizeByOption {
+		fieldFilter, err := bufsynth.NewFieldOptionFilter(resolver, anonymizeByOption)
+		if err != nil {
+			return appcmd.NewInvalidArgumentErrorf("--%s: %v", anonymizeByOptionFlagName, err)
+		}
+		learnOptions = append(learnOptions, bufsynth.LearnWithAnonymizedFields(fieldFilter))
+	}
+	samples, err := readSamples(
+		ctx,
+		container.Logger(),
+		bufcli.NewStorageosProvider(flags.DisableSymlinks),
+		resolver,
+		messageDescriptor,
+		flags.From,
+	)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("--%s: no samples found in %q", fromFlagName, flags.From)
+	}
+	container.Logger().Debug("read_samples", zap.Int("samples", len(samples)))
+	model, err := bufsynth.Learn(messageDescriptor, samples, learnOptions...)
+	if err != nil {
+		return err
+	}
+	var marshaler protoencoding.Marshaler
+	switch toMessageRef.MessageEncoding() {
+	case buffetch.MessageEncodingBinpb:
+		marshaler = protoencoding.NewWireMarshaler()
+	case buffetch.MessageEncodingJSON:
+		marshaler = protoencoding.NewJSONMarshaler(resolver)
+	case buffetch.MessageEncodingTxtpb:
+		marshaler = protoencoding.NewTxtpbMarshaler(resolver)
+	case buffetch.MessageEncodingYAML:
+		marshaler = protoencoding.NewYAMLMarshaler(resolver)
+	default:
+		return fmt.Errorf("unknown message encoding type")
+	}
+	seed := flags.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	random := rand.New(rand.NewSource(seed))
+	writeCloser, err := buffetch.NewWriter(container.Logger()).PutMessageFile(ctx, container, toMessageRef)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeCloser.Close())
+	}()
+	recordWriter, err := recordio.NewWriter(writeCloser, toFraming)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < flags.Count; i++ {
+		data, err := marshaler.Marshal(model.Synthesize(random))
+		if err != nil {
+			return err
+		}
+		if err := recordWriter.Write(data); err != nil {
+			return err
+		}
+	}
+	return recordWriter.Flush()
+}
+
+// readSamples reads the samples from the file or the files in the directory.
+func readSamples(
+	ctx context.Context,
+	logger *zap.Logger,
+	storageosProvider storageos.Provider,
+	resolver protoencoding.Resolver,
+	messageDescriptor protoreflect.MessageDescriptor,
+	from string,
+) ([]proto.Message, error) {
+	sampleReader := &sampleReader{
+		logger:            logger,
+		resolver:          resolver,
+		messageDescriptor: messageDescriptor,
+	}
+	fileInfo, err := os.Stat(from)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %w", fromFlagName, err)
+	}
+	if !fileInfo.IsDir() {
+		data, err := os.ReadFile(from)
+		if err != nil {
+			return nil, err
+		}
+		if err := sampleReader.read(normalpath.Normalize(from), data); err != nil {
+			return nil, err
+		}
+		return sampleReader.samples, nil
+	}
+	readWriteBucket, err := storageosProvider.NewReadWriteBucket(
+		from,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.WalkReadObjects(
+		ctx,
+		readWriteBucket,
+		"",
+		func(readObject storage.ReadObject) error {
+			data, err := io.ReadAll(readObject)
+			if err != nil {
+				return err
+			}
+			return sampleReader.read(normalpath.Join(from, readObject.Path()), data)
+		},
+	); err != nil {
+		return nil, err
+	}
+	return sampleReader.samples, nil
+}
+
+type sampleReader struct {
+	logger            *zap.Logger
+	resolver          protoencoding.Resolver
+	messageDescriptor protoreflect.MessageDescriptor
+
+	samples []proto.Message
+}
+
+func (s *sampleReader) read(path string, data []byte) error {
+	var unmarshaler protoencoding.Unmarshaler
+	framing := recordio.Framing(0)
+	switch normalpath.Ext(path) {
+	case ".binpb":
+		unmarshaler = protoencoding.NewWireUnmarshaler(s.resolver)
+	case ".json":
+		unmarshaler = protoencoding.NewJSONUnmarshaler(s.resolver)
+	case ".jsonl":
+		unmarshaler = protoencoding.NewJSONUnmarshaler(s.resolver)
+		framing = recordio.FramingNewline
+	case ".txtpb":
+		unmarshaler = protoencoding.NewTxtpbUnmarshaler(s.resolver)
+	default:
+		s.logger.Debug("ignoring_sample_file", zap.String("path", path))
+		return nil
+	}
+	if framing == 0 {
+		return s.unmarshal(path, unmarshaler, data)
+	}
+	recordReader, err := recordio.NewReader(bytes.NewReader(data), framing)
+	if err != nil {
+		return err
+	}
+	for i := 1; ; i++ {
+		record, err := recordReader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("%s: record %d: %w", path, i, err)
+		}
+		if err := s.unmarshal(fmt.Sprintf("%s: record %d", path, i), unmarshaler, record); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *sampleReader) unmarshal(name string, unmarshaler protoencoding.Unmarshaler, data []byte) error {
+	sample := dynamicpb.NewMessage(s.messageDescriptor)
+	if err := unmarshaler.Unmarshal(data, sample); err != nil {
+		return fmt.Errorf("%s: unable to unmarshal the sample: %v", name, err)
+	}
+	s.samples = append(s.samples, sample)
+	return nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufwire"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufprotovalidate"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+	typeFlagName            = "type"
+	fromFlagName            = "from"
+	formatFlagName          = "format"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Validate a message against its protovalidate constraints",
+		Long: `The message is read from --from as the type given by --type, and validated against
+the buf.validate constraints of the type and the types of its fields.
+
+    $ buf beta validate --type acme.v1.Order --from order.json
+
+Each violation is printed with the path to the field, the description of the violation,
+and the ID of the constraint:
+
+    name: value length must be at least 3 characters [string.min_len]
+
+Set --format=json to print each violation as a line of JSON instead.
+
+If there are violations, buf exits with code 100. The schema must import
+buf/validate/validate.proto, usually as a dependency on buf.build/bufbuild/protovalidate.
+
+` + bufcli.GetInputLong(`the source, module, or image to read the message type from`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+	Type            string
+	From            string
+	Format          string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Type,
+		typeFlagName,
+		"",
+		`Required. The fully-qualified name of the message to validate, such as acme.v1.Order`,
+	)
+	flagSet.StringVar(
+		&f.From,
+		fromFlagName,
+		"-",
+		fmt.Sprintf(
+			`The location of the message to validate. Supported formats are %s`,
+			buffetch.MessageFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprotovalidate.FormatText.String(),
+		fmt.Sprintf(
+			`The format to print violations in. Must be one of %s`,
+			stringutil.SliceToString(bufprotovalidate.AllFormatStrings),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	if flags.Type == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", typeFlagName)
+	}
+	format, err := bufprotovalidate.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentErrorf("--%s: %v", formatFlagName, err)
+	}
+	fromMessageRef, err := buffetch.NewMessageRefParser(
+		container.Logger(),
+		buffetch.MessageRefParserWithDefaultMessageEncoding(
+			buffetch.MessageEncodingJSON,
+		),
+	).GetMessageRef(ctx, flags.From)
+	if err != nil {
+		return fmt.Errorf("--%s: %v", fromFlagName, err)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false,
+		// The schema is only used to resolve types, the source code info is never needed.
+		bufwire.ImageConfigReaderWithLazySourceCodeInfo(),
+	)
+	if err != nil {
+		return err
+	}
+	message, err := bufcli.NewWireProtoEncodingReader(
+		container,
+		container.Logger(),
+		bufcli.NewStorageosProvider(flags.DisableSymlinks),
+		command.NewRunner(),
+	).GetMessage(
+		ctx,
+		container,
+		image,
+		flags.Type,
+		fromMessageRef,
+	)
+	if err != nil {
+		return err
+	}
+	violations, err := bufprotovalidate.Validate(message)
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	if err := bufprotovalidate.PrintViolations(container.Stdout(), violations, format); err != nil {
+		return err
+	}
+	return bufcli.ErrFileAnnotation
+}
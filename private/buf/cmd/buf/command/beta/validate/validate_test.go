@@ -0,0 +1,162 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// This test is in its own file as opposed to buf_test because it writes an image
+// with the buf.validate descriptors that are linked into the binary, as the
+// sources of buf/validate/validate.proto are not available.
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	imagePath := testWriteImage(t)
+	cmd := func(use string) *appcmd.Command { return NewCommand("validate", appflag.NewBuilder("validate")) }
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		appcmdtesting.RunCommandExitCodeStdout(
+			t,
+			cmd,
+			0,
+			``,
+			nil,
+			nil,
+			imagePath,
+			"--type",
+			"acme.v1.Order",
+			"--from",
+			testWriteMessage(t, `{"name":"abc"}`),
+		)
+	})
+	t.Run("text", func(t *testing.T) {
+		t.Parallel()
+		appcmdtesting.RunCommandExitCodeStdout(
+			t,
+			cmd,
+			100,
+			`name: value length must be at least 3 characters [string.min_len]`,
+			nil,
+			nil,
+			imagePath,
+			"--type",
+			"acme.v1.Order",
+			"--from",
+			testWriteMessage(t, `{"name":"a"}`),
+		)
+	})
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		appcmdtesting.RunCommandExitCodeStdout(
+			t,
+			cmd,
+			100,
+			`{"fieldPath":"name","constraintId":"string.min_len","message":"value length must be at least 3 characters"}`,
+			nil,
+			nil,
+			imagePath,
+			"--type",
+			"acme.v1.Order",
+			"--from",
+			testWriteMessage(t, `{"name":"a"}`),
+			"--format",
+			"json",
+		)
+	})
+}
+
+func testWriteMessage(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "order.json")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	return path
+}
+
+// testWriteImage writes an image with acme/v1/order.proto and its imports, where
+// acme.v1.Order is equivalent to:
+//
+//	message Order {
+//	  string name = 1 [(buf.validate.field).string.min_len = 3];
+//	}
+func testWriteImage(t *testing.T) string {
+	fieldOptions := &descriptorpb.FieldOptions{}
+	proto.SetExtension(
+		fieldOptions,
+		validate.E_Field,
+		&validate.FieldConstraints{
+			Type: &validate.FieldConstraints_String_{
+				String_: &validate.StringRules{MinLen: proto.Uint64(3)},
+			},
+		},
+	)
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]struct{})
+	var addFile func(protoreflect.FileDescriptor)
+	addFile = func(fileDescriptor protoreflect.FileDescriptor) {
+		if _, ok := seen[fileDescriptor.Path()]; ok {
+			return
+		}
+		seen[fileDescriptor.Path()] = struct{}{}
+		imports := fileDescriptor.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			addFile(imports.Get(i).FileDescriptor)
+		}
+		fileDescriptorSet.File = append(fileDescriptorSet.File, protodesc.ToFileDescriptorProto(fileDescriptor))
+	}
+	addFile(validate.File_buf_validate_validate_proto)
+	fileDescriptor, err := protodesc.NewFile(
+		&descriptorpb.FileDescriptorProto{
+			Name:       proto.String("acme/v1/order.proto"),
+			Package:    proto.String("acme.v1"),
+			Syntax:     proto.String("proto3"),
+			Dependency: []string{validate.File_buf_validate_validate_proto.Path()},
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Order"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{
+							Name:     proto.String("name"),
+							JsonName: proto.String("name"),
+							Number:   proto.Int32(1),
+							Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+							Options:  fieldOptions,
+						},
+					},
+				},
+			},
+		},
+		protoregistry.GlobalFiles,
+	)
+	require.NoError(t, err)
+	addFile(fileDescriptor)
+	data, err := proto.Marshal(fileDescriptorSet)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "image.binpb")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
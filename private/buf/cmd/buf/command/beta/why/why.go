@@ -0,0 +1,242 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package why
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	disableSymlinksFlagName = "disable-symlinks"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <module> <source>",
+		Short: "Print the chains of imports that cause a module to be required",
+		Long: fmt.Sprintf(
+			`The first argument is the module to explain, such as buf.build/acme/weather.
+
+For each file of the module that is imported, the shortest chain of imports from a file of the source
+to it is printed, with each imported file followed by the module that it is from. A file is not
+printed if its chain goes through another file of the module, as the other file already explains it.
+
+If nothing in the source imports the module, this is printed instead. The module can then be removed
+from the deps of buf.yaml, unless another dependency requires it.
+
+The second argument is the source to explain the module for.
+The second argument must be one of format %s.
+Defaults to "." if no second argument is specified.`,
+			buffetch.SourceFormatsString,
+		),
+		Args: cobra.RangeArgs(1, 2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Config          string
+	DisableSymlinks bool
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use to use for configuration`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	moduleIdentity, err := bufmoduleref.ModuleIdentityForString(container.Arg(0))
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	input := "."
+	if container.NumArgs() > 1 {
+		input = container.Arg(1)
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		true,
+	)
+	if err != nil {
+		return err
+	}
+	return printImportChains(container.Stdout(), moduleIdentity, getImportChains(image, moduleIdentity))
+}
+
+// getImportChains returns the shortest chain of imports from a non-import
+// file of the image to each file of the module that is imported, sorted by
+// the path of the last file.
+//
+// Chains that go through another file of the module are not returned.
+func getImportChains(image bufimage.Image, moduleIdentity bufmoduleref.ModuleIdentity) [][]bufimage.ImageFile {
+	identityString := moduleIdentity.IdentityString()
+	isModuleFile := func(imageFile bufimage.ImageFile) bool {
+		fileModuleIdentity := imageFile.ModuleIdentity()
+		return fileModuleIdentity != nil && fileModuleIdentity.IdentityString() == identityString
+	}
+	// A breadth-first search from all of the non-import files at once finds
+	// the shortest chain to each file. The files are visited in path order
+	// so that the chains are deterministic.
+	pathToPrevious := make(map[string]bufimage.ImageFile)
+	var queue []bufimage.ImageFile
+	for _, imageFile := range sortedImageFiles(image.Files()) {
+		if !imageFile.IsImport() {
+			pathToPrevious[imageFile.Path()] = nil
+			queue = append(queue, imageFile)
+		}
+	}
+	var moduleFiles []bufimage.ImageFile
+	for len(queue) > 0 {
+		imageFile := queue[0]
+		queue = queue[1:]
+		if isModuleFile(imageFile) {
+			moduleFiles = append(moduleFiles, imageFile)
+			// Files imported by this file are explained by it.
+			continue
+		}
+		dependencies := append([]string{}, imageFile.FileDescriptorProto().GetDependency()...)
+		sort.Strings(dependencies)
+		for _, dependency := range dependencies {
+			if _, ok := pathToPrevious[dependency]; ok {
+				continue
+			}
+			dependencyImageFile := image.GetFile(dependency)
+			if dependencyImageFile == nil {
+				continue
+			}
+			pathToPrevious[dependency] = imageFile
+			queue = append(queue, dependencyImageFile)
+		}
+	}
+	sort.Slice(
+		moduleFiles,
+		func(i int, j int) bool {
+			return moduleFiles[i].Path() < moduleFiles[j].Path()
+		},
+	)
+	chains := make([][]bufimage.ImageFile, 0, len(moduleFiles))
+	for _, moduleFile := range moduleFiles {
+		var chain []bufimage.ImageFile
+		for imageFile := moduleFile; imageFile != nil; imageFile = pathToPrevious[imageFile.Path()] {
+			chain = append([]bufimage.ImageFile{imageFile}, chain...)
+		}
+		chains = append(chains, chain)
+	}
+	return chains
+}
+
+// printImportChains prints the chains in the same form as go mod why, where
+// each chain is a list of paths, and each imported path is followed by the
+// module that it is from, if any.
+func printImportChains(writer io.Writer, moduleIdentity bufmoduleref.ModuleIdentity, chains [][]bufimage.ImageFile) error {
+	var builder strings.Builder
+	builder.WriteString("# ")
+	builder.WriteString(moduleIdentity.IdentityString())
+	builder.WriteString("\n")
+	if len(chains) == 0 {
+		builder.WriteString("(the source does not import any files of ")
+		builder.WriteString(moduleIdentity.IdentityString())
+		builder.WriteString(")\n")
+	}
+	for i, chain := range chains {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		for _, imageFile := range chain {
+			builder.WriteString(imageFile.Path())
+			if fileModuleIdentity := imageFile.ModuleIdentity(); imageFile.IsImport() && fileModuleIdentity != nil {
+				builder.WriteString(" (")
+				builder.WriteString(fileModuleIdentity.IdentityString())
+				builder.WriteString(")")
+			}
+			builder.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+func sortedImageFiles(imageFiles []bufimage.ImageFile) []bufimage.ImageFile {
+	sortedImageFiles := append([]bufimage.ImageFile{}, imageFiles...)
+	sort.Slice(
+		sortedImageFiles,
+		func(i int, j int) bool {
+			return sortedImageFiles[i].Path() < sortedImageFiles[j].Path()
+		},
+	)
+	return sortedImageFiles
+}
@@ -165,11 +165,13 @@ func run(
 	if err != nil {
 		return err
 	}
+	// Breaking change detection only reads the source code info for the locations of failures.
 	imageConfigReader, err := bufcli.NewWireImageConfigReader(
 		container,
 		storageosProvider,
 		runner,
 		clientConfig,
+		bufwire.ImageConfigReaderWithLazySourceCodeInfo(),
 	)
 	if err != nil {
 		return err
@@ -0,0 +1,136 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cachels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/diskcache"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+const (
+	formatFlagName = "format"
+
+	formatText = "text"
+	formatJSON = "json"
+
+	lastUsedLayout = "2006-01-02 15:04"
+)
+
+var allFormats = []string{formatText, formatJSON}
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name,
+		Short: "List the entries of the Buf cache",
+		Long: `Each module, compiled WASM plugin, and schema downloaded by buf curl is listed with
+its size and the last time that it was used, followed by the total size of the cache.
+
+The cache can be pruned with "buf cache prune", or automatically by setting $` + bufcli.CacheMaxSizeEnvKey + `
+and $` + bufcli.CacheMaxAgeEnvKey + `.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Format string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		formatText,
+		fmt.Sprintf(
+			"The format to print the entries with. Must be one of %s",
+			stringutil.SliceToString(allFormats),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) (retErr error) {
+	if flags.Format != formatText && flags.Format != formatJSON {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s must be one of %s",
+			formatFlagName,
+			stringutil.SliceToString(allFormats),
+		)
+	}
+	entries, err := diskcache.ListEntries(bufcli.NewCaches(container.CacheDirPath())...)
+	if err != nil {
+		return err
+	}
+	if flags.Format == formatJSON {
+		encoder := json.NewEncoder(container.Stdout())
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tabWriter := tabwriter.NewWriter(container.Stdout(), 0, 0, 2, ' ', 0)
+	defer func() {
+		retErr = multierr.Append(retErr, tabWriter.Flush())
+	}()
+	if _, err := fmt.Fprintln(tabWriter, "CACHE\tSIZE\tLAST USED\tPATH"); err != nil {
+		return err
+	}
+	var totalSize int64
+	for _, entry := range entries {
+		totalSize += entry.Size
+		if _, err := fmt.Fprintf(
+			tabWriter,
+			"%s\t%s\t%s\t%s\n",
+			entry.CacheName,
+			diskcache.FormatSize(entry.Size),
+			entry.LastUsed.Local().Format(lastUsedLayout),
+			entry.Path,
+		); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(tabWriter, "total\t%s\t\t\n", diskcache.FormatSize(totalSize))
+	return err
+}
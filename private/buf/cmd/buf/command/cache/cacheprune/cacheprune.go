@@ -0,0 +1,128 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheprune
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/diskcache"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	maxSizeFlagName = "max-size"
+	maxAgeFlagName  = "max-age"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name,
+		Short: "Remove the least recently used entries of the Buf cache",
+		Long: `Entries that were last used longer ago than --max-age are removed, and then the least
+recently used entries are removed until the cache is at most --max-size. At least one
+of the flags must be set. Removed entries are fetched again when they are next needed.
+
+To prune the cache automatically, set $` + bufcli.CacheMaxSizeEnvKey + ` and $` + bufcli.CacheMaxAgeEnvKey + ` to the same
+values as the flags. The cache is then pruned at most once an hour when it is used.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	MaxSize string
+	MaxAge  string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.MaxSize,
+		maxSizeFlagName,
+		"",
+		`The maximum total size of the cache, such as 5GB. The units are B, KB, MB, GB, and TB, which are powers of 1024`,
+	)
+	flagSet.StringVar(
+		&f.MaxAge,
+		maxAgeFlagName,
+		"",
+		`The maximum time since an entry was last used, such as 30d. The units are those of Go durations, or d for days and w for weeks`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	var pruneOptions []diskcache.PruneOption
+	if flags.MaxSize != "" {
+		maxSize, err := diskcache.ParseSize(flags.MaxSize)
+		if err != nil {
+			return appcmd.NewInvalidArgumentErrorf("--%s: %v", maxSizeFlagName, err)
+		}
+		pruneOptions = append(pruneOptions, diskcache.PruneWithMaxSize(maxSize))
+	}
+	if flags.MaxAge != "" {
+		maxAge, err := diskcache.ParseAge(flags.MaxAge)
+		if err != nil {
+			return appcmd.NewInvalidArgumentErrorf("--%s: %v", maxAgeFlagName, err)
+		}
+		pruneOptions = append(pruneOptions, diskcache.PruneWithMaxAge(maxAge))
+	}
+	if len(pruneOptions) == 0 {
+		return appcmd.NewInvalidArgumentErrorf("at least one of --%s and --%s must be set", maxSizeFlagName, maxAgeFlagName)
+	}
+	entries, err := diskcache.ListEntries(bufcli.NewCaches(container.CacheDirPath())...)
+	if err != nil {
+		return err
+	}
+	removedEntries, err := diskcache.Prune(entries, pruneOptions...)
+	var removedSize int64
+	for _, removedEntry := range removedEntries {
+		removedSize += removedEntry.Size
+		if _, err := container.Stderr().Write([]byte("deleted " + removedEntry.Path + "\n")); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(
+		container.Stderr(),
+		"removed %d entries, %s\n",
+		len(removedEntries),
+		diskcache.FormatSize(removedSize),
+	)
+	return err
+}
@@ -0,0 +1,175 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configjsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufgen"
+	"github.com/bufbuild/buf/private/buf/bufwork"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/jsonschema"
+	"github.com/spf13/cobra"
+)
+
+// fileTypes are the types of the files that schemas are generated for, keyed by
+// the name of the file.
+var fileTypes = map[string][]versionType{
+	bufconfig.ExternalConfigV1FilePath: {
+		{
+			version:      bufconfig.V1Version,
+			externalType: reflect.TypeOf(bufconfig.ExternalConfigV1{}),
+		},
+		{
+			version:      bufconfig.V2Version,
+			externalType: reflect.TypeOf(bufconfig.ExternalConfigV2{}),
+		},
+	},
+	bufwork.ExternalConfigV1FilePath: {
+		{
+			version:      bufwork.V1Version,
+			externalType: reflect.TypeOf(bufwork.ExternalConfigV1{}),
+		},
+	},
+	bufgen.ExternalConfigFilePath: {
+		{
+			version:      bufgen.V1Version,
+			externalType: reflect.TypeOf(bufgen.ExternalConfigV1{}),
+		},
+	},
+}
+
+// versionType is the type of a version of a file.
+type versionType struct {
+	version      string
+	externalType reflect.Type
+}
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   name + " <file>",
+		Short: "Print the JSON Schema for a configuration file",
+		Long: `Print the JSON Schema for the latest versions of a configuration file, for
+editors to complete and validate the file with. The file must be one of ` + strings.Join(fileNames(), ", ") + `.
+
+For example, with the YAML language server, the schema for buf.yaml can be used by
+writing it to a file and adding a comment to the top of the buf.yaml:
+
+    $ buf config jsonschema buf.yaml > buf.schema.json
+
+    # yaml-language-server: $schema=buf.schema.json
+    version: v1`,
+		Args: cobra.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container)
+			},
+		),
+	}
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+) error {
+	fileName := container.Arg(0)
+	versionTypes, ok := fileTypes[fileName]
+	if !ok {
+		return appcmd.NewInvalidArgumentErrorf("unknown file %q, must be one of %s", fileName, strings.Join(fileNames(), ", "))
+	}
+	schema, err := newSchema(fileName, versionTypes)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = container.Stdout().Write(append(data, '\n'))
+	return err
+}
+
+// newSchema returns the schema for the given versions of a file.
+//
+// If there is more than one version, the schema is valid for any of them, and
+// the version property determines which one applies.
+func newSchema(fileName string, versionTypes []versionType) (jsonschema.Schema, error) {
+	options := []jsonschema.ForTypeOption{
+		// These can also be set to a string, which is the default.
+		jsonschema.ForTypeWithAlternative(
+			reflect.TypeOf(bufgen.ExternalJavaPackagePrefixConfigV1{}),
+			jsonschema.Schema{"type": "string"},
+		),
+		jsonschema.ForTypeWithAlternative(
+			reflect.TypeOf(bufgen.ExternalOptimizeForConfigV1{}),
+			jsonschema.Schema{"type": "string"},
+		),
+	}
+	versionSchemas := make([]interface{}, 0, len(versionTypes))
+	for _, versionType := range versionTypes {
+		versionSchema, err := jsonschema.ForType(versionType.externalType, options...)
+		if err != nil {
+			return nil, err
+		}
+		properties, ok := versionSchema["properties"].(map[string]interface{})
+		if !ok {
+			// Unreachable, as the external types are all structs.
+			return nil, fmt.Errorf("schema for %v has no properties", versionType.externalType)
+		}
+		properties["version"] = jsonschema.Schema{
+			"type": "string",
+			"enum": []string{versionType.version},
+		}
+		versionSchema["required"] = []string{"version"}
+		// Only the schema of the file has these.
+		delete(versionSchema, "$schema")
+		versionSchemas = append(versionSchemas, versionSchema)
+	}
+	if len(versionSchemas) == 1 {
+		schema, ok := versionSchemas[0].(jsonschema.Schema)
+		if !ok {
+			// Unreachable.
+			return nil, fmt.Errorf("invalid schema for %s", fileName)
+		}
+		schema["$schema"] = jsonschema.DraftURI
+		schema["title"] = fileName
+		return schema, nil
+	}
+	return jsonschema.Schema{
+		"$schema": jsonschema.DraftURI,
+		"title":   fileName,
+		"oneOf":   versionSchemas,
+	}, nil
+}
+
+func fileNames() []string {
+	fileNames := make([]string, 0, len(fileTypes))
+	for fileName := range fileTypes {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	return fileNames
+}
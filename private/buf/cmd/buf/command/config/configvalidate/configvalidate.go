@@ -0,0 +1,356 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configvalidate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufgen"
+	"github.com/bufbuild/buf/private/buf/bufwork"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/buflock"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+)
+
+const disableSymlinksFlagName = "disable-symlinks"
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <directory>",
+		Short: "Validate the configuration files in a directory",
+		Long: `Validate the buf.work.yaml, buf.yaml, buf.lock, and buf.gen.yaml files in the directory,
+and the buf.yaml and buf.lock files of the modules of the workspace, if any.
+Defaults to the current directory if not specified.
+
+The files are checked for unknown keys and invalid values, and for consistency with each
+other. For example, each dependency of a module must be pinned in its buf.lock, and the
+modules of a workspace must have different names.
+
+Each problem is printed on its own line, prefixed with the path of the file.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	DisableSymlinks bool
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	dirPath, err := getDirPath(container)
+	if err != nil {
+		return err
+	}
+	readBucket, err := bufcli.NewStorageosProvider(flags.DisableSymlinks).NewReadWriteBucket(
+		dirPath,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return err
+	}
+	validator := newValidator(container.Logger(), readBucket, bufcli.GetProfile(container), container)
+	if err := validator.validate(ctx); err != nil {
+		return err
+	}
+	if len(validator.problems) == 0 {
+		return nil
+	}
+	for _, problem := range validator.problems {
+		if _, err := fmt.Fprintf(
+			container.Stdout(),
+			"%s: %s\n",
+			normalpath.Unnormalize(normalpath.Join(dirPath, problem.path)),
+			problem.message,
+		); err != nil {
+			return err
+		}
+	}
+	return bufcli.ErrFileAnnotation
+}
+
+// problem is a problem with a configuration file.
+type problem struct {
+	// The path of the file, relative to the root of the bucket.
+	path    string
+	message string
+}
+
+type validator struct {
+	logger     *zap.Logger
+	readBucket storage.ReadBucket
+	// The profile that is applied to the configuration files, if any.
+	profile string
+	// The environment variables that are expanded in the configuration files.
+	envContainer app.EnvContainer
+	problems     []problem
+}
+
+func newValidator(
+	logger *zap.Logger,
+	readBucket storage.ReadBucket,
+	profile string,
+	envContainer app.EnvContainer,
+) *validator {
+	return &validator{
+		logger:       logger,
+		readBucket:   readBucket,
+		profile:      profile,
+		envContainer: envContainer,
+	}
+}
+
+// validate validates the configuration files in the bucket, and adds the problems
+// that it finds.
+//
+// An error is only returned if the files cannot be read.
+func (v *validator) validate(ctx context.Context) error {
+	workspaceConfigFilePath, err := bufwork.ExistingConfigFilePath(ctx, v.readBucket)
+	if err != nil {
+		return err
+	}
+	if workspaceConfigFilePath != "" {
+		if err := v.validateWorkspace(ctx, workspaceConfigFilePath); err != nil {
+			return err
+		}
+	} else {
+		moduleConfigFilePath, err := bufconfig.ExistingConfigFilePath(ctx, v.readBucket)
+		if err != nil {
+			return err
+		}
+		if moduleConfigFilePath != "" {
+			if _, err := v.validateModule(ctx, v.readBucket, moduleConfigFilePath, buflock.ExternalConfigFilePath, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return v.validateGenerateConfig(ctx)
+}
+
+func (v *validator) validateWorkspace(ctx context.Context, workspaceConfigFilePath string) error {
+	workspaceConfig, err := bufwork.GetConfigForBucket(ctx, v.readBucket, ".", bufwork.GetConfigWithEnvContainer(v.envContainer))
+	if err != nil {
+		v.addProblem(workspaceConfigFilePath, err.Error())
+		return nil
+	}
+	isV2 := workspaceConfigFilePath == bufconfig.ExternalConfigV2FilePath
+	if !isV2 {
+		moduleConfigFilePath, err := bufconfig.ExistingConfigFilePath(ctx, v.readBucket)
+		if err != nil {
+			return err
+		}
+		if moduleConfigFilePath != "" {
+			v.addProblem(
+				moduleConfigFilePath,
+				fmt.Sprintf("is not used, as the directory is a workspace defined by %s that does not list it", workspaceConfigFilePath),
+			)
+		}
+	}
+	nameToDirectory := make(map[string]string)
+	for _, directory := range workspaceConfig.Directories {
+		readBucketForDirectory, err := bufwork.ReadBucketForDirectory(ctx, workspaceConfig, v.readBucket, ".", directory)
+		if err != nil {
+			v.addProblem(workspaceConfigFilePath, err.Error())
+			continue
+		}
+		nestedWorkspaceConfigFilePath, err := bufwork.ExistingConfigFilePath(ctx, readBucketForDirectory)
+		if err != nil {
+			return err
+		}
+		if nestedWorkspaceConfigFilePath != "" {
+			v.addProblem(
+				normalpath.Join(directory, nestedWorkspaceConfigFilePath),
+				fmt.Sprintf("is not used, as the directory is a module of the workspace defined by %s", workspaceConfigFilePath),
+			)
+		}
+		// The configuration of a module of a v2 workspace is generated from the
+		// buf.yaml of the workspace, and its buf.lock is the buf.lock of the workspace.
+		moduleConfigFilePath := bufconfig.ExternalConfigV2FilePath
+		lockFilePath := buflock.ExternalConfigFilePath
+		if !isV2 {
+			existingModuleConfigFilePath, err := bufconfig.ExistingConfigFilePath(ctx, readBucketForDirectory)
+			if err != nil {
+				return err
+			}
+			if existingModuleConfigFilePath == "" {
+				continue
+			}
+			moduleConfigFilePath = normalpath.Join(directory, existingModuleConfigFilePath)
+			lockFilePath = normalpath.Join(directory, buflock.ExternalConfigFilePath)
+		}
+		name, err := v.validateModule(
+			ctx,
+			readBucketForDirectory,
+			moduleConfigFilePath,
+			lockFilePath,
+			workspaceConfig.ModuleConfigDefaults,
+		)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		if otherDirectory, ok := nameToDirectory[name]; ok {
+			v.addProblem(
+				workspaceConfigFilePath,
+				fmt.Sprintf(
+					`directories "%s" and "%s" are both modules named %s`,
+					normalpath.Unnormalize(otherDirectory),
+					normalpath.Unnormalize(directory),
+					name,
+				),
+			)
+			continue
+		}
+		nameToDirectory[name] = directory
+	}
+	return nil
+}
+
+// validateModule validates the configuration and buf.lock of the module in the
+// given bucket, and returns the name of the module, if it is valid and has one.
+//
+// The paths of the files are only used for the problems.
+func (v *validator) validateModule(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	moduleConfigFilePath string,
+	lockFilePath string,
+	defaults *bufconfig.ConfigDefaults,
+) (string, error) {
+	config, err := bufconfig.ReadConfigOS(
+		ctx,
+		readBucket,
+		bufconfig.ReadConfigOSWithDefaults(defaults),
+		bufconfig.ReadConfigOSWithProfile(v.profile),
+		bufconfig.ReadConfigOSWithEnvContainer(v.envContainer),
+	)
+	if err != nil {
+		v.addProblem(moduleConfigFilePath, err.Error())
+		return "", nil
+	}
+	var name string
+	if config.ModuleIdentity != nil {
+		name = config.ModuleIdentity.IdentityString()
+	}
+	if config.Build == nil || len(config.Build.DependencyModuleReferences) == 0 {
+		return name, nil
+	}
+	exists, err := storage.Exists(ctx, readBucket, buflock.ExternalConfigFilePath)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		v.addProblem(moduleConfigFilePath, fmt.Sprintf("has deps, but there is no %s, run buf mod update to create it", lockFilePath))
+		return name, nil
+	}
+	lockFile, err := buflock.ReadConfig(ctx, readBucket)
+	if err != nil {
+		v.addProblem(lockFilePath, err.Error())
+		return name, nil
+	}
+	pinnedIdentities := make(map[string]struct{}, len(lockFile.Dependencies))
+	for _, dependency := range lockFile.Dependencies {
+		pinnedIdentities[dependency.Remote+"/"+dependency.Owner+"/"+dependency.Repository] = struct{}{}
+	}
+	for _, dependencyModuleReference := range config.Build.DependencyModuleReferences {
+		identity := dependencyModuleReference.IdentityString()
+		if _, ok := pinnedIdentities[identity]; !ok {
+			v.addProblem(lockFilePath, fmt.Sprintf("dep %s of %s is not pinned, run buf mod update to pin it", identity, moduleConfigFilePath))
+		}
+	}
+	return name, nil
+}
+
+func (v *validator) validateGenerateConfig(ctx context.Context) error {
+	exists, err := bufgen.ConfigExists(ctx, v.readBucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	if _, err := bufgen.ReadConfig(
+		ctx,
+		v.logger,
+		bufgen.NewProvider(v.logger),
+		v.readBucket,
+		bufgen.ReadConfigWithProfile(v.profile),
+		bufgen.ReadConfigWithEnvContainer(v.envContainer),
+	); err != nil {
+		v.addProblem(bufgen.ExternalConfigFilePath, err.Error())
+	}
+	return nil
+}
+
+func (v *validator) addProblem(path string, message string) {
+	for _, existingProblem := range v.problems {
+		// The modules of a v2 workspace share their configuration files.
+		if existingProblem.path == path && existingProblem.message == message {
+			return
+		}
+	}
+	v.problems = append(
+		v.problems,
+		problem{
+			path:    path,
+			message: message,
+		},
+	)
+}
+
+func getDirPath(container app.Container) (string, error) {
+	switch numArgs := container.NumArgs(); numArgs {
+	case 0:
+		return ".", nil
+	case 1:
+		return container.Arg(0), nil
+	default:
+		return "", appcmd.NewInvalidArgumentErrorf("only 1 argument allowed but %d arguments specified", numArgs)
+	}
+}
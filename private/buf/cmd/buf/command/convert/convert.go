@@ -21,12 +21,14 @@ import (
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufwire"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
 	"github.com/bufbuild/buf/private/gen/data/datawkt"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/recordio"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -38,6 +40,8 @@ const (
 	fromFlagName            = "from"
 	outputFlagName          = "to"
 	disableSymlinksFlagName = "disable-symlinks"
+	fromFramingFlagName     = "from-framing"
+	toFramingFlagName       = "to-framing"
 )
 
 // NewCommand returns a new Command.
@@ -79,6 +83,21 @@ Redirect from stdin to <input>:
 Use a module on the bsr:
 
     $ buf convert <buf.build/owner/repository> --type buf.Foo --from=payload.json
+
+Convert a stream of messages by setting "--from-framing". Messages are read, converted and
+written one at a time, so streams of any size can be converted with bounded memory:
+
+    $ buf convert example.proto --type=buf.Foo --from=records.binpb --from-framing=varint --to=records.json
+
+The supported framings are:
+
+    varint   Each message is prefixed with its length as a varint, as written by
+             writeDelimitedTo in protobuf-java.
+    fixed32  Each message is prefixed with its length as a 4-byte big-endian integer.
+    newline  Each message is terminated by a newline. Only supported for JSON.
+
+If "--to-framing" is not set, "newline" is used for JSON output, the framing of "--from-framing"
+is used if it is "varint" or "fixed32", and "varint" is used otherwise.
 `,
 		Args: cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -97,6 +116,8 @@ type flags struct {
 	From            string
 	To              string
 	DisableSymlinks bool
+	FromFraming     string
+	ToFraming       string
 
 	// special
 	InputHashtag string
@@ -142,6 +163,26 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 			buffetch.MessageFormatsString,
 		),
 	)
+	flagSet.StringVar(
+		&f.FromFraming,
+		fromFramingFlagName,
+		"",
+		fmt.Sprintf(
+			`The framing of the messages in the stream to convert. If set, --%s is read as a stream of messages. Must be one of %s`,
+			fromFlagName,
+			stringutil.SliceToString(recordio.AllFramingStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.ToFraming,
+		toFramingFlagName,
+		"",
+		fmt.Sprintf(
+			`The framing of the messages in the converted stream. Requires --%s. Must be one of %s`,
+			fromFramingFlagName,
+			stringutil.SliceToString(recordio.AllFramingStrings),
+		),
+	)
 }
 
 func run(
@@ -152,6 +193,17 @@ func run(
 	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
 		return err
 	}
+	if flags.ToFraming != "" && flags.FromFraming == "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s requires --%s to be set", toFramingFlagName, fromFramingFlagName)
+	}
+	fromFraming, err := parseFraming(flags.FromFraming, fromFramingFlagName)
+	if err != nil {
+		return err
+	}
+	toFraming, err := parseFraming(flags.ToFraming, toFramingFlagName)
+	if err != nil {
+		return err
+	}
 	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
 	if err != nil {
 		return err
@@ -167,6 +219,8 @@ func run(
 		nil,   // externalExcludeDirOrFilePaths
 		false, // externalDirOrFilePathsAllowNotExist
 		false, // excludeSourceCodeInfo
+		// The schema is only used to resolve types, the source code info is never needed.
+		bufwire.ImageConfigReaderWithLazySourceCodeInfo(),
 	)
 	var resolveWellKnownType bool
 	// only resolve wkts if input was not set.
@@ -204,7 +258,32 @@ func run(
 	}
 	storageosProvider := bufcli.NewStorageosProvider(flags.DisableSymlinks)
 	runner := command.NewRunner()
+	if fromFraming != 0 {
+		toMessageRef, err := getToMessageRef(ctx, container, flags, fromMessageRef)
+		if err != nil {
+			return err
+		}
+		if toFraming == 0 {
+			toFraming = defaultToFraming(fromFraming, toMessageRef)
+		}
+		return bufcli.NewWireProtoEncodingStreamConverter(
+			container,
+			container.Logger(),
+			storageosProvider,
+			runner,
+		).ConvertStream(
+			ctx,
+			container,
+			image,
+			flags.Type,
+			fromMessageRef,
+			fromFraming,
+			toMessageRef,
+			toFraming,
+		)
+	}
 	message, err := bufcli.NewWireProtoEncodingReader(
+		container,
 		container.Logger(),
 		storageosProvider,
 		runner,
@@ -218,19 +297,10 @@ func run(
 	if err != nil {
 		return err
 	}
-	defaultToEncoding, err := inverseEncoding(fromMessageRef.MessageEncoding())
+	toMessageRef, err := getToMessageRef(ctx, container, flags, fromMessageRef)
 	if err != nil {
 		return err
 	}
-	toMessageRef, err := buffetch.NewMessageRefParser(
-		container.Logger(),
-		buffetch.MessageRefParserWithDefaultMessageEncoding(
-			defaultToEncoding,
-		),
-	).GetMessageRef(ctx, flags.To)
-	if err != nil {
-		return fmt.Errorf("--%s: %v", outputFlagName, err)
-	}
 	return bufcli.NewWireProtoEncodingWriter(
 		container.Logger(),
 	).PutMessage(
@@ -242,6 +312,52 @@ func run(
 	)
 }
 
+func getToMessageRef(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+	fromMessageRef buffetch.MessageRef,
+) (buffetch.MessageRef, error) {
+	defaultToEncoding, err := inverseEncoding(fromMessageRef.MessageEncoding())
+	if err != nil {
+		return nil, err
+	}
+	toMessageRef, err := buffetch.NewMessageRefParser(
+		container.Logger(),
+		buffetch.MessageRefParserWithDefaultMessageEncoding(
+			defaultToEncoding,
+		),
+	).GetMessageRef(ctx, flags.To)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %v", outputFlagName, err)
+	}
+	return toMessageRef, nil
+}
+
+// parseFraming parses the framing flag value, returning 0 if it is not set.
+func parseFraming(value string, flagName string) (recordio.Framing, error) {
+	if value == "" {
+		return 0, nil
+	}
+	framing, err := recordio.ParseFraming(value)
+	if err != nil {
+		return 0, appcmd.NewInvalidArgumentErrorf("--%s: %v", flagName, err)
+	}
+	return framing, nil
+}
+
+// defaultToFraming returns the framing to use for the output stream if
+// it was not set.
+func defaultToFraming(fromFraming recordio.Framing, toMessageRef buffetch.MessageRef) recordio.Framing {
+	if toMessageRef.MessageEncoding() == buffetch.MessageEncodingJSON {
+		return recordio.FramingNewline
+	}
+	if fromFraming != recordio.FramingNewline {
+		return fromFraming
+	}
+	return recordio.FramingVarint
+}
+
 // inverseEncoding returns the opposite encoding of the provided encoding,
 // which will be the default output encoding for a given payload encoding.
 func inverseEncoding(encoding buffetch.MessageEncoding) (buffetch.MessageEncoding, error) {
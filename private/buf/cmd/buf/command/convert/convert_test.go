@@ -397,4 +397,74 @@ func TestConvertDir(t *testing.T) {
 			"-#format=json",
 		)
 	})
+	t.Run("stream-varint-to-newline-json", func(t *testing.T) {
+		t.Parallel()
+		appcmdtesting.RunCommandExitCodeStdoutFile(
+			t,
+			cmd,
+			0,
+			"testdata/convert/bin_json/stream.json",
+			nil,
+			nil,
+			"--type",
+			"buf.Foo",
+			"--from",
+			"testdata/convert/bin_json/stream.binpb",
+			"--from-framing",
+			"varint",
+		)
+	})
+	t.Run("stream-newline-json-to-varint", func(t *testing.T) {
+		t.Parallel()
+		appcmdtesting.RunCommandExitCodeStdoutFile(
+			t,
+			cmd,
+			0,
+			"testdata/convert/bin_json/stream.binpb",
+			nil,
+			nil,
+			"--type",
+			"buf.Foo",
+			"--from",
+			"testdata/convert/bin_json/stream.json",
+			"--from-framing",
+			"newline",
+		)
+	})
+	t.Run("stream-newline-binpb", func(t *testing.T) {
+		t.Parallel()
+		appcmdtesting.RunCommandExitCode(
+			t,
+			cmd,
+			1,
+			nil,
+			nil,
+			nil,
+			nil,
+			"--type",
+			"buf.Foo",
+			"--from",
+			"testdata/convert/bin_json/stream.binpb",
+			"--from-framing",
+			"newline",
+		)
+	})
+	t.Run("stream-to-framing-without-from-framing", func(t *testing.T) {
+		t.Parallel()
+		appcmdtesting.RunCommandExitCode(
+			t,
+			cmd,
+			1,
+			nil,
+			nil,
+			nil,
+			nil,
+			"--type",
+			"buf.Foo",
+			"--from",
+			"testdata/convert/bin_json/payload.binpb",
+			"--to-framing",
+			"varint",
+		)
+	})
 }
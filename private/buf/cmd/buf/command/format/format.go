@@ -17,21 +17,29 @@ package format
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/buf/bufformat"
 	"github.com/bufbuild/buf/private/buf/bufwork"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/diff"
+	"github.com/bufbuild/buf/private/pkg/editorconfig"
+	"github.com/bufbuild/buf/private/pkg/git"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
@@ -42,16 +50,30 @@ import (
 )
 
 const (
+	againstGitRefFlagName   = "against-git-ref"
+	alignFieldsFlagName     = "align-fields"
+	canonicalOrderFlagName  = "canonical-order"
+	commentSpaceFlagName    = "comment-space"
 	configFlagName          = "config"
 	diffFlagName            = "diff"
 	diffFlagShortName       = "d"
 	disableSymlinksFlagName = "disable-symlinks"
+	editorConfigFlagName    = "editorconfig"
 	errorFormatFlagName     = "error-format"
 	excludePathsFlagName    = "exclude-path"
 	exitCodeFlagName        = "exit-code"
+	groupImportsFlagName    = "group-imports"
+	indentFlagName          = "indent"
+	lineDocCommentsFlagName = "line-doc-comments"
+	maxLineWidthFlagName    = "max-line-width"
+	onlyChangedFlagName     = "only-changed"
 	outputFlagName          = "output"
 	outputFlagShortName     = "o"
 	pathsFlagName           = "path"
+	rangeFlagName           = "range"
+	reflowCommentsFlagName  = "reflow-comments"
+	sortEnumValuesFlagName  = "sort-enum-values"
+	useTabsFlagName         = "use-tabs"
 	writeFlagName           = "write"
 	writeFlagShortName      = "w"
 )
@@ -98,6 +120,12 @@ Write a diff instead of the formatted file:
     +  bytes value = 2;
      }
 
+Use --error-format=json with -d to write a JSON object for each file instead,
+which has the path of the file, whether it differs, and the diff if it does:
+
+    $ buf format simple/simple.proto -d --error-format=json
+    {"path":"simple/simple.proto","differs":true,"diff":"diff -u simple/simple.proto.orig simple/simple.proto\n..."}
+
 Use the --exit-code flag to exit with a non-zero exit code if there is a diff:
 
     $ buf format --exit-code
@@ -138,11 +166,14 @@ Write the formatted directory to another directory, creating it if it doesn't ex
 
     $ buf format proto -o formatted
 
-This also works with module references:
+This also works with module references, archives, and git repositories, which
+is useful to vendor formatted copies of their files:
 
     $ buf format buf.build/acme/weather -o formatted
+    $ buf format https://example.com/protos.tar.gz#subdir=proto -o formatted
+    $ buf format https://github.com/acme/weather.git#branch=main -o formatted
 
-Rewrite the file(s) in-place with -w. e.g.
+Rewrite the file(s) of a local directory or file in-place with -w. e.g.
 
 Rewrite a single file in-place:
 
@@ -159,7 +190,53 @@ Write a diff and rewrite the file(s) in-place:
     $ diff -u simple/simple.proto.orig simple/simple.proto
     ...
 
+Only format the files that differ from a git ref, or that are untracked, with
+--only-changed. This formats a large tree incrementally as files are changed:
+
+    $ buf format --only-changed --against-git-ref origin/main -w
+
+Format only some lines of a file given on stdin with --range, where the source
+must be -. This is used by editors to format a selection. The range is expanded
+to whole declarations, the formatted replacement for the expanded range is
+written to stdout, and the first and last lines that it replaces are written to
+stderr:
+
+    $ buf format - --range 6:7 < simple/simple.proto
+      string key = 1;
+      bytes value = 2;
+    6:7
+
 The -w and -o flags cannot be used together in a single invocation.
+
+The style of the formatted content can be configured in the format section of
+your buf.yaml, and the --indent, --use-tabs, --max-line-width, --group-imports,
+--align-fields, --canonical-order, --sort-enum-values, --line-doc-comments,
+--comment-space, and --reflow-comments flags take precedence over it:
+
+    version: v1
+    format:
+      indent: 4
+      use_tabs: false
+      max_line_width: 100
+      group_imports: true
+      align_fields: true
+      canonical_order: true
+      sort_enum_values: true
+      line_doc_comments: true
+      comment_space: true
+      reflow_comments: true
+
+The comment rules do not change directive comments, such as buf:lint:ignore.
+
+With --editorconfig, the indent_style, indent_size, tab_width, end_of_line, and
+insert_final_newline properties of the .editorconfig files of local files are
+also applied, and the format section of your buf.yaml and the flags take
+precedence over them.
+
+Write the formatted content indented with 4 spaces, and with field definitions
+and option values wrapped at 100 characters:
+
+    $ buf format --indent 4 --max-line-width 100
 `,
 		Args: cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -173,17 +250,32 @@ The -w and -o flags cannot be used together in a single invocation.
 }
 
 type flags struct {
+	AgainstGitRef   string
+	AlignFields     bool
+	CanonicalOrder  bool
+	CommentSpace    bool
 	Config          string
 	Diff            bool
 	DisableSymlinks bool
+	EditorConfig    bool
 	ErrorFormat     string
 	ExcludePaths    []string
 	ExitCode        bool
+	GroupImports    bool
+	Indent          int
+	LineDocComments bool
+	MaxLineWidth    int
+	OnlyChanged     bool
 	Paths           []string
 	Output          string
+	Range           string
+	ReflowComments  bool
+	SortEnumValues  bool
+	UseTabs         bool
 	Write           bool
 	// special
 	InputHashtag string
+	flagSet      *pflag.FlagSet
 }
 
 func newFlags() *flags {
@@ -191,6 +283,7 @@ func newFlags() *flags {
 }
 
 func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	f.flagSet = flagSet
 	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
 	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
@@ -215,12 +308,106 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		false,
 		"Rewrite files in-place",
 	)
+	flagSet.BoolVar(
+		&f.OnlyChanged,
+		onlyChangedFlagName,
+		false,
+		fmt.Sprintf(
+			"Only format files that differ from the git ref given by --%s, or that are untracked. The source must be a local directory or file in a git repository",
+			againstGitRefFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.AgainstGitRef,
+		againstGitRefFlagName,
+		"HEAD",
+		fmt.Sprintf(
+			"The git ref, such as a branch, tag, or commit, to compare files against when --%s is set",
+			onlyChangedFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.Range,
+		rangeFlagName,
+		"",
+		"Only format the lines in the range start:end, inclusive and numbered from 1, of the file read from stdin. The source must be -",
+	)
+	flagSet.IntVar(
+		&f.Indent,
+		indentFlagName,
+		0,
+		"The number of spaces in each level of indentation. Overrides the format.indent value in the configuration, which defaults to 2",
+	)
+	flagSet.BoolVar(
+		&f.UseTabs,
+		useTabsFlagName,
+		false,
+		"Indent with tabs instead of spaces. Overrides the format.use_tabs value in the configuration",
+	)
+	flagSet.BoolVar(
+		&f.GroupImports,
+		groupImportsFlagName,
+		false,
+		"Group imports from google/, other modules, and the same module, with a blank line between each group. Overrides the format.group_imports value in the configuration",
+	)
+	flagSet.BoolVar(
+		&f.AlignFields,
+		alignFieldsFlagName,
+		false,
+		"Vertically align the '=' signs and compact options of consecutive fields and enum values. Overrides the format.align_fields value in the configuration",
+	)
+	flagSet.BoolVar(
+		&f.CanonicalOrder,
+		canonicalOrderFlagName,
+		false,
+		"Write the types of each file in the order messages, enums, services, then extensions. Overrides the format.canonical_order value in the configuration",
+	)
+	flagSet.BoolVar(
+		&f.SortEnumValues,
+		sortEnumValuesFlagName,
+		false,
+		"Sort the values of each enum by number, other than the first value, which is the default. Overrides the format.sort_enum_values value in the configuration",
+	)
+	flagSet.BoolVar(
+		&f.LineDocComments,
+		lineDocCommentsFlagName,
+		false,
+		"Write /* */ doc comments as // comments. Overrides the format.line_doc_comments value in the configuration",
+	)
+	flagSet.BoolVar(
+		&f.CommentSpace,
+		commentSpaceFlagName,
+		false,
+		"Write a space after the // of comments. Overrides the format.comment_space value in the configuration",
+	)
+	flagSet.BoolVar(
+		&f.ReflowComments,
+		reflowCommentsFlagName,
+		false,
+		fmt.Sprintf(
+			"Reflow doc comments with lines longer than --%s to fit within it. Overrides the format.reflow_comments value in the configuration",
+			maxLineWidthFlagName,
+		),
+	)
+	flagSet.BoolVar(
+		&f.EditorConfig,
+		editorConfigFlagName,
+		false,
+		"Apply the indentation and line ending properties of the .editorconfig files of the files. Only local files can be formatted with this flag",
+	)
+	flagSet.IntVar(
+		&f.MaxLineWidth,
+		maxLineWidthFlagName,
+		0,
+		"The maximum width of a line, after which field definitions and option values are wrapped. Overrides the format.max_line_width value in the configuration. Zero means there is no maximum",
+	)
 	flagSet.StringVar(
 		&f.ErrorFormat,
 		errorFormatFlagName,
 		"text",
 		fmt.Sprintf(
-			"The format for build errors printed to stderr. Must be one of %s",
+			"The format for build errors printed to stderr, and for diffs printed to stdout with --%s. Must be one of %s. With json, a JSON object with the path of each file, whether it differs, and the diff is printed on its own line instead of the diffs",
+			diffFlagName,
 			stringutil.SliceToString(bufanalysis.AllFormatStrings),
 		),
 	)
@@ -253,17 +440,47 @@ func run(
 	if flags.Output != "-" && flags.Write {
 		return fmt.Errorf("--%s cannot be used with --%s", outputFlagName, writeFlagName)
 	}
+	if flags.flagSet.Changed(indentFlagName) && flags.Indent <= 0 {
+		return fmt.Errorf("--%s must be positive", indentFlagName)
+	}
+	if flags.MaxLineWidth < 0 {
+		return fmt.Errorf("--%s must not be negative", maxLineWidthFlagName)
+	}
+	if flags.flagSet.Changed(againstGitRefFlagName) && !flags.OnlyChanged {
+		return fmt.Errorf("--%s can only be used with --%s", againstGitRefFlagName, onlyChangedFlagName)
+	}
 	source, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
 	if err != nil {
 		return err
 	}
+	if flags.Range != "" {
+		return runRange(ctx, container, flags, source)
+	}
 	refParser := buffetch.NewRefParser(container.Logger())
 	sourceOrModuleRef, err := refParser.GetSourceOrModuleRef(ctx, source)
 	if err != nil {
 		return err
 	}
-	if _, ok := sourceOrModuleRef.(buffetch.ModuleRef); ok && flags.Write {
-		return fmt.Errorf("--%s cannot be used with module reference inputs", writeFlagName)
+	if !buffetch.IsLocalSourceRef(sourceOrModuleRef) {
+		// Only files in local directories can be rewritten in-place, or
+		// compared with the working tree of a git repository.
+		for _, localOnlyFlag := range []struct {
+			name  string
+			value bool
+		}{
+			{name: writeFlagName, value: flags.Write},
+			{name: onlyChangedFlagName, value: flags.OnlyChanged},
+			{name: editorConfigFlagName, value: flags.EditorConfig},
+		} {
+			if !localOnlyFlag.value {
+				continue
+			}
+			flagName := localOnlyFlag.name
+			if _, ok := sourceOrModuleRef.(buffetch.ModuleRef); ok {
+				return fmt.Errorf("--%s cannot be used with module reference inputs", flagName)
+			}
+			return fmt.Errorf("--%s cannot be used with archive or git inputs", flagName)
+		}
 	}
 	clientConfig, err := bufcli.NewConnectClientConfig(container)
 	if err != nil {
@@ -273,7 +490,23 @@ func run(
 	if err != nil {
 		return err
 	}
+	var editorConfigReader editorconfig.Reader
+	if flags.EditorConfig {
+		// The .editorconfig files can only be read for files on disk.
+		editorConfigReader = editorconfig.NewReader()
+	}
 	runner := command.NewRunner()
+	var changedFilePaths map[string]struct{}
+	if flags.OnlyChanged {
+		dirPath, ok := buffetch.GetLocalSourceRefDirPath(sourceOrModuleRef)
+		if !ok {
+			return fmt.Errorf("--%s can only be used with local directory or file inputs", onlyChangedFlagName)
+		}
+		changedFilePaths, err = getChangedFilePaths(ctx, container, runner, dirPath, flags.AgainstGitRef)
+		if err != nil {
+			return err
+		}
+	}
 	storageosProvider := bufcli.NewStorageosProvider(flags.DisableSymlinks)
 	moduleConfigReader, err := bufcli.NewWireModuleConfigReaderForModuleReader(
 		container,
@@ -297,6 +530,9 @@ func run(
 	if err != nil {
 		return err
 	}
+	defer func() {
+		retErr = multierr.Append(retErr, moduleConfigSet.Close())
+	}()
 	moduleConfigs := moduleConfigSet.ModuleConfigs()
 	var outputDirectory string
 	var singleFileOutputFilename string
@@ -404,6 +640,16 @@ func run(
 		if err != nil {
 			return err
 		}
+		if changedFilePaths != nil {
+			module, err = moduleWithOnlyChangedFiles(ctx, module, changedFilePaths)
+			if err != nil {
+				return err
+			}
+			if module == nil {
+				// The file has not changed, so there is nothing to format.
+				return nil
+			}
+		}
 		diffPresent, err := formatModule(
 			ctx,
 			container,
@@ -415,6 +661,7 @@ func run(
 			flags.ErrorFormat,
 			flags.Diff,
 			flags.Write,
+			flags.formatOptions(moduleConfigs[0].Config(), editorConfigReader),
 		)
 		if err != nil {
 			return err
@@ -425,17 +672,29 @@ func run(
 		return nil
 	}
 	for _, moduleConfig := range moduleConfigs {
+		module := moduleConfig.Module()
+		if changedFilePaths != nil {
+			module, err = moduleWithOnlyChangedFiles(ctx, module, changedFilePaths)
+			if err != nil {
+				return err
+			}
+			if module == nil {
+				// None of the module's files have changed.
+				continue
+			}
+		}
 		diffPresent, err := formatModule(
 			ctx,
 			container,
 			runner,
 			storageosProvider,
-			moduleConfig.Module(),
+			module,
 			outputDirectory,
 			singleFileOutputFilename,
 			flags.ErrorFormat,
 			flags.Diff,
 			flags.Write,
+			flags.formatOptions(moduleConfig.Config(), editorConfigReader),
 		)
 		if err != nil {
 			return err
@@ -447,6 +706,266 @@ func run(
 	return nil
 }
 
+// runRange formats the range of lines given by --range of the file read from
+// stdin, and writes the replacement for the range to stdout.
+func runRange(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+	source string,
+) error {
+	if source != "-" {
+		return fmt.Errorf("--%s can only be used with the source -", rangeFlagName)
+	}
+	for _, flagName := range []string{
+		diffFlagName,
+		exitCodeFlagName,
+		onlyChangedFlagName,
+		outputFlagName,
+		writeFlagName,
+	} {
+		if flags.flagSet.Changed(flagName) {
+			return fmt.Errorf("--%s cannot be used with --%s", flagName, rangeFlagName)
+		}
+	}
+	startLine, endLine, err := parseRange(flags.Range)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(container.Stdin())
+	if err != nil {
+		return err
+	}
+	readWriteBucket, err := bufcli.NewStorageosProvider(flags.DisableSymlinks).NewReadWriteBucket(
+		".",
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return err
+	}
+	config, err := bufconfig.ReadConfigOS(
+		ctx,
+		readWriteBucket,
+		bufconfig.ReadConfigOSWithOverride(flags.Config),
+	)
+	if err != nil {
+		return err
+	}
+	startLine, endLine, err = bufformat.FormatRange(
+		container.Stdout(),
+		"<stdin>",
+		data,
+		startLine,
+		endLine,
+		flags.formatOptions(config, nil)...,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(container.Stderr(), "%d:%d\n", startLine, endLine)
+	return err
+}
+
+// parseRange parses a range in the form start:end.
+func parseRange(value string) (int, int, error) {
+	start, end, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("--%s must be in the form start:end, but was %q", rangeFlagName, value)
+	}
+	startLine, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("--%s must be in the form start:end, but was %q", rangeFlagName, value)
+	}
+	endLine, err := strconv.Atoi(end)
+	if err != nil {
+		return 0, 0, fmt.Errorf("--%s must be in the form start:end, but was %q", rangeFlagName, value)
+	}
+	if startLine < 1 || endLine < startLine {
+		return 0, 0, fmt.Errorf("--%s must have a start line of at least 1 and an end line of at least the start line, but was %q", rangeFlagName, value)
+	}
+	return startLine, endLine, nil
+}
+
+// getChangedFilePaths returns the absolute paths of the files in the directory
+// that differ from the given git ref, or that are untracked.
+func getChangedFilePaths(
+	ctx context.Context,
+	container appflag.Container,
+	runner command.Runner,
+	dirPath string,
+	gitRef string,
+) (map[string]struct{}, error) {
+	changedFiles, err := git.NewLister(runner).ListChangedFiles(ctx, container, dirPath, gitRef)
+	if err != nil {
+		return nil, fmt.Errorf("could not list the files changed relative to git ref %q: %w", gitRef, err)
+	}
+	changedFilePaths := make(map[string]struct{}, len(changedFiles))
+	for _, changedFile := range changedFiles {
+		changedFilePath, err := filepath.Abs(filepath.Join(dirPath, changedFile))
+		if err != nil {
+			return nil, err
+		}
+		changedFilePaths[changedFilePath] = struct{}{}
+	}
+	return changedFilePaths, nil
+}
+
+// moduleWithOnlyChangedFiles returns the module with only the target files
+// that are in changedFilePaths, or nil if there are none.
+func moduleWithOnlyChangedFiles(
+	ctx context.Context,
+	module bufmodule.Module,
+	changedFilePaths map[string]struct{},
+) (bufmodule.Module, error) {
+	fileInfos, err := module.TargetFileInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var targetPaths []string
+	for _, fileInfo := range fileInfos {
+		externalPath, err := filepath.Abs(fileInfo.ExternalPath())
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := changedFilePaths[externalPath]; ok {
+			targetPaths = append(targetPaths, fileInfo.Path())
+		}
+	}
+	if len(targetPaths) == 0 {
+		return nil, nil
+	}
+	return bufmodule.ModuleWithTargetPaths(module, targetPaths, nil)
+}
+
+// formatOptions returns the options to format a module with the given config.
+// The flags take precedence over the format section of the config, which takes
+// precedence over the .editorconfig files read by the editorConfigReader, if
+// it is not nil.
+func (f *flags) formatOptions(config *bufconfig.Config, editorConfigReader editorconfig.Reader) []bufformat.FormatOption {
+	formatConfig := &bufconfig.FormatConfig{}
+	if config != nil && config.Format != nil {
+		formatConfig = config.Format
+	}
+	indent := formatConfig.Indent
+	if f.flagSet.Changed(indentFlagName) {
+		indent = f.Indent
+	}
+	useTabs := formatConfig.UseTabs
+	// Spaces are only set explicitly with --use-tabs=false, so that it
+	// overrides the indent_style of the .editorconfig files.
+	var useSpaces bool
+	if f.flagSet.Changed(useTabsFlagName) {
+		useTabs = f.UseTabs
+		useSpaces = !f.UseTabs
+	}
+	maxLineWidth := formatConfig.MaxLineWidth
+	if f.flagSet.Changed(maxLineWidthFlagName) {
+		maxLineWidth = f.MaxLineWidth
+	}
+	groupImports := formatConfig.GroupImports
+	if f.flagSet.Changed(groupImportsFlagName) {
+		groupImports = f.GroupImports
+	}
+	alignFields := formatConfig.AlignFields
+	if f.flagSet.Changed(alignFieldsFlagName) {
+		alignFields = f.AlignFields
+	}
+	canonicalOrder := formatConfig.CanonicalOrder
+	if f.flagSet.Changed(canonicalOrderFlagName) {
+		canonicalOrder = f.CanonicalOrder
+	}
+	sortEnumValues := formatConfig.SortEnumValues
+	if f.flagSet.Changed(sortEnumValuesFlagName) {
+		sortEnumValues = f.SortEnumValues
+	}
+	lineDocComments := formatConfig.LineDocComments
+	if f.flagSet.Changed(lineDocCommentsFlagName) {
+		lineDocComments = f.LineDocComments
+	}
+	commentSpace := formatConfig.CommentSpace
+	if f.flagSet.Changed(commentSpaceFlagName) {
+		commentSpace = f.CommentSpace
+	}
+	reflowComments := formatConfig.ReflowComments
+	if f.flagSet.Changed(reflowCommentsFlagName) {
+		reflowComments = f.ReflowComments
+	}
+	var formatOptions []bufformat.FormatOption
+	if indent > 0 {
+		formatOptions = append(formatOptions, bufformat.FormatWithIndent(indent))
+	}
+	if useTabs {
+		formatOptions = append(formatOptions, bufformat.FormatWithTabs())
+	}
+	if useSpaces {
+		formatOptions = append(formatOptions, bufformat.FormatWithSpaces())
+	}
+	if maxLineWidth > 0 {
+		formatOptions = append(formatOptions, bufformat.FormatWithMaxLineWidth(maxLineWidth))
+	}
+	if groupImports {
+		formatOptions = append(formatOptions, bufformat.FormatWithImportGroups())
+	}
+	if alignFields {
+		formatOptions = append(formatOptions, bufformat.FormatWithAlignedFields())
+	}
+	if canonicalOrder {
+		formatOptions = append(formatOptions, bufformat.FormatWithCanonicalOrder())
+	}
+	if sortEnumValues {
+		formatOptions = append(formatOptions, bufformat.FormatWithSortedEnumValues())
+	}
+	if lineDocComments {
+		formatOptions = append(formatOptions, bufformat.FormatWithLineDocComments())
+	}
+	if commentSpace {
+		formatOptions = append(formatOptions, bufformat.FormatWithCommentSpace())
+	}
+	if reflowComments {
+		formatOptions = append(formatOptions, bufformat.FormatWithReflowedComments())
+	}
+	if editorConfigReader != nil {
+		formatOptions = append(
+			formatOptions,
+			bufformat.FormatWithFileOptions(
+				func(externalPath string) ([]bufformat.FormatOption, error) {
+					properties, err := editorConfigReader.GetProperties(externalPath)
+					if err != nil {
+						return nil, err
+					}
+					return editorConfigFormatOptions(properties), nil
+				},
+			),
+		)
+	}
+	return formatOptions
+}
+
+// editorConfigFormatOptions returns the options to format a file with the
+// given .editorconfig properties.
+func editorConfigFormatOptions(properties editorconfig.Properties) []bufformat.FormatOption {
+	var formatOptions []bufformat.FormatOption
+	if properties.IndentStyle() == editorconfig.IndentStyleTab {
+		formatOptions = append(formatOptions, bufformat.FormatWithTabs())
+		// With tabs, the indent is the width of a tab.
+		if tabWidth := properties.TabWidth(); tabWidth > 0 {
+			formatOptions = append(formatOptions, bufformat.FormatWithIndent(tabWidth))
+		}
+	} else if indentSize := properties.IndentSize(); indentSize > 0 {
+		formatOptions = append(formatOptions, bufformat.FormatWithIndent(indentSize))
+	}
+	switch properties.EndOfLine() {
+	case editorconfig.EndOfLineCRLF:
+		formatOptions = append(formatOptions, bufformat.FormatWithLineEnding("\r\n"))
+	case editorconfig.EndOfLineCR:
+		formatOptions = append(formatOptions, bufformat.FormatWithLineEnding("\r"))
+	}
+	if insertFinalNewline, ok := properties.InsertFinalNewline(); ok && !insertFinalNewline {
+		formatOptions = append(formatOptions, bufformat.FormatWithoutFinalNewline())
+	}
+	return formatOptions
+}
+
 // formatModule formats the module's target files and writes them to the
 // writeBucket, if any. If diff is true, the diff between the original and
 // formatted files is written to stdout.
@@ -463,6 +982,7 @@ func formatModule(
 	errorFormat string,
 	diff bool,
 	rewrite bool,
+	formatOptions []bufformat.FormatOption,
 ) (_ bool, retErr error) {
 	originalReadWriteBucket := storagemem.NewReadWriteBucket()
 	if err := bufmodule.TargetModuleFilesToBucket(
@@ -473,7 +993,7 @@ func formatModule(
 		return false, err
 	}
 	// Note that external paths are set properly for the files in this read bucket.
-	formattedReadBucket, err := bufformat.FormatModule(ctx, module)
+	formattedReadBucket, err := bufformat.FormatModule(ctx, module, formatOptions...)
 	if err != nil {
 		return false, err
 	}
@@ -490,7 +1010,21 @@ func formatModule(
 	}
 	diffPresent := diffBuffer.Len() > 0
 	if diff {
-		if _, err := io.Copy(container.Stdout(), diffBuffer); err != nil {
+		errorFormatValue, err := bufanalysis.ParseFormat(errorFormat)
+		if err != nil {
+			return false, err
+		}
+		if errorFormatValue == bufanalysis.FormatJSON {
+			if err := writeJSONDiffs(
+				ctx,
+				container.Stdout(),
+				runner,
+				originalReadWriteBucket,
+				formattedReadBucket,
+			); err != nil {
+				return false, err
+			}
+		} else if _, err := io.Copy(container.Stdout(), diffBuffer); err != nil {
 			return false, err
 		}
 		if outputDirectory == "" && singleFileOutputFilename == "" && !rewrite {
@@ -619,3 +1153,67 @@ func formatModule(
 	}
 	return diffPresent, nil
 }
+
+// jsonDiff is the record written for each file for --diff with
+// --error-format=json.
+type jsonDiff struct {
+	// The external path of the file.
+	Path string `json:"path"`
+	// Whether the formatted file differs from the original.
+	Differs bool `json:"differs"`
+	// The unified diff between the original and formatted file, if it differs.
+	Diff string `json:"diff,omitempty"`
+}
+
+// writeJSONDiffs writes a jsonDiff as a line of JSON for each of the original
+// files, in order of their paths.
+func writeJSONDiffs(
+	ctx context.Context,
+	writer io.Writer,
+	runner command.Runner,
+	originalReadBucket storage.ReadBucket,
+	formattedReadBucket storage.ReadBucket,
+) error {
+	paths, err := storage.AllPaths(ctx, originalReadBucket, "")
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	encoder := json.NewEncoder(writer)
+	for _, path := range paths {
+		objectInfo, err := originalReadBucket.Stat(ctx, path)
+		if err != nil {
+			return err
+		}
+		originalData, err := storage.ReadPath(ctx, originalReadBucket, path)
+		if err != nil {
+			return err
+		}
+		formattedData, err := storage.ReadPath(ctx, formattedReadBucket, path)
+		if err != nil {
+			return err
+		}
+		// The paths match those of the text diffs, which also use external paths.
+		diffData, err := diff.Diff(
+			ctx,
+			runner,
+			originalData,
+			formattedData,
+			objectInfo.ExternalPath(),
+			objectInfo.ExternalPath(),
+		)
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(
+			jsonDiff{
+				Path:    objectInfo.ExternalPath(),
+				Differs: len(diffData) > 0,
+				Diff:    string(diffData),
+			},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -48,6 +48,8 @@ const (
 	disableSymlinksFlagName     = "disable-symlinks"
 	typeFlagName                = "type"
 	typeDeprecatedFlagName      = "include-types"
+	lockedFlagName              = "locked"
+	manifestFlagName            = "manifest"
 )
 
 // NewCommand returns a new Command.
@@ -83,7 +85,11 @@ func NewCommand(
         # The custom path to the plugin binary, if not protoc-gen-NAME on your $PATH.
         # Optional, and exclusive with "remote".
         path: custom-gen-go
-        # The generation strategy to use. There are two options:
+        # The expected hex-encoded SHA-256 digest of the plugin binary at "path".
+        # If set, buf verifies the binary before executing it and errors on mismatch.
+        # Optional, and requires "path" to be set.
+        sha256: 2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae
+        # The generation strategy to use. There are three options:
         #
         # 1. "directory"
         #
@@ -106,6 +112,14 @@ func NewCommand(
         #
         #   This is needed for certain plugins that expect all files to be given at once.
         #
+        # 3. "package"
+        #
+        #   This will result in buf splitting the input files by package, and making separate plugin
+        #   invocations in parallel, each with only the files of a single package to generate.
+        #
+        #   This is needed for certain plugins, such as documentation and gateway generators, that
+        #   expect the files of a package to be given together, even if they span directories.
+        #
         # If omitted, "directory" is used. Most users should not need to set this option.
         # Optional.
         strategy: directory
@@ -115,6 +129,37 @@ func NewCommand(
         # If version is omitted, uses the latest version of the plugin.
       - plugin: buf.build/protocolbuffers/python:v21.9
         out: gen/python
+        # Use the latest plugin version matching a constraint. The resolved version is
+        # recorded in "buf.gen.lock" in the current directory and reused until the
+        # constraint changes. Constraints are of the form ^1.31, ~1.31.0, or >=1.28.0, <1.32.0.
+      - plugin: buf.build/protocolbuffers/go:^1.31
+        out: gen/go
+    # The inputs to generate for. Each input is built separately, and the plugins
+    # are invoked once per input. If an input is given on the command line, this is ignored.
+    # Optional.
+    inputs:
+        # The input to generate for, in the same format as the command line argument.
+        # Required.
+      - input: proto
+        # Only generate for these types and their dependencies.
+        # Optional.
+        types:
+          - acme.weather.v1.WeatherService
+        # Only generate for the files in these paths.
+        # Optional.
+        paths:
+          - proto/acme/weather
+        # Exclude these paths from generation.
+        # Optional.
+        exclude_paths:
+          - proto/acme/weather/internal
+        # Also generate all imports except for Well-Known Types.
+        # Optional.
+        include_imports: true
+        # Also generate Well-Known Types. Cannot be set without include_imports.
+        # Optional.
+        include_wkt: false
+      - input: buf.build/acme/petapis
 
 As an example, here's a typical "buf.gen.yaml" go and grpc, assuming
 "protoc-gen-go" and "protoc-gen-go-grpc" are on your "$PATH":
@@ -177,11 +222,26 @@ Note that all paths must be contained within the same module. For example, if yo
 module in "proto", you cannot specify "--path proto", however "--path proto/foo" is allowed
 as "proto/foo" is contained within "proto".
 
+If you only want to generate stubs for specific types, you can do so via --type. Only the
+given types and the types they depend on, transitively, are passed to plugins. The types can
+be packages, messages, enums, extensions, services, or methods. e.g.
+
+Only generate for the service acme.orders.v1.OrderService and its requests and responses:
+
+    $ buf generate --type acme.orders.v1.OrderService
+
 Plugins are invoked in the order they are specified in the template, but each plugin
 has a per-directory parallel invocation, with results from each invocation combined
 before writing the result.
 
 Insertion points are processed in the order the plugins are specified in the template.
+
+With --manifest, a provenance manifest is written to buf.gen.manifest.json in the base
+output directory. It records the digest of the image built from each input and the remote
+modules it contains, the plugins with their versions or the digests of their binaries,
+and the digest of every generated file:
+
+    $ buf generate --manifest
 `,
 		Args: cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -209,6 +269,8 @@ type flags struct {
 	// want to find out what will break if we do.
 	Types           []string
 	TypesDeprecated []string
+	Locked          bool
+	Manifest        bool
 	// special
 	InputHashtag string
 }
@@ -277,6 +339,24 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		nil,
 		"The types (package, message, enum, extension, service, method) that should be included in this image. When specified, the resulting image will only include descriptors to describe the requested types. Flag usage overrides buf.gen.yaml",
 	)
+	flagSet.BoolVar(
+		&f.Locked,
+		lockedFlagName,
+		false,
+		fmt.Sprintf(
+			"Fail if remote plugin version constraints are not exactly matched by the versions locked in %s, instead of updating it",
+			bufgen.ExternalLockFilePath,
+		),
+	)
+	flagSet.BoolVar(
+		&f.Manifest,
+		manifestFlagName,
+		false,
+		fmt.Sprintf(
+			"Write a provenance manifest of the inputs, plugins, and generated files to %s in the base output directory",
+			bufgen.ExternalManifestFilePath,
+		),
+	)
 	_ = flagSet.MarkDeprecated(typeDeprecatedFlagName, fmt.Sprintf("Use --%s instead", typeFlagName))
 	_ = flagSet.MarkHidden(typeDeprecatedFlagName)
 }
@@ -297,14 +377,6 @@ func run(
 	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
 		return err
 	}
-	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
-	if err != nil {
-		return err
-	}
-	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, input)
-	if err != nil {
-		return err
-	}
 	storageosProvider := bufcli.NewStorageosProvider(flags.DisableSymlinks)
 	runner := command.NewRunner()
 	readWriteBucket, err := storageosProvider.NewReadWriteBucket(
@@ -320,14 +392,37 @@ func run(
 		bufgen.NewProvider(logger),
 		readWriteBucket,
 		bufgen.ReadConfigWithOverride(flags.Template),
+		bufgen.ReadConfigWithProfile(bufcli.GetProfile(container)),
+		bufgen.ReadConfigWithEnvContainer(container),
 	)
 	if err != nil {
 		return err
 	}
+	inputConfigs, err := getInputConfigs(container, flags, genConfig)
+	if err != nil {
+		return err
+	}
 	clientConfig, err := bufcli.NewConnectClientConfig(container)
 	if err != nil {
 		return err
 	}
+	var resolvePluginVersionsOptions []bufgen.ResolvePluginVersionsOption
+	if flags.Locked {
+		resolvePluginVersionsOptions = append(
+			resolvePluginVersionsOptions,
+			bufgen.ResolvePluginVersionsWithLocked(),
+		)
+	}
+	if err := bufgen.ResolvePluginVersions(
+		ctx,
+		logger,
+		clientConfig,
+		readWriteBucket,
+		genConfig,
+		resolvePluginVersionsOptions...,
+	); err != nil {
+		return err
+	}
 	imageConfigReader, err := bufcli.NewWireImageConfigReader(
 		container,
 		storageosProvider,
@@ -337,87 +432,174 @@ func run(
 	if err != nil {
 		return err
 	}
-	imageConfigs, fileAnnotations, err := imageConfigReader.GetImageConfigs(
-		ctx,
-		container,
-		ref,
-		flags.Config,
-		flags.Paths,        // we filter on files
-		flags.ExcludePaths, // we exclude these paths
-		false,              // input files must exist
-		false,              // we must include source info for generation
-	)
+	wasmEnabled, err := bufcli.IsAlphaWASMEnabled(container)
 	if err != nil {
 		return err
 	}
-	if len(fileAnnotations) > 0 {
-		if err := bufanalysis.PrintFileAnnotations(container.Stderr(), fileAnnotations, flags.ErrorFormat); err != nil {
-			return err
-		}
-		return bufcli.ErrFileAnnotation
-	}
-	images := make([]bufimage.Image, 0, len(imageConfigs))
-	for _, imageConfig := range imageConfigs {
-		images = append(images, imageConfig.Image())
-	}
-	image, err := bufimage.MergeImages(images...)
+	wasmPluginExecutor, err := bufwasm.NewPluginExecutor(
+		filepath.Join(container.CacheDirPath(), bufcli.WASMCompilationCacheDir))
 	if err != nil {
 		return err
 	}
-	generateOptions := []bufgen.GenerateOption{
-		bufgen.GenerateWithBaseOutDirPath(flags.BaseOutDirPath),
-	}
-	if flags.IncludeImports {
-		generateOptions = append(
-			generateOptions,
-			bufgen.GenerateWithIncludeImports(),
-		)
+	generator := bufgen.NewGenerator(
+		logger,
+		storageosProvider,
+		runner,
+		wasmPluginExecutor,
+		clientConfig,
+	)
+	var manifest *bufgen.Manifest
+	if flags.Manifest {
+		manifest = bufgen.NewManifest()
 	}
-	if flags.IncludeWKT {
-		generateOptions = append(
-			generateOptions,
-			bufgen.GenerateWithIncludeWellKnownTypes(),
+	// Each input is built and generated separately, in the order specified.
+	for _, inputConfig := range inputConfigs {
+		ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, inputConfig.Input)
+		if err != nil {
+			return err
+		}
+		imageConfigs, fileAnnotations, err := imageConfigReader.GetImageConfigs(
+			ctx,
+			container,
+			ref,
+			flags.Config,
+			inputConfig.Paths,        // we filter on files
+			inputConfig.ExcludePaths, // we exclude these paths
+			false,                    // input files must exist
+			false,                    // we must include source info for generation
 		)
+		if err != nil {
+			return err
+		}
+		if len(fileAnnotations) > 0 {
+			if err := bufanalysis.PrintFileAnnotations(container.Stderr(), fileAnnotations, flags.ErrorFormat); err != nil {
+				return err
+			}
+			return bufcli.ErrFileAnnotation
+		}
+		images := make([]bufimage.Image, 0, len(imageConfigs))
+		for _, imageConfig := range imageConfigs {
+			images = append(images, imageConfig.Image())
+		}
+		image, err := bufimage.MergeImages(images...)
+		if err != nil {
+			return err
+		}
+		generateOptions := []bufgen.GenerateOption{
+			bufgen.GenerateWithBaseOutDirPath(flags.BaseOutDirPath),
+		}
+		if inputConfig.IncludeImports {
+			generateOptions = append(
+				generateOptions,
+				bufgen.GenerateWithIncludeImports(),
+			)
+		}
+		if inputConfig.IncludeWKT {
+			generateOptions = append(
+				generateOptions,
+				bufgen.GenerateWithIncludeWellKnownTypes(),
+			)
+		}
+		if wasmEnabled {
+			generateOptions = append(
+				generateOptions,
+				bufgen.GenerateWithWASMEnabled(),
+			)
+		}
+		if manifest != nil {
+			generateOptions = append(
+				generateOptions,
+				bufgen.GenerateWithManifest(manifest, inputConfig.Input),
+			)
+		}
+		if len(inputConfig.Types) > 0 {
+			image, err = bufimageutil.ImageFilteredByTypes(image, inputConfig.Types...)
+			if err != nil {
+				return err
+			}
+		}
+		if err := generator.Generate(
+			ctx,
+			container,
+			genConfig,
+			image,
+			generateOptions...,
+		); err != nil {
+			return err
+		}
 	}
-	wasmEnabled, err := bufcli.IsAlphaWASMEnabled(container)
-	if err != nil {
-		return err
-	}
-	if wasmEnabled {
-		generateOptions = append(
-			generateOptions,
-			bufgen.GenerateWithWASMEnabled(),
+	if manifest != nil {
+		manifestReadWriteBucket, err := storageosProvider.NewReadWriteBucket(
+			flags.BaseOutDirPath,
+			storageos.ReadWriteBucketWithSymlinksIfSupported(),
 		)
+		if err != nil {
+			return err
+		}
+		return bufgen.WriteManifest(ctx, manifestReadWriteBucket, manifest)
 	}
+	return nil
+}
+
+// getInputConfigs returns the inputs to generate for.
+//
+// If an input is specified on the command line, or no inputs are specified in
+// the generation template, a single input is returned that is entirely derived
+// from the flags. Otherwise, the inputs from the generation template are returned,
+// with any of --path, --exclude-path and --type overriding the corresponding values
+// of each input, and --include-imports and --include-wkt applying to all inputs.
+func getInputConfigs(
+	container appflag.Container,
+	flags *flags,
+	genConfig *bufgen.Config,
+) ([]*bufgen.InputConfig, error) {
 	var includedTypes []string
 	if len(flags.Types) > 0 || len(flags.TypesDeprecated) > 0 {
 		// command-line flags take precedence
 		includedTypes = append(flags.Types, flags.TypesDeprecated...)
-	} else if genConfig.TypesConfig != nil {
-		includedTypes = genConfig.TypesConfig.Include
 	}
-	if len(includedTypes) > 0 {
-		image, err = bufimageutil.ImageFilteredByTypes(image, includedTypes...)
+	if container.NumArgs() > 0 || flags.InputHashtag != "" || len(genConfig.InputConfigs) == 0 {
+		input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if len(includedTypes) == 0 && genConfig.TypesConfig != nil {
+			includedTypes = genConfig.TypesConfig.Include
 		}
+		return []*bufgen.InputConfig{
+			{
+				Input:          input,
+				Types:          includedTypes,
+				Paths:          flags.Paths,
+				ExcludePaths:   flags.ExcludePaths,
+				IncludeImports: flags.IncludeImports,
+				IncludeWKT:     flags.IncludeWKT,
+			},
+		}, nil
 	}
-	wasmPluginExecutor, err := bufwasm.NewPluginExecutor(
-		filepath.Join(container.CacheDirPath(), bufcli.WASMCompilationCacheDir))
-	if err != nil {
-		return err
+	inputConfigs := make([]*bufgen.InputConfig, 0, len(genConfig.InputConfigs))
+	for _, genInputConfig := range genConfig.InputConfigs {
+		inputConfig := &bufgen.InputConfig{
+			Input:          genInputConfig.Input,
+			Types:          genInputConfig.Types,
+			Paths:          genInputConfig.Paths,
+			ExcludePaths:   genInputConfig.ExcludePaths,
+			IncludeImports: genInputConfig.IncludeImports || flags.IncludeImports,
+			IncludeWKT:     genInputConfig.IncludeWKT || flags.IncludeWKT,
+		}
+		if len(inputConfig.Types) == 0 && genConfig.TypesConfig != nil {
+			inputConfig.Types = genConfig.TypesConfig.Include
+		}
+		if len(includedTypes) > 0 {
+			inputConfig.Types = includedTypes
+		}
+		if len(flags.Paths) > 0 {
+			inputConfig.Paths = flags.Paths
+		}
+		if len(flags.ExcludePaths) > 0 {
+			inputConfig.ExcludePaths = flags.ExcludePaths
+		}
+		inputConfigs = append(inputConfigs, inputConfig)
 	}
-	return bufgen.NewGenerator(
-		logger,
-		storageosProvider,
-		runner,
-		wasmPluginExecutor,
-		clientConfig,
-	).Generate(
-		ctx,
-		container,
-		genConfig,
-		image,
-		generateOptions...,
-	)
+	return inputConfigs, nil
 }
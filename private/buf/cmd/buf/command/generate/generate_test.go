@@ -168,6 +168,47 @@ func TestProtoFileRefIncludePackageFiles(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestGenerateTypes(t *testing.T) {
+	t.Parallel()
+	tempDirPath := t.TempDir()
+	testRunSuccess(
+		t,
+		"--output",
+		tempDirPath,
+		"--template",
+		filepath.Join("testdata", "types", "buf.gen.yaml"),
+		filepath.Join("testdata", "types"),
+	)
+	_, err := os.Stat(filepath.Join(tempDirPath, "java", "a", "v1", "A.java"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tempDirPath, "java", "b", "v1", "B.java"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(tempDirPath, "java", "c", "v1", "C.java"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestGenerateTypesFlag(t *testing.T) {
+	t.Parallel()
+	tempDirPath := t.TempDir()
+	testRunSuccess(
+		t,
+		"--output",
+		tempDirPath,
+		"--template",
+		filepath.Join("testdata", "types", "buf.gen.yaml"),
+		"--type",
+		"b.v1.FooService",
+		filepath.Join("testdata", "types"),
+	)
+	// The files of the dependencies of the service are generated as well.
+	_, err := os.Stat(filepath.Join(tempDirPath, "java", "a", "v1", "A.java"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tempDirPath, "java", "b", "v1", "B.java"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tempDirPath, "java", "c", "v1", "C.java"))
+	require.True(t, os.IsNotExist(err))
+}
+
 func TestGenerateDuplicatePlugins(t *testing.T) {
 	t.Parallel()
 	tempDirPath := t.TempDir()
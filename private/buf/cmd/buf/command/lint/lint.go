@@ -20,6 +20,7 @@ import (
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufwire"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
@@ -118,11 +119,13 @@ func run(
 	if err != nil {
 		return err
 	}
+	// Lint only reads the source code info for comments and the locations of failures.
 	imageConfigReader, err := bufcli.NewWireImageConfigReader(
 		container,
 		storageosProvider,
 		runner,
 		clientConfig,
+		bufwire.ImageConfigReaderWithLazySourceCodeInfo(),
 	)
 	if err != nil {
 		return err
@@ -15,16 +15,24 @@
 package lsfiles
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufprint"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/data/datawkt"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -36,6 +44,7 @@ const (
 	errorFormatFlagName     = "error-format"
 	includeImportsFlagName  = "include-imports"
 	disableSymlinksFlagName = "disable-symlinks"
+	formatFlagName          = "format"
 )
 
 // NewCommand returns a new Command.
@@ -47,8 +56,18 @@ func NewCommand(
 	return &appcmd.Command{
 		Use:   name + " <input>",
 		Short: "List Protobuf files",
-		Long:  bufcli.GetInputLong(`the source, module, or image to list from`),
-		Args:  cobra.MaximumNArgs(1),
+		Long: `The files are printed one per line. If --format=json is set, the files are instead
+printed as one JSON object per line with the package, module, and direct imports of each
+file, whether the file is an import or a Well-Known Type, and the digest of the file, so
+that build systems can compute the dependency graph of the files. The input is built to
+read this information, so the JSON format is slower than the text format.
+
+The digest is the shake256 digest of the deterministically-serialized FileDescriptorProto
+of the file, including its source code info, so that the digest changes when the file changes
+for any kind of input, including images.
+
+` + bufcli.GetInputLong(`the source, module, or image to list from`),
+		Args: cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
 				return run(ctx, container, flags)
@@ -65,6 +84,7 @@ type flags struct {
 	ErrorFormat     string
 	IncludeImports  bool
 	DisableSymlinks bool
+	Format          string
 	// special
 	InputHashtag string
 }
@@ -103,6 +123,12 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		false,
 		"Include imports",
 	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
 }
 
 func run(
@@ -114,6 +140,13 @@ func run(
 	if err != nil {
 		return err
 	}
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	if format == bufprint.FormatJSON {
+		return runJSON(ctx, container, flags, input)
+	}
 	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, input)
 	if err != nil {
 		return err
@@ -171,3 +204,101 @@ func run(
 	}
 	return nil
 }
+
+// externalFile is the JSON representation of a file.
+type externalFile struct {
+	// Path is the path of the file as it is imported.
+	Path string `json:"path"`
+	// ExternalPath is the path of the file as it is referred to by the input.
+	ExternalPath string `json:"external_path"`
+	Package      string `json:"package,omitempty"`
+	// Module is the module that the file is from, if any.
+	Module string `json:"module,omitempty"`
+	// Commit is the commit of the module that the file is from, if known.
+	Commit string `json:"commit,omitempty"`
+	// Imports are the paths of the files that the file directly imports.
+	Imports []string `json:"imports,omitempty"`
+	// Import is true if the file is an import of the input, not a file of the input.
+	Import bool `json:"import,omitempty"`
+	// WKT is true if the file is a Well-Known Type.
+	WKT    bool   `json:"wkt,omitempty"`
+	Digest string `json:"digest"`
+}
+
+func runJSON(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+	input string,
+) error {
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		nil,
+		nil,
+		false,
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	var imageFiles []bufimage.ImageFile
+	for _, imageFile := range image.Files() {
+		if flags.IncludeImports || !imageFile.IsImport() {
+			imageFiles = append(imageFiles, imageFile)
+		}
+	}
+	if flags.AsImportPaths {
+		sort.Slice(imageFiles, func(i int, j int) bool {
+			return imageFiles[i].Path() < imageFiles[j].Path()
+		})
+	} else {
+		sort.Slice(imageFiles, func(i int, j int) bool {
+			return imageFiles[i].ExternalPath() < imageFiles[j].ExternalPath()
+		})
+	}
+	encoder := json.NewEncoder(container.Stdout())
+	for _, imageFile := range imageFiles {
+		externalFile, err := newExternalFile(imageFile)
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(externalFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newExternalFile(imageFile bufimage.ImageFile) (*externalFile, error) {
+	fileDescriptorProto := imageFile.FileDescriptorProto()
+	data, err := protoencoding.NewWireMarshaler().Marshal(fileDescriptorProto)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := bufcas.NewDigestForContent(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	externalFile := &externalFile{
+		Path:         imageFile.Path(),
+		ExternalPath: imageFile.ExternalPath(),
+		Package:      fileDescriptorProto.GetPackage(),
+		Imports:      fileDescriptorProto.GetDependency(),
+		Import:       imageFile.IsImport(),
+		Digest:       digest.String(),
+	}
+	if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil {
+		externalFile.Module = moduleIdentity.IdentityString()
+		externalFile.Commit = imageFile.Commit()
+	} else {
+		// The Well-Known Types are the only files without a module that
+		// are not part of the input.
+		externalFile.WKT = imageFile.IsImport() && datawkt.Exists(imageFile.Path())
+	}
+	return externalFile, nil
+}
@@ -102,6 +102,7 @@ func run(
 		ctx,
 		readWriteBucket,
 		bufconfig.ReadConfigOSWithOverride(flags.Config),
+		bufconfig.ReadConfigOSWithEnvContainer(container),
 	)
 	if err != nil {
 		return err
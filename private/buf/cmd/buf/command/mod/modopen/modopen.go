@@ -70,7 +70,7 @@ func run(
 	if existingConfigFilePath == "" {
 		return bufcli.ErrNoConfigFile
 	}
-	config, err := bufconfig.GetConfigForBucket(ctx, readWriteBucket)
+	config, err := bufconfig.GetConfigForBucket(ctx, readWriteBucket, bufconfig.GetConfigWithEnvContainer(container))
 	if err != nil {
 		return err
 	}
@@ -23,7 +23,6 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
 	"github.com/bufbuild/buf/private/bufpkg/buflock"
-	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
@@ -77,17 +76,17 @@ func run(
 	if existingConfigFilePath == "" {
 		return bufcli.ErrNoConfigFile
 	}
-	config, err := bufconfig.GetConfigForBucket(ctx, readWriteBucket)
+	config, err := bufconfig.GetConfigForBucket(ctx, readWriteBucket, bufconfig.GetConfigWithEnvContainer(container))
 	if err != nil {
 		return err
 	}
 
-	module, err := bufmodule.NewModuleForBucket(ctx, readWriteBucket)
+	currentDependencyModulePins, err := bufmoduleref.DependencyModulePinsForBucket(ctx, readWriteBucket)
 	if err != nil {
 		return fmt.Errorf("couldn't read current dependencies: %w", err)
 	}
 
-	requestReferences, err := referencesPinnedByLock(config.Build.DependencyModuleReferences, module.DependencyModulePins())
+	requestReferences, err := referencesPinnedByLock(config.Build.DependencyModuleReferences, currentDependencyModulePins)
 	if err != nil {
 		return err
 	}
@@ -135,7 +134,21 @@ func run(
 			return bufcli.NewInternalError(err)
 		}
 	}
-	if err := bufmoduleref.PutDependencyModulePinsToBucket(ctx, readWriteBucket, dependencyModulePins); err != nil {
+	var putDependencyModulePinsOptions []bufmoduleref.PutDependencyModulePinsOption
+	if config.Extends != nil {
+		// The pin of the module that the configuration extends is kept.
+		extendsModulePin, err := bufmoduleref.ExtendsModulePinForBucket(ctx, readWriteBucket)
+		if err != nil {
+			return err
+		}
+		if extendsModulePin != nil && extendsModulePin.IdentityString() == config.Extends.IdentityString() {
+			putDependencyModulePinsOptions = append(
+				putDependencyModulePinsOptions,
+				bufmoduleref.PutDependencyModulePinsWithExtendsModulePin(extendsModulePin),
+			)
+		}
+	}
+	if err := bufmoduleref.PutDependencyModulePinsToBucket(ctx, readWriteBucket, dependencyModulePins, putDependencyModulePinsOptions...); err != nil {
 		return err
 	}
 	return nil
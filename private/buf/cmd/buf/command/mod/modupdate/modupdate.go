@@ -20,6 +20,7 @@ import (
 
 	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufapimodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
 	"github.com/bufbuild/buf/private/bufpkg/buflock"
@@ -54,7 +55,28 @@ func NewCommand(
 		Long: "Fetch the latest digests for the specified references in the config file, " +
 			"and write them and their transitive dependencies to the " +
 			buflock.ExternalConfigFilePath +
-			` file. The first argument is the directory of the local module to update. Defaults to "." if no argument is specified.`,
+			` file. The first argument is the directory of the local module to update. Defaults to "." if no argument is specified.
+
+How each dependency is updated can be constrained with the dependency_constraints key of the ` + bufconfig.ExternalConfigV1FilePath + ` file:
+
+    version: v1
+    deps:
+      - buf.build/acme/weather
+      - buf.build/acme/petapis
+      - buf.build/acme/paymentapis
+    dependency_constraints:
+      # Update to the latest commit with the label v1, instead of main.
+      buf.build/acme/weather:
+        label: v1
+      # Update to the latest commit of the draft feature.
+      buf.build/acme/petapis:
+        draft: feature
+      # Do not update, and keep the commit in ` + buflock.ExternalConfigFilePath + `.
+      buf.build/acme/paymentapis:
+        exclude: true
+
+If the ` + bufconfig.ExternalConfigV1FilePath + ` file extends the configuration of a module with the extends key,
+the module is also pinned in the ` + buflock.ExternalConfigFilePath + ` file. It is not updated if --` + onlyFlagName + ` is set.`,
 		Args: cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
@@ -108,7 +130,7 @@ func run(
 	if existingConfigFilePath == "" {
 		return bufcli.ErrNoConfigFile
 	}
-	moduleConfig, err := bufconfig.GetConfigForBucket(ctx, readWriteBucket)
+	moduleConfig, err := bufconfig.GetConfigForBucket(ctx, readWriteBucket, bufconfig.GetConfigWithEnvContainer(container))
 	if err != nil {
 		return err
 	}
@@ -157,12 +179,17 @@ func run(
 	}
 	// Before updating buf.lock file, verify that no file path exists in more than one module.
 	pathToModuleIdentityStrings := make(map[string][]string)
-	currentModule, err := bufmodule.NewModuleForBucket(ctx, readWriteBucket)
+	// Only the .proto files are read, as the configuration was already read above,
+	// with its environment variables expanded.
+	currentModule, err := bufmodule.NewModuleForBucket(
+		ctx,
+		storage.MapReadBucket(readWriteBucket, storage.MatchPathExt(".proto")),
+	)
 	if err != nil {
 		return bufcli.NewInternalError(err)
 	}
 	currentModuleIdentityString := "the current module"
-	if currentModuleIdentity := currentModule.ModuleIdentity(); currentModuleIdentity != nil {
+	if currentModuleIdentity := moduleConfig.ModuleIdentity; currentModuleIdentity != nil {
 		currentModuleIdentityString = currentModuleIdentity.IdentityString()
 	}
 	currentModuleSourceFileInfos, err := currentModule.SourceFileInfos(ctx)
@@ -197,12 +224,56 @@ func run(
 			return fmt.Errorf("%s is found in multiple modules: %s\n%s", path, stringutil.SliceToHumanString(moduleIdentityStrings), explanation)
 		}
 	}
-	if err := bufmoduleref.PutDependencyModulePinsToBucket(ctx, readWriteBucket, dependencyModulePins); err != nil {
+	extendsModulePin, err := getExtendsModulePin(ctx, clientConfig, container, flags, moduleConfig, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	if err := bufmoduleref.PutDependencyModulePinsToBucket(
+		ctx,
+		readWriteBucket,
+		dependencyModulePins,
+		bufmoduleref.PutDependencyModulePinsWithExtendsModulePin(extendsModulePin),
+	); err != nil {
 		return bufcli.NewInternalError(err)
 	}
 	return nil
 }
 
+// getExtendsModulePin returns the pin of the module that the configuration extends,
+// or nil if it does not extend a module.
+//
+// The current pin is kept if only some dependencies are updated.
+func getExtendsModulePin(
+	ctx context.Context,
+	clientConfig *connectclient.Config,
+	container appflag.Container,
+	flags *flags,
+	moduleConfig *bufconfig.Config,
+	readWriteBucket storage.ReadWriteBucket,
+) (bufmoduleref.ModulePin, error) {
+	if moduleConfig.Extends == nil {
+		return nil, nil
+	}
+	if len(flags.Only) > 0 {
+		currentModulePin, err := bufmoduleref.ExtendsModulePinForBucket(ctx, readWriteBucket)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read current extends: %w", err)
+		}
+		if currentModulePin != nil && currentModulePin.IdentityString() == moduleConfig.Extends.IdentityString() {
+			return currentModulePin, nil
+		}
+	}
+	moduleResolver := bufapimodule.NewModuleResolver(
+		container.Logger(),
+		bufapimodule.NewRepositoryCommitServiceClientFactory(clientConfig),
+	)
+	modulePin, err := moduleResolver.GetModulePin(ctx, moduleConfig.Extends)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve extends %q: %w", moduleConfig.Extends.String(), err)
+	}
+	return modulePin, nil
+}
+
 func getDependencies(
 	ctx context.Context,
 	clientConfig *connectclient.Config,
@@ -236,28 +307,56 @@ func getDependencies(
 		))
 	}
 	service := connectclient.Make(clientConfig, remote, registryv1alpha1connect.NewResolveServiceClient)
+	dependencyModuleReferences, excludedIdentities, err := constrainedDependencyModuleReferences(moduleConfig)
+	if err != nil {
+		return nil, err
+	}
 	var protoDependencyModuleReferences []*modulev1alpha1.ModuleReference
 	var currentProtoModulePins []*modulev1alpha1.ModulePin
-	if len(flags.Only) > 0 {
+	if len(flags.Only) > 0 || len(excludedIdentities) > 0 {
+		// The current pins are kept for the dependencies that are not updated.
+		currentModulePins, err := bufmoduleref.DependencyModulePinsForBucket(ctx, readWriteBucket)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read current dependencies: %w", err)
+		}
+		currentProtoModulePins = bufmoduleref.NewProtoModulePinsForModulePins(currentModulePins...)
+		pinnedIdentities := make(map[string]struct{}, len(currentModulePins))
+		for _, currentModulePin := range currentModulePins {
+			pinnedIdentities[currentModulePin.IdentityString()] = struct{}{}
+		}
 		referencesByIdentity := map[string]bufmoduleref.ModuleReference{}
-		for _, reference := range moduleConfig.Build.DependencyModuleReferences {
+		for _, reference := range dependencyModuleReferences {
 			referencesByIdentity[reference.IdentityString()] = reference
 		}
-		for _, only := range flags.Only {
-			moduleReference, ok := referencesByIdentity[only]
-			if !ok {
-				return nil, fmt.Errorf("%q is not a valid --only input: no such dependency in current module deps", only)
+		if len(flags.Only) > 0 {
+			for _, onlyIdentity := range flags.Only {
+				moduleReference, ok := referencesByIdentity[onlyIdentity]
+				if !ok {
+					if _, ok := excludedIdentities[onlyIdentity]; ok {
+						return nil, fmt.Errorf("%q is not a valid --%s input: the dependency is excluded from updates by dependency_constraints", onlyIdentity, onlyFlagName)
+					}
+					return nil, fmt.Errorf("%q is not a valid --%s input: no such dependency in current module deps", onlyIdentity, onlyFlagName)
+				}
+				protoDependencyModuleReferences = append(protoDependencyModuleReferences, bufmoduleref.NewProtoModuleReferenceForModuleReference(moduleReference))
 			}
-			protoDependencyModuleReferences = append(protoDependencyModuleReferences, bufmoduleref.NewProtoModuleReferenceForModuleReference(moduleReference))
+		} else {
+			protoDependencyModuleReferences = bufmoduleref.NewProtoModuleReferencesForModuleReferences(
+				dependencyModuleReferences...,
+			)
 		}
-		currentModulePins, err := bufmoduleref.DependencyModulePinsForBucket(ctx, readWriteBucket)
-		if err != nil {
-			return nil, fmt.Errorf("couldn't read current dependencies: %w", err)
+		for _, reference := range moduleConfig.Build.DependencyModuleReferences {
+			if _, ok := excludedIdentities[reference.IdentityString()]; !ok {
+				continue
+			}
+			if _, ok := pinnedIdentities[reference.IdentityString()]; !ok {
+				// An excluded dependency that is not in buf.lock yet has no
+				// commit to stay at, so it is resolved.
+				protoDependencyModuleReferences = append(protoDependencyModuleReferences, bufmoduleref.NewProtoModuleReferenceForModuleReference(reference))
+			}
 		}
-		currentProtoModulePins = bufmoduleref.NewProtoModulePinsForModulePins(currentModulePins...)
 	} else {
 		protoDependencyModuleReferences = bufmoduleref.NewProtoModuleReferencesForModuleReferences(
-			moduleConfig.Build.DependencyModuleReferences...,
+			dependencyModuleReferences...,
 		)
 	}
 	resp, err := service.GetModulePins(
@@ -315,6 +414,39 @@ func getDependencies(
 	return allPinnedRepositories, nil
 }
 
+// constrainedDependencyModuleReferences returns the references of the
+// dependencies to update with the dependency constraints of the config
+// applied, and the identities of the dependencies that are excluded from
+// updates.
+func constrainedDependencyModuleReferences(
+	moduleConfig *bufconfig.Config,
+) ([]bufmoduleref.ModuleReference, map[string]struct{}, error) {
+	var moduleReferences []bufmoduleref.ModuleReference
+	excludedIdentities := make(map[string]struct{})
+	for _, moduleReference := range moduleConfig.Build.DependencyModuleReferences {
+		dependencyConstraint, ok := moduleConfig.DependencyConstraints[moduleReference.IdentityString()]
+		if !ok {
+			moduleReferences = append(moduleReferences, moduleReference)
+			continue
+		}
+		if dependencyConstraint.Exclude {
+			excludedIdentities[moduleReference.IdentityString()] = struct{}{}
+			continue
+		}
+		constrainedModuleReference, err := bufmoduleref.NewModuleReference(
+			moduleReference.Remote(),
+			moduleReference.Owner(),
+			moduleReference.Repository(),
+			dependencyConstraint.Reference(),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		moduleReferences = append(moduleReferences, constrainedModuleReference)
+	}
+	return moduleReferences, excludedIdentities, nil
+}
+
 type pinnedRepository struct {
 	modulePin  bufmoduleref.ModulePin
 	repository *registryv1alpha1.Repository
@@ -0,0 +1,216 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufapimodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/buflock"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const keyFlagName = "key"
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <directory>",
+		Short: fmt.Sprintf("Verify the signatures of the dependencies in the %s file", buflock.ExternalConfigFilePath),
+		Long: fmt.Sprintf(`Verify that the dependencies pinned in the %s file are signed with trusted keys.
+
+Every dependency must have a valid signature, as made by "buf push --sign", of the digest that
+the %s file pins it to, made by one of the trusted keys. The trusted keys are the public keys
+of --%s if set, and otherwise the keys of the trusted_keys section of the buf configuration,
+in which case the dependencies that the configuration has no keys for are skipped.
+
+The first argument is the directory of the local module to verify. Defaults to "." if no
+argument is specified.`,
+			buflock.ExternalConfigFilePath,
+			buflock.ExternalConfigFilePath,
+			keyFlagName,
+		),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Keys []string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringSliceVar(
+		&f.Keys,
+		keyFlagName,
+		nil,
+		`The path to a PEM-encoded public key that the dependencies must be signed with. Multiple keys are trusted if specified multiple times`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	directoryInput, err := bufcli.GetInputValue(container, "", ".")
+	if err != nil {
+		return err
+	}
+	trustRoots, err := getTrustRoots(container, flags.Keys)
+	if err != nil {
+		return err
+	}
+	storageosProvider := storageos.NewProvider(storageos.ProviderWithSymlinks())
+	readBucket, err := storageosProvider.NewReadWriteBucket(
+		directoryInput,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return err
+	}
+	existingConfigFilePath, err := bufconfig.ExistingConfigFilePath(ctx, readBucket)
+	if err != nil {
+		return err
+	}
+	if existingConfigFilePath == "" {
+		return bufcli.ErrNoConfigFile
+	}
+	dependencyModulePins, err := bufmoduleref.DependencyModulePinsForBucket(ctx, readBucket)
+	if err != nil {
+		return fmt.Errorf("couldn't read current dependencies: %w", err)
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	signatureReader := bufcli.NewSignatureReader(clientConfig)
+	var failed bool
+	for _, modulePin := range dependencyModulePins {
+		result, err := verify(ctx, signatureReader, trustRoots, modulePin)
+		if err != nil {
+			var verifyError *bufmodulesign.VerifyError
+			if !errors.As(err, &verifyError) {
+				return err
+			}
+			failed = true
+			result = "failed: " + err.Error()
+		}
+		if _, err := fmt.Fprintf(container.Stdout(), "%s: %s\n", modulePin.String(), result); err != nil {
+			return err
+		}
+	}
+	if failed {
+		return bufcli.ErrFileAnnotation
+	}
+	return nil
+}
+
+// verify verifies the dependency, and returns the result to print.
+//
+// Returns a *bufmodulesign.VerifyError if the dependency has no valid signature.
+func verify(
+	ctx context.Context,
+	signatureReader bufapimodule.SignatureReader,
+	trustRoots bufmodulesign.TrustRoots,
+	modulePin bufmoduleref.ModulePin,
+) (string, error) {
+	moduleIdentity, err := bufmoduleref.NewModuleIdentity(modulePin.Remote(), modulePin.Owner(), modulePin.Repository())
+	if err != nil {
+		return "", err
+	}
+	verifier := trustRoots.VerifierForModuleIdentity(moduleIdentity)
+	if verifier == nil {
+		return "skipped, no trusted keys", nil
+	}
+	if modulePin.Digest() == "" {
+		return "", fmt.Errorf(`%s has no digest in the %s file, run "buf mod update" to pin it`, modulePin.String(), buflock.ExternalConfigFilePath)
+	}
+	manifestDigest, err := bufcas.ParseDigest(modulePin.Digest())
+	if err != nil {
+		return "", fmt.Errorf("malformed digest %q of %s: %w", modulePin.Digest(), modulePin.String(), err)
+	}
+	signatures, err := signatureReader.GetSignatures(ctx, modulePin)
+	if err != nil {
+		return "", fmt.Errorf("could not get the signatures of %s: %w", modulePin.String(), err)
+	}
+	keyID, err := verifier.Verify(moduleIdentity, manifestDigest, signatures)
+	if err != nil {
+		return "", err
+	}
+	return "verified, signed with key " + keyID, nil
+}
+
+func getTrustRoots(container appflag.Container, keyFilePaths []string) (bufmodulesign.TrustRoots, error) {
+	if len(keyFilePaths) == 0 {
+		config, err := bufcli.NewConfig(container)
+		if err != nil {
+			return nil, err
+		}
+		if config.TrustRoots == nil {
+			return nil, appcmd.NewInvalidArgumentErrorf("no trusted keys, set --%s or the trusted_keys section of the buf configuration", keyFlagName)
+		}
+		return config.TrustRoots, nil
+	}
+	publicKeyPEMs := make([][]byte, len(keyFilePaths))
+	for i, keyFilePath := range keyFilePaths {
+		publicKeyPEM, err := os.ReadFile(keyFilePath)
+		if err != nil {
+			return nil, err
+		}
+		publicKeyPEMs[i] = publicKeyPEM
+	}
+	verifier, err := bufmodulesign.NewVerifier(publicKeyPEMs...)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %w", keyFlagName, err)
+	}
+	return allTrustRoots{verifier: verifier}, nil
+}
+
+// allTrustRoots are trust roots that require every module to be signed with
+// the keys of the verifier.
+type allTrustRoots struct {
+	verifier bufmodulesign.Verifier
+}
+
+func (a allTrustRoots) VerifierForModuleIdentity(bufmoduleref.ModuleIdentity) bufmodulesign.Verifier {
+	return a.verifier
+}
@@ -0,0 +1,131 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modverify
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateKeyData, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicKeyData, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	require.NoError(t, err)
+	signer, err := bufmodulesign.NewSigner(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyData}))
+	require.NoError(t, err)
+	trustRoots, err := bufmodulesign.NewTrustRoots(
+		map[string][][]byte{
+			"buf.build/acme": {pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyData})},
+		},
+	)
+	require.NoError(t, err)
+	manifestDigest, err := bufcas.NewDigestForContent(bytes.NewReader([]byte("manifest")))
+	require.NoError(t, err)
+	moduleIdentity, err := bufmoduleref.NewModuleIdentity("buf.build", "acme", "weather")
+	require.NoError(t, err)
+	signature, err := signer.Sign(moduleIdentity, manifestDigest)
+	require.NoError(t, err)
+	testCases := []struct {
+		desc           string
+		owner          string
+		digest         string
+		signatures     []*bufmodulesign.Signature
+		expectedResult string
+		expectedErr    string
+		expectedVerify bool
+	}{
+		{
+			desc:           "signed",
+			owner:          "acme",
+			digest:         manifestDigest.String(),
+			signatures:     []*bufmodulesign.Signature{signature},
+			expectedResult: "verified, signed with key " + signer.KeyID(),
+		},
+		{
+			desc:           "unsigned",
+			owner:          "acme",
+			digest:         manifestDigest.String(),
+			expectedErr:    "module buf.build/acme/weather has no signatures",
+			expectedVerify: true,
+		},
+		{
+			desc:           "signed for another digest",
+			owner:          "acme",
+			digest:         "shake256:" + strings.Repeat("00", 64),
+			signatures:     []*bufmodulesign.Signature{signature},
+			expectedErr:    "has no valid signature by a trusted key",
+			expectedVerify: true,
+		},
+		{
+			desc:        "no digest",
+			owner:       "acme",
+			expectedErr: `buf.build/acme/weather:commit has no digest in the buf.lock file`,
+		},
+		{
+			desc:           "no trusted keys",
+			owner:          "other",
+			digest:         manifestDigest.String(),
+			expectedResult: "skipped, no trusted keys",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.desc, func(t *testing.T) {
+			t.Parallel()
+			modulePin, err := bufmoduleref.NewModulePin("buf.build", testCase.owner, "weather", "commit", testCase.digest)
+			require.NoError(t, err)
+			result, err := verify(
+				context.Background(),
+				mockSignatureReader(testCase.signatures),
+				trustRoots,
+				modulePin,
+			)
+			if testCase.expectedErr != "" {
+				assert.ErrorContains(t, err, testCase.expectedErr)
+				var verifyError *bufmodulesign.VerifyError
+				assert.Equal(t, testCase.expectedVerify, errors.As(err, &verifyError))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedResult, result)
+		})
+	}
+}
+
+type mockSignatureReader []*bufmodulesign.Signature
+
+func (m mockSignatureReader) GetSignatures(
+	context.Context,
+	bufmoduleref.ModulePin,
+) ([]*bufmodulesign.Signature, error) {
+	return m, nil
+}
@@ -18,6 +18,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 
 	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/buf/bufcli"
@@ -27,26 +31,38 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/buflock"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
 	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/thread"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
 )
 
 const (
-	tagFlagName              = "tag"
-	tagFlagShortName         = "t"
-	draftFlagName            = "draft"
-	branchFlagName           = "branch"
-	errorFormatFlagName      = "error-format"
-	disableSymlinksFlagName  = "disable-symlinks"
-	createFlagName           = "create"
-	createVisibilityFlagName = "create-visibility"
+	tagFlagName                = "tag"
+	tagFlagShortName           = "t"
+	labelFlagName              = "label"
+	draftFlagName              = "draft"
+	branchFlagName             = "branch"
+	errorFormatFlagName        = "error-format"
+	disableSymlinksFlagName    = "disable-symlinks"
+	createFlagName             = "create"
+	createVisibilityFlagName   = "create-visibility"
+	createDescriptionFlagName  = "create-description"
+	createDefaultLabelFlagName = "create-default-label"
+	ensureSettingsFlagName     = "ensure-settings"
+	dryRunFlagName             = "dry-run"
+	exitCodeFlagName           = "exit-code"
+	signFlagName               = "sign"
 	// deprecated
 	trackFlagName = "track"
 )
@@ -60,8 +76,31 @@ func NewCommand(
 	return &appcmd.Command{
 		Use:   name + " <source>",
 		Short: "Push a module to a registry",
-		Long:  bufcli.GetSourceLong(`the source to push`),
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Push a module to a registry.
+
+Use --sign to sign the pushed commit with a private key, so that consumers can verify that
+the module was pushed by a holder of the key. The key must be an unencrypted PEM-encoded
+PKCS #8 ECDSA P-256 or Ed25519 key, such as the keys generated by:
+
+    $ openssl genpkey -algorithm ed25519 -out private.pem
+    $ openssl pkey -in private.pem -pubout -out public.pem
+
+The signature is over the name of the module and the digest of its manifest, and is
+attached to the commit as a tag of the form sig-<key id>-<signature>, which is created
+together with the commit. Keyless signing with Sigstore is not supported.
+
+Consumers configure the public keys that modules must be signed with in the trusted_keys
+section of the buf configuration, and modules are then verified when they are downloaded:
+
+    version: v1
+    trusted_keys:
+      buf.build/acme:
+        - /path/to/public.pem
+
+Use "buf mod verify" to verify the dependencies of a module.
+
+` + bufcli.GetSourceLong(`the source to push`),
+		Args: cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
 				return run(ctx, container, flags)
@@ -73,13 +112,20 @@ func NewCommand(
 }
 
 type flags struct {
-	Tags             []string
-	Branch           string
-	Draft            string
-	ErrorFormat      string
-	DisableSymlinks  bool
-	Create           bool
-	CreateVisibility string
+	Tags               []string
+	Labels             []string
+	Branch             string
+	Draft              string
+	ErrorFormat        string
+	DisableSymlinks    bool
+	Create             bool
+	CreateVisibility   string
+	CreateDescription  string
+	CreateDefaultLabel string
+	EnsureSettings     bool
+	DryRun             bool
+	ExitCode           bool
+	Sign               string
 	// Deprecated
 	Tracks []string
 	// special
@@ -104,6 +150,16 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 			draftFlagName,
 		),
 	)
+	flagSet.StringSliceVar(
+		&f.Labels,
+		labelFlagName,
+		nil,
+		fmt.Sprintf(
+			"Create a label for the pushed commit. Multiple labels are created if specified multiple times. Cannot be used together with --%s or --%s",
+			draftFlagName,
+			branchFlagName,
+		),
+	)
 	flagSet.StringVar(
 		&f.Draft,
 		draftFlagName,
@@ -141,6 +197,58 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		false,
 		fmt.Sprintf("Create the repository if it does not exist. Must set a visibility using --%s", createVisibilityFlagName),
 	)
+	flagSet.BoolVar(
+		&f.DryRun,
+		dryRunFlagName,
+		false,
+		fmt.Sprintf(
+			`Print the files that differ from the latest commit of the target, with a status of A (added), M (modified), or D (deleted), without pushing.
+The target is the --%s or --%s if set, and the %q branch otherwise`,
+			branchFlagName,
+			draftFlagName,
+			bufmoduleref.Main,
+		),
+	)
+	flagSet.BoolVar(
+		&f.ExitCode,
+		exitCodeFlagName,
+		false,
+		fmt.Sprintf("Exit with a non-zero exit code if any files differ. Can only be used together with --%s", dryRunFlagName),
+	)
+	flagSet.StringVar(
+		&f.CreateDescription,
+		createDescriptionFlagName,
+		"",
+		fmt.Sprintf("The repository's description, if created. Can only be set if --%s is set", createFlagName),
+	)
+	flagSet.StringVar(
+		&f.CreateDefaultLabel,
+		createDefaultLabelFlagName,
+		"",
+		fmt.Sprintf("The repository's default label, if created. Can only be set if --%s is set", createFlagName),
+	)
+	flagSet.BoolVar(
+		&f.EnsureSettings,
+		ensureSettingsFlagName,
+		false,
+		fmt.Sprintf(
+			"If the repository already exists, update its settings to match --%s, --%s, and --%s. Can only be set if --%s is set",
+			createVisibilityFlagName,
+			createDescriptionFlagName,
+			createDefaultLabelFlagName,
+			createFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.Sign,
+		signFlagName,
+		"",
+		fmt.Sprintf(
+			"The path to the private key to sign the pushed commit with. Cannot be used together with --%s or --%s",
+			draftFlagName,
+			branchFlagName,
+		),
+	)
 	flagSet.StringSliceVar(
 		&f.Tracks,
 		trackFlagName,
@@ -172,6 +280,25 @@ func run(
 	if len(flags.Tags) > 0 && flags.Branch != "" {
 		return appcmd.NewInvalidArgumentErrorf("--%s (-%s) and --%s cannot be used together.", tagFlagName, tagFlagShortName, branchFlagName)
 	}
+	if len(flags.Labels) > 0 && flags.Draft != "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s and --%s cannot be used together.", labelFlagName, draftFlagName)
+	}
+	if len(flags.Labels) > 0 && flags.Branch != "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s and --%s cannot be used together.", labelFlagName, branchFlagName)
+	}
+	// Signatures are attached as tags, which drafts cannot have.
+	if flags.Sign != "" && flags.Draft != "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s and --%s cannot be used together.", signFlagName, draftFlagName)
+	}
+	if flags.Sign != "" && flags.Branch != "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s and --%s cannot be used together.", signFlagName, branchFlagName)
+	}
+	if flags.ExitCode && !flags.DryRun {
+		return appcmd.NewInvalidArgumentErrorf("--%s can only be used together with --%s.", exitCodeFlagName, dryRunFlagName)
+	}
+	if err := validateLabels(flags.Labels); err != nil {
+		return err
+	}
 	if flags.CreateVisibility != "" {
 		if !flags.Create {
 			return appcmd.NewInvalidArgumentErrorf("Cannot set --%s without --%s.", createVisibilityFlagName, createFlagName)
@@ -184,6 +311,17 @@ func run(
 	} else if flags.Create {
 		return appcmd.NewInvalidArgumentErrorf("--%s is required if --%s is set.", createVisibilityFlagName, createFlagName)
 	}
+	if !flags.Create {
+		if flags.CreateDescription != "" {
+			return appcmd.NewInvalidArgumentErrorf("Cannot set --%s without --%s.", createDescriptionFlagName, createFlagName)
+		}
+		if flags.CreateDefaultLabel != "" {
+			return appcmd.NewInvalidArgumentErrorf("Cannot set --%s without --%s.", createDefaultLabelFlagName, createFlagName)
+		}
+		if flags.EnsureSettings {
+			return appcmd.NewInvalidArgumentErrorf("Cannot set --%s without --%s.", ensureSettingsFlagName, createFlagName)
+		}
+	}
 	source, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
 	if err != nil {
 		return err
@@ -203,6 +341,9 @@ func run(
 	if err != nil {
 		return err
 	}
+	defer func() {
+		retErr = multierr.Append(retErr, sourceBucket.Close())
+	}()
 	if err := buflock.CheckDeprecatedDigests(ctx, container.Logger(), sourceBucket); err != nil {
 		return err
 	}
@@ -215,7 +356,21 @@ func run(
 	if err != nil {
 		return err
 	}
-	modulePin, err := pushOrCreate(ctx, container, moduleIdentity, builtModule, flags)
+	if flags.DryRun {
+		return dryRun(ctx, container, moduleIdentity, builtModule, flags)
+	}
+	var signer bufmodulesign.Signer
+	if flags.Sign != "" {
+		privateKeyPEM, err := os.ReadFile(flags.Sign)
+		if err != nil {
+			return err
+		}
+		signer, err = bufmodulesign.NewSigner(privateKeyPEM)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", signFlagName, err)
+		}
+	}
+	modulePin, err := pushOrCreate(ctx, container, moduleIdentity, builtModule, signer, flags)
 	if err != nil {
 		if connect.CodeOf(err) == connect.CodeAlreadyExists {
 			if _, err := container.Stderr().Write(
@@ -233,6 +388,188 @@ func run(
 	if _, err := container.Stdout().Write([]byte(modulePin.Commit + "\n")); err != nil {
 		return err
 	}
+	if len(flags.Labels) > 0 {
+		return createLabels(ctx, container, moduleIdentity, modulePin.Commit, flags.Labels)
+	}
+	return nil
+}
+
+// dryRun prints the files of the built module that differ from the latest
+// commit of the target, without pushing.
+//
+// If the repository or the target does not exist, all of the files are added.
+func dryRun(
+	ctx context.Context,
+	container appflag.Container,
+	moduleIdentity bufmoduleref.ModuleIdentity,
+	builtModule *bufmodulebuild.BuiltModule,
+	flags *flags,
+) error {
+	fileSet, err := bufcas.NewFileSetForBucket(ctx, builtModule.Bucket)
+	if err != nil {
+		return err
+	}
+	remoteManifest, err := getRemoteManifest(ctx, container, moduleIdentity, getDraftOrBranchName(flags))
+	if err != nil {
+		return err
+	}
+	changes := diffManifests(remoteManifest, fileSet.Manifest())
+	for _, change := range changes {
+		if _, err := fmt.Fprintln(container.Stdout(), change); err != nil {
+			return err
+		}
+	}
+	if flags.ExitCode && len(changes) > 0 {
+		return bufcli.ErrFileAnnotation
+	}
+	return nil
+}
+
+// getRemoteManifest gets the manifest of the latest commit of the reference,
+// or nil if the repository or the reference does not exist.
+func getRemoteManifest(
+	ctx context.Context,
+	container appflag.Container,
+	moduleIdentity bufmoduleref.ModuleIdentity,
+	reference string,
+) (bufcas.Manifest, error) {
+	if reference == "" {
+		reference = bufmoduleref.Main
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	service := connectclient.Make(clientConfig, moduleIdentity.Remote(), registryv1alpha1connect.NewDownloadServiceClient)
+	resp, err := service.DownloadManifestAndBlobs(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.DownloadManifestAndBlobsRequest{
+			Owner:      moduleIdentity.Owner(),
+			Repository: moduleIdentity.Repository(),
+			Reference:  reference,
+		}),
+	)
+	if err != nil {
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if resp.Msg.Manifest == nil {
+		return nil, errors.New("expected non-nil manifest")
+	}
+	return bufcas.ProtoBlobToManifest(bufcasalpha.AlphaToBlob(resp.Msg.Manifest))
+}
+
+// diffManifests returns the changes from the remote manifest to the local
+// manifest, sorted by path, in the form "<status> <path>".
+//
+// The remote manifest may be nil.
+func diffManifests(remoteManifest bufcas.Manifest, localManifest bufcas.Manifest) []string {
+	pathToStatus := make(map[string]string)
+	if remoteManifest != nil {
+		for _, fileNode := range remoteManifest.FileNodes() {
+			if localManifest.GetDigest(fileNode.Path()) == nil {
+				pathToStatus[fileNode.Path()] = "D"
+			}
+		}
+	}
+	for _, fileNode := range localManifest.FileNodes() {
+		var remoteDigest bufcas.Digest
+		if remoteManifest != nil {
+			remoteDigest = remoteManifest.GetDigest(fileNode.Path())
+		}
+		if remoteDigest == nil {
+			pathToStatus[fileNode.Path()] = "A"
+		} else if !bufcas.DigestEqual(remoteDigest, fileNode.Digest()) {
+			pathToStatus[fileNode.Path()] = "M"
+		}
+	}
+	paths := make([]string, 0, len(pathToStatus))
+	for path := range pathToStatus {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	changes := make([]string, len(paths))
+	for i, path := range paths {
+		changes[i] = pathToStatus[path] + " " + path
+	}
+	return changes
+}
+
+func validateLabels(labels []string) error {
+	seen := make(map[string]struct{}, len(labels))
+	for _, label := range labels {
+		if label == "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be empty.", labelFlagName)
+		}
+		if _, ok := seen[label]; ok {
+			return appcmd.NewInvalidArgumentErrorf("--%s %q was specified more than once.", labelFlagName, label)
+		}
+		seen[label] = struct{}{}
+	}
+	return nil
+}
+
+// createLabels creates the labels for the pushed commit in parallel.
+//
+// All of the labels are attempted. The labels that already exist are reported
+// together in the returned error, after the labels that could be created are.
+func createLabels(
+	ctx context.Context,
+	container appflag.Container,
+	moduleIdentity bufmoduleref.ModuleIdentity,
+	commit string,
+	labels []string,
+) error {
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	service := connectclient.Make(clientConfig, moduleIdentity.Remote(), registryv1alpha1connect.NewLabelServiceClient)
+	var lock sync.Mutex
+	var existingLabels []string
+	jobs := make([]func(context.Context) error, len(labels))
+	for i, label := range labels {
+		label := label
+		jobs[i] = func(ctx context.Context) error {
+			_, err := service.CreateLabel(
+				ctx,
+				connect.NewRequest(&registryv1alpha1.CreateLabelRequest{
+					LabelName: &registryv1alpha1.LabelName{
+						// Labels, unlike tags, are not immutable.
+						Namespace: registryv1alpha1.LabelNamespace_LABEL_NAMESPACE_BRANCH,
+						Name:      label,
+					},
+					LabelValue: &registryv1alpha1.LabelValue{
+						CommitId: commit,
+					},
+				}),
+			)
+			if err != nil {
+				if connect.CodeOf(err) == connect.CodeAlreadyExists {
+					lock.Lock()
+					existingLabels = append(existingLabels, label)
+					lock.Unlock()
+					return nil
+				}
+				return fmt.Errorf("could not create label %q: %w", label, err)
+			}
+			return nil
+		}
+	}
+	if err := thread.Parallelize(ctx, jobs); err != nil {
+		return err
+	}
+	if len(existingLabels) > 0 {
+		sort.Strings(existingLabels)
+		return fmt.Errorf(
+			"commit %s was pushed, but the following labels already exist in %s and were not created: %s",
+			commit,
+			moduleIdentity.IdentityString(),
+			strings.Join(existingLabels, ", "),
+		)
+	}
 	return nil
 }
 
@@ -241,13 +578,19 @@ func pushOrCreate(
 	container appflag.Container,
 	moduleIdentity bufmoduleref.ModuleIdentity,
 	builtModule *bufmodulebuild.BuiltModule,
+	signer bufmodulesign.Signer,
 	flags *flags,
 ) (*registryv1alpha1.LocalModulePin, error) {
 	clientConfig, err := bufcli.NewConnectClientConfig(container)
 	if err != nil {
 		return nil, err
 	}
-	modulePin, err := push(ctx, container, clientConfig, moduleIdentity, builtModule, flags)
+	if flags.EnsureSettings {
+		if err := ensureSettings(ctx, container, clientConfig, moduleIdentity, flags); err != nil {
+			return nil, err
+		}
+	}
+	modulePin, err := push(ctx, container, clientConfig, moduleIdentity, builtModule, signer, flags)
 	if err != nil {
 		// We rely on Push* returning a NotFound error to denote the repository is not created.
 		// This technically could be a NotFound error for some other entity than the repository
@@ -259,7 +602,7 @@ func pushOrCreate(
 			if err := create(ctx, container, clientConfig, moduleIdentity, flags); err != nil {
 				return nil, err
 			}
-			return push(ctx, container, clientConfig, moduleIdentity, builtModule, flags)
+			return push(ctx, container, clientConfig, moduleIdentity, builtModule, signer, flags)
 		}
 		return nil, err
 	}
@@ -272,6 +615,7 @@ func push(
 	clientConfig *connectclient.Config,
 	moduleIdentity bufmoduleref.ModuleIdentity,
 	builtModule *bufmodulebuild.BuiltModule,
+	signer bufmodulesign.Signer,
 	flags *flags,
 ) (*registryv1alpha1.LocalModulePin, error) {
 	service := connectclient.Make(clientConfig, moduleIdentity.Remote(), registryv1alpha1connect.NewPushServiceClient)
@@ -283,11 +627,22 @@ func push(
 	if err != nil {
 		return nil, err
 	}
-	draftOrBranchName := flags.Draft
-	if draftOrBranchName == "" {
-		// If draft is not set, then we we set the draft name to branch.
-		draftOrBranchName = flags.Branch
+	tags := flags.Tags
+	if signer != nil {
+		manifestBlob, err := bufcas.ManifestToBlob(fileSet.Manifest())
+		if err != nil {
+			return nil, err
+		}
+		signature, err := signer.Sign(moduleIdentity, manifestBlob.Digest())
+		if err != nil {
+			return nil, err
+		}
+		// The signature is attached as a tag that is created together with
+		// the commit, so that a commit is never pushed without its signature.
+		tags = append(slicesext.Copy(tags), bufmodulesign.SignatureToTag(signature))
+		container.Logger().Info("signed module", zap.String("key_id", signer.KeyID()))
 	}
+	draftOrBranchName := getDraftOrBranchName(flags)
 	resp, err := service.PushManifestAndBlobs(
 		ctx,
 		connect.NewRequest(&registryv1alpha1.PushManifestAndBlobsRequest{
@@ -295,7 +650,7 @@ func push(
 			Repository: moduleIdentity.Repository(),
 			Manifest:   bufcasalpha.BlobToAlpha(protoManifestBlob),
 			Blobs:      bufcasalpha.BlobsToAlpha(protoBlobs),
-			Tags:       flags.Tags,
+			Tags:       tags,
 			DraftName:  draftOrBranchName,
 		}),
 	)
@@ -305,6 +660,14 @@ func push(
 	return resp.Msg.LocalModulePin, nil
 }
 
+func getDraftOrBranchName(flags *flags) string {
+	if flags.Draft != "" {
+		return flags.Draft
+	}
+	// If draft is not set, then we we set the draft name to branch.
+	return flags.Branch
+}
+
 func create(
 	ctx context.Context,
 	container appflag.Container,
@@ -325,8 +688,84 @@ func create(
 			Visibility: visiblity,
 		}),
 	)
-	if err != nil && connect.CodeOf(err) == connect.CodeAlreadyExists {
-		return connect.NewError(connect.CodeInternal, fmt.Errorf("Expected repository %s to be missing but found the repository to already exist", fullName))
+	if err != nil {
+		if connect.CodeOf(err) == connect.CodeAlreadyExists {
+			return connect.NewError(connect.CodeInternal, fmt.Errorf("Expected repository %s to be missing but found the repository to already exist", fullName))
+		}
+		return err
 	}
+	// The description and default label cannot be set when the repository is
+	// created, so they are set afterwards.
+	if flags.CreateDescription == "" && flags.CreateDefaultLabel == "" {
+		return nil
+	}
+	request := &registryv1alpha1.UpdateRepositorySettingsByNameRequest{
+		OwnerName:      moduleIdentity.Owner(),
+		RepositoryName: moduleIdentity.Repository(),
+	}
+	if flags.CreateDescription != "" {
+		request.Description = &flags.CreateDescription
+	}
+	if flags.CreateDefaultLabel != "" {
+		request.DefaultBranch = &flags.CreateDefaultLabel
+	}
+	_, err = service.UpdateRepositorySettingsByName(ctx, connect.NewRequest(request))
 	return err
 }
+
+// ensureSettings updates the settings of the repository that differ from the
+// --create-* flags, if the repository exists.
+//
+// The repository is not updated if all of its settings match.
+func ensureSettings(
+	ctx context.Context,
+	container appflag.Container,
+	clientConfig *connectclient.Config,
+	moduleIdentity bufmoduleref.ModuleIdentity,
+	flags *flags,
+) error {
+	service := connectclient.Make(clientConfig, moduleIdentity.Remote(), registryv1alpha1connect.NewRepositoryServiceClient)
+	visibility, err := bufcli.VisibilityFlagToVisibility(flags.CreateVisibility)
+	if err != nil {
+		return err
+	}
+	resp, err := service.GetRepositoryByFullName(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetRepositoryByFullNameRequest{
+			FullName: moduleIdentity.Owner() + "/" + moduleIdentity.Repository(),
+		}),
+	)
+	if err != nil {
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			// The repository is created with the settings on push.
+			return nil
+		}
+		return err
+	}
+	repository := resp.Msg.Repository
+	request := &registryv1alpha1.UpdateRepositorySettingsByNameRequest{
+		OwnerName:      moduleIdentity.Owner(),
+		RepositoryName: moduleIdentity.Repository(),
+	}
+	var changed bool
+	if repository.Visibility != visibility {
+		request.Visibility = visibility
+		changed = true
+	}
+	if flags.CreateDescription != "" && repository.Description != flags.CreateDescription {
+		request.Description = &flags.CreateDescription
+		changed = true
+	}
+	if flags.CreateDefaultLabel != "" && repository.DefaultBranch != flags.CreateDefaultLabel {
+		request.DefaultBranch = &flags.CreateDefaultLabel
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if _, err := service.UpdateRepositorySettingsByName(ctx, connect.NewRequest(request)); err != nil {
+		return err
+	}
+	container.Logger().Info("updated repository settings", zap.String("repository", moduleIdentity.IdentityString()))
+	return nil
+}
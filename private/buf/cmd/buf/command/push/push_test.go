@@ -16,8 +16,13 @@ package push
 
 import (
 	"archive/tar"
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -26,15 +31,21 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
 
 	storagev1beta1 "buf.build/gen/go/bufbuild/registry/protocolbuffers/go/buf/registry/storage/v1beta1"
 	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/internal/internaltesting"
 	"github.com/bufbuild/buf/private/bufpkg/bufcas"
 	"github.com/bufbuild/buf/private/bufpkg/bufcas/bufcasalpha"
+	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
 	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
 	"github.com/bufbuild/buf/private/pkg/app"
@@ -117,6 +128,94 @@ func TestPushManifestCreate(t *testing.T) {
 	)
 }
 
+func TestPushManifestCreateSettings(t *testing.T) {
+	t.Parallel()
+	mock := newMockPushService(t)
+	mock.pushManifestResponse = &registryv1alpha1.PushManifestAndBlobsResponse{
+		LocalModulePin: &registryv1alpha1.LocalModulePin{},
+	}
+	mock.pushManifestResponseError = connect.NewError(connect.CodeNotFound, errors.New("repository not found"))
+	mockRepositoryService := newMockRepositoryService(t)
+	server := createServer(t, mock, mockRepositoryService)
+	files := map[string][]byte{
+		"buf.yaml":  bufYAML(t, server.URL, "owner", "repo"),
+		"foo.proto": nil,
+	}
+	err := appRun(
+		t,
+		files,
+		"--create",
+		"--create-visibility=private",
+		"--create-description=The repository.",
+		"--create-default-label=release",
+	)
+	require.NoError(t, err)
+	updateRequests := mockRepositoryService.UpdateRequests()
+	require.Len(t, updateRequests, 1)
+	assert.Equal(t, "The repository.", updateRequests[0].GetDescription())
+	assert.Equal(t, "release", updateRequests[0].GetDefaultBranch())
+	err = appRun(t, files, "--create-description=The repository.")
+	assert.ErrorContains(t, err, "Cannot set --create-description without --create.")
+	err = appRun(t, files, "--ensure-settings")
+	assert.ErrorContains(t, err, "Cannot set --ensure-settings without --create.")
+}
+
+func TestPushManifestEnsureSettings(t *testing.T) {
+	t.Parallel()
+	mock := newMockPushService(t)
+	mock.pushManifestResponse = &registryv1alpha1.PushManifestAndBlobsResponse{
+		LocalModulePin: &registryv1alpha1.LocalModulePin{},
+	}
+	mockRepositoryService := newMockRepositoryService(t)
+	mockRepositoryService.repository = &registryv1alpha1.Repository{
+		Name:          "repo",
+		Visibility:    registryv1alpha1.Visibility_VISIBILITY_PUBLIC,
+		Description:   "The repository.",
+		DefaultBranch: "main",
+	}
+	server := createServer(t, mock, mockRepositoryService)
+	files := map[string][]byte{
+		"buf.yaml":  bufYAML(t, server.URL, "owner", "repo"),
+		"foo.proto": nil,
+	}
+	args := []string{
+		"--create",
+		"--create-visibility=public",
+		"--create-description=The repository.",
+		"--ensure-settings",
+	}
+	require.NoError(t, appRun(t, files, args...))
+	assert.Empty(t, mockRepositoryService.UpdateRequests(), "settings already match")
+	require.NoError(t, appRun(t, files, append(args, "--create-default-label=release")...))
+	updateRequests := mockRepositoryService.UpdateRequests()
+	require.Len(t, updateRequests, 1)
+	assert.Equal(t, registryv1alpha1.Visibility_VISIBILITY_UNSPECIFIED, updateRequests[0].Visibility)
+	assert.Nil(t, updateRequests[0].Description)
+	assert.Equal(t, "release", updateRequests[0].GetDefaultBranch())
+}
+
+func TestPushManifestOffline(t *testing.T) {
+	t.Parallel()
+	mock := newMockPushService(t)
+	mock.pushManifestResponse = &registryv1alpha1.PushManifestAndBlobsResponse{
+		LocalModulePin: &registryv1alpha1.LocalModulePin{},
+	}
+	server := createServer(t, mock, nil)
+	err := appRunWithEnv(
+		t,
+		map[string]string{
+			"BUF_OFFLINE": "1",
+		},
+		os.Stdout,
+		map[string][]byte{
+			"buf.yaml":  bufYAML(t, server.URL, "owner", "repo"),
+			"foo.proto": nil,
+		},
+	)
+	assert.ErrorIs(t, err, bufconnect.ErrOffline)
+	assert.Nil(t, mock.PushManifestRequest(), "nothing should be pushed")
+}
+
 func TestPushManifestIsSmallerBucket(t *testing.T) {
 	// Assert push only manifests with only the files needed to build the
 	// module as described by configuration and file extension.
@@ -148,6 +247,136 @@ func TestPushManifestIsSmallerBucket(t *testing.T) {
 	assert.Nil(t, manifest.GetDigest("baz.file"), "baz.file should not be pushed")
 }
 
+func TestPushManifestLabels(t *testing.T) {
+	t.Parallel()
+	mock := newMockPushService(t)
+	mock.pushManifestResponse = &registryv1alpha1.PushManifestAndBlobsResponse{
+		LocalModulePin: &registryv1alpha1.LocalModulePin{
+			Commit: "abc",
+		},
+	}
+	mockLabelService := newMockLabelService(t, "v1.4.0")
+	server := createServerWithServices(t, mock, nil, mockLabelService, nil)
+	files := map[string][]byte{
+		"buf.yaml":  bufYAML(t, server.URL, "owner", "repo"),
+		"foo.proto": nil,
+	}
+	err := appRun(t, files, "--label", "main", "--label", "release")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main", "release"}, mockLabelService.CreatedLabels("abc"))
+	err = appRun(t, files, "--label", "v1.4.0", "--label", "main", "--label", "stable")
+	assert.ErrorContains(t, err, "commit abc was pushed, but the following labels already exist in "+server.Listener.Addr().String()+"/owner/repo and were not created: main, v1.4.0")
+	assert.Equal(t, []string{"main", "release", "stable"}, mockLabelService.CreatedLabels("abc"))
+	err = appRun(t, files, "--label", "main", "--label", "main")
+	assert.ErrorContains(t, err, `--label "main" was specified more than once.`)
+	err = appRun(t, files, "--label", "main", "--draft", "draft")
+	assert.ErrorContains(t, err, "--label and --draft cannot be used together.")
+	err = appRun(t, files, "--label", "main", "--branch", "branch")
+	assert.ErrorContains(t, err, "--label and --branch cannot be used together.")
+}
+
+func TestPushManifestSign(t *testing.T) {
+	t.Parallel()
+	mock := newMockPushService(t)
+	mock.pushManifestResponse = &registryv1alpha1.PushManifestAndBlobsResponse{
+		LocalModulePin: &registryv1alpha1.LocalModulePin{
+			Commit: "abc",
+		},
+	}
+	server := createServer(t, mock, nil)
+	files := map[string][]byte{
+		"buf.yaml":  bufYAML(t, server.URL, "owner", "repo"),
+		"foo.proto": nil,
+	}
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateKeyData, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicKeyData, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	require.NoError(t, err)
+	privateKeyFilePath := filepath.Join(t.TempDir(), "private.pem")
+	require.NoError(t, os.WriteFile(privateKeyFilePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyData}), 0600))
+	err = appRun(t, files, "--sign", privateKeyFilePath, "--tag", "v1.0.0")
+	require.NoError(t, err)
+	request := mock.PushManifestRequest()
+	require.NotNil(t, request)
+	require.Len(t, request.Tags, 2)
+	assert.Equal(t, "v1.0.0", request.Tags[0])
+	signature, ok, err := bufmodulesign.TagToSignature(request.Tags[1])
+	require.NoError(t, err)
+	require.True(t, ok)
+	manifest, err := bufcas.ProtoBlobToManifest(bufcasalpha.AlphaToBlob(request.Manifest))
+	require.NoError(t, err)
+	manifestBlob, err := bufcas.ManifestToBlob(manifest)
+	require.NoError(t, err)
+	verifier, err := bufmodulesign.NewVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyData}))
+	require.NoError(t, err)
+	moduleIdentity, err := bufmoduleref.ModuleIdentityForString(server.Listener.Addr().String() + "/owner/repo")
+	require.NoError(t, err)
+	_, err = verifier.Verify(moduleIdentity, manifestBlob.Digest(), []*bufmodulesign.Signature{signature})
+	assert.NoError(t, err)
+	err = appRun(t, files, "--sign", privateKeyFilePath, "--draft", "draft")
+	assert.ErrorContains(t, err, "--sign and --draft cannot be used together.")
+	err = appRun(t, files, "--sign", privateKeyFilePath, "--branch", "branch")
+	assert.ErrorContains(t, err, "--sign and --branch cannot be used together.")
+	publicKeyFilePath := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, os.WriteFile(publicKeyFilePath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyData}), 0600))
+	err = appRun(t, files, "--sign", publicKeyFilePath)
+	assert.ErrorContains(t, err, "--sign: unsupported PEM block type")
+}
+
+func TestPushDryRun(t *testing.T) {
+	t.Parallel()
+	mock := newMockPushService(t)
+	mockDownloadService := newMockDownloadService(
+		t,
+		map[string]map[string][]byte{
+			"main": {
+				"a.proto": []byte(`syntax = "proto3";`),
+				"b.proto": []byte(`syntax = "proto3";`),
+				"c.proto": []byte(`syntax = "proto3";`),
+			},
+		},
+	)
+	server := createServerWithServices(t, mock, nil, nil, mockDownloadService)
+	buildFiles := func(files map[string]string) map[string][]byte {
+		result := map[string][]byte{
+			"buf.yaml": bufYAML(t, server.URL, "owner", "repo"),
+		}
+		for path, content := range files {
+			result[path] = []byte(content)
+		}
+		return result
+	}
+	testPushDryRun(
+		t,
+		buildFiles(
+			map[string]string{
+				"a.proto": `syntax = "proto3";`,
+				"b.proto": `syntax = "proto2";`,
+				"d.proto": `syntax = "proto3";`,
+			},
+		),
+		[]string{"--dry-run", "--exit-code"},
+		"M b.proto\nA buf.yaml\nD c.proto\nA d.proto\n",
+		true,
+	)
+	testPushDryRun(
+		t,
+		buildFiles(
+			map[string]string{
+				"a.proto": `syntax = "proto3";`,
+			},
+		),
+		[]string{"--dry-run", "--branch", "feature"},
+		"A a.proto\nA buf.yaml\n",
+		false,
+	)
+	assert.Nil(t, mock.PushManifestRequest(), "nothing should be pushed")
+	err := appRun(t, buildFiles(nil), "--exit-code")
+	assert.ErrorContains(t, err, "--exit-code can only be used together with --dry-run.")
+}
+
 func TestBucketBlobs(t *testing.T) {
 	t.Parallel()
 	bucket, err := storagemem.NewReadBucket(
@@ -236,6 +465,13 @@ func (m *mockPushService) PushManifestRequest() *registryv1alpha1.PushManifestAn
 
 type mockRepositoryService struct {
 	t *testing.T
+
+	// protects repository and updateRequests
+	sync.Mutex
+
+	// the existing repository, if any
+	repository     *registryv1alpha1.Repository
+	updateRequests []*registryv1alpha1.UpdateRepositorySettingsByNameRequest
 }
 
 var _ registryv1alpha1connect.RepositoryServiceHandler = (*mockRepositoryService)(nil)
@@ -271,7 +507,14 @@ func (m *mockRepositoryService) GetRepository(_ context.Context, _ *connect.Requ
 }
 
 func (m *mockRepositoryService) GetRepositoryByFullName(_ context.Context, _ *connect.Request[registryv1alpha1.GetRepositoryByFullNameRequest]) (*connect.Response[registryv1alpha1.GetRepositoryByFullNameResponse], error) {
-	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("unimplemented"))
+	m.Lock()
+	defer m.Unlock()
+	if m.repository == nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("repository not found"))
+	}
+	return connect.NewResponse(&registryv1alpha1.GetRepositoryByFullNameResponse{
+		Repository: m.repository,
+	}), nil
 }
 
 func (m *mockRepositoryService) ListRepositories(_ context.Context, _ *connect.Request[registryv1alpha1.ListRepositoriesRequest]) (*connect.Response[registryv1alpha1.ListRepositoriesResponse], error) {
@@ -326,8 +569,17 @@ func (m *mockRepositoryService) GetRepositorySettings(_ context.Context, _ *conn
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("unimplemented"))
 }
 
-func (m *mockRepositoryService) UpdateRepositorySettingsByName(_ context.Context, _ *connect.Request[registryv1alpha1.UpdateRepositorySettingsByNameRequest]) (*connect.Response[registryv1alpha1.UpdateRepositorySettingsByNameResponse], error) {
-	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("unimplemented"))
+func (m *mockRepositoryService) UpdateRepositorySettingsByName(_ context.Context, req *connect.Request[registryv1alpha1.UpdateRepositorySettingsByNameRequest]) (*connect.Response[registryv1alpha1.UpdateRepositorySettingsByNameResponse], error) {
+	m.Lock()
+	defer m.Unlock()
+	m.updateRequests = append(m.updateRequests, req.Msg)
+	return connect.NewResponse(&registryv1alpha1.UpdateRepositorySettingsByNameResponse{}), nil
+}
+
+func (m *mockRepositoryService) UpdateRequests() []*registryv1alpha1.UpdateRepositorySettingsByNameRequest {
+	m.Lock()
+	defer m.Unlock()
+	return m.updateRequests
 }
 
 func (m *mockRepositoryService) GetRepositoriesMetadata(_ context.Context, _ *connect.Request[registryv1alpha1.GetRepositoriesMetadataRequest]) (*connect.Response[registryv1alpha1.GetRepositoriesMetadataResponse], error) {
@@ -338,8 +590,133 @@ func (m *mockRepositoryService) GetRepositoryDependencyDOTString(_ context.Conte
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("unimplemented"))
 }
 
+type mockLabelService struct {
+	t *testing.T
+
+	// protects commitToLabels
+	sync.Mutex
+
+	existingLabels map[string]struct{}
+	commitToLabels map[string][]string
+}
+
+var _ registryv1alpha1connect.LabelServiceHandler = (*mockLabelService)(nil)
+
+func newMockLabelService(t *testing.T, existingLabels ...string) *mockLabelService {
+	mock := &mockLabelService{
+		t:              t,
+		existingLabels: make(map[string]struct{}),
+		commitToLabels: make(map[string][]string),
+	}
+	for _, existingLabel := range existingLabels {
+		mock.existingLabels[existingLabel] = struct{}{}
+	}
+	return mock
+}
+
+func (m *mockLabelService) CreateLabel(
+	_ context.Context,
+	req *connect.Request[registryv1alpha1.CreateLabelRequest],
+) (*connect.Response[registryv1alpha1.CreateLabelResponse], error) {
+	m.Lock()
+	defer m.Unlock()
+	name := req.Msg.GetLabelName().GetName()
+	if _, ok := m.existingLabels[name]; ok {
+		return nil, connect.NewError(connect.CodeAlreadyExists, fmt.Errorf("label %q already exists", name))
+	}
+	commit := req.Msg.GetLabelValue().GetCommitId()
+	m.commitToLabels[commit] = append(m.commitToLabels[commit], name)
+	m.existingLabels[name] = struct{}{}
+	return connect.NewResponse(&registryv1alpha1.CreateLabelResponse{
+		CommitId: req.Msg.GetLabelValue(),
+	}), nil
+}
+
+func (m *mockLabelService) MoveLabel(_ context.Context, _ *connect.Request[registryv1alpha1.MoveLabelRequest]) (*connect.Response[registryv1alpha1.MoveLabelResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("unimplemented"))
+}
+
+func (m *mockLabelService) GetLabels(_ context.Context, _ *connect.Request[registryv1alpha1.GetLabelsRequest]) (*connect.Response[registryv1alpha1.GetLabelsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("unimplemented"))
+}
+
+func (m *mockLabelService) GetLabelsInNamespace(_ context.Context, _ *connect.Request[registryv1alpha1.GetLabelsInNamespaceRequest]) (*connect.Response[registryv1alpha1.GetLabelsInNamespaceResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("unimplemented"))
+}
+
+// CreatedLabels returns the sorted labels created for the commit.
+func (m *mockLabelService) CreatedLabels(commit string) []string {
+	m.Lock()
+	defer m.Unlock()
+	labels := append([]string{}, m.commitToLabels[commit]...)
+	sort.Strings(labels)
+	return labels
+}
+
+type mockDownloadService struct {
+	t *testing.T
+
+	referenceToFiles map[string]map[string][]byte
+}
+
+var _ registryv1alpha1connect.DownloadServiceHandler = (*mockDownloadService)(nil)
+
+func newMockDownloadService(t *testing.T, referenceToFiles map[string]map[string][]byte) *mockDownloadService {
+	return &mockDownloadService{
+		t:                t,
+		referenceToFiles: referenceToFiles,
+	}
+}
+
+func (m *mockDownloadService) Download(
+	context.Context,
+	*connect.Request[registryv1alpha1.DownloadRequest],
+) (*connect.Response[registryv1alpha1.DownloadResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("Download RPC should not be called, use DownloadManifestAndBlobs RPC instead"))
+}
+
+func (m *mockDownloadService) DownloadManifestAndBlobs(
+	ctx context.Context,
+	req *connect.Request[registryv1alpha1.DownloadManifestAndBlobsRequest],
+) (*connect.Response[registryv1alpha1.DownloadManifestAndBlobsResponse], error) {
+	files, ok := m.referenceToFiles[req.Msg.Reference]
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("reference %q not found", req.Msg.Reference))
+	}
+	bucket, err := storagemem.NewReadBucket(files)
+	require.NoError(m.t, err)
+	fileSet, err := bufcas.NewFileSetForBucket(ctx, bucket)
+	require.NoError(m.t, err)
+	protoManifestBlob, protoBlobs, err := bufcas.FileSetToProtoManifestBlobAndBlobs(fileSet)
+	require.NoError(m.t, err)
+	return connect.NewResponse(&registryv1alpha1.DownloadManifestAndBlobsResponse{
+		Manifest: bufcasalpha.BlobToAlpha(protoManifestBlob),
+		Blobs:    bufcasalpha.BlobsToAlpha(protoBlobs),
+	}), nil
+}
+
 func createServer(t *testing.T, mockPushService *mockPushService, mockRepositoryService *mockRepositoryService) *httptest.Server {
 	t.Helper()
+	return createServerWithServices(t, mockPushService, mockRepositoryService, nil, nil)
+}
+
+// createServerWithServices creates a server with the given services. A mock
+// without any labels or downloadable references is used for a nil label or
+// download service.
+func createServerWithServices(
+	t *testing.T,
+	mockPushService *mockPushService,
+	mockRepositoryService *mockRepositoryService,
+	mockLabelService *mockLabelService,
+	mockDownloadService *mockDownloadService,
+) *httptest.Server {
+	t.Helper()
+	if mockLabelService == nil {
+		mockLabelService = newMockLabelService(t)
+	}
+	if mockDownloadService == nil {
+		mockDownloadService = newMockDownloadService(t, nil)
+	}
 	mux := http.NewServeMux()
 	mux.Handle(
 		registryv1alpha1connect.NewPushServiceHandler(mockPushService),
@@ -347,6 +724,12 @@ func createServer(t *testing.T, mockPushService *mockPushService, mockRepository
 	mux.Handle(
 		registryv1alpha1connect.NewRepositoryServiceHandler(mockRepositoryService),
 	)
+	mux.Handle(
+		registryv1alpha1connect.NewLabelServiceHandler(mockLabelService),
+	)
+	mux.Handle(
+		registryv1alpha1connect.NewDownloadServiceHandler(mockDownloadService),
+	)
 	server := httptest.NewServer(mux)
 	t.Cleanup(func() {
 		server.Close()
@@ -358,6 +741,25 @@ func appRun(
 	t *testing.T,
 	files map[string][]byte,
 	args ...string,
+) error {
+	return appRunStdout(t, os.Stdout, files, args...)
+}
+
+func appRunStdout(
+	t *testing.T,
+	stdout io.Writer,
+	files map[string][]byte,
+	args ...string,
+) error {
+	return appRunWithEnv(t, nil, stdout, files, args...)
+}
+
+func appRunWithEnv(
+	t *testing.T,
+	extraEnv map[string]string,
+	stdout io.Writer,
+	files map[string][]byte,
+	args ...string,
 ) error {
 	const appName = "test"
 	defaultArgs := []string{appName, "-#format=tar"} // using stdin as a tar
@@ -369,12 +771,15 @@ func appRun(
 				internaltesting.NewEnvFunc(t),
 				func(env map[string]string) map[string]string {
 					env["BUF_TOKEN"] = "invalid"
+					for key, value := range extraEnv {
+						env[key] = value
+					}
 					injectConfig(t, appName, env)
 					return env
 				},
 			)(appName),
 			tarball(files),
-			os.Stdout,
+			stdout,
 			os.Stderr,
 			args...,
 		),
@@ -424,6 +829,24 @@ func testPushManifest(
 	})
 }
 
+func testPushDryRun(
+	t *testing.T,
+	files map[string][]byte,
+	args []string,
+	expectedStdout string,
+	expectedExitCodeError bool,
+) {
+	t.Helper()
+	stdout := bytes.NewBuffer(nil)
+	err := appRunStdout(t, stdout, files, args...)
+	if expectedExitCodeError {
+		assert.ErrorIs(t, err, bufcli.ErrFileAnnotation)
+	} else {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, expectedStdout, stdout.String())
+}
+
 // tarball returns a tar stream of files[path] = content.
 func tarball(files map[string][]byte) io.ReadCloser {
 	pr, pw := io.Pipe()
@@ -24,19 +24,26 @@ import (
 	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
+	"github.com/bufbuild/buf/private/bufpkg/bufsso"
 	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/credentialstore"
 	"github.com/bufbuild/buf/private/pkg/netrc"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
 const (
-	usernameFlagName   = "username"
-	tokenStdinFlagName = "token-stdin"
+	usernameFlagName            = "username"
+	tokenStdinFlagName          = "token-stdin"
+	fromNetrcFlagName           = "from-netrc"
+	ssoFlagName                 = "sso"
+	ssoIssuerFlagName           = "sso-issuer"
+	ssoClientIDFlagName         = "sso-client-id"
+	ssoTokenExchangeURLFlagName = "sso-token-exchange-url"
 )
 
 // NewCommand returns a new Command.
@@ -48,8 +55,36 @@ func NewCommand(
 	return &appcmd.Command{
 		Use:   name + " <domain>",
 		Short: `Log in to the Buf Schema Registry`,
-		Long: fmt.Sprintf(`This prompts for your BSR username and a BSR token and updates your %s file with these credentials.
-The <domain> argument will default to buf.build if not specified.`, netrc.Filename),
+		Long: fmt.Sprintf(`This prompts for your BSR username and a BSR token and saves these credentials to the credential store.
+The <domain> argument will default to buf.build if not specified.
+
+The credential store is your %s file by default. To save credentials to the credential store of
+your operating system instead, set credential_store in the buf.yaml file of your buf configuration
+directory to one of %s.
+
+Use --%s to move existing credentials from your %s file to the configured credential store.
+
+Use --%s to log in with the identity provider of your organization instead of a BSR token. This
+prints a URL and a code to enter at the URL to log in, and saves a short-lived BSR token that is
+refreshed automatically. The identity provider for the domain is set by sso in the buf.yaml file
+of your buf configuration directory, or by --%s, --%s, and --%s. The token exchange URL is the
+endpoint of the BSR that exchanges ID tokens from the identity provider for BSR tokens:
+
+    version: v1
+    sso:
+      buf.example.com:
+        issuer: https://idp.example.com
+        client_id: buf
+        token_exchange_url: https://buf.example.com/oauth2/token`,
+			netrc.Filename,
+			credentialstore.AllTypesString,
+			fromNetrcFlagName,
+			netrc.Filename,
+			ssoFlagName,
+			ssoIssuerFlagName,
+			ssoClientIDFlagName,
+			ssoTokenExchangeURLFlagName,
+		),
 		Args: cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
@@ -62,8 +97,13 @@ The <domain> argument will default to buf.build if not specified.`, netrc.Filena
 }
 
 type flags struct {
-	Username   string
-	TokenStdin bool
+	Username            string
+	TokenStdin          bool
+	FromNetrc           bool
+	SSO                 bool
+	SSOIssuer           string
+	SSOClientID         string
+	SSOTokenExchangeURL string
 }
 
 func newFlags() *flags {
@@ -83,6 +123,39 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		false,
 		"Read the token from stdin. This command prompts for a token by default",
 	)
+	flagSet.BoolVar(
+		&f.FromNetrc,
+		fromNetrcFlagName,
+		false,
+		fmt.Sprintf(
+			"Move the existing credentials from your %s file to the configured credential store instead of prompting for new credentials",
+			netrc.Filename,
+		),
+	)
+	flagSet.BoolVar(
+		&f.SSO,
+		ssoFlagName,
+		false,
+		"Log in with the single sign-on identity provider of the domain instead of a BSR token",
+	)
+	flagSet.StringVar(
+		&f.SSOIssuer,
+		ssoIssuerFlagName,
+		"",
+		fmt.Sprintf("The OpenID Connect issuer URL of the identity provider for --%s. Overrides the buf configuration", ssoFlagName),
+	)
+	flagSet.StringVar(
+		&f.SSOClientID,
+		ssoClientIDFlagName,
+		"",
+		fmt.Sprintf("The client ID of buf at the identity provider for --%s. Overrides the buf configuration", ssoFlagName),
+	)
+	flagSet.StringVar(
+		&f.SSOTokenExchangeURL,
+		ssoTokenExchangeURLFlagName,
+		"",
+		fmt.Sprintf("The URL of the BSR endpoint that exchanges ID tokens for BSR tokens for --%s. Overrides the buf configuration", ssoFlagName),
+	)
 }
 
 func run(
@@ -136,6 +209,25 @@ func inner(
 	if container.NumArgs() == 1 {
 		remote = container.Arg(0)
 	}
+	credentialStore, err := bufcli.NewCredentialStore(container)
+	if err != nil {
+		return err
+	}
+	if flags.FromNetrc {
+		return migrateFromNetrc(ctx, container, credentialStore, remote, flags)
+	}
+	if flags.SSO {
+		return loginSSO(ctx, container, credentialStore, remote, flags)
+	}
+	if flags.SSOIssuer != "" || flags.SSOClientID != "" || flags.SSOTokenExchangeURL != "" {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s, --%s, and --%s require --%s",
+			ssoIssuerFlagName,
+			ssoClientIDFlagName,
+			ssoTokenExchangeURLFlagName,
+			ssoFlagName,
+		)
+	}
 	// Do not print unless we are prompting
 	if flags.Username == "" && !flags.TokenStdin {
 		if _, err := fmt.Fprintf(
@@ -181,29 +273,15 @@ func inner(
 	if token == "" {
 		return errors.New("token cannot be empty string")
 	}
-	clientConfig, err := bufcli.NewConnectClientConfigWithToken(container, token)
+	user, err := getCurrentUser(ctx, container, remote, token)
 	if err != nil {
 		return err
 	}
-	authnService := connectclient.Make(clientConfig, remote, registryv1alpha1connect.NewAuthnServiceClient)
-	resp, err := authnService.GetCurrentUser(ctx, connect.NewRequest(&registryv1alpha1.GetCurrentUserRequest{}))
-	if err != nil {
-		if connectErr := new(connect.Error); errors.As(err, &connectErr) && connectErr.Code() == connect.CodeUnavailable {
-			return connectErr
-		}
-		// We don't want to use the default error from wrapError here if the error
-		// an unauthenticated error.
-		return errors.New("invalid token provided")
-	}
-	user := resp.Msg.User
-	if user == nil {
-		return errors.New("no user found for provided token")
-	}
 	if user.Username != username {
 		return errors.New("the username associated with the provided token does not match the provided username")
 	}
-	if err := netrc.PutMachines(
-		container,
+	if err := credentialStore.PutMachine(
+		ctx,
 		netrc.NewMachine(
 			remote,
 			username,
@@ -212,14 +290,15 @@ func inner(
 	); err != nil {
 		return err
 	}
-	if _, err := netrc.DeleteMachineForName(container, "go."+remote); err != nil {
+	// The registry token would be replaced when a previous single sign-on
+	// session is refreshed.
+	if _, err := credentialStore.DeleteMachine(ctx, bufsso.SessionMachineName(remote)); err != nil {
 		return err
 	}
-	netrcFilePath, err := netrc.GetFilePath(container)
-	if err != nil {
+	if err := deleteNetrcCredentials(container, credentialStore, remote); err != nil {
 		return err
 	}
-	loggedInMessage := fmt.Sprintf("Credentials saved to %s.\n", netrcFilePath)
+	loggedInMessage := fmt.Sprintf("Credentials saved to %s.\n", credentialStore.Location())
 	// Unless we did not prompt at all, print a newline first
 	if flags.Username == "" || !flags.TokenStdin {
 		loggedInMessage = "\n" + loggedInMessage
@@ -229,3 +308,108 @@ func inner(
 	}
 	return nil
 }
+
+// migrateFromNetrc moves the credentials for the remote from the .netrc file
+// to the credential store.
+func migrateFromNetrc(
+	ctx context.Context,
+	container appflag.Container,
+	credentialStore credentialstore.Store,
+	remote string,
+	flags *flags,
+) error {
+	if flags.Username != "" || flags.TokenStdin || flags.SSO {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s cannot be used with --%s, --%s, or --%s",
+			fromNetrcFlagName,
+			usernameFlagName,
+			tokenStdinFlagName,
+			ssoFlagName,
+		)
+	}
+	if credentialStore.Type() == credentialstore.TypeNetrc {
+		return fmt.Errorf(
+			"--%s requires credential_store to be set to one of %s in your buf configuration",
+			fromNetrcFlagName,
+			credentialstore.AllTypesString,
+		)
+	}
+	machine, err := netrc.GetMachineForName(container, remote)
+	if err != nil {
+		return err
+	}
+	if machine == nil {
+		netrcFilePath, err := netrc.GetFilePath(container)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("no credentials for %s found in %s", remote, netrcFilePath)
+	}
+	if err := credentialStore.PutMachine(ctx, netrc.NewMachine(remote, machine.Login(), machine.Password())); err != nil {
+		return err
+	}
+	sessionMachine, err := netrc.GetMachineForName(container, bufsso.SessionMachineName(remote))
+	if err != nil {
+		return err
+	}
+	if sessionMachine != nil {
+		if err := credentialStore.PutMachine(ctx, sessionMachine); err != nil {
+			return err
+		}
+	}
+	if err := deleteNetrcCredentials(container, credentialStore, remote); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(container.Stdout(), "Credentials moved to %s.\n", credentialStore.Location())
+	return err
+}
+
+// getCurrentUser gets the user of the token.
+func getCurrentUser(
+	ctx context.Context,
+	container appflag.Container,
+	remote string,
+	token string,
+) (*registryv1alpha1.User, error) {
+	clientConfig, err := bufcli.NewConnectClientConfigWithToken(container, token)
+	if err != nil {
+		return nil, err
+	}
+	authnService := connectclient.Make(clientConfig, remote, registryv1alpha1connect.NewAuthnServiceClient)
+	resp, err := authnService.GetCurrentUser(ctx, connect.NewRequest(&registryv1alpha1.GetCurrentUserRequest{}))
+	if err != nil {
+		if connectErr := new(connect.Error); errors.As(err, &connectErr) && connectErr.Code() == connect.CodeUnavailable {
+			return nil, connectErr
+		}
+		// We don't want to use the default error from wrapError here if the error
+		// an unauthenticated error.
+		return nil, errors.New("invalid token provided")
+	}
+	user := resp.Msg.User
+	if user == nil {
+		return nil, errors.New("no user found for provided token")
+	}
+	return user, nil
+}
+
+// deleteNetrcCredentials deletes the credentials for the remote that are no
+// longer used from the .netrc file.
+func deleteNetrcCredentials(
+	container appflag.Container,
+	credentialStore credentialstore.Store,
+	remote string,
+) error {
+	if _, err := netrc.DeleteMachineForName(container, "go."+remote); err != nil {
+		return err
+	}
+	if credentialStore.Type() == credentialstore.TypeNetrc {
+		return nil
+	}
+	// The credentials in the credential store take precedence, but
+	// credentials are not left in plaintext.
+	if _, err := netrc.DeleteMachineForName(container, remote); err != nil {
+		return err
+	}
+	_, err := netrc.DeleteMachineForName(container, bufsso.SessionMachineName(remote))
+	return err
+}
@@ -0,0 +1,149 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registrylogin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufsso"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/credentialstore"
+	"github.com/bufbuild/buf/private/pkg/netrc"
+	"github.com/bufbuild/buf/private/pkg/oidc"
+	"github.com/bufbuild/buf/private/pkg/transport/http/httpclient"
+)
+
+// loginSSO logs in to the remote with the device authorization grant of the
+// identity provider of the remote, and saves the resulting registry token and
+// single sign-on session to the credential store.
+func loginSSO(
+	ctx context.Context,
+	container appflag.Container,
+	credentialStore credentialstore.Store,
+	remote string,
+	flags *flags,
+) error {
+	if flags.Username != "" || flags.TokenStdin {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s cannot be used with --%s or --%s",
+			ssoFlagName,
+			usernameFlagName,
+			tokenStdinFlagName,
+		)
+	}
+	config, err := bufcli.NewConfig(container)
+	if err != nil {
+		return err
+	}
+	issuer := flags.SSOIssuer
+	clientID := flags.SSOClientID
+	tokenExchangeURL := flags.SSOTokenExchangeURL
+	scopes := bufsso.DefaultScopes
+	if ssoConfig, ok := config.RemoteToSSOConfig[remote]; ok {
+		if issuer == "" {
+			issuer = ssoConfig.Issuer
+		}
+		if clientID == "" {
+			clientID = ssoConfig.ClientID
+		}
+		if tokenExchangeURL == "" {
+			tokenExchangeURL = ssoConfig.TokenExchangeURL
+		}
+		if len(ssoConfig.Scopes) > 0 {
+			scopes = ssoConfig.Scopes
+		}
+	}
+	if issuer == "" || clientID == "" || tokenExchangeURL == "" {
+		return fmt.Errorf(
+			"no identity provider configured for %s, set sso in your buf configuration or use --%s, --%s, and --%s",
+			remote,
+			ssoIssuerFlagName,
+			ssoClientIDFlagName,
+			ssoTokenExchangeURLFlagName,
+		)
+	}
+	httpClient := httpclient.NewClient(config.TLS, httpclient.ClientWithHostTLSConfigs(config.RemoteToTLS))
+	providerMetadata, err := oidc.Discover(ctx, httpClient, issuer)
+	if err != nil {
+		return err
+	}
+	deviceAuthorization, err := oidc.StartDeviceAuthorization(ctx, httpClient, providerMetadata, clientID, scopes)
+	if err != nil {
+		return err
+	}
+	verificationURI := deviceAuthorization.VerificationURI
+	if deviceAuthorization.VerificationURIComplete != "" {
+		verificationURI = deviceAuthorization.VerificationURIComplete
+	}
+	if _, err := fmt.Fprintf(
+		container.Stdout(),
+		"To log in to %s, open %s in your browser and enter the code %s.\n\nWaiting for you to log in...\n",
+		remote,
+		verificationURI,
+		deviceAuthorization.UserCode,
+	); err != nil {
+		return err
+	}
+	token, err := oidc.PollDeviceToken(ctx, httpClient, providerMetadata, clientID, deviceAuthorization)
+	if err != nil {
+		if oauthErr := (&oidc.Error{}); errors.As(err, &oauthErr) {
+			switch oauthErr.Code {
+			case oidc.ErrorCodeAccessDenied:
+				return errors.New("the login was denied")
+			case oidc.ErrorCodeExpiredToken:
+				return fmt.Errorf("the login was not completed in time, run buf registry login --%s again", ssoFlagName)
+			}
+		}
+		return err
+	}
+	credentials, err := bufsso.CompleteLogin(
+		ctx,
+		httpClient,
+		tokenExchangeURL,
+		providerMetadata,
+		clientID,
+		token,
+		time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+	user, err := getCurrentUser(ctx, container, remote, credentials.RegistryToken)
+	if err != nil {
+		return err
+	}
+	if err := bufcli.PutSSOCredentials(
+		ctx,
+		credentialStore,
+		netrc.NewMachine(
+			remote,
+			user.Username,
+			credentials.RegistryToken,
+		),
+		credentials.Session,
+	); err != nil {
+		return err
+	}
+	if err := deleteNetrcCredentials(container, credentialStore, remote); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(container.Stdout(), "\nLogged in as %s. Credentials saved to %s.\n", user.Username, credentialStore.Location())
+	return err
+}
@@ -20,8 +20,10 @@ import (
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
+	"github.com/bufbuild/buf/private/bufpkg/bufsso"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/credentialstore"
 	"github.com/bufbuild/buf/private/pkg/netrc"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -38,7 +40,7 @@ func NewCommand(
 		// TODO: Update when we have self-hosted.
 		Use:   name,
 		Short: `Log out of the Buf Schema Registry`,
-		Long:  fmt.Sprintf(`This command removes any BSR credentials from your %s file`, netrc.Filename),
+		Long:  fmt.Sprintf(`This command removes any BSR credentials from the credential store and your %s file`, netrc.Filename),
 		Args:  cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
@@ -67,21 +69,43 @@ func run(
 	if container.NumArgs() == 1 {
 		remote = container.Arg(0)
 	}
-	modified1, err := netrc.DeleteMachineForName(container, remote)
+	credentialStore, err := bufcli.NewCredentialStore(container)
 	if err != nil {
 		return err
 	}
-	modified2, err := netrc.DeleteMachineForName(container, "go."+remote)
+	modified1, err := credentialStore.DeleteMachine(ctx, remote)
 	if err != nil {
 		return err
 	}
-	netrcFilePath, err := netrc.GetFilePath(container)
+	if _, err := credentialStore.DeleteMachine(ctx, bufsso.SessionMachineName(remote)); err != nil {
+		return err
+	}
+	var modified2 bool
+	if credentialStore.Type() != credentialstore.TypeNetrc {
+		// Credentials saved before the credential store was configured.
+		modified2, err = netrc.DeleteMachineForName(container, remote)
+		if err != nil {
+			return err
+		}
+		if _, err := netrc.DeleteMachineForName(container, bufsso.SessionMachineName(remote)); err != nil {
+			return err
+		}
+	}
+	modified3, err := netrc.DeleteMachineForName(container, "go."+remote)
 	if err != nil {
 		return err
 	}
-	loggedOutMessage := fmt.Sprintf("All existing BSR credentials removed from %s\n", netrcFilePath)
-	if !modified1 && !modified2 {
-		loggedOutMessage = fmt.Sprintf("No BSR credentials found in %s; you are already logged out\n", netrcFilePath)
+	location := credentialStore.Location()
+	if credentialStore.Type() != credentialstore.TypeNetrc {
+		netrcFilePath, err := netrc.GetFilePath(container)
+		if err != nil {
+			return err
+		}
+		location = fmt.Sprintf("%s and %s", location, netrcFilePath)
+	}
+	loggedOutMessage := fmt.Sprintf("All existing BSR credentials removed from %s\n", location)
+	if !modified1 && !modified2 && !modified3 {
+		loggedOutMessage = fmt.Sprintf("No BSR credentials found in %s; you are already logged out\n", location)
 	}
 	if _, err := container.Stdout().Write([]byte(loggedOutMessage)); err != nil {
 		return err
@@ -497,6 +497,107 @@ func TestWorkspaceWithLock(t *testing.T) {
 	)
 }
 
+func TestWorkspaceGraph(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`flowchart LR
+  n1["bufbuild.test/workspace/first"]
+  n2["bufbuild.test/workspace/second"]
+  n3["bufbuild.test/workspace/third"]
+  n1 --> n2
+  n2 --> n3`,
+		"beta",
+		"graph",
+		filepath.Join("testdata", "workspace", "success", "transitive", "proto"),
+		"--format",
+		"mermaid",
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`{
+  "nodes": [
+    {
+      "id": "c.proto",
+      "module": "bufbuild.test/workspace/third",
+      "is_import": true
+    },
+    {
+      "id": "b.proto",
+      "module": "bufbuild.test/workspace/second",
+      "is_import": true
+    },
+    {
+      "id": "a.proto",
+      "module": "bufbuild.test/workspace/first",
+      "is_import": false
+    }
+  ],
+  "edges": [
+    {
+      "from": "a.proto",
+      "to": "b.proto"
+    },
+    {
+      "from": "b.proto",
+      "to": "c.proto"
+    }
+  ]
+}`,
+		"beta",
+		"graph",
+		filepath.Join("testdata", "workspace", "success", "transitive", "proto"),
+		"--level",
+		"file",
+		"--format",
+		"json",
+	)
+}
+
+func TestWorkspaceWhy(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`# bufbuild.test/workspace/third
+a.proto
+b.proto (bufbuild.test/workspace/second)
+c.proto (bufbuild.test/workspace/third)`,
+		"beta",
+		"why",
+		"bufbuild.test/workspace/third",
+		filepath.Join("testdata", "workspace", "success", "transitive", "proto"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`# bufbuild.test/workspace/second
+a.proto
+b.proto (bufbuild.test/workspace/second)`,
+		"beta",
+		"why",
+		"bufbuild.test/workspace/second",
+		filepath.Join("testdata", "workspace", "success", "transitive", "proto"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`# bufbuild.test/workspace/first
+(the source does not import any files of bufbuild.test/workspace/first)`,
+		"beta",
+		"why",
+		"bufbuild.test/workspace/first",
+		filepath.Join("testdata", "workspace", "success", "transitive", "private", "proto"),
+	)
+}
+
 func TestWorkspaceWithTransitiveDependencies(t *testing.T) {
 	// The workspace points to a module that includes transitive
 	// dependencies (i.e. a depends on b, and b depends on c).
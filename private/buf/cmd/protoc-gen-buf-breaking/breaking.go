@@ -25,6 +25,7 @@ import (
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufwire"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
@@ -100,7 +101,13 @@ func handle(
 	}
 	storageosProvider := storageos.NewProvider(storageos.ProviderWithSymlinks())
 	runner := command.NewRunner()
-	imageReader := bufcli.NewWireImageReader(logger, storageosProvider, runner)
+	imageReader := bufcli.NewWireImageReader(
+		container,
+		logger,
+		storageosProvider,
+		runner,
+		bufwire.ImageReaderWithLazySourceCodeInfo(),
+	)
 	againstImage, err := imageReader.GetImage(
 		ctx,
 		newContainer(container),
@@ -36,6 +36,26 @@ const (
 	//
 	// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#setting-an-error-message.
 	FormatGithubActions
+	// FormatLSP is the Language Server Protocol format for FileAnnotations.
+	//
+	// Each line is the JSON parameters of a textDocument/publishDiagnostics notification
+	// for a file, with the diagnostics of the file.
+	//
+	// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_publishDiagnostics.
+	FormatLSP
+	// FormatVSCode is the Visual Studio Code format for FileAnnotations.
+	//
+	// Each line is of the form "path(startLine,startColumn,endLine,endColumn): severity type: message",
+	// which can be matched by a problem matcher with the pattern:
+	//
+	//	{
+	//	  "regexp": "^(.*)\\((\\d+),(\\d+),(\\d+),(\\d+)\\): (error|warning) (\\S+): (.*)$",
+	//	  "file": 1, "line": 2, "column": 3, "endLine": 4, "endColumn": 5,
+	//	  "severity": 6, "code": 7, "message": 8
+	//	}
+	//
+	// See https://code.visualstudio.com/docs/editor/tasks#_defining-a-problem-matcher.
+	FormatVSCode
 )
 
 var (
@@ -48,6 +68,8 @@ var (
 		"msvs",
 		"junit",
 		"github-actions",
+		"lsp",
+		"vscode",
 	}
 	// AllFormatStringsWithAliases is all format strings with aliases.
 	//
@@ -59,6 +81,8 @@ var (
 		"msvs",
 		"junit",
 		"github-actions",
+		"lsp",
+		"vscode",
 	}
 
 	stringToFormat = map[string]Format{
@@ -69,6 +93,8 @@ var (
 		"msvs":           FormatMSVS,
 		"junit":          FormatJUnit,
 		"github-actions": FormatGithubActions,
+		"lsp":            FormatLSP,
+		"vscode":         FormatVSCode,
 	}
 	formatToString = map[Format]string{
 		FormatText:          "text",
@@ -76,6 +102,8 @@ var (
 		FormatMSVS:          "msvs",
 		FormatJUnit:         "junit",
 		FormatGithubActions: "github-actions",
+		FormatLSP:           "lsp",
+		FormatVSCode:        "vscode",
 	}
 )
 
@@ -146,6 +174,45 @@ type FileAnnotation interface {
 	Type() string
 	// Message is the message of the annotation.
 	Message() string
+	// Edits are the edits of the file that fix the annotation.
+	//
+	// Only annotations that can be fixed mechanically, such as annotations for
+	// names that do not match a naming convention, have edits.
+	Edits() []Edit
+}
+
+// Edit is an edit of the text of a file.
+//
+// Lines and columns are 1-indexed, and the end column is exclusive, as with the
+// lines and columns of a FileAnnotation.
+type Edit interface {
+	// StartLine is the starting line of the text to replace.
+	StartLine() int
+	// StartColumn is the starting column of the text to replace.
+	StartColumn() int
+	// EndLine is the ending line of the text to replace.
+	EndLine() int
+	// EndColumn is the ending column of the text to replace.
+	EndColumn() int
+	// NewText is the text to replace the text with.
+	NewText() string
+}
+
+// NewEdit returns a new Edit.
+func NewEdit(
+	startLine int,
+	startColumn int,
+	endLine int,
+	endColumn int,
+	newText string,
+) Edit {
+	return newEdit(
+		startLine,
+		startColumn,
+		endLine,
+		endColumn,
+		newText,
+	)
 }
 
 // NewFileAnnotation returns a new FileAnnotation.
@@ -157,6 +224,7 @@ func NewFileAnnotation(
 	endColumn int,
 	typeString string,
 	message string,
+	options ...FileAnnotationOption,
 ) FileAnnotation {
 	return newFileAnnotation(
 		fileInfo,
@@ -166,9 +234,21 @@ func NewFileAnnotation(
 		endColumn,
 		typeString,
 		message,
+		options...,
 	)
 }
 
+// FileAnnotationOption is an option for a new FileAnnotation.
+type FileAnnotationOption func(*fileAnnotation)
+
+// FileAnnotationWithEdits returns a new FileAnnotationOption that sets the
+// edits that fix the FileAnnotation.
+func FileAnnotationWithEdits(edits ...Edit) FileAnnotationOption {
+	return func(fileAnnotation *fileAnnotation) {
+		fileAnnotation.edits = append(fileAnnotation.edits, edits...)
+	}
+}
+
 // SortFileAnnotations sorts the FileAnnotations.
 //
 // The order of sorting is:
@@ -219,6 +299,10 @@ func PrintFileAnnotations(writer io.Writer, fileAnnotations []FileAnnotation, fo
 		return printAsJUnit(writer, fileAnnotations)
 	case FormatGithubActions:
 		return printAsGithubActions(writer, fileAnnotations)
+	case FormatLSP:
+		return printAsLSP(writer, fileAnnotations)
+	case FormatVSCode:
+		return printAsVSCode(writer, fileAnnotations)
 	default:
 		return fmt.Errorf("unknown FileAnnotation Format: %v", format)
 	}
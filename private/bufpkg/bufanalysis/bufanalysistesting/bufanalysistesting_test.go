@@ -15,6 +15,7 @@
 package bufanalysistesting
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -64,6 +65,32 @@ path/to/file.proto:2:1:Hello.
 		t,
 		`{"path":"path/to/file.proto","start_line":1,"start_column":1,"end_line":1,"end_column":1,"type":"FOO","message":"Hello."}
 {"path":"path/to/file.proto","start_line":2,"start_column":1,"end_line":2,"end_column":1,"type":"FOO","message":"Hello."}
+`,
+		sb.String(),
+	)
+	sb.Reset()
+	err = bufanalysis.PrintFileAnnotations(
+		sb,
+		[]bufanalysis.FileAnnotation{
+			bufanalysis.NewFileAnnotation(
+				nil,
+				3,
+				9,
+				3,
+				16,
+				"FOO",
+				"Hello.",
+				bufanalysis.FileAnnotationWithEdits(
+					bufanalysis.NewEdit(3, 9, 3, 16, "order_id"),
+				),
+			),
+		},
+		"json",
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"start_line":3,"start_column":9,"end_line":3,"end_column":16,"type":"FOO","message":"Hello.","edits":[{"start_line":3,"start_column":9,"end_line":3,"end_column":16,"new_text":"order_id"}]}
 `,
 		sb.String(),
 	)
@@ -73,6 +100,65 @@ path/to/file.proto:2:1:Hello.
 	assert.Equal(t,
 		`path/to/file.proto(1,1) : error FOO : Hello.
 path/to/file.proto(2,1) : error FOO : Hello.
+`,
+		sb.String(),
+	)
+	sb.Reset()
+	err = bufanalysis.PrintFileAnnotations(
+		sb,
+		append(
+			fileAnnotations,
+			newFileAnnotation(
+				t,
+				"path/to/file.proto",
+				3,
+				5,
+				3,
+				10,
+				"COMPILE",
+				"Syntax error.",
+			),
+		),
+		"vscode",
+	)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`path/to/file.proto(1,1,1,1): warning FOO: Hello.
+path/to/file.proto(2,1,2,1): warning FOO: Hello.
+path/to/file.proto(3,5,3,10): error COMPILE: Syntax error.
+`,
+		sb.String(),
+	)
+	sb.Reset()
+	err = bufanalysis.PrintFileAnnotations(
+		sb,
+		append(
+			fileAnnotations,
+			newFileAnnotation(
+				t,
+				"path/to/file.proto",
+				3,
+				5,
+				3,
+				10,
+				"COMPILE",
+				"Syntax error.",
+			),
+		),
+		"lsp",
+	)
+	require.NoError(t, err)
+	absPath, err := filepath.Abs(filepath.FromSlash("path/to/file.proto"))
+	require.NoError(t, err)
+	uriPath := filepath.ToSlash(absPath)
+	if !strings.HasPrefix(uriPath, "/") {
+		uriPath = "/" + uriPath
+	}
+	assert.Equal(t,
+		`{"uri":"file://`+uriPath+`","diagnostics":[`+
+			`{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"severity":2,"code":"FOO","source":"buf","message":"Hello."},`+
+			`{"range":{"start":{"line":1,"character":0},"end":{"line":1,"character":0}},"severity":2,"code":"FOO","source":"buf","message":"Hello."},`+
+			`{"range":{"start":{"line":2,"character":4},"end":{"line":2,"character":9}},"severity":1,"code":"COMPILE","source":"buf","message":"Syntax error."}]}
 `,
 		sb.String(),
 	)
@@ -27,6 +27,7 @@ type fileAnnotation struct {
 	endColumn   int
 	typeString  string
 	message     string
+	edits       []Edit
 }
 
 func newFileAnnotation(
@@ -37,8 +38,9 @@ func newFileAnnotation(
 	endColumn int,
 	typeString string,
 	message string,
+	options ...FileAnnotationOption,
 ) *fileAnnotation {
-	return &fileAnnotation{
+	fileAnnotation := &fileAnnotation{
 		fileInfo:    fileInfo,
 		startLine:   startLine,
 		startColumn: startColumn,
@@ -47,6 +49,10 @@ func newFileAnnotation(
 		typeString:  typeString,
 		message:     message,
 	}
+	for _, option := range options {
+		option(fileAnnotation)
+	}
+	return fileAnnotation
 }
 
 func (f *fileAnnotation) FileInfo() FileInfo {
@@ -77,6 +83,10 @@ func (f *fileAnnotation) Message() string {
 	return f.message
 }
 
+func (f *fileAnnotation) Edits() []Edit {
+	return f.edits
+}
+
 func (f *fileAnnotation) String() string {
 	if f == nil {
 		return ""
@@ -20,10 +20,25 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
+const (
+	// lspDiagnosticSeverityError is the DiagnosticSeverity for errors.
+	lspDiagnosticSeverityError = 1
+	// lspDiagnosticSeverityWarning is the DiagnosticSeverity for warnings.
+	lspDiagnosticSeverityWarning = 2
+	// lspDiagnosticSource is the source of diagnostics.
+	lspDiagnosticSource = "buf"
+	// compileType is the type of FileAnnotations for compilation errors,
+	// which are printed as errors by the editor formats. All other
+	// FileAnnotations are printed as warnings.
+	compileType = "COMPILE"
+)
+
 func printAsText(writer io.Writer, fileAnnotations []FileAnnotation) error {
 	return printEachAnnotationOnNewLine(
 		writer,
@@ -56,6 +71,39 @@ func printAsGithubActions(writer io.Writer, fileAnnotations []FileAnnotation) er
 	)
 }
 
+func printAsVSCode(writer io.Writer, fileAnnotations []FileAnnotation) error {
+	return printEachAnnotationOnNewLine(
+		writer,
+		fileAnnotations,
+		printFileAnnotationAsVSCode,
+	)
+}
+
+func printAsLSP(writer io.Writer, fileAnnotations []FileAnnotation) error {
+	encoder := json.NewEncoder(writer)
+	for _, annotations := range groupAnnotationsByPath(fileAnnotations) {
+		uri := ""
+		if fileInfo := annotations[0].FileInfo(); fileInfo != nil {
+			var err error
+			uri, err = pathToURI(fileInfo.ExternalPath())
+			if err != nil {
+				return err
+			}
+		}
+		params := externalLSPPublishDiagnosticsParams{
+			URI:         uri,
+			Diagnostics: make([]externalLSPDiagnostic, len(annotations)),
+		}
+		for i, annotation := range annotations {
+			params.Diagnostics[i] = newExternalLSPDiagnostic(annotation)
+		}
+		if err := encoder.Encode(params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func printAsJUnit(writer io.Writer, fileAnnotations []FileAnnotation) error {
 	encoder := xml.NewEncoder(writer)
 	encoder.Indent("", "  ")
@@ -206,6 +254,42 @@ func printFileAnnotationAsJSON(buffer *bytes.Buffer, f FileAnnotation) error {
 	return nil
 }
 
+func printFileAnnotationAsVSCode(buffer *bytes.Buffer, f FileAnnotation) error {
+	if f == nil {
+		return nil
+	}
+	path := "<input>"
+	if f.FileInfo() != nil {
+		path = f.FileInfo().ExternalPath()
+	}
+	startLine := atLeast1(f.StartLine())
+	startColumn := atLeast1(f.StartColumn())
+	endLine := f.EndLine()
+	endColumn := f.EndColumn()
+	if endLine == 0 {
+		endLine = startLine
+		endColumn = startColumn
+	}
+	endColumn = atLeast1(endColumn)
+	severity := "warning"
+	if f.Type() == compileType {
+		severity = "error"
+	}
+	typeString := f.Type()
+	if typeString == "" {
+		// should never happen but just in case
+		typeString = "FAILURE"
+	}
+	_, _ = buffer.WriteString(path)
+	_, _ = buffer.WriteString(fmt.Sprintf("(%d,%d,%d,%d): ", startLine, startColumn, endLine, endColumn))
+	_, _ = buffer.WriteString(severity)
+	_, _ = buffer.WriteRune(' ')
+	_, _ = buffer.WriteString(typeString)
+	_, _ = buffer.WriteString(": ")
+	_, _ = buffer.WriteString(f.Message())
+	return nil
+}
+
 func printFileAnnotationAsGithubActions(buffer *bytes.Buffer, f FileAnnotation) error {
 	if f == nil {
 		return nil
@@ -257,6 +341,16 @@ type externalFileAnnotation struct {
 	EndColumn   int    `json:"end_column,omitempty" yaml:"end_column,omitempty"`
 	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
 	Message     string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Edits are the edits that fix the annotation, if any.
+	Edits []externalEdit `json:"edits,omitempty" yaml:"edits,omitempty"`
+}
+
+type externalEdit struct {
+	StartLine   int    `json:"start_line" yaml:"start_line"`
+	StartColumn int    `json:"start_column" yaml:"start_column"`
+	EndLine     int    `json:"end_line" yaml:"end_line"`
+	EndColumn   int    `json:"end_column" yaml:"end_column"`
+	NewText     string `json:"new_text" yaml:"new_text"`
 }
 
 func newExternalFileAnnotation(f FileAnnotation) externalFileAnnotation {
@@ -272,7 +366,133 @@ func newExternalFileAnnotation(f FileAnnotation) externalFileAnnotation {
 		EndColumn:   atLeast1(f.EndColumn()),
 		Type:        f.Type(),
 		Message:     f.Message(),
+		Edits:       newExternalEdits(f.Edits()),
+	}
+}
+
+func newExternalEdits(edits []Edit) []externalEdit {
+	if len(edits) == 0 {
+		return nil
+	}
+	externalEdits := make([]externalEdit, len(edits))
+	for i, edit := range edits {
+		externalEdits[i] = externalEdit{
+			StartLine:   edit.StartLine(),
+			StartColumn: edit.StartColumn(),
+			EndLine:     edit.EndLine(),
+			EndColumn:   edit.EndColumn(),
+			NewText:     edit.NewText(),
+		}
+	}
+	return externalEdits
+}
+
+// externalLSPPublishDiagnosticsParams is the PublishDiagnosticsParams of the
+// Language Server Protocol.
+type externalLSPPublishDiagnosticsParams struct {
+	URI         string                  `json:"uri"`
+	Diagnostics []externalLSPDiagnostic `json:"diagnostics"`
+}
+
+// externalLSPDiagnostic is the Diagnostic of the Language Server Protocol.
+type externalLSPDiagnostic struct {
+	Range    externalLSPRange `json:"range"`
+	Severity int              `json:"severity"`
+	Code     string           `json:"code,omitempty"`
+	Source   string           `json:"source"`
+	Message  string           `json:"message"`
+	// Data contains the edits that fix the diagnostic, if any, so that they
+	// are passed back to a textDocument/codeAction request.
+	Data *externalLSPDiagnosticData `json:"data,omitempty"`
+}
+
+type externalLSPDiagnosticData struct {
+	Edits []externalLSPTextEdit `json:"edits"`
+}
+
+// externalLSPTextEdit is the TextEdit of the Language Server Protocol.
+type externalLSPTextEdit struct {
+	Range   externalLSPRange `json:"range"`
+	NewText string           `json:"newText"`
+}
+
+type externalLSPRange struct {
+	Start externalLSPPosition `json:"start"`
+	End   externalLSPPosition `json:"end"`
+}
+
+// externalLSPPosition is a 0-indexed position.
+type externalLSPPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+func newExternalLSPDiagnostic(f FileAnnotation) externalLSPDiagnostic {
+	severity := lspDiagnosticSeverityWarning
+	if f.Type() == compileType {
+		severity = lspDiagnosticSeverityError
+	}
+	startLine := f.StartLine()
+	startColumn := f.StartColumn()
+	endLine := f.EndLine()
+	endColumn := f.EndColumn()
+	if endLine == 0 {
+		endLine = startLine
+		endColumn = startColumn
+	}
+	diagnostic := externalLSPDiagnostic{
+		Range: externalLSPRange{
+			Start: newExternalLSPPosition(startLine, startColumn),
+			End:   newExternalLSPPosition(endLine, endColumn),
+		},
+		Severity: severity,
+		Code:     f.Type(),
+		Source:   lspDiagnosticSource,
+		Message:  f.Message(),
+	}
+	if edits := f.Edits(); len(edits) > 0 {
+		diagnostic.Data = &externalLSPDiagnosticData{
+			Edits: make([]externalLSPTextEdit, len(edits)),
+		}
+		for i, edit := range edits {
+			diagnostic.Data.Edits[i] = externalLSPTextEdit{
+				Range: externalLSPRange{
+					Start: newExternalLSPPosition(edit.StartLine(), edit.StartColumn()),
+					End:   newExternalLSPPosition(edit.EndLine(), edit.EndColumn()),
+				},
+				NewText: edit.NewText(),
+			}
+		}
+	}
+	return diagnostic
+}
+
+// newExternalLSPPosition returns the position for the 1-indexed line and column,
+// where 0 means that the line or column is not known.
+func newExternalLSPPosition(line int, column int) externalLSPPosition {
+	if line > 0 {
+		line--
+	}
+	if column > 0 {
+		column--
+	}
+	return externalLSPPosition{
+		Line:      line,
+		Character: column,
+	}
+}
+
+// pathToURI returns the file URI of the path.
+func pathToURI(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	uriPath := filepath.ToSlash(absPath)
+	if !strings.HasPrefix(uriPath, "/") {
+		uriPath = "/" + uriPath
 	}
+	return (&url.URL{Scheme: "file", Path: uriPath}).String(), nil
 }
 
 func printEachAnnotationOnNewLine(
@@ -16,7 +16,11 @@
 package bufapimodule
 
 import (
+	"context"
+
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
 	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
 	"go.uber.org/zap"
@@ -25,6 +29,7 @@ import (
 type DownloadServiceClientFactory func(address string) registryv1alpha1connect.DownloadServiceClient
 type RepositoryCommitServiceClientFactory func(address string) registryv1alpha1connect.RepositoryCommitServiceClient
 type RepositoryServiceClientFactory func(address string) registryv1alpha1connect.RepositoryServiceClient
+type RepositoryTagServiceClientFactory func(address string) registryv1alpha1connect.RepositoryTagServiceClient
 
 func NewDownloadServiceClientFactory(clientConfig *connectclient.Config) DownloadServiceClientFactory {
 	return func(address string) registryv1alpha1connect.DownloadServiceClient {
@@ -44,6 +49,12 @@ func NewRepositoryServiceClientFactory(clientConfig *connectclient.Config) Repos
 	}
 }
 
+func NewRepositoryTagServiceClientFactory(clientConfig *connectclient.Config) RepositoryTagServiceClientFactory {
+	return func(address string) registryv1alpha1connect.RepositoryTagServiceClient {
+		return connectclient.Make(clientConfig, address, registryv1alpha1connect.NewRepositoryTagServiceClient)
+	}
+}
+
 // NewModuleReader returns a new ModuleReader backed by the download service.
 func NewModuleReader(
 	logger *zap.Logger,
@@ -70,6 +81,33 @@ func ModuleReaderWithDeprecationWarning(
 	}
 }
 
+// ModuleReaderWithSignatureVerification makes the module reader verify the
+// signatures of the modules that the trust roots require to be signed, and
+// return an error if a module has no valid signature.
+func ModuleReaderWithSignatureVerification(
+	signatureReader SignatureReader,
+	trustRoots bufmodulesign.TrustRoots,
+) ModuleReaderOption {
+	return func(reader *moduleReader) {
+		reader.signatureReader = signatureReader
+		reader.trustRoots = trustRoots
+	}
+}
+
+// SignatureReader reads the signatures of commits.
+type SignatureReader interface {
+	// GetSignatures gets the signatures that are attached to the commit of the pin.
+	GetSignatures(ctx context.Context, modulePin bufmoduleref.ModulePin) ([]*bufmodulesign.Signature, error)
+}
+
+// NewSignatureReader returns a new SignatureReader backed by the repository tag service.
+func NewSignatureReader(
+	repositoryClientFactory RepositoryServiceClientFactory,
+	repositoryTagClientFactory RepositoryTagServiceClientFactory,
+) SignatureReader {
+	return newSignatureReader(repositoryClientFactory, repositoryTagClientFactory)
+}
+
 // NewModuleResolver returns a new ModuleResolver backed by the resolve service.
 func NewModuleResolver(
 	logger *zap.Logger,
@@ -25,6 +25,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufcas/bufcasalpha"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
 	"go.uber.org/zap"
 )
@@ -34,6 +35,9 @@ type moduleReader struct {
 	downloadClientFactory DownloadServiceClientFactory
 	// repositoryClientFactory may be nil
 	repositoryClientFactory RepositoryServiceClientFactory
+	// signatureReader and trustRoots may be nil
+	signatureReader SignatureReader
+	trustRoots      bufmodulesign.TrustRoots
 }
 
 func newModuleReader(
@@ -79,6 +83,11 @@ func (m *moduleReader) GetModule(ctx context.Context, modulePin bufmoduleref.Mod
 	if err != nil {
 		return nil, err
 	}
+	if m.trustRoots != nil {
+		if err := m.verifySignatures(ctx, modulePin, moduleIdentity, fileSet); err != nil {
+			return nil, err
+		}
+	}
 	if m.repositoryClientFactory != nil {
 		if err := warnIfDeprecated(ctx, m.repositoryClientFactory, modulePin, m.logger); err != nil {
 			return nil, err
@@ -110,6 +119,38 @@ func (m *moduleReader) downloadManifestAndBlobs(
 	return resp.Msg, err
 }
 
+// verifySignatures verifies the signatures of the module, if the trust roots
+// require it to be signed.
+func (m *moduleReader) verifySignatures(
+	ctx context.Context,
+	modulePin bufmoduleref.ModulePin,
+	moduleIdentity bufmoduleref.ModuleIdentity,
+	fileSet bufcas.FileSet,
+) error {
+	verifier := m.trustRoots.VerifierForModuleIdentity(moduleIdentity)
+	if verifier == nil {
+		return nil
+	}
+	manifestBlob, err := bufcas.ManifestToBlob(fileSet.Manifest())
+	if err != nil {
+		return err
+	}
+	signatures, err := m.signatureReader.GetSignatures(ctx, modulePin)
+	if err != nil {
+		return fmt.Errorf("could not get the signatures of %s: %w", modulePin.String(), err)
+	}
+	keyID, err := verifier.Verify(moduleIdentity, manifestBlob.Digest(), signatures)
+	if err != nil {
+		return fmt.Errorf("could not verify %s: %w", modulePin.String(), err)
+	}
+	m.logger.Debug(
+		"verified_signature",
+		zap.String("module", modulePin.String()),
+		zap.String("key_id", keyID),
+	)
+	return nil
+}
+
 // warnIfDeprecated emits a warning message to logger if the repository
 // is deprecated on the BSR.
 func warnIfDeprecated(
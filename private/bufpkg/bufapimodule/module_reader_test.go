@@ -16,6 +16,10 @@ package bufapimodule
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"testing"
 
@@ -23,6 +27,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufcas"
 	"github.com/bufbuild/buf/private/bufpkg/bufcas/bufcasalpha"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
 	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
 	modulev1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/module/v1alpha1"
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
@@ -108,6 +113,98 @@ message Test {}
 	)
 }
 
+func TestDownloadSignatureVerification(t *testing.T) {
+	t.Parallel()
+	files := map[string][]byte{
+		"test.proto": []byte(`syntax = "proto3";
+message Test {}
+`),
+	}
+	bucket, err := storagemem.NewReadBucket(files)
+	require.NoError(t, err)
+	fileSet, err := bufcas.NewFileSetForBucket(context.Background(), bucket)
+	require.NoError(t, err)
+	manifestBlob, err := bufcas.ManifestToBlob(fileSet.Manifest())
+	require.NoError(t, err)
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateKeyData, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicKeyData, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	require.NoError(t, err)
+	signer, err := bufmodulesign.NewSigner(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyData}))
+	require.NoError(t, err)
+	trustRoots, err := bufmodulesign.NewTrustRoots(
+		map[string][][]byte{
+			"remote/owner": {pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyData})},
+		},
+	)
+	require.NoError(t, err)
+	moduleIdentity, err := bufmoduleref.NewModuleIdentity("remote", "owner", "repository")
+	require.NoError(t, err)
+	signature, err := signer.Sign(moduleIdentity, manifestBlob.Digest())
+	require.NoError(t, err)
+	otherModuleIdentity, err := bufmoduleref.NewModuleIdentity("remote", "owner", "other")
+	require.NoError(t, err)
+	otherSignature, err := signer.Sign(otherModuleIdentity, manifestBlob.Digest())
+	require.NoError(t, err)
+	testCases := []struct {
+		desc          string
+		owner         string
+		signatures    []*bufmodulesign.Signature
+		errorContains string
+	}{
+		{
+			desc:       "valid signature",
+			owner:      "owner",
+			signatures: []*bufmodulesign.Signature{signature},
+		},
+		{
+			desc:          "no signatures",
+			owner:         "owner",
+			errorContains: "could not verify remote/owner/repository:commit: module remote/owner/repository has no signatures",
+		},
+		{
+			desc:          "signature of another module",
+			owner:         "owner",
+			signatures:    []*bufmodulesign.Signature{otherSignature},
+			errorContains: "has no valid signature by a trusted key",
+		},
+		{
+			desc:  "module without trust roots",
+			owner: "other",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.desc, func(t *testing.T) {
+			t.Parallel()
+			moduleReader := newModuleReader(
+				zap.NewNop(),
+				newMockDownloadService(t, withBlobsFromMap(files)).factory,
+				ModuleReaderWithSignatureVerification(
+					mockSignatureReader(testCase.signatures),
+					trustRoots,
+				),
+			)
+			pin, err := bufmoduleref.NewModulePin(
+				"remote",
+				testCase.owner,
+				"repository",
+				"commit",
+				"digest",
+			)
+			require.NoError(t, err)
+			_, err = moduleReader.GetModule(context.Background(), pin)
+			if testCase.errorContains != "" {
+				assert.ErrorContains(t, err, testCase.errorContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func testDownload(
 	t *testing.T,
 	desc string,
@@ -248,6 +345,15 @@ func (m *mockDownloadService) DownloadManifestAndBlobs(
 	}), nil
 }
 
+type mockSignatureReader []*bufmodulesign.Signature
+
+func (m mockSignatureReader) GetSignatures(
+	context.Context,
+	bufmoduleref.ModulePin,
+) ([]*bufmodulesign.Signature, error) {
+	return m, nil
+}
+
 type nopRepositoryServiceClient struct {
 	registryv1alpha1connect.UnimplementedRepositoryServiceHandler
 }
@@ -0,0 +1,87 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufapimodule
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+)
+
+// tagsPageSize is the page size when listing the tags of a commit.
+const tagsPageSize = 100
+
+type signatureReader struct {
+	repositoryClientFactory    RepositoryServiceClientFactory
+	repositoryTagClientFactory RepositoryTagServiceClientFactory
+}
+
+func newSignatureReader(
+	repositoryClientFactory RepositoryServiceClientFactory,
+	repositoryTagClientFactory RepositoryTagServiceClientFactory,
+) *signatureReader {
+	return &signatureReader{
+		repositoryClientFactory:    repositoryClientFactory,
+		repositoryTagClientFactory: repositoryTagClientFactory,
+	}
+}
+
+func (s *signatureReader) GetSignatures(
+	ctx context.Context,
+	modulePin bufmoduleref.ModulePin,
+) ([]*bufmodulesign.Signature, error) {
+	repositoryResp, err := s.repositoryClientFactory(modulePin.Remote()).GetRepositoryByFullName(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetRepositoryByFullNameRequest{
+			FullName: fmt.Sprintf("%s/%s", modulePin.Owner(), modulePin.Repository()),
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	repositoryTagService := s.repositoryTagClientFactory(modulePin.Remote())
+	var signatures []*bufmodulesign.Signature
+	var pageToken string
+	for {
+		resp, err := repositoryTagService.ListRepositoryTagsForReference(
+			ctx,
+			connect.NewRequest(&registryv1alpha1.ListRepositoryTagsForReferenceRequest{
+				RepositoryId: repositoryResp.Msg.Repository.Id,
+				Reference:    modulePin.Commit(),
+				PageSize:     tagsPageSize,
+				PageToken:    pageToken,
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, repositoryTag := range resp.Msg.RepositoryTags {
+			// Malformed signature tags cannot be valid signatures, so they are
+			// ignored like any other tag.
+			signature, ok, err := bufmodulesign.TagToSignature(repositoryTag.Name)
+			if ok && err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+		pageToken = resp.Msg.NextPageToken
+		if pageToken == "" {
+			return signatures, nil
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufapimodule
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulesign"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSignatures(t *testing.T) {
+	t.Parallel()
+	repositoryTagService := &mockRepositoryTagService{
+		pages: [][]string{
+			{"v1.0.0", "sig-0123456789abcdef-AAEC"},
+			{"sig-malformed", "sig-fedcba9876543210-AwQF"},
+		},
+	}
+	signatureReader := newSignatureReader(
+		func(string) registryv1alpha1connect.RepositoryServiceClient {
+			return &mockRepositoryServiceClient{}
+		},
+		func(string) registryv1alpha1connect.RepositoryTagServiceClient {
+			return repositoryTagService
+		},
+	)
+	pin, err := bufmoduleref.NewModulePin("remote", "owner", "repository", "commit", "")
+	require.NoError(t, err)
+	signatures, err := signatureReader.GetSignatures(context.Background(), pin)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*bufmodulesign.Signature{
+			{KeyID: "0123456789abcdef", Value: []byte{0, 1, 2}},
+			{KeyID: "fedcba9876543210", Value: []byte{3, 4, 5}},
+		},
+		signatures,
+	)
+	for _, request := range repositoryTagService.requests {
+		assert.Equal(t, "repository-id", request.RepositoryId)
+		assert.Equal(t, "commit", request.Reference)
+	}
+}
+
+type mockRepositoryServiceClient struct {
+	registryv1alpha1connect.UnimplementedRepositoryServiceHandler
+}
+
+func (m *mockRepositoryServiceClient) GetRepositoryByFullName(
+	_ context.Context,
+	req *connect.Request[registryv1alpha1.GetRepositoryByFullNameRequest],
+) (*connect.Response[registryv1alpha1.GetRepositoryByFullNameResponse], error) {
+	if req.Msg.FullName != "owner/repository" {
+		return nil, connect.NewError(connect.CodeNotFound, nil)
+	}
+	return connect.NewResponse(&registryv1alpha1.GetRepositoryByFullNameResponse{
+		Repository: &registryv1alpha1.Repository{Id: "repository-id"},
+	}), nil
+}
+
+type mockRepositoryTagService struct {
+	registryv1alpha1connect.UnimplementedRepositoryTagServiceHandler
+
+	// pages are the names of the tags of every page.
+	pages    [][]string
+	requests []*registryv1alpha1.ListRepositoryTagsForReferenceRequest
+}
+
+func (m *mockRepositoryTagService) ListRepositoryTagsForReference(
+	_ context.Context,
+	req *connect.Request[registryv1alpha1.ListRepositoryTagsForReferenceRequest],
+) (*connect.Response[registryv1alpha1.ListRepositoryTagsForReferenceResponse], error) {
+	m.requests = append(m.requests, req.Msg)
+	page := len(m.requests) - 1
+	resp := &registryv1alpha1.ListRepositoryTagsForReferenceResponse{}
+	for _, name := range m.pages[page] {
+		resp.RepositoryTags = append(resp.RepositoryTags, &registryv1alpha1.RepositoryTag{Name: name})
+	}
+	if page+1 < len(m.pages) {
+		resp.NextPageToken = "next"
+	}
+	return connect.NewResponse(resp), nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufavro converts between the messages of an image and Avro schemas.
+//
+// Messages are converted to Avro schemas as follows:
+//
+//   - Messages are records and enums are enums, named by their names, in the
+//     namespace of their package or of the message that they are nested in. The
+//     default of an enum is its first value.
+//   - bool is boolean, int32, sint32, and sfixed32 are int, uint32, fixed32, and
+//     all 64-bit integers are long, float is float, double is double, string is
+//     string, and bytes is bytes. uint64 and fixed64 values above the maximum of a
+//     long wrap around, as Avro has no unsigned types.
+//   - Fields that have explicit presence, such as message fields, optional fields,
+//     and the fields of oneofs, are unions of null and their type, with a default
+//     of null. Avro has no equivalent of oneofs, so their fields are independent.
+//     Other fields have the default value of their type as default.
+//   - Repeated fields are arrays with a default of [], and map fields are maps with
+//     a default of {}. The keys of Avro maps are always strings, so maps with other
+//     key types are keyed by the string representation of their keys.
+//   - google.protobuf.Timestamp is a long with the logical type timestamp-micros,
+//     and wrappers such as google.protobuf.StringValue are unions of null and their
+//     wrapped type. Other well-known types are records.
+//
+// Avro schemas are converted to Protobuf files as follows:
+//
+//   - The top-level schema must be a record. Records are messages and enums are
+//     enums. The package is the namespace of the top-level record. Named types in
+//     the namespace of a record are nested in its message, and named types in other
+//     namespaces are declared by their names in the package.
+//   - Fields are numbered from 1 in the order that they are declared, and enum
+//     values are numbered from 0 in the order of their symbols. Appending fields
+//     and symbols keeps the numbers of the existing fields and values.
+//   - boolean is bool, int is int32, long is int64, float is float, double is
+//     double, string is string, and bytes and fixed are bytes.
+//   - Unions of null and one other type are optional fields, or message, repeated,
+//     or map fields if the other type is a record, array, or map. Unions of more
+//     than one other type are oneofs, with a field for each type that is named
+//     after the field and the type, such as value_string.
+//   - Arrays are repeated fields, and maps are map fields with string keys. Arrays
+//     and maps of arrays, maps, and unions of more than one other type cannot be
+//     converted, as Protobuf has no equivalent.
+//   - The logical types timestamp-millis and timestamp-micros are
+//     google.protobuf.Timestamp. Other logical types are their underlying types,
+//     such as int32 for date and bytes for decimal.
+package bufavro
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+// Schema is an Avro schema.
+//
+// A Schema is either a union, if it has branches, or a schema of the given type.
+// A Schema that only has a type is a primitive type, or a reference to a named type
+// that is declared elsewhere.
+type Schema struct {
+	// Type is a primitive type, "record", "enum", "array", "map", "fixed", or the
+	// name of a named type.
+	Type string `json:"type,omitempty"`
+	// Name, Namespace, and Doc are set for records, enums, and fixed.
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Doc       string `json:"doc,omitempty"`
+	// Fields are the fields of a record.
+	Fields []*Field `json:"fields,omitempty"`
+	// Symbols and Default are the symbols and the default symbol of an enum.
+	Symbols []string `json:"symbols,omitempty"`
+	Default string   `json:"default,omitempty"`
+	// Items is the schema of the items of an array.
+	Items *Schema `json:"items,omitempty"`
+	// Values is the schema of the values of a map.
+	Values *Schema `json:"values,omitempty"`
+	// Size is the size of a fixed.
+	Size        int    `json:"size,omitempty"`
+	LogicalType string `json:"logicalType,omitempty"`
+	Precision   int    `json:"precision,omitempty"`
+	Scale       int    `json:"scale,omitempty"`
+	// Branches are the schemas of a union.
+	Branches []*Schema `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if s.Branches != nil {
+		return json.Marshal(s.Branches)
+	}
+	if s.isTypeOnly() {
+		return json.Marshal(s.Type)
+	}
+	type schema Schema
+	if s.Type == "record" && len(s.Fields) == 0 {
+		// The fields of records are required, even if there are none.
+		return json.Marshal(
+			struct {
+				*schema
+				Fields []*Field `json:"fields"`
+			}{
+				schema: (*schema)(s),
+				Fields: []*Field{},
+			},
+		)
+	}
+	return json.Marshal((*schema)(s))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	switch data[0] {
+	case '"':
+		*s = Schema{}
+		return json.Unmarshal(data, &s.Type)
+	case '[':
+		*s = Schema{Branches: []*Schema{}}
+		return json.Unmarshal(data, &s.Branches)
+	default:
+		type schema Schema
+		*s = Schema{}
+		return json.Unmarshal(data, (*schema)(s))
+	}
+}
+
+// Field is a field of an Avro record.
+type Field struct {
+	Name string  `json:"name"`
+	Doc  string  `json:"doc,omitempty"`
+	Type *Schema `json:"type"`
+	// Default is the JSON of the default value, or empty if the field has no
+	// default.
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+// FromMessage converts the message with the given fully-qualified name to an Avro
+// schema.
+//
+// The message may be declared in any file of the image, including imports.
+func FromMessage(image bufimage.Image, typeName string) (*Schema, error) {
+	return fromMessage(image, typeName)
+}
+
+// ToProto converts the Avro schema of a record to the content of a Protobuf file.
+func ToProto(schema *Schema, options ...ToProtoOption) ([]byte, error) {
+	return toProto(schema, options...)
+}
+
+// ToProtoOption is an option for ToProto.
+type ToProtoOption func(*toProtoOptions)
+
+// ToProtoWithPackage sets the package of the Protobuf file.
+//
+// The default is the namespace of the record.
+func ToProtoWithPackage(packageName string) ToProtoOption {
+	return func(toProtoOptions *toProtoOptions) {
+		toProtoOptions.packageName = packageName
+	}
+}
+
+// Parse parses the JSON of an Avro schema.
+func Parse(data []byte) (*Schema, error) {
+	schema := &Schema{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Marshal marshals the Avro schema to indented JSON.
+func Marshal(schema *Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (s *Schema) isTypeOnly() bool {
+	return s.Name == "" &&
+		s.Namespace == "" &&
+		s.Doc == "" &&
+		s.Fields == nil &&
+		s.Symbols == nil &&
+		s.Default == "" &&
+		s.Items == nil &&
+		s.Values == nil &&
+		s.Size == 0 &&
+		s.LogicalType == "" &&
+		s.Precision == 0 &&
+		s.Scale == 0
+}
@@ -0,0 +1,263 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufavro
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+// An order.
+message Order {
+  string name = 1;
+  // The status of the order.
+  Status status = 2;
+  google.protobuf.Timestamp create_time = 3;
+  repeated Item items = 4;
+  map<string, int64> quantities = 5;
+  optional string note = 6;
+  google.protobuf.StringValue coupon = 7;
+  oneof payment {
+    string card = 8;
+    Order parent = 9;
+  }
+
+  message Item {
+    string sku = 1;
+    bytes data = 2;
+  }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OPEN = 1;
+}
+`
+
+func TestFromMessage(t *testing.T) {
+	t.Parallel()
+	schema, err := FromMessage(bufimagetesting.BuildImage(t, map[string]string{"acme/v1/order.proto": testProto}), "acme.v1.Order")
+	require.NoError(t, err)
+	data, err := Marshal(schema)
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{
+  "type": "record",
+  "name": "Order",
+  "namespace": "acme.v1",
+  "doc": "An order.",
+  "fields": [
+    {"name": "name", "type": "string", "default": ""},
+    {
+      "name": "status",
+      "doc": "The status of the order.",
+      "type": {
+        "type": "enum",
+        "name": "Status",
+        "namespace": "acme.v1",
+        "symbols": ["STATUS_UNSPECIFIED", "STATUS_OPEN"],
+        "default": "STATUS_UNSPECIFIED"
+      },
+      "default": "STATUS_UNSPECIFIED"
+    },
+    {"name": "create_time", "type": ["null", {"type": "long", "logicalType": "timestamp-micros"}], "default": null},
+    {
+      "name": "items",
+      "type": {
+        "type": "array",
+        "items": {
+          "type": "record",
+          "name": "Item",
+          "namespace": "acme.v1.Order",
+          "fields": [
+            {"name": "sku", "type": "string", "default": ""},
+            {"name": "data", "type": "bytes", "default": ""}
+          ]
+        }
+      },
+      "default": []
+    },
+    {"name": "quantities", "type": {"type": "map", "values": "long"}, "default": {}},
+    {"name": "note", "type": ["null", "string"], "default": null},
+    {"name": "coupon", "type": ["null", "string"], "default": null},
+    {"name": "card", "type": ["null", "string"], "default": null},
+    {"name": "parent", "type": ["null", "acme.v1.Order"], "default": null}
+  ]
+}`,
+		string(data),
+	)
+
+	_, err = FromMessage(bufimagetesting.BuildImage(t, map[string]string{"acme/v1/order.proto": testProto}), "acme.v1.Missing")
+	require.EqualError(t, err, `message "acme.v1.Missing" is not declared in the input`)
+}
+
+func TestToProto(t *testing.T) {
+	t.Parallel()
+	schema, err := Parse([]byte(`{
+  "type": "record",
+  "name": "Event",
+  "namespace": "acme.events",
+  "doc": "An event.",
+  "fields": [
+    {"name": "id", "type": {"type": "string", "logicalType": "uuid"}},
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "day", "type": ["null", {"type": "int", "logicalType": "date"}], "default": null},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["KIND_UNKNOWN", "KIND_CLICK"]}},
+    {"name": "value", "doc": "The value of the event.", "type": ["null", "string", "long", "Kind"]},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "attributes", "type": {"type": "map", "values": ["null", "double"]}},
+    {"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 16}},
+    {"name": "checksum", "type": "Hash"},
+    {"name": "source", "type": ["null", {"type": "record", "name": "Source", "namespace": "acme.events.Event", "fields": []}]},
+    {"name": "origin", "type": {"type": "record", "name": "Origin", "namespace": "acme.common", "fields": [{"name": "host", "type": "string"}]}}
+  ]
+}`))
+	require.NoError(t, err)
+	data, err := ToProto(schema)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`syntax = "proto3";
+
+package acme.events;
+
+import "google/protobuf/timestamp.proto";
+
+// An event.
+message Event {
+  string id = 1;
+  google.protobuf.Timestamp time = 2;
+  optional int32 day = 3;
+  Kind kind = 4;
+  // The value of the event.
+  oneof value {
+    string value_string = 5;
+    int64 value_long = 6;
+    Kind value_kind = 7;
+  }
+  repeated string tags = 8;
+  map<string, double> attributes = 9;
+  bytes hash = 10;
+  bytes checksum = 11;
+  Event.Source source = 12;
+  Origin origin = 13;
+
+  message Source {}
+}
+
+enum Kind {
+  KIND_UNKNOWN = 0;
+  KIND_CLICK = 1;
+}
+
+message Origin {
+  string host = 1;
+}
+`,
+		string(data),
+	)
+
+	data, err = ToProto(schema, ToProtoWithPackage("acme.events.v1"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "package acme.events.v1;\n")
+	assert.Contains(t, string(data), "  Source source = 12;\n")
+}
+
+func TestToProtoError(t *testing.T) {
+	t.Parallel()
+	testToProtoError(t, `"string"`, "the schema must be a record, not string")
+	testToProtoError(
+		t,
+		`{"type": "record", "name": "A", "fields": [{"name": "a", "type": {"type": "array", "items": {"type": "array", "items": "int"}}}]}`,
+		"field a of A: arrays of array cannot be converted to Protobuf",
+	)
+	testToProtoError(
+		t,
+		`{"type": "record", "name": "A", "fields": [{"name": "a", "type": ["int", {"type": "map", "values": "int"}]}]}`,
+		"field a of A: unions of map cannot be converted to Protobuf",
+	)
+	testToProtoError(
+		t,
+		`{"type": "record", "name": "A", "fields": [{"name": "a", "type": "B"}]}`,
+		"field a of A: type B is not declared",
+	)
+	testToProtoError(
+		t,
+		`{"type": "record", "name": "A", "namespace": "x", "fields": [{"name": "a", "type": {"type": "record", "name": "A", "namespace": "y", "fields": []}}]}`,
+		"x.A and y.A both have the Protobuf name A",
+	)
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+	schema, err := FromMessage(bufimagetesting.BuildImage(t, map[string]string{"acme/v1/order.proto": testProto}), "acme.v1.Order")
+	require.NoError(t, err)
+	data, err := ToProto(schema)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/timestamp.proto";
+
+// An order.
+message Order {
+  string name = 1;
+  // The status of the order.
+  Status status = 2;
+  google.protobuf.Timestamp create_time = 3;
+  repeated Order.Item items = 4;
+  map<string, int64> quantities = 5;
+  optional string note = 6;
+  optional string coupon = 7;
+  optional string card = 8;
+  Order parent = 9;
+
+  message Item {
+    string sku = 1;
+    bytes data = 2;
+  }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OPEN = 1;
+}
+`,
+		string(data),
+	)
+	// The converted file must compile.
+	bufimagetesting.BuildImage(t, map[string]string{"acme/v1/order.proto": string(data)})
+}
+
+func testToProtoError(t *testing.T, schemaJSON string, expectedErrorMessage string) {
+	schema, err := Parse([]byte(schemaJSON))
+	require.NoError(t, err)
+	_, err = ToProto(schema)
+	assert.EqualError(t, err, expectedErrorMessage)
+}
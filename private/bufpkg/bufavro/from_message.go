@@ -0,0 +1,284 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	nullDefault  = json.RawMessage("null")
+	arrayDefault = json.RawMessage("[]")
+	mapDefault   = json.RawMessage("{}")
+)
+
+func fromMessage(image bufimage.Image, typeName string) (*Schema, error) {
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	typeName = strings.TrimPrefix(typeName, ".")
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return nil, fmt.Errorf("message %q is not declared in the input", typeName)
+	}
+	messageDescriptor, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a message", typeName)
+	}
+	converter := &fromMessageConverter{
+		declared: make(map[protoreflect.FullName]struct{}),
+	}
+	return converter.getMessageSchema(messageDescriptor)
+}
+
+type fromMessageConverter struct {
+	// declared are the named types that have already been declared, and are
+	// referenced by name from then on.
+	declared map[protoreflect.FullName]struct{}
+}
+
+func (c *fromMessageConverter) getMessageSchema(messageDescriptor protoreflect.MessageDescriptor) (*Schema, error) {
+	if schema, ok := c.getDeclaredSchema(messageDescriptor); ok {
+		return schema, nil
+	}
+	schema := &Schema{
+		Type:      "record",
+		Name:      string(messageDescriptor.Name()),
+		Namespace: getNamespace(messageDescriptor),
+		Doc:       getDoc(messageDescriptor),
+	}
+	fieldDescriptors := messageDescriptor.Fields()
+	for i := 0; i < fieldDescriptors.Len(); i++ {
+		field, err := c.getField(fieldDescriptors.Get(i))
+		if err != nil {
+			return nil, err
+		}
+		schema.Fields = append(schema.Fields, field)
+	}
+	return schema, nil
+}
+
+func (c *fromMessageConverter) getEnumSchema(enumDescriptor protoreflect.EnumDescriptor) *Schema {
+	if schema, ok := c.getDeclaredSchema(enumDescriptor); ok {
+		return schema
+	}
+	enumValueDescriptors := enumDescriptor.Values()
+	symbols := make([]string, enumValueDescriptors.Len())
+	for i := 0; i < enumValueDescriptors.Len(); i++ {
+		symbols[i] = string(enumValueDescriptors.Get(i).Name())
+	}
+	return &Schema{
+		Type:      "enum",
+		Name:      string(enumDescriptor.Name()),
+		Namespace: getNamespace(enumDescriptor),
+		Doc:       getDoc(enumDescriptor),
+		Symbols:   symbols,
+		// Readers use the default for the symbols that they do not know, like
+		// Protobuf uses the zero value for unknown values of closed enums.
+		Default: symbols[0],
+	}
+}
+
+// getDeclaredSchema returns a reference to the named type if it was already
+// declared, and otherwise marks it as declared.
+//
+// Types are marked as declared before their fields are converted, so that
+// recursive messages refer to themselves by name.
+func (c *fromMessageConverter) getDeclaredSchema(descriptor protoreflect.Descriptor) (*Schema, bool) {
+	if _, ok := c.declared[descriptor.FullName()]; ok {
+		return &Schema{Type: string(descriptor.FullName())}, true
+	}
+	c.declared[descriptor.FullName()] = struct{}{}
+	return nil, false
+}
+
+func (c *fromMessageConverter) getField(fieldDescriptor protoreflect.FieldDescriptor) (*Field, error) {
+	field := &Field{
+		Name: string(fieldDescriptor.Name()),
+		Doc:  getDoc(fieldDescriptor),
+	}
+	switch {
+	case fieldDescriptor.IsMap():
+		values, err := c.getSingularSchema(fieldDescriptor.MapValue())
+		if err != nil {
+			return nil, err
+		}
+		field.Type = &Schema{
+			Type:   "map",
+			Values: values,
+		}
+		field.Default = mapDefault
+	case fieldDescriptor.IsList():
+		items, err := c.getSingularSchema(fieldDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		field.Type = &Schema{
+			Type:  "array",
+			Items: items,
+		}
+		field.Default = arrayDefault
+	default:
+		schema, err := c.getSingularSchema(fieldDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case fieldDescriptor.HasPresence() && fieldDescriptor.Cardinality() != protoreflect.Required:
+			field.Type = newNullableSchema(schema)
+			field.Default = nullDefault
+		case fieldDescriptor.Kind() == protoreflect.MessageKind || fieldDescriptor.Kind() == protoreflect.GroupKind:
+			// Required message fields have no default.
+			field.Type = schema
+		default:
+			field.Type = schema
+			if fieldDescriptor.Cardinality() != protoreflect.Required {
+				defaultValue, err := getDefault(fieldDescriptor)
+				if err != nil {
+					return nil, err
+				}
+				field.Default = defaultValue
+			}
+		}
+	}
+	return field, nil
+}
+
+func (c *fromMessageConverter) getSingularSchema(fieldDescriptor protoreflect.FieldDescriptor) (*Schema, error) {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.EnumKind:
+		return c.getEnumSchema(fieldDescriptor.Enum()), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if schema, ok := getWellKnownTypeSchema(fieldDescriptor.Message()); ok {
+			return schema, nil
+		}
+		return c.getMessageSchema(fieldDescriptor.Message())
+	default:
+		return getScalarSchema(fieldDescriptor.Kind()), nil
+	}
+}
+
+func getScalarSchema(kind protoreflect.Kind) *Schema {
+	switch kind {
+	case protoreflect.BoolKind:
+		return &Schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Schema{Type: "int"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Schema{Type: "long"}
+	case protoreflect.FloatKind:
+		return &Schema{Type: "float"}
+	case protoreflect.DoubleKind:
+		return &Schema{Type: "double"}
+	case protoreflect.StringKind:
+		return &Schema{Type: "string"}
+	default:
+		return &Schema{Type: "bytes"}
+	}
+}
+
+// getWellKnownTypeSchema returns the schema of the well-known type, or false if
+// the message is not a well-known type that has an equivalent in Avro.
+//
+// Wrappers are their wrapped type, and are nullable as all message fields are.
+func getWellKnownTypeSchema(messageDescriptor protoreflect.MessageDescriptor) (*Schema, bool) {
+	switch messageDescriptor.FullName() {
+	case "google.protobuf.Timestamp":
+		return &Schema{Type: "long", LogicalType: "timestamp-micros"}, true
+	case "google.protobuf.BoolValue":
+		return getScalarSchema(protoreflect.BoolKind), true
+	case "google.protobuf.Int32Value":
+		return getScalarSchema(protoreflect.Int32Kind), true
+	case "google.protobuf.UInt32Value":
+		return getScalarSchema(protoreflect.Uint32Kind), true
+	case "google.protobuf.Int64Value":
+		return getScalarSchema(protoreflect.Int64Kind), true
+	case "google.protobuf.UInt64Value":
+		return getScalarSchema(protoreflect.Uint64Kind), true
+	case "google.protobuf.FloatValue":
+		return getScalarSchema(protoreflect.FloatKind), true
+	case "google.protobuf.DoubleValue":
+		return getScalarSchema(protoreflect.DoubleKind), true
+	case "google.protobuf.StringValue":
+		return getScalarSchema(protoreflect.StringKind), true
+	case "google.protobuf.BytesValue":
+		return getScalarSchema(protoreflect.BytesKind), true
+	default:
+		return nil, false
+	}
+}
+
+// getDefault returns the JSON of the default value of the field in Avro.
+func getDefault(fieldDescriptor protoreflect.FieldDescriptor) (json.RawMessage, error) {
+	var value interface{}
+	switch fieldDescriptor.Kind() {
+	case protoreflect.EnumKind:
+		enumValueDescriptor := fieldDescriptor.DefaultEnumValue()
+		if enumValueDescriptor == nil {
+			// Fields without an explicit default have the first value as default.
+			enumValueDescriptor = fieldDescriptor.Enum().Values().Get(0)
+		}
+		value = string(enumValueDescriptor.Name())
+	case protoreflect.BytesKind:
+		// Defaults of bytes are strings of the code points 0-255 in Avro.
+		bytesValue := fieldDescriptor.Default().Bytes()
+		runes := make([]rune, len(bytesValue))
+		for i, b := range bytesValue {
+			runes[i] = rune(b)
+		}
+		value = string(runes)
+	default:
+		value = fieldDescriptor.Default().Interface()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert the default of %s: %w", fieldDescriptor.FullName(), err)
+	}
+	return data, nil
+}
+
+func newNullableSchema(schema *Schema) *Schema {
+	return &Schema{
+		Branches: []*Schema{
+			{Type: "null"},
+			schema,
+		},
+	}
+}
+
+// getNamespace returns the full name of the parent of the descriptor, which is
+// either its package or the message that it is nested in.
+func getNamespace(descriptor protoreflect.Descriptor) string {
+	return string(descriptor.FullName().Parent())
+}
+
+// getDoc returns the leading comments of the descriptor.
+func getDoc(descriptor protoreflect.Descriptor) string {
+	comments := descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor).LeadingComments
+	lines := strings.Split(strings.TrimSpace(comments), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
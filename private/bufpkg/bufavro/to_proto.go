@@ -0,0 +1,460 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufavro
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+)
+
+const timestampImport = "google/protobuf/timestamp.proto"
+
+func toProto(schema *Schema, options ...ToProtoOption) ([]byte, error) {
+	toProtoOptions := newToProtoOptions()
+	for _, option := range options {
+		option(toProtoOptions)
+	}
+	if schema.Branches != nil || schema.Type != "record" {
+		return nil, fmt.Errorf("the schema must be a record, not %s", getKindName(schema))
+	}
+	packageName := toProtoOptions.packageName
+	if packageName == "" {
+		packageName = getParentName(getFullName(schema.Name, schema.Namespace))
+	}
+	converter := &toProtoConverter{
+		packageName:    packageName,
+		fullNameToType: make(map[string]*protoType),
+		pathToType:     make(map[string]*protoType),
+		imports:        make(map[string]struct{}),
+	}
+	if _, err := converter.declare(schema, ""); err != nil {
+		return nil, err
+	}
+	return converter.print(), nil
+}
+
+type toProtoConverter struct {
+	packageName    string
+	fullNameToType map[string]*protoType
+	pathToType     map[string]*protoType
+	imports        map[string]struct{}
+	types          []*protoType
+}
+
+// protoType is a message or enum, or a fixed, which is bytes in Protobuf and is
+// not declared.
+type protoType struct {
+	fullName string
+	// path is the name of the type relative to the package, such as Order.Item
+	// for a message Item that is nested in the message Order.
+	path     string
+	name     string
+	doc      string
+	kind     string
+	symbols  []string
+	elements []*protoElement
+	nested   []*protoType
+	number   int
+}
+
+// protoElement is either a field or a oneof of a message.
+type protoElement struct {
+	field *protoField
+	oneof *protoOneof
+}
+
+type protoField struct {
+	doc      string
+	label    string
+	typeName string
+	name     string
+	number   int
+}
+
+type protoOneof struct {
+	doc    string
+	name   string
+	fields []*protoField
+}
+
+// declare declares the record, enum, or fixed.
+func (c *toProtoConverter) declare(schema *Schema, namespace string) (*protoType, error) {
+	if schema.Name == "" {
+		return nil, fmt.Errorf("a %s must have a name", schema.Type)
+	}
+	if schema.Namespace != "" {
+		namespace = schema.Namespace
+	}
+	fullName := getFullName(schema.Name, namespace)
+	if _, ok := c.fullNameToType[fullName]; ok {
+		return nil, fmt.Errorf("%s is declared more than once", fullName)
+	}
+	declaredType := &protoType{
+		fullName: fullName,
+		name:     getShortName(fullName),
+		doc:      schema.Doc,
+		kind:     schema.Type,
+		symbols:  schema.Symbols,
+	}
+	c.fullNameToType[fullName] = declaredType
+	if declaredType.kind == "fixed" {
+		return declaredType, nil
+	}
+	if err := c.place(declaredType); err != nil {
+		return nil, err
+	}
+	if declaredType.kind == "enum" {
+		if len(declaredType.symbols) == 0 {
+			return nil, fmt.Errorf("enum %s must have symbols", fullName)
+		}
+		return declaredType, nil
+	}
+	// Named types that are declared in the fields inherit the namespace of the
+	// record, not its full name.
+	namespace = getParentName(fullName)
+	for _, field := range schema.Fields {
+		if err := c.addField(declaredType, field, namespace); err != nil {
+			return nil, err
+		}
+	}
+	return declaredType, nil
+}
+
+// place places the message or enum in the file, nested in the message that
+// its namespace refers to, if any.
+func (c *toProtoConverter) place(declaredType *protoType) error {
+	relativeName := declaredType.fullName
+	if c.packageName != "" {
+		if strings.HasPrefix(declaredType.fullName, c.packageName+".") {
+			relativeName = strings.TrimPrefix(declaredType.fullName, c.packageName+".")
+		} else {
+			relativeName = declaredType.name
+		}
+	}
+	path := declaredType.name
+	var parent *protoType
+	if candidate, ok := c.pathToType[getParentName(relativeName)]; ok && candidate.kind == "record" {
+		parent = candidate
+		path = relativeName
+	}
+	if existing, ok := c.pathToType[path]; ok {
+		return fmt.Errorf("%s and %s both have the Protobuf name %s", existing.fullName, declaredType.fullName, path)
+	}
+	declaredType.path = path
+	c.pathToType[path] = declaredType
+	if parent != nil {
+		parent.nested = append(parent.nested, declaredType)
+	} else {
+		c.types = append(c.types, declaredType)
+	}
+	return nil
+}
+
+func (c *toProtoConverter) addField(message *protoType, field *Field, namespace string) error {
+	schema := field.Type
+	if schema == nil {
+		return fmt.Errorf("field %s of %s has no type", field.Name, message.fullName)
+	}
+	var nullable bool
+	if schema.Branches != nil {
+		var nonNullSchemas []*Schema
+		for _, branch := range schema.Branches {
+			if branch.Branches == nil && branch.Type == "null" {
+				nullable = true
+				continue
+			}
+			nonNullSchemas = append(nonNullSchemas, branch)
+		}
+		switch len(nonNullSchemas) {
+		case 0:
+			return fmt.Errorf("field %s of %s must have a type other than null", field.Name, message.fullName)
+		case 1:
+			schema = nonNullSchemas[0]
+		default:
+			return c.addOneof(message, field, nonNullSchemas, namespace)
+		}
+	}
+	messageField := &protoField{
+		doc:  field.Doc,
+		name: field.Name,
+	}
+	switch schema.Type {
+	case "array", "map":
+		var elementSchema *Schema
+		if schema.Type == "array" {
+			elementSchema = schema.Items
+		} else {
+			elementSchema = schema.Values
+		}
+		elementTypeName, err := c.getElementTypeName(message, field, schema.Type, elementSchema, namespace)
+		if err != nil {
+			return err
+		}
+		if schema.Type == "array" {
+			messageField.label = "repeated"
+			messageField.typeName = elementTypeName
+		} else {
+			messageField.typeName = "map<string, " + elementTypeName + ">"
+		}
+	default:
+		typeName, isMessage, err := c.getTypeName(message, field, schema, namespace)
+		if err != nil {
+			return err
+		}
+		if nullable && !isMessage {
+			messageField.label = "optional"
+		}
+		messageField.typeName = typeName
+	}
+	message.number++
+	messageField.number = message.number
+	message.elements = append(message.elements, &protoElement{field: messageField})
+	return nil
+}
+
+// addOneof adds a oneof for the field with a union of more than one type other
+// than null.
+func (c *toProtoConverter) addOneof(message *protoType, field *Field, schemas []*Schema, namespace string) error {
+	oneof := &protoOneof{
+		doc:  field.Doc,
+		name: field.Name,
+	}
+	names := make(map[string]struct{}, len(schemas))
+	for _, schema := range schemas {
+		if schema.Branches != nil || schema.Type == "array" || schema.Type == "map" {
+			return fmt.Errorf("field %s of %s: unions of %s cannot be converted to Protobuf", field.Name, message.fullName, getKindName(schema))
+		}
+		typeName, _, err := c.getTypeName(message, field, schema, namespace)
+		if err != nil {
+			return err
+		}
+		suffix := schema.Type
+		if schema.Name != "" {
+			suffix = stringutil.ToLowerSnakeCase(schema.Name)
+		} else if _, ok := c.fullNameToType[getFullName(schema.Type, namespace)]; ok {
+			suffix = stringutil.ToLowerSnakeCase(getShortName(schema.Type))
+		}
+		name := field.Name + "_" + suffix
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("field %s of %s has more than one %s in its union", field.Name, message.fullName, suffix)
+		}
+		names[name] = struct{}{}
+		message.number++
+		oneof.fields = append(
+			oneof.fields,
+			&protoField{
+				typeName: typeName,
+				name:     name,
+				number:   message.number,
+			},
+		)
+	}
+	message.elements = append(message.elements, &protoElement{oneof: oneof})
+	return nil
+}
+
+// getElementTypeName returns the type name of the items of an array, or the values
+// of a map.
+//
+// Nulls are dropped from unions, as repeated fields and maps cannot have nulls.
+func (c *toProtoConverter) getElementTypeName(message *protoType, field *Field, kind string, schema *Schema, namespace string) (string, error) {
+	if schema == nil {
+		return "", fmt.Errorf("field %s of %s is a %s without a type", field.Name, message.fullName, kind)
+	}
+	if schema.Branches != nil {
+		var nonNullSchemas []*Schema
+		for _, branch := range schema.Branches {
+			if branch.Branches == nil && branch.Type == "null" {
+				continue
+			}
+			nonNullSchemas = append(nonNullSchemas, branch)
+		}
+		if len(nonNullSchemas) == 1 {
+			schema = nonNullSchemas[0]
+		}
+	}
+	if schema.Branches != nil || schema.Type == "array" || schema.Type == "map" {
+		return "", fmt.Errorf("field %s of %s: %ss of %s cannot be converted to Protobuf", field.Name, message.fullName, kind, getKindName(schema))
+	}
+	typeName, _, err := c.getTypeName(message, field, schema, namespace)
+	return typeName, err
+}
+
+// getTypeName returns the Protobuf type name of the schema, which is not a union,
+// array, or map, and whether it is a message.
+func (c *toProtoConverter) getTypeName(message *protoType, field *Field, schema *Schema, namespace string) (string, bool, error) {
+	switch schema.Type {
+	case "null":
+		return "", false, fmt.Errorf("field %s of %s: null is only supported in unions", field.Name, message.fullName)
+	case "boolean":
+		return "bool", false, nil
+	case "int":
+		return "int32", false, nil
+	case "long":
+		switch schema.LogicalType {
+		case "timestamp-millis", "timestamp-micros":
+			c.imports[timestampImport] = struct{}{}
+			return "google.protobuf.Timestamp", true, nil
+		default:
+			return "int64", false, nil
+		}
+	case "float", "double", "string", "bytes":
+		return schema.Type, false, nil
+	case "record", "enum", "fixed":
+		declaredType, err := c.declare(schema, namespace)
+		if err != nil {
+			return "", false, err
+		}
+		return declaredType.getTypeName()
+	default:
+		declaredType, ok := c.fullNameToType[getFullName(schema.Type, namespace)]
+		if !ok {
+			declaredType, ok = c.fullNameToType[schema.Type]
+		}
+		if !ok {
+			return "", false, fmt.Errorf("field %s of %s: type %s is not declared", field.Name, message.fullName, schema.Type)
+		}
+		return declaredType.getTypeName()
+	}
+}
+
+func (t *protoType) getTypeName() (string, bool, error) {
+	switch t.kind {
+	case "fixed":
+		return "bytes", false, nil
+	case "record":
+		return t.path, true, nil
+	default:
+		return t.path, false, nil
+	}
+}
+
+func (c *toProtoConverter) print() []byte {
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString("syntax = \"proto3\";\n")
+	if c.packageName != "" {
+		fmt.Fprintf(buffer, "\npackage %s;\n", c.packageName)
+	}
+	if len(c.imports) > 0 {
+		buffer.WriteString("\n")
+		// There is at most one import.
+		for importPath := range c.imports {
+			fmt.Fprintf(buffer, "import %q;\n", importPath)
+		}
+	}
+	for _, declaredType := range c.types {
+		buffer.WriteString("\n")
+		printType(buffer, declaredType, "")
+	}
+	return buffer.Bytes()
+}
+
+func printType(buffer *bytes.Buffer, declaredType *protoType, indent string) {
+	printComment(buffer, declaredType.doc, indent)
+	if declaredType.kind == "enum" {
+		fmt.Fprintf(buffer, "%senum %s {\n", indent, declaredType.name)
+		for i, symbol := range declaredType.symbols {
+			fmt.Fprintf(buffer, "%s  %s = %d;\n", indent, symbol, i)
+		}
+		fmt.Fprintf(buffer, "%s}\n", indent)
+		return
+	}
+	if len(declaredType.elements) == 0 && len(declaredType.nested) == 0 {
+		fmt.Fprintf(buffer, "%smessage %s {}\n", indent, declaredType.name)
+		return
+	}
+	fmt.Fprintf(buffer, "%smessage %s {\n", indent, declaredType.name)
+	for _, element := range declaredType.elements {
+		if element.field != nil {
+			printField(buffer, element.field, indent+"  ")
+			continue
+		}
+		printComment(buffer, element.oneof.doc, indent+"  ")
+		fmt.Fprintf(buffer, "%s  oneof %s {\n", indent, element.oneof.name)
+		for _, field := range element.oneof.fields {
+			printField(buffer, field, indent+"    ")
+		}
+		fmt.Fprintf(buffer, "%s  }\n", indent)
+	}
+	for i, nested := range declaredType.nested {
+		if i > 0 || len(declaredType.elements) > 0 {
+			buffer.WriteString("\n")
+		}
+		printType(buffer, nested, indent+"  ")
+	}
+	fmt.Fprintf(buffer, "%s}\n", indent)
+}
+
+func printField(buffer *bytes.Buffer, field *protoField, indent string) {
+	printComment(buffer, field.doc, indent)
+	buffer.WriteString(indent)
+	if field.label != "" {
+		buffer.WriteString(field.label + " ")
+	}
+	buffer.WriteString(field.typeName + " " + field.name + " = " + strconv.Itoa(field.number) + ";\n")
+}
+
+func printComment(buffer *bytes.Buffer, doc string, indent string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			buffer.WriteString(indent + "//\n")
+		} else {
+			buffer.WriteString(indent + "// " + line + "\n")
+		}
+	}
+}
+
+// getKindName returns the name of the kind of the schema for error messages.
+func getKindName(schema *Schema) string {
+	if schema.Branches != nil {
+		return "union"
+	}
+	return schema.Type
+}
+
+// getFullName returns the full name of the named type with the given name, in the
+// given namespace if the name is not already a full name.
+func getFullName(name string, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func getParentName(fullName string) string {
+	if index := strings.LastIndexByte(fullName, '.'); index >= 0 {
+		return fullName[:index]
+	}
+	return ""
+}
+
+func getShortName(fullName string) string {
+	return fullName[strings.LastIndexByte(fullName, '.')+1:]
+}
+
+type toProtoOptions struct {
+	packageName string
+}
+
+func newToProtoOptions() *toProtoOptions {
+	return &toProtoOptions{}
+}
@@ -15,6 +15,8 @@
 package bufbreakingcheck
 
 import (
+	"context"
+
 	"fmt"
 	"sort"
 	"strings"
@@ -109,8 +111,8 @@ func newCorpus(
 
 func newFilesCheckFunc(
 	f func(addFunc, *corpus) error,
-) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-	return func(id string, ignoreFunc internal.IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 		helper := internal.NewHelper(id, ignoreFunc)
 		if err := f(helper.AddFileAnnotationWithExtraIgnoreDescriptorsf, newCorpus(previousFiles, files)); err != nil {
 			return nil, err
@@ -119,34 +121,49 @@ func newFilesCheckFunc(
 	}
 }
 
+// newFilePairCheckFunc returns a check function that checks each pair of a
+// previous file and a file with the same path independently, so the pairs are
+// checked concurrently.
 func newFilePairCheckFunc(
 	f func(addFunc, *corpus, protosource.File, protosource.File) error,
-) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-	return newFilesCheckFunc(
-		func(add addFunc, corpus *corpus) error {
-			previousFilePathToFile, err := protosource.FilePathToFile(corpus.previousFiles...)
-			if err != nil {
-				return err
-			}
-			filePathToFile, err := protosource.FilePathToFile(corpus.files...)
-			if err != nil {
-				return err
-			}
-			for previousFilePath, previousFile := range previousFilePathToFile {
-				if file, ok := filePathToFile[previousFilePath]; ok {
-					if err := f(add, corpus, previousFile, file); err != nil {
-						return err
-					}
-				}
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+		corpus := newCorpus(previousFiles, files)
+		previousFilePathToFile, err := protosource.FilePathToFile(corpus.previousFiles...)
+		if err != nil {
+			return nil, err
+		}
+		filePathToFile, err := protosource.FilePathToFile(corpus.files...)
+		if err != nil {
+			return nil, err
+		}
+		filePaths := make([]string, 0, len(previousFilePathToFile))
+		for previousFilePath := range previousFilePathToFile {
+			if _, ok := filePathToFile[previousFilePath]; ok {
+				filePaths = append(filePaths, previousFilePath)
 			}
-			return nil
-		},
-	)
+		}
+		sort.Strings(filePaths)
+		return internal.CheckParallel(
+			ctx,
+			id,
+			ignoreFunc,
+			len(filePaths),
+			func(helper *internal.Helper, i int) error {
+				return f(
+					helper.AddFileAnnotationWithExtraIgnoreDescriptorsf,
+					corpus,
+					previousFilePathToFile[filePaths[i]],
+					filePathToFile[filePaths[i]],
+				)
+			},
+		)
+	}
 }
 
 func newEnumPairCheckFunc(
 	f func(addFunc, *corpus, protosource.Enum, protosource.Enum) error,
-) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFilesCheckFunc(
 		func(add addFunc, corpus *corpus) error {
 			previousFullNameToEnum, err := protosource.FullNameToEnum(corpus.previousFiles...)
@@ -173,7 +190,7 @@ func newEnumPairCheckFunc(
 // map is from name to EnumValue for the given number
 func newEnumValuePairCheckFunc(
 	f func(addFunc, *corpus, map[string]protosource.EnumValue, map[string]protosource.EnumValue) error,
-) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newEnumPairCheckFunc(
 		func(add addFunc, corpus *corpus, previousEnum protosource.Enum, enum protosource.Enum) error {
 			previousNumberToNameToEnumValue, err := protosource.NumberToNameToEnumValue(previousEnum)
@@ -198,7 +215,7 @@ func newEnumValuePairCheckFunc(
 
 func newMessagePairCheckFunc(
 	f func(addFunc, *corpus, protosource.Message, protosource.Message) error,
-) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFilesCheckFunc(
 		func(add addFunc, corpus *corpus) error {
 			previousFullNameToMessage, err := protosource.FullNameToMessage(corpus.previousFiles...)
@@ -223,7 +240,7 @@ func newMessagePairCheckFunc(
 
 func newFieldPairCheckFunc(
 	f func(addFunc, *corpus, protosource.Field, protosource.Field) error,
-) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newMessagePairCheckFunc(
 		func(add addFunc, corpus *corpus, previousMessage protosource.Message, message protosource.Message) error {
 			previousNumberToField, err := protosource.NumberToMessageField(previousMessage)
@@ -248,7 +265,7 @@ func newFieldPairCheckFunc(
 
 func newServicePairCheckFunc(
 	f func(addFunc, *corpus, protosource.Service, protosource.Service) error,
-) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFilesCheckFunc(
 		func(add addFunc, corpus *corpus) error {
 			previousFullNameToService, err := protosource.FullNameToService(corpus.previousFiles...)
@@ -273,7 +290,7 @@ func newServicePairCheckFunc(
 
 func newMethodPairCheckFunc(
 	f func(addFunc, *corpus, protosource.Method, protosource.Method) error,
-) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newServicePairCheckFunc(
 		func(add addFunc, corpus *corpus, previousService protosource.Service, service protosource.Service) error {
 			previousNameToMethod, err := protosource.NameToMethod(previousService)
@@ -16,6 +16,8 @@
 package buflintbuild
 
 import (
+	"context"
+
 	"errors"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
@@ -116,8 +118,8 @@ var (
 			if configBuilder.EnumZeroValueSuffix == "" {
 				return nil, errors.New("enum_zero_value_suffix is empty")
 			}
-			return internal.CheckFunc(func(id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-				return buflintcheck.CheckEnumZeroValueSuffix(id, ignoreFunc, files, configBuilder.EnumZeroValueSuffix)
+			return internal.CheckFunc(func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return buflintcheck.CheckEnumZeroValueSuffix(ctx, id, ignoreFunc, files, configBuilder.EnumZeroValueSuffix)
 			}), nil
 		},
 	)
@@ -278,8 +280,9 @@ var (
 			return "RPC request and response types are only used in one RPC (configurable)", nil
 		},
 		func(configBuilder internal.ConfigBuilder) (internal.CheckFunc, error) {
-			return internal.CheckFunc(func(id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+			return internal.CheckFunc(func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 				return buflintcheck.CheckRPCRequestResponseUnique(
+					ctx,
 					id,
 					ignoreFunc,
 					files,
@@ -297,8 +300,9 @@ var (
 			return "RPC request type names are RPCNameRequest or ServiceNameRPCNameRequest (configurable)", nil
 		},
 		func(configBuilder internal.ConfigBuilder) (internal.CheckFunc, error) {
-			return internal.CheckFunc(func(id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+			return internal.CheckFunc(func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 				return buflintcheck.CheckRPCRequestStandardName(
+					ctx,
 					id,
 					ignoreFunc,
 					files,
@@ -314,8 +318,9 @@ var (
 			return "RPC response type names are RPCNameResponse or ServiceNameRPCNameResponse (configurable)", nil
 		},
 		func(configBuilder internal.ConfigBuilder) (internal.CheckFunc, error) {
-			return internal.CheckFunc(func(id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+			return internal.CheckFunc(func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 				return buflintcheck.CheckRPCResponseStandardName(
+					ctx,
 					id,
 					ignoreFunc,
 					files,
@@ -343,8 +348,8 @@ var (
 			if configBuilder.ServiceSuffix == "" {
 				return nil, errors.New("service_suffix is empty")
 			}
-			return internal.CheckFunc(func(id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-				return buflintcheck.CheckServiceSuffix(id, ignoreFunc, files, configBuilder.ServiceSuffix)
+			return internal.CheckFunc(func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return buflintcheck.CheckServiceSuffix(ctx, id, ignoreFunc, files, configBuilder.ServiceSuffix)
 			}), nil
 		},
 	)
@@ -357,9 +362,9 @@ var (
 )
 
 func newAdapter(
-	f func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error),
-) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-	return func(id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-		return f(id, ignoreFunc, files)
+	f func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error),
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+		return f(ctx, id, ignoreFunc, files)
 	}
 }
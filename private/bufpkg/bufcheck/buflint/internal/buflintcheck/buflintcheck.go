@@ -18,6 +18,7 @@
 package buflintcheck
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -182,9 +183,9 @@ func checkEnumFirstValueZero(add addFunc, enum protosource.Enum) error {
 }
 
 // CheckEnumValuePrefix is a check function.
-var CheckEnumValuePrefix = newEnumValueCheckFunc(checkEnumValuePrefix)
+var CheckEnumValuePrefix = newEnumValueWithEditsCheckFunc(checkEnumValuePrefix)
 
-func checkEnumValuePrefix(add addFunc, enumValue protosource.EnumValue) error {
+func checkEnumValuePrefix(add addWithEditsFunc, enumValue protosource.EnumValue) error {
 	name := enumValue.Name()
 	expectedPrefix := fieldToUpperSnakeCase(enumValue.Enum().Name()) + "_"
 	if !strings.HasPrefix(name, expectedPrefix) {
@@ -197,6 +198,7 @@ func checkEnumValuePrefix(add addFunc, enumValue protosource.EnumValue) error {
 			[]protosource.Location{
 				enumValue.Enum().Location(),
 			},
+			newRenameEdits(enumValue.NameLocation(), expectedPrefix+name),
 			"Enum value name %q should be prefixed with %q.",
 			name,
 			expectedPrefix,
@@ -206,9 +208,9 @@ func checkEnumValuePrefix(add addFunc, enumValue protosource.EnumValue) error {
 }
 
 // CheckEnumValueUpperSnakeCase is a check function.
-var CheckEnumValueUpperSnakeCase = newEnumValueCheckFunc(checkEnumValueUpperSnakeCase)
+var CheckEnumValueUpperSnakeCase = newEnumValueWithEditsCheckFunc(checkEnumValueUpperSnakeCase)
 
-func checkEnumValueUpperSnakeCase(add addFunc, enumValue protosource.EnumValue) error {
+func checkEnumValueUpperSnakeCase(add addWithEditsFunc, enumValue protosource.EnumValue) error {
 	name := enumValue.Name()
 	expectedName := fieldToUpperSnakeCase(name)
 	if name != expectedName {
@@ -220,6 +222,7 @@ func checkEnumValueUpperSnakeCase(add addFunc, enumValue protosource.EnumValue)
 			[]protosource.Location{
 				enumValue.Enum().Location(),
 			},
+			newRenameEdits(enumValue.NameLocation(), expectedName),
 			"Enum value name %q should be UPPER_SNAKE_CASE, such as %q.",
 			name,
 			expectedName,
@@ -230,19 +233,20 @@ func checkEnumValueUpperSnakeCase(add addFunc, enumValue protosource.EnumValue)
 
 // CheckEnumZeroValueSuffix is a check function.
 var CheckEnumZeroValueSuffix = func(
+	ctx context.Context,
 	id string,
 	ignoreFunc internal.IgnoreFunc,
 	files []protosource.File,
 	suffix string,
 ) ([]bufanalysis.FileAnnotation, error) {
-	return newEnumValueCheckFunc(
-		func(add addFunc, enumValue protosource.EnumValue) error {
+	return newEnumValueWithEditsCheckFunc(
+		func(add addWithEditsFunc, enumValue protosource.EnumValue) error {
 			return checkEnumZeroValueSuffix(add, enumValue, suffix)
 		},
-	)(id, ignoreFunc, files)
+	)(ctx, id, ignoreFunc, files)
 }
 
-func checkEnumZeroValueSuffix(add addFunc, enumValue protosource.EnumValue, suffix string) error {
+func checkEnumZeroValueSuffix(add addWithEditsFunc, enumValue protosource.EnumValue, suffix string) error {
 	if enumValue.Number() != 0 {
 		return nil
 	}
@@ -256,6 +260,7 @@ func checkEnumZeroValueSuffix(add addFunc, enumValue protosource.EnumValue, suff
 			[]protosource.Location{
 				enumValue.Enum().Location(),
 			},
+			newRenameEdits(enumValue.NameLocation(), name+suffix),
 			"Enum zero value name %q should be suffixed with %q.",
 			name,
 			suffix,
@@ -265,9 +270,9 @@ func checkEnumZeroValueSuffix(add addFunc, enumValue protosource.EnumValue, suff
 }
 
 // CheckFieldLowerSnakeCase is a check function.
-var CheckFieldLowerSnakeCase = newFieldCheckFunc(checkFieldLowerSnakeCase)
+var CheckFieldLowerSnakeCase = newFieldWithEditsCheckFunc(checkFieldLowerSnakeCase)
 
-func checkFieldLowerSnakeCase(add addFunc, field protosource.Field) error {
+func checkFieldLowerSnakeCase(add addWithEditsFunc, field protosource.Field) error {
 	message := field.ParentMessage()
 	if message == nil {
 		// just a sanity check
@@ -288,6 +293,7 @@ func checkFieldLowerSnakeCase(add addFunc, field protosource.Field) error {
 			[]protosource.Location{
 				field.ParentMessage().Location(),
 			},
+			newRenameEdits(field.NameLocation(), expectedName),
 			"Field name %q should be lower_snake_case, such as %q.",
 			name,
 			expectedName,
@@ -677,9 +683,9 @@ func checkRPCNoServerStreaming(add addFunc, method protosource.Method) error {
 }
 
 // CheckRPCPascalCase is a check function.
-var CheckRPCPascalCase = newMethodCheckFunc(checkRPCPascalCase)
+var CheckRPCPascalCase = newMethodWithEditsCheckFunc(checkRPCPascalCase)
 
-func checkRPCPascalCase(add addFunc, method protosource.Method) error {
+func checkRPCPascalCase(add addWithEditsFunc, method protosource.Method) error {
 	name := method.Name()
 	expectedName := stringutil.ToPascalCase(name)
 	if name != expectedName {
@@ -691,6 +697,7 @@ func checkRPCPascalCase(add addFunc, method protosource.Method) error {
 			[]protosource.Location{
 				method.Service().Location(),
 			},
+			newRenameEdits(method.NameLocation(), expectedName),
 			"RPC name %q should be PascalCase, such as %q.",
 			name,
 			expectedName,
@@ -701,6 +708,7 @@ func checkRPCPascalCase(add addFunc, method protosource.Method) error {
 
 // CheckRPCRequestResponseUnique is a check function.
 var CheckRPCRequestResponseUnique = func(
+	ctx context.Context,
 	id string,
 	ignoreFunc internal.IgnoreFunc,
 	files []protosource.File,
@@ -718,7 +726,7 @@ var CheckRPCRequestResponseUnique = func(
 				allowGoogleProtobufEmptyResponses,
 			)
 		},
-	)(id, ignoreFunc, files)
+	)(ctx, id, ignoreFunc, files)
 }
 
 func checkRPCRequestResponseUnique(
@@ -844,6 +852,7 @@ func checkRPCRequestResponseUnique(
 
 // CheckRPCRequestStandardName is a check function.
 var CheckRPCRequestStandardName = func(
+	ctx context.Context,
 	id string,
 	ignoreFunc internal.IgnoreFunc,
 	files []protosource.File,
@@ -853,7 +862,7 @@ var CheckRPCRequestStandardName = func(
 		func(add addFunc, method protosource.Method) error {
 			return checkRPCRequestStandardName(add, method, allowGoogleProtobufEmptyRequests)
 		},
-	)(id, ignoreFunc, files)
+	)(ctx, id, ignoreFunc, files)
 }
 
 func checkRPCRequestStandardName(add addFunc, method protosource.Method, allowGoogleProtobufEmptyRequests bool) error {
@@ -892,6 +901,7 @@ func checkRPCRequestStandardName(add addFunc, method protosource.Method, allowGo
 
 // CheckRPCResponseStandardName is a check function.
 var CheckRPCResponseStandardName = func(
+	ctx context.Context,
 	id string,
 	ignoreFunc internal.IgnoreFunc,
 	files []protosource.File,
@@ -901,7 +911,7 @@ var CheckRPCResponseStandardName = func(
 		func(add addFunc, method protosource.Method) error {
 			return checkRPCResponseStandardName(add, method, allowGoogleProtobufEmptyResponses)
 		},
-	)(id, ignoreFunc, files)
+	)(ctx, id, ignoreFunc, files)
 }
 
 func checkRPCResponseStandardName(add addFunc, method protosource.Method, allowGoogleProtobufEmptyResponses bool) error {
@@ -939,35 +949,36 @@ func checkRPCResponseStandardName(add addFunc, method protosource.Method, allowG
 }
 
 // CheckServicePascalCase is a check function.
-var CheckServicePascalCase = newServiceCheckFunc(checkServicePascalCase)
+var CheckServicePascalCase = newServiceWithEditsCheckFunc(checkServicePascalCase)
 
-func checkServicePascalCase(add addFunc, service protosource.Service) error {
+func checkServicePascalCase(add addWithEditsFunc, service protosource.Service) error {
 	name := service.Name()
 	expectedName := stringutil.ToPascalCase(name)
 	if name != expectedName {
-		add(service, service.NameLocation(), nil, "Service name %q should be PascalCase, such as %q.", name, expectedName)
+		add(service, service.NameLocation(), nil, newRenameEdits(service.NameLocation(), expectedName), "Service name %q should be PascalCase, such as %q.", name, expectedName)
 	}
 	return nil
 }
 
 // CheckServiceSuffix is a check function.
 var CheckServiceSuffix = func(
+	ctx context.Context,
 	id string,
 	ignoreFunc internal.IgnoreFunc,
 	files []protosource.File,
 	suffix string,
 ) ([]bufanalysis.FileAnnotation, error) {
-	return newServiceCheckFunc(
-		func(add addFunc, service protosource.Service) error {
+	return newServiceWithEditsCheckFunc(
+		func(add addWithEditsFunc, service protosource.Service) error {
 			return checkServiceSuffix(add, service, suffix)
 		},
-	)(id, ignoreFunc, files)
+	)(ctx, id, ignoreFunc, files)
 }
 
-func checkServiceSuffix(add addFunc, service protosource.Service, suffix string) error {
+func checkServiceSuffix(add addWithEditsFunc, service protosource.Service, suffix string) error {
 	name := service.Name()
 	if !strings.HasSuffix(name, suffix) {
-		add(service, service.NameLocation(), nil, "Service name %q should be suffixed with %q.", name, suffix)
+		add(service, service.NameLocation(), nil, newRenameEdits(service.NameLocation(), name+suffix), "Service name %q should be suffixed with %q.", name, suffix)
 	}
 	return nil
 }
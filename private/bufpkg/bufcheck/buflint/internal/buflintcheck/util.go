@@ -15,6 +15,8 @@
 package buflintcheck
 
 import (
+	"context"
+
 	"strings"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
@@ -28,6 +30,11 @@ import (
 // Both the Descriptor and Locations can be nil.
 type addFunc func(protosource.Descriptor, protosource.Location, []protosource.Location, string, ...interface{})
 
+// addWithEditsFunc adds a FileAnnotation with the Edits that fix it.
+//
+// Both the Descriptor and Locations can be nil.
+type addWithEditsFunc func(protosource.Descriptor, protosource.Location, []protosource.Location, []bufanalysis.Edit, string, ...interface{})
+
 func fieldToLowerSnakeCase(s string) string {
 	// Try running this on googleapis and watch
 	// We allow both effectively by not passing the option
@@ -102,8 +109,8 @@ func getImportCycleIfExists(
 // linting of any files that are imports via protosource.File.IsImport().
 func newFilesWithImportsCheckFunc(
 	f func(addFunc, []protosource.File) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-	return func(id string, ignoreFunc internal.IgnoreFunc, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 		helper := internal.NewHelper(id, ignoreFunc)
 		if err := f(helper.AddFileAnnotationWithExtraIgnoreLocationsf, files); err != nil {
 			return nil, err
@@ -114,16 +121,10 @@ func newFilesWithImportsCheckFunc(
 
 func newFilesCheckFunc(
 	f func(addFunc, []protosource.File) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-	return func(id string, ignoreFunc internal.IgnoreFunc, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-		filesWithoutImports := make([]protosource.File, 0, len(files))
-		for _, file := range files {
-			if !file.IsImport() {
-				filesWithoutImports = append(filesWithoutImports, file)
-			}
-		}
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 		helper := internal.NewHelper(id, ignoreFunc)
-		if err := f(helper.AddFileAnnotationWithExtraIgnoreLocationsf, filesWithoutImports); err != nil {
+		if err := f(helper.AddFileAnnotationWithExtraIgnoreLocationsf, getFilesWithoutImports(files)); err != nil {
 			return nil, err
 		}
 		return helper.FileAnnotations(), nil
@@ -132,7 +133,7 @@ func newFilesCheckFunc(
 
 func newPackageToFilesCheckFunc(
 	f func(add addFunc, pkg string, files []protosource.File) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFilesCheckFunc(
 		func(add addFunc, files []protosource.File) error {
 			packageToFiles, err := protosource.PackageToFiles(files...)
@@ -151,7 +152,7 @@ func newPackageToFilesCheckFunc(
 
 func newDirToFilesCheckFunc(
 	f func(add addFunc, dirPath string, files []protosource.File) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFilesCheckFunc(
 		func(add addFunc, files []protosource.File) error {
 			dirPathToFiles, err := protosource.DirPathToFiles(files...)
@@ -168,24 +169,47 @@ func newDirToFilesCheckFunc(
 	)
 }
 
+// newFileCheckFunc returns a check function that checks each file that is not
+// an import independently, so the files are checked concurrently.
 func newFileCheckFunc(
 	f func(addFunc, protosource.File) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-	return newFilesCheckFunc(
-		func(add addFunc, files []protosource.File) error {
-			for _, file := range files {
-				if err := f(add, file); err != nil {
-					return err
-				}
-			}
-			return nil
-		},
-	)
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+		filesWithoutImports := getFilesWithoutImports(files)
+		return internal.CheckParallel(
+			ctx,
+			id,
+			ignoreFunc,
+			len(filesWithoutImports),
+			func(helper *internal.Helper, i int) error {
+				return f(helper.AddFileAnnotationWithExtraIgnoreLocationsf, filesWithoutImports[i])
+			},
+		)
+	}
+}
+
+// newRenameEdits returns the Edits that replace the name at the location with
+// the new name.
+//
+// Only the declaration is renamed, not the references to the declaration.
+func newRenameEdits(nameLocation protosource.Location, newName string) []bufanalysis.Edit {
+	if nameLocation == nil {
+		return nil
+	}
+	return []bufanalysis.Edit{
+		bufanalysis.NewEdit(
+			nameLocation.StartLine(),
+			nameLocation.StartColumn(),
+			nameLocation.EndLine(),
+			nameLocation.EndColumn(),
+			newName,
+		),
+	}
 }
 
 func newFileImportCheckFunc(
 	f func(addFunc, protosource.FileImport) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFileCheckFunc(
 		func(add addFunc, file protosource.File) error {
 			for _, fileImport := range file.FileImports() {
@@ -200,7 +224,7 @@ func newFileImportCheckFunc(
 
 func newEnumCheckFunc(
 	f func(addFunc, protosource.Enum) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFileCheckFunc(
 		func(add addFunc, file protosource.File) error {
 			return protosource.ForEachEnum(
@@ -215,7 +239,7 @@ func newEnumCheckFunc(
 
 func newEnumValueCheckFunc(
 	f func(addFunc, protosource.EnumValue) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newEnumCheckFunc(
 		func(add addFunc, enum protosource.Enum) error {
 			for _, enumValue := range enum.Values() {
@@ -230,7 +254,7 @@ func newEnumValueCheckFunc(
 
 func newMessageCheckFunc(
 	f func(addFunc, protosource.Message) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFileCheckFunc(
 		func(add addFunc, file protosource.File) error {
 			return protosource.ForEachMessage(
@@ -245,7 +269,7 @@ func newMessageCheckFunc(
 
 func newFieldCheckFunc(
 	f func(addFunc, protosource.Field) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newMessageCheckFunc(
 		func(add addFunc, message protosource.Message) error {
 			for _, field := range message.Fields() {
@@ -266,7 +290,7 @@ func newFieldCheckFunc(
 
 func newOneofCheckFunc(
 	f func(addFunc, protosource.Oneof) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newMessageCheckFunc(
 		func(add addFunc, message protosource.Message) error {
 			for _, oneof := range message.Oneofs() {
@@ -281,7 +305,7 @@ func newOneofCheckFunc(
 
 func newServiceCheckFunc(
 	f func(addFunc, protosource.Service) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFileCheckFunc(
 		func(add addFunc, file protosource.File) error {
 			for _, service := range file.Services() {
@@ -296,7 +320,7 @@ func newServiceCheckFunc(
 
 func newMethodCheckFunc(
 	f func(addFunc, protosource.Method) error,
-) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newServiceCheckFunc(
 		func(add addFunc, service protosource.Service) error {
 			for _, method := range service.Methods() {
@@ -308,3 +332,110 @@ func newMethodCheckFunc(
 		},
 	)
 }
+
+// The check functions of rules that can be fixed mechanically use the
+// addWithEditsFunc instead of the addFunc, so that the FileAnnotations contain
+// the Edits that fix them.
+
+// newFileWithEditsCheckFunc is newFileCheckFunc for the addWithEditsFunc.
+func newFileWithEditsCheckFunc(
+	f func(addWithEditsFunc, protosource.File) error,
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return func(ctx context.Context, id string, ignoreFunc internal.IgnoreFunc, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+		filesWithoutImports := getFilesWithoutImports(files)
+		return internal.CheckParallel(
+			ctx,
+			id,
+			ignoreFunc,
+			len(filesWithoutImports),
+			func(helper *internal.Helper, i int) error {
+				return f(helper.AddFileAnnotationWithEditsf, filesWithoutImports[i])
+			},
+		)
+	}
+}
+
+func newEnumValueWithEditsCheckFunc(
+	f func(addWithEditsFunc, protosource.EnumValue) error,
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return newFileWithEditsCheckFunc(
+		func(add addWithEditsFunc, file protosource.File) error {
+			return protosource.ForEachEnum(
+				func(enum protosource.Enum) error {
+					for _, enumValue := range enum.Values() {
+						if err := f(add, enumValue); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+				file,
+			)
+		},
+	)
+}
+
+func newFieldWithEditsCheckFunc(
+	f func(addWithEditsFunc, protosource.Field) error,
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return newFileWithEditsCheckFunc(
+		func(add addWithEditsFunc, file protosource.File) error {
+			return protosource.ForEachMessage(
+				func(message protosource.Message) error {
+					for _, field := range message.Fields() {
+						if err := f(add, field); err != nil {
+							return err
+						}
+					}
+					for _, field := range message.Extensions() {
+						if err := f(add, field); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+				file,
+			)
+		},
+	)
+}
+
+func newServiceWithEditsCheckFunc(
+	f func(addWithEditsFunc, protosource.Service) error,
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return newFileWithEditsCheckFunc(
+		func(add addWithEditsFunc, file protosource.File) error {
+			for _, service := range file.Services() {
+				if err := f(add, service); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+func newMethodWithEditsCheckFunc(
+	f func(addWithEditsFunc, protosource.Method) error,
+) func(context.Context, string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return newServiceWithEditsCheckFunc(
+		func(add addWithEditsFunc, service protosource.Service) error {
+			for _, method := range service.Methods() {
+				if err := f(add, method); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+func getFilesWithoutImports(files []protosource.File) []protosource.File {
+	filesWithoutImports := make([]protosource.File, 0, len(files))
+	for _, file := range files {
+		if !file.IsImport() {
+			filesWithoutImports = append(filesWithoutImports, file)
+		}
+	}
+	return filesWithoutImports
+}
@@ -15,10 +15,12 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/pkg/protosource"
+	"github.com/bufbuild/buf/private/pkg/thread"
 )
 
 // Helper is a helper for rules.
@@ -51,6 +53,7 @@ func (h *Helper) AddFileAnnotationf(
 		nil,
 		location,
 		nil,
+		nil,
 		format,
 		args...,
 	)
@@ -74,6 +77,7 @@ func (h *Helper) AddFileAnnotationWithExtraIgnoreDescriptorsf(
 		extraIgnoreDescriptors,
 		location,
 		nil,
+		nil,
 		format,
 		args...,
 	)
@@ -97,6 +101,33 @@ func (h *Helper) AddFileAnnotationWithExtraIgnoreLocationsf(
 		nil,
 		location,
 		extraIgnoreLocations,
+		nil,
+		format,
+		args...,
+	)
+}
+
+// AddFileAnnotationWithEditsf adds a FileAnnotation with the id as the Type,
+// and with the edits that fix it.
+//
+// extraIgnoreLocations are extra locations to check for comment ignores.
+//
+// If descriptor is nil, no filename information is added.
+// If location is nil, no line or column information will be added.
+func (h *Helper) AddFileAnnotationWithEditsf(
+	descriptor protosource.Descriptor,
+	location protosource.Location,
+	extraIgnoreLocations []protosource.Location,
+	edits []bufanalysis.Edit,
+	format string,
+	args ...interface{},
+) {
+	h.addFileAnnotationf(
+		descriptor,
+		nil,
+		location,
+		extraIgnoreLocations,
+		edits,
 		format,
 		args...,
 	)
@@ -107,6 +138,7 @@ func (h *Helper) addFileAnnotationf(
 	extraIgnoreDescriptors []protosource.Descriptor,
 	location protosource.Location,
 	extraIgnoreLocations []protosource.Location,
+	edits []bufanalysis.Edit,
 	format string,
 	args ...interface{},
 ) {
@@ -123,6 +155,7 @@ func (h *Helper) addFileAnnotationf(
 			h.id,
 			descriptor,
 			location,
+			edits,
 			format,
 			args...,
 		),
@@ -134,6 +167,39 @@ func (h *Helper) FileAnnotations() []bufanalysis.FileAnnotation {
 	return h.fileAnnotations
 }
 
+// CheckParallel calls f for each index in [0, n) concurrently, each with its own
+// Helper for the id, and returns the FileAnnotations of the Helpers in the order
+// of the indices.
+//
+// This is used by check functions that check each file, or each pair of files,
+// independently, so that large sets of files are checked on all CPUs. At most
+// thread.ParallelismForContext(ctx) calls are run at once.
+func CheckParallel(
+	ctx context.Context,
+	id string,
+	ignoreFunc IgnoreFunc,
+	n int,
+	f func(helper *Helper, i int) error,
+) ([]bufanalysis.FileAnnotation, error) {
+	helpers := make([]*Helper, n)
+	jobs := make([]func(context.Context) error, n)
+	for i := 0; i < n; i++ {
+		i := i
+		helpers[i] = NewHelper(id, ignoreFunc)
+		jobs[i] = func(context.Context) error {
+			return f(helpers[i], i)
+		}
+	}
+	if err := thread.Parallelize(ctx, jobs); err != nil {
+		return nil, err
+	}
+	var fileAnnotations []bufanalysis.FileAnnotation
+	for _, helper := range helpers {
+		fileAnnotations = append(fileAnnotations, helper.FileAnnotations()...)
+	}
+	return fileAnnotations, nil
+}
+
 // newFileAnnotationf adds a FileAnnotation with the id as the Type.
 //
 // If descriptor is nil, no filename information is added.
@@ -142,6 +208,7 @@ func newFileAnnotationf(
 	id string,
 	descriptor protosource.Descriptor,
 	location protosource.Location,
+	edits []bufanalysis.Edit,
 	format string,
 	args ...interface{},
 ) bufanalysis.FileAnnotation {
@@ -167,5 +234,6 @@ func newFileAnnotationf(
 		endColumn,
 		id,
 		fmt.Sprintf(format, args...),
+		bufanalysis.FileAnnotationWithEdits(edits...),
 	)
 }
@@ -15,6 +15,7 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"sort"
 
@@ -36,7 +37,7 @@ import (
 type IgnoreFunc func(id string, descriptors []protosource.Descriptor, locations []protosource.Location) bool
 
 // CheckFunc is a check function.
-type CheckFunc func(id string, ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error)
+type CheckFunc func(ctx context.Context, id string, ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error)
 
 // Rule provides a base embeddable rule.
 type Rule struct {
@@ -92,8 +93,8 @@ func (c *Rule) MarshalJSON() ([]byte, error) {
 	return json.Marshal(ruleJSON{ID: c.id, Categories: c.categories, Purpose: c.purpose})
 }
 
-func (c *Rule) check(ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-	return c.checkFunc(c.ID(), ignoreFunc, previousFiles, files)
+func (c *Rule) check(ctx context.Context, ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return c.checkFunc(ctx, c.ID(), ignoreFunc, previousFiles, files)
 }
 
 type ruleJSON struct {
@@ -23,6 +23,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/protosource"
 	"github.com/bufbuild/buf/private/pkg/protoversion"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/thread"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -82,25 +83,43 @@ func (r *Runner) Check(ctx context.Context, config *Config, previousFiles []prot
 	defer span.End()
 
 	ignoreFunc := r.newIgnoreFunc(config)
-	var fileAnnotations []bufanalysis.FileAnnotation
-	resultC := make(chan *result, len(rules))
-	for _, rule := range rules {
+	// The rules are checked concurrently, and the check functions of rules that
+	// check each file independently also check the files concurrently. The
+	// results are stored by the index of the rule, and the FileAnnotations are
+	// sorted, so that the output does not depend on the order that the rules
+	// complete in.
+	results := make([]*result, len(rules))
+	jobs := make([]func(context.Context) error, len(rules))
+	for i, rule := range rules {
+		i := i
 		rule := rule
-		go func() {
-			iFileAnnotations, iErr := rule.check(ignoreFunc, previousFiles, files)
-			resultC <- newResult(iFileAnnotations, iErr)
-		}()
+		jobs[i] = func(ctx context.Context) error {
+			iFileAnnotations, iErr := rule.check(ctx, ignoreFunc, previousFiles, files)
+			results[i] = newResult(iFileAnnotations, iErr)
+			return nil
+		}
 	}
-	var err error
-	for i := 0; i < len(rules); i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case result := <-resultC:
-			fileAnnotations = append(fileAnnotations, result.FileAnnotations...)
-			err = multierr.Append(err, result.Err)
+	// The check functions only use the context to parallelize, so Check returns
+	// as soon as the context is done instead of waiting for the rules that are
+	// being checked.
+	doneC := make(chan error, 1)
+	go func() {
+		doneC <- thread.Parallelize(ctx, jobs)
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err := <-doneC:
+		if err != nil {
+			return nil, err
 		}
 	}
+	var fileAnnotations []bufanalysis.FileAnnotation
+	var err error
+	for _, result := range results {
+		fileAnnotations = append(fileAnnotations, result.FileAnnotations...)
+		err = multierr.Append(err, result.Err)
+	}
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
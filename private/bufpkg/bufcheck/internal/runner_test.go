@@ -0,0 +1,129 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/pkg/protosource"
+	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCheckParallelOrder(t *testing.T) {
+	t.Parallel()
+	const n = 50
+	var expectedMessages []string
+	for i := 0; i < n; i++ {
+		expectedMessages = append(expectedMessages, fmt.Sprintf("message %d", i))
+	}
+	for _, parallelism := range []int{1, 2, 4, 16} {
+		fileAnnotations, err := CheckParallel(
+			thread.WithParallelism(context.Background(), parallelism),
+			"ID",
+			func(string, []protosource.Descriptor, []protosource.Location) bool { return false },
+			n,
+			func(helper *Helper, i int) error {
+				// The later indices complete first.
+				time.Sleep(time.Duration(n-i) * 10 * time.Microsecond)
+				helper.AddFileAnnotationf(nil, nil, "message %d", i)
+				return nil
+			},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, expectedMessages, fileAnnotationMessages(fileAnnotations), "parallelism %d", parallelism)
+	}
+}
+
+func TestRunnerCheckOrder(t *testing.T) {
+	t.Parallel()
+	var rules []*Rule
+	for i := 0; i < 10; i++ {
+		i := i
+		rules = append(
+			rules,
+			newRule(
+				fmt.Sprintf("RULE_%d", i),
+				nil,
+				"rules are checked",
+				func(ctx context.Context, id string, ignoreFunc IgnoreFunc, _ []protosource.File, _ []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+					return CheckParallel(
+						ctx,
+						id,
+						ignoreFunc,
+						5,
+						func(helper *Helper, j int) error {
+							time.Sleep(time.Duration(10-i) * 10 * time.Microsecond)
+							helper.AddFileAnnotationf(nil, nil, "message %d", j)
+							return nil
+						},
+					)
+				},
+			),
+		)
+	}
+	var expectedFileAnnotations []bufanalysis.FileAnnotation
+	for _, parallelism := range []int{1, 2, 4, 16} {
+		ctx := thread.WithParallelism(context.Background(), parallelism)
+		fileAnnotations, err := NewRunner(zap.NewNop()).Check(ctx, &Config{Rules: rules}, nil, nil)
+		require.NoError(t, err)
+		require.Len(t, fileAnnotations, 50)
+		if expectedFileAnnotations == nil {
+			expectedFileAnnotations = fileAnnotations
+			continue
+		}
+		assert.Equal(t, expectedFileAnnotations, fileAnnotations, "parallelism %d", parallelism)
+	}
+}
+
+func TestRunnerCheckCanceled(t *testing.T) {
+	t.Parallel()
+	unblockC := make(chan struct{})
+	defer close(unblockC)
+	startedC := make(chan struct{})
+	rules := []*Rule{
+		newRule(
+			"BLOCKED",
+			nil,
+			"the check is canceled",
+			func(context.Context, string, IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				close(startedC)
+				<-unblockC
+				return nil, nil
+			},
+		),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-startedC
+		cancel()
+	}()
+	_, err := NewRunner(zap.NewNop()).Check(ctx, &Config{Rules: rules}, nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func fileAnnotationMessages(fileAnnotations []bufanalysis.FileAnnotation) []string {
+	messages := make([]string, len(fileAnnotations))
+	for i, fileAnnotation := range fileAnnotations {
+		messages[i] = fileAnnotation.Message()
+	}
+	return messages
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/storage"
 )
 
@@ -39,6 +40,16 @@ const (
 	// V1Beta1Version is the v1beta1 version.
 	V1Beta1Version = "v1beta1"
 
+	// ExternalConfigV2FilePath is the v2 file path.
+	ExternalConfigV2FilePath = "buf.yaml"
+
+	// V2Version is the v2 version.
+	//
+	// A v2 configuration is at the root of a workspace, and defines the modules
+	// of the workspace, their shared dependencies, and their lint and breaking
+	// change configuration in a single file. See ExternalConfigV2.
+	V2Version = "v2"
+
 	// backupExternalConfigV1FilePath is another acceptable configuration file path for v1.
 	//
 	// Originally we thought we were going to move to buf.mod, and had this around for
@@ -70,20 +81,142 @@ type Config struct {
 	Build          *bufmoduleconfig.Config
 	Breaking       *bufbreakingconfig.Config
 	Lint           *buflintconfig.Config
+	Format         *FormatConfig
+	// DependencyConstraints are the constraints on how dependencies are
+	// updated by buf mod update, keyed by the identity string of the
+	// dependency, such as buf.build/acme/weather.
+	DependencyConstraints map[string]*DependencyConstraint
+	// Extends is the module with the breaking and lint configuration that this
+	// configuration extends, if any.
+	//
+	// The extends key is a module reference, optionally followed by the
+	// configuration file of the module, such as buf.build/acme/standards:buf.yaml.
+	// Modules only store their buf.yaml, so no other file can be extended.
+	//
+	// The module is pinned in the buf.lock by buf mod update, and is not a dependency.
+	// The Breaking and Lint only include the configuration of the module if the
+	// configuration was read with ReadConfigOSWithExtendsResolver.
+	Extends bufmoduleref.ModuleReference
+}
+
+// DependencyConstraint is a constraint on how a dependency is updated.
+//
+// Exactly one of the fields is set.
+type DependencyConstraint struct {
+	// The label that the dependency is updated to, instead of the reference in deps.
+	Label string
+	// The draft that the dependency is updated to, instead of the reference in deps.
+	Draft string
+	// If true, the dependency is not updated, and stays at the commit in buf.lock.
+	Exclude bool
+}
+
+// Reference returns the reference that the dependency is updated to, or empty
+// if the reference in deps is used.
+func (d *DependencyConstraint) Reference() string {
+	if d.Label != "" {
+		return d.Label
+	}
+	return d.Draft
+}
+
+// FormatConfig is the configuration for buf format.
+//
+// The zero values of all fields select the default style.
+type FormatConfig struct {
+	// The number of spaces in each level of indentation.
+	Indent int
+	// If true, each level of indentation is a tab instead of spaces.
+	UseTabs bool
+	// The maximum width of a line, which is exceeded only if a line cannot
+	// be wrapped. Zero means there is no maximum.
+	MaxLineWidth int
+	// If true, imports are grouped by where they are imported from.
+	GroupImports bool
+	// If true, consecutive fields and enum values are vertically aligned.
+	AlignFields bool
+	// If true, the types of each file are written in a canonical order.
+	CanonicalOrder bool
+	// If true, enum values are sorted by number.
+	SortEnumValues bool
+	// If true, '/* */' doc comments are converted to '//' comments.
+	LineDocComments bool
+	// If true, a space is written after the '//' of comments.
+	CommentSpace bool
+	// If true, long doc comments are reflowed to fit within MaxLineWidth.
+	ReflowComments bool
+}
+
+// ConfigDefaults are the defaults for the keys of the build, breaking, and lint
+// sections of a v1 configuration that the configuration does not set.
+//
+// Each section maps the keys of the section, such as except, to the values that
+// are used for the keys that are not set. A key that is set in the configuration,
+// even to an empty value, is not defaulted.
+type ConfigDefaults struct {
+	Build    map[string]interface{}
+	Breaking map[string]interface{}
+	Lint     map[string]interface{}
+}
+
+// ExtendsResolver resolves the configurations that configurations extend.
+type ExtendsResolver interface {
+	// GetExtendsConfigDefaults gets the breaking and lint configuration of the module
+	// that the configuration in the bucket extends, as defaults for the keys that the
+	// configuration does not set.
+	//
+	// The module is read at the commit that is pinned in the buf.lock of the bucket.
+	// The bucket may be nil if the configuration is an override without a bucket.
+	GetExtendsConfigDefaults(
+		ctx context.Context,
+		readBucket storage.ReadBucket,
+		moduleReference bufmoduleref.ModuleReference,
+	) (*ConfigDefaults, error)
 }
 
 // GetConfigForBucket gets the Config for the YAML data at ConfigFilePath.
 //
 // If the data is of length 0, returns the default config.
-func GetConfigForBucket(ctx context.Context, readBucket storage.ReadBucket) (*Config, error) {
-	return getConfigForBucket(ctx, readBucket)
+func GetConfigForBucket(ctx context.Context, readBucket storage.ReadBucket, options ...GetConfigOption) (*Config, error) {
+	getConfigOptions := newGetConfigOptions()
+	for _, option := range options {
+		option(getConfigOptions)
+	}
+	return getConfigForBucket(ctx, readBucket, nil, "", getConfigOptions.envContainer)
 }
 
 // GetConfigForData gets the Config for the given JSON or YAML data.
 //
 // If the data is of length 0, returns the default config.
-func GetConfigForData(ctx context.Context, data []byte) (*Config, error) {
-	return getConfigForData(ctx, data)
+func GetConfigForData(ctx context.Context, data []byte, options ...GetConfigOption) (*Config, error) {
+	getConfigOptions := newGetConfigOptions()
+	for _, option := range options {
+		option(getConfigOptions)
+	}
+	return getConfigForData(ctx, data, nil, "", getConfigOptions.envContainer)
+}
+
+// GetConfigOption is an option for GetConfigForBucket and GetConfigForData.
+type GetConfigOption func(*getConfigOptions)
+
+// GetConfigWithEnvContainer sets the environment variables that are expanded in
+// the configuration.
+//
+// References to environment variables of the form ${VAR} or ${VAR:-default} are
+// expanded in the deps, the extends, and the ignore and ignore_only paths of the
+// breaking and lint sections. It is an error to reference a variable that is not
+// set without a default. If no EnvContainer is set, they are not expanded.
+func GetConfigWithEnvContainer(envContainer app.EnvContainer) GetConfigOption {
+	return func(getConfigOptions *getConfigOptions) {
+		getConfigOptions.envContainer = envContainer
+	}
+}
+
+// ExternalConfigV1ForConfig returns the v1 external representation of the Config.
+//
+// The Config must be a v1 Config.
+func ExternalConfigV1ForConfig(config *Config) (ExternalConfigV1, error) {
+	return externalConfigV1ForConfig(config)
 }
 
 // WriteConfig writes an initial configuration file into the bucket.
@@ -204,6 +337,52 @@ func ReadConfigOSWithOverride(override string) ReadConfigOSOption {
 	}
 }
 
+// ReadConfigOSWithDefaults sets the defaults for the keys of the build, breaking, and
+// lint sections that the configuration does not set.
+//
+// The defaults are applied to v1 configurations, including the default configuration
+// if there is no configuration file, and to the override, if any.
+func ReadConfigOSWithDefaults(defaults *ConfigDefaults) ReadConfigOSOption {
+	return func(readConfigOSOptions *readConfigOSOptions) {
+		readConfigOSOptions.defaults = defaults
+	}
+}
+
+// ReadConfigOSWithExtendsResolver sets the ExtendsResolver that is used to resolve
+// the module that a v1 configuration extends.
+//
+// The keys of the breaking and lint sections of the configuration of the module are
+// used for the keys that the configuration does not set, and take precedence over the
+// defaults, if any. The extends key is ignored if no ExtendsResolver is set.
+func ReadConfigOSWithExtendsResolver(extendsResolver ExtendsResolver) ReadConfigOSOption {
+	return func(readConfigOSOptions *readConfigOSOptions) {
+		readConfigOSOptions.extendsResolver = extendsResolver
+	}
+}
+
+// ReadConfigOSWithEnvContainer sets the environment variables that are expanded
+// in the configuration.
+//
+// See GetConfigWithEnvContainer for where they are expanded.
+func ReadConfigOSWithEnvContainer(envContainer app.EnvContainer) ReadConfigOSOption {
+	return func(readConfigOSOptions *readConfigOSOptions) {
+		readConfigOSOptions.envContainer = envContainer
+	}
+}
+
+// ReadConfigOSWithProfile sets the profile to apply to v1 configurations.
+//
+// The keys of the breaking and lint sections of the profile override the keys of
+// the sections of the configuration, and the defaults, if any. It is an error for a
+// configuration to define profiles but not the given profile. Configurations that do
+// not define any profiles are unaffected, so that only some of the modules of a
+// workspace need to define profiles.
+func ReadConfigOSWithProfile(profile string) ReadConfigOSOption {
+	return func(readConfigOSOptions *readConfigOSOptions) {
+		readConfigOSOptions.profile = profile
+	}
+}
+
 // ExistingConfigFilePath checks if a configuration file exists, and if so, returns the path
 // within the ReadBucket of this configuration file.
 //
@@ -248,9 +427,78 @@ type ExternalConfigV1 struct {
 	Version  string                             `json:"version,omitempty" yaml:"version,omitempty"`
 	Name     string                             `json:"name,omitempty" yaml:"name,omitempty"`
 	Deps     []string                           `json:"deps,omitempty" yaml:"deps,omitempty"`
+	Extends  string                             `json:"extends,omitempty" yaml:"extends,omitempty"`
 	Build    bufmoduleconfig.ExternalConfigV1   `json:"build,omitempty" yaml:"build,omitempty"`
 	Breaking bufbreakingconfig.ExternalConfigV1 `json:"breaking,omitempty" yaml:"breaking,omitempty"`
 	Lint     buflintconfig.ExternalConfigV1     `json:"lint,omitempty" yaml:"lint,omitempty"`
+	Format   ExternalFormatConfigV1             `json:"format,omitempty" yaml:"format,omitempty"`
+	// DependencyConstraints are keyed by the identity of the dependency, such as buf.build/acme/weather.
+	DependencyConstraints map[string]ExternalDependencyConstraintV1 `json:"dependency_constraints,omitempty" yaml:"dependency_constraints,omitempty"`
+	// Profiles are keyed by the name of the profile, such as ci.
+	Profiles map[string]ExternalProfileConfigV1 `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// ExternalConfigV2 represents the on-disk representation of the Config
+// at version v2.
+//
+// The Deps, Extends, Format, DependencyConstraints, and Profiles are shared by all
+// of the Modules, and the Breaking and Lint are the defaults for the keys that the
+// Modules do not set. The buf.lock at the root of the workspace is shared by the Modules.
+type ExternalConfigV2 struct {
+	Version  string                             `json:"version,omitempty" yaml:"version,omitempty"`
+	Modules  []ExternalModuleConfigV2           `json:"modules,omitempty" yaml:"modules,omitempty"`
+	Deps     []string                           `json:"deps,omitempty" yaml:"deps,omitempty"`
+	Extends  string                             `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Breaking bufbreakingconfig.ExternalConfigV1 `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	Lint     buflintconfig.ExternalConfigV1     `json:"lint,omitempty" yaml:"lint,omitempty"`
+	Format   ExternalFormatConfigV1             `json:"format,omitempty" yaml:"format,omitempty"`
+	// DependencyConstraints are keyed by the identity of the dependency, such as buf.build/acme/weather.
+	DependencyConstraints map[string]ExternalDependencyConstraintV1 `json:"dependency_constraints,omitempty" yaml:"dependency_constraints,omitempty"`
+	// Profiles are keyed by the name of the profile, such as ci.
+	Profiles map[string]ExternalProfileConfigV1 `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// ExternalProfileConfigV1 represents the on-disk representation of a profile,
+// which overrides the keys of the breaking and lint sections that it sets when
+// it is selected.
+type ExternalProfileConfigV1 struct {
+	Breaking bufbreakingconfig.ExternalConfigV1 `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	Lint     buflintconfig.ExternalConfigV1     `json:"lint,omitempty" yaml:"lint,omitempty"`
+}
+
+// ExternalModuleConfigV2 represents the on-disk representation of a module
+// in the ExternalConfigV2.
+type ExternalModuleConfigV2 struct {
+	// Path is the directory of the module, relative to the root of the workspace.
+	Path     string                             `json:"path,omitempty" yaml:"path,omitempty"`
+	Name     string                             `json:"name,omitempty" yaml:"name,omitempty"`
+	Excludes []string                           `json:"excludes,omitempty" yaml:"excludes,omitempty"`
+	Includes []string                           `json:"includes,omitempty" yaml:"includes,omitempty"`
+	Breaking bufbreakingconfig.ExternalConfigV1 `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	Lint     buflintconfig.ExternalConfigV1     `json:"lint,omitempty" yaml:"lint,omitempty"`
+}
+
+// ExternalDependencyConstraintV1 represents the on-disk representation of the
+// DependencyConstraint at version v1.
+type ExternalDependencyConstraintV1 struct {
+	Label   string `json:"label,omitempty" yaml:"label,omitempty"`
+	Draft   string `json:"draft,omitempty" yaml:"draft,omitempty"`
+	Exclude bool   `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// ExternalFormatConfigV1 represents the on-disk representation of the
+// FormatConfig at version v1.
+type ExternalFormatConfigV1 struct {
+	Indent          int  `json:"indent,omitempty" yaml:"indent,omitempty"`
+	UseTabs         bool `json:"use_tabs,omitempty" yaml:"use_tabs,omitempty"`
+	MaxLineWidth    int  `json:"max_line_width,omitempty" yaml:"max_line_width,omitempty"`
+	GroupImports    bool `json:"group_imports,omitempty" yaml:"group_imports,omitempty"`
+	AlignFields     bool `json:"align_fields,omitempty" yaml:"align_fields,omitempty"`
+	CanonicalOrder  bool `json:"canonical_order,omitempty" yaml:"canonical_order,omitempty"`
+	SortEnumValues  bool `json:"sort_enum_values,omitempty" yaml:"sort_enum_values,omitempty"`
+	LineDocComments bool `json:"line_doc_comments,omitempty" yaml:"line_doc_comments,omitempty"`
+	CommentSpace    bool `json:"comment_space,omitempty" yaml:"comment_space,omitempty"`
+	ReflowComments  bool `json:"reflow_comments,omitempty" yaml:"reflow_comments,omitempty"`
 }
 
 // ExternalConfigVersion defines the subset of all config
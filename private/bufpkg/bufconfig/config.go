@@ -15,6 +15,10 @@
 package bufconfig
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/bufbreakingconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleconfig"
@@ -39,6 +43,7 @@ func newConfigV1Beta1(externalConfig ExternalConfigV1Beta1) (*Config, error) {
 		Build:          buildConfig,
 		Breaking:       bufbreakingconfig.NewConfigV1Beta1(externalConfig.Breaking),
 		Lint:           buflintconfig.NewConfigV1Beta1(externalConfig.Lint),
+		Format:         &FormatConfig{},
 	}, nil
 }
 
@@ -54,11 +59,210 @@ func newConfigV1(externalConfig ExternalConfigV1) (*Config, error) {
 			return nil, err
 		}
 	}
+	formatConfig, err := newFormatConfigV1(externalConfig.Format)
+	if err != nil {
+		return nil, err
+	}
+	dependencyConstraints, err := newDependencyConstraintsV1(externalConfig.DependencyConstraints, buildConfig.DependencyModuleReferences)
+	if err != nil {
+		return nil, err
+	}
+	var extends bufmoduleref.ModuleReference
+	if externalConfig.Extends != "" {
+		extends, err = extendsModuleReferenceForString(externalConfig.Extends)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extends: %w", err)
+		}
+	}
 	return &Config{
-		Version:        V1Version,
-		ModuleIdentity: moduleIdentity,
-		Build:          buildConfig,
-		Breaking:       bufbreakingconfig.NewConfigV1(externalConfig.Breaking),
-		Lint:           buflintconfig.NewConfigV1(externalConfig.Lint),
+		Version:               V1Version,
+		ModuleIdentity:        moduleIdentity,
+		Build:                 buildConfig,
+		Breaking:              bufbreakingconfig.NewConfigV1(externalConfig.Breaking),
+		Lint:                  buflintconfig.NewConfigV1(externalConfig.Lint),
+		Format:                formatConfig,
+		DependencyConstraints: dependencyConstraints,
+		Extends:               extends,
 	}, nil
 }
+
+// newConfigV2 returns the Config of the root of the workspace that the v2
+// configuration defines, with the shared dependencies and the default lint
+// and breaking change configuration.
+//
+// The modules are validated, but the Config does not include them, as each
+// module has its own Config.
+func newConfigV2(externalConfig ExternalConfigV2) (*Config, error) {
+	for _, externalModuleConfig := range externalConfig.Modules {
+		if externalModuleConfig.Path == "" {
+			return nil, errors.New("modules in a v2 configuration must set a path")
+		}
+		if externalModuleConfig.Name != "" {
+			if _, err := bufmoduleref.ModuleIdentityForString(externalModuleConfig.Name); err != nil {
+				return nil, fmt.Errorf("invalid name for module %q: %w", externalModuleConfig.Path, err)
+			}
+		}
+		if _, err := bufmoduleconfig.NewConfigV1(
+			bufmoduleconfig.ExternalConfigV1{
+				Excludes: externalModuleConfig.Excludes,
+				Includes: externalModuleConfig.Includes,
+			},
+		); err != nil {
+			return nil, fmt.Errorf("invalid build configuration for module %q: %w", externalModuleConfig.Path, err)
+		}
+	}
+	config, err := newConfigV1(
+		ExternalConfigV1{
+			Version:               V1Version,
+			Deps:                  externalConfig.Deps,
+			Extends:               externalConfig.Extends,
+			Breaking:              externalConfig.Breaking,
+			Lint:                  externalConfig.Lint,
+			Format:                externalConfig.Format,
+			DependencyConstraints: externalConfig.DependencyConstraints,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	config.Version = V2Version
+	return config, nil
+}
+
+func newDependencyConstraintsV1(
+	externalDependencyConstraints map[string]ExternalDependencyConstraintV1,
+	dependencyModuleReferences []bufmoduleref.ModuleReference,
+) (map[string]*DependencyConstraint, error) {
+	if len(externalDependencyConstraints) == 0 {
+		return nil, nil
+	}
+	identityToModuleReference := make(map[string]bufmoduleref.ModuleReference, len(dependencyModuleReferences))
+	for _, moduleReference := range dependencyModuleReferences {
+		identityToModuleReference[moduleReference.IdentityString()] = moduleReference
+	}
+	dependencyConstraints := make(map[string]*DependencyConstraint, len(externalDependencyConstraints))
+	for identity, externalDependencyConstraint := range externalDependencyConstraints {
+		moduleReference, ok := identityToModuleReference[identity]
+		if !ok {
+			return nil, fmt.Errorf("dependency_constraints has %q, which is not in deps", identity)
+		}
+		var numSet int
+		for _, set := range []bool{
+			externalDependencyConstraint.Label != "",
+			externalDependencyConstraint.Draft != "",
+			externalDependencyConstraint.Exclude,
+		} {
+			if set {
+				numSet++
+			}
+		}
+		if numSet != 1 {
+			return nil, fmt.Errorf("dependency_constraints for %q must set exactly one of label, draft, or exclude", identity)
+		}
+		if !externalDependencyConstraint.Exclude && moduleReference.Reference() != bufmoduleref.Main {
+			return nil, fmt.Errorf("dependency_constraints for %q cannot set a label or draft, as deps has the reference %q", identity, moduleReference.Reference())
+		}
+		dependencyConstraints[identity] = &DependencyConstraint{
+			Label:   externalDependencyConstraint.Label,
+			Draft:   externalDependencyConstraint.Draft,
+			Exclude: externalDependencyConstraint.Exclude,
+		}
+	}
+	return dependencyConstraints, nil
+}
+
+func newFormatConfigV1(externalFormatConfig ExternalFormatConfigV1) (*FormatConfig, error) {
+	if externalFormatConfig.Indent < 0 {
+		return nil, fmt.Errorf("format indent must not be negative, but was %d", externalFormatConfig.Indent)
+	}
+	if externalFormatConfig.MaxLineWidth < 0 {
+		return nil, fmt.Errorf("format max_line_width must not be negative, but was %d", externalFormatConfig.MaxLineWidth)
+	}
+	return &FormatConfig{
+		Indent:          externalFormatConfig.Indent,
+		UseTabs:         externalFormatConfig.UseTabs,
+		MaxLineWidth:    externalFormatConfig.MaxLineWidth,
+		GroupImports:    externalFormatConfig.GroupImports,
+		AlignFields:     externalFormatConfig.AlignFields,
+		CanonicalOrder:  externalFormatConfig.CanonicalOrder,
+		SortEnumValues:  externalFormatConfig.SortEnumValues,
+		LineDocComments: externalFormatConfig.LineDocComments,
+		CommentSpace:    externalFormatConfig.CommentSpace,
+		ReflowComments:  externalFormatConfig.ReflowComments,
+	}, nil
+}
+
+func externalConfigV1ForConfig(config *Config) (ExternalConfigV1, error) {
+	if config.Version != V1Version {
+		return ExternalConfigV1{}, fmt.Errorf("expected a %s configuration but got %s, see buf beta migrate-v1beta1 to migrate", V1Version, config.Version)
+	}
+	externalConfig := ExternalConfigV1{
+		Version: config.Version,
+	}
+	if config.ModuleIdentity != nil {
+		externalConfig.Name = config.ModuleIdentity.IdentityString()
+	}
+	if config.Build != nil {
+		for _, dependencyModuleReference := range config.Build.DependencyModuleReferences {
+			externalConfig.Deps = append(externalConfig.Deps, dependencyModuleReference.String())
+		}
+		// v1 configurations only have the root ".".
+		externalConfig.Build.Excludes = config.Build.RootToExcludes["."]
+		externalConfig.Build.Includes = config.Build.Includes
+	}
+	if config.Breaking != nil {
+		externalConfig.Breaking = bufbreakingconfig.ExternalConfigV1ForConfig(config.Breaking)
+	}
+	if config.Lint != nil {
+		externalConfig.Lint = buflintconfig.ExternalConfigV1ForConfig(config.Lint)
+	}
+	if config.Format != nil {
+		externalConfig.Format = ExternalFormatConfigV1{
+			Indent:          config.Format.Indent,
+			UseTabs:         config.Format.UseTabs,
+			MaxLineWidth:    config.Format.MaxLineWidth,
+			GroupImports:    config.Format.GroupImports,
+			AlignFields:     config.Format.AlignFields,
+			CanonicalOrder:  config.Format.CanonicalOrder,
+			SortEnumValues:  config.Format.SortEnumValues,
+			LineDocComments: config.Format.LineDocComments,
+			CommentSpace:    config.Format.CommentSpace,
+			ReflowComments:  config.Format.ReflowComments,
+		}
+	}
+	if len(config.DependencyConstraints) > 0 {
+		externalConfig.DependencyConstraints = make(map[string]ExternalDependencyConstraintV1, len(config.DependencyConstraints))
+		for identity, dependencyConstraint := range config.DependencyConstraints {
+			externalConfig.DependencyConstraints[identity] = ExternalDependencyConstraintV1{
+				Label:   dependencyConstraint.Label,
+				Draft:   dependencyConstraint.Draft,
+				Exclude: dependencyConstraint.Exclude,
+			}
+		}
+	}
+	return externalConfig, nil
+}
+
+// extendsModuleReferenceForString returns the ModuleReference of the module that
+// a configuration extends.
+//
+// The module reference may be followed by the configuration file of the module,
+// such as buf.build/acme/standards:buf.yaml. Modules only store their buf.yaml,
+// so this is the only configuration file that can be extended.
+func extendsModuleReferenceForString(extends string) (bufmoduleref.ModuleReference, error) {
+	moduleReferenceString := extends
+	if index := strings.LastIndex(extends, ":"); index >= 0 {
+		if filePath := extends[index+1:]; strings.HasSuffix(filePath, ".yaml") || strings.HasSuffix(filePath, ".yml") {
+			if filePath != ExternalConfigV1FilePath {
+				return nil, fmt.Errorf(
+					"cannot extend %s of %s, modules only store their %s",
+					filePath,
+					extends[:index],
+					ExternalConfigV1FilePath,
+				)
+			}
+			moduleReferenceString = extends[:index]
+		}
+	}
+	return bufmoduleref.ModuleReferenceForString(moduleReferenceString)
+}
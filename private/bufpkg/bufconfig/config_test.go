@@ -0,0 +1,439 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconfig
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencyConstraints(t *testing.T) {
+	t.Parallel()
+	config, err := GetConfigForData(
+		context.Background(),
+		[]byte(`version: v1
+deps:
+  - buf.build/acme/weather
+  - buf.build/acme/petapis
+  - buf.build/acme/paymentapis
+  - buf.build/acme/other
+dependency_constraints:
+  buf.build/acme/weather:
+    label: v1
+  buf.build/acme/petapis:
+    draft: feature
+  buf.build/acme/paymentapis:
+    exclude: true
+`),
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string]*DependencyConstraint{
+			"buf.build/acme/weather": {
+				Label: "v1",
+			},
+			"buf.build/acme/petapis": {
+				Draft: "feature",
+			},
+			"buf.build/acme/paymentapis": {
+				Exclude: true,
+			},
+		},
+		config.DependencyConstraints,
+	)
+	assert.Equal(t, "v1", config.DependencyConstraints["buf.build/acme/weather"].Reference())
+	assert.Equal(t, "feature", config.DependencyConstraints["buf.build/acme/petapis"].Reference())
+	testDependencyConstraintsError(
+		t,
+		`version: v1
+deps:
+  - buf.build/acme/weather
+dependency_constraints:
+  buf.build/acme/petapis:
+    label: v1
+`,
+		`dependency_constraints has "buf.build/acme/petapis", which is not in deps`,
+	)
+	testDependencyConstraintsError(
+		t,
+		`version: v1
+deps:
+  - buf.build/acme/weather
+dependency_constraints:
+  buf.build/acme/weather:
+    label: v1
+    exclude: true
+`,
+		`dependency_constraints for "buf.build/acme/weather" must set exactly one of label, draft, or exclude`,
+	)
+	testDependencyConstraintsError(
+		t,
+		`version: v1
+deps:
+  - buf.build/acme/weather:v2
+dependency_constraints:
+  buf.build/acme/weather:
+    label: v1
+`,
+		`dependency_constraints for "buf.build/acme/weather" cannot set a label or draft, as deps has the reference "v2"`,
+	)
+}
+
+func TestReadConfigOSWithDefaults(t *testing.T) {
+	t.Parallel()
+	defaults := &ConfigDefaults{
+		Build: map[string]interface{}{
+			"excludes": []interface{}{"vendor"},
+		},
+		Breaking: map[string]interface{}{
+			"use": []interface{}{"WIRE"},
+		},
+		Lint: map[string]interface{}{
+			"use":                    []interface{}{"DEFAULT"},
+			"except":                 []interface{}{"PACKAGE_VERSION_SUFFIX"},
+			"enum_zero_value_suffix": "_NONE",
+		},
+	}
+	config, err := ReadConfigOS(
+		context.Background(),
+		nil,
+		ReadConfigOSWithOverride(`version: v1
+lint:
+  except: []
+  enum_zero_value_suffix: _UNKNOWN
+`),
+		ReadConfigOSWithDefaults(defaults),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{".": {"vendor"}}, config.Build.RootToExcludes)
+	assert.Equal(t, []string{"WIRE"}, config.Breaking.Use)
+	assert.Equal(t, []string{"DEFAULT"}, config.Lint.Use)
+	assert.Empty(t, config.Lint.Except)
+	assert.Equal(t, "_UNKNOWN", config.Lint.EnumZeroValueSuffix)
+	// The defaults also apply if there is no configuration file.
+	readBucket, err := storagemem.NewReadBucket(nil)
+	require.NoError(t, err)
+	config, err = ReadConfigOS(
+		context.Background(),
+		readBucket,
+		ReadConfigOSWithDefaults(defaults),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PACKAGE_VERSION_SUFFIX"}, config.Lint.Except)
+	externalConfig, err := ExternalConfigV1ForConfig(config)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vendor"}, externalConfig.Build.Excludes)
+	assert.Equal(t, []string{"WIRE"}, externalConfig.Breaking.Use)
+	assert.Equal(t, "_NONE", externalConfig.Lint.EnumZeroValueSuffix)
+}
+
+func TestReadConfigOSWithProfile(t *testing.T) {
+	t.Parallel()
+	data := `version: v1
+breaking:
+  use:
+    - FILE
+lint:
+  use:
+    - DEFAULT
+  except:
+    - PACKAGE_VERSION_SUFFIX
+profiles:
+  strict:
+    breaking:
+      use:
+        - WIRE_JSON
+    lint:
+      except: []
+      enum_zero_value_suffix: _UNKNOWN
+  local: {}
+`
+	defaults := &ConfigDefaults{
+		Lint: map[string]interface{}{
+			"enum_zero_value_suffix": "_NONE",
+		},
+	}
+	config, err := ReadConfigOS(
+		context.Background(),
+		nil,
+		ReadConfigOSWithOverride(data),
+		ReadConfigOSWithDefaults(defaults),
+		ReadConfigOSWithProfile("strict"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"WIRE_JSON"}, config.Breaking.Use)
+	assert.Equal(t, []string{"DEFAULT"}, config.Lint.Use)
+	assert.Empty(t, config.Lint.Except)
+	assert.Equal(t, "_UNKNOWN", config.Lint.EnumZeroValueSuffix)
+	config, err = ReadConfigOS(
+		context.Background(),
+		nil,
+		ReadConfigOSWithOverride(data),
+		ReadConfigOSWithDefaults(defaults),
+		ReadConfigOSWithProfile("local"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"FILE"}, config.Breaking.Use)
+	assert.Equal(t, []string{"PACKAGE_VERSION_SUFFIX"}, config.Lint.Except)
+	assert.Equal(t, "_NONE", config.Lint.EnumZeroValueSuffix)
+	_, err = ReadConfigOS(
+		context.Background(),
+		nil,
+		ReadConfigOSWithOverride(data),
+		ReadConfigOSWithProfile("ci"),
+	)
+	assert.ErrorContains(t, err, `profile "ci" is not defined, the defined profiles are: local, strict`)
+	// Configurations that do not define any profiles are unaffected.
+	config, err = ReadConfigOS(
+		context.Background(),
+		nil,
+		ReadConfigOSWithOverride(`version: v1
+lint:
+  use:
+    - MINIMAL
+`),
+		ReadConfigOSWithProfile("ci"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MINIMAL"}, config.Lint.Use)
+}
+
+func TestReadConfigOSWithExtendsResolver(t *testing.T) {
+	t.Parallel()
+	extendsResolver := &testExtendsResolver{
+		identityToConfigDefaults: map[string]*ConfigDefaults{
+			"buf.build/acme/style": {
+				Breaking: map[string]interface{}{
+					"use": []interface{}{"WIRE"},
+				},
+				Lint: map[string]interface{}{
+					"use":                    []interface{}{"DEFAULT"},
+					"enum_zero_value_suffix": "_NONE",
+				},
+			},
+		},
+	}
+	defaults := &ConfigDefaults{
+		Lint: map[string]interface{}{
+			"use":    []interface{}{"MINIMAL"},
+			"except": []interface{}{"PACKAGE_VERSION_SUFFIX"},
+		},
+	}
+	config, err := ReadConfigOS(
+		context.Background(),
+		nil,
+		ReadConfigOSWithOverride(`version: v1
+extends: buf.build/acme/style
+lint:
+  enum_zero_value_suffix: _UNKNOWN
+`),
+		ReadConfigOSWithDefaults(defaults),
+		ReadConfigOSWithExtendsResolver(extendsResolver),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, config.Extends)
+	assert.Equal(t, "buf.build/acme/style", config.Extends.IdentityString())
+	assert.Equal(t, []string{"WIRE"}, config.Breaking.Use)
+	assert.Equal(t, []string{"DEFAULT"}, config.Lint.Use)
+	assert.Equal(t, []string{"PACKAGE_VERSION_SUFFIX"}, config.Lint.Except)
+	assert.Equal(t, "_UNKNOWN", config.Lint.EnumZeroValueSuffix)
+	_, err = ReadConfigOS(
+		context.Background(),
+		nil,
+		ReadConfigOSWithOverride(`version: v1
+extends: buf.build/acme/other
+`),
+		ReadConfigOSWithExtendsResolver(extendsResolver),
+	)
+	assert.ErrorContains(t, err, `unknown module "buf.build/acme/other"`)
+	_, err = GetConfigForData(
+		context.Background(),
+		[]byte(`version: v1
+extends: acme/style
+`),
+	)
+	assert.ErrorContains(t, err, "invalid extends")
+}
+
+func TestGetConfigForDataExtends(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		extends           string
+		expectedReference string
+		expectedError     string
+	}{
+		{
+			extends:           "buf.build/acme/style",
+			expectedReference: "buf.build/acme/style",
+		},
+		{
+			extends:           "buf.build/acme/style:v1",
+			expectedReference: "buf.build/acme/style:v1",
+		},
+		{
+			extends:           "buf.build/acme/style:buf.yaml",
+			expectedReference: "buf.build/acme/style",
+		},
+		{
+			extends:           "buf.build/acme/style:v1:buf.yaml",
+			expectedReference: "buf.build/acme/style:v1",
+		},
+		{
+			extends:       "buf.build/acme/style:config.yaml",
+			expectedError: "cannot extend config.yaml of buf.build/acme/style, modules only store their buf.yaml",
+		},
+		{
+			extends:       "buf.build/acme/style:v1:lint.yml",
+			expectedError: "cannot extend lint.yml of buf.build/acme/style:v1, modules only store their buf.yaml",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.extends, func(t *testing.T) {
+			t.Parallel()
+			config, err := GetConfigForData(
+				context.Background(),
+				[]byte("version: v1\nextends: "+testCase.extends+"\n"),
+			)
+			if testCase.expectedError != "" {
+				assert.ErrorContains(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, config.Extends)
+			assert.Equal(t, testCase.expectedReference, config.Extends.String())
+		})
+	}
+}
+
+func TestReadConfigOSWithEnvContainer(t *testing.T) {
+	t.Parallel()
+	envContainer := app.NewEnvContainer(
+		map[string]string{
+			"BUF_REMOTE": "buf.example.com",
+			"VENDOR_DIR": "vendor",
+		},
+	)
+	config, err := ReadConfigOS(
+		context.Background(),
+		nil,
+		ReadConfigOSWithOverride(`version: v1
+deps:
+  - ${BUF_REMOTE}/acme/weather
+  - ${OTHER_REMOTE:-buf.build}/acme/other
+lint:
+  ignore_only:
+    ENUM_ZERO_VALUE_SUFFIX:
+      - ${VENDOR_DIR}/a
+`),
+		ReadConfigOSWithDefaults(
+			&ConfigDefaults{
+				Breaking: map[string]interface{}{
+					"ignore": []interface{}{"${VENDOR_DIR}"},
+				},
+			},
+		),
+		ReadConfigOSWithEnvContainer(envContainer),
+	)
+	require.NoError(t, err)
+	require.Len(t, config.Build.DependencyModuleReferences, 2)
+	assert.Equal(t, "buf.example.com/acme/weather", config.Build.DependencyModuleReferences[0].IdentityString())
+	assert.Equal(t, "buf.build/acme/other", config.Build.DependencyModuleReferences[1].IdentityString())
+	assert.Equal(t, []string{"vendor"}, config.Breaking.IgnoreRootPaths)
+	assert.Equal(t, map[string][]string{"ENUM_ZERO_VALUE_SUFFIX": {"vendor/a"}}, config.Lint.IgnoreIDOrCategoryToRootPaths)
+	_, err = GetConfigForData(
+		context.Background(),
+		[]byte(`version: v1
+deps:
+  - ${BUF_REMOTE}/acme/weather
+`),
+		GetConfigWithEnvContainer(app.NewEnvContainer(nil)),
+	)
+	assert.ErrorContains(t, err, "line 3: environment variable BUF_REMOTE is not set")
+}
+
+func TestGetConfigForDataV2(t *testing.T) {
+	t.Parallel()
+	config, err := GetConfigForData(
+		context.Background(),
+		[]byte(`version: v2
+modules:
+  - path: proto/a
+    name: buf.build/acme/a
+    excludes:
+      - internal
+  - path: proto/b
+deps:
+  - buf.build/acme/c
+lint:
+  use:
+    - DEFAULT
+`),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, V2Version, config.Version)
+	require.Len(t, config.Build.DependencyModuleReferences, 1)
+	assert.Equal(t, "buf.build/acme/c", config.Build.DependencyModuleReferences[0].IdentityString())
+	assert.Equal(t, []string{"DEFAULT"}, config.Lint.Use)
+	_, err = GetConfigForData(
+		context.Background(),
+		[]byte(`version: v2
+modules:
+  - name: buf.build/acme/a
+`),
+	)
+	require.Error(t, err)
+	_, err = GetConfigForData(
+		context.Background(),
+		[]byte(`version: v2
+modules:
+  - path: proto/a
+    build:
+      excludes:
+        - internal
+`),
+	)
+	require.Error(t, err)
+}
+
+func testDependencyConstraintsError(t *testing.T, data string, expectedError string) {
+	_, err := GetConfigForData(context.Background(), []byte(data))
+	assert.ErrorContains(t, err, expectedError)
+}
+
+type testExtendsResolver struct {
+	identityToConfigDefaults map[string]*ConfigDefaults
+}
+
+func (r *testExtendsResolver) GetExtendsConfigDefaults(
+	_ context.Context,
+	_ storage.ReadBucket,
+	moduleReference bufmoduleref.ModuleReference,
+) (*ConfigDefaults, error) {
+	configDefaults, ok := r.identityToConfigDefaults[moduleReference.IdentityString()]
+	if !ok {
+		return nil, fmt.Errorf("unknown module %q", moduleReference.IdentityString())
+	}
+	return configDefaults, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconfig
+
+import (
+	"fmt"
+
+	"github.com/bufbuild/buf/private/pkg/encoding"
+)
+
+// applyConfigDefaults returns the given v1 configuration data with the keys of
+// its build, breaking, and lint sections that are not set set to the defaults.
+func applyConfigDefaults(
+	data []byte,
+	unmarshalNonStrict func([]byte, interface{}) error,
+	defaults *ConfigDefaults,
+) ([]byte, error) {
+	externalConfig := make(map[string]interface{})
+	if err := unmarshalNonStrict(data, &externalConfig); err != nil {
+		return nil, err
+	}
+	for _, section := range []struct {
+		name     string
+		defaults map[string]interface{}
+	}{
+		{name: "build", defaults: defaults.Build},
+		{name: "breaking", defaults: defaults.Breaking},
+		{name: "lint", defaults: defaults.Lint},
+	} {
+		if len(section.defaults) == 0 {
+			continue
+		}
+		var externalSection map[string]interface{}
+		if value, ok := externalConfig[section.name]; ok && value != nil {
+			externalSection, ok = value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s must be a map to apply the workspace defaults, but was %T", section.name, value)
+			}
+		} else {
+			externalSection = make(map[string]interface{}, len(section.defaults))
+		}
+		for key, value := range section.defaults {
+			if _, ok := externalSection[key]; !ok {
+				externalSection[key] = value
+			}
+		}
+		externalConfig[section.name] = externalSection
+	}
+	return encoding.MarshalYAML(externalConfig)
+}
+
+// mergeConfigDefaults returns the defaults with the keys of each section of the
+// given defaults, where the keys of the primary defaults take precedence.
+//
+// Either of the defaults may be nil.
+func mergeConfigDefaults(primary *ConfigDefaults, secondary *ConfigDefaults) *ConfigDefaults {
+	if primary == nil {
+		return secondary
+	}
+	if secondary == nil {
+		return primary
+	}
+	return &ConfigDefaults{
+		Build:    mergeConfigDefaultsSection(primary.Build, secondary.Build),
+		Breaking: mergeConfigDefaultsSection(primary.Breaking, secondary.Breaking),
+		Lint:     mergeConfigDefaultsSection(primary.Lint, secondary.Lint),
+	}
+}
+
+func mergeConfigDefaultsSection(primary map[string]interface{}, secondary map[string]interface{}) map[string]interface{} {
+	if len(primary) == 0 {
+		return secondary
+	}
+	if len(secondary) == 0 {
+		return primary
+	}
+	section := make(map[string]interface{}, len(primary)+len(secondary))
+	for key, value := range secondary {
+		section[key] = value
+	}
+	for key, value := range primary {
+		section[key] = value
+	}
+	return section
+}
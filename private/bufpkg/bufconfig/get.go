@@ -19,7 +19,9 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/envexpand"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"go.opentelemetry.io/otel"
@@ -27,7 +29,28 @@ import (
 	"go.uber.org/multierr"
 )
 
-func getConfigForBucket(ctx context.Context, readBucket storage.ReadBucket) (_ *Config, retErr error) {
+// expandedExternalConfigPaths are the paths of the values of configurations that
+// environment variables are expanded in.
+var expandedExternalConfigPaths = [][]string{
+	{"deps", "*"},
+	{"extends"},
+	{"breaking", "ignore", "*"},
+	{"breaking", "ignore_only", "*", "*"},
+	{"lint", "ignore", "*"},
+	{"lint", "ignore_only", "*", "*"},
+	{"modules", "*", "breaking", "ignore", "*"},
+	{"modules", "*", "breaking", "ignore_only", "*", "*"},
+	{"modules", "*", "lint", "ignore", "*"},
+	{"modules", "*", "lint", "ignore_only", "*", "*"},
+}
+
+func getConfigForBucket(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	defaults *ConfigDefaults,
+	profile string,
+	envContainer app.EnvContainer,
+) (_ *Config, retErr error) {
 	ctx, span := otel.GetTracerProvider().Tracer("bufbuild/buf").Start(ctx, "get_config")
 	defer span.End()
 	defer func() {
@@ -54,6 +77,18 @@ func getConfigForBucket(ctx context.Context, readBucket storage.ReadBucket) (_ *
 	switch len(foundConfigFilePaths) {
 	case 0:
 		// Did not find anything, return the default.
+		if defaults != nil {
+			return getConfigForDataInternal(
+				ctx,
+				encoding.UnmarshalYAMLNonStrict,
+				encoding.UnmarshalYAMLStrict,
+				[]byte("version: "+V1Version),
+				"Default configuration",
+				defaults,
+				"",
+				nil,
+			)
+		}
 		return newConfigV1(ExternalConfigV1{})
 	case 1:
 		readObjectCloser, err := readBucket.Get(ctx, foundConfigFilePaths[0])
@@ -73,13 +108,22 @@ func getConfigForBucket(ctx context.Context, readBucket storage.ReadBucket) (_ *
 			encoding.UnmarshalYAMLStrict,
 			data,
 			readObjectCloser.ExternalPath(),
+			defaults,
+			profile,
+			envContainer,
 		)
 	default:
 		return nil, fmt.Errorf("only one configuration file can exist but found multiple configuration files: %s", stringutil.SliceToString(foundConfigFilePaths))
 	}
 }
 
-func getConfigForData(ctx context.Context, data []byte) (*Config, error) {
+func getConfigForData(
+	ctx context.Context,
+	data []byte,
+	defaults *ConfigDefaults,
+	profile string,
+	envContainer app.EnvContainer,
+) (*Config, error) {
 	_, span := otel.GetTracerProvider().Tracer("bufbuild/buf").Start(ctx, "get_config_for_data")
 	defer span.End()
 	config, err := getConfigForDataInternal(
@@ -88,6 +132,9 @@ func getConfigForData(ctx context.Context, data []byte) (*Config, error) {
 		encoding.UnmarshalJSONOrYAMLStrict,
 		data,
 		"Configuration data",
+		defaults,
+		profile,
+		envContainer,
 	)
 	if err != nil {
 		span.RecordError(err)
@@ -102,6 +149,9 @@ func getConfigForDataInternal(
 	unmarshalStrict func([]byte, interface{}) error,
 	data []byte,
 	id string,
+	defaults *ConfigDefaults,
+	profile string,
+	envContainer app.EnvContainer,
 ) (*Config, error) {
 	var externalConfigVersion ExternalConfigVersion
 	if err := unmarshalNonStrict(data, &externalConfigVersion); err != nil {
@@ -117,11 +167,45 @@ func getConfigForDataInternal(
 		}
 		return newConfigV1Beta1(externalConfigV1Beta1)
 	case V1Version:
+		if profile != "" {
+			var err error
+			data, err = applyConfigProfile(data, unmarshalNonStrict, id, profile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if defaults != nil {
+			var err error
+			data, err = applyConfigDefaults(data, unmarshalNonStrict, defaults)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// The environment variables are expanded last, so that they are also
+		// expanded in the profile and the defaults.
+		var err error
+		data, err = expandExternalConfigEnv(data, id, envContainer)
+		if err != nil {
+			return nil, err
+		}
 		var externalConfigV1 ExternalConfigV1
 		if err := unmarshalStrict(data, &externalConfigV1); err != nil {
 			return nil, err
 		}
 		return newConfigV1(externalConfigV1)
+	case V2Version:
+		// The defaults and profiles are only for the modules that a v2 configuration
+		// defines, and so do not apply to it.
+		var err error
+		data, err = expandExternalConfigEnv(data, id, envContainer)
+		if err != nil {
+			return nil, err
+		}
+		var externalConfigV2 ExternalConfigV2
+		if err := unmarshalStrict(data, &externalConfigV2); err != nil {
+			return nil, err
+		}
+		return newConfigV2(externalConfigV2)
 	default:
 		return nil, fmt.Errorf(
 			`%s has an invalid "version: %s" set. Please add "version: %s". See https://docs.buf.build/faq for more details`,
@@ -131,3 +215,24 @@ func getConfigForDataInternal(
 		)
 	}
 }
+
+type getConfigOptions struct {
+	envContainer app.EnvContainer
+}
+
+func newGetConfigOptions() *getConfigOptions {
+	return &getConfigOptions{}
+}
+
+// expandExternalConfigEnv expands the environment variables in the configuration
+// data, if the EnvContainer is not nil.
+func expandExternalConfigEnv(data []byte, id string, envContainer app.EnvContainer) ([]byte, error) {
+	if envContainer == nil {
+		return data, nil
+	}
+	expandedData, err := envexpand.ExpandYAML(data, envContainer, expandedExternalConfigPaths...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	return expandedData, nil
+}
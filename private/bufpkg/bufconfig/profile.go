@@ -0,0 +1,87 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/encoding"
+)
+
+// applyConfigProfile returns the given v1 configuration data with the keys of
+// its breaking and lint sections overridden by the keys of the given profile.
+//
+// The data is returned as is if it does not define any profiles.
+func applyConfigProfile(
+	data []byte,
+	unmarshalNonStrict func([]byte, interface{}) error,
+	id string,
+	profile string,
+) ([]byte, error) {
+	externalConfig := make(map[string]interface{})
+	if err := unmarshalNonStrict(data, &externalConfig); err != nil {
+		return nil, err
+	}
+	value, ok := externalConfig["profiles"]
+	if !ok || value == nil {
+		return data, nil
+	}
+	externalProfiles, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: profiles must be a map, but was %T", id, value)
+	}
+	value, ok = externalProfiles[profile]
+	if !ok {
+		profiles := make([]string, 0, len(externalProfiles))
+		for profile := range externalProfiles {
+			profiles = append(profiles, profile)
+		}
+		sort.Strings(profiles)
+		return nil, fmt.Errorf("%s: profile %q is not defined, the defined profiles are: %s", id, profile, strings.Join(profiles, ", "))
+	}
+	if value == nil {
+		return data, nil
+	}
+	externalProfile, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: profile %q must be a map, but was %T", id, profile, value)
+	}
+	for _, sectionName := range []string{"breaking", "lint"} {
+		value, ok := externalProfile[sectionName]
+		if !ok || value == nil {
+			continue
+		}
+		externalProfileSection, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: %s of profile %q must be a map, but was %T", id, sectionName, profile, value)
+		}
+		var externalSection map[string]interface{}
+		if value, ok := externalConfig[sectionName]; ok && value != nil {
+			externalSection, ok = value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must be a map to apply profile %q, but was %T", id, sectionName, profile, value)
+			}
+		} else {
+			externalSection = make(map[string]interface{}, len(externalProfileSection))
+		}
+		for key, value := range externalProfileSection {
+			externalSection[key] = value
+		}
+		externalConfig[sectionName] = externalSection
+	}
+	return encoding.MarshalYAML(externalConfig)
+}
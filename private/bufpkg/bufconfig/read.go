@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/storage"
 )
 
@@ -32,6 +33,32 @@ func readConfigOS(
 	for _, option := range options {
 		option(readConfigOSOptions)
 	}
+	config, err := readConfigOSForDefaults(ctx, readBucket, readConfigOSOptions, readConfigOSOptions.defaults)
+	if err != nil {
+		return nil, err
+	}
+	if config.Version != V1Version || config.Extends == nil || readConfigOSOptions.extendsResolver == nil {
+		return config, nil
+	}
+	extendsDefaults, err := readConfigOSOptions.extendsResolver.GetExtendsConfigDefaults(ctx, readBucket, config.Extends)
+	if err != nil {
+		return nil, err
+	}
+	// The configuration is read again with the configuration that it extends beneath it.
+	return readConfigOSForDefaults(
+		ctx,
+		readBucket,
+		readConfigOSOptions,
+		mergeConfigDefaults(extendsDefaults, readConfigOSOptions.defaults),
+	)
+}
+
+func readConfigOSForDefaults(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	readConfigOSOptions *readConfigOSOptions,
+	defaults *ConfigDefaults,
+) (*Config, error) {
 	if readConfigOSOptions.override != "" {
 		var data []byte
 		var err error
@@ -44,13 +71,17 @@ func readConfigOS(
 		default:
 			data = []byte(readConfigOSOptions.override)
 		}
-		return GetConfigForData(ctx, data)
+		return getConfigForData(ctx, data, defaults, readConfigOSOptions.profile, readConfigOSOptions.envContainer)
 	}
-	return GetConfigForBucket(ctx, readBucket)
+	return getConfigForBucket(ctx, readBucket, defaults, readConfigOSOptions.profile, readConfigOSOptions.envContainer)
 }
 
 type readConfigOSOptions struct {
-	override string
+	override        string
+	defaults        *ConfigDefaults
+	profile         string
+	extendsResolver ExtendsResolver
+	envContainer    app.EnvContainer
 }
 
 func newReadConfigOSOptions() *readConfigOSOptions {
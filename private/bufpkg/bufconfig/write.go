@@ -71,6 +71,18 @@ build:
   {{if not .Uncomment}}#{{end}}  - foo
   {{if not .Uncomment}}#{{end}}  - bar/baz
 
+  # includes is the list of globs of the files to include.
+  #
+  # If includes is set, only the files that match at least one of the globs
+  # are built or checked. Files that are excluded are never included.
+  #
+  # All globs in includes must be relative to the directory of your buf.yaml.
+  # Each path component is matched with the syntax of Go's path.Match, and
+  # "**" matches zero or more directories, ie "**/v1/**" includes only the
+  # files within v1 directories.
+  {{if not .Uncomment}}#{{end}}includes:
+  {{if not .Uncomment}}#{{end}}  - "**/v1/**"
+
 # lint contains the options for lint rules.
 lint:
 
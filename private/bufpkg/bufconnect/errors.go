@@ -16,6 +16,9 @@ package bufconnect
 
 import "errors"
 
+// ErrOffline is returned for requests when network access is disabled.
+var ErrOffline = errors.New("network access is disabled")
+
 // AuthError wraps the error returned in the auth provider to add additional context.
 type AuthError struct {
 	cause error
@@ -0,0 +1,175 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconnect
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+)
+
+const (
+	// retryMaxAttempts is the maximum number of attempts of a request,
+	// including the first.
+	retryMaxAttempts = 5
+	// retryInitialBackoff is the backoff before the first retry, which is
+	// doubled for each retry after it, up to retryMaxBackoff.
+	retryInitialBackoff = 500 * time.Millisecond
+	retryMaxBackoff     = 8 * time.Second
+	// retryMaxDelay is the longest delay requested by a server that is waited
+	// for. Requests are not retried if the server requests a longer delay.
+	retryMaxDelay = time.Minute
+
+	retryAfterHeaderName     = "Retry-After"
+	rateLimitResetHeaderName = "RateLimit-Reset"
+)
+
+// NewRetryInterceptor returns a new Connect Interceptor that retries requests
+// that fail with transient errors, such as when the server is rate limiting
+// requests or is unavailable.
+//
+// Requests are retried with exponential backoff and jitter, unless the server
+// returns a Retry-After or RateLimit-Reset header, in which case the retry is
+// delayed for as long as it requests. Errors that may be returned after the
+// server processed the request, such as internal errors, are only retried for
+// procedures that are idempotent.
+//
+// Each retry is printed to the verbose printer.
+func NewRetryInterceptor(verbosePrinter verbose.Printer) connect.UnaryInterceptorFunc {
+	return newRetryInterceptor(verbosePrinter, sleep, jitter)
+}
+
+func newRetryInterceptor(
+	verbosePrinter verbose.Printer,
+	sleep func(context.Context, time.Duration) error,
+	jitter func(time.Duration) time.Duration,
+) connect.UnaryInterceptorFunc {
+	interceptor := func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			backoff := retryInitialBackoff
+			for attempt := 1; ; attempt++ {
+				resp, err := next(ctx, req)
+				if err == nil || attempt == retryMaxAttempts || ctx.Err() != nil {
+					return resp, err
+				}
+				delay, ok := retryDelay(req.Spec(), err)
+				if !ok {
+					return resp, err
+				}
+				if delay == 0 {
+					delay = jitter(backoff)
+					backoff *= 2
+					if backoff > retryMaxBackoff {
+						backoff = retryMaxBackoff
+					}
+				}
+				verbosePrinter.Printf(
+					"retrying %s in %v after attempt %d of %d failed: %v",
+					req.Spec().Procedure,
+					delay.Round(time.Millisecond),
+					attempt,
+					retryMaxAttempts,
+					err,
+				)
+				if err := sleep(ctx, delay); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return interceptor
+}
+
+// retryDelay returns the delay before the request that failed with the given
+// error is retried, which is zero if the server did not request a delay, and
+// false if the request should not be retried.
+func retryDelay(spec connect.Spec, err error) (time.Duration, bool) {
+	connectErr := new(connect.Error)
+	if !errors.As(err, &connectErr) || errors.Is(err, ErrOffline) {
+		return 0, false
+	}
+	delay, hasDelay := serverRetryDelay(connectErr.Meta(), time.Now())
+	if hasDelay && delay > retryMaxDelay {
+		return 0, false
+	}
+	switch connectErr.Code() {
+	case connect.CodeUnavailable:
+		// HTTP 429, 502, 503, and 504 responses without a Connect error
+		// also have this code.
+		return delay, true
+	case connect.CodeResourceExhausted:
+		// This code is also used for requests that are too large, which
+		// are not transient, so it is only retried for rate limits.
+		return delay, hasDelay
+	case connect.CodeUnknown, connect.CodeInternal:
+		switch spec.IdempotencyLevel {
+		case connect.IdempotencyNoSideEffects, connect.IdempotencyIdempotent:
+			return delay, true
+		default:
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+}
+
+// serverRetryDelay returns the delay requested by the Retry-After or
+// RateLimit-Reset header, and false if neither is set.
+func serverRetryDelay(header http.Header, now time.Time) (time.Duration, bool) {
+	if value := strings.TrimSpace(header.Get(retryAfterHeaderName)); value != "" {
+		// The value is either a number of seconds or an HTTP date.
+		if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if retryTime, err := http.ParseTime(value); err == nil {
+			if delay := retryTime.Sub(now); delay > 0 {
+				return delay, true
+			}
+			return 0, true
+		}
+	}
+	if value := strings.TrimSpace(header.Get(rateLimitResetHeaderName)); value != "" {
+		if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, false
+}
+
+// jitter returns a random duration between half of the given duration and
+// the given duration, so that clients that fail at the same time do not retry
+// at the same time.
+func jitter(duration time.Duration) time.Duration {
+	half := duration / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
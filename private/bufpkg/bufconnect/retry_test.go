@@ -0,0 +1,138 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconnect
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRetryInterceptor(t *testing.T) {
+	t.Parallel()
+	rateLimitErr := connect.NewError(connect.CodeResourceExhausted, errors.New("rate limited"))
+	rateLimitErr.Meta().Set(retryAfterHeaderName, "3")
+	var delays []time.Duration
+	interceptor := newRetryInterceptor(
+		verbose.NopPrinter,
+		func(_ context.Context, delay time.Duration) error {
+			delays = append(delays, delay)
+			return nil
+		},
+		func(duration time.Duration) time.Duration {
+			return duration
+		},
+	)
+	errs := []error{
+		connect.NewError(connect.CodeUnavailable, errors.New("unavailable")),
+		rateLimitErr,
+		connect.NewError(connect.CodeUnavailable, errors.New("unavailable")),
+	}
+	attempts := 0
+	_, err := interceptor(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		attempts++
+		if attempts <= len(errs) {
+			return nil, errs[attempts-1]
+		}
+		return nil, nil
+	})(context.Background(), connect.NewRequest(&bytes.Buffer{}))
+	require.NoError(t, err)
+	assert.Equal(t, 4, attempts)
+	assert.Equal(t, []time.Duration{retryInitialBackoff, 3 * time.Second, 2 * retryInitialBackoff}, delays)
+
+	delays = nil
+	attempts = 0
+	_, err = interceptor(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		attempts++
+		return nil, connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+	})(context.Background(), connect.NewRequest(&bytes.Buffer{}))
+	assert.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+	assert.Equal(t, retryMaxAttempts, attempts)
+	assert.Len(t, delays, retryMaxAttempts-1)
+
+	attempts = 0
+	_, err = interceptor(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		attempts++
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid"))
+	})(context.Background(), connect.NewRequest(&bytes.Buffer{}))
+	assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryDelay(t *testing.T) {
+	t.Parallel()
+	testRetryDelay(t, connect.Spec{}, connect.NewError(connect.CodeUnavailable, errors.New("")), nil, 0, true)
+	testRetryDelay(t, connect.Spec{}, connect.NewError(connect.CodeResourceExhausted, errors.New("")), nil, 0, false)
+	testRetryDelay(t, connect.Spec{}, connect.NewError(connect.CodeResourceExhausted, errors.New("")), map[string]string{rateLimitResetHeaderName: "10"}, 10*time.Second, true)
+	testRetryDelay(t, connect.Spec{}, connect.NewError(connect.CodeUnavailable, errors.New("")), map[string]string{retryAfterHeaderName: "3600"}, 0, false)
+	testRetryDelay(t, connect.Spec{}, connect.NewError(connect.CodeInternal, errors.New("")), nil, 0, false)
+	testRetryDelay(t, connect.Spec{IdempotencyLevel: connect.IdempotencyNoSideEffects}, connect.NewError(connect.CodeInternal, errors.New("")), nil, 0, true)
+	testRetryDelay(t, connect.Spec{IdempotencyLevel: connect.IdempotencyIdempotent}, connect.NewError(connect.CodeUnknown, errors.New("")), nil, 0, true)
+	testRetryDelay(t, connect.Spec{}, connect.NewError(connect.CodeFailedPrecondition, ErrOffline), nil, 0, false)
+	testRetryDelay(t, connect.Spec{}, errors.New("not a connect error"), nil, 0, false)
+}
+
+func TestServerRetryDelay(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	testServerRetryDelay(t, now, map[string]string{retryAfterHeaderName: "5"}, 5*time.Second, true)
+	testServerRetryDelay(t, now, map[string]string{retryAfterHeaderName: now.Add(time.Minute).Format(http.TimeFormat)}, time.Minute, true)
+	testServerRetryDelay(t, now, map[string]string{retryAfterHeaderName: now.Add(-time.Minute).Format(http.TimeFormat)}, 0, true)
+	testServerRetryDelay(t, now, map[string]string{retryAfterHeaderName: "invalid", rateLimitResetHeaderName: "7"}, 7*time.Second, true)
+	testServerRetryDelay(t, now, map[string]string{rateLimitResetHeaderName: "-1"}, 0, false)
+	testServerRetryDelay(t, now, nil, 0, false)
+}
+
+func testRetryDelay(
+	t *testing.T,
+	spec connect.Spec,
+	err error,
+	headers map[string]string,
+	expectedDelay time.Duration,
+	expectedOK bool,
+) {
+	if connectErr := new(connect.Error); errors.As(err, &connectErr) {
+		for key, value := range headers {
+			connectErr.Meta().Set(key, value)
+		}
+	}
+	delay, ok := retryDelay(spec, err)
+	assert.Equal(t, expectedOK, ok, err.Error())
+	assert.Equal(t, expectedDelay, delay, err.Error())
+}
+
+func testServerRetryDelay(
+	t *testing.T,
+	now time.Time,
+	headers map[string]string,
+	expectedDelay time.Duration,
+	expectedOK bool,
+) {
+	header := make(http.Header)
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+	delay, ok := serverRetryDelay(header, now)
+	assert.Equal(t, expectedOK, ok)
+	assert.Equal(t, expectedDelay, delay)
+}
@@ -0,0 +1,76 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufdatagen generates random messages from the schema of their type.
+//
+// Values resemble real data where the schema allows it: strings are words, or
+// email addresses, UUIDs, URLs, and the like for fields whose names suggest them,
+// numbers are small, and timestamps are recent. Fields with presence are set most
+// of the time, repeated and map fields have a few elements, and one field of most
+// oneofs is set. Messages are nested up to a limited depth.
+//
+// The protovalidate constraints of fields are respected: values are drawn from
+// the ranges, lengths, formats, patterns, and sets of values that the constraints
+// allow, and required fields are always set. Constraints that values cannot be
+// drawn from directly, such as CEL expressions, are respected by generating
+// messages until one is valid, up to a limit.
+//
+// Serialized messages can also be mutated, which results in messages that are
+// likely invalid, for example to seed the corpus of a fuzzer.
+package bufdatagen
+
+import (
+	"math/rand"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Generator generates random messages of a message type.
+type Generator interface {
+	// Generate returns a new random message that is valid according to the
+	// protovalidate constraints of its type.
+	//
+	// The same sequence of random values results in the same message, unless the
+	// message has timestamp constraints relative to the current time.
+	//
+	// An error is returned if no valid message was generated within a limited
+	// number of attempts.
+	Generate(random *rand.Rand) (proto.Message, error)
+}
+
+// NewGenerator returns a new Generator for the message type.
+func NewGenerator(messageDescriptor protoreflect.MessageDescriptor) (Generator, error) {
+	return newGenerator(messageDescriptor)
+}
+
+// Mutator mutates serialized messages of a message type.
+type Mutator interface {
+	// Mutate returns a mutated copy of the serialized message.
+	//
+	// A single mutation is applied to the message or to one of the messages
+	// nested in it, such as a changed wire type, a length that overflows, an
+	// overlong varint, invalid UTF-8 in a string, an undefined enum value, a
+	// removed, duplicated, or unknown field, a truncation, or a flipped bit. The
+	// lengths of the messages that the mutated message is nested in are updated,
+	// so that the mutation is the only defect of the message.
+	//
+	// The same sequence of random values results in the same mutation.
+	Mutate(random *rand.Rand, data []byte) []byte
+}
+
+// NewMutator returns a new Mutator for the message type.
+func NewMutator(messageDescriptor protoreflect.MessageDescriptor) Mutator {
+	return newMutator(messageDescriptor)
+}
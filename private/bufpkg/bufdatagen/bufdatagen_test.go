@@ -0,0 +1,238 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdatagen
+
+import (
+	"context"
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufprotovalidate"
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "buf/validate/validate.proto";
+import "google/protobuf/duration.proto";
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+message Order {
+  option (buf.validate.message).cel = {
+    id: "order.quantity"
+    message: "quantity must be even"
+    expression: "this.quantity % 2 == 0"
+  };
+  string id = 1 [(buf.validate.field).string.uuid = true];
+  string email = 2 [(buf.validate.field).string.email = true];
+  string sku = 3 [(buf.validate.field).string.pattern = "^[A-Z]{3}-[0-9]{4}(-[a-z]+)?$"];
+  string name = 4 [(buf.validate.field).string = {min_len: 20, max_len: 30}];
+  string code = 5 [(buf.validate.field).string = {prefix: "ord_", len: 10}];
+  int32 quantity = 6 [(buf.validate.field).int32 = {gt: 0, lte: 10}];
+  double price = 7 [(buf.validate.field).double = {gte: 0.5, lt: 1}];
+  uint64 priority = 8 [(buf.validate.field).uint64 = {in: [1, 2, 3]}];
+  sint64 offset = 9 [(buf.validate.field).sint64 = {lt: -5000}];
+  Status status = 10 [(buf.validate.field).enum = {defined_only: true, not_in: [1]}];
+  repeated string tags = 11 [(buf.validate.field).repeated = {
+    min_items: 5
+    max_items: 6
+    unique: true
+    items: {string: {min_len: 3}}
+  }];
+  map<string, int64> counts = 12 [(buf.validate.field).map = {
+    min_pairs: 1
+    keys: {string: {max_len: 3}}
+    values: {int64: {gte: 100, lt: 200}}
+  }];
+  google.protobuf.Timestamp create_time = 13 [
+    (buf.validate.field).required = true,
+    (buf.validate.field).timestamp.lt = {seconds: 1000}
+  ];
+  google.protobuf.Duration timeout = 14 [(buf.validate.field).duration = {
+    gte: {seconds: 60}
+    lte: {seconds: 120}
+  }];
+  google.protobuf.StringValue note = 15 [(buf.validate.field).string.max_len = 3];
+  bytes signature = 16 [(buf.validate.field).bytes.len = 32];
+  Address address = 17 [(buf.validate.field).required = true];
+  oneof payment {
+    option (buf.validate.oneof).required = true;
+    string card = 18;
+    string account = 19;
+  }
+  Order parent = 20;
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_PENDING = 1;
+  STATUS_SHIPPED = 2;
+  STATUS_DELIVERED = 3;
+}
+
+message Address {
+  string city = 1 [(buf.validate.field).required = true];
+}
+
+message Impossible {
+  option (buf.validate.message).cel = {
+    id: "impossible"
+    message: "never valid"
+    expression: "false"
+  };
+}
+`
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	messageDescriptor := testGetMessageDescriptor(t, "Order")
+	generator, err := NewGenerator(messageDescriptor)
+	require.NoError(t, err)
+	random := rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		message, err := generator.Generate(random)
+		require.NoError(t, err)
+		violations, err := bufprotovalidate.Validate(message)
+		require.NoError(t, err)
+		require.Empty(t, violations)
+		reflectMessage := message.ProtoReflect()
+		fields := messageDescriptor.Fields()
+		assert.Equal(t, int64(0), reflectMessage.Get(fields.ByName("quantity")).Int()%2)
+		assert.NotContains(
+			t,
+			[]protoreflect.EnumNumber{0, 1},
+			reflectMessage.Get(fields.ByName("status")).Enum(),
+		)
+		assert.True(t, reflectMessage.Has(fields.ByName("address")))
+		assert.True(t, reflectMessage.WhichOneof(messageDescriptor.Oneofs().ByName("payment")) != nil)
+	}
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	t.Parallel()
+	generator, err := NewGenerator(testGetMessageDescriptor(t, "Order"))
+	require.NoError(t, err)
+	random1 := rand.New(rand.NewSource(7))
+	random2 := rand.New(rand.NewSource(7))
+	for i := 0; i < 10; i++ {
+		message1, err := generator.Generate(random1)
+		require.NoError(t, err)
+		message2, err := generator.Generate(random2)
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(message1, message2))
+	}
+}
+
+func TestGenerateImpossible(t *testing.T) {
+	t.Parallel()
+	generator, err := NewGenerator(testGetMessageDescriptor(t, "Impossible"))
+	require.NoError(t, err)
+	_, err = generator.Generate(rand.New(rand.NewSource(1)))
+	assert.EqualError(
+		t,
+		err,
+		"unable to generate a valid acme.v1.Impossible in 100 attempts, the last attempt had the violation: never valid [impossible]",
+	)
+}
+
+func TestMutate(t *testing.T) {
+	t.Parallel()
+	messageDescriptor := testGetMessageDescriptor(t, "Order")
+	generator, err := NewGenerator(messageDescriptor)
+	require.NoError(t, err)
+	mutator := NewMutator(messageDescriptor)
+	random := rand.New(rand.NewSource(3))
+	var invalidCount int
+	for i := 0; i < 200; i++ {
+		message, err := generator.Generate(random)
+		require.NoError(t, err)
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+		require.NoError(t, err)
+		mutated := mutator.Mutate(random, data)
+		assert.NotEqual(t, data, mutated)
+		if err := proto.Unmarshal(mutated, dynamicpb.NewMessage(messageDescriptor)); err != nil {
+			invalidCount++
+		}
+	}
+	// Some mutations, such as unknown and duplicated fields, result in messages
+	// that can be parsed.
+	assert.Greater(t, invalidCount, 50)
+	assert.Less(t, invalidCount, 200)
+	assert.NotEmpty(t, mutator.Mutate(random, nil))
+}
+
+func TestGeneratePattern(t *testing.T) {
+	t.Parallel()
+	random := rand.New(rand.NewSource(1))
+	for _, pattern := range []string{
+		`^[a-z]+$`,
+		`^\d{3}-\d{2,4}$`,
+		`^(foo|bar)[^a-z]?baz$`,
+		`(?i)^hello\s+world$`,
+		`^\w+@example\.com$`,
+		`^[\p{Greek}]{2}$`,
+	} {
+		for i := 0; i < 20; i++ {
+			s, err := generatePattern(random, pattern)
+			require.NoError(t, err)
+			assert.Regexp(t, regexp.MustCompile(pattern), s)
+		}
+	}
+	_, err := generatePattern(random, `[^\x00-\x{10FFFF}]`)
+	assert.EqualError(t, err, "pattern does not match any string")
+}
+
+func testGetMessageDescriptor(t *testing.T, name protoreflect.Name) protoreflect.MessageDescriptor {
+	// buf/validate/validate.proto and the well-known types are resolved from the
+	// descriptors that are linked into the binary.
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.CompositeResolver{
+			&protocompile.SourceResolver{
+				Accessor: protocompile.SourceAccessorFromMap(
+					map[string]string{
+						"acme/v1/order.proto": testOrderProto,
+					},
+				),
+			},
+			protocompile.ResolverFunc(
+				func(path string) (protocompile.SearchResult, error) {
+					fileDescriptor, err := protoregistry.GlobalFiles.FindFileByPath(path)
+					if err != nil {
+						return protocompile.SearchResult{}, err
+					}
+					return protocompile.SearchResult{Desc: fileDescriptor}, nil
+				},
+			),
+		},
+	}
+	files, err := compiler.Compile(context.Background(), "acme/v1/order.proto")
+	require.NoError(t, err)
+	fileDescriptor, err := protodesc.NewFile(protodesc.ToFileDescriptorProto(files[0]), protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	messageDescriptor := fileDescriptor.Messages().ByName(name)
+	require.NotNil(t, messageDescriptor)
+	return messageDescriptor
+}
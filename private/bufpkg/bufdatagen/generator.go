@@ -0,0 +1,356 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdatagen
+
+import (
+	"fmt"
+	"math/rand"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/bufbuild/buf/private/bufpkg/bufprotovalidate"
+	"github.com/bufbuild/protovalidate-go/resolver"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// maxAttempts is the maximum number of attempts to generate a valid message,
+	// and to generate a value that is not excluded by a not_in constraint or
+	// that is unique within a repeated or map field.
+	maxAttempts = 100
+	// maxDepth is the maximum depth of nested messages. Message fields deeper than
+	// this are only set if required.
+	maxDepth = 5
+	// maxLength is the default maximum length of repeated and map fields.
+	maxLength = 3
+)
+
+var (
+	// ungeneratedMessageFullNames are the well-known types that are never
+	// generated, as their random values are not meaningful.
+	ungeneratedMessageFullNames = map[protoreflect.FullName]struct{}{
+		"google.protobuf.Any":       {},
+		"google.protobuf.FieldMask": {},
+		"google.protobuf.ListValue": {},
+		"google.protobuf.Struct":    {},
+		"google.protobuf.Value":     {},
+	}
+	// wrapperMessageFullNames are the well-known wrapper types, whose constraints
+	// apply to their value field.
+	wrapperMessageFullNames = map[protoreflect.FullName]struct{}{
+		"google.protobuf.BoolValue":   {},
+		"google.protobuf.BytesValue":  {},
+		"google.protobuf.DoubleValue": {},
+		"google.protobuf.FloatValue":  {},
+		"google.protobuf.Int32Value":  {},
+		"google.protobuf.Int64Value":  {},
+		"google.protobuf.StringValue": {},
+		"google.protobuf.UInt32Value": {},
+		"google.protobuf.UInt64Value": {},
+	}
+)
+
+type generator struct {
+	messageDescriptor  protoreflect.MessageDescriptor
+	validator          bufprotovalidate.Validator
+	constraintResolver resolver.DefaultResolver
+}
+
+func newGenerator(messageDescriptor protoreflect.MessageDescriptor) (*generator, error) {
+	validator, err := bufprotovalidate.NewValidator()
+	if err != nil {
+		return nil, err
+	}
+	return &generator{
+		messageDescriptor: messageDescriptor,
+		validator:         validator,
+	}, nil
+}
+
+func (g *generator) Generate(random *rand.Rand) (proto.Message, error) {
+	var violations []*bufprotovalidate.Violation
+	for i := 0; i < maxAttempts; i++ {
+		message := dynamicpb.NewMessage(g.messageDescriptor)
+		g.generateMessage(random, message, nil, 0)
+		var err error
+		violations, err = g.validator.Validate(message)
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) == 0 {
+			return message, nil
+		}
+	}
+	return nil, fmt.Errorf(
+		"unable to generate a valid %s in %d attempts, the last attempt had the violation: %s",
+		g.messageDescriptor.FullName(),
+		maxAttempts,
+		violations[0].String(),
+	)
+}
+
+// generateMessage sets the fields of the message to random values.
+//
+// The constraints are the constraints of the field that contains the message,
+// which apply to the value field of wrapper messages.
+func (g *generator) generateMessage(
+	random *rand.Rand,
+	message protoreflect.Message,
+	constraints *validate.FieldConstraints,
+	depth int,
+) {
+	messageDescriptor := message.Descriptor()
+	switch messageDescriptor.FullName() {
+	case "google.protobuf.Timestamp":
+		generateTimestamp(random, message, constraints.GetTimestamp())
+		return
+	case "google.protobuf.Duration":
+		generateDuration(random, message, constraints.GetDuration())
+		return
+	}
+	if _, ok := wrapperMessageFullNames[messageDescriptor.FullName()]; ok {
+		valueFieldDescriptor := messageDescriptor.Fields().ByName("value")
+		message.Set(valueFieldDescriptor, g.generateValue(random, valueFieldDescriptor, constraints, depth, nil))
+		return
+	}
+	// If the constraints of the message are disabled, the constraints of its
+	// fields are not evaluated.
+	disabled := g.constraintResolver.ResolveMessageConstraints(messageDescriptor).GetDisabled()
+	fields := messageDescriptor.Fields()
+	seenOneofs := make(map[protoreflect.FullName]struct{})
+	for i := 0; i < fields.Len(); i++ {
+		fieldDescriptor := fields.Get(i)
+		if oneof := fieldDescriptor.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			if _, ok := seenOneofs[oneof.FullName()]; ok {
+				continue
+			}
+			seenOneofs[oneof.FullName()] = struct{}{}
+			if !g.constraintResolver.ResolveOneofConstraints(oneof).GetRequired() && random.Intn(4) == 0 {
+				continue
+			}
+			fieldDescriptor = oneof.Fields().Get(random.Intn(oneof.Fields().Len()))
+		}
+		var constraints *validate.FieldConstraints
+		if !disabled {
+			constraints = g.constraintResolver.ResolveFieldConstraints(fieldDescriptor)
+			if constraints.GetSkipped() {
+				constraints = nil
+			}
+		}
+		if !shouldGenerateField(random, fieldDescriptor, constraints, depth) {
+			continue
+		}
+		g.generateField(random, message, fieldDescriptor, constraints, depth)
+	}
+}
+
+func (g *generator) generateField(
+	random *rand.Rand,
+	message protoreflect.Message,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	constraints *validate.FieldConstraints,
+	depth int,
+) {
+	switch {
+	case fieldDescriptor.IsList():
+		repeatedRules := constraints.GetRepeated()
+		itemConstraints := repeatedRules.GetItems()
+		list := message.Mutable(fieldDescriptor).List()
+		var minItems, maxItems *uint64
+		if repeatedRules != nil {
+			minItems, maxItems = repeatedRules.MinItems, repeatedRules.MaxItems
+		}
+		length := generateLength(random, fieldDescriptor, minItems, maxItems, depth)
+		seen := make(map[interface{}]struct{})
+		for i := 0; i < length; i++ {
+			value := g.generateValue(random, fieldDescriptor, itemConstraints, depth, list.NewElement)
+			if repeatedRules.GetUnique() && !isMessageField(fieldDescriptor) {
+				for j := 0; j < maxAttempts && isSeen(seen, value); j++ {
+					value = g.generateValue(random, fieldDescriptor, itemConstraints, depth, list.NewElement)
+				}
+			}
+			list.Append(value)
+		}
+	case fieldDescriptor.IsMap():
+		mapRules := constraints.GetMap()
+		mapValue := message.Mutable(fieldDescriptor).Map()
+		var minPairs, maxPairs *uint64
+		if mapRules != nil {
+			minPairs, maxPairs = mapRules.MinPairs, mapRules.MaxPairs
+		}
+		length := generateLength(random, fieldDescriptor.MapValue(), minPairs, maxPairs, depth)
+		for i := 0; i < length; i++ {
+			mapKey := g.generateValue(random, fieldDescriptor.MapKey(), mapRules.GetKeys(), depth, nil).MapKey()
+			for j := 0; j < maxAttempts && mapValue.Has(mapKey); j++ {
+				mapKey = g.generateValue(random, fieldDescriptor.MapKey(), mapRules.GetKeys(), depth, nil).MapKey()
+			}
+			mapValue.Set(mapKey, g.generateValue(random, fieldDescriptor.MapValue(), mapRules.GetValues(), depth, mapValue.NewValue))
+		}
+	default:
+		message.Set(
+			fieldDescriptor,
+			g.generateValue(
+				random,
+				fieldDescriptor,
+				constraints,
+				depth,
+				func() protoreflect.Value {
+					return message.NewField(fieldDescriptor)
+				},
+			),
+		)
+	}
+}
+
+// generateValue returns a random singular value of the field.
+//
+// The newValue function returns a new message value, and is only called for
+// message fields.
+func (g *generator) generateValue(
+	random *rand.Rand,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	constraints *validate.FieldConstraints,
+	depth int,
+	newValue func() protoreflect.Value,
+) protoreflect.Value {
+	name := string(fieldDescriptor.Name())
+	rules := getRules(constraints)
+	switch kind := fieldDescriptor.Kind(); kind {
+	case protoreflect.BoolKind:
+		if boolRules := constraints.GetBool(); boolRules != nil && boolRules.Const != nil {
+			return protoreflect.ValueOfBool(boolRules.GetConst())
+		}
+		return protoreflect.ValueOfBool(random.Intn(2) == 0)
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(generateEnum(random, fieldDescriptor.Enum(), constraints.GetEnum()))
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(generateInt(random, rules, true)))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(generateInt(random, rules, false))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(generateUint(random, rules, true)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(generateUint(random, rules, false))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(generateFloat(random, rules)))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(generateFloat(random, rules))
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(generateString(random, name, constraints.GetString_()))
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(generateBytes(random, constraints.GetBytes()))
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		value := newValue()
+		g.generateMessage(random, value.Message(), constraints, depth+1)
+		return value
+	default:
+		panic(fmt.Sprintf("unknown kind: %v", kind))
+	}
+}
+
+// shouldGenerateField returns true if the field should be set.
+func shouldGenerateField(
+	random *rand.Rand,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	constraints *validate.FieldConstraints,
+	depth int,
+) bool {
+	valueFieldDescriptor := fieldDescriptor
+	if fieldDescriptor.IsMap() {
+		valueFieldDescriptor = fieldDescriptor.MapValue()
+	}
+	if isMessageField(valueFieldDescriptor) {
+		if _, ok := ungeneratedMessageFullNames[valueFieldDescriptor.Message().FullName()]; ok {
+			return false
+		}
+	}
+	if constraints.GetRequired() {
+		return true
+	}
+	switch {
+	case fieldDescriptor.IsList(), fieldDescriptor.IsMap():
+		// The length is picked when the field is generated, and is limited by the depth.
+		return true
+	case isMessageField(fieldDescriptor):
+		return depth < maxDepth && random.Intn(4) != 0
+	case fieldDescriptor.HasPresence():
+		return random.Intn(4) != 0
+	default:
+		return true
+	}
+}
+
+// generateLength returns a random length for a repeated or map field within the
+// given bounds, if any.
+//
+// The fieldDescriptor is the field of the elements, or the values of the map.
+func generateLength(
+	random *rand.Rand,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	minRule *uint64,
+	maxRule *uint64,
+	depth int,
+) int {
+	minLength, maxLength := 0, maxLength
+	if minRule != nil {
+		minLength = int(*minRule)
+		if minLength > maxLength {
+			maxLength = minLength + maxLength
+		}
+	}
+	if maxRule != nil && int(*maxRule) < maxLength {
+		maxLength = int(*maxRule)
+	}
+	if isMessageField(fieldDescriptor) && depth >= maxDepth {
+		maxLength = minLength
+	}
+	if maxLength <= minLength {
+		return minLength
+	}
+	return minLength + random.Intn(maxLength-minLength+1)
+}
+
+// getRules returns the rules of the type of the field, such as the StringRules,
+// or nil if the constraints have no rules.
+func getRules(constraints *validate.FieldConstraints) protoreflect.Message {
+	if constraints == nil {
+		return nil
+	}
+	reflectConstraints := constraints.ProtoReflect()
+	fieldDescriptor := reflectConstraints.WhichOneof(reflectConstraints.Descriptor().Oneofs().ByName("type"))
+	if fieldDescriptor == nil {
+		return nil
+	}
+	return reflectConstraints.Get(fieldDescriptor).Message()
+}
+
+func isMessageField(fieldDescriptor protoreflect.FieldDescriptor) bool {
+	kind := fieldDescriptor.Kind()
+	return kind == protoreflect.MessageKind || kind == protoreflect.GroupKind
+}
+
+// isSeen returns true if the scalar value was seen before, and otherwise records it.
+func isSeen(seen map[interface{}]struct{}, value protoreflect.Value) bool {
+	key := value.Interface()
+	if bytes, ok := key.([]byte); ok {
+		key = string(bytes)
+	}
+	if _, ok := seen[key]; ok {
+		return true
+	}
+	seen[key] = struct{}{}
+	return false
+}
@@ -0,0 +1,248 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdatagen
+
+import (
+	"math/rand"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	mutationWireType mutation = iota + 1
+	mutationLength
+	mutationVarint
+	mutationUTF8
+	mutationEnum
+	mutationRemove
+	mutationDuplicate
+	mutationUnknown
+	mutationTruncate
+	mutationBitFlip
+)
+
+type mutation int
+
+type mutator struct {
+	messageDescriptor protoreflect.MessageDescriptor
+}
+
+func newMutator(messageDescriptor protoreflect.MessageDescriptor) *mutator {
+	return &mutator{
+		messageDescriptor: messageDescriptor,
+	}
+}
+
+func (m *mutator) Mutate(random *rand.Rand, data []byte) []byte {
+	return mutateMessage(random, data, m.messageDescriptor, 0)
+}
+
+// wireField is a field of a serialized message.
+type wireField struct {
+	number   protowire.Number
+	wireType protowire.Type
+	// value is the contents of the field for length-delimited fields, and the
+	// encoded value otherwise.
+	value []byte
+	// encodedValue is the encoded value, including the length of length-delimited fields.
+	encodedValue    []byte
+	fieldDescriptor protoreflect.FieldDescriptor
+	// encoded is the encoded field, including its tag.
+	encoded []byte
+}
+
+func mutateMessage(random *rand.Rand, data []byte, messageDescriptor protoreflect.MessageDescriptor, depth int) []byte {
+	fields, ok := parseWireFields(data, messageDescriptor)
+	if !ok {
+		// The message is already invalid, so only mutations of its bytes apply.
+		return mutateBytes(random, data, []mutation{mutationTruncate, mutationBitFlip})
+	}
+	var nestedIndexes []int
+	for i, field := range fields {
+		if isNestedMessage(field) {
+			nestedIndexes = append(nestedIndexes, i)
+		}
+	}
+	if len(nestedIndexes) > 0 && depth < maxDepth && random.Intn(2) == 0 {
+		field := fields[nestedIndexes[random.Intn(len(nestedIndexes))]]
+		nestedData := mutateMessage(random, field.value, field.fieldDescriptor.Message(), depth+1)
+		field.encoded = protowire.AppendBytes(protowire.AppendTag(nil, field.number, field.wireType), nestedData)
+		return joinWireFields(fields)
+	}
+	mutations := []mutation{mutationUnknown, mutationTruncate, mutationBitFlip}
+	if len(fields) > 0 {
+		mutations = append(mutations, mutationWireType, mutationRemove, mutationDuplicate)
+	}
+	fieldsForMutation := make(map[mutation][]*wireField)
+	for _, field := range fields {
+		switch field.wireType {
+		case protowire.BytesType:
+			fieldsForMutation[mutationLength] = append(fieldsForMutation[mutationLength], field)
+			if field.fieldDescriptor != nil && field.fieldDescriptor.Kind() == protoreflect.StringKind {
+				fieldsForMutation[mutationUTF8] = append(fieldsForMutation[mutationUTF8], field)
+			}
+		case protowire.VarintType:
+			fieldsForMutation[mutationVarint] = append(fieldsForMutation[mutationVarint], field)
+			if field.fieldDescriptor != nil && field.fieldDescriptor.Kind() == protoreflect.EnumKind {
+				fieldsForMutation[mutationEnum] = append(fieldsForMutation[mutationEnum], field)
+			}
+		}
+	}
+	for _, fieldMutation := range []mutation{mutationLength, mutationUTF8, mutationVarint, mutationEnum} {
+		if len(fieldsForMutation[fieldMutation]) > 0 {
+			mutations = append(mutations, fieldMutation)
+		}
+	}
+	selectedMutation := mutations[random.Intn(len(mutations))]
+	switch selectedMutation {
+	case mutationWireType, mutationRemove, mutationDuplicate:
+		i := random.Intn(len(fields))
+		field := fields[i]
+		switch selectedMutation {
+		case mutationWireType:
+			// The value is kept, so that it is read as a value of another type.
+			// Wire types 6 and 7 do not exist.
+			wireTypes := []protowire.Type{
+				protowire.VarintType,
+				protowire.Fixed32Type,
+				protowire.Fixed64Type,
+				protowire.BytesType,
+				protowire.StartGroupType,
+				6,
+				7,
+			}
+			wireType := wireTypes[random.Intn(len(wireTypes))]
+			for wireType == field.wireType {
+				wireType = wireTypes[random.Intn(len(wireTypes))]
+			}
+			field.encoded = append(protowire.AppendTag(nil, field.number, wireType), field.encodedValue...)
+		case mutationRemove:
+			fields = append(fields[:i:i], fields[i+1:]...)
+		case mutationDuplicate:
+			fields = append(fields[:i+1:i+1], fields[i:]...)
+		}
+	case mutationLength, mutationUTF8, mutationVarint, mutationEnum:
+		candidates := fieldsForMutation[selectedMutation]
+		field := candidates[random.Intn(len(candidates))]
+		encoded := protowire.AppendTag(nil, field.number, field.wireType)
+		switch selectedMutation {
+		case mutationLength:
+			// The length is larger than the remaining bytes, and possibly larger than
+			// any message can be.
+			lengths := []uint64{uint64(len(field.value)) + 1 + uint64(random.Intn(16)), 1 << 31, 1<<64 - 1}
+			encoded = protowire.AppendVarint(encoded, lengths[random.Intn(len(lengths))])
+			encoded = append(encoded, field.value...)
+		case mutationUTF8:
+			// 0xff is never valid in UTF-8.
+			position := random.Intn(len(field.value) + 1)
+			value := make([]byte, 0, len(field.value)+1)
+			value = append(value, field.value[:position]...)
+			value = append(value, 0xff)
+			value = append(value, field.value[position:]...)
+			encoded = protowire.AppendBytes(encoded, value)
+		case mutationVarint:
+			// Varints are at most 10 bytes long.
+			for i := 0; i < 10; i++ {
+				encoded = append(encoded, 0x80)
+			}
+			encoded = append(encoded, 0)
+		case mutationEnum:
+			var maxNumber protoreflect.EnumNumber
+			values := field.fieldDescriptor.Enum().Values()
+			for i := 0; i < values.Len(); i++ {
+				if number := values.Get(i).Number(); number > maxNumber {
+					maxNumber = number
+				}
+			}
+			encoded = protowire.AppendVarint(encoded, uint64(int64(maxNumber)+1+int64(random.Intn(16))))
+		}
+		field.encoded = encoded
+	case mutationUnknown:
+		number := protowire.Number(1 + random.Intn(int(protowire.MaxValidNumber)))
+		for messageDescriptor.Fields().ByNumber(number) != nil {
+			number = protowire.Number(1 + random.Intn(int(protowire.MaxValidNumber)))
+		}
+		encoded := protowire.AppendTag(nil, number, protowire.BytesType)
+		encoded = protowire.AppendBytes(encoded, []byte(pickWord(random)))
+		i := random.Intn(len(fields) + 1)
+		fields = append(fields[:i:i], append([]*wireField{{encoded: encoded}}, fields[i:]...)...)
+	default:
+		return mutateBytes(random, joinWireFields(fields), []mutation{selectedMutation})
+	}
+	return joinWireFields(fields)
+}
+
+// mutateBytes applies a mutation that does not depend on the fields of the
+// message to the bytes of the message.
+func mutateBytes(random *rand.Rand, data []byte, mutations []mutation) []byte {
+	if len(data) == 0 {
+		// Nothing can be truncated or flipped, a byte that is not a valid tag is added.
+		return []byte{0xff}
+	}
+	switch mutations[random.Intn(len(mutations))] {
+	case mutationTruncate:
+		return append([]byte(nil), data[:random.Intn(len(data))]...)
+	default:
+		mutated := append([]byte(nil), data...)
+		mutated[random.Intn(len(mutated))] ^= 1 << random.Intn(8)
+		return mutated
+	}
+}
+
+// parseWireFields parses the fields of the serialized message, and returns false
+// if the message is not valid.
+func parseWireFields(data []byte, messageDescriptor protoreflect.MessageDescriptor) ([]*wireField, bool) {
+	var fields []*wireField
+	for len(data) > 0 {
+		number, wireType, tagLength := protowire.ConsumeTag(data)
+		if tagLength < 0 {
+			return nil, false
+		}
+		valueLength := protowire.ConsumeFieldValue(number, wireType, data[tagLength:])
+		if valueLength < 0 {
+			return nil, false
+		}
+		field := &wireField{
+			number:          number,
+			wireType:        wireType,
+			value:           data[tagLength : tagLength+valueLength],
+			encodedValue:    data[tagLength : tagLength+valueLength],
+			fieldDescriptor: messageDescriptor.Fields().ByNumber(number),
+			encoded:         data[:tagLength+valueLength],
+		}
+		if wireType == protowire.BytesType {
+			field.value, _ = protowire.ConsumeBytes(field.value)
+		}
+		fields = append(fields, field)
+		data = data[tagLength+valueLength:]
+	}
+	return fields, true
+}
+
+func joinWireFields(fields []*wireField) []byte {
+	var data []byte
+	for _, field := range fields {
+		data = append(data, field.encoded...)
+	}
+	return data
+}
+
+func isNestedMessage(field *wireField) bool {
+	return field.wireType == protowire.BytesType &&
+		field.fieldDescriptor != nil &&
+		field.fieldDescriptor.Message() != nil &&
+		field.fieldDescriptor.Kind() == protoreflect.MessageKind
+}
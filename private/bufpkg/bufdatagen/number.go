@@ -0,0 +1,411 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdatagen
+
+import (
+	"math"
+	"math/rand"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// defaultMaxNumber is the maximum of numbers without an upper bound, and the
+// width of the range of numbers with only one bound.
+const defaultMaxNumber = 1000
+
+// generateInt returns a random signed integer within the rules, which are any of
+// the signed integer rules of protovalidate, all of which have the same fields.
+func generateInt(random *rand.Rand, rules protoreflect.Message, is32 bool) int64 {
+	typeMin, typeMax := int64(math.MinInt64), int64(math.MaxInt64)
+	if is32 {
+		typeMin, typeMax = math.MinInt32, math.MaxInt32
+	}
+	if value, ok := getIntRule(rules, "const"); ok {
+		return value
+	}
+	if in := getIntRules(rules, "in"); len(in) > 0 {
+		return in[random.Intn(len(in))]
+	}
+	lower, hasLower := int64(0), false
+	if gt, ok := getIntRule(rules, "gt"); ok && gt < typeMax {
+		lower, hasLower = gt+1, true
+	}
+	if gte, ok := getIntRule(rules, "gte"); ok {
+		lower, hasLower = gte, true
+	}
+	upper, hasUpper := int64(0), false
+	if lt, ok := getIntRule(rules, "lt"); ok && lt > typeMin {
+		upper, hasUpper = lt-1, true
+	}
+	if lte, ok := getIntRule(rules, "lte"); ok {
+		upper, hasUpper = lte, true
+	}
+	minValue, maxValue := int64(0), int64(defaultMaxNumber)
+	switch {
+	case hasLower && hasUpper && lower > upper:
+		// The range is exclusive, so the value is either above lower or below upper.
+		if random.Intn(2) == 0 {
+			minValue, maxValue = lower, addIntSaturated(lower, defaultMaxNumber, typeMin, typeMax)
+		} else {
+			minValue, maxValue = addIntSaturated(upper, -defaultMaxNumber, typeMin, typeMax), upper
+		}
+	default:
+		if hasLower {
+			minValue = lower
+		}
+		if hasUpper {
+			maxValue = upper
+		}
+		if !hasUpper && maxValue < minValue {
+			maxValue = addIntSaturated(lower, defaultMaxNumber, typeMin, typeMax)
+		}
+		if !hasLower && minValue > maxValue {
+			minValue = addIntSaturated(upper, -defaultMaxNumber, typeMin, typeMax)
+		}
+	}
+	return generateNotIn(
+		random,
+		getIntRules(rules, "not_in"),
+		func() int64 {
+			return minValue + int64(randomUint64n(random, uint64(maxValue-minValue)))
+		},
+	)
+}
+
+// generateUint returns a random unsigned integer within the rules, which are any
+// of the unsigned integer rules of protovalidate, all of which have the same fields.
+func generateUint(random *rand.Rand, rules protoreflect.Message, is32 bool) uint64 {
+	typeMax := uint64(math.MaxUint64)
+	if is32 {
+		typeMax = math.MaxUint32
+	}
+	if value, ok := getUintRule(rules, "const"); ok {
+		return value
+	}
+	if in := getUintRules(rules, "in"); len(in) > 0 {
+		return in[random.Intn(len(in))]
+	}
+	lower, hasLower := uint64(0), false
+	if gt, ok := getUintRule(rules, "gt"); ok && gt < typeMax {
+		lower, hasLower = gt+1, true
+	}
+	if gte, ok := getUintRule(rules, "gte"); ok {
+		lower, hasLower = gte, true
+	}
+	upper, hasUpper := uint64(0), false
+	if lt, ok := getUintRule(rules, "lt"); ok && lt > 0 {
+		upper, hasUpper = lt-1, true
+	}
+	if lte, ok := getUintRule(rules, "lte"); ok {
+		upper, hasUpper = lte, true
+	}
+	minValue, maxValue := uint64(0), uint64(defaultMaxNumber)
+	switch {
+	case hasLower && hasUpper && lower > upper:
+		// The range is exclusive, so the value is either above lower or below upper.
+		if random.Intn(2) == 0 {
+			minValue, maxValue = lower, addUintSaturated(lower, defaultMaxNumber, typeMax)
+		} else {
+			maxValue = upper
+			if upper > defaultMaxNumber {
+				minValue = upper - defaultMaxNumber
+			}
+		}
+	default:
+		if hasLower {
+			minValue = lower
+		}
+		if hasUpper {
+			maxValue = upper
+		}
+		if !hasUpper && maxValue < minValue {
+			maxValue = addUintSaturated(lower, defaultMaxNumber, typeMax)
+		}
+	}
+	return generateNotIn(
+		random,
+		getUintRules(rules, "not_in"),
+		func() uint64 {
+			return minValue + randomUint64n(random, maxValue-minValue)
+		},
+	)
+}
+
+// generateFloat returns a random floating-point number within the rules, which are
+// either the float or double rules of protovalidate.
+//
+// Numbers are rounded to two decimal places if the rounded number is still within
+// the rules.
+func generateFloat(random *rand.Rand, rules protoreflect.Message) float64 {
+	if value, ok := getFloatRule(rules, "const"); ok {
+		return value
+	}
+	if in := getFloatRules(rules, "in"); len(in) > 0 {
+		return in[random.Intn(len(in))]
+	}
+	// Bounds are inclusive if the corresponding flag is set.
+	lower, hasLower, lowerInclusive := 0.0, false, false
+	if gt, ok := getFloatRule(rules, "gt"); ok {
+		lower, hasLower = gt, true
+	}
+	if gte, ok := getFloatRule(rules, "gte"); ok {
+		lower, hasLower, lowerInclusive = gte, true, true
+	}
+	upper, hasUpper, upperInclusive := 0.0, false, false
+	if lt, ok := getFloatRule(rules, "lt"); ok {
+		upper, hasUpper = lt, true
+	}
+	if lte, ok := getFloatRule(rules, "lte"); ok {
+		upper, hasUpper, upperInclusive = lte, true, true
+	}
+	minValue, maxValue := 0.0, float64(defaultMaxNumber)
+	switch {
+	case hasLower && hasUpper && lower > upper:
+		// The range is exclusive, so the value is either above lower or below upper.
+		if random.Intn(2) == 0 {
+			minValue, maxValue = lower, lower+defaultMaxNumber
+			upperInclusive = true
+		} else {
+			minValue, maxValue = upper-defaultMaxNumber, upper
+			lowerInclusive = true
+		}
+	default:
+		if hasLower {
+			minValue = lower
+		}
+		if hasUpper {
+			maxValue = upper
+		}
+		if !hasUpper && maxValue < minValue {
+			maxValue = lower + defaultMaxNumber
+		}
+		if !hasLower && minValue > maxValue {
+			minValue = upper - defaultMaxNumber
+		}
+	}
+	if math.IsInf(maxValue-minValue, 0) {
+		minValue, maxValue = math.Max(minValue, -defaultMaxNumber), math.Min(maxValue, defaultMaxNumber)
+	}
+	isWithin := func(value float64) bool {
+		return (value > minValue || (lowerInclusive && value == minValue) || !hasLower) &&
+			(value < maxValue || (upperInclusive && value == maxValue) || !hasUpper)
+	}
+	return generateNotIn(
+		random,
+		getFloatRules(rules, "not_in"),
+		func() float64 {
+			value := minValue + random.Float64()*(maxValue-minValue)
+			if rounded := math.Round(value*100) / 100; isWithin(rounded) {
+				return rounded
+			}
+			return value
+		},
+	)
+}
+
+// generateEnum returns a random enum number within the rules.
+//
+// The zero value is only picked if no other value is allowed, as it is usually
+// the unspecified value.
+func generateEnum(
+	random *rand.Rand,
+	enumDescriptor protoreflect.EnumDescriptor,
+	rules *validate.EnumRules,
+) protoreflect.EnumNumber {
+	if rules != nil && rules.Const != nil {
+		return protoreflect.EnumNumber(rules.GetConst())
+	}
+	if in := rules.GetIn(); len(in) > 0 {
+		return protoreflect.EnumNumber(in[random.Intn(len(in))])
+	}
+	notIn := slicesext.ToStructMap(rules.GetNotIn())
+	var numbers []protoreflect.EnumNumber
+	values := enumDescriptor.Values()
+	for i := 0; i < values.Len(); i++ {
+		number := values.Get(i).Number()
+		if _, ok := notIn[int32(number)]; ok || number == 0 {
+			continue
+		}
+		numbers = append(numbers, number)
+	}
+	if len(numbers) == 0 {
+		return 0
+	}
+	return numbers[random.Intn(len(numbers))]
+}
+
+// generateNotIn returns a value returned by generate that is not in notIn, or the
+// last generated value if no such value was generated within maxAttempts.
+func generateNotIn[T comparable](random *rand.Rand, notIn []T, generate func() T) T {
+	value := generate()
+	if len(notIn) == 0 {
+		return value
+	}
+	notInMap := slicesext.ToStructMap(notIn)
+	for i := 0; i < maxAttempts; i++ {
+		if _, ok := notInMap[value]; !ok {
+			break
+		}
+		value = generate()
+	}
+	return value
+}
+
+// randomUint64n returns a random number in [0, n].
+func randomUint64n(random *rand.Rand, n uint64) uint64 {
+	if n == math.MaxUint64 {
+		return random.Uint64()
+	}
+	return random.Uint64() % (n + 1)
+}
+
+func addIntSaturated(value int64, delta int64, typeMin int64, typeMax int64) int64 {
+	if delta > 0 && value > typeMax-delta {
+		return typeMax
+	}
+	if delta < 0 && value < typeMin-delta {
+		return typeMin
+	}
+	return value + delta
+}
+
+func addUintSaturated(value uint64, delta uint64, typeMax uint64) uint64 {
+	if value > typeMax-delta {
+		return typeMax
+	}
+	return value + delta
+}
+
+// getRule returns the value of the field of the rules with the name, if set.
+func getRule(rules protoreflect.Message, name protoreflect.Name) (protoreflect.Value, bool) {
+	if rules == nil {
+		return protoreflect.Value{}, false
+	}
+	fieldDescriptor := rules.Descriptor().Fields().ByName(name)
+	if fieldDescriptor == nil || !rules.Has(fieldDescriptor) {
+		return protoreflect.Value{}, false
+	}
+	return rules.Get(fieldDescriptor), true
+}
+
+// getRuleList returns the values of the repeated field of the rules with the name.
+func getRuleList(rules protoreflect.Message, name protoreflect.Name) []protoreflect.Value {
+	value, ok := getRule(rules, name)
+	if !ok {
+		return nil
+	}
+	list, ok := value.Interface().(protoreflect.List)
+	if !ok {
+		return nil
+	}
+	values := make([]protoreflect.Value, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		values[i] = list.Get(i)
+	}
+	return values
+}
+
+func getIntRule(rules protoreflect.Message, name protoreflect.Name) (int64, bool) {
+	value, ok := getRule(rules, name)
+	if !ok {
+		return 0, false
+	}
+	return toInt(value)
+}
+
+func getIntRules(rules protoreflect.Message, name protoreflect.Name) []int64 {
+	var ints []int64
+	for _, value := range getRuleList(rules, name) {
+		if i, ok := toInt(value); ok {
+			ints = append(ints, i)
+		}
+	}
+	return ints
+}
+
+func getUintRule(rules protoreflect.Message, name protoreflect.Name) (uint64, bool) {
+	value, ok := getRule(rules, name)
+	if !ok {
+		return 0, false
+	}
+	return toUint(value)
+}
+
+func getUintRules(rules protoreflect.Message, name protoreflect.Name) []uint64 {
+	var uints []uint64
+	for _, value := range getRuleList(rules, name) {
+		if u, ok := toUint(value); ok {
+			uints = append(uints, u)
+		}
+	}
+	return uints
+}
+
+func getFloatRule(rules protoreflect.Message, name protoreflect.Name) (float64, bool) {
+	value, ok := getRule(rules, name)
+	if !ok {
+		return 0, false
+	}
+	return toFloat(value)
+}
+
+func getFloatRules(rules protoreflect.Message, name protoreflect.Name) []float64 {
+	var floats []float64
+	for _, value := range getRuleList(rules, name) {
+		if f, ok := toFloat(value); ok {
+			floats = append(floats, f)
+		}
+	}
+	return floats
+}
+
+// toInt, toUint, and toFloat return the number of the value, and false if the
+// value is of another type, which is the case if the rules do not match the type
+// of the field.
+
+func toInt(value protoreflect.Value) (int64, bool) {
+	switch i := value.Interface().(type) {
+	case int32:
+		return int64(i), true
+	case int64:
+		return i, true
+	default:
+		return 0, false
+	}
+}
+
+func toUint(value protoreflect.Value) (uint64, bool) {
+	switch u := value.Interface().(type) {
+	case uint32:
+		return uint64(u), true
+	case uint64:
+		return u, true
+	default:
+		return 0, false
+	}
+}
+
+func toFloat(value protoreflect.Value) (float64, bool) {
+	switch f := value.Interface().(type) {
+	case float32:
+		return float64(f), true
+	case float64:
+		return f, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdatagen
+
+import (
+	"errors"
+	"math/rand"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxPatternRepeat is the number of repetitions added to the minimum of
+	// unbounded repetitions in patterns, such as "a*" and "a{2,}".
+	maxPatternRepeat = 3
+	// minPrintableRune and maxPrintableRune are the range of printable ASCII
+	// characters, which are preferred in character classes.
+	minPrintableRune       = ' '
+	maxPrintableRune       = '~'
+	alphanumericCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// generatePattern returns a random string that matches the RE2 pattern.
+//
+// Empty-width assertions, such as "^" and "\b", are ignored, so the string only
+// matches patterns with word boundaries by chance.
+func generatePattern(random *rand.Rand, pattern string) (string, error) {
+	regexp, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	if err := writePattern(random, &builder, regexp.Simplify()); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+func writePattern(random *rand.Rand, builder *strings.Builder, regexp *syntax.Regexp) error {
+	switch regexp.Op {
+	case syntax.OpNoMatch:
+		return errors.New("pattern does not match any string")
+	case syntax.OpCharClass:
+		if len(regexp.Rune) == 0 {
+			return errors.New("pattern does not match any string")
+		}
+		_, _ = builder.WriteRune(pickRune(random, regexp.Rune))
+	case syntax.OpLiteral:
+		for _, r := range regexp.Rune {
+			if regexp.Flags&syntax.FoldCase != 0 && random.Intn(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			_, _ = builder.WriteRune(r)
+		}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		_ = builder.WriteByte(alphanumericCharacters[random.Intn(len(alphanumericCharacters))])
+	case syntax.OpCapture:
+		return writePattern(random, builder, regexp.Sub[0])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		var minRepeat, maxRepeat int
+		switch regexp.Op {
+		case syntax.OpStar:
+			minRepeat, maxRepeat = 0, maxPatternRepeat
+		case syntax.OpPlus:
+			minRepeat, maxRepeat = 1, 1+maxPatternRepeat
+		case syntax.OpQuest:
+			minRepeat, maxRepeat = 0, 1
+		default:
+			minRepeat, maxRepeat = regexp.Min, regexp.Max
+			if maxRepeat < 0 {
+				maxRepeat = minRepeat + maxPatternRepeat
+			}
+		}
+		repeat := minRepeat + random.Intn(maxRepeat-minRepeat+1)
+		for i := 0; i < repeat; i++ {
+			if err := writePattern(random, builder, regexp.Sub[0]); err != nil {
+				return err
+			}
+		}
+	case syntax.OpConcat:
+		for _, sub := range regexp.Sub {
+			if err := writePattern(random, builder, sub); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		return writePattern(random, builder, regexp.Sub[random.Intn(len(regexp.Sub))])
+	}
+	// All other ops are empty-width, and match the empty string.
+	return nil
+}
+
+// pickRune returns a random rune of the character class, which is a list of
+// inclusive ranges of runes.
+//
+// Printable ASCII characters are picked if the class contains any.
+func pickRune(random *rand.Rand, ranges []rune) rune {
+	var printableRanges []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < minPrintableRune {
+			lo = minPrintableRune
+		}
+		if hi > maxPrintableRune {
+			hi = maxPrintableRune
+		}
+		if lo <= hi {
+			printableRanges = append(printableRanges, lo, hi)
+		}
+	}
+	if len(printableRanges) > 0 {
+		ranges = printableRanges
+	}
+	var count int
+	for i := 0; i+1 < len(ranges); i += 2 {
+		count += int(ranges[i+1]-ranges[i]) + 1
+	}
+	n := random.Intn(count)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		size := int(ranges[i+1]-ranges[i]) + 1
+		if n < size {
+			return ranges[i] + rune(n)
+		}
+		n -= size
+	}
+	return ranges[0]
+}
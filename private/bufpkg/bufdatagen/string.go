@@ -0,0 +1,291 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdatagen
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+)
+
+const (
+	letters = "abcdefghijklmnopqrstuvwxyz"
+	// defaultMinBytesLength and defaultMaxBytesLength are the range of the
+	// lengths of bytes without length rules.
+	defaultMinBytesLength = 8
+	defaultMaxBytesLength = 16
+)
+
+var (
+	words = []string{
+		"amber", "anchor", "apple", "arrow", "aspen", "autumn", "birch", "breeze",
+		"canyon", "cedar", "cloud", "comet", "coral", "crystal", "dawn", "delta",
+		"desert", "ember", "falcon", "fern", "forest", "frost", "glacier", "harbor",
+		"hazel", "horizon", "island", "ivory", "jade", "lagoon", "lantern", "maple",
+		"meadow", "mesa", "orchid", "pebble", "prairie", "quartz", "raven", "river",
+		"sage", "shadow", "spruce", "summit", "thunder", "tide", "valley", "willow",
+	}
+	topLevelDomains = []string{"com", "dev", "io", "net", "org"}
+)
+
+// generateString returns a random string within the rules.
+//
+// The name is the name of the field, which is used to pick strings that resemble
+// the values of fields with similar names, such as email addresses for fields
+// named "email".
+func generateString(random *rand.Rand, name string, rules *validate.StringRules) string {
+	if rules != nil && rules.Const != nil {
+		return rules.GetConst()
+	}
+	if in := rules.GetIn(); len(in) > 0 {
+		return in[random.Intn(len(in))]
+	}
+	return generateNotIn(
+		random,
+		rules.GetNotIn(),
+		func() string {
+			if s, ok := generateWellKnownString(random, rules); ok {
+				return s
+			}
+			return generateStringWithLength(random, name, rules)
+		},
+	)
+}
+
+// generateWellKnownString returns a random string that matches the pattern or the
+// well-known format of the rules, and false if the rules have neither.
+func generateWellKnownString(random *rand.Rand, rules *validate.StringRules) (string, bool) {
+	if pattern := rules.GetPattern(); pattern != "" {
+		s, err := generatePattern(random, pattern)
+		if err != nil {
+			// The pattern is invalid, which protovalidate reports when the
+			// message is validated.
+			return "", true
+		}
+		return s, true
+	}
+	switch {
+	case rules.GetEmail():
+		return generateEmail(random), true
+	case rules.GetHostname():
+		return generateHostname(random), true
+	case rules.GetIp(), rules.GetIpv4(), rules.GetAddress():
+		return generateIPv4(random), true
+	case rules.GetIpv6():
+		return generateIPv6(random), true
+	case rules.GetUri(), rules.GetUriRef():
+		return generateURL(random), true
+	case rules.GetUuid():
+		return generateUUID(random), true
+	case rules.GetIpWithPrefixlen(), rules.GetIpv4WithPrefixlen():
+		return generateIPv4(random) + "/24", true
+	case rules.GetIpv6WithPrefixlen():
+		return generateIPv6(random) + "/64", true
+	case rules.GetIpPrefix(), rules.GetIpv4Prefix():
+		return fmt.Sprintf("10.%d.0.0/16", random.Intn(256)), true
+	case rules.GetIpv6Prefix():
+		return fmt.Sprintf("fd00:%x::/32", random.Intn(0x10000)), true
+	case rules.GetWellKnownRegex() == validate.KnownRegex_KNOWN_REGEX_HTTP_HEADER_NAME:
+		return "x-" + pickWord(random), true
+	case rules.GetWellKnownRegex() == validate.KnownRegex_KNOWN_REGEX_HTTP_HEADER_VALUE:
+		return pickWord(random), true
+	default:
+		return "", false
+	}
+}
+
+// generateStringWithLength returns a random string for the field with the name,
+// with the prefix, suffix, and contents of the rules, and padded with words or
+// truncated to the lengths of the rules.
+//
+// Generated strings are ASCII, so lengths in characters and in bytes are the same.
+func generateStringWithLength(random *rand.Rand, name string, rules *validate.StringRules) string {
+	body := generateStringForName(random, name)
+	if contains := rules.GetContains(); contains != "" && !strings.Contains(body, contains) {
+		body += contains
+	}
+	if rules == nil {
+		return body
+	}
+	minLength, maxLength := 0, -1
+	for _, length := range []*uint64{rules.Len, rules.MinLen, rules.LenBytes, rules.MinBytes} {
+		if length != nil && int(*length) > minLength {
+			minLength = int(*length)
+		}
+	}
+	for _, length := range []*uint64{rules.Len, rules.MaxLen, rules.LenBytes, rules.MaxBytes} {
+		if length != nil && (maxLength < 0 || int(*length) < maxLength) {
+			maxLength = int(*length)
+		}
+	}
+	prefix, suffix := rules.GetPrefix(), rules.GetSuffix()
+	affixLength := len(prefix) + len(suffix)
+	for affixLength+len(body) < minLength {
+		body += " " + pickWord(random)
+	}
+	if maxLength >= 0 && affixLength+len(body) > maxLength {
+		bodyLength := maxLength - affixLength
+		if bodyLength < 0 {
+			bodyLength = 0
+		}
+		body = body[:bodyLength]
+		if strings.HasSuffix(body, " ") {
+			body = body[:len(body)-1] + string(letters[random.Intn(len(letters))])
+		}
+	}
+	return prefix + body + suffix
+}
+
+// generateStringForName returns a random string that resembles the values of
+// fields with the name.
+func generateStringForName(random *rand.Rand, name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "email"):
+		return generateEmail(random)
+	case strings.Contains(name, "uuid"), name == "id", strings.HasSuffix(name, "_id"):
+		return generateUUID(random)
+	case strings.Contains(name, "url"), strings.Contains(name, "uri"), strings.Contains(name, "link"):
+		return generateURL(random)
+	case strings.Contains(name, "host"), strings.Contains(name, "domain"):
+		return generateHostname(random)
+	case name == "ip", strings.Contains(name, "ip_address"):
+		return generateIPv4(random)
+	case strings.Contains(name, "phone"):
+		return fmt.Sprintf("+1-555-%03d-%04d", random.Intn(1000), random.Intn(10000))
+	case strings.Contains(name, "name"), strings.Contains(name, "title"):
+		return capitalize(pickWord(random)) + " " + capitalize(pickWord(random))
+	case strings.Contains(name, "description"), strings.Contains(name, "comment"), strings.Contains(name, "text"):
+		sentence := make([]string, 4+random.Intn(5))
+		for i := range sentence {
+			sentence[i] = pickWord(random)
+		}
+		return capitalize(strings.Join(sentence, " ")) + "."
+	default:
+		return pickWord(random)
+	}
+}
+
+// generateBytes returns random bytes within the rules.
+func generateBytes(random *rand.Rand, rules *validate.BytesRules) []byte {
+	if rules != nil && rules.Const != nil {
+		return rules.GetConst()
+	}
+	if in := rules.GetIn(); len(in) > 0 {
+		return in[random.Intn(len(in))]
+	}
+	notIn := make([]string, len(rules.GetNotIn()))
+	for i, value := range rules.GetNotIn() {
+		notIn[i] = string(value)
+	}
+	return []byte(
+		generateNotIn(
+			random,
+			notIn,
+			func() string {
+				return string(generateBytesCandidate(random, rules))
+			},
+		),
+	)
+}
+
+func generateBytesCandidate(random *rand.Rand, rules *validate.BytesRules) []byte {
+	if pattern := rules.GetPattern(); pattern != "" {
+		s, err := generatePattern(random, pattern)
+		if err != nil {
+			return nil
+		}
+		return []byte(s)
+	}
+	switch {
+	case rules.GetIp(), rules.GetIpv4():
+		return randomBytes(random, 4)
+	case rules.GetIpv6():
+		return randomBytes(random, 16)
+	}
+	minLength, maxLength := defaultMinBytesLength, defaultMaxBytesLength
+	if rules != nil {
+		if rules.MinLen != nil {
+			minLength = int(rules.GetMinLen())
+			if minLength > maxLength {
+				maxLength = minLength + defaultMaxBytesLength
+			}
+		}
+		if rules.MaxLen != nil {
+			maxLength = int(rules.GetMaxLen())
+			if minLength > maxLength {
+				minLength = 0
+			}
+		}
+		if rules.Len != nil {
+			minLength, maxLength = int(rules.GetLen()), int(rules.GetLen())
+		}
+	}
+	prefix, suffix, contains := rules.GetPrefix(), rules.GetSuffix(), rules.GetContains()
+	affixLength := len(prefix) + len(suffix) + len(contains)
+	length := minLength + random.Intn(maxLength-minLength+1) - affixLength
+	if length < 0 {
+		length = 0
+	}
+	value := append([]byte{}, prefix...)
+	value = append(value, contains...)
+	value = append(value, randomBytes(random, length)...)
+	return append(value, suffix...)
+}
+
+func generateEmail(random *rand.Rand) string {
+	return fmt.Sprintf("%s.%s@%s", pickWord(random), pickWord(random), generateHostname(random))
+}
+
+func generateHostname(random *rand.Rand) string {
+	return fmt.Sprintf("%s.%s", pickWord(random), topLevelDomains[random.Intn(len(topLevelDomains))])
+}
+
+func generateURL(random *rand.Rand) string {
+	return fmt.Sprintf("https://%s/%s", generateHostname(random), pickWord(random))
+}
+
+func generateIPv4(random *rand.Rand) string {
+	return fmt.Sprintf("10.%d.%d.%d", random.Intn(256), random.Intn(256), 1+random.Intn(254))
+}
+
+func generateIPv6(random *rand.Rand) string {
+	return fmt.Sprintf("fd00::%x:%x", random.Intn(0x10000), 1+random.Intn(0xffff))
+}
+
+// generateUUID returns a random version 4 UUID.
+func generateUUID(random *rand.Rand) string {
+	uuid := randomBytes(random, 16)
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+func pickWord(random *rand.Rand) string {
+	return words[random.Intn(len(words))]
+}
+
+// capitalize returns the word with its first letter in upper case. Words are ASCII.
+func capitalize(word string) string {
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+func randomBytes(random *rand.Rand, length int) []byte {
+	value := make([]byte, length)
+	_, _ = random.Read(value)
+	return value
+}
@@ -0,0 +1,159 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdatagen
+
+import (
+	"math/rand"
+	"time"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	// defaultMinTime and defaultMaxTime are the range of timestamps without
+	// bounds. The range is fixed, rather than relative to the current time, so
+	// that the same random values always result in the same timestamps.
+	defaultMinTime = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	defaultMaxTime = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// defaultMinDuration and defaultMaxDuration are the range of durations
+	// without bounds.
+	defaultMinDuration = time.Second
+	defaultMaxDuration = time.Hour
+)
+
+// generateTimestamp sets the google.protobuf.Timestamp message to a random time
+// within the rules, in whole seconds.
+func generateTimestamp(random *rand.Rand, message protoreflect.Message, rules *validate.TimestampRules) {
+	if rules.GetConst() != nil {
+		setSecondsAndNanos(message, rules.GetConst().GetSeconds(), rules.GetConst().GetNanos())
+		return
+	}
+	var lower, upper *time.Time
+	setLower := func(t time.Time) {
+		if lower == nil || t.After(*lower) {
+			lower = &t
+		}
+	}
+	setUpper := func(t time.Time) {
+		if upper == nil || t.Before(*upper) {
+			upper = &t
+		}
+	}
+	now := time.Now()
+	if rules.GetGt() != nil {
+		setLower(rules.GetGt().AsTime().Add(time.Second))
+	}
+	if rules.GetGte() != nil {
+		setLower(rules.GetGte().AsTime())
+	}
+	if rules.GetGtNow() {
+		setLower(now.Add(time.Second))
+	}
+	if rules.GetLt() != nil {
+		setUpper(rules.GetLt().AsTime().Add(-time.Second))
+	}
+	if rules.GetLte() != nil {
+		setUpper(rules.GetLte().AsTime())
+	}
+	if rules.GetLtNow() {
+		setUpper(now.Add(-time.Second))
+	}
+	if rules.GetWithin() != nil {
+		within := rules.GetWithin().AsDuration()
+		setLower(now.Add(-within))
+		setUpper(now.Add(within))
+	}
+	minTime, maxTime := defaultMinTime, defaultMaxTime
+	if lower != nil {
+		minTime = *lower
+	}
+	if upper != nil {
+		maxTime = *upper
+	}
+	if upper == nil && !maxTime.After(minTime) {
+		maxTime = minTime.Add(defaultMaxTime.Sub(defaultMinTime))
+	}
+	if lower == nil && !minTime.Before(maxTime) {
+		minTime = maxTime.Add(-defaultMaxTime.Sub(defaultMinTime))
+	}
+	seconds := minTime.Unix()
+	if span := maxTime.Unix() - seconds; span > 0 {
+		seconds += random.Int63n(span + 1)
+	}
+	setSecondsAndNanos(message, seconds, 0)
+}
+
+// generateDuration sets the google.protobuf.Duration message to a random duration
+// within the rules, in whole seconds.
+func generateDuration(random *rand.Rand, message protoreflect.Message, rules *validate.DurationRules) {
+	if rules.GetConst() != nil {
+		setSecondsAndNanos(message, rules.GetConst().GetSeconds(), rules.GetConst().GetNanos())
+		return
+	}
+	if in := rules.GetIn(); len(in) > 0 {
+		duration := in[random.Intn(len(in))]
+		setSecondsAndNanos(message, duration.GetSeconds(), duration.GetNanos())
+		return
+	}
+	minDuration, maxDuration := defaultMinDuration, defaultMaxDuration
+	hasLower, hasUpper := false, false
+	if rules.GetGt() != nil {
+		minDuration, hasLower = rules.GetGt().AsDuration()+time.Second, true
+	}
+	if rules.GetGte() != nil {
+		minDuration, hasLower = rules.GetGte().AsDuration(), true
+	}
+	if rules.GetLt() != nil {
+		maxDuration, hasUpper = rules.GetLt().AsDuration()-time.Second, true
+	}
+	if rules.GetLte() != nil {
+		maxDuration, hasUpper = rules.GetLte().AsDuration(), true
+	}
+	if !hasUpper && maxDuration < minDuration {
+		maxDuration = minDuration + defaultMaxDuration
+	}
+	if !hasLower && minDuration > maxDuration {
+		minDuration = maxDuration - defaultMaxDuration
+	}
+	notIn := make(map[time.Duration]struct{}, len(rules.GetNotIn()))
+	for _, duration := range rules.GetNotIn() {
+		notIn[duration.AsDuration()] = struct{}{}
+	}
+	minSeconds, maxSeconds := int64(minDuration/time.Second), int64(maxDuration/time.Second)
+	var seconds int64
+	for i := 0; i < maxAttempts; i++ {
+		seconds = minSeconds
+		if maxSeconds > minSeconds {
+			seconds += random.Int63n(maxSeconds - minSeconds + 1)
+		}
+		if _, ok := notIn[time.Duration(seconds)*time.Second]; !ok {
+			break
+		}
+	}
+	setSecondsAndNanos(message, seconds, 0)
+}
+
+// setSecondsAndNanos sets the seconds and nanos fields of the message, which is
+// either a google.protobuf.Timestamp or a google.protobuf.Duration.
+//
+// The fields are set by reflection, as the message may be a dynamic message.
+func setSecondsAndNanos(message protoreflect.Message, seconds int64, nanos int32) {
+	fields := message.Descriptor().Fields()
+	message.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(seconds))
+	if nanos != 0 {
+		message.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(nanos))
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufdoc generates documentation for the files of an image from their
+// comments.
+//
+// A Document is the model of the documentation of the packages of an image, and
+// is rendered as Markdown or HTML with Go templates.
+//
+// A Document is also the structured documentation of an image for tools that
+// render documentation themselves: each declaration has its leading, trailing,
+// and detached comments, and the comments of any declaration can be looked up
+// by its fully-qualified name, so that tools do not need to traverse the source
+// code info of the image.
+//
+// This package cannot be a public Go API. Like all packages under private, it
+// panics when it is imported by a module outside of github.com/bufbuild, and it
+// depends on other private packages such as bufimage. Tools outside of
+// github.com/bufbuild read the Document as JSON instead, which is written with
+// FormatJSON by "buf beta docs generate --format json". The JSON field names
+// are those of the json tags of the types of this package, and are only
+// changed in backwards-compatible ways.
+package bufdoc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+)
+
+const (
+	// FormatMarkdown is the Markdown format.
+	FormatMarkdown Format = 1
+	// FormatHTML is the HTML format.
+	FormatHTML Format = 2
+	// FormatJSON is the JSON format, which is the Document itself.
+	FormatJSON Format = 3
+)
+
+var (
+	// AllFormatsString is the string representation of all Formats.
+	AllFormatsString = stringutil.SliceToString([]string{FormatMarkdown.String(), FormatHTML.String(), FormatJSON.String()})
+)
+
+// Format is a documentation format.
+type Format int
+
+// ParseFormat parses the format.
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "markdown":
+		return FormatMarkdown, nil
+	case "html":
+		return FormatHTML, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return 0, fmt.Errorf("unknown format: %s", s)
+	}
+}
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	switch f {
+	case FormatMarkdown:
+		return "markdown"
+	case FormatHTML:
+		return "html"
+	case FormatJSON:
+		return "json"
+	default:
+		return strconv.Itoa(int(f))
+	}
+}
+
+// Document is the documentation of the packages of an image.
+type Document struct {
+	// Packages are the packages of the files of the image that are not imports,
+	// sorted by name.
+	//
+	// If DocumentWithImports is set, the packages of the imports are also included.
+	Packages []*Package `json:"packages,omitempty"`
+
+	fullNameToComments map[string]Comments
+}
+
+// CommentsForFullName returns the comments of the declaration with the
+// fully-qualified name, for example acme.v1.Order.customer_id.
+//
+// The declaration is a message, field, extension, enum, enum value, service, or
+// method of the Document. Returns false if there is no such declaration.
+func (d *Document) CommentsForFullName(fullName string) (Comments, bool) {
+	comments, ok := d.fullNameToComments[strings.TrimPrefix(fullName, ".")]
+	return comments, ok
+}
+
+// Package is the documentation of a package.
+type Package struct {
+	// Name is the name of the package, or empty if the files do not declare a package.
+	Name string `json:"name,omitempty"`
+	// Comments are the comments of the package statements of the files of the package.
+	Comments Comments `json:"comments"`
+	// Files are the files of the package, sorted by path.
+	Files []*File `json:"files,omitempty"`
+	// Messages are the messages of the package, including nested messages, sorted
+	// by full name.
+	//
+	// The entries of map fields are not included.
+	Messages []*Message `json:"messages,omitempty"`
+	// Enums are the enums of the package, including nested enums, sorted by full name.
+	Enums []*Enum `json:"enums,omitempty"`
+	// Services are the services of the package, sorted by full name.
+	Services []*Service `json:"services,omitempty"`
+	// Extensions are the extensions that are declared in the package, including
+	// the extensions that are declared in messages, sorted by full name.
+	Extensions []*Field `json:"extensions,omitempty"`
+}
+
+// File is the documentation of a file.
+type File struct {
+	// Path is the path of the file.
+	Path string `json:"path,omitempty"`
+	// Import is true if the file is an import of the image.
+	//
+	// Imports are only included if DocumentWithImports is set.
+	Import bool `json:"import,omitempty"`
+	// Comments are the comments of the package statement of the file, or of the
+	// syntax statement if the file does not declare a package.
+	Comments Comments `json:"comments"`
+	// Options are the options of the file.
+	Options []*Option `json:"options,omitempty"`
+}
+
+// Message is the documentation of a message.
+type Message struct {
+	// Name is the name of the message, for example Order.
+	Name string `json:"name,omitempty"`
+	// FullName is the fully-qualified name of the message, for example acme.v1.Order.
+	FullName string `json:"full_name,omitempty"`
+	// FilePath is the path of the file that declares the message.
+	FilePath string    `json:"file_path,omitempty"`
+	Comments Comments  `json:"comments"`
+	Options  []*Option `json:"options,omitempty"`
+	// Deprecated is true if the deprecated option is set.
+	Deprecated bool `json:"deprecated,omitempty"`
+	// Fields are the fields of the message, in the order they are declared.
+	Fields []*Field `json:"fields,omitempty"`
+}
+
+// Field is the documentation of a field or an extension.
+type Field struct {
+	// Name is the name of the field, for example customer_id.
+	Name string `json:"name,omitempty"`
+	// FullName is the fully-qualified name of the field, for example acme.v1.Order.customer_id.
+	FullName string `json:"full_name,omitempty"`
+	// JSONName is the JSON name of the field, for example customerId.
+	JSONName string `json:"json_name,omitempty"`
+	// Number is the field number.
+	Number int `json:"number"`
+	// Label is optional, required, repeated, or empty if the field does not have a label.
+	//
+	// Map fields do not have a label.
+	Label string `json:"label,omitempty"`
+	// Type is the type of the field as it would be written in a .proto file, with
+	// message and enum types fully-qualified, for example string, acme.v1.Customer,
+	// or map<string, acme.v1.Item>.
+	Type string `json:"type,omitempty"`
+	// TypeFullName is the fully-qualified name of the message or enum type of the
+	// field, or of the values of a map field, or empty if the type is a scalar.
+	TypeFullName string `json:"type_full_name,omitempty"`
+	// Oneof is the name of the oneof that the field is in, or empty if the field
+	// is not in a oneof.
+	//
+	// Synthetic oneofs of proto3 optional fields are not included.
+	Oneof string `json:"oneof,omitempty"`
+	// Extendee is the fully-qualified name of the message that the field extends,
+	// or empty if the field is not an extension.
+	Extendee   string    `json:"extendee,omitempty"`
+	Comments   Comments  `json:"comments"`
+	Options    []*Option `json:"options,omitempty"`
+	Deprecated bool      `json:"deprecated,omitempty"`
+}
+
+// Enum is the documentation of an enum.
+type Enum struct {
+	Name       string    `json:"name,omitempty"`
+	FullName   string    `json:"full_name,omitempty"`
+	FilePath   string    `json:"file_path,omitempty"`
+	Comments   Comments  `json:"comments"`
+	Options    []*Option `json:"options,omitempty"`
+	Deprecated bool      `json:"deprecated,omitempty"`
+	// Values are the values of the enum, in the order they are declared.
+	Values []*EnumValue `json:"values,omitempty"`
+}
+
+// EnumValue is the documentation of an enum value.
+type EnumValue struct {
+	Name string `json:"name,omitempty"`
+	// FullName is the fully-qualified name of the value, which is in the scope
+	// of the enum's parent, for example acme.v1.Order.STATUS_SHIPPED.
+	FullName   string    `json:"full_name,omitempty"`
+	Number     int       `json:"number"`
+	Comments   Comments  `json:"comments"`
+	Options    []*Option `json:"options,omitempty"`
+	Deprecated bool      `json:"deprecated,omitempty"`
+}
+
+// Service is the documentation of a service.
+type Service struct {
+	Name       string    `json:"name,omitempty"`
+	FullName   string    `json:"full_name,omitempty"`
+	FilePath   string    `json:"file_path,omitempty"`
+	Comments   Comments  `json:"comments"`
+	Options    []*Option `json:"options,omitempty"`
+	Deprecated bool      `json:"deprecated,omitempty"`
+	// Methods are the methods of the service, in the order they are declared.
+	Methods []*Method `json:"methods,omitempty"`
+}
+
+// Method is the documentation of a method.
+type Method struct {
+	Name     string `json:"name,omitempty"`
+	FullName string `json:"full_name,omitempty"`
+	// InputType is the fully-qualified name of the input message.
+	InputType string `json:"input_type,omitempty"`
+	// OutputType is the fully-qualified name of the output message.
+	OutputType      string    `json:"output_type,omitempty"`
+	ClientStreaming bool      `json:"client_streaming,omitempty"`
+	ServerStreaming bool      `json:"server_streaming,omitempty"`
+	Comments        Comments  `json:"comments"`
+	Options         []*Option `json:"options,omitempty"`
+	Deprecated      bool      `json:"deprecated,omitempty"`
+}
+
+// Comments are the comments that are attached to a declaration.
+//
+// Comment markers and the leading space of each line are removed.
+type Comments struct {
+	// Leading are the comments directly before the declaration.
+	Leading string `json:"leading,omitempty"`
+	// Trailing are the comments directly after the declaration, on the same line
+	// or the next line.
+	Trailing string `json:"trailing,omitempty"`
+	// Detached are the comments before the declaration that are separated from
+	// it and from each other by blank lines, in the order they are written.
+	//
+	// Detached comments are usually not documentation of the declaration, such
+	// as commented-out code or section headers, so they are not rendered.
+	Detached []string `json:"detached,omitempty"`
+}
+
+// String returns the leading and trailing comments, separated by a blank line.
+//
+// The detached comments are not included.
+func (c Comments) String() string {
+	var parts []string
+	for _, comment := range []string{c.Leading, c.Trailing} {
+		if comment != "" {
+			parts = append(parts, comment)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Option is an option that is set on a declaration.
+type Option struct {
+	// Name is the name of the option, for example deprecated, or the
+	// fully-qualified name in parentheses for custom options, for example
+	// (acme.v1.sensitive).
+	Name string `json:"name,omitempty"`
+	// Value is the value of the option as it would be written in a .proto
+	// file, with messages as JSON.
+	Value string `json:"value,omitempty"`
+}
+
+// NewDocument returns a new Document for the files of the image that are not imports.
+func NewDocument(ctx context.Context, image bufimage.Image, options ...DocumentOption) (*Document, error) {
+	return newDocument(ctx, image, options...)
+}
+
+// DocumentOption is an option for a new Document.
+type DocumentOption func(*documentOptions)
+
+// DocumentWithImports returns a new DocumentOption that includes the files of
+// the image that are imports in the Document.
+func DocumentWithImports() DocumentOption {
+	return func(documentOptions *documentOptions) {
+		documentOptions.includeImports = true
+	}
+}
+
+// Generate renders the Document in the Format, and writes the files of the
+// documentation to the bucket.
+//
+// An index file and a file for each package are written, with the extension of
+// the Format. The file of a package is named after the package, for example
+// acme.v1.md.
+//
+// The files are rendered by Go templates that are composed of named templates,
+// one for each kind of declaration, so that the layout of each kind is defined
+// in one place. The index file is rendered by the "index" template with the
+// Document, and the file of each package by the "package" template with the
+// Package. The other named templates are "head" (HTML only), "service",
+// "message", "enum", "comments", "options", "type", "fieldType", and
+// "fieldDescription".
+//
+// Any of the named templates can be replaced with GenerateWithTemplates.
+//
+// For FormatJSON, the Document is written as a single file named
+// document.json instead, and templates are not supported.
+func Generate(
+	ctx context.Context,
+	writeBucket storage.WriteBucket,
+	format Format,
+	document *Document,
+	options ...GenerateOption,
+) error {
+	return generate(ctx, writeBucket, format, document, options...)
+}
+
+// GenerateOption is an option for Generate.
+type GenerateOption func(*generateOptions)
+
+// GenerateWithTemplates returns a new GenerateOption that parses the files of
+// the bucket with the .tmpl extension as Go templates after the built-in
+// templates of the Format, in path order.
+//
+// A template that is defined with the same name as a built-in template, for
+// example with {{define "message"}}, replaces it, so that the layout can be
+// changed without copying all of the built-in templates. The templates of the
+// HTML format are parsed with html/template, and the templates of the Markdown
+// format with text/template. The functions that are available to the built-in
+// templates are also available: packageName, packageFile, link, summary, and
+// cell.
+func GenerateWithTemplates(templateReadBucket storage.ReadBucket) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.templateReadBucket = templateReadBucket
+	}
+}
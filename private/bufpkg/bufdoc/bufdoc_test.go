@@ -0,0 +1,268 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdoc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testOrderProto = `syntax = "proto3";
+
+// Package acme.v1 manages orders.
+package acme.v1;
+
+import "acme/v1/options.proto";
+
+// Orders.
+
+// Order is an order.
+//
+// Orders are immutable.
+message Order {
+  option (acme.v1.audited) = true;
+
+  // The ID of the order.
+  string id = 1;
+  map<string, Item> items = 2;
+  Status status = 3 [deprecated = true];
+  oneof payment {
+    string card = 4 [(acme.v1.sensitive) = true];
+    string voucher = 5;
+  }
+  optional string note = 6; // A note for the courier.
+
+  message Item {
+    int64 quantity = 1;
+  }
+
+  enum Status {
+    STATUS_UNSPECIFIED = 0;
+    // The order shipped.
+    STATUS_SHIPPED = 1;
+  }
+}
+
+// OrderService manages orders.
+service OrderService {
+  // GetOrder gets an order.
+  rpc GetOrder(Order) returns (Order);
+  rpc WatchOrders(stream Order) returns (stream Order) {
+    option deprecated = true;
+  }
+}
+`
+	testOptionsProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.MessageOptions {
+  // Audited messages are logged.
+  bool audited = 50000;
+}
+
+extend google.protobuf.FieldOptions {
+  bool sensitive = 50001;
+}
+`
+)
+
+func TestNewDocument(t *testing.T) {
+	t.Parallel()
+	document, err := NewDocument(context.Background(), newTestImage(t))
+	require.NoError(t, err)
+	require.Len(t, document.Packages, 1)
+	pkg := document.Packages[0]
+	assert.Equal(t, "acme.v1", pkg.Name)
+	assert.Equal(t, "Package acme.v1 manages orders.", pkg.Comments.String())
+	require.Len(t, pkg.Files, 2)
+	assert.Equal(t, "acme/v1/options.proto", pkg.Files[0].Path)
+	assert.Equal(t, "acme/v1/order.proto", pkg.Files[1].Path)
+
+	require.Len(t, pkg.Messages, 2)
+	order := pkg.Messages[0]
+	assert.Equal(t, "acme.v1.Order", order.FullName)
+	assert.Equal(t, "acme/v1/order.proto", order.FilePath)
+	assert.Equal(t, "Order is an order.\n\nOrders are immutable.", order.Comments.Leading)
+	assert.Equal(t, []string{"Orders."}, order.Comments.Detached)
+	assert.Equal(t, "Order is an order.\n\nOrders are immutable.", order.Comments.String())
+	assert.Equal(t, []*Option{{Name: "(acme.v1.audited)", Value: "true"}}, order.Options)
+	require.Len(t, order.Fields, 6)
+	assert.Equal(t, "The ID of the order.", order.Fields[0].Comments.Leading)
+	assert.Equal(t, "map<string, acme.v1.Order.Item>", order.Fields[1].Type)
+	assert.Equal(t, "acme.v1.Order.Item", order.Fields[1].TypeFullName)
+	assert.Equal(t, "", order.Fields[1].Label)
+	assert.True(t, order.Fields[2].Deprecated)
+	assert.Equal(t, "payment", order.Fields[3].Oneof)
+	assert.Equal(t, []*Option{{Name: "(acme.v1.sensitive)", Value: "true"}}, order.Fields[3].Options)
+	assert.Equal(t, "optional", order.Fields[5].Label)
+	assert.Equal(t, "", order.Fields[5].Oneof)
+	assert.Equal(t, "A note for the courier.", order.Fields[5].Comments.Trailing)
+	assert.Equal(t, "acme.v1.Order.Item", pkg.Messages[1].FullName)
+
+	require.Len(t, pkg.Enums, 1)
+	assert.Equal(t, "acme.v1.Order.Status", pkg.Enums[0].FullName)
+	require.Len(t, pkg.Enums[0].Values, 2)
+	assert.Equal(t, "The order shipped.", pkg.Enums[0].Values[1].Comments.Leading)
+
+	require.Len(t, pkg.Services, 1)
+	require.Len(t, pkg.Services[0].Methods, 2)
+	assert.Equal(t, "GetOrder gets an order.", pkg.Services[0].Methods[0].Comments.Leading)
+	watchOrders := pkg.Services[0].Methods[1]
+	assert.True(t, watchOrders.ClientStreaming)
+	assert.True(t, watchOrders.ServerStreaming)
+	assert.True(t, watchOrders.Deprecated)
+
+	require.Len(t, pkg.Extensions, 2)
+	assert.Equal(t, "acme.v1.audited", pkg.Extensions[0].FullName)
+	assert.Equal(t, "google.protobuf.MessageOptions", pkg.Extensions[0].Extendee)
+
+	comments, ok := document.CommentsForFullName("acme.v1.Order.STATUS_SHIPPED")
+	require.True(t, ok)
+	assert.Equal(t, "The order shipped.", comments.Leading)
+	comments, ok = document.CommentsForFullName(".acme.v1.Order.note")
+	require.True(t, ok)
+	assert.Equal(t, "A note for the courier.", comments.Trailing)
+	_, ok = document.CommentsForFullName("acme.v1.Order.missing")
+	assert.False(t, ok)
+	_, ok = document.CommentsForFullName("google.protobuf.DescriptorProto")
+	assert.False(t, ok)
+}
+
+func TestNewDocumentWithImports(t *testing.T) {
+	t.Parallel()
+	document, err := NewDocument(context.Background(), newTestImage(t), DocumentWithImports())
+	require.NoError(t, err)
+	require.Len(t, document.Packages, 2)
+	assert.Equal(t, "acme.v1", document.Packages[0].Name)
+	assert.False(t, document.Packages[0].Files[0].Import)
+	assert.Equal(t, "google.protobuf", document.Packages[1].Name)
+	require.Len(t, document.Packages[1].Files, 1)
+	assert.Equal(t, "google/protobuf/descriptor.proto", document.Packages[1].Files[0].Path)
+	assert.True(t, document.Packages[1].Files[0].Import)
+	comments, ok := document.CommentsForFullName("google.protobuf.DescriptorProto")
+	require.True(t, ok)
+	assert.Equal(t, "Describes a message type.", comments.Leading)
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	document, err := NewDocument(ctx, newTestImage(t))
+	require.NoError(t, err)
+	for _, format := range []Format{FormatMarkdown, FormatHTML} {
+		readWriteBucket := storagemem.NewReadWriteBucket()
+		require.NoError(t, Generate(ctx, readWriteBucket, format, document))
+		paths, err := storage.AllPaths(ctx, readWriteBucket, "")
+		require.NoError(t, err)
+		fileExtension, err := getFileExtension(format)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"acme.v1" + fileExtension, "index" + fileExtension}, paths)
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	document, err := NewDocument(ctx, newTestImage(t))
+	require.NoError(t, err)
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	require.NoError(t, Generate(ctx, readWriteBucket, FormatJSON, document))
+	paths, err := storage.AllPaths(ctx, readWriteBucket, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"document.json"}, paths)
+	data, err := storage.ReadPath(ctx, readWriteBucket, "document.json")
+	require.NoError(t, err)
+	var jsonDocument struct {
+		Packages []struct {
+			Name  string `json:"name"`
+			Enums []struct {
+				Values []map[string]interface{} `json:"values"`
+			} `json:"enums"`
+			Messages []struct {
+				FullName string `json:"full_name"`
+				Comments struct {
+					Leading  string   `json:"leading"`
+					Detached []string `json:"detached"`
+				} `json:"comments"`
+			} `json:"messages"`
+		} `json:"packages"`
+	}
+	require.NoError(t, json.Unmarshal(data, &jsonDocument))
+	require.Len(t, jsonDocument.Packages, 1)
+	assert.Equal(t, "acme.v1", jsonDocument.Packages[0].Name)
+	require.Len(t, jsonDocument.Packages[0].Messages, 2)
+	order := jsonDocument.Packages[0].Messages[0]
+	assert.Equal(t, "acme.v1.Order", order.FullName)
+	assert.Equal(t, "Order is an order.\n\nOrders are immutable.", order.Comments.Leading)
+	assert.Equal(t, []string{"Orders."}, order.Comments.Detached)
+	// The number of an enum value is written even if it is zero.
+	require.Len(t, jsonDocument.Packages[0].Enums, 1)
+	assert.Equal(t, float64(0), jsonDocument.Packages[0].Enums[0].Values[0]["number"])
+
+	templateReadBucket := storagemem.NewReadWriteBucket()
+	assert.Error(t, Generate(ctx, storagemem.NewReadWriteBucket(), FormatJSON, document, GenerateWithTemplates(templateReadBucket)))
+}
+
+func TestGenerateWithTemplates(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	document, err := NewDocument(ctx, newTestImage(t))
+	require.NoError(t, err)
+	templateReadBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"message.tmpl": []byte(`{{define "message"}}<section id="{{.FullName}}">{{summary .Comments.String}}</section>{{end}}`),
+			"README.md":    []byte(`{{define "message"}}ignored{{end}}`),
+		},
+	)
+	require.NoError(t, err)
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	require.NoError(t, Generate(ctx, readWriteBucket, FormatMarkdown, document, GenerateWithTemplates(templateReadBucket)))
+	data, err := storage.ReadPath(ctx, readWriteBucket, "acme.v1.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<section id="acme.v1.Order">Order is an order.</section>`)
+	assert.Contains(t, string(data), "## Services")
+	readWriteBucket = storagemem.NewReadWriteBucket()
+	require.NoError(t, Generate(ctx, readWriteBucket, FormatHTML, document, GenerateWithTemplates(templateReadBucket)))
+	data, err = storage.ReadPath(ctx, readWriteBucket, "acme.v1.html")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<section id="acme.v1.Order">Order is an order.</section>`)
+	assert.Contains(t, string(data), "<h2>Services</h2>")
+
+	emptyReadBucket, err := storagemem.NewReadBucket(nil)
+	require.NoError(t, err)
+	assert.Error(t, Generate(ctx, storagemem.NewReadWriteBucket(), FormatHTML, document, GenerateWithTemplates(emptyReadBucket)))
+}
+
+func newTestImage(t *testing.T) bufimage.Image {
+	return bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"acme/v1/order.proto":   testOrderProto,
+			"acme/v1/options.proto": testOptionsProto,
+		},
+	)
+}
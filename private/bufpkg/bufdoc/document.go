@@ -0,0 +1,469 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdoc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// filePackageTag is the tag of the package field of FileDescriptorProto.
+	filePackageTag = 2
+	// fileSyntaxTag is the tag of the syntax field of FileDescriptorProto.
+	fileSyntaxTag = 12
+)
+
+type documentOptions struct {
+	includeImports bool
+}
+
+func newDocumentOptions() *documentOptions {
+	return &documentOptions{}
+}
+
+func newDocument(ctx context.Context, image bufimage.Image, options ...DocumentOption) (*Document, error) {
+	documentOptions := newDocumentOptions()
+	for _, option := range options {
+		option(documentOptions)
+	}
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptorProtos(image)...)
+	if err != nil {
+		return nil, err
+	}
+	builder := &documentBuilder{
+		resolver:           resolver,
+		nameToPackage:      make(map[string]*Package),
+		fullNameToComments: make(map[string]Comments),
+	}
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() && !documentOptions.includeImports {
+			continue
+		}
+		fileDescriptor, err := resolver.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		if err := builder.addFile(fileDescriptor, imageFile.IsImport()); err != nil {
+			return nil, err
+		}
+	}
+	return builder.document(), nil
+}
+
+type documentBuilder struct {
+	resolver           protoencoding.Resolver
+	nameToPackage      map[string]*Package
+	fullNameToComments map[string]Comments
+}
+
+func (b *documentBuilder) document() *Document {
+	document := &Document{
+		fullNameToComments: b.fullNameToComments,
+	}
+	for _, pkg := range b.nameToPackage {
+		sort.Slice(pkg.Files, func(i int, j int) bool { return pkg.Files[i].Path < pkg.Files[j].Path })
+		sort.Slice(pkg.Messages, func(i int, j int) bool { return pkg.Messages[i].FullName < pkg.Messages[j].FullName })
+		sort.Slice(pkg.Enums, func(i int, j int) bool { return pkg.Enums[i].FullName < pkg.Enums[j].FullName })
+		sort.Slice(pkg.Services, func(i int, j int) bool { return pkg.Services[i].FullName < pkg.Services[j].FullName })
+		sort.Slice(pkg.Extensions, func(i int, j int) bool { return pkg.Extensions[i].FullName < pkg.Extensions[j].FullName })
+		var comments []string
+		for _, file := range pkg.Files {
+			if file.Comments.Leading != "" {
+				comments = append(comments, file.Comments.Leading)
+			}
+		}
+		pkg.Comments = Comments{Leading: strings.Join(comments, "\n\n")}
+		document.Packages = append(document.Packages, pkg)
+	}
+	sort.Slice(document.Packages, func(i int, j int) bool { return document.Packages[i].Name < document.Packages[j].Name })
+	return document
+}
+
+func (b *documentBuilder) addFile(fileDescriptor protoreflect.FileDescriptor, isImport bool) error {
+	packageName := string(fileDescriptor.Package())
+	pkg, ok := b.nameToPackage[packageName]
+	if !ok {
+		pkg = &Package{
+			Name: packageName,
+		}
+		b.nameToPackage[packageName] = pkg
+	}
+	options, err := b.getOptions(fileDescriptor)
+	if err != nil {
+		return err
+	}
+	sourcePath := protoreflect.SourcePath{filePackageTag}
+	if packageName == "" {
+		sourcePath = protoreflect.SourcePath{fileSyntaxTag}
+	}
+	pkg.Files = append(
+		pkg.Files,
+		&File{
+			Path:     fileDescriptor.Path(),
+			Import:   isImport,
+			Comments: newComments(fileDescriptor.SourceLocations().ByPath(sourcePath)),
+			Options:  options,
+		},
+	)
+	return b.addDeclarations(pkg, fileDescriptor)
+}
+
+// declarationContainer is a file or a message.
+type declarationContainer interface {
+	protoreflect.Descriptor
+	Messages() protoreflect.MessageDescriptors
+	Enums() protoreflect.EnumDescriptors
+	Extensions() protoreflect.ExtensionDescriptors
+}
+
+func (b *documentBuilder) addDeclarations(pkg *Package, container declarationContainer) error {
+	messageDescriptors := container.Messages()
+	for i := 0; i < messageDescriptors.Len(); i++ {
+		messageDescriptor := messageDescriptors.Get(i)
+		if messageDescriptor.IsMapEntry() {
+			continue
+		}
+		message, err := b.newMessage(messageDescriptor)
+		if err != nil {
+			return err
+		}
+		pkg.Messages = append(pkg.Messages, message)
+		if err := b.addDeclarations(pkg, messageDescriptor); err != nil {
+			return err
+		}
+	}
+	enumDescriptors := container.Enums()
+	for i := 0; i < enumDescriptors.Len(); i++ {
+		enum, err := b.newEnum(enumDescriptors.Get(i))
+		if err != nil {
+			return err
+		}
+		pkg.Enums = append(pkg.Enums, enum)
+	}
+	extensionDescriptors := container.Extensions()
+	for i := 0; i < extensionDescriptors.Len(); i++ {
+		extension, err := b.newField(extensionDescriptors.Get(i))
+		if err != nil {
+			return err
+		}
+		pkg.Extensions = append(pkg.Extensions, extension)
+	}
+	if fileDescriptor, ok := container.(protoreflect.FileDescriptor); ok {
+		serviceDescriptors := fileDescriptor.Services()
+		for i := 0; i < serviceDescriptors.Len(); i++ {
+			service, err := b.newService(serviceDescriptors.Get(i))
+			if err != nil {
+				return err
+			}
+			pkg.Services = append(pkg.Services, service)
+		}
+	}
+	return nil
+}
+
+func (b *documentBuilder) newMessage(messageDescriptor protoreflect.MessageDescriptor) (*Message, error) {
+	options, err := b.getOptions(messageDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	message := &Message{
+		Name:       string(messageDescriptor.Name()),
+		FullName:   string(messageDescriptor.FullName()),
+		FilePath:   messageDescriptor.ParentFile().Path(),
+		Comments:   b.getComments(messageDescriptor),
+		Options:    options,
+		Deprecated: isDeprecated(messageDescriptor),
+	}
+	fieldDescriptors := messageDescriptor.Fields()
+	for i := 0; i < fieldDescriptors.Len(); i++ {
+		field, err := b.newField(fieldDescriptors.Get(i))
+		if err != nil {
+			return nil, err
+		}
+		message.Fields = append(message.Fields, field)
+	}
+	return message, nil
+}
+
+func (b *documentBuilder) newField(fieldDescriptor protoreflect.FieldDescriptor) (*Field, error) {
+	options, err := b.getOptions(fieldDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	field := &Field{
+		Name:       string(fieldDescriptor.Name()),
+		FullName:   string(fieldDescriptor.FullName()),
+		JSONName:   fieldDescriptor.JSONName(),
+		Number:     int(fieldDescriptor.Number()),
+		Label:      getLabel(fieldDescriptor),
+		Comments:   b.getComments(fieldDescriptor),
+		Options:    options,
+		Deprecated: isDeprecated(fieldDescriptor),
+	}
+	if fieldDescriptor.IsMap() {
+		field.Type = fmt.Sprintf(
+			"map<%s, %s>",
+			getTypeName(fieldDescriptor.MapKey()),
+			getTypeName(fieldDescriptor.MapValue()),
+		)
+		field.TypeFullName = getTypeFullName(fieldDescriptor.MapValue())
+	} else {
+		field.Type = getTypeName(fieldDescriptor)
+		field.TypeFullName = getTypeFullName(fieldDescriptor)
+	}
+	if oneofDescriptor := fieldDescriptor.ContainingOneof(); oneofDescriptor != nil && !oneofDescriptor.IsSynthetic() {
+		field.Oneof = string(oneofDescriptor.Name())
+	}
+	if fieldDescriptor.IsExtension() {
+		field.Extendee = string(fieldDescriptor.ContainingMessage().FullName())
+	}
+	return field, nil
+}
+
+func (b *documentBuilder) newEnum(enumDescriptor protoreflect.EnumDescriptor) (*Enum, error) {
+	options, err := b.getOptions(enumDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	enum := &Enum{
+		Name:       string(enumDescriptor.Name()),
+		FullName:   string(enumDescriptor.FullName()),
+		FilePath:   enumDescriptor.ParentFile().Path(),
+		Comments:   b.getComments(enumDescriptor),
+		Options:    options,
+		Deprecated: isDeprecated(enumDescriptor),
+	}
+	valueDescriptors := enumDescriptor.Values()
+	for i := 0; i < valueDescriptors.Len(); i++ {
+		valueDescriptor := valueDescriptors.Get(i)
+		options, err := b.getOptions(valueDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		enum.Values = append(
+			enum.Values,
+			&EnumValue{
+				Name:       string(valueDescriptor.Name()),
+				FullName:   string(valueDescriptor.FullName()),
+				Number:     int(valueDescriptor.Number()),
+				Comments:   b.getComments(valueDescriptor),
+				Options:    options,
+				Deprecated: isDeprecated(valueDescriptor),
+			},
+		)
+	}
+	return enum, nil
+}
+
+func (b *documentBuilder) newService(serviceDescriptor protoreflect.ServiceDescriptor) (*Service, error) {
+	options, err := b.getOptions(serviceDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	service := &Service{
+		Name:       string(serviceDescriptor.Name()),
+		FullName:   string(serviceDescriptor.FullName()),
+		FilePath:   serviceDescriptor.ParentFile().Path(),
+		Comments:   b.getComments(serviceDescriptor),
+		Options:    options,
+		Deprecated: isDeprecated(serviceDescriptor),
+	}
+	methodDescriptors := serviceDescriptor.Methods()
+	for i := 0; i < methodDescriptors.Len(); i++ {
+		methodDescriptor := methodDescriptors.Get(i)
+		options, err := b.getOptions(methodDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		service.Methods = append(
+			service.Methods,
+			&Method{
+				Name:            string(methodDescriptor.Name()),
+				FullName:        string(methodDescriptor.FullName()),
+				InputType:       string(methodDescriptor.Input().FullName()),
+				OutputType:      string(methodDescriptor.Output().FullName()),
+				ClientStreaming: methodDescriptor.IsStreamingClient(),
+				ServerStreaming: methodDescriptor.IsStreamingServer(),
+				Comments:        b.getComments(methodDescriptor),
+				Options:         options,
+				Deprecated:      isDeprecated(methodDescriptor),
+			},
+		)
+	}
+	return service, nil
+}
+
+// getOptions returns the options that are set on the descriptor, sorted by name.
+//
+// Custom options are unknown fields of the options message until they are
+// parsed with the extensions of the image.
+func (b *documentBuilder) getOptions(descriptor protoreflect.Descriptor) ([]*Option, error) {
+	optionsMessage := descriptor.Options()
+	if optionsMessage == nil {
+		return nil, nil
+	}
+	optionsMessage = proto.Clone(optionsMessage)
+	reflectMessage := optionsMessage.ProtoReflect()
+	if !reflectMessage.IsValid() {
+		return nil, nil
+	}
+	if err := protoencoding.ReparseUnrecognized(b.resolver, reflectMessage); err != nil {
+		return nil, err
+	}
+	var options []*Option
+	var rangeErr error
+	reflectMessage.Range(func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		name := string(fieldDescriptor.Name())
+		if fieldDescriptor.IsExtension() {
+			name = "(" + string(fieldDescriptor.FullName()) + ")"
+		}
+		formattedValue, err := b.formatValue(fieldDescriptor, value)
+		if err != nil {
+			rangeErr = err
+			return false
+		}
+		options = append(options, &Option{Name: name, Value: formattedValue})
+		return true
+	})
+	if rangeErr != nil {
+		return nil, rangeErr
+	}
+	sort.Slice(options, func(i int, j int) bool { return options[i].Name < options[j].Name })
+	return options, nil
+}
+
+func (b *documentBuilder) formatValue(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) (string, error) {
+	if fieldDescriptor.IsList() {
+		list := value.List()
+		values := make([]string, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			formattedValue, err := b.formatSingularValue(fieldDescriptor, list.Get(i))
+			if err != nil {
+				return "", err
+			}
+			values[i] = formattedValue
+		}
+		return "[" + strings.Join(values, ", ") + "]", nil
+	}
+	return b.formatSingularValue(fieldDescriptor, value)
+}
+
+func (b *documentBuilder) formatSingularValue(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) (string, error) {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		data, err := protoencoding.NewJSONMarshaler(
+			b.resolver,
+			protoencoding.JSONMarshalerWithUseProtoNames(),
+		).Marshal(value.Message().Interface())
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case protoreflect.EnumKind:
+		if enumValueDescriptor := fieldDescriptor.Enum().Values().ByNumber(value.Enum()); enumValueDescriptor != nil {
+			return string(enumValueDescriptor.Name()), nil
+		}
+		return strconv.Itoa(int(value.Enum())), nil
+	case protoreflect.StringKind:
+		return strconv.Quote(value.String()), nil
+	case protoreflect.BytesKind:
+		return strconv.Quote(string(value.Bytes())), nil
+	default:
+		return value.String(), nil
+	}
+}
+
+// getComments returns the comments of the declaration, and records them so that
+// they can be looked up by the full name of the declaration.
+func (b *documentBuilder) getComments(descriptor protoreflect.Descriptor) Comments {
+	comments := newComments(descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor))
+	b.fullNameToComments[string(descriptor.FullName())] = comments
+	return comments
+}
+
+func newComments(sourceLocation protoreflect.SourceLocation) Comments {
+	var detached []string
+	for _, comment := range sourceLocation.LeadingDetachedComments {
+		if comment = cleanComment(comment); comment != "" {
+			detached = append(detached, comment)
+		}
+	}
+	return Comments{
+		Leading:  cleanComment(sourceLocation.LeadingComments),
+		Trailing: cleanComment(sourceLocation.TrailingComments),
+		Detached: detached,
+	}
+}
+
+// cleanComment removes the leading space of each line of the comment, and the
+// leading and trailing blank lines.
+func cleanComment(comment string) string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.TrimPrefix(line, " "), " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+func getLabel(fieldDescriptor protoreflect.FieldDescriptor) string {
+	switch {
+	case fieldDescriptor.IsMap():
+		return ""
+	case fieldDescriptor.Cardinality() == protoreflect.Repeated:
+		return "repeated"
+	case fieldDescriptor.Cardinality() == protoreflect.Required:
+		return "required"
+	case fieldDescriptor.HasOptionalKeyword():
+		return "optional"
+	default:
+		return ""
+	}
+}
+
+func getTypeName(fieldDescriptor protoreflect.FieldDescriptor) string {
+	if typeFullName := getTypeFullName(fieldDescriptor); typeFullName != "" {
+		return typeFullName
+	}
+	return fieldDescriptor.Kind().String()
+}
+
+func getTypeFullName(fieldDescriptor protoreflect.FieldDescriptor) string {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return string(fieldDescriptor.Message().FullName())
+	case protoreflect.EnumKind:
+		return string(fieldDescriptor.Enum().FullName())
+	default:
+		return ""
+	}
+}
+
+// isDeprecated returns true if the deprecated option of the descriptor is set.
+func isDeprecated(descriptor protoreflect.Descriptor) bool {
+	type deprecatedOptions interface {
+		GetDeprecated() bool
+	}
+	options, ok := descriptor.Options().(deprecatedOptions)
+	return ok && options.GetDeprecated()
+}
@@ -0,0 +1,258 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdoc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+)
+
+const (
+	indexTemplateName   = "index"
+	packageTemplateName = "package"
+
+	templateFileExtension = ".tmpl"
+
+	indexFileBaseName = "index"
+	// documentFilePath is the path of the file of the JSON format.
+	documentFilePath = "document.json"
+	// noPackageFileBaseName is the name of the file of the files without a package,
+	// which is not a valid package name.
+	noPackageFileBaseName = "no-package"
+	noPackageName         = "(no package)"
+)
+
+// templateExecutor is implemented by both text/template.Template and html/template.Template.
+type templateExecutor interface {
+	ExecuteTemplate(writer io.Writer, name string, data interface{}) error
+}
+
+func generate(
+	ctx context.Context,
+	writeBucket storage.WriteBucket,
+	format Format,
+	document *Document,
+	options ...GenerateOption,
+) error {
+	generateOptions := newGenerateOptions()
+	for _, option := range options {
+		option(generateOptions)
+	}
+	if format == FormatJSON {
+		if generateOptions.templateReadBucket != nil {
+			return fmt.Errorf("templates are not supported for the %s format", format)
+		}
+		data, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return err
+		}
+		return storage.PutPath(ctx, writeBucket, documentFilePath, append(data, '\n'))
+	}
+	fileExtension, err := getFileExtension(format)
+	if err != nil {
+		return err
+	}
+	var userTemplates []*userTemplate
+	if generateOptions.templateReadBucket != nil {
+		userTemplates, err = readUserTemplates(ctx, generateOptions.templateReadBucket)
+		if err != nil {
+			return err
+		}
+	}
+	executor, err := newTemplateExecutor(format, newTemplateFuncs(document, fileExtension), userTemplates)
+	if err != nil {
+		return err
+	}
+	if err := executeTemplate(ctx, writeBucket, executor, indexTemplateName, indexFileBaseName+fileExtension, document); err != nil {
+		return err
+	}
+	for _, pkg := range document.Packages {
+		if err := executeTemplate(ctx, writeBucket, executor, packageTemplateName, getPackageFileBaseName(pkg.Name)+fileExtension, pkg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type generateOptions struct {
+	templateReadBucket storage.ReadBucket
+}
+
+func newGenerateOptions() *generateOptions {
+	return &generateOptions{}
+}
+
+// userTemplate is a file of templates that replace the built-in templates.
+type userTemplate struct {
+	path string
+	text string
+}
+
+// readUserTemplates reads the files with the template extension of the bucket,
+// sorted by path.
+func readUserTemplates(ctx context.Context, readBucket storage.ReadBucket) ([]*userTemplate, error) {
+	paths, err := storage.AllPaths(ctx, readBucket, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var userTemplates []*userTemplate
+	for _, path := range paths {
+		if normalpath.Ext(path) != templateFileExtension {
+			continue
+		}
+		data, err := storage.ReadPath(ctx, readBucket, path)
+		if err != nil {
+			return nil, err
+		}
+		userTemplates = append(
+			userTemplates,
+			&userTemplate{
+				path: path,
+				text: string(data),
+			},
+		)
+	}
+	if len(userTemplates) == 0 {
+		return nil, fmt.Errorf("no %s files found in template directory", templateFileExtension)
+	}
+	return userTemplates, nil
+}
+
+// newTemplateExecutor parses the built-in templates of the format, and then the
+// user templates, so that the templates that the user templates define replace
+// the built-in templates with the same name.
+//
+// The text of each user template outside of its define actions is a template
+// named after the path of the file, which is not executed.
+func newTemplateExecutor(format Format, funcs map[string]interface{}, userTemplates []*userTemplate) (templateExecutor, error) {
+	switch format {
+	case FormatMarkdown:
+		template, err := texttemplate.New(indexTemplateName).Funcs(funcs).Parse(markdownTemplate)
+		if err != nil {
+			return nil, err
+		}
+		for _, userTemplate := range userTemplates {
+			if _, err := template.New(userTemplate.path).Parse(userTemplate.text); err != nil {
+				return nil, err
+			}
+		}
+		return template, nil
+	case FormatHTML:
+		template, err := htmltemplate.New(indexTemplateName).Funcs(funcs).Parse(htmlTemplate)
+		if err != nil {
+			return nil, err
+		}
+		for _, userTemplate := range userTemplates {
+			if _, err := template.New(userTemplate.path).Parse(userTemplate.text); err != nil {
+				return nil, err
+			}
+		}
+		return template, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+func executeTemplate(
+	ctx context.Context,
+	writeBucket storage.WriteBucket,
+	executor templateExecutor,
+	templateName string,
+	path string,
+	data interface{},
+) error {
+	buffer := bytes.NewBuffer(nil)
+	if err := executor.ExecuteTemplate(buffer, templateName, data); err != nil {
+		return err
+	}
+	return storage.PutPath(ctx, writeBucket, path, buffer.Bytes())
+}
+
+// newTemplateFuncs returns the functions that are available to templates.
+func newTemplateFuncs(document *Document, fileExtension string) map[string]interface{} {
+	fullNameToPackageName := make(map[string]string)
+	for _, pkg := range document.Packages {
+		for _, message := range pkg.Messages {
+			fullNameToPackageName[message.FullName] = pkg.Name
+		}
+		for _, enum := range pkg.Enums {
+			fullNameToPackageName[enum.FullName] = pkg.Name
+		}
+	}
+	return map[string]interface{}{
+		// packageName returns the name to display for the package.
+		"packageName": func(name string) string {
+			if name == "" {
+				return noPackageName
+			}
+			return name
+		},
+		// packageFile returns the path of the file of the package.
+		"packageFile": func(name string) string {
+			return getPackageFileBaseName(name) + fileExtension
+		},
+		// link returns the link to the message or enum with the given full name,
+		// or an empty string if it is not in the document.
+		"link": func(fullName string) string {
+			packageName, ok := fullNameToPackageName[fullName]
+			if !ok {
+				return ""
+			}
+			return getPackageFileBaseName(packageName) + fileExtension + "#" + fullName
+		},
+		// summary returns the first paragraph of the comments on one line.
+		"summary": func(comments string) string {
+			paragraph, _, _ := strings.Cut(comments, "\n\n")
+			return strings.Join(strings.Fields(paragraph), " ")
+		},
+		// cell escapes the text for a cell of a Markdown table.
+		"cell": func(text string) string {
+			return strings.ReplaceAll(
+				strings.ReplaceAll(strings.TrimSpace(text), "|", `\|`),
+				"\n",
+				"<br>",
+			)
+		},
+	}
+}
+
+func getFileExtension(format Format) (string, error) {
+	switch format {
+	case FormatMarkdown:
+		return ".md", nil
+	case FormatHTML:
+		return ".html", nil
+	default:
+		return "", fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+func getPackageFileBaseName(packageName string) string {
+	if packageName == "" {
+		return noPackageFileBaseName
+	}
+	return packageName
+}
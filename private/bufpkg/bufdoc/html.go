@@ -0,0 +1,115 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdoc
+
+// htmlTemplate is the template of the HTML format.
+const htmlTemplate = `{{define "index" -}}
+<!DOCTYPE html>
+<html>
+{{template "head" "Protobuf Documentation"}}
+<body>
+<h1>Protobuf Documentation</h1>
+<table>
+<tr><th>Package</th><th>Description</th></tr>
+{{range .Packages}}<tr><td><a href="{{packageFile .Name}}">{{packageName .Name}}</a></td><td>{{summary .Comments.String}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+{{end}}
+
+{{- define "package" -}}
+<!DOCTYPE html>
+<html>
+{{template "head" (packageName .Name)}}
+<body>
+<p><a href="index.html">Index</a></p>
+<h1>{{packageName .Name}}</h1>
+{{with .Comments.String}}<div class="comments">{{.}}</div>
+{{end}}<h2>Files</h2>
+<ul>
+{{range .Files}}<li><code>{{.Path}}</code></li>
+{{end}}</ul>
+{{if .Services}}<h2>Services</h2>
+{{range .Services}}{{template "service" .}}{{end}}{{end}}
+{{- if .Messages}}<h2>Messages</h2>
+{{range .Messages}}{{template "message" .}}{{end}}{{end}}
+{{- if .Enums}}<h2>Enums</h2>
+{{range .Enums}}{{template "enum" .}}{{end}}{{end}}
+{{- if .Extensions}}<h2>Extensions</h2>
+<table>
+<tr><th>Extension</th><th>Extends</th><th>Type</th><th>Number</th><th>Description</th></tr>
+{{range .Extensions}}<tr id="{{.FullName}}"><td><code>{{.FullName}}</code></td><td>{{template "type" .Extendee}}</td><td>{{template "fieldType" .}}</td><td>{{.Number}}</td><td>{{template "fieldDescription" .}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+{{end}}
+
+{{- define "head"}}<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+.comments, td.comments { white-space: pre-wrap; }
+.deprecated { color: #a00; font-weight: bold; }
+</style>
+</head>{{end}}
+
+{{- define "service"}}<h3 id="{{.FullName}}">{{.Name}}</h3>
+{{template "comments" .}}
+{{- template "options" .Options -}}
+<table>
+<tr><th>Method</th><th>Request</th><th>Response</th><th>Description</th></tr>
+{{range .Methods}}<tr id="{{.FullName}}"><td><code>{{.Name}}</code></td><td>{{if .ClientStreaming}}stream {{end}}{{template "type" .InputType}}</td><td>{{if .ServerStreaming}}stream {{end}}{{template "type" .OutputType}}</td><td class="comments">{{if .Deprecated}}<span class="deprecated">Deprecated.</span> {{end}}{{.Comments.String}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{- define "message"}}<h3 id="{{.FullName}}">{{.Name}}</h3>
+<p><code>{{.FullName}}</code></p>
+{{template "comments" .}}
+{{- template "options" .Options -}}
+{{if .Fields}}<table>
+<tr><th>Field</th><th>Type</th><th>Number</th><th>Description</th></tr>
+{{range .Fields}}<tr id="{{.FullName}}"><td><code>{{.Name}}</code></td><td>{{template "fieldType" .}}</td><td>{{.Number}}</td><td class="comments">{{template "fieldDescription" .}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+
+{{- define "enum"}}<h3 id="{{.FullName}}">{{.Name}}</h3>
+<p><code>{{.FullName}}</code></p>
+{{template "comments" .}}
+{{- template "options" .Options -}}
+<table>
+<tr><th>Name</th><th>Number</th><th>Description</th></tr>
+{{range .Values}}<tr><td><code>{{.Name}}</code></td><td>{{.Number}}</td><td class="comments">{{if .Deprecated}}<span class="deprecated">Deprecated.</span> {{end}}{{.Comments.String}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{- define "comments"}}{{if .Deprecated}}<p class="deprecated">Deprecated.</p>
+{{end}}{{with .Comments.String}}<div class="comments">{{.}}</div>
+{{end}}{{end}}
+
+{{- define "options"}}{{if .}}<p>Options:</p>
+<ul>
+{{range .}}<li><code>{{.Name}} = {{.Value}}</code></li>
+{{end}}</ul>
+{{end}}{{end}}
+
+{{- define "type"}}{{with link .}}<a href="{{.}}"><code>{{$}}</code></a>{{else}}<code>{{.}}</code>{{end}}{{end}}
+
+{{- define "fieldType"}}{{with .Label}}{{.}} {{end}}{{if .TypeFullName}}{{with link .TypeFullName}}<a href="{{.}}"><code>{{$.Type}}</code></a>{{else}}<code>{{.Type}}</code>{{end}}{{else}}<code>{{.Type}}</code>{{end}}{{end}}
+
+{{- define "fieldDescription"}}{{if .Deprecated}}<span class="deprecated">Deprecated.</span> {{end}}{{with .Oneof}}Oneof <code>{{.}}</code>. {{end}}{{.Comments.String}}{{range .Options}}{{if ne .Name "deprecated"}} <code>{{.Name}} = {{.Value}}</code>{{end}}{{end}}{{end}}
+`
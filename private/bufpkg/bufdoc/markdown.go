@@ -0,0 +1,101 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdoc
+
+// markdownTemplate is the template of the Markdown format.
+const markdownTemplate = `{{define "index" -}}
+# Protobuf Documentation
+
+| Package | Description |
+| --- | --- |
+{{range .Packages}}| [{{packageName .Name}}]({{packageFile .Name}}) | {{cell (summary .Comments.String)}} |
+{{end}}{{end}}
+
+{{- define "package" -}}
+# {{packageName .Name}}
+{{with .Comments.String}}
+{{.}}
+{{end}}
+## Files
+{{range .Files}}
+- ` + "`{{.Path}}`" + `{{end}}
+{{if .Services}}
+## Services
+{{range .Services}}{{template "service" .}}{{end}}{{end}}
+{{- if .Messages}}
+## Messages
+{{range .Messages}}{{template "message" .}}{{end}}{{end}}
+{{- if .Enums}}
+## Enums
+{{range .Enums}}{{template "enum" .}}{{end}}{{end}}
+{{- if .Extensions}}
+## Extensions
+
+| Extension | Extends | Type | Number | Description |
+| --- | --- | --- | --- | --- |
+{{range .Extensions}}| <a id="{{.FullName}}"></a>` + "`{{.FullName}}`" + ` | {{template "type" .Extendee}} | {{template "fieldType" .}} | {{.Number}} | {{template "fieldDescription" .}} |
+{{end}}{{end}}
+{{- end}}
+
+{{- define "service"}}
+### <a id="{{.FullName}}"></a>{{.Name}}
+{{template "comments" .}}
+{{- template "options" .Options}}
+| Method | Request | Response | Description |
+| --- | --- | --- | --- |
+{{range .Methods}}| ` + "`{{.Name}}`" + ` | {{if .ClientStreaming}}stream {{end}}{{template "type" .InputType}} | {{if .ServerStreaming}}stream {{end}}{{template "type" .OutputType}} | {{if .Deprecated}}**Deprecated.** {{end}}{{cell .Comments.String}} |
+{{end}}{{end}}
+
+{{- define "message"}}
+### <a id="{{.FullName}}"></a>{{.Name}}
+
+` + "`{{.FullName}}`" + `
+{{template "comments" .}}
+{{- template "options" .Options}}
+{{- if .Fields}}
+| Field | Type | Number | Description |
+| --- | --- | --- | --- |
+{{range .Fields}}| ` + "`{{.Name}}`" + ` | {{template "fieldType" .}} | {{.Number}} | {{template "fieldDescription" .}} |
+{{end}}{{end}}{{end}}
+
+{{- define "enum"}}
+### <a id="{{.FullName}}"></a>{{.Name}}
+
+` + "`{{.FullName}}`" + `
+{{template "comments" .}}
+{{- template "options" .Options}}
+| Name | Number | Description |
+| --- | --- | --- |
+{{range .Values}}| ` + "`{{.Name}}`" + ` | {{.Number}} | {{if .Deprecated}}**Deprecated.** {{end}}{{cell .Comments.String}} |
+{{end}}{{end}}
+
+{{- define "comments"}}{{if .Deprecated}}
+**Deprecated.**
+{{end}}{{with .Comments.String}}
+{{.}}
+{{end}}{{end}}
+
+{{- define "options"}}{{if .}}
+Options:
+{{range .}}
+- ` + "`{{.Name}} = {{.Value}}`" + `{{end}}
+{{end}}{{end}}
+
+{{- define "type"}}{{with link .}}[` + "`{{$}}`" + `]({{.}}){{else}}` + "`{{.}}`" + `{{end}}{{end}}
+
+{{- define "fieldType"}}{{with .Label}}{{.}} {{end}}{{if .TypeFullName}}{{with link .TypeFullName}}[` + "`{{$.Type}}`" + `]({{.}}){{else}}` + "`{{.Type}}`" + `{{end}}{{else}}` + "`{{.Type}}`" + `{{end}}{{end}}
+
+{{- define "fieldDescription"}}{{if .Deprecated}}**Deprecated.** {{end}}{{with .Oneof}}Oneof ` + "`{{.}}`" + `. {{end}}{{cell .Comments.String}}{{range .Options}}{{if ne .Name "deprecated"}} ` + "`{{.Name}} = {{.Value}}`" + `{{end}}{{end}}{{end}}
+`
@@ -0,0 +1,87 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufexample extracts example messages that are declared in a schema.
+//
+// Examples are declared either in the leading comments of a message, or with
+// a custom message option of type string that contains the example as JSON.
+//
+// In comments, an example starts with a line consisting of "@example", optionally
+// followed by the name of the example, and continues with the JSON of the example
+// up to the next blank line or the end of the comment:
+//
+//	// An order for a single product.
+//	//
+//	// @example single
+//	// {
+//	//   "id": "1234",
+//	//   "quantity": 1
+//	// }
+//	message Order { ... }
+package bufexample
+
+import (
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"google.golang.org/protobuf/proto"
+)
+
+// Example is an example message declared in a schema.
+type Example struct {
+	// TypeName is the fully-qualified name of the message.
+	TypeName string
+	// Name is the name of the example.
+	//
+	// If the example was not explicitly named, this is the 1-indexed
+	// position of the example among the examples of the message.
+	Name string
+	// FilePath is the path of the file that contains the message.
+	FilePath string
+	// Line is the 1-indexed line of the message declaration, or 0 if the
+	// image has no source code info.
+	Line int
+	// JSON is the example as it was declared.
+	JSON []byte
+	// Message is the example parsed as a message of type TypeName.
+	Message proto.Message
+}
+
+// Extract extracts and validates the examples of the non-import files in the image.
+//
+// Examples are validated by parsing them as JSON messages of the type they are declared
+// on. If any example fails to validate, an error describing all of the invalid examples
+// is returned.
+func Extract(image bufimage.Image, options ...ExtractOption) ([]*Example, error) {
+	return extract(image, options...)
+}
+
+// ExtractOption is an option for Extract.
+type ExtractOption func(*extractOptions)
+
+// ExtractWithOptionName says to also read examples from the custom message option with
+// the given fully-qualified name, for example "corp.example".
+//
+// The option must be of type string, or repeated string, and each value must be the JSON
+// of an example. The extension declaring the option must be contained in the image.
+func ExtractWithOptionName(optionName string) ExtractOption {
+	return func(extractOptions *extractOptions) {
+		extractOptions.optionName = optionName
+	}
+}
+
+// ExtractWithoutComments says to not read examples from comments.
+func ExtractWithoutComments() ExtractOption {
+	return func(extractOptions *extractOptions) {
+		extractOptions.excludeComments = true
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufexample
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.MessageOptions {
+  repeated string example = 50000;
+}
+
+// An order.
+//
+// @example single
+// {
+//   "id": "1234",
+//   "quantity": 1
+// }
+//
+// @example
+// {"id": "5678"}
+message Order {
+  option (example) = "{\"quantity\": 2}";
+
+  string id = 1;
+  int32 quantity = 2;
+
+  // @example
+  // {"sku": "abc"}
+  message Item {
+    string sku = 1;
+  }
+}
+
+message NoExamples {
+  string id = 1;
+}
+`
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+	image := testGetImage(t, testProto)
+
+	examples, err := Extract(image)
+	require.NoError(t, err)
+	require.Len(t, examples, 3)
+	assert.Equal(t, "acme.v1.Order", examples[0].TypeName)
+	assert.Equal(t, "single", examples[0].Name)
+	assert.Equal(t, "acme.proto", examples[0].FilePath)
+	assert.Equal(t, 21, examples[0].Line)
+	assert.Equal(t, "{\n\"id\": \"1234\",\n\"quantity\": 1\n}", string(examples[0].JSON))
+	assert.Equal(t, "acme.v1.Order", examples[1].TypeName)
+	assert.Equal(t, "2", examples[1].Name)
+	assert.Equal(t, "acme.v1.Order.Item", examples[2].TypeName)
+	assert.Equal(t, "1", examples[2].Name)
+
+	examples, err = Extract(image, ExtractWithoutComments(), ExtractWithOptionName("acme.v1.example"))
+	require.NoError(t, err)
+	require.Len(t, examples, 1)
+	assert.Equal(t, "acme.v1.Order", examples[0].TypeName)
+	assert.Equal(t, `{"quantity": 2}`, string(examples[0].JSON))
+
+	_, err = Extract(image, ExtractWithOptionName("acme.v1.missing"))
+	require.Error(t, err)
+}
+
+func TestExtractInvalid(t *testing.T) {
+	t.Parallel()
+	image := testGetImage(
+		t,
+		`syntax = "proto3";
+
+package acme.v1;
+
+// @example bad
+// {"unknown": 1}
+message Order {
+  string id = 1;
+}
+`,
+	)
+	_, err := Extract(image)
+	require.ErrorContains(t, err, `acme.proto:7: example "bad" of acme.v1.Order is invalid`)
+}
+
+func testGetImage(t *testing.T, content string) bufimage.Image {
+	return bufimagetesting.BuildImage(t, map[string]string{"acme.proto": content})
+}
@@ -0,0 +1,250 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufexample
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const commentExampleTag = "@example"
+
+func extract(image bufimage.Image, options ...ExtractOption) ([]*Example, error) {
+	extractOptions := newExtractOptions()
+	for _, option := range options {
+		option(extractOptions)
+	}
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptorProtos(image)...)
+	if err != nil {
+		return nil, err
+	}
+	var optionExtensionType protoreflect.ExtensionType
+	if extractOptions.optionName != "" {
+		optionExtensionType, err = getOptionExtensionType(resolver, extractOptions.optionName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	extractor := &extractor{
+		resolver:            resolver,
+		jsonUnmarshaler:     protoencoding.NewJSONUnmarshaler(resolver, protoencoding.JSONUnmarshalerWithDisallowUnknown()),
+		optionExtensionType: optionExtensionType,
+		excludeComments:     extractOptions.excludeComments,
+	}
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptor, err := files.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		if err := extractor.extractMessages(fileDescriptor, fileDescriptor.Messages()); err != nil {
+			return nil, err
+		}
+	}
+	if extractor.errs != nil {
+		return nil, extractor.errs
+	}
+	return extractor.examples, nil
+}
+
+type extractor struct {
+	resolver            protoencoding.Resolver
+	jsonUnmarshaler     protoencoding.Unmarshaler
+	optionExtensionType protoreflect.ExtensionType
+	excludeComments     bool
+
+	examples []*Example
+	errs     error
+}
+
+func (e *extractor) extractMessages(
+	fileDescriptor protoreflect.FileDescriptor,
+	messageDescriptors protoreflect.MessageDescriptors,
+) error {
+	for i := 0; i < messageDescriptors.Len(); i++ {
+		messageDescriptor := messageDescriptors.Get(i)
+		if messageDescriptor.IsMapEntry() {
+			continue
+		}
+		sourceLocation := fileDescriptor.SourceLocations().ByDescriptor(messageDescriptor)
+		var line int
+		if sourceLocation.Path != nil {
+			line = sourceLocation.StartLine + 1
+		}
+		var namedJSONs []*namedJSON
+		if !e.excludeComments {
+			namedJSONs = append(namedJSONs, parseCommentExamples(sourceLocation.LeadingComments)...)
+		}
+		if e.optionExtensionType != nil {
+			optionNamedJSONs, err := e.getOptionExamples(messageDescriptor)
+			if err != nil {
+				return err
+			}
+			namedJSONs = append(namedJSONs, optionNamedJSONs...)
+		}
+		for j, namedJSON := range namedJSONs {
+			name := namedJSON.name
+			if name == "" {
+				name = strconv.Itoa(j + 1)
+			}
+			message := dynamicpb.NewMessage(messageDescriptor)
+			if err := e.jsonUnmarshaler.Unmarshal(namedJSON.json, message); err != nil {
+				e.errs = multierr.Append(
+					e.errs,
+					fmt.Errorf(
+						"%s:%d: example %q of %s is invalid: %v",
+						fileDescriptor.Path(),
+						line,
+						name,
+						messageDescriptor.FullName(),
+						err,
+					),
+				)
+				continue
+			}
+			e.examples = append(
+				e.examples,
+				&Example{
+					TypeName: string(messageDescriptor.FullName()),
+					Name:     name,
+					FilePath: fileDescriptor.Path(),
+					Line:     line,
+					JSON:     namedJSON.json,
+					Message:  message,
+				},
+			)
+		}
+		if err := e.extractMessages(fileDescriptor, messageDescriptor.Messages()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) getOptionExamples(messageDescriptor protoreflect.MessageDescriptor) ([]*namedJSON, error) {
+	messageOptions, ok := messageDescriptor.Options().(*descriptorpb.MessageOptions)
+	if !ok || messageOptions == nil {
+		return nil, nil
+	}
+	data, err := proto.Marshal(messageOptions)
+	if err != nil {
+		return nil, err
+	}
+	// Custom options are unrecognized fields until the options are parsed
+	// with a resolver that contains the extension.
+	reparsedMessageOptions := &descriptorpb.MessageOptions{}
+	if err := (proto.UnmarshalOptions{Resolver: e.resolver}).Unmarshal(data, reparsedMessageOptions); err != nil {
+		return nil, err
+	}
+	extensionTypeDescriptor := e.optionExtensionType.TypeDescriptor()
+	reflectMessageOptions := reparsedMessageOptions.ProtoReflect()
+	if !reflectMessageOptions.Has(extensionTypeDescriptor) {
+		return nil, nil
+	}
+	value := reflectMessageOptions.Get(extensionTypeDescriptor)
+	if !extensionTypeDescriptor.IsList() {
+		return []*namedJSON{{json: []byte(value.String())}}, nil
+	}
+	list := value.List()
+	namedJSONs := make([]*namedJSON, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		namedJSONs = append(namedJSONs, &namedJSON{json: []byte(list.Get(i).String())})
+	}
+	return namedJSONs, nil
+}
+
+type namedJSON struct {
+	name string
+	json []byte
+}
+
+// parseCommentExamples parses the examples in the comments.
+//
+// An example starts with a line starting with "@example" and runs until the next
+// blank line, the next example, or the end of the comments.
+func parseCommentExamples(comments string) []*namedJSON {
+	var namedJSONs []*namedJSON
+	var current *namedJSON
+	var currentLines []string
+	flush := func() {
+		if current != nil {
+			current.json = []byte(strings.Join(currentLines, "\n"))
+			namedJSONs = append(namedJSONs, current)
+		}
+		current = nil
+		currentLines = nil
+	}
+	for _, line := range strings.Split(comments, "\n") {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, commentExampleTag) {
+			name := strings.TrimPrefix(trimmedLine, commentExampleTag)
+			if name == "" || name[0] == ' ' || name[0] == '\t' {
+				flush()
+				current = &namedJSON{name: strings.TrimSpace(name)}
+				continue
+			}
+		}
+		if current == nil {
+			continue
+		}
+		if trimmedLine == "" {
+			flush()
+			continue
+		}
+		currentLines = append(currentLines, trimmedLine)
+	}
+	flush()
+	return namedJSONs
+}
+
+func getOptionExtensionType(resolver protoencoding.Resolver, optionName string) (protoreflect.ExtensionType, error) {
+	extensionType, err := resolver.FindExtensionByName(protoreflect.FullName(optionName))
+	if err != nil {
+		return nil, fmt.Errorf("could not find option %q: %w", optionName, err)
+	}
+	extensionTypeDescriptor := extensionType.TypeDescriptor()
+	if containingMessage := extensionTypeDescriptor.ContainingMessage().FullName(); containingMessage != "google.protobuf.MessageOptions" {
+		return nil, fmt.Errorf("option %q must extend google.protobuf.MessageOptions but extends %s", optionName, containingMessage)
+	}
+	if extensionTypeDescriptor.Kind() != protoreflect.StringKind {
+		return nil, fmt.Errorf("option %q must be of type string but is of type %s", optionName, extensionTypeDescriptor.Kind())
+	}
+	return extensionType, nil
+}
+
+type extractOptions struct {
+	optionName      string
+	excludeComments bool
+}
+
+func newExtractOptions() *extractOptions {
+	return &extractOptions{}
+}
@@ -0,0 +1,181 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufgraphql converts the messages and services of an image to a GraphQL
+// schema in the schema definition language (SDL).
+//
+// Each unary method of a service is a field of the Query type if it does not have
+// side effects, and of the Mutation type otherwise, that takes the request as the
+// input argument and returns the response. The messages that are used in requests
+// are input types with the suffix "Input", and the messages that are used in
+// responses are object types. Enums are enums, and map fields are lists of the
+// entries of the map.
+//
+// The schema is a starting point for a gateway layer: the fields are stubs, and
+// the resolvers that call the methods are not generated.
+package bufgraphql
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+)
+
+const (
+	// TypeNameStyleShort names types by their names within their package, with
+	// the names of nested types joined with underscores, for example Order_Item.
+	TypeNameStyleShort TypeNameStyle = 1
+	// TypeNameStyleFull names types by their fully-qualified names, with the parts
+	// joined with underscores, for example acme_v1_Order_Item.
+	TypeNameStyleFull TypeNameStyle = 2
+
+	// FieldNameStyleJSON names fields by their JSON names, for example createTime.
+	FieldNameStyleJSON FieldNameStyle = 1
+	// FieldNameStyleProto names fields by their names, for example create_time.
+	FieldNameStyleProto FieldNameStyle = 2
+)
+
+var (
+	// AllTypeNameStylesString is the string representation of all TypeNameStyles.
+	AllTypeNameStylesString = stringutil.SliceToString([]string{TypeNameStyleShort.String(), TypeNameStyleFull.String()})
+	// AllFieldNameStylesString is the string representation of all FieldNameStyles.
+	AllFieldNameStylesString = stringutil.SliceToString([]string{FieldNameStyleJSON.String(), FieldNameStyleProto.String()})
+
+	// DefaultQueryMethodPrefixes are the prefixes of the names of the methods that are
+	// queries by default.
+	DefaultQueryMethodPrefixes = []string{"Get", "List", "Search"}
+)
+
+// TypeNameStyle is how messages and enums are named in the schema.
+type TypeNameStyle int
+
+// ParseTypeNameStyle parses the TypeNameStyle.
+func ParseTypeNameStyle(s string) (TypeNameStyle, error) {
+	switch s {
+	case "short":
+		return TypeNameStyleShort, nil
+	case "full":
+		return TypeNameStyleFull, nil
+	default:
+		return 0, fmt.Errorf("unknown type name style: %s", s)
+	}
+}
+
+// String implements fmt.Stringer.
+func (t TypeNameStyle) String() string {
+	switch t {
+	case TypeNameStyleShort:
+		return "short"
+	case TypeNameStyleFull:
+		return "full"
+	default:
+		return strconv.Itoa(int(t))
+	}
+}
+
+// FieldNameStyle is how fields are named in the schema.
+type FieldNameStyle int
+
+// ParseFieldNameStyle parses the FieldNameStyle.
+func ParseFieldNameStyle(s string) (FieldNameStyle, error) {
+	switch s {
+	case "json":
+		return FieldNameStyleJSON, nil
+	case "proto":
+		return FieldNameStyleProto, nil
+	default:
+		return 0, fmt.Errorf("unknown field name style: %s", s)
+	}
+}
+
+// String implements fmt.Stringer.
+func (f FieldNameStyle) String() string {
+	switch f {
+	case FieldNameStyleJSON:
+		return "json"
+	case FieldNameStyleProto:
+		return "proto"
+	default:
+		return strconv.Itoa(int(f))
+	}
+}
+
+// Generate generates the GraphQL schema of the services of the files of the image
+// that are not imports.
+//
+// An error is returned if two types, or two methods, have the same name in the
+// schema.
+func Generate(image bufimage.Image, options ...GenerateOption) ([]byte, error) {
+	return generate(image, options...)
+}
+
+// GenerateOption is an option for Generate.
+type GenerateOption func(*generateOptions)
+
+// GenerateWithServiceNames says to only include the services with the given
+// fully-qualified names.
+//
+// An error is returned if any of the services is not declared in the image.
+func GenerateWithServiceNames(serviceNames ...string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.serviceNames = append(generateOptions.serviceNames, serviceNames...)
+	}
+}
+
+// GenerateWithTypeNameStyle sets how messages and enums are named.
+//
+// The default is TypeNameStyleShort.
+func GenerateWithTypeNameStyle(typeNameStyle TypeNameStyle) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.typeNameStyle = typeNameStyle
+	}
+}
+
+// GenerateWithFieldNameStyle sets how fields are named.
+//
+// The default is FieldNameStyleJSON.
+func GenerateWithFieldNameStyle(fieldNameStyle FieldNameStyle) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.fieldNameStyle = fieldNameStyle
+	}
+}
+
+// GenerateWithScalarMapping maps the Protobuf type to the GraphQL type.
+//
+// The Protobuf type is either the name of a scalar type, such as int64, or the
+// fully-qualified name of a well-known type that has a special JSON representation,
+// such as google.protobuf.Timestamp. A GraphQL type that is not a built-in scalar
+// is declared as a custom scalar.
+//
+// By default, 64-bit integers and bytes are Strings, unsigned 32-bit integers are
+// Floats, timestamps, durations, and field masks are Strings, wrappers are their
+// wrapped type, and structs, values, list values, and Anys are the custom JSON scalar.
+func GenerateWithScalarMapping(protobufType string, graphQLType string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.scalarMappings[protobufType] = graphQLType
+	}
+}
+
+// GenerateWithQueryMethodPrefixes sets the prefixes of the names of the methods that
+// are queries instead of mutations.
+//
+// Methods with the idempotency level NO_SIDE_EFFECTS are always queries. The default
+// is DefaultQueryMethodPrefixes.
+func GenerateWithQueryMethodPrefixes(queryMethodPrefixes ...string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.queryMethodPrefixes = queryMethodPrefixes
+	}
+}
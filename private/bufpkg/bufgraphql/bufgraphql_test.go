@@ -0,0 +1,199 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgraphql
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+
+// Manages orders.
+service OrderService {
+  // Gets an order.
+  rpc GetOrder(GetOrderRequest) returns (Order);
+  rpc CancelOrder(CancelOrderRequest) returns (Order) {
+    option deprecated = true;
+  }
+  rpc CountOrders(CountOrdersRequest) returns (CountOrdersResponse) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+  rpc WatchOrders(GetOrderRequest) returns (stream Order);
+}
+
+message GetOrderRequest {
+  string name = 1;
+}
+
+message CancelOrderRequest {
+  string name = 1;
+  // Why the order is canceled.
+  optional string reason = 2;
+}
+
+message CountOrdersRequest {}
+
+message CountOrdersResponse {
+  int64 count = 1;
+}
+
+// An order.
+message Order {
+  string name = 1;
+  Status status = 2;
+  google.protobuf.Timestamp create_time = 3;
+  repeated Item items = 4;
+  map<string, string> labels = 5;
+  google.protobuf.Struct metadata = 6;
+  int32 legacy_id = 7 [deprecated = true];
+
+  message Item {
+    string sku = 1;
+  }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  // The order is open.
+  STATUS_OPEN = 1;
+}
+`
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	data, err := Generate(bufimagetesting.BuildImage(t, map[string]string{"acme/v1/order.proto": testProto}))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`scalar JSON
+
+type Query {
+  """Gets an order."""
+  getOrder(input: GetOrderRequestInput!): Order
+  countOrders: CountOrdersResponse
+}
+
+type Mutation {
+  cancelOrder(input: CancelOrderRequestInput!): Order @deprecated
+}
+
+type CountOrdersResponse {
+  count: String!
+}
+
+"""An order."""
+type Order {
+  name: String!
+  status: Status!
+  createTime: String
+  items: [Order_Item!]!
+  labels: [Order_LabelsEntry!]!
+  metadata: JSON
+  legacyId: Int! @deprecated
+}
+
+type Order_Item {
+  sku: String!
+}
+
+type Order_LabelsEntry {
+  key: String!
+  value: String!
+}
+
+input CancelOrderRequestInput {
+  name: String
+  """Why the order is canceled."""
+  reason: String
+}
+
+input GetOrderRequestInput {
+  name: String
+}
+
+enum Status {
+  STATUS_UNSPECIFIED
+  """The order is open."""
+  STATUS_OPEN
+}
+`,
+		string(data),
+	)
+}
+
+func TestGenerateWithOptions(t *testing.T) {
+	t.Parallel()
+	data, err := Generate(
+		bufimagetesting.BuildImage(t, map[string]string{"acme/v1/order.proto": testProto}),
+		GenerateWithTypeNameStyle(TypeNameStyleFull),
+		GenerateWithFieldNameStyle(FieldNameStyleProto),
+		GenerateWithScalarMapping("int64", "Long"),
+		GenerateWithScalarMapping("google.protobuf.Timestamp", "DateTime"),
+		GenerateWithQueryMethodPrefixes("Watch"),
+	)
+	require.NoError(t, err)
+	sdl := string(data)
+	assert.Contains(t, sdl, "scalar DateTime\n\nscalar JSON\n\nscalar Long\n\n")
+	assert.Contains(t, sdl, "type Mutation {\n  \"\"\"Gets an order.\"\"\"\n  getOrder(input: acme_v1_GetOrderRequestInput!): acme_v1_Order\n")
+	assert.Contains(t, sdl, "  create_time: DateTime\n")
+	assert.Contains(t, sdl, "type acme_v1_CountOrdersResponse {\n  count: Long!\n}")
+	assert.Contains(t, sdl, "type Query {\n  countOrders: acme_v1_CountOrdersResponse\n}")
+
+	_, err = Generate(
+		bufimagetesting.BuildImage(t, map[string]string{"acme/v1/order.proto": testProto}),
+		GenerateWithScalarMapping("acme.v1.Order", "Order"),
+	)
+	require.EqualError(t, err, `cannot map "acme.v1.Order" to a GraphQL scalar, only scalar types and well-known types can be mapped`)
+}
+
+func TestGenerateNameConflict(t *testing.T) {
+	t.Parallel()
+	image := bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"acme/v1/order.proto": `syntax = "proto3";
+package acme.v1;
+import "acme/v2/order.proto";
+service OrderService {
+  rpc GetOrder(GetOrderRequest) returns (acme.v2.Order);
+}
+message GetOrderRequest {
+  Order order = 1;
+}
+message Order {}
+`,
+			"acme/v2/order.proto": `syntax = "proto3";
+package acme.v2;
+message Order {
+  string name = 1;
+}
+`,
+		},
+	)
+	_, err := Generate(image)
+	require.EqualError(t, err, "acme.v2.Order and acme.v1.Order both have the GraphQL name Order, use the full type name style")
+	data, err := Generate(image, GenerateWithTypeNameStyle(TypeNameStyleFull))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "input acme_v1_OrderInput {\n  _: Boolean\n}")
+}
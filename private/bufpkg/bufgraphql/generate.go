@@ -0,0 +1,571 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgraphql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	inputTypeNameSuffix = "Input"
+	inputArgumentName   = "input"
+	jsonScalarName      = "JSON"
+	// placeholderFieldName is the name of the field of the types of messages without
+	// fields, as GraphQL types must have at least one field.
+	placeholderFieldName = "_"
+)
+
+var builtinScalarNames = map[string]struct{}{
+	"Int":     {},
+	"Float":   {},
+	"String":  {},
+	"Boolean": {},
+	"ID":      {},
+}
+
+// defaultScalarMappings are the default GraphQL types of the Protobuf scalar types,
+// and of the well-known types that have a special JSON representation.
+//
+// The wrappers are not included, as they are mapped to the GraphQL type of the
+// scalar type that they wrap.
+var defaultScalarMappings = map[string]string{
+	protoreflect.BoolKind.String():     "Boolean",
+	protoreflect.StringKind.String():   "String",
+	protoreflect.BytesKind.String():    "String",
+	protoreflect.Int32Kind.String():    "Int",
+	protoreflect.Sint32Kind.String():   "Int",
+	protoreflect.Sfixed32Kind.String(): "Int",
+	protoreflect.Uint32Kind.String():   "Float",
+	protoreflect.Fixed32Kind.String():  "Float",
+	protoreflect.Int64Kind.String():    "String",
+	protoreflect.Sint64Kind.String():   "String",
+	protoreflect.Sfixed64Kind.String(): "String",
+	protoreflect.Uint64Kind.String():   "String",
+	protoreflect.Fixed64Kind.String():  "String",
+	protoreflect.FloatKind.String():    "Float",
+	protoreflect.DoubleKind.String():   "Float",
+	"google.protobuf.Timestamp":        "String",
+	"google.protobuf.Duration":         "String",
+	"google.protobuf.FieldMask":        "String",
+	"google.protobuf.Struct":           jsonScalarName,
+	"google.protobuf.Value":            jsonScalarName,
+	"google.protobuf.ListValue":        jsonScalarName,
+	"google.protobuf.Any":              jsonScalarName,
+}
+
+var wrapperFullNameToKind = map[protoreflect.FullName]protoreflect.Kind{
+	"google.protobuf.BoolValue":   protoreflect.BoolKind,
+	"google.protobuf.StringValue": protoreflect.StringKind,
+	"google.protobuf.BytesValue":  protoreflect.BytesKind,
+	"google.protobuf.Int32Value":  protoreflect.Int32Kind,
+	"google.protobuf.UInt32Value": protoreflect.Uint32Kind,
+	"google.protobuf.Int64Value":  protoreflect.Int64Kind,
+	"google.protobuf.UInt64Value": protoreflect.Uint64Kind,
+	"google.protobuf.FloatValue":  protoreflect.FloatKind,
+	"google.protobuf.DoubleValue": protoreflect.DoubleKind,
+}
+
+func generate(image bufimage.Image, options ...GenerateOption) ([]byte, error) {
+	generateOptions := newGenerateOptions()
+	for _, option := range options {
+		option(generateOptions)
+	}
+	scalarMappings := make(map[string]string, len(defaultScalarMappings))
+	for protobufType, graphQLType := range defaultScalarMappings {
+		scalarMappings[protobufType] = graphQLType
+	}
+	for protobufType, graphQLType := range generateOptions.scalarMappings {
+		if _, ok := defaultScalarMappings[protobufType]; !ok {
+			return nil, fmt.Errorf("cannot map %q to a GraphQL scalar, only scalar types and well-known types can be mapped", protobufType)
+		}
+		if !isName(graphQLType) {
+			return nil, fmt.Errorf("%q is not a valid GraphQL name", graphQLType)
+		}
+		scalarMappings[protobufType] = graphQLType
+	}
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	serviceNameToFound := make(map[string]bool, len(generateOptions.serviceNames))
+	for _, serviceName := range generateOptions.serviceNames {
+		serviceNameToFound[strings.TrimPrefix(serviceName, ".")] = false
+	}
+	generator := &generator{
+		generateOptions:       generateOptions,
+		scalarMappings:        scalarMappings,
+		customScalarNames:     make(map[string]struct{}),
+		nameToFullName:        make(map[string]string),
+		nameToObjectType:      make(map[string]*objectType),
+		nameToInputObjectType: make(map[string]*objectType),
+		nameToEnumType:        make(map[string]*enumType),
+		query:                 &objectType{name: "Query"},
+		mutation:              &objectType{name: "Mutation"},
+	}
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptor, err := files.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		serviceDescriptors := fileDescriptor.Services()
+		for i := 0; i < serviceDescriptors.Len(); i++ {
+			serviceDescriptor := serviceDescriptors.Get(i)
+			serviceName := string(serviceDescriptor.FullName())
+			if len(serviceNameToFound) > 0 {
+				if _, ok := serviceNameToFound[serviceName]; !ok {
+					continue
+				}
+				serviceNameToFound[serviceName] = true
+			}
+			methodDescriptors := serviceDescriptor.Methods()
+			for j := 0; j < methodDescriptors.Len(); j++ {
+				if err := generator.addMethod(methodDescriptors.Get(j)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for _, serviceName := range generateOptions.serviceNames {
+		if !serviceNameToFound[strings.TrimPrefix(serviceName, ".")] {
+			return nil, fmt.Errorf("service %q is not declared in the input", serviceName)
+		}
+	}
+	return generator.print(), nil
+}
+
+type generator struct {
+	generateOptions   *generateOptions
+	scalarMappings    map[string]string
+	customScalarNames map[string]struct{}
+	// nameToFullName is used to detect messages and enums with the same name.
+	nameToFullName        map[string]string
+	nameToObjectType      map[string]*objectType
+	nameToInputObjectType map[string]*objectType
+	nameToEnumType        map[string]*enumType
+	query                 *objectType
+	mutation              *objectType
+}
+
+type objectType struct {
+	name        string
+	description string
+	fields      []*field
+}
+
+type field struct {
+	name        string
+	description string
+	arguments   string
+	typeName    string
+	deprecated  bool
+}
+
+type enumType struct {
+	name        string
+	description string
+	values      []*field
+}
+
+func (g *generator) addMethod(methodDescriptor protoreflect.MethodDescriptor) error {
+	if methodDescriptor.IsStreamingClient() || methodDescriptor.IsStreamingServer() {
+		return nil
+	}
+	outputTypeName, err := g.getMessageTypeName(methodDescriptor.Output(), false)
+	if err != nil {
+		return err
+	}
+	operation := &field{
+		name:        lowerCamelCase(string(methodDescriptor.Name())),
+		description: getDescription(methodDescriptor),
+		typeName:    outputTypeName,
+	}
+	// Input types must have fields, so requests without fields are not arguments.
+	if methodDescriptor.Input().Fields().Len() > 0 {
+		inputTypeName, err := g.getMessageTypeName(methodDescriptor.Input(), true)
+		if err != nil {
+			return err
+		}
+		operation.arguments = inputArgumentName + ": " + inputTypeName + "!"
+	}
+	methodOptions, _ := methodDescriptor.Options().(*descriptorpb.MethodOptions)
+	operation.deprecated = methodOptions.GetDeprecated()
+	parent := g.mutation
+	if g.isQuery(methodDescriptor.Name(), methodOptions) {
+		parent = g.query
+	}
+	for _, existingOperation := range parent.fields {
+		if existingOperation.name == operation.name {
+			return fmt.Errorf(
+				"%s is mapped to %s.%s, which is already the field of another method",
+				methodDescriptor.FullName(),
+				parent.name,
+				operation.name,
+			)
+		}
+	}
+	parent.fields = append(parent.fields, operation)
+	return nil
+}
+
+func (g *generator) isQuery(methodName protoreflect.Name, methodOptions *descriptorpb.MethodOptions) bool {
+	if methodOptions.GetIdempotencyLevel() == descriptorpb.MethodOptions_NO_SIDE_EFFECTS {
+		return true
+	}
+	for _, queryMethodPrefix := range g.generateOptions.queryMethodPrefixes {
+		if strings.HasPrefix(string(methodName), queryMethodPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// getMessageTypeName returns the name of the GraphQL type of the message, and adds
+// the type if it was not already added.
+func (g *generator) getMessageTypeName(messageDescriptor protoreflect.MessageDescriptor, input bool) (string, error) {
+	if scalarName, ok := g.getWellKnownTypeScalarName(messageDescriptor); ok {
+		return scalarName, nil
+	}
+	name, err := g.getTypeName(messageDescriptor)
+	if err != nil {
+		return "", err
+	}
+	nameToObjectType := g.nameToObjectType
+	if input {
+		name += inputTypeNameSuffix
+		nameToObjectType = g.nameToInputObjectType
+	}
+	if _, ok := nameToObjectType[name]; ok {
+		return name, nil
+	}
+	messageObjectType := &objectType{
+		name:        name,
+		description: getDescription(messageDescriptor),
+	}
+	// The type is added before the fields so that recursive messages terminate.
+	nameToObjectType[name] = messageObjectType
+	fieldDescriptors := messageDescriptor.Fields()
+	for i := 0; i < fieldDescriptors.Len(); i++ {
+		fieldDescriptor := fieldDescriptors.Get(i)
+		typeName, err := g.getFieldTypeName(fieldDescriptor, input)
+		if err != nil {
+			return "", err
+		}
+		fieldOptions, _ := fieldDescriptor.Options().(*descriptorpb.FieldOptions)
+		messageObjectType.fields = append(
+			messageObjectType.fields,
+			&field{
+				name:        g.getFieldName(fieldDescriptor),
+				description: getDescription(fieldDescriptor),
+				typeName:    typeName,
+				// Input fields cannot be deprecated in the October 2021 edition of GraphQL.
+				deprecated: !input && fieldOptions.GetDeprecated(),
+			},
+		)
+	}
+	if len(messageObjectType.fields) == 0 {
+		messageObjectType.fields = []*field{
+			{
+				name:     placeholderFieldName,
+				typeName: "Boolean",
+			},
+		}
+	}
+	return name, nil
+}
+
+// getFieldTypeName returns the GraphQL type of the field.
+//
+// In object types, fields without explicit presence are non-null, as they always
+// have a value. In input types, all fields are nullable, as they may be omitted.
+func (g *generator) getFieldTypeName(fieldDescriptor protoreflect.FieldDescriptor, input bool) (string, error) {
+	var typeName string
+	var err error
+	switch {
+	case fieldDescriptor.IsMap():
+		// The map entry is a message with key and value fields.
+		typeName, err = g.getMessageTypeName(fieldDescriptor.Message(), input)
+	default:
+		typeName, err = g.getSingularFieldTypeName(fieldDescriptor, input)
+	}
+	if err != nil {
+		return "", err
+	}
+	if fieldDescriptor.IsList() || fieldDescriptor.IsMap() {
+		typeName = "[" + typeName + "!]"
+		if !input {
+			typeName += "!"
+		}
+		return typeName, nil
+	}
+	if !input && !fieldDescriptor.HasPresence() {
+		typeName += "!"
+	}
+	return typeName, nil
+}
+
+func (g *generator) getSingularFieldTypeName(fieldDescriptor protoreflect.FieldDescriptor, input bool) (string, error) {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.EnumKind:
+		return g.getEnumTypeName(fieldDescriptor.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.getMessageTypeName(fieldDescriptor.Message(), input)
+	default:
+		return g.getScalarName(fieldDescriptor.Kind().String()), nil
+	}
+}
+
+func (g *generator) getEnumTypeName(enumDescriptor protoreflect.EnumDescriptor) (string, error) {
+	if enumDescriptor.FullName() == "google.protobuf.NullValue" {
+		return g.getScalarName("google.protobuf.Value"), nil
+	}
+	name, err := g.getTypeName(enumDescriptor)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := g.nameToEnumType[name]; ok {
+		return name, nil
+	}
+	enumValueDescriptors := enumDescriptor.Values()
+	values := make([]*field, 0, enumValueDescriptors.Len())
+	for i := 0; i < enumValueDescriptors.Len(); i++ {
+		enumValueDescriptor := enumValueDescriptors.Get(i)
+		enumValueOptions, _ := enumValueDescriptor.Options().(*descriptorpb.EnumValueOptions)
+		values = append(
+			values,
+			&field{
+				name:        string(enumValueDescriptor.Name()),
+				description: getDescription(enumValueDescriptor),
+				deprecated:  enumValueOptions.GetDeprecated(),
+			},
+		)
+	}
+	g.nameToEnumType[name] = &enumType{
+		name:        name,
+		description: getDescription(enumDescriptor),
+		values:      values,
+	}
+	return name, nil
+}
+
+// getTypeName returns the name of the message or enum in the style of the options.
+func (g *generator) getTypeName(descriptor protoreflect.Descriptor) (string, error) {
+	fullName := string(descriptor.FullName())
+	name := fullName
+	if g.generateOptions.typeNameStyle == TypeNameStyleShort {
+		if packageName := string(descriptor.ParentFile().Package()); packageName != "" {
+			name = strings.TrimPrefix(name, packageName+".")
+		}
+	}
+	name = strings.ReplaceAll(name, ".", "_")
+	if existingFullName, ok := g.nameToFullName[name]; ok && existingFullName != fullName {
+		return "", fmt.Errorf(
+			"%s and %s both have the GraphQL name %s, use the %s type name style",
+			existingFullName,
+			fullName,
+			name,
+			TypeNameStyleFull,
+		)
+	}
+	g.nameToFullName[name] = fullName
+	return name, nil
+}
+
+func (g *generator) getFieldName(fieldDescriptor protoreflect.FieldDescriptor) string {
+	if g.generateOptions.fieldNameStyle == FieldNameStyleProto {
+		return string(fieldDescriptor.Name())
+	}
+	return fieldDescriptor.JSONName()
+}
+
+// getWellKnownTypeScalarName returns the name of the scalar of the well-known type,
+// or false if the message is not a well-known type that is mapped to a scalar.
+func (g *generator) getWellKnownTypeScalarName(messageDescriptor protoreflect.MessageDescriptor) (string, bool) {
+	fullName := messageDescriptor.FullName()
+	if kind, ok := wrapperFullNameToKind[fullName]; ok {
+		return g.getScalarName(kind.String()), true
+	}
+	if _, ok := g.scalarMappings[string(fullName)]; ok {
+		return g.getScalarName(string(fullName)), true
+	}
+	return "", false
+}
+
+// getScalarName returns the name of the GraphQL scalar of the Protobuf type, and
+// records it if it is a custom scalar.
+func (g *generator) getScalarName(protobufType string) string {
+	scalarName := g.scalarMappings[protobufType]
+	if _, ok := builtinScalarNames[scalarName]; !ok {
+		g.customScalarNames[scalarName] = struct{}{}
+	}
+	return scalarName
+}
+
+func (g *generator) print() []byte {
+	printer := &printer{}
+	for _, customScalarName := range slicesext.MapKeysToSortedSlice(g.customScalarNames) {
+		printer.P("scalar ", customScalarName)
+		printer.P()
+	}
+	query := g.query
+	if len(query.fields) == 0 {
+		// The schema must have a Query type with at least one field.
+		query.fields = []*field{
+			{
+				name:     placeholderFieldName,
+				typeName: "Boolean",
+			},
+		}
+	}
+	printer.printObjectType("type", query)
+	if len(g.mutation.fields) > 0 {
+		printer.printObjectType("type", g.mutation)
+	}
+	for _, name := range slicesext.MapKeysToSortedSlice(g.nameToObjectType) {
+		printer.printObjectType("type", g.nameToObjectType[name])
+	}
+	for _, name := range slicesext.MapKeysToSortedSlice(g.nameToInputObjectType) {
+		printer.printObjectType("input", g.nameToInputObjectType[name])
+	}
+	for _, name := range slicesext.MapKeysToSortedSlice(g.nameToEnumType) {
+		printer.printEnumType(g.nameToEnumType[name])
+	}
+	return printer.Bytes()
+}
+
+type printer struct {
+	strings.Builder
+}
+
+func (p *printer) P(args ...string) {
+	for _, arg := range args {
+		p.WriteString(arg)
+	}
+	p.WriteString("\n")
+}
+
+func (p *printer) Bytes() []byte {
+	return []byte(strings.TrimSuffix(p.String(), "\n"))
+}
+
+func (p *printer) printObjectType(keyword string, objectType *objectType) {
+	p.printDescription("", objectType.description)
+	p.P(keyword, " ", objectType.name, " {")
+	for _, objectTypeField := range objectType.fields {
+		p.printDescription("  ", objectTypeField.description)
+		arguments := ""
+		if objectTypeField.arguments != "" {
+			arguments = "(" + objectTypeField.arguments + ")"
+		}
+		p.P("  ", objectTypeField.name, arguments, ": ", objectTypeField.typeName, deprecatedDirective(objectTypeField.deprecated))
+	}
+	p.P("}")
+	p.P()
+}
+
+func (p *printer) printEnumType(enumType *enumType) {
+	p.printDescription("", enumType.description)
+	p.P("enum ", enumType.name, " {")
+	for _, value := range enumType.values {
+		p.printDescription("  ", value.description)
+		p.P("  ", value.name, deprecatedDirective(value.deprecated))
+	}
+	p.P("}")
+	p.P()
+}
+
+func (p *printer) printDescription(indent string, description string) {
+	if description == "" {
+		return
+	}
+	description = strings.ReplaceAll(description, `"""`, `\"""`)
+	if !strings.Contains(description, "\n") {
+		p.P(indent, `"""`, description, `"""`)
+		return
+	}
+	p.P(indent, `"""`)
+	for _, line := range strings.Split(description, "\n") {
+		if line == "" {
+			p.P()
+			continue
+		}
+		p.P(indent, line)
+	}
+	p.P(indent, `"""`)
+}
+
+func deprecatedDirective(deprecated bool) string {
+	if deprecated {
+		return " @deprecated"
+	}
+	return ""
+}
+
+// getDescription returns the leading comments of the descriptor.
+func getDescription(descriptor protoreflect.Descriptor) string {
+	comments := descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor).LeadingComments
+	lines := strings.Split(strings.TrimSpace(comments), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func lowerCamelCase(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// isName returns true if the string is a GraphQL name.
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+type generateOptions struct {
+	serviceNames        []string
+	typeNameStyle       TypeNameStyle
+	fieldNameStyle      FieldNameStyle
+	scalarMappings      map[string]string
+	queryMethodPrefixes []string
+}
+
+func newGenerateOptions() *generateOptions {
+	return &generateOptions{
+		typeNameStyle:       TypeNameStyleShort,
+		fieldNameStyle:      FieldNameStyleJSON,
+		scalarMappings:      make(map[string]string),
+		queryMethodPrefixes: DefaultQueryMethodPrefixes,
+	}
+}
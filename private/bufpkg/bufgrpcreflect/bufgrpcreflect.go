@@ -0,0 +1,43 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufgrpcreflect serves gRPC server reflection for an image.
+//
+// Both the grpc.reflection.v1.ServerReflection and grpc.reflection.v1alpha.ServerReflection
+// services are served, with the Connect, gRPC, and gRPC-Web protocols. Clients such as
+// grpcurl can then discover the services of the image without a real server.
+package bufgrpcreflect
+
+import (
+	"net/http"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+// Handler is a http.Handler that serves gRPC server reflection.
+type Handler interface {
+	http.Handler
+
+	// Services returns the fully-qualified names of the services that are listed,
+	// such as "acme.v1.OrderService", in sorted order.
+	Services() []string
+}
+
+// NewHandler returns a new Handler that serves reflection for the files of the image.
+//
+// The services of the non-import files of the image are listed. The image must
+// contain its imports, so that the dependencies of every file can be resolved.
+func NewHandler(image bufimage.Image) (Handler, error) {
+	return newHandler(image)
+}
@@ -0,0 +1,185 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgrpcreflect
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	reflectionv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/reflection/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "acme/v1/options.proto";
+import "google/protobuf/timestamp.proto";
+
+service OrderService {
+  rpc GetOrder(GetOrderRequest) returns (Order);
+}
+
+message GetOrderRequest {
+  string id = 1 [(acme.v1.sensitive) = true];
+}
+
+message Order {
+  string id = 1;
+  google.protobuf.Timestamp create_time = 2;
+}
+`
+
+const testOptionsProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/descriptor.proto";
+
+service HealthService {}
+
+extend google.protobuf.FieldOptions {
+  bool sensitive = 50000;
+}
+
+message Extensions {
+  extend google.protobuf.FieldOptions {
+    string owner = 50001;
+  }
+}
+`
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	handler, err := NewHandler(testGetImage(t))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme.v1.HealthService", "acme.v1.OrderService"}, handler.Services())
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	for _, procedure := range []string{v1Procedure, v1alphaProcedure} {
+		stream := connect.NewClient[reflectionv1.ServerReflectionRequest, reflectionv1.ServerReflectionResponse](
+			server.Client(),
+			server.URL+procedure,
+			connect.WithGRPC(),
+		).CallBidiStream(context.Background())
+		t.Cleanup(func() {
+			assert.NoError(t, stream.CloseRequest())
+			assert.NoError(t, stream.CloseResponse())
+		})
+		send := func(request *reflectionv1.ServerReflectionRequest) *reflectionv1.ServerReflectionResponse {
+			require.NoError(t, stream.Send(request))
+			response, err := stream.Receive()
+			require.NoError(t, err)
+			return response
+		}
+
+		response := send(&reflectionv1.ServerReflectionRequest{
+			Host: "localhost",
+			MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{
+				ListServices: "*",
+			},
+		})
+		assert.Equal(t, "localhost", response.GetValidHost())
+		assert.Equal(
+			t,
+			[]*reflectionv1.ServiceResponse{
+				{Name: "acme.v1.HealthService"},
+				{Name: "acme.v1.OrderService"},
+			},
+			response.GetListServicesResponse().GetService(),
+		)
+
+		response = send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: "acme.v1.OrderService.GetOrder",
+			},
+		})
+		assert.Equal(
+			t,
+			[]string{
+				"acme/v1/order.proto",
+				"acme/v1/options.proto",
+				"google/protobuf/timestamp.proto",
+				"google/protobuf/descriptor.proto",
+			},
+			testFileDescriptorPaths(t, response),
+		)
+		// Files that were already sent on the stream are not sent again, except for
+		// the requested file.
+		response = send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_FileByFilename{
+				FileByFilename: "acme/v1/options.proto",
+			},
+		})
+		assert.Equal(t, []string{"acme/v1/options.proto"}, testFileDescriptorPaths(t, response))
+
+		response = send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingExtension{
+				FileContainingExtension: &reflectionv1.ExtensionRequest{
+					ContainingType:  "google.protobuf.FieldOptions",
+					ExtensionNumber: 50001,
+				},
+			},
+		})
+		assert.Equal(t, []string{"acme/v1/options.proto"}, testFileDescriptorPaths(t, response))
+
+		response = send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_AllExtensionNumbersOfType{
+				AllExtensionNumbersOfType: "google.protobuf.FieldOptions",
+			},
+		})
+		assert.Equal(t, "google.protobuf.FieldOptions", response.GetAllExtensionNumbersResponse().GetBaseTypeName())
+		assert.Equal(t, []int32{50000, 50001}, response.GetAllExtensionNumbersResponse().GetExtensionNumber())
+
+		response = send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: "acme.v1.UserService",
+			},
+		})
+		assert.Equal(t, int32(connect.CodeNotFound), response.GetErrorResponse().GetErrorCode())
+		assert.Equal(t, `symbol "acme.v1.UserService" not found`, response.GetErrorResponse().GetErrorMessage())
+	}
+}
+
+func testFileDescriptorPaths(t *testing.T, response *reflectionv1.ServerReflectionResponse) []string {
+	var paths []string
+	for _, data := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+		require.NoError(t, proto.Unmarshal(data, fileDescriptorProto))
+		paths = append(paths, fileDescriptorProto.GetName())
+	}
+	return paths
+}
+
+func testGetImage(t *testing.T) bufimage.Image {
+	return bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"acme/v1/order.proto":   testOrderProto,
+			"acme/v1/options.proto": testOptionsProto,
+		},
+	)
+}
@@ -0,0 +1,243 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgrpcreflect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	reflectionv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/reflection/v1"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	v1Procedure      = "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+	v1alphaProcedure = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+)
+
+type handler struct {
+	*http.ServeMux
+
+	files *protoregistry.Files
+	// pathToData is the marshaled FileDescriptorProto of every file.
+	pathToData map[string][]byte
+	// pathToDependencies are the paths of the direct dependencies of every file.
+	pathToDependencies map[string][]string
+	// typeNameToExtensionNumberToPath are the paths of the files that declare the
+	// extensions of every message.
+	typeNameToExtensionNumberToPath map[protoreflect.FullName]map[protoreflect.FieldNumber]string
+	services                        []string
+}
+
+func newHandler(image bufimage.Image) (*handler, error) {
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	handler := &handler{
+		ServeMux:                        http.NewServeMux(),
+		files:                           files,
+		pathToData:                      make(map[string][]byte),
+		pathToDependencies:              make(map[string][]string),
+		typeNameToExtensionNumberToPath: make(map[protoreflect.FullName]map[protoreflect.FieldNumber]string),
+	}
+	for _, imageFile := range image.Files() {
+		fileDescriptorProto := imageFile.FileDescriptorProto()
+		data, err := proto.Marshal(fileDescriptorProto)
+		if err != nil {
+			return nil, err
+		}
+		handler.pathToData[imageFile.Path()] = data
+		handler.pathToDependencies[imageFile.Path()] = fileDescriptorProto.GetDependency()
+		fileDescriptor, err := files.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		handler.addExtensions(fileDescriptor.Path(), fileDescriptor.Extensions())
+		handler.addMessageExtensions(fileDescriptor.Path(), fileDescriptor.Messages())
+		if imageFile.IsImport() {
+			continue
+		}
+		services := fileDescriptor.Services()
+		for i := 0; i < services.Len(); i++ {
+			handler.services = append(handler.services, string(services.Get(i).FullName()))
+		}
+	}
+	handler.services = slicesext.ToUniqueSorted(handler.services)
+	// The messages of v1alpha are identical to the messages of v1, so both
+	// services are served with the messages of v1.
+	for _, procedure := range []string{v1Procedure, v1alphaProcedure} {
+		handler.Handle(
+			procedure,
+			connect.NewBidiStreamHandler(
+				procedure,
+				handler.serverReflectionInfo,
+			),
+		)
+	}
+	return handler, nil
+}
+
+func (h *handler) Services() []string {
+	return h.services
+}
+
+func (h *handler) addMessageExtensions(path string, messageDescriptors protoreflect.MessageDescriptors) {
+	for i := 0; i < messageDescriptors.Len(); i++ {
+		messageDescriptor := messageDescriptors.Get(i)
+		h.addExtensions(path, messageDescriptor.Extensions())
+		h.addMessageExtensions(path, messageDescriptor.Messages())
+	}
+}
+
+func (h *handler) addExtensions(path string, extensionDescriptors protoreflect.ExtensionDescriptors) {
+	for i := 0; i < extensionDescriptors.Len(); i++ {
+		extensionDescriptor := extensionDescriptors.Get(i)
+		typeName := extensionDescriptor.ContainingMessage().FullName()
+		extensionNumberToPath, ok := h.typeNameToExtensionNumberToPath[typeName]
+		if !ok {
+			extensionNumberToPath = make(map[protoreflect.FieldNumber]string)
+			h.typeNameToExtensionNumberToPath[typeName] = extensionNumberToPath
+		}
+		extensionNumberToPath[extensionDescriptor.Number()] = path
+	}
+}
+
+func (h *handler) serverReflectionInfo(
+	_ context.Context,
+	stream *connect.BidiStream[reflectionv1.ServerReflectionRequest, reflectionv1.ServerReflectionResponse],
+) error {
+	// The files that were already sent on the stream are not sent again, as
+	// clients cache the files of a stream.
+	sentPaths := make(map[string]struct{})
+	for {
+		request, err := stream.Receive()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		response := &reflectionv1.ServerReflectionResponse{
+			ValidHost:       request.GetHost(),
+			OriginalRequest: request,
+		}
+		switch messageRequest := request.GetMessageRequest().(type) {
+		case *reflectionv1.ServerReflectionRequest_FileByFilename:
+			if _, ok := h.pathToData[messageRequest.FileByFilename]; !ok {
+				response.MessageResponse = newErrorResponse(connect.CodeNotFound, "file %q not found", messageRequest.FileByFilename)
+				break
+			}
+			response.MessageResponse = h.newFileDescriptorResponse(messageRequest.FileByFilename, sentPaths)
+		case *reflectionv1.ServerReflectionRequest_FileContainingSymbol:
+			descriptor, err := h.files.FindDescriptorByName(protoreflect.FullName(messageRequest.FileContainingSymbol))
+			if err != nil {
+				response.MessageResponse = newErrorResponse(connect.CodeNotFound, "symbol %q not found", messageRequest.FileContainingSymbol)
+				break
+			}
+			response.MessageResponse = h.newFileDescriptorResponse(descriptor.ParentFile().Path(), sentPaths)
+		case *reflectionv1.ServerReflectionRequest_FileContainingExtension:
+			typeName := protoreflect.FullName(messageRequest.FileContainingExtension.GetContainingType())
+			extensionNumber := protoreflect.FieldNumber(messageRequest.FileContainingExtension.GetExtensionNumber())
+			path, ok := h.typeNameToExtensionNumberToPath[typeName][extensionNumber]
+			if !ok {
+				response.MessageResponse = newErrorResponse(connect.CodeNotFound, "extension %d of %q not found", extensionNumber, typeName)
+				break
+			}
+			response.MessageResponse = h.newFileDescriptorResponse(path, sentPaths)
+		case *reflectionv1.ServerReflectionRequest_AllExtensionNumbersOfType:
+			typeName := protoreflect.FullName(messageRequest.AllExtensionNumbersOfType)
+			if _, err := h.files.FindDescriptorByName(typeName); err != nil {
+				response.MessageResponse = newErrorResponse(connect.CodeNotFound, "type %q not found", typeName)
+				break
+			}
+			extensionNumbers := slicesext.MapKeysToSortedSlice(h.typeNameToExtensionNumberToPath[typeName])
+			response.MessageResponse = &reflectionv1.ServerReflectionResponse_AllExtensionNumbersResponse{
+				AllExtensionNumbersResponse: &reflectionv1.ExtensionNumberResponse{
+					BaseTypeName: string(typeName),
+					ExtensionNumber: slicesext.Map(
+						extensionNumbers,
+						func(extensionNumber protoreflect.FieldNumber) int32 {
+							return int32(extensionNumber)
+						},
+					),
+				},
+			}
+		case *reflectionv1.ServerReflectionRequest_ListServices:
+			response.MessageResponse = &reflectionv1.ServerReflectionResponse_ListServicesResponse{
+				ListServicesResponse: &reflectionv1.ListServiceResponse{
+					Service: slicesext.Map(
+						h.services,
+						func(service string) *reflectionv1.ServiceResponse {
+							return &reflectionv1.ServiceResponse{Name: service}
+						},
+					),
+				},
+			}
+		default:
+			return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unknown message request %T", messageRequest))
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+// newFileDescriptorResponse returns a response with the file of the path and its
+// transitive dependencies, in that order, except for the files that were already sent.
+//
+// The file of the path is always sent.
+func (h *handler) newFileDescriptorResponse(path string, sentPaths map[string]struct{}) *reflectionv1.ServerReflectionResponse_FileDescriptorResponse {
+	var fileDescriptorProtos [][]byte
+	seenPaths := make(map[string]struct{})
+	paths := []string{path}
+	for len(paths) > 0 {
+		path := paths[0]
+		paths = paths[1:]
+		if _, ok := seenPaths[path]; ok {
+			continue
+		}
+		seenPaths[path] = struct{}{}
+		if _, ok := sentPaths[path]; ok && len(fileDescriptorProtos) > 0 {
+			continue
+		}
+		sentPaths[path] = struct{}{}
+		fileDescriptorProtos = append(fileDescriptorProtos, h.pathToData[path])
+		paths = append(paths, h.pathToDependencies[path]...)
+	}
+	return &reflectionv1.ServerReflectionResponse_FileDescriptorResponse{
+		FileDescriptorResponse: &reflectionv1.FileDescriptorResponse{
+			FileDescriptorProto: fileDescriptorProtos,
+		},
+	}
+}
+
+func newErrorResponse(code connect.Code, format string, args ...any) *reflectionv1.ServerReflectionResponse_ErrorResponse {
+	return &reflectionv1.ServerReflectionResponse_ErrorResponse{
+		ErrorResponse: &reflectionv1.ErrorResponse{
+			ErrorCode:    int32(code),
+			ErrorMessage: fmt.Sprintf(format, args...),
+		},
+	}
+}
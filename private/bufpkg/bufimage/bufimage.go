@@ -36,6 +36,13 @@ type ImageFile interface {
 	// This will never be nil.
 	// The value Path() is equal to FileDescriptorProto().GetName() .
 	FileDescriptorProto() *descriptorpb.FileDescriptorProto
+	// SourceCodeInfo is the source code info of the File.
+	//
+	// This is the same as FileDescriptorProto().GetSourceCodeInfo(), except for
+	// Files of Images created with WithLazySourceCodeInfo, for which the source
+	// code info is not part of the FileDescriptorProto, and is only decoded when
+	// SourceCodeInfo is called.
+	SourceCodeInfo() *descriptorpb.SourceCodeInfo
 	// IsImport returns true if this file is an import.
 	IsImport() bool
 	// IsSyntaxUnspecified will be true if the syntax was not explicitly specified.
@@ -84,6 +91,9 @@ func ImageFileWithIsImport(imageFile ImageFile, isImport bool) ImageFile {
 	if imageFile.IsImport() == isImport {
 		return imageFile
 	}
+	if lazyImageFile, ok := lazyImageFileWithIsImport(imageFile, isImport); ok {
+		return lazyImageFile
+	}
 	// No need to validate as ImageFile is already validated.
 	return newImageFileNoValidate(
 		imageFile.FileDescriptorProto(),
@@ -189,40 +199,7 @@ func NewImageForProto(protoImage *imagev1.Image, options ...NewImageForProtoOpti
 	}
 	imageFiles := make([]ImageFile, len(protoImage.File))
 	for i, protoImageFile := range protoImage.File {
-		var isImport bool
-		var isSyntaxUnspecified bool
-		var unusedDependencyIndexes []int32
-		var moduleIdentity bufmoduleref.ModuleIdentity
-		var commit string
-		var err error
-		if protoImageFileExtension := protoImageFile.GetBufExtension(); protoImageFileExtension != nil {
-			isImport = protoImageFileExtension.GetIsImport()
-			isSyntaxUnspecified = protoImageFileExtension.GetIsSyntaxUnspecified()
-			unusedDependencyIndexes = protoImageFileExtension.GetUnusedDependency()
-			if protoModuleInfo := protoImageFileExtension.GetModuleInfo(); protoModuleInfo != nil {
-				if protoModuleName := protoModuleInfo.GetName(); protoModuleName != nil {
-					moduleIdentity, err = bufmoduleref.NewModuleIdentity(
-						protoModuleName.GetRemote(),
-						protoModuleName.GetOwner(),
-						protoModuleName.GetRepository(),
-					)
-					if err != nil {
-						return nil, err
-					}
-					// we only want to set this if there is a module name
-					commit = protoModuleInfo.GetCommit()
-				}
-			}
-		}
-		imageFile, err := NewImageFile(
-			protoImageFile,
-			moduleIdentity,
-			commit,
-			protoImageFile.GetName(),
-			isImport,
-			isSyntaxUnspecified,
-			unusedDependencyIndexes,
-		)
+		imageFile, err := newImageFileForProtoImageFile(protoImageFile)
 		if err != nil {
 			return nil, err
 		}
@@ -231,6 +208,34 @@ func NewImageForProto(protoImage *imagev1.Image, options ...NewImageForProtoOpti
 	return NewImage(imageFiles)
 }
 
+// NewImageForBinary returns a new Image for the binary encoding of a proto Image
+// or a FileDescriptorSet, which have the same encoding.
+//
+// Unlike unmarshalling a proto Image and calling NewImageForProto, the files are only
+// decoded when their FileDescriptorProto is first accessed, and their custom options
+// are resolved against their dependencies at that time. Files that are never accessed,
+// such as files that are not selected by ImageWithOnlyPaths, are never kept in memory
+// in decoded form, and the dependencies of accessed files are kept without their
+// source code info. This keeps the memory of very large images down.
+//
+// The input Files are expected to be in correct DAG order!
+// The data must not be modified after calling NewImageForBinary.
+func NewImageForBinary(data []byte, options ...NewImageForBinaryOption) (Image, error) {
+	var newImageOptions newImageForBinaryOptions
+	for _, option := range options {
+		option(&newImageOptions)
+	}
+	imageFiles, err := newLazyImageFiles(
+		data,
+		newImageOptions.excludeSourceCodeInfo,
+		newImageOptions.lazySourceCodeInfo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return NewImage(imageFiles)
+}
+
 // NewImageForCodeGeneratorRequest returns a new Image from a given CodeGeneratorRequest.
 //
 // The input Files are expected to be in correct DAG order!
@@ -286,6 +291,31 @@ func WithUnusedImportsComputation() NewImageForProtoOption {
 	}
 }
 
+// NewImageForBinaryOption is an option for use with NewImageForBinary.
+type NewImageForBinaryOption func(*newImageForBinaryOptions)
+
+// WithExcludeSourceCodeInfo instructs NewImageForBinary to never decode the
+// source code info of the files.
+func WithExcludeSourceCodeInfo() NewImageForBinaryOption {
+	return func(options *newImageForBinaryOptions) {
+		options.excludeSourceCodeInfo = true
+	}
+}
+
+// WithLazySourceCodeInfo instructs NewImageForBinary to leave the source code
+// info out of the FileDescriptorProtos of the files, and to only decode it when
+// ImageFile.SourceCodeInfo is called.
+//
+// This is for consumers that only read the source code info of some files, if
+// any, such as when printing the locations of lint failures. The source code
+// info is still included when the Image is converted back to protos, such as
+// with ImageToProtoImage.
+func WithLazySourceCodeInfo() NewImageForBinaryOption {
+	return func(options *newImageForBinaryOptions) {
+		options.lazySourceCodeInfo = true
+	}
+}
+
 // ImageWithoutImports returns a copy of the Image without imports.
 //
 // The backing Files are not copied.
@@ -367,6 +397,37 @@ func ImageByDir(image Image) ([]Image, error) {
 	return newImages, nil
 }
 
+// ImageByPackage returns multiple images that have non-imports split
+// by package.
+//
+// That is, each Image will only contain a single package's files
+// as it's non-imports, along with all required imports for the
+// files in that package.
+func ImageByPackage(image Image) ([]Image, error) {
+	pkgToPaths := make(map[string][]string)
+	for _, imageFile := range image.Files() {
+		if !imageFile.IsImport() {
+			pkg := imageFile.FileDescriptorProto().GetPackage()
+			pkgToPaths[pkg] = append(pkgToPaths[pkg], imageFile.Path())
+		}
+	}
+	// we need this to produce a deterministic order of the returned Images
+	pkgs := make([]string, 0, len(pkgToPaths))
+	for pkg := range pkgToPaths {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	newImages := make([]Image, 0, len(pkgToPaths))
+	for _, pkg := range pkgs {
+		newImage, err := ImageWithOnlyPaths(image, pkgToPaths[pkg], nil)
+		if err != nil {
+			return nil, err
+		}
+		newImages = append(newImages, newImage)
+	}
+	return newImages, nil
+}
+
 // ImageToProtoImage returns a new ProtoImage for the Image.
 func ImageToProtoImage(image Image) *imagev1.Image {
 	imageFiles := image.Files()
@@ -570,6 +631,11 @@ type newImageForProtoOptions struct {
 	computeUnusedImports bool
 }
 
+type newImageForBinaryOptions struct {
+	excludeSourceCodeInfo bool
+	lazySourceCodeInfo    bool
+}
+
 func reparseImageProto(protoImage *imagev1.Image, computeUnusedImports bool) error {
 	// TODO right now, NewResolver sets AllowUnresolvable to true all the time
 	// we want to make this into a check, and we verify if we need this for the individual command
@@ -315,7 +315,7 @@ func getBuildResult(
 		sourceInfoMode = protocompile.SourceInfoNone
 	}
 	compiler := protocompile.Compiler{
-		MaxParallelism: thread.Parallelism(),
+		MaxParallelism: thread.ParallelismForContext(ctx),
 		SourceInfoMode: sourceInfoMode,
 		Resolver:       &protocompile.SourceResolver{Accessor: parserAccessorHandler.Open},
 		Reporter: reporter.NewReporter(
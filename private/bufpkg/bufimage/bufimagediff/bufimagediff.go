@@ -0,0 +1,121 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufimagediff computes the semantic differences between images.
+//
+// Images are compared declaration by declaration, rather than byte by byte, so
+// images that only differ in the order of their files and declarations, or in
+// their source code info, have no differences. This distinguishes a schema that
+// actually changed from an identical schema that was rebuilt.
+package bufimagediff
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+const (
+	// DifferenceTypeAdded is the type of a Difference for an added file or declaration.
+	DifferenceTypeAdded DifferenceType = iota + 1
+	// DifferenceTypeRemoved is the type of a Difference for a removed file or declaration.
+	DifferenceTypeRemoved
+	// DifferenceTypeChanged is the type of a Difference for a changed property of a
+	// file or declaration.
+	DifferenceTypeChanged
+)
+
+var (
+	differenceTypeToString = map[DifferenceType]string{
+		DifferenceTypeAdded:   "added",
+		DifferenceTypeRemoved: "removed",
+		DifferenceTypeChanged: "changed",
+	}
+)
+
+// DifferenceType is the type of a Difference.
+type DifferenceType int
+
+// String implements fmt.Stringer.
+func (d DifferenceType) String() string {
+	s, ok := differenceTypeToString[d]
+	if !ok {
+		return strconv.Itoa(int(d))
+	}
+	return s
+}
+
+// Difference is a difference between two images.
+type Difference struct {
+	// Type is the type of the difference.
+	Type DifferenceType
+	// FilePath is the path of the file that differs.
+	FilePath string
+	// Element is the declaration of the file that differs, such as
+	// "field acme.v1.Order.quantity" or "import google/protobuf/timestamp.proto".
+	//
+	// Empty if the file itself was added or removed, or a property of the file changed.
+	Element string
+	// Property is the property of the element, or of the file if Element is empty,
+	// that changed, such as "type" or "options.deprecated".
+	//
+	// Only set for DifferenceTypeChanged.
+	Property string
+	// From is the value of the property in the image compared from, or "unset".
+	//
+	// Only set for DifferenceTypeChanged.
+	From string
+	// To is the value of the property in the image compared to, or "unset".
+	//
+	// Only set for DifferenceTypeChanged.
+	To string
+}
+
+// String returns a single-line description of the Difference, such as:
+//
+//	acme/v1/order.proto: field acme.v1.Order.quantity: type changed from TYPE_INT32 to TYPE_INT64
+func (d *Difference) String() string {
+	var builder strings.Builder
+	_, _ = builder.WriteString(d.FilePath)
+	if d.Element != "" {
+		_, _ = builder.WriteString(": ")
+		_, _ = builder.WriteString(d.Element)
+	}
+	switch d.Type {
+	case DifferenceTypeChanged:
+		_, _ = builder.WriteString(": ")
+		_, _ = builder.WriteString(d.Property)
+		_, _ = builder.WriteString(" changed from ")
+		_, _ = builder.WriteString(d.From)
+		_, _ = builder.WriteString(" to ")
+		_, _ = builder.WriteString(d.To)
+	default:
+		_, _ = builder.WriteString(" ")
+		_, _ = builder.WriteString(d.Type.String())
+	}
+	return builder.String()
+}
+
+// Diff returns the differences from the image to the other image.
+//
+// The order of files and declarations, and source code info, are ignored.
+// Declarations are matched by name, so a renamed declaration is a removed
+// declaration and an added declaration. Custom options are compared by value.
+//
+// The differences are ordered by file path, and then by the declarations of each
+// file, with the properties of a declaration before its nested declarations.
+func Diff(from bufimage.Image, to bufimage.Image) ([]*Difference, error) {
+	return diff(from, to)
+}
@@ -0,0 +1,194 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagediff
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/timestamp.proto";
+
+// OrderService manages orders.
+service OrderService {
+  rpc GetOrder(GetOrderRequest) returns (Order);
+  rpc ListOrders(ListOrdersRequest) returns (ListOrdersResponse);
+}
+
+message GetOrderRequest {
+  string id = 1;
+}
+
+// Order is an order.
+message Order {
+  string id = 1;
+  int32 quantity = 2;
+  Status status = 3;
+  google.protobuf.Timestamp create_time = 4;
+  oneof payment {
+    string card = 5;
+    string account = 6;
+  }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_PENDING = 1;
+  STATUS_SHIPPED = 2;
+}
+
+message ListOrdersRequest {}
+
+message ListOrdersResponse {
+  repeated Order orders = 1;
+}
+`
+
+// testReorderedOrderProto is testOrderProto with its declarations reordered and its
+// comments changed, which are not semantic changes.
+const testReorderedOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/timestamp.proto";
+
+message ListOrdersResponse {
+  repeated Order orders = 1;
+}
+
+message ListOrdersRequest {}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_SHIPPED = 2;
+  STATUS_PENDING = 1;
+}
+
+// An order.
+message Order {
+  oneof payment {
+    string account = 6;
+    string card = 5;
+  }
+  google.protobuf.Timestamp create_time = 4;
+  Status status = 3;
+  int32 quantity = 2;
+  string id = 1;
+}
+
+message GetOrderRequest {
+  string id = 1;
+}
+
+service OrderService {
+  rpc ListOrders(ListOrdersRequest) returns (ListOrdersResponse);
+  rpc GetOrder(GetOrderRequest) returns (Order);
+}
+`
+
+// testChangedOrderProto is testOrderProto with semantic changes.
+const testChangedOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+option go_package = "acme/v1;acmev1";
+
+service OrderService {
+  rpc GetOrder(GetOrderRequest) returns (Order) {
+    option deprecated = true;
+  }
+  rpc ListOrders(ListOrdersRequest) returns (stream Order);
+}
+
+message GetOrderRequest {
+  string id = 1;
+}
+
+message Order {
+  string id = 1;
+  int64 quantity = 2;
+  Status status = 3;
+  string card = 5;
+  oneof payment {
+    string account = 6;
+  }
+  string note = 7;
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_PENDING = 1;
+  STATUS_SHIPPED = 3;
+}
+
+message ListOrdersRequest {}
+`
+
+func TestDiffIdentical(t *testing.T) {
+	t.Parallel()
+	differences, err := Diff(
+		bufimagetesting.BuildImage(t, map[string]string{"acme/v1/order.proto": testOrderProto}),
+		bufimagetesting.BuildImage(t, map[string]string{"acme/v1/order.proto": testReorderedOrderProto}),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, differences)
+}
+
+func TestDiffChanged(t *testing.T) {
+	t.Parallel()
+	differences, err := Diff(
+		bufimagetesting.BuildImage(
+			t,
+			map[string]string{
+				"acme/v1/order.proto": testOrderProto,
+			},
+		),
+		bufimagetesting.BuildImage(
+			t,
+			map[string]string{
+				"acme/v1/order.proto": testChangedOrderProto,
+				"acme/v1/user.proto":  `syntax = "proto3"; package acme.v1; message User {}`,
+			},
+		),
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			`acme/v1/order.proto: options.go_package changed from unset to "acme/v1;acmev1"`,
+			`acme/v1/order.proto: import google/protobuf/timestamp.proto removed`,
+			`acme/v1/order.proto: message acme.v1.ListOrdersResponse removed`,
+			`acme/v1/order.proto: field acme.v1.Order.card: oneof changed from payment to unset`,
+			`acme/v1/order.proto: field acme.v1.Order.create_time removed`,
+			`acme/v1/order.proto: field acme.v1.Order.note added`,
+			`acme/v1/order.proto: field acme.v1.Order.quantity: type changed from TYPE_INT32 to TYPE_INT64`,
+			`acme/v1/order.proto: enum value acme.v1.Status.STATUS_SHIPPED: number changed from 2 to 3`,
+			`acme/v1/order.proto: method acme.v1.OrderService.GetOrder: options.deprecated changed from unset to true`,
+			`acme/v1/order.proto: method acme.v1.OrderService.ListOrders: output_type changed from ".acme.v1.ListOrdersResponse" to ".acme.v1.Order"`,
+			`acme/v1/order.proto: method acme.v1.OrderService.ListOrders: server_streaming changed from unset to true`,
+			`acme/v1/user.proto added`,
+			`google/protobuf/timestamp.proto removed`,
+		},
+		slicesext.Map(differences, (*Difference).String),
+	)
+}
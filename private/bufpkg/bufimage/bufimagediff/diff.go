@@ -0,0 +1,550 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagediff
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const unsetValue = "unset"
+
+var (
+	// skippedFileProperties are the properties of files that are compared as
+	// declarations, or not compared at all.
+	skippedFileProperties = newProperties(
+		"name",
+		"dependency",
+		"public_dependency",
+		"weak_dependency",
+		"message_type",
+		"enum_type",
+		"service",
+		"extension",
+		"source_code_info",
+	)
+	skippedMessageProperties = newProperties(
+		"name",
+		"field",
+		"extension",
+		"nested_type",
+		"enum_type",
+		"oneof_decl",
+	)
+	// oneof_index is compared as the name of the oneof.
+	skippedFieldProperties = newProperties(
+		"name",
+		"oneof_index",
+	)
+	skippedEnumProperties = newProperties(
+		"name",
+		"value",
+	)
+	skippedServiceProperties = newProperties(
+		"name",
+		"method",
+	)
+	skippedNameProperties = newProperties(
+		"name",
+	)
+)
+
+func diff(from bufimage.Image, to bufimage.Image) ([]*Difference, error) {
+	fromFiles, err := getFileDescriptorProtos(from)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := getFileDescriptorProtos(to)
+	if err != nil {
+		return nil, err
+	}
+	differ := &differ{}
+	for _, filePath := range getSortedUnionKeys(fromFiles, toFiles) {
+		fromFile, fromOK := fromFiles[filePath]
+		toFile, toOK := toFiles[filePath]
+		switch {
+		case !toOK:
+			differ.add(DifferenceTypeRemoved, filePath, "")
+		case !fromOK:
+			differ.add(DifferenceTypeAdded, filePath, "")
+		default:
+			differ.diffFile(filePath, fromFile, toFile)
+		}
+	}
+	return differ.differences, nil
+}
+
+// getFileDescriptorProtos returns the FileDescriptorProtos of the image by path.
+//
+// The custom options of the files are resolved against the image, so that they are
+// compared by value rather than by their encoding.
+func getFileDescriptorProtos(image bufimage.Image) (map[string]*descriptorpb.FileDescriptorProto, error) {
+	fileDescriptorProtos := bufimage.ImageToFileDescriptorProtos(image)
+	resolver, err := protoencoding.NewResolver(fileDescriptorProtos...)
+	if err != nil {
+		return nil, err
+	}
+	pathToFileDescriptorProto := make(map[string]*descriptorpb.FileDescriptorProto, len(fileDescriptorProtos))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		data, err := proto.Marshal(fileDescriptorProto)
+		if err != nil {
+			return nil, err
+		}
+		resolvedFileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+		if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(data, resolvedFileDescriptorProto); err != nil {
+			return nil, err
+		}
+		pathToFileDescriptorProto[fileDescriptorProto.GetName()] = resolvedFileDescriptorProto
+	}
+	return pathToFileDescriptorProto, nil
+}
+
+type differ struct {
+	differences []*Difference
+}
+
+func (d *differ) add(differenceType DifferenceType, filePath string, element string) {
+	d.differences = append(
+		d.differences,
+		&Difference{
+			Type:     differenceType,
+			FilePath: filePath,
+			Element:  element,
+		},
+	)
+}
+
+func (d *differ) addChanged(filePath string, element string, property string, from string, to string) {
+	d.differences = append(
+		d.differences,
+		&Difference{
+			Type:     DifferenceTypeChanged,
+			FilePath: filePath,
+			Element:  element,
+			Property: property,
+			From:     from,
+			To:       to,
+		},
+	)
+}
+
+func (d *differ) diffFile(filePath string, from *descriptorpb.FileDescriptorProto, to *descriptorpb.FileDescriptorProto) {
+	d.diffProperties(filePath, "", "", from.ProtoReflect(), to.ProtoReflect(), skippedFileProperties)
+	fromImports, toImports := getImports(from), getImports(to)
+	for _, importPath := range getSortedUnionKeys(fromImports, toImports) {
+		element := "import " + importPath
+		fromImport, fromOK := fromImports[importPath]
+		toImport, toOK := toImports[importPath]
+		switch {
+		case !toOK:
+			d.add(DifferenceTypeRemoved, filePath, element)
+		case !fromOK:
+			d.add(DifferenceTypeAdded, filePath, element)
+		default:
+			if fromImport.public != toImport.public {
+				d.addChanged(filePath, element, "public", strconv.FormatBool(fromImport.public), strconv.FormatBool(toImport.public))
+			}
+			if fromImport.weak != toImport.weak {
+				d.addChanged(filePath, element, "weak", strconv.FormatBool(fromImport.weak), strconv.FormatBool(toImport.weak))
+			}
+		}
+	}
+	prefix := from.GetPackage()
+	if prefix != "" {
+		prefix += "."
+	}
+	d.diffMessages(filePath, prefix, from.GetMessageType(), to.GetMessageType())
+	d.diffEnums(filePath, prefix, from.GetEnumType(), to.GetEnumType())
+	d.diffServices(filePath, prefix, from.GetService(), to.GetService())
+	d.diffFields(filePath, "extension", prefix, from.GetExtension(), to.GetExtension(), nil, nil)
+}
+
+func (d *differ) diffMessages(filePath string, prefix string, from []*descriptorpb.DescriptorProto, to []*descriptorpb.DescriptorProto) {
+	diffNamed(
+		d,
+		filePath,
+		"message",
+		prefix,
+		from,
+		to,
+		func(element string, fullName string, from *descriptorpb.DescriptorProto, to *descriptorpb.DescriptorProto) {
+			d.diffProperties(filePath, element, "", from.ProtoReflect(), to.ProtoReflect(), skippedMessageProperties)
+			nestedPrefix := fullName + "."
+			d.diffFields(filePath, "field", nestedPrefix, from.GetField(), to.GetField(), from.GetOneofDecl(), to.GetOneofDecl())
+			diffNamed(
+				d,
+				filePath,
+				"oneof",
+				nestedPrefix,
+				from.GetOneofDecl(),
+				to.GetOneofDecl(),
+				func(element string, _ string, from *descriptorpb.OneofDescriptorProto, to *descriptorpb.OneofDescriptorProto) {
+					d.diffProperties(filePath, element, "", from.ProtoReflect(), to.ProtoReflect(), skippedNameProperties)
+				},
+			)
+			d.diffMessages(filePath, nestedPrefix, from.GetNestedType(), to.GetNestedType())
+			d.diffEnums(filePath, nestedPrefix, from.GetEnumType(), to.GetEnumType())
+			d.diffFields(filePath, "extension", nestedPrefix, from.GetExtension(), to.GetExtension(), nil, nil)
+		},
+	)
+}
+
+// diffFields diffs fields or extensions.
+//
+// The oneofs are the oneofs of the message of the fields, if any.
+func (d *differ) diffFields(
+	filePath string,
+	kind string,
+	prefix string,
+	from []*descriptorpb.FieldDescriptorProto,
+	to []*descriptorpb.FieldDescriptorProto,
+	fromOneofs []*descriptorpb.OneofDescriptorProto,
+	toOneofs []*descriptorpb.OneofDescriptorProto,
+) {
+	diffNamed(
+		d,
+		filePath,
+		kind,
+		prefix,
+		from,
+		to,
+		func(element string, _ string, from *descriptorpb.FieldDescriptorProto, to *descriptorpb.FieldDescriptorProto) {
+			d.diffProperties(filePath, element, "", from.ProtoReflect(), to.ProtoReflect(), skippedFieldProperties)
+			fromOneof, toOneof := getOneofName(from, fromOneofs), getOneofName(to, toOneofs)
+			if fromOneof != toOneof {
+				d.addChanged(filePath, element, "oneof", fromOneof, toOneof)
+			}
+		},
+	)
+}
+
+func (d *differ) diffEnums(filePath string, prefix string, from []*descriptorpb.EnumDescriptorProto, to []*descriptorpb.EnumDescriptorProto) {
+	diffNamed(
+		d,
+		filePath,
+		"enum",
+		prefix,
+		from,
+		to,
+		func(element string, fullName string, from *descriptorpb.EnumDescriptorProto, to *descriptorpb.EnumDescriptorProto) {
+			d.diffProperties(filePath, element, "", from.ProtoReflect(), to.ProtoReflect(), skippedEnumProperties)
+			diffNamed(
+				d,
+				filePath,
+				"enum value",
+				fullName+".",
+				from.GetValue(),
+				to.GetValue(),
+				func(element string, _ string, from *descriptorpb.EnumValueDescriptorProto, to *descriptorpb.EnumValueDescriptorProto) {
+					d.diffProperties(filePath, element, "", from.ProtoReflect(), to.ProtoReflect(), skippedNameProperties)
+				},
+			)
+		},
+	)
+}
+
+func (d *differ) diffServices(filePath string, prefix string, from []*descriptorpb.ServiceDescriptorProto, to []*descriptorpb.ServiceDescriptorProto) {
+	diffNamed(
+		d,
+		filePath,
+		"service",
+		prefix,
+		from,
+		to,
+		func(element string, fullName string, from *descriptorpb.ServiceDescriptorProto, to *descriptorpb.ServiceDescriptorProto) {
+			d.diffProperties(filePath, element, "", from.ProtoReflect(), to.ProtoReflect(), skippedServiceProperties)
+			diffNamed(
+				d,
+				filePath,
+				"method",
+				fullName+".",
+				from.GetMethod(),
+				to.GetMethod(),
+				func(element string, _ string, from *descriptorpb.MethodDescriptorProto, to *descriptorpb.MethodDescriptorProto) {
+					d.diffProperties(filePath, element, "", from.ProtoReflect(), to.ProtoReflect(), skippedNameProperties)
+				},
+			)
+		},
+	)
+}
+
+// diffProperties diffs the properties of the messages, which are their fields
+// other than the skipped fields, recursing into singular message fields such as
+// options.
+//
+// Unset properties are compared as their default values, and repeated properties
+// are compared regardless of the order of their elements.
+func (d *differ) diffProperties(
+	filePath string,
+	element string,
+	prefix string,
+	from protoreflect.Message,
+	to protoreflect.Message,
+	skipped map[protoreflect.Name]struct{},
+) {
+	fieldDescriptors := make(map[string]protoreflect.FieldDescriptor)
+	addFieldDescriptor := func(fieldDescriptor protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if _, ok := skipped[fieldDescriptor.Name()]; !ok || fieldDescriptor.IsExtension() {
+			fieldDescriptors[getPropertyName(fieldDescriptor)] = fieldDescriptor
+		}
+		return true
+	}
+	from.Range(addFieldDescriptor)
+	to.Range(addFieldDescriptor)
+	for _, name := range slicesext.MapKeysToSortedSlice(fieldDescriptors) {
+		fieldDescriptor := fieldDescriptors[name]
+		property := prefix + name
+		fromValue, toValue := from.Get(fieldDescriptor), to.Get(fieldDescriptor)
+		if isMessageField(fieldDescriptor) && !fieldDescriptor.IsList() && !fieldDescriptor.IsMap() {
+			d.diffProperties(filePath, element, property+".", fromValue.Message(), toValue.Message(), nil)
+			continue
+		}
+		if !valuesEqual(fieldDescriptor, fromValue, toValue) {
+			d.addChanged(
+				filePath,
+				element,
+				property,
+				formatValue(fieldDescriptor, from.Has(fieldDescriptor), fromValue),
+				formatValue(fieldDescriptor, to.Has(fieldDescriptor), toValue),
+			)
+		}
+	}
+	if fromUnknown, toUnknown := from.GetUnknown(), to.GetUnknown(); !bytes.Equal(fromUnknown, toUnknown) {
+		d.addChanged(
+			filePath,
+			element,
+			prefix+"unknown fields",
+			fmt.Sprintf("%d bytes", len(fromUnknown)),
+			fmt.Sprintf("%d bytes", len(toUnknown)),
+		)
+	}
+}
+
+// named is a descriptor proto with a name.
+type named interface {
+	GetName() string
+}
+
+// diffNamed diffs the declarations of the kind by name, calling diffElements for
+// the declarations that are in both.
+//
+// Declarations are prefixed with the prefix to form their full names.
+func diffNamed[T named](
+	d *differ,
+	filePath string,
+	kind string,
+	prefix string,
+	from []T,
+	to []T,
+	diffElements func(element string, fullName string, from T, to T),
+) {
+	fromByName, toByName := getByName(from), getByName(to)
+	for _, name := range getSortedUnionKeys(fromByName, toByName) {
+		fullName := prefix + name
+		element := kind + " " + fullName
+		fromElement, fromOK := fromByName[name]
+		toElement, toOK := toByName[name]
+		switch {
+		case !toOK:
+			d.add(DifferenceTypeRemoved, filePath, element)
+		case !fromOK:
+			d.add(DifferenceTypeAdded, filePath, element)
+		default:
+			diffElements(element, fullName, fromElement, toElement)
+		}
+	}
+}
+
+type importProperties struct {
+	public bool
+	weak   bool
+}
+
+func getImports(fileDescriptorProto *descriptorpb.FileDescriptorProto) map[string]importProperties {
+	dependencies := fileDescriptorProto.GetDependency()
+	imports := make(map[string]importProperties, len(dependencies))
+	for _, dependency := range dependencies {
+		imports[dependency] = importProperties{}
+	}
+	for _, index := range fileDescriptorProto.GetPublicDependency() {
+		if int(index) < len(dependencies) {
+			properties := imports[dependencies[index]]
+			properties.public = true
+			imports[dependencies[index]] = properties
+		}
+	}
+	for _, index := range fileDescriptorProto.GetWeakDependency() {
+		if int(index) < len(dependencies) {
+			properties := imports[dependencies[index]]
+			properties.weak = true
+			imports[dependencies[index]] = properties
+		}
+	}
+	return imports
+}
+
+func getOneofName(fieldDescriptorProto *descriptorpb.FieldDescriptorProto, oneofs []*descriptorpb.OneofDescriptorProto) string {
+	if fieldDescriptorProto.OneofIndex == nil {
+		return unsetValue
+	}
+	index := int(fieldDescriptorProto.GetOneofIndex())
+	if index < 0 || index >= len(oneofs) {
+		return strconv.Itoa(index)
+	}
+	return oneofs[index].GetName()
+}
+
+func getByName[T named](elements []T) map[string]T {
+	byName := make(map[string]T, len(elements))
+	for _, element := range elements {
+		byName[element.GetName()] = element
+	}
+	return byName
+}
+
+func getSortedUnionKeys[V any](one map[string]V, two map[string]V) []string {
+	keys := slicesext.MapKeysToSlice(one)
+	for key := range two {
+		if _, ok := one[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// getPropertyName returns the name of the property of the field, which is the name
+// of the field, or the full name of the extension in parentheses.
+func getPropertyName(fieldDescriptor protoreflect.FieldDescriptor) string {
+	if fieldDescriptor.IsExtension() {
+		return "(" + string(fieldDescriptor.FullName()) + ")"
+	}
+	return string(fieldDescriptor.Name())
+}
+
+// valuesEqual returns true if the values of the field are equal, regardless of the
+// order of the elements of lists.
+func valuesEqual(fieldDescriptor protoreflect.FieldDescriptor, from protoreflect.Value, to protoreflect.Value) bool {
+	switch {
+	case fieldDescriptor.IsList():
+		return slicesext.ElementsEqual(
+			getSortedElementKeys(fieldDescriptor, from.List()),
+			getSortedElementKeys(fieldDescriptor, to.List()),
+		)
+	case fieldDescriptor.IsMap():
+		return slicesext.ElementsEqual(
+			getSortedEntryKeys(fieldDescriptor, from.Map()),
+			getSortedEntryKeys(fieldDescriptor, to.Map()),
+		)
+	}
+	return getKey(fieldDescriptor, from) == getKey(fieldDescriptor, to)
+}
+
+func getSortedElementKeys(fieldDescriptor protoreflect.FieldDescriptor, list protoreflect.List) []string {
+	keys := make([]string, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		keys[i] = getKey(fieldDescriptor, list.Get(i))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func getSortedEntryKeys(fieldDescriptor protoreflect.FieldDescriptor, mapValue protoreflect.Map) []string {
+	keys := make([]string, 0, mapValue.Len())
+	mapValue.Range(
+		func(mapKey protoreflect.MapKey, value protoreflect.Value) bool {
+			keys = append(
+				keys,
+				getKey(fieldDescriptor.MapKey(), mapKey.Value())+"="+getKey(fieldDescriptor.MapValue(), value),
+			)
+			return true
+		},
+	)
+	sort.Strings(keys)
+	return keys
+}
+
+// getKey returns a key of the singular value that is equal for equal values.
+func getKey(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	if isMessageField(fieldDescriptor) {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(value.Message().Interface())
+		if err != nil {
+			return fmt.Sprintf("%v", value.Interface())
+		}
+		return string(data)
+	}
+	return fmt.Sprintf("%v", value.Interface())
+}
+
+// formatValue formats the value of the field for display, or returns "unset".
+func formatValue(fieldDescriptor protoreflect.FieldDescriptor, has bool, value protoreflect.Value) string {
+	if !has {
+		return unsetValue
+	}
+	if fieldDescriptor.IsMap() {
+		return "{" + strings.Join(getSortedEntryKeys(fieldDescriptor, value.Map()), ", ") + "}"
+	}
+	if fieldDescriptor.IsList() {
+		list := value.List()
+		elements := make([]string, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			elements[i] = formatSingularValue(fieldDescriptor, list.Get(i))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+	return formatSingularValue(fieldDescriptor, value)
+}
+
+func formatSingularValue(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.EnumKind:
+		if enumValueDescriptor := fieldDescriptor.Enum().Values().ByNumber(value.Enum()); enumValueDescriptor != nil {
+			return string(enumValueDescriptor.Name())
+		}
+		return strconv.Itoa(int(value.Enum()))
+	case protoreflect.StringKind:
+		return strconv.Quote(value.String())
+	case protoreflect.BytesKind:
+		return strconv.Quote(string(value.Bytes()))
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		data, err := prototext.MarshalOptions{}.Marshal(value.Message().Interface())
+		if err != nil {
+			return fmt.Sprintf("%v", value.Interface())
+		}
+		return "{" + strings.TrimSpace(string(data)) + "}"
+	default:
+		return fmt.Sprintf("%v", value.Interface())
+	}
+}
+
+func isMessageField(fieldDescriptor protoreflect.FieldDescriptor) bool {
+	kind := fieldDescriptor.Kind()
+	return kind == protoreflect.MessageKind || kind == protoreflect.GroupKind
+}
+
+func newProperties(names ...protoreflect.Name) map[protoreflect.Name]struct{} {
+	return slicesext.ToStructMap(names)
+}
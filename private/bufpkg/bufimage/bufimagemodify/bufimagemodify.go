@@ -29,6 +29,24 @@ import (
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
+// OverridePackageKeyPrefix is the prefix of override keys that match files by
+// their package instead of their path, for example "package:acme.weather.v1".
+//
+// Override keys are matched against each file with the following precedence:
+//
+//  1. The exact path of the file, for example "acme/weather/v1/weather.proto".
+//  2. Glob patterns on the path of the file, for example "acme/**/*.proto". A "*"
+//     matches any sequence of characters within a path component, a "?" matches
+//     a single character within a path component, and a "**" component matches
+//     zero or more path components.
+//  3. The exact package of the file, for example "package:acme.weather.v1".
+//  4. Glob patterns on the package of the file, for example "package:acme.**".
+//     Package components are separated by "." instead of "/".
+//
+// Within 2 and 4, the pattern with the most non-wildcard characters takes precedence,
+// with ties broken by the lexicographical order of the patterns.
+const OverridePackageKeyPrefix = "package:"
+
 // Modifier modifies Images.
 type Modifier interface {
 	// Modify modifies the Image.
@@ -306,6 +324,92 @@ func RubyPackage(
 	)
 }
 
+// OptionTarget is the kind of element an OptionInjection sets the option on.
+type OptionTarget int
+
+const (
+	// OptionTargetFile sets the option on files.
+	OptionTargetFile OptionTarget = iota + 1
+	// OptionTargetMessage sets the option on all messages, including nested messages.
+	OptionTargetMessage
+	// OptionTargetField sets the option on all fields of all messages.
+	OptionTargetField
+)
+
+var (
+	// AllOptionTargetStrings are all OptionTarget strings.
+	AllOptionTargetStrings = []string{
+		"file",
+		"message",
+		"field",
+	}
+
+	optionTargetToString = map[OptionTarget]string{
+		OptionTargetFile:    "file",
+		OptionTargetMessage: "message",
+		OptionTargetField:   "field",
+	}
+	stringToOptionTarget = map[string]OptionTarget{
+		"file":    OptionTargetFile,
+		"message": OptionTargetMessage,
+		"field":   OptionTargetField,
+	}
+)
+
+// String implements fmt.Stringer.
+func (o OptionTarget) String() string {
+	s, ok := optionTargetToString[o]
+	if !ok {
+		return strconv.Itoa(int(o))
+	}
+	return s
+}
+
+// ParseOptionTarget parses the OptionTarget.
+//
+// The empty string is parsed as OptionTargetFile.
+func ParseOptionTarget(s string) (OptionTarget, error) {
+	if s == "" {
+		return OptionTargetFile, nil
+	}
+	optionTarget, ok := stringToOptionTarget[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("unknown option target: %q, must be one of %s", s, strings.Join(AllOptionTargetStrings, ", "))
+	}
+	return optionTarget, nil
+}
+
+// OptionInjection is a custom option to set on the files, or the elements of the
+// files, that it matches.
+type OptionInjection struct {
+	// Option is the fully-qualified name of the extension that declares the option,
+	// for example "acme.api.version".
+	Option string
+	// Value is the value of the option in the Protobuf text format, for example
+	// "true", "VERSION_V1", or "{ name: \"v1\" }". The quotes around string values
+	// may be omitted.
+	Value string
+	// Target is the kind of element to set the option on.
+	Target OptionTarget
+	// Match are the override keys of the files to set the option in,
+	// matched according to the precedence documented on OverridePackageKeyPrefix.
+	// If empty, the option is set in all files.
+	Match []string
+}
+
+// InjectOptions returns a Modifier that sets the custom options of the given
+// OptionInjections, replacing existing values of the options.
+//
+// The extensions that declare the options must be contained in the Image.
+// Well-known types are not modified.
+func InjectOptions(
+	logger *zap.Logger,
+	sweeper Sweeper,
+	optionInjections []*OptionInjection,
+) Modifier {
+	return injectOptions(logger, sweeper, optionInjections)
+}
+
 // isWellKnownType returns true if the given path is one of the well-known types.
 func isWellKnownType(ctx context.Context, imageFile bufimage.ImageFile) bool {
 	return datawkt.Exists(imageFile.Path())
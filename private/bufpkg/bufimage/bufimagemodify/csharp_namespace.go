@@ -50,10 +50,11 @@ func csharpNamespace(
 	for moduleIdentity, csharpNamespace := range moduleOverrides {
 		overrideModuleIdentityStrings[moduleIdentity.IdentityString()] = csharpNamespace
 	}
+	overrideMatcher := newOverrideMatcher(overrides)
 	return ModifierFunc(
 		func(ctx context.Context, image bufimage.Image) error {
 			seenModuleIdentityStrings := make(map[string]struct{}, len(overrideModuleIdentityStrings))
-			seenOverrideFiles := make(map[string]struct{}, len(overrides))
+			seenOverrideKeys := make(map[string]struct{}, len(overrides))
 			for _, imageFile := range image.Files() {
 				csharpNamespaceValue := csharpNamespaceValue(imageFile)
 				if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil {
@@ -63,9 +64,9 @@ func csharpNamespace(
 						csharpNamespaceValue = moduleNamespaceOverride
 					}
 				}
-				if overrideValue, ok := overrides[imageFile.Path()]; ok {
+				if overrideKey, overrideValue, ok := overrideMatcher.match(imageFile); ok {
 					csharpNamespaceValue = overrideValue
-					seenOverrideFiles[imageFile.Path()] = struct{}{}
+					seenOverrideKeys[overrideKey] = struct{}{}
 				}
 				if err := csharpNamespaceForFile(
 					ctx,
@@ -82,9 +83,9 @@ func csharpNamespace(
 					logger.Sugar().Warnf("csharp_namespace_prefix override for %q was unused", moduleIdentityString)
 				}
 			}
-			for overrideFile := range overrides {
-				if _, ok := seenOverrideFiles[overrideFile]; !ok {
-					logger.Sugar().Warnf("%s override for %q was unused", CsharpNamespaceID, overrideFile)
+			for overrideKey := range overrides {
+				if _, ok := seenOverrideKeys[overrideKey]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", CsharpNamespaceID, overrideKey)
 				}
 			}
 			return nil
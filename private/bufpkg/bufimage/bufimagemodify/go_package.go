@@ -54,7 +54,8 @@ func goPackage(
 		overrideModuleIdentityStrings[moduleIdentity.IdentityString()] = goPackagePrefix
 	}
 	seenModuleIdentityStrings := make(map[string]struct{}, len(overrideModuleIdentityStrings))
-	seenOverrideFiles := make(map[string]struct{}, len(overrides))
+	seenOverrideKeys := make(map[string]struct{}, len(overrides))
+	overrideMatcher := newOverrideMatcher(overrides)
 	return ModifierFunc(
 		func(ctx context.Context, image bufimage.Image) error {
 			for _, imageFile := range image.Files() {
@@ -67,9 +68,9 @@ func goPackage(
 					}
 				}
 				goPackageValue := GoPackageImportPathForFile(imageFile, importPathPrefix)
-				if overrideValue, ok := overrides[imageFile.Path()]; ok {
+				if overrideKey, overrideValue, ok := overrideMatcher.match(imageFile); ok {
 					goPackageValue = overrideValue
-					seenOverrideFiles[imageFile.Path()] = struct{}{}
+					seenOverrideKeys[overrideKey] = struct{}{}
 				}
 				if err := goPackageForFile(
 					ctx,
@@ -86,9 +87,9 @@ func goPackage(
 					logger.Sugar().Warnf("go_package_prefix override for %q was unused", moduleIdentityString)
 				}
 			}
-			for overrideFile := range overrides {
-				if _, ok := seenOverrideFiles[overrideFile]; !ok {
-					logger.Sugar().Warnf("%s override for %q was unused", GoPackageID, overrideFile)
+			for overrideKey := range overrides {
+				if _, ok := seenOverrideKeys[overrideKey]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", GoPackageID, overrideKey)
 				}
 			}
 			return nil
@@ -0,0 +1,233 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// InjectOptionsID is the ID of the option injection modifier.
+const InjectOptionsID = "INJECT_OPTIONS"
+
+func injectOptions(
+	logger *zap.Logger,
+	sweeper Sweeper,
+	optionInjections []*OptionInjection,
+) Modifier {
+	// A nil matcher matches all files.
+	overrideMatchers := make([]*overrideMatcher[struct{}], len(optionInjections))
+	for i, optionInjection := range optionInjections {
+		if len(optionInjection.Match) == 0 {
+			continue
+		}
+		keys := make(map[string]struct{}, len(optionInjection.Match))
+		for _, key := range optionInjection.Match {
+			keys[key] = struct{}{}
+		}
+		overrideMatchers[i] = newOverrideMatcher(keys)
+	}
+	return ModifierFunc(
+		func(ctx context.Context, image bufimage.Image) error {
+			if len(optionInjections) == 0 {
+				return nil
+			}
+			resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptorProtos(image)...)
+			if err != nil {
+				return err
+			}
+			optionInjectors := make([]*optionInjector, len(optionInjections))
+			for i, optionInjection := range optionInjections {
+				optionInjector, err := newOptionInjector(resolver, optionInjection)
+				if err != nil {
+					return fmt.Errorf("invalid option injection for %q: %w", optionInjection.Option, err)
+				}
+				optionInjectors[i] = optionInjector
+			}
+			matchedOptionInjections := make(map[int]struct{}, len(optionInjections))
+			for _, imageFile := range image.Files() {
+				if isWellKnownType(ctx, imageFile) {
+					continue
+				}
+				for i, optionInjector := range optionInjectors {
+					if overrideMatcher := overrideMatchers[i]; overrideMatcher != nil {
+						if _, _, ok := overrideMatcher.match(imageFile); !ok {
+							continue
+						}
+					}
+					matchedOptionInjections[i] = struct{}{}
+					if err := optionInjector.injectForFile(sweeper, imageFile); err != nil {
+						return fmt.Errorf("failed to inject option %q in %s: %w", optionInjector.optionName, imageFile.Path(), err)
+					}
+				}
+			}
+			for i, optionInjection := range optionInjections {
+				if _, ok := matchedOptionInjections[i]; !ok {
+					logger.Sugar().Warnf("%s for %q matched no files", InjectOptionsID, optionInjection.Option)
+				}
+			}
+			return nil
+		},
+	)
+}
+
+type optionInjector struct {
+	optionName              string
+	target                  OptionTarget
+	resolver                protoencoding.Resolver
+	extensionTypeDescriptor protoreflect.ExtensionTypeDescriptor
+	// template is an options message with only the option set to the injected value.
+	template proto.Message
+}
+
+func newOptionInjector(resolver protoencoding.Resolver, optionInjection *OptionInjection) (*optionInjector, error) {
+	var template proto.Message
+	switch optionInjection.Target {
+	case OptionTargetFile:
+		template = &descriptorpb.FileOptions{}
+	case OptionTargetMessage:
+		template = &descriptorpb.MessageOptions{}
+	case OptionTargetField:
+		template = &descriptorpb.FieldOptions{}
+	default:
+		return nil, fmt.Errorf("unknown option target %v", optionInjection.Target)
+	}
+	optionName := strings.TrimSuffix(strings.TrimPrefix(optionInjection.Option, "("), ")")
+	extensionType, err := resolver.FindExtensionByName(protoreflect.FullName(optionName))
+	if err != nil {
+		return nil, fmt.Errorf("could not find option %q: %w", optionName, err)
+	}
+	extensionTypeDescriptor := extensionType.TypeDescriptor()
+	optionsFullName := template.ProtoReflect().Descriptor().FullName()
+	if containingMessage := extensionTypeDescriptor.ContainingMessage().FullName(); containingMessage != optionsFullName {
+		return nil, fmt.Errorf(
+			"option %q extends %s and cannot be set on a %s, it must extend %s",
+			optionName,
+			containingMessage,
+			optionInjection.Target.String(),
+			optionsFullName,
+		)
+	}
+	value := optionInjection.Value
+	if kind := extensionTypeDescriptor.Kind(); (kind == protoreflect.StringKind || kind == protoreflect.BytesKind) &&
+		!extensionTypeDescriptor.IsList() &&
+		!strings.HasPrefix(value, `"`) &&
+		!strings.HasPrefix(value, `'`) {
+		value = strconv.Quote(value)
+	}
+	if err := (prototext.UnmarshalOptions{Resolver: resolver}).Unmarshal(
+		[]byte(fmt.Sprintf("[%s]: %s", optionName, value)),
+		template,
+	); err != nil {
+		return nil, fmt.Errorf("invalid value %q: %w", optionInjection.Value, err)
+	}
+	return &optionInjector{
+		optionName:              optionName,
+		target:                  optionInjection.Target,
+		resolver:                resolver,
+		extensionTypeDescriptor: extensionTypeDescriptor,
+		template:                template,
+	}, nil
+}
+
+func (o *optionInjector) injectForFile(sweeper Sweeper, imageFile bufimage.ImageFile) error {
+	descriptor := imageFile.FileDescriptorProto()
+	switch o.target {
+	case OptionTargetFile:
+		options := descriptor.GetOptions()
+		if options == nil {
+			options = &descriptorpb.FileOptions{}
+		}
+		if err := o.inject(options); err != nil {
+			return err
+		}
+		descriptor.Options = options
+		if sweeper != nil {
+			sweeper.mark(imageFile.Path(), append(append([]int32{}, fileOptionPath...), int32(o.extensionTypeDescriptor.Number())))
+		}
+		return nil
+	case OptionTargetMessage, OptionTargetField:
+		// The SourceCodeInfo locations of message and field options are not swept,
+		// as the sweeper only handles file options.
+		return o.injectForMessages(descriptor.GetMessageType())
+	default:
+		return fmt.Errorf("unknown option target %v", o.target)
+	}
+}
+
+func (o *optionInjector) injectForMessages(messages []*descriptorpb.DescriptorProto) error {
+	for _, message := range messages {
+		if message.GetOptions().GetMapEntry() {
+			continue
+		}
+		switch o.target {
+		case OptionTargetMessage:
+			options := message.GetOptions()
+			if options == nil {
+				options = &descriptorpb.MessageOptions{}
+			}
+			if err := o.inject(options); err != nil {
+				return err
+			}
+			message.Options = options
+		case OptionTargetField:
+			for _, field := range message.GetField() {
+				options := field.GetOptions()
+				if options == nil {
+					options = &descriptorpb.FieldOptions{}
+				}
+				if err := o.inject(options); err != nil {
+					return err
+				}
+				field.Options = options
+			}
+		}
+		if err := o.injectForMessages(message.GetNestedType()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inject returns a copy of the options with the option set to the injected value.
+func (o *optionInjector) inject(options proto.Message) error {
+	data, err := proto.Marshal(options)
+	if err != nil {
+		return err
+	}
+	// Custom options are unrecognized fields until the options are parsed
+	// with a resolver that contains the extension, in which case the existing
+	// value is replaced below instead of being appended to.
+	proto.Reset(options)
+	if err := (proto.UnmarshalOptions{Resolver: o.resolver}).Unmarshal(data, options); err != nil {
+		return err
+	}
+	template := proto.Clone(o.template)
+	options.ProtoReflect().Set(
+		o.extensionTypeDescriptor,
+		template.ProtoReflect().Get(o.extensionTypeDescriptor),
+	)
+	return nil
+}
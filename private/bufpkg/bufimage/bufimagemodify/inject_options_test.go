@@ -0,0 +1,193 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestInjectOptions(t *testing.T) {
+	t.Parallel()
+	dirPath := filepath.Join("testdata", "customoptions")
+	image := testGetImage(t, dirPath, true)
+	sweeper := NewFileOptionSweeper()
+	modifier := Merge(
+		InjectOptions(
+			zap.NewNop(),
+			sweeper,
+			[]*OptionInjection{
+				{
+					Option: "acme.api.version",
+					Value:  "v1",
+					Target: OptionTargetFile,
+					Match:  []string{"package:acme.orders.**"},
+				},
+				{
+					Option: "(acme.api.owner)",
+					Value:  `{ team: "orders" }`,
+					Target: OptionTargetFile,
+					Match:  []string{"acme/orders/**/*.proto"},
+				},
+				{
+					Option: "acme.api.audited",
+					Value:  "true",
+					Target: OptionTargetMessage,
+				},
+				{
+					Option: "acme.api.sensitivity",
+					Value:  "3",
+					Target: OptionTargetField,
+					Match:  []string{"package:acme.orders.v1"},
+				},
+			},
+		),
+		ModifierFunc(sweeper.Sweep),
+	)
+	require.NoError(t, modifier.Modify(context.Background(), image))
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptorProtos(image)...)
+	require.NoError(t, err)
+
+	ordersFile := image.GetFile("acme/orders/v1/orders.proto")
+	require.NotNil(t, ordersFile)
+	descriptor := ordersFile.FileDescriptorProto()
+	assert.Equal(t, "v1", testGetOption(t, resolver, descriptor.GetOptions(), "acme.api.version").String())
+	assert.Equal(
+		t,
+		"orders",
+		testGetOption(t, resolver, descriptor.GetOptions(), "acme.api.owner").Message().Get(
+			testGetFieldDescriptor(t, resolver, "acme.api.Owner", "team"),
+		).String(),
+	)
+	assertFileOptionSourceCodeInfoEmpty(t, image, []int32{8, 50000}, true)
+	require.Len(t, descriptor.GetMessageType(), 1)
+	order := descriptor.GetMessageType()[0]
+	assert.True(t, testGetOption(t, resolver, order.GetOptions(), "acme.api.audited").Bool())
+	for _, field := range order.GetField() {
+		assert.Equal(t, int64(3), testGetOption(t, resolver, field.GetOptions(), "acme.api.sensitivity").Int())
+	}
+	for _, nestedMessage := range order.GetNestedType() {
+		if nestedMessage.GetOptions().GetMapEntry() {
+			assert.False(t, testHasOption(t, resolver, nestedMessage.GetOptions(), "acme.api.audited"))
+			for _, field := range nestedMessage.GetField() {
+				assert.Nil(t, field.GetOptions())
+			}
+			continue
+		}
+		assert.True(t, testGetOption(t, resolver, nestedMessage.GetOptions(), "acme.api.audited").Bool())
+	}
+
+	otherFile := image.GetFile("other/other.proto")
+	require.NotNil(t, otherFile)
+	assert.Nil(t, otherFile.FileDescriptorProto().GetOptions())
+	otherMessage := otherFile.FileDescriptorProto().GetMessageType()[0]
+	assert.True(t, testGetOption(t, resolver, otherMessage.GetOptions(), "acme.api.audited").Bool())
+	assert.Nil(t, otherMessage.GetField()[0].GetOptions())
+
+	wktFile := image.GetFile("google/protobuf/descriptor.proto")
+	require.NotNil(t, wktFile)
+	for _, message := range wktFile.FileDescriptorProto().GetMessageType() {
+		assert.False(t, testHasOption(t, resolver, message.GetOptions(), "acme.api.audited"))
+	}
+}
+
+func TestInjectOptionsError(t *testing.T) {
+	t.Parallel()
+	dirPath := filepath.Join("testdata", "customoptions")
+	testInjectOptionsError(
+		t,
+		dirPath,
+		&OptionInjection{Option: "acme.api.missing", Value: "v1", Target: OptionTargetFile},
+		`could not find option "acme.api.missing"`,
+	)
+	testInjectOptionsError(
+		t,
+		dirPath,
+		&OptionInjection{Option: "acme.api.version", Value: "v1", Target: OptionTargetMessage},
+		`option "acme.api.version" extends google.protobuf.FileOptions and cannot be set on a message`,
+	)
+	testInjectOptionsError(
+		t,
+		dirPath,
+		&OptionInjection{Option: "acme.api.sensitivity", Value: "high", Target: OptionTargetField},
+		`invalid value "high"`,
+	)
+}
+
+func TestParseOptionTarget(t *testing.T) {
+	t.Parallel()
+	for _, optionTargetString := range AllOptionTargetStrings {
+		optionTarget, err := ParseOptionTarget(optionTargetString)
+		require.NoError(t, err)
+		assert.Equal(t, optionTargetString, optionTarget.String())
+	}
+	optionTarget, err := ParseOptionTarget("")
+	require.NoError(t, err)
+	assert.Equal(t, OptionTargetFile, optionTarget)
+	_, err = ParseOptionTarget("service")
+	require.Error(t, err)
+}
+
+func testInjectOptionsError(t *testing.T, dirPath string, optionInjection *OptionInjection, expectedErrorMessage string) {
+	image := testGetImage(t, dirPath, false)
+	err := InjectOptions(zap.NewNop(), nil, []*OptionInjection{optionInjection}).Modify(context.Background(), image)
+	require.ErrorContains(t, err, expectedErrorMessage)
+}
+
+func testGetOption(t *testing.T, resolver protoencoding.Resolver, options proto.Message, optionName string) protoreflect.Value {
+	reflectOptions, extensionTypeDescriptor := testReparseOptions(t, resolver, options, optionName)
+	require.True(t, reflectOptions.Has(extensionTypeDescriptor), "expected option %s to be set", optionName)
+	return reflectOptions.Get(extensionTypeDescriptor)
+}
+
+func testHasOption(t *testing.T, resolver protoencoding.Resolver, options proto.Message, optionName string) bool {
+	reflectOptions, extensionTypeDescriptor := testReparseOptions(t, resolver, options, optionName)
+	return reflectOptions.Has(extensionTypeDescriptor)
+}
+
+func testReparseOptions(
+	t *testing.T,
+	resolver protoencoding.Resolver,
+	options proto.Message,
+	optionName string,
+) (protoreflect.Message, protoreflect.ExtensionTypeDescriptor) {
+	extensionType, err := resolver.FindExtensionByName(protoreflect.FullName(optionName))
+	require.NoError(t, err)
+	extensionTypeDescriptor := extensionType.TypeDescriptor()
+	data, err := proto.Marshal(options)
+	require.NoError(t, err)
+	messageType, err := resolver.FindMessageByName(extensionTypeDescriptor.ContainingMessage().FullName())
+	require.NoError(t, err)
+	reflectOptions := messageType.New()
+	require.NoError(t, proto.UnmarshalOptions{Resolver: resolver}.Unmarshal(data, reflectOptions.Interface()))
+	return reflectOptions, extensionTypeDescriptor
+}
+
+func testGetFieldDescriptor(t *testing.T, resolver protoencoding.Resolver, messageName string, fieldName string) protoreflect.FieldDescriptor {
+	messageType, err := resolver.FindMessageByName(protoreflect.FullName(messageName))
+	require.NoError(t, err)
+	fieldDescriptor := messageType.Descriptor().Fields().ByName(protoreflect.Name(fieldName))
+	require.NotNil(t, fieldDescriptor)
+	return fieldDescriptor
+}
@@ -42,22 +42,23 @@ func javaMultipleFiles(
 	overrides map[string]bool,
 	preserveExistingValue bool,
 ) Modifier {
+	overrideMatcher := newOverrideMatcher(overrides)
 	return ModifierFunc(
 		func(ctx context.Context, image bufimage.Image) error {
-			seenOverrideFiles := make(map[string]struct{}, len(overrides))
+			seenOverrideKeys := make(map[string]struct{}, len(overrides))
 			for _, imageFile := range image.Files() {
 				modifierValue := value
-				if overrideValue, ok := overrides[imageFile.Path()]; ok {
+				if overrideKey, overrideValue, ok := overrideMatcher.match(imageFile); ok {
 					modifierValue = overrideValue
-					seenOverrideFiles[imageFile.Path()] = struct{}{}
+					seenOverrideKeys[overrideKey] = struct{}{}
 				}
 				if err := javaMultipleFilesForFile(ctx, sweeper, imageFile, modifierValue, preserveExistingValue); err != nil {
 					return err
 				}
 			}
-			for overrideFile := range overrides {
-				if _, ok := seenOverrideFiles[overrideFile]; !ok {
-					logger.Sugar().Warnf("%s override for %q was unused", JavaMultipleFilesID, overrideFile)
+			for overrideKey := range overrides {
+				if _, ok := seenOverrideKeys[overrideKey]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", JavaMultipleFilesID, overrideKey)
 				}
 			}
 			return nil
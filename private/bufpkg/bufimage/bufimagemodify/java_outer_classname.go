@@ -38,22 +38,23 @@ func javaOuterClassname(
 	overrides map[string]string,
 	preserveExistingValue bool,
 ) Modifier {
+	overrideMatcher := newOverrideMatcher(overrides)
 	return ModifierFunc(
 		func(ctx context.Context, image bufimage.Image) error {
-			seenOverrideFiles := make(map[string]struct{}, len(overrides))
+			seenOverrideKeys := make(map[string]struct{}, len(overrides))
 			for _, imageFile := range image.Files() {
 				javaOuterClassnameValue := javaOuterClassnameValue(imageFile)
-				if overrideValue, ok := overrides[imageFile.Path()]; ok {
+				if overrideKey, overrideValue, ok := overrideMatcher.match(imageFile); ok {
 					javaOuterClassnameValue = overrideValue
-					seenOverrideFiles[imageFile.Path()] = struct{}{}
+					seenOverrideKeys[overrideKey] = struct{}{}
 				}
 				if err := javaOuterClassnameForFile(ctx, sweeper, imageFile, javaOuterClassnameValue, preserveExistingValue); err != nil {
 					return err
 				}
 			}
-			for overrideFile := range overrides {
-				if _, ok := seenOverrideFiles[overrideFile]; !ok {
-					logger.Sugar().Warnf("%s override for %q was unused", JavaOuterClassNameID, overrideFile)
+			for overrideKey := range overrides {
+				if _, ok := seenOverrideKeys[overrideKey]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", JavaOuterClassNameID, overrideKey)
 				}
 			}
 			return nil
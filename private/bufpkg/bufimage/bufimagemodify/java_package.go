@@ -59,7 +59,8 @@ func javaPackage(
 		overrideModuleIdentityStrings[moduleIdentity.IdentityString()] = javaPackagePrefix
 	}
 	seenModuleIdentityStrings := make(map[string]struct{}, len(overrideModuleIdentityStrings))
-	seenOverrideFiles := make(map[string]struct{}, len(overrides))
+	seenOverrideKeys := make(map[string]struct{}, len(overrides))
+	overrideMatcher := newOverrideMatcher(overrides)
 	return ModifierFunc(
 		func(ctx context.Context, image bufimage.Image) error {
 			for _, imageFile := range image.Files() {
@@ -72,9 +73,9 @@ func javaPackage(
 					}
 				}
 				javaPackageValue := javaPackageValue(imageFile, packagePrefix)
-				if overridePackagePrefix, ok := overrides[imageFile.Path()]; ok {
+				if overrideKey, overridePackagePrefix, ok := overrideMatcher.match(imageFile); ok {
 					javaPackageValue = overridePackagePrefix
-					seenOverrideFiles[imageFile.Path()] = struct{}{}
+					seenOverrideKeys[overrideKey] = struct{}{}
 				}
 				if err := javaPackageForFile(
 					ctx,
@@ -91,9 +92,9 @@ func javaPackage(
 					logger.Sugar().Warnf("java_package_prefix override for %q was unused", moduleIdentityString)
 				}
 			}
-			for overrideFile := range overrides {
-				if _, ok := seenOverrideFiles[overrideFile]; !ok {
-					logger.Sugar().Warnf("%s override for %q was unused", JavaPackageID, overrideFile)
+			for overrideKey := range overrides {
+				if _, ok := seenOverrideKeys[overrideKey]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", JavaPackageID, overrideKey)
 				}
 			}
 			return nil
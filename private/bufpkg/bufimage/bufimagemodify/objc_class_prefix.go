@@ -52,10 +52,11 @@ func objcClassPrefix(
 	for moduleIdentity, goPackagePrefix := range moduleOverrides {
 		overrideModuleIdentityStrings[moduleIdentity.IdentityString()] = goPackagePrefix
 	}
+	overrideMatcher := newOverrideMatcher(overrides)
 	return ModifierFunc(
 		func(ctx context.Context, image bufimage.Image) error {
 			seenModuleIdentityStrings := make(map[string]struct{}, len(overrideModuleIdentityStrings))
-			seenOverrideFiles := make(map[string]struct{}, len(overrides))
+			seenOverrideKeys := make(map[string]struct{}, len(overrides))
 			for _, imageFile := range image.Files() {
 				objcClassPrefixValue := objcClassPrefixValue(imageFile)
 				if defaultPrefix != "" {
@@ -68,9 +69,9 @@ func objcClassPrefix(
 						seenModuleIdentityStrings[moduleIdentityString] = struct{}{}
 					}
 				}
-				if overrideValue, ok := overrides[imageFile.Path()]; ok {
+				if overrideKey, overrideValue, ok := overrideMatcher.match(imageFile); ok {
 					objcClassPrefixValue = overrideValue
-					seenOverrideFiles[imageFile.Path()] = struct{}{}
+					seenOverrideKeys[overrideKey] = struct{}{}
 				}
 				if err := objcClassPrefixForFile(ctx, sweeper, imageFile, objcClassPrefixValue, exceptModuleIdentityStrings); err != nil {
 					return err
@@ -81,9 +82,9 @@ func objcClassPrefix(
 					logger.Sugar().Warnf("%s override for %q was unused", ObjcClassPrefixID, moduleIdentityString)
 				}
 			}
-			for overrideFile := range overrides {
-				if _, ok := seenOverrideFiles[overrideFile]; !ok {
-					logger.Sugar().Warnf("%s override for %q was unused", ObjcClassPrefixID, overrideFile)
+			for overrideKey := range overrides {
+				if _, ok := seenOverrideKeys[overrideKey]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", ObjcClassPrefixID, overrideKey)
 				}
 			}
 			return nil
@@ -51,10 +51,11 @@ func optimizeFor(
 	for moduleIdentity, optimizeFor := range moduleOverrides {
 		overrideModuleIdentityStrings[moduleIdentity.IdentityString()] = optimizeFor
 	}
+	overrideMatcher := newOverrideMatcher(overrides)
 	return ModifierFunc(
 		func(ctx context.Context, image bufimage.Image) error {
 			seenModuleIdentityStrings := make(map[string]struct{}, len(overrideModuleIdentityStrings))
-			seenOverrideFiles := make(map[string]struct{}, len(overrides))
+			seenOverrideKeys := make(map[string]struct{}, len(overrides))
 			for _, imageFile := range image.Files() {
 				modifierValue := defaultOptimizeFor
 				if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil {
@@ -64,9 +65,9 @@ func optimizeFor(
 						seenModuleIdentityStrings[moduleIdentityString] = struct{}{}
 					}
 				}
-				if overrideValue, ok := overrides[imageFile.Path()]; ok {
+				if overrideKey, overrideValue, ok := overrideMatcher.match(imageFile); ok {
 					modifierValue = overrideValue
-					seenOverrideFiles[imageFile.Path()] = struct{}{}
+					seenOverrideKeys[overrideKey] = struct{}{}
 				}
 				if err := optimizeForForFile(
 					ctx,
@@ -83,9 +84,9 @@ func optimizeFor(
 					logger.Sugar().Warnf("optimize_for override for %q was unused", moduleIdentityString)
 				}
 			}
-			for overrideFile := range overrides {
-				if _, ok := seenOverrideFiles[overrideFile]; !ok {
-					logger.Sugar().Warnf("%s override for %q was unused", OptimizeForID, overrideFile)
+			for overrideKey := range overrides {
+				if _, ok := seenOverrideKeys[overrideKey]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", OptimizeForID, overrideKey)
 				}
 			}
 			return nil
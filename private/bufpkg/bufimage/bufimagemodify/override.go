@@ -0,0 +1,157 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+// overrideMatcher matches image files against the keys of an override map
+// with the precedence documented on OverridePackageKeyPrefix.
+type overrideMatcher[T any] struct {
+	pathToValue     map[string]T
+	packageToValue  map[string]T
+	pathPatterns    []*overridePattern[T]
+	packagePatterns []*overridePattern[T]
+}
+
+type overridePattern[T any] struct {
+	key    string
+	regexp *regexp.Regexp
+	value  T
+	// numLiterals is the number of non-wildcard characters in the pattern.
+	numLiterals int
+}
+
+func newOverrideMatcher[T any](overrides map[string]T) *overrideMatcher[T] {
+	overrideMatcher := &overrideMatcher[T]{
+		pathToValue:    make(map[string]T),
+		packageToValue: make(map[string]T),
+	}
+	for key, value := range overrides {
+		if strings.HasPrefix(key, OverridePackageKeyPrefix) {
+			packageName := strings.TrimPrefix(key, OverridePackageKeyPrefix)
+			if !isGlobPattern(packageName) {
+				overrideMatcher.packageToValue[packageName] = value
+				continue
+			}
+			overrideMatcher.packagePatterns = append(
+				overrideMatcher.packagePatterns,
+				newOverridePattern(key, packageName, '.', value),
+			)
+			continue
+		}
+		if !isGlobPattern(key) {
+			overrideMatcher.pathToValue[key] = value
+			continue
+		}
+		overrideMatcher.pathPatterns = append(
+			overrideMatcher.pathPatterns,
+			newOverridePattern(key, key, '/', value),
+		)
+	}
+	sortOverridePatterns(overrideMatcher.pathPatterns)
+	sortOverridePatterns(overrideMatcher.packagePatterns)
+	return overrideMatcher
+}
+
+// match returns the override key and value that apply to the image file, if any.
+func (o *overrideMatcher[T]) match(imageFile bufimage.ImageFile) (string, T, bool) {
+	if value, ok := o.pathToValue[imageFile.Path()]; ok {
+		return imageFile.Path(), value, true
+	}
+	for _, pathPattern := range o.pathPatterns {
+		if pathPattern.regexp.MatchString(imageFile.Path()) {
+			return pathPattern.key, pathPattern.value, true
+		}
+	}
+	packageName := imageFile.FileDescriptorProto().GetPackage()
+	if value, ok := o.packageToValue[packageName]; ok {
+		return OverridePackageKeyPrefix + packageName, value, true
+	}
+	for _, packagePattern := range o.packagePatterns {
+		if packagePattern.regexp.MatchString(packageName) {
+			return packagePattern.key, packagePattern.value, true
+		}
+	}
+	var zero T
+	return "", zero, false
+}
+
+func newOverridePattern[T any](key string, pattern string, separator byte, value T) *overridePattern[T] {
+	return &overridePattern[T]{
+		key: key,
+		// globToRegexp quotes all non-wildcard characters, so the result always compiles.
+		regexp:      regexp.MustCompile(globToRegexp(pattern, separator)),
+		value:       value,
+		numLiterals: len(pattern) - strings.Count(pattern, "*") - strings.Count(pattern, "?"),
+	}
+}
+
+func sortOverridePatterns[T any](overridePatterns []*overridePattern[T]) {
+	sort.Slice(
+		overridePatterns,
+		func(i int, j int) bool {
+			if overridePatterns[i].numLiterals != overridePatterns[j].numLiterals {
+				return overridePatterns[i].numLiterals > overridePatterns[j].numLiterals
+			}
+			return overridePatterns[i].key < overridePatterns[j].key
+		},
+	)
+}
+
+func isGlobPattern(value string) bool {
+	return strings.ContainsAny(value, "*?")
+}
+
+// globToRegexp converts the glob pattern to an anchored regular expression.
+//
+// A "*" matches any sequence of characters other than the separator, a "?"
+// matches a single character other than the separator, and a "**" component
+// matches zero or more components.
+func globToRegexp(pattern string, separator byte) string {
+	quotedSeparator := regexp.QuoteMeta(string(separator))
+	notSeparator := "[^" + quotedSeparator + "]"
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				atComponentStart := i == 1 || pattern[i-2] == separator
+				if atComponentStart && i+1 < len(pattern) && pattern[i+1] == separator {
+					// "**/" matches zero or more leading components.
+					i++
+					builder.WriteString("(?:.*" + quotedSeparator + ")?")
+					continue
+				}
+				builder.WriteString(".*")
+				continue
+			}
+			builder.WriteString(notSeparator + "*")
+		case '?':
+			builder.WriteString(notSeparator)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	return builder.String()
+}
@@ -0,0 +1,119 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	t.Parallel()
+	testGlobMatches(t, "a/*.proto", '/', "a/b.proto")
+	testGlobNotMatches(t, "a/*.proto", '/', "a/b/c.proto", "b/a.proto")
+	testGlobMatches(t, "a/**/*.proto", '/', "a/b.proto", "a/b/c.proto", "a/b/c/d.proto")
+	testGlobNotMatches(t, "a/**/*.proto", '/', "b/a.proto")
+	testGlobMatches(t, "**/internal/*.proto", '/', "internal/a.proto", "a/b/internal/c.proto")
+	testGlobMatches(t, "a/**", '/', "a/b.proto", "a/b/c.proto")
+	testGlobNotMatches(t, "a/**", '/', "ab/c.proto")
+	testGlobMatches(t, "a/?.proto", '/', "a/b.proto")
+	testGlobNotMatches(t, "a/?.proto", '/', "a/bc.proto")
+	testGlobMatches(t, "acme.*.v1", '.', "acme.weather.v1")
+	testGlobNotMatches(t, "acme.*.v1", '.', "acme.weather.alpha.v1")
+	testGlobMatches(t, "acme.**", '.', "acme.weather", "acme.weather.v1")
+	testGlobNotMatches(t, "acme.**", '.', "acmeweather")
+}
+
+func TestOverrideMatcher(t *testing.T) {
+	t.Parallel()
+	overrideMatcher := newOverrideMatcher(
+		map[string]string{
+			"a/b/c.proto":            "exact",
+			"a/**/*.proto":           "glob",
+			"a/b/*.proto":            "specific-glob",
+			"package:acme.v1":        "package",
+			"package:acme.**":        "package-glob",
+			"package:acme.weather.*": "specific-package-glob",
+		},
+	)
+	testOverrideMatch(t, overrideMatcher, "a/b/c.proto", "acme.v1", "a/b/c.proto", "exact")
+	testOverrideMatch(t, overrideMatcher, "a/b/d.proto", "acme.v1", "a/b/*.proto", "specific-glob")
+	testOverrideMatch(t, overrideMatcher, "a/d/e.proto", "acme.v1", "a/**/*.proto", "glob")
+	testOverrideMatch(t, overrideMatcher, "b/c.proto", "acme.v1", "package:acme.v1", "package")
+	testOverrideMatch(t, overrideMatcher, "b/c.proto", "acme.weather.v1", "package:acme.weather.*", "specific-package-glob")
+	testOverrideMatch(t, overrideMatcher, "b/c.proto", "acme.orders.v1", "package:acme.**", "package-glob")
+	testOverrideNotMatch(t, overrideMatcher, "b/c.proto", "other.v1")
+}
+
+func testGlobMatches(t *testing.T, pattern string, separator byte, values ...string) {
+	re := regexp.MustCompile(globToRegexp(pattern, separator))
+	for _, value := range values {
+		assert.True(t, re.MatchString(value), "expected %q to match %q", pattern, value)
+	}
+}
+
+func testGlobNotMatches(t *testing.T, pattern string, separator byte, values ...string) {
+	re := regexp.MustCompile(globToRegexp(pattern, separator))
+	for _, value := range values {
+		assert.False(t, re.MatchString(value), "expected %q to not match %q", pattern, value)
+	}
+}
+
+func testOverrideMatch(
+	t *testing.T,
+	overrideMatcher *overrideMatcher[string],
+	path string,
+	packageName string,
+	expectedKey string,
+	expectedValue string,
+) {
+	key, value, ok := overrideMatcher.match(testNewImageFile(t, path, packageName))
+	require.True(t, ok)
+	assert.Equal(t, expectedKey, key)
+	assert.Equal(t, expectedValue, value)
+}
+
+func testOverrideNotMatch(
+	t *testing.T,
+	overrideMatcher *overrideMatcher[string],
+	path string,
+	packageName string,
+) {
+	_, _, ok := overrideMatcher.match(testNewImageFile(t, path, packageName))
+	assert.False(t, ok)
+}
+
+func testNewImageFile(t *testing.T, path string, packageName string) bufimage.ImageFile {
+	imageFile, err := bufimage.NewImageFile(
+		&descriptorpb.FileDescriptorProto{
+			Name:    proto.String(path),
+			Package: proto.String(packageName),
+		},
+		nil,
+		"",
+		"",
+		false,
+		false,
+		nil,
+	)
+	require.NoError(t, err)
+	return imageFile
+}
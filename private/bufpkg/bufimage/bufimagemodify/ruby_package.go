@@ -50,10 +50,11 @@ func rubyPackage(
 	for moduleIdentity, rubyPackage := range moduleOverrides {
 		overrideModuleIdentityStrings[moduleIdentity.IdentityString()] = rubyPackage
 	}
+	overrideMatcher := newOverrideMatcher(overrides)
 	return ModifierFunc(
 		func(ctx context.Context, image bufimage.Image) error {
 			seenModuleIdentityStrings := make(map[string]struct{}, len(overrideModuleIdentityStrings))
-			seenOverrideFiles := make(map[string]struct{}, len(overrides))
+			seenOverrideKeys := make(map[string]struct{}, len(overrides))
 			for _, imageFile := range image.Files() {
 				rubyPackageValue := rubyPackageValue(imageFile)
 				if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil {
@@ -63,9 +64,9 @@ func rubyPackage(
 						rubyPackageValue = moduleNamespaceOverride
 					}
 				}
-				if overrideValue, ok := overrides[imageFile.Path()]; ok {
+				if overrideKey, overrideValue, ok := overrideMatcher.match(imageFile); ok {
 					rubyPackageValue = overrideValue
-					seenOverrideFiles[imageFile.Path()] = struct{}{}
+					seenOverrideKeys[overrideKey] = struct{}{}
 				}
 				if err := rubyPackageForFile(
 					ctx,
@@ -82,9 +83,9 @@ func rubyPackage(
 					logger.Sugar().Warnf("ruby_package override for %q was unused", moduleIdentityString)
 				}
 			}
-			for overrideFile := range overrides {
-				if _, ok := seenOverrideFiles[overrideFile]; !ok {
-					logger.Sugar().Warnf("%s override for %q was unused", RubyPackageID, overrideFile)
+			for overrideKey := range overrides {
+				if _, ok := seenOverrideKeys[overrideKey]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", RubyPackageID, overrideKey)
 				}
 			}
 			return nil
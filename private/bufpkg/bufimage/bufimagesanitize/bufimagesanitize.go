@@ -0,0 +1,112 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufimagesanitize removes internal details from images, so that schemas
+// can be published outside of an organization, for example to partners.
+package bufimagesanitize
+
+import (
+	"errors"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+// ErrOptionNotFound is the error returned if a custom option that is configured
+// to be stripped or to remove declarations is not declared in the image.
+//
+// Options that are not found are errors rather than ignored, so that a typo never
+// results in internal details being published.
+var ErrOptionNotFound = errors.New("option not found")
+
+// DanglingReference is a reference from a declaration that is kept to a
+// declaration that is removed.
+type DanglingReference struct {
+	// FilePath is the path of the file of the declaration with the reference.
+	FilePath string
+	// Element is the declaration with the reference, such as
+	// "field acme.v1.Order.audit" or "method acme.v1.OrderService.GetAudit".
+	Element string
+	// Reference is the full name of the removed declaration, such as "acme.v1.Audit".
+	Reference string
+}
+
+// String returns a single-line description of the DanglingReference, such as:
+//
+//	acme/v1/order.proto: field acme.v1.Order.audit refers to removed acme.v1.Audit
+func (d *DanglingReference) String() string {
+	return d.FilePath + ": " + d.Element + " refers to removed " + d.Reference
+}
+
+// SanitizeOption is an option for Sanitize.
+type SanitizeOption func(*sanitizeOptions)
+
+// SanitizeWithStrippedOption returns a new SanitizeOption that strips the custom
+// option with the full name, such as "acme.owner", from all declarations.
+func SanitizeWithStrippedOption(optionName string) SanitizeOption {
+	return func(sanitizeOptions *sanitizeOptions) {
+		sanitizeOptions.strippedOptionNames = append(sanitizeOptions.strippedOptionNames, optionName)
+	}
+}
+
+// SanitizeWithRemovedDeclarations returns a new SanitizeOption that removes the
+// declarations that have the custom option with the full name set to the value,
+// such as "acme.internal" set to "true".
+//
+// The value is compared to the name of the value for enum options, and to the
+// value itself for all other options, which must not be repeated or messages.
+func SanitizeWithRemovedDeclarations(optionName string, value string) SanitizeOption {
+	return func(sanitizeOptions *sanitizeOptions) {
+		sanitizeOptions.removals = append(
+			sanitizeOptions.removals,
+			&removal{
+				optionName: optionName,
+				value:      value,
+			},
+		)
+	}
+}
+
+// Sanitize returns a copy of the image with its internal details removed.
+//
+//   - All source code info, which includes all comments, is removed.
+//   - The custom options configured with SanitizeWithStrippedOption are stripped.
+//   - The declarations matched by SanitizeWithRemovedDeclarations are removed along
+//     with the declarations nested in them. A file is removed if its options match.
+//     The custom options declared by removed extensions are stripped.
+//   - Imports that are no longer used are removed, and so are imported files that
+//     are no longer imported.
+//
+// If a declaration that is kept refers to a declaration that is removed, the image
+// would be invalid, so the dangling references are returned instead of the image.
+func Sanitize(image bufimage.Image, options ...SanitizeOption) (bufimage.Image, []*DanglingReference, error) {
+	sanitizeOptions := newSanitizeOptions()
+	for _, option := range options {
+		option(sanitizeOptions)
+	}
+	return sanitize(image, sanitizeOptions)
+}
+
+type sanitizeOptions struct {
+	strippedOptionNames []string
+	removals            []*removal
+}
+
+func newSanitizeOptions() *sanitizeOptions {
+	return &sanitizeOptions{}
+}
+
+type removal struct {
+	optionName string
+	value      string
+}
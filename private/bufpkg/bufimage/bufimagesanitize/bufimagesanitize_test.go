@@ -0,0 +1,215 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagesanitize
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testInternalProto = `syntax = "proto3";
+
+package acme.internal.v1;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.FileOptions {
+  bool internal_file = 50000;
+}
+
+extend google.protobuf.MessageOptions {
+  bool internal_message = 50000;
+  string owner = 50001;
+}
+
+extend google.protobuf.FieldOptions {
+  bool internal_field = 50000;
+}
+
+extend google.protobuf.EnumValueOptions {
+  bool internal_value = 50000;
+}
+
+extend google.protobuf.MethodOptions {
+  Visibility visibility = 50000;
+}
+
+enum Visibility {
+  VISIBILITY_UNSPECIFIED = 0;
+  VISIBILITY_PUBLIC = 1;
+  VISIBILITY_INTERNAL = 2;
+}
+`
+
+const testOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "acme/internal/v1/internal.proto";
+import "google/protobuf/timestamp.proto";
+
+// OrderService manages orders.
+service OrderService {
+  rpc GetOrder(GetOrderRequest) returns (Order) {
+    option (acme.internal.v1.visibility) = VISIBILITY_PUBLIC;
+  }
+  rpc GetAudit(GetOrderRequest) returns (Audit) {
+    option (acme.internal.v1.visibility) = VISIBILITY_INTERNAL;
+  }
+}
+
+message GetOrderRequest {
+  string id = 1;
+}
+
+// Order is an order.
+message Order {
+  option (acme.internal.v1.owner) = "team-orders";
+  string id = 1;
+  Status status = 2;
+  map<string, string> debug_labels = 3 [(acme.internal.v1.internal_field) = true];
+  google.protobuf.Timestamp debug_time = 4 [(acme.internal.v1.internal_field) = true];
+  oneof debug {
+    string trace_id = 5 [(acme.internal.v1.internal_field) = true];
+  }
+  oneof payment {
+    string card = 6;
+    string account = 7;
+  }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_PENDING = 1;
+  STATUS_QUARANTINED = 2 [(acme.internal.v1.internal_value) = true];
+}
+
+message Audit {
+  option (acme.internal.v1.internal_message) = true;
+  string actor = 1;
+}
+`
+
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+	image := bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"acme/internal/v1/internal.proto": testInternalProto,
+			"acme/v1/order.proto":             testOrderProto,
+			"acme/v1/debug.proto": `syntax = "proto3";
+package acme.v1;
+import "acme/internal/v1/internal.proto";
+option (acme.internal.v1.internal_file) = true;
+message Debug {}
+`,
+		},
+	)
+	sanitizedImage, danglingReferences, err := Sanitize(
+		image,
+		SanitizeWithStrippedOption("acme.internal.v1.owner"),
+		SanitizeWithRemovedDeclarations("acme.internal.v1.internal_file", "true"),
+		SanitizeWithRemovedDeclarations("acme.internal.v1.internal_message", "true"),
+		SanitizeWithRemovedDeclarations("acme.internal.v1.internal_field", "true"),
+		SanitizeWithRemovedDeclarations("acme.internal.v1.internal_value", "true"),
+		SanitizeWithRemovedDeclarations("acme.internal.v1.visibility", "VISIBILITY_INTERNAL"),
+	)
+	require.NoError(t, err)
+	require.Empty(t, danglingReferences)
+	// The timestamp file is no longer imported.
+	assert.Equal(
+		t,
+		[]string{
+			"google/protobuf/descriptor.proto",
+			"acme/internal/v1/internal.proto",
+			"acme/v1/order.proto",
+		},
+		slicesext.Map(sanitizedImage.Files(), bufimage.ImageFile.Path),
+	)
+	fileDescriptorProto := sanitizedImage.GetFile("acme/v1/order.proto").FileDescriptorProto()
+	assert.Nil(t, fileDescriptorProto.SourceCodeInfo)
+	// The internal file is still imported, as it declares the visibility option
+	// that is set on GetOrder.
+	assert.Equal(t, []string{"acme/internal/v1/internal.proto"}, fileDescriptorProto.Dependency)
+	assert.Equal(
+		t,
+		[]string{"GetOrderRequest", "Order"},
+		testNames(fileDescriptorProto.MessageType),
+	)
+	orderDescriptorProto := fileDescriptorProto.MessageType[1]
+	assert.Nil(t, orderDescriptorProto.Options)
+	assert.Equal(t, []string{"id", "status", "card", "account"}, testNames(orderDescriptorProto.Field))
+	assert.Empty(t, orderDescriptorProto.NestedType)
+	assert.Equal(t, []string{"payment"}, testNames(orderDescriptorProto.OneofDecl))
+	assert.Equal(t, int32(0), orderDescriptorProto.Field[2].GetOneofIndex())
+	assert.Equal(t, []string{"STATUS_UNSPECIFIED", "STATUS_PENDING"}, testNames(fileDescriptorProto.EnumType[0].Value))
+	assert.Equal(t, []string{"GetOrder"}, testNames(fileDescriptorProto.Service[0].Method))
+	// The sanitized image is valid.
+	_, err = protodesc.NewFiles(
+		&descriptorpb.FileDescriptorSet{
+			File: bufimage.ImageToFileDescriptorProtos(sanitizedImage),
+		},
+	)
+	require.NoError(t, err)
+}
+
+func TestSanitizeDanglingReferences(t *testing.T) {
+	t.Parallel()
+	image := bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"acme/internal/v1/internal.proto": testInternalProto,
+			"acme/v1/order.proto":             testOrderProto,
+		},
+	)
+	sanitizedImage, danglingReferences, err := Sanitize(
+		image,
+		SanitizeWithRemovedDeclarations("acme.internal.v1.internal_message", "true"),
+	)
+	require.NoError(t, err)
+	assert.Nil(t, sanitizedImage)
+	assert.Equal(
+		t,
+		[]string{
+			"acme/v1/order.proto: method acme.v1.OrderService.GetAudit refers to removed acme.v1.Audit",
+		},
+		slicesext.Map(danglingReferences, (*DanglingReference).String),
+	)
+}
+
+func TestSanitizeOptionNotFound(t *testing.T) {
+	t.Parallel()
+	image := bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"acme/internal/v1/internal.proto": testInternalProto,
+		},
+	)
+	_, _, err := Sanitize(image, SanitizeWithStrippedOption("acme.internal.v1.ownr"))
+	assert.ErrorIs(t, err, ErrOptionNotFound)
+	_, _, err = Sanitize(image, SanitizeWithRemovedDeclarations("acme.internal.v1.internal", "true"))
+	assert.ErrorIs(t, err, ErrOptionNotFound)
+}
+
+func testNames[T interface{ GetName() string }](values []T) []string {
+	return slicesext.Map(values, T.GetName)
+}
@@ -0,0 +1,759 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagesanitize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func sanitize(image bufimage.Image, options *sanitizeOptions) (bufimage.Image, []*DanglingReference, error) {
+	imageFiles := image.Files()
+	fileDescriptorProtos, resolver, err := getResolvedFileDescriptorProtos(imageFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	sanitizer := newSanitizer()
+	for _, optionName := range options.strippedOptionNames {
+		extensionDescriptor, err := findOption(resolver, optionName)
+		if err != nil {
+			return nil, nil, err
+		}
+		sanitizer.strippedOptions[extensionDescriptor.FullName()] = struct{}{}
+	}
+	for _, removal := range options.removals {
+		extensionDescriptor, err := findOption(resolver, removal.optionName)
+		if err != nil {
+			return nil, nil, err
+		}
+		if extensionDescriptor.IsList() || extensionDescriptor.Message() != nil {
+			return nil, nil, fmt.Errorf("option %q must not be repeated or a message to remove declarations", removal.optionName)
+		}
+		sanitizer.matchers = append(
+			sanitizer.matchers,
+			&matcher{
+				extensionDescriptor: extensionDescriptor,
+				value:               removal.value,
+			},
+		)
+	}
+	// All declarations are removed before anything else, as any file may refer to
+	// the declarations removed from any other file.
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		if sanitizer.matches(fileDescriptorProto.GetOptions()) {
+			sanitizer.removedFilePaths[fileDescriptorProto.GetName()] = struct{}{}
+			sanitizer.addRemovedFile(fileDescriptorProto)
+			continue
+		}
+		sanitizer.removeFromFile(fileDescriptorProto)
+	}
+	var keptFileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	var keptImageFiles []bufimage.ImageFile
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		if _, ok := sanitizer.removedFilePaths[fileDescriptorProto.GetName()]; ok {
+			continue
+		}
+		keptFileDescriptorProtos = append(keptFileDescriptorProtos, fileDescriptorProto)
+		keptImageFiles = append(keptImageFiles, imageFiles[i])
+	}
+	for _, fileDescriptorProto := range keptFileDescriptorProtos {
+		sanitizer.checkFile(fileDescriptorProto)
+	}
+	if len(sanitizer.danglingReferences) > 0 {
+		return nil, sanitizer.danglingReferences, nil
+	}
+	for _, fileDescriptorProto := range keptFileDescriptorProtos {
+		fileDescriptorProto.SourceCodeInfo = nil
+		rangeOptions(fileDescriptorProto, sanitizer.strip)
+	}
+	keptFileDescriptorProtos, keptImageFiles = removeUnusedImports(keptFileDescriptorProtos, keptImageFiles)
+	if len(keptImageFiles) == 0 {
+		return nil, nil, fmt.Errorf("all files were removed")
+	}
+	sanitizedImageFiles := make([]bufimage.ImageFile, len(keptImageFiles))
+	for i, imageFile := range keptImageFiles {
+		sanitizedImageFile, err := bufimage.NewImageFile(
+			keptFileDescriptorProtos[i],
+			imageFile.ModuleIdentity(),
+			imageFile.Commit(),
+			imageFile.ExternalPath(),
+			imageFile.IsImport(),
+			imageFile.IsSyntaxUnspecified(),
+			nil,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		sanitizedImageFiles[i] = sanitizedImageFile
+	}
+	sanitizedImage, err := bufimage.NewImage(sanitizedImageFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sanitizedImage, nil, nil
+}
+
+// getResolvedFileDescriptorProtos returns copies of the FileDescriptorProtos of the
+// image files with their custom options resolved, so that options can be matched
+// and stripped by name.
+func getResolvedFileDescriptorProtos(
+	imageFiles []bufimage.ImageFile,
+) ([]*descriptorpb.FileDescriptorProto, protoencoding.Resolver, error) {
+	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(imageFiles))
+	for i, imageFile := range imageFiles {
+		fileDescriptorProtos[i] = imageFile.FileDescriptorProto()
+	}
+	resolver, err := protoencoding.NewResolver(fileDescriptorProtos...)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolvedFileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		data, err := proto.Marshal(fileDescriptorProto)
+		if err != nil {
+			return nil, nil, err
+		}
+		resolvedFileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+		if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(data, resolvedFileDescriptorProto); err != nil {
+			return nil, nil, err
+		}
+		resolvedFileDescriptorProtos[i] = resolvedFileDescriptorProto
+	}
+	return resolvedFileDescriptorProtos, resolver, nil
+}
+
+func findOption(resolver protoencoding.Resolver, optionName string) (protoreflect.ExtensionTypeDescriptor, error) {
+	extensionType, err := resolver.FindExtensionByName(protoreflect.FullName(optionName))
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", optionName, ErrOptionNotFound)
+	}
+	return extensionType.TypeDescriptor(), nil
+}
+
+type sanitizer struct {
+	matchers         []*matcher
+	strippedOptions  map[protoreflect.FullName]struct{}
+	removedFilePaths map[string]struct{}
+	// removedNames are the full names of the removed messages, enums, and extensions.
+	removedNames map[string]struct{}
+	// removedEnumValues are the full names of the removed values of the enums
+	// that are kept, by the full name of the enum and the name of the value.
+	removedEnumValues  map[string]map[string]string
+	danglingReferences []*DanglingReference
+}
+
+func newSanitizer() *sanitizer {
+	return &sanitizer{
+		strippedOptions:   make(map[protoreflect.FullName]struct{}),
+		removedFilePaths:  make(map[string]struct{}),
+		removedNames:      make(map[string]struct{}),
+		removedEnumValues: make(map[string]map[string]string),
+	}
+}
+
+func (s *sanitizer) removeFromFile(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+	packageName := fileDescriptorProto.GetPackage()
+	fileDescriptorProto.MessageType = s.removeFromMessages(packageName, fileDescriptorProto.MessageType)
+	fileDescriptorProto.EnumType = s.removeFromEnums(packageName, fileDescriptorProto.EnumType)
+	fileDescriptorProto.Extension = s.removeFromExtensions(packageName, fileDescriptorProto.Extension)
+	fileDescriptorProto.Service = slicesext.Filter(
+		fileDescriptorProto.Service,
+		func(serviceDescriptorProto *descriptorpb.ServiceDescriptorProto) bool {
+			if s.matches(serviceDescriptorProto.GetOptions()) {
+				return false
+			}
+			serviceDescriptorProto.Method = slicesext.Filter(
+				serviceDescriptorProto.Method,
+				func(methodDescriptorProto *descriptorpb.MethodDescriptorProto) bool {
+					return !s.matches(methodDescriptorProto.GetOptions())
+				},
+			)
+			return true
+		},
+	)
+}
+
+func (s *sanitizer) removeFromMessages(
+	prefix string,
+	descriptorProtos []*descriptorpb.DescriptorProto,
+) []*descriptorpb.DescriptorProto {
+	return slicesext.Filter(
+		descriptorProtos,
+		func(descriptorProto *descriptorpb.DescriptorProto) bool {
+			if s.matches(descriptorProto.GetOptions()) {
+				s.addRemovedMessage(prefix, descriptorProto)
+				return false
+			}
+			s.removeFromMessage(prefix, descriptorProto)
+			return true
+		},
+	)
+}
+
+func (s *sanitizer) removeFromMessage(prefix string, descriptorProto *descriptorpb.DescriptorProto) {
+	fullName := joinName(prefix, descriptorProto.GetName())
+	removedOneofIndexes := make(map[int32]struct{})
+	for i, oneofDescriptorProto := range descriptorProto.OneofDecl {
+		if s.matches(oneofDescriptorProto.GetOptions()) {
+			removedOneofIndexes[int32(i)] = struct{}{}
+		}
+	}
+	descriptorProto.Field = slicesext.Filter(
+		descriptorProto.Field,
+		func(fieldDescriptorProto *descriptorpb.FieldDescriptorProto) bool {
+			if fieldDescriptorProto.OneofIndex != nil {
+				if _, ok := removedOneofIndexes[fieldDescriptorProto.GetOneofIndex()]; ok {
+					return false
+				}
+			}
+			return !s.matches(fieldDescriptorProto.GetOptions())
+		},
+	)
+	descriptorProto.NestedType = s.removeFromMessages(fullName, descriptorProto.NestedType)
+	descriptorProto.EnumType = s.removeFromEnums(fullName, descriptorProto.EnumType)
+	descriptorProto.Extension = s.removeFromExtensions(fullName, descriptorProto.Extension)
+	// Map entries of removed map fields, and oneofs without fields, are invalid,
+	// so they are removed along with the fields.
+	fieldTypeNames := make(map[string]struct{})
+	oneofFieldCounts := make(map[int32]int)
+	for _, fieldDescriptorProto := range descriptorProto.Field {
+		fieldTypeNames[fieldDescriptorProto.GetTypeName()] = struct{}{}
+		if fieldDescriptorProto.OneofIndex != nil {
+			oneofFieldCounts[fieldDescriptorProto.GetOneofIndex()]++
+		}
+	}
+	descriptorProto.NestedType = slicesext.Filter(
+		descriptorProto.NestedType,
+		func(nestedDescriptorProto *descriptorpb.DescriptorProto) bool {
+			if !nestedDescriptorProto.GetOptions().GetMapEntry() {
+				return true
+			}
+			_, ok := fieldTypeNames["."+joinName(fullName, nestedDescriptorProto.GetName())]
+			return ok
+		},
+	)
+	var oneofDescriptorProtos []*descriptorpb.OneofDescriptorProto
+	oneofIndexes := make(map[int32]int32)
+	for i, oneofDescriptorProto := range descriptorProto.OneofDecl {
+		if oneofFieldCounts[int32(i)] == 0 {
+			continue
+		}
+		oneofIndexes[int32(i)] = int32(len(oneofDescriptorProtos))
+		oneofDescriptorProtos = append(oneofDescriptorProtos, oneofDescriptorProto)
+	}
+	descriptorProto.OneofDecl = oneofDescriptorProtos
+	for _, fieldDescriptorProto := range descriptorProto.Field {
+		if fieldDescriptorProto.OneofIndex != nil {
+			fieldDescriptorProto.OneofIndex = proto.Int32(oneofIndexes[fieldDescriptorProto.GetOneofIndex()])
+		}
+	}
+}
+
+func (s *sanitizer) removeFromEnums(
+	prefix string,
+	enumDescriptorProtos []*descriptorpb.EnumDescriptorProto,
+) []*descriptorpb.EnumDescriptorProto {
+	return slicesext.Filter(
+		enumDescriptorProtos,
+		func(enumDescriptorProto *descriptorpb.EnumDescriptorProto) bool {
+			fullName := joinName(prefix, enumDescriptorProto.GetName())
+			if s.matches(enumDescriptorProto.GetOptions()) {
+				s.removedNames[fullName] = struct{}{}
+				return false
+			}
+			enumDescriptorProto.Value = slicesext.Filter(
+				enumDescriptorProto.Value,
+				func(enumValueDescriptorProto *descriptorpb.EnumValueDescriptorProto) bool {
+					if !s.matches(enumValueDescriptorProto.GetOptions()) {
+						return true
+					}
+					removedValues, ok := s.removedEnumValues[fullName]
+					if !ok {
+						removedValues = make(map[string]string)
+						s.removedEnumValues[fullName] = removedValues
+					}
+					// Enum values are siblings of their enum.
+					removedValues[enumValueDescriptorProto.GetName()] = joinName(prefix, enumValueDescriptorProto.GetName())
+					return false
+				},
+			)
+			return true
+		},
+	)
+}
+
+func (s *sanitizer) removeFromExtensions(
+	prefix string,
+	fieldDescriptorProtos []*descriptorpb.FieldDescriptorProto,
+) []*descriptorpb.FieldDescriptorProto {
+	return slicesext.Filter(
+		fieldDescriptorProtos,
+		func(fieldDescriptorProto *descriptorpb.FieldDescriptorProto) bool {
+			if s.matches(fieldDescriptorProto.GetOptions()) {
+				s.addRemovedExtension(prefix, fieldDescriptorProto)
+				return false
+			}
+			return true
+		},
+	)
+}
+
+func (s *sanitizer) addRemovedFile(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+	packageName := fileDescriptorProto.GetPackage()
+	for _, descriptorProto := range fileDescriptorProto.MessageType {
+		s.addRemovedMessage(packageName, descriptorProto)
+	}
+	for _, enumDescriptorProto := range fileDescriptorProto.EnumType {
+		s.removedNames[joinName(packageName, enumDescriptorProto.GetName())] = struct{}{}
+	}
+	for _, fieldDescriptorProto := range fileDescriptorProto.Extension {
+		s.addRemovedExtension(packageName, fieldDescriptorProto)
+	}
+}
+
+func (s *sanitizer) addRemovedMessage(prefix string, descriptorProto *descriptorpb.DescriptorProto) {
+	fullName := joinName(prefix, descriptorProto.GetName())
+	s.removedNames[fullName] = struct{}{}
+	for _, nestedDescriptorProto := range descriptorProto.NestedType {
+		s.addRemovedMessage(fullName, nestedDescriptorProto)
+	}
+	for _, enumDescriptorProto := range descriptorProto.EnumType {
+		s.removedNames[joinName(fullName, enumDescriptorProto.GetName())] = struct{}{}
+	}
+	for _, fieldDescriptorProto := range descriptorProto.Extension {
+		s.addRemovedExtension(fullName, fieldDescriptorProto)
+	}
+}
+
+// addRemovedExtension adds the removed extension, which is also stripped
+// from all options, as an option can not be set without its declaration.
+func (s *sanitizer) addRemovedExtension(prefix string, fieldDescriptorProto *descriptorpb.FieldDescriptorProto) {
+	fullName := joinName(prefix, fieldDescriptorProto.GetName())
+	s.removedNames[fullName] = struct{}{}
+	s.strippedOptions[protoreflect.FullName(fullName)] = struct{}{}
+}
+
+func (s *sanitizer) checkFile(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+	filePath := fileDescriptorProto.GetName()
+	packageName := fileDescriptorProto.GetPackage()
+	for _, descriptorProto := range fileDescriptorProto.MessageType {
+		s.checkMessage(filePath, packageName, descriptorProto)
+	}
+	for _, fieldDescriptorProto := range fileDescriptorProto.Extension {
+		s.checkField(filePath, "extension "+joinName(packageName, fieldDescriptorProto.GetName()), fieldDescriptorProto)
+	}
+	for _, serviceDescriptorProto := range fileDescriptorProto.Service {
+		serviceName := joinName(packageName, serviceDescriptorProto.GetName())
+		for _, methodDescriptorProto := range serviceDescriptorProto.Method {
+			element := "method " + joinName(serviceName, methodDescriptorProto.GetName())
+			s.checkReference(filePath, element, methodDescriptorProto.GetInputType())
+			s.checkReference(filePath, element, methodDescriptorProto.GetOutputType())
+		}
+	}
+}
+
+func (s *sanitizer) checkMessage(filePath string, prefix string, descriptorProto *descriptorpb.DescriptorProto) {
+	fullName := joinName(prefix, descriptorProto.GetName())
+	for _, fieldDescriptorProto := range descriptorProto.Field {
+		s.checkField(filePath, "field "+joinName(fullName, fieldDescriptorProto.GetName()), fieldDescriptorProto)
+	}
+	for _, fieldDescriptorProto := range descriptorProto.Extension {
+		s.checkField(filePath, "extension "+joinName(fullName, fieldDescriptorProto.GetName()), fieldDescriptorProto)
+	}
+	for _, nestedDescriptorProto := range descriptorProto.NestedType {
+		s.checkMessage(filePath, fullName, nestedDescriptorProto)
+	}
+}
+
+func (s *sanitizer) checkField(filePath string, element string, fieldDescriptorProto *descriptorpb.FieldDescriptorProto) {
+	s.checkReference(filePath, element, fieldDescriptorProto.GetTypeName())
+	s.checkReference(filePath, element, fieldDescriptorProto.GetExtendee())
+	if fieldDescriptorProto.GetType() == descriptorpb.FieldDescriptorProto_TYPE_ENUM && fieldDescriptorProto.DefaultValue != nil {
+		removedValues := s.removedEnumValues[strings.TrimPrefix(fieldDescriptorProto.GetTypeName(), ".")]
+		if valueFullName, ok := removedValues[fieldDescriptorProto.GetDefaultValue()]; ok {
+			s.addDanglingReference(filePath, element, valueFullName)
+		}
+	}
+}
+
+func (s *sanitizer) checkReference(filePath string, element string, typeName string) {
+	fullName := strings.TrimPrefix(typeName, ".")
+	if _, ok := s.removedNames[fullName]; ok {
+		s.addDanglingReference(filePath, element, fullName)
+	}
+}
+
+func (s *sanitizer) addDanglingReference(filePath string, element string, reference string) {
+	s.danglingReferences = append(
+		s.danglingReferences,
+		&DanglingReference{
+			FilePath:  filePath,
+			Element:   element,
+			Reference: reference,
+		},
+	)
+}
+
+// matches returns true if the options match any matcher.
+func (s *sanitizer) matches(options proto.Message) bool {
+	message := options.ProtoReflect()
+	if !message.IsValid() {
+		return false
+	}
+	for _, matcher := range s.matchers {
+		if matcher.matches(message) {
+			return true
+		}
+	}
+	return false
+}
+
+// strip strips the stripped options, and returns false if no options remain.
+func (s *sanitizer) strip(options proto.Message) bool {
+	message := options.ProtoReflect()
+	if !message.IsValid() {
+		return true
+	}
+	var strippedFieldDescriptors []protoreflect.FieldDescriptor
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if _, ok := s.strippedOptions[fieldDescriptor.FullName()]; ok && fieldDescriptor.IsExtension() {
+				strippedFieldDescriptors = append(strippedFieldDescriptors, fieldDescriptor)
+			}
+			return true
+		},
+	)
+	for _, fieldDescriptor := range strippedFieldDescriptors {
+		message.Clear(fieldDescriptor)
+	}
+	return proto.Size(options) > 0
+}
+
+type matcher struct {
+	extensionDescriptor protoreflect.ExtensionTypeDescriptor
+	value               string
+}
+
+func (m *matcher) matches(message protoreflect.Message) bool {
+	if message.Descriptor().FullName() != m.extensionDescriptor.ContainingMessage().FullName() ||
+		!message.Has(m.extensionDescriptor) {
+		return false
+	}
+	value := message.Get(m.extensionDescriptor)
+	switch m.extensionDescriptor.Kind() {
+	case protoreflect.EnumKind:
+		enumValueDescriptor := m.extensionDescriptor.Enum().Values().ByNumber(value.Enum())
+		return enumValueDescriptor != nil && string(enumValueDescriptor.Name()) == m.value
+	case protoreflect.BytesKind:
+		return string(value.Bytes()) == m.value
+	default:
+		return value.String() == m.value
+	}
+}
+
+// removeUnusedImports removes the imports that are no longer used from the files,
+// and the imported files that are no longer imported.
+//
+// Public imports are always kept, as the files that import the file with the
+// public import may use them.
+func removeUnusedImports(
+	fileDescriptorProtos []*descriptorpb.FileDescriptorProto,
+	imageFiles []bufimage.ImageFile,
+) ([]*descriptorpb.FileDescriptorProto, []bufimage.ImageFile) {
+	nameToFilePath := make(map[string]string)
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		addNames(nameToFilePath, fileDescriptorProto)
+	}
+	filePathToPublicDependencies := make(map[string][]string)
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		for _, index := range fileDescriptorProto.PublicDependency {
+			filePathToPublicDependencies[fileDescriptorProto.GetName()] = append(
+				filePathToPublicDependencies[fileDescriptorProto.GetName()],
+				fileDescriptorProto.Dependency[index],
+			)
+		}
+	}
+	filePathToFileDescriptorProto := make(map[string]*descriptorpb.FileDescriptorProto, len(fileDescriptorProtos))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		filePathToFileDescriptorProto[fileDescriptorProto.GetName()] = fileDescriptorProto
+	}
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		usedFilePaths := getUsedFilePaths(fileDescriptorProto, nameToFilePath)
+		publicIndexes := slicesext.ToStructMap(fileDescriptorProto.PublicDependency)
+		weakIndexes := slicesext.ToStructMap(fileDescriptorProto.WeakDependency)
+		var dependencies []string
+		var publicDependencies []int32
+		var weakDependencies []int32
+		for i, dependency := range fileDescriptorProto.Dependency {
+			if _, ok := filePathToFileDescriptorProto[dependency]; !ok {
+				continue
+			}
+			_, isPublic := publicIndexes[int32(i)]
+			if !isPublic && !isUsed(dependency, usedFilePaths, filePathToPublicDependencies, make(map[string]struct{})) {
+				continue
+			}
+			if isPublic {
+				publicDependencies = append(publicDependencies, int32(len(dependencies)))
+			}
+			if _, isWeak := weakIndexes[int32(i)]; isWeak {
+				weakDependencies = append(weakDependencies, int32(len(dependencies)))
+			}
+			dependencies = append(dependencies, dependency)
+		}
+		fileDescriptorProto.Dependency = dependencies
+		fileDescriptorProto.PublicDependency = publicDependencies
+		fileDescriptorProto.WeakDependency = weakDependencies
+	}
+	importedFilePaths := make(map[string]struct{})
+	var addImportedFilePaths func(filePath string)
+	addImportedFilePaths = func(filePath string) {
+		for _, dependency := range filePathToFileDescriptorProto[filePath].Dependency {
+			if _, ok := importedFilePaths[dependency]; ok {
+				continue
+			}
+			importedFilePaths[dependency] = struct{}{}
+			addImportedFilePaths(dependency)
+		}
+	}
+	for i, imageFile := range imageFiles {
+		if !imageFile.IsImport() {
+			addImportedFilePaths(fileDescriptorProtos[i].GetName())
+		}
+	}
+	var keptFileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	var keptImageFiles []bufimage.ImageFile
+	for i, imageFile := range imageFiles {
+		if _, ok := importedFilePaths[imageFile.Path()]; ok || !imageFile.IsImport() {
+			keptFileDescriptorProtos = append(keptFileDescriptorProtos, fileDescriptorProtos[i])
+			keptImageFiles = append(keptImageFiles, imageFile)
+		}
+	}
+	return keptFileDescriptorProtos, keptImageFiles
+}
+
+// isUsed returns true if the file at the path, or a file it imports publicly, is used.
+func isUsed(
+	filePath string,
+	usedFilePaths map[string]struct{},
+	filePathToPublicDependencies map[string][]string,
+	seenFilePaths map[string]struct{},
+) bool {
+	if _, ok := usedFilePaths[filePath]; ok {
+		return true
+	}
+	if _, ok := seenFilePaths[filePath]; ok {
+		return false
+	}
+	seenFilePaths[filePath] = struct{}{}
+	for _, publicDependency := range filePathToPublicDependencies[filePath] {
+		if isUsed(publicDependency, usedFilePaths, filePathToPublicDependencies, seenFilePaths) {
+			return true
+		}
+	}
+	return false
+}
+
+// getUsedFilePaths returns the paths of the files that declare the types and the
+// custom options that the file uses.
+func getUsedFilePaths(
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	nameToFilePath map[string]string,
+) map[string]struct{} {
+	usedFilePaths := make(map[string]struct{})
+	addTypeName := func(typeName string) {
+		if filePath, ok := nameToFilePath[strings.TrimPrefix(typeName, ".")]; ok {
+			usedFilePaths[filePath] = struct{}{}
+		}
+	}
+	addField := func(fieldDescriptorProto *descriptorpb.FieldDescriptorProto) {
+		addTypeName(fieldDescriptorProto.GetTypeName())
+		addTypeName(fieldDescriptorProto.GetExtendee())
+	}
+	var addMessage func(descriptorProto *descriptorpb.DescriptorProto)
+	addMessage = func(descriptorProto *descriptorpb.DescriptorProto) {
+		for _, fieldDescriptorProto := range descriptorProto.Field {
+			addField(fieldDescriptorProto)
+		}
+		for _, fieldDescriptorProto := range descriptorProto.Extension {
+			addField(fieldDescriptorProto)
+		}
+		for _, nestedDescriptorProto := range descriptorProto.NestedType {
+			addMessage(nestedDescriptorProto)
+		}
+	}
+	for _, descriptorProto := range fileDescriptorProto.MessageType {
+		addMessage(descriptorProto)
+	}
+	for _, fieldDescriptorProto := range fileDescriptorProto.Extension {
+		addField(fieldDescriptorProto)
+	}
+	for _, serviceDescriptorProto := range fileDescriptorProto.Service {
+		for _, methodDescriptorProto := range serviceDescriptorProto.Method {
+			addTypeName(methodDescriptorProto.GetInputType())
+			addTypeName(methodDescriptorProto.GetOutputType())
+		}
+	}
+	rangeOptions(
+		fileDescriptorProto,
+		func(options proto.Message) bool {
+			addOptionFilePaths(usedFilePaths, options.ProtoReflect())
+			return true
+		},
+	)
+	return usedFilePaths
+}
+
+// addOptionFilePaths adds the paths of the files that declare the extensions
+// set in the message, including the extensions set in nested messages.
+func addOptionFilePaths(usedFilePaths map[string]struct{}, message protoreflect.Message) {
+	if !message.IsValid() {
+		return
+	}
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if fieldDescriptor.IsExtension() {
+				usedFilePaths[fieldDescriptor.ParentFile().Path()] = struct{}{}
+			}
+			switch {
+			case fieldDescriptor.IsMap():
+				if fieldDescriptor.MapValue().Message() != nil {
+					value.Map().Range(
+						func(_ protoreflect.MapKey, mapValue protoreflect.Value) bool {
+							addOptionFilePaths(usedFilePaths, mapValue.Message())
+							return true
+						},
+					)
+				}
+			case fieldDescriptor.Message() == nil:
+			case fieldDescriptor.IsList():
+				list := value.List()
+				for i := 0; i < list.Len(); i++ {
+					addOptionFilePaths(usedFilePaths, list.Get(i).Message())
+				}
+			default:
+				addOptionFilePaths(usedFilePaths, value.Message())
+			}
+			return true
+		},
+	)
+}
+
+func addNames(nameToFilePath map[string]string, fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+	filePath := fileDescriptorProto.GetName()
+	var addMessage func(prefix string, descriptorProto *descriptorpb.DescriptorProto)
+	addMessage = func(prefix string, descriptorProto *descriptorpb.DescriptorProto) {
+		fullName := joinName(prefix, descriptorProto.GetName())
+		nameToFilePath[fullName] = filePath
+		for _, nestedDescriptorProto := range descriptorProto.NestedType {
+			addMessage(fullName, nestedDescriptorProto)
+		}
+		for _, enumDescriptorProto := range descriptorProto.EnumType {
+			nameToFilePath[joinName(fullName, enumDescriptorProto.GetName())] = filePath
+		}
+	}
+	packageName := fileDescriptorProto.GetPackage()
+	for _, descriptorProto := range fileDescriptorProto.MessageType {
+		addMessage(packageName, descriptorProto)
+	}
+	for _, enumDescriptorProto := range fileDescriptorProto.EnumType {
+		nameToFilePath[joinName(packageName, enumDescriptorProto.GetName())] = filePath
+	}
+}
+
+// rangeOptions calls f with the options of the file and of all of its declarations,
+// and unsets the options for which f returns false.
+func rangeOptions(fileDescriptorProto *descriptorpb.FileDescriptorProto, f func(proto.Message) bool) {
+	if !f(fileDescriptorProto.GetOptions()) {
+		fileDescriptorProto.Options = nil
+	}
+	for _, descriptorProto := range fileDescriptorProto.MessageType {
+		rangeMessageOptions(descriptorProto, f)
+	}
+	for _, enumDescriptorProto := range fileDescriptorProto.EnumType {
+		rangeEnumOptions(enumDescriptorProto, f)
+	}
+	for _, fieldDescriptorProto := range fileDescriptorProto.Extension {
+		if !f(fieldDescriptorProto.GetOptions()) {
+			fieldDescriptorProto.Options = nil
+		}
+	}
+	for _, serviceDescriptorProto := range fileDescriptorProto.Service {
+		if !f(serviceDescriptorProto.GetOptions()) {
+			serviceDescriptorProto.Options = nil
+		}
+		for _, methodDescriptorProto := range serviceDescriptorProto.Method {
+			if !f(methodDescriptorProto.GetOptions()) {
+				methodDescriptorProto.Options = nil
+			}
+		}
+	}
+}
+
+func rangeMessageOptions(descriptorProto *descriptorpb.DescriptorProto, f func(proto.Message) bool) {
+	if !f(descriptorProto.GetOptions()) {
+		descriptorProto.Options = nil
+	}
+	for _, fieldDescriptorProto := range descriptorProto.Field {
+		if !f(fieldDescriptorProto.GetOptions()) {
+			fieldDescriptorProto.Options = nil
+		}
+	}
+	for _, fieldDescriptorProto := range descriptorProto.Extension {
+		if !f(fieldDescriptorProto.GetOptions()) {
+			fieldDescriptorProto.Options = nil
+		}
+	}
+	for _, oneofDescriptorProto := range descriptorProto.OneofDecl {
+		if !f(oneofDescriptorProto.GetOptions()) {
+			oneofDescriptorProto.Options = nil
+		}
+	}
+	for _, extensionRange := range descriptorProto.ExtensionRange {
+		if !f(extensionRange.GetOptions()) {
+			extensionRange.Options = nil
+		}
+	}
+	for _, nestedDescriptorProto := range descriptorProto.NestedType {
+		rangeMessageOptions(nestedDescriptorProto, f)
+	}
+	for _, enumDescriptorProto := range descriptorProto.EnumType {
+		rangeEnumOptions(enumDescriptorProto, f)
+	}
+}
+
+func rangeEnumOptions(enumDescriptorProto *descriptorpb.EnumDescriptorProto, f func(proto.Message) bool) {
+	if !f(enumDescriptorProto.GetOptions()) {
+		enumDescriptorProto.Options = nil
+	}
+	for _, enumValueDescriptorProto := range enumDescriptorProto.Value {
+		if !f(enumValueDescriptorProto.GetOptions()) {
+			enumValueDescriptorProto.Options = nil
+		}
+	}
+}
+
+func joinName(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
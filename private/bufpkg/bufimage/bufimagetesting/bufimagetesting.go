@@ -15,14 +15,19 @@
 package bufimagetesting
 
 import (
+	"context"
 	"testing"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	imagev1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/image/v1"
 	"github.com/bufbuild/buf/private/pkg/protodescriptor"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -115,3 +120,33 @@ func normalizeImageFiles(t testing.TB, imageFiles []bufimage.ImageFile) []bufima
 	}
 	return normalizedImageFiles
 }
+
+// BuildImage returns a new Image built from a module with the files at the
+// paths with the given contents for testing.
+//
+// The module has no dependencies, so all imports must be in the module, and the
+// build must not have any FileAnnotations.
+func BuildImage(
+	t testing.TB,
+	pathToContent map[string]string,
+) bufimage.Image {
+	ctx := context.Background()
+	pathToData := make(map[string][]byte, len(pathToContent))
+	for path, content := range pathToContent {
+		pathToData[path] = []byte(content)
+	}
+	readBucket, err := storagemem.NewReadBucket(pathToData)
+	require.NoError(t, err)
+	module, err := bufmodule.NewModuleForBucket(ctx, readBucket)
+	require.NoError(t, err)
+	image, fileAnnotations, err := bufimagebuild.NewBuilder(
+		zap.NewNop(),
+		bufmodule.NewNopModuleReader(),
+	).Build(
+		ctx,
+		module,
+	)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	return image
+}
@@ -700,6 +700,64 @@ func TestBasic(t *testing.T) {
 	}
 }
 
+func TestImageByPackage(t *testing.T) {
+	t.Parallel()
+
+	protoImageFileImport := NewProtoImageFileIsImport(
+		t,
+		"import.proto",
+	)
+	protoImageFileAA := NewProtoImageFile(
+		t,
+		"a/a.proto",
+		"import.proto",
+	)
+	protoImageFileAA.Package = proto.String("acme.a.v1")
+	protoImageFileAC := NewProtoImageFile(
+		t,
+		"a/c.proto",
+		"a/a.proto",
+	)
+	protoImageFileAC.Package = proto.String("acme.c.v1")
+	protoImageFileBA := NewProtoImageFile(
+		t,
+		"b/a.proto",
+		"a/a.proto",
+	)
+	protoImageFileBA.Package = proto.String("acme.a.v1")
+
+	image, err := bufimage.NewImage(
+		[]bufimage.ImageFile{
+			NewImageFile(t, protoImageFileImport, nil, "", "import.proto", true, false, nil),
+			NewImageFile(t, protoImageFileAA, nil, "", "a/a.proto", false, false, nil),
+			NewImageFile(t, protoImageFileAC, nil, "", "a/c.proto", false, false, nil),
+			NewImageFile(t, protoImageFileBA, nil, "", "b/a.proto", false, false, nil),
+		},
+	)
+	require.NoError(t, err)
+	imagesByPackage, err := bufimage.ImageByPackage(image)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(imagesByPackage))
+	AssertImageFilesEqual(
+		t,
+		[]bufimage.ImageFile{
+			NewImageFile(t, protoImageFileImport, nil, "", "import.proto", true, false, nil),
+			NewImageFile(t, protoImageFileAA, nil, "", "a/a.proto", false, false, nil),
+			NewImageFile(t, protoImageFileBA, nil, "", "b/a.proto", false, false, nil),
+		},
+		imagesByPackage[0].Files(),
+	)
+	AssertImageFilesEqual(
+		t,
+		[]bufimage.ImageFile{
+			NewImageFile(t, protoImageFileImport, nil, "", "import.proto", true, false, nil),
+			NewImageFile(t, protoImageFileAA, nil, "", "a/a.proto", true, false, nil),
+			NewImageFile(t, protoImageFileAC, nil, "", "a/c.proto", false, false, nil),
+		},
+		imagesByPackage[1].Files(),
+	)
+}
+
 func testProtoImageFileToFileDescriptorProto(imageFile *imagev1.ImageFile) *descriptorpb.FileDescriptorProto {
 	return &descriptorpb.FileDescriptorProto{
 		Name:       imageFile.Name,
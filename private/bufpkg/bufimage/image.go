@@ -120,7 +120,7 @@ func orderImageFilesRec(
 		return outputImageFiles
 	}
 	alreadySeen[path] = struct{}{}
-	for _, dependency := range inputImageFile.FileDescriptorProto().GetDependency() {
+	for _, dependency := range getImageFileDependencies(inputImageFile) {
 		dependencyImageFile, ok := pathToImageFile[dependency]
 		if ok {
 			outputImageFiles = orderImageFilesRec(
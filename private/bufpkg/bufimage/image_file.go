@@ -16,6 +16,7 @@ package bufimage
 
 import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	imagev1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/image/v1"
 	"github.com/bufbuild/buf/private/pkg/protodescriptor"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
@@ -25,7 +26,10 @@ var _ ImageFile = &imageFile{}
 type imageFile struct {
 	bufmoduleref.FileInfo
 
-	fileDescriptorProto     *descriptorpb.FileDescriptorProto
+	fileDescriptorProto *descriptorpb.FileDescriptorProto
+	// lazyFileDescriptorProto is set instead of fileDescriptorProto for files
+	// created by NewImageForBinary.
+	lazyFileDescriptorProto *lazyFileDescriptorProto
 	isImport                bool
 	isSyntaxUnspecified     bool
 	unusedDependencyIndexes []int32
@@ -84,9 +88,19 @@ func newImageFileNoValidate(
 }
 
 func (f *imageFile) FileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	if f.lazyFileDescriptorProto != nil {
+		return f.lazyFileDescriptorProto.fileDescriptorProto()
+	}
 	return f.fileDescriptorProto
 }
 
+func (f *imageFile) SourceCodeInfo() *descriptorpb.SourceCodeInfo {
+	if f.lazyFileDescriptorProto != nil {
+		return f.lazyFileDescriptorProto.getSourceCodeInfo()
+	}
+	return f.fileDescriptorProto.GetSourceCodeInfo()
+}
+
 func (f *imageFile) IsImport() bool {
 	return f.isImport
 }
@@ -100,3 +114,68 @@ func (f *imageFile) UnusedDependencyIndexes() []int32 {
 }
 
 func (*imageFile) isImageFile() {}
+
+// lazyImageFileWithIsImport returns a copy of the ImageFile with isImport set
+// if the ImageFile is lazily decoded, without decoding it.
+func lazyImageFileWithIsImport(file ImageFile, isImport bool) (ImageFile, bool) {
+	lazyImageFile, ok := file.(*imageFile)
+	if !ok || lazyImageFile.lazyFileDescriptorProto == nil {
+		return nil, false
+	}
+	return &imageFile{
+		FileInfo:                lazyImageFile.FileInfo,
+		lazyFileDescriptorProto: lazyImageFile.lazyFileDescriptorProto,
+		isImport:                isImport,
+		isSyntaxUnspecified:     lazyImageFile.isSyntaxUnspecified,
+		unusedDependencyIndexes: lazyImageFile.unusedDependencyIndexes,
+	}, true
+}
+
+// getImageFileDependencies returns the dependencies of the ImageFile.
+//
+// This does not decode a lazily decoded file.
+func getImageFileDependencies(file ImageFile) []string {
+	if lazyImageFile, ok := file.(*imageFile); ok && lazyImageFile.lazyFileDescriptorProto != nil {
+		return lazyImageFile.lazyFileDescriptorProto.dependencies
+	}
+	return file.FileDescriptorProto().GetDependency()
+}
+
+// newImageFileForProtoImageFile returns a new imageFile for the proto ImageFile,
+// reading the values of the buf extension.
+func newImageFileForProtoImageFile(protoImageFile *imagev1.ImageFile) (*imageFile, error) {
+	var isImport bool
+	var isSyntaxUnspecified bool
+	var unusedDependencyIndexes []int32
+	var moduleIdentity bufmoduleref.ModuleIdentity
+	var commit string
+	var err error
+	if protoImageFileExtension := protoImageFile.GetBufExtension(); protoImageFileExtension != nil {
+		isImport = protoImageFileExtension.GetIsImport()
+		isSyntaxUnspecified = protoImageFileExtension.GetIsSyntaxUnspecified()
+		unusedDependencyIndexes = protoImageFileExtension.GetUnusedDependency()
+		if protoModuleInfo := protoImageFileExtension.GetModuleInfo(); protoModuleInfo != nil {
+			if protoModuleName := protoModuleInfo.GetName(); protoModuleName != nil {
+				moduleIdentity, err = bufmoduleref.NewModuleIdentity(
+					protoModuleName.GetRemote(),
+					protoModuleName.GetOwner(),
+					protoModuleName.GetRepository(),
+				)
+				if err != nil {
+					return nil, err
+				}
+				// we only want to set this if there is a module name
+				commit = protoModuleInfo.GetCommit()
+			}
+		}
+	}
+	return newImageFile(
+		protoImageFile,
+		moduleIdentity,
+		commit,
+		protoImageFile.GetName(),
+		isImport,
+		isSyntaxUnspecified,
+		unusedDependencyIndexes,
+	)
+}
@@ -15,12 +15,17 @@
 package bufimage
 
 import (
+	"fmt"
 	"testing"
 
 	imagev1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/image/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func TestMergeImagesWithImports(t *testing.T) {
@@ -137,3 +142,218 @@ func TestMergeImagesOrdered(t *testing.T) {
 	}
 	assert.Equal(t, []string{"a.proto", "b.proto", "c.proto", "d.proto"}, paths)
 }
+
+func TestNewImageForBinary(t *testing.T) {
+	t.Parallel()
+	descriptorFileDescriptorProto := protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto)
+	fileOptions := &descriptorpb.FileOptions{}
+	// option (b.value) = "a";
+	fileOptions.ProtoReflect().SetUnknown(protowire.AppendString(protowire.AppendTag(nil, 50000, protowire.BytesType), "a"))
+	protoImage := &imagev1.Image{
+		File: []*imagev1.ImageFile{
+			protoImageFileForFileDescriptorProto(t, descriptorFileDescriptorProto, true),
+			{
+				Syntax:     proto.String("proto3"),
+				Name:       proto.String("b.proto"),
+				Package:    proto.String("b"),
+				Dependency: []string{"google/protobuf/descriptor.proto"},
+				Extension: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("value"),
+						Number:   proto.Int32(50000),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Extendee: proto.String(".google.protobuf.FileOptions"),
+						JsonName: proto.String("value"),
+					},
+				},
+				SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+					Location: []*descriptorpb.SourceCodeInfo_Location{{Span: []int32{0, 0, 1}}},
+				},
+				BufExtension: &imagev1.ImageFileExtension{
+					IsImport: proto.Bool(true),
+				},
+			},
+			{
+				Syntax:     proto.String("proto3"),
+				Name:       proto.String("a.proto"),
+				Dependency: []string{"b.proto"},
+				Options:    fileOptions,
+				SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+					Location: []*descriptorpb.SourceCodeInfo_Location{{Span: []int32{0, 0, 1}}},
+				},
+			},
+			{
+				Syntax: proto.String("proto3"),
+				Name:   proto.String("c.proto"),
+			},
+		},
+	}
+	data, err := proto.Marshal(protoImage)
+	require.NoError(t, err)
+
+	image, err := NewImageForBinary(data)
+	require.NoError(t, err)
+	require.Len(t, image.Files(), 4)
+	image, err = ImageWithOnlyPaths(image, []string{"a.proto"}, nil)
+	require.NoError(t, err)
+	imageFiles := image.Files()
+	require.Len(t, imageFiles, 3)
+	assert.Equal(t, "google/protobuf/descriptor.proto", imageFiles[0].Path())
+	assert.Equal(t, "b.proto", imageFiles[1].Path())
+	assert.True(t, imageFiles[1].IsImport())
+	assert.Equal(t, "a.proto", imageFiles[2].Path())
+	assert.False(t, imageFiles[2].IsImport())
+	// Nothing is decoded until it is accessed.
+	for _, file := range imageFiles {
+		assert.Nil(t, file.(*imageFile).lazyFileDescriptorProto.value)
+	}
+	fileDescriptorProto := imageFiles[2].FileDescriptorProto()
+	assert.Equal(t, []string{"b.proto"}, fileDescriptorProto.GetDependency())
+	assert.NotNil(t, fileDescriptorProto.GetSourceCodeInfo())
+	assert.Empty(t, fileDescriptorProto.GetOptions().ProtoReflect().GetUnknown())
+	var numExtensions int
+	fileDescriptorProto.GetOptions().ProtoReflect().Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			assert.Equal(t, protoreflect.FullName("b.value"), fieldDescriptor.FullName())
+			assert.Equal(t, "a", value.String())
+			numExtensions++
+			return true
+		},
+	)
+	assert.Equal(t, 1, numExtensions)
+	assert.Same(t, fileDescriptorProto, imageFiles[2].FileDescriptorProto())
+	// The dependencies were decoded once to resolve the option, and the decoded
+	// dependencies are returned when they are accessed.
+	for _, file := range imageFiles {
+		lazyFileDescriptorProto := file.(*imageFile).lazyFileDescriptorProto
+		assert.NotNil(t, lazyFileDescriptorProto.value)
+		assert.Nil(t, lazyFileDescriptorProto.data)
+	}
+	dependencyFileDescriptorProto := imageFiles[1].(*imageFile).lazyFileDescriptorProto.value
+	assert.Same(t, dependencyFileDescriptorProto, imageFiles[1].FileDescriptorProto())
+	assert.NotNil(t, imageFiles[1].FileDescriptorProto().GetSourceCodeInfo())
+	// The buf extension is not part of the FileDescriptorProto.
+	assert.Empty(t, imageFiles[1].FileDescriptorProto().ProtoReflect().GetUnknown())
+
+	image, err = NewImageForBinary(data, WithExcludeSourceCodeInfo())
+	require.NoError(t, err)
+	assert.Nil(t, image.GetFile("a.proto").FileDescriptorProto().GetSourceCodeInfo())
+	assert.Equal(t, "b", image.GetFile("b.proto").FileDescriptorProto().GetPackage())
+
+	image, err = NewImageForBinary(data, WithLazySourceCodeInfo())
+	require.NoError(t, err)
+	aImageFile := image.GetFile("a.proto")
+	assert.Nil(t, aImageFile.FileDescriptorProto().GetSourceCodeInfo())
+	// The source code info is only decoded when it is accessed.
+	assert.Nil(t, aImageFile.(*imageFile).lazyFileDescriptorProto.sourceCodeInfo)
+	assert.NotEmpty(t, aImageFile.(*imageFile).lazyFileDescriptorProto.sourceCodeInfoData)
+	sourceCodeInfo := aImageFile.SourceCodeInfo()
+	require.NotNil(t, sourceCodeInfo)
+	assert.Nil(t, aImageFile.(*imageFile).lazyFileDescriptorProto.sourceCodeInfoData)
+	assert.Len(t, sourceCodeInfo.GetLocation(), 1)
+	assert.Same(t, sourceCodeInfo, aImageFile.SourceCodeInfo())
+	// The source code info is included when the image is converted to protos.
+	assert.True(t, proto.Equal(protoImage.File[2].GetSourceCodeInfo(), ImageToProtoImage(image).File[2].GetSourceCodeInfo()))
+	fileDescriptorSet := ImageToFileDescriptorSet(image)
+	assert.True(t, proto.Equal(protoImage.File[2].GetSourceCodeInfo(), fileDescriptorSet.File[2].GetSourceCodeInfo()))
+	assert.Equal(t, "a.proto", fileDescriptorSet.File[2].GetName())
+	assert.Nil(t, aImageFile.FileDescriptorProto().GetSourceCodeInfo())
+
+	// A FileDescriptorSet has the same encoding.
+	data, err = proto.Marshal(
+		&descriptorpb.FileDescriptorSet{
+			File: []*descriptorpb.FileDescriptorProto{descriptorFileDescriptorProto},
+		},
+	)
+	require.NoError(t, err)
+	image, err = NewImageForBinary(data)
+	require.NoError(t, err)
+	assert.True(
+		t,
+		proto.Equal(
+			descriptorFileDescriptorProto,
+			image.GetFile("google/protobuf/descriptor.proto").FileDescriptorProto(),
+		),
+	)
+}
+
+func BenchmarkNewImageForBinary(b *testing.B) {
+	descriptorFileDescriptorProto := protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto)
+	protoImage := &imagev1.Image{
+		File: []*imagev1.ImageFile{
+			protoImageFileForFileDescriptorProto(b, descriptorFileDescriptorProto, true),
+		},
+	}
+	// A chain of files that each depend on the previous file, so that decoding a
+	// file decodes all of the files before it to resolve its custom options.
+	dependency := descriptorFileDescriptorProto.GetName()
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("file%d.proto", i)
+		protoImage.File = append(
+			protoImage.File,
+			&imagev1.ImageFile{
+				Syntax:     proto.String("proto3"),
+				Name:       proto.String(name),
+				Dependency: []string{dependency},
+				SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+					Location: []*descriptorpb.SourceCodeInfo_Location{{Span: []int32{0, 0, 1}}},
+				},
+			},
+		)
+		dependency = name
+	}
+	data, err := proto.Marshal(protoImage)
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		image, err := NewImageForBinary(data)
+		require.NoError(b, err)
+		// Access the files from the last file, which decodes all of them.
+		imageFiles := image.Files()
+		for j := len(imageFiles) - 1; j >= 0; j-- {
+			_ = imageFiles[j].FileDescriptorProto()
+		}
+	}
+}
+
+func TestNewImageForBinaryErrors(t *testing.T) {
+	t.Parallel()
+	_, err := NewImageForBinary(nil)
+	assert.EqualError(t, err, "image contains no files")
+	_, err = NewImageForBinary([]byte{0x0a, 0x05, 0x0a})
+	assert.Error(t, err)
+	data, err := proto.Marshal(
+		&imagev1.Image{
+			File: []*imagev1.ImageFile{
+				{
+					Name:       proto.String("a.proto"),
+					Dependency: []string{"b.proto"},
+				},
+				{
+					Name:       proto.String("b.proto"),
+					Dependency: []string{"a.proto"},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	_, err = NewImageForBinary(data)
+	assert.ErrorContains(t, err, "import cycle in image at file")
+}
+
+func protoImageFileForFileDescriptorProto(
+	t testing.TB,
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	isImport bool,
+) *imagev1.ImageFile {
+	data, err := proto.Marshal(fileDescriptorProto)
+	require.NoError(t, err)
+	protoImageFile := &imagev1.ImageFile{}
+	require.NoError(t, proto.Unmarshal(data, protoImageFile))
+	protoImageFile.BufExtension = &imagev1.ImageFileExtension{
+		IsImport: proto.Bool(isImport),
+	}
+	return protoImageFile
+}
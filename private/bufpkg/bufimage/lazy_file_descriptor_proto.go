@@ -0,0 +1,279 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimage
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	imagev1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/image/v1"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// The field number of Image.file and FileDescriptorSet.file.
+	imageFileFieldNumber = 1
+	// The field number of FileDescriptorProto.source_code_info.
+	sourceCodeInfoFieldNumber = 9
+)
+
+// lazyFileDescriptorProto is a FileDescriptorProto that is decoded from its
+// binary encoding when it is first accessed.
+//
+// The file is decoded once, both when it is accessed and when a dependent is
+// decoded and resolves its custom options against it. The binary encoding is
+// released once it has been decoded.
+type lazyFileDescriptorProto struct {
+	dependencies          []string
+	excludeSourceCodeInfo bool
+	lazySourceCodeInfo    bool
+	// pathToLazyFileDescriptorProto contains all files of the image, and is used
+	// to resolve the custom options of the file against its dependencies.
+	pathToLazyFileDescriptorProto map[string]*lazyFileDescriptorProto
+
+	once sync.Once
+	// data is only read in once, and is set to nil after.
+	data []byte
+	// value is the FileDescriptorProto returned by fileDescriptorProto.
+	value *descriptorpb.FileDescriptorProto
+	// sourceCodeInfoData is the binary encoding of the source code info, which is
+	// kept by once if lazySourceCodeInfo is set, and set to nil by
+	// sourceCodeInfoOnce.
+	sourceCodeInfoData []byte
+	sourceCodeInfoOnce sync.Once
+	sourceCodeInfo     *descriptorpb.SourceCodeInfo
+}
+
+// newLazyImageFiles splits the binary encoding of an Image or FileDescriptorSet
+// into ImageFiles that are decoded when they are accessed.
+//
+// Each file is unmarshalled once here to validate it, one at a time, so that only one
+// decoded file is held in memory at once.
+func newLazyImageFiles(data []byte, excludeSourceCodeInfo bool, lazySourceCodeInfo bool) ([]ImageFile, error) {
+	pathToLazyFileDescriptorProto := make(map[string]*lazyFileDescriptorProto)
+	var imageFiles []ImageFile
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, fmt.Errorf("could not unmarshal image: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+		if number != imageFileFieldNumber || wireType != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+			if n < 0 {
+				return nil, fmt.Errorf("could not unmarshal image: %w", protowire.ParseError(n))
+			}
+			data = data[n:]
+			continue
+		}
+		fileData, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, fmt.Errorf("could not unmarshal image: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+		protoImageFile := &imagev1.ImageFile{}
+		if err := proto.Unmarshal(fileData, protoImageFile); err != nil {
+			return nil, fmt.Errorf("could not unmarshal image: %w", err)
+		}
+		if err := validateProtoImageFile(protoImageFile); err != nil {
+			return nil, err
+		}
+		imageFile, err := newImageFileForProtoImageFile(protoImageFile)
+		if err != nil {
+			return nil, err
+		}
+		path := imageFile.Path()
+		if _, ok := pathToLazyFileDescriptorProto[path]; ok {
+			return nil, fmt.Errorf("duplicate file path: %s", path)
+		}
+		lazyFileDescriptorProto := &lazyFileDescriptorProto{
+			data:                          fileData,
+			dependencies:                  protoImageFile.GetDependency(),
+			excludeSourceCodeInfo:         excludeSourceCodeInfo,
+			lazySourceCodeInfo:            lazySourceCodeInfo,
+			pathToLazyFileDescriptorProto: pathToLazyFileDescriptorProto,
+		}
+		pathToLazyFileDescriptorProto[path] = lazyFileDescriptorProto
+		// Drop the decoded file, it is decoded again when it is accessed.
+		imageFile.fileDescriptorProto = nil
+		imageFile.lazyFileDescriptorProto = lazyFileDescriptorProto
+		imageFiles = append(imageFiles, imageFile)
+	}
+	if len(imageFiles) == 0 {
+		return nil, errors.New("image contains no files")
+	}
+	// Decoding a file decodes its dependencies, which would never finish for an
+	// import cycle.
+	if err := checkNoImportCycles(pathToLazyFileDescriptorProto); err != nil {
+		return nil, err
+	}
+	return imageFiles, nil
+}
+
+func (l *lazyFileDescriptorProto) fileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	l.once.Do(func() {
+		l.value = l.decode(!l.excludeSourceCodeInfo && !l.lazySourceCodeInfo)
+		if l.lazySourceCodeInfo {
+			l.sourceCodeInfoData = filterFields(
+				l.data,
+				func(number protowire.Number) bool {
+					return number == sourceCodeInfoFieldNumber
+				},
+			)
+		}
+		l.data = nil
+	})
+	return l.value
+}
+
+func (l *lazyFileDescriptorProto) getSourceCodeInfo() *descriptorpb.SourceCodeInfo {
+	if l.excludeSourceCodeInfo {
+		return nil
+	}
+	if !l.lazySourceCodeInfo {
+		return l.fileDescriptorProto().GetSourceCodeInfo()
+	}
+	l.sourceCodeInfoOnce.Do(func() {
+		// The source code info is split from the data when the file is decoded.
+		_ = l.fileDescriptorProto()
+		// Only the source code info is decoded, which does not have custom options.
+		fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+		_ = proto.Unmarshal(l.sourceCodeInfoData, fileDescriptorProto)
+		l.sourceCodeInfo = fileDescriptorProto.GetSourceCodeInfo()
+		l.sourceCodeInfoData = nil
+	})
+	return l.sourceCodeInfo
+}
+
+// decode decodes the FileDescriptorProto and reparses its custom options.
+//
+// The data was already successfully unmarshalled in newLazyImageFiles, so this does
+// not fail. If the custom options cannot be reparsed, they are left as unrecognized
+// fields.
+func (l *lazyFileDescriptorProto) decode(withSourceCodeInfo bool) *descriptorpb.FileDescriptorProto {
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+	_ = proto.Unmarshal(
+		filterFields(
+			l.data,
+			func(number protowire.Number) bool {
+				return number != bufExtensionFieldNumber &&
+					(withSourceCodeInfo || number != sourceCodeInfoFieldNumber)
+			},
+		),
+		fileDescriptorProto,
+	)
+	fileDescriptorProtos := []*descriptorpb.FileDescriptorProto{fileDescriptorProto}
+	seenPaths := map[string]struct{}{fileDescriptorProto.GetName(): {}}
+	fileDescriptorProtos = l.appendDependencyDescriptors(fileDescriptorProtos, seenPaths)
+	_ = protoencoding.ReparseUnrecognized(
+		protoencoding.NewLazyResolver(fileDescriptorProtos...),
+		fileDescriptorProto.ProtoReflect(),
+	)
+	return fileDescriptorProto
+}
+
+// appendDependencyDescriptors appends the descriptors of the transitive dependencies
+// of the file that are in the image.
+//
+// The dependencies are decoded as if they were accessed, so that each file is only
+// decoded once.
+func (l *lazyFileDescriptorProto) appendDependencyDescriptors(
+	fileDescriptorProtos []*descriptorpb.FileDescriptorProto,
+	seenPaths map[string]struct{},
+) []*descriptorpb.FileDescriptorProto {
+	for _, dependency := range l.dependencies {
+		if _, ok := seenPaths[dependency]; ok {
+			continue
+		}
+		seenPaths[dependency] = struct{}{}
+		dependencyLazyFileDescriptorProto, ok := l.pathToLazyFileDescriptorProto[dependency]
+		if !ok {
+			continue
+		}
+		fileDescriptorProtos = append(fileDescriptorProtos, dependencyLazyFileDescriptorProto.fileDescriptorProto())
+		fileDescriptorProtos = dependencyLazyFileDescriptorProto.appendDependencyDescriptors(fileDescriptorProtos, seenPaths)
+	}
+	return fileDescriptorProtos
+}
+
+func checkNoImportCycles(pathToLazyFileDescriptorProto map[string]*lazyFileDescriptorProto) error {
+	// A path is mapped to false while its dependencies are being visited, and to true
+	// once they have been visited.
+	pathToVisited := make(map[string]bool, len(pathToLazyFileDescriptorProto))
+	var visit func(path string) error
+	visit = func(path string) error {
+		visited, ok := pathToVisited[path]
+		if ok {
+			if !visited {
+				return fmt.Errorf("import cycle in image at file %s", path)
+			}
+			return nil
+		}
+		lazyFileDescriptorProto, ok := pathToLazyFileDescriptorProto[path]
+		if !ok {
+			return nil
+		}
+		pathToVisited[path] = false
+		for _, dependency := range lazyFileDescriptorProto.dependencies {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		pathToVisited[path] = true
+		return nil
+	}
+	for path := range pathToLazyFileDescriptorProto {
+		if err := visit(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterFields returns the binary encoding of a message with only the fields
+// for which keep returns true.
+//
+// The data is not copied if all fields are kept.
+func filterFields(data []byte, keep func(protowire.Number) bool) []byte {
+	var result []byte
+	remaining := data
+	for len(remaining) > 0 {
+		number, wireType, n := protowire.ConsumeTag(remaining)
+		if n < 0 {
+			return data
+		}
+		m := protowire.ConsumeFieldValue(number, wireType, remaining[n:])
+		if m < 0 {
+			return data
+		}
+		field := remaining[:n+m]
+		if !keep(number) {
+			if result == nil {
+				result = append(make([]byte, 0, len(data)), data[:len(data)-len(remaining)]...)
+			}
+		} else if result != nil {
+			result = append(result, field...)
+		}
+		remaining = remaining[n+m:]
+	}
+	if result == nil {
+		return data
+	}
+	return result
+}
@@ -252,7 +252,7 @@ func addFileWithImports(
 	seenPaths[path] = struct{}{}
 
 	// then, add imports first, for proper ordering
-	for _, importPath := range imageFile.FileDescriptorProto().GetDependency() {
+	for _, importPath := range getImageFileDependencies(imageFile) {
 		if importFile := image.GetFile(importPath); importFile != nil {
 			accumulator = addFileWithImports(
 				accumulator,
@@ -294,13 +294,42 @@ func checkExcludePathsExistInImage(image Image, excludeFileOrDirPaths []string)
 func imageFilesToFileDescriptorProtos(imageFiles []ImageFile) []*descriptorpb.FileDescriptorProto {
 	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(imageFiles))
 	for i, imageFile := range imageFiles {
-		fileDescriptorProtos[i] = imageFile.FileDescriptorProto()
+		fileDescriptorProtos[i] = imageFileToFileDescriptorProto(imageFile)
 	}
 	return fileDescriptorProtos
 }
 
+// imageFileToFileDescriptorProto returns the FileDescriptorProto of the ImageFile
+// with its source code info, which is not part of the FileDescriptorProto of files
+// of Images created with WithLazySourceCodeInfo.
+func imageFileToFileDescriptorProto(imageFile ImageFile) *descriptorpb.FileDescriptorProto {
+	fileDescriptorProto := imageFile.FileDescriptorProto()
+	sourceCodeInfo := imageFile.SourceCodeInfo()
+	if sourceCodeInfo == nil || fileDescriptorProto.SourceCodeInfo == sourceCodeInfo {
+		return fileDescriptorProto
+	}
+	// This is a shallow copy, the FileDescriptorProto of the ImageFile is not modified.
+	resultFileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:             fileDescriptorProto.Name,
+		Package:          fileDescriptorProto.Package,
+		Dependency:       fileDescriptorProto.GetDependency(),
+		PublicDependency: fileDescriptorProto.GetPublicDependency(),
+		WeakDependency:   fileDescriptorProto.GetWeakDependency(),
+		MessageType:      fileDescriptorProto.GetMessageType(),
+		EnumType:         fileDescriptorProto.GetEnumType(),
+		Service:          fileDescriptorProto.GetService(),
+		Extension:        fileDescriptorProto.GetExtension(),
+		Options:          fileDescriptorProto.GetOptions(),
+		SourceCodeInfo:   sourceCodeInfo,
+		Syntax:           fileDescriptorProto.Syntax,
+		Edition:          fileDescriptorProto.Edition,
+	}
+	resultFileDescriptorProto.ProtoReflect().SetUnknown(fileDescriptorProto.ProtoReflect().GetUnknown())
+	return resultFileDescriptorProto
+}
+
 func imageFileToProtoImageFile(imageFile ImageFile) *imagev1.ImageFile {
-	return fileDescriptorProtoToProtoImageFile(
+	protoImageFile := fileDescriptorProtoToProtoImageFile(
 		imageFile.FileDescriptorProto(),
 		imageFile.IsImport(),
 		imageFile.IsSyntaxUnspecified(),
@@ -308,6 +337,8 @@ func imageFileToProtoImageFile(imageFile ImageFile) *imagev1.ImageFile {
 		imageFile.ModuleIdentity(),
 		imageFile.Commit(),
 	)
+	protoImageFile.SourceCodeInfo = imageFile.SourceCodeInfo()
+	return protoImageFile
 }
 
 func fileDescriptorProtoToProtoImageFile(
@@ -424,7 +455,7 @@ func imageToCodeGeneratorRequest(
 		request.Parameter = proto.String(parameter)
 	}
 	for i, imageFile := range imageFiles {
-		request.ProtoFile[i] = imageFile.FileDescriptorProto()
+		request.ProtoFile[i] = imageFileToFileDescriptorProto(imageFile)
 		if isFileToGenerate(
 			imageFile,
 			alreadyUsedPaths,
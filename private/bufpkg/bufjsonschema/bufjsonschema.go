@@ -0,0 +1,111 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufjsonschema generates JSON Schemas for the JSON representation of
+// the messages of an image.
+//
+// The schemas conform to JSON Schema draft 2020-12. Each schema describes the
+// Protobuf JSON representation of a message, with the messages and enums that it
+// refers to in $defs, keyed by their fully-qualified names.
+package bufjsonschema
+
+import (
+	"encoding/json"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+// Draft is the URI of the dialect of the schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema.
+//
+// Additional properties are either a *Schema, or false.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *int64             `json:"minimum,omitempty"`
+	Maximum              *int64             `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Not                  *Schema            `json:"not,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Generate generates a Schema for each message of the files of the image that are
+// not imports, keyed by the fully-qualified name of the message.
+//
+// Map entries are not included.
+func Generate(image bufimage.Image, options ...GenerateOption) (map[string]*Schema, error) {
+	return generate(image, options...)
+}
+
+// GenerateOption is an option for Generate.
+type GenerateOption func(*generateOptions)
+
+// GenerateWithTypeNames says to only generate Schemas for the messages with the
+// given fully-qualified names.
+//
+// The messages may be declared in any file of the image, including imports. An
+// error is returned if any of the messages is not declared in the image.
+func GenerateWithTypeNames(typeNames ...string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.typeNames = append(generateOptions.typeNames, typeNames...)
+	}
+}
+
+// GenerateWithImplicitPresenceRequired says to require the fields that do not
+// have explicit presence, such as the fields of proto3 that are not optional,
+// and repeated and map fields.
+//
+// This matches the JSON of Protobuf JSON marshalers that emit unpopulated fields.
+// Fields that are required in proto2 are always required.
+func GenerateWithImplicitPresenceRequired() GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.implicitPresenceRequired = true
+	}
+}
+
+// GenerateWithExclusiveOneofs says to only allow at most one of the fields of
+// each oneof to be set.
+//
+// By default, the fields of oneofs are not constrained.
+func GenerateWithExclusiveOneofs() GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.exclusiveOneofs = true
+	}
+}
+
+// Marshal marshals the Schema as indented JSON.
+func Marshal(schema *Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufjsonschema
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProto = `syntax = "proto3";
+
+package acme.events.v1;
+
+import "google/protobuf/timestamp.proto";
+
+// An order was created.
+message OrderCreated {
+  // The ID of the order.
+  string id = 1;
+  int64 quantity = 2;
+  optional string note = 3;
+  Status status = 4;
+  google.protobuf.Timestamp create_time = 5;
+  repeated Item items = 6;
+  map<string, string> labels = 7;
+  oneof payment {
+    string card = 8;
+    string invoice = 9;
+  }
+  OrderCreated parent = 10;
+
+  message Item {
+    string sku = 1;
+  }
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OPEN = 1;
+}
+`
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	image := testGetImage(t)
+	typeNameToSchema, err := Generate(image)
+	require.NoError(t, err)
+	assert.Len(t, typeNameToSchema, 2)
+	assert.Contains(t, typeNameToSchema, "acme.events.v1.OrderCreated.Item")
+
+	schema := typeNameToSchema["acme.events.v1.OrderCreated"]
+	require.NotNil(t, schema)
+	assert.Equal(t, Draft, schema.Schema)
+	assert.Equal(t, "acme.events.v1.OrderCreated.schema.json", schema.ID)
+	assert.Equal(t, "acme.events.v1.OrderCreated", schema.Title)
+	assert.Equal(t, "An order was created.", schema.Description)
+	assert.Equal(t, false, schema.AdditionalProperties)
+	assert.Empty(t, schema.Required)
+	assert.Empty(t, schema.AllOf)
+	assert.Equal(t, &Schema{Type: "string", Description: "The ID of the order."}, schema.Properties["id"])
+	assert.Equal(t, []string{"string", "integer"}, schema.Properties["quantity"].Type)
+	assert.Equal(t, &Schema{Ref: "#/$defs/acme.events.v1.Status"}, schema.Properties["status"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, schema.Properties["createTime"])
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: "#/$defs/acme.events.v1.OrderCreated.Item"}}, schema.Properties["items"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}, schema.Properties["labels"])
+	assert.Equal(t, &Schema{Ref: "#"}, schema.Properties["parent"])
+	assert.Equal(
+		t,
+		&Schema{
+			Type: []string{"string", "integer"},
+			Enum: []interface{}{"STATUS_UNSPECIFIED", "STATUS_OPEN", int32(0), int32(1)},
+		},
+		schema.Defs["acme.events.v1.Status"],
+	)
+	assert.Contains(t, schema.Defs, "acme.events.v1.OrderCreated.Item")
+	assert.NotContains(t, schema.Defs, "acme.events.v1.OrderCreated")
+
+	_, err = Generate(image, GenerateWithTypeNames("acme.events.v1.Unknown"))
+	require.EqualError(t, err, `message "acme.events.v1.Unknown" is not declared in the input`)
+	_, err = Generate(image, GenerateWithTypeNames("acme.events.v1.Status"))
+	require.EqualError(t, err, `"acme.events.v1.Status" is not a message`)
+}
+
+func TestGenerateWithOptions(t *testing.T) {
+	t.Parallel()
+	typeNameToSchema, err := Generate(
+		testGetImage(t),
+		GenerateWithTypeNames("acme.events.v1.OrderCreated"),
+		GenerateWithImplicitPresenceRequired(),
+		GenerateWithExclusiveOneofs(),
+	)
+	require.NoError(t, err)
+	require.Len(t, typeNameToSchema, 1)
+	schema := typeNameToSchema["acme.events.v1.OrderCreated"]
+	require.NotNil(t, schema)
+	assert.Equal(t, []string{"id", "quantity", "status", "items", "labels"}, schema.Required)
+	assert.Equal(
+		t,
+		[]*Schema{
+			{
+				Description: "At most one field of oneof payment is set.",
+				Not: &Schema{
+					AnyOf: []*Schema{
+						{Required: []string{"card", "invoice"}},
+					},
+				},
+			},
+		},
+		schema.AllOf,
+	)
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+	data, err := Marshal(
+		&Schema{
+			Schema:               Draft,
+			Title:                "acme.v1.Empty",
+			Type:                 "object",
+			AdditionalProperties: false,
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "acme.v1.Empty",
+  "type": "object",
+  "additionalProperties": false
+}
+`,
+		string(data),
+	)
+}
+
+func testGetImage(t *testing.T) bufimage.Image {
+	return bufimagetesting.BuildImage(t, map[string]string{"acme/events/v1/events.proto": testProto})
+}
@@ -0,0 +1,388 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufjsonschema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	defsRefPrefix = "#/$defs/"
+	rootRef       = "#"
+
+	signedIntegerPattern   = `^-?[0-9]+$`
+	unsignedIntegerPattern = `^[0-9]+$`
+	specialFloatPattern    = `^(NaN|-?Infinity)$`
+	durationPattern        = `^-?[0-9]+(\.[0-9]{1,9})?s$`
+)
+
+func generate(image bufimage.Image, options ...GenerateOption) (map[string]*Schema, error) {
+	generateOptions := newGenerateOptions()
+	for _, option := range options {
+		option(generateOptions)
+	}
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	var messageDescriptors []protoreflect.MessageDescriptor
+	if len(generateOptions.typeNames) > 0 {
+		for _, typeName := range generateOptions.typeNames {
+			messageDescriptor, err := findMessage(files, strings.TrimPrefix(typeName, "."))
+			if err != nil {
+				return nil, err
+			}
+			messageDescriptors = append(messageDescriptors, messageDescriptor)
+		}
+	} else {
+		for _, imageFile := range image.Files() {
+			if imageFile.IsImport() {
+				continue
+			}
+			fileDescriptor, err := files.FindFileByPath(imageFile.Path())
+			if err != nil {
+				return nil, err
+			}
+			messageDescriptors = appendMessages(messageDescriptors, fileDescriptor.Messages())
+		}
+	}
+	typeNameToSchema := make(map[string]*Schema, len(messageDescriptors))
+	for _, messageDescriptor := range messageDescriptors {
+		typeName := string(messageDescriptor.FullName())
+		generator := &generator{
+			generateOptions: generateOptions,
+			root:            messageDescriptor,
+			defs:            make(map[string]*Schema),
+		}
+		schema := generator.newMessageSchema(messageDescriptor)
+		schema.Schema = Draft
+		schema.ID = typeName + ".schema.json"
+		schema.Title = typeName
+		if len(generator.defs) > 0 {
+			schema.Defs = generator.defs
+		}
+		typeNameToSchema[typeName] = schema
+	}
+	return typeNameToSchema, nil
+}
+
+type generator struct {
+	generateOptions *generateOptions
+	root            protoreflect.MessageDescriptor
+	defs            map[string]*Schema
+}
+
+func (g *generator) newMessageSchema(messageDescriptor protoreflect.MessageDescriptor) *Schema {
+	schema := &Schema{
+		Type:                 "object",
+		Description:          getDescription(messageDescriptor),
+		AdditionalProperties: false,
+	}
+	fieldDescriptors := messageDescriptor.Fields()
+	if fieldDescriptors.Len() > 0 {
+		schema.Properties = make(map[string]*Schema, fieldDescriptors.Len())
+	}
+	for i := 0; i < fieldDescriptors.Len(); i++ {
+		fieldDescriptor := fieldDescriptors.Get(i)
+		jsonName := fieldDescriptor.JSONName()
+		schema.Properties[jsonName] = g.getFieldSchema(fieldDescriptor)
+		if fieldDescriptor.Cardinality() == protoreflect.Required ||
+			(g.generateOptions.implicitPresenceRequired && !fieldDescriptor.HasPresence()) {
+			schema.Required = append(schema.Required, jsonName)
+		}
+	}
+	if g.generateOptions.exclusiveOneofs {
+		oneofDescriptors := messageDescriptor.Oneofs()
+		for i := 0; i < oneofDescriptors.Len(); i++ {
+			if oneofSchema := getExclusiveOneofSchema(oneofDescriptors.Get(i)); oneofSchema != nil {
+				schema.AllOf = append(schema.AllOf, oneofSchema)
+			}
+		}
+	}
+	return schema
+}
+
+func (g *generator) getFieldSchema(fieldDescriptor protoreflect.FieldDescriptor) *Schema {
+	var schema *Schema
+	switch {
+	case fieldDescriptor.IsMap():
+		schema = &Schema{
+			Type:                 "object",
+			AdditionalProperties: g.getSingularFieldSchema(fieldDescriptor.MapValue()),
+		}
+	case fieldDescriptor.IsList():
+		schema = &Schema{
+			Type:  "array",
+			Items: g.getSingularFieldSchema(fieldDescriptor),
+		}
+	default:
+		schema = g.getSingularFieldSchema(fieldDescriptor)
+	}
+	schema.Description = getDescription(fieldDescriptor)
+	if fieldOptions, ok := fieldDescriptor.Options().(*descriptorpb.FieldOptions); ok {
+		schema.Deprecated = fieldOptions.GetDeprecated()
+	}
+	return schema
+}
+
+func (g *generator) getSingularFieldSchema(fieldDescriptor protoreflect.FieldDescriptor) *Schema {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.EnumKind:
+		return g.getEnumSchema(fieldDescriptor.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.getMessageSchema(fieldDescriptor.Message())
+	default:
+		return getScalarSchema(fieldDescriptor.Kind())
+	}
+}
+
+// getMessageSchema returns a reference to the schema of the message, and adds the
+// schema to the definitions if it was not already added.
+//
+// The well-known types that have a special JSON representation are not referenced,
+// and their schema is returned instead.
+func (g *generator) getMessageSchema(messageDescriptor protoreflect.MessageDescriptor) *Schema {
+	if schema, ok := getWellKnownTypeSchema(messageDescriptor); ok {
+		return schema
+	}
+	if messageDescriptor.FullName() == g.root.FullName() {
+		return &Schema{Ref: rootRef}
+	}
+	name := string(messageDescriptor.FullName())
+	if _, ok := g.defs[name]; !ok {
+		// The definition is added before the fields so that recursive messages terminate.
+		g.defs[name] = &Schema{}
+		g.defs[name] = g.newMessageSchema(messageDescriptor)
+	}
+	return &Schema{Ref: defsRefPrefix + name}
+}
+
+func (g *generator) getEnumSchema(enumDescriptor protoreflect.EnumDescriptor) *Schema {
+	if enumDescriptor.FullName() == "google.protobuf.NullValue" {
+		return &Schema{Type: "null"}
+	}
+	name := string(enumDescriptor.FullName())
+	if _, ok := g.defs[name]; !ok {
+		enumValueDescriptors := enumDescriptor.Values()
+		enum := make([]interface{}, 0, 2*enumValueDescriptors.Len())
+		for i := 0; i < enumValueDescriptors.Len(); i++ {
+			enum = append(enum, string(enumValueDescriptors.Get(i).Name()))
+		}
+		// The numbers of the values are also accepted by Protobuf JSON unmarshalers.
+		for i := 0; i < enumValueDescriptors.Len(); i++ {
+			enum = append(enum, int32(enumValueDescriptors.Get(i).Number()))
+		}
+		g.defs[name] = &Schema{
+			Type:        []string{"string", "integer"},
+			Description: getDescription(enumDescriptor),
+			Enum:        enum,
+		}
+	}
+	return &Schema{Ref: defsRefPrefix + name}
+}
+
+// getScalarSchema returns the schema of the JSON of the scalar kind.
+//
+// Canonically, 64-bit integers are strings and other integers are numbers, but
+// Protobuf JSON unmarshalers accept both, and both are allowed.
+func getScalarSchema(kind protoreflect.Kind) *Schema {
+	switch kind {
+	case protoreflect.BoolKind:
+		return &Schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Schema{
+			Type:    []string{"integer", "string"},
+			Format:  "int32",
+			Pattern: signedIntegerPattern,
+			Minimum: int64Ptr(math.MinInt32),
+			Maximum: int64Ptr(math.MaxInt32),
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &Schema{
+			Type:    []string{"integer", "string"},
+			Format:  "uint32",
+			Pattern: unsignedIntegerPattern,
+			Minimum: int64Ptr(0),
+			Maximum: int64Ptr(math.MaxUint32),
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &Schema{
+			Type:    []string{"string", "integer"},
+			Format:  "int64",
+			Pattern: signedIntegerPattern,
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Schema{
+			Type:    []string{"string", "integer"},
+			Format:  "uint64",
+			Pattern: unsignedIntegerPattern,
+			Minimum: int64Ptr(0),
+		}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return &Schema{
+			Type:    []string{"number", "string"},
+			Pattern: specialFloatPattern,
+		}
+	case protoreflect.StringKind:
+		return &Schema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &Schema{Type: "string", ContentEncoding: "base64"}
+	default:
+		return &Schema{}
+	}
+}
+
+// getWellKnownTypeSchema returns the schema of the JSON representation of the
+// well-known type, or false if the message is not a well-known type with a special
+// JSON representation.
+func getWellKnownTypeSchema(messageDescriptor protoreflect.MessageDescriptor) (*Schema, bool) {
+	switch messageDescriptor.FullName() {
+	case "google.protobuf.Timestamp":
+		return &Schema{Type: "string", Format: "date-time"}, true
+	case "google.protobuf.Duration":
+		return &Schema{Type: "string", Pattern: durationPattern}, true
+	case "google.protobuf.FieldMask":
+		return &Schema{Type: "string"}, true
+	case "google.protobuf.Struct":
+		return &Schema{Type: "object"}, true
+	case "google.protobuf.Value":
+		return &Schema{}, true
+	case "google.protobuf.ListValue":
+		return &Schema{Type: "array"}, true
+	case "google.protobuf.Empty":
+		return &Schema{Type: "object", AdditionalProperties: false}, true
+	case "google.protobuf.Any":
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"@type": {Type: "string"},
+			},
+			Required: []string{"@type"},
+		}, true
+	case "google.protobuf.BoolValue":
+		return getScalarSchema(protoreflect.BoolKind), true
+	case "google.protobuf.Int32Value":
+		return getScalarSchema(protoreflect.Int32Kind), true
+	case "google.protobuf.UInt32Value":
+		return getScalarSchema(protoreflect.Uint32Kind), true
+	case "google.protobuf.Int64Value":
+		return getScalarSchema(protoreflect.Int64Kind), true
+	case "google.protobuf.UInt64Value":
+		return getScalarSchema(protoreflect.Uint64Kind), true
+	case "google.protobuf.FloatValue":
+		return getScalarSchema(protoreflect.FloatKind), true
+	case "google.protobuf.DoubleValue":
+		return getScalarSchema(protoreflect.DoubleKind), true
+	case "google.protobuf.StringValue":
+		return getScalarSchema(protoreflect.StringKind), true
+	case "google.protobuf.BytesValue":
+		return getScalarSchema(protoreflect.BytesKind), true
+	default:
+		return nil, false
+	}
+}
+
+// getExclusiveOneofSchema returns a schema that does not allow two of the fields
+// of the oneof to be set, or nil if the oneof has less than two fields.
+//
+// Synthetic oneofs of proto3 optional fields are not constrained.
+func getExclusiveOneofSchema(oneofDescriptor protoreflect.OneofDescriptor) *Schema {
+	if oneofDescriptor.IsSynthetic() {
+		return nil
+	}
+	fieldDescriptors := oneofDescriptor.Fields()
+	if fieldDescriptors.Len() < 2 {
+		return nil
+	}
+	var pairSchemas []*Schema
+	for i := 0; i < fieldDescriptors.Len(); i++ {
+		for j := i + 1; j < fieldDescriptors.Len(); j++ {
+			pairSchemas = append(
+				pairSchemas,
+				&Schema{
+					Required: []string{
+						fieldDescriptors.Get(i).JSONName(),
+						fieldDescriptors.Get(j).JSONName(),
+					},
+				},
+			)
+		}
+	}
+	return &Schema{
+		Description: fmt.Sprintf("At most one field of oneof %s is set.", oneofDescriptor.Name()),
+		Not: &Schema{
+			AnyOf: pairSchemas,
+		},
+	}
+}
+
+func findMessage(files *protoregistry.Files, typeName string) (protoreflect.MessageDescriptor, error) {
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return nil, fmt.Errorf("message %q is not declared in the input", typeName)
+	}
+	messageDescriptor, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a message", typeName)
+	}
+	return messageDescriptor, nil
+}
+
+func appendMessages(
+	messageDescriptors []protoreflect.MessageDescriptor,
+	nestedMessageDescriptors protoreflect.MessageDescriptors,
+) []protoreflect.MessageDescriptor {
+	for i := 0; i < nestedMessageDescriptors.Len(); i++ {
+		messageDescriptor := nestedMessageDescriptors.Get(i)
+		if messageDescriptor.IsMapEntry() {
+			continue
+		}
+		messageDescriptors = append(messageDescriptors, messageDescriptor)
+		messageDescriptors = appendMessages(messageDescriptors, messageDescriptor.Messages())
+	}
+	return messageDescriptors
+}
+
+// getDescription returns the leading comments of the descriptor.
+func getDescription(descriptor protoreflect.Descriptor) string {
+	comments := descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor).LeadingComments
+	lines := strings.Split(strings.TrimSpace(comments), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+type generateOptions struct {
+	typeNames                []string
+	implicitPresenceRequired bool
+	exclusiveOneofs          bool
+}
+
+func newGenerateOptions() *generateOptions {
+	return &generateOptions{}
+}
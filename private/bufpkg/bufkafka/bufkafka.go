@@ -0,0 +1,202 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufkafka consumes Kafka records and decodes their Protobuf values.
+package bufkafka
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+const (
+	// SASLMechanismPlain is the PLAIN SASL mechanism.
+	SASLMechanismPlain SASLMechanism = iota + 1
+	// SASLMechanismSCRAMSHA256 is the SCRAM-SHA-256 SASL mechanism.
+	SASLMechanismSCRAMSHA256
+	// SASLMechanismSCRAMSHA512 is the SCRAM-SHA-512 SASL mechanism.
+	SASLMechanismSCRAMSHA512
+)
+
+var (
+	// AllSASLMechanismStrings is all SASL mechanism strings.
+	//
+	// Sorted in the order we want to display them.
+	AllSASLMechanismStrings = []string{
+		"plain",
+		"scram-sha-256",
+		"scram-sha-512",
+	}
+
+	stringToSASLMechanism = map[string]SASLMechanism{
+		"plain":         SASLMechanismPlain,
+		"scram-sha-256": SASLMechanismSCRAMSHA256,
+		"scram-sha-512": SASLMechanismSCRAMSHA512,
+	}
+	saslMechanismToString = map[SASLMechanism]string{
+		SASLMechanismPlain:       "plain",
+		SASLMechanismSCRAMSHA256: "scram-sha-256",
+		SASLMechanismSCRAMSHA512: "scram-sha-512",
+	}
+)
+
+// SASLMechanism is a SASL mechanism to authenticate to brokers with.
+type SASLMechanism int
+
+// String implements fmt.Stringer.
+func (s SASLMechanism) String() string {
+	str, ok := saslMechanismToString[s]
+	if !ok {
+		return strconv.Itoa(int(s))
+	}
+	return str
+}
+
+// ParseSASLMechanism parses the SASLMechanism.
+func ParseSASLMechanism(s string) (SASLMechanism, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	saslMechanism, ok := stringToSASLMechanism[s]
+	if ok {
+		return saslMechanism, nil
+	}
+	return 0, fmt.Errorf("unknown SASL mechanism: %q", s)
+}
+
+// Record is a Kafka record.
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+}
+
+// Consume consumes the records of all of the partitions of the topic, and calls f
+// for each record, until the context is done, f returns an error, or the maximum
+// number of records is consumed.
+//
+// No consumer group is joined, so no offsets are committed. Consuming stops without
+// error when the context is canceled.
+func Consume(
+	ctx context.Context,
+	brokers []string,
+	topic string,
+	f func(*Record) error,
+	options ...ConsumeOption,
+) error {
+	return consume(ctx, brokers, topic, f, options...)
+}
+
+// ConsumeOption is an option for Consume.
+type ConsumeOption func(*consumeOptions)
+
+// ConsumeWithFromBeginning says to consume the records from the beginning of each
+// partition.
+//
+// The default is to only consume the records that are produced after consuming
+// starts.
+func ConsumeWithFromBeginning() ConsumeOption {
+	return func(consumeOptions *consumeOptions) {
+		consumeOptions.fromBeginning = true
+	}
+}
+
+// ConsumeWithMaxRecords says to stop after the given number of records.
+//
+// The default is to consume until the context is done.
+func ConsumeWithMaxRecords(maxRecords int) ConsumeOption {
+	return func(consumeOptions *consumeOptions) {
+		consumeOptions.maxRecords = maxRecords
+	}
+}
+
+// ConsumeWithTLSConfig says to connect to the brokers with TLS.
+func ConsumeWithTLSConfig(tlsConfig *tls.Config) ConsumeOption {
+	return func(consumeOptions *consumeOptions) {
+		consumeOptions.tlsConfig = tlsConfig
+	}
+}
+
+// ConsumeWithSASL says to authenticate to the brokers with the SASL mechanism.
+func ConsumeWithSASL(saslMechanism SASLMechanism, username string, password string) ConsumeOption {
+	return func(consumeOptions *consumeOptions) {
+		consumeOptions.saslMechanism = saslMechanism
+		consumeOptions.saslUsername = username
+		consumeOptions.saslPassword = password
+	}
+}
+
+// SchemaRegistryHeader is the header that schema registry serializers prefix the
+// values of records with.
+//
+// The header is a zero magic byte, the ID of the schema as a 4-byte big-endian
+// integer, and the indexes of the message in the file of the schema.
+type SchemaRegistryHeader struct {
+	SchemaID uint32
+	// MessageIndexes are the indexes of the message in the file of the schema, and of
+	// the messages that it is nested in. The first top-level message is [0].
+	MessageIndexes []int64
+}
+
+// ParseSchemaRegistryFraming parses the schema registry header of the value, and
+// returns the header and the encoded message that follows it.
+func ParseSchemaRegistryFraming(value []byte) (*SchemaRegistryHeader, []byte, error) {
+	return parseSchemaRegistryFraming(value)
+}
+
+// Decoder decodes the values of records.
+//
+// Decoders are not safe for concurrent use.
+type Decoder interface {
+	// DecodeValue decodes the value to JSON.
+	DecodeValue(value []byte) ([]byte, error)
+	// DecodeRecord returns a JSON object with the topic, partition, offset, timestamp,
+	// and key of the record, and its decoded value.
+	//
+	// If the value cannot be decoded, the object has the error instead of the value,
+	// so that one bad record does not stop the inspection of a topic.
+	DecodeRecord(record *Record) ([]byte, error)
+}
+
+// NewDecoder returns a new Decoder for values that are the message with the given
+// fully-qualified name.
+func NewDecoder(
+	ctx context.Context,
+	image bufimage.Image,
+	typeName string,
+	options ...DecoderOption,
+) (Decoder, error) {
+	return newDecoder(ctx, image, typeName, options...)
+}
+
+// DecoderOption is an option for a new Decoder.
+type DecoderOption func(*decoder)
+
+// DecoderWithSchemaRegistryFraming says that the values are framed with a schema
+// registry header.
+//
+// The header is removed before the value is decoded, and the ID of the schema is
+// included in the output of DecodeRecord.
+func DecoderWithSchemaRegistryFraming() DecoderOption {
+	return func(decoder *decoder) {
+		decoder.schemaRegistryFraming = true
+	}
+}
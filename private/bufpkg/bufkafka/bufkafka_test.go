@@ -0,0 +1,124 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufkafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestParseSchemaRegistryFraming(t *testing.T) {
+	t.Parallel()
+	header, payload, err := ParseSchemaRegistryFraming([]byte{0, 0, 0, 1, 2, 0, 0x0a, 0x01, 'a'})
+	require.NoError(t, err)
+	assert.Equal(t, &SchemaRegistryHeader{SchemaID: 258, MessageIndexes: []int64{0}}, header)
+	assert.Equal(t, []byte{0x0a, 0x01, 'a'}, payload)
+
+	// [1, 2], as zig-zag varints.
+	header, payload, err = ParseSchemaRegistryFraming([]byte{0, 0, 0, 0, 7, 4, 2, 4, 0x0a})
+	require.NoError(t, err)
+	assert.Equal(t, &SchemaRegistryHeader{SchemaID: 7, MessageIndexes: []int64{1, 2}}, header)
+	assert.Equal(t, []byte{0x0a}, payload)
+
+	_, _, err = ParseSchemaRegistryFraming([]byte{0, 0, 0})
+	assert.EqualError(t, err, "the value is too short to have a schema registry header")
+	_, _, err = ParseSchemaRegistryFraming([]byte{1, 0, 0, 0, 7, 0})
+	assert.EqualError(t, err, "the value does not start with the magic byte of a schema registry header, found 0x1")
+	_, _, err = ParseSchemaRegistryFraming([]byte{0, 0, 0, 0, 7, 20, 2})
+	assert.EqualError(t, err, "the message indexes of the schema registry header are malformed")
+}
+
+func TestDecoder(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	image := bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"acme/v1/order.proto": `syntax = "proto3";
+package acme.v1;
+message Order {
+  string name = 1;
+  int64 quantity = 2;
+}
+`,
+		},
+	)
+	var value []byte
+	value = protowire.AppendTag(value, 1, protowire.BytesType)
+	value = protowire.AppendString(value, "abc")
+	value = protowire.AppendTag(value, 2, protowire.VarintType)
+	value = protowire.AppendVarint(value, 3)
+
+	decoder, err := NewDecoder(ctx, image, "acme.v1.Order")
+	require.NoError(t, err)
+	data, err := decoder.DecodeValue(value)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"abc","quantity":"3"}`, string(data))
+
+	decoder, err = NewDecoder(ctx, image, "acme.v1.Order", DecoderWithSchemaRegistryFraming())
+	require.NoError(t, err)
+	data, err = decoder.DecodeRecord(
+		&Record{
+			Topic:     "orders",
+			Partition: 1,
+			Offset:    42,
+			Timestamp: time.Date(2023, 11, 1, 12, 0, 0, 0, time.UTC),
+			Key:       []byte("abc"),
+			Value:     append([]byte{0, 0, 0, 0, 9, 0}, value...),
+		},
+	)
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{
+  "topic": "orders",
+  "partition": 1,
+  "offset": 42,
+  "timestamp": "2023-11-01T12:00:00Z",
+  "key": "abc",
+  "schemaId": 9,
+  "value": {"name":"abc","quantity":"3"}
+}`,
+		string(data),
+	)
+	// The value is not framed, so the magic byte is missing.
+	data, err = decoder.DecodeRecord(
+		&Record{
+			Topic:     "orders",
+			Timestamp: time.Date(2023, 11, 1, 12, 0, 0, 0, time.UTC),
+			Key:       []byte{0xff},
+			Value:     value,
+		},
+	)
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{
+  "topic": "orders",
+  "partition": 0,
+  "offset": 0,
+  "timestamp": "2023-11-01T12:00:00Z",
+  "keyBytes": "/w==",
+  "error": "the value does not start with the magic byte of a schema registry header, found 0xa"
+}`,
+		string(data),
+	)
+}
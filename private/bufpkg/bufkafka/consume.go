@@ -0,0 +1,127 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufkafka
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+	"go.uber.org/multierr"
+)
+
+func consume(
+	ctx context.Context,
+	brokers []string,
+	topic string,
+	f func(*Record) error,
+	options ...ConsumeOption,
+) error {
+	consumeOptions := newConsumeOptions()
+	for _, option := range options {
+		option(consumeOptions)
+	}
+	offset := kgo.NewOffset().AtEnd()
+	if consumeOptions.fromBeginning {
+		offset = kgo.NewOffset().AtStart()
+	}
+	clientOptions := []kgo.Opt{
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(offset),
+	}
+	if consumeOptions.tlsConfig != nil {
+		clientOptions = append(clientOptions, kgo.DialTLSConfig(consumeOptions.tlsConfig))
+	}
+	if consumeOptions.saslMechanism != 0 {
+		saslMechanism, err := newSASLMechanism(
+			consumeOptions.saslMechanism,
+			consumeOptions.saslUsername,
+			consumeOptions.saslPassword,
+		)
+		if err != nil {
+			return err
+		}
+		clientOptions = append(clientOptions, kgo.SASL(saslMechanism))
+	}
+	client, err := kgo.NewClient(clientOptions...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var numRecords int
+	for {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return nil
+		}
+		var fetchErr error
+		fetches.EachError(func(topic string, partition int32, err error) {
+			fetchErr = multierr.Append(fetchErr, fmt.Errorf("topic %s partition %d: %w", topic, partition, err))
+		})
+		if fetchErr != nil {
+			return fetchErr
+		}
+		for iter := fetches.RecordIter(); !iter.Done(); {
+			record := iter.Next()
+			if err := f(
+				&Record{
+					Topic:     record.Topic,
+					Partition: record.Partition,
+					Offset:    record.Offset,
+					Timestamp: record.Timestamp,
+					Key:       record.Key,
+					Value:     record.Value,
+				},
+			); err != nil {
+				return err
+			}
+			numRecords++
+			if consumeOptions.maxRecords > 0 && numRecords >= consumeOptions.maxRecords {
+				return nil
+			}
+		}
+	}
+}
+
+func newSASLMechanism(saslMechanism SASLMechanism, username string, password string) (sasl.Mechanism, error) {
+	switch saslMechanism {
+	case SASLMechanismPlain:
+		return plain.Auth{User: username, Pass: password}.AsMechanism(), nil
+	case SASLMechanismSCRAMSHA256:
+		return scram.Auth{User: username, Pass: password}.AsSha256Mechanism(), nil
+	case SASLMechanismSCRAMSHA512:
+		return scram.Auth{User: username, Pass: password}.AsSha512Mechanism(), nil
+	default:
+		return nil, fmt.Errorf("unknown SASLMechanism: %v", saslMechanism)
+	}
+}
+
+type consumeOptions struct {
+	fromBeginning bool
+	maxRecords    int
+	tlsConfig     *tls.Config
+	saslMechanism SASLMechanism
+	saslUsername  string
+	saslPassword  string
+}
+
+func newConsumeOptions() *consumeOptions {
+	return &consumeOptions{}
+}
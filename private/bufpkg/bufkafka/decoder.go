@@ -0,0 +1,183 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufkafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufreflect"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// schemaRegistryHeaderSize is the size of the magic byte and the schema ID.
+const schemaRegistryHeaderSize = 5
+
+type decoder struct {
+	unmarshaler           protoencoding.Unmarshaler
+	marshaler             protoencoding.Marshaler
+	message               proto.Message
+	schemaRegistryFraming bool
+}
+
+func newDecoder(
+	ctx context.Context,
+	image bufimage.Image,
+	typeName string,
+	options ...DecoderOption,
+) (*decoder, error) {
+	resolver, err := protoencoding.NewResolver(
+		bufimage.ImageToFileDescriptorProtos(image)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	message, err := bufreflect.NewMessage(ctx, image, typeName)
+	if err != nil {
+		return nil, err
+	}
+	decoder := &decoder{
+		unmarshaler: protoencoding.NewWireUnmarshaler(resolver),
+		marshaler:   protoencoding.NewJSONStreamMarshaler(resolver),
+		message:     message,
+	}
+	for _, option := range options {
+		option(decoder)
+	}
+	return decoder, nil
+}
+
+func (d *decoder) DecodeValue(value []byte) ([]byte, error) {
+	_, data, err := d.decodeValue(value)
+	return data, err
+}
+
+func (d *decoder) DecodeRecord(record *Record) ([]byte, error) {
+	output := &recordOutput{
+		Topic:     record.Topic,
+		Partition: record.Partition,
+		Offset:    record.Offset,
+		Timestamp: record.Timestamp.UTC().Format(time.RFC3339Nano),
+	}
+	if record.Key != nil {
+		if utf8.Valid(record.Key) {
+			output.Key = string(record.Key)
+		} else {
+			output.KeyBytes = record.Key
+		}
+	}
+	header, value, err := d.decodeValue(record.Value)
+	if header != nil {
+		output.SchemaID = &header.SchemaID
+	}
+	if err != nil {
+		output.Error = err.Error()
+	} else {
+		output.Value = value
+	}
+	return json.Marshal(output)
+}
+
+// decodeValue returns the schema registry header of the value, if any, and the
+// JSON of the message.
+func (d *decoder) decodeValue(value []byte) (*SchemaRegistryHeader, []byte, error) {
+	var header *SchemaRegistryHeader
+	if d.schemaRegistryFraming {
+		var err error
+		header, value, err = parseSchemaRegistryFraming(value)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	proto.Reset(d.message)
+	if err := d.unmarshaler.Unmarshal(value, d.message); err != nil {
+		return header, nil, fmt.Errorf("unable to unmarshal the message: %w", err)
+	}
+	data, err := d.marshaler.Marshal(d.message)
+	if err != nil {
+		return header, nil, err
+	}
+	return header, data, nil
+}
+
+// recordOutput is the JSON output of DecodeRecord.
+//
+// Keys that are not valid UTF-8 are output as base64 in keyBytes.
+type recordOutput struct {
+	Topic     string          `json:"topic"`
+	Partition int32           `json:"partition"`
+	Offset    int64           `json:"offset"`
+	Timestamp string          `json:"timestamp"`
+	Key       string          `json:"key,omitempty"`
+	KeyBytes  []byte          `json:"keyBytes,omitempty"`
+	SchemaID  *uint32         `json:"schemaId,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+func parseSchemaRegistryFraming(value []byte) (*SchemaRegistryHeader, []byte, error) {
+	if len(value) < schemaRegistryHeaderSize {
+		return nil, nil, errors.New("the value is too short to have a schema registry header")
+	}
+	if value[0] != 0 {
+		return nil, nil, fmt.Errorf("the value does not start with the magic byte of a schema registry header, found %#x", value[0])
+	}
+	header := &SchemaRegistryHeader{
+		SchemaID: binary.BigEndian.Uint32(value[1:schemaRegistryHeaderSize]),
+	}
+	value = value[schemaRegistryHeaderSize:]
+	// The message indexes are an array of zig-zag varints, prefixed by its length
+	// as a zig-zag varint. The common case of [0] is encoded as an empty array.
+	length, n := consumeZigZag(value)
+	if n < 0 || length < 0 {
+		return nil, nil, errors.New("the message indexes of the schema registry header are malformed")
+	}
+	value = value[n:]
+	if length == 0 {
+		header.MessageIndexes = []int64{0}
+		return header, value, nil
+	}
+	if length > int64(len(value)) {
+		return nil, nil, errors.New("the message indexes of the schema registry header are malformed")
+	}
+	header.MessageIndexes = make([]int64, length)
+	for i := range header.MessageIndexes {
+		messageIndex, n := consumeZigZag(value)
+		if n < 0 || messageIndex < 0 {
+			return nil, nil, errors.New("the message indexes of the schema registry header are malformed")
+		}
+		header.MessageIndexes[i] = messageIndex
+		value = value[n:]
+	}
+	return header, value, nil
+}
+
+// consumeZigZag parses a zig-zag varint, and returns its value and length, or a
+// negative length if the varint is malformed.
+func consumeZigZag(data []byte) (int64, int) {
+	value, n := protowire.ConsumeVarint(data)
+	if n < 0 {
+		return 0, n
+	}
+	return protowire.DecodeZigZag(value), n
+}
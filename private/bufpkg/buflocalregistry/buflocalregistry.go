@@ -0,0 +1,65 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buflocalregistry serves the module resolution and download APIs of
+// the BSR from modules in a local directory.
+package buflocalregistry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// Registry is a set of modules that are served by a Handler.
+type Registry interface {
+	// ModuleFullNames returns the owner/repository names of the modules,
+	// in sorted order.
+	ModuleFullNames() []string
+
+	// getModule returns the module with the given owner and repository, or
+	// nil if there is none.
+	getModule(owner string, repository string) *module
+}
+
+// NewRegistry returns a new Registry for the modules in the bucket.
+//
+// Each module is in the owner/repository directory of the bucket, such as
+// acme/weather for the buf.build/acme/weather module, and has a buf.yaml file.
+// Each module has a single commit, which is derived from the digest of its
+// content. All references other than commits, such as tags, branches, and
+// drafts, resolve to that commit.
+//
+// The modules are read when the Registry is created, so changes to the bucket
+// are not served until a new Registry is created.
+func NewRegistry(ctx context.Context, logger *zap.Logger, bucket storage.ReadBucket) (Registry, error) {
+	return newRegistry(ctx, logger, bucket)
+}
+
+// NewHandler returns a new handler that serves the modules of the Registry.
+//
+// The services that are used to resolve and download modules, such as by
+// buf mod update and buf build, are served. Modules are matched by their
+// owner and repository, so the handler serves the same modules for any remote.
+func NewHandler(registry Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(registryv1alpha1connect.NewResolveServiceHandler(newResolveServiceHandler(registry)))
+	mux.Handle(registryv1alpha1connect.NewDownloadServiceHandler(newDownloadServiceHandler(registry)))
+	mux.Handle(registryv1alpha1connect.NewRepositoryServiceHandler(newRepositoryServiceHandler(registry)))
+	mux.Handle(registryv1alpha1connect.NewRepositoryCommitServiceHandler(newRepositoryCommitServiceHandler(registry)))
+	return mux
+}
@@ -0,0 +1,154 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflocalregistry
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufcas/bufcasalpha"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	modulev1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/module/v1alpha1"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"acme/petapis/buf.yaml": []byte(`version: v1
+name: buf.build/acme/petapis
+deps:
+  - buf.build/acme/paymentapis
+`),
+			"acme/petapis/pet/v1/pet.proto": []byte(`syntax = "proto3";
+package pet.v1;
+import "payment/v1/payment.proto";
+message Pet {
+  payment.v1.Payment payment = 1;
+}
+`),
+			"acme/paymentapis/buf.yaml": []byte(`version: v1
+name: buf.build/acme/paymentapis
+`),
+			"acme/paymentapis/payment/v1/payment.proto": []byte(`syntax = "proto3";
+package payment.v1;
+message Payment {}
+`),
+			"acme/notes/README.md": []byte(`Not a module.`),
+		},
+	)
+	require.NoError(t, err)
+	registry, err := NewRegistry(ctx, zap.NewNop(), bucket)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme/paymentapis", "acme/petapis"}, registry.ModuleFullNames())
+	server := httptest.NewServer(NewHandler(registry))
+	t.Cleanup(server.Close)
+
+	resolveService := registryv1alpha1connect.NewResolveServiceClient(server.Client(), server.URL)
+	pinsResponse, err := resolveService.GetModulePins(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetModulePinsRequest{
+			ModuleReferences: []*modulev1alpha1.ModuleReference{
+				{
+					Remote:     "buf.example.com",
+					Owner:      "acme",
+					Repository: "petapis",
+					Reference:  "v1.0.0",
+				},
+			},
+		}),
+	)
+	require.NoError(t, err)
+	modulePins := pinsResponse.Msg.ModulePins
+	require.Len(t, modulePins, 2)
+	assert.Equal(t, "buf.build", modulePins[0].Remote)
+	assert.Equal(t, "paymentapis", modulePins[0].Repository)
+	assert.Equal(t, "buf.example.com", modulePins[1].Remote)
+	assert.Equal(t, "petapis", modulePins[1].Repository)
+	petModulePin := modulePins[1]
+	assert.Len(t, petModulePin.Commit, 32)
+
+	repositoryCommitService := registryv1alpha1connect.NewRepositoryCommitServiceClient(server.Client(), server.URL)
+	commitResponse, err := repositoryCommitService.GetRepositoryCommitByReference(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetRepositoryCommitByReferenceRequest{
+			RepositoryOwner: "acme",
+			RepositoryName:  "petapis",
+			Reference:       petModulePin.Commit,
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, petModulePin.Commit, commitResponse.Msg.RepositoryCommit.Name)
+	assert.Equal(t, petModulePin.ManifestDigest, commitResponse.Msg.RepositoryCommit.ManifestDigest)
+	_, err = repositoryCommitService.GetRepositoryCommitByReference(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetRepositoryCommitByReferenceRequest{
+			RepositoryOwner: "acme",
+			RepositoryName:  "petapis",
+			Reference:       "0123456789abcdef0123456789abcdef",
+		}),
+	)
+	assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+
+	downloadService := registryv1alpha1connect.NewDownloadServiceClient(server.Client(), server.URL)
+	downloadResponse, err := downloadService.DownloadManifestAndBlobs(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.DownloadManifestAndBlobsRequest{
+			Owner:      "acme",
+			Repository: "petapis",
+			Reference:  petModulePin.Commit,
+		}),
+	)
+	require.NoError(t, err)
+	fileSet, err := bufcas.ProtoManifestBlobAndBlobsToFileSet(
+		bufcasalpha.AlphaToBlob(downloadResponse.Msg.Manifest),
+		bufcasalpha.AlphaToBlobs(downloadResponse.Msg.Blobs),
+	)
+	require.NoError(t, err)
+	var paths []string
+	for _, fileNode := range fileSet.Manifest().FileNodes() {
+		paths = append(paths, fileNode.Path())
+	}
+	assert.Contains(t, paths, "pet/v1/pet.proto")
+	_, err = downloadService.DownloadManifestAndBlobs(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.DownloadManifestAndBlobsRequest{
+			Owner:      "acme",
+			Repository: "notes",
+		}),
+	)
+	assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+
+	repositoryService := registryv1alpha1connect.NewRepositoryServiceClient(server.Client(), server.URL)
+	repositoriesResponse, err := repositoryService.GetRepositoriesByFullName(
+		ctx,
+		connect.NewRequest(&registryv1alpha1.GetRepositoriesByFullNameRequest{
+			FullNames: []string{"acme/petapis", "acme/paymentapis"},
+		}),
+	)
+	require.NoError(t, err)
+	require.Len(t, repositoriesResponse.Msg.Repositories, 2)
+	assert.Equal(t, "petapis", repositoriesResponse.Msg.Repositories[0].Name)
+	assert.False(t, repositoriesResponse.Msg.Repositories[0].Deprecated)
+}
@@ -0,0 +1,177 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflocalregistry
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufcas/bufcasalpha"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	modulev1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/module/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// commitLength is the number of bytes of the manifest digest that the commit
+// name of a module is derived from, so that it has the format of a BSR commit.
+const commitLength = 16
+
+type localRegistry struct {
+	fullNameToModule map[string]*module
+}
+
+// module is a module that is served by the registry.
+type module struct {
+	owner      string
+	repository string
+	commit     string
+	// manifestDigest is the digest of the manifest blob, which is the digest
+	// of the module pin.
+	manifestDigest string
+	manifest       *modulev1alpha1.Blob
+	blobs          []*modulev1alpha1.Blob
+	// dependencyModuleReferences are the deps of the buf.yaml file of the
+	// module.
+	dependencyModuleReferences []bufmoduleref.ModuleReference
+	createTime                 time.Time
+}
+
+func newRegistry(ctx context.Context, logger *zap.Logger, bucket storage.ReadBucket) (*localRegistry, error) {
+	fullNames, err := moduleFullNamesForBucket(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	createTime := time.Now()
+	fullNameToModule := make(map[string]*module, len(fullNames))
+	for _, fullName := range fullNames {
+		moduleBucket := storage.MapReadBucket(bucket, storage.MapOnPrefix(fullName))
+		configFilePath, err := bufconfig.ExistingConfigFilePath(ctx, moduleBucket)
+		if err != nil {
+			return nil, err
+		}
+		if configFilePath == "" {
+			logger.Debug("skipping directory without a buf.yaml file", zap.String("directory", fullName))
+			continue
+		}
+		module, err := newModule(ctx, moduleBucket, fullName, createTime)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fullName, err)
+		}
+		logger.Debug("loaded module", zap.String("module", fullName), zap.String("commit", module.commit))
+		fullNameToModule[fullName] = module
+	}
+	return &localRegistry{
+		fullNameToModule: fullNameToModule,
+	}, nil
+}
+
+func (r *localRegistry) ModuleFullNames() []string {
+	fullNames := make([]string, 0, len(r.fullNameToModule))
+	for fullName := range r.fullNameToModule {
+		fullNames = append(fullNames, fullName)
+	}
+	sort.Strings(fullNames)
+	return fullNames
+}
+
+func (r *localRegistry) getModule(owner string, repository string) *module {
+	return r.fullNameToModule[owner+"/"+repository]
+}
+
+func newModule(
+	ctx context.Context,
+	moduleBucket storage.ReadBucket,
+	fullName string,
+	createTime time.Time,
+) (*module, error) {
+	owner, repository, _ := strings.Cut(fullName, "/")
+	config, err := bufconfig.GetConfigForBucket(ctx, moduleBucket)
+	if err != nil {
+		return nil, err
+	}
+	builtModule, err := bufmodulebuild.NewModuleBucketBuilder().BuildForBucket(
+		ctx,
+		moduleBucket,
+		config.Build,
+	)
+	if err != nil {
+		return nil, err
+	}
+	fileSet, err := bufcas.NewFileSetForBucket(ctx, builtModule.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	manifestBlob, err := bufcas.ManifestToBlob(fileSet.Manifest())
+	if err != nil {
+		return nil, err
+	}
+	protoManifestBlob, protoBlobs, err := bufcas.FileSetToProtoManifestBlobAndBlobs(fileSet)
+	if err != nil {
+		return nil, err
+	}
+	return &module{
+		owner:                      owner,
+		repository:                 repository,
+		commit:                     hex.EncodeToString(manifestBlob.Digest().Value()[:commitLength]),
+		manifestDigest:             manifestBlob.Digest().String(),
+		manifest:                   bufcasalpha.BlobToAlpha(protoManifestBlob),
+		blobs:                      bufcasalpha.BlobsToAlpha(protoBlobs),
+		dependencyModuleReferences: config.Build.DependencyModuleReferences,
+		createTime:                 createTime,
+	}, nil
+}
+
+// resolve returns the module for the reference, or nil if the reference is for
+// a commit that is not the commit of the module.
+func (m *module) resolve(reference string) *module {
+	if bufmoduleref.IsCommitReference(reference) && reference != m.commit {
+		return nil
+	}
+	return m
+}
+
+// moduleFullNamesForBucket returns the owner/repository directories of the
+// bucket that have files.
+func moduleFullNamesForBucket(ctx context.Context, bucket storage.ReadBucket) ([]string, error) {
+	fullNameMap := make(map[string]struct{})
+	if err := bucket.Walk(
+		ctx,
+		"",
+		func(objectInfo storage.ObjectInfo) error {
+			components := normalpath.Components(objectInfo.Path())
+			if len(components) > 2 {
+				fullNameMap[components[0]+"/"+components[1]] = struct{}{}
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	fullNames := make([]string, 0, len(fullNameMap))
+	for fullName := range fullNameMap {
+		fullNames = append(fullNames, fullName)
+	}
+	sort.Strings(fullNames)
+	return fullNames, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflocalregistry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	modulev1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/module/v1alpha1"
+	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type resolveServiceHandler struct {
+	registryv1alpha1connect.UnimplementedResolveServiceHandler
+
+	registry Registry
+}
+
+func newResolveServiceHandler(registry Registry) *resolveServiceHandler {
+	return &resolveServiceHandler{
+		registry: registry,
+	}
+}
+
+// GetModulePins returns the pins of the referenced modules and of their
+// transitive dependencies.
+//
+// The current module pins are ignored, as each module has a single commit.
+func (h *resolveServiceHandler) GetModulePins(
+	ctx context.Context,
+	req *connect.Request[registryv1alpha1.GetModulePinsRequest],
+) (*connect.Response[registryv1alpha1.GetModulePinsResponse], error) {
+	fullNameToModulePin := make(map[string]*modulev1alpha1.ModulePin)
+	protoModuleReferences := req.Msg.ModuleReferences
+	for len(protoModuleReferences) > 0 {
+		protoModuleReference := protoModuleReferences[0]
+		protoModuleReferences = protoModuleReferences[1:]
+		fullName := protoModuleReference.Owner + "/" + protoModuleReference.Repository
+		if _, ok := fullNameToModulePin[fullName]; ok {
+			continue
+		}
+		module, err := resolveModule(h.registry, protoModuleReference.Owner, protoModuleReference.Repository, protoModuleReference.Reference)
+		if err != nil {
+			return nil, err
+		}
+		fullNameToModulePin[fullName] = &modulev1alpha1.ModulePin{
+			Remote:         protoModuleReference.Remote,
+			Owner:          module.owner,
+			Repository:     module.repository,
+			Commit:         module.commit,
+			ManifestDigest: module.manifestDigest,
+		}
+		protoModuleReferences = append(
+			protoModuleReferences,
+			bufmoduleref.NewProtoModuleReferencesForModuleReferences(module.dependencyModuleReferences...)...,
+		)
+	}
+	modulePins := make([]*modulev1alpha1.ModulePin, 0, len(fullNameToModulePin))
+	for _, modulePin := range fullNameToModulePin {
+		modulePins = append(modulePins, modulePin)
+	}
+	sort.Slice(
+		modulePins,
+		func(i int, j int) bool {
+			return modulePins[i].Owner+"/"+modulePins[i].Repository < modulePins[j].Owner+"/"+modulePins[j].Repository
+		},
+	)
+	return connect.NewResponse(
+		&registryv1alpha1.GetModulePinsResponse{
+			ModulePins: modulePins,
+		},
+	), nil
+}
+
+type downloadServiceHandler struct {
+	registryv1alpha1connect.UnimplementedDownloadServiceHandler
+
+	registry Registry
+}
+
+func newDownloadServiceHandler(registry Registry) *downloadServiceHandler {
+	return &downloadServiceHandler{
+		registry: registry,
+	}
+}
+
+func (h *downloadServiceHandler) DownloadManifestAndBlobs(
+	ctx context.Context,
+	req *connect.Request[registryv1alpha1.DownloadManifestAndBlobsRequest],
+) (*connect.Response[registryv1alpha1.DownloadManifestAndBlobsResponse], error) {
+	module, err := resolveModule(h.registry, req.Msg.Owner, req.Msg.Repository, req.Msg.Reference)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(
+		&registryv1alpha1.DownloadManifestAndBlobsResponse{
+			Manifest: module.manifest,
+			Blobs:    module.blobs,
+		},
+	), nil
+}
+
+type repositoryServiceHandler struct {
+	registryv1alpha1connect.UnimplementedRepositoryServiceHandler
+
+	registry Registry
+}
+
+func newRepositoryServiceHandler(registry Registry) *repositoryServiceHandler {
+	return &repositoryServiceHandler{
+		registry: registry,
+	}
+}
+
+func (h *repositoryServiceHandler) GetRepositoryByFullName(
+	ctx context.Context,
+	req *connect.Request[registryv1alpha1.GetRepositoryByFullNameRequest],
+) (*connect.Response[registryv1alpha1.GetRepositoryByFullNameResponse], error) {
+	repository, err := h.getRepository(req.Msg.FullName)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(
+		&registryv1alpha1.GetRepositoryByFullNameResponse{
+			Repository: repository,
+		},
+	), nil
+}
+
+func (h *repositoryServiceHandler) GetRepositoriesByFullName(
+	ctx context.Context,
+	req *connect.Request[registryv1alpha1.GetRepositoriesByFullNameRequest],
+) (*connect.Response[registryv1alpha1.GetRepositoriesByFullNameResponse], error) {
+	repositories := make([]*registryv1alpha1.Repository, len(req.Msg.FullNames))
+	for i, fullName := range req.Msg.FullNames {
+		repository, err := h.getRepository(fullName)
+		if err != nil {
+			return nil, err
+		}
+		repositories[i] = repository
+	}
+	return connect.NewResponse(
+		&registryv1alpha1.GetRepositoriesByFullNameResponse{
+			Repositories: repositories,
+		},
+	), nil
+}
+
+func (h *repositoryServiceHandler) getRepository(fullName string) (*registryv1alpha1.Repository, error) {
+	owner, repository, _ := strings.Cut(fullName, "/")
+	module, err := resolveModule(h.registry, owner, repository, "")
+	if err != nil {
+		return nil, err
+	}
+	return &registryv1alpha1.Repository{
+		Id:            fullName,
+		CreateTime:    timestamppb.New(module.createTime),
+		UpdateTime:    timestamppb.New(module.createTime),
+		Name:          module.repository,
+		Visibility:    registryv1alpha1.Visibility_VISIBILITY_PUBLIC,
+		OwnerName:     module.owner,
+		DefaultBranch: bufmoduleref.Main,
+	}, nil
+}
+
+type repositoryCommitServiceHandler struct {
+	registryv1alpha1connect.UnimplementedRepositoryCommitServiceHandler
+
+	registry Registry
+}
+
+func newRepositoryCommitServiceHandler(registry Registry) *repositoryCommitServiceHandler {
+	return &repositoryCommitServiceHandler{
+		registry: registry,
+	}
+}
+
+func (h *repositoryCommitServiceHandler) GetRepositoryCommitByReference(
+	ctx context.Context,
+	req *connect.Request[registryv1alpha1.GetRepositoryCommitByReferenceRequest],
+) (*connect.Response[registryv1alpha1.GetRepositoryCommitByReferenceResponse], error) {
+	module, err := resolveModule(h.registry, req.Msg.RepositoryOwner, req.Msg.RepositoryName, req.Msg.Reference)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(
+		&registryv1alpha1.GetRepositoryCommitByReferenceResponse{
+			RepositoryCommit: &registryv1alpha1.RepositoryCommit{
+				Id:             module.commit,
+				CreateTime:     timestamppb.New(module.createTime),
+				Name:           module.commit,
+				Branch:         bufmoduleref.Main,
+				ManifestDigest: module.manifestDigest,
+			},
+		},
+	), nil
+}
+
+// resolveModule returns the module for the reference, or a not found error.
+func resolveModule(registry Registry, owner string, repository string, reference string) (*module, error) {
+	module := registry.getModule(owner, repository)
+	if module == nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("module %s/%s not found", owner, repository))
+	}
+	if module = module.resolve(reference); module == nil {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("commit %s not found for module %s/%s", reference, owner, repository))
+	}
+	return module, nil
+}
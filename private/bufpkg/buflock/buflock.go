@@ -38,6 +38,11 @@ const (
 // Config holds the parsed lock file information.
 type Config struct {
 	Dependencies []Dependency
+	// Extends is the pinned module of the configuration that the module configuration
+	// extends, if any.
+	//
+	// This is not a dependency of the module.
+	Extends *Dependency
 }
 
 // Dependency describes a single pinned dependency.
@@ -74,6 +79,7 @@ func CheckDeprecatedDigests(
 type ExternalConfigV1 struct {
 	Version string                       `json:"version,omitempty" yaml:"version,omitempty"`
 	Deps    []ExternalConfigDependencyV1 `json:"deps,omitempty" yaml:"deps,omitempty"`
+	Extends *ExternalConfigDependencyV1  `json:"extends,omitempty" yaml:"extends,omitempty"`
 }
 
 // ExternalConfigV1Beta1 represents the v1beta1 lock file.
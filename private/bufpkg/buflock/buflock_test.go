@@ -86,6 +86,12 @@ func TestWriteReadConfig(t *testing.T) {
 				Commit:     bufmoduletesting.TestCommit,
 			},
 		},
+		Extends: &buflock.Dependency{
+			Remote:     "buf.build",
+			Owner:      "test3",
+			Repository: "standards",
+			Commit:     bufmoduletesting.TestCommit,
+		},
 	}
 	err = buflock.WriteConfig(context.Background(), readWriteBucket, testConfig)
 	require.NoError(t, err)
@@ -64,6 +64,10 @@ func readConfig(ctx context.Context, readBucket storage.ReadBucket) (_ *Config,
 		for _, dep := range externalConfig.Deps {
 			config.Dependencies = append(config.Dependencies, DependencyForExternalConfigDependencyV1(dep))
 		}
+		if externalConfig.Extends != nil {
+			extends := DependencyForExternalConfigDependencyV1(*externalConfig.Extends)
+			config.Extends = &extends
+		}
 		return config, nil
 	default:
 		return nil, fmt.Errorf("unknown lock file versions %q", configVersion.Version)
@@ -78,6 +82,10 @@ func writeConfig(ctx context.Context, writeBucket storage.WriteBucket, config *C
 	for _, dep := range config.Dependencies {
 		externalConfig.Deps = append(externalConfig.Deps, ExternalConfigDependencyV1ForDependency(dep))
 	}
+	if config.Extends != nil {
+		extends := ExternalConfigDependencyV1ForDependency(*config.Extends)
+		externalConfig.Extends = &extends
+	}
 	configBytes, err := encoding.MarshalYAML(&externalConfig)
 	if err != nil {
 		return fmt.Errorf("failed to marshal lock file: %w", err)
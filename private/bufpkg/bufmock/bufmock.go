@@ -0,0 +1,116 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufmock serves mock implementations of the services of an image.
+//
+// Every method responds with an example of its response message. The example is
+// the first example that is declared on the message, as extracted by bufexample,
+// or otherwise an example that is derived from the schema: every scalar field is
+// set to a placeholder value, every repeated and map field has a single element,
+// the first field of every oneof is set, and every message field is set to the
+// example of its message. Responses can be overridden per method.
+//
+// The services are served with the Connect, gRPC, and gRPC-Web protocols, with both
+// the binary and JSON encodings.
+package bufmock
+
+import (
+	"net/http"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+// Handler is a http.Handler that serves mock implementations of services.
+type Handler interface {
+	http.Handler
+
+	// Procedures returns the procedures that are served, such as
+	// "/acme.v1.OrderService/GetOrder", in sorted order.
+	Procedures() []string
+}
+
+// NewHandler returns a new Handler that serves all of the services of the
+// non-import files of the image.
+//
+// The image must contain source code info for examples that are declared in
+// comments to be used.
+func NewHandler(image bufimage.Image, options ...HandlerOption) (Handler, error) {
+	return newHandler(image, options...)
+}
+
+// HandlerOption is an option for NewHandler.
+type HandlerOption func(*handlerOptions)
+
+// HandlerWithOverrides returns a new HandlerOption that overrides the responses
+// of the methods in the Overrides.
+//
+// NewHandler returns an error if a method of the Overrides is not served, or its
+// response is not valid for the method.
+func HandlerWithOverrides(overrides *Overrides) HandlerOption {
+	return func(handlerOptions *handlerOptions) {
+		handlerOptions.overrides = overrides
+	}
+}
+
+// Overrides are overrides of the responses of methods.
+type Overrides struct {
+	// MethodOverrides are the overrides of the methods, keyed by the fully-qualified
+	// name of the method, such as "acme.v1.OrderService/GetOrder".
+	MethodOverrides map[string]*MethodOverride
+}
+
+// MethodOverride is an override of the response of a method.
+//
+// Exactly one of Response and Error is set.
+type MethodOverride struct {
+	// Response is the response message, as YAML.
+	//
+	// As JSON is a subset of YAML, this can also be the JSON of the message.
+	Response []byte
+	// Error is the error to respond with.
+	Error *Error
+}
+
+// Error is an error to respond with.
+type Error struct {
+	// Code is the Connect code of the error, such as "not_found".
+	Code string
+	// Message is the message of the error.
+	Message string
+}
+
+// ParseOverrides parses Overrides from YAML or JSON data.
+//
+// The data is of the form:
+//
+//	methods:
+//	  acme.v1.OrderService/GetOrder:
+//	    response:
+//	      id: "1234"
+//	      quantity: 3
+//	  acme.v1.OrderService/DeleteOrder:
+//	    error:
+//	      code: permission_denied
+//	      message: orders cannot be deleted
+func ParseOverrides(data []byte) (*Overrides, error) {
+	return parseOverrides(data)
+}
+
+type handlerOptions struct {
+	overrides *Overrides
+}
+
+func newHandlerOptions() *handlerOptions {
+	return &handlerOptions{}
+}
@@ -0,0 +1,186 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmock
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/timestamp.proto";
+
+service OrderService {
+  rpc GetOrder(GetOrderRequest) returns (Order);
+  rpc ListOrders(ListOrdersRequest) returns (ListOrdersResponse);
+  rpc DeleteOrder(DeleteOrderRequest) returns (DeleteOrderResponse);
+}
+
+message GetOrderRequest {
+  string id = 1;
+}
+
+// @example
+// {"id": "1234", "status": "STATUS_SHIPPED"}
+message Order {
+  string id = 1;
+  Status status = 2;
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_PENDING = 1;
+  STATUS_SHIPPED = 2;
+}
+
+message ListOrdersRequest {}
+
+message ListOrdersResponse {
+  repeated Order orders = 1;
+  map<string, int64> counts = 2;
+  google.protobuf.Timestamp create_time = 3;
+  Node node = 4;
+  oneof cursor {
+    string next = 5;
+    int32 offset = 6;
+  }
+}
+
+message Node {
+  string name = 1;
+  bool leaf = 2;
+  Node child = 3;
+}
+
+message DeleteOrderRequest {
+  string id = 1;
+}
+
+message DeleteOrderResponse {}
+`
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	handler, err := NewHandler(testGetImage(t))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			"/acme.v1.OrderService/DeleteOrder",
+			"/acme.v1.OrderService/GetOrder",
+			"/acme.v1.OrderService/ListOrders",
+		},
+		handler.Procedures(),
+	)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	statusCode, body := testPostJSON(t, server, "/acme.v1.OrderService/GetOrder")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.JSONEq(t, `{"id":"1234","status":"STATUS_SHIPPED"}`, body)
+	statusCode, body = testPostJSON(t, server, "/acme.v1.OrderService/ListOrders")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.JSONEq(
+		t,
+		`{
+  "orders": [{"id": "1234", "status": "STATUS_SHIPPED"}],
+  "counts": {"key": "1"},
+  "createTime": "2023-01-01T00:00:00Z",
+  "node": {"name": "name", "leaf": true},
+  "next": "next"
+}`,
+		body,
+	)
+}
+
+func TestHandlerOverrides(t *testing.T) {
+	t.Parallel()
+	overrides, err := ParseOverrides([]byte(`methods:
+  acme.v1.OrderService/GetOrder:
+    response:
+      id: "5678"
+  acme.v1.OrderService/DeleteOrder:
+    error:
+      code: permission_denied
+      message: orders cannot be deleted
+`))
+	require.NoError(t, err)
+	handler, err := NewHandler(testGetImage(t), HandlerWithOverrides(overrides))
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	statusCode, body := testPostJSON(t, server, "/acme.v1.OrderService/GetOrder")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.JSONEq(t, `{"id":"5678"}`, body)
+	statusCode, body = testPostJSON(t, server, "/acme.v1.OrderService/DeleteOrder")
+	assert.Equal(t, http.StatusForbidden, statusCode)
+	assert.JSONEq(t, `{"code":"permission_denied","message":"orders cannot be deleted"}`, body)
+
+	overrides, err = ParseOverrides([]byte(`methods:
+  acme.v1.OrderService/GetOrder:
+    response:
+      unknown: 1
+  acme.v1.OrderService/CreateOrder:
+    response: {}
+`))
+	require.NoError(t, err)
+	_, err = NewHandler(testGetImage(t), HandlerWithOverrides(overrides))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "method acme.v1.OrderService/GetOrder: the response of the overrides is not a valid acme.v1.Order")
+	assert.Contains(t, err.Error(), "method acme.v1.OrderService/CreateOrder of the overrides is not served")
+}
+
+func TestParseOverrides(t *testing.T) {
+	t.Parallel()
+	_, err := ParseOverrides([]byte(`methods:
+  acme.v1.OrderService/GetOrder: {}
+  acme.v1.OrderService/DeleteOrder:
+    error:
+      code: not_a_code
+`))
+	assert.EqualError(
+		t,
+		err,
+		`method acme.v1.OrderService/DeleteOrder: unknown code "not_a_code"; method acme.v1.OrderService/GetOrder: exactly one of response and error must be set`,
+	)
+}
+
+func testPostJSON(t *testing.T, server *httptest.Server, procedure string) (int, string) {
+	response, err := server.Client().Post(server.URL+procedure, "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response.StatusCode, string(body)
+}
+
+func testGetImage(t *testing.T) bufimage.Image {
+	return bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"acme/v1/order.proto": testOrderProto,
+		},
+	)
+}
@@ -0,0 +1,161 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmock
+
+import (
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// exampleTime is the time of the examples of google.protobuf.Timestamp fields.
+var exampleTime = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// skippedWellKnownTypeNames are the well-known types that are left unset in
+// derived examples, as they have no meaningful placeholder value.
+var skippedWellKnownTypeNames = map[protoreflect.FullName]struct{}{
+	"google.protobuf.Any":       {},
+	"google.protobuf.FieldMask": {},
+	"google.protobuf.ListValue": {},
+	"google.protobuf.Struct":    {},
+	"google.protobuf.Value":     {},
+}
+
+type exampleBuilder struct {
+	jsonUnmarshaler       protoencoding.Unmarshaler
+	typeNameToExampleJSON map[string][]byte
+}
+
+// newExample returns the example of the message.
+//
+// The declared example of the message is used if it has one, otherwise the
+// example is derived from its schema.
+func (b *exampleBuilder) newExample(messageDescriptor protoreflect.MessageDescriptor) (proto.Message, error) {
+	message := dynamicpb.NewMessage(messageDescriptor)
+	if err := b.setExample(message, map[protoreflect.FullName]struct{}{}); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// setExample sets the fields of the message to its example.
+//
+// The messages that are being derived are in ancestors, and fields of those types
+// are left unset to terminate recursive types.
+func (b *exampleBuilder) setExample(message protoreflect.Message, ancestors map[protoreflect.FullName]struct{}) error {
+	messageDescriptor := message.Descriptor()
+	if exampleJSON, ok := b.typeNameToExampleJSON[string(messageDescriptor.FullName())]; ok {
+		return b.jsonUnmarshaler.Unmarshal(exampleJSON, message.Interface())
+	}
+	switch messageDescriptor.FullName() {
+	case "google.protobuf.Timestamp":
+		message.Set(messageDescriptor.Fields().ByName("seconds"), protoreflect.ValueOfInt64(exampleTime.Unix()))
+		return nil
+	case "google.protobuf.Duration":
+		message.Set(messageDescriptor.Fields().ByName("seconds"), protoreflect.ValueOfInt64(1))
+		return nil
+	}
+	ancestors[messageDescriptor.FullName()] = struct{}{}
+	defer delete(ancestors, messageDescriptor.FullName())
+	fields := messageDescriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() && oneof.Fields().Get(0) != field {
+			continue
+		}
+		valueMessageDescriptor := field.Message()
+		if field.IsMap() {
+			valueMessageDescriptor = field.MapValue().Message()
+		}
+		if valueMessageDescriptor != nil && !isExampleMessage(valueMessageDescriptor, ancestors) {
+			continue
+		}
+		var err error
+		switch {
+		case field.IsMap():
+			mapValue := message.Mutable(field).Map()
+			key := newScalarExample(field.MapKey())
+			if field.MapValue().Message() != nil {
+				err = b.setExample(mapValue.Mutable(key.MapKey()).Message(), ancestors)
+			} else {
+				mapValue.Set(key.MapKey(), newScalarExample(field.MapValue()))
+			}
+		case field.IsList():
+			list := message.Mutable(field).List()
+			if field.Message() != nil {
+				err = b.setExample(list.AppendMutable().Message(), ancestors)
+			} else {
+				list.Append(newScalarExample(field))
+			}
+		case field.Message() != nil:
+			err = b.setExample(message.Mutable(field).Message(), ancestors)
+		default:
+			message.Set(field, newScalarExample(field))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isExampleMessage returns true if fields of the message type are set in derived
+// examples.
+func isExampleMessage(messageDescriptor protoreflect.MessageDescriptor, ancestors map[protoreflect.FullName]struct{}) bool {
+	if _, ok := ancestors[messageDescriptor.FullName()]; ok {
+		return false
+	}
+	_, ok := skippedWellKnownTypeNames[messageDescriptor.FullName()]
+	return !ok
+}
+
+// newScalarExample returns the example of a field that is not of a message type.
+func newScalarExample(field protoreflect.FieldDescriptor) protoreflect.Value {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		// Prefer the first value that is not the zero value, as the zero value
+		// is usually UNSPECIFIED.
+		for i := 0; i < values.Len(); i++ {
+			if values.Get(i).Number() != 0 {
+				return protoreflect.ValueOfEnum(values.Get(i).Number())
+			}
+		}
+		return protoreflect.ValueOfEnum(values.Get(0).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(1)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(1)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(1)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(1)
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(1)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(1)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(string(field.Name()))
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(field.Name()))
+	default:
+		return field.Default()
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/bufpkg/bufexample"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type handler struct {
+	*http.ServeMux
+	procedures []string
+}
+
+func newHandler(image bufimage.Image, options ...HandlerOption) (*handler, error) {
+	handlerOptions := newHandlerOptions()
+	for _, option := range options {
+		option(handlerOptions)
+	}
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptorProtos(image)...)
+	if err != nil {
+		return nil, err
+	}
+	examples, err := bufexample.Extract(image)
+	if err != nil {
+		return nil, err
+	}
+	// The first example of each message is its response.
+	typeNameToExampleJSON := make(map[string][]byte)
+	for _, example := range examples {
+		if _, ok := typeNameToExampleJSON[example.TypeName]; !ok {
+			typeNameToExampleJSON[example.TypeName] = example.JSON
+		}
+	}
+	builder := &handlerBuilder{
+		resolver:        resolver,
+		yamlUnmarshaler: protoencoding.NewYAMLUnmarshaler(resolver),
+		exampleBuilder: &exampleBuilder{
+			jsonUnmarshaler:       protoencoding.NewJSONUnmarshaler(resolver),
+			typeNameToExampleJSON: typeNameToExampleJSON,
+		},
+		methodOverrides: make(map[string]*MethodOverride),
+	}
+	if handlerOptions.overrides != nil {
+		for methodName, methodOverride := range handlerOptions.overrides.MethodOverrides {
+			builder.methodOverrides[methodName] = methodOverride
+		}
+	}
+	handler := &handler{
+		ServeMux: http.NewServeMux(),
+	}
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptor, err := files.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		services := fileDescriptor.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				procedure, methodHandler, err := builder.newMethodHandler(methods.Get(j))
+				if err != nil {
+					builder.errs = multierr.Append(builder.errs, err)
+					continue
+				}
+				handler.Handle(procedure, methodHandler)
+				handler.procedures = append(handler.procedures, procedure)
+			}
+		}
+	}
+	for _, methodName := range slicesext.MapKeysToSortedSlice(builder.methodOverrides) {
+		builder.errs = multierr.Append(builder.errs, fmt.Errorf("method %s of the overrides is not served", methodName))
+	}
+	if builder.errs != nil {
+		return nil, builder.errs
+	}
+	handler.procedures = slicesext.ToUniqueSorted(handler.procedures)
+	return handler, nil
+}
+
+func (h *handler) Procedures() []string {
+	return h.procedures
+}
+
+type handlerBuilder struct {
+	resolver        protoencoding.Resolver
+	yamlUnmarshaler protoencoding.Unmarshaler
+	exampleBuilder  *exampleBuilder
+	// methodOverrides are the overrides that have not been used yet.
+	methodOverrides map[string]*MethodOverride
+
+	errs error
+}
+
+// newMethodHandler returns the procedure and handler of the method.
+func (b *handlerBuilder) newMethodHandler(methodDescriptor protoreflect.MethodDescriptor) (string, http.Handler, error) {
+	methodName := fmt.Sprintf("%s/%s", methodDescriptor.Parent().FullName(), methodDescriptor.Name())
+	procedure := "/" + methodName
+	responseFunc, err := b.newResponseFunc(methodName, methodDescriptor.Output())
+	if err != nil {
+		return "", nil, fmt.Errorf("method %s: %w", methodName, err)
+	}
+	handlerOptions := []connect.HandlerOption{
+		connect.WithCodec(&codec{name: "proto", marshaler: protoencoding.NewWireMarshaler()}),
+		connect.WithCodec(&codec{name: "json", marshaler: protoencoding.NewJSONMarshaler(b.resolver)}),
+	}
+	switch {
+	case methodDescriptor.IsStreamingClient() && methodDescriptor.IsStreamingServer():
+		return procedure, connect.NewBidiStreamHandler(
+			procedure,
+			func(ctx context.Context, stream *connect.BidiStream[request, response]) error {
+				// Every request is responded to, until the client closes its side of the stream.
+				for {
+					if _, err := stream.Receive(); err != nil {
+						if errors.Is(err, io.EOF) {
+							return nil
+						}
+						return err
+					}
+					response, err := responseFunc()
+					if err != nil {
+						return err
+					}
+					if err := stream.Send(response); err != nil {
+						return err
+					}
+				}
+			},
+			handlerOptions...,
+		), nil
+	case methodDescriptor.IsStreamingClient():
+		return procedure, connect.NewClientStreamHandler(
+			procedure,
+			func(ctx context.Context, stream *connect.ClientStream[request]) (*connect.Response[response], error) {
+				for stream.Receive() {
+				}
+				if err := stream.Err(); err != nil {
+					return nil, err
+				}
+				response, err := responseFunc()
+				if err != nil {
+					return nil, err
+				}
+				return connect.NewResponse(response), nil
+			},
+			handlerOptions...,
+		), nil
+	case methodDescriptor.IsStreamingServer():
+		return procedure, connect.NewServerStreamHandler(
+			procedure,
+			func(ctx context.Context, _ *connect.Request[request], stream *connect.ServerStream[response]) error {
+				response, err := responseFunc()
+				if err != nil {
+					return err
+				}
+				return stream.Send(response)
+			},
+			handlerOptions...,
+		), nil
+	default:
+		return procedure, connect.NewUnaryHandler(
+			procedure,
+			func(ctx context.Context, _ *connect.Request[request]) (*connect.Response[response], error) {
+				response, err := responseFunc()
+				if err != nil {
+					return nil, err
+				}
+				return connect.NewResponse(response), nil
+			},
+			handlerOptions...,
+		), nil
+	}
+}
+
+// newResponseFunc returns a function that returns the response of the method, or
+// the error to respond with.
+func (b *handlerBuilder) newResponseFunc(
+	methodName string,
+	outputDescriptor protoreflect.MessageDescriptor,
+) (func() (*response, error), error) {
+	methodOverride, ok := b.methodOverrides[methodName]
+	delete(b.methodOverrides, methodName)
+	if ok && methodOverride.Error != nil {
+		code, err := parseCode(methodOverride.Error.Code)
+		if err != nil {
+			return nil, err
+		}
+		return func() (*response, error) {
+			return nil, connect.NewError(code, errors.New(methodOverride.Error.Message))
+		}, nil
+	}
+	var message proto.Message
+	if ok {
+		message = dynamicpb.NewMessage(outputDescriptor)
+		if err := b.yamlUnmarshaler.Unmarshal(methodOverride.Response, message); err != nil {
+			return nil, fmt.Errorf("the response of the overrides is not a valid %s: %w", outputDescriptor.FullName(), err)
+		}
+	} else {
+		var err error
+		message, err = b.exampleBuilder.newExample(outputDescriptor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return func() (*response, error) {
+		return &response{message: message}, nil
+	}, nil
+}
+
+// request is a request of any type.
+//
+// The contents of requests are not used, so they are never unmarshaled.
+type request struct{}
+
+// response is a response of any type.
+type response struct {
+	message proto.Message
+}
+
+// codec is a connect.Codec that unmarshals requests and marshals responses.
+type codec struct {
+	name      string
+	marshaler protoencoding.Marshaler
+}
+
+var _ connect.Codec = (*codec)(nil)
+
+func (c *codec) Name() string {
+	return c.name
+}
+
+func (c *codec) Marshal(value any) ([]byte, error) {
+	response, ok := value.(*response)
+	if !ok {
+		return nil, fmt.Errorf("unable to marshal %T", value)
+	}
+	return c.marshaler.Marshal(response.message)
+}
+
+func (c *codec) Unmarshal(_ []byte, value any) error {
+	if _, ok := value.(*request); !ok {
+		return fmt.Errorf("unable to unmarshal into %T", value)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmock
+
+import (
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"go.uber.org/multierr"
+)
+
+type externalOverrides struct {
+	Methods map[string]*externalMethodOverride `json:"methods,omitempty" yaml:"methods,omitempty"`
+}
+
+type externalMethodOverride struct {
+	Response interface{}    `json:"response,omitempty" yaml:"response,omitempty"`
+	Error    *externalError `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type externalError struct {
+	Code    string `json:"code,omitempty" yaml:"code,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+func parseOverrides(data []byte) (*Overrides, error) {
+	var externalOverrides externalOverrides
+	if err := encoding.UnmarshalJSONOrYAMLStrict(data, &externalOverrides); err != nil {
+		return nil, err
+	}
+	overrides := &Overrides{
+		MethodOverrides: make(map[string]*MethodOverride, len(externalOverrides.Methods)),
+	}
+	var errs error
+	for _, methodName := range slicesext.MapKeysToSortedSlice(externalOverrides.Methods) {
+		methodOverride, err := parseMethodOverride(externalOverrides.Methods[methodName])
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("method %s: %w", methodName, err))
+			continue
+		}
+		overrides.MethodOverrides[methodName] = methodOverride
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return overrides, nil
+}
+
+func parseMethodOverride(externalMethodOverride *externalMethodOverride) (*MethodOverride, error) {
+	if externalMethodOverride == nil || (externalMethodOverride.Response == nil) == (externalMethodOverride.Error == nil) {
+		return nil, errors.New("exactly one of response and error must be set")
+	}
+	if externalMethodOverride.Error != nil {
+		if _, err := parseCode(externalMethodOverride.Error.Code); err != nil {
+			return nil, err
+		}
+		return &MethodOverride{
+			Error: &Error{
+				Code:    externalMethodOverride.Error.Code,
+				Message: externalMethodOverride.Error.Message,
+			},
+		}, nil
+	}
+	response, err := encoding.MarshalYAML(externalMethodOverride.Response)
+	if err != nil {
+		return nil, err
+	}
+	return &MethodOverride{
+		Response: response,
+	}, nil
+}
+
+func parseCode(s string) (connect.Code, error) {
+	if s == "" {
+		return 0, errors.New("the code of the error must be set")
+	}
+	var code connect.Code
+	if err := code.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown code %q", s)
+	}
+	if code == 0 {
+		// "ok" is not a valid code for an error.
+		return 0, fmt.Errorf("unknown code %q", s)
+	}
+	return code, nil
+}
@@ -309,6 +309,15 @@ func NewNopModuleReader() ModuleReader {
 	return newNopModuleReader()
 }
 
+// NewExtendsResolver returns a new bufconfig.ExtendsResolver that reads the modules
+// that configurations extend with the ModuleReader.
+//
+// The ignore and ignore_only keys of the configuration of a module are not extended,
+// as the paths are of the files of the module.
+func NewExtendsResolver(moduleReader ModuleReader) bufconfig.ExtendsResolver {
+	return newExtendsResolver(moduleReader)
+}
+
 // ModuleFileSet is a Protobuf module file set.
 //
 // It contains the files for both targets, sources and dependencies.
@@ -128,6 +128,21 @@ func (b *moduleBucketBuilder) buildForBucket(
 				),
 			)
 		}
+		if len(config.Includes) != 0 {
+			includeMatchers := make([]storage.Matcher, 0, len(config.Includes))
+			for _, include := range config.Includes {
+				includeMatchers = append(
+					includeMatchers,
+					storage.MatchPathGlob(include),
+				)
+			}
+			mappers = append(
+				mappers,
+				storage.MatchOr(
+					includeMatchers...,
+				),
+			)
+		}
 		rootBuckets = append(
 			rootBuckets,
 			storage.MapReadBucket(
@@ -141,6 +141,30 @@ func TestBucketGetAllFileInfos5(t *testing.T) {
 	)
 }
 
+func TestBucketGetFileInfos6(t *testing.T) {
+	t.Parallel()
+	config, err := bufmoduleconfig.NewConfigV1(
+		bufmoduleconfig.ExternalConfigV1{
+			Excludes: []string{"proto/a/c"},
+			Includes: []string{
+				"proto/a/**",
+				"**/2.proto",
+			},
+		},
+	)
+	require.NoError(t, err)
+	testBucketGetFileInfos(
+		t,
+		"testdata/1",
+		config,
+		bufmoduletesting.NewFileInfo(t, "proto/a/1.proto", "testdata/1/proto/a/1.proto", nil, ""),
+		bufmoduletesting.NewFileInfo(t, "proto/a/2.proto", "testdata/1/proto/a/2.proto", nil, ""),
+		bufmoduletesting.NewFileInfo(t, "proto/a/3.proto", "testdata/1/proto/a/3.proto", nil, ""),
+		bufmoduletesting.NewFileInfo(t, "proto/b/2.proto", "testdata/1/proto/b/2.proto", nil, ""),
+		bufmoduletesting.NewFileInfo(t, "proto/d/2.proto", "testdata/1/proto/d/2.proto", nil, ""),
+	)
+}
+
 func TestConfigV1Beta1BucketGetFileInfos1(t *testing.T) {
 	t.Parallel()
 	config, err := bufmoduleconfig.NewConfigV1Beta1(
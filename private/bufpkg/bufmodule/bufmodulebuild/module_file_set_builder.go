@@ -16,11 +16,19 @@ package bufmodulebuild
 
 import (
 	"context"
+	"sync"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/thread"
 	"go.uber.org/zap"
 )
 
+// getModulesParallelismMultiplier is the multiplier of thread.Parallelism for
+// the number of Modules that are read at once, as reading Modules is mostly
+// spent waiting on the network when they are not cached.
+const getModulesParallelismMultiplier = 4
+
 type moduleFileSetBuilder struct {
 	logger       *zap.Logger
 	moduleReader bufmodule.ModuleReader
@@ -93,6 +101,7 @@ func (m *moduleFileSetBuilder) build(
 	}
 	// We know these are unique by remote, owner, repository and
 	// contain all transitive dependencies.
+	var dependencyModulePins []bufmoduleref.ModulePin
 	for _, dependencyModulePin := range module.DependencyModulePins() {
 		if workspace != nil {
 			if _, ok := workspace.GetModule(dependencyModulePin); ok {
@@ -101,11 +110,57 @@ func (m *moduleFileSetBuilder) build(
 				continue
 			}
 		}
-		dependencyModule, err := m.moduleReader.GetModule(ctx, dependencyModulePin)
-		if err != nil {
-			return nil, err
-		}
-		dependencyModules = append(dependencyModules, dependencyModule)
+		dependencyModulePins = append(dependencyModulePins, dependencyModulePin)
+	}
+	readDependencyModules, err := m.getModules(ctx, dependencyModulePins)
+	if err != nil {
+		return nil, err
 	}
+	dependencyModules = append(dependencyModules, readDependencyModules...)
 	return bufmodule.NewModuleFileSet(module, dependencyModules), nil
 }
+
+// getModules gets the Modules for the given ModulePins concurrently, as
+// reading a Module may require downloading it. The Modules are returned in
+// the same order as the ModulePins.
+func (m *moduleFileSetBuilder) getModules(
+	ctx context.Context,
+	modulePins []bufmoduleref.ModulePin,
+) ([]bufmodule.Module, error) {
+	modules := make([]bufmodule.Module, len(modulePins))
+	var lock sync.Mutex
+	var completed int
+	jobs := make([]func(context.Context) error, len(modulePins))
+	for i, modulePin := range modulePins {
+		i := i
+		modulePin := modulePin
+		jobs[i] = func(ctx context.Context) error {
+			module, err := m.moduleReader.GetModule(ctx, modulePin)
+			if err != nil {
+				return err
+			}
+			modules[i] = module
+			lock.Lock()
+			completed++
+			m.logger.Debug(
+				"dependency module read",
+				zap.String("module", modulePin.String()),
+				zap.Int("completed", completed),
+				zap.Int("total", len(modulePins)),
+			)
+			lock.Unlock()
+			return nil
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := thread.Parallelize(
+		ctx,
+		jobs,
+		thread.ParallelizeWithMultiplier(getModulesParallelismMultiplier),
+		thread.ParallelizeWithCancel(cancel),
+	); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
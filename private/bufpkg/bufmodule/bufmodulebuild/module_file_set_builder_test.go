@@ -0,0 +1,163 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulebuild
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGetModules(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name        string
+		parallelism int
+		numPins     int
+		failingPin  int
+		expectedErr bool
+	}{
+		{
+			name:        "none",
+			parallelism: 1,
+			failingPin:  -1,
+		},
+		{
+			name:        "serial",
+			parallelism: 1,
+			numPins:     10,
+			failingPin:  -1,
+		},
+		{
+			name:        "parallel",
+			parallelism: 2,
+			numPins:     20,
+			failingPin:  -1,
+		},
+		{
+			name:        "more jobs than pins",
+			parallelism: 8,
+			numPins:     3,
+			failingPin:  -1,
+		},
+		{
+			name:        "error",
+			parallelism: 2,
+			numPins:     20,
+			failingPin:  7,
+			expectedErr: true,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			modulePins := make([]bufmoduleref.ModulePin, testCase.numPins)
+			for i := range modulePins {
+				modulePin, err := bufmoduleref.NewModulePin(
+					"buf.build",
+					"acme",
+					fmt.Sprintf("repository%d", i),
+					fmt.Sprintf("commit%d", i),
+					bufmoduletesting.TestDigest,
+				)
+				require.NoError(t, err)
+				modulePins[i] = modulePin
+			}
+			moduleReader := &testModuleReader{}
+			if testCase.failingPin >= 0 {
+				moduleReader.failingRepository = modulePins[testCase.failingPin].Repository()
+			}
+			moduleFileSetBuilder := newModuleFileSetBuilder(zap.NewNop(), moduleReader)
+			ctx := thread.WithParallelism(context.Background(), testCase.parallelism)
+			modules, err := moduleFileSetBuilder.getModules(ctx, modulePins)
+			if testCase.expectedErr {
+				assert.ErrorIs(t, err, errTestModuleReader)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, modules, len(modulePins))
+			for i, module := range modules {
+				// The Modules are in the order of the ModulePins.
+				assert.Equal(t, modulePins[i].IdentityString(), module.ModuleIdentity().IdentityString())
+				assert.Equal(t, modulePins[i].Commit(), module.Commit())
+			}
+			maxConcurrent := testCase.parallelism * getModulesParallelismMultiplier
+			if testCase.numPins < maxConcurrent {
+				maxConcurrent = testCase.numPins
+			}
+			assert.LessOrEqual(t, moduleReader.maxConcurrent, maxConcurrent)
+			if testCase.numPins > 1 && testCase.parallelism > 1 {
+				assert.Greater(t, moduleReader.maxConcurrent, 1)
+			}
+		})
+	}
+}
+
+var errTestModuleReader = errors.New("module not found")
+
+// testModuleReader is a bufmodule.ModuleReader that records how many Modules
+// are read at once.
+type testModuleReader struct {
+	// failingRepository is the repository for which an error is returned.
+	failingRepository string
+
+	lock          sync.Mutex
+	concurrent    int
+	maxConcurrent int
+}
+
+func (r *testModuleReader) GetModule(
+	ctx context.Context,
+	modulePin bufmoduleref.ModulePin,
+) (bufmodule.Module, error) {
+	r.lock.Lock()
+	r.concurrent++
+	if r.concurrent > r.maxConcurrent {
+		r.maxConcurrent = r.concurrent
+	}
+	r.lock.Unlock()
+	defer func() {
+		r.lock.Lock()
+		r.concurrent--
+		r.lock.Unlock()
+	}()
+	// Simulates a download, so that reads overlap.
+	time.Sleep(10 * time.Millisecond)
+	if modulePin.Repository() == r.failingRepository {
+		return nil, errTestModuleReader
+	}
+	readBucket, err := storagemem.NewReadBucket(nil)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewModuleForBucket(
+		ctx,
+		readBucket,
+		bufmodule.ModuleWithModuleIdentityAndCommit(modulePin, modulePin.Commit()),
+	)
+}
@@ -99,5 +99,6 @@ func (c *casModuleReader) GetModule(
 	if err := c.cache.PutModule(ctx, modulePin, remoteModule); err != nil {
 		return nil, err
 	}
+	c.verbosePrinter.Printf("downloaded %s", modulePin.String())
 	return remoteModule, nil
 }
@@ -37,7 +37,16 @@ type Config struct {
 	// The excludes in this map will be relative to the root they map to!
 	//
 	// If RootToExcludes is empty, the default is "." with no excludes.
-	RootToExcludes             map[string][]string
+	RootToExcludes map[string][]string
+	// Includes are the globs of the files within the roots to include.
+	//
+	// If Includes is empty, all files that are not excluded are included. Otherwise,
+	// only the files that match at least one of the globs are included. The globs
+	// are relative to the root, and are matched with normalpath.MatchGlob, ie
+	// **/v1/** includes all files within a v1 directory.
+	//
+	// All includes will be normalized and validated.
+	Includes                   []string
 	DependencyModuleReferences []bufmoduleref.ModuleReference
 }
 
@@ -60,4 +69,5 @@ type ExternalConfigV1Beta1 struct {
 // ExternalConfigV1 is an external config.
 type ExternalConfigV1 struct {
 	Excludes []string `json:"excludes,omitempty" yaml:"excludes,omitempty"`
+	Includes []string `json:"includes,omitempty" yaml:"includes,omitempty"`
 }
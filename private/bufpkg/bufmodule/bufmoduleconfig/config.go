@@ -15,6 +15,7 @@
 package bufmoduleconfig
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -136,12 +137,43 @@ func newConfigV1(externalConfig ExternalConfigV1, deps ...string) (*Config, erro
 	rootToExcludes := map[string][]string{
 		".": excludes, // all excludes are relative to the root
 	}
+	includes, err := normalizeAndCheckIncludes(externalConfig.Includes)
+	if err != nil {
+		return nil, err
+	}
 	return &Config{
 		RootToExcludes:             rootToExcludes,
+		Includes:                   includes,
 		DependencyModuleReferences: dependencyModuleReferences,
 	}, nil
 }
 
+// normalizeAndCheckIncludes normalizes and validates the include globs, and
+// returns them unique and sorted.
+func normalizeAndCheckIncludes(includes []string) ([]string, error) {
+	if len(includes) == 0 {
+		return nil, nil
+	}
+	normalizedIncludes := make([]string, 0, len(includes))
+	for _, include := range includes {
+		if include == "" {
+			return nil, errors.New("include value is empty")
+		}
+		normalizedInclude, err := normalpath.NormalizeAndValidate(include)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", include, err)
+		}
+		if normalizedInclude == "." {
+			return nil, errors.New(`include "." is not allowed, omit includes to include all files`)
+		}
+		if err := normalpath.ValidateGlob(normalizedInclude); err != nil {
+			return nil, fmt.Errorf("include %s is not a valid glob: %w", include, err)
+		}
+		normalizedIncludes = append(normalizedIncludes, normalizedInclude)
+	}
+	return stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(normalizedIncludes), nil
+}
+
 func parseDependencyModuleReferences(deps ...string) ([]bufmoduleref.ModuleReference, error) {
 	if len(deps) == 0 {
 		return nil, nil
@@ -388,6 +388,29 @@ func TestNewConfigV1Equal2(t *testing.T) {
 	)
 }
 
+func TestNewConfigV1Includes(t *testing.T) {
+	t.Parallel()
+	config, err := bufmoduleconfig.NewConfigV1(
+		bufmoduleconfig.ExternalConfigV1{
+			Includes: []string{
+				"./**/v1/**",
+				"a/*.proto",
+				"**/v1/**",
+			},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"**/v1/**", "a/*.proto"}, config.Includes)
+	for _, include := range []string{"", ".", "../a", "/a", "a/[b"} {
+		_, err := bufmoduleconfig.NewConfigV1(
+			bufmoduleconfig.ExternalConfigV1{
+				Includes: []string{include},
+			},
+		)
+		assert.Error(t, err, include)
+	}
+}
+
 func testNewConfigV1Beta1Success(t *testing.T, roots []string, excludes []string, deps []string) {
 	_, err := bufmoduleconfig.NewConfigV1Beta1(bufmoduleconfig.ExternalConfigV1Beta1{Roots: roots, Excludes: excludes}, deps...)
 	assert.NoError(t, err, fmt.Sprintf("%v %v %v", roots, excludes, deps))
@@ -466,12 +466,39 @@ func DependencyModulePinsForBucket(
 	return modulePins, nil
 }
 
+// ExtendsModulePinForBucket reads the pin of the module that the module configuration
+// extends from the lock file in the bucket, and returns nil if there is none.
+func ExtendsModulePinForBucket(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+) (ModulePin, error) {
+	lockFile, err := buflock.ReadConfig(ctx, readBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	if lockFile.Extends == nil {
+		return nil, nil
+	}
+	return NewModulePin(
+		lockFile.Extends.Remote,
+		lockFile.Extends.Owner,
+		lockFile.Extends.Repository,
+		lockFile.Extends.Commit,
+		lockFile.Extends.Digest,
+	)
+}
+
 // PutDependencyModulePinsToBucket writes the module dependencies to the write bucket in the form of a lock file.
 func PutDependencyModulePinsToBucket(
 	ctx context.Context,
 	writeBucket storage.WriteBucket,
 	modulePins []ModulePin,
+	options ...PutDependencyModulePinsOption,
 ) error {
+	putDependencyModulePinsOptions := &putDependencyModulePinsOptions{}
+	for _, option := range options {
+		option(putDependencyModulePinsOptions)
+	}
 	if err := ValidateModulePinsUniqueByIdentity(modulePins); err != nil {
 		return err
 	}
@@ -479,6 +506,15 @@ func PutDependencyModulePinsToBucket(
 	lockFile := &buflock.Config{
 		Dependencies: make([]buflock.Dependency, 0, len(modulePins)),
 	}
+	if extendsModulePin := putDependencyModulePinsOptions.extendsModulePin; extendsModulePin != nil {
+		lockFile.Extends = &buflock.Dependency{
+			Remote:     extendsModulePin.Remote(),
+			Owner:      extendsModulePin.Owner(),
+			Repository: extendsModulePin.Repository(),
+			Commit:     extendsModulePin.Commit(),
+			Digest:     extendsModulePin.Digest(),
+		}
+	}
 	for _, pin := range modulePins {
 		lockFile.Dependencies = append(
 			lockFile.Dependencies,
@@ -494,6 +530,19 @@ func PutDependencyModulePinsToBucket(
 	return buflock.WriteConfig(ctx, writeBucket, lockFile)
 }
 
+// PutDependencyModulePinsOption is an option for PutDependencyModulePinsToBucket.
+type PutDependencyModulePinsOption func(*putDependencyModulePinsOptions)
+
+// PutDependencyModulePinsWithExtendsModulePin returns a new PutDependencyModulePinsOption
+// that also writes the pin of the module that the module configuration extends.
+//
+// The default is to not write a pin, in which case any existing pin is removed.
+func PutDependencyModulePinsWithExtendsModulePin(extendsModulePin ModulePin) PutDependencyModulePinsOption {
+	return func(putDependencyModulePinsOptions *putDependencyModulePinsOptions) {
+		putDependencyModulePinsOptions.extendsModulePin = extendsModulePin
+	}
+}
+
 // SortFileInfos sorts the FileInfos by Path.
 //
 // This should be treated as the default sorting mechanism.
@@ -559,3 +608,7 @@ func (e *digestChangedError) Error() string {
 		e.updatedPin.Digest(),
 	)
 }
+
+type putDependencyModulePinsOptions struct {
+	extendsModulePin ModulePin
+}
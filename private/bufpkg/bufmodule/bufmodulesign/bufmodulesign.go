@@ -0,0 +1,141 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufmodulesign signs modules and verifies the signatures of modules.
+//
+// A signature is over the identity of the module and the digest of its manifest,
+// so it covers every file of the module, and cannot be reused for another module.
+// Signatures are made with ECDSA P-256 or Ed25519 keys in PEM format, such as the
+// keys generated by:
+//
+//	openssl genpkey -algorithm ed25519 -out private.pem
+//	openssl pkey -in private.pem -pubout -out public.pem
+//
+// Signatures are attached to a commit as tags, much like cosign attaches the
+// signatures of OCI images as tags, so that they are stored and served by any
+// registry without any support for signatures.
+package bufmodulesign
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+)
+
+// Signature is a signature of a module.
+type Signature struct {
+	// KeyID is the ID of the key that made the signature.
+	KeyID string
+	// Value is the value of the signature.
+	Value []byte
+}
+
+// SignatureToTag returns the tag that attaches the signature to a commit.
+func SignatureToTag(signature *Signature) string {
+	return signatureToTag(signature)
+}
+
+// TagToSignature returns the signature that the tag attaches to a commit.
+//
+// Returns false if the tag is not a signature tag.
+func TagToSignature(tag string) (*Signature, bool, error) {
+	return tagToSignature(tag)
+}
+
+// Signer signs modules.
+type Signer interface {
+	// KeyID returns the ID of the key of the Signer.
+	KeyID() string
+	// Sign signs the module with the identity and the digest of its manifest.
+	Sign(moduleIdentity bufmoduleref.ModuleIdentity, manifestDigest bufcas.Digest) (*Signature, error)
+}
+
+// NewSigner returns a new Signer for the PEM-encoded PKCS #8 private key.
+//
+// The key must be an ECDSA P-256 or Ed25519 key.
+func NewSigner(privateKeyPEM []byte) (Signer, error) {
+	return newSigner(privateKeyPEM)
+}
+
+// Verifier verifies the signatures of modules.
+type Verifier interface {
+	// Verify verifies that one of the signatures is a valid signature of the module
+	// with the identity and the digest of its manifest, made by a key of the Verifier.
+	//
+	// Returns the ID of the key that made the valid signature, or a *VerifyError if
+	// no signature is valid.
+	Verify(
+		moduleIdentity bufmoduleref.ModuleIdentity,
+		manifestDigest bufcas.Digest,
+		signatures []*Signature,
+	) (string, error)
+}
+
+// NewVerifier returns a new Verifier that trusts the PEM-encoded PKIX public keys.
+//
+// The keys must be ECDSA P-256 or Ed25519 keys, and at least one key is required.
+func NewVerifier(publicKeyPEMs ...[]byte) (Verifier, error) {
+	return newVerifier(publicKeyPEMs...)
+}
+
+// VerifyError is the error returned by Verifier.Verify if no signature is valid.
+type VerifyError struct {
+	// ModuleIdentity is the identity of the module.
+	ModuleIdentity bufmoduleref.ModuleIdentity
+	// SignatureCount is the number of signatures that were checked.
+	SignatureCount int
+}
+
+// Error implements error.
+func (e *VerifyError) Error() string {
+	if e.SignatureCount == 0 {
+		return fmt.Sprintf("module %s has no signatures", e.ModuleIdentity.IdentityString())
+	}
+	return fmt.Sprintf("module %s has no valid signature by a trusted key", e.ModuleIdentity.IdentityString())
+}
+
+// TrustRoots are the keys that modules must be signed with.
+type TrustRoots interface {
+	// VerifierForModuleIdentity returns the Verifier for the module, or nil
+	// if the module is not required to be signed.
+	VerifierForModuleIdentity(moduleIdentity bufmoduleref.ModuleIdentity) Verifier
+}
+
+// NewTrustRoots returns new TrustRoots for the PEM-encoded PKIX public keys of module
+// names.
+//
+// A module name is either of the form remote/owner, for all of the modules of the
+// owner, or remote/owner/repository. The modules are verified with the keys of the
+// most specific name that matches.
+func NewTrustRoots(moduleNameToPublicKeyPEMs map[string][][]byte) (TrustRoots, error) {
+	return newTrustRoots(moduleNameToPublicKeyPEMs)
+}
+
+// ValidateModuleName validates that the name is of the form remote/owner or
+// remote/owner/repository, as accepted by NewTrustRoots.
+func ValidateModuleName(moduleName string) error {
+	components := strings.Split(moduleName, "/")
+	if len(components) != 2 && len(components) != 3 {
+		return errors.New("must be of the form remote/owner or remote/owner/repository")
+	}
+	for _, component := range components {
+		if component == "" {
+			return errors.New("must be of the form remote/owner or remote/owner/repository")
+		}
+	}
+	return nil
+}
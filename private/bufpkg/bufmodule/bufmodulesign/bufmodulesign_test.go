@@ -0,0 +1,232 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulesign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	t.Parallel()
+	_, ed25519PrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecdsaPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	for _, privateKey := range []crypto.Signer{ed25519PrivateKey, ecdsaPrivateKey} {
+		privateKeyPEM, publicKeyPEM := testMarshalKey(t, privateKey)
+		signer, err := NewSigner(privateKeyPEM)
+		require.NoError(t, err)
+		moduleIdentity := testNewModuleIdentity(t, "buf.build/acme/weather")
+		manifestDigest := testNewDigest(t, "manifest")
+		signature, err := signer.Sign(moduleIdentity, manifestDigest)
+		require.NoError(t, err)
+		assert.Equal(t, signer.KeyID(), signature.KeyID)
+
+		// The signature survives being attached as a tag.
+		tag := SignatureToTag(signature)
+		assert.LessOrEqual(t, len(tag), 128)
+		tagSignature, ok, err := TagToSignature(tag)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, signature, tagSignature)
+
+		verifier, err := NewVerifier(publicKeyPEM)
+		require.NoError(t, err)
+		keyID, err := verifier.Verify(moduleIdentity, manifestDigest, []*Signature{signature})
+		require.NoError(t, err)
+		assert.Equal(t, signer.KeyID(), keyID)
+
+		// The signature is only valid for the module that was signed.
+		_, err = verifier.Verify(moduleIdentity, testNewDigest(t, "other manifest"), []*Signature{signature})
+		testAssertVerifyError(t, err, 1)
+		_, err = verifier.Verify(testNewModuleIdentity(t, "buf.build/acme/other"), manifestDigest, []*Signature{signature})
+		testAssertVerifyError(t, err, 1)
+		_, err = verifier.Verify(moduleIdentity, manifestDigest, nil)
+		testAssertVerifyError(t, err, 0)
+
+		// A signature by another key is not trusted.
+		_, otherPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		otherPrivateKeyPEM, _ := testMarshalKey(t, otherPrivateKey)
+		otherSigner, err := NewSigner(otherPrivateKeyPEM)
+		require.NoError(t, err)
+		otherSignature, err := otherSigner.Sign(moduleIdentity, manifestDigest)
+		require.NoError(t, err)
+		_, err = verifier.Verify(moduleIdentity, manifestDigest, []*Signature{otherSignature})
+		testAssertVerifyError(t, err, 1)
+		// A forged signature that claims the trusted key is not valid.
+		otherSignature.KeyID = signer.KeyID()
+		_, err = verifier.Verify(moduleIdentity, manifestDigest, []*Signature{otherSignature})
+		testAssertVerifyError(t, err, 1)
+		keyID, err = verifier.Verify(moduleIdentity, manifestDigest, []*Signature{otherSignature, signature})
+		require.NoError(t, err)
+		assert.Equal(t, signer.KeyID(), keyID)
+	}
+}
+
+func TestUnsupportedKeys(t *testing.T) {
+	t.Parallel()
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKeyPEM, publicKeyPEM := testMarshalKey(t, rsaPrivateKey)
+	_, err = NewSigner(privateKeyPEM)
+	assert.ErrorContains(t, err, "must be ECDSA P-256 or Ed25519")
+	_, err = NewVerifier(publicKeyPEM)
+	assert.ErrorContains(t, err, "must be ECDSA P-256 or Ed25519")
+	_, err = NewSigner(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("data")}))
+	assert.ErrorContains(t, err, "must be an unencrypted PKCS #8")
+	_, err = NewVerifier()
+	assert.Error(t, err)
+}
+
+func TestTagToSignature(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		tag         string
+		expected    *Signature
+		expectedOK  bool
+		expectedErr string
+	}{
+		{
+			tag: "v1.0.0",
+		},
+		{
+			tag:        "sig-0123456789abcdef-AAEC",
+			expected:   &Signature{KeyID: "0123456789abcdef", Value: []byte{0, 1, 2}},
+			expectedOK: true,
+		},
+		{
+			tag:         "sig-0123-AAEC",
+			expectedOK:  true,
+			expectedErr: "malformed signature tag",
+		},
+		{
+			tag:         "sig-0123456789abcdeg-AAEC",
+			expectedOK:  true,
+			expectedErr: "malformed key ID",
+		},
+		{
+			tag:         "sig-0123456789abcdef-A+EC",
+			expectedOK:  true,
+			expectedErr: "malformed signature",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.tag, func(t *testing.T) {
+			t.Parallel()
+			signature, ok, err := TagToSignature(testCase.tag)
+			assert.Equal(t, testCase.expectedOK, ok)
+			if testCase.expectedErr != "" {
+				assert.ErrorContains(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, signature)
+		})
+	}
+}
+
+func TestTrustRoots(t *testing.T) {
+	t.Parallel()
+	_, ownerPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, repositoryPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ownerPrivateKeyPEM, ownerPublicKeyPEM := testMarshalKey(t, ownerPrivateKey)
+	repositoryPrivateKeyPEM, repositoryPublicKeyPEM := testMarshalKey(t, repositoryPrivateKey)
+	trustRoots, err := NewTrustRoots(
+		map[string][][]byte{
+			"buf.build/acme":         {ownerPublicKeyPEM},
+			"buf.build/acme/weather": {repositoryPublicKeyPEM},
+		},
+	)
+	require.NoError(t, err)
+	assert.Nil(t, trustRoots.VerifierForModuleIdentity(testNewModuleIdentity(t, "buf.build/acmeco/weather")))
+	assert.Nil(t, trustRoots.VerifierForModuleIdentity(testNewModuleIdentity(t, "example.com/acme/weather")))
+	manifestDigest := testNewDigest(t, "manifest")
+	testCases := []struct {
+		moduleName    string
+		privateKeyPEM []byte
+		valid         bool
+	}{
+		{moduleName: "buf.build/acme/orders", privateKeyPEM: ownerPrivateKeyPEM, valid: true},
+		{moduleName: "buf.build/acme/orders", privateKeyPEM: repositoryPrivateKeyPEM},
+		// The most specific name wins.
+		{moduleName: "buf.build/acme/weather", privateKeyPEM: repositoryPrivateKeyPEM, valid: true},
+		{moduleName: "buf.build/acme/weather", privateKeyPEM: ownerPrivateKeyPEM},
+	}
+	for _, testCase := range testCases {
+		moduleIdentity := testNewModuleIdentity(t, testCase.moduleName)
+		signer, err := NewSigner(testCase.privateKeyPEM)
+		require.NoError(t, err)
+		signature, err := signer.Sign(moduleIdentity, manifestDigest)
+		require.NoError(t, err)
+		verifier := trustRoots.VerifierForModuleIdentity(moduleIdentity)
+		require.NotNil(t, verifier)
+		_, err = verifier.Verify(moduleIdentity, manifestDigest, []*Signature{signature})
+		if testCase.valid {
+			assert.NoError(t, err)
+		} else {
+			testAssertVerifyError(t, err, 1)
+		}
+	}
+	_, err = NewTrustRoots(map[string][][]byte{"buf.build": {ownerPublicKeyPEM}})
+	assert.ErrorContains(t, err, `invalid module name "buf.build"`)
+	_, err = NewTrustRoots(map[string][][]byte{"buf.build/acme": nil})
+	assert.ErrorContains(t, err, "no public keys")
+}
+
+func testAssertVerifyError(t *testing.T, err error, expectedSignatureCount int) {
+	var verifyError *VerifyError
+	require.True(t, errors.As(err, &verifyError), "expected *VerifyError, got %v", err)
+	assert.Equal(t, expectedSignatureCount, verifyError.SignatureCount)
+}
+
+func testMarshalKey(t *testing.T, privateKey crypto.Signer) ([]byte, []byte) {
+	privateKeyData, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicKeyData, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyData}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyData})
+}
+
+func testNewModuleIdentity(t *testing.T, moduleName string) bufmoduleref.ModuleIdentity {
+	moduleIdentity, err := bufmoduleref.ModuleIdentityForString(moduleName)
+	require.NoError(t, err)
+	return moduleIdentity
+}
+
+func testNewDigest(t *testing.T, content string) bufcas.Digest {
+	digest, err := bufcas.NewDigestForContent(bytes.NewReader([]byte(content)))
+	require.NoError(t, err)
+	return digest
+}
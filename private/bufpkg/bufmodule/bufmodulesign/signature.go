@@ -0,0 +1,224 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulesign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+)
+
+const (
+	// signatureTagPrefix is the prefix of signature tags, which are of the
+	// form sig-<key id>-<base64url signature>.
+	signatureTagPrefix = "sig-"
+	// keyIDLength is the length of key IDs, which are the first hex characters
+	// of the SHA-256 digest of the PKIX encoding of the public key.
+	keyIDLength = 16
+	// payloadHeader is the first line of the payload that is signed.
+	payloadHeader = "buf module signature v1"
+)
+
+func signatureToTag(signature *Signature) string {
+	return signatureTagPrefix + signature.KeyID + "-" + base64.RawURLEncoding.EncodeToString(signature.Value)
+}
+
+func tagToSignature(tag string) (*Signature, bool, error) {
+	if !strings.HasPrefix(tag, signatureTagPrefix) {
+		return nil, false, nil
+	}
+	rest := strings.TrimPrefix(tag, signatureTagPrefix)
+	if len(rest) < keyIDLength+1 || rest[keyIDLength] != '-' {
+		return nil, true, fmt.Errorf("malformed signature tag %q", tag)
+	}
+	keyID := rest[:keyIDLength]
+	if _, err := hex.DecodeString(keyID); err != nil {
+		return nil, true, fmt.Errorf("malformed key ID in signature tag %q", tag)
+	}
+	value, err := base64.RawURLEncoding.DecodeString(rest[keyIDLength+1:])
+	if err != nil {
+		return nil, true, fmt.Errorf("malformed signature in signature tag %q: %w", tag, err)
+	}
+	return &Signature{
+		KeyID: keyID,
+		Value: value,
+	}, true, nil
+}
+
+type signer struct {
+	privateKey crypto.Signer
+	keyID      string
+}
+
+func newSigner(privateKeyPEM []byte) (*signer, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM data found in private key")
+	}
+	if block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("unsupported PEM block type %q for private key, must be an unencrypted PKCS #8 %q", block.Type, "PRIVATE KEY")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if err := validatePublicKey(privateKey.Public()); err != nil {
+		return nil, err
+	}
+	keyID, err := getKeyID(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &signer{
+		privateKey: privateKey,
+		keyID:      keyID,
+	}, nil
+}
+
+func (s *signer) KeyID() string {
+	return s.keyID
+}
+
+func (s *signer) Sign(moduleIdentity bufmoduleref.ModuleIdentity, manifestDigest bufcas.Digest) (*Signature, error) {
+	payload := getPayload(moduleIdentity, manifestDigest)
+	var value []byte
+	var err error
+	switch privateKey := s.privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(payload)
+		value, err = ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+	case ed25519.PrivateKey:
+		value = ed25519.Sign(privateKey, payload)
+	default:
+		err = fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{
+		KeyID: s.keyID,
+		Value: value,
+	}, nil
+}
+
+type verifier struct {
+	keyIDToPublicKey map[string]crypto.PublicKey
+}
+
+func newVerifier(publicKeyPEMs ...[]byte) (*verifier, error) {
+	if len(publicKeyPEMs) == 0 {
+		return nil, errors.New("no public keys")
+	}
+	keyIDToPublicKey := make(map[string]crypto.PublicKey, len(publicKeyPEMs))
+	for _, publicKeyPEM := range publicKeyPEMs {
+		block, _ := pem.Decode(publicKeyPEM)
+		if block == nil {
+			return nil, errors.New("no PEM data found in public key")
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unsupported PEM block type %q for public key, must be %q", block.Type, "PUBLIC KEY")
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if err := validatePublicKey(publicKey); err != nil {
+			return nil, err
+		}
+		keyID, err := getKeyID(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		keyIDToPublicKey[keyID] = publicKey
+	}
+	return &verifier{
+		keyIDToPublicKey: keyIDToPublicKey,
+	}, nil
+}
+
+func (v *verifier) Verify(
+	moduleIdentity bufmoduleref.ModuleIdentity,
+	manifestDigest bufcas.Digest,
+	signatures []*Signature,
+) (string, error) {
+	payload := getPayload(moduleIdentity, manifestDigest)
+	for _, signature := range signatures {
+		publicKey, ok := v.keyIDToPublicKey[signature.KeyID]
+		if !ok {
+			continue
+		}
+		var valid bool
+		switch publicKey := publicKey.(type) {
+		case *ecdsa.PublicKey:
+			digest := sha256.Sum256(payload)
+			valid = ecdsa.VerifyASN1(publicKey, digest[:], signature.Value)
+		case ed25519.PublicKey:
+			valid = ed25519.Verify(publicKey, payload, signature.Value)
+		}
+		if valid {
+			return signature.KeyID, nil
+		}
+	}
+	return "", &VerifyError{
+		ModuleIdentity: moduleIdentity,
+		SignatureCount: len(signatures),
+	}
+}
+
+// getPayload returns the payload that is signed for the module.
+func getPayload(moduleIdentity bufmoduleref.ModuleIdentity, manifestDigest bufcas.Digest) []byte {
+	return []byte(payloadHeader + "\n" + moduleIdentity.IdentityString() + "\n" + manifestDigest.String() + "\n")
+}
+
+func validatePublicKey(publicKey crypto.PublicKey) error {
+	switch publicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if publicKey.Curve != elliptic.P256() {
+			return fmt.Errorf("unsupported ECDSA curve %s, must be P-256", publicKey.Curve.Params().Name)
+		}
+		return nil
+	case ed25519.PublicKey:
+		return nil
+	default:
+		// RSA signatures are too large to be attached as tags.
+		return fmt.Errorf("unsupported key type %T, must be ECDSA P-256 or Ed25519", publicKey)
+	}
+}
+
+func getKeyID(publicKey crypto.PublicKey) (string, error) {
+	data, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])[:keyIDLength], nil
+}
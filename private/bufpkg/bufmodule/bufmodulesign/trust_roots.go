@@ -0,0 +1,49 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulesign
+
+import (
+	"fmt"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+)
+
+type trustRoots struct {
+	moduleNameToVerifier map[string]Verifier
+}
+
+func newTrustRoots(moduleNameToPublicKeyPEMs map[string][][]byte) (*trustRoots, error) {
+	moduleNameToVerifier := make(map[string]Verifier, len(moduleNameToPublicKeyPEMs))
+	for moduleName, publicKeyPEMs := range moduleNameToPublicKeyPEMs {
+		if err := ValidateModuleName(moduleName); err != nil {
+			return nil, fmt.Errorf("invalid module name %q: %w", moduleName, err)
+		}
+		verifier, err := newVerifier(publicKeyPEMs...)
+		if err != nil {
+			return nil, fmt.Errorf("module name %q: %w", moduleName, err)
+		}
+		moduleNameToVerifier[moduleName] = verifier
+	}
+	return &trustRoots{
+		moduleNameToVerifier: moduleNameToVerifier,
+	}, nil
+}
+
+func (t *trustRoots) VerifierForModuleIdentity(moduleIdentity bufmoduleref.ModuleIdentity) Verifier {
+	if verifier, ok := t.moduleNameToVerifier[moduleIdentity.IdentityString()]; ok {
+		return verifier
+	}
+	return t.moduleNameToVerifier[moduleIdentity.Remote()+"/"+moduleIdentity.Owner()]
+}
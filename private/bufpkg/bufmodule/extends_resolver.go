@@ -0,0 +1,102 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodule
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/bufbreakingconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/buflock"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/storage"
+)
+
+// extendsIgnoredKeys are the keys of the breaking and lint configuration of a
+// module that are not extended, as the paths are of the files of the module.
+var extendsIgnoredKeys = []string{
+	"ignore",
+	"ignore_only",
+}
+
+type extendsResolver struct {
+	moduleReader ModuleReader
+}
+
+func newExtendsResolver(moduleReader ModuleReader) *extendsResolver {
+	return &extendsResolver{
+		moduleReader: moduleReader,
+	}
+}
+
+func (e *extendsResolver) GetExtendsConfigDefaults(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	moduleReference bufmoduleref.ModuleReference,
+) (*bufconfig.ConfigDefaults, error) {
+	var modulePin bufmoduleref.ModulePin
+	if readBucket != nil {
+		var err error
+		modulePin, err = bufmoduleref.ExtendsModulePinForBucket(ctx, readBucket)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if modulePin == nil || modulePin.IdentityString() != moduleReference.IdentityString() {
+		return nil, fmt.Errorf(
+			`extends %q is not pinned in %s, run "buf mod update" to pin it`,
+			moduleReference.String(),
+			buflock.ExternalConfigFilePath,
+		)
+	}
+	module, err := e.moduleReader.GetModule(ctx, modulePin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s for extends: %w", modulePin.String(), err)
+	}
+	configDefaults := &bufconfig.ConfigDefaults{}
+	if breakingConfig := module.BreakingConfig(); breakingConfig != nil {
+		configDefaults.Breaking, err = newExtendsConfigDefaultsSection(bufbreakingconfig.ExternalConfigV1ForConfig(breakingConfig))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if lintConfig := module.LintConfig(); lintConfig != nil {
+		configDefaults.Lint, err = newExtendsConfigDefaultsSection(buflintconfig.ExternalConfigV1ForConfig(lintConfig))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return configDefaults, nil
+}
+
+// newExtendsConfigDefaultsSection returns the keys of the given external
+// configuration that are set.
+func newExtendsConfigDefaultsSection(externalConfig interface{}) (map[string]interface{}, error) {
+	data, err := encoding.MarshalYAML(externalConfig)
+	if err != nil {
+		return nil, err
+	}
+	section := make(map[string]interface{})
+	if err := encoding.UnmarshalYAMLNonStrict(data, &section); err != nil {
+		return nil, err
+	}
+	for _, key := range extendsIgnoredKeys {
+		delete(section, key)
+	}
+	return section, nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufopenapi converts the services of an image to OpenAPI v3 documents.
+//
+// A method that has a google.api.http annotation is mapped to the operations of
+// its HTTP rule and additional bindings, with the variables of the path template
+// as path parameters, and the fields of the request that are neither bound to
+// the path nor to the body as query parameters.
+//
+// A method without a google.api.http annotation is mapped to a POST operation on
+// /<package>.<Service>/<Method> that takes the JSON of the request as body and
+// returns the JSON of the response, as with the Connect protocol.
+//
+// Streaming methods cannot be described by OpenAPI and are not included.
+package bufopenapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+)
+
+const (
+	// FormatYAML is the YAML format.
+	FormatYAML Format = 1
+	// FormatJSON is the JSON format.
+	FormatJSON Format = 2
+
+	// Version is the version of OpenAPI that documents conform to.
+	Version = "3.0.3"
+
+	// DefaultInfoVersion is the default version of the API of documents.
+	DefaultInfoVersion = "0.0.1"
+)
+
+var (
+	// AllFormatsString is the string representation of all Formats.
+	AllFormatsString = stringutil.SliceToString([]string{FormatYAML.String(), FormatJSON.String()})
+)
+
+// Format is a document format.
+type Format int
+
+// ParseFormat parses the format.
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "yaml":
+		return FormatYAML, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return 0, fmt.Errorf("unknown format: %s", s)
+	}
+}
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	switch f {
+	case FormatYAML:
+		return "yaml"
+	case FormatJSON:
+		return "json"
+	default:
+		return strconv.Itoa(int(f))
+	}
+}
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi" yaml:"openapi"`
+	Info       *Info                `json:"info" yaml:"info"`
+	Tags       []*Tag               `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Paths      map[string]*PathItem `json:"paths" yaml:"paths"`
+	Components *Components          `json:"components,omitempty" yaml:"components,omitempty"`
+}
+
+// Info is the metadata of the API of a document.
+type Info struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version" yaml:"version"`
+}
+
+// Tag is a tag of operations.
+//
+// The operations of a service are tagged with the fully-qualified name of the service.
+type Tag struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// PathItem is the operations on a path.
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty" yaml:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty" yaml:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty" yaml:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty" yaml:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty" yaml:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty" yaml:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty" yaml:"patch,omitempty"`
+	Trace   *Operation `json:"trace,omitempty" yaml:"trace,omitempty"`
+}
+
+// Operation is an operation on a path.
+type Operation struct {
+	Tags        []string             `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	OperationID string               `json:"operationId" yaml:"operationId"`
+	Parameters  []*Parameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+}
+
+// Parameter is a path or query parameter of an operation.
+type Parameter struct {
+	Name        string  `json:"name" yaml:"name"`
+	In          string  `json:"in" yaml:"in"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *Schema `json:"schema" yaml:"schema"`
+}
+
+// RequestBody is the body of the request of an operation.
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty" yaml:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content" yaml:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string                `json:"description" yaml:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// MediaType is the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema" yaml:"schema"`
+}
+
+// Components are the schemas that are referenced by the operations of a document.
+type Components struct {
+	// Schemas are keyed by the fully-qualified name of the message or enum.
+	Schemas map[string]*Schema `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+}
+
+// Schema is the schema of the JSON of a message, enum, or field.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+}
+
+// Generate generates a Document for each service of the files of the image that
+// are not imports, keyed by the fully-qualified name of the service.
+//
+// The title of the Info of each Document is the fully-qualified name of the service,
+// and the description is the comments of the service.
+func Generate(image bufimage.Image, options ...GenerateOption) (map[string]*Document, error) {
+	return generate(image, options...)
+}
+
+// GenerateOption is an option for Generate.
+type GenerateOption func(*generateOptions)
+
+// GenerateWithServiceNames says to only generate Documents for the services with
+// the given fully-qualified names.
+//
+// An error is returned if any of the services is not declared in the image.
+func GenerateWithServiceNames(serviceNames ...string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.serviceNames = append(generateOptions.serviceNames, serviceNames...)
+	}
+}
+
+// GenerateWithInfoVersion sets the version of the Info of the Documents.
+//
+// The default is DefaultInfoVersion.
+func GenerateWithInfoVersion(infoVersion string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.infoVersion = infoVersion
+	}
+}
+
+// Merge merges the paths, tags, and schemas of the Documents into a single Document
+// with the given Info.
+//
+// An error is returned if two of the Documents declare an operation with the same
+// method on the same path.
+func Merge(info *Info, documents ...*Document) (*Document, error) {
+	return merge(info, documents...)
+}
+
+// Marshal marshals the Document in the given format.
+func Marshal(document *Document, format Format) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return encoding.MarshalYAML(document)
+	case FormatJSON:
+		data, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown format: %v", format)
+	}
+}
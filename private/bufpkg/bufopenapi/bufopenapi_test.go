@@ -0,0 +1,309 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufopenapi
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHTTPProto = `syntax = "proto3";
+
+package google.api;
+
+message HttpRule {
+  string selector = 1;
+  oneof pattern {
+    string get = 2;
+    string put = 3;
+    string post = 4;
+    string delete = 5;
+    string patch = 6;
+    CustomHttpPattern custom = 8;
+  }
+  string body = 7;
+  string response_body = 12;
+  repeated HttpRule additional_bindings = 11;
+}
+
+message CustomHttpPattern {
+  string kind = 1;
+  string path = 2;
+}
+`
+
+const testAnnotationsProto = `syntax = "proto3";
+
+package google.api;
+
+import "google/api/http.proto";
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.MethodOptions {
+  HttpRule http = 72295728;
+}
+`
+
+const testOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/api/annotations.proto";
+import "google/protobuf/timestamp.proto";
+
+// Manages orders.
+service OrderService {
+  // Gets an order.
+  rpc GetOrder(GetOrderRequest) returns (Order) {
+    option (google.api.http) = {
+      get: "/v1/{name=orders/*}"
+      additional_bindings {
+        get: "/v1/shops/{shop_id}/{name=orders/*}"
+      }
+    };
+  }
+  rpc UpdateOrder(UpdateOrderRequest) returns (Order) {
+    option (google.api.http) = {
+      patch: "/v1/{order.name=orders/*}"
+      body: "order"
+    };
+  }
+  rpc CancelOrder(CancelOrderRequest) returns (CancelOrderResponse) {
+    option deprecated = true;
+  }
+  rpc WatchOrders(CancelOrderRequest) returns (stream Order);
+}
+
+message GetOrderRequest {
+  // The name of the order.
+  string name = 1;
+  string shop_id = 2;
+  repeated string fields = 3;
+  Order filter = 4;
+}
+
+message UpdateOrderRequest {
+  Order order = 1;
+  bool validate_only = 2;
+}
+
+message CancelOrderRequest {
+  string name = 1;
+}
+
+message CancelOrderResponse {}
+
+// An order.
+message Order {
+  string name = 1;
+  int64 quantity = 2;
+  Status status = 3;
+  google.protobuf.Timestamp create_time = 4;
+  map<string, string> labels = 5;
+  repeated Order children = 6;
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OPEN = 1;
+}
+`
+
+const testShopProto = `syntax = "proto3";
+
+package acme.v1;
+
+service ShopService {
+  rpc GetShop(GetShopRequest) returns (Shop);
+}
+
+message GetShopRequest {
+  string name = 1;
+}
+
+message Shop {
+  string name = 1;
+}
+`
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	image := testGetImage(t)
+	serviceNameToDocument, err := Generate(image, GenerateWithInfoVersion("v1"))
+	require.NoError(t, err)
+	require.Len(t, serviceNameToDocument, 2)
+
+	document := serviceNameToDocument["acme.v1.OrderService"]
+	require.NotNil(t, document)
+	assert.Equal(t, &Info{Title: "acme.v1.OrderService", Description: "Manages orders.", Version: "v1"}, document.Info)
+	assert.Len(t, document.Paths, 4)
+
+	getOrder := document.Paths["/v1/{name}"].Get
+	require.NotNil(t, getOrder)
+	assert.Equal(t, "acme.v1.OrderService.GetOrder", getOrder.OperationID)
+	assert.Equal(t, "Gets an order.", getOrder.Description)
+	assert.Nil(t, getOrder.RequestBody)
+	assert.Equal(
+		t,
+		[]*Parameter{
+			{Name: "name", In: "path", Description: "The name of the order.", Required: true, Schema: &Schema{Type: "string", Description: "The name of the order."}},
+			{Name: "shopId", In: "query", Schema: &Schema{Type: "string"}},
+			{Name: "fields", In: "query", Schema: &Schema{Type: "array", Items: &Schema{Type: "string"}}},
+		},
+		getOrder.Parameters,
+	)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/acme.v1.Order"}, getOrder.Responses["200"].Content["application/json"].Schema)
+
+	getOrderAdditionalBinding := document.Paths["/v1/shops/{shop_id}/{name}"].Get
+	require.NotNil(t, getOrderAdditionalBinding)
+	assert.Equal(t, "acme.v1.OrderService.GetOrder_1", getOrderAdditionalBinding.OperationID)
+	require.Len(t, getOrderAdditionalBinding.Parameters, 3)
+	assert.Equal(t, "path", getOrderAdditionalBinding.Parameters[1].In)
+
+	updateOrder := document.Paths["/v1/{order.name}"].Patch
+	require.NotNil(t, updateOrder)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/acme.v1.Order"}, updateOrder.RequestBody.Content["application/json"].Schema)
+	require.Len(t, updateOrder.Parameters, 2)
+	assert.Equal(t, "order.name", updateOrder.Parameters[0].Name)
+	assert.Equal(t, "validateOnly", updateOrder.Parameters[1].Name)
+
+	cancelOrder := document.Paths["/acme.v1.OrderService/CancelOrder"].Post
+	require.NotNil(t, cancelOrder)
+	assert.True(t, cancelOrder.Deprecated)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/acme.v1.CancelOrderRequest"}, cancelOrder.RequestBody.Content["application/json"].Schema)
+
+	require.NotNil(t, document.Components)
+	assert.Equal(
+		t,
+		&Schema{
+			Type:        "object",
+			Description: "An order.",
+			Properties: map[string]*Schema{
+				"name":       {Type: "string"},
+				"quantity":   {Type: "string", Format: "int64"},
+				"status":     {Ref: "#/components/schemas/acme.v1.Status"},
+				"createTime": {Type: "string", Format: "date-time"},
+				"labels":     {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+				"children":   {Type: "array", Items: &Schema{Ref: "#/components/schemas/acme.v1.Order"}},
+			},
+		},
+		document.Components.Schemas["acme.v1.Order"],
+	)
+	assert.Equal(
+		t,
+		&Schema{Type: "string", Enum: []string{"STATUS_UNSPECIFIED", "STATUS_OPEN"}},
+		document.Components.Schemas["acme.v1.Status"],
+	)
+
+	serviceNameToDocument, err = Generate(image, GenerateWithServiceNames("acme.v1.ShopService"))
+	require.NoError(t, err)
+	require.Len(t, serviceNameToDocument, 1)
+	assert.Equal(t, DefaultInfoVersion, serviceNameToDocument["acme.v1.ShopService"].Info.Version)
+	_, err = Generate(image, GenerateWithServiceNames("acme.v1.Unknown"))
+	require.EqualError(t, err, `service "acme.v1.Unknown" is not declared in the input`)
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+	serviceNameToDocument, err := Generate(testGetImage(t))
+	require.NoError(t, err)
+	document, err := Merge(
+		&Info{Title: "Acme", Version: "v1"},
+		serviceNameToDocument["acme.v1.OrderService"],
+		serviceNameToDocument["acme.v1.ShopService"],
+	)
+	require.NoError(t, err)
+	assert.Len(t, document.Paths, 5)
+	assert.Len(t, document.Tags, 2)
+	assert.Contains(t, document.Components.Schemas, "acme.v1.Order")
+	assert.Contains(t, document.Components.Schemas, "acme.v1.Shop")
+	_, err = Merge(
+		&Info{Title: "Acme", Version: "v1"},
+		serviceNameToDocument["acme.v1.ShopService"],
+		serviceNameToDocument["acme.v1.ShopService"],
+	)
+	require.EqualError(t, err, "acme.v1.ShopService.GetShop and acme.v1.ShopService.GetShop are both mapped to POST /acme.v1.ShopService/GetShop")
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+	document := &Document{
+		OpenAPI: Version,
+		Info:    &Info{Title: "Acme", Version: "v1"},
+		Paths: map[string]*PathItem{
+			"/v1/{name}": {
+				Get: &Operation{
+					OperationID: "acme.v1.OrderService.GetOrder",
+					Responses: map[string]*Response{
+						"200": {Description: "OK"},
+					},
+				},
+			},
+		},
+	}
+	data, err := Marshal(document, FormatYAML)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`openapi: 3.0.3
+info:
+  title: Acme
+  version: v1
+paths:
+  /v1/{name}:
+    get:
+      operationId: acme.v1.OrderService.GetOrder
+      responses:
+        "200":
+          description: OK
+`,
+		string(data),
+	)
+	data, err = Marshal(document, FormatJSON)
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{"openapi":"3.0.3","info":{"title":"Acme","version":"v1"},"paths":{"/v1/{name}":{"get":{"operationId":"acme.v1.OrderService.GetOrder","responses":{"200":{"description":"OK"}}}}}}`,
+		string(data),
+	)
+}
+
+func TestParsePathTemplate(t *testing.T) {
+	t.Parallel()
+	path, fieldPaths, err := parsePathTemplate("/v1/{name=shelves/*/books/**}:publish")
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/{name}:publish", path)
+	assert.Equal(t, []string{"name"}, fieldPaths)
+	_, _, err = parsePathTemplate("/v1/{name")
+	require.Error(t, err)
+	_, _, err = parsePathTemplate("v1")
+	require.Error(t, err)
+}
+
+func testGetImage(t *testing.T) bufimage.Image {
+	return bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"google/api/http.proto":        testHTTPProto,
+			"google/api/annotations.proto": testAnnotationsProto,
+			"acme/v1/order.proto":          testOrderProto,
+			"acme/v1/shop.proto":           testShopProto,
+		},
+	)
+}
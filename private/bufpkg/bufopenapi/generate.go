@@ -0,0 +1,710 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufopenapi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	schemaRefPrefix     = "#/components/schemas/"
+	jsonContentType     = "application/json"
+	okStatusCode        = "200"
+	okStatusDescription = "OK"
+)
+
+func generate(image bufimage.Image, options ...GenerateOption) (map[string]*Document, error) {
+	generateOptions := newGenerateOptions()
+	for _, option := range options {
+		option(generateOptions)
+	}
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	serviceNameToFound := make(map[string]bool, len(generateOptions.serviceNames))
+	for _, serviceName := range generateOptions.serviceNames {
+		serviceNameToFound[strings.TrimPrefix(serviceName, ".")] = false
+	}
+	serviceNameToDocument := make(map[string]*Document)
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptor, err := files.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		serviceDescriptors := fileDescriptor.Services()
+		for i := 0; i < serviceDescriptors.Len(); i++ {
+			serviceDescriptor := serviceDescriptors.Get(i)
+			serviceName := string(serviceDescriptor.FullName())
+			if len(serviceNameToFound) > 0 {
+				if _, ok := serviceNameToFound[serviceName]; !ok {
+					continue
+				}
+				serviceNameToFound[serviceName] = true
+			}
+			document, err := newServiceDocument(serviceDescriptor, generateOptions.infoVersion)
+			if err != nil {
+				return nil, err
+			}
+			serviceNameToDocument[serviceName] = document
+		}
+	}
+	for _, serviceName := range generateOptions.serviceNames {
+		if !serviceNameToFound[strings.TrimPrefix(serviceName, ".")] {
+			return nil, fmt.Errorf("service %q is not declared in the input", serviceName)
+		}
+	}
+	return serviceNameToDocument, nil
+}
+
+func newServiceDocument(serviceDescriptor protoreflect.ServiceDescriptor, infoVersion string) (*Document, error) {
+	serviceName := string(serviceDescriptor.FullName())
+	description := getDescription(serviceDescriptor)
+	generator := &generator{
+		paths:   make(map[string]*PathItem),
+		schemas: make(map[string]*Schema),
+	}
+	methodDescriptors := serviceDescriptor.Methods()
+	for i := 0; i < methodDescriptors.Len(); i++ {
+		if err := generator.addMethod(serviceName, methodDescriptors.Get(i)); err != nil {
+			return nil, err
+		}
+	}
+	document := &Document{
+		OpenAPI: Version,
+		Info: &Info{
+			Title:       serviceName,
+			Description: description,
+			Version:     infoVersion,
+		},
+		Tags: []*Tag{
+			{
+				Name:        serviceName,
+				Description: description,
+			},
+		},
+		Paths: generator.paths,
+	}
+	if len(generator.schemas) > 0 {
+		document.Components = &Components{
+			Schemas: generator.schemas,
+		}
+	}
+	return document, nil
+}
+
+type generator struct {
+	paths   map[string]*PathItem
+	schemas map[string]*Schema
+}
+
+func (g *generator) addMethod(serviceName string, methodDescriptor protoreflect.MethodDescriptor) error {
+	if methodDescriptor.IsStreamingClient() || methodDescriptor.IsStreamingServer() {
+		return nil
+	}
+	operationID := string(methodDescriptor.FullName())
+	httpRule, err := getHTTPRule(methodDescriptor)
+	if err != nil {
+		return err
+	}
+	if httpRule == nil {
+		operation := g.newOperation(serviceName, operationID, methodDescriptor)
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				jsonContentType: {
+					Schema: g.getMessageSchema(methodDescriptor.Input()),
+				},
+			},
+		}
+		operation.Responses[okStatusCode].Content = map[string]*MediaType{
+			jsonContentType: {
+				Schema: g.getMessageSchema(methodDescriptor.Output()),
+			},
+		}
+		return g.addOperation(
+			"POST",
+			"/"+serviceName+"/"+string(methodDescriptor.Name()),
+			operation,
+		)
+	}
+	if err := g.addHTTPRule(serviceName, operationID, methodDescriptor, httpRule); err != nil {
+		return err
+	}
+	for i, additionalBinding := range httpRule.GetAdditionalBindings() {
+		if err := g.addHTTPRule(
+			serviceName,
+			operationID+"_"+strconv.Itoa(i+1),
+			methodDescriptor,
+			additionalBinding,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) addHTTPRule(
+	serviceName string,
+	operationID string,
+	methodDescriptor protoreflect.MethodDescriptor,
+	httpRule *annotations.HttpRule,
+) error {
+	httpMethod, pathTemplate, err := getHTTPMethodAndPathTemplate(httpRule)
+	if err != nil {
+		return fmt.Errorf("%s: %w", methodDescriptor.FullName(), err)
+	}
+	path, pathFieldPaths, err := parsePathTemplate(pathTemplate)
+	if err != nil {
+		return fmt.Errorf("%s: %w", methodDescriptor.FullName(), err)
+	}
+	inputDescriptor := methodDescriptor.Input()
+	operation := g.newOperation(serviceName, operationID, methodDescriptor)
+	for _, pathFieldPath := range pathFieldPaths {
+		fieldDescriptor, err := getField(inputDescriptor, pathFieldPath)
+		if err != nil {
+			return fmt.Errorf("%s: path %q: %w", methodDescriptor.FullName(), pathTemplate, err)
+		}
+		operation.Parameters = append(
+			operation.Parameters,
+			&Parameter{
+				Name:        pathFieldPath,
+				In:          "path",
+				Description: getDescription(fieldDescriptor),
+				Required:    true,
+				Schema:      g.getFieldSchema(fieldDescriptor),
+			},
+		)
+	}
+	boundFieldPaths := pathFieldPaths
+	switch body := httpRule.GetBody(); body {
+	case "":
+	case "*":
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				jsonContentType: {
+					Schema: g.getMessageSchema(inputDescriptor),
+				},
+			},
+		}
+	default:
+		fieldDescriptor, err := getField(inputDescriptor, body)
+		if err != nil {
+			return fmt.Errorf("%s: body %q: %w", methodDescriptor.FullName(), body, err)
+		}
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				jsonContentType: {
+					Schema: g.getFieldSchema(fieldDescriptor),
+				},
+			},
+		}
+		boundFieldPaths = append(boundFieldPaths, body)
+	}
+	if httpRule.GetBody() != "*" {
+		operation.Parameters = append(
+			operation.Parameters,
+			g.getQueryParameters(inputDescriptor, boundFieldPaths)...,
+		)
+	}
+	responseSchema := g.getMessageSchema(methodDescriptor.Output())
+	if responseBody := httpRule.GetResponseBody(); responseBody != "" {
+		fieldDescriptor, err := getField(methodDescriptor.Output(), responseBody)
+		if err != nil {
+			return fmt.Errorf("%s: response body %q: %w", methodDescriptor.FullName(), responseBody, err)
+		}
+		responseSchema = g.getFieldSchema(fieldDescriptor)
+	}
+	operation.Responses[okStatusCode].Content = map[string]*MediaType{
+		jsonContentType: {
+			Schema: responseSchema,
+		},
+	}
+	return g.addOperation(httpMethod, path, operation)
+}
+
+func (g *generator) newOperation(
+	serviceName string,
+	operationID string,
+	methodDescriptor protoreflect.MethodDescriptor,
+) *Operation {
+	operation := &Operation{
+		Tags:        []string{serviceName},
+		Description: getDescription(methodDescriptor),
+		OperationID: operationID,
+		Responses: map[string]*Response{
+			okStatusCode: {
+				Description: okStatusDescription,
+			},
+		},
+	}
+	if methodOptions, ok := methodDescriptor.Options().(*descriptorpb.MethodOptions); ok {
+		operation.Deprecated = methodOptions.GetDeprecated()
+	}
+	return operation
+}
+
+func (g *generator) addOperation(httpMethod string, path string, operation *Operation) error {
+	pathItem, ok := g.paths[path]
+	if !ok {
+		pathItem = &PathItem{}
+		g.paths[path] = pathItem
+	}
+	operationPtr := pathItem.operationPtr(httpMethod)
+	if operationPtr == nil {
+		return fmt.Errorf("%s: HTTP method %q is not supported by OpenAPI", operation.OperationID, httpMethod)
+	}
+	if *operationPtr != nil {
+		return fmt.Errorf(
+			"%s and %s are both mapped to %s %s",
+			(*operationPtr).OperationID,
+			operation.OperationID,
+			httpMethod,
+			path,
+		)
+	}
+	*operationPtr = operation
+	return nil
+}
+
+// getQueryParameters returns the query parameters for the fields of the message that
+// are not bound to the path or the body.
+//
+// Only fields that are represented as a single JSON value, or as a list of them, can
+// be query parameters.
+func (g *generator) getQueryParameters(
+	messageDescriptor protoreflect.MessageDescriptor,
+	boundFieldPaths []string,
+) []*Parameter {
+	var parameters []*Parameter
+	fieldDescriptors := messageDescriptor.Fields()
+	for i := 0; i < fieldDescriptors.Len(); i++ {
+		fieldDescriptor := fieldDescriptors.Get(i)
+		if isBoundField(fieldDescriptor, boundFieldPaths) || fieldDescriptor.IsMap() {
+			continue
+		}
+		if fieldDescriptor.Message() != nil {
+			if _, ok := getWellKnownTypeSchema(fieldDescriptor.Message()); !ok {
+				continue
+			}
+		}
+		parameters = append(
+			parameters,
+			&Parameter{
+				Name:        fieldDescriptor.JSONName(),
+				In:          "query",
+				Description: getDescription(fieldDescriptor),
+				Schema:      g.getFieldSchema(fieldDescriptor),
+			},
+		)
+	}
+	return parameters
+}
+
+func (g *generator) getFieldSchema(fieldDescriptor protoreflect.FieldDescriptor) *Schema {
+	var schema *Schema
+	switch {
+	case fieldDescriptor.IsMap():
+		schema = &Schema{
+			Type:                 "object",
+			AdditionalProperties: g.getSingularFieldSchema(fieldDescriptor.MapValue()),
+		}
+	case fieldDescriptor.IsList():
+		schema = &Schema{
+			Type:  "array",
+			Items: g.getSingularFieldSchema(fieldDescriptor),
+		}
+	default:
+		schema = g.getSingularFieldSchema(fieldDescriptor)
+	}
+	// Siblings of $ref are ignored by OpenAPI v3.0.
+	if schema.Ref == "" {
+		schema.Description = getDescription(fieldDescriptor)
+		if fieldOptions, ok := fieldDescriptor.Options().(*descriptorpb.FieldOptions); ok {
+			schema.Deprecated = fieldOptions.GetDeprecated()
+		}
+	}
+	return schema
+}
+
+func (g *generator) getSingularFieldSchema(fieldDescriptor protoreflect.FieldDescriptor) *Schema {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.BoolKind:
+		return &Schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Schema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &Schema{Type: "integer", Format: "uint32"}
+	// 64-bit integers are strings in JSON.
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &Schema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Schema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &Schema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &Schema{Type: "number", Format: "double"}
+	case protoreflect.StringKind:
+		return &Schema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &Schema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		return g.getEnumSchema(fieldDescriptor.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.getMessageSchema(fieldDescriptor.Message())
+	default:
+		return &Schema{}
+	}
+}
+
+// getMessageSchema returns a reference to the schema of the message, and adds the
+// schema to the components if it was not already added.
+//
+// The well-known types that have a special JSON representation are not referenced,
+// and their schema is returned instead.
+func (g *generator) getMessageSchema(messageDescriptor protoreflect.MessageDescriptor) *Schema {
+	if schema, ok := getWellKnownTypeSchema(messageDescriptor); ok {
+		return schema
+	}
+	name := string(messageDescriptor.FullName())
+	if _, ok := g.schemas[name]; !ok {
+		schema := &Schema{
+			Type:        "object",
+			Description: getDescription(messageDescriptor),
+		}
+		// The schema is added before the fields so that recursive messages terminate.
+		g.schemas[name] = schema
+		fieldDescriptors := messageDescriptor.Fields()
+		if fieldDescriptors.Len() > 0 {
+			schema.Properties = make(map[string]*Schema, fieldDescriptors.Len())
+		}
+		for i := 0; i < fieldDescriptors.Len(); i++ {
+			fieldDescriptor := fieldDescriptors.Get(i)
+			schema.Properties[fieldDescriptor.JSONName()] = g.getFieldSchema(fieldDescriptor)
+		}
+	}
+	return &Schema{Ref: schemaRefPrefix + name}
+}
+
+func (g *generator) getEnumSchema(enumDescriptor protoreflect.EnumDescriptor) *Schema {
+	if enumDescriptor.FullName() == "google.protobuf.NullValue" {
+		return &Schema{Nullable: true}
+	}
+	name := string(enumDescriptor.FullName())
+	if _, ok := g.schemas[name]; !ok {
+		enumValueDescriptors := enumDescriptor.Values()
+		enumValueNames := make([]string, 0, enumValueDescriptors.Len())
+		for i := 0; i < enumValueDescriptors.Len(); i++ {
+			enumValueNames = append(enumValueNames, string(enumValueDescriptors.Get(i).Name()))
+		}
+		g.schemas[name] = &Schema{
+			Type:        "string",
+			Description: getDescription(enumDescriptor),
+			Enum:        enumValueNames,
+		}
+	}
+	return &Schema{Ref: schemaRefPrefix + name}
+}
+
+// getWellKnownTypeSchema returns the schema of the JSON representation of the
+// well-known type, or false if the message is not a well-known type with a special
+// JSON representation.
+func getWellKnownTypeSchema(messageDescriptor protoreflect.MessageDescriptor) (*Schema, bool) {
+	switch messageDescriptor.FullName() {
+	case "google.protobuf.Timestamp":
+		return &Schema{Type: "string", Format: "date-time"}, true
+	case "google.protobuf.Duration", "google.protobuf.FieldMask":
+		return &Schema{Type: "string"}, true
+	case "google.protobuf.Struct":
+		return &Schema{Type: "object", AdditionalProperties: &Schema{}}, true
+	case "google.protobuf.Value":
+		return &Schema{}, true
+	case "google.protobuf.ListValue":
+		return &Schema{Type: "array", Items: &Schema{}}, true
+	case "google.protobuf.Empty":
+		return &Schema{Type: "object"}, true
+	case "google.protobuf.Any":
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"@type": {Type: "string"},
+			},
+			AdditionalProperties: &Schema{},
+		}, true
+	case "google.protobuf.BoolValue":
+		return &Schema{Type: "boolean", Nullable: true}, true
+	case "google.protobuf.Int32Value":
+		return &Schema{Type: "integer", Format: "int32", Nullable: true}, true
+	case "google.protobuf.UInt32Value":
+		return &Schema{Type: "integer", Format: "uint32", Nullable: true}, true
+	case "google.protobuf.Int64Value":
+		return &Schema{Type: "string", Format: "int64", Nullable: true}, true
+	case "google.protobuf.UInt64Value":
+		return &Schema{Type: "string", Format: "uint64", Nullable: true}, true
+	case "google.protobuf.FloatValue":
+		return &Schema{Type: "number", Format: "float", Nullable: true}, true
+	case "google.protobuf.DoubleValue":
+		return &Schema{Type: "number", Format: "double", Nullable: true}, true
+	case "google.protobuf.StringValue":
+		return &Schema{Type: "string", Nullable: true}, true
+	case "google.protobuf.BytesValue":
+		return &Schema{Type: "string", Format: "byte", Nullable: true}, true
+	default:
+		return nil, false
+	}
+}
+
+// getHTTPRule returns the google.api.http annotation of the method, or nil if
+// the method does not have one.
+func getHTTPRule(methodDescriptor protoreflect.MethodDescriptor) (*annotations.HttpRule, error) {
+	methodOptions, ok := methodDescriptor.Options().(*descriptorpb.MethodOptions)
+	if !ok || methodOptions == nil {
+		return nil, nil
+	}
+	data, err := proto.Marshal(methodOptions)
+	if err != nil {
+		return nil, err
+	}
+	// The annotation is an unrecognized field until the options are parsed
+	// with the extension registered.
+	reparsedMethodOptions := &descriptorpb.MethodOptions{}
+	if err := proto.Unmarshal(data, reparsedMethodOptions); err != nil {
+		return nil, err
+	}
+	if !proto.HasExtension(reparsedMethodOptions, annotations.E_Http) {
+		return nil, nil
+	}
+	httpRule, ok := proto.GetExtension(reparsedMethodOptions, annotations.E_Http).(*annotations.HttpRule)
+	if !ok {
+		return nil, nil
+	}
+	return httpRule, nil
+}
+
+func getHTTPMethodAndPathTemplate(httpRule *annotations.HttpRule) (string, string, error) {
+	switch pattern := httpRule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return "GET", pattern.Get, nil
+	case *annotations.HttpRule_Put:
+		return "PUT", pattern.Put, nil
+	case *annotations.HttpRule_Post:
+		return "POST", pattern.Post, nil
+	case *annotations.HttpRule_Delete:
+		return "DELETE", pattern.Delete, nil
+	case *annotations.HttpRule_Patch:
+		return "PATCH", pattern.Patch, nil
+	case *annotations.HttpRule_Custom:
+		return strings.ToUpper(pattern.Custom.GetKind()), pattern.Custom.GetPath(), nil
+	default:
+		return "", "", errors.New("google.api.http annotation does not have a pattern")
+	}
+}
+
+// parsePathTemplate converts the path template of an HTTP rule to an OpenAPI path,
+// and returns the field paths of the variables of the template.
+//
+// For example, /v1/{name=shelves/*}/books/{book.id} is converted to
+// /v1/{name}/books/{book.id}, with the field paths name and book.id.
+func parsePathTemplate(pathTemplate string) (string, []string, error) {
+	if !strings.HasPrefix(pathTemplate, "/") {
+		return "", nil, fmt.Errorf("path %q does not start with /", pathTemplate)
+	}
+	var builder strings.Builder
+	var fieldPaths []string
+	remaining := pathTemplate
+	for {
+		start := strings.IndexByte(remaining, '{')
+		if start < 0 {
+			if strings.IndexByte(remaining, '}') >= 0 {
+				return "", nil, fmt.Errorf("path %q has an unmatched }", pathTemplate)
+			}
+			builder.WriteString(remaining)
+			break
+		}
+		end := strings.IndexByte(remaining[start:], '}')
+		if end < 0 {
+			return "", nil, fmt.Errorf("path %q has an unmatched {", pathTemplate)
+		}
+		end += start
+		fieldPath := remaining[start+1 : end]
+		if index := strings.IndexByte(fieldPath, '='); index >= 0 {
+			fieldPath = fieldPath[:index]
+		}
+		if fieldPath == "" {
+			return "", nil, fmt.Errorf("path %q has a variable without a field", pathTemplate)
+		}
+		builder.WriteString(remaining[:start])
+		builder.WriteString("{" + fieldPath + "}")
+		fieldPaths = append(fieldPaths, fieldPath)
+		remaining = remaining[end+1:]
+	}
+	return builder.String(), fieldPaths, nil
+}
+
+// getField returns the field at the dot-separated path of field names, for example
+// book.id, starting from the message.
+func getField(messageDescriptor protoreflect.MessageDescriptor, fieldPath string) (protoreflect.FieldDescriptor, error) {
+	var fieldDescriptor protoreflect.FieldDescriptor
+	for _, fieldName := range strings.Split(fieldPath, ".") {
+		if messageDescriptor == nil {
+			return nil, fmt.Errorf("%s is not a message", fieldDescriptor.FullName())
+		}
+		fieldDescriptor = messageDescriptor.Fields().ByName(protoreflect.Name(fieldName))
+		if fieldDescriptor == nil {
+			return nil, fmt.Errorf("%s has no field %q", messageDescriptor.FullName(), fieldName)
+		}
+		messageDescriptor = fieldDescriptor.Message()
+	}
+	return fieldDescriptor, nil
+}
+
+// isBoundField returns true if the field, or a field within it, is one of the
+// bound field paths.
+func isBoundField(fieldDescriptor protoreflect.FieldDescriptor, boundFieldPaths []string) bool {
+	name := string(fieldDescriptor.Name())
+	for _, boundFieldPath := range boundFieldPaths {
+		if boundFieldPath == name || strings.HasPrefix(boundFieldPath, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// getDescription returns the leading comments of the descriptor.
+func getDescription(descriptor protoreflect.Descriptor) string {
+	comments := descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor).LeadingComments
+	lines := strings.Split(strings.TrimSpace(comments), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func merge(info *Info, documents ...*Document) (*Document, error) {
+	merged := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+	schemas := make(map[string]*Schema)
+	for _, document := range documents {
+		merged.Tags = append(merged.Tags, document.Tags...)
+		paths := make([]string, 0, len(document.Paths))
+		for path := range document.Paths {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			pathItem := document.Paths[path]
+			mergedPathItem, ok := merged.Paths[path]
+			if !ok {
+				mergedPathItem = &PathItem{}
+				merged.Paths[path] = mergedPathItem
+			}
+			for _, httpMethod := range allHTTPMethods {
+				operation := *pathItem.operationPtr(httpMethod)
+				if operation == nil {
+					continue
+				}
+				mergedOperationPtr := mergedPathItem.operationPtr(httpMethod)
+				if *mergedOperationPtr != nil {
+					return nil, fmt.Errorf(
+						"%s and %s are both mapped to %s %s",
+						(*mergedOperationPtr).OperationID,
+						operation.OperationID,
+						httpMethod,
+						path,
+					)
+				}
+				*mergedOperationPtr = operation
+			}
+		}
+		if document.Components != nil {
+			// Schemas with the same name are of the same message or enum.
+			for name, schema := range document.Components.Schemas {
+				schemas[name] = schema
+			}
+		}
+	}
+	if len(schemas) > 0 {
+		merged.Components = &Components{
+			Schemas: schemas,
+		}
+	}
+	return merged, nil
+}
+
+var allHTTPMethods = []string{
+	"GET",
+	"PUT",
+	"POST",
+	"DELETE",
+	"OPTIONS",
+	"HEAD",
+	"PATCH",
+	"TRACE",
+}
+
+// operationPtr returns a pointer to the operation of the HTTP method, or nil if
+// OpenAPI does not support the HTTP method.
+func (p *PathItem) operationPtr(httpMethod string) **Operation {
+	switch httpMethod {
+	case "GET":
+		return &p.Get
+	case "PUT":
+		return &p.Put
+	case "POST":
+		return &p.Post
+	case "DELETE":
+		return &p.Delete
+	case "OPTIONS":
+		return &p.Options
+	case "HEAD":
+		return &p.Head
+	case "PATCH":
+		return &p.Patch
+	case "TRACE":
+		return &p.Trace
+	default:
+		return nil
+	}
+}
+
+type generateOptions struct {
+	serviceNames []string
+	infoVersion  string
+}
+
+func newGenerateOptions() *generateOptions {
+	return &generateOptions{
+		infoVersion: DefaultInfoVersion,
+	}
+}
@@ -17,6 +17,8 @@ package bufpluginref
 import (
 	"fmt"
 	"strings"
+
+	"golang.org/x/mod/semver"
 )
 
 // PluginIdentity is a plugin identity.
@@ -108,8 +110,51 @@ func ParsePluginIdentityOptionalVersion(rawReference string) (PluginIdentity, st
 	return identity, "", nil
 }
 
+// PluginVersionConstraint is a constraint on the semantic version of a plugin.
+//
+// Constraints are a comma-separated list of terms that must all match. Each term
+// is one of ^X.Y.Z, ~X.Y.Z, =X.Y.Z, >X.Y.Z, >=X.Y.Z, <X.Y.Z or <=X.Y.Z, where the
+// minor and patch components may be omitted and the "v" prefix is optional.
+type PluginVersionConstraint interface {
+	// Matches returns true if the given semantic version satisfies the constraint.
+	//
+	// Pre-release versions only match if the constraint itself contains a pre-release version.
+	Matches(version string) bool
+	// String returns the constraint as it was specified.
+	String() string
+}
+
+// ParsePluginVersionConstraint parses the PluginVersionConstraint.
+func ParsePluginVersionConstraint(constraint string) (PluginVersionConstraint, error) {
+	return newPluginVersionConstraint(constraint)
+}
+
+// ParsePluginIdentityVersionConstraint returns the PluginIdentity and PluginVersionConstraint for the given string.
+//
+// This parses the path in the form remote/owner/plugin:constraint. Exact versions are not
+// considered constraints, use PluginReferenceForString for these.
+func ParsePluginIdentityVersionConstraint(rawReference string) (PluginIdentity, PluginVersionConstraint, error) {
+	name, rawConstraint, ok := strings.Cut(rawReference, ":")
+	if !ok {
+		return nil, nil, fmt.Errorf("plugin version constraints must be specified as \"<name>:<constraint>\" strings")
+	}
+	if semver.IsValid(rawConstraint) {
+		return nil, nil, fmt.Errorf("plugin version %q is an exact version and not a constraint", rawConstraint)
+	}
+	identity, err := PluginIdentityForString(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	constraint, err := ParsePluginVersionConstraint(rawConstraint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return identity, constraint, nil
+}
+
 // IsPluginReferenceOrIdentity returns true if the argument matches a plugin
-// reference (with version) or a plugin identity (without version).
+// reference (with version), a plugin identity (without version), or a plugin
+// identity with a version constraint.
 func IsPluginReferenceOrIdentity(plugin string) bool {
 	if _, err := PluginReferenceForString(plugin, 0); err == nil {
 		return true
@@ -117,6 +162,9 @@ func IsPluginReferenceOrIdentity(plugin string) bool {
 	if _, err := PluginIdentityForString(plugin); err == nil {
 		return true
 	}
+	if _, _, err := ParsePluginIdentityVersionConstraint(plugin); err == nil {
+		return true
+	}
 	return false
 }
 
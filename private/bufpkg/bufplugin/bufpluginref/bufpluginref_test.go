@@ -77,3 +77,89 @@ func TestPluginIdentityForStringError(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePluginVersionConstraint(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		Constraint string
+		Matches    []string
+		NotMatches []string
+	}{
+		{
+			Constraint: "^1.31",
+			Matches:    []string{"v1.31.0", "v1.31.5", "v1.40.0", "1.99.99"},
+			NotMatches: []string{"v1.30.9", "v2.0.0", "v1.32.0-rc1"},
+		},
+		{
+			Constraint: "^0.5.2",
+			Matches:    []string{"v0.5.2", "v0.5.9"},
+			NotMatches: []string{"v0.5.1", "v0.6.0", "v1.0.0"},
+		},
+		{
+			Constraint: "^0.0.3",
+			Matches:    []string{"v0.0.3"},
+			NotMatches: []string{"v0.0.4"},
+		},
+		{
+			Constraint: "~1.31",
+			Matches:    []string{"v1.31.0", "v1.31.7"},
+			NotMatches: []string{"v1.32.0", "v1.30.0"},
+		},
+		{
+			Constraint: "~1",
+			Matches:    []string{"v1.0.0", "v1.9.0"},
+			NotMatches: []string{"v2.0.0"},
+		},
+		{
+			Constraint: ">=v1.2.0, <1.4.0",
+			Matches:    []string{"v1.2.0", "v1.3.9"},
+			NotMatches: []string{"v1.1.9", "v1.4.0"},
+		},
+		{
+			Constraint: "=1.2.3",
+			Matches:    []string{"v1.2.3"},
+			NotMatches: []string{"v1.2.4"},
+		},
+		{
+			Constraint: ">=1.0.0-rc1",
+			Matches:    []string{"v1.0.0-rc1", "v1.0.0", "v1.0.1-rc1"},
+			NotMatches: []string{"v1.0.0-alpha"},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.Constraint, func(t *testing.T) {
+			t.Parallel()
+			constraint, err := ParsePluginVersionConstraint(testCase.Constraint)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.Constraint, constraint.String())
+			for _, version := range testCase.Matches {
+				assert.True(t, constraint.Matches(version), version)
+			}
+			for _, version := range testCase.NotMatches {
+				assert.False(t, constraint.Matches(version), version)
+			}
+		})
+	}
+}
+
+func TestParsePluginVersionConstraintError(t *testing.T) {
+	t.Parallel()
+	for _, constraint := range []string{"", "1.2.3", "^", "^foo", ">=1.0.0,", "latest"} {
+		_, err := ParsePluginVersionConstraint(constraint)
+		assert.Error(t, err, constraint)
+	}
+}
+
+func TestParsePluginIdentityVersionConstraint(t *testing.T) {
+	t.Parallel()
+	identity, constraint, err := ParsePluginIdentityVersionConstraint("buf.build/protocolbuffers/go:^1.31")
+	require.NoError(t, err)
+	assert.Equal(t, "buf.build/protocolbuffers/go", identity.IdentityString())
+	assert.Equal(t, "^1.31", constraint.String())
+	assert.True(t, IsPluginReferenceOrIdentity("buf.build/protocolbuffers/go:^1.31"))
+	_, _, err = ParsePluginIdentityVersionConstraint("buf.build/protocolbuffers/go:v1.31.0")
+	assert.Error(t, err)
+	_, _, err = ParsePluginIdentityVersionConstraint("buf.build/protocolbuffers/go")
+	assert.Error(t, err)
+}
@@ -0,0 +1,187 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufpluginref
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+type pluginVersionConstraint struct {
+	raw               string
+	comparators       []*versionComparator
+	includePrerelease bool
+}
+
+func (p *pluginVersionConstraint) Matches(version string) bool {
+	version = canonicalVersion(version)
+	if !semver.IsValid(version) {
+		return false
+	}
+	if semver.Prerelease(version) != "" && !p.includePrerelease {
+		return false
+	}
+	for _, comparator := range p.comparators {
+		if !comparator.matches(version) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *pluginVersionConstraint) String() string {
+	return p.raw
+}
+
+func newPluginVersionConstraint(raw string) (*pluginVersionConstraint, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return nil, newInvalidPluginVersionConstraintError(raw)
+	}
+	var comparators []*versionComparator
+	for _, part := range strings.Split(trimmed, ",") {
+		partComparators, err := parseVersionComparators(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", newInvalidPluginVersionConstraintError(raw), err)
+		}
+		comparators = append(comparators, partComparators...)
+	}
+	return &pluginVersionConstraint{
+		raw:               raw,
+		comparators:       comparators,
+		includePrerelease: strings.Contains(trimmed, "-"),
+	}, nil
+}
+
+type versionComparator struct {
+	operator string
+	version  string
+}
+
+func (v *versionComparator) matches(version string) bool {
+	compare := semver.Compare(version, v.version)
+	switch v.operator {
+	case "=":
+		return compare == 0
+	case ">":
+		return compare > 0
+	case ">=":
+		return compare >= 0
+	case "<":
+		return compare < 0
+	case "<=":
+		return compare <= 0
+	default:
+		return false
+	}
+}
+
+// parseVersionComparators parses a single term of a constraint.
+//
+// Caret and tilde ranges expand into a lower and an upper bound.
+func parseVersionComparators(term string) ([]*versionComparator, error) {
+	if term == "" {
+		return nil, errors.New("empty constraint term")
+	}
+	for _, operator := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(term, operator) {
+			version, err := parseConstraintVersion(strings.TrimSpace(strings.TrimPrefix(term, operator)))
+			if err != nil {
+				return nil, err
+			}
+			return []*versionComparator{{operator: operator, version: version}}, nil
+		}
+	}
+	switch {
+	case strings.HasPrefix(term, "^"):
+		return parseRangeComparators(strings.TrimPrefix(term, "^"), true)
+	case strings.HasPrefix(term, "~"):
+		return parseRangeComparators(strings.TrimPrefix(term, "~"), false)
+	default:
+		return nil, fmt.Errorf("constraint term %q must start with one of ^, ~, =, >, >=, <, <=", term)
+	}
+}
+
+// parseRangeComparators expands a caret or tilde range.
+//
+// A caret range allows changes that do not modify the left-most non-zero
+// component, for example ^1.31 is >=v1.31.0, <v2.0.0. A tilde range allows
+// patch-level changes if a minor version is specified, for example ~1.31 is
+// >=v1.31.0, <v1.32.0, and minor-level changes otherwise.
+func parseRangeComparators(value string, caret bool) ([]*versionComparator, error) {
+	lower, err := parseConstraintVersion(value)
+	if err != nil {
+		return nil, err
+	}
+	numComponents := len(strings.Split(strings.SplitN(strings.TrimPrefix(value, "v"), "-", 2)[0], "."))
+	major, minor, patch, err := versionComponents(lower)
+	if err != nil {
+		return nil, err
+	}
+	var upper string
+	switch {
+	case numComponents == 1, caret && major > 0:
+		upper = fmt.Sprintf("v%d.0.0", major+1)
+	case !caret, minor > 0, numComponents == 2:
+		upper = fmt.Sprintf("v%d.%d.0", major, minor+1)
+	default:
+		upper = fmt.Sprintf("v%d.%d.%d", major, minor, patch+1)
+	}
+	return []*versionComparator{
+		{operator: ">=", version: lower},
+		{operator: "<", version: upper},
+	}, nil
+}
+
+func parseConstraintVersion(value string) (string, error) {
+	version := canonicalVersion(value)
+	if !semver.IsValid(version) {
+		return "", fmt.Errorf("%q is not a valid semantic version", value)
+	}
+	return semver.Canonical(version), nil
+}
+
+func versionComponents(version string) (int, int, int, error) {
+	release, _, _ := strings.Cut(strings.TrimPrefix(semver.Canonical(version), "v"), "-")
+	components := strings.Split(release, ".")
+	if len(components) != 3 {
+		return 0, 0, 0, fmt.Errorf("%q is not a valid semantic version", version)
+	}
+	values := make([]int, 0, 3)
+	for _, component := range components {
+		value, err := strconv.Atoi(component)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("%q is not a valid semantic version", version)
+		}
+		values = append(values, value)
+	}
+	return values[0], values[1], values[2], nil
+}
+
+// canonicalVersion adds the "v" prefix expected by the semver package if it is missing.
+func canonicalVersion(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
+
+func newInvalidPluginVersionConstraintError(s string) error {
+	return fmt.Errorf("plugin version constraint %q is invalid", s)
+}
@@ -119,6 +119,16 @@ func GenerateWithProtocPath(protocPath string) GenerateOption {
 	}
 }
 
+// GenerateWithPluginSHA256 returns a new GenerateOption that verifies that the SHA-256
+// digest of the plugin binary is equal to the given hex-encoded digest before executing it.
+//
+// This requires GenerateWithPluginPath to be set.
+func GenerateWithPluginSHA256(pluginSHA256 string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.pluginSHA256 = pluginSHA256
+	}
+}
+
 // GenerateWithWASMEnabled returns a new GenerateOption that sets wasmEnabled according to the env variable.
 func GenerateWithWASMEnabled() GenerateOption {
 	return func(generateOptions *generateOptions) {
@@ -131,7 +141,8 @@ func GenerateWithWASMEnabled() GenerateOption {
 // protocPath and pluginPath are optional.
 //
 //   - If a WASM plugin path is specified as the plugin name, this returns a WASM handler.
-//   - If the plugin path is set, this returns a new binary handler for that path.
+//   - If the plugin path is set, this returns a new binary handler for that path. If a plugin
+//     SHA-256 digest is also set, the binary is verified against the digest first.
 //   - If the plugin path is unset, this does exec.LookPath for a binary named protoc-gen-pluginName,
 //     and if one is found, a new binary handler is returned for this.
 //   - Else, if the name is in ProtocProxyPluginNames, this returns a new protoc proxy handler.
@@ -158,8 +169,21 @@ func NewHandler(
 	// Initialize binary plugin handler when path is specified with optional args. Return
 	// on error as something is wrong with the supplied pluginPath option.
 	if len(handlerOptions.pluginPath) > 0 {
+		if handlerOptions.pluginSHA256 != "" {
+			pluginPath, err := unsafeLookPath(handlerOptions.pluginPath[0])
+			if err != nil {
+				return nil, err
+			}
+			if err := verifyPluginSHA256(pluginPath, handlerOptions.pluginSHA256); err != nil {
+				return nil, err
+			}
+			return newBinaryHandler(runner, pluginPath, handlerOptions.pluginPath[1:]), nil
+		}
 		return NewBinaryHandler(runner, handlerOptions.pluginPath[0], handlerOptions.pluginPath[1:])
 	}
+	if handlerOptions.pluginSHA256 != "" {
+		return nil, fmt.Errorf("plugin %s: a plugin path is required to verify the plugin sha256", pluginName)
+	}
 
 	// Initialize binary plugin handler based on plugin name.
 	if handler, err := NewBinaryHandler(runner, "protoc-gen-"+pluginName, nil); err == nil {
@@ -209,6 +233,17 @@ func HandlerWithPluginPath(pluginPath ...string) HandlerOption {
 	}
 }
 
+// HandlerWithPluginSHA256 returns a new HandlerOption that sets the expected hex-encoded
+// SHA-256 digest of the plugin binary.
+//
+// The binary is verified against the digest before a handler is returned. This
+// requires HandlerWithPluginPath to be set.
+func HandlerWithPluginSHA256(pluginSHA256 string) HandlerOption {
+	return func(handlerOptions *handlerOptions) {
+		handlerOptions.pluginSHA256 = pluginSHA256
+	}
+}
+
 // HandlerWithWASMEnabled returns a new HandlerOption that sets wasmEnabled according to the env variable.
 func HandlerWithWASMEnabled() HandlerOption {
 	return func(handlerOptions *handlerOptions) {
@@ -228,10 +263,28 @@ func NewBinaryHandler(runner command.Runner, pluginPath string, pluginArgs []str
 	return newBinaryHandler(runner, pluginPath, pluginArgs), nil
 }
 
+// LookPluginBinaryPath returns the path of the binary that a Handler created by
+// NewHandler for the plugin name and plugin path invokes.
+//
+// This returns false if the plugin is not invoked as a binary, such as for protoc
+// builtin plugins, or if the binary cannot be found. WASM plugins are not considered.
+func LookPluginBinaryPath(pluginName string, pluginPath ...string) (string, bool) {
+	file := "protoc-gen-" + pluginName
+	if len(pluginPath) > 0 {
+		file = pluginPath[0]
+	}
+	path, err := unsafeLookPath(file)
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
 type handlerOptions struct {
-	protocPath  string
-	pluginPath  []string
-	wasmEnabled bool
+	protocPath   string
+	pluginPath   []string
+	pluginSHA256 string
+	wasmEnabled  bool
 }
 
 func newHandlerOptions() *handlerOptions {
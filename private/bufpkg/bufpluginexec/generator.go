@@ -61,6 +61,7 @@ func (g *generator) Generate(
 	handlerOptions := []HandlerOption{
 		HandlerWithPluginPath(generateOptions.pluginPath...),
 		HandlerWithProtocPath(generateOptions.protocPath),
+		HandlerWithPluginSHA256(generateOptions.pluginSHA256),
 	}
 	if generateOptions.wasmEnabled {
 		handlerOptions = append(
@@ -89,9 +90,10 @@ func (g *generator) Generate(
 }
 
 type generateOptions struct {
-	pluginPath  []string
-	protocPath  string
-	wasmEnabled bool
+	pluginPath   []string
+	pluginSHA256 string
+	protocPath   string
+	wasmEnabled  bool
 }
 
 func newGenerateOptions() *generateOptions {
@@ -15,9 +15,15 @@
 package bufpluginexec
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+
+	"go.uber.org/multierr"
 )
 
 // handlePotentialTooManyFilesError checks if the error is a result of too many files
@@ -44,3 +50,28 @@ func isTooManyFilesError(err error) bool {
 	}
 	return false
 }
+
+// verifyPluginSHA256 verifies that the SHA-256 digest of the file at pluginPath
+// is equal to the hex-encoded expectedSHA256.
+func verifyPluginSHA256(pluginPath string, expectedSHA256 string) (retErr error) {
+	file, err := os.Open(pluginPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, file.Close())
+	}()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if actualSHA256 := hex.EncodeToString(hash.Sum(nil)); actualSHA256 != strings.ToLower(expectedSHA256) {
+		return fmt.Errorf(
+			"plugin %s has sha256 %s but expected %s, the plugin binary may be stale or may have been tampered with",
+			pluginPath,
+			actualSHA256,
+			expectedSHA256,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufpluginexec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPluginSHA256(t *testing.T) {
+	t.Parallel()
+	pluginPath := filepath.Join(t.TempDir(), "protoc-gen-foo")
+	require.NoError(t, os.WriteFile(pluginPath, []byte("foo"), 0600))
+	// sha256 of "foo"
+	const fooSHA256 = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	require.NoError(t, verifyPluginSHA256(pluginPath, fooSHA256))
+	require.NoError(t, verifyPluginSHA256(pluginPath, strings.ToUpper(fooSHA256)))
+	require.ErrorContains(
+		t,
+		verifyPluginSHA256(pluginPath, "fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9"),
+		"may be stale or may have been tampered with",
+	)
+	require.Error(t, verifyPluginSHA256(filepath.Join(t.TempDir(), "missing"), fooSHA256))
+}
@@ -0,0 +1,208 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufprotovalidate validates messages against their protovalidate constraints.
+//
+// The constraints are read from the buf.validate options of the descriptors of the
+// message, so messages of types that are only known at runtime, such as dynamic
+// messages created from an image, can be validated.
+package bufprotovalidate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/protovalidate-go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// FormatText is the text format for Violations.
+	FormatText Format = iota + 1
+	// FormatJSON is the JSON format for Violations.
+	FormatJSON
+)
+
+var (
+	// AllFormatStrings is all format strings.
+	AllFormatStrings = []string{
+		"text",
+		"json",
+	}
+
+	stringToFormat = map[string]Format{
+		"text": FormatText,
+		"json": FormatJSON,
+	}
+	formatToString = map[Format]string{
+		FormatText: "text",
+		FormatJSON: "json",
+	}
+)
+
+// Format is a Violation format.
+type Format int
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	s, ok := formatToString[f]
+	if !ok {
+		return strconv.Itoa(int(f))
+	}
+	return s
+}
+
+// ParseFormat parses the Format.
+//
+// The empty strings defaults to FormatText.
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return FormatText, nil
+	}
+	f, ok := stringToFormat[s]
+	if ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown format: %q", s)
+}
+
+// Violation is a violation of a constraint by a message.
+type Violation struct {
+	// FieldPath is the path to the field that violates the constraint, such as
+	// "items[0].name".
+	//
+	// Empty if the constraint is on the message itself.
+	FieldPath string `json:"fieldPath,omitempty"`
+	// ConstraintID is the ID of the constraint, such as "string.min_len".
+	ConstraintID string `json:"constraintId"`
+	// Message is the human-readable description of the violation.
+	Message string `json:"message"`
+	// ForKey is true if the violation is for the key of a map field rather than
+	// its value.
+	ForKey bool `json:"forKey,omitempty"`
+}
+
+// String returns the Violation in the text format.
+func (v *Violation) String() string {
+	var builder strings.Builder
+	if v.FieldPath != "" {
+		_, _ = builder.WriteString(v.FieldPath)
+		if v.ForKey {
+			_, _ = builder.WriteString(" (key)")
+		}
+		_, _ = builder.WriteString(": ")
+	}
+	_, _ = builder.WriteString(v.Message)
+	if v.ConstraintID != "" {
+		_, _ = builder.WriteString(" [")
+		_, _ = builder.WriteString(v.ConstraintID)
+		_, _ = builder.WriteString("]")
+	}
+	return builder.String()
+}
+
+// Validator validates messages against their protovalidate constraints.
+//
+// The constraints of each message type are compiled once, and reused for all
+// messages of that type.
+type Validator interface {
+	// Validate validates the message against the constraints of its type and the
+	// types of its fields, and returns the violations, if any.
+	//
+	// An error is returned if the constraints cannot be evaluated, for example if a
+	// CEL expression does not compile.
+	Validate(message proto.Message) ([]*Violation, error)
+}
+
+// NewValidator returns a new Validator.
+func NewValidator() (Validator, error) {
+	return newValidator()
+}
+
+// Validate validates the message against the constraints of its type and the types
+// of its fields, and returns the violations, if any.
+//
+// An error is returned if the constraints cannot be evaluated, for example if a
+// CEL expression does not compile. Use a Validator to validate many messages.
+func Validate(message proto.Message) ([]*Violation, error) {
+	validator, err := newValidator()
+	if err != nil {
+		return nil, err
+	}
+	return validator.Validate(message)
+}
+
+type validator struct {
+	validator *protovalidate.Validator
+}
+
+func newValidator() (*validator, error) {
+	protovalidateValidator, err := protovalidate.New()
+	if err != nil {
+		return nil, err
+	}
+	return &validator{
+		validator: protovalidateValidator,
+	}, nil
+}
+
+func (v *validator) Validate(message proto.Message) ([]*Violation, error) {
+	err := v.validator.Validate(message)
+	if err == nil {
+		return nil, nil
+	}
+	var validationError *protovalidate.ValidationError
+	if !errors.As(err, &validationError) {
+		return nil, err
+	}
+	violations := make([]*Violation, len(validationError.Violations))
+	for i, violation := range validationError.Violations {
+		violations[i] = &Violation{
+			FieldPath:    violation.GetFieldPath(),
+			ConstraintID: violation.GetConstraintId(),
+			Message:      violation.GetMessage(),
+			ForKey:       violation.GetForKey(),
+		}
+	}
+	return violations, nil
+}
+
+// PrintViolations prints the violations to the writer in the format, one
+// violation per line.
+func PrintViolations(writer io.Writer, violations []*Violation, format Format) error {
+	for _, violation := range violations {
+		var data []byte
+		switch format {
+		case FormatText:
+			data = []byte(violation.String())
+		case FormatJSON:
+			var err error
+			data, err = json.Marshal(violation)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown Format: %v", format)
+		}
+		if _, err := writer.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
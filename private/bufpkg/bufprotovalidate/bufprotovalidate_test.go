@@ -0,0 +1,185 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprotovalidate
+
+import (
+	"bytes"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	message := testNewOrder(t, `{"name":"a","tags":["b",""]}`)
+	violations, err := Validate(message)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Violation{
+			{
+				ConstraintID: "order.tags",
+				Message:      "an order must have at most one tag",
+			},
+			{
+				FieldPath:    "name",
+				ConstraintID: "string.min_len",
+				Message:      "value length must be at least 3 characters",
+			},
+			{
+				FieldPath:    "tags[1]",
+				ConstraintID: "string.min_len",
+				Message:      "value length must be at least 1 characters",
+			},
+		},
+		violations,
+	)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, PrintViolations(buffer, violations, FormatText))
+	assert.Equal(
+		t,
+		`an order must have at most one tag [order.tags]
+name: value length must be at least 3 characters [string.min_len]
+tags[1]: value length must be at least 1 characters [string.min_len]
+`,
+		buffer.String(),
+	)
+	buffer.Reset()
+	require.NoError(t, PrintViolations(buffer, violations[:2], FormatJSON))
+	assert.Equal(
+		t,
+		`{"constraintId":"order.tags","message":"an order must have at most one tag"}
+{"fieldPath":"name","constraintId":"string.min_len","message":"value length must be at least 3 characters"}
+`,
+		buffer.String(),
+	)
+
+	message = testNewOrder(t, `{"name":"abc","tags":["b"]}`)
+	violations, err = Validate(message)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+	format, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatText, format)
+	format, err = ParseFormat("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+	_, err = ParseFormat("yaml")
+	assert.EqualError(t, err, `unknown format: "yaml"`)
+}
+
+// testNewOrder returns a dynamic acme.v1.Order unmarshaled from the JSON, where
+// acme.v1.Order is equivalent to:
+//
+//	message Order {
+//	  option (buf.validate.message).cel = {
+//	    id: "order.tags"
+//	    message: "an order must have at most one tag"
+//	    expression: "size(this.tags) <= 1"
+//	  };
+//	  string name = 1 [(buf.validate.field).string.min_len = 3];
+//	  repeated string tags = 2 [(buf.validate.field).repeated.items.string.min_len = 1];
+//	}
+func testNewOrder(t *testing.T, data string) proto.Message {
+	messageOptions := &descriptorpb.MessageOptions{}
+	proto.SetExtension(
+		messageOptions,
+		validate.E_Message,
+		&validate.MessageConstraints{
+			Cel: []*validate.Constraint{
+				{
+					Id:         "order.tags",
+					Message:    "an order must have at most one tag",
+					Expression: "size(this.tags) <= 1",
+				},
+			},
+		},
+	)
+	nameOptions := &descriptorpb.FieldOptions{}
+	proto.SetExtension(
+		nameOptions,
+		validate.E_Field,
+		&validate.FieldConstraints{
+			Type: &validate.FieldConstraints_String_{
+				String_: &validate.StringRules{MinLen: proto.Uint64(3)},
+			},
+		},
+	)
+	tagsOptions := &descriptorpb.FieldOptions{}
+	proto.SetExtension(
+		tagsOptions,
+		validate.E_Field,
+		&validate.FieldConstraints{
+			Type: &validate.FieldConstraints_Repeated{
+				Repeated: &validate.RepeatedRules{
+					Items: &validate.FieldConstraints{
+						Type: &validate.FieldConstraints_String_{
+							String_: &validate.StringRules{MinLen: proto.Uint64(1)},
+						},
+					},
+				},
+			},
+		},
+	)
+	fileDescriptor, err := protodesc.NewFile(
+		&descriptorpb.FileDescriptorProto{
+			Name:       proto.String("acme/v1/order.proto"),
+			Package:    proto.String("acme.v1"),
+			Syntax:     proto.String("proto3"),
+			Dependency: []string{validate.File_buf_validate_validate_proto.Path()},
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name:    proto.String("Order"),
+					Options: messageOptions,
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{
+							Name:     proto.String("name"),
+							JsonName: proto.String("name"),
+							Number:   proto.Int32(1),
+							Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+							Options:  nameOptions,
+						},
+						{
+							Name:     proto.String("tags"),
+							JsonName: proto.String("tags"),
+							Number:   proto.Int32(2),
+							Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+							Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+							Options:  tagsOptions,
+						},
+					},
+				},
+			},
+		},
+		protoregistry.GlobalFiles,
+	)
+	require.NoError(t, err)
+	message := dynamicpb.NewMessage(fileDescriptor.Messages().ByName("Order"))
+	require.NoError(t, protojson.Unmarshal([]byte(data), message))
+	return message
+}
@@ -0,0 +1,122 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufsbom writes software bill of materials (SBOM) documents for the
+// dependencies of modules, in the SPDX and CycloneDX formats.
+package bufsbom
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+)
+
+const (
+	// FormatSPDX is the SPDX 2.3 JSON format.
+	FormatSPDX Format = 1
+	// FormatCycloneDX is the CycloneDX 1.5 JSON format.
+	FormatCycloneDX Format = 2
+)
+
+var (
+	// AllFormatsString is the string representation of all Formats.
+	AllFormatsString = stringutil.SliceToString([]string{FormatSPDX.String(), FormatCycloneDX.String()})
+)
+
+// Format is a SBOM format.
+type Format int
+
+// ParseFormat parses the format.
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "spdx":
+		return FormatSPDX, nil
+	case "cyclonedx":
+		return FormatCycloneDX, nil
+	default:
+		return 0, fmt.Errorf("unknown format: %s", s)
+	}
+}
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	switch f {
+	case FormatSPDX:
+		return "spdx"
+	case FormatCycloneDX:
+		return "cyclonedx"
+	default:
+		return strconv.Itoa(int(f))
+	}
+}
+
+const (
+	// ComponentTypeModule is the type of a module Component.
+	ComponentTypeModule ComponentType = 1
+	// ComponentTypePlugin is the type of a plugin Component.
+	ComponentTypePlugin ComponentType = 2
+)
+
+// ComponentType is the type of a Component.
+type ComponentType int
+
+// Component is a dependency in a Document.
+type Component struct {
+	// Required.
+	Type ComponentType
+	// Name is the full name of the module or plugin, such as
+	// buf.build/acme/weather or buf.build/protocolbuffers/go.
+	//
+	// Required.
+	Name string
+	// Version is the commit of a module, or the version of a plugin.
+	Version string
+	// Digest is the digest of a module, such as shake256:...
+	Digest string
+	// License is the content of the license file of a module, if any.
+	License string
+}
+
+// Document is a SBOM.
+type Document struct {
+	// Name is the name of the document, such as the input that the
+	// dependencies are of.
+	//
+	// Required.
+	Name string
+	// Created is the time the document was created.
+	//
+	// Required.
+	Created time.Time
+	// Components are the dependencies, which are written in the given order.
+	Components []*Component
+}
+
+// Write writes the Document in the given Format.
+//
+// The output only depends on the Document, so the same Document is always
+// written the same way.
+func Write(writer io.Writer, format Format, document *Document) error {
+	switch format {
+	case FormatSPDX:
+		return writeSPDX(writer, document)
+	case FormatCycloneDX:
+		return writeCycloneDX(writer, document)
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsbom
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSPDX(t *testing.T) {
+	t.Parallel()
+	document := newTestDocument()
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, Write(buffer, FormatSPDX, document))
+	assert.JSONEq(
+		t,
+		`{
+  "spdxVersion": "SPDX-2.3",
+  "dataLicense": "CC0-1.0",
+  "SPDXID": "SPDXRef-DOCUMENT",
+  "name": "proto",
+  "documentNamespace": "https://buf.build/spdx/`+documentUUID(document).String()+`",
+  "creationInfo": {
+    "created": "2023-05-01T12:00:00Z",
+    "creators": ["Tool: buf"]
+  },
+  "packages": [
+    {
+      "name": "buf.build/acme/weather",
+      "SPDXID": "SPDXRef-Package-1",
+      "versionInfo": "3f9f3b4a6c4d4e5f8a9b0c1d2e3f4a5b",
+      "downloadLocation": "NOASSERTION",
+      "filesAnalyzed": false,
+      "licenseConcluded": "NOASSERTION",
+      "licenseDeclared": "LicenseRef-1",
+      "copyrightText": "NOASSERTION",
+      "primaryPackagePurpose": "LIBRARY",
+      "comment": "digest: shake256:abcd"
+    },
+    {
+      "name": "buf.build/acme/units",
+      "SPDXID": "SPDXRef-Package-2",
+      "versionInfo": "9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d",
+      "downloadLocation": "NOASSERTION",
+      "filesAnalyzed": false,
+      "licenseConcluded": "NOASSERTION",
+      "licenseDeclared": "NOASSERTION",
+      "copyrightText": "NOASSERTION",
+      "primaryPackagePurpose": "LIBRARY",
+      "comment": "digest: shake256:ef01"
+    },
+    {
+      "name": "buf.build/protocolbuffers/go",
+      "SPDXID": "SPDXRef-Package-3",
+      "versionInfo": "v1.30.0",
+      "downloadLocation": "NOASSERTION",
+      "filesAnalyzed": false,
+      "licenseConcluded": "NOASSERTION",
+      "licenseDeclared": "NOASSERTION",
+      "copyrightText": "NOASSERTION",
+      "primaryPackagePurpose": "APPLICATION"
+    }
+  ],
+  "hasExtractedLicensingInfos": [
+    {
+      "licenseId": "LicenseRef-1",
+      "extractedText": "Weather License",
+      "name": "License of buf.build/acme/weather"
+    }
+  ],
+  "relationships": [
+    {"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Package-1"},
+    {"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Package-2"},
+    {"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Package-3"}
+  ]
+}`,
+		buffer.String(),
+	)
+}
+
+func TestWriteCycloneDX(t *testing.T) {
+	t.Parallel()
+	document := newTestDocument()
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, Write(buffer, FormatCycloneDX, document))
+	assert.JSONEq(
+		t,
+		`{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "serialNumber": "urn:uuid:`+documentUUID(document).String()+`",
+  "version": 1,
+  "metadata": {
+    "timestamp": "2023-05-01T12:00:00Z",
+    "tools": [{"name": "buf"}],
+    "component": {"type": "application", "name": "proto"}
+  },
+  "components": [
+    {
+      "type": "library",
+      "bom-ref": "buf.build/acme/weather:3f9f3b4a6c4d4e5f8a9b0c1d2e3f4a5b",
+      "group": "buf.build/acme",
+      "name": "weather",
+      "version": "3f9f3b4a6c4d4e5f8a9b0c1d2e3f4a5b",
+      "licenses": [
+        {
+          "license": {
+            "name": "License of buf.build/acme/weather",
+            "text": {"contentType": "text/plain", "content": "Weather License"}
+          }
+        }
+      ],
+      "properties": [{"name": "buf:digest", "value": "shake256:abcd"}]
+    },
+    {
+      "type": "library",
+      "bom-ref": "buf.build/acme/units:9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d",
+      "group": "buf.build/acme",
+      "name": "units",
+      "version": "9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d",
+      "properties": [{"name": "buf:digest", "value": "shake256:ef01"}]
+    },
+    {
+      "type": "application",
+      "bom-ref": "buf.build/protocolbuffers/go:v1.30.0",
+      "group": "buf.build/protocolbuffers",
+      "name": "go",
+      "version": "v1.30.0"
+    }
+  ]
+}`,
+		buffer.String(),
+	)
+}
+
+func TestDocumentUUID(t *testing.T) {
+	t.Parallel()
+	document := newTestDocument()
+	assert.Equal(t, documentUUID(document), documentUUID(newTestDocument()))
+	document.Components[0].Version = "other"
+	assert.NotEqual(t, documentUUID(document), documentUUID(newTestDocument()))
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+	for _, format := range []Format{FormatSPDX, FormatCycloneDX} {
+		parsedFormat, err := ParseFormat(format.String())
+		require.NoError(t, err)
+		assert.Equal(t, format, parsedFormat)
+	}
+	_, err := ParseFormat("json")
+	assert.Error(t, err)
+}
+
+func newTestDocument() *Document {
+	return &Document{
+		Name:    "proto",
+		Created: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+		Components: []*Component{
+			{
+				Type:    ComponentTypeModule,
+				Name:    "buf.build/acme/weather",
+				Version: "3f9f3b4a6c4d4e5f8a9b0c1d2e3f4a5b",
+				Digest:  "shake256:abcd",
+				License: "Weather License",
+			},
+			{
+				Type:    ComponentTypeModule,
+				Name:    "buf.build/acme/units",
+				Version: "9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d",
+				Digest:  "shake256:ef01",
+			},
+			{
+				Type:    ComponentTypePlugin,
+				Name:    "buf.build/protocolbuffers/go",
+				Version: "v1.30.0",
+			},
+		},
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsbom
+
+import (
+	"io"
+)
+
+// See https://cyclonedx.org/docs/1.5/json/ for the format.
+
+const cycloneDXSpecVersion = "1.5"
+
+type cycloneDXDocument struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string                 `json:"timestamp"`
+	Tools     []cycloneDXTool        `json:"tools"`
+	Component cycloneDXMetaComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXMetaComponent struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type cycloneDXComponent struct {
+	Type     string                   `json:"type"`
+	BOMRef   string                   `json:"bom-ref"`
+	Group    string                   `json:"group,omitempty"`
+	Name     string                   `json:"name"`
+	Version  string                   `json:"version,omitempty"`
+	Licenses []cycloneDXLicenseChoice `json:"licenses,omitempty"`
+	// CycloneDX does not support SHAKE256 hashes, so module digests are
+	// written as a property.
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXLicenseChoice struct {
+	License cycloneDXLicense `json:"license"`
+}
+
+type cycloneDXLicense struct {
+	Name string              `json:"name"`
+	Text cycloneDXAttachment `json:"text"`
+}
+
+type cycloneDXAttachment struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func writeCycloneDX(writer io.Writer, document *Document) error {
+	cycloneDXDocument := &cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + documentUUID(document).String(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: formatTime(document.Created),
+			Tools:     []cycloneDXTool{{Name: toolName}},
+			Component: cycloneDXMetaComponent{
+				Type: "application",
+				Name: document.Name,
+			},
+		},
+		Components: make([]cycloneDXComponent, 0, len(document.Components)),
+	}
+	for _, component := range document.Components {
+		group, name := splitName(component.Name)
+		cycloneDXComponent := cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  component.Name,
+			Group:   group,
+			Name:    name,
+			Version: component.Version,
+		}
+		if component.Version != "" {
+			cycloneDXComponent.BOMRef += ":" + component.Version
+		}
+		if component.Type == ComponentTypePlugin {
+			cycloneDXComponent.Type = "application"
+		}
+		if component.License != "" {
+			cycloneDXComponent.Licenses = []cycloneDXLicenseChoice{
+				{
+					License: cycloneDXLicense{
+						Name: "License of " + component.Name,
+						Text: cycloneDXAttachment{
+							ContentType: "text/plain",
+							Content:     component.License,
+						},
+					},
+				},
+			}
+		}
+		if component.Digest != "" {
+			cycloneDXComponent.Properties = []cycloneDXProperty{
+				{
+					Name:  "buf:digest",
+					Value: component.Digest,
+				},
+			}
+		}
+		cycloneDXDocument.Components = append(cycloneDXDocument.Components, cycloneDXComponent)
+	}
+	return writeJSON(writer, cycloneDXDocument)
+}
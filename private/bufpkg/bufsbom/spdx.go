@@ -0,0 +1,128 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsbom
+
+import (
+	"fmt"
+	"io"
+)
+
+// See https://spdx.github.io/spdx-spec/v2.3/ for the format.
+
+const (
+	spdxVersion     = "SPDX-2.3"
+	spdxNoAssertion = "NOASSERTION"
+)
+
+type spdxDocument struct {
+	SPDXVersion                string                     `json:"spdxVersion"`
+	DataLicense                string                     `json:"dataLicense"`
+	SPDXID                     string                     `json:"SPDXID"`
+	Name                       string                     `json:"name"`
+	DocumentNamespace          string                     `json:"documentNamespace"`
+	CreationInfo               spdxCreationInfo           `json:"creationInfo"`
+	Packages                   []spdxPackage              `json:"packages"`
+	HasExtractedLicensingInfos []spdxExtractedLicenseInfo `json:"hasExtractedLicensingInfos,omitempty"`
+	Relationships              []spdxRelationship         `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string `json:"name"`
+	SPDXID                string `json:"SPDXID"`
+	VersionInfo           string `json:"versionInfo,omitempty"`
+	DownloadLocation      string `json:"downloadLocation"`
+	FilesAnalyzed         bool   `json:"filesAnalyzed"`
+	LicenseConcluded      string `json:"licenseConcluded"`
+	LicenseDeclared       string `json:"licenseDeclared"`
+	CopyrightText         string `json:"copyrightText"`
+	PrimaryPackagePurpose string `json:"primaryPackagePurpose"`
+	// SPDX does not support SHAKE256 checksums, so module digests are
+	// written in the comment.
+	Comment string `json:"comment,omitempty"`
+}
+
+type spdxExtractedLicenseInfo struct {
+	LicenseID     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+	Name          string `json:"name"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func writeSPDX(writer io.Writer, document *Document) error {
+	spdxDocument := &spdxDocument{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              document.Name,
+		DocumentNamespace: "https://buf.build/spdx/" + documentUUID(document).String(),
+		CreationInfo: spdxCreationInfo{
+			Created:  formatTime(document.Created),
+			Creators: []string{"Tool: " + toolName},
+		},
+		Packages:      make([]spdxPackage, 0, len(document.Components)),
+		Relationships: make([]spdxRelationship, 0, len(document.Components)),
+	}
+	for i, component := range document.Components {
+		spdxID := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		spdxPackage := spdxPackage{
+			Name:                  component.Name,
+			SPDXID:                spdxID,
+			VersionInfo:           component.Version,
+			DownloadLocation:      spdxNoAssertion,
+			LicenseConcluded:      spdxNoAssertion,
+			LicenseDeclared:       spdxNoAssertion,
+			CopyrightText:         spdxNoAssertion,
+			PrimaryPackagePurpose: "LIBRARY",
+		}
+		if component.Type == ComponentTypePlugin {
+			spdxPackage.PrimaryPackagePurpose = "APPLICATION"
+		}
+		if component.Digest != "" {
+			spdxPackage.Comment = "digest: " + component.Digest
+		}
+		if component.License != "" {
+			licenseID := fmt.Sprintf("LicenseRef-%d", len(spdxDocument.HasExtractedLicensingInfos)+1)
+			spdxPackage.LicenseDeclared = licenseID
+			spdxDocument.HasExtractedLicensingInfos = append(
+				spdxDocument.HasExtractedLicensingInfos,
+				spdxExtractedLicenseInfo{
+					LicenseID:     licenseID,
+					ExtractedText: component.License,
+					Name:          "License of " + component.Name,
+				},
+			)
+		}
+		spdxDocument.Packages = append(spdxDocument.Packages, spdxPackage)
+		spdxDocument.Relationships = append(
+			spdxDocument.Relationships,
+			spdxRelationship{
+				SPDXElementID:      spdxDocument.SPDXID,
+				RelationshipType:   "DESCRIBES",
+				RelatedSPDXElement: spdxID,
+			},
+		)
+	}
+	return writeJSON(writer, spdxDocument)
+}
@@ -0,0 +1,71 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsbom
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// toolName is the name of the tool that created the documents.
+const toolName = "buf"
+
+// documentUUID returns a UUID for the document that is derived from its
+// contents, so that documents for the same dependencies have the same UUID.
+func documentUUID(document *Document) uuid.UUID {
+	var builder strings.Builder
+	builder.WriteString(document.Name)
+	builder.WriteString("\n")
+	builder.WriteString(formatTime(document.Created))
+	for _, component := range document.Components {
+		builder.WriteString("\n")
+		builder.WriteString(strings.Join(
+			[]string{
+				strconv.Itoa(int(component.Type)),
+				component.Name,
+				component.Version,
+				component.Digest,
+				component.License,
+			},
+			"\x00",
+		))
+	}
+	return uuid.NewV5(uuid.NamespaceURL, builder.String())
+}
+
+// splitName splits the full name of a component into the part before the
+// last '/', such as buf.build/acme, and the part after it, such as weather.
+func splitName(name string) (string, string) {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func writeJSON(writer io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(value)
+}
@@ -0,0 +1,218 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufsso logs in to the Buf Schema Registry with single sign-on.
+//
+// An ID token from the identity provider of an organization is exchanged for a
+// short-lived registry token at the token exchange URL of the registry, with
+// the token exchange of RFC 8693. The refresh token from the identity provider
+// is saved as a Session, which is used to get a new registry token before the
+// registry token expires.
+//
+// The registry does not have a well-known token exchange endpoint, so the
+// token exchange URL must be configured.
+package bufsso
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/netrc"
+	"github.com/bufbuild/buf/private/pkg/oidc"
+)
+
+const (
+	sessionMachineNamePrefix = "sso."
+	sessionMachineLogin      = "sso"
+	// refreshBeforeExpiry is how long before the registry token expires that
+	// it is refreshed, so that it does not expire during a command.
+	refreshBeforeExpiry = time.Minute
+)
+
+// DefaultScopes are the scopes requested from the identity provider if none
+// are configured. The offline_access scope is needed for a refresh token.
+var DefaultScopes = []string{"openid", "offline_access"}
+
+// Session is a single sign-on session for a remote.
+type Session struct {
+	// Issuer is the issuer of the identity provider.
+	Issuer string `json:"issuer,omitempty"`
+	// ClientID is the client ID of buf at the identity provider.
+	ClientID string `json:"client_id,omitempty"`
+	// TokenExchangeURL is the URL that ID tokens are exchanged for registry
+	// tokens at.
+	TokenExchangeURL string `json:"token_exchange_url,omitempty"`
+	// RefreshToken is the refresh token from the identity provider.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// ExpireTime is the time that the registry token expires.
+	ExpireTime time.Time `json:"expire_time,omitempty"`
+}
+
+// NeedsRefresh returns true if the registry token expires soon after now,
+// or has expired.
+func (s *Session) NeedsRefresh(now time.Time) bool {
+	return !now.Add(refreshBeforeExpiry).Before(s.ExpireTime)
+}
+
+// Credentials are the credentials from a single sign-on login or refresh.
+type Credentials struct {
+	// RegistryToken is the short-lived registry token.
+	RegistryToken string
+	// Session is the session to refresh the registry token with.
+	Session *Session
+}
+
+// SessionMachineName returns the name of the machine in the credential store
+// that the Session for the remote is saved as.
+func SessionMachineName(remote string) string {
+	return sessionMachineNamePrefix + remote
+}
+
+// NewSessionMachine returns the machine that the Session for the remote is
+// saved as.
+func NewSessionMachine(remote string, session *Session) (netrc.Machine, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	// The Session is encoded so that it is a single token in a .netrc file,
+	// and it has no characters that credential stores may not allow.
+	return netrc.NewMachine(
+		SessionMachineName(remote),
+		sessionMachineLogin,
+		base64.RawURLEncoding.EncodeToString(data),
+	), nil
+}
+
+// SessionForMachine returns the Session saved as the machine.
+func SessionForMachine(machine netrc.Machine) (*Session, error) {
+	data, err := base64.RawURLEncoding.DecodeString(machine.Password())
+	if err != nil {
+		return nil, fmt.Errorf("invalid single sign-on session for %s: %w", machine.Name(), err)
+	}
+	session := &Session{}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("invalid single sign-on session for %s: %w", machine.Name(), err)
+	}
+	if session.Issuer == "" || session.ClientID == "" || session.TokenExchangeURL == "" || session.RefreshToken == "" {
+		return nil, fmt.Errorf("invalid single sign-on session for %s: incomplete session", machine.Name())
+	}
+	return session, nil
+}
+
+// CompleteLogin exchanges the token from the device authorization of the
+// identity provider for a registry token at the token exchange URL of the
+// registry.
+//
+// Returns an error if the token has no ID token or refresh token.
+func CompleteLogin(
+	ctx context.Context,
+	httpClient *http.Client,
+	tokenExchangeURL string,
+	providerMetadata *oidc.ProviderMetadata,
+	clientID string,
+	token *oidc.Token,
+	now time.Time,
+) (*Credentials, error) {
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("identity provider %s did not return an ID token, the openid scope is required", providerMetadata.Issuer)
+	}
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("identity provider %s did not return a refresh token, the offline_access scope may be required", providerMetadata.Issuer)
+	}
+	return exchangeIDToken(
+		ctx,
+		httpClient,
+		token.IDToken,
+		&Session{
+			Issuer:           providerMetadata.Issuer,
+			ClientID:         clientID,
+			TokenExchangeURL: tokenExchangeURL,
+			RefreshToken:     token.RefreshToken,
+		},
+		now,
+	)
+}
+
+// Refresh gets a new registry token for the Session.
+func Refresh(
+	ctx context.Context,
+	httpClient *http.Client,
+	session *Session,
+	now time.Time,
+) (*Credentials, error) {
+	providerMetadata, err := oidc.Discover(ctx, httpClient, session.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	token, err := oidc.Refresh(ctx, httpClient, providerMetadata, session.ClientID, session.RefreshToken)
+	if err != nil {
+		if oauthErr := (&oidc.Error{}); errors.As(err, &oauthErr) {
+			return nil, fmt.Errorf("could not refresh single sign-on session, log in again with buf registry login --sso: %w", err)
+		}
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("identity provider %s did not return an ID token when refreshing", session.Issuer)
+	}
+	refreshToken := token.RefreshToken
+	if refreshToken == "" {
+		// The identity provider does not rotate refresh tokens.
+		refreshToken = session.RefreshToken
+	}
+	return exchangeIDToken(
+		ctx,
+		httpClient,
+		token.IDToken,
+		&Session{
+			Issuer:           session.Issuer,
+			ClientID:         session.ClientID,
+			TokenExchangeURL: session.TokenExchangeURL,
+			RefreshToken:     refreshToken,
+		},
+		now,
+	)
+}
+
+func exchangeIDToken(
+	ctx context.Context,
+	httpClient *http.Client,
+	idToken string,
+	session *Session,
+	now time.Time,
+) (*Credentials, error) {
+	registryToken, err := oidc.ExchangeToken(
+		ctx,
+		httpClient,
+		session.TokenExchangeURL,
+		idToken,
+		oidc.TokenTypeIDToken,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not exchange ID token for a registry token: %w", err)
+	}
+	if registryToken.ExpiresIn <= 0 {
+		return nil, errors.New("registry did not return the expiry of the registry token")
+	}
+	session.ExpireTime = now.Add(time.Duration(registryToken.ExpiresIn) * time.Second).UTC()
+	return &Credentials{
+		RegistryToken: registryToken.AccessToken,
+		Session:       session,
+	}, nil
+}
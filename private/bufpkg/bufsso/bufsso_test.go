@@ -0,0 +1,117 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsso
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionMachine(t *testing.T) {
+	t.Parallel()
+	session := &Session{
+		Issuer:           "https://idp.example.com",
+		ClientID:         "buf",
+		TokenExchangeURL: "https://buf.example.com/token",
+		RefreshToken:     "refresh token with spaces and \"quotes\"",
+		ExpireTime:       time.Date(2023, 11, 1, 12, 0, 0, 0, time.UTC),
+	}
+	machine, err := NewSessionMachine("buf.example.com", session)
+	require.NoError(t, err)
+	assert.Equal(t, "sso.buf.example.com", machine.Name())
+	assert.NotContains(t, machine.Password(), " ")
+	assert.NotContains(t, machine.Password(), `"`)
+	parsedSession, err := SessionForMachine(machine)
+	require.NoError(t, err)
+	assert.Equal(t, session, parsedSession)
+	assert.False(t, session.NeedsRefresh(session.ExpireTime.Add(-2*time.Minute)))
+	assert.True(t, session.NeedsRefresh(session.ExpireTime.Add(-30*time.Second)))
+	assert.True(t, session.NeedsRefresh(session.ExpireTime.Add(time.Hour)))
+}
+
+func TestRefresh(t *testing.T) {
+	t.Parallel()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(writer http.ResponseWriter, _ *http.Request) {
+		writeJSON(writer, http.StatusOK, &oidc.ProviderMetadata{Issuer: server.URL, TokenEndpoint: server.URL + "/idp/token"})
+	})
+	mux.HandleFunc("/idp/token", func(writer http.ResponseWriter, request *http.Request) {
+		require.NoError(t, request.ParseForm())
+		if request.Form.Get("refresh_token") != "refresh-token" {
+			writeJSON(writer, http.StatusBadRequest, &oidc.Error{Code: "invalid_grant"})
+			return
+		}
+		writeJSON(writer, http.StatusOK, &oidc.Token{IDToken: "id-token"})
+	})
+	mux.HandleFunc("/registry/token", func(writer http.ResponseWriter, request *http.Request) {
+		require.NoError(t, request.ParseForm())
+		assert.Equal(t, "id-token", request.Form.Get("subject_token"))
+		writeJSON(writer, http.StatusOK, &oidc.Token{AccessToken: "registry-token", ExpiresIn: 3600})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	now := time.Date(2023, 11, 1, 12, 0, 0, 0, time.UTC)
+	credentials, err := Refresh(
+		context.Background(),
+		server.Client(),
+		&Session{
+			Issuer:           server.URL,
+			ClientID:         "buf",
+			TokenExchangeURL: server.URL + "/registry/token",
+			RefreshToken:     "refresh-token",
+		},
+		now,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "registry-token", credentials.RegistryToken)
+	assert.Equal(
+		t,
+		&Session{
+			Issuer:           server.URL,
+			ClientID:         "buf",
+			TokenExchangeURL: server.URL + "/registry/token",
+			RefreshToken:     "refresh-token",
+			ExpireTime:       now.Add(time.Hour),
+		},
+		credentials.Session,
+	)
+	_, err = Refresh(
+		context.Background(),
+		server.Client(),
+		&Session{
+			Issuer:           server.URL,
+			ClientID:         "buf",
+			TokenExchangeURL: server.URL + "/registry/token",
+			RefreshToken:     "revoked",
+		},
+		now,
+	)
+	assert.ErrorContains(t, err, "log in again with buf registry login --sso: invalid_grant")
+}
+
+func writeJSON(writer http.ResponseWriter, statusCode int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_ = json.NewEncoder(writer).Encode(value)
+}
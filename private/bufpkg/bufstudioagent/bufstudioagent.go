@@ -31,7 +31,12 @@ func NewHandler(
 	disallowedHeaders map[string]struct{},
 	forwardHeaders map[string]string,
 	privateNetwork bool,
+	options ...HandlerOption,
 ) http.Handler {
+	handlerOptions := newHandlerOptions()
+	for _, option := range options {
+		option(handlerOptions)
+	}
 	corsHandlerOptions := cors.Options{
 		AllowedOrigins:   []string{origin},
 		AllowedMethods:   []string{http.MethodPost, http.MethodOptions},
@@ -41,7 +46,7 @@ func NewHandler(
 		corsHandlerOptions.AllowPrivateNetwork = true
 	}
 	corsHandler := cors.New(corsHandlerOptions)
-	plainHandler := corsHandler.Handler(newPlainPostHandler(logger, disallowedHeaders, forwardHeaders, tlsClientConfig))
+	plainHandler := corsHandler.Handler(newPlainPostHandler(logger, disallowedHeaders, forwardHeaders, tlsClientConfig, handlerOptions))
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -65,3 +70,37 @@ func NewHandler(
 	})
 	return mux
 }
+
+// HandlerOption is an option for NewHandler.
+type HandlerOption func(*handlerOptions)
+
+// HandlerWithUpstreams returns a new HandlerOption that only forwards requests
+// to the origins of the upstreams, and sets the headers and tokens of the
+// upstream of the origin on each request.
+//
+// The origins of the upstreams must be valid according to NormalizeOrigin.
+//
+// The default is to forward requests to any origin.
+func HandlerWithUpstreams(upstreams ...*Upstream) HandlerOption {
+	return func(handlerOptions *handlerOptions) {
+		handlerOptions.upstreams = append(handlerOptions.upstreams, upstreams...)
+	}
+}
+
+// HandlerWithRedactedHeaders returns a new HandlerOption that replaces the
+// values of the response headers and trailers with the names with "[REDACTED]",
+// so that they are not exposed to the browser.
+func HandlerWithRedactedHeaders(headers ...string) HandlerOption {
+	return func(handlerOptions *handlerOptions) {
+		handlerOptions.redactedHeaders = append(handlerOptions.redactedHeaders, headers...)
+	}
+}
+
+type handlerOptions struct {
+	upstreams       []*Upstream
+	redactedHeaders []string
+}
+
+func newHandlerOptions() *handlerOptions {
+	return &handlerOptions{}
+}
@@ -24,11 +24,15 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
 	studiov1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/studio/v1alpha1"
+	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/protoencoding"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -219,6 +223,202 @@ func testPlainPostHandlerErrors(t *testing.T, upstreamServer *httptest.Server) {
 	})
 }
 
+func TestPlainPostHandlerUpstreams(t *testing.T) {
+	t.Parallel()
+	upstreamServer := newTestConnectServer(t, false)
+	defer upstreamServer.Close()
+	agentServer := httptest.NewTLSServer(
+		NewHandler(
+			zaptest.NewLogger(t),
+			"https://example.buf.build",
+			nil,
+			nil,
+			nil,
+			false,
+			HandlerWithUpstreams(
+				&Upstream{
+					Origin: upstreamServer.URL,
+					Headers: http.Header{
+						"X-Api-Key": []string{"secret"},
+					},
+					TokenSource: testTokenSource("token"),
+					TokenPrefix: "Bearer ",
+				},
+				&Upstream{
+					Origin:      "http://failing.example.com",
+					TokenSource: testTokenSource(""),
+				},
+			),
+			HandlerWithRedactedHeaders("echo-x-api-key"),
+		),
+	)
+	defer agentServer.Close()
+
+	response, invokeResponse := testInvoke(
+		t,
+		agentServer,
+		upstreamServer.URL+echoPath,
+		http.Header{
+			"Content-Type":  []string{"application/proto"},
+			"Authorization": []string{"Bearer browser"},
+		},
+	)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	upstreamResponseHeaders := make(http.Header)
+	addProtoHeadersToGoHeader(invokeResponse.Headers, upstreamResponseHeaders)
+	// The token of the upstream replaces the header of the request.
+	assert.Equal(t, []string{"Bearer token"}, upstreamResponseHeaders.Values("Echo-Authorization"))
+	assert.Equal(t, []string{redactedHeaderValue}, upstreamResponseHeaders.Values("Echo-X-Api-Key"))
+
+	response, _ = testInvoke(
+		t,
+		agentServer,
+		"http://other.example.com"+echoPath,
+		http.Header{"Content-Type": []string{"application/proto"}},
+	)
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+	response, _ = testInvoke(
+		t,
+		agentServer,
+		"http://FAILING.example.com:80"+echoPath,
+		http.Header{"Content-Type": []string{"application/proto"}},
+	)
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+}
+
+func TestPlainPostHandlerUpstreamsRedirect(t *testing.T) {
+	t.Parallel()
+	var redirectTargetRequests int
+	redirectTargetServer := httptest.NewServer(
+		h2c.NewHandler(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				redirectTargetRequests++
+			}),
+			&http2.Server{},
+		),
+	)
+	defer redirectTargetServer.Close()
+	upstreamServer := httptest.NewServer(
+		h2c.NewHandler(
+			http.RedirectHandler(redirectTargetServer.URL, http.StatusTemporaryRedirect),
+			&http2.Server{},
+		),
+	)
+	defer upstreamServer.Close()
+
+	handler := newPlainPostHandler(zaptest.NewLogger(t), nil, nil, nil, &handlerOptions{})
+	response, err := handler.H2CClient.Get(upstreamServer.URL)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 1, redirectTargetRequests)
+
+	// The headers of an upstream are never sent to the redirect target.
+	handler = newPlainPostHandler(
+		zaptest.NewLogger(t),
+		nil,
+		nil,
+		nil,
+		&handlerOptions{
+			upstreams: []*Upstream{
+				{
+					Origin: upstreamServer.URL,
+				},
+			},
+		},
+	)
+	for _, client := range []*http.Client{handler.H2CClient, handler.TLSClient} {
+		assert.Equal(t, http.ErrUseLastResponse, client.CheckRedirect(nil, nil))
+	}
+	response, err = handler.H2CClient.Get(upstreamServer.URL)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusTemporaryRedirect, response.StatusCode)
+	assert.Equal(t, 1, redirectTargetRequests)
+}
+
+func TestCommandTokenSource(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("the token command is a shell script")
+	}
+	// The command prints a new token every time it is run, and only uses shell
+	// builtins, as it is run without environment variables.
+	args := []string{
+		"-c",
+		`n=0; [ -f "$0" ] && read n < "$0"; n=$((n+1)); echo $n > "$0"; echo " token-$n "`,
+		filepath.Join(t.TempDir(), "count"),
+	}
+	runner := command.NewRunner()
+	tokenSource := NewCommandTokenSource(runner, "sh", args, CommandTokenSourceWithTTL(time.Hour))
+	for i := 0; i < 2; i++ {
+		token, err := tokenSource.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", token)
+	}
+	tokenSource = NewCommandTokenSource(runner, "sh", args)
+	token, err := tokenSource.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+	token, err = tokenSource.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-3", token)
+}
+
+func TestNormalizeOrigin(t *testing.T) {
+	t.Parallel()
+	origin, err := NormalizeOrigin("HTTPS://Example.com/path")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com:443", origin)
+	origin, err = NormalizeOrigin("http://[::1]:8080")
+	require.NoError(t, err)
+	assert.Equal(t, "http://[::1]:8080", origin)
+	_, err = NormalizeOrigin("ftp://example.com")
+	assert.Error(t, err)
+	_, err = NormalizeOrigin("https://")
+	assert.Error(t, err)
+}
+
+func testInvoke(
+	t *testing.T,
+	agentServer *httptest.Server,
+	target string,
+	header http.Header,
+) (*http.Response, *studiov1alpha1.InvokeResponse) {
+	requestBytes := protoMarshalBase64(
+		t,
+		&studiov1alpha1.InvokeRequest{
+			Target:  target,
+			Headers: goHeadersToProtoHeaders(header),
+			Body:    []byte("echothis"),
+		},
+	)
+	request, err := http.NewRequest(http.MethodPost, agentServer.URL, bytes.NewReader(requestBytes))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "text/plain")
+	request.Header.Set("Origin", "https://example.buf.build")
+	response, err := agentServer.Client().Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	responseBytes, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	invokeResponse := &studiov1alpha1.InvokeResponse{}
+	if response.StatusCode == http.StatusOK {
+		protoUnmarshalBase64(t, responseBytes, invokeResponse)
+	}
+	return response, invokeResponse
+}
+
+type testTokenSource string
+
+func (s testTokenSource) Token(context.Context) (string, error) {
+	if s == "" {
+		return "", errors.New("no token")
+	}
+	return string(s), nil
+}
+
 func newTestConnectServer(t *testing.T, tls bool) *httptest.Server {
 	mux := http.NewServeMux()
 	// echoPath echoes all incoming headers (prefixed with "Echo-") and the
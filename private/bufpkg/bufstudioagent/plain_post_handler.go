@@ -38,6 +38,9 @@ import (
 // from the request body.
 const MaxMessageSizeBytesDefault = 1024 * 1024 * 5
 
+// redactedHeaderValue is the value of redacted headers.
+const redactedHeaderValue = "[REDACTED]"
+
 // plainPostHandler implements a POST handler for forwarding requests that can
 // be called with simple CORS requests.
 //
@@ -57,6 +60,10 @@ type plainPostHandler struct {
 	H2CClient           *http.Client
 	DisallowedHeaders   map[string]struct{}
 	ForwardHeaders      map[string]string
+	// Upstreams are the upstreams by normalized origin, or nil if requests
+	// to any origin are forwarded.
+	Upstreams       map[string]*Upstream
+	RedactedHeaders map[string]struct{}
 }
 
 func newPlainPostHandler(
@@ -64,6 +71,7 @@ func newPlainPostHandler(
 	disallowedHeaders map[string]struct{},
 	forwardHeaders map[string]string,
 	tlsClientConfig *tls.Config,
+	handlerOptions *handlerOptions,
 ) *plainPostHandler {
 	canonicalDisallowedHeaders := make(map[string]struct{}, len(disallowedHeaders))
 	for k := range disallowedHeaders {
@@ -73,6 +81,29 @@ func newPlainPostHandler(
 	for k, v := range forwardHeaders {
 		canonicalForwardHeaders[textproto.CanonicalMIMEHeaderKey(k)] = v
 	}
+	var upstreams map[string]*Upstream
+	if len(handlerOptions.upstreams) > 0 {
+		upstreams = make(map[string]*Upstream, len(handlerOptions.upstreams))
+		for _, upstream := range handlerOptions.upstreams {
+			// Upstreams with invalid origins never match a target.
+			if origin, err := NormalizeOrigin(upstream.Origin); err == nil {
+				upstreams[origin] = upstream
+			}
+		}
+	}
+	redactedHeaders := make(map[string]struct{}, len(handlerOptions.redactedHeaders))
+	for _, header := range handlerOptions.redactedHeaders {
+		redactedHeaders[textproto.CanonicalMIMEHeaderKey(header)] = struct{}{}
+	}
+	var checkRedirect func(*http.Request, []*http.Request) error
+	if upstreams != nil {
+		// Redirects are not followed, as the headers and tokens of an upstream
+		// must only be sent to its origin, and the client copies headers to
+		// the redirect target. The redirect response is returned instead.
+		checkRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
 	return &plainPostHandler{
 		B64Encoding:       base64.StdEncoding,
 		DisallowedHeaders: canonicalDisallowedHeaders,
@@ -84,6 +115,7 @@ func newPlainPostHandler(
 					return net.Dial(netw, addr)
 				},
 			},
+			CheckRedirect: checkRedirect,
 		},
 		Logger:              logger,
 		MaxMessageSizeBytes: MaxMessageSizeBytesDefault,
@@ -91,7 +123,10 @@ func newPlainPostHandler(
 			Transport: &http2.Transport{
 				TLSClientConfig: tlsClientConfig,
 			},
+			CheckRedirect: checkRedirect,
 		},
+		Upstreams:       upstreams,
+		RedactedHeaders: redactedHeaders,
 	}
 }
 
@@ -157,6 +192,30 @@ func (i *plainPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("must specify http or https url scheme, got %q", targetURL.Scheme), http.StatusBadRequest)
 		return
 	}
+	if i.Upstreams != nil {
+		origin, err := normalizeOrigin(targetURL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		upstream, ok := i.Upstreams[origin]
+		if !ok {
+			http.Error(w, fmt.Sprintf("origin %q disallowed by agent", origin), http.StatusForbidden)
+			return
+		}
+		// The headers of the upstream are set last, so that they take precedence
+		// over the headers of the request.
+		if err := upstream.setHeaders(r.Context(), request.Header()); err != nil {
+			// The error is only logged, as it may contain details of the credentials.
+			i.Logger.Warn(
+				"upstream_token_error",
+				zap.String("origin", origin),
+				zap.Error(err),
+			)
+			http.Error(w, fmt.Sprintf("cannot get token for origin %q", origin), http.StatusBadGateway)
+			return
+		}
+	}
 	clientOptions, err := connectClientOptionsFromContentType(request.Header().Get("Content-Type"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -195,7 +254,7 @@ func (i *plainPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			i.writeProtoMessage(w, &studiov1alpha1.InvokeResponse{
 				// connectErr.Meta contains the trailers for the
 				// caller to find out the error details.
-				Headers: goHeadersToProtoHeaders(connectErr.Meta()),
+				Headers: goHeadersToProtoHeaders(i.redactHeaders(connectErr.Meta())),
 			})
 			return
 		}
@@ -207,9 +266,9 @@ func (i *plainPostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	i.writeProtoMessage(w, &studiov1alpha1.InvokeResponse{
-		Headers:  goHeadersToProtoHeaders(response.Header()),
+		Headers:  goHeadersToProtoHeaders(i.redactHeaders(response.Header())),
 		Body:     response.Msg.Bytes(),
-		Trailers: goHeadersToProtoHeaders(response.Trailer()),
+		Trailers: goHeadersToProtoHeaders(i.redactHeaders(response.Trailer())),
 	})
 }
 
@@ -257,6 +316,26 @@ func (i *plainPostHandler) writeProtoMessage(w http.ResponseWriter, message prot
 	}
 }
 
+// redactHeaders returns a copy of the header with the values of the redacted
+// headers replaced.
+func (i *plainPostHandler) redactHeaders(header http.Header) http.Header {
+	if len(i.RedactedHeaders) == 0 {
+		return header
+	}
+	redactedHeader := make(http.Header, len(header))
+	for key, values := range header {
+		if _, ok := i.RedactedHeaders[textproto.CanonicalMIMEHeaderKey(key)]; ok {
+			redactedValues := make([]string, len(values))
+			for j := range values {
+				redactedValues[j] = redactedHeaderValue
+			}
+			values = redactedValues
+		}
+		redactedHeader[key] = values
+	}
+	return redactedHeader
+}
+
 func goHeadersToProtoHeaders(in http.Header) []*studiov1alpha1.Headers {
 	var out []*studiov1alpha1.Headers
 	for k, v := range in {
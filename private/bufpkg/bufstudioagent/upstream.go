@@ -0,0 +1,205 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufstudioagent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/command"
+)
+
+// Upstream is the configuration of the requests to an origin that the agent
+// forwards requests to.
+type Upstream struct {
+	// Origin is the scheme, host, and optional port of the targets of the
+	// requests, such as "https://orders.internal.example.com".
+	Origin string
+	// Headers are set on each request to the origin, replacing the headers of
+	// the request with the same names.
+	Headers http.Header
+	// TokenSource provides the token that is set on each request to the origin,
+	// if not nil.
+	TokenSource TokenSource
+	// TokenHeader is the header that the token is set in, replacing the header of
+	// the request with the same name. "Authorization" if empty.
+	TokenHeader string
+	// TokenPrefix is the prefix of the token in the header, such as "Bearer ".
+	TokenPrefix string
+}
+
+// TokenSource provides tokens for the requests to an Upstream.
+type TokenSource interface {
+	// Token returns the current token.
+	Token(ctx context.Context) (string, error)
+}
+
+// NewCommandTokenSource returns a new TokenSource that runs the command with the
+// arguments, and uses its output without leading and trailing whitespace as the
+// token, such as "gcloud auth print-access-token".
+//
+// The command is run for every token unless CommandTokenSourceWithTTL is used.
+func NewCommandTokenSource(
+	runner command.Runner,
+	name string,
+	args []string,
+	options ...CommandTokenSourceOption,
+) TokenSource {
+	return newCommandTokenSource(runner, name, args, options...)
+}
+
+// CommandTokenSourceOption is an option for NewCommandTokenSource.
+type CommandTokenSourceOption func(*commandTokenSource)
+
+// CommandTokenSourceWithEnv returns a new CommandTokenSourceOption that runs the
+// command with the environment variables.
+//
+// The default is to run the command without any environment variables.
+func CommandTokenSourceWithEnv(env map[string]string) CommandTokenSourceOption {
+	return func(commandTokenSource *commandTokenSource) {
+		commandTokenSource.env = env
+	}
+}
+
+// CommandTokenSourceWithTTL returns a new CommandTokenSourceOption that reuses
+// the token for the duration after the command was run.
+func CommandTokenSourceWithTTL(ttl time.Duration) CommandTokenSourceOption {
+	return func(commandTokenSource *commandTokenSource) {
+		commandTokenSource.ttl = ttl
+	}
+}
+
+// NormalizeOrigin returns the origin of the URL in the form scheme://host:port,
+// with the default port of the scheme if the URL has no port.
+//
+// The URL must have the http or https scheme.
+func NormalizeOrigin(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return normalizeOrigin(parsedURL)
+}
+
+func normalizeOrigin(parsedURL *url.URL) (string, error) {
+	scheme := strings.ToLower(parsedURL.Scheme)
+	var defaultPort string
+	switch scheme {
+	case "http":
+		defaultPort = "80"
+	case "https":
+		defaultPort = "443"
+	default:
+		return "", fmt.Errorf("must specify http or https url scheme, got %q", parsedURL.Scheme)
+	}
+	if parsedURL.Hostname() == "" {
+		return "", fmt.Errorf("url %q has no host", parsedURL.String())
+	}
+	port := parsedURL.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return scheme + "://" + net.JoinHostPort(strings.ToLower(parsedURL.Hostname()), port), nil
+}
+
+// setHeaders sets the headers and the token of the upstream on the header.
+func (u *Upstream) setHeaders(ctx context.Context, header http.Header) error {
+	for key, values := range u.Headers {
+		header.Del(key)
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+	if u.TokenSource == nil {
+		return nil
+	}
+	token, err := u.TokenSource.Token(ctx)
+	if err != nil {
+		return err
+	}
+	tokenHeader := u.TokenHeader
+	if tokenHeader == "" {
+		tokenHeader = "Authorization"
+	}
+	header.Set(tokenHeader, u.TokenPrefix+token)
+	return nil
+}
+
+type commandTokenSource struct {
+	runner command.Runner
+	name   string
+	args   []string
+	env    map[string]string
+	ttl    time.Duration
+
+	lock       sync.Mutex
+	token      string
+	expiryTime time.Time
+}
+
+func newCommandTokenSource(
+	runner command.Runner,
+	name string,
+	args []string,
+	options ...CommandTokenSourceOption,
+) *commandTokenSource {
+	commandTokenSource := &commandTokenSource{
+		runner: runner,
+		name:   name,
+		args:   args,
+	}
+	for _, option := range options {
+		option(commandTokenSource)
+	}
+	return commandTokenSource
+}
+
+func (c *commandTokenSource) Token(ctx context.Context) (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.token != "" && time.Now().Before(c.expiryTime) {
+		return c.token, nil
+	}
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	if err := c.runner.Run(
+		ctx,
+		c.name,
+		command.RunWithArgs(c.args...),
+		command.RunWithEnv(c.env),
+		command.RunWithStdout(stdout),
+		command.RunWithStderr(stderr),
+	); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("token command %q: %w: %s", c.name, err, message)
+		}
+		return "", fmt.Errorf("token command %q: %w", c.name, err)
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("token command %q: %w", c.name, errors.New("empty output"))
+	}
+	c.token = token
+	c.expiryTime = time.Now().Add(c.ttl)
+	return token, nil
+}
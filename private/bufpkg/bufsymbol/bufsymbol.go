@@ -0,0 +1,223 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufsymbol indexes the symbols that are declared in an image, and the
+// references to them by name.
+//
+// The symbols are the messages, enums, enum values, fields, extensions, oneofs,
+// services, and methods of the files of the image, including its imports. The
+// references are the type names of fields, the extendees of extensions, and the
+// input and output types of methods.
+package bufsymbol
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protosource"
+)
+
+const (
+	// KindMessage is the Kind of a message.
+	KindMessage Kind = iota + 1
+	// KindEnum is the Kind of an enum.
+	KindEnum
+	// KindEnumValue is the Kind of an enum value.
+	KindEnumValue
+	// KindField is the Kind of a field of a message.
+	KindField
+	// KindExtension is the Kind of an extension.
+	KindExtension
+	// KindOneof is the Kind of a oneof.
+	KindOneof
+	// KindService is the Kind of a service.
+	KindService
+	// KindMethod is the Kind of a method.
+	KindMethod
+)
+
+var (
+	kindToString = map[Kind]string{
+		KindMessage:   "message",
+		KindEnum:      "enum",
+		KindEnumValue: "enum value",
+		KindField:     "field",
+		KindExtension: "extension",
+		KindOneof:     "oneof",
+		KindService:   "service",
+		KindMethod:    "method",
+	}
+)
+
+// Kind is the kind of a Symbol.
+type Kind int
+
+// String implements fmt.Stringer.
+func (k Kind) String() string {
+	s, ok := kindToString[k]
+	if !ok {
+		return strconv.Itoa(int(k))
+	}
+	return s
+}
+
+// Span is a span of a file.
+//
+// Lines and columns are 1-indexed, and the end column is exclusive, as with
+// protosource.Location. A Span is the zero value if the image does not have
+// source code info for it.
+type Span struct {
+	StartLine   int
+	StartColumn int
+	EndLine     int
+	EndColumn   int
+}
+
+// IsZero returns true if the Span is the zero value.
+func (s Span) IsZero() bool {
+	return s == Span{}
+}
+
+// Contains returns true if the position is within the Span.
+//
+// The position just after the end of the Span is also contained, so that a
+// position at the end of a name refers to the name.
+func (s Span) Contains(line int, column int) bool {
+	if s.IsZero() {
+		return false
+	}
+	if line < s.StartLine || line > s.EndLine {
+		return false
+	}
+	if line == s.StartLine && column < s.StartColumn {
+		return false
+	}
+	if line == s.EndLine && column > s.EndColumn {
+		return false
+	}
+	return true
+}
+
+// Symbol is a named descriptor that is declared in an image.
+type Symbol struct {
+	// FullName is the fully-qualified name of the symbol, without a leading
+	// period, for example acme.v1.Order.id.
+	FullName string
+	// Kind is the kind of the symbol.
+	Kind Kind
+	// ImageFile is the file that declares the symbol.
+	ImageFile bufimage.ImageFile
+	// Span is the span of the declaration of the symbol.
+	Span Span
+	// NameSpan is the span of the name of the declaration of the symbol.
+	NameSpan Span
+	// Descriptor is the descriptor of the symbol.
+	//
+	// The descriptor is one of protosource.Message, protosource.Enum,
+	// protosource.EnumValue, protosource.Field, protosource.Oneof,
+	// protosource.Service, or protosource.Method, depending on the Kind.
+	Descriptor protosource.NamedDescriptor
+}
+
+// Declaration returns the declaration of the symbol as it would be written in a
+// .proto file, with the types of fields and methods fully-qualified, for example
+// "repeated acme.v1.Item items = 2;" or "message acme.v1.Order".
+func (s *Symbol) Declaration() string {
+	return getDeclaration(s)
+}
+
+// Reference is a reference by name to a Symbol.
+type Reference struct {
+	// Symbol is the Symbol that is referenced.
+	Symbol *Symbol
+	// Referrer is the Symbol whose declaration contains the reference, which is
+	// a field or extension for type names and extendees, or a method for input
+	// and output types.
+	Referrer *Symbol
+	// ImageFile is the file that contains the reference.
+	ImageFile bufimage.ImageFile
+	// Span is the span of the name of the reference.
+	Span Span
+}
+
+// Index is an index of the symbols of an image.
+type Index struct {
+	symbols                []*Symbol
+	fullNameToSymbol       map[string]*Symbol
+	fullNameToReferences   map[string][]*Reference
+	pathToSymbols          map[string][]*Symbol
+	pathToReferences       map[string][]*Reference
+	pathToImageFile        map[string]bufimage.ImageFile
+	externalPathToFilePath map[string]string
+}
+
+// NewIndex returns a new Index for the image.
+func NewIndex(ctx context.Context, image bufimage.Image) (*Index, error) {
+	return newIndex(ctx, image)
+}
+
+// Symbols returns the symbols of the image, sorted by full name.
+func (i *Index) Symbols() []*Symbol {
+	return i.symbols
+}
+
+// SymbolForFullName returns the Symbol with the given fully-qualified name.
+//
+// A leading period in the name is ignored. Returns nil if there is no such Symbol.
+func (i *Index) SymbolForFullName(fullName string) *Symbol {
+	return i.fullNameToSymbol[trimLeadingPeriod(fullName)]
+}
+
+// References returns the references to the Symbol, sorted by file path and
+// position.
+func (i *Index) References(symbol *Symbol) []*Reference {
+	return i.fullNameToReferences[symbol.FullName]
+}
+
+// ImageFileForPath returns the ImageFile with the given path, or nil if the
+// image does not contain such a file.
+func (i *Index) ImageFileForPath(path string) bufimage.ImageFile {
+	return i.pathToImageFile[path]
+}
+
+// ImageFileForExternalPath returns the ImageFile with the given external path,
+// or nil if the image does not contain such a file.
+func (i *Index) ImageFileForExternalPath(externalPath string) bufimage.ImageFile {
+	path, ok := i.externalPathToFilePath[externalPath]
+	if !ok {
+		return nil
+	}
+	return i.pathToImageFile[path]
+}
+
+// SymbolAtPosition returns the Symbol that is declared or referenced at the
+// position of the file with the given path.
+//
+// The line and column are 1-indexed. References take precedence over declarations,
+// as a reference can be within a declaration, but not the other way around.
+// Returns nil if there is no Symbol at the position.
+func (i *Index) SymbolAtPosition(path string, line int, column int) *Symbol {
+	for _, reference := range i.pathToReferences[path] {
+		if reference.Span.Contains(line, column) {
+			return reference.Symbol
+		}
+	}
+	for _, symbol := range i.pathToSymbols[path] {
+		if symbol.NameSpan.Contains(line, column) {
+			return symbol
+		}
+	}
+	return nil
+}
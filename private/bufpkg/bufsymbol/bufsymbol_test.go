@@ -0,0 +1,113 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsymbol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testOrderProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "acme/v1/customer.proto";
+
+// An order.
+message Order {
+  string id = 1;
+  Customer customer = 2;
+  Status status = 3;
+
+  enum Status {
+    STATUS_UNSPECIFIED = 0;
+  }
+}
+
+service OrderService {
+  rpc GetOrder(Order) returns (Order);
+}
+`
+	testCustomerProto = `syntax = "proto3";
+
+package acme.v1;
+
+message Customer {
+  string id = 1;
+}
+`
+)
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+	image := bufimagetesting.BuildImage(
+		t,
+		map[string]string{
+			"acme/v1/order.proto":    testOrderProto,
+			"acme/v1/customer.proto": testCustomerProto,
+		},
+	)
+	index, err := NewIndex(context.Background(), image)
+	require.NoError(t, err)
+
+	fullNames := make([]string, 0, len(index.Symbols()))
+	for _, symbol := range index.Symbols() {
+		fullNames = append(fullNames, symbol.FullName)
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"acme.v1.Customer",
+			"acme.v1.Customer.id",
+			"acme.v1.Order",
+			"acme.v1.Order.Status",
+			"acme.v1.Order.Status.STATUS_UNSPECIFIED",
+			"acme.v1.Order.customer",
+			"acme.v1.Order.id",
+			"acme.v1.Order.status",
+			"acme.v1.OrderService",
+			"acme.v1.OrderService.GetOrder",
+		},
+		fullNames,
+	)
+
+	customer := index.SymbolForFullName(".acme.v1.Customer")
+	require.NotNil(t, customer)
+	assert.Equal(t, KindMessage, customer.Kind)
+	assert.Equal(t, "acme/v1/customer.proto", customer.ImageFile.Path())
+	assert.Equal(t, Span{StartLine: 5, StartColumn: 9, EndLine: 5, EndColumn: 17}, customer.NameSpan)
+	references := index.References(customer)
+	require.Len(t, references, 1)
+	assert.Equal(t, "acme/v1/order.proto", references[0].ImageFile.Path())
+	assert.Equal(t, index.SymbolForFullName("acme.v1.Order.customer"), references[0].Referrer)
+	assert.Equal(t, Span{StartLine: 10, StartColumn: 3, EndLine: 10, EndColumn: 11}, references[0].Span)
+
+	order := index.SymbolForFullName("acme.v1.Order")
+	require.NotNil(t, order)
+	assert.Len(t, index.References(order), 2)
+
+	// The type of the customer field.
+	assert.Equal(t, customer, index.SymbolAtPosition("acme/v1/order.proto", 10, 5))
+	// The end of the name of the status field.
+	assert.Equal(t, index.SymbolForFullName("acme.v1.Order.status"), index.SymbolAtPosition("acme/v1/order.proto", 11, 13))
+	// The input type of GetOrder.
+	assert.Equal(t, order, index.SymbolAtPosition("acme/v1/order.proto", 19, 16))
+	assert.Nil(t, index.SymbolAtPosition("acme/v1/order.proto", 1, 1))
+}
@@ -0,0 +1,89 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsymbol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/protosource"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// getDeclaration returns the declaration of the symbol as it would be written in
+// a .proto file, with the types of fields and methods fully-qualified.
+func getDeclaration(symbol *Symbol) string {
+	switch descriptor := symbol.Descriptor.(type) {
+	case protosource.Field:
+		var builder strings.Builder
+		if symbol.Kind == KindExtension {
+			builder.WriteString("extend ")
+			builder.WriteString(strings.TrimPrefix(descriptor.Extendee(), "."))
+			builder.WriteString(" { ")
+		}
+		switch descriptor.Label() {
+		case descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+			builder.WriteString("repeated ")
+		case descriptorpb.FieldDescriptorProto_LABEL_REQUIRED:
+			builder.WriteString("required ")
+		case descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL:
+			if descriptor.Proto3Optional() || descriptor.File().Syntax() != protosource.SyntaxProto3 {
+				builder.WriteString("optional ")
+			}
+		}
+		builder.WriteString(getFieldTypeName(descriptor))
+		builder.WriteString(" ")
+		builder.WriteString(descriptor.Name())
+		builder.WriteString(" = ")
+		builder.WriteString(strconv.Itoa(descriptor.Number()))
+		builder.WriteString(";")
+		if symbol.Kind == KindExtension {
+			builder.WriteString(" }")
+		}
+		return builder.String()
+	case protosource.Method:
+		return fmt.Sprintf(
+			"rpc %s(%s%s) returns (%s%s);",
+			descriptor.Name(),
+			getStreamingPrefix(descriptor.ClientStreaming()),
+			strings.TrimPrefix(descriptor.InputTypeName(), "."),
+			getStreamingPrefix(descriptor.ServerStreaming()),
+			strings.TrimPrefix(descriptor.OutputTypeName(), "."),
+		)
+	case protosource.EnumValue:
+		return fmt.Sprintf("%s = %d;", symbol.FullName, descriptor.Number())
+	default:
+		return fmt.Sprintf("%s %s", symbol.Kind.String(), symbol.FullName)
+	}
+}
+
+func getFieldTypeName(field protosource.Field) string {
+	switch field.Type() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptorpb.FieldDescriptorProto_TYPE_ENUM,
+		descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		return strings.TrimPrefix(field.TypeName(), ".")
+	default:
+		return strings.ToLower(strings.TrimPrefix(field.Type().String(), "TYPE_"))
+	}
+}
+
+func getStreamingPrefix(streaming bool) string {
+	if streaming {
+		return "stream "
+	}
+	return ""
+}
@@ -0,0 +1,216 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsymbol
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
+	"github.com/bufbuild/buf/private/pkg/protosource"
+)
+
+func newIndex(ctx context.Context, image bufimage.Image) (*Index, error) {
+	files, err := protosource.NewFilesUnstable(ctx, bufimageutil.NewInputFiles(image.Files())...)
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{
+		fullNameToSymbol:       make(map[string]*Symbol),
+		fullNameToReferences:   make(map[string][]*Reference),
+		pathToSymbols:          make(map[string][]*Symbol),
+		pathToReferences:       make(map[string][]*Reference),
+		pathToImageFile:        make(map[string]bufimage.ImageFile),
+		externalPathToFilePath: make(map[string]string),
+	}
+	for _, imageFile := range image.Files() {
+		index.pathToImageFile[imageFile.Path()] = imageFile
+		index.externalPathToFilePath[imageFile.ExternalPath()] = imageFile.Path()
+	}
+	// The references are resolved once all symbols are known, as a
+	// file can reference symbols that are declared in files after it.
+	var pendingReferences []*pendingReference
+	for _, file := range files {
+		indexer := &fileIndexer{
+			index:     index,
+			imageFile: index.pathToImageFile[file.Path()],
+		}
+		indexer.indexFile(file)
+		pendingReferences = append(pendingReferences, indexer.pendingReferences...)
+	}
+	for _, pendingReference := range pendingReferences {
+		symbol := index.fullNameToSymbol[pendingReference.fullName]
+		if symbol == nil {
+			// Scalar types, or types that the image does not contain.
+			continue
+		}
+		reference := &Reference{
+			Symbol:    symbol,
+			Referrer:  pendingReference.referrer,
+			ImageFile: pendingReference.imageFile,
+			Span:      pendingReference.span,
+		}
+		index.fullNameToReferences[symbol.FullName] = append(index.fullNameToReferences[symbol.FullName], reference)
+		path := reference.ImageFile.Path()
+		index.pathToReferences[path] = append(index.pathToReferences[path], reference)
+	}
+	sort.Slice(
+		index.symbols,
+		func(i int, j int) bool {
+			return index.symbols[i].FullName < index.symbols[j].FullName
+		},
+	)
+	for _, references := range index.fullNameToReferences {
+		sortReferences(references)
+	}
+	for _, references := range index.pathToReferences {
+		sortReferences(references)
+	}
+	return index, nil
+}
+
+type pendingReference struct {
+	fullName  string
+	referrer  *Symbol
+	imageFile bufimage.ImageFile
+	span      Span
+}
+
+type fileIndexer struct {
+	index             *Index
+	imageFile         bufimage.ImageFile
+	pendingReferences []*pendingReference
+}
+
+func (f *fileIndexer) indexFile(file protosource.File) {
+	for _, enum := range file.Enums() {
+		f.indexEnum(enum)
+	}
+	for _, message := range file.Messages() {
+		f.indexMessage(message)
+	}
+	for _, extension := range file.Extensions() {
+		f.indexField(extension, KindExtension)
+	}
+	for _, service := range file.Services() {
+		f.addSymbol(service, KindService)
+		for _, method := range service.Methods() {
+			methodSymbol := f.addSymbol(method, KindMethod)
+			f.addReference(methodSymbol, method.InputTypeName(), method.InputTypeLocation())
+			f.addReference(methodSymbol, method.OutputTypeName(), method.OutputTypeLocation())
+		}
+	}
+}
+
+func (f *fileIndexer) indexMessage(message protosource.Message) {
+	f.addSymbol(message, KindMessage)
+	for _, field := range message.Fields() {
+		f.indexField(field, KindField)
+	}
+	for _, oneof := range message.Oneofs() {
+		f.addSymbol(oneof, KindOneof)
+	}
+	for _, extension := range message.Extensions() {
+		f.indexField(extension, KindExtension)
+	}
+	for _, enum := range message.Enums() {
+		f.indexEnum(enum)
+	}
+	for _, nestedMessage := range message.Messages() {
+		f.indexMessage(nestedMessage)
+	}
+}
+
+func (f *fileIndexer) indexEnum(enum protosource.Enum) {
+	f.addSymbol(enum, KindEnum)
+	for _, enumValue := range enum.Values() {
+		f.addSymbol(enumValue, KindEnumValue)
+	}
+}
+
+func (f *fileIndexer) indexField(field protosource.Field, kind Kind) {
+	fieldSymbol := f.addSymbol(field, kind)
+	if typeName := field.TypeName(); typeName != "" {
+		f.addReference(fieldSymbol, typeName, field.TypeNameLocation())
+	}
+	if extendee := field.Extendee(); extendee != "" {
+		f.addReference(fieldSymbol, extendee, field.ExtendeeLocation())
+	}
+}
+
+func (f *fileIndexer) addSymbol(namedDescriptor protosource.NamedDescriptor, kind Kind) *Symbol {
+	symbol := &Symbol{
+		FullName:   namedDescriptor.FullName(),
+		Kind:       kind,
+		ImageFile:  f.imageFile,
+		Span:       spanForLocation(namedDescriptor.Location()),
+		NameSpan:   spanForLocation(namedDescriptor.NameLocation()),
+		Descriptor: namedDescriptor,
+	}
+	f.index.symbols = append(f.index.symbols, symbol)
+	f.index.fullNameToSymbol[symbol.FullName] = symbol
+	path := f.imageFile.Path()
+	f.index.pathToSymbols[path] = append(f.index.pathToSymbols[path], symbol)
+	return symbol
+}
+
+// addReference adds a reference, which has a zero span if the image does not
+// have source code info for it.
+func (f *fileIndexer) addReference(referrer *Symbol, fullName string, location protosource.Location) {
+	f.pendingReferences = append(
+		f.pendingReferences,
+		&pendingReference{
+			fullName:  trimLeadingPeriod(fullName),
+			referrer:  referrer,
+			imageFile: f.imageFile,
+			span:      spanForLocation(location),
+		},
+	)
+}
+
+func spanForLocation(location protosource.Location) Span {
+	if location == nil {
+		return Span{}
+	}
+	return Span{
+		StartLine:   location.StartLine(),
+		StartColumn: location.StartColumn(),
+		EndLine:     location.EndLine(),
+		EndColumn:   location.EndColumn(),
+	}
+}
+
+func sortReferences(references []*Reference) {
+	sort.SliceStable(
+		references,
+		func(i int, j int) bool {
+			one := references[i]
+			two := references[j]
+			if one.ImageFile.Path() != two.ImageFile.Path() {
+				return one.ImageFile.Path() < two.ImageFile.Path()
+			}
+			if one.Span.StartLine != two.Span.StartLine {
+				return one.Span.StartLine < two.Span.StartLine
+			}
+			return one.Span.StartColumn < two.Span.StartColumn
+		},
+	)
+}
+
+func trimLeadingPeriod(fullName string) string {
+	return strings.TrimPrefix(fullName, ".")
+}
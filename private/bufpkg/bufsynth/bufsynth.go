@@ -0,0 +1,77 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufsynth synthesizes messages from models learned from sample messages.
+//
+// A model records, for every field of every message seen in the samples, how often
+// the field is set, the lengths of repeated and map fields, and the values of the
+// field. Synthesized messages draw from these distributions, so that they resemble
+// the samples without being copies of them.
+//
+// Fields can be anonymized, in which case their values are never recorded. Only the
+// shape of anonymized values is recorded: the lengths of strings and bytes, the range
+// of numbers, and the ratio of true to false bools. Synthesized values for anonymized
+// fields are random values of the same shape.
+package bufsynth
+
+import (
+	"math/rand"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldFilter returns true for the fields it selects.
+type FieldFilter func(fieldDescriptor protoreflect.FieldDescriptor) bool
+
+// NewFieldOptionFilter returns a new FieldFilter that selects the fields that have
+// the given field option set to the given value.
+//
+// The option is of the form "(corp.pii)=true", where the parentheses are optional.
+// If the value is omitted, it defaults to "true". Enum values are compared by name.
+// The extension declaring the option must be resolvable by the resolver.
+func NewFieldOptionFilter(resolver protoencoding.Resolver, option string) (FieldFilter, error) {
+	return newFieldOptionFilter(resolver, option)
+}
+
+// Model is a model of a message type learned from samples.
+type Model interface {
+	// Synthesize returns a new synthetic message.
+	//
+	// The same sequence of random values results in the same message.
+	Synthesize(random *rand.Rand) proto.Message
+}
+
+// Learn learns a Model of the message type from the samples.
+//
+// All samples must be of the message type. At least one sample is required.
+func Learn(
+	messageDescriptor protoreflect.MessageDescriptor,
+	samples []proto.Message,
+	options ...LearnOption,
+) (Model, error) {
+	return learn(messageDescriptor, samples, options...)
+}
+
+// LearnOption is an option for Learn.
+type LearnOption func(*learnOptions)
+
+// LearnWithAnonymizedFields returns a new LearnOption that anonymizes the fields
+// selected by the FieldFilter.
+func LearnWithAnonymizedFields(fieldFilter FieldFilter) LearnOption {
+	return func(learnOptions *learnOptions) {
+		learnOptions.anonymizedFieldFilters = append(learnOptions.anonymizedFieldFilters, fieldFilter)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsynth
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testProto = `syntax = "proto3";
+
+package acme.v1;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.FieldOptions {
+  bool pii = 50000;
+}
+
+message Order {
+  string id = 1;
+  string email = 2 [(pii) = true];
+  int64 quantity = 3 [(pii) = true];
+  repeated Item items = 4;
+  map<string, string> labels = 5;
+  oneof payment {
+    string card = 6;
+    string invoice = 7;
+  }
+  string note = 8;
+}
+
+message Item {
+  string sku = 1;
+  string owner = 2 [(pii) = true];
+}
+`
+
+var testSamples = []string{
+	`{"id": "order-1", "email": "alice@example.com", "quantity": 10, "items": [{"sku": "sku-1", "owner": "alice"}], "labels": {"region": "eu"}, "card": "4111"}`,
+	`{"id": "order-2", "email": "bob@example.com", "quantity": 20, "items": [{"sku": "sku-2", "owner": "bob"}, {"sku": "sku-3", "owner": "bob"}], "invoice": "inv-1"}`,
+	`{"id": "order-3", "email": "carol@example.com", "quantity": 30, "labels": {"region": "us"}, "card": "4222"}`,
+}
+
+func TestSynthesize(t *testing.T) {
+	t.Parallel()
+	messageDescriptor, resolver := testGetMessageDescriptor(t, "acme.v1.Order")
+	samples := testGetSamples(t, messageDescriptor, resolver)
+	fieldFilter, err := NewFieldOptionFilter(resolver, "(acme.v1.pii)=true")
+	require.NoError(t, err)
+	model, err := Learn(messageDescriptor, samples, LearnWithAnonymizedFields(fieldFilter))
+	require.NoError(t, err)
+
+	sampleValues := make(map[string]struct{})
+	for _, sample := range samples {
+		testRangeStrings(sample.ProtoReflect(), func(_ protoreflect.FieldDescriptor, value string) {
+			sampleValues[value] = struct{}{}
+		})
+	}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		message := model.Synthesize(random).ProtoReflect()
+		fields := messageDescriptor.Fields()
+		assert.True(t, message.Has(fields.ByName("id")))
+		assert.True(t, message.Has(fields.ByName("email")))
+		assert.False(t, message.Has(fields.ByName("note")))
+		assert.False(t, message.Has(fields.ByName("card")) && message.Has(fields.ByName("invoice")))
+		quantity := message.Get(fields.ByName("quantity")).Int()
+		assert.GreaterOrEqual(t, quantity, int64(10))
+		assert.LessOrEqual(t, quantity, int64(30))
+		assert.LessOrEqual(t, message.Get(fields.ByName("items")).List().Len(), 2)
+		testRangeStrings(message, func(fieldDescriptor protoreflect.FieldDescriptor, value string) {
+			_, isSampleValue := sampleValues[value]
+			if fieldFilter(fieldDescriptor) {
+				assert.False(t, isSampleValue, "anonymized value %q of %s is a sample value", value, fieldDescriptor.FullName())
+			} else {
+				assert.True(t, isSampleValue, "value %q of %s is not a sample value", value, fieldDescriptor.FullName())
+			}
+		})
+	}
+}
+
+func TestSynthesizeDeterministic(t *testing.T) {
+	t.Parallel()
+	messageDescriptor, resolver := testGetMessageDescriptor(t, "acme.v1.Order")
+	samples := testGetSamples(t, messageDescriptor, resolver)
+	model, err := Learn(messageDescriptor, samples)
+	require.NoError(t, err)
+	otherModel, err := Learn(messageDescriptor, samples)
+	require.NoError(t, err)
+	random := rand.New(rand.NewSource(42))
+	otherRandom := rand.New(rand.NewSource(42))
+	for i := 0; i < 10; i++ {
+		assert.True(t, proto.Equal(model.Synthesize(random), otherModel.Synthesize(otherRandom)))
+	}
+}
+
+func TestLearnError(t *testing.T) {
+	t.Parallel()
+	messageDescriptor, resolver := testGetMessageDescriptor(t, "acme.v1.Order")
+	_, err := Learn(messageDescriptor, nil)
+	require.Error(t, err)
+	itemMessageDescriptor, _ := testGetMessageDescriptor(t, "acme.v1.Item")
+	_, err = Learn(messageDescriptor, []proto.Message{dynamicpb.NewMessage(itemMessageDescriptor)})
+	require.ErrorContains(t, err, "sample 1 is of type acme.v1.Item but expected acme.v1.Order")
+	_, err = NewFieldOptionFilter(resolver, "acme.v1.missing")
+	require.Error(t, err)
+}
+
+func TestParseFieldOption(t *testing.T) {
+	t.Parallel()
+	testParseFieldOption(t, "(corp.pii)=true", "corp.pii", "true")
+	testParseFieldOption(t, "corp.pii", "corp.pii", "true")
+	testParseFieldOption(t, "(corp.sensitivity) = HIGH", "corp.sensitivity", "HIGH")
+	testParseFieldOption(t, `corp.label="secret"`, "corp.label", "secret")
+	_, _, err := parseFieldOption("=true")
+	require.Error(t, err)
+	_, _, err = parseFieldOption("corp.pii=")
+	require.Error(t, err)
+}
+
+func testParseFieldOption(t *testing.T, option string, expectedName string, expectedValue string) {
+	name, value, err := parseFieldOption(option)
+	require.NoError(t, err)
+	assert.Equal(t, expectedName, name)
+	assert.Equal(t, expectedValue, value)
+}
+
+func testRangeStrings(message protoreflect.Message, f func(protoreflect.FieldDescriptor, string)) {
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			switch {
+			case fieldDescriptor.IsList():
+				list := value.List()
+				for i := 0; i < list.Len(); i++ {
+					if fieldDescriptor.Kind() == protoreflect.MessageKind {
+						testRangeStrings(list.Get(i).Message(), f)
+					} else if fieldDescriptor.Kind() == protoreflect.StringKind {
+						f(fieldDescriptor, list.Get(i).String())
+					}
+				}
+			case fieldDescriptor.IsMap():
+				value.Map().Range(
+					func(mapKey protoreflect.MapKey, mapValue protoreflect.Value) bool {
+						f(fieldDescriptor.MapKey(), mapKey.String())
+						f(fieldDescriptor.MapValue(), mapValue.String())
+						return true
+					},
+				)
+			case fieldDescriptor.Kind() == protoreflect.MessageKind:
+				testRangeStrings(value.Message(), f)
+			case fieldDescriptor.Kind() == protoreflect.StringKind:
+				f(fieldDescriptor, value.String())
+			}
+			return true
+		},
+	)
+}
+
+func testGetSamples(
+	t *testing.T,
+	messageDescriptor protoreflect.MessageDescriptor,
+	resolver protoencoding.Resolver,
+) []proto.Message {
+	unmarshaler := protoencoding.NewJSONUnmarshaler(resolver)
+	samples := make([]proto.Message, 0, len(testSamples))
+	for _, testSample := range testSamples {
+		sample := dynamicpb.NewMessage(messageDescriptor)
+		require.NoError(t, unmarshaler.Unmarshal([]byte(testSample), sample))
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+func testGetMessageDescriptor(t *testing.T, typeName string) (protoreflect.MessageDescriptor, protoencoding.Resolver) {
+	image := bufimagetesting.BuildImage(t, map[string]string{"acme.proto": testProto})
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptorProtos(image)...)
+	require.NoError(t, err)
+	messageType, err := resolver.FindMessageByName(protoreflect.FullName(typeName))
+	require.NoError(t, err)
+	return messageType.Descriptor(), resolver
+}
@@ -0,0 +1,109 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsynth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const defaultFieldOptionValue = "true"
+
+func newFieldOptionFilter(resolver protoencoding.Resolver, option string) (FieldFilter, error) {
+	optionName, optionValue, err := parseFieldOption(option)
+	if err != nil {
+		return nil, err
+	}
+	extensionType, err := resolver.FindExtensionByName(protoreflect.FullName(optionName))
+	if err != nil {
+		return nil, fmt.Errorf("could not find option %q: %w", optionName, err)
+	}
+	extensionTypeDescriptor := extensionType.TypeDescriptor()
+	if containingMessage := extensionTypeDescriptor.ContainingMessage().FullName(); containingMessage != "google.protobuf.FieldOptions" {
+		return nil, fmt.Errorf("option %q must extend google.protobuf.FieldOptions but extends %s", optionName, containingMessage)
+	}
+	if extensionTypeDescriptor.IsList() {
+		return nil, fmt.Errorf("option %q must not be repeated", optionName)
+	}
+	switch kind := extensionTypeDescriptor.Kind(); kind {
+	case protoreflect.MessageKind, protoreflect.GroupKind, protoreflect.BytesKind:
+		return nil, fmt.Errorf("option %q must be of a scalar or enum type but is of type %s", optionName, kind)
+	}
+	return func(fieldDescriptor protoreflect.FieldDescriptor) bool {
+		fieldOptions, ok := fieldDescriptor.Options().(*descriptorpb.FieldOptions)
+		if !ok || fieldOptions == nil {
+			return false
+		}
+		data, err := proto.Marshal(fieldOptions)
+		if err != nil {
+			return false
+		}
+		// Custom options are unrecognized fields until the options are parsed
+		// with a resolver that contains the extension.
+		reparsedFieldOptions := &descriptorpb.FieldOptions{}
+		if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(data, reparsedFieldOptions); err != nil {
+			return false
+		}
+		reflectFieldOptions := reparsedFieldOptions.ProtoReflect()
+		if !reflectFieldOptions.Has(extensionTypeDescriptor) {
+			return false
+		}
+		return formatFieldOptionValue(
+			extensionTypeDescriptor,
+			reflectFieldOptions.Get(extensionTypeDescriptor),
+		) == optionValue
+	}, nil
+}
+
+// parseFieldOption parses an option of the form "(corp.pii)=true" into its name and value.
+func parseFieldOption(option string) (string, string, error) {
+	optionName, optionValue := option, defaultFieldOptionValue
+	if index := strings.IndexByte(option, '='); index >= 0 {
+		optionName, optionValue = option[:index], strings.TrimSpace(option[index+1:])
+	}
+	optionName = strings.TrimSpace(optionName)
+	if strings.HasPrefix(optionName, "(") && strings.HasSuffix(optionName, ")") {
+		optionName = strings.TrimSuffix(strings.TrimPrefix(optionName, "("), ")")
+	}
+	if optionName == "" {
+		return "", "", fmt.Errorf("invalid option %q: no option name", option)
+	}
+	if optionValue == "" {
+		return "", "", fmt.Errorf("invalid option %q: no option value", option)
+	}
+	return optionName, strings.Trim(optionValue, `"`), nil
+}
+
+func formatFieldOptionValue(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(value.Bool())
+	case protoreflect.StringKind:
+		return value.String()
+	case protoreflect.EnumKind:
+		if enumValue := fieldDescriptor.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.Itoa(int(value.Enum()))
+	default:
+		return fmt.Sprintf("%v", value.Interface())
+	}
+}
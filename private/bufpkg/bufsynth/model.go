@@ -0,0 +1,454 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsynth
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// maxObservations is the maximum number of observations of values and
+	// lengths recorded per field. Beyond this, observations are reservoir sampled.
+	maxObservations = 1024
+	// learnSeed seeds the reservoir sampling so that learning is deterministic.
+	learnSeed = 1
+
+	anonymizedStringCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+func learn(
+	messageDescriptor protoreflect.MessageDescriptor,
+	samples []proto.Message,
+	options ...LearnOption,
+) (Model, error) {
+	learnOptions := newLearnOptions()
+	for _, option := range options {
+		option(learnOptions)
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("at least one sample is required")
+	}
+	learner := &learner{
+		random:       rand.New(rand.NewSource(learnSeed)),
+		learnOptions: learnOptions,
+	}
+	messageModel := learner.newMessageModel(messageDescriptor)
+	for i, sample := range samples {
+		reflectSample := sample.ProtoReflect()
+		if sampleFullName := reflectSample.Descriptor().FullName(); sampleFullName != messageDescriptor.FullName() {
+			return nil, fmt.Errorf("sample %d is of type %s but expected %s", i+1, sampleFullName, messageDescriptor.FullName())
+		}
+		messageModel.add(reflectSample)
+	}
+	return &model{messageModel: messageModel}, nil
+}
+
+type model struct {
+	messageModel *messageModel
+}
+
+func (m *model) Synthesize(random *rand.Rand) proto.Message {
+	message := dynamicpb.NewMessage(m.messageModel.descriptor)
+	m.messageModel.synthesize(random, message)
+	return message
+}
+
+type learner struct {
+	random       *rand.Rand
+	learnOptions *learnOptions
+}
+
+func (l *learner) newMessageModel(messageDescriptor protoreflect.MessageDescriptor) *messageModel {
+	return &messageModel{
+		learner:     l,
+		descriptor:  messageDescriptor,
+		fieldModels: make(map[protoreflect.FieldNumber]*fieldModel),
+	}
+}
+
+func (l *learner) newFieldModel(fieldDescriptor protoreflect.FieldDescriptor) *fieldModel {
+	anonymized := l.learnOptions.isAnonymized(fieldDescriptor)
+	fieldModel := &fieldModel{
+		descriptor: fieldDescriptor,
+		lengths:    newObservations[int](l.random),
+	}
+	switch {
+	case fieldDescriptor.IsMap():
+		fieldModel.key = l.newValueModel(fieldDescriptor.MapKey(), anonymized)
+		fieldModel.value = l.newValueModel(fieldDescriptor.MapValue(), anonymized)
+	default:
+		fieldModel.value = l.newValueModel(fieldDescriptor, anonymized)
+	}
+	return fieldModel
+}
+
+func (l *learner) newValueModel(fieldDescriptor protoreflect.FieldDescriptor, anonymized bool) *valueModel {
+	valueModel := &valueModel{
+		descriptor: fieldDescriptor,
+		anonymized: anonymized,
+		values:     newObservations[protoreflect.Value](l.random),
+		lengths:    newObservations[int](l.random),
+	}
+	if kind := fieldDescriptor.Kind(); kind == protoreflect.MessageKind || kind == protoreflect.GroupKind {
+		// Nested messages are learned independently of whether the field containing
+		// them is anonymized, the fields of the nested message are anonymized on their own.
+		valueModel.messageModel = l.newMessageModel(fieldDescriptor.Message())
+	}
+	return valueModel
+}
+
+// messageModel models the fields of a message.
+type messageModel struct {
+	learner     *learner
+	descriptor  protoreflect.MessageDescriptor
+	numSamples  int
+	fieldModels map[protoreflect.FieldNumber]*fieldModel
+}
+
+func (m *messageModel) add(message protoreflect.Message) {
+	m.numSamples++
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if fieldDescriptor.IsExtension() {
+				return true
+			}
+			fieldModel, ok := m.fieldModels[fieldDescriptor.Number()]
+			if !ok {
+				fieldModel = m.learner.newFieldModel(fieldDescriptor)
+				m.fieldModels[fieldDescriptor.Number()] = fieldModel
+			}
+			fieldModel.add(value)
+			return true
+		},
+	)
+}
+
+func (m *messageModel) synthesize(random *rand.Rand, message protoreflect.Message) {
+	fields := m.descriptor.Fields()
+	seenOneofs := make(map[protoreflect.FullName]struct{})
+	for i := 0; i < fields.Len(); i++ {
+		fieldDescriptor := fields.Get(i)
+		if oneof := fieldDescriptor.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			if _, ok := seenOneofs[oneof.FullName()]; ok {
+				continue
+			}
+			seenOneofs[oneof.FullName()] = struct{}{}
+			if fieldModel := m.pickOneofFieldModel(random, oneof); fieldModel != nil {
+				fieldModel.synthesize(random, message)
+			}
+			continue
+		}
+		fieldModel, ok := m.fieldModels[fieldDescriptor.Number()]
+		if !ok || random.Intn(m.numSamples) >= fieldModel.numPresent {
+			continue
+		}
+		fieldModel.synthesize(random, message)
+	}
+}
+
+// pickOneofFieldModel picks the field of the oneof to set, weighted by how often
+// each field was set in the samples, or nil if none should be set.
+func (m *messageModel) pickOneofFieldModel(random *rand.Rand, oneof protoreflect.OneofDescriptor) *fieldModel {
+	pick := random.Intn(m.numSamples)
+	oneofFields := oneof.Fields()
+	for i := 0; i < oneofFields.Len(); i++ {
+		fieldModel, ok := m.fieldModels[oneofFields.Get(i).Number()]
+		if !ok {
+			continue
+		}
+		if pick < fieldModel.numPresent {
+			return fieldModel
+		}
+		pick -= fieldModel.numPresent
+	}
+	return nil
+}
+
+// fieldModel models a field of a message.
+type fieldModel struct {
+	descriptor protoreflect.FieldDescriptor
+	// numPresent is the number of samples of the containing message the field was set in.
+	numPresent int
+	// lengths are the lengths of list and map values.
+	lengths *observations[int]
+	// key is only set for maps.
+	key *valueModel
+	// value models the elements of lists and the values of maps.
+	value *valueModel
+}
+
+func (f *fieldModel) add(value protoreflect.Value) {
+	f.numPresent++
+	switch {
+	case f.descriptor.IsList():
+		list := value.List()
+		f.lengths.add(list.Len())
+		for i := 0; i < list.Len(); i++ {
+			f.value.add(list.Get(i))
+		}
+	case f.descriptor.IsMap():
+		mapValue := value.Map()
+		f.lengths.add(mapValue.Len())
+		mapValue.Range(
+			func(mapKey protoreflect.MapKey, mapValue protoreflect.Value) bool {
+				f.key.add(mapKey.Value())
+				f.value.add(mapValue)
+				return true
+			},
+		)
+	default:
+		f.value.add(value)
+	}
+}
+
+func (f *fieldModel) synthesize(random *rand.Rand, message protoreflect.Message) {
+	switch {
+	case f.descriptor.IsList():
+		list := message.Mutable(f.descriptor).List()
+		length := f.lengths.pick(random)
+		for i := 0; i < length; i++ {
+			list.Append(f.value.synthesize(random, list.NewElement))
+		}
+	case f.descriptor.IsMap():
+		mapValue := message.Mutable(f.descriptor).Map()
+		length := f.lengths.pick(random)
+		// Keys can collide, in which case the map is shorter than the picked length.
+		for i := 0; i < length; i++ {
+			mapKey := f.key.synthesize(random, nil).MapKey()
+			mapValue.Set(mapKey, f.value.synthesize(random, mapValue.NewValue))
+		}
+	default:
+		message.Set(
+			f.descriptor,
+			f.value.synthesize(
+				random,
+				func() protoreflect.Value {
+					return message.NewField(f.descriptor)
+				},
+			),
+		)
+	}
+}
+
+// valueModel models singular values.
+type valueModel struct {
+	descriptor protoreflect.FieldDescriptor
+	anonymized bool
+	// numValues is the number of values added.
+	numValues int
+	// values are the observed values, only set if not anonymized.
+	values *observations[protoreflect.Value]
+	// lengths are the observed lengths of anonymized strings and bytes.
+	lengths *observations[int]
+	// numTrue is the number of true values of anonymized bools.
+	numTrue int
+	// The ranges of anonymized numbers.
+	intRange   *valueRange[int64]
+	uintRange  *valueRange[uint64]
+	floatRange *valueRange[float64]
+	// messageModel is only set for messages.
+	messageModel *messageModel
+}
+
+func (v *valueModel) add(value protoreflect.Value) {
+	v.numValues++
+	if v.messageModel != nil {
+		v.messageModel.add(value.Message())
+		return
+	}
+	if !v.anonymized {
+		v.values.add(value)
+		return
+	}
+	switch v.descriptor.Kind() {
+	case protoreflect.StringKind:
+		v.lengths.add(len(value.String()))
+	case protoreflect.BytesKind:
+		v.lengths.add(len(value.Bytes()))
+	case protoreflect.BoolKind:
+		if value.Bool() {
+			v.numTrue++
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v.intRange = v.intRange.add(value.Int())
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v.uintRange = v.uintRange.add(value.Uint())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		v.floatRange = v.floatRange.add(value.Float())
+	}
+}
+
+// synthesize synthesizes a value.
+//
+// newMessageValue returns a new mutable message value, and is only called for messages.
+func (v *valueModel) synthesize(random *rand.Rand, newMessageValue func() protoreflect.Value) protoreflect.Value {
+	if v.messageModel != nil {
+		value := newMessageValue()
+		v.messageModel.synthesize(random, value.Message())
+		return value
+	}
+	if !v.anonymized {
+		return v.values.pick(random)
+	}
+	switch kind := v.descriptor.Kind(); kind {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(string(randomBytes(random, v.lengths.pick(random), anonymizedStringCharacters)))
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(randomBytes(random, v.lengths.pick(random), ""))
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(random.Intn(v.numValues) < v.numTrue)
+	case protoreflect.EnumKind:
+		enumValues := v.descriptor.Enum().Values()
+		return protoreflect.ValueOfEnum(enumValues.Get(random.Intn(enumValues.Len())).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(v.intRange.pickInt(random)))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(v.intRange.pickInt(random))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(v.uintRange.pickUint(random)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(v.uintRange.pickUint(random))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(v.floatRange.pickFloat(random)))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(v.floatRange.pickFloat(random))
+	default:
+		// unreachable, messages and groups are handled above
+		return v.descriptor.Default()
+	}
+}
+
+// observations is a bounded, reservoir-sampled set of observed values.
+type observations[T any] struct {
+	random   *rand.Rand
+	values   []T
+	numAdded int
+}
+
+func newObservations[T any](random *rand.Rand) *observations[T] {
+	return &observations[T]{
+		random: random,
+	}
+}
+
+func (o *observations[T]) add(value T) {
+	o.numAdded++
+	if len(o.values) < maxObservations {
+		o.values = append(o.values, value)
+		return
+	}
+	if i := o.random.Intn(o.numAdded); i < maxObservations {
+		o.values[i] = value
+	}
+}
+
+// pick picks a random observed value.
+//
+// This must only be called if at least one value was added.
+func (o *observations[T]) pick(random *rand.Rand) T {
+	return o.values[random.Intn(len(o.values))]
+}
+
+// valueRange is the range of observed numbers.
+type valueRange[T int64 | uint64 | float64] struct {
+	min T
+	max T
+}
+
+// add returns the range expanded to include the value.
+//
+// The range may be nil, in which case a range of just the value is returned.
+func (v *valueRange[T]) add(value T) *valueRange[T] {
+	if v == nil {
+		return &valueRange[T]{min: value, max: value}
+	}
+	if value < v.min {
+		v.min = value
+	}
+	if value > v.max {
+		v.max = value
+	}
+	return v
+}
+
+func (v *valueRange[T]) pickFloat(random *rand.Rand) float64 {
+	return float64(v.min) + random.Float64()*(float64(v.max)-float64(v.min))
+}
+
+func (v *valueRange[T]) pickInt(random *rand.Rand) int64 {
+	min, max := int64(v.min), int64(v.max)
+	width := uint64(max - min)
+	if width == 0 {
+		return min
+	}
+	return min + int64(randomUint64n(random, width))
+}
+
+func (v *valueRange[T]) pickUint(random *rand.Rand) uint64 {
+	min, max := uint64(v.min), uint64(v.max)
+	if max == min {
+		return min
+	}
+	return min + randomUint64n(random, max-min)
+}
+
+// randomUint64n returns a random value in [0, n], including n.
+func randomUint64n(random *rand.Rand, n uint64) uint64 {
+	if n == ^uint64(0) {
+		return random.Uint64()
+	}
+	n++
+	// Modulo bias is negligible for our purposes.
+	return random.Uint64() % n
+}
+
+// randomBytes returns random bytes of the given length. If characters is non-empty,
+// the bytes are picked from characters.
+func randomBytes(random *rand.Rand, length int, characters string) []byte {
+	value := make([]byte, length)
+	for i := range value {
+		if characters == "" {
+			value[i] = byte(random.Intn(256))
+		} else {
+			value[i] = characters[random.Intn(len(characters))]
+		}
+	}
+	return value
+}
+
+type learnOptions struct {
+	anonymizedFieldFilters []FieldFilter
+}
+
+func newLearnOptions() *learnOptions {
+	return &learnOptions{}
+}
+
+func (l *learnOptions) isAnonymized(fieldDescriptor protoreflect.FieldDescriptor) bool {
+	for _, anonymizedFieldFilter := range l.anonymizedFieldFilters {
+		if anonymizedFieldFilter(fieldDescriptor) {
+			return true
+		}
+	}
+	return false
+}
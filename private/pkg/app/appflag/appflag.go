@@ -41,6 +41,10 @@ type Interceptor func(func(context.Context, Container) error) func(context.Conte
 type Builder interface {
 	BindRoot(flagSet *pflag.FlagSet)
 	NewRunFunc(func(context.Context, Container) error, ...Interceptor) func(context.Context, app.Container) error
+	// NewRunFuncWithoutTimeout is the same as NewRunFunc, but the timeout flag is ignored.
+	//
+	// This should be used for long-running commands such as servers.
+	NewRunFuncWithoutTimeout(func(context.Context, Container) error, ...Interceptor) func(context.Context, app.Container) error
 }
 
 // NewBuilder returns a new Builder.
@@ -64,3 +68,65 @@ func BuilderWithTracing() BuilderOption {
 		builder.tracing = true
 	}
 }
+
+// BuilderWithEnvFlag returns a new BuilderOption that adds a root flag that, if set,
+// sets the environment variable envKey to "1" for the run functions.
+//
+// This allows the flag to be used as an alternative to setting the environment variable.
+func BuilderWithEnvFlag(flagName string, envKey string, usage string) BuilderOption {
+	return func(builder *builder) {
+		builder.envFlags = append(
+			builder.envFlags,
+			&envFlag{
+				name:   flagName,
+				envKey: envKey,
+				usage:  usage,
+			},
+		)
+	}
+}
+
+// BuilderWithEnvStringFlag returns a new BuilderOption that adds a root flag that, if set
+// to a non-empty value, sets the environment variable envKey to the value for the run functions.
+//
+// This allows the flag to be used as an alternative to setting the environment variable.
+func BuilderWithEnvStringFlag(flagName string, envKey string, usage string) BuilderOption {
+	return func(builder *builder) {
+		builder.envFlags = append(
+			builder.envFlags,
+			&envFlag{
+				name:     flagName,
+				envKey:   envKey,
+				usage:    usage,
+				isString: true,
+			},
+		)
+	}
+}
+
+// BuilderWithProfileFlagPrefix returns a new BuilderOption that sets the prefix
+// of the names of the hidden profiling flags, such as "pprof" for "--pprof" and
+// "--pprof-path". This frees the flag names for other uses.
+//
+// The default is "profile".
+func BuilderWithProfileFlagPrefix(profileFlagPrefix string) BuilderOption {
+	return func(builder *builder) {
+		builder.profileFlagPrefix = profileFlagPrefix
+	}
+}
+
+// BuilderWithParallelismFlag returns a new BuilderOption that adds a root int flag
+// that sets the parallelism of the run functions, that is the maximum number of
+// jobs that thread.Parallelize runs at once for the context of the run function.
+// The global parallelism of the thread package is not changed.
+//
+// If the flag is not set, the parallelism is read from the environment variable
+// envKey, if it is set. If neither is set, the parallelism is the default of the
+// thread package, which is the number of CPUs.
+func BuilderWithParallelismFlag(flagName string, envKey string, usage string) BuilderOption {
+	return func(builder *builder) {
+		builder.parallelismFlagName = flagName
+		builder.parallelismEnvKey = envKey
+		builder.parallelismUsage = usage
+	}
+}
@@ -18,12 +18,16 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/app/applog"
 	"github.com/bufbuild/buf/private/pkg/app/appverbose"
+	"github.com/bufbuild/buf/private/pkg/observabilityotlp"
 	"github.com/bufbuild/buf/private/pkg/observabilityzap"
+	"github.com/bufbuild/buf/private/pkg/thread"
 	"github.com/pkg/profile"
 	"github.com/spf13/pflag"
 	"go.uber.org/multierr"
@@ -38,6 +42,8 @@ type builder struct {
 	noWarn    bool
 	logFormat string
 
+	// profileFlagPrefix is the prefix of the names of the profiling flags.
+	profileFlagPrefix string
 	profile           bool
 	profilePath       string
 	profileLoops      int
@@ -49,11 +55,44 @@ type builder struct {
 	defaultTimeout time.Duration
 
 	tracing bool
+
+	envFlags []*envFlag
+
+	parallelismFlagName string
+	parallelismEnvKey   string
+	parallelismUsage    string
+	parallelism         int
+	// parallelismFlag is the bound parallelism flag, used to check if it was set.
+	parallelismFlag *pflag.Flag
+}
+
+type envFlag struct {
+	name   string
+	envKey string
+	usage  string
+	value  bool
+	// isString is true if the flag takes a value that the environment
+	// variable is set to, instead of being a bool flag.
+	isString    bool
+	stringValue string
+}
+
+// envValue returns the value to set the environment variable to, or empty
+// if the flag is not set.
+func (e *envFlag) envValue() string {
+	if e.isString {
+		return e.stringValue
+	}
+	if e.value {
+		return "1"
+	}
+	return ""
 }
 
 func newBuilder(appName string, options ...BuilderOption) *builder {
 	builder := &builder{
-		appName: appName,
+		appName:           appName,
+		profileFlagPrefix: "profile",
 	}
 	for _, option := range options {
 		option(builder)
@@ -69,16 +108,29 @@ func (b *builder) BindRoot(flagSet *pflag.FlagSet) {
 		flagSet.DurationVar(&b.timeout, "timeout", b.defaultTimeout, `The duration until timing out, setting it to zero means no timeout`)
 	}
 
-	flagSet.BoolVar(&b.profile, "profile", false, "Run profiling")
-	_ = flagSet.MarkHidden("profile")
-	flagSet.StringVar(&b.profilePath, "profile-path", "", "The profile base directory path")
-	_ = flagSet.MarkHidden("profile-path")
-	flagSet.IntVar(&b.profileLoops, "profile-loops", 1, "The number of loops to run")
-	_ = flagSet.MarkHidden("profile-loops")
-	flagSet.StringVar(&b.profileType, "profile-type", "cpu", "The profile type [cpu,mem,block,mutex]")
-	_ = flagSet.MarkHidden("profile-type")
-	flagSet.BoolVar(&b.profileAllowError, "profile-allow-error", false, "Allow errors for profiled commands")
-	_ = flagSet.MarkHidden("profile-allow-error")
+	flagSet.BoolVar(&b.profile, b.profileFlagPrefix, false, "Run profiling")
+	_ = flagSet.MarkHidden(b.profileFlagPrefix)
+	flagSet.StringVar(&b.profilePath, b.profileFlagPrefix+"-path", "", "The profile base directory path")
+	_ = flagSet.MarkHidden(b.profileFlagPrefix + "-path")
+	flagSet.IntVar(&b.profileLoops, b.profileFlagPrefix+"-loops", 1, "The number of loops to run")
+	_ = flagSet.MarkHidden(b.profileFlagPrefix + "-loops")
+	flagSet.StringVar(&b.profileType, b.profileFlagPrefix+"-type", "cpu", "The profile type [cpu,mem,block,mutex]")
+	_ = flagSet.MarkHidden(b.profileFlagPrefix + "-type")
+	flagSet.BoolVar(&b.profileAllowError, b.profileFlagPrefix+"-allow-error", false, "Allow errors for profiled commands")
+	_ = flagSet.MarkHidden(b.profileFlagPrefix + "-allow-error")
+
+	if b.parallelismFlagName != "" {
+		flagSet.IntVar(&b.parallelism, b.parallelismFlagName, 0, b.parallelismUsage)
+		b.parallelismFlag = flagSet.Lookup(b.parallelismFlagName)
+	}
+
+	for _, envFlag := range b.envFlags {
+		if envFlag.isString {
+			flagSet.StringVar(&envFlag.stringValue, envFlag.name, "", envFlag.usage)
+		} else {
+			flagSet.BoolVar(&envFlag.value, envFlag.name, false, envFlag.usage)
+		}
+	}
 
 	// We do not officially support this flag, this is for testing, where we need warnings turned off.
 	flagSet.BoolVar(&b.noWarn, "no-warn", false, "Turn off warn logging")
@@ -88,19 +140,35 @@ func (b *builder) BindRoot(flagSet *pflag.FlagSet) {
 func (b *builder) NewRunFunc(
 	f func(context.Context, Container) error,
 	interceptors ...Interceptor,
+) func(context.Context, app.Container) error {
+	return b.newRunFunc(f, true, interceptors...)
+}
+
+func (b *builder) NewRunFuncWithoutTimeout(
+	f func(context.Context, Container) error,
+	interceptors ...Interceptor,
+) func(context.Context, app.Container) error {
+	return b.newRunFunc(f, false, interceptors...)
+}
+
+func (b *builder) newRunFunc(
+	f func(context.Context, Container) error,
+	withTimeout bool,
+	interceptors ...Interceptor,
 ) func(context.Context, app.Container) error {
 	interceptor := chainInterceptors(interceptors...)
 	return func(ctx context.Context, appContainer app.Container) error {
 		if interceptor != nil {
-			return b.run(ctx, appContainer, interceptor(f))
+			return b.run(ctx, appContainer, withTimeout, interceptor(f))
 		}
-		return b.run(ctx, appContainer, f)
+		return b.run(ctx, appContainer, withTimeout, f)
 	}
 }
 
 func (b *builder) run(
 	ctx context.Context,
 	appContainer app.Container,
+	withTimeout bool,
 	f func(context.Context, Container) error,
 ) (retErr error) {
 	logLevel, err := getLogLevel(b.debug, b.noWarn)
@@ -114,6 +182,17 @@ func (b *builder) run(
 	defer func() {
 		retErr = multierr.Append(retErr, logger.Sync())
 	}()
+	appContainer = b.withEnvFlags(appContainer)
+	parallelism, err := b.getParallelism(appContainer)
+	if err != nil {
+		return err
+	}
+	if parallelism > 0 {
+		logger.Debug("parallelism", zap.Int("parallelism", parallelism))
+		// The parallelism is set on the context and not globally, as run
+		// functions may run concurrently in the same process.
+		ctx = thread.WithParallelism(ctx, parallelism)
+	}
 	verbosePrinter := appverbose.NewVerbosePrinter(appContainer.Stderr(), b.appName, b.verbose)
 	container, err := newContainer(appContainer, b.appName, logger, verbosePrinter)
 	if err != nil {
@@ -121,13 +200,17 @@ func (b *builder) run(
 	}
 
 	var cancel context.CancelFunc
-	if !b.profile && b.timeout != 0 {
+	if withTimeout && !b.profile && b.timeout != 0 {
 		ctx, cancel = context.WithTimeout(ctx, b.timeout)
 		defer cancel()
 	}
 
 	if b.tracing {
-		tracerProvider, closer := observabilityzap.Start(logger)
+		startOptions, err := newObservabilityStartOptions(appContainer, b.appName)
+		if err != nil {
+			return err
+		}
+		tracerProvider, closer := observabilityzap.Start(logger, startOptions...)
 		defer func() {
 			retErr = multierr.Append(retErr, closer.Close())
 		}()
@@ -149,6 +232,83 @@ func (b *builder) run(
 	)
 }
 
+// newObservabilityStartOptions returns the options to export traces with OTLP
+// if the OpenTelemetry environment variables configure an endpoint.
+func newObservabilityStartOptions(envContainer app.EnvContainer, appName string) ([]observabilityzap.StartOption, error) {
+	sampler, err := observabilityotlp.NewSamplerForEnv(envContainer)
+	if err != nil {
+		return nil, err
+	}
+	startOptions := []observabilityzap.StartOption{
+		observabilityzap.StartWithSampler(sampler),
+	}
+	spanExporter, err := observabilityotlp.NewSpanExporterForEnv(envContainer)
+	if err != nil {
+		return nil, err
+	}
+	if spanExporter == nil {
+		return startOptions, nil
+	}
+	resource, err := observabilityotlp.NewResourceForEnv(envContainer, appName)
+	if err != nil {
+		return nil, err
+	}
+	return append(
+		startOptions,
+		observabilityzap.StartWithSpanExporter(spanExporter),
+		observabilityzap.StartWithResource(resource),
+	), nil
+}
+
+// withEnvFlags returns the container with the environment variables of the
+// env flags that are set.
+func (b *builder) withEnvFlags(appContainer app.Container) app.Container {
+	var env map[string]string
+	for _, envFlag := range b.envFlags {
+		value := envFlag.envValue()
+		if value == "" {
+			continue
+		}
+		if env == nil {
+			env = app.EnvironMap(appContainer)
+		}
+		env[envFlag.envKey] = value
+	}
+	if env == nil {
+		return appContainer
+	}
+	return app.NewContainer(
+		env,
+		appContainer.Stdin(),
+		appContainer.Stdout(),
+		appContainer.Stderr(),
+		app.Args(appContainer)...,
+	)
+}
+
+// getParallelism returns the parallelism of the parallelism flag, or of its
+// environment variable if the flag is not set, or 0 if neither is set.
+func (b *builder) getParallelism(envContainer app.EnvContainer) (int, error) {
+	if b.parallelismFlagName == "" {
+		return 0, nil
+	}
+	if b.parallelismFlag != nil && b.parallelismFlag.Changed {
+		if b.parallelism < 1 {
+			return 0, fmt.Errorf("--%s must be positive but was %d", b.parallelismFlagName, b.parallelism)
+		}
+		return b.parallelism, nil
+	}
+	value := strings.TrimSpace(envContainer.Env(b.parallelismEnvKey))
+	if value == "" {
+		return 0, nil
+	}
+	parallelism, err := strconv.Atoi(value)
+	if err != nil || parallelism < 1 {
+		return 0, fmt.Errorf("%s must be a positive integer but was %q", b.parallelismEnvKey, value)
+	}
+	return parallelism, nil
+}
+
 // runProfile profiles the function.
 func runProfile(
 	logger *zap.Logger,
@@ -0,0 +1,163 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appflag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRunFuncTimeout(t *testing.T) {
+	t.Parallel()
+	builder := NewBuilder("test", BuilderWithTimeout(time.Minute))
+	builder.BindRoot(pflag.NewFlagSet("test", pflag.ContinueOnError))
+	var hasDeadline bool
+	f := func(ctx context.Context, container Container) error {
+		_, hasDeadline = ctx.Deadline()
+		return nil
+	}
+	require.NoError(t, builder.NewRunFunc(f)(context.Background(), newTestContainer()))
+	assert.True(t, hasDeadline)
+	require.NoError(t, builder.NewRunFuncWithoutTimeout(f)(context.Background(), newTestContainer()))
+	assert.False(t, hasDeadline)
+}
+
+func TestBindRootProfileFlags(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name              string
+		options           []BuilderOption
+		expectedFlagNames []string
+	}{
+		{
+			name: "default",
+			expectedFlagNames: []string{
+				"profile",
+				"profile-path",
+				"profile-loops",
+				"profile-type",
+				"profile-allow-error",
+			},
+		},
+		{
+			name:    "prefix",
+			options: []BuilderOption{BuilderWithProfileFlagPrefix("pprof")},
+			expectedFlagNames: []string{
+				"pprof",
+				"pprof-path",
+				"pprof-loops",
+				"pprof-type",
+				"pprof-allow-error",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			NewBuilder("test", testCase.options...).BindRoot(flagSet)
+			for _, flagName := range testCase.expectedFlagNames {
+				flag := flagSet.Lookup(flagName)
+				if assert.NotNil(t, flag, flagName) {
+					assert.True(t, flag.Hidden, flagName)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRunFuncParallelism(t *testing.T) {
+	t.Parallel()
+	globalParallelism := thread.Parallelism()
+	testCases := []struct {
+		name                string
+		args                []string
+		env                 map[string]string
+		expectedParallelism int
+		expectedErr         string
+	}{
+		{
+			name:                "default",
+			expectedParallelism: globalParallelism,
+		},
+		{
+			name:                "flag",
+			args:                []string{"--jobs", "3"},
+			expectedParallelism: 3,
+		},
+		{
+			name:                "env",
+			env:                 map[string]string{"TEST_JOBS": "4"},
+			expectedParallelism: 4,
+		},
+		{
+			name:                "flag_overrides_env",
+			args:                []string{"--jobs", "3"},
+			env:                 map[string]string{"TEST_JOBS": "4"},
+			expectedParallelism: 3,
+		},
+		{
+			name:        "flag_zero",
+			args:        []string{"--jobs", "0"},
+			expectedErr: "--jobs must be positive but was 0",
+		},
+		{
+			name:        "flag_negative",
+			args:        []string{"--jobs", "-1"},
+			expectedErr: "--jobs must be positive but was -1",
+		},
+		{
+			name:        "env_zero",
+			env:         map[string]string{"TEST_JOBS": "0"},
+			expectedErr: `TEST_JOBS must be a positive integer but was "0"`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			builder := NewBuilder("test", BuilderWithParallelismFlag("jobs", "TEST_JOBS", "The number of jobs"))
+			flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			builder.BindRoot(flagSet)
+			require.NoError(t, flagSet.Parse(testCase.args))
+			var parallelism int
+			err := builder.NewRunFunc(
+				func(ctx context.Context, container Container) error {
+					parallelism = thread.ParallelismForContext(ctx)
+					return nil
+				},
+			)(context.Background(), app.NewContainer(testCase.env, nil, nil, nil))
+			if testCase.expectedErr != "" {
+				assert.EqualError(t, err, testCase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedParallelism, parallelism)
+			assert.Equal(t, globalParallelism, thread.Parallelism())
+		})
+	}
+}
+
+func newTestContainer() app.Container {
+	return app.NewContainer(nil, nil, nil, nil)
+}
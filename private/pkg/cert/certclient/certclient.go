@@ -16,6 +16,7 @@ package certclient
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -27,11 +28,19 @@ import (
 type ExternalClientTLSConfig struct {
 	Use               string   `json:"use,omitempty" yaml:"use,omitempty"`
 	RootCertFilePaths []string `json:"root_cert_file_paths,omitempty" yaml:"root_cert_file_paths,omitempty"`
+	// ClientCertFilePath and ClientKeyFilePath are the paths to the PEM
+	// certificate and key that are presented to servers that require client
+	// certificates. Either both or neither are set.
+	ClientCertFilePath string `json:"client_cert_file_path,omitempty" yaml:"client_cert_file_path,omitempty"`
+	ClientKeyFilePath  string `json:"client_key_file_path,omitempty" yaml:"client_key_file_path,omitempty"`
 }
 
 // IsEmpty returns true if the ExternalClientTLSConfig is empty.
 func (e ExternalClientTLSConfig) IsEmpty() bool {
-	return e.Use == "" && len(e.RootCertFilePaths) == 0
+	return e.Use == "" &&
+		len(e.RootCertFilePaths) == 0 &&
+		e.ClientCertFilePath == "" &&
+		e.ClientKeyFilePath == ""
 }
 
 // NewClientTLSConfig creates a new *tls.Config from the ExternalTLSConfig
@@ -42,6 +51,12 @@ func NewClientTLSConfig(
 	externalClientTLSConfig ExternalClientTLSConfig,
 ) (*tls.Config, error) {
 	opts := []TLSOption{}
+	if externalClientTLSConfig.ClientCertFilePath != "" || externalClientTLSConfig.ClientKeyFilePath != "" {
+		if externalClientTLSConfig.ClientCertFilePath == "" || externalClientTLSConfig.ClientKeyFilePath == "" {
+			return nil, errors.New("tls.client_cert_file_path and tls.client_key_file_path must be set together")
+		}
+		opts = append(opts, WithClientCertFilePath(externalClientTLSConfig.ClientCertFilePath, externalClientTLSConfig.ClientKeyFilePath))
+	}
 	switch t := strings.ToLower(strings.TrimSpace(externalClientTLSConfig.Use)); t {
 	case "systemandlocal":
 		opts = append(opts, WithSystemCertPool())
@@ -60,8 +75,11 @@ func NewClientTLSConfig(
 		opts = append(opts, WithRootCertFilePaths(rootCertFilePaths...))
 		return NewClientTLS(opts...)
 	case "", "system":
-		return NewClientTLS(WithSystemCertPool())
+		return NewClientTLS(append(opts, WithSystemCertPool())...)
 	case "false":
+		if len(opts) > 0 {
+			return nil, errors.New("tls.client_cert_file_path cannot be set when tls.use is false")
+		}
 		return nil, nil
 	default:
 		return nil, fmt.Errorf("unknown tls.use: %q", t)
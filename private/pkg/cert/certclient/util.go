@@ -23,8 +23,10 @@ import (
 )
 
 type tlsOptions struct {
-	useSystemCerts    bool
-	rootCertFilePaths []string
+	useSystemCerts     bool
+	rootCertFilePaths  []string
+	clientCertFilePath string
+	clientKeyFilePath  string
 }
 
 // TLSOption is an option for a new TLS Config.
@@ -46,6 +48,16 @@ func WithRootCertFilePaths(rootCertFilePaths ...string) TLSOption {
 	}
 }
 
+// WithClientCertFilePath returns a new TLSOption to present the PEM
+// certificate and key at the given paths to servers that request a client
+// certificate.
+func WithClientCertFilePath(clientCertFilePath string, clientKeyFilePath string) TLSOption {
+	return func(opts *tlsOptions) {
+		opts.clientCertFilePath = clientCertFilePath
+		opts.clientKeyFilePath = clientKeyFilePath
+	}
+}
+
 // NewClientTLScreates a new tls.Config from a root certificate files.
 func NewClientTLS(options ...TLSOption) (*tls.Config, error) {
 	opts := &tlsOptions{}
@@ -60,7 +72,18 @@ func NewClientTLS(options ...TLSOption) (*tls.Config, error) {
 		}
 		rootCertDatas[i] = rootCertData
 	}
-	return newClientTLSConfigFromRootCertDatas(opts.useSystemCerts, rootCertDatas...)
+	tlsConfig, err := newClientTLSConfigFromRootCertDatas(opts.useSystemCerts, rootCertDatas...)
+	if err != nil {
+		return nil, err
+	}
+	if opts.clientCertFilePath != "" {
+		clientCert, err := tls.LoadX509KeyPair(opts.clientCertFilePath, opts.clientKeyFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+	return tlsConfig, nil
 }
 
 // newClientTLSConfigFromRootCertDatas creates a new tls.Config from root certificate datas.
@@ -0,0 +1,127 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package credentialstore stores the credentials for machines, such as the
+// tokens for the Buf Schema Registry, in a .netrc file or in the credential
+// store of the operating system.
+package credentialstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/netrc"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+)
+
+const (
+	// TypeNetrc is the type of a Store that stores credentials in the .netrc file.
+	TypeNetrc = "netrc"
+	// TypeKeychain is the type of a Store that stores credentials in the macOS Keychain.
+	TypeKeychain = "keychain"
+	// TypeWinCred is the type of a Store that stores credentials in the Windows Credential Manager.
+	TypeWinCred = "wincred"
+	// TypeSecretService is the type of a Store that stores credentials with the
+	// Secret Service API with libsecret, such as in the GNOME Keyring.
+	TypeSecretService = "secret-service"
+)
+
+var (
+	// AllTypes are all Store types.
+	AllTypes = []string{
+		TypeNetrc,
+		TypeKeychain,
+		TypeWinCred,
+		TypeSecretService,
+	}
+	// AllTypesString is the string representation of AllTypes.
+	AllTypesString = stringutil.SliceToString(AllTypes)
+)
+
+// Store stores the credentials for machines.
+type Store interface {
+	// Type returns the type of the Store.
+	Type() string
+	// Location returns a description of where the credentials are stored,
+	// such as the path of the .netrc file.
+	Location() string
+	// GetMachine gets the Machine for the given name.
+	//
+	// Returns nil if no such Machine.
+	GetMachine(ctx context.Context, name string) (netrc.Machine, error)
+	// PutMachine adds the Machine, replacing any Machine with the same name.
+	PutMachine(ctx context.Context, machine netrc.Machine) error
+	// DeleteMachine deletes the Machine for the given name, if present.
+	//
+	// Returns false if there was no Machine for the given name.
+	DeleteMachine(ctx context.Context, name string) (bool, error)
+}
+
+// NewStore returns a new Store of the given type.
+//
+// If storeType is empty, TypeNetrc is used. The Stores other than the netrc
+// Store run the command line tool of the credential store with the runner,
+// except for TypeWinCred, which is only supported on Windows.
+func NewStore(
+	container app.EnvContainer,
+	runner command.Runner,
+	storeType string,
+) (Store, error) {
+	switch storeType {
+	case "", TypeNetrc:
+		return NewNetrcStore(container)
+	case TypeKeychain:
+		return NewKeychainStore(container, runner), nil
+	case TypeWinCred:
+		return NewWinCredStore()
+	case TypeSecretService:
+		return NewSecretServiceStore(container, runner), nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q, must be one of %s", storeType, AllTypesString)
+	}
+}
+
+// NewNetrcStore returns a new Store that stores credentials in the .netrc
+// file of the environment.
+func NewNetrcStore(envContainer app.EnvContainer) (Store, error) {
+	return newNetrcStore(envContainer)
+}
+
+// NewKeychainStore returns a new Store that stores credentials in the macOS
+// Keychain with the security command.
+func NewKeychainStore(envContainer app.EnvContainer, runner command.Runner) Store {
+	return newKeychainStore(envContainer, runner)
+}
+
+// NewSecretServiceStore returns a new Store that stores credentials with the
+// Secret Service API with the secret-tool command of libsecret.
+func NewSecretServiceStore(envContainer app.EnvContainer, runner command.Runner) Store {
+	return newSecretServiceStore(envContainer, runner)
+}
+
+// NewWinCredStore returns a new Store that stores credentials in the Windows
+// Credential Manager.
+//
+// Returns an error if not on Windows.
+func NewWinCredStore() (Store, error) {
+	return newWinCredStore()
+}
+
+// serviceName returns the name of the entry in the credential store of the
+// operating system for the Machine with the given name.
+func serviceName(name string) string {
+	return "buf:" + name
+}
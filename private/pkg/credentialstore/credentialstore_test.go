@@ -0,0 +1,183 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentialstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/netrc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetrcStore(t *testing.T) {
+	t.Parallel()
+	filePath := filepath.Join(t.TempDir(), ".netrc")
+	store, err := NewStore(app.NewEnvContainer(map[string]string{"NETRC": filePath}), nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, TypeNetrc, store.Type())
+	assert.Equal(t, filePath, store.Location())
+	testStore(t, store)
+}
+
+func TestKeychainStore(t *testing.T) {
+	t.Parallel()
+	fakeCommand := newFakeCredentialCommand()
+	fakeCommand.handle = func(args []string, stdin string) (string, error) {
+		switch args[0] {
+		case "-i":
+			// add-generic-password -U -s "service" -a "login" -w "password"
+			fields := strings.Split(strings.TrimSpace(stdin), `"`)
+			require.Len(t, fields, 7, stdin)
+			require.Equal(t, "add-generic-password -U -s ", fields[0])
+			fakeCommand.items[fields[1]] = [2]string{fields[3], fields[5]}
+			return "", nil
+		case "find-generic-password":
+			item, ok := fakeCommand.items[args[2]]
+			if !ok {
+				return "", &fakeExitError{exitCode: securityItemNotFoundExitCode}
+			}
+			if len(args) > 3 && args[3] == "-w" {
+				return item[1] + "\n", nil
+			}
+			return fmt.Sprintf("keychain: \"login.keychain-db\"\nattributes:\n    \"acct\"<blob>=\"%s\"\n    \"svce\"<blob>=\"%s\"\n", item[0], args[2]), nil
+		case "delete-generic-password":
+			if _, ok := fakeCommand.items[args[2]]; !ok {
+				return "", &fakeExitError{exitCode: securityItemNotFoundExitCode}
+			}
+			delete(fakeCommand.items, args[2])
+			return "", nil
+		default:
+			return "", fmt.Errorf("unexpected args: %v", args)
+		}
+	}
+	store := &keychainStore{run: fakeCommand.run}
+	testStore(t, store)
+	assert.Error(t, store.PutMachine(context.Background(), netrc.NewMachine("buf.build", "user", `pass"word`)))
+}
+
+func TestSecretServiceStore(t *testing.T) {
+	t.Parallel()
+	fakeCommand := newFakeCredentialCommand()
+	fakeCommand.handle = func(args []string, stdin string) (string, error) {
+		switch args[0] {
+		case "store":
+			require.Equal(t, []string{"--label", "Buf credentials for " + strings.TrimPrefix(args[4], "buf:"), "service", args[4], "login", args[6]}, args[1:])
+			fakeCommand.items[args[4]] = [2]string{args[6], stdin}
+			return "", nil
+		case "lookup":
+			item, ok := fakeCommand.items[args[2]]
+			if !ok {
+				return "", &fakeExitError{exitCode: secretToolNotFoundExitCode}
+			}
+			return item[1], nil
+		case "search":
+			item, ok := fakeCommand.items[args[2]]
+			if !ok {
+				return "", nil
+			}
+			return fmt.Sprintf("[/org/freedesktop/secrets/collection/login/1]\nlabel = Buf credentials\nsecret = %s\nattribute.login = %s\nattribute.service = %s\n", item[1], item[0], args[2]), nil
+		case "clear":
+			delete(fakeCommand.items, args[2])
+			return "", nil
+		default:
+			return "", fmt.Errorf("unexpected args: %v", args)
+		}
+	}
+	testStore(t, &secretServiceStore{run: fakeCommand.run})
+}
+
+func TestNewStoreUnknownType(t *testing.T) {
+	t.Parallel()
+	_, err := NewStore(app.NewEnvContainer(nil), nil, "plaintext")
+	assert.Error(t, err)
+}
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	machine, err := store.GetMachine(ctx, "buf.build")
+	require.NoError(t, err)
+	assert.Nil(t, machine)
+	deleted, err := store.DeleteMachine(ctx, "buf.build")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	require.NoError(t, store.PutMachine(ctx, netrc.NewMachine("buf.build", "user", "token1")))
+	require.NoError(t, store.PutMachine(ctx, netrc.NewMachine("buf.example.com", "other", "token2")))
+	require.NoError(t, store.PutMachine(ctx, netrc.NewMachine("buf.build", "user2", "token3")))
+	machine, err = store.GetMachine(ctx, "buf.build")
+	require.NoError(t, err)
+	require.NotNil(t, machine)
+	assert.Equal(t, "buf.build", machine.Name())
+	assert.Equal(t, "user2", machine.Login())
+	assert.Equal(t, "token3", machine.Password())
+	deleted, err = store.DeleteMachine(ctx, "buf.build")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	machine, err = store.GetMachine(ctx, "buf.build")
+	require.NoError(t, err)
+	assert.Nil(t, machine)
+	machine, err = store.GetMachine(ctx, "buf.example.com")
+	require.NoError(t, err)
+	require.NotNil(t, machine)
+	assert.Equal(t, "other", machine.Login())
+	assert.Equal(t, "token2", machine.Password())
+}
+
+// fakeCredentialCommand is a fake of the command line tool of a credential
+// store, which stores the login and password for each service name.
+type fakeCredentialCommand struct {
+	items  map[string][2]string
+	handle func(args []string, stdin string) (string, error)
+}
+
+func newFakeCredentialCommand() *fakeCredentialCommand {
+	return &fakeCredentialCommand{
+		items: make(map[string][2]string),
+	}
+}
+
+func (f *fakeCredentialCommand) run(_ context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+	var stdinString string
+	if stdin != nil {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		stdinString = string(data)
+	}
+	stdout, err := f.handle(args, stdinString)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(stdout), nil
+}
+
+type fakeExitError struct {
+	exitCode int
+}
+
+func (f *fakeExitError) Error() string {
+	return fmt.Sprintf("exit status %d", f.exitCode)
+}
+
+func (f *fakeExitError) ExitCode() int {
+	return f.exitCode
+}
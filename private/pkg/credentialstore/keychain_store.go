@@ -0,0 +1,186 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentialstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/netrc"
+)
+
+const (
+	securityCommandName = "security"
+	// securityItemNotFoundExitCode is the exit code of the security command
+	// if there is no such item in the keychain.
+	securityItemNotFoundExitCode = 44
+)
+
+type keychainStore struct {
+	// run runs the command with the arguments, and returns its stdout.
+	run func(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error)
+}
+
+func newKeychainStore(envContainer app.EnvContainer, runner command.Runner) *keychainStore {
+	return &keychainStore{
+		run: func(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+			return runCredentialCommand(ctx, envContainer, runner, stdin, securityCommandName, args...)
+		},
+	}
+}
+
+func (k *keychainStore) Type() string {
+	return TypeKeychain
+}
+
+func (k *keychainStore) Location() string {
+	return "the macOS Keychain"
+}
+
+func (k *keychainStore) GetMachine(ctx context.Context, name string) (netrc.Machine, error) {
+	attributes, err := k.run(ctx, nil, "find-generic-password", "-s", serviceName(name))
+	if err != nil {
+		if isExitCode(err, securityItemNotFoundExitCode) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	password, err := k.run(ctx, nil, "find-generic-password", "-s", serviceName(name), "-w")
+	if err != nil {
+		return nil, err
+	}
+	return netrc.NewMachine(
+		name,
+		parseKeychainAccount(attributes),
+		strings.TrimSuffix(string(password), "\n"),
+	), nil
+}
+
+func (k *keychainStore) PutMachine(ctx context.Context, machine netrc.Machine) error {
+	for _, value := range []string{machine.Name(), machine.Login(), machine.Password()} {
+		if strings.ContainsAny(value, "\"\\\n") {
+			return fmt.Errorf("credentials for %s cannot contain quotes, backslashes, or newlines to be stored in the macOS Keychain", machine.Name())
+		}
+	}
+	// The command is written to the stdin of security in interactive mode,
+	// so that the password is not in the arguments of the process.
+	_, err := k.run(
+		ctx,
+		strings.NewReader(
+			fmt.Sprintf(
+				"add-generic-password -U -s \"%s\" -a \"%s\" -w \"%s\"\n",
+				serviceName(machine.Name()),
+				machine.Login(),
+				machine.Password(),
+			),
+		),
+		"-i",
+	)
+	return err
+}
+
+func (k *keychainStore) DeleteMachine(ctx context.Context, name string) (bool, error) {
+	if _, err := k.run(ctx, nil, "delete-generic-password", "-s", serviceName(name)); err != nil {
+		if isExitCode(err, securityItemNotFoundExitCode) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// parseKeychainAccount parses the account from the attributes printed by
+// security find-generic-password, which are lines such as
+// '    "acct"<blob>="login"'.
+func parseKeychainAccount(attributes []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(attributes))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if value := strings.TrimPrefix(line, `"acct"<blob>=`); value != line {
+			return strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+		}
+	}
+	return ""
+}
+
+// runCredentialCommand runs the command line tool of a credential store with
+// the environment, and returns its stdout.
+//
+// If the command fails, the returned error includes its stderr.
+func runCredentialCommand(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	runner command.Runner,
+	stdin io.Reader,
+	name string,
+	args ...string,
+) ([]byte, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	runOptions := []command.RunOption{
+		command.RunWithArgs(args...),
+		command.RunWithEnv(app.EnvironMap(envContainer)),
+		command.RunWithStdout(stdout),
+		command.RunWithStderr(stderr),
+	}
+	if stdin != nil {
+		runOptions = append(runOptions, command.RunWithStdin(stdin))
+	}
+	if err := runner.Run(ctx, name, runOptions...); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s is required for the credential store but was not found: %w", name, err)
+		}
+		return nil, &commandError{
+			name:   name,
+			err:    err,
+			stderr: strings.TrimSpace(stderr.String()),
+		}
+	}
+	return stdout.Bytes(), nil
+}
+
+// commandError is an error from running the command line tool of a
+// credential store.
+type commandError struct {
+	name   string
+	err    error
+	stderr string
+}
+
+func (c *commandError) Error() string {
+	if c.stderr == "" {
+		return fmt.Sprintf("%s: %v", c.name, c.err)
+	}
+	return fmt.Sprintf("%s: %v: %s", c.name, c.err, c.stderr)
+}
+
+func (c *commandError) Unwrap() error {
+	return c.err
+}
+
+// isExitCode returns true if the error is from a command that exited with the
+// given exit code, such as an *exec.ExitError.
+func isExitCode(err error, exitCode int) bool {
+	var exitCoder interface{ ExitCode() int }
+	return errors.As(err, &exitCoder) && exitCoder.ExitCode() == exitCode
+}
@@ -0,0 +1,58 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentialstore
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/netrc"
+)
+
+type netrcStore struct {
+	envContainer app.EnvContainer
+	filePath     string
+}
+
+func newNetrcStore(envContainer app.EnvContainer) (*netrcStore, error) {
+	filePath, err := netrc.GetFilePath(envContainer)
+	if err != nil {
+		return nil, err
+	}
+	return &netrcStore{
+		envContainer: envContainer,
+		filePath:     filePath,
+	}, nil
+}
+
+func (n *netrcStore) Type() string {
+	return TypeNetrc
+}
+
+func (n *netrcStore) Location() string {
+	return n.filePath
+}
+
+func (n *netrcStore) GetMachine(_ context.Context, name string) (netrc.Machine, error) {
+	return netrc.GetMachineForNameAndFilePath(name, n.filePath)
+}
+
+func (n *netrcStore) PutMachine(_ context.Context, machine netrc.Machine) error {
+	return netrc.PutMachines(n.envContainer, machine)
+}
+
+func (n *netrcStore) DeleteMachine(_ context.Context, name string) (bool, error) {
+	return netrc.DeleteMachineForName(n.envContainer, name)
+}
@@ -0,0 +1,135 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentialstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/netrc"
+)
+
+const (
+	secretToolCommandName = "secret-tool"
+	// secretToolNotFoundExitCode is the exit code of secret-tool lookup if
+	// there is no such secret.
+	secretToolNotFoundExitCode = 1
+	// secretServiceAttributeName is the attribute that identifies the secrets
+	// by their service name.
+	secretServiceAttributeName = "service"
+	// secretLoginAttributeName is the attribute that the login is stored in.
+	secretLoginAttributeName = "login"
+)
+
+type secretServiceStore struct {
+	// run runs the command with the arguments, and returns its stdout.
+	run func(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error)
+}
+
+func newSecretServiceStore(envContainer app.EnvContainer, runner command.Runner) *secretServiceStore {
+	return &secretServiceStore{
+		run: func(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+			return runCredentialCommand(ctx, envContainer, runner, stdin, secretToolCommandName, args...)
+		},
+	}
+}
+
+func (s *secretServiceStore) Type() string {
+	return TypeSecretService
+}
+
+func (s *secretServiceStore) Location() string {
+	return "the Secret Service"
+}
+
+func (s *secretServiceStore) GetMachine(ctx context.Context, name string) (netrc.Machine, error) {
+	password, ok, err := s.lookup(ctx, name)
+	if err != nil || !ok {
+		return nil, err
+	}
+	attributes, err := s.run(ctx, nil, "search", secretServiceAttributeName, serviceName(name))
+	if err != nil {
+		return nil, err
+	}
+	return netrc.NewMachine(
+		name,
+		parseSecretToolAttribute(attributes, secretLoginAttributeName),
+		password,
+	), nil
+}
+
+func (s *secretServiceStore) PutMachine(ctx context.Context, machine netrc.Machine) error {
+	// The login is an attribute of the secret, so a secret with a different
+	// login would not be replaced by secret-tool store.
+	if _, err := s.run(ctx, nil, "clear", secretServiceAttributeName, serviceName(machine.Name())); err != nil {
+		return err
+	}
+	// The password is read from stdin, so that it is not in the arguments of
+	// the process.
+	_, err := s.run(
+		ctx,
+		strings.NewReader(machine.Password()),
+		"store",
+		"--label",
+		"Buf credentials for "+machine.Name(),
+		secretServiceAttributeName,
+		serviceName(machine.Name()),
+		secretLoginAttributeName,
+		machine.Login(),
+	)
+	return err
+}
+
+func (s *secretServiceStore) DeleteMachine(ctx context.Context, name string) (bool, error) {
+	_, ok, err := s.lookup(ctx, name)
+	if err != nil || !ok {
+		return false, err
+	}
+	if _, err := s.run(ctx, nil, "clear", secretServiceAttributeName, serviceName(name)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// lookup returns the password for the Machine with the given name, and false
+// if there is none.
+func (s *secretServiceStore) lookup(ctx context.Context, name string) (string, bool, error) {
+	password, err := s.run(ctx, nil, "lookup", secretServiceAttributeName, serviceName(name))
+	if err != nil {
+		if isExitCode(err, secretToolNotFoundExitCode) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return strings.TrimSuffix(string(password), "\n"), true, nil
+}
+
+// parseSecretToolAttribute parses the value of the attribute from the output
+// of secret-tool search, which has lines such as 'attribute.login = value'.
+func parseSecretToolAttribute(output []byte, attributeName string) string {
+	prefix := "attribute." + attributeName + " = "
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if value := strings.TrimPrefix(scanner.Text(), prefix); value != scanner.Text() {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+// +build windows
+
+package credentialstore
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"github.com/bufbuild/buf/private/pkg/netrc"
+)
+
+const (
+	// https://learn.microsoft.com/en-us/windows/win32/api/wincred/ns-wincred-credentialw
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	// errorNotFound is ERROR_NOT_FOUND, which is returned if there is no such
+	// credential.
+	errorNotFound syscall.Errno = 1168
+)
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+// credential is CREDENTIALW.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+type winCredStore struct{}
+
+func newWinCredStore() (*winCredStore, error) {
+	return &winCredStore{}, nil
+}
+
+func (w *winCredStore) Type() string {
+	return TypeWinCred
+}
+
+func (w *winCredStore) Location() string {
+	return "the Windows Credential Manager"
+}
+
+func (w *winCredStore) GetMachine(_ context.Context, name string) (netrc.Machine, error) {
+	targetName, err := syscall.UTF16PtrFromString(serviceName(name))
+	if err != nil {
+		return nil, err
+	}
+	var cred *credential
+	if ret, _, err := procCredReadW.Call(
+		uintptr(unsafe.Pointer(targetName)),
+		credTypeGeneric,
+		0,
+		uintptr(unsafe.Pointer(&cred)),
+	); ret == 0 {
+		if errors.Is(err, errorNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_, _, _ = procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	}()
+	var password string
+	if cred.CredentialBlobSize > 0 {
+		password = string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize))
+	}
+	var login string
+	if cred.UserName != nil {
+		login = utf16PtrToString(cred.UserName)
+	}
+	return netrc.NewMachine(name, login, password), nil
+}
+
+func (w *winCredStore) PutMachine(_ context.Context, machine netrc.Machine) error {
+	targetName, err := syscall.UTF16PtrFromString(serviceName(machine.Name()))
+	if err != nil {
+		return err
+	}
+	userName, err := syscall.UTF16PtrFromString(machine.Login())
+	if err != nil {
+		return err
+	}
+	password := []byte(machine.Password())
+	cred := &credential{
+		Type:               credTypeGeneric,
+		TargetName:         targetName,
+		CredentialBlobSize: uint32(len(password)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(password) > 0 {
+		cred.CredentialBlob = &password[0]
+	}
+	if ret, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(cred)), 0); ret == 0 {
+		return err
+	}
+	return nil
+}
+
+func (w *winCredStore) DeleteMachine(_ context.Context, name string) (bool, error) {
+	targetName, err := syscall.UTF16PtrFromString(serviceName(name))
+	if err != nil {
+		return false, err
+	}
+	if ret, _, err := procCredDeleteW.Call(
+		uintptr(unsafe.Pointer(targetName)),
+		credTypeGeneric,
+		0,
+	); ret == 0 {
+		if errors.Is(err, errorNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// utf16PtrToString returns the string for the given NUL-terminated UTF-16
+// string.
+func utf16PtrToString(p *uint16) string {
+	var chars []uint16
+	for ptr := unsafe.Pointer(p); ; ptr = unsafe.Add(ptr, unsafe.Sizeof(*p)) {
+		char := *(*uint16)(ptr)
+		if char == 0 {
+			break
+		}
+		chars = append(chars, char)
+	}
+	return syscall.UTF16ToString(chars)
+}
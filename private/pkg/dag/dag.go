@@ -206,61 +206,26 @@ func (g *Graph[Key]) TopoSort(start Key) ([]Key, error) {
 // keyToString is used to print out the label for each node.
 // https://graphviz.org/doc/info/lang.html
 func (g *Graph[Key]) DOTString(keyToString func(Key) string) (string, error) {
-	keyToIndex := make(map[Key]int)
-	nextIndex := 1
 	var nodeStrings []string
 	var edgeStrings []string
-	if err := g.WalkEdges(
-		func(from Key, to Key) error {
-			fromIndex, ok := keyToIndex[from]
-			if !ok {
-				fromIndex = nextIndex
-				nextIndex++
-				keyToIndex[from] = fromIndex
-				nodeStrings = append(
-					nodeStrings,
-					fmt.Sprintf("%d [label=%q]", fromIndex, keyToString(from)),
-				)
-			}
-			toIndex, ok := keyToIndex[to]
-			if !ok {
-				toIndex = nextIndex
-				nextIndex++
-				keyToIndex[to] = toIndex
-				nodeStrings = append(
-					nodeStrings,
-					fmt.Sprintf("%d [label=%q]", toIndex, keyToString(to)),
-				)
-			}
+	if err := g.walkIndexed(
+		func(index int, key Key) {
+			nodeStrings = append(
+				nodeStrings,
+				fmt.Sprintf("%d [label=%q]", index, keyToString(key)),
+			)
+		},
+		func(fromIndex int, toIndex int) {
 			edgeStrings = append(
 				edgeStrings,
 				fmt.Sprintf("%d -> %d", fromIndex, toIndex),
 			)
-			return nil
 		},
-	); err != nil {
-		return "", err
-	}
-	// We also want to pick up any nodes that do not have edges, and display them.
-	if err := g.WalkNodes(
-		func(key Key, inboundEdges []Key, outboundEdges []Key) error {
-			if _, ok := keyToIndex[key]; ok {
-				return nil
-			}
-			if len(inboundEdges) == 0 && len(outboundEdges) == 0 {
-				nodeStrings = append(
-					nodeStrings,
-					fmt.Sprintf("%d [label=%q]", nextIndex, keyToString(key)),
-				)
-				edgeStrings = append(
-					edgeStrings,
-					fmt.Sprintf("%d", nextIndex),
-				)
-				nextIndex++
-				return nil
-			}
-			// This is a system error.
-			return fmt.Errorf("got node %v with %d inbound edges and %d outbound edges, but this was not processed during WalkEdges", key, len(inboundEdges), len(outboundEdges))
+		func(index int) {
+			edgeStrings = append(
+				edgeStrings,
+				fmt.Sprintf("%d", index),
+			)
 		},
 	); err != nil {
 		return "", err
@@ -285,6 +250,100 @@ func (g *Graph[Key]) DOTString(keyToString func(Key) string) (string, error) {
 	return buffer.String(), nil
 }
 
+// MermaidString returns a Mermaid flowchart representation of the graph.
+//
+// keyToString is used to print out the label for each node.
+// https://mermaid.js.org/syntax/flowchart.html
+func (g *Graph[Key]) MermaidString(keyToString func(Key) string) (string, error) {
+	var nodeStrings []string
+	var edgeStrings []string
+	if err := g.walkIndexed(
+		func(index int, key Key) {
+			nodeStrings = append(
+				nodeStrings,
+				fmt.Sprintf(
+					"n%d[\"%s\"]",
+					index,
+					// Mermaid labels cannot contain quotes, which are written as entity codes instead.
+					strings.ReplaceAll(keyToString(key), `"`, "#quot;"),
+				),
+			)
+		},
+		func(fromIndex int, toIndex int) {
+			edgeStrings = append(
+				edgeStrings,
+				fmt.Sprintf("n%d --> n%d", fromIndex, toIndex),
+			)
+		},
+		func(int) {},
+	); err != nil {
+		return "", err
+	}
+	buffer := bytes.NewBuffer(nil)
+	_, _ = buffer.WriteString("flowchart LR\n")
+	for _, nodeString := range nodeStrings {
+		_, _ = buffer.WriteString("  ")
+		_, _ = buffer.WriteString(nodeString)
+		_, _ = buffer.WriteString("\n")
+	}
+	for _, edgeString := range edgeStrings {
+		_, _ = buffer.WriteString("  ")
+		_, _ = buffer.WriteString(edgeString)
+		_, _ = buffer.WriteString("\n")
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// walkIndexed walks the edges of the graph starting at the source keys, and
+// then the nodes that do not have edges, assigning each node an index
+// starting at 1 in the order that it is visited.
+//
+// onNode is called the first time each node is visited, onEdge is called for
+// each edge, and onIsolatedNode is called for nodes without edges after
+// onNode is called for them.
+func (g *Graph[Key]) walkIndexed(
+	onNode func(int, Key),
+	onEdge func(int, int),
+	onIsolatedNode func(int),
+) error {
+	keyToIndex := make(map[Key]int)
+	nextIndex := 1
+	getIndex := func(key Key) int {
+		index, ok := keyToIndex[key]
+		if !ok {
+			index = nextIndex
+			nextIndex++
+			keyToIndex[key] = index
+			onNode(index, key)
+		}
+		return index
+	}
+	if err := g.WalkEdges(
+		func(from Key, to Key) error {
+			fromIndex := getIndex(from)
+			toIndex := getIndex(to)
+			onEdge(fromIndex, toIndex)
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+	// We also want to pick up any nodes that do not have edges, and display them.
+	return g.WalkNodes(
+		func(key Key, inboundEdges []Key, outboundEdges []Key) error {
+			if _, ok := keyToIndex[key]; ok {
+				return nil
+			}
+			if len(inboundEdges) == 0 && len(outboundEdges) == 0 {
+				onIsolatedNode(getIndex(key))
+				return nil
+			}
+			// This is a system error.
+			return fmt.Errorf("got node %v with %d inbound edges and %d outbound edges, but this was not processed during WalkEdges", key, len(inboundEdges), len(outboundEdges))
+		},
+	)
+}
+
 func (g *Graph[Key]) init() {
 	if g.keyToNode == nil {
 		g.keyToNode = make(map[Key]*node[Key])
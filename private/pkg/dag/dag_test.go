@@ -405,6 +405,35 @@ func TestDOTString(t *testing.T) {
 	)
 }
 
+func TestMermaidString(t *testing.T) {
+	t.Parallel()
+	graph := &dag.Graph[string]{}
+	graph.AddEdge("a", "b")
+	graph.AddEdge("a", "d")
+	graph.AddEdge("b", "c")
+	graph.AddEdge("c", "d")
+	graph.AddEdge("e", "b")
+	graph.AddNode(`"f"`)
+	s, err := graph.MermaidString(func(key string) string { return key })
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		`flowchart LR
+  n1["a"]
+  n2["b"]
+  n3["c"]
+  n4["d"]
+  n5["e"]
+  n6["#quot;f#quot;"]
+  n1 --> n2
+  n2 --> n3
+  n3 --> n4
+  n1 --> n4
+  n5 --> n2`,
+		s,
+	)
+}
+
 func testTopoSortSuccess(
 	t *testing.T,
 	setupGraph func(*dag.Graph[string]),
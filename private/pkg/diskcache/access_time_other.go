@@ -0,0 +1,29 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package diskcache
+
+import (
+	"io/fs"
+	"time"
+)
+
+// Access times are not used on other platforms, where the modification time
+// is the last time that a file was used.
+func getAccessTime(fs.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,322 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diskcache lists and prunes the entries of caches on disk.
+package diskcache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache is a cache on disk.
+type Cache struct {
+	// Name is the name of the cache.
+	Name string
+	// DirPath is the directory of the cache.
+	DirPath string
+	// EntryDepth is the depth below DirPath of the entries of the cache.
+	//
+	// Entries are removed as a whole, so an entry must be safe to remove on
+	// its own, such as the files of a single module. Files at a lower depth
+	// are also entries. If zero, DirPath itself is the only entry.
+	EntryDepth int
+}
+
+// Entry is an entry of a Cache.
+type Entry struct {
+	// CacheName is the name of the Cache of the entry.
+	CacheName string `json:"cache"`
+	// Path is the path of the file or directory of the entry.
+	Path string `json:"path"`
+	// Size is the total size of the files of the entry.
+	Size int64 `json:"size"`
+	// LastUsed is the last time that a file of the entry was modified or accessed.
+	//
+	// Access times are only used on platforms where they are available, and file
+	// systems may only update them once a day.
+	LastUsed time.Time `json:"last_used"`
+}
+
+// ListEntries lists the entries of the caches.
+//
+// Caches whose directory does not exist have no entries.
+// The entries are sorted by cache, in the order of the given caches, and then by path.
+func ListEntries(caches ...Cache) ([]*Entry, error) {
+	var entries []*Entry
+	for _, cache := range caches {
+		cacheEntries, err := listCacheEntries(cache)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, cacheEntries...)
+	}
+	return entries, nil
+}
+
+// Prune removes the entries that were last used more than the max age ago, and
+// then the least recently used entries until the total size of the remaining
+// entries is at most the max size.
+//
+// If neither PruneWithMaxSize nor PruneWithMaxAge is given, no entries are removed.
+// Entries that were used within the min age given by PruneWithMinAge are never
+// removed. Returns the removed entries.
+func Prune(entries []*Entry, options ...PruneOption) ([]*Entry, error) {
+	pruneOptions := newPruneOptions()
+	for _, option := range options {
+		option(pruneOptions)
+	}
+	remainingEntries := make([]*Entry, len(entries))
+	copy(remainingEntries, entries)
+	sort.SliceStable(
+		remainingEntries,
+		func(i int, j int) bool {
+			return remainingEntries[i].LastUsed.Before(remainingEntries[j].LastUsed)
+		},
+	)
+	var totalSize int64
+	for _, entry := range remainingEntries {
+		totalSize += entry.Size
+	}
+	var removedEntries []*Entry
+	for _, entry := range remainingEntries {
+		tooOld := pruneOptions.maxAge >= 0 && pruneOptions.now.Sub(entry.LastUsed) > pruneOptions.maxAge
+		tooLarge := pruneOptions.maxSize >= 0 && totalSize > pruneOptions.maxSize
+		tooNew := pruneOptions.now.Sub(entry.LastUsed) <= pruneOptions.minAge
+		if (!tooOld && !tooLarge) || tooNew {
+			// The entries are sorted from least recently used, so all remaining
+			// entries are newer and either fit or are too new to be removed.
+			break
+		}
+		if err := os.RemoveAll(entry.Path); err != nil {
+			return removedEntries, fmt.Errorf("could not remove %q: %w", entry.Path, err)
+		}
+		totalSize -= entry.Size
+		removedEntries = append(removedEntries, entry)
+	}
+	return removedEntries, nil
+}
+
+// PruneOption is an option for Prune.
+type PruneOption func(*pruneOptions)
+
+// PruneWithMaxSize returns a new PruneOption that removes the least recently used
+// entries until the total size of the entries is at most maxSize bytes.
+func PruneWithMaxSize(maxSize int64) PruneOption {
+	return func(pruneOptions *pruneOptions) {
+		pruneOptions.maxSize = maxSize
+	}
+}
+
+// PruneWithMaxAge returns a new PruneOption that removes the entries that were
+// last used more than maxAge ago.
+func PruneWithMaxAge(maxAge time.Duration) PruneOption {
+	return func(pruneOptions *pruneOptions) {
+		pruneOptions.maxAge = maxAge
+	}
+}
+
+// PruneWithMinAge returns a new PruneOption that never removes the entries that
+// were last used within minAge, even if the total size is larger than the max size.
+//
+// This is used to not remove entries that may be in use by another process.
+func PruneWithMinAge(minAge time.Duration) PruneOption {
+	return func(pruneOptions *pruneOptions) {
+		pruneOptions.minAge = minAge
+	}
+}
+
+// ParseSize parses a size such as "5GB" into a number of bytes.
+//
+// The units are B, KB, MB, GB, and TB, which are powers of 1024, and are case-insensitive.
+// A number without a unit is a number of bytes.
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	// The longest units are checked first, as all units end in B.
+	for i := len(sizeUnits) - 1; i >= 0; i-- {
+		if unit := sizeUnits[i]; strings.HasSuffix(value, unit) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit))
+			multiplier = int64(1) << (10 * i)
+			break
+		}
+	}
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q, must be a number with an optional unit of B, KB, MB, GB, or TB", s)
+	}
+	return int64(size * float64(multiplier)), nil
+}
+
+// FormatSize formats a number of bytes with the largest unit that ParseSize accepts
+// for which the value is at least one.
+func FormatSize(size int64) string {
+	for i := len(sizeUnits) - 1; i > 0; i-- {
+		unitSize := int64(1) << (10 * i)
+		if size >= unitSize {
+			return strconv.FormatFloat(float64(size)/float64(unitSize), 'f', 1, 64) + sizeUnits[i]
+		}
+	}
+	return strconv.FormatInt(size, 10) + "B"
+}
+
+// ParseAge parses an age such as "30d".
+//
+// In addition to the units accepted by time.ParseDuration, the units d for days
+// and w for weeks are accepted on their own.
+func ParseAge(s string) (time.Duration, error) {
+	value := strings.TrimSpace(s)
+	for _, unit := range []struct {
+		suffix   string
+		duration time.Duration
+	}{
+		{suffix: "d", duration: 24 * time.Hour},
+		{suffix: "w", duration: 7 * 24 * time.Hour},
+	} {
+		if numberString := strings.TrimSuffix(value, unit.suffix); numberString != value {
+			number, err := strconv.ParseFloat(numberString, 64)
+			if err != nil || number < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(number * float64(unit.duration)), nil
+		}
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid age %q, must be a duration such as 12h, 30d, or 2w", s)
+	}
+	return duration, nil
+}
+
+// *** PRIVATE ***
+
+// sizeUnits are the units of ParseSize and FormatSize, where the index of a unit
+// is its power of 1024.
+var sizeUnits = []string{"B", "KB", "MB", "GB", "TB"}
+
+type pruneOptions struct {
+	// Negative values are unset.
+	maxSize int64
+	maxAge  time.Duration
+	minAge  time.Duration
+	now     time.Time
+}
+
+func newPruneOptions() *pruneOptions {
+	return &pruneOptions{
+		maxSize: -1,
+		maxAge:  -1,
+		minAge:  -1,
+		now:     time.Now(),
+	}
+}
+
+func listCacheEntries(cache Cache) ([]*Entry, error) {
+	fileInfo, err := os.Stat(cache.DirPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !fileInfo.IsDir() {
+		return nil, fmt.Errorf("expected %q to be a directory", cache.DirPath)
+	}
+	var entries []*Entry
+	if err := filepath.WalkDir(
+		cache.DirPath,
+		func(path string, dirEntry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(cache.DirPath, path)
+			if err != nil {
+				return err
+			}
+			depth := 0
+			if relPath != "." {
+				depth = len(strings.Split(relPath, string(filepath.Separator)))
+			}
+			if depth < cache.EntryDepth && dirEntry.IsDir() {
+				return nil
+			}
+			entry, err := newEntry(cache.Name, path)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			if dirEntry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func newEntry(cacheName string, path string) (*Entry, error) {
+	entry := &Entry{
+		CacheName: cacheName,
+		Path:      path,
+	}
+	var rootFileInfo fs.FileInfo
+	if err := filepath.WalkDir(
+		path,
+		func(filePath string, dirEntry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			fileInfo, err := dirEntry.Info()
+			if err != nil {
+				return err
+			}
+			if filePath == path {
+				rootFileInfo = fileInfo
+			}
+			// The times of directories change when files are added, which is not a use.
+			if dirEntry.IsDir() {
+				return nil
+			}
+			entry.Size += fileInfo.Size()
+			if lastUsed := getLastUsed(fileInfo); lastUsed.After(entry.LastUsed) {
+				entry.LastUsed = lastUsed
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	if entry.LastUsed.IsZero() && rootFileInfo != nil {
+		// An empty directory.
+		entry.LastUsed = rootFileInfo.ModTime()
+	}
+	return entry, nil
+}
+
+func getLastUsed(fileInfo fs.FileInfo) time.Time {
+	lastUsed := fileInfo.ModTime()
+	if accessTime, ok := getAccessTime(fileInfo); ok && accessTime.After(lastUsed) {
+		return accessTime
+	}
+	return lastUsed
+}
@@ -0,0 +1,146 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diskcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListEntriesAndPrune(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	now := time.Now()
+	writeTestFile(t, filepath.Join(dirPath, "module", "remote", "owner", "a", "blob"), 100, now.Add(-48*time.Hour))
+	writeTestFile(t, filepath.Join(dirPath, "module", "remote", "owner", "a", "commit"), 10, now.Add(-47*time.Hour))
+	writeTestFile(t, filepath.Join(dirPath, "module", "remote", "owner", "b", "blob"), 200, now.Add(-time.Hour))
+	writeTestFile(t, filepath.Join(dirPath, "module", "remote", "stray"), 1, now.Add(-2*time.Hour))
+	writeTestFile(t, filepath.Join(dirPath, "plugin", "plugin.wasm"), 1000, now.Add(-10*24*time.Hour))
+	caches := []Cache{
+		{
+			Name:       "module",
+			DirPath:    filepath.Join(dirPath, "module"),
+			EntryDepth: 3,
+		},
+		{
+			Name:       "plugin",
+			DirPath:    filepath.Join(dirPath, "plugin"),
+			EntryDepth: 0,
+		},
+		{
+			Name:       "missing",
+			DirPath:    filepath.Join(dirPath, "missing"),
+			EntryDepth: 1,
+		},
+	}
+	entries, err := ListEntries(caches...)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "module", entries[0].CacheName)
+	assert.Equal(t, filepath.Join(dirPath, "module", "remote", "owner", "a"), entries[0].Path)
+	assert.Equal(t, int64(110), entries[0].Size)
+	assert.WithinDuration(t, now.Add(-47*time.Hour), entries[0].LastUsed, time.Second)
+	assert.Equal(t, filepath.Join(dirPath, "module", "remote", "owner", "b"), entries[1].Path)
+	assert.Equal(t, filepath.Join(dirPath, "module", "remote", "stray"), entries[2].Path)
+	assert.Equal(t, "plugin", entries[3].CacheName)
+	assert.Equal(t, filepath.Join(dirPath, "plugin"), entries[3].Path)
+	assert.Equal(t, int64(1000), entries[3].Size)
+
+	removedEntries, err := Prune(entries)
+	require.NoError(t, err)
+	assert.Empty(t, removedEntries)
+
+	removedEntries, err = Prune(entries, PruneWithMaxAge(7*24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, removedEntries, 1)
+	assert.Equal(t, "plugin", removedEntries[0].CacheName)
+	assert.NoDirExists(t, filepath.Join(dirPath, "plugin"))
+
+	entries, err = ListEntries(caches...)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	// The least recently used entries are removed first.
+	removedEntries, err = Prune(entries, PruneWithMaxSize(200))
+	require.NoError(t, err)
+	require.Len(t, removedEntries, 2)
+	assert.Equal(t, filepath.Join(dirPath, "module", "remote", "owner", "a"), removedEntries[0].Path)
+	assert.Equal(t, filepath.Join(dirPath, "module", "remote", "stray"), removedEntries[1].Path)
+	entries, err = ListEntries(caches...)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, filepath.Join(dirPath, "module", "remote", "owner", "b"), entries[0].Path)
+
+	// Entries used within the min age are not removed even if the cache is too large.
+	removedEntries, err = Prune(entries, PruneWithMaxSize(0), PruneWithMinAge(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, removedEntries)
+	assert.DirExists(t, filepath.Join(dirPath, "module", "remote", "owner", "b"))
+}
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+	for s, expected := range map[string]int64{
+		"0":      0,
+		"512":    512,
+		"512B":   512,
+		"1KB":    1024,
+		"1.5kb":  1536,
+		"5GB":    5 << 30,
+		"2 TB":   2 << 40,
+		"100 mb": 100 << 20,
+	} {
+		size, err := ParseSize(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, size, s)
+	}
+	for _, s := range []string{"", "GB", "-1GB", "5PB", "five"} {
+		_, err := ParseSize(s)
+		assert.Error(t, err, s)
+	}
+	assert.Equal(t, "512B", FormatSize(512))
+	assert.Equal(t, "1.5KB", FormatSize(1536))
+	assert.Equal(t, "5.0GB", FormatSize(5<<30))
+}
+
+func TestParseAge(t *testing.T) {
+	t.Parallel()
+	for s, expected := range map[string]time.Duration{
+		"30d":   30 * 24 * time.Hour,
+		"1.5d":  36 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+		"12h":   12 * time.Hour,
+		"1h30m": 90 * time.Minute,
+	} {
+		age, err := ParseAge(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, age, s)
+	}
+	for _, s := range []string{"", "d", "-1d", "30", "thirty days"} {
+		_, err := ParseAge(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, size int, lastUsed time.Time) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0600))
+	require.NoError(t, os.Chtimes(path, lastUsed, lastUsed))
+}
@@ -0,0 +1,132 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package editorconfig reads the properties of files from .editorconfig files.
+//
+// See https://editorconfig.org for the format of .editorconfig files.
+package editorconfig
+
+import (
+	"strconv"
+)
+
+// Filename is the name of .editorconfig files.
+const Filename = ".editorconfig"
+
+const (
+	// IndentStyleTab is the value of the indent_style property to indent with tabs.
+	IndentStyleTab = "tab"
+	// IndentStyleSpace is the value of the indent_style property to indent with spaces.
+	IndentStyleSpace = "space"
+	// EndOfLineLF is the value of the end_of_line property for "\n" line endings.
+	EndOfLineLF = "lf"
+	// EndOfLineCRLF is the value of the end_of_line property for "\r\n" line endings.
+	EndOfLineCRLF = "crlf"
+	// EndOfLineCR is the value of the end_of_line property for "\r" line endings.
+	EndOfLineCR = "cr"
+)
+
+// Properties are the properties of a file.
+//
+// The names of the properties, and the values of the known properties such as
+// indent_style, are lowercase. Properties that are not set, or that are set to
+// "unset", are not present.
+type Properties map[string]string
+
+// IndentStyle returns the indent_style property, which is either IndentStyleTab,
+// IndentStyleSpace, or empty if not set.
+func (p Properties) IndentStyle() string {
+	switch value := p["indent_style"]; value {
+	case IndentStyleTab, IndentStyleSpace:
+		return value
+	default:
+		return ""
+	}
+}
+
+// IndentSize returns the number of columns in each level of indentation, or
+// zero if not set.
+//
+// If the indent_size property is "tab", this is the tab_width property.
+func (p Properties) IndentSize() int {
+	if p["indent_size"] == "tab" {
+		return p.TabWidth()
+	}
+	return positiveIntProperty(p["indent_size"])
+}
+
+// TabWidth returns the number of columns of a tab, or zero if not set.
+//
+// If the tab_width property is not set, this is the indent_size property.
+func (p Properties) TabWidth() int {
+	if tabWidth := positiveIntProperty(p["tab_width"]); tabWidth > 0 {
+		return tabWidth
+	}
+	return positiveIntProperty(p["indent_size"])
+}
+
+// EndOfLine returns the end_of_line property, which is either EndOfLineLF,
+// EndOfLineCRLF, EndOfLineCR, or empty if not set.
+func (p Properties) EndOfLine() string {
+	switch value := p["end_of_line"]; value {
+	case EndOfLineLF, EndOfLineCRLF, EndOfLineCR:
+		return value
+	default:
+		return ""
+	}
+}
+
+// InsertFinalNewline returns the insert_final_newline property, and false if
+// it is not set.
+//
+// If the property is false, files should not end with a newline.
+func (p Properties) InsertFinalNewline() (value bool, ok bool) {
+	switch p["insert_final_newline"] {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// Reader reads the properties of files.
+type Reader interface {
+	// GetProperties gets the properties of the file at the given path.
+	//
+	// The .editorconfig files in the directory of the file and in each of its
+	// parent directories are read, until the root directory or an
+	// .editorconfig file that sets root = true. The properties of a closer
+	// .editorconfig file take precedence.
+	//
+	// The file does not need to exist.
+	GetProperties(filePath string) (Properties, error)
+}
+
+// NewReader returns a new Reader.
+//
+// The Reader caches the .editorconfig files that it reads, and is safe for
+// concurrent use.
+func NewReader() Reader {
+	return newReader()
+}
+
+func positiveIntProperty(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,183 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProperties(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	writeFile(t, filepath.Join(tempDir, Filename), `
+# Ignored, since the directory below sets root = true.
+[*]
+charset = latin1
+`)
+	rootDirPath := filepath.Join(tempDir, "root")
+	writeFile(t, filepath.Join(rootDirPath, Filename), `
+root = true
+
+[*]
+indent_style = space
+indent_size = 2
+end_of_line = LF
+insert_final_newline = true
+
+[*.{proto,yaml}]
+indent_size = 4
+
+[proto/vendor/**]
+indent_style = tab
+indent_size = tab
+tab_width = 8
+
+[Makefile]
+indent_style = tab
+`)
+	writeFile(t, filepath.Join(rootDirPath, "proto", "a", Filename), `
+[*.proto]
+end_of_line = crlf
+insert_final_newline = unset
+`)
+	reader := NewReader()
+	testGetProperties(
+		t,
+		reader,
+		filepath.Join(rootDirPath, "b.proto"),
+		Properties{
+			"indent_style":         "space",
+			"indent_size":          "4",
+			"end_of_line":          "lf",
+			"insert_final_newline": "true",
+		},
+	)
+	testGetProperties(
+		t,
+		reader,
+		filepath.Join(rootDirPath, "proto", "a", "a.proto"),
+		Properties{
+			"indent_style": "space",
+			"indent_size":  "4",
+			"end_of_line":  "crlf",
+		},
+	)
+	testGetProperties(
+		t,
+		reader,
+		filepath.Join(rootDirPath, "proto", "vendor", "c", "c.proto"),
+		Properties{
+			"indent_style":         "tab",
+			"indent_size":          "tab",
+			"tab_width":            "8",
+			"end_of_line":          "lf",
+			"insert_final_newline": "true",
+		},
+	)
+	testGetProperties(
+		t,
+		reader,
+		filepath.Join(rootDirPath, "sub", "Makefile"),
+		Properties{
+			"indent_style":         "tab",
+			"indent_size":          "2",
+			"end_of_line":          "lf",
+			"insert_final_newline": "true",
+		},
+	)
+	testGetProperties(
+		t,
+		reader,
+		filepath.Join(tempDir, "other.proto"),
+		Properties{
+			"charset": "latin1",
+		},
+	)
+}
+
+func TestProperties(t *testing.T) {
+	t.Parallel()
+	properties := Properties{
+		"indent_style":         "tab",
+		"indent_size":          "tab",
+		"tab_width":            "8",
+		"end_of_line":          "crlf",
+		"insert_final_newline": "false",
+	}
+	assert.Equal(t, IndentStyleTab, properties.IndentStyle())
+	assert.Equal(t, 8, properties.IndentSize())
+	assert.Equal(t, 8, properties.TabWidth())
+	assert.Equal(t, EndOfLineCRLF, properties.EndOfLine())
+	insertFinalNewline, ok := properties.InsertFinalNewline()
+	assert.True(t, ok)
+	assert.False(t, insertFinalNewline)
+	properties = Properties{
+		"indent_style": "other",
+		"indent_size":  "3",
+		"end_of_line":  "other",
+	}
+	assert.Equal(t, "", properties.IndentStyle())
+	assert.Equal(t, 3, properties.IndentSize())
+	assert.Equal(t, 3, properties.TabWidth())
+	assert.Equal(t, "", properties.EndOfLine())
+	_, ok = properties.InsertFinalNewline()
+	assert.False(t, ok)
+}
+
+func TestCompileGlob(t *testing.T) {
+	t.Parallel()
+	testCompileGlob(t, "*", []string{"a", "a.proto", "a/b.proto"}, nil)
+	testCompileGlob(t, "*.proto", []string{"a.proto", "a/b/c.proto"}, []string{"a.yaml", "proto"})
+	testCompileGlob(t, "a/*.proto", []string{"a/b.proto"}, []string{"b.proto", "a/b/c.proto", "c/a/b.proto"})
+	testCompileGlob(t, "/a/**.proto", []string{"a/b.proto", "a/b/c.proto"}, []string{"b/a/c.proto"})
+	testCompileGlob(t, "a/**/b.proto", []string{"a/b.proto", "a/c/b.proto", "a/c/d/b.proto"}, []string{"b.proto"})
+	testCompileGlob(t, "?.proto", []string{"a.proto"}, []string{"ab.proto"})
+	testCompileGlob(t, "[ab].proto", []string{"a.proto", "b.proto"}, []string{"c.proto"})
+	testCompileGlob(t, "[!ab].proto", []string{"c.proto"}, []string{"a.proto"})
+	testCompileGlob(t, "[a-c].proto", []string{"b.proto"}, []string{"d.proto"})
+	testCompileGlob(t, "*.{proto,yaml}", []string{"a.proto", "a.yaml"}, []string{"a.json"})
+	testCompileGlob(t, "{a,{b,c}}.proto", []string{"a.proto", "c.proto"}, []string{"d.proto"})
+	testCompileGlob(t, "{single}.proto", []string{"{single}.proto"}, []string{"single.proto"})
+	testCompileGlob(t, "v{1..3}.proto", []string{"v1.proto", "v3.proto"}, []string{"v0.proto", "v4.proto"})
+	testCompileGlob(t, `\*.proto`, []string{"*.proto"}, []string{"a.proto"})
+	testCompileGlob(t, "[a.proto", []string{"[a.proto"}, []string{"a.proto"})
+}
+
+func testGetProperties(t *testing.T, reader Reader, filePath string, expected Properties) {
+	properties, err := reader.GetProperties(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, properties, filePath)
+}
+
+func testCompileGlob(t *testing.T, glob string, matches []string, nonMatches []string) {
+	pattern, err := compileGlob(glob)
+	require.NoError(t, err)
+	for _, match := range matches {
+		assert.True(t, pattern.MatchString(match), "%q should match %q", glob, match)
+	}
+	for _, nonMatch := range nonMatches {
+		assert.False(t, pattern.MatchString(nonMatch), "%q should not match %q", glob, nonMatch)
+	}
+}
+
+func writeFile(t *testing.T, filePath string, data string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	require.NoError(t, os.WriteFile(filePath, []byte(data), 0600))
+}
@@ -0,0 +1,375 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editorconfig
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxNumericRangeSize is the maximum size of a {num1..num2} range in a glob
+// that is matched exactly. Larger ranges match any integer.
+const maxNumericRangeSize = 1000
+
+// knownProperties are the properties whose values are case insensitive.
+var knownProperties = map[string]struct{}{
+	"indent_style":             {},
+	"indent_size":              {},
+	"tab_width":                {},
+	"end_of_line":              {},
+	"charset":                  {},
+	"trim_trailing_whitespace": {},
+	"insert_final_newline":     {},
+	"root":                     {},
+}
+
+type reader struct {
+	lock sync.Mutex
+	// The parsed .editorconfig file for each directory path, which is nil if
+	// the directory does not have one.
+	dirPathToFile map[string]*file
+}
+
+func newReader() *reader {
+	return &reader{
+		dirPathToFile: make(map[string]*file),
+	}
+}
+
+func (r *reader) GetProperties(filePath string) (Properties, error) {
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	// The files are ordered from the closest to the furthest directory.
+	var files []*file
+	for dirPath := filepath.Dir(absFilePath); ; {
+		file, err := r.getFile(dirPath)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			files = append(files, file)
+			if file.root {
+				break
+			}
+		}
+		parentDirPath := filepath.Dir(dirPath)
+		if parentDirPath == dirPath {
+			break
+		}
+		dirPath = parentDirPath
+	}
+	properties := make(Properties)
+	for i := len(files) - 1; i >= 0; i-- {
+		files[i].apply(properties, absFilePath)
+	}
+	return properties, nil
+}
+
+// getFile gets the .editorconfig file in the given directory, or nil if there
+// is none.
+func (r *reader) getFile(dirPath string) (*file, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if file, ok := r.dirPathToFile[dirPath]; ok {
+		return file, nil
+	}
+	filePath := filepath.Join(dirPath, Filename)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			r.dirPathToFile[dirPath] = nil
+			return nil, nil
+		}
+		return nil, err
+	}
+	file, err := parseFile(dirPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	r.dirPathToFile[dirPath] = file
+	return file, nil
+}
+
+// file is a parsed .editorconfig file.
+type file struct {
+	dirPath  string
+	root     bool
+	sections []*section
+}
+
+type section struct {
+	pattern *regexp.Regexp
+	// The properties in the order they are set.
+	properties []property
+}
+
+type property struct {
+	name  string
+	value string
+}
+
+func parseFile(dirPath string, data []byte) (*file, error) {
+	file := &file{
+		dirPath: dirPath,
+	}
+	var currentSection *section
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			pattern, err := compileGlob(strings.TrimSpace(line[1 : len(line)-1]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			currentSection = &section{
+				pattern: pattern,
+			}
+			file.sections = append(file.sections, currentSection)
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a section or a property, but got %q", lineNumber, line)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if _, ok := knownProperties[name]; ok {
+			value = strings.ToLower(value)
+		}
+		if currentSection == nil {
+			// Only root is defined outside of a section.
+			if name == "root" {
+				file.root = value == "true"
+			}
+			continue
+		}
+		currentSection.properties = append(
+			currentSection.properties,
+			property{
+				name:  name,
+				value: value,
+			},
+		)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// apply sets the properties of the sections that match the given absolute
+// file path, with later sections taking precedence.
+func (f *file) apply(properties Properties, absFilePath string) {
+	relFilePath, err := filepath.Rel(f.dirPath, absFilePath)
+	if err != nil {
+		return
+	}
+	relFilePath = filepath.ToSlash(relFilePath)
+	for _, section := range f.sections {
+		if !section.pattern.MatchString(relFilePath) {
+			continue
+		}
+		for _, property := range section.properties {
+			if property.value == "unset" {
+				delete(properties, property.name)
+				continue
+			}
+			properties[property.name] = property.value
+		}
+	}
+}
+
+// compileGlob compiles the glob of a section to a regular expression that
+// matches the paths of files relative to the directory of the .editorconfig
+// file, with '/' separators.
+//
+// A glob without a '/' matches files in any directory.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	if strings.Contains(glob, "/") {
+		glob = strings.TrimPrefix(glob, "/")
+	} else {
+		glob = "**/" + glob
+	}
+	pattern, err := regexp.Compile("^" + translateGlob(glob) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid section %q: %w", glob, err)
+	}
+	return pattern, nil
+}
+
+// translateGlob translates a glob to a regular expression.
+//
+// The special characters are:
+//
+//   - '*', which matches any characters other than '/'.
+//   - '**', which matches any characters.
+//   - '?', which matches any character other than '/'.
+//   - '[name]' and '[!name]', which match any character in, or not in, name.
+//   - '{s1,s2,s3}', which matches any of the strings.
+//   - '{num1..num2}', which matches any integer between num1 and num2.
+//   - '\', which escapes the next character.
+func translateGlob(glob string) string {
+	var builder strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// '**/' also matches no directories.
+					i++
+					builder.WriteString("(?:.*/)?")
+				} else {
+					builder.WriteString(".*")
+				}
+			} else {
+				builder.WriteString("[^/]*")
+			}
+		case '?':
+			builder.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				builder.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			negated := strings.HasPrefix(class, "!")
+			if negated {
+				class = class[1:]
+			}
+			if class == "" {
+				builder.WriteString(regexp.QuoteMeta(glob[i : i+2+end]))
+			} else {
+				builder.WriteString("[")
+				if negated {
+					builder.WriteString("^")
+				}
+				builder.WriteString(strings.NewReplacer(`\`, `\\`, `[`, `\[`, `^`, `\^`).Replace(class))
+				builder.WriteString("]")
+			}
+			i += end + 1
+		case '{':
+			end := matchingBrace(glob, i)
+			if end < 0 {
+				builder.WriteString(`\{`)
+				continue
+			}
+			builder.WriteString(translateBraces(glob[i+1 : end]))
+			i = end
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			builder.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			builder.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return builder.String()
+}
+
+// translateBraces translates the contents of a '{...}' in a glob.
+func translateBraces(contents string) string {
+	if start, end, ok := strings.Cut(contents, ".."); ok {
+		startNumber, startErr := strconv.Atoi(start)
+		endNumber, endErr := strconv.Atoi(end)
+		if startErr == nil && endErr == nil {
+			if startNumber > endNumber {
+				startNumber, endNumber = endNumber, startNumber
+			}
+			if endNumber-startNumber > maxNumericRangeSize {
+				return `[+-]?[0-9]+`
+			}
+			numbers := make([]string, 0, endNumber-startNumber+1)
+			for n := startNumber; n <= endNumber; n++ {
+				numbers = append(numbers, regexp.QuoteMeta(strconv.Itoa(n)))
+			}
+			return "(?:" + strings.Join(numbers, "|") + ")"
+		}
+	}
+	alternatives := splitAlternatives(contents)
+	if len(alternatives) == 1 {
+		// A '{...}' without alternatives is matched literally.
+		return `\{` + translateGlob(contents) + `\}`
+	}
+	exprs := make([]string, len(alternatives))
+	for i, alternative := range alternatives {
+		exprs[i] = translateGlob(alternative)
+	}
+	return "(?:" + strings.Join(exprs, "|") + ")"
+}
+
+// matchingBrace returns the index of the '}' that matches the '{' at the given
+// index, or -1 if there is none.
+func matchingBrace(glob string, start int) int {
+	depth := 0
+	for i := start; i < len(glob); i++ {
+		switch glob[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAlternatives splits the contents of a '{...}' at the commas that are
+// not within a nested '{...}'.
+func splitAlternatives(contents string) []string {
+	var alternatives []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(contents); i++ {
+		switch contents[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alternatives = append(alternatives, contents[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alternatives, contents[start:])
+}
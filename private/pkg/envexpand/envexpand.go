@@ -0,0 +1,46 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package envexpand expands environment variables in strings and in the values
+// of YAML documents.
+//
+// References to environment variables have the form ${VAR}, or ${VAR:-default}
+// to use default if VAR is not set. Expansion is strict, so that it is an error
+// to reference a variable that is not set without a default. A literal "${" is
+// written as "$${".
+package envexpand
+
+import (
+	"github.com/bufbuild/buf/private/pkg/app"
+)
+
+// Expand expands the references to environment variables in the value.
+func Expand(value string, envContainer app.EnvContainer) (string, error) {
+	return expand(value, envContainer)
+}
+
+// ExpandYAML expands the references to environment variables in the scalar
+// values of the YAML document at the given paths.
+//
+// Each path is the keys of the mappings from the root of the document to the
+// value, where "*" matches any key of a mapping or any element of a sequence.
+// For example, []string{"deps", "*"} matches each element of the deps sequence.
+//
+// The data is returned unchanged if it does not reference any environment
+// variables. Otherwise the document is returned re-encoded, and the expanded
+// values are resolved as if they had been written in the document, so that a
+// value of ${REVISION} that expands to 1 is an integer.
+func ExpandYAML(data []byte, envContainer app.EnvContainer, paths ...[]string) ([]byte, error) {
+	return expandYAML(data, envContainer, paths)
+}
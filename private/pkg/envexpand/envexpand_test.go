@@ -0,0 +1,98 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envexpand
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	t.Parallel()
+	envContainer := app.NewEnvContainer(
+		map[string]string{
+			"REMOTE": "buf.example.com",
+			"OWNER":  "acme",
+		},
+	)
+	testExpand(t, envContainer, "buf.build/acme/weather", "buf.build/acme/weather")
+	testExpand(t, envContainer, "${REMOTE}/${OWNER}/weather", "buf.example.com/acme/weather")
+	testExpand(t, envContainer, "${REGION:-us}/a", "us/a")
+	testExpand(t, envContainer, "${REMOTE:-buf.build}", "buf.example.com")
+	testExpand(t, envContainer, "${REGION:-}a", "a")
+	testExpand(t, envContainer, "$${REMOTE}", "${REMOTE}")
+	testExpand(t, envContainer, "$REMOTE", "$REMOTE")
+	testExpandError(t, envContainer, "${REGION}/a", "environment variable REGION is not set")
+	testExpandError(t, envContainer, "${REMOTE", `unterminated environment variable reference in "${REMOTE"`)
+	testExpandError(t, envContainer, "${1REMOTE}", `invalid environment variable reference "${1REMOTE}"`)
+	testExpandError(t, envContainer, "${}", `invalid environment variable reference "${}"`)
+}
+
+func TestExpandYAML(t *testing.T) {
+	t.Parallel()
+	envContainer := app.NewEnvContainer(
+		map[string]string{
+			"REMOTE":   "buf.example.com",
+			"REVISION": "2",
+		},
+	)
+	data := []byte(`version: v1
+name: ${REMOTE}/acme/a
+deps:
+  - ${REMOTE}/acme/b
+plugins:
+  - plugin: ${REMOTE}/acme/go
+    revision: ${REVISION}
+`)
+	expanded, err := ExpandYAML(
+		data,
+		envContainer,
+		[]string{"deps", "*"},
+		[]string{"plugins", "*", "plugin"},
+		[]string{"plugins", "*", "revision"},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`version: v1
+name: ${REMOTE}/acme/a
+deps:
+  - buf.example.com/acme/b
+plugins:
+  - plugin: buf.example.com/acme/go
+    revision: 2
+`,
+		string(expanded),
+	)
+	unexpanded, err := ExpandYAML(data, envContainer, []string{"other"})
+	require.NoError(t, err)
+	assert.Equal(t, data, unexpanded)
+	_, err = ExpandYAML(data, app.NewEnvContainer(nil), []string{"deps", "*"})
+	assert.EqualError(t, err, "line 4: environment variable REMOTE is not set")
+}
+
+func testExpand(t *testing.T, envContainer app.EnvContainer, value string, expected string) {
+	expanded, err := Expand(value, envContainer)
+	require.NoError(t, err)
+	assert.Equal(t, expected, expanded, value)
+}
+
+func testExpandError(t *testing.T, envContainer app.EnvContainer, value string, expectedError string) {
+	_, err := Expand(value, envContainer)
+	assert.EqualError(t, err, expectedError, value)
+}
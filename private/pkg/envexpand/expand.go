@@ -0,0 +1,165 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envexpand
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"gopkg.in/yaml.v3"
+)
+
+func expand(value string, envContainer app.EnvContainer) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+	var builder strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			builder.WriteString(value)
+			return builder.String(), nil
+		}
+		if start > 0 && value[start-1] == '$' {
+			// "$${" is a literal "${".
+			builder.WriteString(value[:start-1])
+			builder.WriteString("${")
+			value = value[start+2:]
+			continue
+		}
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated environment variable reference in %q", value)
+		}
+		builder.WriteString(value[:start])
+		expanded, err := expandReference(value[start+2:start+end], envContainer)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(expanded)
+		value = value[start+end+1:]
+	}
+}
+
+// expandReference expands the contents of a ${...} reference.
+func expandReference(reference string, envContainer app.EnvContainer) (string, error) {
+	name, defaultValue, hasDefault := strings.Cut(reference, ":-")
+	if !isValidName(name) {
+		return "", fmt.Errorf("invalid environment variable reference %q", "${"+reference+"}")
+	}
+	if value := envContainer.Env(name); value != "" {
+		return value, nil
+	}
+	if hasDefault {
+		return defaultValue, nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set", name)
+}
+
+// isValidName returns true if the name is a valid environment variable name,
+// which consists of letters, digits, and underscores, and does not start with
+// a digit.
+func isValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func expandYAML(data []byte, envContainer app.EnvContainer, paths [][]string) ([]byte, error) {
+	if !bytes.Contains(data, []byte("${")) {
+		return data, nil
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		// The error is reported when the data is unmarshalled by the caller.
+		return data, nil
+	}
+	if len(document.Content) == 0 {
+		return data, nil
+	}
+	expanded := false
+	for _, path := range paths {
+		pathExpanded, err := expandYAMLNode(document.Content[0], path, envContainer)
+		if err != nil {
+			return nil, err
+		}
+		expanded = expanded || pathExpanded
+	}
+	if !expanded {
+		return data, nil
+	}
+	var buffer bytes.Buffer
+	yamlEncoder := yaml.NewEncoder(&buffer)
+	yamlEncoder.SetIndent(2)
+	if err := yamlEncoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err := yamlEncoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// expandYAMLNode expands the scalar values at the path from the node, and
+// returns true if any value was changed.
+func expandYAMLNode(node *yaml.Node, path []string, envContainer app.EnvContainer) (bool, error) {
+	if len(path) == 0 {
+		if node.Kind != yaml.ScalarNode || !strings.Contains(node.Value, "${") {
+			return false, nil
+		}
+		value, err := expand(node.Value, envContainer)
+		if err != nil {
+			return false, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		node.Value = value
+		// The tag is resolved again from the expanded value.
+		node.Tag = ""
+		node.Style = 0
+		return true, nil
+	}
+	var children []*yaml.Node
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if path[0] == "*" || node.Content[i].Value == path[0] {
+				children = append(children, node.Content[i+1])
+			}
+		}
+	case yaml.SequenceNode:
+		if path[0] == "*" {
+			children = node.Content
+		}
+	}
+	expanded := false
+	for _, child := range children {
+		childExpanded, err := expandYAMLNode(child, path[1:], envContainer)
+		if err != nil {
+			return false, err
+		}
+		expanded = expanded || childExpanded
+	}
+	return expanded, nil
+}
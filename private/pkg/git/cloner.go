@@ -94,6 +94,9 @@ func (c *cloner) CloneToBucket(
 	default:
 		return fmt.Errorf("invalid git url: %q", url)
 	}
+	if c.options.OfflineErr != nil && !strings.HasPrefix(url, "file://") {
+		return c.options.OfflineErr
+	}
 
 	if depth == 0 {
 		err := errors.New("depth must be > 0")
@@ -0,0 +1,56 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestClonerOffline(t *testing.T) {
+	t.Parallel()
+	offlineErr := errors.New("offline")
+	cloner := NewCloner(
+		zap.NewNop(),
+		storageos.NewProvider(),
+		command.NewRunner(),
+		ClonerOptions{
+			OfflineErr: offlineErr,
+		},
+	)
+	for _, url := range []string{
+		"https://github.com/bufbuild/buf.git",
+		"ssh://git@github.com/bufbuild/buf.git",
+		"git://github.com/bufbuild/buf.git",
+	} {
+		err := cloner.CloneToBucket(
+			context.Background(),
+			app.NewEnvContainer(nil),
+			url,
+			1,
+			storagemem.NewReadWriteBucket(),
+			CloneToBucketOptions{},
+		)
+		assert.ErrorIs(t, err, offlineErr, url)
+	}
+}
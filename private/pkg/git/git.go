@@ -130,6 +130,10 @@ type ClonerOptions struct {
 	HTTPSPasswordEnvKey      string
 	SSHKeyFileEnvKey         string
 	SSHKnownHostsFilesEnvKey string
+	// OfflineErr is returned instead of cloning repositories that are not
+	// file:// URLs if it is not nil. This is used to disable network
+	// access.
+	OfflineErr error
 }
 
 // Lister lists files in git repositories.
@@ -156,6 +160,28 @@ type Lister interface {
 		envContainer app.EnvStdioContainer,
 		options ListFilesAndUnstagedFilesOptions,
 	) ([]string, error)
+	// ListChangedFiles lists all files in the working tree that differ from
+	// the given ref, which is any commit-ish such as a branch, tag, or hash,
+	// and also lists untracked files that are not ignored.
+	//
+	// This does not list files that were deleted since the ref.
+	// Only files in the given directory, or its subdirectories, are listed.
+	// Refs that start with "-" are rejected, as git would parse them as options.
+	//
+	// The returned paths will be unnormalized, and relative to the given
+	// directory.
+	//
+	// This is the equivalent of doing, in the given directory:
+	//
+	//	sort -u \
+	//		<(git diff --name-only --relative --no-renames --diff-filter=d REF --) \
+	//		<(git ls-files --others --exclude-standard)
+	ListChangedFiles(
+		ctx context.Context,
+		envContainer app.EnvStdioContainer,
+		dirPath string,
+		ref string,
+	) ([]string, error)
 }
 
 // NewLister returns a new Lister.
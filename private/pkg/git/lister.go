@@ -15,9 +15,13 @@
 package git
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"regexp"
+	"strings"
 
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/command"
@@ -80,6 +84,80 @@ func (l *lister) ListFilesAndUnstagedFiles(
 	), nil
 }
 
+func (l *lister) ListChangedFiles(
+	ctx context.Context,
+	container app.EnvStdioContainer,
+	dirPath string,
+	ref string,
+) ([]string, error) {
+	if ref == "" {
+		return nil, errors.New("ref must not be empty")
+	}
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid ref %q: must not start with \"-\"", ref)
+	}
+	changedFilesOutput, err := runStdoutInDir(
+		ctx,
+		container,
+		l.runner,
+		dirPath,
+		"diff",
+		"--name-only",
+		"--relative",
+		"--no-renames",
+		"--diff-filter=d",
+		ref,
+		// Terminate the options so that the ref is never interpreted as a path.
+		"--",
+	)
+	if err != nil {
+		return nil, err
+	}
+	untrackedFilesOutput, err := runStdoutInDir(
+		ctx,
+		container,
+		l.runner,
+		dirPath,
+		"ls-files",
+		"--others",
+		"--exclude-standard",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return slicesext.ToUniqueSorted(
+		append(
+			stringutil.SplitTrimLinesNoEmpty(string(changedFilesOutput)),
+			stringutil.SplitTrimLinesNoEmpty(string(untrackedFilesOutput))...,
+		),
+	), nil
+}
+
+// runStdoutInDir runs git with the args in the directory, and returns its
+// stdout.
+func runStdoutInDir(
+	ctx context.Context,
+	container app.EnvStdioContainer,
+	runner command.Runner,
+	dirPath string,
+	args ...string,
+) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := runner.Run(
+		ctx,
+		"git",
+		command.RunWithArgs(args...),
+		command.RunWithEnv(app.EnvironMap(container)),
+		command.RunWithStdin(container.Stdin()),
+		command.RunWithStdout(buffer),
+		command.RunWithStderr(container.Stderr()),
+		command.RunWithDir(dirPath),
+	); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
 // stringSliceExcept returns all elements in source that are not in except.
 func stringSliceExcept(source []string, except []string) []string {
 	exceptMap := slicesext.ToStructMap(except)
@@ -0,0 +1,65 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListChangedFiles(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	container, err := app.NewContainerForOS()
+	require.NoError(t, err)
+	runner := command.NewRunner()
+	repoPath := t.TempDir()
+	protoPath := filepath.Join(repoPath, "proto")
+	require.NoError(t, os.MkdirAll(protoPath, 0700))
+	runCommand(ctx, t, container, runner, "git", "-C", repoPath, "init")
+	runCommand(ctx, t, container, runner, "git", "-C", repoPath, "config", "user.email", "tests@buf.build")
+	runCommand(ctx, t, container, runner, "git", "-C", repoPath, "config", "user.name", "Buf go tests")
+	for _, filePath := range []string{"root.proto", "proto/a.proto", "proto/b.proto", "proto/deleted.proto"} {
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, filePath), []byte("// commit 0"), 0600))
+	}
+	runCommand(ctx, t, container, runner, "git", "-C", repoPath, "add", ".")
+	runCommand(ctx, t, container, runner, "git", "-C", repoPath, "commit", "-m", "commit 0")
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "root.proto"), []byte("// changed"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(protoPath, "a.proto"), []byte("// changed"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(protoPath, "untracked.proto"), []byte("// untracked"), 0600))
+	require.NoError(t, os.Remove(filepath.Join(protoPath, "deleted.proto")))
+
+	lister := NewLister(runner)
+	// The files are listed relative to the directory, not the current
+	// directory of the process.
+	changedFiles, err := lister.ListChangedFiles(ctx, container, protoPath, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.proto", "untracked.proto"}, changedFiles)
+	changedFiles, err = lister.ListChangedFiles(ctx, container, repoPath, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"proto/a.proto", "proto/untracked.proto", "root.proto"}, changedFiles)
+
+	_, err = lister.ListChangedFiles(ctx, container, repoPath, "--output=/tmp/pwned")
+	assert.ErrorContains(t, err, `invalid ref "--output=/tmp/pwned"`)
+	_, err = lister.ListChangedFiles(ctx, container, repoPath, "")
+	assert.Error(t, err)
+}
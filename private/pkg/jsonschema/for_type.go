@@ -0,0 +1,172 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonschema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+func forType(t reflect.Type, options ...ForTypeOption) (Schema, error) {
+	forTypeOptions := newForTypeOptions()
+	for _, option := range options {
+		option(forTypeOptions)
+	}
+	generator := &generator{
+		typeToAlternatives: forTypeOptions.typeToAlternatives,
+		visiting:           make(map[reflect.Type]struct{}),
+	}
+	schema, err := generator.schemaForType(t)
+	if err != nil {
+		return nil, err
+	}
+	schema["$schema"] = DraftURI
+	if forTypeOptions.title != "" {
+		schema["title"] = forTypeOptions.title
+	}
+	return schema, nil
+}
+
+type generator struct {
+	typeToAlternatives map[reflect.Type][]Schema
+	// The struct types that are being generated, to detect recursive types.
+	visiting map[reflect.Type]struct{}
+}
+
+func (g *generator) schemaForType(t reflect.Type) (Schema, error) {
+	schema, err := g.schemaForTypeWithoutAlternatives(t)
+	if err != nil {
+		return nil, err
+	}
+	alternatives := g.typeToAlternatives[t]
+	if len(alternatives) == 0 {
+		return schema, nil
+	}
+	anyOf := make([]interface{}, 0, len(alternatives)+1)
+	for _, alternative := range alternatives {
+		anyOf = append(anyOf, alternative)
+	}
+	return Schema{
+		"anyOf": append(anyOf, schema),
+	}, nil
+}
+
+func (g *generator) schemaForTypeWithoutAlternatives(t reflect.Type) (Schema, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}, nil
+	case reflect.String:
+		return Schema{"type": "string"}, nil
+	case reflect.Interface:
+		return Schema{}, nil
+	case reflect.Pointer:
+		return g.schemaForType(t.Elem())
+	case reflect.Slice, reflect.Array:
+		items, err := g.schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return Schema{
+			"type":  "array",
+			"items": items,
+		}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map type %v must have string keys", t)
+		}
+		additionalProperties, err := g.schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return Schema{
+			"type":                 "object",
+			"additionalProperties": additionalProperties,
+		}, nil
+	case reflect.Struct:
+		return g.schemaForStruct(t)
+	default:
+		return nil, fmt.Errorf("type %v is not supported", t)
+	}
+}
+
+func (g *generator) schemaForStruct(t reflect.Type) (Schema, error) {
+	if _, ok := g.visiting[t]; ok {
+		return nil, fmt.Errorf("recursive type %v is not supported", t)
+	}
+	g.visiting[t] = struct{}{}
+	defer delete(g.visiting, t)
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, err := propertyName(field)
+		if err != nil {
+			return nil, fmt.Errorf("%v.%s: %w", t, field.Name, err)
+		}
+		if name == "" {
+			continue
+		}
+		property, err := g.schemaForType(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%v.%s: %w", t, field.Name, err)
+		}
+		properties[name] = property
+	}
+	return Schema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}, nil
+}
+
+// propertyName returns the name of the property for the given field, or empty
+// if the field is ignored.
+func propertyName(field reflect.StructField) (string, error) {
+	tag := field.Tag.Get("yaml")
+	if tag == "-" {
+		return "", nil
+	}
+	name, flags, _ := strings.Cut(tag, ",")
+	for _, flag := range strings.Split(flags, ",") {
+		if flag == "inline" {
+			return "", errors.New("inline fields are not supported")
+		}
+	}
+	if name == "" {
+		// This is the default of gopkg.in/yaml.
+		return strings.ToLower(field.Name), nil
+	}
+	return name, nil
+}
+
+type forTypeOptions struct {
+	typeToAlternatives map[reflect.Type][]Schema
+	title              string
+}
+
+func newForTypeOptions() *forTypeOptions {
+	return &forTypeOptions{
+		typeToAlternatives: make(map[reflect.Type][]Schema),
+	}
+}
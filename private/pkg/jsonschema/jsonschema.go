@@ -0,0 +1,67 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonschema generates JSON Schemas for the types that configuration
+// files are unmarshalled into.
+//
+// The schemas use the yaml tags of the fields of structs, and do not allow any
+// properties other than the fields, as the files are unmarshalled strictly.
+//
+// See https://json-schema.org for the format of JSON Schemas.
+package jsonschema
+
+import (
+	"reflect"
+)
+
+// DraftURI is the URI of the draft of JSON Schema that the schemas use.
+const DraftURI = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a JSON Schema.
+//
+// This can be marshalled to JSON, and modified before it is.
+type Schema map[string]interface{}
+
+// ForType returns the Schema for values of the given type.
+//
+// Booleans, integers, floats, and strings are the corresponding JSON types. Slices
+// and arrays are arrays, maps with string keys are objects, pointers are the schema
+// of the type they point to, and interfaces allow any value. Structs are objects
+// with a property for each exported field that is not ignored by its yaml tag.
+//
+// Other types, recursive types, and inline fields are not supported.
+func ForType(t reflect.Type, options ...ForTypeOption) (Schema, error) {
+	return forType(t, options...)
+}
+
+// ForTypeOption is an option for ForType.
+type ForTypeOption func(*forTypeOptions)
+
+// ForTypeWithAlternative allows the given schema as an alternative to the schema
+// of the given type, wherever the type is used.
+//
+// This is used for types that have custom unmarshalling, such as types that can
+// also be unmarshalled from a string.
+func ForTypeWithAlternative(t reflect.Type, alternative Schema) ForTypeOption {
+	return func(forTypeOptions *forTypeOptions) {
+		forTypeOptions.typeToAlternatives[t] = append(forTypeOptions.typeToAlternatives[t], alternative)
+	}
+}
+
+// ForTypeWithTitle sets the title of the schema.
+func ForTypeWithTitle(title string) ForTypeOption {
+	return func(forTypeOptions *forTypeOptions) {
+		forTypeOptions.title = title
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Version  string             `yaml:"version,omitempty"`
+	Enabled  *bool              `yaml:"enabled,omitempty"`
+	Count    int                `yaml:"count,omitempty"`
+	Names    []string           `yaml:"names,omitempty"`
+	Options  map[string]string  `yaml:"options,omitempty"`
+	Prefix   testPrefixConfig   `yaml:"prefix,omitempty"`
+	Opt      interface{}        `yaml:"opt,omitempty"`
+	Ignored  string             `yaml:"-"`
+	Default  string             ``
+	Children []testPrefixConfig `yaml:"children,omitempty"`
+}
+
+type testPrefixConfig struct {
+	Default string `yaml:"default,omitempty"`
+}
+
+type testRecursiveConfig struct {
+	Children []testRecursiveConfig `yaml:"children,omitempty"`
+}
+
+type testInlineConfig struct {
+	Prefix testPrefixConfig `yaml:",inline"`
+}
+
+func TestForType(t *testing.T) {
+	t.Parallel()
+	prefixSchema := Schema{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"default": Schema{"type": "string"},
+		},
+		"additionalProperties": false,
+	}
+	schema, err := ForType(
+		reflect.TypeOf(testConfig{}),
+		ForTypeWithAlternative(reflect.TypeOf(testPrefixConfig{}), Schema{"type": "string"}),
+		ForTypeWithTitle("test"),
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		Schema{
+			"$schema": DraftURI,
+			"title":   "test",
+			"type":    "object",
+			"properties": map[string]interface{}{
+				"version": Schema{"type": "string"},
+				"enabled": Schema{"type": "boolean"},
+				"count":   Schema{"type": "integer"},
+				"names": Schema{
+					"type":  "array",
+					"items": Schema{"type": "string"},
+				},
+				"options": Schema{
+					"type":                 "object",
+					"additionalProperties": Schema{"type": "string"},
+				},
+				"prefix": Schema{
+					"anyOf": []interface{}{
+						Schema{"type": "string"},
+						prefixSchema,
+					},
+				},
+				"opt":     Schema{},
+				"default": Schema{"type": "string"},
+				"children": Schema{
+					"type": "array",
+					"items": Schema{
+						"anyOf": []interface{}{
+							Schema{"type": "string"},
+							prefixSchema,
+						},
+					},
+				},
+			},
+			"additionalProperties": false,
+		},
+		schema,
+	)
+}
+
+func TestForTypeError(t *testing.T) {
+	t.Parallel()
+	_, err := ForType(reflect.TypeOf(testRecursiveConfig{}))
+	assert.Error(t, err)
+	_, err = ForType(reflect.TypeOf(testInlineConfig{}))
+	assert.Error(t, err)
+	_, err = ForType(reflect.TypeOf(map[int]string{}))
+	assert.Error(t, err)
+	_, err = ForType(reflect.TypeOf(func() {}))
+	assert.Error(t, err)
+}
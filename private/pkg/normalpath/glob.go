@@ -0,0 +1,69 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package normalpath
+
+import (
+	"path"
+	"strings"
+)
+
+// ValidateGlob validates that the glob is a valid glob for MatchGlob.
+//
+// The glob is expected to be normalized and validated. Each of its components
+// must be a valid pattern for path.Match.
+func ValidateGlob(glob string) error {
+	for _, globComponent := range strings.Split(glob, "/") {
+		// path.Match only reports a syntax error if it gets far enough
+		// to see it, so the component is matched against the empty string.
+		if _, err := path.Match(globComponent, ""); err != nil {
+			return NewError(glob, err)
+		}
+	}
+	return nil
+}
+
+// MatchGlob returns true if the normalized and validated path matches the glob.
+//
+// Each component of the glob is matched against a component of the path with
+// path.Match, except for "**", which matches zero or more components. For
+// example, "**/v1/**" matches "foo/v1/bar.proto" and "v1/bar.proto".
+//
+// The glob is expected to be validated with ValidateGlob. Invalid components
+// never match.
+func MatchGlob(glob string, name string) bool {
+	return matchGlobComponents(strings.Split(glob, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobComponents(globComponents []string, components []string) bool {
+	if len(globComponents) == 0 {
+		return len(components) == 0
+	}
+	if globComponents[0] == "**" {
+		for i := 0; i <= len(components); i++ {
+			if matchGlobComponents(globComponents[1:], components[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(components) == 0 {
+		return false
+	}
+	matched, err := path.Match(globComponents[0], components[0])
+	if err != nil || !matched {
+		return false
+	}
+	return matchGlobComponents(globComponents[1:], components[1:])
+}
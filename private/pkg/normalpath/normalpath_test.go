@@ -197,3 +197,17 @@ func testChunkByDir(t *testing.T, paths []string, suggestedChunkSize int, expect
 	// algorithm, our expectations will change.
 	assert.Equal(t, expected, ChunkByDir(paths, suggestedChunkSize))
 }
+
+func TestMatchGlob(t *testing.T) {
+	t.Parallel()
+	assert.True(t, MatchGlob("**/v1/**", "v1/a.proto"))
+	assert.True(t, MatchGlob("**/v1/**", "a/b/v1/c/d.proto"))
+	assert.False(t, MatchGlob("**/v1/**", "a/v1alpha1/b.proto"))
+	assert.True(t, MatchGlob("a/*.proto", "a/b.proto"))
+	assert.False(t, MatchGlob("a/*.proto", "a/b/c.proto"))
+	assert.True(t, MatchGlob("a/**", "a/b/c.proto"))
+	assert.True(t, MatchGlob("a/**/c.proto", "a/c.proto"))
+	assert.False(t, MatchGlob("a/[b", "a/["))
+	assert.NoError(t, ValidateGlob("a/**/[bc]?.proto"))
+	assert.Error(t, ValidateGlob("a/[b"))
+}
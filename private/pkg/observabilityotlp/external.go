@@ -0,0 +1,260 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observabilityotlp
+
+import (
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// The external types are the JSON encoding of the OTLP ExportTraceServiceRequest.
+//
+// See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding. Trace and span
+// IDs are hex-encoded, and 64-bit integers are encoded as strings.
+
+// The values of the OTLP Status.StatusCode enum.
+const (
+	externalStatusCodeOK    = 1
+	externalStatusCodeError = 2
+)
+
+type externalExportTraceServiceRequest struct {
+	ResourceSpans []*externalResourceSpans `json:"resourceSpans"`
+}
+
+type externalResourceSpans struct {
+	Resource   *externalResource     `json:"resource"`
+	ScopeSpans []*externalScopeSpans `json:"scopeSpans"`
+}
+
+type externalResource struct {
+	Attributes []*externalKeyValue `json:"attributes,omitempty"`
+}
+
+type externalScopeSpans struct {
+	Scope *externalScope  `json:"scope"`
+	Spans []*externalSpan `json:"spans"`
+}
+
+type externalScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type externalSpan struct {
+	TraceID           string              `json:"traceId"`
+	SpanID            string              `json:"spanId"`
+	ParentSpanID      string              `json:"parentSpanId,omitempty"`
+	Name              string              `json:"name"`
+	Kind              int                 `json:"kind"`
+	StartTimeUnixNano string              `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string              `json:"endTimeUnixNano"`
+	Attributes        []*externalKeyValue `json:"attributes,omitempty"`
+	Events            []*externalEvent    `json:"events,omitempty"`
+	Status            *externalStatus     `json:"status,omitempty"`
+}
+
+type externalEvent struct {
+	TimeUnixNano string              `json:"timeUnixNano"`
+	Name         string              `json:"name"`
+	Attributes   []*externalKeyValue `json:"attributes,omitempty"`
+}
+
+type externalStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type externalKeyValue struct {
+	Key   string         `json:"key"`
+	Value *externalValue `json:"value"`
+}
+
+type externalValue struct {
+	StringValue *string             `json:"stringValue,omitempty"`
+	BoolValue   *bool               `json:"boolValue,omitempty"`
+	IntValue    *string             `json:"intValue,omitempty"`
+	DoubleValue *float64            `json:"doubleValue,omitempty"`
+	ArrayValue  *externalArrayValue `json:"arrayValue,omitempty"`
+}
+
+type externalArrayValue struct {
+	Values []*externalValue `json:"values"`
+}
+
+// newExternalExportTraceServiceRequest groups the spans by resource and then by
+// instrumentation scope, in the order that they are first seen.
+func newExternalExportTraceServiceRequest(spans []sdktrace.ReadOnlySpan) *externalExportTraceServiceRequest {
+	request := &externalExportTraceServiceRequest{}
+	resourceToResourceSpans := make(map[*resource.Resource]*externalResourceSpans)
+	resourceSpansToScopeToScopeSpans := make(map[*externalResourceSpans]map[instrumentation.Scope]*externalScopeSpans)
+	for _, span := range spans {
+		resourceSpans, ok := resourceToResourceSpans[span.Resource()]
+		if !ok {
+			resourceSpans = &externalResourceSpans{
+				Resource: newExternalResource(span.Resource()),
+			}
+			resourceToResourceSpans[span.Resource()] = resourceSpans
+			resourceSpansToScopeToScopeSpans[resourceSpans] = make(map[instrumentation.Scope]*externalScopeSpans)
+			request.ResourceSpans = append(request.ResourceSpans, resourceSpans)
+		}
+		scope := span.InstrumentationScope()
+		scopeSpans, ok := resourceSpansToScopeToScopeSpans[resourceSpans][scope]
+		if !ok {
+			scopeSpans = &externalScopeSpans{
+				Scope: &externalScope{
+					Name:    scope.Name,
+					Version: scope.Version,
+				},
+			}
+			resourceSpansToScopeToScopeSpans[resourceSpans][scope] = scopeSpans
+			resourceSpans.ScopeSpans = append(resourceSpans.ScopeSpans, scopeSpans)
+		}
+		scopeSpans.Spans = append(scopeSpans.Spans, newExternalSpan(span))
+	}
+	return request
+}
+
+func newExternalResource(resource *resource.Resource) *externalResource {
+	if resource == nil {
+		return &externalResource{}
+	}
+	return &externalResource{
+		Attributes: newExternalKeyValues(resource.Attributes()),
+	}
+}
+
+func newExternalSpan(span sdktrace.ReadOnlySpan) *externalSpan {
+	spanContext := span.SpanContext()
+	externalSpan := &externalSpan{
+		TraceID:           spanContext.TraceID().String(),
+		SpanID:            spanContext.SpanID().String(),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()),
+		StartTimeUnixNano: timeToExternal(span.StartTime()),
+		EndTimeUnixNano:   timeToExternal(span.EndTime()),
+		Attributes:        newExternalKeyValues(span.Attributes()),
+	}
+	if parent := span.Parent(); parent.HasSpanID() {
+		externalSpan.ParentSpanID = parent.SpanID().String()
+	}
+	for _, event := range span.Events() {
+		externalSpan.Events = append(
+			externalSpan.Events,
+			&externalEvent{
+				TimeUnixNano: timeToExternal(event.Time),
+				Name:         event.Name,
+				Attributes:   newExternalKeyValues(event.Attributes),
+			},
+		)
+	}
+	// The codes of the OpenTelemetry API differ from the codes of OTLP.
+	switch status := span.Status(); status.Code {
+	case codes.Ok:
+		externalSpan.Status = &externalStatus{Code: externalStatusCodeOK}
+	case codes.Error:
+		externalSpan.Status = &externalStatus{
+			Code:    externalStatusCodeError,
+			Message: status.Description,
+		}
+	}
+	return externalSpan
+}
+
+func newExternalKeyValues(keyValues []attribute.KeyValue) []*externalKeyValue {
+	if len(keyValues) == 0 {
+		return nil
+	}
+	externalKeyValues := make([]*externalKeyValue, len(keyValues))
+	for i, keyValue := range keyValues {
+		externalKeyValues[i] = &externalKeyValue{
+			Key:   string(keyValue.Key),
+			Value: newExternalValue(keyValue.Value),
+		}
+	}
+	return externalKeyValues
+}
+
+func newExternalValue(value attribute.Value) *externalValue {
+	switch value.Type() {
+	case attribute.BOOL:
+		return newExternalBoolValue(value.AsBool())
+	case attribute.INT64:
+		return newExternalIntValue(value.AsInt64())
+	case attribute.FLOAT64:
+		return newExternalDoubleValue(value.AsFloat64())
+	case attribute.BOOLSLICE:
+		values := value.AsBoolSlice()
+		externalValues := make([]*externalValue, len(values))
+		for i, value := range values {
+			externalValues[i] = newExternalBoolValue(value)
+		}
+		return newExternalArrayValue(externalValues)
+	case attribute.INT64SLICE:
+		values := value.AsInt64Slice()
+		externalValues := make([]*externalValue, len(values))
+		for i, value := range values {
+			externalValues[i] = newExternalIntValue(value)
+		}
+		return newExternalArrayValue(externalValues)
+	case attribute.FLOAT64SLICE:
+		values := value.AsFloat64Slice()
+		externalValues := make([]*externalValue, len(values))
+		for i, value := range values {
+			externalValues[i] = newExternalDoubleValue(value)
+		}
+		return newExternalArrayValue(externalValues)
+	case attribute.STRINGSLICE:
+		values := value.AsStringSlice()
+		externalValues := make([]*externalValue, len(values))
+		for i, value := range values {
+			externalValues[i] = newExternalStringValue(value)
+		}
+		return newExternalArrayValue(externalValues)
+	default:
+		return newExternalStringValue(value.Emit())
+	}
+}
+
+func newExternalStringValue(value string) *externalValue {
+	return &externalValue{StringValue: &value}
+}
+
+func newExternalBoolValue(value bool) *externalValue {
+	return &externalValue{BoolValue: &value}
+}
+
+func newExternalIntValue(value int64) *externalValue {
+	intValue := strconv.FormatInt(value, 10)
+	return &externalValue{IntValue: &intValue}
+}
+
+func newExternalDoubleValue(value float64) *externalValue {
+	return &externalValue{DoubleValue: &value}
+}
+
+func newExternalArrayValue(values []*externalValue) *externalValue {
+	return &externalValue{ArrayValue: &externalArrayValue{Values: values}}
+}
+
+func timeToExternal(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
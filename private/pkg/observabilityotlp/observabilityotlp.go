@@ -0,0 +1,268 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observabilityotlp exports OpenTelemetry traces to an OTLP endpoint.
+//
+// Spans are sent with the OTLP/HTTP transport using the JSON encoding, which all
+// OpenTelemetry collectors accept. The exporter, sampler, and resource are configured
+// with the standard OpenTelemetry environment variables.
+package observabilityotlp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	// EndpointEnvKey is the env var for the base URL of the OTLP endpoint.
+	//
+	// Traces are sent to the path /v1/traces of the URL.
+	EndpointEnvKey = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// TracesEndpointEnvKey is the env var for the URL that traces are sent to.
+	//
+	// This takes precedence over EndpointEnvKey, and is used as-is.
+	TracesEndpointEnvKey = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	// HeadersEnvKey is the env var for the headers sent to the OTLP endpoint,
+	// as a comma-separated list of key=value pairs with URL-encoded values.
+	HeadersEnvKey = "OTEL_EXPORTER_OTLP_HEADERS"
+	// TracesHeadersEnvKey is the env var for the headers sent with traces to the
+	// OTLP endpoint, in the same form as HeadersEnvKey.
+	//
+	// These headers take precedence over the headers of HeadersEnvKey.
+	TracesHeadersEnvKey = "OTEL_EXPORTER_OTLP_TRACES_HEADERS"
+	// SamplerEnvKey is the env var for the sampler of traces.
+	//
+	// This must be one of always_on, always_off, traceidratio, parentbased_always_on,
+	// parentbased_always_off, or parentbased_traceidratio.
+	SamplerEnvKey = "OTEL_TRACES_SAMPLER"
+	// SamplerArgEnvKey is the env var for the ratio of traces that are sampled by
+	// the traceidratio samplers, between 0 and 1.
+	//
+	// If this is set and SamplerEnvKey is not, the sampler is traceidratio.
+	SamplerArgEnvKey = "OTEL_TRACES_SAMPLER_ARG"
+	// ServiceNameEnvKey is the env var for the service.name resource attribute.
+	ServiceNameEnvKey = "OTEL_SERVICE_NAME"
+	// ResourceAttributesEnvKey is the env var for additional resource attributes,
+	// in the same form as HeadersEnvKey.
+	ResourceAttributesEnvKey = "OTEL_RESOURCE_ATTRIBUTES"
+
+	samplerAlwaysOn                = "always_on"
+	samplerAlwaysOff               = "always_off"
+	samplerTraceIDRatio            = "traceidratio"
+	samplerParentBasedAlwaysOn     = "parentbased_always_on"
+	samplerParentBasedAlwaysOff    = "parentbased_always_off"
+	samplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+
+	tracesPath       = "/v1/traces"
+	serviceNameKey   = "service.name"
+	defaultRatioText = "1"
+)
+
+var allSamplers = []string{
+	samplerAlwaysOn,
+	samplerAlwaysOff,
+	samplerTraceIDRatio,
+	samplerParentBasedAlwaysOn,
+	samplerParentBasedAlwaysOff,
+	samplerParentBasedTraceIDRatio,
+}
+
+// NewSpanExporter returns a new SpanExporter that sends spans to the given URL.
+//
+// The URL is the full URL that traces are sent to, such as
+// http://localhost:4318/v1/traces.
+func NewSpanExporter(url string, options ...SpanExporterOption) sdktrace.SpanExporter {
+	return newSpanExporter(url, options...)
+}
+
+// SpanExporterOption is an option for a new SpanExporter.
+type SpanExporterOption func(*spanExporter)
+
+// SpanExporterWithHeaders returns a new SpanExporterOption that sets the headers
+// sent with each request.
+func SpanExporterWithHeaders(headers map[string]string) SpanExporterOption {
+	return func(spanExporter *spanExporter) {
+		spanExporter.headers = headers
+	}
+}
+
+// SpanExporterWithHTTPClient returns a new SpanExporterOption that sets the
+// HTTP client used to send spans.
+//
+// The default is a client with a timeout of 10 seconds.
+func SpanExporterWithHTTPClient(httpClient *http.Client) SpanExporterOption {
+	return func(spanExporter *spanExporter) {
+		spanExporter.httpClient = httpClient
+	}
+}
+
+// NewSpanExporterForEnv returns a new SpanExporter configured by the environment
+// variables of the container.
+//
+// If neither EndpointEnvKey nor TracesEndpointEnvKey is set, this returns nil.
+func NewSpanExporterForEnv(
+	envContainer app.EnvContainer,
+	options ...SpanExporterOption,
+) (sdktrace.SpanExporter, error) {
+	tracesURL, err := getTracesURL(envContainer)
+	if err != nil {
+		return nil, err
+	}
+	if tracesURL == "" {
+		return nil, nil
+	}
+	headers, err := parseKeyValues(envContainer, HeadersEnvKey)
+	if err != nil {
+		return nil, err
+	}
+	tracesHeaders, err := parseKeyValues(envContainer, TracesHeadersEnvKey)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range tracesHeaders {
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[key] = value
+	}
+	return newSpanExporter(
+		tracesURL,
+		append(
+			[]SpanExporterOption{SpanExporterWithHeaders(headers)},
+			options...,
+		)...,
+	), nil
+}
+
+// NewSamplerForEnv returns a new Sampler configured by the environment variables
+// of the container.
+//
+// If neither SamplerEnvKey nor SamplerArgEnvKey is set, all traces are sampled.
+func NewSamplerForEnv(envContainer app.EnvContainer) (sdktrace.Sampler, error) {
+	sampler := strings.TrimSpace(envContainer.Env(SamplerEnvKey))
+	ratioText := strings.TrimSpace(envContainer.Env(SamplerArgEnvKey))
+	if sampler == "" {
+		if ratioText == "" {
+			return sdktrace.AlwaysSample(), nil
+		}
+		sampler = samplerTraceIDRatio
+	}
+	switch sampler {
+	case samplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case samplerAlwaysOff:
+		return sdktrace.NeverSample(), nil
+	case samplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case samplerParentBasedAlwaysOff:
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case samplerTraceIDRatio, samplerParentBasedTraceIDRatio:
+		if ratioText == "" {
+			ratioText = defaultRatioText
+		}
+		ratio, err := strconv.ParseFloat(ratioText, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("%s must be a number between 0 and 1 but was %q", SamplerArgEnvKey, ratioText)
+		}
+		if sampler == samplerTraceIDRatio {
+			return sdktrace.TraceIDRatioBased(ratio), nil
+		}
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("%s must be one of %s but was %q", SamplerEnvKey, strings.Join(allSamplers, ", "), sampler)
+	}
+}
+
+// NewResourceForEnv returns a new Resource configured by the environment variables
+// of the container.
+//
+// The service.name attribute is defaultServiceName if neither ServiceNameEnvKey nor
+// ResourceAttributesEnvKey sets it.
+func NewResourceForEnv(envContainer app.EnvContainer, defaultServiceName string) (*resource.Resource, error) {
+	keyValues, err := parseKeyValues(envContainer, ResourceAttributesEnvKey)
+	if err != nil {
+		return nil, err
+	}
+	if serviceName := strings.TrimSpace(envContainer.Env(ServiceNameEnvKey)); serviceName != "" {
+		if keyValues == nil {
+			keyValues = make(map[string]string)
+		}
+		keyValues[serviceNameKey] = serviceName
+	}
+	attributes := []attribute.KeyValue{
+		attribute.String(serviceNameKey, defaultServiceName),
+	}
+	for key, value := range keyValues {
+		attributes = append(attributes, attribute.String(key, value))
+	}
+	// Later attributes with the same key take precedence.
+	return resource.NewSchemaless(attributes...), nil
+}
+
+func getTracesURL(envContainer app.EnvContainer) (string, error) {
+	envKey := TracesEndpointEnvKey
+	value := strings.TrimSpace(envContainer.Env(TracesEndpointEnvKey))
+	if value == "" {
+		envKey = EndpointEnvKey
+		value = strings.TrimSpace(envContainer.Env(EndpointEnvKey))
+		if value == "" {
+			return "", nil
+		}
+		value = strings.TrimSuffix(value, "/") + tracesPath
+	}
+	parsedURL, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a valid URL: %w", envKey, err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return "", fmt.Errorf("%s must be a http or https URL but was %q", envKey, value)
+	}
+	return value, nil
+}
+
+// parseKeyValues parses the comma-separated key=value pairs of the env var.
+//
+// The values are URL-decoded.
+func parseKeyValues(envContainer app.EnvContainer, envKey string) (map[string]string, error) {
+	value := strings.TrimSpace(envContainer.Env(envKey))
+	if value == "" {
+		return nil, nil
+	}
+	keyValues := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s must be a comma-separated list of key=value pairs but had %q", envKey, pair)
+		}
+		unescapedValue, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s had an invalid value for %q: %w", envKey, key, err)
+		}
+		keyValues[key] = unescapedValue
+	}
+	return keyValues, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observabilityotlp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSpanExporter(t *testing.T) {
+	t.Parallel()
+	requests := make(chan *externalExportTraceServiceRequest, 1)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				assert.Equal(t, "/v1/traces", request.URL.Path)
+				assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+				assert.Equal(t, "value=1", request.Header.Get("X-Key"))
+				data, err := io.ReadAll(request.Body)
+				assert.NoError(t, err)
+				exportRequest := &externalExportTraceServiceRequest{}
+				assert.NoError(t, json.Unmarshal(data, exportRequest))
+				requests <- exportRequest
+			},
+		),
+	)
+	t.Cleanup(server.Close)
+	spanExporter, err := NewSpanExporterForEnv(
+		app.NewEnvContainer(
+			map[string]string{
+				EndpointEnvKey:      server.URL + "/",
+				HeadersEnvKey:       "x-key=value%3D1, other=2",
+				TracesHeadersEnvKey: "other=3",
+			},
+		),
+	)
+	require.NoError(t, err)
+	resource, err := NewResourceForEnv(
+		app.NewEnvContainer(
+			map[string]string{
+				ResourceAttributesEnvKey: "ci.job=42",
+			},
+		),
+		"buf",
+	)
+	require.NoError(t, err)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(spanExporter),
+		sdktrace.WithResource(resource),
+	)
+	ctx, parentSpan := tracerProvider.Tracer("test").Start(context.Background(), "parent")
+	_, childSpan := tracerProvider.Tracer("test").Start(ctx, "child")
+	childSpan.SetAttributes(
+		attribute.Int64("count", 3),
+		attribute.StringSlice("paths", []string{"a.proto", "b.proto"}),
+	)
+	childSpan.SetStatus(codes.Error, "failed")
+	childSpan.End()
+	exportRequest := <-requests
+	require.Len(t, exportRequest.ResourceSpans, 1)
+	resourceSpans := exportRequest.ResourceSpans[0]
+	assert.Equal(
+		t,
+		[]*externalKeyValue{
+			{Key: "ci.job", Value: newExternalStringValue("42")},
+			{Key: "service.name", Value: newExternalStringValue("buf")},
+		},
+		resourceSpans.Resource.Attributes,
+	)
+	require.Len(t, resourceSpans.ScopeSpans, 1)
+	assert.Equal(t, "test", resourceSpans.ScopeSpans[0].Scope.Name)
+	require.Len(t, resourceSpans.ScopeSpans[0].Spans, 1)
+	span := resourceSpans.ScopeSpans[0].Spans[0]
+	assert.Equal(t, "child", span.Name)
+	assert.Equal(t, parentSpan.SpanContext().TraceID().String(), span.TraceID)
+	assert.Equal(t, parentSpan.SpanContext().SpanID().String(), span.ParentSpanID)
+	assert.Equal(t, &externalStatus{Code: externalStatusCodeError, Message: "failed"}, span.Status)
+	assert.Equal(
+		t,
+		[]*externalKeyValue{
+			{Key: "count", Value: newExternalIntValue(3)},
+			{
+				Key: "paths",
+				Value: newExternalArrayValue(
+					[]*externalValue{
+						newExternalStringValue("a.proto"),
+						newExternalStringValue("b.proto"),
+					},
+				),
+			},
+		},
+		span.Attributes,
+	)
+	require.NoError(t, tracerProvider.Shutdown(context.Background()))
+}
+
+func TestSpanExporterError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				responseWriter.WriteHeader(http.StatusUnauthorized)
+			},
+		),
+	)
+	t.Cleanup(server.Close)
+	tracerProvider := sdktrace.NewTracerProvider()
+	_, span := tracerProvider.Tracer("test").Start(context.Background(), "span")
+	span.End()
+	readOnlySpan, ok := span.(sdktrace.ReadOnlySpan)
+	require.True(t, ok)
+	err := NewSpanExporter(server.URL).ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{readOnlySpan})
+	assert.EqualError(t, err, "exporting spans to "+server.URL+" failed: 401 Unauthorized")
+}
+
+func TestNewSpanExporterForEnv(t *testing.T) {
+	t.Parallel()
+	exporter, err := NewSpanExporterForEnv(app.NewEnvContainer(nil))
+	require.NoError(t, err)
+	assert.Nil(t, exporter)
+	exporter, err = NewSpanExporterForEnv(
+		app.NewEnvContainer(
+			map[string]string{
+				EndpointEnvKey:       "http://localhost:4318",
+				TracesEndpointEnvKey: "https://example.com/traces",
+			},
+		),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/traces", exporter.(*spanExporter).url)
+	_, err = NewSpanExporterForEnv(app.NewEnvContainer(map[string]string{EndpointEnvKey: "localhost:4318"}))
+	assert.EqualError(t, err, `OTEL_EXPORTER_OTLP_ENDPOINT must be a http or https URL but was "localhost:4318/v1/traces"`)
+	_, err = NewSpanExporterForEnv(
+		app.NewEnvContainer(
+			map[string]string{
+				EndpointEnvKey: "http://localhost:4318",
+				HeadersEnvKey:  "token",
+			},
+		),
+	)
+	assert.EqualError(t, err, `OTEL_EXPORTER_OTLP_HEADERS must be a comma-separated list of key=value pairs but had "token"`)
+}
+
+func TestNewSamplerForEnv(t *testing.T) {
+	t.Parallel()
+	testNewSamplerForEnv(t, "", "", "AlwaysOnSampler")
+	testNewSamplerForEnv(t, "", "0.25", "TraceIDRatioBased{0.25}")
+	testNewSamplerForEnv(t, "always_off", "", "AlwaysOffSampler")
+	testNewSamplerForEnv(t, "traceidratio", "", "AlwaysOnSampler")
+	testNewSamplerForEnv(
+		t,
+		"parentbased_traceidratio",
+		"0.5",
+		"ParentBased{root:TraceIDRatioBased{0.5},remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}",
+	)
+	_, err := NewSamplerForEnv(app.NewEnvContainer(map[string]string{SamplerArgEnvKey: "half"}))
+	assert.EqualError(t, err, `OTEL_TRACES_SAMPLER_ARG must be a number between 0 and 1 but was "half"`)
+	_, err = NewSamplerForEnv(app.NewEnvContainer(map[string]string{SamplerEnvKey: "jaeger_remote"}))
+	assert.EqualError(
+		t,
+		err,
+		`OTEL_TRACES_SAMPLER must be one of always_on, always_off, traceidratio, parentbased_always_on, parentbased_always_off, parentbased_traceidratio but was "jaeger_remote"`,
+	)
+}
+
+func testNewSamplerForEnv(t *testing.T, sampler string, samplerArg string, expectedDescription string) {
+	sdkSampler, err := NewSamplerForEnv(
+		app.NewEnvContainer(
+			map[string]string{
+				SamplerEnvKey:    sampler,
+				SamplerArgEnvKey: samplerArg,
+			},
+		),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, expectedDescription, sdkSampler.Description())
+}
@@ -0,0 +1,84 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observabilityotlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const defaultHTTPTimeout = 10 * time.Second
+
+var _ sdktrace.SpanExporter = &spanExporter{}
+
+type spanExporter struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+func newSpanExporter(url string, options ...SpanExporterOption) *spanExporter {
+	spanExporter := &spanExporter{
+		url: url,
+		httpClient: &http.Client{
+			Timeout: defaultHTTPTimeout,
+		},
+	}
+	for _, option := range options {
+		option(spanExporter)
+	}
+	return spanExporter
+}
+
+func (s *spanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(newExternalExportTraceServiceRequest(spans))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range s.headers {
+		request.Header.Set(key, value)
+	}
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// Drain the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("exporting spans to %s failed: %s", s.url, response.Status)
+	}
+	return nil
+}
+
+func (s *spanExporter) Shutdown(ctx context.Context) error {
+	s.httpClient.CloseIdleConnections()
+	return nil
+}
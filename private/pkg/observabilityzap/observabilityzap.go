@@ -19,6 +19,7 @@ import (
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -26,14 +27,76 @@ import (
 
 // Start creates a Zap logging exporter for Opentelemetry traces and returns
 // the exporter. The exporter implements io.Closer for clean-up.
-func Start(logger *zap.Logger) (trace.TracerProvider, io.Closer) {
+func Start(logger *zap.Logger, options ...StartOption) (trace.TracerProvider, io.Closer) {
+	startOptions := newStartOptions()
+	for _, option := range options {
+		option(startOptions)
+	}
 	exporter := newZapExporter(logger)
 	tracerProviderOptions := []sdktrace.TracerProviderOption{
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSampler(startOptions.sampler),
 		sdktrace.WithSpanProcessor(sdktrace.NewBatchSpanProcessor(exporter)),
 	}
+	for _, spanExporter := range startOptions.spanExporters {
+		tracerProviderOptions = append(
+			tracerProviderOptions,
+			sdktrace.WithSpanProcessor(sdktrace.NewBatchSpanProcessor(spanExporter)),
+		)
+	}
+	if startOptions.resource != nil {
+		tracerProviderOptions = append(tracerProviderOptions, sdktrace.WithResource(startOptions.resource))
+	}
 	tracerProvider := sdktrace.NewTracerProvider(tracerProviderOptions...)
 	otel.SetTracerProvider(tracerProvider)
 	otel.SetTextMapPropagator(propagation.TraceContext{})
+	// Errors from exporting spans are not fatal, and are logged instead of being
+	// printed by the default handler of the otel package.
+	otel.SetErrorHandler(
+		otel.ErrorHandlerFunc(
+			func(err error) {
+				logger.Warn("tracing", zap.Error(err))
+			},
+		),
+	)
 	return tracerProvider, newTracerProviderCloser(tracerProvider)
 }
+
+// StartOption is an option for Start.
+type StartOption func(*startOptions)
+
+// StartWithSpanExporter returns a new StartOption that also exports the spans
+// to the given SpanExporter.
+func StartWithSpanExporter(spanExporter sdktrace.SpanExporter) StartOption {
+	return func(startOptions *startOptions) {
+		startOptions.spanExporters = append(startOptions.spanExporters, spanExporter)
+	}
+}
+
+// StartWithSampler returns a new StartOption that sets the sampler of traces.
+//
+// The default is to sample all traces.
+func StartWithSampler(sampler sdktrace.Sampler) StartOption {
+	return func(startOptions *startOptions) {
+		startOptions.sampler = sampler
+	}
+}
+
+// StartWithResource returns a new StartOption that sets the resource that the
+// spans are attributed to.
+func StartWithResource(resource *resource.Resource) StartOption {
+	return func(startOptions *startOptions) {
+		startOptions.resource = resource
+	}
+}
+
+type startOptions struct {
+	spanExporters []sdktrace.SpanExporter
+	sampler       sdktrace.Sampler
+	resource      *resource.Resource
+}
+
+func newStartOptions() *startOptions {
+	return &startOptions{
+		sampler: sdktrace.AlwaysSample(),
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+
+	grantTypeDeviceCode    = "urn:ietf:params:oauth:grant-type:device_code"
+	grantTypeRefreshToken  = "refresh_token"
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// defaultPollInterval is the interval between polling requests if the
+	// provider does not specify one, per RFC 8628.
+	defaultPollInterval = 5 * time.Second
+	// slowDownInterval is added to the interval between polling requests
+	// each time the provider returns ErrorCodeSlowDown, per RFC 8628.
+	slowDownInterval = 5 * time.Second
+	// maxResponseSize is the maximum size of a response that is read.
+	maxResponseSize = 1 << 20
+)
+
+func discover(ctx context.Context, httpClient *http.Client, issuer string) (_ *ProviderMetadata, retErr error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+discoveryPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not discover OpenID provider %s: unexpected HTTP status code %d", issuer, response.StatusCode)
+	}
+	providerMetadata := &ProviderMetadata{}
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(providerMetadata); err != nil {
+		return nil, fmt.Errorf("could not discover OpenID provider %s: %w", issuer, err)
+	}
+	if strings.TrimSuffix(providerMetadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OpenID provider %s returned metadata for issuer %q", issuer, providerMetadata.Issuer)
+	}
+	if providerMetadata.TokenEndpoint == "" {
+		return nil, fmt.Errorf("OpenID provider %s did not return a token endpoint", issuer)
+	}
+	return providerMetadata, nil
+}
+
+func startDeviceAuthorization(
+	ctx context.Context,
+	httpClient *http.Client,
+	providerMetadata *ProviderMetadata,
+	clientID string,
+	scopes []string,
+) (*DeviceAuthorization, error) {
+	if providerMetadata.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OpenID provider %s does not support the device authorization grant", providerMetadata.Issuer)
+	}
+	values := url.Values{
+		"client_id": {clientID},
+	}
+	if len(scopes) > 0 {
+		values.Set("scope", strings.Join(scopes, " "))
+	}
+	deviceAuthorization := &DeviceAuthorization{}
+	if err := postForm(ctx, httpClient, providerMetadata.DeviceAuthorizationEndpoint, values, deviceAuthorization); err != nil {
+		return nil, err
+	}
+	if deviceAuthorization.DeviceCode == "" || deviceAuthorization.UserCode == "" || deviceAuthorization.VerificationURI == "" {
+		return nil, fmt.Errorf("OpenID provider %s returned an incomplete device authorization", providerMetadata.Issuer)
+	}
+	return deviceAuthorization, nil
+}
+
+func pollDeviceToken(
+	ctx context.Context,
+	httpClient *http.Client,
+	providerMetadata *ProviderMetadata,
+	clientID string,
+	deviceAuthorization *DeviceAuthorization,
+	sleep func(context.Context, time.Duration) error,
+) (*Token, error) {
+	interval := time.Duration(deviceAuthorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	if deviceAuthorization.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(deviceAuthorization.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	values := url.Values{
+		"grant_type":  {grantTypeDeviceCode},
+		"device_code": {deviceAuthorization.DeviceCode},
+		"client_id":   {clientID},
+	}
+	for {
+		if err := sleep(ctx, interval); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, &Error{Code: ErrorCodeExpiredToken, Description: "the device code expired"}
+			}
+			return nil, err
+		}
+		token := &Token{}
+		err := postForm(ctx, httpClient, providerMetadata.TokenEndpoint, values, token)
+		if err == nil {
+			return token, nil
+		}
+		oauthErr := &Error{}
+		if !errors.As(err, &oauthErr) {
+			return nil, err
+		}
+		switch oauthErr.Code {
+		case ErrorCodeAuthorizationPending:
+		case ErrorCodeSlowDown:
+			interval += slowDownInterval
+		default:
+			return nil, err
+		}
+	}
+}
+
+func refresh(
+	ctx context.Context,
+	httpClient *http.Client,
+	providerMetadata *ProviderMetadata,
+	clientID string,
+	refreshToken string,
+) (*Token, error) {
+	token := &Token{}
+	if err := postForm(
+		ctx,
+		httpClient,
+		providerMetadata.TokenEndpoint,
+		url.Values{
+			"grant_type":    {grantTypeRefreshToken},
+			"refresh_token": {refreshToken},
+			"client_id":     {clientID},
+		},
+		token,
+	); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func exchangeToken(
+	ctx context.Context,
+	httpClient *http.Client,
+	tokenEndpoint string,
+	subjectToken string,
+	subjectTokenType string,
+) (*Token, error) {
+	token := &Token{}
+	if err := postForm(
+		ctx,
+		httpClient,
+		tokenEndpoint,
+		url.Values{
+			"grant_type":           {grantTypeTokenExchange},
+			"subject_token":        {subjectToken},
+			"subject_token_type":   {subjectTokenType},
+			"requested_token_type": {TokenTypeAccessToken},
+		},
+		token,
+	); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token exchange at %s did not return an access token", tokenEndpoint)
+	}
+	return token, nil
+}
+
+// postForm posts the form values to the endpoint and decodes the JSON
+// response into value.
+//
+// Error responses are returned as an *Error if they have an error code.
+func postForm(
+	ctx context.Context,
+	httpClient *http.Client,
+	endpoint string,
+	values url.Values,
+	value interface{},
+) (retErr error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		oauthErr := &Error{}
+		if err := json.Unmarshal(data, oauthErr); err == nil && oauthErr.Code != "" {
+			return oauthErr
+		}
+		return fmt.Errorf("unexpected HTTP status code %d from %s", response.StatusCode, endpoint)
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", endpoint, err)
+	}
+	return nil
+}
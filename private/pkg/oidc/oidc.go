@@ -0,0 +1,168 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oidc implements the OpenID Connect and OAuth 2.0 requests that are
+// used to log in with an identity provider from a command line tool.
+//
+// This includes discovery, the device authorization grant of RFC 8628, the
+// refresh token grant, and the token exchange of RFC 8693.
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// TokenTypeIDToken is the token type of OpenID Connect ID tokens for
+	// token exchange.
+	TokenTypeIDToken = "urn:ietf:params:oauth:token-type:id_token"
+	// TokenTypeAccessToken is the token type of OAuth 2.0 access tokens for
+	// token exchange.
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	// ErrorCodeAuthorizationPending is the error code returned while the user
+	// has not yet completed a device authorization.
+	ErrorCodeAuthorizationPending = "authorization_pending"
+	// ErrorCodeSlowDown is the error code returned when a device token is
+	// polled too often.
+	ErrorCodeSlowDown = "slow_down"
+	// ErrorCodeAccessDenied is the error code returned when the user denied a
+	// device authorization.
+	ErrorCodeAccessDenied = "access_denied"
+	// ErrorCodeExpiredToken is the error code returned when a device code has
+	// expired.
+	ErrorCodeExpiredToken = "expired_token"
+)
+
+// ProviderMetadata is the metadata of an OpenID provider, as returned by
+// discovery.
+type ProviderMetadata struct {
+	Issuer                      string `json:"issuer,omitempty"`
+	TokenEndpoint               string `json:"token_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+}
+
+// DeviceAuthorization is the response to a device authorization request.
+type DeviceAuthorization struct {
+	DeviceCode string `json:"device_code,omitempty"`
+	// UserCode is the code that the user enters at the VerificationURI.
+	UserCode        string `json:"user_code,omitempty"`
+	VerificationURI string `json:"verification_uri,omitempty"`
+	// VerificationURIComplete is the VerificationURI with the UserCode
+	// included, which is not returned by all providers.
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	// ExpiresIn is the number of seconds that the DeviceCode is valid for.
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+	// Interval is the number of seconds to wait between polling requests,
+	// which is zero if the provider did not specify one.
+	Interval int64 `json:"interval,omitempty"`
+}
+
+// Token is the response to a token request.
+type Token struct {
+	AccessToken     string `json:"access_token,omitempty"`
+	TokenType       string `json:"token_type,omitempty"`
+	IDToken         string `json:"id_token,omitempty"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+	// ExpiresIn is the number of seconds that the AccessToken is valid for,
+	// which is zero if the provider did not specify it.
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+}
+
+// Error is an error response from an OAuth 2.0 endpoint.
+type Error struct {
+	Code        string `json:"error,omitempty"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Description)
+	}
+	return e.Code
+}
+
+// Discover gets the metadata of the OpenID provider for the issuer.
+func Discover(ctx context.Context, httpClient *http.Client, issuer string) (*ProviderMetadata, error) {
+	return discover(ctx, httpClient, issuer)
+}
+
+// StartDeviceAuthorization starts a device authorization for the client with
+// the given scopes.
+//
+// Returns an error if the provider does not support the device authorization
+// grant.
+func StartDeviceAuthorization(
+	ctx context.Context,
+	httpClient *http.Client,
+	providerMetadata *ProviderMetadata,
+	clientID string,
+	scopes []string,
+) (*DeviceAuthorization, error) {
+	return startDeviceAuthorization(ctx, httpClient, providerMetadata, clientID, scopes)
+}
+
+// PollDeviceToken polls the token endpoint until the user completes the device
+// authorization, and returns the token.
+//
+// Returns an *Error with ErrorCodeAccessDenied or ErrorCodeExpiredToken if
+// the user denied the authorization or did not complete it in time.
+func PollDeviceToken(
+	ctx context.Context,
+	httpClient *http.Client,
+	providerMetadata *ProviderMetadata,
+	clientID string,
+	deviceAuthorization *DeviceAuthorization,
+) (*Token, error) {
+	return pollDeviceToken(ctx, httpClient, providerMetadata, clientID, deviceAuthorization, sleep)
+}
+
+// Refresh gets a new token with the refresh token.
+func Refresh(
+	ctx context.Context,
+	httpClient *http.Client,
+	providerMetadata *ProviderMetadata,
+	clientID string,
+	refreshToken string,
+) (*Token, error) {
+	return refresh(ctx, httpClient, providerMetadata, clientID, refreshToken)
+}
+
+// ExchangeToken exchanges the subject token of the given type, such as
+// TokenTypeIDToken, for an access token at the token endpoint.
+func ExchangeToken(
+	ctx context.Context,
+	httpClient *http.Client,
+	tokenEndpoint string,
+	subjectToken string,
+	subjectTokenType string,
+) (*Token, error) {
+	return exchangeToken(ctx, httpClient, tokenEndpoint, subjectToken, subjectTokenType)
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
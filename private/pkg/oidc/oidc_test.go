@@ -0,0 +1,204 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceAuthorization(t *testing.T) {
+	t.Parallel()
+	provider := newTestProvider(t)
+	ctx := context.Background()
+	httpClient := provider.server.Client()
+	providerMetadata, err := Discover(ctx, httpClient, provider.server.URL+"/")
+	require.NoError(t, err)
+	assert.Equal(t, provider.server.URL+"/token", providerMetadata.TokenEndpoint)
+	deviceAuthorization, err := StartDeviceAuthorization(ctx, httpClient, providerMetadata, "buf", []string{"openid", "offline_access"})
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD-EFGH", deviceAuthorization.UserCode)
+	var intervals []time.Duration
+	token, err := pollDeviceToken(
+		ctx,
+		httpClient,
+		providerMetadata,
+		"buf",
+		deviceAuthorization,
+		func(_ context.Context, duration time.Duration) error {
+			intervals = append(intervals, duration)
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "id-token", token.IDToken)
+	assert.Equal(t, "refresh-token", token.RefreshToken)
+	// The polling interval is increased after slow_down.
+	assert.Equal(t, []time.Duration{2 * time.Second, 2 * time.Second, 7 * time.Second}, intervals)
+	assert.Equal(t, "openid offline_access", provider.scope)
+
+	token, err = Refresh(ctx, httpClient, providerMetadata, "buf", "refresh-token")
+	require.NoError(t, err)
+	assert.Equal(t, "refreshed-id-token", token.IDToken)
+	_, err = Refresh(ctx, httpClient, providerMetadata, "buf", "other")
+	oauthErr := &Error{}
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "invalid_grant", oauthErr.Code)
+}
+
+func TestDeviceAuthorizationDenied(t *testing.T) {
+	t.Parallel()
+	provider := newTestProvider(t)
+	provider.deny = true
+	ctx := context.Background()
+	httpClient := provider.server.Client()
+	providerMetadata, err := Discover(ctx, httpClient, provider.server.URL)
+	require.NoError(t, err)
+	deviceAuthorization, err := StartDeviceAuthorization(ctx, httpClient, providerMetadata, "buf", nil)
+	require.NoError(t, err)
+	_, err = pollDeviceToken(
+		ctx,
+		httpClient,
+		providerMetadata,
+		"buf",
+		deviceAuthorization,
+		func(context.Context, time.Duration) error { return nil },
+	)
+	oauthErr := &Error{}
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, ErrorCodeAccessDenied, oauthErr.Code)
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writeJSON(writer, http.StatusOK, &ProviderMetadata{Issuer: "https://other.example.com", TokenEndpoint: "https://other.example.com/token"})
+	}))
+	t.Cleanup(server.Close)
+	_, err := Discover(context.Background(), server.Client(), server.URL)
+	assert.ErrorContains(t, err, `returned metadata for issuer "https://other.example.com"`)
+}
+
+func TestExchangeToken(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		require.NoError(t, request.ParseForm())
+		if request.Form.Get("grant_type") != grantTypeTokenExchange ||
+			request.Form.Get("subject_token_type") != TokenTypeIDToken ||
+			request.Form.Get("subject_token") != "id-token" {
+			writeJSON(writer, http.StatusBadRequest, &Error{Code: "invalid_request"})
+			return
+		}
+		writeJSON(writer, http.StatusOK, &Token{AccessToken: "registry-token", IssuedTokenType: TokenTypeAccessToken, ExpiresIn: 3600})
+	}))
+	t.Cleanup(server.Close)
+	token, err := ExchangeToken(context.Background(), server.Client(), server.URL, "id-token", TokenTypeIDToken)
+	require.NoError(t, err)
+	assert.Equal(t, "registry-token", token.AccessToken)
+	assert.Equal(t, int64(3600), token.ExpiresIn)
+	_, err = ExchangeToken(context.Background(), server.Client(), server.URL, "other", TokenTypeIDToken)
+	assert.EqualError(t, err, "invalid_request")
+}
+
+type testProvider struct {
+	server *httptest.Server
+	deny   bool
+
+	lock  sync.Mutex
+	polls int
+	scope string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	provider := &testProvider{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(writer http.ResponseWriter, _ *http.Request) {
+		writeJSON(
+			writer,
+			http.StatusOK,
+			&ProviderMetadata{
+				Issuer:                      provider.server.URL,
+				TokenEndpoint:               provider.server.URL + "/token",
+				DeviceAuthorizationEndpoint: provider.server.URL + "/device",
+			},
+		)
+	})
+	mux.HandleFunc("/device", func(writer http.ResponseWriter, request *http.Request) {
+		require.NoError(t, request.ParseForm())
+		provider.lock.Lock()
+		provider.scope = request.Form.Get("scope")
+		provider.lock.Unlock()
+		writeJSON(
+			writer,
+			http.StatusOK,
+			&DeviceAuthorization{
+				DeviceCode:      "device-code",
+				UserCode:        "ABCD-EFGH",
+				VerificationURI: provider.server.URL + "/activate",
+				ExpiresIn:       600,
+				Interval:        2,
+			},
+		)
+	})
+	mux.HandleFunc("/token", func(writer http.ResponseWriter, request *http.Request) {
+		require.NoError(t, request.ParseForm())
+		assert.Equal(t, "buf", request.Form.Get("client_id"))
+		switch request.Form.Get("grant_type") {
+		case grantTypeDeviceCode:
+			assert.Equal(t, "device-code", request.Form.Get("device_code"))
+			if provider.deny {
+				writeJSON(writer, http.StatusBadRequest, &Error{Code: ErrorCodeAccessDenied})
+				return
+			}
+			provider.lock.Lock()
+			provider.polls++
+			polls := provider.polls
+			provider.lock.Unlock()
+			switch polls {
+			case 1:
+				writeJSON(writer, http.StatusBadRequest, &Error{Code: ErrorCodeAuthorizationPending})
+			case 2:
+				writeJSON(writer, http.StatusBadRequest, &Error{Code: ErrorCodeSlowDown})
+			default:
+				writeJSON(writer, http.StatusOK, &Token{AccessToken: "access-token", IDToken: "id-token", RefreshToken: "refresh-token"})
+			}
+		case grantTypeRefreshToken:
+			if request.Form.Get("refresh_token") != "refresh-token" {
+				writeJSON(writer, http.StatusBadRequest, &Error{Code: "invalid_grant"})
+				return
+			}
+			writeJSON(writer, http.StatusOK, &Token{AccessToken: "access-token", IDToken: "refreshed-id-token"})
+		default:
+			writeJSON(writer, http.StatusBadRequest, &Error{Code: "unsupported_grant_type"})
+		}
+	})
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+func writeJSON(writer http.ResponseWriter, statusCode int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_ = json.NewEncoder(writer).Encode(value)
+}
@@ -0,0 +1,467 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const nullValueFullName protoreflect.FullName = "google.protobuf.NullValue"
+
+// errFallback is returned by the encoders when the value must be marshalled with
+// the jsonMarshaler instead, so that errors are the same as those of protojson.
+var errFallback = errors.New("fallback to protojson")
+
+type jsonStreamMarshaler struct {
+	jsonMarshaler *jsonMarshaler
+	bufferPool    sync.Pool
+	// messageDescriptorToMessageEncoder caches the *jsonMessageEncoder of each
+	// protoreflect.MessageDescriptor.
+	messageDescriptorToMessageEncoder sync.Map
+}
+
+func newJSONStreamMarshaler(resolver Resolver, options ...JSONMarshalerOption) Marshaler {
+	jsonMarshaler := newJSONMarshaler(resolver, options...).(*jsonMarshaler)
+	if jsonMarshaler.emitUnpopulated {
+		// Unpopulated fields are not specialized, they are rarely used for streams.
+		return jsonMarshaler
+	}
+	return &jsonStreamMarshaler{
+		jsonMarshaler: jsonMarshaler,
+		bufferPool: sync.Pool{
+			New: func() interface{} {
+				return new([]byte)
+			},
+		},
+	}
+}
+
+func (m *jsonStreamMarshaler) Marshal(message proto.Message) ([]byte, error) {
+	reflectMessage := message.ProtoReflect()
+	if err := ReparseUnrecognized(m.jsonMarshaler.resolver, reflectMessage); err != nil {
+		return nil, err
+	}
+	bufferPointer := m.bufferPool.Get().(*[]byte)
+	defer func() {
+		m.bufferPool.Put(bufferPointer)
+	}()
+	buffer, err := m.appendMessage((*bufferPointer)[:0], reflectMessage)
+	// Keep the grown buffer even if there was an error.
+	*bufferPointer = buffer
+	if err != nil {
+		if errors.Is(err, errFallback) {
+			return m.jsonMarshaler.Marshal(message)
+		}
+		return nil, err
+	}
+	if m.jsonMarshaler.indent != "" {
+		indentBuffer := bytes.NewBuffer(make([]byte, 0, len(buffer)*2))
+		if err := json.Indent(indentBuffer, buffer, "", m.jsonMarshaler.indent); err != nil {
+			return nil, err
+		}
+		return indentBuffer.Bytes(), nil
+	}
+	return append(make([]byte, 0, len(buffer)), buffer...), nil
+}
+
+func (m *jsonStreamMarshaler) appendMessage(out []byte, message protoreflect.Message) ([]byte, error) {
+	return m.getMessageEncoder(message.Descriptor()).appendMessage(out, message)
+}
+
+// getMessageEncoder returns the encoder for the message descriptor, creating it
+// on first use.
+func (m *jsonStreamMarshaler) getMessageEncoder(messageDescriptor protoreflect.MessageDescriptor) *jsonMessageEncoder {
+	if value, ok := m.messageDescriptorToMessageEncoder.Load(messageDescriptor); ok {
+		return value.(*jsonMessageEncoder)
+	}
+	value, _ := m.messageDescriptorToMessageEncoder.LoadOrStore(
+		messageDescriptor,
+		newJSONMessageEncoder(m, messageDescriptor),
+	)
+	return value.(*jsonMessageEncoder)
+}
+
+// appendProtoJSON appends the compacted protojson encoding of the message.
+//
+// This is used for the messages with a special JSON encoding, such as the
+// well-known types, and for messages with extensions.
+func (m *jsonStreamMarshaler) appendProtoJSON(out []byte, message protoreflect.Message) ([]byte, error) {
+	options := protojson.MarshalOptions{
+		Resolver:       m.jsonMarshaler.resolver,
+		UseProtoNames:  m.jsonMarshaler.useProtoNames,
+		UseEnumNumbers: m.jsonMarshaler.useEnumNumbers,
+	}
+	data, err := options.Marshal(message.Interface())
+	if err != nil {
+		return out, errFallback
+	}
+	buffer := bytes.NewBuffer(out)
+	if err := json.Compact(buffer, data); err != nil {
+		return out, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// jsonMessageEncoder encodes the messages of one message descriptor.
+type jsonMessageEncoder struct {
+	marshaler *jsonStreamMarshaler
+	// useProtoJSON is true if the messages are encoded with protojson.
+	useProtoJSON bool
+	// fieldEncoders are in declaration order, which is the order of protojson.
+	fieldEncoders []*jsonFieldEncoder
+}
+
+func newJSONMessageEncoder(
+	marshaler *jsonStreamMarshaler,
+	messageDescriptor protoreflect.MessageDescriptor,
+) *jsonMessageEncoder {
+	messageEncoder := &jsonMessageEncoder{
+		marshaler: marshaler,
+	}
+	// Well-known types have special encodings, and extensions are rare enough
+	// to leave to protojson.
+	if messageDescriptor.FullName().Parent() == "google.protobuf" || messageDescriptor.ExtensionRanges().Len() > 0 {
+		messageEncoder.useProtoJSON = true
+		return messageEncoder
+	}
+	fieldDescriptors := messageDescriptor.Fields()
+	messageEncoder.fieldEncoders = make([]*jsonFieldEncoder, fieldDescriptors.Len())
+	for i := 0; i < fieldDescriptors.Len(); i++ {
+		messageEncoder.fieldEncoders[i] = newJSONFieldEncoder(marshaler, fieldDescriptors.Get(i))
+	}
+	return messageEncoder
+}
+
+func (e *jsonMessageEncoder) appendMessage(out []byte, message protoreflect.Message) ([]byte, error) {
+	if e.useProtoJSON {
+		return e.marshaler.appendProtoJSON(out, message)
+	}
+	out = append(out, '{')
+	first := true
+	var err error
+	for _, fieldEncoder := range e.fieldEncoders {
+		if !message.Has(fieldEncoder.fieldDescriptor) {
+			continue
+		}
+		if !first {
+			out = append(out, ',')
+		}
+		first = false
+		out = append(out, fieldEncoder.name...)
+		out, err = fieldEncoder.appendValue(out, message.Get(fieldEncoder.fieldDescriptor))
+		if err != nil {
+			return out, err
+		}
+	}
+	return append(out, '}'), nil
+}
+
+// jsonFieldEncoder encodes the values of one field.
+type jsonFieldEncoder struct {
+	fieldDescriptor protoreflect.FieldDescriptor
+	// name is the quoted JSON name followed by a colon.
+	name        []byte
+	appendValue func([]byte, protoreflect.Value) ([]byte, error)
+}
+
+func newJSONFieldEncoder(
+	marshaler *jsonStreamMarshaler,
+	fieldDescriptor protoreflect.FieldDescriptor,
+) *jsonFieldEncoder {
+	name := fieldDescriptor.JSONName()
+	if marshaler.jsonMarshaler.useProtoNames {
+		name = fieldDescriptor.TextName()
+	}
+	// Field names are valid UTF-8.
+	quotedName, _ := appendJSONString(nil, name)
+	fieldEncoder := &jsonFieldEncoder{
+		fieldDescriptor: fieldDescriptor,
+		name:            append(quotedName, ':'),
+	}
+	switch {
+	case fieldDescriptor.IsMap():
+		fieldEncoder.appendValue = newJSONMapEncoder(marshaler, fieldDescriptor)
+	case fieldDescriptor.IsList():
+		appendSingular := newJSONSingularEncoder(marshaler, fieldDescriptor)
+		fieldEncoder.appendValue = func(out []byte, value protoreflect.Value) ([]byte, error) {
+			list := value.List()
+			out = append(out, '[')
+			var err error
+			for i := 0; i < list.Len(); i++ {
+				if i > 0 {
+					out = append(out, ',')
+				}
+				if out, err = appendSingular(out, list.Get(i)); err != nil {
+					return out, err
+				}
+			}
+			return append(out, ']'), nil
+		}
+	default:
+		fieldEncoder.appendValue = newJSONSingularEncoder(marshaler, fieldDescriptor)
+	}
+	return fieldEncoder
+}
+
+func newJSONMapEncoder(
+	marshaler *jsonStreamMarshaler,
+	fieldDescriptor protoreflect.FieldDescriptor,
+) func([]byte, protoreflect.Value) ([]byte, error) {
+	appendMapValue := newJSONSingularEncoder(marshaler, fieldDescriptor.MapValue())
+	keyKind := fieldDescriptor.MapKey().Kind()
+	return func(out []byte, value protoreflect.Value) ([]byte, error) {
+		mapValue := value.Map()
+		mapKeys := make([]protoreflect.MapKey, 0, mapValue.Len())
+		mapValue.Range(
+			func(mapKey protoreflect.MapKey, _ protoreflect.Value) bool {
+				mapKeys = append(mapKeys, mapKey)
+				return true
+			},
+		)
+		sortMapKeys(mapKeys, keyKind)
+		out = append(out, '{')
+		var err error
+		for i, mapKey := range mapKeys {
+			if i > 0 {
+				out = append(out, ',')
+			}
+			if out, err = appendJSONString(out, mapKey.String()); err != nil {
+				return out, err
+			}
+			out = append(out, ':')
+			if out, err = appendMapValue(out, mapValue.Get(mapKey)); err != nil {
+				return out, err
+			}
+		}
+		return append(out, '}'), nil
+	}
+}
+
+// newJSONSingularEncoder returns the function that encodes a single value of the field,
+// that is a value of a non-repeated field, or an element of a list or map.
+func newJSONSingularEncoder(
+	marshaler *jsonStreamMarshaler,
+	fieldDescriptor protoreflect.FieldDescriptor,
+) func([]byte, protoreflect.Value) ([]byte, error) {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.BoolKind:
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			return strconv.AppendBool(out, value.Bool()), nil
+		}
+	case protoreflect.StringKind:
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			return appendJSONString(out, value.String())
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			return strconv.AppendInt(out, value.Int(), 10), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			return strconv.AppendUint(out, value.Uint(), 10), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// 64-bit integers are encoded as strings.
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			out = append(out, '"')
+			out = strconv.AppendInt(out, value.Int(), 10)
+			return append(out, '"'), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			out = append(out, '"')
+			out = strconv.AppendUint(out, value.Uint(), 10)
+			return append(out, '"'), nil
+		}
+	case protoreflect.FloatKind:
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			return appendJSONFloat(out, value.Float(), 32), nil
+		}
+	case protoreflect.DoubleKind:
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			return appendJSONFloat(out, value.Float(), 64), nil
+		}
+	case protoreflect.BytesKind:
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			data := value.Bytes()
+			out = append(out, '"')
+			start := len(out)
+			out = append(out, make([]byte, base64.StdEncoding.EncodedLen(len(data)))...)
+			base64.StdEncoding.Encode(out[start:], data)
+			return append(out, '"'), nil
+		}
+	case protoreflect.EnumKind:
+		enumDescriptor := fieldDescriptor.Enum()
+		if enumDescriptor.FullName() == nullValueFullName {
+			return func(out []byte, value protoreflect.Value) ([]byte, error) {
+				return append(out, "null"...), nil
+			}
+		}
+		enumValueDescriptors := enumDescriptor.Values()
+		useEnumNumbers := marshaler.jsonMarshaler.useEnumNumbers
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			number := value.Enum()
+			if !useEnumNumbers {
+				if enumValueDescriptor := enumValueDescriptors.ByNumber(number); enumValueDescriptor != nil {
+					return appendJSONString(out, string(enumValueDescriptor.Name()))
+				}
+			}
+			return strconv.AppendInt(out, int64(number), 10), nil
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		messageDescriptor := fieldDescriptor.Message()
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			// The encoder is looked up when it is used, as messages may be recursive.
+			return marshaler.getMessageEncoder(messageDescriptor).appendMessage(out, value.Message())
+		}
+	default:
+		return func(out []byte, value protoreflect.Value) ([]byte, error) {
+			return out, errFallback
+		}
+	}
+}
+
+// sortMapKeys sorts the map keys in the order of protojson, that is false
+// before true, numbers in ascending order, and strings in lexicographical order.
+func sortMapKeys(mapKeys []protoreflect.MapKey, keyKind protoreflect.Kind) {
+	var less func(i int, j int) bool
+	switch keyKind {
+	case protoreflect.BoolKind:
+		less = func(i int, j int) bool {
+			return !mapKeys[i].Bool() && mapKeys[j].Bool()
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		less = func(i int, j int) bool {
+			return mapKeys[i].Int() < mapKeys[j].Int()
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		less = func(i int, j int) bool {
+			return mapKeys[i].Uint() < mapKeys[j].Uint()
+		}
+	default:
+		less = func(i int, j int) bool {
+			return mapKeys[i].String() < mapKeys[j].String()
+		}
+	}
+	sort.Slice(mapKeys, less)
+}
+
+// appendJSONString appends the string as a quoted JSON string, with the same
+// escaping as protojson.
+//
+// This returns errFallback if the string is not valid UTF-8.
+func appendJSONString(out []byte, in string) ([]byte, error) {
+	out = append(out, '"')
+	for len(in) > 0 {
+		i := indexNeedEscapeInJSONString(in)
+		in, out = in[i:], append(out, in[:i]...)
+		if len(in) == 0 {
+			break
+		}
+		r, n := utf8.DecodeRuneInString(in)
+		switch {
+		case r == utf8.RuneError && n == 1:
+			return out, errFallback
+		case r < ' ' || r == '"' || r == '\\':
+			out = append(out, '\\')
+			switch r {
+			case '"', '\\':
+				out = append(out, byte(r))
+			case '\b':
+				out = append(out, 'b')
+			case '\f':
+				out = append(out, 'f')
+			case '\n':
+				out = append(out, 'n')
+			case '\r':
+				out = append(out, 'r')
+			case '\t':
+				out = append(out, 't')
+			default:
+				out = append(out, 'u')
+				out = append(out, "0000"[1+(bits.Len32(uint32(r))-1)/4:]...)
+				out = strconv.AppendUint(out, uint64(r), 16)
+			}
+		default:
+			// A valid encoding of utf8.RuneError.
+			out = append(out, in[:n]...)
+		}
+		in = in[n:]
+	}
+	return append(out, '"'), nil
+}
+
+// indexNeedEscapeInJSONString returns the index of the first character that
+// needs escaping or validation, or the length of the string if there is none.
+func indexNeedEscapeInJSONString(s string) int {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c == '\\' || c == '"' || c >= utf8.RuneSelf {
+			if c < utf8.RuneSelf {
+				return i
+			}
+			r, n := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError {
+				return i
+			}
+			i += n - 1
+		}
+	}
+	return len(s)
+}
+
+// appendJSONFloat appends the float with the same formatting as protojson,
+// which is the formatting of encoding/json, with the special values quoted.
+func appendJSONFloat(out []byte, f float64, bitSize int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(out, `"NaN"`...)
+	case math.IsInf(f, 1):
+		return append(out, `"Infinity"`...)
+	case math.IsInf(f, -1):
+		return append(out, `"-Infinity"`...)
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bitSize == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bitSize == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	out = strconv.AppendFloat(out, f, format, -1, bitSize)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		n := len(out)
+		if n >= 4 && out[n-4] == 'e' && out[n-3] == '-' && out[n-2] == '0' {
+			out[n-2] = out[n-1]
+			out = out[:n-1]
+		}
+	}
+	return out
+}
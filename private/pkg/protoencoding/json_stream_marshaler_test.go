@@ -0,0 +1,240 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestJSONStreamMarshaler(t *testing.T) {
+	t.Parallel()
+	messageDescriptor := newTestMessageDescriptor(t)
+	messages := []proto.Message{
+		newTestMessage(t, messageDescriptor, "first"),
+		newTestMessage(t, messageDescriptor, "second \"quoted\"\n �\x01 ünïcödé"),
+		dynamicpb.NewMessage(messageDescriptor),
+		protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+		timestamppb.New(timestamppb.Now().AsTime()),
+	}
+	for _, options := range [][]JSONMarshalerOption{
+		nil,
+		{JSONMarshalerWithUseProtoNames()},
+		{JSONMarshalerWithUseEnumNumbers()},
+		{JSONMarshalerWithIndent()},
+	} {
+		jsonMarshaler := NewJSONMarshaler(nil, options...)
+		jsonStreamMarshaler := NewJSONStreamMarshaler(nil, options...)
+		for _, message := range messages {
+			expected, err := jsonMarshaler.Marshal(message)
+			require.NoError(t, err)
+			// Marshal twice to use the cached encoders and pooled buffers.
+			for i := 0; i < 2; i++ {
+				actual, err := jsonStreamMarshaler.Marshal(message)
+				require.NoError(t, err)
+				assert.Equal(t, string(expected), string(actual))
+			}
+		}
+	}
+}
+
+func TestJSONStreamMarshalerInvalidUTF8(t *testing.T) {
+	t.Parallel()
+	message := newTestMessage(t, newTestMessageDescriptor(t), "invalid \xff")
+	_, expectedErr := NewJSONMarshaler(nil).Marshal(message)
+	require.Error(t, expectedErr)
+	_, err := NewJSONStreamMarshaler(nil).Marshal(message)
+	assert.Equal(t, expectedErr, err)
+}
+
+func BenchmarkJSONMarshaler(b *testing.B) {
+	benchmarkJSONMarshaler(b, NewJSONMarshaler(nil))
+}
+
+func BenchmarkJSONStreamMarshaler(b *testing.B) {
+	benchmarkJSONMarshaler(b, NewJSONStreamMarshaler(nil))
+}
+
+func benchmarkJSONMarshaler(b *testing.B, marshaler Marshaler) {
+	message := newTestMessage(b, newTestMessageDescriptor(b), "benchmark")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := marshaler.Marshal(message); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// newTestMessageDescriptor returns the descriptor of a message with a field of
+// each kind, as well as lists, maps, oneofs, and well-known types.
+func newTestMessageDescriptor(tb testing.TB) protoreflect.MessageDescriptor {
+	field := func(name string, number int32, fieldType descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     fieldType.Enum(),
+			JsonName: proto.String(name + "Json"),
+		}
+	}
+	withTypeName := func(fieldDescriptorProto *descriptorpb.FieldDescriptorProto, typeName string) *descriptorpb.FieldDescriptorProto {
+		fieldDescriptorProto.TypeName = proto.String(typeName)
+		return fieldDescriptorProto
+	}
+	repeated := func(fieldDescriptorProto *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		fieldDescriptorProto.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return fieldDescriptorProto
+	}
+	inOneof := func(fieldDescriptorProto *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		fieldDescriptorProto.OneofIndex = proto.Int32(0)
+		return fieldDescriptorProto
+	}
+	mapEntry := func(name string, keyType descriptorpb.FieldDescriptorProto_Type) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("key", 1, keyType),
+				field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}
+	}
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/test.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/struct.proto", "google/protobuf/timestamp.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			{
+				Name: proto.String("Color"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("COLOR_UNSPECIFIED"), Number: proto.Int32(0)},
+					{Name: proto.String("COLOR_RED"), Number: proto.Int32(1)},
+				},
+			},
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Record"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("bool", 1, descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+					field("string", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+					field("int32", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+					field("sint32", 4, descriptorpb.FieldDescriptorProto_TYPE_SINT32),
+					field("uint32", 5, descriptorpb.FieldDescriptorProto_TYPE_UINT32),
+					field("int64", 6, descriptorpb.FieldDescriptorProto_TYPE_INT64),
+					field("uint64", 7, descriptorpb.FieldDescriptorProto_TYPE_UINT64),
+					field("fixed64", 8, descriptorpb.FieldDescriptorProto_TYPE_FIXED64),
+					field("float", 9, descriptorpb.FieldDescriptorProto_TYPE_FLOAT),
+					field("double", 10, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE),
+					field("bytes", 11, descriptorpb.FieldDescriptorProto_TYPE_BYTES),
+					withTypeName(field("color", 12, descriptorpb.FieldDescriptorProto_TYPE_ENUM), ".test.v1.Color"),
+					withTypeName(field("child", 13, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), ".test.v1.Record"),
+					repeated(withTypeName(field("children", 14, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), ".test.v1.Record")),
+					repeated(field("doubles", 15, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE)),
+					repeated(withTypeName(field("colors", 16, descriptorpb.FieldDescriptorProto_TYPE_ENUM), ".test.v1.Color")),
+					repeated(withTypeName(field("string_map", 17, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), ".test.v1.Record.StringMapEntry")),
+					repeated(withTypeName(field("int_map", 18, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), ".test.v1.Record.IntMapEntry")),
+					repeated(withTypeName(field("bool_map", 19, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), ".test.v1.Record.BoolMapEntry")),
+					inOneof(field("oneof_string", 20, descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+					inOneof(field("oneof_int32", 21, descriptorpb.FieldDescriptorProto_TYPE_INT32)),
+					withTypeName(field("timestamp", 22, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), ".google.protobuf.Timestamp"),
+					withTypeName(field("struct", 23, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE), ".google.protobuf.Struct"),
+					withTypeName(field("null", 24, descriptorpb.FieldDescriptorProto_TYPE_ENUM), ".google.protobuf.NullValue"),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					mapEntry("StringMapEntry", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+					mapEntry("IntMapEntry", descriptorpb.FieldDescriptorProto_TYPE_SINT64),
+					mapEntry("BoolMapEntry", descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{
+					{Name: proto.String("choice")},
+				},
+			},
+		},
+	}
+	fileDescriptor, err := protodesc.NewFile(fileDescriptorProto, protoregistry.GlobalFiles)
+	require.NoError(tb, err)
+	return fileDescriptor.Messages().ByName("Record")
+}
+
+func newTestMessage(tb testing.TB, messageDescriptor protoreflect.MessageDescriptor, text string) proto.Message {
+	fields := messageDescriptor.Fields()
+	message := dynamicpb.NewMessage(messageDescriptor)
+	set := func(name protoreflect.Name, value protoreflect.Value) {
+		message.Set(fields.ByName(name), value)
+	}
+	set("bool", protoreflect.ValueOfBool(true))
+	set("string", protoreflect.ValueOfString(text))
+	set("int32", protoreflect.ValueOfInt32(-42))
+	set("sint32", protoreflect.ValueOfInt32(math.MinInt32))
+	set("uint32", protoreflect.ValueOfUint32(math.MaxUint32))
+	set("int64", protoreflect.ValueOfInt64(math.MinInt64))
+	set("uint64", protoreflect.ValueOfUint64(math.MaxUint64))
+	set("fixed64", protoreflect.ValueOfUint64(7))
+	set("float", protoreflect.ValueOfFloat32(1.1))
+	set("double", protoreflect.ValueOfFloat64(1e21))
+	set("bytes", protoreflect.ValueOfBytes([]byte("\x00\xffbytes")))
+	set("color", protoreflect.ValueOfEnum(1))
+	child := message.NewField(fields.ByName("child")).Message()
+	child.Set(fields.ByName("string"), protoreflect.ValueOfString("child"))
+	child.Set(fields.ByName("color"), protoreflect.ValueOfEnum(5))
+	set("child", protoreflect.ValueOfMessage(child))
+	children := message.NewField(fields.ByName("children")).List()
+	children.Append(protoreflect.ValueOfMessage(child))
+	children.Append(protoreflect.ValueOfMessage(dynamicpb.NewMessage(messageDescriptor)))
+	set("children", protoreflect.ValueOfList(children))
+	doubles := message.NewField(fields.ByName("doubles")).List()
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Copysign(0, -1), 1e-7, 0.1, 123456789} {
+		doubles.Append(protoreflect.ValueOfFloat64(value))
+	}
+	set("doubles", protoreflect.ValueOfList(doubles))
+	colors := message.NewField(fields.ByName("colors")).List()
+	colors.Append(protoreflect.ValueOfEnum(0))
+	colors.Append(protoreflect.ValueOfEnum(1))
+	set("colors", protoreflect.ValueOfList(colors))
+	stringMap := message.NewField(fields.ByName("string_map")).Map()
+	for _, key := range []string{"b", "a", "\"c\"", "ä"} {
+		stringMap.Set(protoreflect.ValueOfString(key).MapKey(), protoreflect.ValueOfString(key+text))
+	}
+	set("string_map", protoreflect.ValueOfMap(stringMap))
+	intMap := message.NewField(fields.ByName("int_map")).Map()
+	for _, key := range []int64{10, -1, 2} {
+		intMap.Set(protoreflect.ValueOfInt64(key).MapKey(), protoreflect.ValueOfString("int"))
+	}
+	set("int_map", protoreflect.ValueOfMap(intMap))
+	boolMap := message.NewField(fields.ByName("bool_map")).Map()
+	boolMap.Set(protoreflect.ValueOfBool(true).MapKey(), protoreflect.ValueOfString("true"))
+	boolMap.Set(protoreflect.ValueOfBool(false).MapKey(), protoreflect.ValueOfString("false"))
+	set("bool_map", protoreflect.ValueOfMap(boolMap))
+	set("oneof_int32", protoreflect.ValueOfInt32(0))
+	set("timestamp", protoreflect.ValueOfMessage(timestamppb.New(timestamppb.Now().AsTime()).ProtoReflect()))
+	structValue, err := structpb.NewStruct(map[string]interface{}{"key": []interface{}{1, "two", nil}})
+	require.NoError(tb, err)
+	set("struct", protoreflect.ValueOfMessage(structValue.ProtoReflect()))
+	return message
+}
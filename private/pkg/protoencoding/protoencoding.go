@@ -79,6 +79,19 @@ func NewJSONMarshaler(resolver Resolver, options ...JSONMarshalerOption) Marshal
 	return newJSONMarshaler(resolver, options...)
 }
 
+// NewJSONStreamMarshaler returns a new Marshaler for JSON that is specialized for
+// marshalling many messages, such as the records of a stream.
+//
+// The output is the same as the output of a Marshaler returned by NewJSONMarshaler
+// with the same options. The encoding of the fields of each message type is prepared
+// on first use, and buffers are reused between calls. Well-known types and messages
+// with extension ranges are still marshalled with protojson.
+//
+// The returned Marshaler is safe for concurrent use.
+func NewJSONStreamMarshaler(resolver Resolver, options ...JSONMarshalerOption) Marshaler {
+	return newJSONStreamMarshaler(resolver, options...)
+}
+
 // JSONMarshalerOption is an option for a new JSONMarshaler.
 type JSONMarshalerOption func(*jsonMarshaler)
 
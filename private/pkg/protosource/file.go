@@ -233,7 +233,12 @@ func (f *file) SyntaxLocation() Location {
 // does not validation of the fileDescriptorProto - this is assumed to be done elsewhere
 // does no duplicate checking by name - could just have maps ie importToFileImport, enumNameToEnum, etc
 func newFile(inputFile InputFile) (*file, error) {
-	locationStore := newLocationStore(inputFile.FileDescriptorProto().GetSourceCodeInfo().GetLocation())
+	// The source code info may be decoded separately, only do so if a location is requested.
+	locationStore := newLazyLocationStore(
+		func() []*descriptorpb.SourceCodeInfo_Location {
+			return inputFile.SourceCodeInfo().GetLocation()
+		},
+	)
 	f := &file{
 		FileInfo:       inputFile,
 		fileDescriptor: inputFile.FileDescriptorProto(),
@@ -28,7 +28,7 @@ func newFilesUnstable(ctx context.Context, inputFiles ...InputFile) ([]File, err
 		return nil, nil
 	}
 
-	chunkSize := len(inputFiles) / thread.Parallelism()
+	chunkSize := len(inputFiles) / thread.ParallelismForContext(ctx)
 	if defaultChunkSizeThreshold != 0 && chunkSize < defaultChunkSizeThreshold {
 		files := make([]File, 0, len(inputFiles))
 		for _, inputFile := range inputFiles {
@@ -21,16 +21,27 @@ import (
 )
 
 type locationStore struct {
-	sourceCodeInfoLocations []*descriptorpb.SourceCodeInfo_Location
+	loadSourceCodeInfoLocations func() []*descriptorpb.SourceCodeInfo_Location
 
-	initLocations  sync.Once
-	pathToLocation map[string]Location
+	initLocations           sync.Once
+	sourceCodeInfoLocations []*descriptorpb.SourceCodeInfo_Location
+	pathToLocation          map[string]Location
 }
 
 func newLocationStore(sourceCodeInfoLocations []*descriptorpb.SourceCodeInfo_Location) *locationStore {
+	return newLazyLocationStore(
+		func() []*descriptorpb.SourceCodeInfo_Location {
+			return sourceCodeInfoLocations
+		},
+	)
+}
+
+// newLazyLocationStore returns a new locationStore that only gets the locations
+// when a location is first requested.
+func newLazyLocationStore(loadSourceCodeInfoLocations func() []*descriptorpb.SourceCodeInfo_Location) *locationStore {
 	return &locationStore{
-		sourceCodeInfoLocations: sourceCodeInfoLocations,
-		pathToLocation:          make(map[string]Location),
+		loadSourceCodeInfoLocations: loadSourceCodeInfoLocations,
+		pathToLocation:              make(map[string]Location),
 	}
 }
 
@@ -39,7 +50,18 @@ func (l *locationStore) getLocation(path []int32) Location {
 }
 
 func (l *locationStore) getLocationByPathKey(pathKey string) Location {
+	l.init()
+	return l.pathToLocation[pathKey]
+}
+
+func (l *locationStore) getSourceCodeInfoLocations() []*descriptorpb.SourceCodeInfo_Location {
+	l.init()
+	return l.sourceCodeInfoLocations
+}
+
+func (l *locationStore) init() {
 	l.initLocations.Do(func() {
+		l.sourceCodeInfoLocations = l.loadSourceCodeInfoLocations()
 		pathToLocation := make(map[string]Location)
 		for _, sourceCodeInfoLocation := range l.sourceCodeInfoLocations {
 			pathKey := getPathKey(sourceCodeInfoLocation.Path)
@@ -53,8 +75,6 @@ func (l *locationStore) getLocationByPathKey(pathKey string) Location {
 		}
 		l.pathToLocation = pathToLocation
 	})
-
-	return l.pathToLocation[pathKey]
 }
 
 func getPathKey(path []int32) string {
@@ -68,7 +68,7 @@ func (o *optionExtensionDescriptor) OptionExtensionLocation(extensionType protor
 	// the path we are trying to find), use the first such one encountered.
 	var bestMatch *descriptorpb.SourceCodeInfo_Location
 	var bestMatchPathLen int
-	for _, loc := range o.locationStore.sourceCodeInfoLocations {
+	for _, loc := range o.locationStore.getSourceCodeInfoLocations() {
 		if len(loc.Path) >= extensionPathLen && isDescendantPath(path, loc.Path) && len(loc.Path) > bestMatchPathLen {
 			bestMatch = loc
 			bestMatchPathLen = len(loc.Path)
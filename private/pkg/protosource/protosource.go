@@ -472,6 +472,11 @@ type InputFile interface {
 	// This will never be nil.
 	// The value Path() is equal to FileDescriptorProto().GetName() .
 	FileDescriptorProto() *descriptorpb.FileDescriptorProto
+	// SourceCodeInfo is the source code info for this File.
+	//
+	// This may not be part of FileDescriptorProto, and is only called when a
+	// Location is first requested.
+	SourceCodeInfo() *descriptorpb.SourceCodeInfo
 	// IsSyntaxUnspecified will be true if the syntax was not explicitly specified.
 	IsSyntaxUnspecified() bool
 	// UnusedDependencyIndexes returns the indexes of the unused dependencies within
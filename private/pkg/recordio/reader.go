@@ -0,0 +1,131 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recordio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type reader struct {
+	reader        *bufio.Reader
+	framing       Framing
+	maxRecordSize int
+	buffer        []byte
+}
+
+func newReader(ioReader io.Reader, framing Framing, options ...ReaderOption) (*reader, error) {
+	if _, ok := framingToString[framing]; !ok {
+		return nil, fmt.Errorf("unknown framing: %v", framing)
+	}
+	reader := &reader{
+		reader:        bufio.NewReader(ioReader),
+		framing:       framing,
+		maxRecordSize: DefaultMaxRecordSize,
+	}
+	for _, option := range options {
+		option(reader)
+	}
+	return reader, nil
+}
+
+func (r *reader) Next() ([]byte, error) {
+	switch r.framing {
+	case FramingVarint:
+		size, err := binary.ReadUvarint(r.reader)
+		if err != nil {
+			return nil, lengthPrefixError(err)
+		}
+		return r.readRecord(size)
+	case FramingFixed32:
+		var sizeBytes [4]byte
+		if _, err := io.ReadFull(r.reader, sizeBytes[:]); err != nil {
+			return nil, lengthPrefixError(err)
+		}
+		return r.readRecord(uint64(binary.BigEndian.Uint32(sizeBytes[:])))
+	case FramingNewline:
+		return r.readLine()
+	default:
+		return nil, fmt.Errorf("unknown framing: %v", r.framing)
+	}
+}
+
+// readRecord reads a record of the given size after its length prefix has been read.
+func (r *reader) readRecord(size uint64) ([]byte, error) {
+	if size > uint64(r.maxRecordSize) {
+		return nil, fmt.Errorf("record of size %d exceeds maximum record size %d", size, r.maxRecordSize)
+	}
+	if uint64(cap(r.buffer)) < size {
+		r.buffer = make([]byte, size)
+	}
+	r.buffer = r.buffer[:size]
+	if _, err := io.ReadFull(r.reader, r.buffer); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return r.buffer, nil
+}
+
+func (r *reader) readLine() ([]byte, error) {
+	r.buffer = r.buffer[:0]
+	for {
+		line, err := r.reader.ReadSlice('\n')
+		if len(r.buffer)+len(line) > r.maxRecordSize+1 {
+			return nil, fmt.Errorf("record exceeds maximum record size %d", r.maxRecordSize)
+		}
+		r.buffer = append(r.buffer, line...)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		record := trimNewline(r.buffer)
+		if len(record) == 0 {
+			if err != nil {
+				// io.EOF
+				return nil, err
+			}
+			// Skip blank lines.
+			r.buffer = r.buffer[:0]
+			continue
+		}
+		return record, nil
+	}
+}
+
+func trimNewline(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+	}
+	return line
+}
+
+// lengthPrefixError converts an io.ErrUnexpectedEOF while reading a length prefix
+// into a descriptive error, and passes through io.EOF at a record boundary.
+func lengthPrefixError(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return errors.New("unexpected end of stream while reading record length")
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recordio reads and writes streams of framed records.
+//
+// Records are read and written one at a time, so that arbitrarily large streams
+// can be processed with memory bounded by the size of the largest record.
+package recordio
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FramingVarint frames each record with its length as a protobuf base-128
+	// varint. This is the framing used by writeDelimitedTo in protobuf-java and
+	// by the protodelim package in protobuf-go.
+	FramingVarint Framing = iota + 1
+	// FramingFixed32 frames each record with its length as a 4-byte big-endian
+	// unsigned integer.
+	FramingFixed32
+	// FramingNewline terminates each record with a newline.
+	//
+	// Records must not contain newlines, so this is only suitable for textual
+	// records such as single-line JSON.
+	FramingNewline
+
+	// DefaultMaxRecordSize is the default maximum size of a record.
+	DefaultMaxRecordSize = 64 << 20
+)
+
+var (
+	// AllFramingStrings is all framing strings.
+	//
+	// Sorted in the order we want to display them.
+	AllFramingStrings = []string{
+		"varint",
+		"fixed32",
+		"newline",
+	}
+
+	stringToFraming = map[string]Framing{
+		"varint":  FramingVarint,
+		"fixed32": FramingFixed32,
+		"newline": FramingNewline,
+	}
+	framingToString = map[Framing]string{
+		FramingVarint:  "varint",
+		FramingFixed32: "fixed32",
+		FramingNewline: "newline",
+	}
+)
+
+// Framing is the framing of records in a stream.
+type Framing int
+
+// String implements fmt.Stringer.
+func (f Framing) String() string {
+	s, ok := framingToString[f]
+	if !ok {
+		return strconv.Itoa(int(f))
+	}
+	return s
+}
+
+// ParseFraming parses the Framing.
+func ParseFraming(s string) (Framing, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	f, ok := stringToFraming[s]
+	if ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown framing: %q", s)
+}
+
+// Reader reads records.
+type Reader interface {
+	// Next returns the next record.
+	//
+	// The returned slice is only valid until the next call to Next.
+	// Returns io.EOF if there are no more records.
+	Next() ([]byte, error)
+}
+
+// NewReader returns a new Reader that reads records with the given framing.
+func NewReader(reader io.Reader, framing Framing, options ...ReaderOption) (Reader, error) {
+	return newReader(reader, framing, options...)
+}
+
+// ReaderOption is an option for a new Reader.
+type ReaderOption func(*reader)
+
+// ReaderWithMaxRecordSize returns a new ReaderOption that sets the maximum size
+// of a record.
+//
+// Records larger than this result in an error instead of being buffered.
+// The default is DefaultMaxRecordSize.
+func ReaderWithMaxRecordSize(maxRecordSize int) ReaderOption {
+	return func(reader *reader) {
+		reader.maxRecordSize = maxRecordSize
+	}
+}
+
+// Writer writes records.
+type Writer interface {
+	// Write writes the record.
+	Write(record []byte) error
+	// Flush flushes any buffered records to the underlying io.Writer.
+	Flush() error
+}
+
+// NewWriter returns a new Writer that writes records with the given framing.
+func NewWriter(writer io.Writer, framing Framing) (Writer, error) {
+	return newWriter(writer, framing)
+}
@@ -0,0 +1,118 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+	records := [][]byte{
+		[]byte(`{"id":"1"}`),
+		[]byte(strings.Repeat("a", 300)),
+		[]byte(`{"id":"3"}`),
+	}
+	for _, framing := range []Framing{FramingVarint, FramingFixed32, FramingNewline} {
+		framing := framing
+		t.Run(framing.String(), func(t *testing.T) {
+			t.Parallel()
+			buffer := bytes.NewBuffer(nil)
+			writer, err := NewWriter(buffer, framing)
+			require.NoError(t, err)
+			for _, record := range records {
+				require.NoError(t, writer.Write(record))
+			}
+			require.NoError(t, writer.Flush())
+			reader, err := NewReader(buffer, framing)
+			require.NoError(t, err)
+			assert.Equal(t, records, testReadAll(t, reader))
+		})
+	}
+}
+
+func TestReadFixed32(t *testing.T) {
+	t.Parallel()
+	reader, err := NewReader(bytes.NewReader([]byte{0, 0, 0, 2, 'h', 'i', 0, 0, 0, 0}), FramingFixed32)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("hi"), {}}, testReadAll(t, reader))
+}
+
+func TestReadNewline(t *testing.T) {
+	t.Parallel()
+	reader, err := NewReader(strings.NewReader("one\r\n\ntwo\nthree"), FramingNewline)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("one"), []byte("two"), []byte("three")}, testReadAll(t, reader))
+}
+
+func TestReadErrors(t *testing.T) {
+	t.Parallel()
+	reader, err := NewReader(bytes.NewReader([]byte{5, 'h', 'i'}), FramingVarint)
+	require.NoError(t, err)
+	_, err = reader.Next()
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	reader, err = NewReader(bytes.NewReader([]byte{0, 0}), FramingFixed32)
+	require.NoError(t, err)
+	_, err = reader.Next()
+	require.ErrorContains(t, err, "unexpected end of stream")
+
+	reader, err = NewReader(bytes.NewReader([]byte{0, 0, 1, 0}), FramingFixed32, ReaderWithMaxRecordSize(16))
+	require.NoError(t, err)
+	_, err = reader.Next()
+	require.ErrorContains(t, err, "exceeds maximum record size")
+
+	reader, err = NewReader(strings.NewReader(strings.Repeat("a", 32)), FramingNewline, ReaderWithMaxRecordSize(16))
+	require.NoError(t, err)
+	_, err = reader.Next()
+	require.ErrorContains(t, err, "exceeds maximum record size")
+}
+
+func TestWriteNewlineError(t *testing.T) {
+	t.Parallel()
+	writer, err := NewWriter(io.Discard, FramingNewline)
+	require.NoError(t, err)
+	require.Error(t, writer.Write([]byte("one\ntwo")))
+}
+
+func TestParseFraming(t *testing.T) {
+	t.Parallel()
+	for _, s := range AllFramingStrings {
+		framing, err := ParseFraming(s)
+		require.NoError(t, err)
+		assert.Equal(t, s, framing.String())
+	}
+	_, err := ParseFraming("riegeli")
+	require.Error(t, err)
+}
+
+func testReadAll(t *testing.T, reader Reader) [][]byte {
+	var records [][]byte
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, append([]byte{}, record...))
+	}
+}
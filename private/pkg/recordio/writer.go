@@ -0,0 +1,77 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recordio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+type writer struct {
+	writer  *bufio.Writer
+	framing Framing
+	prefix  [binary.MaxVarintLen64]byte
+}
+
+func newWriter(ioWriter io.Writer, framing Framing) (*writer, error) {
+	if _, ok := framingToString[framing]; !ok {
+		return nil, fmt.Errorf("unknown framing: %v", framing)
+	}
+	return &writer{
+		writer:  bufio.NewWriter(ioWriter),
+		framing: framing,
+	}, nil
+}
+
+func (w *writer) Write(record []byte) error {
+	switch w.framing {
+	case FramingVarint:
+		n := binary.PutUvarint(w.prefix[:], uint64(len(record)))
+		if _, err := w.writer.Write(w.prefix[:n]); err != nil {
+			return err
+		}
+		_, err := w.writer.Write(record)
+		return err
+	case FramingFixed32:
+		if uint64(len(record)) > math.MaxUint32 {
+			return fmt.Errorf("record of size %d is too large for fixed32 framing", len(record))
+		}
+		binary.BigEndian.PutUint32(w.prefix[:4], uint32(len(record)))
+		if _, err := w.writer.Write(w.prefix[:4]); err != nil {
+			return err
+		}
+		_, err := w.writer.Write(record)
+		return err
+	case FramingNewline:
+		if bytes.IndexByte(record, '\n') >= 0 {
+			return errors.New("record contains a newline and cannot be written with newline framing")
+		}
+		if _, err := w.writer.Write(record); err != nil {
+			return err
+		}
+		return w.writer.WriteByte('\n')
+	default:
+		return fmt.Errorf("unknown framing: %v", w.framing)
+	}
+}
+
+func (w *writer) Flush() error {
+	return w.writer.Flush()
+}
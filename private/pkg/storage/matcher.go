@@ -63,6 +63,16 @@ func MatchPathContained(containingDir string) Matcher {
 	})
 }
 
+// MatchPathGlob returns a Matcher for the glob.
+//
+// The glob is expected to be validated with normalpath.ValidateGlob.
+// See normalpath.MatchGlob for the syntax of the glob.
+func MatchPathGlob(glob string) Matcher {
+	return pathMatcherFunc(func(path string) bool {
+		return normalpath.MatchGlob(glob, path)
+	})
+}
+
 // MatchOr returns an Or of the Matchers.
 func MatchOr(matchers ...Matcher) Matcher {
 	return orMatcher(matchers)
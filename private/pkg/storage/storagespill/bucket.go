@@ -0,0 +1,307 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagespill
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageutil"
+	"go.uber.org/multierr"
+)
+
+const tempFilePattern = "buf-spill-*"
+
+type bucket struct {
+	maxMemorySize int64
+	tempDirPath   string
+
+	lock         sync.RWMutex
+	pathToObject map[string]*object
+	// memorySize is the total size of the data of the objects in memory.
+	memorySize int64
+	// file is the temporary file, or nil if no object has been spilled.
+	file *os.File
+	// fileSize is the size of the data that has been appended to file.
+	fileSize int64
+	// filePath is the path of file if it could not be removed on creation.
+	filePath string
+	closed   bool
+}
+
+func newBucket(maxMemorySize int64, options ...ReadWriteBucketCloserOption) *bucket {
+	bucket := &bucket{
+		maxMemorySize: maxMemorySize,
+		pathToObject:  make(map[string]*object),
+	}
+	for _, option := range options {
+		option(bucket)
+	}
+	return bucket
+}
+
+func (b *bucket) Get(ctx context.Context, path string) (storage.ReadObjectCloser, error) {
+	object, err := b.readLockAndGetObject("read", path)
+	if err != nil {
+		return nil, err
+	}
+	if object.segments == nil {
+		return newReadObjectCloser(object, object.newMemoryReader()), nil
+	}
+	b.lock.RLock()
+	file := b.file
+	b.lock.RUnlock()
+	return newReadObjectCloser(object, object.newFileReader(file)), nil
+}
+
+func (b *bucket) Stat(ctx context.Context, path string) (storage.ObjectInfo, error) {
+	return b.readLockAndGetObject("stat", path)
+}
+
+func (b *bucket) Walk(ctx context.Context, prefix string, f func(storage.ObjectInfo) error) error {
+	prefix, err := storageutil.ValidatePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	walkChecker := storageutil.NewWalkChecker()
+	// Objects are copied so that f can use the bucket.
+	b.lock.RLock()
+	objects := make([]*object, 0, len(b.pathToObject))
+	for path, object := range b.pathToObject {
+		if normalpath.EqualsOrContainsPath(prefix, path, normalpath.Relative) {
+			objects = append(objects, object)
+		}
+	}
+	b.lock.RUnlock()
+	// To ensure the same iteration order as storagemem.
+	sort.Slice(
+		objects,
+		func(i int, j int) bool {
+			return objects[i].Path() < objects[j].Path()
+		},
+	)
+	for _, object := range objects {
+		if err := walkChecker.Check(ctx); err != nil {
+			return err
+		}
+		if err := f(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *bucket) Put(ctx context.Context, path string, _ ...storage.PutOption) (storage.WriteObjectCloser, error) {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	// Writes are atomic, as objects are only added on close.
+	return newWriteObjectCloser(b, path), nil
+}
+
+func (b *bucket) Delete(ctx context.Context, path string) error {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return err
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.pathToObject[path]; !ok {
+		return &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+	}
+	b.deleteObject(path)
+	return nil
+}
+
+func (b *bucket) DeleteAll(ctx context.Context, prefix string) error {
+	prefix, err := storageutil.ValidatePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for path := range b.pathToObject {
+		if normalpath.EqualsOrContainsPath(prefix, path, normalpath.Relative) {
+			b.deleteObject(path)
+		}
+	}
+	return nil
+}
+
+func (*bucket) SetExternalPathSupported() bool {
+	return true
+}
+
+func (b *bucket) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return storage.ErrClosed
+	}
+	b.closed = true
+	b.pathToObject = nil
+	b.memorySize = 0
+	if b.file == nil {
+		return nil
+	}
+	err := b.file.Close()
+	if b.filePath != "" {
+		err = multierr.Append(err, os.Remove(b.filePath))
+	}
+	return err
+}
+
+func (b *bucket) readLockAndGetObject(op string, path string) (*object, error) {
+	path, err := storageutil.ValidatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if b.closed {
+		return nil, storage.ErrClosed
+	}
+	object, ok := b.pathToObject[path]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: path, Err: fs.ErrNotExist}
+	}
+	return object, nil
+}
+
+// reserveMemory returns true if size more bytes fit in memory, in which case
+// they are added to the memory size.
+//
+// Must not be called with the lock held.
+func (b *bucket) reserveMemory(size int64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.memorySize+size > b.maxMemorySize {
+		return false
+	}
+	b.memorySize += size
+	return true
+}
+
+// releaseMemory removes size bytes from the memory size.
+//
+// Must not be called with the lock held.
+func (b *bucket) releaseMemory(size int64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.memorySize -= size
+}
+
+// appendToFile appends the data to the temporary file, creating it if
+// necessary, and returns the segment of the file that the data was written to.
+//
+// Must not be called with the lock held.
+func (b *bucket) appendToFile(data []byte) (segment, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return segment{}, storage.ErrClosed
+	}
+	if b.file == nil {
+		file, err := os.CreateTemp(b.tempDirPath, tempFilePattern)
+		if err != nil {
+			return segment{}, err
+		}
+		// Removing an open file is not possible on all platforms, in which
+		// case the file is removed on Close.
+		if err := os.Remove(file.Name()); err != nil {
+			b.filePath = file.Name()
+		}
+		b.file = file
+	}
+	if _, err := b.file.WriteAt(data, b.fileSize); err != nil {
+		return segment{}, err
+	}
+	segment := segment{
+		offset: b.fileSize,
+		length: int64(len(data)),
+	}
+	b.fileSize += segment.length
+	return segment, nil
+}
+
+// putObject adds the object, replacing any existing object with the same path.
+//
+// The memory of the object must already be reserved.
+//
+// Must not be called with the lock held.
+func (b *bucket) putObject(object *object) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return storage.ErrClosed
+	}
+	b.deleteObject(object.Path())
+	b.pathToObject[object.Path()] = object
+	return nil
+}
+
+// deleteObject deletes the object with the path, if it exists.
+//
+// Must be called with the lock held.
+func (b *bucket) deleteObject(path string) {
+	existingObject, ok := b.pathToObject[path]
+	if !ok {
+		return
+	}
+	// Note that if there is an existing reader for an object of the same path,
+	// that reader will continue to read the original data, as the data in
+	// memory is immutable and the data in the file is never overwritten.
+	b.memorySize -= int64(len(existingObject.data))
+	delete(b.pathToObject, path)
+}
+
+// object is an object of the bucket.
+//
+// An object is immutable once it is added to the bucket.
+type object struct {
+	storageutil.ObjectInfo
+
+	// data is the data of the object if it is in memory.
+	data []byte
+	// segments are the segments of the temporary file that the data of the
+	// object was written to, in order, or nil if the object is in memory.
+	segments []segment
+}
+
+func (o *object) newMemoryReader() io.Reader {
+	return bytes.NewReader(o.data)
+}
+
+func (o *object) newFileReader(readerAt io.ReaderAt) io.Reader {
+	readers := make([]io.Reader, len(o.segments))
+	for i, segment := range o.segments {
+		readers[i] = io.NewSectionReader(readerAt, segment.offset, segment.length)
+	}
+	return io.MultiReader(readers...)
+}
+
+// segment is a segment of the temporary file.
+type segment struct {
+	offset int64
+	length int64
+}
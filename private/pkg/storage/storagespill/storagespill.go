@@ -0,0 +1,57 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storagespill implements a storage Bucket that is kept in memory up to
+// a maximum size, and spills the rest of its objects to a temporary file.
+package storagespill
+
+import (
+	"github.com/bufbuild/buf/private/pkg/storage"
+)
+
+// NewReadWriteBucketCloser returns a new ReadWriteBucketCloser that keeps the
+// data of its objects in memory until their total size would exceed
+// maxMemorySize, and appends the data of the objects that do not fit to a
+// temporary file.
+//
+// An object is written to memory while it fits, and is moved to the temporary
+// file as soon as it does not, so that a single large object is never buffered
+// in memory beyond maxMemorySize.
+//
+// The temporary file is created on the first write that does not fit in memory.
+// Where the platform allows it, the file is removed as soon as it is created, so
+// that it is reclaimed when the process exits even if the bucket is not closed.
+// Otherwise, it is removed on Close. Objects cannot be read after Close.
+//
+// The space of objects in the temporary file that are overwritten or deleted is
+// not reclaimed until Close.
+func NewReadWriteBucketCloser(
+	maxMemorySize int64,
+	options ...ReadWriteBucketCloserOption,
+) storage.ReadWriteBucketCloser {
+	return newBucket(maxMemorySize, options...)
+}
+
+// ReadWriteBucketCloserOption is an option for a new ReadWriteBucketCloser.
+type ReadWriteBucketCloserOption func(*bucket)
+
+// ReadWriteBucketCloserWithTempDirPath returns a new ReadWriteBucketCloserOption
+// that creates the temporary file in the directory.
+//
+// The default is the default directory for temporary files of the platform.
+func ReadWriteBucketCloserWithTempDirPath(tempDirPath string) ReadWriteBucketCloserOption {
+	return func(bucket *bucket) {
+		bucket.tempDirPath = tempDirPath
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagespill_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/storage/storagespill"
+	"github.com/bufbuild/buf/private/pkg/storage/storagetesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var storagetestingDirPath = filepath.Join("..", "storagetesting")
+
+func TestSpill(t *testing.T) {
+	t.Parallel()
+	for _, maxMemorySize := range []int64{0, 16, 1 << 20} {
+		maxMemorySize := maxMemorySize
+		t.Run(
+			"",
+			func(t *testing.T) {
+				t.Parallel()
+				storagetesting.RunTestSuite(
+					t,
+					storagetestingDirPath,
+					func(t *testing.T, dirPath string, storageosProvider storageos.Provider) (storage.ReadBucket, storagetesting.GetExternalPathFunc) {
+						osBucket, err := storageosProvider.NewReadWriteBucket(
+							dirPath,
+							storageos.ReadWriteBucketWithSymlinksIfSupported(),
+						)
+						require.NoError(t, err)
+						readWriteBucket := testNewReadWriteBucket(t, maxMemorySize)
+						_, err = storage.Copy(
+							context.Background(),
+							osBucket,
+							readWriteBucket,
+							storage.CopyWithExternalPaths(),
+						)
+						require.NoError(t, err)
+						return readWriteBucket, func(t *testing.T, rootPath string, path string) string {
+							// Join calls Clean
+							return normalpath.Unnormalize(normalpath.Join(rootPath, path))
+						}
+					},
+					func(t *testing.T, _ storageos.Provider) storage.WriteBucket {
+						return testNewReadWriteBucket(t, maxMemorySize)
+					},
+					func(t *testing.T, writeBucket storage.WriteBucket) storage.ReadBucket {
+						readWriteBucket, ok := writeBucket.(storage.ReadWriteBucket)
+						require.True(t, ok)
+						return readWriteBucket
+					},
+				)
+			},
+		)
+	}
+}
+
+func TestSpillMemoryLimit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDirPath := t.TempDir()
+	readWriteBucket := storagespill.NewReadWriteBucketCloser(
+		8,
+		storagespill.ReadWriteBucketCloserWithTempDirPath(tempDirPath),
+	)
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "a", []byte("1234")))
+	assertTempFileCount(t, tempDirPath, 0)
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "b", []byte("5678")))
+	assertTempFileCount(t, tempDirPath, 0)
+	// Written in chunks so that the object is spilled after it is partly in memory.
+	writeObjectCloser, err := readWriteBucket.Put(ctx, "c")
+	require.NoError(t, err)
+	for _, chunk := range []string{"abc", "def", "ghi"} {
+		_, err := writeObjectCloser.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writeObjectCloser.Close())
+	// Deleting a makes room for d in memory.
+	require.NoError(t, readWriteBucket.Delete(ctx, "a"))
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "d", []byte("wxyz")))
+	// Overwriting c keeps it in the file.
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "c", []byte("abcdefghij")))
+	// e is empty.
+	require.NoError(t, storage.PutPath(ctx, readWriteBucket, "e", nil))
+	storagetesting.AssertPathToContent(
+		t,
+		readWriteBucket,
+		"",
+		map[string]string{
+			"b": "5678",
+			"c": "abcdefghij",
+			"d": "wxyz",
+			"e": "",
+		},
+	)
+	readObjectCloser, err := readWriteBucket.Get(ctx, "c")
+	require.NoError(t, err)
+	assert.Equal(t, "c", readObjectCloser.ExternalPath())
+	require.NoError(t, readObjectCloser.Close())
+	require.NoError(t, readWriteBucket.Close())
+	assertTempFileCount(t, tempDirPath, 0)
+	_, err = readWriteBucket.Get(ctx, "b")
+	assert.ErrorIs(t, err, storage.ErrClosed)
+}
+
+func TestSpillLargeObject(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	readWriteBucket := testNewReadWriteBucket(t, 1024)
+	data := bytes.Repeat([]byte("0123456789"), 1024)
+	writeObjectCloser, err := readWriteBucket.Put(ctx, "large")
+	require.NoError(t, err)
+	_, err = io.Copy(writeObjectCloser, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.NoError(t, writeObjectCloser.Close())
+	readData, err := storage.ReadPath(ctx, readWriteBucket, "large")
+	require.NoError(t, err)
+	assert.Equal(t, data, readData)
+}
+
+func testNewReadWriteBucket(t *testing.T, maxMemorySize int64) storage.ReadWriteBucketCloser {
+	readWriteBucket := storagespill.NewReadWriteBucketCloser(
+		maxMemorySize,
+		storagespill.ReadWriteBucketCloserWithTempDirPath(t.TempDir()),
+	)
+	t.Cleanup(
+		func() {
+			assert.NoError(t, readWriteBucket.Close())
+		},
+	)
+	return readWriteBucket
+}
+
+func assertTempFileCount(t *testing.T, tempDirPath string, expectedCount int) {
+	dirEntries, err := os.ReadDir(tempDirPath)
+	require.NoError(t, err)
+	assert.Len(t, dirEntries, expectedCount)
+}
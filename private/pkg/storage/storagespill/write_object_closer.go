@@ -0,0 +1,130 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagespill
+
+import (
+	"fmt"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageutil"
+)
+
+type writeObjectCloser struct {
+	bucket       *bucket
+	path         string
+	externalPath string
+	// data is the data that has been written while the object fits in memory,
+	// which is reserved in the memory of the bucket.
+	data []byte
+	// segments are the segments of the temporary file that the data has been
+	// written to once the object does not fit in memory, or nil while it does.
+	segments []segment
+	// err is the error of a failed write, after which the object is not added
+	// to the bucket.
+	err    error
+	closed bool
+}
+
+func newWriteObjectCloser(
+	bucket *bucket,
+	path string,
+) *writeObjectCloser {
+	return &writeObjectCloser{
+		bucket: bucket,
+		path:   path,
+	}
+}
+
+func (w *writeObjectCloser) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, storage.ErrClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if w.segments == nil {
+		if w.bucket.reserveMemory(int64(len(p))) {
+			w.data = append(w.data, p...)
+			return len(p), nil
+		}
+		if err := w.spill(); err != nil {
+			w.err = err
+			return 0, err
+		}
+	}
+	segment, err := w.bucket.appendToFile(p)
+	if err != nil {
+		w.err = err
+		return 0, err
+	}
+	w.segments = append(w.segments, segment)
+	return len(p), nil
+}
+
+func (w *writeObjectCloser) SetExternalPath(externalPath string) error {
+	if w.externalPath != "" {
+		return fmt.Errorf("external path already set: %q", w.externalPath)
+	}
+	w.externalPath = externalPath
+	return nil
+}
+
+func (w *writeObjectCloser) Close() error {
+	if w.closed {
+		return storage.ErrClosed
+	}
+	w.closed = true
+	if w.err != nil {
+		w.bucket.releaseMemory(int64(len(w.data)))
+		return w.err
+	}
+	externalPath := w.externalPath
+	if externalPath == "" {
+		externalPath = w.path
+	}
+	if err := w.bucket.putObject(
+		&object{
+			ObjectInfo: storageutil.NewObjectInfo(w.path, externalPath),
+			data:       w.data,
+			segments:   w.segments,
+		},
+	); err != nil {
+		w.bucket.releaseMemory(int64(len(w.data)))
+		return err
+	}
+	return nil
+}
+
+// spill moves the data that has been written to the temporary file, and
+// releases its memory.
+func (w *writeObjectCloser) spill() error {
+	// segments is never nil once the object is in the file, even if the
+	// object is empty, so that it is not mistaken for an object in memory.
+	segments := make([]segment, 0, 1)
+	if len(w.data) > 0 {
+		segment, err := w.bucket.appendToFile(w.data)
+		if err != nil {
+			return err
+		}
+		segments = append(segments, segment)
+		w.bucket.releaseMemory(int64(len(w.data)))
+		w.data = nil
+	}
+	w.segments = segments
+	return nil
+}
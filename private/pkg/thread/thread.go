@@ -27,6 +27,8 @@ var (
 	globalLock        sync.RWMutex
 )
 
+type parallelismContextKey struct{}
+
 // Parallelism returns the current parellism.
 //
 // This defaults to the number of CPUs.
@@ -49,9 +51,30 @@ func SetParallelism(parallelism int) {
 	globalLock.Unlock()
 }
 
+// WithParallelism returns a new context that overrides the parallelism for
+// functions that are given the context, such as Parallelize.
+//
+// Unlike SetParallelism, this does not change the global parallelism.
+// If parallelism < 1, the parallelism is 1.
+func WithParallelism(ctx context.Context, parallelism int) context.Context {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return context.WithValue(ctx, parallelismContextKey{}, parallelism)
+}
+
+// ParallelismForContext returns the parallelism set on the context with
+// WithParallelism, or Parallelism() if none is set.
+func ParallelismForContext(ctx context.Context) int {
+	if parallelism, ok := ctx.Value(parallelismContextKey{}).(int); ok {
+		return parallelism
+	}
+	return Parallelism()
+}
+
 // Parallelize runs the jobs in parallel.
 //
-// A max of Parallelism jobs will be run at once.
+// A max of ParallelismForContext jobs will be run at once.
 // Returns the combined error from the jobs.
 func Parallelize(ctx context.Context, jobs []func(context.Context) error, options ...ParallelizeOption) error {
 	parallelizeOptions := newParallelizeOptions()
@@ -68,7 +91,7 @@ func Parallelize(ctx context.Context, jobs []func(context.Context) error, option
 	if multiplier < 1 {
 		multiplier = 1
 	}
-	semaphoreC := make(chan struct{}, Parallelism()*multiplier)
+	semaphoreC := make(chan struct{}, ParallelismForContext(ctx)*multiplier)
 	var retErr error
 	var wg sync.WaitGroup
 	var lock sync.Mutex
@@ -119,9 +142,9 @@ func Parallelize(ctx context.Context, jobs []func(context.Context) error, option
 type ParallelizeOption func(*parallelizeOptions)
 
 // ParallelizeWithMultiplier returns a new ParallelizeOption that will use a multiple
-// of Parallelism() for the number of jobs that can be run at once.
+// of ParallelismForContext for the number of jobs that can be run at once.
 //
-// The default is to only do Parallelism() number of jobs.
+// The default is to only do ParallelismForContext number of jobs.
 // A multiplier of <1 has no meaning.
 func ParallelizeWithMultiplier(multiplier int) ParallelizeOption {
 	return func(parallelizeOptions *parallelizeOptions) {
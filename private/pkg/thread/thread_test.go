@@ -17,6 +17,7 @@ package thread
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/atomic"
@@ -60,3 +61,30 @@ func TestParallelizeWithImmediateCancellation(t *testing.T) {
 		assert.Equal(t, int64(0), executed.Load(), "jobs executed")
 	})
 }
+
+func TestParallelismForContext(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	assert.Equal(t, Parallelism(), ParallelismForContext(ctx))
+	assert.Equal(t, 3, ParallelismForContext(WithParallelism(ctx, 3)))
+	assert.Equal(t, 1, ParallelismForContext(WithParallelism(ctx, 0)))
+	var running atomic.Int64
+	var maxRunning atomic.Int64
+	var jobs []func(context.Context) error
+	for i := 0; i < 20; i++ {
+		jobs = append(jobs, func(_ context.Context) error {
+			current := running.Inc()
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CAS(previous, current) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Dec()
+			return nil
+		})
+	}
+	assert.NoError(t, Parallelize(WithParallelism(ctx, 2), jobs))
+	assert.LessOrEqual(t, maxRunning.Load(), int64(2))
+}
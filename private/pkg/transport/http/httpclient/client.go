@@ -15,15 +15,164 @@
 package httpclient
 
 import (
+	"bytes"
 	"crypto/tls"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 )
 
-func newClient(clientTLSConfig *tls.Config) *http.Client {
+// maxIdleConnsPerHost is the maximum number of idle connections kept per host,
+// which is larger than the default of 2 as requests are made concurrently.
+const maxIdleConnsPerHost = 16
+
+func newClient(clientTLSConfig *tls.Config, options ...ClientOption) *http.Client {
+	clientOptions := newClientOptions()
+	for _, option := range options {
+		option(clientOptions)
+	}
+	var transport http.RoundTripper = newTransport(clientTLSConfig)
+	if len(clientOptions.hostToTLSConfig) > 0 {
+		hostToTransport := make(map[string]http.RoundTripper, len(clientOptions.hostToTLSConfig))
+		for host, tlsConfig := range clientOptions.hostToTLSConfig {
+			hostToTransport[host] = newTransport(tlsConfig)
+		}
+		transport = newHostRoundTripper(transport, hostToTransport)
+	}
+	// The mirror round tripper is outside of the host round tripper, so that
+	// the transport for the host of a mirror is used for the requests to it.
+	if len(clientOptions.hostToMirrors) > 0 {
+		transport = newMirrorRoundTripper(transport, clientOptions.hostToMirrors)
+	}
+	if clientOptions.offlineErr != nil {
+		transport = newOfflineRoundTripper(clientOptions.offlineErr)
+	}
 	return &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: clientTLSConfig,
-			Proxy:           http.ProxyFromEnvironment,
-		},
+		Transport: transport,
+	}
+}
+
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           http.ProxyFromEnvironment,
+		// Setting TLSClientConfig disables HTTP/2 unless it is forced, and
+		// requests such as module downloads are made concurrently to the same
+		// host, so connections are multiplexed and kept idle for reuse.
+		ForceAttemptHTTP2:   true,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+	}
+}
+
+type hostRoundTripper struct {
+	delegate        http.RoundTripper
+	hostToTransport map[string]http.RoundTripper
+}
+
+func newHostRoundTripper(delegate http.RoundTripper, hostToTransport map[string]http.RoundTripper) *hostRoundTripper {
+	return &hostRoundTripper{
+		delegate:        delegate,
+		hostToTransport: hostToTransport,
+	}
+}
+
+func (h *hostRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if transport, ok := h.hostToTransport[request.URL.Host]; ok {
+		return transport.RoundTrip(request)
 	}
+	return h.delegate.RoundTrip(request)
+}
+
+type mirrorRoundTripper struct {
+	delegate      http.RoundTripper
+	hostToMirrors map[string][]string
+}
+
+func newMirrorRoundTripper(delegate http.RoundTripper, hostToMirrors map[string][]string) *mirrorRoundTripper {
+	return &mirrorRoundTripper{
+		delegate:      delegate,
+		hostToMirrors: hostToMirrors,
+	}
+}
+
+func (m *mirrorRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	mirrors := m.hostToMirrors[request.URL.Host]
+	if len(mirrors) == 0 {
+		return m.delegate.RoundTrip(request)
+	}
+	if len(mirrors) == 1 {
+		return m.delegate.RoundTrip(requestForMirror(request, mirrors[0], nil))
+	}
+	// The body is read up front so that it can be sent again to the next
+	// mirror if the request to a mirror fails.
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err := request.Body.Close(); err != nil {
+			return nil, err
+		}
+	}
+	errorMessages := make([]string, 0, len(mirrors))
+	for _, mirror := range mirrors {
+		response, err := m.delegate.RoundTrip(requestForMirror(request, mirror, body))
+		if err == nil {
+			return response, nil
+		}
+		if request.Context().Err() != nil {
+			return nil, err
+		}
+		errorMessages = append(errorMessages, fmt.Sprintf("%s: %v", mirror, err))
+	}
+	return nil, fmt.Errorf("all mirrors for %s failed: %s", request.URL.Host, strings.Join(errorMessages, "; "))
+}
+
+// requestForMirror returns a copy of the request that is sent to the mirror.
+//
+// If the body is not nil, it is the body of the copy.
+func requestForMirror(request *http.Request, mirror string, body []byte) *http.Request {
+	mirrorRequest := request.Clone(request.Context())
+	mirrorRequest.URL.Host = mirror
+	// The Host header is set from the URL.
+	mirrorRequest.Host = ""
+	if body != nil {
+		mirrorRequest.Body = io.NopCloser(bytes.NewReader(body))
+		mirrorRequest.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		mirrorRequest.ContentLength = int64(len(body))
+	}
+	return mirrorRequest
+}
+
+type offlineRoundTripper struct {
+	offlineErr error
+}
+
+func newOfflineRoundTripper(offlineErr error) *offlineRoundTripper {
+	return &offlineRoundTripper{
+		offlineErr: offlineErr,
+	}
+}
+
+func (o *offlineRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	// A RoundTripper must always close the body, including on errors.
+	if request.Body != nil {
+		_ = request.Body.Close()
+	}
+	return nil, o.offlineErr
+}
+
+type clientOptions struct {
+	hostToMirrors   map[string][]string
+	hostToTLSConfig map[string]*tls.Config
+	offlineErr      error
+}
+
+func newClientOptions() *clientOptions {
+	return &clientOptions{}
 }
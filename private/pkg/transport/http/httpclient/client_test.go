@@ -0,0 +1,138 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientWithHostMirrors(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		assert.NoError(t, err)
+		_, _ = responseWriter.Write([]byte(request.Host + " " + string(body)))
+	}))
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	unavailableMirror := newUnavailableHost(t)
+	client := NewClient(
+		nil,
+		ClientWithHostMirrors(
+			map[string][]string{
+				"remote.example.com": {serverURL.Host},
+				"other.example.com":  {unavailableMirror, serverURL.Host},
+				"failed.example.com": {unavailableMirror},
+			},
+		),
+	)
+	testClientWithHostMirrors(t, client, "http://remote.example.com/path", serverURL.Host+" body")
+	testClientWithHostMirrors(t, client, "http://other.example.com/path", serverURL.Host+" body")
+	testClientWithHostMirrors(t, client, server.URL, serverURL.Host+" body")
+	_, err = client.Post("http://failed.example.com/path", "text/plain", strings.NewReader("body"))
+	assert.ErrorContains(t, err, unavailableMirror)
+}
+
+func TestClientWithOffline(t *testing.T) {
+	t.Parallel()
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+	t.Cleanup(server.Close)
+	offlineErr := errors.New("offline")
+	client := NewClient(
+		nil,
+		ClientWithOffline(offlineErr),
+		ClientWithHostMirrors(
+			map[string][]string{
+				"remote.example.com": {server.Listener.Addr().String()},
+			},
+		),
+	)
+	_, err := client.Post(server.URL, "text/plain", strings.NewReader("body"))
+	assert.ErrorIs(t, err, offlineErr)
+	_, err = client.Get("http://remote.example.com/path")
+	assert.ErrorIs(t, err, offlineErr)
+	assert.False(t, called, "request should not be sent")
+	response, err := NewClient(nil, ClientWithOffline(nil)).Get(server.URL)
+	require.NoError(t, err)
+	assert.NoError(t, response.Body.Close())
+	assert.True(t, called)
+}
+
+func TestClientWithHostTLSConfigs(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		_, _ = responseWriter.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	// The certificate of the server is not trusted by the default TLS config.
+	_, err = NewClient(&tls.Config{MinVersion: tls.VersionTLS12}).Get(server.URL)
+	assert.Error(t, err)
+	client := NewClient(
+		&tls.Config{MinVersion: tls.VersionTLS12},
+		ClientWithHostTLSConfigs(
+			map[string]*tls.Config{
+				serverURL.Host: server.Client().Transport.(*http.Transport).TLSClientConfig, //nolint:forcetypeassert
+			},
+		),
+		ClientWithHostMirrors(
+			map[string][]string{
+				"remote.example.com": {serverURL.Host},
+			},
+		),
+	)
+	for _, url := range []string{server.URL, "https://remote.example.com/path"} {
+		response, err := client.Get(url)
+		require.NoError(t, err)
+		data, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+		assert.Equal(t, "ok", string(data), url)
+	}
+}
+
+func testClientWithHostMirrors(t *testing.T, client *http.Client, url string, expectedResponse string) {
+	response, err := client.Post(url, "text/plain", strings.NewReader("body"))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, expectedResponse, string(data), url)
+}
+
+// newUnavailableHost returns a host that refuses connections.
+func newUnavailableHost(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	host := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return host
+}
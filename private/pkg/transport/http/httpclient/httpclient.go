@@ -20,6 +20,45 @@ import (
 )
 
 // NewClient returns a new Client.
-func NewClient(clientTLSConfig *tls.Config) *http.Client {
-	return newClient(clientTLSConfig)
+func NewClient(clientTLSConfig *tls.Config, options ...ClientOption) *http.Client {
+	return newClient(clientTLSConfig, options...)
+}
+
+// ClientOption is an option for a new Client.
+type ClientOption func(*clientOptions)
+
+// ClientWithHostMirrors returns a new ClientOption that sends the requests for
+// each host to its mirrors instead.
+//
+// The mirrors for a host are tried in order, and the next mirror is only tried
+// if the request to a mirror fails without a response, such as if the mirror
+// cannot be connected to. The host itself is not tried, unless it is one of its
+// mirrors.
+//
+// The hosts and mirrors are of the form host[:port].
+func ClientWithHostMirrors(hostToMirrors map[string][]string) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.hostToMirrors = hostToMirrors
+	}
+}
+
+// ClientWithOffline returns a new ClientOption that fails all requests without
+// sending them, with an error that wraps offlineErr, if offlineErr is not nil.
+//
+// This is used to disable network access.
+func ClientWithOffline(offlineErr error) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.offlineErr = offlineErr
+	}
+}
+
+// ClientWithHostTLSConfigs returns a new ClientOption that uses the TLS config
+// for each host instead of the TLS config of the Client.
+//
+// The hosts are of the form host[:port], and are matched against the host that
+// a request is sent to, so the mirrors of a host need their own TLS configs.
+func ClientWithHostTLSConfigs(hostToTLSConfig map[string]*tls.Config) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.hostToTLSConfig = hostToTLSConfig
+	}
 }